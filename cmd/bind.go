@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 
 	"github.com/dopejs/gozen/internal/config"
@@ -16,37 +17,83 @@ var bindCmd = &cobra.Command{
 After binding, running 'zen' in this directory will automatically use the bound settings.
 
 Examples:
+  zen bind                      # Pick a profile
   zen bind work                 # Bind to profile 'work'
   zen bind --client codex       # Bind to use Codex
   zen bind work --client codex  # Bind to profile 'work' with Codex
-  zen bind --client ""          # Clear client binding (use default)`,
-	Args: cobra.MaximumNArgs(1),
-	RunE: runBind,
+  zen bind --client ""          # Clear client binding (use default)
+  zen bind work --remote        # Bind every checkout of this repo's origin remote
+  zen bind work --repo          # Bind every repo with this repo's name
+  zen bind work --pattern '~/work/*'  # Bind every directory matching a pattern
+  zen bind --claude-settings    # Let zen manage .claude/settings.local.json
+  zen bind --claude-model opus  # ...and pin Claude Code's model`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeFirstProfileName,
+	RunE:              runBind,
 }
 
 var unbindCmd = &cobra.Command{
 	Use:   "unbind",
 	Short: "Remove binding for current directory",
-	Long:  `Remove the profile and client binding for the current directory.`,
+	Long:  `Remove the profile and client binding for the current directory, or with --remote or --repo for its git remote or repo name.`,
 	Args:  cobra.NoArgs,
 	RunE:  runUnbind,
 }
 
-var statusCmd = &cobra.Command{
-	Use:   "status",
-	Short: "Show binding status for current directory",
-	Long:  `Show the profile and client binding status for the current directory.`,
-	Args:  cobra.NoArgs,
-	RunE:  runStatus,
-}
-
 var bindClient string
+var bindRemote, bindRepo bool
+var bindPattern string
+var bindClaudeSettings bool
+var bindClaudeModel string
 
 func init() {
-	bindCmd.Flags().StringVarP(&bindClient, "client", "c", "", "client to use (claude, codex, opencode)")
+	bindCmd.Flags().StringVarP(&bindClient, "client", "c", "", "client to use (claude, codex, opencode, gemini, aider)")
 	bindCmd.Flags().String("cli", "", "alias for --client (deprecated)")
 	bindCmd.Flags().Lookup("cli").Hidden = true
+	bindCmd.RegisterFlagCompletionFunc("client", completeClientNames)
+	bindCmd.Flags().BoolVar(&bindClaudeSettings, "claude-settings", false, "manage Claude Code's settings.local.json while it runs here (--claude-settings=false to stop)")
+	bindCmd.Flags().StringVar(&bindClaudeModel, "claude-model", "", "model to pin in the managed Claude Code settings (implies --claude-settings)")
+	for _, c := range []*cobra.Command{bindCmd, unbindCmd} {
+		c.Flags().BoolVar(&bindRemote, "remote", false, "bind by the git remote URL instead of the directory")
+		c.Flags().BoolVar(&bindRepo, "repo", false, "bind by the git repo name instead of the directory")
+		c.Flags().StringVar(&bindPattern, "pattern", "", "bind a path pattern such as '~/work/*' instead of the directory")
+	}
+}
+
+// bindTarget returns the binding key for the current directory: its path,
+// or with --remote or --repo its git origin URL or repo name. With
+// --pattern it is the pattern.
+func bindTarget() (string, error) {
+	if bindPattern != "" {
+		if bindRemote || bindRepo {
+			return "", fmt.Errorf("--pattern cannot be used with --remote or --repo")
+		}
+		if !config.IsPatternBinding(bindPattern) {
+			return "", fmt.Errorf("%q is not a pattern (use *, ** or a leading ~/)", bindPattern)
+		}
+		return bindPattern, config.ValidateBindingKey(bindPattern)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current directory: %w", err)
+	}
+	cwd = filepath.Clean(cwd)
+	if !bindRemote && !bindRepo {
+		return cwd, nil
+	}
+	if bindRemote && bindRepo {
+		return "", fmt.Errorf("--remote and --repo cannot be used together")
+	}
+	remotes := config.GitRemotes(cwd)
+	if len(remotes) == 0 {
+		return "", fmt.Errorf("%s is not in a git repository with a remote", cwd)
+	}
+	if bindRemote {
+		return config.RemoteBindingKey(remotes[0]), nil
+	}
+	return config.RepoBindingKey(path.Base(config.NormalizeRemoteURL(remotes[0]))), nil
 }
+
 func runBind(cmd *cobra.Command, args []string) error {
 	var profile string
 	if len(args) > 0 {
@@ -59,22 +106,32 @@ func runBind(cmd *cobra.Command, args []string) error {
 		bindClient, _ = cmd.Flags().GetString("cli")
 	}
 
-	// If neither profile nor client specified, show usage
-	if profile == "" && !clientSet {
-		return fmt.Errorf("specify a profile name and/or --client flag")
-	}
-
-	// Get current directory (absolute path)
-	cwd, err := os.Getwd()
+	// Get the current directory, or its git remote
+	cwd, err := bindTarget()
 	if err != nil {
-		return fmt.Errorf("failed to get current directory: %w", err)
+		return err
 	}
 
-	// Clean the path
-	cwd = filepath.Clean(cwd)
-
 	// Get existing binding to preserve values not being changed
 	existing := config.GetProjectBinding(cwd)
+
+	settingsSet := cmd.Flags().Changed("claude-settings") || cmd.Flags().Changed("claude-model")
+
+	// If neither profile nor client specified, pick a profile
+	if profile == "" && !clientSet && !settingsSet {
+		current := config.GetDefaultProfile()
+		if existing != nil && existing.Profile != "" {
+			current = existing.Profile
+		}
+		name, ok, err := pickName("Bind "+cwd+" to profile", config.ListProfiles(), current)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("specify a profile name and/or --client flag")
+		}
+		profile = name
+	}
 	if existing != nil {
 		if profile == "" {
 			profile = existing.Profile
@@ -88,6 +145,30 @@ func runBind(cmd *cobra.Command, args []string) error {
 	if err := config.BindProject(cwd, profile, bindClient); err != nil {
 		return err
 	}
+	if settingsSet {
+		var cs *config.ClaudeSettingsConfig
+		if existing != nil {
+			cs = existing.ClaudeSettings.Clone()
+		}
+		if cs == nil {
+			cs = &config.ClaudeSettingsConfig{}
+		}
+		cs.Enabled = bindClaudeSettings || !cmd.Flags().Changed("claude-settings")
+		if cmd.Flags().Changed("claude-model") {
+			cs.Model = bindClaudeModel
+		}
+		if err := config.SetProjectClaudeSettings(cwd, cs); err != nil {
+			return err
+		}
+		state := "off"
+		if cs.Enabled {
+			state = "on"
+		}
+		fmt.Printf("Managed Claude Code settings for %s: %s\n", cwd, state)
+		if profile == "" && bindClient == "" {
+			return nil
+		}
+	}
 
 	// Build status message
 	var msg string
@@ -104,11 +185,10 @@ func runBind(cmd *cobra.Command, args []string) error {
 }
 
 func runUnbind(cmd *cobra.Command, args []string) error {
-	cwd, err := os.Getwd()
+	cwd, err := bindTarget()
 	if err != nil {
-		return fmt.Errorf("failed to get current directory: %w", err)
+		return err
 	}
-	cwd = filepath.Clean(cwd)
 
 	binding := config.GetProjectBinding(cwd)
 	if binding == nil {
@@ -131,55 +211,3 @@ func runUnbind(cmd *cobra.Command, args []string) error {
 	fmt.Printf("Removed binding for %s (was: %s)\n", cwd, was)
 	return nil
 }
-
-func runStatus(cmd *cobra.Command, args []string) error {
-	cwd, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("failed to get current directory: %w", err)
-	}
-	cwd = filepath.Clean(cwd)
-
-	binding := config.GetProjectBinding(cwd)
-
-	fmt.Printf("Directory: %s\n", cwd)
-	if binding != nil {
-		if binding.Profile != "" {
-			fmt.Printf("Profile:   %s\n", binding.Profile)
-			if config.GetProfileConfig(binding.Profile) == nil {
-				fmt.Printf("           (profile no longer exists, will use default)\n")
-			}
-		} else {
-			fmt.Printf("Profile:   (default)\n")
-		}
-		if binding.Client != "" {
-			fmt.Printf("Client:    %s\n", binding.Client)
-		} else {
-			fmt.Printf("Client:    (default)\n")
-		}
-	} else {
-		fmt.Printf("Profile:   (not bound, will use default)\n")
-		fmt.Printf("Client:    (not bound, will use default)\n")
-	}
-
-	bindings := config.GetAllProjectBindings()
-	if len(bindings) > 0 {
-		fmt.Printf("\nAll project bindings:\n")
-		for path, b := range bindings {
-			marker := "  "
-			if path == cwd {
-				marker = "> "
-			}
-			var info string
-			if b.Profile != "" && b.Client != "" {
-				info = fmt.Sprintf("%s (client: %s)", b.Profile, b.Client)
-			} else if b.Profile != "" {
-				info = b.Profile
-			} else if b.Client != "" {
-				info = fmt.Sprintf("(client: %s)", b.Client)
-			}
-			fmt.Printf("%s%s -> %s\n", marker, path, info)
-		}
-	}
-
-	return nil
-}
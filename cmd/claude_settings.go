@@ -0,0 +1,321 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"syscall"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// claudeSettingsFile is the settings file zen manages in a project:
+// Claude Code's personal project settings, which take precedence over the
+// shared project and user settings.
+const claudeSettingsFile = ".claude/settings.local.json"
+
+// The user's own settings file is kept at the backup path while zen manages
+// it, and the state file tracks the sessions using it.
+const (
+	claudeSettingsBackupSuffix = ".zen-backup"
+	claudeSettingsStateSuffix  = ".zen-state"
+)
+
+// claudeSettingsState records what zen changed in a managed settings file
+// and which zen processes use it, so the last one to exit restores the
+// user's values. It survives a crashed session, whose dead PID is dropped.
+type claudeSettingsState struct {
+	PIDs     []int    `json:"pids"`
+	Original bool     `json:"original"`        // the user had a settings file, kept at the backup path
+	Env      []string `json:"env,omitempty"`   // env entries zen set
+	Model    bool     `json:"model,omitempty"` // zen set model
+	MCP      bool     `json:"mcp,omitempty"`   // zen set enabledMcpjsonServers
+}
+
+// applyClaudeSettings manages Claude Code's settings in the current
+// directory while the client runs, if it is Claude Code and the directory's
+// binding enables it. The returned func restores them; it does nothing when
+// nothing was managed.
+func applyClaudeSettings(clientBin, proxyURL string, envVars map[string]string) func() {
+	noop := func() {}
+	if GetClientType(clientBin) != ClientClaude {
+		return noop
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return noop
+	}
+	cwd = filepath.Clean(cwd)
+	_, binding := config.FindProjectBinding(cwd)
+	if binding == nil || binding.ClaudeSettings == nil || !binding.ClaudeSettings.Enabled {
+		return noop
+	}
+
+	// Settings env overrides the process env, so write the proxy's too:
+	// a base URL in the user's settings would otherwise bypass zen
+	env := map[string]string{
+		"ANTHROPIC_BASE_URL":   proxyURL,
+		"ANTHROPIC_AUTH_TOKEN": proxyAPIKey(),
+	}
+	for k, v := range envVars {
+		env[k] = v
+	}
+	restore, err := manageClaudeSettings(cwd, binding.ClaudeSettings, env)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to write Claude Code settings: %v\n", err)
+		return noop
+	}
+	return restore
+}
+
+// manageClaudeSettings writes the managed settings of a binding into the
+// Claude Code settings of the project in dir: env (the proxy environment
+// and the binding's entries), the model pin and the .mcp.json servers to
+// enable. The user's other settings pass through unchanged. The returned
+// func puts the user's values back once the session ends.
+func manageClaudeSettings(dir string, cs *config.ClaudeSettingsConfig, env map[string]string) (func(), error) {
+	path := filepath.Join(dir, claudeSettingsFile)
+	backup := path + claudeSettingsBackupSuffix
+
+	state, err := readClaudeSettingsState(path)
+	if err != nil {
+		return nil, err
+	}
+	// With no state, the file (if any) is the user's own; otherwise a
+	// running or crashed session already backed it up
+	if state == nil {
+		state = &claudeSettingsState{}
+		if data, err := os.ReadFile(path); err == nil {
+			if err := writeFileAtomic(backup, data); err != nil {
+				return nil, fmt.Errorf("back up %s: %w", path, err)
+			}
+			state.Original = true
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	settings, err := readSettingsJSON(path)
+	if err != nil {
+		return nil, err
+	}
+	original := map[string]interface{}{}
+	if state.Original {
+		if original, err = readSettingsJSON(backup); err != nil {
+			return nil, err
+		}
+	}
+
+	entries := make(map[string]string, len(env)+len(cs.Env))
+	for k, v := range env {
+		entries[k] = v
+	}
+	for k, v := range cs.Env {
+		entries[k] = v
+	}
+	if len(entries) > 0 {
+		envMap, _ := settings["env"].(map[string]interface{})
+		if envMap == nil {
+			envMap = map[string]interface{}{}
+		}
+		for k, v := range entries {
+			envMap[k] = v
+			state.Env = appendUnique(state.Env, k)
+		}
+		settings["env"] = envMap
+	}
+	if cs.Model != "" {
+		settings["model"] = cs.Model
+		state.Model = true
+	}
+	if len(cs.MCPServers) > 0 {
+		servers := stringList(original["enabledMcpjsonServers"])
+		for _, name := range cs.MCPServers {
+			servers = appendUnique(servers, name)
+		}
+		settings["enabledMcpjsonServers"] = servers
+		state.MCP = true
+	}
+
+	state.PIDs = append(livePIDs(state.PIDs), os.Getpid())
+	if err := writeSettingsJSON(path, settings); err != nil {
+		return nil, err
+	}
+	if err := writeClaudeSettingsState(path, state); err != nil {
+		return nil, err
+	}
+	return func() {
+		if err := restoreClaudeSettings(path); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to restore %s: %v\n", path, err)
+		}
+	}, nil
+}
+
+// restoreClaudeSettings ends this process's use of a managed settings file.
+// The last session puts the user's values of what zen changed back and
+// keeps every other change, such as permissions granted while it ran.
+func restoreClaudeSettings(path string) error {
+	state, err := readClaudeSettingsState(path)
+	if err != nil || state == nil {
+		return err
+	}
+	var others []int
+	for _, pid := range livePIDs(state.PIDs) {
+		if pid != os.Getpid() {
+			others = append(others, pid)
+		}
+	}
+	if len(others) > 0 {
+		state.PIDs = others
+		return writeClaudeSettingsState(path, state)
+	}
+
+	backup := path + claudeSettingsBackupSuffix
+	original := map[string]interface{}{}
+	if state.Original {
+		if original, err = readSettingsJSON(backup); err != nil {
+			return err
+		}
+	}
+	settings, err := readSettingsJSON(path)
+	if err != nil {
+		return err
+	}
+
+	if len(state.Env) > 0 {
+		envMap, _ := settings["env"].(map[string]interface{})
+		if envMap == nil {
+			envMap = map[string]interface{}{}
+		}
+		origEnv, _ := original["env"].(map[string]interface{})
+		for _, k := range state.Env {
+			if v, ok := origEnv[k]; ok {
+				envMap[k] = v
+			} else {
+				delete(envMap, k)
+			}
+		}
+		if len(envMap) == 0 && origEnv == nil {
+			delete(settings, "env")
+		} else {
+			settings["env"] = envMap
+		}
+	}
+	restoreKey := func(key string) {
+		if v, ok := original[key]; ok {
+			settings[key] = v
+		} else {
+			delete(settings, key)
+		}
+	}
+	if state.Model {
+		restoreKey("model")
+	}
+	if state.MCP {
+		restoreKey("enabledMcpjsonServers")
+	}
+
+	if len(settings) == 0 && !state.Original {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	} else if err := writeSettingsJSON(path, settings); err != nil {
+		return err
+	}
+	os.Remove(backup)
+	return os.Remove(path + claudeSettingsStateSuffix)
+}
+
+func readClaudeSettingsState(path string) (*claudeSettingsState, error) {
+	data, err := os.ReadFile(path + claudeSettingsStateSuffix)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state claudeSettingsState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path+claudeSettingsStateSuffix, err)
+	}
+	return &state, nil
+}
+
+func writeClaudeSettingsState(path string, state *claudeSettingsState) error {
+	sort.Strings(state.Env)
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path+claudeSettingsStateSuffix, data)
+}
+
+// readSettingsJSON reads a settings file; a missing one is empty.
+func readSettingsJSON(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return map[string]interface{}{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	settings := map[string]interface{}{}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return settings, nil
+}
+
+func writeSettingsJSON(path string, settings map[string]interface{}) error {
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'))
+}
+
+// writeFileAtomic writes a file through a temporary file and a rename, so
+// Claude Code never reads a partial settings file.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// livePIDs drops the PIDs of processes that have exited.
+func livePIDs(pids []int) []int {
+	var live []int
+	for _, pid := range pids {
+		if proc, err := os.FindProcess(pid); err == nil && proc.Signal(syscall.Signal(0)) == nil {
+			live = append(live, pid)
+		}
+	}
+	return live
+}
+
+func stringList(v interface{}) []string {
+	items, _ := v.([]interface{})
+	var out []string
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+func readTestSettings(t *testing.T, path string) map[string]interface{} {
+	t.Helper()
+	settings, err := readSettingsJSON(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return settings
+}
+
+func TestManageClaudeSettingsRestoresUserValues(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, claudeSettingsFile)
+	original := `{"env":{"ANTHROPIC_BASE_URL":"https://api.example.com","KEEP":"1"},"model":"haiku","enabledMcpjsonServers":["github"]}`
+	os.MkdirAll(filepath.Dir(path), 0755)
+	os.WriteFile(path, []byte(original), 0644)
+
+	cs := &config.ClaudeSettingsConfig{
+		Enabled:    true,
+		Model:      "opus",
+		Env:        map[string]string{"BASH_DEFAULT_TIMEOUT_MS": "60000"},
+		MCPServers: []string{"zen", "github"},
+	}
+	restore, err := manageClaudeSettings(dir, cs, map[string]string{"ANTHROPIC_BASE_URL": "http://127.0.0.1:19841/default/s1"})
+	if err != nil {
+		t.Fatalf("manageClaudeSettings() error: %v", err)
+	}
+
+	managed := readTestSettings(t, path)
+	env := managed["env"].(map[string]interface{})
+	if env["ANTHROPIC_BASE_URL"] != "http://127.0.0.1:19841/default/s1" || env["BASH_DEFAULT_TIMEOUT_MS"] != "60000" || env["KEEP"] != "1" {
+		t.Errorf("managed env = %v", env)
+	}
+	if managed["model"] != "opus" {
+		t.Errorf("managed model = %v, want opus", managed["model"])
+	}
+	if got := stringList(managed["enabledMcpjsonServers"]); !reflect.DeepEqual(got, []string{"github", "zen"}) {
+		t.Errorf("enabledMcpjsonServers = %v", got)
+	}
+
+	// Claude Code records permissions in the same file while it runs
+	managed["permissions"] = map[string]interface{}{"allow": []interface{}{"Bash(go test:*)"}}
+	writeSettingsJSON(path, managed)
+
+	restore()
+
+	restored := readTestSettings(t, path)
+	var want map[string]interface{}
+	json.Unmarshal([]byte(original), &want)
+	want["permissions"] = managed["permissions"]
+	if !reflect.DeepEqual(restored, want) {
+		t.Errorf("restored settings = %v, want %v", restored, want)
+	}
+	for _, suffix := range []string{claudeSettingsBackupSuffix, claudeSettingsStateSuffix} {
+		if _, err := os.Stat(path + suffix); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed after restore", suffix)
+		}
+	}
+}
+
+func TestManageClaudeSettingsWithoutUserFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, claudeSettingsFile)
+
+	restore, err := manageClaudeSettings(dir, &config.ClaudeSettingsConfig{Enabled: true}, map[string]string{"ANTHROPIC_AUTH_TOKEN": "zen-proxy"})
+	if err != nil {
+		t.Fatalf("manageClaudeSettings() error: %v", err)
+	}
+	if env, _ := readTestSettings(t, path)["env"].(map[string]interface{}); env["ANTHROPIC_AUTH_TOKEN"] != "zen-proxy" {
+		t.Errorf("managed env = %v", env)
+	}
+
+	restore()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("a settings file zen created should be removed on restore")
+	}
+}
+
+func TestManageClaudeSettingsSharedSession(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, claudeSettingsFile)
+
+	restore, err := manageClaudeSettings(dir, &config.ClaudeSettingsConfig{Enabled: true, Model: "opus"}, nil)
+	if err != nil {
+		t.Fatalf("manageClaudeSettings() error: %v", err)
+	}
+
+	// Another live session (our parent stands in for it) still uses the file
+	state, _ := readClaudeSettingsState(path)
+	state.PIDs = append(state.PIDs, os.Getppid())
+	writeClaudeSettingsState(path, state)
+
+	restore()
+	if readTestSettings(t, path)["model"] != "opus" {
+		t.Fatal("settings should stay managed while another session runs")
+	}
+	if state, _ := readClaudeSettingsState(path); state == nil || !reflect.DeepEqual(state.PIDs, []int{os.Getppid()}) {
+		t.Fatalf("state after restore = %+v", state)
+	}
+
+	// Once that session is gone, the next restore puts things back
+	state.PIDs = []int{os.Getpid()}
+	writeClaudeSettingsState(path, state)
+	restoreClaudeSettings(path)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("settings should be removed once the last session ends")
+	}
+}
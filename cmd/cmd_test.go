@@ -23,6 +23,89 @@ func TestCompleteConfigNames(t *testing.T) {
 	}
 }
 
+func TestCompleteProfileNames(t *testing.T) {
+	setTestHome(t)
+	writeTestProvider(t, "alpha", &config.ProviderConfig{BaseURL: "https://a.com", AuthToken: "tok"})
+	if err := config.WriteProfileOrder("work", []string{"alpha"}); err != nil {
+		t.Fatal(err)
+	}
+
+	names, _ := completeFirstProfileName(bindCmd, nil, "")
+	if !containsString(names, "work") {
+		t.Errorf("profile names = %v", names)
+	}
+	if names, _ := completeFirstProfileName(bindCmd, []string{"work"}, ""); len(names) != 0 {
+		t.Errorf("second argument completed with %v", names)
+	}
+	if names, _ := completeFirstConfigName(useCmd, []string{"alpha"}, ""); len(names) != 0 {
+		t.Errorf("client args completed with %v", names)
+	}
+}
+
+func TestCompletionCompletesNames(t *testing.T) {
+	setTestHome(t)
+	writeTestProvider(t, "alpha", &config.ProviderConfig{BaseURL: "https://a.com", AuthToken: "tok"})
+	if err := config.WriteProfileOrder("work", []string{"alpha"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The shell scripts call the hidden __complete command
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"__complete", "use", ""}, "alpha"},
+		{[]string{"__complete", "bind", ""}, "work"},
+		{[]string{"__complete", "config", "edit", "profile", ""}, "work"},
+		{[]string{"__complete", "-p", ""}, "work"},
+		{[]string{"__complete", "run", "-c", ""}, "codex"},
+	} {
+		var buf bytes.Buffer
+		rootCmd.SetOut(&buf)
+		rootCmd.SetArgs(tt.args)
+		err := rootCmd.Execute()
+		rootCmd.SetOut(nil)
+		rootCmd.SetArgs(nil)
+		if err != nil {
+			t.Fatalf("%v: %v", tt.args, err)
+		}
+		if !containsString(strings.Split(buf.String(), "\n"), tt.want) {
+			t.Errorf("%v completed %q, want %q", tt.args, buf.String(), tt.want)
+		}
+	}
+}
+
+func TestBindWithoutProfileNotInteractive(t *testing.T) {
+	home := setTestHome(t)
+	noTerminal(t)
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(home)
+
+	err := runBind(bindCmd, nil)
+	if err == nil || !strings.Contains(err.Error(), "specify a profile name") {
+		t.Errorf("runBind() error = %v", err)
+	}
+}
+
+// noTerminal makes commands run without their name argument fall back to
+// usage instead of a picker.
+func noTerminal(t *testing.T) {
+	t.Helper()
+	old := stdinIsTerminal
+	stdinIsTerminal = func() bool { return false }
+	t.Cleanup(func() { stdinIsTerminal = old })
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
 func TestRunCompletion(t *testing.T) {
 	tests := []struct {
 		shell   string
@@ -492,6 +575,7 @@ func TestRunEnable(t *testing.T) {
 
 func TestConfigEditDeleteRequiresArgs(t *testing.T) {
 	setTestHome(t)
+	noTerminal(t)
 
 	cmds := []struct {
 		name string
@@ -1,32 +1,70 @@
 package cmd
 
 import (
-	"os"
-
+	"github.com/dopejs/gozen/internal/config"
 	"github.com/spf13/cobra"
 )
 
 var completionCmd = &cobra.Command{
-	Use:       "completion <shell>",
-	Short:     "Generate shell completion script",
-	Long:      "Generate completion script for zsh, bash, fish, or powershell.",
+	Use:   "completion <shell>",
+	Short: "Generate shell completion script",
+	Long: `Generate completion script for zsh, bash, fish, or powershell.
+
+Besides commands and flags, the scripts complete provider and profile names
+(zen use, zen bind, zen config edit, -p) and clients (-c).
+
+Install:
+  bash  zen completion bash > /etc/bash_completion.d/zen
+        (needs the bash-completion package)
+  zsh   zen completion zsh > "${fpath[1]}/_zen"
+  fish  zen completion fish > ~/.config/fish/completions/zen.fish`,
 	Args:      cobra.ExactArgs(1),
 	ValidArgs: []string{"zsh", "bash", "fish", "powershell"},
 	RunE:      runCompletion,
 }
 
 func runCompletion(cmd *cobra.Command, args []string) error {
+	out := cmd.OutOrStdout()
 	switch args[0] {
 	case "zsh":
-		return rootCmd.GenZshCompletion(os.Stdout)
+		return rootCmd.GenZshCompletion(out)
 	case "bash":
-		return rootCmd.GenBashCompletion(os.Stdout)
+		return rootCmd.GenBashCompletionV2(out, true)
 	case "fish":
-		return rootCmd.GenFishCompletion(os.Stdout, true)
+		return rootCmd.GenFishCompletion(out, true)
 	case "powershell":
-		return rootCmd.GenPowerShellCompletionWithDesc(os.Stdout)
+		return rootCmd.GenPowerShellCompletionWithDesc(out)
 	default:
 		cmd.PrintErrf("Unsupported shell: %s\n", args[0])
 		return nil
 	}
 }
+
+func completeConfigNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	names := config.ProviderNames()
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeFirstConfigName completes a provider name as the first argument only.
+func completeFirstConfigName(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeConfigNames(cmd, args, toComplete)
+}
+
+// completeFirstProfileName completes a profile name as the first argument only.
+func completeFirstProfileName(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeProfileNames(cmd, args, toComplete)
+}
+
+func completeProfileNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return config.ListProfiles(), cobra.ShellCompDirectiveNoFileComp
+}
+
+func completeClientNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return config.AvailableClients, cobra.ShellCompDirectiveNoFileComp
+}
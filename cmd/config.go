@@ -3,7 +3,9 @@ package cmd
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
@@ -34,6 +36,11 @@ Available Commands:
   default-client         Set the default client
   default-profile        Set the default profile
   reset-password         Reset Web UI access password
+  validate               Check zen.json for errors and report how to fix them
+  check-secrets          Check that secret references (env://, vault://, ...) resolve
+  encrypt                Encrypt tokens and credentials in zen.json
+  decrypt                Write zen.json back in plaintext
+  import [file]          Import from claude-code-router or litellm (--format ccr|litellm)
 
 Use "zen config [command] --help" for more information about a command.`,
 	DisableFlagParsing: true,
@@ -90,8 +97,9 @@ var configDeleteCmd = &cobra.Command{
 }
 
 var configDeleteProviderCmd = &cobra.Command{
-	Use:   "provider <name>",
-	Short: "Delete a provider",
+	Use:               "provider <name>",
+	Short:             "Delete a provider",
+	ValidArgsFunction: completeFirstConfigName,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if len(args) == 0 {
 			return cmd.Usage()
@@ -101,8 +109,9 @@ var configDeleteProviderCmd = &cobra.Command{
 }
 
 var configDeleteGroupCmd = &cobra.Command{
-	Use:   "profile <name>",
-	Short: "Delete a profile",
+	Use:               "profile <name>",
+	Short:             "Delete a profile",
+	ValidArgsFunction: completeFirstProfileName,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if len(args) == 0 {
 			return cmd.Usage()
@@ -187,22 +196,38 @@ var configEditCmd = &cobra.Command{
 }
 
 var configEditProviderCmd = &cobra.Command{
-	Use:   "provider <name>",
-	Short: "Edit a provider",
+	Use:               "provider [name]",
+	Short:             "Edit a provider (pick one if name is omitted)",
+	ValidArgsFunction: completeFirstConfigName,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if len(args) == 0 {
-			return cmd.Usage()
+			name, ok, err := pickName("Edit provider", config.ProviderNames(), "")
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return cmd.Usage()
+			}
+			args = []string{name}
 		}
 		return editProvider(args[0])
 	},
 }
 
 var configEditGroupCmd = &cobra.Command{
-	Use:   "profile <name>",
-	Short: "Edit a profile",
+	Use:               "profile [name]",
+	Short:             "Edit a profile (pick one if name is omitted)",
+	ValidArgsFunction: completeFirstProfileName,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if len(args) == 0 {
-			return cmd.Usage()
+			name, ok, err := pickName("Edit profile", config.ListProfiles(), config.GetDefaultProfile())
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return cmd.Usage()
+			}
+			args = []string{name}
 		}
 		return editGroup(args[0])
 	},
@@ -298,7 +323,22 @@ var configDefaultProfileCmd = &cobra.Command{
 var configResetPasswordCmd = &cobra.Command{
 	Use:   "reset-password",
 	Short: "Reset Web UI access password",
+	Long: `Reset the Web UI access password and print the new one.
+
+With --viewer, set a second, read-only password instead: it logs in with the
+viewer role, which can read usage, health and logs but not change anything.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if viewer, _ := cmd.Flags().GetBool("viewer"); viewer {
+			if !web.HasPassword() {
+				return fmt.Errorf("set the Web UI password first: zen config reset-password")
+			}
+			password, err := web.GenerateViewerPassword()
+			if err != nil {
+				return fmt.Errorf("failed to generate password: %w", err)
+			}
+			fmt.Printf("New Web UI viewer password: %s\n", password)
+			return nil
+		}
 		password, err := web.GeneratePassword()
 		if err != nil {
 			return fmt.Errorf("failed to generate password: %w", err)
@@ -332,6 +372,193 @@ var configSyncCmd = &cobra.Command{
 	},
 }
 
+var configSyncRotateCmd = &cobra.Command{
+	Use:   "rotate-passphrase",
+	Short: "Re-encrypt the synced config with a new passphrase",
+	Long: `Re-encrypt the remote sync payload with a new passphrase and save the new
+passphrase in the local sync config. The current passphrase must decrypt the
+remote payload. The new passphrase is read from the first line of standard
+input; an empty line stores the payload unencrypted.
+
+Other devices fail to sync with a "wrong sync passphrase" error until they
+are given the new passphrase.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runConfigSyncRotate(cmd.InOrStdin(), cmd.OutOrStdout())
+	},
+}
+
+func runConfigSyncRotate(in io.Reader, out io.Writer) error {
+	cfg := config.GetSyncConfig()
+	if cfg == nil || cfg.Backend == "" {
+		return fmt.Errorf("sync not configured")
+	}
+	mgr, err := gosync.NewSyncManager(cfg)
+	if err != nil {
+		return fmt.Errorf("sync init: %w", err)
+	}
+	fmt.Fprint(out, "New passphrase: ")
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && line == "" {
+		return fmt.Errorf("read passphrase: %w", err)
+	}
+	passphrase := strings.TrimRight(line, "\r\n")
+	fmt.Fprintln(out)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := mgr.RotatePassphrase(ctx, passphrase); err != nil {
+		return err
+	}
+	status := mgr.Status()
+	if passphrase == "" {
+		fmt.Fprintf(out, "Sync payload on %s is no longer encrypted.\n", cfg.Backend)
+	} else {
+		fmt.Fprintf(out, "Sync payload on %s re-encrypted (key version %d).\n", cfg.Backend, status.KeyVersion)
+	}
+	fmt.Fprintln(out, "Set the new passphrase on your other devices before they sync again.")
+	return nil
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check zen.json for errors and report how to fix them",
+	Long: `Check zen.json for errors and warnings: JSON syntax, profiles and routes that
+reference missing providers, invalid ports, conflicting project bindings and
+everything else checked when the config is loaded.
+
+Exits with an error if the config has errors. Warnings alone don't fail.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		asJSON, _ := cmd.Flags().GetBool("json")
+		return runConfigValidate(cmd.OutOrStdout(), asJSON)
+	},
+}
+
+func runConfigValidate(out io.Writer, asJSON bool) error {
+	report, err := config.CheckConfigFile(config.ConfigFilePath())
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		for _, issue := range append(report.Errors, report.Warnings...) {
+			label := "ERROR"
+			if issue.Severity == config.SeverityWarning {
+				label = "WARN "
+			}
+			fmt.Fprintf(out, "  %s  %s\n", label, issue.Message)
+			if issue.Field != "" {
+				fmt.Fprintf(out, "         field: %s\n", issue.Field)
+			}
+			if issue.Hint != "" {
+				fmt.Fprintf(out, "         fix:   %s\n", issue.Hint)
+			}
+		}
+		if report.Valid && len(report.Warnings) == 0 {
+			fmt.Fprintf(out, "%s is valid.\n", config.ConfigFilePath())
+		}
+	}
+	if !report.Valid {
+		return fmt.Errorf("config has %d error(s)", len(report.Errors))
+	}
+	return nil
+}
+
+var configCheckSecretsCmd = &cobra.Command{
+	Use:   "check-secrets",
+	Short: "Check that secret references in the config resolve",
+	Long: `Resolve every secret reference in the config and report those that fail.
+
+Provider and bot tokens can reference a secret instead of holding it:
+  env://NAME                  environment variable
+  file:///path/to/token       file contents
+  keychain://service/account  macOS Keychain or Linux Secret Service
+  vault://path#field          HashiCorp Vault (VAULT_ADDR, VAULT_TOKEN)`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runConfigCheckSecrets(cmd.OutOrStdout())
+	},
+}
+
+func runConfigCheckSecrets(out io.Writer) error {
+	refs := config.GetSecretRefs()
+	if len(refs) == 0 {
+		fmt.Fprintln(out, "No secret references in the config.")
+		return nil
+	}
+	failed := config.CheckSecretRefs(refs)
+	for _, ref := range refs {
+		if err := failed[ref.Field]; err != nil {
+			fmt.Fprintf(out, "  FAIL  %s: %v\n", ref.Field, err)
+		} else {
+			fmt.Fprintf(out, "  ok    %s (%s)\n", ref.Field, ref.Ref)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d secret references failed to resolve", len(failed), len(refs))
+	}
+	return nil
+}
+
+var configEncryptCmd = &cobra.Command{
+	Use:   "encrypt",
+	Short: "Encrypt tokens and credentials in zen.json",
+	Long: `Encrypt the provider, bot and sync tokens and credentials stored in zen.json.
+
+By default the key is derived from a passphrase, read from ZEN_CONFIG_PASSPHRASE
+or the first line of standard input. ZEN_CONFIG_PASSPHRASE must then be set
+wherever zen runs. With --keychain a random key is stored in the OS keyring
+(macOS Keychain or Linux Secret Service) instead.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		keychain, _ := cmd.Flags().GetBool("keychain")
+		return runConfigEncrypt(cmd.InOrStdin(), cmd.OutOrStdout(), keychain)
+	},
+}
+
+func runConfigEncrypt(in io.Reader, out io.Writer, keychain bool) error {
+	source, passphrase := config.EncryptionKeyKeychain, ""
+	if !keychain {
+		source = config.EncryptionKeyPassphrase
+		if passphrase = os.Getenv(config.ConfigPassphraseEnv); passphrase == "" {
+			fmt.Fprint(out, "Passphrase: ")
+			line, err := bufio.NewReader(in).ReadString('\n')
+			if err != nil && line == "" {
+				return fmt.Errorf("read passphrase: %w", err)
+			}
+			passphrase = strings.TrimRight(line, "\r\n")
+			fmt.Fprintln(out)
+		}
+	}
+	if err := config.EnableEncryption(source, passphrase); err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "Config encrypted.")
+	if !keychain {
+		fmt.Fprintf(out, "Set %s to the passphrase wherever zen runs, including the daemon.\n", config.ConfigPassphraseEnv)
+	}
+	return nil
+}
+
+var configDecryptCmd = &cobra.Command{
+	Use:   "decrypt",
+	Short: "Write zen.json back in plaintext",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.DisableEncryption(); err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), "Config decrypted.")
+		return nil
+	},
+}
+
 var configSetCmd = &cobra.Command{
 	Use:   "set <key> <value>",
 	Short: "Set a configuration value",
@@ -368,6 +595,10 @@ func runConfigSet(cmd *cobra.Command, args []string) error {
 }
 
 func init() {
+	configValidateCmd.Flags().Bool("json", false, "print the report as JSON")
+	configEncryptCmd.Flags().Bool("keychain", false, "store a random key in the OS keyring instead of using a passphrase")
+	configResetPasswordCmd.Flags().Bool("viewer", false, "set the read-only viewer password instead")
+
 	configAddCmd.AddCommand(configAddProviderCmd)
 	configAddCmd.AddCommand(configAddGroupCmd)
 
@@ -384,5 +615,12 @@ func init() {
 	configCmd.AddCommand(configDefaultClientCmd)
 	configCmd.AddCommand(configDefaultProfileCmd)
 	configCmd.AddCommand(configResetPasswordCmd)
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configCheckSecretsCmd)
+	configCmd.AddCommand(configEncryptCmd)
+	configCmd.AddCommand(configDecryptCmd)
+	configSyncCmd.AddCommand(configSyncRotateCmd)
 	configCmd.AddCommand(configSyncCmd)
+	configCmd.AddCommand(configTeamCmd)
+	configCmd.AddCommand(configImportCmd)
 }
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/migrate"
+	"github.com/spf13/cobra"
+)
+
+var (
+	importFormat    string
+	importDryRun    bool
+	importOverwrite bool
+)
+
+var configImportCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Import providers and profiles from another proxy tool",
+	Long: `Import providers and profiles from another proxy tool's config.
+
+Supported formats:
+  ccr         claude-code-router config.json (default: ~/.claude-code-router/config.json)
+  litellm     litellm proxy config.yaml
+  openrouter  OpenRouter-style JSON provider list ([{"name", "base_url", "api_key", "models"}, ...])
+
+Existing providers and profiles with the same name are skipped unless --overwrite is set.
+Anything that could not be mapped is reported as a warning.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runConfigImport,
+}
+
+func init() {
+	configImportCmd.Flags().StringVar(&importFormat, "format", "", "source format ("+strings.Join(migrate.Formats, ", ")+")")
+	configImportCmd.Flags().BoolVar(&importDryRun, "dry-run", false, "show what would be imported without saving")
+	configImportCmd.Flags().BoolVar(&importOverwrite, "overwrite", false, "replace existing providers and profiles with the same name")
+}
+
+func runConfigImport(cmd *cobra.Command, args []string) error {
+	out := cmd.OutOrStdout()
+	if importFormat == "" {
+		return fmt.Errorf("--format is required (%s)", strings.Join(migrate.Formats, ", "))
+	}
+
+	path := migrate.DefaultPath(importFormat)
+	if len(args) > 0 {
+		path = args[0]
+	}
+	if path == "" {
+		return fmt.Errorf("specify the %s config file to import", importFormat)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+	res, err := migrate.Import(importFormat, data)
+	if err != nil {
+		return err
+	}
+
+	store := config.DefaultStore()
+	var imported, skipped int
+
+	for _, name := range res.ProviderNames() {
+		if store.GetProvider(name) != nil && !importOverwrite {
+			fmt.Fprintf(out, "  skip provider %s (already exists)\n", name)
+			skipped++
+			continue
+		}
+		p := res.Providers[name]
+		fmt.Fprintf(out, "  provider %-20s %s %s\n", name, p.GetType(), p.BaseURL)
+		if !importDryRun {
+			if err := store.SetProvider(name, p); err != nil {
+				return fmt.Errorf("save provider %q: %w", name, err)
+			}
+		}
+		imported++
+	}
+
+	for _, name := range res.ProfileNames() {
+		if store.GetProfileConfig(name) != nil && !importOverwrite {
+			fmt.Fprintf(out, "  skip profile %s (already exists)\n", name)
+			skipped++
+			continue
+		}
+		pc := res.Profiles[name]
+		fmt.Fprintf(out, "  profile  %-20s %s\n", name, strings.Join(pc.Providers, ", "))
+		if !importDryRun {
+			if err := store.SetProfileConfig(name, pc); err != nil {
+				return fmt.Errorf("save profile %q: %w", name, err)
+			}
+		}
+		imported++
+	}
+
+	for _, w := range res.Warnings {
+		fmt.Fprintf(out, "  warning: %s\n", w)
+	}
+
+	if importDryRun {
+		fmt.Fprintf(out, "Dry run: %d item(s) would be imported, %d skipped.\n", imported, skipped)
+	} else {
+		fmt.Fprintf(out, "Imported %d item(s), %d skipped.\n", imported, skipped)
+	}
+	return nil
+}
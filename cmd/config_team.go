@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+	gosync "github.com/dopejs/gozen/internal/sync"
+	"github.com/spf13/cobra"
+)
+
+var teamPushTokens bool
+
+var configTeamCmd = &cobra.Command{
+	Use:   "team",
+	Short: "Share providers, profiles and budgets with a team",
+	Long: `Share providers, profiles and budgets through a team layer.
+
+The team layer is kept on a sync backend set under "team" in zen.json, in the
+same form as "sync". A team lead pushes it, team members pull it. zen.json is
+laid over the team layer, so each machine keeps its own bindings, ports and
+tokens, and can override any team setting.`,
+}
+
+var configTeamPullCmd = &cobra.Command{
+	Use:   "pull",
+	Short: "Pull the team layer",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runConfigTeamPull(cmd.OutOrStdout())
+	},
+}
+
+var configTeamPushCmd = &cobra.Command{
+	Use:   "push",
+	Short: "Publish this machine's providers, profiles and budgets as the team layer",
+	Long: `Publish this machine's providers, profiles and budgets as the team layer,
+replacing the one on the team backend. Provider tokens are left out unless
+--include-tokens is set, so each team member adds their own.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runConfigTeamPush(cmd.OutOrStdout(), teamPushTokens)
+	},
+}
+
+var configTeamStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the team layer in effect",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runConfigTeamStatus(cmd.OutOrStdout())
+	},
+}
+
+var configTeamLeaveCmd = &cobra.Command{
+	Use:   "leave",
+	Short: "Stop using the team layer",
+	Long: `Stop using the team layer. The team's current settings are kept in zen.json
+and team.json is removed. Remove "team" from zen.json to stop pulling it again.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.DefaultStore().SetTeamLayer(nil); err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), "Left the team layer; its settings are kept in zen.json.")
+		return nil
+	},
+}
+
+func init() {
+	configTeamPushCmd.Flags().BoolVar(&teamPushTokens, "include-tokens", false, "include provider tokens in the team layer")
+	configTeamCmd.AddCommand(configTeamPullCmd, configTeamPushCmd, configTeamStatusCmd, configTeamLeaveCmd)
+}
+
+// teamSource returns the configured team backend.
+func teamSource() (*config.SyncConfig, error) {
+	cfg := config.DefaultStore().GetTeamSource()
+	if cfg == nil || cfg.Backend == "" {
+		return nil, fmt.Errorf(`team not configured: set "team" in zen.json to a sync backend`)
+	}
+	return cfg, nil
+}
+
+func runConfigTeamPull(out io.Writer) error {
+	cfg, err := teamSource()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	layer, err := gosync.PullTeam(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Pulled team layer from %s: %d provider(s), %d profile(s).\n", cfg.Backend, len(layer.Providers), len(layer.Profiles))
+	return nil
+}
+
+func runConfigTeamPush(out io.Writer, withTokens bool) error {
+	cfg, err := teamSource()
+	if err != nil {
+		return err
+	}
+	layer := config.DefaultStore().TeamLayerFromConfig(withTokens)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := gosync.PushTeam(ctx, cfg, layer); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Pushed team layer to %s: %d provider(s), %d profile(s).\n", cfg.Backend, len(layer.Providers), len(layer.Profiles))
+	if !withTokens {
+		fmt.Fprintln(out, "Provider tokens were left out; team members set their own in zen.json.")
+	}
+	return nil
+}
+
+func runConfigTeamStatus(out io.Writer) error {
+	status := config.DefaultStore().TeamStatus()
+	switch {
+	case !status.Loaded && !status.Configured:
+		fmt.Fprintln(out, "No team configured.")
+		return nil
+	case !status.Loaded:
+		fmt.Fprintln(out, "Team configured but not pulled yet: run 'zen config team pull'.")
+		return nil
+	}
+	fmt.Fprintf(out, "Team layer updated %s", status.UpdatedAt.Local().Format("2006-01-02 15:04"))
+	if status.UpdatedBy != "" {
+		fmt.Fprintf(out, " by %s", status.UpdatedBy)
+	}
+	fmt.Fprintln(out)
+	if len(status.Providers) > 0 {
+		fmt.Fprintf(out, "  providers: %s\n", strings.Join(status.Providers, ", "))
+	}
+	if len(status.Profiles) > 0 {
+		fmt.Fprintf(out, "  profiles:  %s\n", strings.Join(status.Profiles, ", "))
+	}
+	if status.Budgets {
+		fmt.Fprintln(out, "  budgets")
+	}
+	return nil
+}
@@ -3,6 +3,8 @@ package cmd
 import (
 	"bytes"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/dopejs/gozen/internal/config"
@@ -124,3 +126,172 @@ func TestConfigSet(t *testing.T) {
 		}
 	})
 }
+
+func TestRunConfigImport(t *testing.T) {
+	dir := setTestHome(t)
+	writeTestProvider(t, "deepseek", &config.ProviderConfig{BaseURL: "https://existing.com", AuthToken: "tok"})
+
+	path := filepath.Join(dir, "ccr.json")
+	os.WriteFile(path, []byte(`{
+		"Providers": [
+			{"name": "deepseek", "api_base_url": "https://api.deepseek.com/chat/completions", "api_key": "k", "models": ["deepseek-chat"]},
+			{"name": "kimi", "api_base_url": "https://api.moonshot.cn/v1/chat/completions", "api_key": "k", "models": ["kimi-k2"]}
+		],
+		"Router": {"default": "kimi,kimi-k2"}
+	}`), 0644)
+
+	importFormat, importDryRun, importOverwrite = "ccr", false, false
+	defer func() { importFormat = "" }()
+
+	var buf bytes.Buffer
+	configImportCmd.SetOut(&buf)
+	defer configImportCmd.SetOut(nil)
+
+	if err := runConfigImport(configImportCmd, []string{path}); err != nil {
+		t.Fatalf("runConfigImport() error = %v", err)
+	}
+
+	if p := config.GetProvider("deepseek"); p.BaseURL != "https://existing.com" {
+		t.Errorf("existing provider overwritten: %s", p.BaseURL)
+	}
+	if p := config.GetProvider("kimi"); p == nil || p.BaseURL != "https://api.moonshot.cn/v1" {
+		t.Errorf("kimi = %+v", p)
+	}
+	if pc := config.GetProfileConfig("ccr"); pc == nil || pc.Providers[0] != "kimi" {
+		t.Errorf("ccr profile = %+v", pc)
+	}
+	if !strings.Contains(buf.String(), "skip provider deepseek") {
+		t.Errorf("output = %s", buf.String())
+	}
+}
+
+func TestRunConfigImportRequiresFormat(t *testing.T) {
+	setTestHome(t)
+	importFormat = ""
+	if err := runConfigImport(configImportCmd, []string{"x"}); err == nil {
+		t.Error("expected error without --format")
+	}
+}
+
+func TestConfigCheckSecrets(t *testing.T) {
+	setTestHome(t)
+	config.ClearSecretCache()
+	t.Cleanup(config.ClearSecretCache)
+	t.Setenv("ZEN_TEST_KEY", "sk-test")
+
+	var buf bytes.Buffer
+	if err := runConfigCheckSecrets(&buf); err != nil || !strings.Contains(buf.String(), "No secret references") {
+		t.Fatalf("empty config: %v, %q", err, buf.String())
+	}
+
+	writeTestProvider(t, "good", &config.ProviderConfig{BaseURL: "https://a.example.com", AuthToken: "env://ZEN_TEST_KEY"})
+	writeTestProvider(t, "bad", &config.ProviderConfig{BaseURL: "https://b.example.com", AuthToken: "env://ZEN_TEST_UNSET"})
+	buf.Reset()
+	err := runConfigCheckSecrets(&buf)
+	if err == nil {
+		t.Error("expected an error for the unresolved reference")
+	}
+	out := buf.String()
+	if !strings.Contains(out, "FAIL  providers.bad.auth_token") || !strings.Contains(out, "ok    providers.good.auth_token") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+	if strings.Contains(out, "sk-test") {
+		t.Error("output leaks the resolved secret")
+	}
+}
+
+func TestConfigEncrypt(t *testing.T) {
+	home := setTestHome(t)
+	t.Setenv(config.ConfigPassphraseEnv, "")
+	writeTestProvider(t, "p", &config.ProviderConfig{BaseURL: "https://a.example.com", AuthToken: "sk-plain"})
+
+	var buf bytes.Buffer
+	if err := runConfigEncrypt(strings.NewReader("hunter2\n"), &buf, false); err != nil {
+		t.Fatalf("runConfigEncrypt() error: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(home, config.ConfigDir, config.ConfigFile))
+	if strings.Contains(string(data), "sk-plain") {
+		t.Error("zen.json still holds the token in plaintext")
+	}
+	if !config.IsEncrypted() || config.GetProvider("p").AuthToken != "sk-plain" {
+		t.Error("the config should be encrypted and still readable in this process")
+	}
+	if err := runConfigEncrypt(strings.NewReader("again\n"), &buf, false); err == nil {
+		t.Error("encrypting twice should fail")
+	}
+
+	if err := config.DisableEncryption(); err != nil {
+		t.Fatalf("DisableEncryption() error: %v", err)
+	}
+	data, _ = os.ReadFile(filepath.Join(home, config.ConfigDir, config.ConfigFile))
+	if !strings.Contains(string(data), "sk-plain") {
+		t.Error("zen.json should be plaintext after decrypt")
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	setTestHome(t)
+	writeTestProvider(t, "a", &config.ProviderConfig{BaseURL: "https://a.example.com", AuthToken: "k"})
+	config.SetProfileConfig("default", &config.ProfileConfig{Providers: []string{"a"}})
+
+	var buf bytes.Buffer
+	if err := runConfigValidate(&buf, false); err != nil {
+		t.Fatalf("valid config: %v\n%s", err, buf.String())
+	}
+	if !strings.Contains(buf.String(), "is valid") {
+		t.Errorf("unexpected output: %q", buf.String())
+	}
+
+	config.SetProxyPort(config.DefaultWebPort)
+	buf.Reset()
+	if err := runConfigValidate(&buf, false); err == nil {
+		t.Error("expected an error for clashing ports")
+	}
+	if out := buf.String(); !strings.Contains(out, "ERROR") || !strings.Contains(out, "field: web_port") || !strings.Contains(out, "fix:") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+
+	buf.Reset()
+	runConfigValidate(&buf, true)
+	if !strings.Contains(buf.String(), `"valid": false`) {
+		t.Errorf("unexpected JSON output:\n%s", buf.String())
+	}
+}
+
+func TestConfigSyncRotateNotConfigured(t *testing.T) {
+	setTestHome(t)
+	var buf bytes.Buffer
+	err := runConfigSyncRotate(strings.NewReader("new\n"), &buf)
+	if err == nil || !strings.Contains(err.Error(), "not configured") {
+		t.Fatalf("expected not configured error, got %v", err)
+	}
+}
+
+func TestConfigTeamStatus(t *testing.T) {
+	setTestHome(t)
+	var buf bytes.Buffer
+	if err := runConfigTeamStatus(&buf); err != nil {
+		t.Fatalf("runConfigTeamStatus() error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "No team configured") {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+
+	writeTestProvider(t, "p", &config.ProviderConfig{BaseURL: "https://a.example.com", AuthToken: "sk-a"})
+	if err := config.DefaultStore().SetTeamLayer(&config.TeamLayer{
+		UpdatedBy: "lead",
+		Providers: map[string]*config.ProviderConfig{"team-api": {BaseURL: "https://team.example.com"}},
+	}); err != nil {
+		t.Fatalf("SetTeamLayer() error: %v", err)
+	}
+	buf.Reset()
+	if err := runConfigTeamStatus(&buf); err != nil {
+		t.Fatalf("runConfigTeamStatus() error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "by lead") || !strings.Contains(buf.String(), "providers: team-api") {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+	if _, err := teamSource(); err == nil {
+		t.Error("teamSource() should fail without a team backend")
+	}
+}
@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/dopejs/gozen/internal/crashreport"
+	"github.com/spf13/cobra"
+)
+
+// crashIssueURL is where zen crash submit opens a new issue.
+const crashIssueURL = "https://github.com/dopejs/gozen/issues/new"
+
+// Lines of a report's stack and log tail put in a submitted issue, which
+// has to fit in a URL.
+const (
+	crashIssueStackLines = 60
+	crashIssueLogLines   = 20
+)
+
+// crashOpenURL opens a submitted report's issue; tests replace it.
+var crashOpenURL = openBrowser
+
+var crashCmd = &cobra.Command{
+	Use:   "crash",
+	Short: "List, show and submit crash reports",
+	Long: `zend writes a crash report to ~/.zen/crashes when one of its goroutines
+panics, and keeps running: the panic, its stack and the last lines of the
+daemon log, with credentials redacted. zen status shows how many reports are
+pending.
+
+Reports never leave the machine unless you submit one with 'zen crash submit',
+which opens a GitHub issue prefilled with it for you to review and send.
+
+Without a subcommand, list the reports.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runCrashList(cmd.OutOrStdout())
+	},
+}
+
+var crashListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List crash reports, newest first",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runCrashList(cmd.OutOrStdout())
+	},
+}
+
+var crashShowCmd = &cobra.Command{
+	Use:   "show [id]",
+	Short: "Print a crash report (default: the newest)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := loadCrashReport(args)
+		if err != nil {
+			return err
+		}
+		printCrashReport(cmd.OutOrStdout(), r)
+		return nil
+	},
+}
+
+var crashSubmitCmd = &cobra.Command{
+	Use:   "submit [id]",
+	Short: "Open a GitHub issue with a crash report (default: the newest)",
+	Long: `Print the crash report that would be submitted, ask for confirmation, then
+open a new GitHub issue in the browser prefilled with it. Nothing is sent
+until you submit the issue there. The issue carries the start of the stack
+and the last lines of the log; check them before sending.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		yes, _ := cmd.Flags().GetBool("yes")
+		return runCrashSubmit(cmd.InOrStdin(), cmd.OutOrStdout(), args, yes)
+	},
+}
+
+var crashClearCmd = &cobra.Command{
+	Use:   "clear [id...]",
+	Short: "Remove crash reports (default: all)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runCrashClear(cmd.OutOrStdout(), args)
+	},
+}
+
+func init() {
+	crashSubmitCmd.Flags().BoolP("yes", "y", false, "don't ask for confirmation")
+	crashCmd.AddCommand(crashListCmd)
+	crashCmd.AddCommand(crashShowCmd)
+	crashCmd.AddCommand(crashSubmitCmd)
+	crashCmd.AddCommand(crashClearCmd)
+}
+
+func runCrashList(out io.Writer) error {
+	reports, err := crashreport.List()
+	if err != nil {
+		return err
+	}
+	if len(reports) == 0 {
+		fmt.Fprintln(out, "No crash reports.")
+		return nil
+	}
+	for _, r := range reports {
+		state := "pending"
+		if r.Submitted != nil {
+			state = "submitted"
+		}
+		fmt.Fprintf(out, "%s  %-9s  %-12s  %s\n", r.ID, state, r.Component, firstLine(r.Panic))
+	}
+	return nil
+}
+
+// loadCrashReport returns the report named by args, or the newest one.
+func loadCrashReport(args []string) (*crashreport.Report, error) {
+	if len(args) == 1 {
+		return crashreport.Load(args[0])
+	}
+	reports, err := crashreport.List()
+	if err != nil {
+		return nil, err
+	}
+	if len(reports) == 0 {
+		return nil, fmt.Errorf("no crash reports")
+	}
+	return reports[0], nil
+}
+
+func printCrashReport(out io.Writer, r *crashreport.Report) {
+	fmt.Fprintf(out, "Report:    %s\n", r.ID)
+	fmt.Fprintf(out, "Time:      %s\n", r.Time.Local().Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(out, "Component: %s\n", r.Component)
+	fmt.Fprintf(out, "Version:   %s (%s %s/%s)\n", r.Version, r.GoVersion, r.OS, r.Arch)
+	if r.Submitted != nil {
+		fmt.Fprintf(out, "Submitted: %s\n", r.Submitted.Local().Format("2006-01-02 15:04:05"))
+	}
+	fmt.Fprintf(out, "Panic:     %s\n\n%s\n", r.Panic, strings.TrimRight(r.Stack, "\n"))
+	if len(r.LogTail) > 0 {
+		fmt.Fprintln(out, "\nLog before the panic:")
+		for _, line := range r.LogTail {
+			fmt.Fprintf(out, "  %s\n", line)
+		}
+	}
+}
+
+func runCrashSubmit(in io.Reader, out io.Writer, args []string, yes bool) error {
+	r, err := loadCrashReport(args)
+	if err != nil {
+		return err
+	}
+	title, body := crashIssue(r)
+	fmt.Fprintf(out, "%s\n\n%s\n\n", title, body)
+	if !yes {
+		fmt.Fprint(out, "Open a GitHub issue with this report? [y/N] ")
+		scanner := bufio.NewScanner(in)
+		answer := ""
+		if scanner.Scan() {
+			answer = strings.TrimSpace(scanner.Text())
+		}
+		if answer != "y" && answer != "Y" && answer != "yes" {
+			fmt.Fprintln(out, "Aborted.")
+			return nil
+		}
+	}
+
+	issue := crashIssueURL + "?" + url.Values{"title": {title}, "body": {body}}.Encode()
+	fmt.Fprintf(out, "Opening %s\n", crashIssueURL)
+	crashOpenURL(issue)
+	return crashreport.MarkSubmitted(r)
+}
+
+// crashIssue returns the title and Markdown body of the issue for r.
+func crashIssue(r *crashreport.Report) (string, string) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "zen %s (%s %s/%s), component %s, report %s\n\n", r.Version, r.GoVersion, r.OS, r.Arch, r.Component, r.ID)
+	fmt.Fprintf(&b, "```\npanic: %s\n\n%s\n```\n", r.Panic, strings.Join(headLines(r.Stack, crashIssueStackLines), "\n"))
+	if len(r.LogTail) > 0 {
+		tail := r.LogTail[max(0, len(r.LogTail)-crashIssueLogLines):]
+		fmt.Fprintf(&b, "\nLog before the panic:\n\n```\n%s\n```\n", strings.Join(tail, "\n"))
+	}
+	return "Crash in " + r.Component + ": " + truncateRight(firstLine(r.Panic), 80), b.String()
+}
+
+func headLines(s string, n int) []string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = append(lines[:n], "...")
+	}
+	return lines
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+func runCrashClear(out io.Writer, ids []string) error {
+	if len(ids) == 0 {
+		reports, err := crashreport.List()
+		if err != nil {
+			return err
+		}
+		for _, r := range reports {
+			ids = append(ids, r.ID)
+		}
+	}
+	for _, id := range ids {
+		r, err := crashreport.Load(id)
+		if err != nil {
+			return err
+		}
+		if err := crashreport.Remove(r.ID); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(out, "Removed %d crash report(s).\n", len(ids))
+	return nil
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/crashreport"
+)
+
+// writeTestCrash writes a crash report under the test home.
+func writeTestCrash(t *testing.T, component string, p interface{}) *crashreport.Report {
+	t.Helper()
+	crashreport.Init(Version, log.New(io.Discard, "", 0))
+	r, err := crashreport.Write(component, p, []byte("goroutine 7 [running]:\nmain.serve()\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestCrashListAndSubmit(t *testing.T) {
+	setTestHome(t)
+	r := writeTestCrash(t, "proxy", "nil map write")
+
+	var out bytes.Buffer
+	if err := runCrashList(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), r.ID) || !strings.Contains(out.String(), "pending") {
+		t.Errorf("list output:\n%s", out.String())
+	}
+
+	// Declining leaves the report pending
+	out.Reset()
+	var opened string
+	crashOpenURL = func(u string) { opened = u }
+	defer func() { crashOpenURL = openBrowser }()
+	if err := runCrashSubmit(strings.NewReader("n\n"), &out, nil, false); err != nil {
+		t.Fatal(err)
+	}
+	if opened != "" || crashreport.Pending() != 1 {
+		t.Errorf("declined submit opened %q, pending %d", opened, crashreport.Pending())
+	}
+
+	out.Reset()
+	if err := runCrashSubmit(nil, &out, []string{r.ID}, true); err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(opened)
+	if err != nil || !strings.HasPrefix(opened, crashIssueURL+"?") {
+		t.Fatalf("opened %q", opened)
+	}
+	if title := u.Query().Get("title"); title != "Crash in proxy: nil map write" {
+		t.Errorf("issue title = %q", title)
+	}
+	if body := u.Query().Get("body"); !strings.Contains(body, "main.serve()") || !strings.Contains(body, r.ID) {
+		t.Errorf("issue body = %q", body)
+	}
+	if crashreport.Pending() != 0 {
+		t.Errorf("Pending() = %d after submit, want 0", crashreport.Pending())
+	}
+
+	out.Reset()
+	if err := runCrashClear(&out, nil); err != nil {
+		t.Fatal(err)
+	}
+	if reports, _ := crashreport.List(); len(reports) != 0 {
+		t.Errorf("%d reports left after clear", len(reports))
+	}
+}
+
+func TestRunStatusCrashReports(t *testing.T) {
+	home := setTestHome(t)
+	config.SetProxyPort(1)
+	writeTestCrash(t, "bot:telegram", "index out of range")
+
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(home)
+
+	var buf bytes.Buffer
+	statusCmd.SetOut(&buf)
+	defer statusCmd.SetOut(nil)
+	if err := runStatus(statusCmd, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "Crashes:   1 crash report pending") {
+		t.Errorf("output missing pending crash report:\n%s", buf.String())
+	}
+}
@@ -5,7 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"io"
 	"net"
 	"net/http"
 	"os"
@@ -16,11 +16,13 @@ import (
 	"time"
 
 	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/crashreport"
 	"github.com/dopejs/gozen/internal/daemon"
 	"github.com/spf13/cobra"
 )
 
 var daemonForegroundFlag bool
+var daemonDrainTimeoutFlag time.Duration
 
 var daemonCmd = &cobra.Command{
 	Use:   "daemon",
@@ -78,6 +80,7 @@ var daemonDisableCmd = &cobra.Command{
 
 func init() {
 	daemonStartCmd.Flags().BoolVar(&daemonForegroundFlag, "foreground", false, "run in foreground (don't daemonize)")
+	daemonRestartCmd.Flags().DurationVar(&daemonDrainTimeoutFlag, "drain-timeout", 5*time.Minute, "how long the old daemon waits for in-flight requests")
 	daemonCmd.AddCommand(daemonStartCmd)
 	daemonCmd.AddCommand(daemonStopCmd)
 	daemonCmd.AddCommand(daemonRestartCmd)
@@ -107,30 +110,68 @@ func runDaemonStart(cmd *cobra.Command, args []string) error {
 
 // runDaemonForeground runs the zend daemon in the foreground with auto-restart.
 func runDaemonForeground() error {
-	logFile, logger := setupDaemonLogger()
+	logFile, logSink := setupDaemonLogger()
 	if logFile != nil {
 		defer logFile.Close()
 	}
+	logger := logSink.Logger()
+	crashreport.Init(Version, logger)
 
 	// Create structured logger for daemon events
-	structuredLog := daemon.NewStructuredLogger(os.Stderr)
+	structuredLog := daemon.NewStructuredLogger(logSink)
 
-	// Auto-restart wrapper with exponential backoff
+	// Auto-restart wrapper with exponential backoff. Crashes are counted in
+	// a record next to the PID file, so the backoff keeps growing when
+	// launchd or systemd restarts zend after it gives up.
 	const maxRestarts = 5
 	restartCount := 0
-	backoff := 1 * time.Second
+	var lastCrash error
 
 	// Signal handling outside the loop (shared across restarts)
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 	defer signal.Stop(sigCh)
 
+	// A PID file left behind by another process means the previous daemon
+	// did not shut down cleanly, which counts as a crash. A handoff
+	// successor starts while its predecessor still owns the PID file.
+	uncleanExit := ""
+	if pid, err := daemon.ReadDaemonPid(); err == nil && pid != os.Getpid() && !daemon.StartedByHandoff() {
+		uncleanExit = fmt.Sprintf("previous zend (PID %d) did not shut down cleanly", pid)
+		crashes, err := daemon.RecordCrash(uncleanExit)
+		if err != nil {
+			logger.Printf("[daemon] cannot record crash: %v", err)
+		}
+		if crashes.CrashLooping() {
+			backoff := crashes.Backoff()
+			logger.Printf("[daemon] crash loop detected (%s), starting in %v", crashes.Summary(), backoff)
+			structuredLog.Error("daemon_crash_loop", map[string]interface{}{
+				"restarts":        crashes.Restarts,
+				"backoff_seconds": backoff.Seconds(),
+				"error":           crashes.LastError,
+			})
+			select {
+			case <-sigCh:
+				return nil
+			case <-time.After(backoff):
+			}
+		}
+	}
+
 	for {
 		d := daemon.NewDaemon(Version, logger)
+		d.SetLogSink(logSink)
 
 		// Clean up legacy web daemon PID files from v2.0 and earlier
 		daemon.CleanupLegacyPidFiles()
 
+		// Report why this instance starts
+		if lastCrash != nil {
+			d.SetStartReason("crash", fmt.Sprintf("restart %d/%d after: %v", restartCount, maxRestarts, lastCrash))
+		} else if uncleanExit != "" {
+			d.SetStartReason("unclean_exit", uncleanExit)
+		}
+
 		// Write PID file
 		daemon.WriteDaemonPid(os.Getpid())
 
@@ -156,13 +197,18 @@ func runDaemonForeground() error {
 				// Instance cancelled, exit goroutine without shutdown
 				return
 			}
-			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			ctx, cancel := context.WithTimeout(context.Background(), d.DrainTimeout())
 			defer cancel()
 			d.Shutdown(ctx)
 			close(shutdownDone)
 		}()
 
-		err := d.Start()
+		// Once an instance stays up for the crash window, earlier crashes
+		// no longer count and zend stops reporting itself degraded
+		stable := time.AfterFunc(daemon.CrashWindow, daemon.ClearCrashRecord)
+
+		err := d.StartRecovered()
+		stable.Stop()
 
 		// If Start() returned due to shutdown signal, wait for cleanup to complete
 		// Use a short timeout to avoid hanging if shutdown wasn't triggered
@@ -171,7 +217,7 @@ func runDaemonForeground() error {
 			// Shutdown completed, PID file should be removed
 		case <-time.After(100 * time.Millisecond):
 			// Start returned for other reason (error), clean up PID file
-			daemon.RemoveDaemonPid()
+			daemon.RemoveOwnDaemonPid()
 			// Cancel the shutdown goroutine to prevent it from leaking
 			instanceCancel()
 		}
@@ -198,32 +244,38 @@ func runDaemonForeground() error {
 
 			// Recoverable errors trigger restart with exponential backoff
 			restartCount++
+			lastCrash = err
+			crashes, recErr := daemon.RecordCrash(err.Error())
+			if recErr != nil {
+				logger.Printf("[daemon] cannot record crash: %v", recErr)
+			}
 			if restartCount >= maxRestarts {
 				logger.Printf("[daemon] exceeded max restart attempts (%d), giving up: %v", maxRestarts, err)
 				instanceCancel()
 				return fmt.Errorf("daemon crashed after %d restart attempts: %w", maxRestarts, err)
 			}
 
+			backoff := crashes.Backoff()
 			logger.Printf("[daemon] daemon crashed (attempt %d/%d), restarting in %v: %v",
 				restartCount, maxRestarts, backoff, err)
 
 			// Log structured event
 			structuredLog.Error("daemon_crashed_restarting", map[string]interface{}{
-				"restart_count":     restartCount,
-				"max_restarts":      maxRestarts,
-				"backoff_seconds":   backoff.Seconds(),
-				"error":             err.Error(),
+				"restart_count":   restartCount,
+				"max_restarts":    maxRestarts,
+				"crash_count":     crashes.Restarts,
+				"crash_looping":   crashes.CrashLooping(),
+				"backoff_seconds": backoff.Seconds(),
+				"error":           err.Error(),
 			})
 
 			// Cancel the previous instance's goroutine before restarting
 			instanceCancel()
 
-			time.Sleep(backoff)
-
-			// Exponential backoff with 30s cap
-			backoff *= 2
-			if backoff > 30*time.Second {
-				backoff = 30 * time.Second
+			select {
+			case <-sigCh:
+				return err
+			case <-time.After(backoff):
 			}
 			continue
 		}
@@ -365,8 +417,20 @@ func runDaemonStop(cmd *cobra.Command, args []string) error {
 }
 
 func runDaemonRestart(cmd *cobra.Command, args []string) error {
+	return restartDaemon(daemonDrainTimeoutFlag)
+}
+
+// restartDaemon restarts zend without dropping connections when possible:
+// the running daemon hands its sockets to a new process and drains for up
+// to drainTimeout. Falls back to stop and start if that is not supported.
+func restartDaemon(drainTimeout time.Duration) error {
 	if _, running := daemon.IsDaemonRunning(); running {
-		fmt.Println("Stopping zend...")
+		pid, err := daemon.RestartDaemonProcess(drainTimeout)
+		if err == nil {
+			fmt.Printf("zend restarted (PID %d) — in-flight requests finish on the previous instance.\n", pid)
+			return nil
+		}
+		fmt.Printf("Graceful restart unavailable (%v), stopping zend...\n", err)
 		if err := daemon.StopDaemonProcess(30 * time.Second); err != nil {
 			return fmt.Errorf("failed to stop zend: %w", err)
 		}
@@ -381,6 +445,9 @@ func runDaemonStatus(cmd *cobra.Command, args []string) error {
 	pid, running := daemon.IsDaemonRunning()
 	if !running {
 		fmt.Println("zend is not running.")
+		if crashes := daemon.ReadCrashRecord(); crashes != nil {
+			fmt.Printf("  Crashes:  %s\n", crashes.Summary())
+		}
 		return nil
 	}
 
@@ -400,17 +467,24 @@ func runDaemonStatus(cmd *cobra.Command, args []string) error {
 	defer resp.Body.Close()
 
 	var status struct {
+		Status         string `json:"status"`
 		Version        string `json:"version"`
 		Uptime         string `json:"uptime"`
 		ProxyPort      int    `json:"proxy_port"`
 		WebPort        int    `json:"web_port"`
 		ActiveSessions int    `json:"active_sessions"`
-		FeatureGates   *struct {
+		TLS            *struct {
+			Listen      string `json:"listen"`
+			SelfSigned  bool   `json:"self_signed"`
+			Fingerprint string `json:"fingerprint"`
+		} `json:"tls,omitempty"`
+		FeatureGates *struct {
 			Bot         bool `json:"bot"`
 			Compression bool `json:"compression"`
 			Middleware  bool `json:"middleware"`
 			Agent       bool `json:"agent"`
 		} `json:"feature_gates,omitempty"`
+		Crashes *daemon.CrashRecord `json:"crashes,omitempty"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
 		if pid == -1 {
@@ -426,10 +500,27 @@ func runDaemonStatus(cmd *cobra.Command, args []string) error {
 	} else {
 		fmt.Printf("zend is running (PID %d)\n", pid)
 	}
+	if status.Status == "degraded" {
+		fmt.Printf("  Status:   degraded, crash loop (backing off %v before restarts)\n", status.Crashes.Backoff())
+	}
 	fmt.Printf("  Version:  %s\n", status.Version)
+	if config.InstanceID() != "" {
+		fmt.Printf("  Home:     %s\n", config.ConfigDirPath())
+	}
 	fmt.Printf("  Uptime:   %s\n", status.Uptime)
+	if status.Crashes != nil {
+		fmt.Printf("  Crashes:  %s\n", status.Crashes.Summary())
+	}
 	fmt.Printf("  Proxy:    http://127.0.0.1:%d\n", status.ProxyPort)
 	fmt.Printf("  Web UI:   http://127.0.0.1:%d\n", status.WebPort)
+	if status.TLS != nil {
+		kind := "certificate"
+		if status.TLS.SelfSigned {
+			kind = "self-signed certificate"
+		}
+		fmt.Printf("  TLS:      https on %s, %s\n", status.TLS.Listen, kind)
+		fmt.Printf("            SHA-256 %s\n", status.TLS.Fingerprint)
+	}
 	fmt.Printf("  Sessions: %d active\n", status.ActiveSessions)
 
 	// Show feature gates if available
@@ -472,12 +563,18 @@ func queryActiveSessions() int {
 	return result.Count
 }
 
-func setupDaemonLogger() (*os.File, *log.Logger) {
-	logDir := config.ConfigDirPath()
-	os.MkdirAll(logDir, 0755)
-	logFile, err := os.OpenFile(daemon.DaemonLogPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+// setupDaemonLogger opens zend.log, rotated and filtered as the log config
+// says. It falls back to stderr when the file cannot be opened. The last
+// lines written are also kept for crash reports.
+func setupDaemonLogger() (io.Closer, *daemon.LogSink) {
+	logCfg := config.GetLog()
+	level, err := daemon.ParseLogLevel(logCfg.GetLevel())
+	if err != nil {
+		level = daemon.LevelInfo
+	}
+	logFile, err := daemon.OpenRotatingFile(daemon.DaemonLogPath(), logCfg)
 	if err != nil {
-		return nil, log.New(os.Stderr, "[zend] ", log.LstdFlags)
+		return nil, daemon.NewLogSink(io.MultiWriter(os.Stderr, crashreport.LogTail()), logCfg.GetFormat(), level)
 	}
-	return logFile, log.New(logFile, "[zend] ", log.LstdFlags)
+	return logFile, daemon.NewLogSink(io.MultiWriter(logFile, crashreport.LogTail()), logCfg.GetFormat(), level)
 }
@@ -0,0 +1,436 @@
+package cmd
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/daemon"
+	"github.com/dopejs/gozen/internal/proxy"
+	"github.com/spf13/cobra"
+)
+
+var doctorReportFlag bool
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose common problems and suggest fixes",
+	Long: `Check the config, the proxy and web ports, the daemon, provider reachability,
+the SQLite databases, file and socket permissions and the installed clients,
+and print how to fix each problem found.
+
+With --report, print a report to paste into a bug report instead. Tokens,
+credentials in URLs and the home directory are redacted from it.
+
+Exits with an error if a check fails. Warnings alone don't fail.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runDoctor(cmd.OutOrStdout(), doctorReportFlag)
+	},
+}
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorReportFlag, "report", false, "print a redacted report to share")
+}
+
+// Doctor check results.
+const (
+	doctorOK   = "ok"
+	doctorWarn = "warn"
+	doctorFail = "fail"
+)
+
+// doctorCheck is the result of one diagnostic check.
+type doctorCheck struct {
+	Name   string
+	Status string
+	Detail string
+	Fix    string
+}
+
+// clientInstallHints tells how to install each supported client.
+var clientInstallHints = map[string]string{
+	config.ClientClaude:   "npm install -g @anthropic-ai/claude-code",
+	config.ClientCodex:    "npm install -g @openai/codex",
+	config.ClientOpenCode: "npm install -g opencode-ai",
+	config.ClientGemini:   "npm install -g @google/gemini-cli",
+	config.ClientAider:    "python -m pip install aider-install && aider-install",
+}
+
+func runDoctor(out io.Writer, report bool) error {
+	_, running := daemon.IsDaemonRunning()
+
+	var checks []doctorCheck
+	checks = append(checks, doctorConfig())
+	checks = append(checks, doctorDaemon(running))
+	checks = append(checks, doctorPort("proxy port", config.GetProxyPort(), running, "zen config set proxy_port <port>"))
+	checks = append(checks, doctorPort("web port", config.GetWebPort(), running, "set web_port in zen.json"))
+	checks = append(checks, doctorProviders()...)
+	checks = append(checks, doctorDatabases()...)
+	checks = append(checks, doctorPermissions(running)...)
+	checks = append(checks, doctorClients()...)
+
+	if report {
+		writeDoctorReport(out, checks)
+	} else {
+		for _, c := range checks {
+			label := "ok  "
+			switch c.Status {
+			case doctorWarn:
+				label = "WARN"
+			case doctorFail:
+				label = "FAIL"
+			}
+			fmt.Fprintf(out, "  %s  %-22s %s\n", label, c.Name, c.Detail)
+			if c.Fix != "" {
+				fmt.Fprintf(out, "        %-22s fix: %s\n", "", c.Fix)
+			}
+		}
+	}
+
+	failed := 0
+	for _, c := range checks {
+		if c.Status == doctorFail {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+	return nil
+}
+
+// doctorConfig validates zen.json as zen config validate does.
+func doctorConfig() doctorCheck {
+	c := doctorCheck{Name: "config"}
+	path := config.ConfigFilePath()
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		c.Status, c.Detail = doctorWarn, "no config file yet"
+		c.Fix = "add a provider with 'zen config add provider'"
+		return c
+	}
+	report, err := config.CheckConfigFile(path)
+	switch {
+	case err != nil:
+		c.Status, c.Detail = doctorFail, err.Error()
+	case !report.Valid:
+		c.Status, c.Detail = doctorFail, fmt.Sprintf("%d error(s): %s", len(report.Errors), report.Errors[0].Message)
+		c.Fix = "run 'zen config validate' for every problem and its fix"
+	case len(report.Warnings) > 0:
+		c.Status, c.Detail = doctorWarn, fmt.Sprintf("%d warning(s): %s", len(report.Warnings), report.Warnings[0].Message)
+		c.Fix = "run 'zen config validate' for every problem and its fix"
+	default:
+		c.Status, c.Detail = doctorOK, path+" is valid"
+	}
+	return c
+}
+
+// doctorDaemon checks that zend runs and its API answers.
+func doctorDaemon(running bool) doctorCheck {
+	c := doctorCheck{Name: "daemon"}
+	if !running {
+		c.Status, c.Detail = doctorWarn, "zend is not running (zen starts it on demand)"
+		c.Fix = "zen daemon start"
+		return c
+	}
+	var ds statusDaemon
+	if err := fetchDaemonAPI("/api/v1/daemon/status", &ds); err != nil {
+		c.Status, c.Detail = doctorFail, "zend is running but its API is not reachable: "+err.Error()
+		c.Fix = "zen daemon restart; see " + daemon.DaemonLogPath()
+		return c
+	}
+	c.Status = doctorOK
+	c.Detail = fmt.Sprintf("zend v%s running, up %s", strings.TrimPrefix(ds.Version, "v"), ds.Uptime)
+	if v := strings.TrimPrefix(ds.Version, "v"); v != strings.TrimPrefix(Version, "v") {
+		c.Status = doctorWarn
+		c.Detail += fmt.Sprintf(", but zen is v%s", strings.TrimPrefix(Version, "v"))
+		c.Fix = "zen daemon restart"
+	}
+	return c
+}
+
+// doctorPort checks that a port zend listens on is free, or held by zend.
+func doctorPort(name string, port int, running bool, change string) doctorCheck {
+	c := doctorCheck{Name: fmt.Sprintf("%s %d", name, port)}
+	if running {
+		if daemon.IsDaemonPortListening(port) {
+			c.Status, c.Detail = doctorOK, "in use by zend"
+		} else {
+			c.Status, c.Detail = doctorFail, "zend is running but not listening"
+			c.Fix = "zen daemon restart (a port change needs a restart)"
+		}
+		return c
+	}
+	ln, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err == nil {
+		ln.Close()
+		c.Status, c.Detail = doctorOK, "available"
+		return c
+	}
+	c.Status = doctorFail
+	if errors.Is(err, syscall.EADDRINUSE) {
+		c.Detail = "in use by another process"
+		c.Fix = fmt.Sprintf("stop the process using it (lsof -i :%d) or %s", port, change)
+	} else {
+		c.Detail = "cannot listen: " + err.Error()
+		c.Fix = change
+	}
+	return c
+}
+
+// doctorProviders checks that every enabled provider's base URL answers.
+func doctorProviders() []doctorCheck {
+	names := config.ProviderNames()
+	if len(names) == 0 {
+		return []doctorCheck{{
+			Name:   "providers",
+			Status: doctorFail,
+			Detail: "no providers configured",
+			Fix:    "zen config add provider",
+		}}
+	}
+	sort.Strings(names)
+	disabled := config.DefaultStore().GetDisabledProviders()
+	hc := proxy.NewHealthChecker(nil)
+
+	checks := make([]doctorCheck, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		checks[i] = doctorCheck{Name: "provider " + name}
+		if _, ok := disabled[name]; ok {
+			checks[i].Status, checks[i].Detail = doctorWarn, "disabled"
+			checks[i].Fix = "zen enable " + name
+			continue
+		}
+		p := config.GetProvider(name)
+		if p == nil {
+			checks[i].Status, checks[i].Detail = doctorFail, "cannot be loaded"
+			continue
+		}
+		wg.Add(1)
+		go func(c *doctorCheck, p *config.ProviderConfig) {
+			defer wg.Done()
+			host := p.BaseURL
+			if u, err := url.Parse(p.BaseURL); err == nil && u.Host != "" {
+				host = u.Host
+			}
+			r := hc.CheckProvider(strings.TrimPrefix(c.Name, "provider "), p.BaseURL, p.ProxyURL)
+			switch {
+			case !r.Healthy:
+				c.Status, c.Detail = doctorFail, fmt.Sprintf("%s unreachable: %s", host, r.Error)
+				c.Fix = "check base_url and your network or proxy_url settings"
+			case p.AuthToken == "":
+				c.Status, c.Detail = doctorWarn, fmt.Sprintf("%s reachable (%dms), but no auth token", host, r.LatencyMs)
+				c.Fix = "set auth_token with 'zen config edit provider " + strings.TrimPrefix(c.Name, "provider ") + "'"
+			default:
+				c.Status, c.Detail = doctorOK, fmt.Sprintf("%s reachable (%dms)", host, r.LatencyMs)
+			}
+		}(&checks[i], p)
+	}
+	wg.Wait()
+	return checks
+}
+
+// doctorDatabases runs SQLite's quick check on the databases in the config
+// directory.
+func doctorDatabases() []doctorCheck {
+	paths, _ := filepath.Glob(filepath.Join(config.ConfigDirPath(), "*.db"))
+	var checks []doctorCheck
+	for _, path := range paths {
+		c := doctorCheck{Name: "database " + filepath.Base(path)}
+		if err := checkDatabase(path); err != nil {
+			c.Status, c.Detail = doctorFail, err.Error()
+			c.Fix = fmt.Sprintf("stop zend and remove %s; it is rebuilt on start", path)
+		} else {
+			c.Status, c.Detail = doctorOK, "integrity ok"
+		}
+		checks = append(checks, c)
+	}
+	return checks
+}
+
+func checkDatabase(path string) error {
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	db.SetConnMaxLifetime(5 * time.Second)
+
+	rows, err := db.Query("PRAGMA quick_check")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var problems []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return err
+		}
+		if line != "ok" {
+			problems = append(problems, line)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("corrupt: %s", problems[0])
+	}
+	return nil
+}
+
+// doctorPermissions checks that the config directory and zen.json are
+// private to the user and that the bot gateway socket is in order.
+func doctorPermissions(running bool) []doctorCheck {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	var checks []doctorCheck
+
+	dir, file := config.ConfigDirPath(), config.ConfigFilePath()
+	c := doctorCheck{Name: "permissions", Status: doctorOK, Detail: "config is private to you"}
+	if info, err := os.Stat(dir); err == nil && info.Mode().Perm()&0022 != 0 {
+		c.Status, c.Detail = doctorWarn, fmt.Sprintf("%s is writable by others (%s)", dir, info.Mode().Perm())
+		c.Fix = "chmod go-w " + dir
+	} else if info, err := os.Stat(file); err == nil && info.Mode().Perm()&0077 != 0 {
+		c.Status, c.Detail = doctorWarn, fmt.Sprintf("%s, which holds tokens, is readable by others (%s)", file, info.Mode().Perm())
+		c.Fix = "chmod 600 " + file
+	}
+	checks = append(checks, c)
+
+	botCfg := config.GetBot()
+	socket := config.BotSocketPath()
+	if botCfg != nil && botCfg.SocketPath != "" {
+		socket = botCfg.SocketPath
+	}
+	c = doctorCheck{Name: "bot socket"}
+	info, err := os.Lstat(socket)
+	switch {
+	case err != nil && (botCfg == nil || !botCfg.Enabled):
+		return checks
+	case err != nil && running:
+		c.Status, c.Detail = doctorFail, socket+" is missing though the bot is enabled"
+		c.Fix = "zen daemon restart; see " + daemon.DaemonLogPath()
+	case err != nil:
+		c.Status, c.Detail = doctorOK, "created when zend starts"
+	case info.Mode().Type() != fs.ModeSocket:
+		c.Status, c.Detail = doctorFail, socket+" is not a socket"
+		c.Fix = "remove " + socket + " and restart zend"
+	case !running:
+		c.Status, c.Detail = doctorWarn, socket+" is left over from a previous zend"
+		c.Fix = "remove " + socket
+	case info.Mode().Perm()&0002 != 0:
+		c.Status, c.Detail = doctorWarn, fmt.Sprintf("%s is writable by others (%s)", socket, info.Mode().Perm())
+		c.Fix = "set socket_path under bot to a path in a private directory"
+	default:
+		c.Status, c.Detail = doctorOK, socket
+	}
+	return append(checks, c)
+}
+
+// doctorClients checks which supported clients are installed. Only a
+// missing default client fails.
+func doctorClients() []doctorCheck {
+	def := config.GetDefaultClient()
+	var checks []doctorCheck
+	for _, name := range config.AvailableClients {
+		c := doctorCheck{Name: "client " + name}
+		if path, err := exec.LookPath(name); err == nil {
+			c.Status, c.Detail = doctorOK, path
+		} else if name == def {
+			c.Status, c.Detail = doctorFail, "not installed, but it is the default client"
+			c.Fix = clientInstallHints[name] + ", or change it with 'zen config default-client'"
+		} else {
+			c.Status, c.Detail = doctorOK, "not installed"
+		}
+		checks = append(checks, c)
+	}
+	return checks
+}
+
+// writeDoctorReport writes the checks as a report to share, with secrets
+// and the home directory redacted.
+func writeDoctorReport(out io.Writer, checks []doctorCheck) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "zen doctor report\n")
+	fmt.Fprintf(&b, "version:  %s (%s/%s)\n", Version, runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&b, "time:     %s\n", time.Now().UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "client:   %s\n", config.GetDefaultClient())
+	fmt.Fprintf(&b, "profiles: %d, providers: %d\n\n", len(config.ListProfiles()), len(config.ProviderNames()))
+	for _, c := range checks {
+		fmt.Fprintf(&b, "[%s] %s: %s\n", c.Status, c.Name, c.Detail)
+		if c.Fix != "" {
+			fmt.Fprintf(&b, "       fix: %s\n", c.Fix)
+		}
+	}
+	fmt.Fprint(out, redactDoctorReport(b.String(), doctorSecrets()))
+}
+
+// doctorSecrets returns the tokens in the config, to redact from reports.
+func doctorSecrets() []string {
+	var secrets []string
+	for _, name := range config.ProviderNames() {
+		if p := config.GetProvider(name); p != nil && p.AuthToken != "" {
+			secrets = append(secrets, p.AuthToken, config.SecretValue(p.AuthToken))
+		}
+	}
+	if b := config.GetBot(); b != nil && b.Platforms != nil {
+		pl := b.Platforms
+		if pl.Telegram != nil {
+			secrets = append(secrets, pl.Telegram.Token)
+		}
+		if pl.Discord != nil {
+			secrets = append(secrets, pl.Discord.Token)
+		}
+		if pl.Slack != nil {
+			secrets = append(secrets, pl.Slack.BotToken, pl.Slack.AppToken)
+		}
+		if pl.Lark != nil {
+			secrets = append(secrets, pl.Lark.AppSecret)
+		}
+		if pl.FBMessenger != nil {
+			secrets = append(secrets, pl.FBMessenger.PageToken, pl.FBMessenger.VerifyToken, pl.FBMessenger.AppSecret)
+		}
+	}
+	return secrets
+}
+
+var (
+	// reportKeyPattern matches API keys by their common prefixes.
+	reportKeyPattern = regexp.MustCompile(`\b(sk|pk|xox[abpr]|ghp|gho|AIza)[-_A-Za-z0-9]{12,}`)
+	// reportURLCredPattern matches the user info of a URL.
+	reportURLCredPattern = regexp.MustCompile(`://[^/\s:@]+(:[^/\s@]*)?@`)
+)
+
+// redactDoctorReport removes secrets, API keys, URL credentials and the
+// home directory from a report.
+func redactDoctorReport(s string, secrets []string) string {
+	for _, secret := range secrets {
+		if len(secret) >= 4 {
+			s = strings.ReplaceAll(s, secret, "[redacted]")
+		}
+	}
+	s = reportKeyPattern.ReplaceAllString(s, "[redacted]")
+	s = reportURLCredPattern.ReplaceAllString(s, "://[redacted]@")
+	if home, err := os.UserHomeDir(); err == nil && len(home) > 1 {
+		s = strings.ReplaceAll(s, home, "~")
+	}
+	return s
+}
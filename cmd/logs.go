@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// logsPollInterval is how often zen logs --follow asks zend for new requests.
+var logsPollInterval = time.Second
+
+// logsFollowLimit is the number of requests each --follow poll fetches; more
+// new requests than that in one interval are not all shown.
+const logsFollowLimit = 200
+
+// logsOptions are the filters of zen logs.
+type logsOptions struct {
+	provider   string
+	errorsOnly bool
+	project    string
+	lines      int
+	follow     bool
+}
+
+var logsOpts logsOptions
+
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Show recent proxied requests, and follow new ones",
+	Long: `Show the requests zend proxied most recently, oldest first, one per line:
+time, status, latency, provider, model, tokens and cost.
+
+--project takes a directory, such as ., and matches requests from sessions
+started in it or below it. With --follow, keep printing requests as they
+come in until interrupted. The daemon must be running.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		return runLogs(ctx, cmd.OutOrStdout(), logsOpts)
+	},
+}
+
+func init() {
+	logsCmd.Flags().StringVar(&logsOpts.provider, "provider", "", "only requests served by this provider")
+	logsCmd.Flags().BoolVar(&logsOpts.errorsOnly, "errors-only", false, "only failed requests")
+	logsCmd.Flags().StringVar(&logsOpts.project, "project", "", "only requests from sessions in this directory")
+	logsCmd.Flags().IntVarP(&logsOpts.lines, "lines", "n", 20, "number of recent requests to show")
+	logsCmd.Flags().BoolVarP(&logsOpts.follow, "follow", "f", false, "keep printing new requests")
+	logsCmd.RegisterFlagCompletionFunc("provider", completeConfigNames)
+}
+
+// logsRequest mirrors the fields of /api/v1/monitoring/requests used by zen logs.
+type logsRequest struct {
+	ID           string    `json:"id"`
+	Timestamp    time.Time `json:"timestamp"`
+	Provider     string    `json:"provider"`
+	Model        string    `json:"model"`
+	StatusCode   int       `json:"status_code"`
+	DurationMs   int64     `json:"duration_ms"`
+	InputTokens  int       `json:"input_tokens"`
+	OutputTokens int       `json:"output_tokens"`
+	Cost         float64   `json:"cost_usd"`
+	ErrorMessage string    `json:"error_message"`
+}
+
+// fetchLogs gets the latest limit requests matching opts, newest first.
+func fetchLogs(opts logsOptions, limit int) ([]logsRequest, error) {
+	q := url.Values{"limit": {strconv.Itoa(limit)}}
+	if opts.provider != "" {
+		q.Set("provider", opts.provider)
+	}
+	if opts.errorsOnly {
+		q.Set("errors_only", "true")
+	}
+	if opts.project != "" {
+		q.Set("project", opts.project)
+	}
+	var resp struct {
+		Requests []logsRequest `json:"requests"`
+	}
+	if err := fetchDaemonAPI("/api/v1/monitoring/requests?"+q.Encode(), &resp); err != nil {
+		return nil, err
+	}
+	return resp.Requests, nil
+}
+
+func runLogs(ctx context.Context, out io.Writer, opts logsOptions) error {
+	if opts.lines <= 0 || opts.lines > 1000 {
+		return fmt.Errorf("invalid --lines %d (must be 1 to 1000)", opts.lines)
+	}
+	if opts.project != "" {
+		abs, err := filepath.Abs(opts.project)
+		if err != nil {
+			return err
+		}
+		opts.project = abs
+	}
+
+	records, err := fetchLogs(opts, opts.lines)
+	if err != nil {
+		return fmt.Errorf("cannot get requests from zend (start it with 'zen daemon start'): %w", err)
+	}
+	for i := len(records) - 1; i >= 0; i-- {
+		fmt.Fprintln(out, formatLogLine(records[i]))
+	}
+	if !opts.follow {
+		return nil
+	}
+
+	// Requests are listed in the order zend recorded them, so the new ones
+	// are those missing from the previous poll
+	seen := logIDs(records)
+	ticker := time.NewTicker(logsPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		records, err := fetchLogs(opts, logsFollowLimit)
+		if err != nil {
+			continue // zend may be restarting
+		}
+		for i := len(records) - 1; i >= 0; i-- {
+			if !seen[records[i].ID] {
+				fmt.Fprintln(out, formatLogLine(records[i]))
+			}
+		}
+		if len(records) > 0 {
+			seen = logIDs(records)
+		}
+	}
+}
+
+func logIDs(records []logsRequest) map[string]bool {
+	ids := make(map[string]bool, len(records))
+	for _, r := range records {
+		ids[r.ID] = true
+	}
+	return ids
+}
+
+// formatLogLine renders a request as one line of zen logs.
+func formatLogLine(r logsRequest) string {
+	status := strconv.Itoa(r.StatusCode)
+	if r.StatusCode == 0 {
+		status = "ERR"
+	}
+	provider := r.Provider
+	if provider == "" {
+		provider = "-"
+	}
+	model := r.Model
+	if model == "" {
+		model = "-"
+	}
+	line := fmt.Sprintf("%s  %3s %7s  %-16s %-28s %6s/%-6s $%.4f",
+		r.Timestamp.Local().Format("15:04:05"), status, formatLatency(r.DurationMs), truncateRight(provider, 16),
+		truncateRight(model, 28), formatTokens(r.InputTokens), formatTokens(r.OutputTokens), r.Cost)
+	if r.ErrorMessage != "" {
+		line += "  " + truncateRight(r.ErrorMessage, 80)
+	}
+	return line
+}
+
+// formatLatency renders a duration in milliseconds, such as 840ms or 1.2s.
+func formatLatency(ms int64) string {
+	if ms < 1000 {
+		return fmt.Sprintf("%dms", ms)
+	}
+	return fmt.Sprintf("%.1fs", float64(ms)/1000)
+}
+
+// truncateRight shortens s to n runes, keeping its start.
+func truncateRight(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
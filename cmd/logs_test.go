@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+func TestRunLogs(t *testing.T) {
+	setTestHome(t)
+	now := time.Now()
+	var mu sync.Mutex
+	var queries []string
+	records := []logsRequest{
+		{ID: "r2", Timestamp: now, Provider: "backup", Model: "claude-sonnet-4", StatusCode: 529, DurationMs: 840, ErrorMessage: "overloaded"},
+		{ID: "r1", Timestamp: now.Add(-time.Second), Provider: "anthropic", Model: "claude-sonnet-4", StatusCode: 200, DurationMs: 2400, InputTokens: 12000, OutputTokens: 850, Cost: 0.0488},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/monitoring/requests" {
+			http.NotFound(w, r)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		queries = append(queries, r.URL.RawQuery)
+		json.NewEncoder(w).Encode(map[string]interface{}{"requests": records})
+	}))
+	defer srv.Close()
+	setWebPortTo(t, srv)
+
+	var buf bytes.Buffer
+	err := runLogs(context.Background(), &buf, logsOptions{provider: "anthropic", errorsOnly: true, project: "/work/app", lines: 5})
+	if err != nil {
+		t.Fatalf("runLogs() error = %v", err)
+	}
+	for _, want := range []string{"provider=anthropic", "errors_only=true", "project=%2Fwork%2Fapp", "limit=5"} {
+		if !strings.Contains(queries[0], want) {
+			t.Errorf("query %q missing %q", queries[0], want)
+		}
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got:\n%s", buf.String())
+	}
+	// Oldest first
+	for _, want := range []string{"200", "2.4s", "anthropic", "claude-sonnet-4", "12k/850", "$0.0488"} {
+		if !strings.Contains(lines[0], want) {
+			t.Errorf("line %q missing %q", lines[0], want)
+		}
+	}
+	for _, want := range []string{"529", "840ms", "backup", "overloaded"} {
+		if !strings.Contains(lines[1], want) {
+			t.Errorf("line %q missing %q", lines[1], want)
+		}
+	}
+
+	if err := runLogs(context.Background(), &buf, logsOptions{lines: 0}); err == nil {
+		t.Error("runLogs() should reject --lines 0")
+	}
+}
+
+func TestRunLogsFollow(t *testing.T) {
+	setTestHome(t)
+	old := logsPollInterval
+	logsPollInterval = 10 * time.Millisecond
+	defer func() { logsPollInterval = old }()
+
+	now := time.Now()
+	var mu sync.Mutex
+	records := []logsRequest{{ID: "r1", Timestamp: now, Provider: "anthropic", StatusCode: 200}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{"requests": records})
+	}))
+	defer srv.Close()
+	setWebPortTo(t, srv)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var out syncBuffer
+	done := make(chan error, 1)
+	go func() { done <- runLogs(ctx, &out, logsOptions{lines: 20, follow: true}) }()
+
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	records = append([]logsRequest{{ID: "r2", Timestamp: now.Add(time.Second), Provider: "backup", StatusCode: 500}}, records...)
+	mu.Unlock()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !strings.Contains(out.String(), "backup") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("runLogs() error = %v", err)
+	}
+
+	got := out.String()
+	if strings.Count(got, "anthropic") != 1 || strings.Count(got, "backup") != 1 {
+		t.Errorf("each request should print once:\n%s", got)
+	}
+}
+
+// setWebPortTo points the daemon API at a test server.
+func setWebPortTo(t *testing.T, srv *httptest.Server) {
+	t.Helper()
+	config.SetWebPort(srv.Listener.Addr().(*net.TCPAddr).Port)
+}
+
+// syncBuffer is a bytes.Buffer safe to write and read concurrently.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
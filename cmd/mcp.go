@@ -0,0 +1,371 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/mcp"
+	"github.com/dopejs/gozen/internal/proxy"
+	"github.com/spf13/cobra"
+)
+
+// mcpEndpointName is the name Claude Code gets zend's MCP endpoint under,
+// so the tools of a project's server github read mcp__zen__github__<tool>.
+const mcpEndpointName = "zen"
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Manage the MCP servers zen runs for the current project",
+	Long: `zen runs the MCP servers declared in a project's binding and serves their
+tools from a single MCP endpoint on zend, with each tool named
+<server>__<tool>. Every tool call is checked against the guardrail policy
+first, as mcp__<server>__<tool>. Servers start on first use, restart when
+they exit, and stop when the project has gone unused for 30 minutes.
+
+Claude Code launched by zen gets the endpoint automatically. Other clients
+can run 'zen mcp serve' as a stdio MCP server.
+
+Without a subcommand, list the servers.
+
+Examples:
+  zen mcp add github --env GITHUB_TOKEN=env://GH_TOKEN -- npx -y @modelcontextprotocol/server-github
+  zen mcp add fs -- npx -y @modelcontextprotocol/server-filesystem .
+  zen mcp remove fs
+  zen mcp list`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runMCPList(cmd.OutOrStdout())
+	},
+}
+
+var mcpListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the project's MCP servers and their state",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runMCPList(cmd.OutOrStdout())
+	},
+}
+
+var mcpAddCmd = &cobra.Command{
+	Use:   "add <name> -- <command> [args...]",
+	Short: "Add or replace an MCP server of the project's binding",
+	Long: `Add a stdio MCP server to the binding of the current directory, or replace
+the one of that name. zen runs it in the project directory. Env values may
+be secret references such as env://NAME.`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		env, _ := cmd.Flags().GetStringArray("env")
+		return runMCPAdd(cmd.OutOrStdout(), args[0], args[1], args[2:], env)
+	},
+}
+
+var mcpRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove an MCP server from the project's binding",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runMCPRemove(cmd.OutOrStdout(), args[0])
+	},
+}
+
+var mcpServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the project's MCP servers over stdio",
+	Long: `Bridge stdio to zend's MCP endpoint for the current directory, for clients
+that only run stdio MCP servers: configure one with the command 'zen' and the
+arguments 'mcp serve'.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := ensureDaemonRunning(); err != nil {
+			return fmt.Errorf("failed to start zend: %w", err)
+		}
+		dir, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		token, err := mcp.LoadToken()
+		if err != nil {
+			return fmt.Errorf("failed to load the MCP token: %w", err)
+		}
+		session, _ := cmd.Flags().GetString("session")
+		return runMCPServe(cmd.InOrStdin(), cmd.OutOrStdout(), mcpEndpointURL(filepath.Clean(dir), session), token)
+	},
+}
+
+func init() {
+	mcpAddCmd.Flags().StringArrayP("env", "e", nil, "environment variable for the server, KEY=VALUE (repeatable)")
+	mcpServeCmd.Flags().String("session", "", "session the tool calls belong to, for guardrail records")
+	mcpCmd.AddCommand(mcpListCmd)
+	mcpCmd.AddCommand(mcpAddCmd)
+	mcpCmd.AddCommand(mcpRemoveCmd)
+	mcpCmd.AddCommand(mcpServeCmd)
+}
+
+// currentBinding returns the binding of the current directory and its key.
+func currentBinding() (string, string, *config.ProjectBinding, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", "", nil, fmt.Errorf("failed to get current directory: %w", err)
+	}
+	cwd = filepath.Clean(cwd)
+	key, binding := config.FindProjectBinding(cwd)
+	return cwd, key, binding, nil
+}
+
+func runMCPAdd(out io.Writer, name, command string, args, env []string) error {
+	_, key, binding, err := currentBinding()
+	if err != nil {
+		return err
+	}
+	if binding == nil {
+		return fmt.Errorf("this directory is not bound; run 'zen bind' first")
+	}
+	server := &config.MCPServerConfig{Command: command, Args: args}
+	for _, kv := range env {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || k == "" {
+			return fmt.Errorf("invalid --env %q (want KEY=VALUE)", kv)
+		}
+		if server.Env == nil {
+			server.Env = make(map[string]string)
+		}
+		server.Env[k] = v
+	}
+	servers := config.CloneMCPServers(binding.MCPServers)
+	if servers == nil {
+		servers = make(map[string]*config.MCPServerConfig)
+	}
+	servers[name] = server
+	if err := config.SetProjectMCPServers(key, servers); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Added MCP server %s to %s\n", name, key)
+	return nil
+}
+
+func runMCPRemove(out io.Writer, name string) error {
+	_, key, binding, err := currentBinding()
+	if err != nil {
+		return err
+	}
+	if binding == nil || binding.MCPServers[name] == nil {
+		return fmt.Errorf("MCP server '%s' not found", name)
+	}
+	servers := config.CloneMCPServers(binding.MCPServers)
+	delete(servers, name)
+	if err := config.SetProjectMCPServers(key, servers); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Removed MCP server %s from %s\n", name, key)
+	return nil
+}
+
+// mcpStatusResponse is the shape of zend's MCP status API.
+type mcpStatusResponse struct {
+	Projects []struct {
+		Project string `json:"project"`
+		Servers []struct {
+			Name      string `json:"name"`
+			State     string `json:"state"`
+			PID       int    `json:"pid"`
+			Tools     int    `json:"tools"`
+			Restarts  int    `json:"restarts"`
+			LastError string `json:"last_error"`
+		} `json:"servers"`
+	} `json:"projects"`
+}
+
+func runMCPList(out io.Writer) error {
+	cwd, key, binding, err := currentBinding()
+	if err != nil {
+		return err
+	}
+	if binding == nil || len(binding.MCPServers) == 0 {
+		fmt.Fprintln(out, "No MCP servers are configured for this directory.")
+		return nil
+	}
+
+	// States of the servers zend runs for this directory, if any
+	states := map[string]string{}
+	var status mcpStatusResponse
+	if fetchDaemonAPI("/api/v1/daemon/mcp", &status) == nil {
+		for _, p := range status.Projects {
+			if p.Project != cwd {
+				continue
+			}
+			for _, s := range p.Servers {
+				state := fmt.Sprintf("%s, %d tools", s.State, s.Tools)
+				if s.Restarts > 0 {
+					state += fmt.Sprintf(", %d restarts", s.Restarts)
+				}
+				if s.LastError != "" && s.State != "running" {
+					state += ": " + s.LastError
+				}
+				states[s.Name] = state
+			}
+		}
+	}
+
+	fmt.Fprintf(out, "MCP servers of %s:\n", key)
+	names := make([]string, 0, len(binding.MCPServers))
+	for name := range binding.MCPServers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s := binding.MCPServers[name]
+		if s == nil {
+			continue
+		}
+		state := states[name]
+		switch {
+		case s.Disabled:
+			state = "disabled"
+		case state == "":
+			state = "not started"
+		}
+		fmt.Fprintf(out, "  %-16s %-40s %s\n", name, strings.Join(append([]string{s.Command}, s.Args...), " "), state)
+	}
+	fmt.Fprintf(out, "Endpoint: %s\n", mcpEndpointURL(cwd, ""))
+	return nil
+}
+
+// mcpEndpointURL returns the URL of zend's MCP endpoint for a project
+// directory.
+func mcpEndpointURL(dir, session string) string {
+	q := url.Values{"project": {dir}}
+	if session != "" {
+		q.Set("session", session)
+	}
+	return fmt.Sprintf("http://127.0.0.1:%d/mcp?%s", config.GetProxyPort(), q.Encode())
+}
+
+// prependMCPConfigArgs points Claude Code at zend's MCP endpoint, next to
+// the user's own MCP servers, when the directory's binding declares MCP
+// servers. Other clients are left alone.
+func prependMCPConfigArgs(clientBin, profile, sessionID string, args []string) []string {
+	if GetClientType(clientBin) != ClientClaude {
+		return args
+	}
+	cwd, _, binding, err := currentBinding()
+	if err != nil || binding == nil {
+		return args
+	}
+	enabled := false
+	for _, s := range binding.MCPServers {
+		enabled = enabled || (s != nil && !s.Disabled)
+	}
+	if !enabled {
+		return args
+	}
+	token, err := mcp.LoadToken()
+	if err != nil {
+		return args
+	}
+	session := (&proxy.RouteInfo{Profile: profile, SessionID: sessionID}).CacheKey()
+	data, _ := json.Marshal(map[string]interface{}{
+		"mcpServers": map[string]interface{}{
+			mcpEndpointName: map[string]interface{}{
+				"type":    "http",
+				"url":     mcpEndpointURL(cwd, session),
+				"headers": map[string]string{"Authorization": "Bearer " + token},
+			},
+		},
+	})
+	// The = form keeps the variadic flag from taking the user's arguments
+	return append([]string{"--mcp-config=" + string(data)}, args...)
+}
+
+// runMCPServe relays JSON-RPC messages, one per line, between stdio and the
+// MCP endpoint, authenticating with token. Requests run concurrently, so a
+// tool call waiting for approval doesn't hold up the rest.
+func runMCPServe(in io.Reader, out io.Writer, endpoint, token string) error {
+	client := &http.Client{} // no timeout: a tool call may wait for approval
+	var outMu sync.Mutex
+	write := func(data []byte) {
+		outMu.Lock()
+		defer outMu.Unlock()
+		out.Write(append(bytes.TrimSpace(data), '\n'))
+	}
+
+	var wg sync.WaitGroup
+	reader := bufio.NewReaderSize(in, 64*1024)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			wg.Add(1)
+			go func(msg []byte) {
+				defer wg.Done()
+				if reply := relayMCPMessage(client, endpoint, token, msg); len(reply) > 0 {
+					write(reply)
+				}
+			}(line)
+		}
+		if err != nil {
+			wg.Wait()
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// relayMCPMessage posts a message to the endpoint and returns the reply,
+// if one is due. A request the endpoint can't answer gets an error reply.
+func relayMCPMessage(client *http.Client, endpoint, token string, msg []byte) []byte {
+	var req struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+	}
+	json.Unmarshal(msg, &req)
+	isRequest := req.Method != "" && len(req.ID) > 0
+
+	fail := func(err error) []byte {
+		if !isRequest {
+			return nil
+		}
+		data, _ := json.Marshal(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      req.ID,
+			"error":   map[string]interface{}{"code": -32603, "message": "zend MCP endpoint: " + err.Error()},
+		})
+		return data
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(msg))
+	if err != nil {
+		return fail(err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json, text/event-stream")
+	httpReq.Header.Set("Authorization", "Bearer "+token)
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return fail(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusAccepted {
+		return nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fail(err)
+	}
+	if !json.Valid(body) {
+		return fail(fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body))))
+	}
+	return body
+}
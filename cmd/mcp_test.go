@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/mcp"
+)
+
+func TestMCPAddAndClaudeArgs(t *testing.T) {
+	home := setTestHome(t)
+	project := filepath.Join(home, "project")
+	os.MkdirAll(project, 0755)
+	t.Chdir(project)
+
+	var out bytes.Buffer
+	if err := runMCPAdd(&out, "github", "npx", []string{"-y", "server-github"}, []string{"GITHUB_TOKEN=env://GH_TOKEN"}); err == nil {
+		t.Fatal("adding a server to an unbound directory should error")
+	}
+	if err := config.BindProject(project, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	args := []string{"-p", "hello"}
+	if got := prependMCPConfigArgs("claude", "default", "s1", args); len(got) != len(args) {
+		t.Errorf("args without MCP servers = %v", got)
+	}
+
+	if err := runMCPAdd(&out, "github", "npx", []string{"-y", "server-github"}, []string{"GITHUB_TOKEN=env://GH_TOKEN"}); err != nil {
+		t.Fatalf("runMCPAdd() error: %v", err)
+	}
+	server := config.GetProjectBinding(project).MCPServers["github"]
+	if server == nil || server.Command != "npx" || len(server.Args) != 2 || server.Env["GITHUB_TOKEN"] != "env://GH_TOKEN" {
+		t.Fatalf("added server = %+v", server)
+	}
+
+	got := prependMCPConfigArgs("claude", "default", "s1", args)
+	if len(got) != 3 || !strings.HasPrefix(got[0], "--mcp-config=") || got[1] != "-p" {
+		t.Fatalf("claude args = %v", got)
+	}
+	var cfg struct {
+		MCPServers map[string]struct {
+			Type    string            `json:"type"`
+			URL     string            `json:"url"`
+			Headers map[string]string `json:"headers"`
+		} `json:"mcpServers"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(got[0], "--mcp-config=")), &cfg); err != nil {
+		t.Fatalf("invalid --mcp-config: %v", err)
+	}
+	zen := cfg.MCPServers[mcpEndpointName]
+	token, err := mcp.LoadToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if zen.Type != "http" || !strings.Contains(zen.URL, "/mcp?") || !strings.Contains(zen.URL, "session=default%3As1") || zen.Headers["Authorization"] != "Bearer "+token {
+		t.Errorf("endpoint config = %+v", zen)
+	}
+	if got := prependMCPConfigArgs("codex", "default", "s1", args); len(got) != len(args) {
+		t.Errorf("codex args = %v", got)
+	}
+
+	if err := runMCPRemove(&out, "github"); err != nil {
+		t.Fatalf("runMCPRemove() error: %v", err)
+	}
+	if config.GetProjectBinding(project).MCPServers != nil {
+		t.Error("expected the server to be removed")
+	}
+}
+
+func TestMCPServeRelays(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case strings.Contains(string(body), `"notifications/`):
+			w.WriteHeader(http.StatusAccepted)
+		case strings.Contains(string(body), `"tools/list"`):
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"tools":[]}}`))
+		default:
+			http.Error(w, "no servers", http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	in := strings.NewReader(`{"jsonrpc":"2.0","method":"notifications/initialized"}` + "\n" +
+		`{"jsonrpc":"2.0","id":1,"method":"tools/list"}` + "\n" +
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call"}` + "\n")
+	var out bytes.Buffer
+	if err := runMCPServe(in, &out, srv.URL+"/mcp", "secret"); err != nil {
+		t.Fatalf("runMCPServe() error: %v", err)
+	}
+
+	replies := map[string]map[string]interface{}{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var reply map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &reply); err != nil {
+			t.Fatalf("invalid reply line %q: %v", line, err)
+		}
+		id, _ := json.Marshal(reply["id"])
+		replies[string(id)] = reply
+	}
+	if len(replies) != 2 {
+		t.Fatalf("expected replies to the two requests only, got:\n%s", out.String())
+	}
+	if replies["1"]["result"] == nil {
+		t.Errorf("tools/list reply = %v", replies["1"])
+	}
+	if replies["2"]["error"] == nil {
+		t.Errorf("a request the endpoint rejects should get an error reply, got %v", replies["2"])
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/dopejs/gozen/internal/config"
@@ -24,9 +25,10 @@ var pickCmd = &cobra.Command{
 var pickClientFlag string
 
 func init() {
-	pickCmd.Flags().StringVarP(&pickClientFlag, "client", "c", "", "client to use (claude, codex, opencode)")
+	pickCmd.Flags().StringVarP(&pickClientFlag, "client", "c", "", "client to use (claude, codex, opencode, gemini, aider)")
 	pickCmd.Flags().String("cli", "", "alias for --client (deprecated)")
 	pickCmd.Flags().Lookup("cli").Hidden = true
+	pickCmd.RegisterFlagCompletionFunc("client", completeClientNames)
 }
 
 func runPick(cmd *cobra.Command, args []string) error {
@@ -98,3 +100,27 @@ func registerTempProfile(providers []string) (string, error) {
 	}
 	return result.ID, nil
 }
+
+// stdinIsTerminal reports whether stdin is an interactive terminal, so a
+// picker can stand in for an omitted argument. Tests can replace it.
+var stdinIsTerminal = func() bool {
+	fi, err := os.Stdin.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// pickName asks for one of names with the fuzzy picker, for a command run
+// without its name argument. ok is false when stdin is not a terminal or
+// the user cancels.
+func pickName(title string, names []string, current string) (name string, ok bool, err error) {
+	if !stdinIsTerminal() || len(names) == 0 {
+		return "", false, nil
+	}
+	name, err = tui.RunFuzzyPicker(title, names, current)
+	if err != nil {
+		if err.Error() == "cancelled" {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return name, true, nil
+}
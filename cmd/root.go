@@ -44,14 +44,21 @@ var rootCmd = &cobra.Command{
 	DisableFlagParsing: false,
 	SilenceUsage:       true,
 	SilenceErrors:      true,
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := applyHomeFlag(homeFlag); err != nil {
+			return err
+		}
+		if err := applySetFlags(setFlags); err != nil {
+			return err
+		}
 		// Skip update check for commands where it's not useful
 		name := cmd.Name()
 		if name == "upgrade" || name == "version" || name == "completion" {
-			return
+			return nil
 		}
 		updateChecker = update.NewChecker(Version)
 		updateChecker.Start()
+		return nil
 	},
 	PersistentPostRun: func(cmd *cobra.Command, args []string) {
 		if updateChecker != nil {
@@ -65,16 +72,22 @@ var rootCmd = &cobra.Command{
 
 var clientFlag string
 var yesFlag bool
+var setFlags []string
+var homeFlag string
 
 func init() {
 	// -p/--profile is the new flag, -f/--fallback is kept for backward compatibility but hidden
 	rootCmd.Flags().StringP("profile", "p", "", "profile name")
 	rootCmd.Flags().StringP("fallback", "f", "", "alias for --profile (deprecated)")
 	rootCmd.Flags().Lookup("fallback").Hidden = true
-	rootCmd.Flags().StringVarP(&clientFlag, "client", "c", "", "client to use (claude, codex, opencode)")
-	rootCmd.Flags().BoolVarP(&yesFlag, "yes", "y", false, "auto-approve CLI permissions (claude --permission-mode bypassPermissions, codex -a never)")
+	rootCmd.Flags().StringVarP(&clientFlag, "client", "c", "", "client to use (claude, codex, opencode, gemini, aider)")
+	rootCmd.Flags().BoolVarP(&yesFlag, "yes", "y", false, "auto-approve CLI permissions (claude --permission-mode bypassPermissions, codex -a never, gemini --yolo, aider --yes-always)")
 	rootCmd.Flags().String("cli", "", "alias for --client (deprecated)")
 	rootCmd.Flags().Lookup("cli").Hidden = true
+	rootCmd.RegisterFlagCompletionFunc("profile", completeProfileNames)
+	rootCmd.RegisterFlagCompletionFunc("client", completeClientNames)
+	rootCmd.PersistentFlags().StringArrayVar(&setFlags, "set", nil, "override a config key for this run (key=value, e.g. proxy_port=29841)")
+	rootCmd.PersistentFlags().StringVar(&homeFlag, "home", "", "run a separate instance with its config and daemon in this directory instead of ~/.zen (or set ZEN_HOME)")
 	rootCmd.AddCommand(useCmd)
 	rootCmd.AddCommand(configCmd)
 	rootCmd.AddCommand(listCmd)
@@ -84,9 +97,18 @@ func init() {
 	rootCmd.AddCommand(pickCmd)
 	rootCmd.AddCommand(webCmd)
 	rootCmd.AddCommand(daemonCmd)
+	rootCmd.AddCommand(serveCmd)
 	rootCmd.AddCommand(bindCmd)
 	rootCmd.AddCommand(unbindCmd)
+	rootCmd.AddCommand(runCmd)
 	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(doctorCmd)
+	rootCmd.AddCommand(topCmd)
+	rootCmd.AddCommand(usageCmd)
+	rootCmd.AddCommand(costCmd)
+	rootCmd.AddCommand(logsCmd)
+	rootCmd.AddCommand(crashCmd)
+	rootCmd.AddCommand(mcpCmd)
 	rootCmd.AddCommand(experienceCmd)
 	rootCmd.AddCommand(disableCmd)
 	rootCmd.AddCommand(enableCmd)
@@ -119,19 +141,20 @@ Quick Start:
 Configuration:
   config add provider [name]   Add a new provider
   config add profile [name]    Add a new profile
-  config edit provider <name>  Edit an existing provider
-  config edit profile <name>   Edit an existing profile
+  config edit provider [name]  Edit an existing provider
+  config edit profile [name]   Edit an existing profile
   config delete provider <name> Delete a provider
   config delete profile <name>  Delete a profile
   config default-client        Set the default client
   config default-profile       Set the default profile
   config reset-password        Reset Web UI access password
+  config import --format <fmt> Import from claude-code-router or litellm
 
 Project Binding:
-  bind <profile>               Bind current directory to a profile
+  bind [profile]               Bind current directory to a profile
   bind --cli <cli>             Bind current directory to a CLI
   unbind                       Remove binding for current directory
-  status                       Show binding status
+  status                       Show daemon, binding, processes, health, spend and tasks
 
 Web Interface:
   web                          Open web UI in browser (starts daemon if needed)
@@ -143,7 +166,13 @@ Provider Availability:
 Other Commands:
   list                         List all providers and profiles
   pick                         Interactively select providers
-  use <provider>               Use a specific provider directly
+  use [provider]               Use a specific provider directly
+  run -- <command>             Run any command with the proxy environment
+  top                          Live dashboard of requests, providers and sessions
+  usage                        Token usage and cost by model, provider or project
+  cost                         Spend today, this week and this month against budget
+  logs                         Recent requests; -f to follow
+  doctor                       Diagnose problems and suggest fixes
   upgrade                      Upgrade to latest version
   version                      Show version
   completion                   Generate shell completion
@@ -162,6 +191,52 @@ func Execute() error {
 	return rootCmd.Execute()
 }
 
+// applyHomeFlag points this run, and any daemon it starts, at the zen home
+// in dir by setting ZEN_HOME.
+func applyHomeFlag(dir string) error {
+	if dir == "" {
+		return nil
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("invalid --home %q: %w", dir, err)
+	}
+	if err := os.Setenv(config.HomeEnv, abs); err != nil {
+		return err
+	}
+	config.ResetDefaultStore()
+	return nil
+}
+
+// applySetFlags turns each --set key=value into the ZEN_ environment
+// override for key, so it applies to this run and to any daemon it starts.
+func applySetFlags(sets []string) error {
+	if len(sets) == 0 {
+		return nil
+	}
+	names := make(map[string]string) // env name -> key
+	for _, set := range sets {
+		key, value, ok := strings.Cut(set, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return fmt.Errorf("invalid --set %q: expected key=value", set)
+		}
+		name := config.EnvOverridePrefix + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+		if err := os.Setenv(name, value); err != nil {
+			return err
+		}
+		names[name] = key
+	}
+	config.ResetDefaultStore()
+	for _, o := range config.GetConfigOverrides() {
+		delete(names, o.Env)
+	}
+	for _, key := range names {
+		return fmt.Errorf("invalid --set %q: no such config key, or its value does not fit", key)
+	}
+	return nil
+}
+
 func runProxy(cmd *cobra.Command, args []string) error {
 	// Support both -p/--profile (new) and -f/--fallback (deprecated)
 	profileFlag, _ := cmd.Flags().GetString("profile")
@@ -306,11 +381,30 @@ func startViaDaemon(profile, client string, providerNames []string, args []strin
 		}
 	}
 
-	// Run client with retry on connection error (daemon recovery)
-	exitCode, stderrOutput, runErr := runClient(cliPath, clientArgs)
+	// Serve the binding's MCP servers to Claude Code through zend
+	clientArgs = prependMCPConfigArgs(clientBin, profile, sessionID, clientArgs)
+
+	// Manage Claude Code's settings if the binding asks for it
+	restoreSettings := applyClaudeSettings(clientBin, baseURL, mergedEnvVars)
+
+	exitCode, runErr := runClientWithRecovery(cliPath, clientArgs)
+	restoreSettings()
 	if runErr != nil {
 		return runErr
 	}
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
+	return nil
+}
+
+// runClientWithRecovery runs the client, and runs it once more if it failed
+// to connect because zend died, after restarting zend.
+func runClientWithRecovery(cliPath string, clientArgs []string) (int, error) {
+	exitCode, stderrOutput, runErr := runClient(cliPath, clientArgs)
+	if runErr != nil {
+		return exitCode, runErr
+	}
 
 	if exitCode != 0 && isConnectionError(stderrOutput) {
 		// Check if daemon died
@@ -318,30 +412,25 @@ func startViaDaemon(profile, client string, providerNames []string, args []strin
 			fmt.Fprintln(os.Stderr, "Daemon connection lost. Restarting daemon...")
 			if err := ensureDaemonRunning(); err != nil {
 				fmt.Fprintf(os.Stderr, "Failed to restart daemon: %v\n", err)
-				os.Exit(exitCode)
+				return exitCode, nil
 			}
 
 			// Retry once
 			exitCode, _, runErr = runClient(cliPath, clientArgs)
-			if runErr != nil {
-				return runErr
-			}
 		}
 	}
-
-	if exitCode != 0 {
-		os.Exit(exitCode)
-	}
-	return nil
+	return exitCode, runErr
 }
 
 // registerSession registers a session with the daemon for bot visibility.
 func registerSession(proxyPort int, profile, sessionID, clientType string) {
 	url := fmt.Sprintf("http://127.0.0.1:%d/api/v1/daemon/sessions", proxyPort)
+	projectPath, _ := os.Getwd()
 	body, _ := json.Marshal(map[string]string{
-		"session_id":  sessionID,
-		"profile":     profile,
-		"client_type": clientType,
+		"session_id":   sessionID,
+		"profile":      profile,
+		"client_type":  clientType,
+		"project_path": projectPath,
 	})
 
 	client := &http.Client{Timeout: 2 * time.Second}
@@ -524,6 +613,10 @@ func startLegacyProxy(names []string, pc *config.ProfileConfig, cli string, args
 		return fmt.Errorf("%s not found in PATH: %w", cliBin, err)
 	}
 
+	// Manage Claude Code's settings if the binding asks for it
+	restoreSettings := applyClaudeSettings(cliBin, proxyURL, mergedEnvVars)
+	defer restoreSettings()
+
 	// Start CLI as subprocess (not exec, so proxy stays alive)
 	cliCmd := exec.Command(cliPath, args...)
 	cliCmd.Stdin = os.Stdin
@@ -545,6 +638,7 @@ func startLegacyProxy(names []string, pc *config.ProfileConfig, cli string, args
 		signal.Stop(sigCh)
 		close(sigCh)
 		if exitErr, ok := err.(*exec.ExitError); ok {
+			restoreSettings()
 			os.Exit(exitErr.ExitCode())
 		}
 		return err
@@ -570,6 +664,10 @@ func buildProviders(names []string) ([]*proxy.Provider, error) {
 		if p.BaseURL == "" || p.AuthToken == "" {
 			return nil, fmt.Errorf("%s missing base_url or auth_token", name)
 		}
+		p, err := p.ResolveTokens()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
 
 		model := p.Model
 		if model == "" {
@@ -611,7 +709,15 @@ func buildProviders(names []string) ([]*proxy.Provider, error) {
 			ClaudeEnvVars:   p.ClaudeEnvVars,
 			CodexEnvVars:    p.CodexEnvVars,
 			OpenCodeEnvVars: p.OpenCodeEnvVars,
+			GeminiEnvVars:   p.GeminiEnvVars,
+			AiderEnvVars:    p.AiderEnvVars,
 			ProxyURL:        p.ProxyURL,
+			SafetySettings:  p.SafetySettings,
+			Transforms:      p.Transforms,
+			CaptureBodies:   p.CaptureBodies,
+			MaxConcurrent:   p.MaxConcurrent,
+			QueueSize:       p.GetQueueSize(),
+			QueueTimeout:    p.GetQueueTimeout(),
 			Healthy:         true,
 		})
 
@@ -754,8 +860,8 @@ func resolveProviderNamesAndClient(profileFlag string, clientFlag string) ([]str
 	cwd, err := os.Getwd()
 	if err == nil {
 		cwd = filepath.Clean(cwd)
-		if binding := config.GetProjectBinding(cwd); binding != nil {
-			// Found project binding
+		if _, binding := config.FindProjectBinding(cwd); binding != nil {
+			// Found project binding, by path or git remote
 			profile := binding.Profile
 			if profile == "" {
 				profile = config.GetDefaultProfile()
@@ -894,6 +1000,8 @@ const (
 	ClientClaude   ClientType = "claude"
 	ClientCodex    ClientType = "codex"
 	ClientOpenCode ClientType = "opencode"
+	ClientGemini   ClientType = "gemini"
+	ClientAider    ClientType = "aider"
 )
 
 // GetClientType returns the client type from the binary name.
@@ -903,6 +1011,10 @@ func GetClientType(clientBin string) ClientType {
 		return ClientCodex
 	case "opencode":
 		return ClientOpenCode
+	case "gemini":
+		return ClientGemini
+	case "aider":
+		return ClientAider
 	default:
 		return ClientClaude
 	}
@@ -911,22 +1023,28 @@ func GetClientType(clientBin string) ClientType {
 // GetClientFormat returns the API format used by the client.
 func GetClientFormat(clientType ClientType) string {
 	switch clientType {
-	case ClientCodex:
+	case ClientCodex, ClientAider:
 		return config.ProviderTypeOpenAI
 	default:
-		// Claude Code and OpenCode use Anthropic format by default
+		// Claude Code and OpenCode use Anthropic format by default; the
+		// proxy converts Gemini CLI's Gemini API requests to it
 		return config.ProviderTypeAnthropic
 	}
 }
 
 // prependAutoApproveArgs prepends the appropriate auto-approve flags for each CLI.
-// Claude Code: --permission-mode bypassPermissions, Codex: -a never, OpenCode: auto-approves by default (no flag needed).
+// Claude Code: --permission-mode bypassPermissions, Codex: -a never, Gemini CLI: --yolo,
+// Aider: --yes-always, OpenCode: auto-approves by default (no flag needed).
 func prependAutoApproveArgs(clientBin string, args []string) []string {
 	switch GetClientType(clientBin) {
 	case ClientClaude:
 		return append([]string{"--permission-mode", "bypassPermissions"}, args...)
 	case ClientCodex:
 		return append([]string{"-a", "never"}, args...)
+	case ClientGemini:
+		return append([]string{"--yolo"}, args...)
+	case ClientAider:
+		return append([]string{"--yes-always"}, args...)
 	default:
 		// OpenCode auto-approves by default
 		return args
@@ -972,35 +1090,76 @@ func hasPermissionFlags(clientBin string, args []string) bool {
 				return true
 			}
 		}
+	case ClientGemini:
+		for _, arg := range args {
+			if arg == "--yolo" || arg == "-y" || arg == "--approval-mode" || strings.HasPrefix(arg, "--approval-mode=") {
+				return true
+			}
+		}
+	case ClientAider:
+		for _, arg := range args {
+			if arg == "--yes-always" || arg == "--yes" {
+				return true
+			}
+		}
 	}
 	return false
 }
 
 // setupClientEnvironment sets the appropriate environment variables for the client.
+// The client authenticates to the proxy with ZEN_API_KEY when set, so usage
+// on a shared daemon is attributed to that key's user.
 func setupClientEnvironment(clientBin string, proxyURL string, logger *log.Logger) {
 	clientType := GetClientType(clientBin)
+	apiKey := proxyAPIKey()
 
 	switch clientType {
 	case ClientCodex:
 		// Codex uses OpenAI environment variables
 		os.Setenv("OPENAI_BASE_URL", proxyURL)
-		os.Setenv("OPENAI_API_KEY", "zen-proxy")
+		os.Setenv("OPENAI_API_KEY", apiKey)
 		logger.Printf("Setting Codex env: OPENAI_BASE_URL=%s", proxyURL)
 
 	case ClientOpenCode:
 		// OpenCode supports multiple providers, set both
 		// It will use the appropriate one based on the model prefix
 		os.Setenv("ANTHROPIC_BASE_URL", proxyURL)
-		os.Setenv("ANTHROPIC_API_KEY", "zen-proxy")
+		os.Setenv("ANTHROPIC_API_KEY", apiKey)
 		os.Setenv("OPENAI_BASE_URL", proxyURL)
-		os.Setenv("OPENAI_API_KEY", "zen-proxy")
+		os.Setenv("OPENAI_API_KEY", apiKey)
 		logger.Printf("Setting OpenCode env: ANTHROPIC_BASE_URL=%s, OPENAI_BASE_URL=%s", proxyURL, proxyURL)
 
+	case ClientGemini:
+		// Gemini CLI speaks the Gemini API, which the proxy converts for
+		// whichever providers the profile routes to
+		os.Setenv("GOOGLE_GEMINI_BASE_URL", proxyURL)
+		os.Setenv("GEMINI_API_KEY", apiKey)
+		os.Setenv("GEMINI_DEFAULT_AUTH_TYPE", "gemini-api-key")
+		logger.Printf("Setting Gemini CLI env: GOOGLE_GEMINI_BASE_URL=%s", proxyURL)
+
+	case ClientAider:
+		// Aider reaches models through LiteLLM, which reads the *_API_BASE
+		// variables. Set both, so it stays on the proxy whichever model it picks
+		os.Setenv("OPENAI_API_BASE", proxyURL)
+		os.Setenv("OPENAI_API_KEY", apiKey)
+		os.Setenv("ANTHROPIC_API_BASE", proxyURL)
+		os.Setenv("ANTHROPIC_API_KEY", apiKey)
+		logger.Printf("Setting Aider env: OPENAI_API_BASE=%s, ANTHROPIC_API_BASE=%s", proxyURL, proxyURL)
+
 	default:
 		// Claude Code uses Anthropic environment variables
 		os.Setenv("ANTHROPIC_BASE_URL", proxyURL)
-		os.Setenv("ANTHROPIC_AUTH_TOKEN", "zen-proxy")
+		os.Setenv("ANTHROPIC_AUTH_TOKEN", apiKey)
 		logger.Printf("Setting Claude env: ANTHROPIC_BASE_URL=%s", proxyURL)
 	}
 }
 
+
+// proxyAPIKey returns the key clients authenticate to the proxy with:
+// ZEN_API_KEY when set, otherwise a placeholder.
+func proxyAPIKey() string {
+	if key := os.Getenv("ZEN_API_KEY"); key != "" {
+		return key
+	}
+	return "zen-proxy"
+}
@@ -491,6 +491,8 @@ func TestGetCLIType(t *testing.T) {
 		{"claude", ClientClaude},
 		{"codex", ClientCodex},
 		{"opencode", ClientOpenCode},
+		{"gemini", ClientGemini},
+		{"aider", ClientAider},
 		{"", ClientClaude},       // default
 		{"unknown", ClientClaude}, // fallback to default
 	}
@@ -513,6 +515,8 @@ func TestGetCLIClientFormat(t *testing.T) {
 		{ClientClaude, config.ProviderTypeAnthropic},
 		{ClientCodex, config.ProviderTypeOpenAI},
 		{ClientOpenCode, config.ProviderTypeAnthropic},
+		{ClientGemini, config.ProviderTypeAnthropic},
+		{ClientAider, config.ProviderTypeOpenAI},
 	}
 
 	for _, tt := range tests {
@@ -604,6 +608,49 @@ func TestSetupCLIEnvironment_OpenCode(t *testing.T) {
 	}
 }
 
+func TestSetupCLIEnvironment_Gemini(t *testing.T) {
+	t.Setenv("GOOGLE_GEMINI_BASE_URL", "")
+	t.Setenv("GEMINI_API_KEY", "")
+	t.Setenv("GEMINI_DEFAULT_AUTH_TYPE", "")
+	t.Setenv("ANTHROPIC_BASE_URL", "")
+
+	proxyURL := "http://127.0.0.1:12345"
+	setupClientEnvironment("gemini", proxyURL, discardLogger())
+
+	if got := os.Getenv("GOOGLE_GEMINI_BASE_URL"); got != proxyURL {
+		t.Errorf("GOOGLE_GEMINI_BASE_URL = %q, want %q", got, proxyURL)
+	}
+	if got := os.Getenv("GEMINI_API_KEY"); got != "zen-proxy" {
+		t.Errorf("GEMINI_API_KEY = %q, want %q", got, "zen-proxy")
+	}
+	if got := os.Getenv("GEMINI_DEFAULT_AUTH_TYPE"); got != "gemini-api-key" {
+		t.Errorf("GEMINI_DEFAULT_AUTH_TYPE = %q, want %q", got, "gemini-api-key")
+	}
+	if got := os.Getenv("ANTHROPIC_BASE_URL"); got != "" {
+		t.Errorf("ANTHROPIC_BASE_URL should not be set for Gemini CLI, got %q", got)
+	}
+}
+
+func TestSetupCLIEnvironment_Aider(t *testing.T) {
+	for _, k := range []string{"OPENAI_API_BASE", "OPENAI_API_KEY", "ANTHROPIC_API_BASE", "ANTHROPIC_API_KEY"} {
+		t.Setenv(k, "")
+	}
+
+	proxyURL := "http://127.0.0.1:12345"
+	setupClientEnvironment("aider", proxyURL, discardLogger())
+
+	for _, k := range []string{"OPENAI_API_BASE", "ANTHROPIC_API_BASE"} {
+		if got := os.Getenv(k); got != proxyURL {
+			t.Errorf("%s = %q, want %q", k, got, proxyURL)
+		}
+	}
+	for _, k := range []string{"OPENAI_API_KEY", "ANTHROPIC_API_KEY"} {
+		if got := os.Getenv(k); got != "zen-proxy" {
+			t.Errorf("%s = %q, want %q", k, got, "zen-proxy")
+		}
+	}
+}
+
 func TestSetupCLIEnvironment_UnknownCLI(t *testing.T) {
 	// Clear env vars before test
 	os.Unsetenv("ANTHROPIC_BASE_URL")
@@ -625,6 +672,17 @@ func TestSetupCLIEnvironment_UnknownCLI(t *testing.T) {
 	}
 }
 
+func TestSetupCLIEnvironment_APIKey(t *testing.T) {
+	t.Setenv("ZEN_API_KEY", "zen-0123abcd")
+	t.Setenv("ANTHROPIC_AUTH_TOKEN", "")
+
+	setupClientEnvironment("claude", "http://127.0.0.1:12345", discardLogger())
+
+	if got := os.Getenv("ANTHROPIC_AUTH_TOKEN"); got != "zen-0123abcd" {
+		t.Errorf("ANTHROPIC_AUTH_TOKEN = %q, want the ZEN_API_KEY value", got)
+	}
+}
+
 // discardLogger returns a logger that discards all output.
 func discardLogger() *log.Logger {
 	return log.New(io.Discard, "", 0)
@@ -783,6 +841,18 @@ func TestPrependAutoApproveArgs_OpenCode(t *testing.T) {
 			args:     []string{"--verbose"},
 			expected: []string{"--verbose"},
 		},
+		{
+			name:     "gemini with existing args",
+			client:   "gemini",
+			args:     []string{"-m", "gemini-2.5-pro"},
+			expected: []string{"--yolo", "-m", "gemini-2.5-pro"},
+		},
+		{
+			name:     "aider with existing args",
+			client:   "aider",
+			args:     []string{"--model", "sonnet"},
+			expected: []string{"--yes-always", "--model", "sonnet"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -960,6 +1030,30 @@ func TestHasPermissionFlags(t *testing.T) {
 			args:      []string{"--verbose"},
 			wantFound: false,
 		},
+		{
+			name:      "gemini with --yolo",
+			client:    "gemini",
+			args:      []string{"--yolo"},
+			wantFound: true,
+		},
+		{
+			name:      "gemini with --approval-mode=",
+			client:    "gemini",
+			args:      []string{"--approval-mode=auto_edit"},
+			wantFound: true,
+		},
+		{
+			name:      "aider with --yes-always",
+			client:    "aider",
+			args:      []string{"--model", "sonnet", "--yes-always"},
+			wantFound: true,
+		},
+		{
+			name:      "aider without permission flags",
+			client:    "aider",
+			args:      []string{"--model", "sonnet"},
+			wantFound: false,
+		},
 		{
 			name:      "empty args",
 			client:    "claude",
@@ -1367,3 +1461,52 @@ func TestVerify_AllUserStoriesE2E(t *testing.T) {
 		runAndCheck(t, result, []string{"--permission-mode", "acceptEdits"})
 	})
 }
+
+func TestApplySetFlags(t *testing.T) {
+	setTestHome(t)
+	writeTestProvider(t, "my-provider", &config.ProviderConfig{BaseURL: "https://api.example.com", AuthToken: "sk-file"})
+	t.Setenv("ZEN_PROXY_PORT", "")
+	t.Setenv("ZEN_PROVIDERS_MY_PROVIDER_AUTH_TOKEN", "")
+	t.Setenv("ZEN_NO_SUCH_KEY", "")
+
+	if err := applySetFlags([]string{"proxy_port=29841", "providers.my-provider.auth_token=sk-flag"}); err != nil {
+		t.Fatalf("applySetFlags() error: %v", err)
+	}
+	if got := config.GetProxyPort(); got != 29841 {
+		t.Errorf("proxy port = %d, want 29841", got)
+	}
+	if got := config.GetProvider("my-provider").AuthToken; got != "sk-flag" {
+		t.Errorf("token = %q, want sk-flag", got)
+	}
+
+	if err := applySetFlags([]string{"no_such_key=1"}); err == nil {
+		t.Error("applySetFlags() with an unknown key should fail")
+	}
+	if err := applySetFlags([]string{"proxy_port"}); err == nil {
+		t.Error("applySetFlags() without a value should fail")
+	}
+}
+
+func TestApplyHomeFlag(t *testing.T) {
+	setTestHome(t)
+	t.Setenv(config.HomeEnv, "")
+
+	if err := applyHomeFlag(""); err != nil || os.Getenv(config.HomeEnv) != "" {
+		t.Fatalf("applyHomeFlag(\"\") = %v, ZEN_HOME = %q; want no change", err, os.Getenv(config.HomeEnv))
+	}
+
+	dir := filepath.Join(t.TempDir(), "work")
+	if err := applyHomeFlag(dir); err != nil {
+		t.Fatalf("applyHomeFlag() error: %v", err)
+	}
+	if got := config.ConfigDirPath(); got != dir {
+		t.Errorf("ConfigDirPath() = %q, want %q", got, dir)
+	}
+	web, proxy := config.InstancePorts()
+	if got := config.GetProxyPort(); got != proxy || got == config.DefaultProxyPort {
+		t.Errorf("proxy port = %d, want the instance's port %d", got, proxy)
+	}
+	if got := config.GetWebPort(); got != web || got == config.DefaultWebPort {
+		t.Errorf("web port = %d, want the instance's port %d", got, web)
+	}
+}
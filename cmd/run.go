@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/proxy"
+	"github.com/spf13/cobra"
+)
+
+var runProfileFlag string
+var runClientFlag string
+
+var runCmd = &cobra.Command{
+	Use:   "run [flags] -- <command> [args...]",
+	Short: "Run any command with the proxy environment of a profile",
+	Long: `Run any command through zend, with ANTHROPIC_BASE_URL, ANTHROPIC_AUTH_TOKEN,
+ANTHROPIC_API_KEY, OPENAI_BASE_URL and OPENAI_API_KEY pointing at the proxy,
+plus the env vars the profile's providers set for a client.
+
+The profile comes from -p, the current directory's binding or the default
+profile, as for 'zen'. The client (-c, the binding's or the default client)
+picks which of the providers' per-client env vars are exported.
+
+Examples:
+  zen run -- npx promptfoo eval
+  zen run -p work -- python agent.py
+  zen run -c codex -- ./scripts/eval.sh`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runRun,
+}
+
+func init() {
+	runCmd.Flags().StringVarP(&runProfileFlag, "profile", "p", "", "profile name")
+	runCmd.Flags().StringVarP(&runClientFlag, "client", "c", "", "client whose provider env vars to export (claude, codex, opencode, gemini, aider)")
+	runCmd.RegisterFlagCompletionFunc("profile", completeProfileNames)
+	runCmd.RegisterFlagCompletionFunc("client", completeClientNames)
+	// Flags after the command belong to it, even without --
+	runCmd.Flags().SetInterspersed(false)
+}
+
+func runRun(cmd *cobra.Command, args []string) error {
+	providerNames, profile, client, err := resolveProviderNamesAndClient(runProfileFlag, runClientFlag)
+	if err != nil {
+		return err
+	}
+	providerNames, err = validateProviderNames(providerNames, profile)
+	if err != nil {
+		return err
+	}
+	providers, err := buildProviders(providerNames)
+	if err != nil {
+		return err
+	}
+	cmdPath, err := exec.LookPath(args[0])
+	if err != nil {
+		return fmt.Errorf("%s not found in PATH: %w", args[0], err)
+	}
+
+	if err := ensureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start zend: %w", err)
+	}
+	sessionID := generateSessionID()
+	proxyPort := config.GetProxyPort()
+	baseURL := fmt.Sprintf("http://127.0.0.1:%d/%s/%s", proxyPort, profile, sessionID)
+
+	name := filepath.Base(args[0])
+	for k, v := range runEnvironment(providers, client, baseURL) {
+		os.Setenv(k, v)
+	}
+	os.Setenv("X_ZEN_CLIENT", name)
+	registerSession(proxyPort, profile, sessionID, name)
+
+	// Unlike a client, the command is not retried if zend goes away: it may
+	// not be safe to run twice
+	exitCode, _, err := runClient(cmdPath, args[1:])
+	if err != nil {
+		return err
+	}
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
+	return nil
+}
+
+// runEnvironment returns the env vars zen run exports: the providers' env
+// vars for client, then the Anthropic and OpenAI base URLs and keys of the
+// proxy, which take precedence.
+func runEnvironment(providers []*proxy.Provider, client, baseURL string) map[string]string {
+	env := mergeProviderEnvVarsForCLI(providers, client)
+	apiKey := proxyAPIKey()
+	env["ANTHROPIC_BASE_URL"] = baseURL
+	env["ANTHROPIC_AUTH_TOKEN"] = apiKey
+	env["ANTHROPIC_API_KEY"] = apiKey
+	env["OPENAI_BASE_URL"] = baseURL
+	env["OPENAI_API_KEY"] = apiKey
+	return env
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/dopejs/gozen/internal/proxy"
+)
+
+func TestRunEnvironment(t *testing.T) {
+	t.Setenv("ZEN_API_KEY", "")
+	providers := []*proxy.Provider{
+		{Name: "a", EnvVars: map[string]string{"SHARED": "a", "ANTHROPIC_BASE_URL": "https://direct.example.com"}},
+		{Name: "b", CodexEnvVars: map[string]string{"CODEX_ONLY": "b"}, EnvVars: map[string]string{"SHARED": "b"}},
+	}
+	base := "http://127.0.0.1:19841/work/abcd1234"
+
+	env := runEnvironment(providers, "claude", base)
+	for k, want := range map[string]string{
+		"ANTHROPIC_BASE_URL":   base,
+		"ANTHROPIC_AUTH_TOKEN": "zen-proxy",
+		"ANTHROPIC_API_KEY":    "zen-proxy",
+		"OPENAI_BASE_URL":      base,
+		"OPENAI_API_KEY":       "zen-proxy",
+		"SHARED":               "a",
+	} {
+		if env[k] != want {
+			t.Errorf("%s = %q, want %q", k, env[k], want)
+		}
+	}
+	if _, ok := env["CODEX_ONLY"]; ok {
+		t.Error("codex env vars exported for claude")
+	}
+
+	t.Setenv("ZEN_API_KEY", "user-key")
+	env = runEnvironment(providers, "codex", base)
+	if env["CODEX_ONLY"] != "b" || env["OPENAI_API_KEY"] != "user-key" {
+		t.Errorf("codex env = %v", env)
+	}
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/crashreport"
+	"github.com/dopejs/gozen/internal/daemon"
+	"github.com/spf13/cobra"
+)
+
+var serveForegroundFlag bool
+var serveDrainTimeoutFlag time.Duration
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run the proxy, web UI and bot in one process",
+	Long: `Run zend, the proxy, web UI and bot gateway, in one process.
+
+With --foreground, zend stays attached: it logs to stdout instead of
+~/.zen/zend.log, in the level and format of the log config, does not
+restart itself after a crash, and on SIGTERM or SIGINT stops accepting
+requests and drains in-flight ones before exiting.
+This suits Docker and Kubernetes, which collect stdout and restart the
+container. GET /healthz on the proxy or web port answers 200 while zend
+serves requests and 503 once it is shutting down.
+
+Without --foreground, zen serve starts zend in the background like
+'zen daemon start'.
+
+Examples:
+  zen serve --foreground
+  zen serve --foreground --drain-timeout 20s`,
+	Args: cobra.NoArgs,
+	RunE: runServe,
+}
+
+func init() {
+	serveCmd.Flags().BoolVar(&serveForegroundFlag, "foreground", false, "stay attached and log to stdout (for containers)")
+	serveCmd.Flags().DurationVar(&serveDrainTimeoutFlag, "drain-timeout", 30*time.Second, "how long shutdown waits for in-flight requests")
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	if !serveForegroundFlag {
+		return runDaemonStart(cmd, args)
+	}
+	return runServeForeground(serveDrainTimeoutFlag)
+}
+
+// runServeForeground runs zend attached to the terminal or container, with
+// no restart loop: a crash ends the process so the supervisor can restart
+// it.
+func runServeForeground(drainTimeout time.Duration) error {
+	logCfg := config.GetLog()
+	level, err := daemon.ParseLogLevel(logCfg.GetLevel())
+	if err != nil {
+		level = daemon.LevelInfo
+	}
+	logSink := daemon.NewLogSink(io.MultiWriter(os.Stdout, crashreport.LogTail()), logCfg.GetFormat(), level)
+	logger := logSink.Logger()
+	crashreport.Init(Version, logger)
+	d := daemon.NewDaemon(Version, logger)
+	d.SetLogSink(logSink)
+	d.SetDrainTimeout(drainTimeout)
+
+	daemon.CleanupLegacyPidFiles()
+	daemon.WriteDaemonPid(os.Getpid())
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	shutdownDone := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-sigCh:
+			logger.Printf("received %v, draining for up to %v", sig, d.DrainTimeout())
+		case <-d.ShutdownCh():
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), d.DrainTimeout())
+		defer cancel()
+		d.Shutdown(ctx)
+		close(shutdownDone)
+	}()
+
+	if err := d.StartRecovered(); err != nil {
+		daemon.RemoveOwnDaemonPid()
+		return err
+	}
+
+	// Start returns nil once Shutdown has stopped the web server; wait for
+	// the rest of the shutdown to finish
+	<-shutdownDone
+	return nil
+}
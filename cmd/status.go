@@ -0,0 +1,443 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/crashreport"
+	"github.com/dopejs/gozen/internal/daemon"
+	"github.com/spf13/cobra"
+)
+
+var (
+	statusAllBindings bool
+	statusJSON        bool
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show a one-screen system overview",
+	Long: `Show daemon state, the profile and client bound to the current directory,
+the client processes connected to the daemon, provider health, today's spend
+against budget, pending agent tasks, bot gateway state and sync status.
+
+With --json, print the same as JSON for scripts.`,
+	Args: cobra.NoArgs,
+	RunE: runStatus,
+}
+
+func init() {
+	statusCmd.Flags().BoolVar(&statusAllBindings, "bindings", false, "also list all project bindings")
+	statusCmd.Flags().BoolVar(&statusJSON, "json", false, "print the overview as JSON")
+}
+
+// statusDaemon mirrors the fields of /api/v1/daemon/status used by zen status.
+type statusDaemon struct {
+	Version        string `json:"version"`
+	Uptime         string `json:"uptime"`
+	ProxyPort      int    `json:"proxy_port"`
+	ActiveSessions int    `json:"active_sessions"`
+	Bot            *struct {
+		Enabled   bool     `json:"enabled"`
+		Running   bool     `json:"running"`
+		Platforms []string `json:"platforms"`
+	} `json:"bot"`
+}
+
+// statusHealth mirrors the fields of /api/v1/daemon/health used by zen status.
+type statusHealth struct {
+	HealthCheckEnabled bool             `json:"health_check_enabled"`
+	Providers          []statusProvider `json:"providers"`
+}
+
+// statusBudget mirrors the fields of /api/v1/budget/status used by zen status.
+type statusBudget struct {
+	Currency     string  `json:"currency"`
+	DailySpent   float64 `json:"daily_spent"`
+	DailyLimit   float64 `json:"daily_limit"`
+	DailyPercent float64 `json:"daily_percent"`
+	Message      string  `json:"message,omitempty"`
+}
+
+// statusSync mirrors /api/v1/sync/status.
+type statusSync struct {
+	Configured bool      `json:"configured"`
+	Backend    string    `json:"backend"`
+	LastPullAt time.Time `json:"last_pull_at"`
+	LastPushAt time.Time `json:"last_push_at"`
+}
+
+// statusProvider is a provider's health, as /api/v1/daemon/health reports
+// it, or its configured state when the daemon is not running.
+type statusProvider struct {
+	Name        string  `json:"name"`
+	Status      string  `json:"status"`
+	LatencyMs   int     `json:"latency_ms,omitempty"`
+	SuccessRate float64 `json:"success_rate,omitempty"`
+}
+
+// statusProcess mirrors a client process of /api/v1/daemon/sessions.
+type statusProcess struct {
+	ID       string    `json:"id"`
+	Profile  string    `json:"profile"`
+	Client   string    `json:"client"`
+	Project  string    `json:"project_path,omitempty"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// statusTasks counts the agent task queue's tasks by status, as
+// /api/v1/agent/tasks reports them.
+type statusTasks struct {
+	Pending int `json:"pending"`
+	Running int `json:"running"`
+	Failed  int `json:"failed"`
+}
+
+// statusReport is everything zen status shows, and what --json prints.
+type statusReport struct {
+	Daemon struct {
+		Running      bool   `json:"running"`
+		PID          int    `json:"pid,omitempty"`
+		APIReachable bool   `json:"api_reachable"`
+		Version      string `json:"version,omitempty"`
+		Uptime       string `json:"uptime,omitempty"`
+	} `json:"daemon"`
+	Directory     string                            `json:"directory"`
+	Profile       string                            `json:"profile"`
+	ProfileSource string                            `json:"profile_source"`
+	Client        string                            `json:"client"`
+	ClientSource  string                            `json:"client_source"`
+	Processes     []statusProcess                   `json:"processes"`
+	Providers     []statusProvider                  `json:"providers"`
+	HealthChecks  bool                              `json:"health_checks"`
+	Today         *statusBudget                     `json:"today"`       // nil when unknown
+	AgentTasks    *statusTasks                      `json:"agent_tasks"` // nil when the agent is off
+	Bot           string                            `json:"bot"`         // running, stopped, enabled (daemon down) or disabled
+	BotPlatforms  []string                          `json:"bot_platforms,omitempty"`
+	Sync          *statusSync                       `json:"sync"`                   // nil when not configured
+	SyncBackend   string                            `json:"sync_backend,omitempty"` // when the daemon can't report sync status
+	CrashReports  int                               `json:"crash_reports"`          // pending, not submitted
+	Bindings      map[string]*config.ProjectBinding `json:"bindings,omitempty"`
+}
+
+func runStatus(cmd *cobra.Command, args []string) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+	r := collectStatus(filepath.Clean(cwd))
+	if statusJSON {
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+	printStatus(cmd.OutOrStdout(), r)
+	return nil
+}
+
+// collectStatus gathers the overview from the daemon API, falling back to
+// the config for whatever the daemon cannot tell.
+func collectStatus(cwd string) *statusReport {
+	r := &statusReport{
+		Directory: cwd,
+		Processes: make([]statusProcess, 0),
+		Providers: make([]statusProvider, 0),
+	}
+
+	// Daemon
+	pid, running := daemon.IsDaemonRunning()
+	var ds statusDaemon
+	apiOK := running && fetchDaemonAPI("/api/v1/daemon/status", &ds) == nil
+	r.Daemon.Running, r.Daemon.APIReachable = running, apiOK
+	if running && pid > 0 {
+		r.Daemon.PID = pid
+	}
+	if apiOK {
+		r.Daemon.Version, r.Daemon.Uptime = strings.TrimPrefix(ds.Version, "v"), ds.Uptime
+	}
+
+	// Binding for current directory
+	r.Profile, r.ProfileSource, r.Client, r.ClientSource = statusBinding(cwd)
+
+	// Connected client processes
+	var sessions struct {
+		Sessions []statusProcess `json:"sessions"`
+	}
+	if apiOK && fetchDaemonAPI("/api/v1/daemon/sessions", &sessions) == nil {
+		r.Processes = append(r.Processes, sessions.Sessions...)
+		sort.Slice(r.Processes, func(i, j int) bool { return r.Processes[i].LastSeen.After(r.Processes[j].LastSeen) })
+	}
+
+	// Provider health
+	var health statusHealth
+	if apiOK && fetchDaemonAPI("/api/v1/daemon/health", &health) == nil && len(health.Providers) > 0 {
+		r.Providers = append(r.Providers, health.Providers...)
+	} else {
+		disabled := config.DefaultStore().GetDisabledProviders()
+		for _, name := range config.ProviderNames() {
+			state := "configured"
+			if _, ok := disabled[name]; ok {
+				state = "disabled"
+			}
+			r.Providers = append(r.Providers, statusProvider{Name: name, Status: state})
+		}
+	}
+	r.HealthChecks = health.HealthCheckEnabled
+
+	// Spend
+	var budget statusBudget
+	if apiOK && fetchDaemonAPI("/api/v1/budget/status", &budget) == nil {
+		r.Today = &budget
+	}
+
+	// Agent tasks
+	var tasks struct {
+		Stats statusTasks `json:"stats"`
+	}
+	if apiOK && fetchDaemonAPI("/api/v1/agent/tasks", &tasks) == nil {
+		r.AgentTasks = &tasks.Stats
+	}
+
+	// Bot gateway
+	switch {
+	case apiOK && ds.Bot != nil && ds.Bot.Running:
+		r.Bot, r.BotPlatforms = "running", ds.Bot.Platforms
+	case apiOK && ds.Bot != nil && ds.Bot.Enabled:
+		r.Bot = "stopped"
+	default:
+		if bot := config.GetBot(); bot != nil && bot.Enabled {
+			r.Bot = "enabled"
+		} else {
+			r.Bot = "disabled"
+		}
+	}
+
+	// Sync
+	var ss statusSync
+	if apiOK && fetchDaemonAPI("/api/v1/sync/status", &ss) == nil && ss.Configured {
+		r.Sync = &ss
+	} else if sc := config.GetSyncConfig(); sc != nil && sc.Backend != "" {
+		r.SyncBackend = sc.Backend
+	}
+
+	// Crash reports, read from disk so they show while the daemon is down
+	r.CrashReports = crashreport.Pending()
+
+	if statusAllBindings {
+		r.Bindings = config.GetAllProjectBindings()
+	}
+	return r
+}
+
+// printStatus prints the overview in one screen.
+func printStatus(out io.Writer, r *statusReport) {
+	switch {
+	case !r.Daemon.Running:
+		fmt.Fprintln(out, "Daemon:    not running (start with 'zen daemon start')")
+	case !r.Daemon.APIReachable:
+		fmt.Fprintf(out, "Daemon:    running (%s), API not reachable\n", formatPID(statusPID(r)))
+	default:
+		fmt.Fprintf(out, "Daemon:    running (%s), up %s, v%s, %d connected process(es)\n",
+			formatPID(statusPID(r)), r.Daemon.Uptime, r.Daemon.Version, len(r.Processes))
+	}
+	for i, p := range r.Processes {
+		if i == maxStatusProcesses {
+			fmt.Fprintf(out, "           ... and %d more\n", len(r.Processes)-i)
+			break
+		}
+		line := fmt.Sprintf("           %-9s %-12s", p.Client, p.Profile)
+		if p.Project != "" {
+			line += " " + p.Project
+		}
+		fmt.Fprintln(out, strings.TrimRight(line, " "))
+	}
+
+	fmt.Fprintf(out, "Directory: %s\n", r.Directory)
+	fmt.Fprintf(out, "Profile:   %s (%s)\n", r.Profile, r.ProfileSource)
+	fmt.Fprintf(out, "Client:    %s (%s)\n", r.Client, r.ClientSource)
+
+	fmt.Fprintln(out, "\nProviders:")
+	if len(r.Providers) == 0 {
+		fmt.Fprintln(out, "  (none configured)")
+	}
+	for _, p := range r.Providers {
+		line := fmt.Sprintf("  %-20s %-10s", p.Name, p.Status)
+		if p.LatencyMs > 0 {
+			line += fmt.Sprintf(" %5dms", p.LatencyMs)
+		}
+		if p.SuccessRate > 0 {
+			line += fmt.Sprintf(" %6.1f%% ok", p.SuccessRate)
+		}
+		fmt.Fprintln(out, strings.TrimRight(line, " "))
+	}
+	if r.Daemon.APIReachable && !r.HealthChecks {
+		fmt.Fprintln(out, "  (health checks disabled)")
+	}
+
+	switch {
+	case !r.Daemon.APIReachable:
+		fmt.Fprintln(out, "\nToday:     unknown (daemon not running)")
+	case r.Today == nil:
+		fmt.Fprintln(out, "\nToday:     unknown")
+	case r.Today.DailyLimit > 0:
+		fmt.Fprintf(out, "\nToday:     %s of %s budget (%.0f%%)\n",
+			config.FormatAmount(r.Today.Currency, r.Today.DailySpent), config.FormatAmount(r.Today.Currency, r.Today.DailyLimit), r.Today.DailyPercent)
+	default:
+		fmt.Fprintf(out, "\nToday:     %s (no daily budget)\n", config.FormatAmount(r.Today.Currency, r.Today.DailySpent))
+	}
+	if r.Today != nil && r.Today.Message != "" {
+		fmt.Fprintf(out, "           %s\n", r.Today.Message)
+	}
+
+	if t := r.AgentTasks; t != nil {
+		line := fmt.Sprintf("Agent:     %d pending, %d running task(s)", t.Pending, t.Running)
+		if t.Failed > 0 {
+			line += fmt.Sprintf(", %d failed", t.Failed)
+		}
+		fmt.Fprintln(out, line)
+	}
+
+	switch r.Bot {
+	case "running":
+		if len(r.BotPlatforms) > 0 {
+			fmt.Fprintf(out, "Bot:       connected (%s)\n", strings.Join(r.BotPlatforms, ", "))
+		} else {
+			fmt.Fprintln(out, "Bot:       running, no platforms connected")
+		}
+	case "stopped":
+		fmt.Fprintln(out, "Bot:       enabled but not running")
+	case "enabled":
+		fmt.Fprintln(out, "Bot:       enabled (daemon not running)")
+	default:
+		fmt.Fprintln(out, "Bot:       disabled")
+	}
+
+	switch {
+	case r.Sync != nil:
+		fmt.Fprintf(out, "Sync:      %s, last push %s, last pull %s\n", r.Sync.Backend, formatAgo(r.Sync.LastPushAt), formatAgo(r.Sync.LastPullAt))
+	case r.SyncBackend != "":
+		fmt.Fprintf(out, "Sync:      %s (status unavailable)\n", r.SyncBackend)
+	default:
+		fmt.Fprintln(out, "Sync:      not configured")
+	}
+
+	switch {
+	case r.CrashReports == 1:
+		fmt.Fprintln(out, "Crashes:   1 crash report pending (see 'zen crash')")
+	case r.CrashReports > 1:
+		fmt.Fprintf(out, "Crashes:   %d crash reports pending (see 'zen crash')\n", r.CrashReports)
+	}
+
+	if statusAllBindings {
+		printAllBindings(out, r.Directory)
+	}
+}
+
+// maxStatusProcesses bounds the connected processes zen status lists.
+const maxStatusProcesses = 5
+
+// statusPID is the daemon PID for formatPID: -1 when it is unknown.
+func statusPID(r *statusReport) int {
+	if r.Daemon.PID == 0 {
+		return -1
+	}
+	return r.Daemon.PID
+}
+
+// statusBinding resolves the profile and client that apply to dir, and
+// where each comes from.
+func statusBinding(dir string) (profile, profileSource, client, clientSource string) {
+	key, binding := config.FindProjectBinding(dir)
+	bound := "bound"
+	if key != "" && !config.IsPathBinding(key) {
+		bound = "bound by " + key
+	}
+	profile, profileSource = config.GetDefaultProfile(), "default"
+	if binding != nil && binding.Profile != "" {
+		if config.GetProfileConfig(binding.Profile) != nil {
+			profile, profileSource = binding.Profile, bound
+		} else {
+			profileSource = fmt.Sprintf("default; bound profile %q no longer exists", binding.Profile)
+		}
+	}
+
+	client, clientSource = config.GetDefaultClient(), "default"
+	if binding != nil && binding.Client != "" {
+		client, clientSource = binding.Client, bound
+	}
+	return profile, profileSource, client, clientSource
+}
+
+// printAllBindings lists every project binding, marking the current directory.
+func printAllBindings(out io.Writer, cwd string) {
+	bindings := config.GetAllProjectBindings()
+	if len(bindings) == 0 {
+		fmt.Fprintln(out, "\nNo project bindings.")
+		return
+	}
+	current, _ := config.FindProjectBinding(cwd)
+	fmt.Fprintf(out, "\nAll project bindings:\n")
+	for path, b := range bindings {
+		marker := "  "
+		if path == current {
+			marker = "> "
+		}
+		var info string
+		if b.Profile != "" && b.Client != "" {
+			info = fmt.Sprintf("%s (client: %s)", b.Profile, b.Client)
+		} else if b.Profile != "" {
+			info = b.Profile
+		} else if b.Client != "" {
+			info = fmt.Sprintf("(client: %s)", b.Client)
+		}
+		fmt.Fprintf(out, "%s%s -> %s\n", marker, path, info)
+	}
+}
+
+// fetchDaemonAPI GETs a daemon web API path and decodes the JSON response.
+func fetchDaemonAPI(path string, v interface{}) error {
+	url := fmt.Sprintf("http://127.0.0.1:%d%s", config.GetWebPort(), path)
+	client := &http.Client{Timeout: 2 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: HTTP %d", path, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func formatPID(pid int) string {
+	if pid == -1 {
+		return "PID unknown"
+	}
+	return fmt.Sprintf("PID %d", pid)
+}
+
+// formatAgo renders a timestamp as a coarse relative time.
+func formatAgo(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+}
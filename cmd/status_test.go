@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+func TestRunStatusWithoutDaemon(t *testing.T) {
+	home := setTestHome(t)
+	writeTestProvider(t, "alpha", &config.ProviderConfig{BaseURL: "https://a.com", AuthToken: "tok"})
+	config.SetProxyPort(1) // nothing listens here, so the daemon reads as stopped
+
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(home)
+
+	var buf bytes.Buffer
+	statusCmd.SetOut(&buf)
+	defer statusCmd.SetOut(nil)
+
+	if err := runStatus(statusCmd, nil); err != nil {
+		t.Fatalf("runStatus() error = %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"Daemon:    not running",
+		"Profile:   default (default)",
+		"alpha",
+		"Bot:       disabled",
+		"Sync:      not configured",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRunStatusJSON(t *testing.T) {
+	home := setTestHome(t)
+	writeTestProvider(t, "alpha", &config.ProviderConfig{BaseURL: "https://a.com", AuthToken: "tok"})
+	config.SetProxyPort(1)
+
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(home)
+
+	var buf bytes.Buffer
+	statusCmd.SetOut(&buf)
+	defer statusCmd.SetOut(nil)
+	statusJSON = true
+	defer func() { statusJSON = false }()
+
+	if err := runStatus(statusCmd, nil); err != nil {
+		t.Fatalf("runStatus() error = %v", err)
+	}
+
+	var got statusReport
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, buf.String())
+	}
+	if got.Daemon.Running || got.Profile != "default" || got.Bot != "disabled" {
+		t.Errorf("unexpected report: %+v", got)
+	}
+	if len(got.Providers) != 1 || got.Providers[0].Name != "alpha" || got.Providers[0].Status != "configured" {
+		t.Errorf("providers = %+v", got.Providers)
+	}
+	if got.Today != nil || got.AgentTasks != nil {
+		t.Errorf("spend and agent tasks should be unknown without the daemon: %+v", got)
+	}
+}
+
+func TestPrintStatus(t *testing.T) {
+	r := &statusReport{
+		Directory: "/work/app",
+		Profile:   "work", ProfileSource: "bound",
+		Client: "claude", ClientSource: "default",
+		Providers:    []statusProvider{{Name: "alpha", Status: "healthy", LatencyMs: 120, SuccessRate: 99.5}},
+		HealthChecks: true,
+		Today:        &statusBudget{Currency: "USD", DailySpent: 2.5, DailyLimit: 10, DailyPercent: 25},
+		AgentTasks:   &statusTasks{Pending: 3, Running: 1},
+		Bot:          "disabled",
+	}
+	r.Daemon.Running, r.Daemon.APIReachable, r.Daemon.PID = true, true, 42
+	r.Daemon.Version, r.Daemon.Uptime = "3.0.1", "2h"
+	for i := 0; i < maxStatusProcesses+2; i++ {
+		r.Processes = append(r.Processes, statusProcess{Client: "claude", Profile: "work", Project: "/work/app"})
+	}
+
+	var buf bytes.Buffer
+	printStatus(&buf, r)
+	out := buf.String()
+	for _, want := range []string{
+		"Daemon:    running (PID 42), up 2h, v3.0.1, 7 connected process(es)",
+		"           claude    work         /work/app",
+		"... and 2 more",
+		"Profile:   work (bound)",
+		"alpha                healthy      120ms   99.5% ok",
+		"Today:     $2.50 of $10.00 budget (25%)",
+		"Agent:     3 pending, 1 running task(s)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestFormatAgo(t *testing.T) {
+	tests := []struct {
+		t    time.Time
+		want string
+	}{
+		{time.Time{}, "never"},
+		{time.Now().Add(-10 * time.Second), "just now"},
+		{time.Now().Add(-5 * time.Minute), "5m ago"},
+		{time.Now().Add(-3 * time.Hour), "3h ago"},
+		{time.Now().Add(-50 * time.Hour), "2d ago"},
+	}
+	for _, tt := range tests {
+		if got := formatAgo(tt.t); got != tt.want {
+			t.Errorf("formatAgo(%v) = %q, want %q", tt.t, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/daemon"
+	"github.com/dopejs/gozen/tui"
+	"github.com/spf13/cobra"
+)
+
+var topIntervalFlag time.Duration
+
+var topCmd = &cobra.Command{
+	Use:   "top",
+	Short: "Show a live dashboard of requests, providers, budget and agent sessions",
+	Long: `Show a live terminal dashboard of the daemon: recent requests, latency and
+error sparklines per provider, budget burn and agent sessions.
+
+Keys:
+  tab      switch between the providers and sessions panes
+  ↑/↓      select a provider or session
+  p        pause the selected provider for today, or resume it
+  x        kill the selected agent session
+  r        refresh now
+  q        quit`,
+	Args: cobra.NoArgs,
+	RunE: runTop,
+}
+
+func init() {
+	topCmd.Flags().DurationVar(&topIntervalFlag, "interval", 2*time.Second, "time between refreshes")
+}
+
+func runTop(cmd *cobra.Command, args []string) error {
+	if _, running := daemon.IsDaemonRunning(); !running {
+		return fmt.Errorf("zend is not running (start it with 'zen daemon start')")
+	}
+	return tui.RunTop(tui.TopOptions{
+		BaseURL:  fmt.Sprintf("http://127.0.0.1:%d", config.GetWebPort()),
+		Interval: topIntervalFlag,
+	})
+}
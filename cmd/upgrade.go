@@ -188,15 +188,10 @@ func runUpgrade(cmd *cobra.Command, args []string) error {
 	// Restart zend daemon if it was running
 	if _, running := daemon.IsDaemonRunning(); running {
 		fmt.Println("Restarting zend daemon...")
-		if err := daemon.StopDaemonProcess(30 * time.Second); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to stop zend: %v\n", err)
-		} else {
-			time.Sleep(300 * time.Millisecond)
-			// Start the new daemon
-			if err := startDaemonBackground(); err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: failed to start zend: %v\n", err)
-				fmt.Println("Run 'zen daemon start' to start manually.")
-			}
+		// Hand off to the new binary so active sessions are not interrupted
+		if err := restartDaemon(5 * time.Minute); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to restart zend: %v\n", err)
+			fmt.Println("Run 'zen daemon start' to start manually.")
 		}
 	} else {
 		// Daemon was not running, start it for convenience
@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dopejs/gozen/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	usagePeriodFlag  string
+	usageByFlag      string
+	usageProjectFlag string
+	usageCSVFlag     bool
+	costCSVFlag      bool
+)
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Show token usage and cost broken down by model, provider or project",
+	Long: `Show the requests, tokens and cost of a period, broken down by model,
+provider, project, user or tag.
+
+Periods: today, week, month (trailing) or all. With --csv, print the breakdown
+as CSV to pipe into other tools. Costs are in the display currency.
+The daemon must be running.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runUsage(cmd.OutOrStdout(), usagePeriodFlag, usageByFlag, usageProjectFlag, usageCSVFlag)
+	},
+}
+
+var costCmd = &cobra.Command{
+	Use:   "cost",
+	Short: "Show today's, this week's and this month's spend against budget",
+	Long: `Show the requests, tokens and cost of today, the last week and the last month,
+with each budget limit that is set. With --csv, print them as CSV.
+The daemon must be running.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runCost(cmd.OutOrStdout(), costCSVFlag)
+	},
+}
+
+func init() {
+	usageCmd.Flags().StringVar(&usagePeriodFlag, "period", "today", "period: today, week, month or all")
+	usageCmd.Flags().StringVar(&usageByFlag, "by", "model", "break down by model, provider, project, user or tag")
+	usageCmd.Flags().StringVar(&usageProjectFlag, "project", "", "only count requests from this project path")
+	usageCmd.Flags().BoolVar(&usageCSVFlag, "csv", false, "print CSV")
+	costCmd.Flags().BoolVar(&costCSVFlag, "csv", false, "print CSV")
+}
+
+// usageSummary mirrors the fields of /api/v1/usage/summary used by zen usage.
+type usageSummary struct {
+	TotalInputTokens  int                    `json:"total_input_tokens"`
+	TotalOutputTokens int                    `json:"total_output_tokens"`
+	CacheHitRate      float64                `json:"cache_hit_rate"`
+	TotalCost         float64                `json:"total_cost"`
+	RequestCount      int                    `json:"request_count"`
+	ByProvider        map[string]*usageGroup `json:"by_provider"`
+	ByModel           map[string]*usageGroup `json:"by_model"`
+	ByProject         map[string]*usageGroup `json:"by_project"`
+	ByUser            map[string]*usageGroup `json:"by_user"`
+	ByTag             map[string]*usageGroup `json:"by_tag"`
+	Currency          string                 `json:"currency"`
+}
+
+// usageGroup is the usage of one model, provider, project, user or tag.
+type usageGroup struct {
+	InputTokens     int     `json:"input_tokens"`
+	OutputTokens    int     `json:"output_tokens"`
+	CacheReadTokens int     `json:"cache_read_tokens"`
+	CacheHitRate    float64 `json:"cache_hit_rate"`
+	Cost            float64 `json:"cost"`
+	RequestCount    int     `json:"request_count"`
+}
+
+// usagePeriods maps the --period names to the API's periods and labels.
+var usagePeriods = map[string]struct{ api, label string }{
+	"today": {"day", "today"},
+	"day":   {"day", "today"},
+	"week":  {"week", "the last week"},
+	"month": {"month", "the last month"},
+	"all":   {"all", "all time"},
+}
+
+// fetchUsageSummary gets a period's usage summary from the daemon.
+func fetchUsageSummary(period, project string) (*usageSummary, error) {
+	q := url.Values{"period": {period}}
+	if project != "" {
+		q.Set("project", project)
+	}
+	var s usageSummary
+	if err := fetchDaemonAPI("/api/v1/usage/summary?"+q.Encode(), &s); err != nil {
+		return nil, fmt.Errorf("cannot get usage from zend (start it with 'zen daemon start'): %w", err)
+	}
+	return &s, nil
+}
+
+func runUsage(out io.Writer, period, by, project string, asCSV bool) error {
+	p, ok := usagePeriods[period]
+	if !ok {
+		return fmt.Errorf("invalid period %q (must be today, week, month or all)", period)
+	}
+	s, err := fetchUsageSummary(p.api, project)
+	if err != nil {
+		return err
+	}
+	groups := map[string]map[string]*usageGroup{
+		"model": s.ByModel, "provider": s.ByProvider, "project": s.ByProject, "user": s.ByUser, "tag": s.ByTag,
+	}
+	rows, ok := groups[by]
+	if !ok {
+		return fmt.Errorf("invalid --by %q (must be model, provider, project, user or tag)", by)
+	}
+	names := make([]string, 0, len(rows))
+	for name := range rows {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if rows[names[i]].Cost != rows[names[j]].Cost {
+			return rows[names[i]].Cost > rows[names[j]].Cost
+		}
+		return names[i] < names[j]
+	})
+
+	if asCSV {
+		w := csv.NewWriter(out)
+		w.Write([]string{by, "requests", "input_tokens", "output_tokens", "cache_read_tokens", "cache_hit_rate", "cost", "currency"})
+		for _, name := range names {
+			g := rows[name]
+			w.Write([]string{name, strconv.Itoa(g.RequestCount), strconv.Itoa(g.InputTokens), strconv.Itoa(g.OutputTokens),
+				strconv.Itoa(g.CacheReadTokens), strconv.FormatFloat(g.CacheHitRate, 'f', 4, 64), strconv.FormatFloat(g.Cost, 'f', 4, 64), s.Currency})
+		}
+		w.Flush()
+		return w.Error()
+	}
+
+	fmt.Fprintf(out, "Usage %s: %d requests, %s in / %s out tokens, %s\n",
+		p.label, s.RequestCount, formatTokens(s.TotalInputTokens), formatTokens(s.TotalOutputTokens), config.FormatAmount(s.Currency, s.TotalCost))
+	if len(names) == 0 {
+		return nil
+	}
+	fmt.Fprintf(out, "\n  %-36s %8s %9s %9s %6s %12s\n", strings.ToUpper(by), "REQUESTS", "INPUT", "OUTPUT", "CACHE", "COST")
+	for _, name := range names {
+		g := rows[name]
+		label := name
+		if label == "" {
+			label = "(none)"
+		}
+		fmt.Fprintf(out, "  %-36s %8d %9s %9s %5.0f%% %12s\n", truncateLeft(label, 36), g.RequestCount,
+			formatTokens(g.InputTokens), formatTokens(g.OutputTokens), g.CacheHitRate*100, config.FormatAmount(s.Currency, g.Cost))
+	}
+	return nil
+}
+
+func runCost(out io.Writer, asCSV bool) error {
+	// Limits are optional, so a failed budget lookup leaves them unset
+	var budget struct {
+		DailyLimit     float64 `json:"daily_limit"`
+		DailyPercent   float64 `json:"daily_percent"`
+		WeeklyLimit    float64 `json:"weekly_limit"`
+		WeeklyPercent  float64 `json:"weekly_percent"`
+		MonthlyLimit   float64 `json:"monthly_limit"`
+		MonthlyPercent float64 `json:"monthly_percent"`
+		Message        string  `json:"message"`
+	}
+	fetchDaemonAPI("/api/v1/budget/status", &budget)
+
+	type costRow struct {
+		label   string
+		summary *usageSummary
+		limit   float64
+		percent float64 // of the limit, as the budget checker counts it
+	}
+	var rows []costRow
+	for _, p := range []struct {
+		api, label     string
+		limit, percent float64
+	}{
+		{"day", "today", budget.DailyLimit, budget.DailyPercent},
+		{"week", "week", budget.WeeklyLimit, budget.WeeklyPercent},
+		{"month", "month", budget.MonthlyLimit, budget.MonthlyPercent},
+	} {
+		s, err := fetchUsageSummary(p.api, "")
+		if err != nil {
+			return err
+		}
+		rows = append(rows, costRow{p.label, s, p.limit, p.percent})
+	}
+
+	if asCSV {
+		w := csv.NewWriter(out)
+		w.Write([]string{"period", "requests", "input_tokens", "output_tokens", "cost", "limit", "currency"})
+		for _, r := range rows {
+			w.Write([]string{r.label, strconv.Itoa(r.summary.RequestCount), strconv.Itoa(r.summary.TotalInputTokens),
+				strconv.Itoa(r.summary.TotalOutputTokens), strconv.FormatFloat(r.summary.TotalCost, 'f', 4, 64),
+				strconv.FormatFloat(r.limit, 'f', 2, 64), r.summary.Currency})
+		}
+		w.Flush()
+		return w.Error()
+	}
+
+	fmt.Fprintf(out, "  %-8s %8s %9s %9s %12s  %s\n", "PERIOD", "REQUESTS", "INPUT", "OUTPUT", "COST", "BUDGET")
+	for _, r := range rows {
+		s := r.summary
+		line := fmt.Sprintf("  %-8s %8d %9s %9s %12s", r.label, s.RequestCount,
+			formatTokens(s.TotalInputTokens), formatTokens(s.TotalOutputTokens), config.FormatAmount(s.Currency, s.TotalCost))
+		if r.limit > 0 {
+			line += fmt.Sprintf("  %s (%.0f%%)", config.FormatAmount(s.Currency, r.limit), r.percent)
+		} else {
+			line += "  -"
+		}
+		fmt.Fprintln(out, line)
+	}
+	if budget.Message != "" {
+		fmt.Fprintf(out, "\n%s\n", budget.Message)
+	}
+	return nil
+}
+
+// formatTokens renders a token count compactly, such as 1.2M or 560k.
+func formatTokens(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1e6)
+	case n >= 10_000:
+		return fmt.Sprintf("%dk", n/1000)
+	case n >= 1000:
+		return fmt.Sprintf("%.1fk", float64(n)/1e3)
+	}
+	return strconv.Itoa(n)
+}
+
+// truncateLeft shortens s to n runes, keeping its end.
+func truncateLeft(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return "…" + string(r[len(r)-n+1:])
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// serveUsageAPI fakes the daemon's usage and budget endpoints.
+func serveUsageAPI(t *testing.T) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/usage/summary":
+			cost := map[string]float64{"day": 1.5, "week": 7.25, "month": 30}[r.URL.Query().Get("period")]
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"total_input_tokens":  1_234_567,
+				"total_output_tokens": 5600,
+				"total_cost":          cost,
+				"request_count":       42,
+				"currency":            "USD",
+				"by_model": map[string]interface{}{
+					"claude-sonnet-4": map[string]interface{}{"input_tokens": 1000, "output_tokens": 500, "cost": 0.5, "request_count": 10},
+					"claude-opus-4":   map[string]interface{}{"input_tokens": 2000, "output_tokens": 800, "cost": 1.0, "request_count": 32},
+				},
+			})
+		case "/api/v1/budget/status":
+			json.NewEncoder(w).Encode(map[string]interface{}{"daily_limit": 10, "daily_percent": 15})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	setWebPortTo(t, srv)
+}
+
+func TestRunUsage(t *testing.T) {
+	setTestHome(t)
+	serveUsageAPI(t)
+
+	var buf bytes.Buffer
+	if err := runUsage(&buf, "today", "model", "", false); err != nil {
+		t.Fatalf("runUsage() error = %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "42 requests, 1.2M in / 5.6k out tokens") {
+		t.Errorf("missing totals:\n%s", out)
+	}
+	// The costliest model comes first
+	if opus, sonnet := strings.Index(out, "claude-opus-4"), strings.Index(out, "claude-sonnet-4"); opus < 0 || sonnet < opus {
+		t.Errorf("models not sorted by cost:\n%s", out)
+	}
+
+	buf.Reset()
+	if err := runUsage(&buf, "week", "model", "", true); err != nil {
+		t.Fatalf("runUsage(csv) error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "model,requests,") || !strings.HasPrefix(lines[1], "claude-opus-4,32,2000,800,") {
+		t.Errorf("unexpected CSV:\n%s", buf.String())
+	}
+
+	if err := runUsage(&buf, "year", "model", "", false); err == nil {
+		t.Error("runUsage() should reject an unknown period")
+	}
+	if err := runUsage(&buf, "today", "color", "", false); err == nil {
+		t.Error("runUsage() should reject an unknown --by")
+	}
+}
+
+func TestRunCost(t *testing.T) {
+	setTestHome(t)
+	serveUsageAPI(t)
+
+	var buf bytes.Buffer
+	if err := runCost(&buf, false); err != nil {
+		t.Fatalf("runCost() error = %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"today", "$1.50", "$10.00 (15%)", "week", "$7.25", "month", "$30.00"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	if err := runCost(&buf, true); err != nil {
+		t.Fatalf("runCost(csv) error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "today,42,1234567,5600,1.5000,10.00,USD") {
+		t.Errorf("unexpected CSV:\n%s", buf.String())
+	}
+}
+
+func TestFormatTokens(t *testing.T) {
+	for n, want := range map[int]string{0: "0", 999: "999", 1500: "1.5k", 56000: "56k", 2_500_000: "2.5M"} {
+		if got := formatTokens(n); got != want {
+			t.Errorf("formatTokens(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -12,9 +12,9 @@ import (
 )
 
 var useCmd = &cobra.Command{
-	Use:               "use <config> [flags] [-- cli args...]",
+	Use:               "use [provider] [flags] [-- cli args...]",
 	Short:             "Load config and exec CLI directly",
-	ValidArgsFunction: completeConfigNames,
+	ValidArgsFunction: completeFirstConfigName,
 	SilenceUsage:      true,
 	SilenceErrors:     true,
 	RunE:              runUse,
@@ -24,30 +24,42 @@ var useClientFlag string
 var useYesFlag bool
 
 func init() {
-	useCmd.Flags().StringVarP(&useClientFlag, "client", "c", "", "client to use (claude, codex, opencode)")
-	useCmd.Flags().BoolVarP(&useYesFlag, "yes", "y", false, "auto-approve CLI permissions (claude --permission-mode bypassPermissions, codex -a never)")
+	useCmd.Flags().StringVarP(&useClientFlag, "client", "c", "", "client to use (claude, codex, opencode, gemini, aider)")
+	useCmd.Flags().BoolVarP(&useYesFlag, "yes", "y", false, "auto-approve CLI permissions (claude --permission-mode bypassPermissions, codex -a never, gemini --yolo, aider --yes-always)")
 	useCmd.Flags().String("cli", "", "alias for --client (deprecated)")
 	useCmd.Flags().Lookup("cli").Hidden = true
+	useCmd.RegisterFlagCompletionFunc("client", completeClientNames)
 }
 
 func runUse(cmd *cobra.Command, args []string) error {
 	available := config.ProviderNames()
 
-	if len(args) == 0 {
-		fmt.Println("Usage: zen use <provider> [flags] [-- cli args...]")
-		if len(available) > 0 {
-			fmt.Printf("\nAvailable providers: %s\n", strings.Join(available, ", "))
-		} else {
-			fmt.Println("\nNo providers configured. Run 'zen config' to set up providers.")
+	// Without a provider name, before or without --, pick one
+	dashIdx := cmd.ArgsLenAtDash()
+	if len(args) == 0 || dashIdx == 0 {
+		name, ok, err := pickName("Use provider", available, "")
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("Usage: zen use <provider> [flags] [-- cli args...]")
+			if len(available) > 0 {
+				fmt.Printf("\nAvailable providers: %s\n", strings.Join(available, ", "))
+			} else {
+				fmt.Println("\nNo providers configured. Run 'zen config' to set up providers.")
+			}
+			return nil
+		}
+		args = append([]string{name}, args...)
+		if dashIdx == 0 {
+			dashIdx = 1
 		}
-		return nil
 	}
 
 	configName := args[0]
 	cliArgs := args[1:]
 
 	// Handle -- separator: split args at ArgsLenAtDash
-	dashIdx := cmd.ArgsLenAtDash()
 	if dashIdx >= 0 {
 		// Everything before -- is zen args (provider name), everything after is client args
 		cliArgs = args[dashIdx:]
@@ -98,8 +110,3 @@ func runUse(cmd *cobra.Command, args []string) error {
 	argv := append([]string{clientBin}, cliArgs...)
 	return syscall.Exec(clientPath, argv, os.Environ())
 }
-
-func completeConfigNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-	names := config.ProviderNames()
-	return names, cobra.ShellCompDirectiveNoFileComp
-}
@@ -1,6 +1,18 @@
 package agent
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -46,6 +58,29 @@ func TestObservatory_KillSession(t *testing.T) {
 	}
 }
 
+func TestObservatory_OnSessionChange(t *testing.T) {
+	obs := NewObservatory(&config.ObservatoryConfig{Enabled: true, StuckThreshold: 2})
+	var got []string
+	obs.OnSessionChange(func(c SessionChange) {
+		got = append(got, c.ID+":"+c.Status)
+	})
+
+	obs.RegisterSession("s1", "default", "claude", "")
+	obs.RecordRequest("s1", 10, 0.01, nil) // still active, no change
+	obs.RecordRequest("s1", 0, 0, errors.New("overloaded"))
+	obs.RecordRequest("s1", 0, 0, errors.New("overloaded"))
+	obs.PauseSession("s1")
+	obs.ResumeSession("s1")
+	obs.KillSession("s1")
+	obs.RemoveSession("s1")
+	obs.RemoveSession("s1") // already gone, no change
+
+	want := []string{"s1:active", "s1:stuck", "s1:paused", "s1:active", "s1:killed", "s1:removed"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("changes = %v, want %v", got, want)
+	}
+}
+
 func TestGuardrails_CheckRequest(t *testing.T) {
 	gr := NewGuardrails(&config.GuardrailsConfig{
 		Enabled:            true,
@@ -73,6 +108,52 @@ func TestGuardrails_CheckRequest(t *testing.T) {
 	}
 }
 
+func TestGuardrails_SpendSpike(t *testing.T) {
+	gr := NewGuardrails(&config.GuardrailsConfig{
+		Enabled:        true,
+		SpikeMultiple:  4,
+		SpikeWindowMin: 5,
+	})
+	start := time.Now()
+	at := func(min int) time.Time { return start.Add(time.Duration(min) * time.Minute) }
+
+	// A burst before the usual rate is learned is not flagged
+	if a := gr.RecordUsage("new", 5, 500000, start); a != nil {
+		t.Errorf("unlearned session flagged: %+v", a)
+	}
+
+	// Three windows at $0.10 and 10k tokens a minute
+	for min := 0; min <= 15; min++ {
+		if a := gr.RecordUsage("s1", 0.1, 10000, at(min)); a != nil {
+			t.Fatalf("steady spending flagged at minute %d: %+v", min, a)
+		}
+	}
+
+	a := gr.RecordUsage("s1", 2, 10000, at(16))
+	if a == nil {
+		t.Fatal("expected a spike")
+	}
+	if a.Reason != "cost" || a.BaselineCostRate < 0.099 || a.BaselineCostRate > 0.101 || a.CostRate < 0.41 {
+		t.Errorf("unexpected anomaly: %+v", a)
+	}
+	if a := gr.RecordUsage("s1", 2, 10000, at(17)); a != nil {
+		t.Error("a window should be reported once")
+	}
+
+	// Token spikes count too, and the flagged window was not learned
+	if a := gr.RecordUsage("s1", 0.1, 500000, at(20)); a == nil || a.Reason != "tokens" {
+		t.Errorf("expected a token spike, got %+v", a)
+	}
+
+	if total := gr.GetSpending("s1"); total < 5.7 || total > 5.8 {
+		t.Errorf("spending = %v, want 5.7", total)
+	}
+	gr.ResetSpending("s1")
+	if a := gr.RecordUsage("s1", 5, 500000, at(30)); a != nil {
+		t.Error("reset session should relearn its rate")
+	}
+}
+
 func TestGuardrails_CheckSensitiveOperation(t *testing.T) {
 	gr := NewGuardrails(&config.GuardrailsConfig{
 		Enabled:            true,
@@ -92,6 +173,78 @@ func TestGuardrails_CheckSensitiveOperation(t *testing.T) {
 	}
 }
 
+func TestGuardrails_Policy(t *testing.T) {
+	gr := NewGuardrails(&config.GuardrailsConfig{
+		Enabled: true,
+		Policy: []*config.GuardrailRule{
+			{Name: "project-only", Action: config.GuardrailAsk, Tools: []string{"Edit", "Write"}, AllowPaths: []string{"/work/app/**"}},
+			{Name: "secrets", Action: config.GuardrailBlock, DenyPaths: []string{".env", "/etc"}},
+			{Name: "dangerous", Action: config.GuardrailBlock, DenyCommands: []string{`rm\s+-rf\s+/`}},
+			{Name: "main", Action: config.GuardrailAsk, ProtectedBranches: []string{"main"}},
+			{Name: "audit-git", Action: config.GuardrailLog, DenyCommands: []string{`^git `}},
+		},
+	})
+	call := func(name, input string) ToolCall {
+		return ToolCall{Name: name, Input: []byte(input)}
+	}
+
+	tests := []struct {
+		name   string
+		call   ToolCall
+		action string // "" when no rule matches
+		rule   string
+	}{
+		{"edit in project", call("Edit", `{"file_path":"src/main.go"}`), "", ""},
+		{"edit outside project", call("Edit", `{"file_path":"/work/other/main.go"}`), config.GuardrailAsk, "project-only"},
+		{"read outside project", call("Read", `{"file_path":"/work/other/main.go"}`), "", ""},
+		{"edit .env", call("Edit", `{"file_path":"config/.env"}`), config.GuardrailBlock, "secrets"},
+		{"read under /etc", call("Read", `{"file_path":"/etc/hosts"}`), config.GuardrailBlock, "secrets"},
+		{"cat .env", call("Bash", `{"command":"cat ./.env"}`), config.GuardrailBlock, "secrets"},
+		{"apply_patch outside", call("apply_patch", `{"input":"*** Begin Patch\n*** Update File: /etc/passwd\n"}`), config.GuardrailBlock, "secrets"},
+		{"rm -rf /", call("Bash", `{"command":"rm -rf /tmp/x"}`), config.GuardrailBlock, "dangerous"},
+		{"codex shell", call("shell", `{"command":["bash","-lc","rm -rf /"]}`), config.GuardrailBlock, "dangerous"},
+		{"push to main", call("Bash", `{"command":"go test ./... && git push origin HEAD:main"}`), config.GuardrailAsk, "main"},
+		{"delete main", call("Bash", `{"command":"git branch -D main"}`), config.GuardrailAsk, "main"},
+		{"push feature", call("Bash", `{"command":"git push origin feature"}`), config.GuardrailLog, "audit-git"},
+		{"checkout main", call("Bash", `{"command":"git checkout main"}`), config.GuardrailLog, "audit-git"},
+		{"harmless", call("Bash", `{"command":"ls -la"}`), "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := gr.CheckToolCall("s1", "/work/app", tt.call)
+			if tt.action == "" {
+				if d != nil {
+					t.Fatalf("decision = %+v, want none", d)
+				}
+				return
+			}
+			if d == nil || d.Action != tt.action || d.Rule != tt.rule {
+				t.Fatalf("decision = %+v, want %s by %s", d, tt.action, tt.rule)
+			}
+		})
+	}
+
+	hits := make(map[string]int)
+	for _, s := range gr.GetPolicyStats() {
+		hits[s.Name] = s.Hits
+	}
+	want := map[string]int{"project-only": 1, "secrets": 4, "dangerous": 2, "main": 2, "audit-git": 3}
+	for name, n := range want {
+		if hits[name] != n {
+			t.Errorf("hits[%s] = %d, want %d", name, hits[name], n)
+		}
+	}
+	if ops := gr.GetSessionOperations("s1"); len(ops) == 0 || ops[0].Type != SensitiveOpPolicy {
+		t.Errorf("operations = %+v, want policy operations", ops)
+	}
+
+	// Hit counts survive a config update for rules that keep their name
+	gr.UpdateConfig(&config.GuardrailsConfig{Enabled: true, Policy: []*config.GuardrailRule{{Name: "main", Action: config.GuardrailBlock}}})
+	if stats := gr.GetPolicyStats(); len(stats) != 1 || stats[0].Hits != 2 || stats[0].Action != config.GuardrailBlock {
+		t.Errorf("after update: %+v", stats)
+	}
+}
+
 func TestCoordinator_AcquireLock(t *testing.T) {
 	coord := NewCoordinator(&config.CoordinatorConfig{
 		Enabled:        true,
@@ -132,6 +285,187 @@ func TestCoordinator_AcquireLock(t *testing.T) {
 	}
 }
 
+func TestCoordinator_ScopedLocks(t *testing.T) {
+	coord := NewCoordinator(&config.CoordinatorConfig{
+		Enabled:        true,
+		LockTimeoutSec: 300,
+	})
+
+	lock, err := coord.Acquire("src/api/**", "session-1", LockOptions{TaskID: "t1", Reason: "rewrite"})
+	if err != nil {
+		t.Fatalf("Acquire() error: %v", err)
+	}
+	if lock.Scope != LockScopeGlob || lock.TaskID != "t1" || lock.Reason != "rewrite" {
+		t.Errorf("lock = %+v", lock)
+	}
+
+	tests := []struct {
+		path     string
+		conflict bool
+	}{
+		{"src/api/users.go", true},
+		{"src/api/v2/", true},
+		{"src/", true},
+		{"src/*/handlers.go", true},
+		{"src/web/app.go", false},
+		{"src/apiv2/", false},
+		{"docs/**", false},
+	}
+	for _, tt := range tests {
+		_, err := coord.Acquire(tt.path, "session-2", LockOptions{})
+		var conflict *LockConflictError
+		if got := errors.As(err, &conflict); got != tt.conflict {
+			t.Errorf("Acquire(%q) conflict = %v, want %v (err %v)", tt.path, got, tt.conflict, err)
+		}
+		coord.ReleaseLock(tt.path, "session-2")
+	}
+
+	// The holder may lock inside its own scope
+	if _, err := coord.Acquire("src/api/users.go", "session-1", LockOptions{}); err != nil {
+		t.Errorf("holder Acquire() error: %v", err)
+	}
+	if got := coord.LocksCovering("src/api/users.go"); len(got) != 2 {
+		t.Errorf("LocksCovering() = %d locks, want 2", len(got))
+	}
+
+	// A directory lock is distinct from a file lock on the same path
+	if _, err := coord.Acquire("docs/", "session-2", LockOptions{}); err != nil {
+		t.Fatalf("Acquire(docs/) error: %v", err)
+	}
+	if l := coord.GetLock("docs/"); l == nil || l.Scope != LockScopeDir {
+		t.Errorf("GetLock(docs/) = %+v", l)
+	}
+	if _, err := coord.Acquire("docs/guide/intro.md", "session-1", LockOptions{}); err == nil {
+		t.Error("expected conflict with directory lock")
+	}
+	if !coord.ReleaseLock("docs/", "session-2") {
+		t.Error("expected to release directory lock")
+	}
+
+	if _, err := coord.Acquire("src/[", "session-1", LockOptions{}); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
+
+func TestCoordinator_LockQueue(t *testing.T) {
+	coord := NewCoordinator(&config.CoordinatorConfig{
+		Enabled:        true,
+		LockTimeoutSec: 300,
+	})
+	ctx := context.Background()
+
+	if _, err := coord.Acquire("src/", "session-1", LockOptions{}); err != nil {
+		t.Fatalf("Acquire() error: %v", err)
+	}
+
+	// Without a wait the request fails at once
+	if _, err := coord.AcquireWait(ctx, "src/a.go", "session-2", LockOptions{}, 0); err == nil {
+		t.Fatal("expected conflict")
+	}
+
+	// A wait times out
+	if _, err := coord.AcquireWait(ctx, "src/a.go", "session-2", LockOptions{}, 20*time.Millisecond); !errors.Is(err, ErrLockWaitTimeout) {
+		t.Fatalf("err = %v, want ErrLockWaitTimeout", err)
+	}
+	if len(coord.GetWaiting()) != 0 {
+		t.Fatal("timed out request still queued")
+	}
+
+	// Queued requests are granted in FIFO order once the lock is released
+	order := make(chan string, 2)
+	for _, s := range []string{"session-2", "session-3"} {
+		go func() {
+			if _, err := coord.AcquireWait(ctx, "src/a.go", s, LockOptions{}, 5*time.Second); err != nil {
+				t.Errorf("%s: AcquireWait() error: %v", s, err)
+			}
+			order <- s
+			coord.ReleaseLock("src/a.go", s)
+		}()
+		waitFor(t, func() bool { return len(coord.GetWaiting()) == map[string]int{"session-2": 1, "session-3": 2}[s] })
+	}
+	waits := coord.GetWaiting()
+	if waits[1].SessionID != "session-3" || len(waits[1].BlockedBy) != 2 {
+		t.Errorf("waits = %+v", waits)
+	}
+
+	// A new request may not jump the queue
+	if _, err := coord.Acquire("src/a.go", "session-4", LockOptions{}); err == nil {
+		t.Error("expected a queued request to block a new one")
+	}
+
+	coord.ReleaseLock("src/", "session-1")
+	if first, second := <-order, <-order; first != "session-2" || second != "session-3" {
+		t.Errorf("granted %s then %s", first, second)
+	}
+}
+
+func TestCoordinator_Deadlock(t *testing.T) {
+	for _, policy := range []string{config.DeadlockAbortYoungest, config.DeadlockNotify} {
+		t.Run(policy, func(t *testing.T) {
+			coord := NewCoordinator(&config.CoordinatorConfig{
+				Enabled:        true,
+				LockTimeoutSec: 300,
+				DeadlockPolicy: policy,
+			})
+			reported := make(chan Deadlock, 4)
+			coord.OnDeadlock(func(d Deadlock) { reported <- d })
+			ctx := context.Background()
+
+			coord.Acquire("a.go", "older", LockOptions{})
+			time.Sleep(time.Millisecond)
+			coord.Acquire("b.go", "younger", LockOptions{})
+
+			olderErr := make(chan error, 1)
+			go func() {
+				_, err := coord.AcquireWait(ctx, "b.go", "older", LockOptions{}, 300*time.Millisecond)
+				olderErr <- err
+			}()
+			waitFor(t, func() bool { return len(coord.GetWaiting()) == 1 })
+
+			_, err := coord.AcquireWait(ctx, "a.go", "younger", LockOptions{}, 300*time.Millisecond)
+			d := <-reported
+			if len(d.Sessions) != 2 || d.Policy != policy {
+				t.Errorf("deadlock = %+v", d)
+			}
+
+			if policy == config.DeadlockNotify {
+				if !errors.Is(err, ErrLockWaitTimeout) || !errors.Is(<-olderErr, ErrLockWaitTimeout) {
+					t.Errorf("notify: waits should time out, got %v", err)
+				}
+				if len(reported) != 0 {
+					t.Error("deadlock reported more than once")
+				}
+				return
+			}
+
+			var dl *DeadlockError
+			if !errors.As(err, &dl) || dl.Deadlock.Victim != "younger" {
+				t.Fatalf("err = %v, want deadlock aborting younger", err)
+			}
+			if err := <-olderErr; err != nil {
+				t.Errorf("older: AcquireWait() error: %v", err)
+			}
+			if l := coord.GetLock("b.go"); l == nil || l.SessionID != "older" {
+				t.Errorf("b.go lock = %+v", l)
+			}
+			if len(coord.GetDeadlocks()) != 1 {
+				t.Errorf("deadlocks = %+v", coord.GetDeadlocks())
+			}
+		})
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
 func TestCoordinator_RecordChange(t *testing.T) {
 	coord := NewCoordinator(&config.CoordinatorConfig{Enabled: true})
 
@@ -146,6 +480,129 @@ func TestCoordinator_RecordChange(t *testing.T) {
 	}
 }
 
+func TestUnifiedDiff(t *testing.T) {
+	before := fileSnapshot{exists: true, data: []byte("a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n")}
+	after := fileSnapshot{exists: true, data: []byte("a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm")}
+	want := `--- a/x.txt
++++ b/x.txt
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -10,3 +10,4 @@
+ j
+ k
+ l
++m
+\ No newline at end of file
+`
+	if got := unifiedDiff("x.txt", before, after); got != want {
+		t.Errorf("diff =\n%s\nwant\n%s", got, want)
+	}
+
+	created := unifiedDiff("new.txt", fileSnapshot{}, fileSnapshot{exists: true, data: []byte("hi\n")})
+	if created != "--- /dev/null\n+++ b/new.txt\n@@ -0,0 +1 @@\n+hi\n" {
+		t.Errorf("create diff = %q", created)
+	}
+	if d := unifiedDiff("x.txt", before, before); d != "" {
+		t.Errorf("unchanged file diff = %q", d)
+	}
+}
+
+func TestCoordinator_ChangeReview(t *testing.T) {
+	coord := NewCoordinator(&config.CoordinatorConfig{Enabled: true})
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.go")
+	os.WriteFile(file, []byte("package main\n"), 0644)
+
+	edit := func(id, name, input, content string, failed bool) []*FileChange {
+		coord.WatchEdit("s1", dir, ToolCall{ID: id, Name: name, Input: []byte(input)})
+		if content == "" {
+			os.Remove(file)
+		} else {
+			os.WriteFile(file, []byte(content), 0644)
+		}
+		return coord.FinishEdit("s1", ToolResult{ToolUseID: id, IsError: failed})
+	}
+
+	changes := edit("t1", "Edit", `{"file_path": "main.go"}`, "package main\n\nfunc main() {}\n", false)
+	if len(changes) != 1 {
+		t.Fatalf("got %d changes, want 1", len(changes))
+	}
+	first := changes[0]
+	if first.ChangeType != ChangeTypeModify || first.Status != ChangePending || !first.Revertable || first.Summary != "Edit: +2 -0 lines" {
+		t.Errorf("change = %+v", first)
+	}
+	if !strings.Contains(first.Diff, "--- a/main.go\n+++ b/main.go\n") || !strings.Contains(first.Diff, "+func main() {}\n") {
+		t.Errorf("diff = %q", first.Diff)
+	}
+	if changes := edit("t2", "Write", `{"file_path": "main.go"}`, "broken", true); changes != nil {
+		t.Errorf("failed call recorded %+v", changes)
+	}
+	if changes := edit("t3", "Read", `{"file_path": "main.go"}`, "package main\n\nfunc main() {}\n", false); changes != nil {
+		t.Errorf("non-edit tool recorded %+v", changes)
+	}
+	second := edit("t4", "Write", `{"file_path": "main.go"}`, "package main\n\nfunc main() { run() }\n", false)[0]
+
+	// Reverting the latest change restores the content before it
+	if _, err := coord.RevertChange(second.ID); err != nil {
+		t.Fatalf("revert: %v", err)
+	}
+	if data, _ := os.ReadFile(file); string(data) != "package main\n\nfunc main() {}\n" {
+		t.Errorf("after revert file = %q", data)
+	}
+	if _, err := coord.RevertChange(second.ID); !errors.Is(err, ErrChangeReverted) {
+		t.Errorf("second revert error = %v", err)
+	}
+
+	// Outside a git work tree, a file changed since can't be reverted
+	os.WriteFile(file, []byte("package other\n"), 0644)
+	if _, err := coord.RevertChange(first.ID); !errors.Is(err, ErrRevertConflict) {
+		t.Errorf("conflicting revert error = %v", err)
+	}
+	if _, err := coord.RevertChange("chg-missing"); !errors.Is(err, ErrChangeNotFound) {
+		t.Errorf("missing change error = %v", err)
+	}
+
+	if approved := coord.ApproveChanges("s1", nil); len(approved) != 1 || approved[0].ID != first.ID {
+		t.Errorf("approved = %+v", approved)
+	}
+	if got := coord.ListChanges("s1", ChangeApproved, 0); len(got) != 1 || got[0].ReviewedAt == nil {
+		t.Errorf("approved changes = %+v", got)
+	}
+}
+
+func TestCoordinator_RevertWithGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	coord := NewCoordinator(&config.CoordinatorConfig{Enabled: true})
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+	project := filepath.Join(dir, "svc")
+	file := filepath.Join(project, "notes.txt")
+	os.MkdirAll(project, 0755)
+	os.WriteFile(file, []byte("one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\n"), 0644)
+
+	coord.WatchEdit("s1", project, ToolCall{ID: "t1", Name: "Edit", Input: []byte(`{"file_path": "notes.txt"}`)})
+	os.WriteFile(file, []byte("ONE\ntwo\nthree\nfour\nfive\nsix\nseven\neight\n"), 0644)
+	change := coord.FinishEdit("s1", ToolResult{ToolUseID: "t1"})[0]
+
+	// A later edit to other lines is kept when the change is reverted
+	os.WriteFile(file, []byte("ONE\ntwo\nthree\nfour\nfive\nsix\nseven\nEIGHT\n"), 0644)
+	if _, err := coord.RevertChange(change.ID); err != nil {
+		t.Fatalf("revert: %v", err)
+	}
+	if data, _ := os.ReadFile(file); string(data) != "one\ntwo\nthree\nfour\nfive\nsix\nseven\nEIGHT\n" {
+		t.Errorf("after revert file = %q", data)
+	}
+}
+
 func TestTaskQueue_AddAndGetTask(t *testing.T) {
 	tq := NewTaskQueue(&config.TaskQueueConfig{
 		Enabled:    true,
@@ -237,6 +694,228 @@ func TestRuntime_NewRuntime(t *testing.T) {
 	}
 }
 
+func TestRuntime_MultiAgent(t *testing.T) {
+	coord := NewCoordinator(&config.CoordinatorConfig{Enabled: true, LockWaitSec: 5})
+	var mu sync.Mutex
+	routes := make(map[string]string) // session -> profile/model
+	var lockless []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		profile, session := parts[0], parts[1]
+		var req struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if !strings.HasPrefix(r.Header.Get("X-Zen-Tags"), "task:rt-") {
+			t.Errorf("request not tagged with the task: %q", r.Header.Get("X-Zen-Tags"))
+		}
+
+		text := "VALID"
+		switch {
+		case strings.Contains(session, "-planner-"):
+			text = `[{"step": "write the parser", "files": ["parse.go"]}, {"step": "wire the parser", "files": ["parse.go", "main.go"]}]`
+		case strings.Contains(session, "-implementer-"):
+			text = "done by " + session
+			if len(coord.GetSessionLocks(session)) == 0 {
+				mu.Lock()
+				lockless = append(lockless, session)
+				mu.Unlock()
+			}
+		}
+		mu.Lock()
+		routes[session] = profile + "/" + req.Model
+		mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"content": []map[string]string{{"type": "text", "text": text}},
+			"usage":   map[string]int{"input_tokens": 10, "output_tokens": 5},
+		})
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	port, _ := strconv.Atoi(u.Port())
+
+	rt := NewRuntime(&config.RuntimeConfig{
+		Enabled:     true,
+		MaxParallel: 2,
+		Roles: map[string]*config.RuntimeRoleConfig{
+			RolePlanner: {Profile: "thinking", Model: "claude-opus"},
+		},
+	}, port)
+	rt.coord = coord
+
+	task, err := rt.StartMultiAgentTask("add a parser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	done := func() bool {
+		rt.mu.RLock()
+		defer rt.mu.RUnlock()
+		return task.Status == RuntimeStatusCompleted || task.Status == RuntimeStatusFailed
+	}
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatal("task did not finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+	if task.Status != RuntimeStatusCompleted || !task.Result.Success {
+		t.Fatalf("task = %s, result %+v", task.Status, task.Result)
+	}
+	if len(task.SubAgents) != 4 {
+		t.Fatalf("got %d sub-agents, want planner, 2 implementers and reviewer", len(task.SubAgents))
+	}
+	planner := task.SubAgents[0]
+	if planner.Role != RolePlanner || routes[planner.SessionID] != "thinking/claude-opus" {
+		t.Errorf("planner ran as %s on %q", planner.Role, routes[planner.SessionID])
+	}
+	for _, sub := range task.SubAgents[1:3] {
+		if sub.Role != RoleImplementer || routes[sub.SessionID] != "default/"+rt.config.ExecutionModel {
+			t.Errorf("implementer ran as %s on %q", sub.Role, routes[sub.SessionID])
+		}
+		if sub.Status != RuntimeStatusCompleted || sub.Tokens != 15 {
+			t.Errorf("implementer %s = %s with %d tokens", sub.ID, sub.Status, sub.Tokens)
+		}
+	}
+	if len(lockless) > 0 {
+		t.Errorf("implementers ran without locks: %v", lockless)
+	}
+	if locks := coord.GetAllLocks(); len(locks) != 0 {
+		t.Errorf("locks left behind: %d", len(locks))
+	}
+	if task.TotalTokens != 60 || task.Result.Tokens != 60 {
+		t.Errorf("task tokens = %d, result %d, want 60", task.TotalTokens, task.Result.Tokens)
+	}
+	if !strings.Contains(task.Result.Output, "## Step 2: wire the parser\n\ndone by runtime-"+task.ID+"-implementer-") {
+		t.Errorf("merged output = %q", task.Result.Output)
+	}
+}
+
+func TestExecBackends(t *testing.T) {
+	ctx := context.Background()
+	docker := newExecBackend("/work/app", &config.SandboxConfig{Backend: config.SandboxDocker, Image: "golang:1.23", Args: []string{"--memory=2g"}})
+	args := strings.Join(docker.Command(ctx, "go test ./...").Args, " ")
+	for _, want := range []string{"docker run --rm -i -v /work/app:/workspace -w /workspace", "--network none", "--memory=2g golang:1.23 sh -c go test ./..."} {
+		if !strings.Contains(args, want) {
+			t.Errorf("docker command %q lacks %q", args, want)
+		}
+	}
+
+	firejail := newExecBackend("/work/app", &config.SandboxConfig{Backend: config.SandboxFirejail, Network: true})
+	args = strings.Join(firejail.Command(ctx, "make").Args, " ")
+	if args != "firejail --quiet --whitelist=/work/app sh -c make sh" {
+		t.Errorf("firejail command = %q", args)
+	}
+
+	// The host backend runs in the project directory
+	dir := t.TempDir()
+	e := &toolExecutor{backend: newExecBackend(dir, nil), timeout: 5 * time.Second}
+	if out, failed := e.Run(ctx, "write_file", json.RawMessage(`{"path": "pkg/hello.txt", "content": "hi\n"}`)); failed {
+		t.Fatalf("write_file failed: %s", out)
+	}
+	if out, failed := e.Run(ctx, "read_file", json.RawMessage(`{"path": "pkg/hello.txt"}`)); failed || out != "hi\n" {
+		t.Errorf("read_file = %q, failed %v", out, failed)
+	}
+	if out, failed := e.Run(ctx, "bash", json.RawMessage(`{"command": "ls pkg && exit 3"}`)); !failed || !strings.HasPrefix(out, "hello.txt\n") {
+		t.Errorf("bash = %q, failed %v", out, failed)
+	}
+	for _, p := range []string{"../escape", "/etc/passwd"} {
+		if _, failed := e.Run(ctx, "read_file", json.RawMessage(`{"path": "`+p+`"}`)); !failed {
+			t.Errorf("read_file %s outside the project succeeded", p)
+		}
+	}
+}
+
+func TestRuntime_ToolLoop(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	config.ResetDefaultStore()
+	t.Cleanup(config.ResetDefaultStore)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []json.RawMessage `json:"messages"`
+			Tools    []json.RawMessage `json:"tools"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+
+		content := []map[string]interface{}{{"type": "text", "text": "VALID"}}
+		switch {
+		case strings.Contains(string(req.Messages[0]), "task planner"):
+			content[0]["text"] = `["write the greeting"]`
+		case len(req.Tools) > 0 && len(req.Messages) == 1:
+			content = []map[string]interface{}{{
+				"type": "tool_use", "id": "toolu_1", "name": "write_file",
+				"input": map[string]string{"path": "greeting.txt", "content": "hello"},
+			}}
+		case len(req.Tools) > 0:
+			if !strings.Contains(string(req.Messages[2]), "wrote 5 bytes") {
+				t.Errorf("tool result = %s", req.Messages[2])
+			}
+			content[0]["text"] = "written"
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"content": content,
+			"usage":   map[string]int{"input_tokens": 10, "output_tokens": 5},
+		})
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	port, _ := strconv.Atoi(u.Port())
+
+	dir := t.TempDir()
+	rt := NewRuntime(&config.RuntimeConfig{Enabled: true}, port)
+	if _, err := rt.StartTaskWithOptions("greet", RuntimeTaskOptions{ProjectPath: "relative"}); err == nil {
+		t.Error("a relative project path should be refused")
+	}
+	task, err := rt.StartTaskWithOptions("greet", RuntimeTaskOptions{ProjectPath: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		rt.mu.RLock()
+		status := task.Status
+		rt.mu.RUnlock()
+		if status == RuntimeStatusCompleted || status == RuntimeStatusFailed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("task did not finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+	if task.Status != RuntimeStatusCompleted || task.Result.Output != "written" {
+		t.Fatalf("task = %s, result %+v", task.Status, task.Result)
+	}
+	if len(task.Turns) != 4 || task.TotalTokens != 60 {
+		t.Errorf("got %d turns and %d tokens, want 4 and 60", len(task.Turns), task.TotalTokens)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "greeting.txt")); err != nil || string(data) != "hello" {
+		t.Errorf("greeting.txt = %q, %v", data, err)
+	}
+}
+
+func TestParsePlan(t *testing.T) {
+	plan := parsePlan("Here you go:\n[\"one\", \"two\"]", "task")
+	if len(plan.Steps) != 2 || plan.Files != nil {
+		t.Errorf("string plan = %+v", plan)
+	}
+	plan = parsePlan(`[{"step": "one", "files": ["a.go"]}, {"step": "two"}]`, "task")
+	if len(plan.Steps) != 2 || len(plan.Files) != 2 || plan.Files[0][0] != "a.go" || plan.Files[1] != nil {
+		t.Errorf("detailed plan = %+v", plan)
+	}
+	if plan = parsePlan("no plan", "task"); len(plan.Steps) != 1 || plan.Steps[0] != "task" {
+		t.Errorf("fallback plan = %+v", plan)
+	}
+}
+
 func TestObservatory_StuckDetection(t *testing.T) {
 	obs := NewObservatory(&config.ObservatoryConfig{
 		Enabled:        true,
@@ -256,6 +935,183 @@ func TestObservatory_StuckDetection(t *testing.T) {
 	}
 }
 
+func TestObservatory_StuckHeuristics(t *testing.T) {
+	start := time.Now()
+	call := func(id, name, input string) ToolCall {
+		return ToolCall{ID: id, Name: name, Input: []byte(input)}
+	}
+
+	t.Run("loop", func(t *testing.T) {
+		obs := NewObservatory(&config.ObservatoryConfig{Enabled: true, LoopThreshold: 3})
+		for i, id := range []string{"t1", "t2", "t2", "t3"} { // t2 resent by a retry
+			obs.RecordActivity(Activity{
+				SessionID: "s1",
+				Timestamp: start.Add(time.Duration(i) * time.Second),
+				ToolCalls: []ToolCall{call(id, "Bash", `{"command":"go test"}`)},
+			})
+			if s := obs.GetSession("s1"); i < 3 && s.LikelyStuck {
+				t.Fatalf("stuck after %d calls: %s", i+1, s.StuckReason)
+			}
+		}
+		s := obs.GetSession("s1")
+		if !s.LikelyStuck || s.Status != SessionStatusStuck {
+			t.Fatalf("LikelyStuck = %v, Status = %s, want stuck", s.LikelyStuck, s.Status)
+		}
+		if want := "called Bash with identical arguments 3 times in a row"; s.StuckReason != want {
+			t.Errorf("StuckReason = %q, want %q", s.StuckReason, want)
+		}
+
+		obs.RecordActivity(Activity{SessionID: "s1", ToolCalls: []ToolCall{call("t4", "Bash", `{"command":"go vet"}`)}})
+		if s := obs.GetSession("s1"); s.LikelyStuck || s.Status != SessionStatusActive {
+			t.Errorf("after a different call: LikelyStuck = %v, Status = %s", s.LikelyStuck, s.Status)
+		}
+	})
+
+	t.Run("no progress", func(t *testing.T) {
+		obs := NewObservatory(&config.ObservatoryConfig{Enabled: true, NoProgressMin: 10, NoProgressTokens: 1000})
+		obs.RegisterSession("s1", "default", "claude", "")
+		obs.RecordActivity(Activity{SessionID: "s1", Timestamp: start.Add(5 * time.Minute), Tokens: 2000})
+		if s := obs.GetSession("s1"); s.LikelyStuck {
+			t.Fatalf("stuck after 5 minutes: %s", s.StuckReason)
+		}
+		obs.RecordActivity(Activity{SessionID: "s1", Timestamp: start.Add(11 * time.Minute), Tokens: 500})
+		s := obs.GetSession("s1")
+		if !s.LikelyStuck || !strings.HasSuffix(s.StuckReason, "minutes while using 2500 tokens") {
+			t.Fatalf("LikelyStuck = %v, StuckReason = %q", s.LikelyStuck, s.StuckReason)
+		}
+
+		obs.RecordActivity(Activity{
+			SessionID: "s1",
+			Timestamp: start.Add(12 * time.Minute),
+			Tokens:    100,
+			ToolCalls: []ToolCall{call("e1", "Edit", `{"file_path":"main.go"}`)},
+		})
+		if s := obs.GetSession("s1"); s.LikelyStuck || s.TokensSinceEdit != 0 {
+			t.Errorf("after an edit: LikelyStuck = %v, TokensSinceEdit = %d", s.LikelyStuck, s.TokensSinceEdit)
+		}
+	})
+
+	t.Run("paused session keeps status", func(t *testing.T) {
+		obs := NewObservatory(&config.ObservatoryConfig{Enabled: true, LoopThreshold: 1})
+		obs.RegisterSession("s1", "default", "claude", "")
+		obs.PauseSession("s1")
+		obs.RecordActivity(Activity{SessionID: "s1", ToolCalls: []ToolCall{call("t1", "Read", `{}`)}})
+		if s := obs.GetSession("s1"); !s.LikelyStuck || s.Status != SessionStatusPaused {
+			t.Errorf("LikelyStuck = %v, Status = %s, want paused and likely stuck", s.LikelyStuck, s.Status)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		obs := NewObservatory(&config.ObservatoryConfig{Enabled: false})
+		obs.RecordActivity(Activity{SessionID: "s1"})
+		if obs.GetSession("s1") != nil {
+			t.Error("disabled observatory registered a session")
+		}
+	})
+}
+
+func TestSessionReport(t *testing.T) {
+	t.Setenv("GOZEN_CONFIG_DIR", t.TempDir())
+	obs := NewObservatory(&config.ObservatoryConfig{Enabled: true})
+	coord := NewCoordinator(&config.CoordinatorConfig{Enabled: true})
+	tq := NewTaskQueue(&config.TaskQueueConfig{Enabled: true})
+	dir := t.TempDir()
+	start := time.Now()
+
+	obs.RecordActivity(Activity{SessionID: "s1", ProjectPath: dir, Timestamp: start, Tokens: 1000, Cost: 0.02, ToolCalls: []ToolCall{
+		{ID: "e1", Name: "Edit", Input: []byte(`{"file_path":"main.go"}`)},
+		{ID: "b1", Name: "Bash", Input: []byte(`{"command":"go test ./..."}`)},
+	}})
+	obs.RecordActivity(Activity{SessionID: "s1", ProjectPath: dir, Timestamp: start.Add(time.Minute), Tokens: 500, Cost: 0.01, ToolResults: []ToolResult{
+		{ToolUseID: "e1"},
+		{ToolUseID: "b1", IsError: true, Output: "--- FAIL: TestX\nFAIL\tpkg 0.1s"},
+	}})
+	obs.RecordRequest("s1", 0, 0, errors.New("upstream timeout"))
+	tq.AddTask("fix the parser", 1)
+	tq.GetNextTask("s1")
+
+	file := filepath.Join(dir, "main.go")
+	coord.WatchEdit("s1", dir, ToolCall{ID: "e1", Name: "Edit", Input: []byte(`{"file_path":"main.go"}`)})
+	os.WriteFile(file, []byte("package main\n"), 0644)
+	coord.FinishEdit("s1", ToolResult{ToolUseID: "e1"})
+
+	r := BuildSessionReport(obs, coord, tq, nil, "s1")
+	if r == nil {
+		t.Fatal("no report")
+	}
+	if r.Requests != 3 || r.Tokens != 1500 || r.ToolCalls != 2 || r.FailedToolCalls != 1 {
+		t.Errorf("report = %+v", r)
+	}
+	if len(r.Tasks) != 1 || r.Tasks[0].Description != "fix the parser" || r.Tasks[0].Source != "queue" {
+		t.Errorf("tasks = %+v", r.Tasks)
+	}
+	if len(r.Files) != 1 || r.Files[0].Path != filepath.ToSlash(file) || r.Files[0].Edits != 1 || r.Files[0].Added != 1 {
+		t.Errorf("files = %+v", r.Files)
+	}
+	if len(r.Tests) != 1 || r.Tests[0].Command != "go test ./..." || !r.Tests[0].Failed || r.Tests[0].Detail != "FAIL\tpkg 0.1s" {
+		t.Errorf("tests = %+v", r.Tests)
+	}
+	if len(r.Errors) != 1 || r.Errors[0] != "upstream timeout" {
+		t.Errorf("errors = %v", r.Errors)
+	}
+	if BuildSessionReport(obs, nil, nil, nil, "missing") != nil {
+		t.Error("report built for an unknown session")
+	}
+
+	if err := SaveSessionReport(r); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadSessionReport("s1")
+	if err != nil || loaded.Cost != r.Cost || len(loaded.Files) != 1 {
+		t.Errorf("loaded = %+v, %v", loaded, err)
+	}
+	if _, err := LoadSessionReport("s2"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing report error = %v", err)
+	}
+}
+
+func TestObservatory_Timeline(t *testing.T) {
+	obs := NewObservatory(&config.ObservatoryConfig{Enabled: true})
+	start := time.Now()
+	edit := ToolCall{ID: "e1", Name: "Edit", Input: []byte(`{"file_path":"main.go","old_string":"a"}`)}
+
+	obs.RecordActivity(Activity{SessionID: "s1", Timestamp: start})
+	obs.RecordActivity(Activity{SessionID: "s1", Timestamp: start.Add(2 * time.Second), ToolCalls: []ToolCall{edit}})
+	obs.RecordActivity(Activity{ // a retry resends the same call
+		SessionID:   "s1",
+		Timestamp:   start.Add(3 * time.Second),
+		ToolCalls:   []ToolCall{edit},
+		ToolResults: []ToolResult{{ToolUseID: "e1"}},
+		Compression: &Compression{BytesBefore: 9000, BytesAfter: 3000},
+	})
+
+	events, ok := obs.GetTimeline("s1", 0, 0)
+	if !ok {
+		t.Fatal("session not found")
+	}
+	var got []string
+	for _, e := range events {
+		got = append(got, e.Type)
+	}
+	want := []string{TimelineToolStarted, TimelineFileEdited, TimelineCompression, TimelineToolFinished}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+	if e := events[1]; e.Path != "main.go" || !e.Time.Equal(start) {
+		t.Errorf("file_edited = %+v", e)
+	}
+	if e := events[3]; e.DurationMs != 3000 || e.Failed {
+		t.Errorf("tool_call_finished = %+v, want 3000ms", e)
+	}
+
+	if events, _ := obs.GetTimeline("s1", 0, 1); len(events) != 1 || events[0].Seq != 4 {
+		t.Errorf("limit 1 = %+v, want the latest event", events)
+	}
+	if _, ok := obs.GetTimeline("missing", 0, 0); ok {
+		t.Error("GetTimeline found an unknown session")
+	}
+}
+
 type testError struct {
 	msg string
 }
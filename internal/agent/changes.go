@@ -0,0 +1,334 @@
+package agent
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// maxChangeContent is the largest file whose content a change keeps.
+	// Larger files are recorded without a diff and cannot be reverted.
+	maxChangeContent = 1 << 20
+	// maxWatchedEdits bounds the tool calls waiting for their results.
+	maxWatchedEdits = 256
+	// watchedEditTTL is how long a tool call's result is waited for.
+	watchedEditTTL = time.Hour
+)
+
+var (
+	ErrChangeNotFound = errors.New("change not found")
+	ErrChangeReverted = errors.New("change was already reverted")
+	ErrNotRevertable  = errors.New("change has no recorded content to revert to")
+	ErrRevertConflict = errors.New("file was changed since")
+)
+
+// fileSnapshot is a file's content at one moment.
+type fileSnapshot struct {
+	data     []byte
+	mode     fs.FileMode
+	exists   bool
+	tooLarge bool
+}
+
+// readSnapshot reads a file's content, if it is small enough to keep.
+func readSnapshot(path string) fileSnapshot {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return fileSnapshot{}
+	}
+	s := fileSnapshot{mode: info.Mode().Perm(), exists: true}
+	if info.Size() > maxChangeContent {
+		s.tooLarge = true
+		return s
+	}
+	if s.data, err = os.ReadFile(path); err != nil {
+		s.tooLarge = true
+	}
+	return s
+}
+
+func (s fileSnapshot) equal(o fileSnapshot) bool {
+	return s.exists == o.exists && !s.tooLarge && !o.tooLarge && bytes.Equal(s.data, o.data)
+}
+
+// restore writes the snapshot back, removing the file if it did not exist.
+func (s fileSnapshot) restore(path string) error {
+	if !s.exists {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, s.data, s.mode)
+}
+
+// watchedEdit is a file an edit tool call is about to change.
+type watchedEdit struct {
+	sessionID   string
+	projectPath string
+	path        string
+	tool        string
+	before      fileSnapshot
+	started     time.Time
+}
+
+// WatchEdit snapshots the files an edit tool call is about to change, so
+// that FinishEdit can record what it did. Only absolute paths, or paths
+// relative to a known project directory, are watched.
+func (c *Coordinator) WatchEdit(sessionID, projectPath string, call ToolCall) {
+	if !c.IsEnabled() || !editTools[call.Name] || call.ID == "" {
+		return
+	}
+	var edits []*watchedEdit
+	now := time.Now()
+	for _, p := range toolCallPaths(call, projectPath) {
+		p = filepath.FromSlash(p)
+		if !filepath.IsAbs(p) {
+			continue
+		}
+		edits = append(edits, &watchedEdit{
+			sessionID:   sessionID,
+			projectPath: projectPath,
+			path:        p,
+			tool:        call.Name,
+			before:      readSnapshot(p),
+			started:     now,
+		})
+	}
+	if len(edits) == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.watched == nil {
+		c.watched = make(map[string][]*watchedEdit)
+	}
+	for key, w := range c.watched {
+		if now.Sub(w[0].started) > watchedEditTTL {
+			delete(c.watched, key)
+		}
+	}
+	if len(c.watched) < maxWatchedEdits {
+		c.watched[sessionID+"\x00"+call.ID] = edits
+	}
+}
+
+// FinishEdit records the changes a watched tool call made once its result
+// is in. The files of a failed call are left unrecorded.
+func (c *Coordinator) FinishEdit(sessionID string, result ToolResult) []*FileChange {
+	key := sessionID + "\x00" + result.ToolUseID
+	c.mu.Lock()
+	edits := c.watched[key]
+	delete(c.watched, key)
+	c.mu.Unlock()
+	if result.IsError {
+		return nil
+	}
+
+	var changes []*FileChange
+	for _, w := range edits {
+		if ch := c.recordEdit(w.sessionID, w.projectPath, w.path, w.tool, w.before, readSnapshot(w.path)); ch != nil {
+			changes = append(changes, ch)
+		}
+	}
+	return changes
+}
+
+// recordEdit records a file's change from its content before and after,
+// with a unified diff of it. It returns nil if the file did not change.
+func (c *Coordinator) recordEdit(sessionID, projectPath, path, tool string, before, after fileSnapshot) *FileChange {
+	if !c.IsEnabled() || before.equal(after) || (!before.exists && !after.exists) {
+		return nil
+	}
+	change := &FileChange{
+		ID:          generateChangeID(),
+		Path:        path,
+		SessionID:   sessionID,
+		ProjectPath: projectPath,
+		ChangeType:  ChangeTypeModify,
+		Status:      ChangePending,
+		Revertable:  !before.tooLarge && !after.tooLarge,
+		Timestamp:   time.Now(),
+		before:      before,
+		after:       after,
+	}
+	switch {
+	case !before.exists:
+		change.ChangeType = ChangeTypeCreate
+	case !after.exists:
+		change.ChangeType = ChangeTypeDelete
+	}
+	if change.Revertable {
+		change.Diff = unifiedDiff(diffName(projectPath, path), before, after)
+		added, removed := diffStat(change.Diff)
+		change.Summary = fmt.Sprintf("%s: +%d -%d lines", tool, added, removed)
+	} else {
+		change.Summary = tool + ": file too large to diff"
+	}
+
+	c.mu.Lock()
+	c.addChangeLocked(change)
+	c.mu.Unlock()
+	cp := *change
+	return &cp
+}
+
+// addChangeLocked appends a change, keeping the last 500. It must be
+// called with c.mu held.
+func (c *Coordinator) addChangeLocked(change *FileChange) {
+	c.changes = append(c.changes, change)
+	if len(c.changes) > 500 {
+		c.changes = c.changes[len(c.changes)-500:]
+	}
+}
+
+// diffName is the path a diff shows for a file: relative to the project
+// directory when the file is inside it.
+func diffName(projectPath, path string) string {
+	if projectPath != "" {
+		if rel, err := filepath.Rel(projectPath, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return strings.TrimPrefix(filepath.ToSlash(path), "/")
+}
+
+// GetChange returns a change by ID, or nil.
+func (c *Coordinator) GetChange(id string) *FileChange {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, change := range c.changes {
+		if change.ID == id {
+			cp := *change
+			return &cp
+		}
+	}
+	return nil
+}
+
+// ApproveChanges marks a session's pending changes approved: those with
+// the given IDs, or all of them when ids is empty. It returns the changes
+// it approved.
+func (c *Coordinator) ApproveChanges(sessionID string, ids []string) []*FileChange {
+	want := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		want[id] = true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	approved := make([]*FileChange, 0)
+	for _, change := range c.changes {
+		if change.SessionID != sessionID || change.Status != ChangePending || (len(ids) > 0 && !want[change.ID]) {
+			continue
+		}
+		change.Status = ChangeApproved
+		change.ReviewedAt = &now
+		cp := *change
+		approved = append(approved, &cp)
+	}
+	return approved
+}
+
+// RevertChange puts a changed file back as it was before the change. When
+// the file still holds what the change left, its earlier content is
+// restored. When it has changed since and the project is a git work tree,
+// the change's diff is reverse-applied with git, which succeeds as long as
+// the later edits don't touch the same lines; otherwise it fails with
+// ErrRevertConflict.
+func (c *Coordinator) RevertChange(id string) (*FileChange, error) {
+	c.mu.RLock()
+	var change *FileChange
+	for _, ch := range c.changes {
+		if ch.ID == id {
+			change = ch
+			break
+		}
+	}
+	var status string
+	if change != nil {
+		status = change.Status
+	}
+	c.mu.RUnlock()
+
+	switch {
+	case change == nil:
+		return nil, ErrChangeNotFound
+	case status == ChangeReverted:
+		return nil, ErrChangeReverted
+	case !change.Revertable:
+		return nil, ErrNotRevertable
+	}
+
+	current := readSnapshot(change.Path)
+	switch {
+	case current.equal(change.after):
+		if err := change.before.restore(change.Path); err != nil {
+			return nil, err
+		}
+	case current.exists && change.before.exists && change.after.exists && !isBinary(change.after.data):
+		if err := gitReverse(change); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%s: %w", change.Path, ErrRevertConflict)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	change.Status = ChangeReverted
+	change.ReviewedAt = &now
+	cp := *change
+	return &cp, nil
+}
+
+// gitReverse reverse-applies a change's diff to the working tree of the git
+// repository holding the file.
+func gitReverse(change *FileChange) error {
+	dir := filepath.Dir(change.Path)
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return fmt.Errorf("%s: %w", change.Path, ErrRevertConflict)
+	}
+	top := strings.TrimSpace(string(out))
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		// rev-parse reports the resolved path
+		dir = resolved
+	}
+	rel, err := filepath.Rel(top, filepath.Join(dir, filepath.Base(change.Path)))
+	if err != nil {
+		return fmt.Errorf("%s: %w", change.Path, ErrRevertConflict)
+	}
+
+	cmd := exec.Command("git", "apply", "-R", "--whitespace=nowarn", "-")
+	cmd.Dir = top
+	cmd.Stdin = strings.NewReader(unifiedDiff(filepath.ToSlash(rel), change.before, change.after))
+	if msg, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w: %s", change.Path, ErrRevertConflict, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+func generateChangeID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		// Fallback to timestamp-based ID if crypto/rand fails
+		return fmt.Sprintf("chg-%d", time.Now().UnixNano())
+	}
+	return "chg-" + hex.EncodeToString(b)
+}
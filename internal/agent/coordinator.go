@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -11,10 +12,15 @@ import (
 
 // Coordinator manages file locks and change awareness between agents.
 type Coordinator struct {
-	config  *config.CoordinatorConfig
-	locks   map[string]*FileLock   // file path -> lock
-	changes []*FileChange          // recent changes
-	mu      sync.RWMutex
+	config     *config.CoordinatorConfig
+	locks      map[string]*FileLock // file path -> lock
+	waiters    []*lockWaiter        // queued lock requests, oldest first
+	deadlocks  []Deadlock           // recent deadlocks
+	reported   map[string]bool      // cycles already reported under the notify policy
+	onDeadlock func(Deadlock)
+	changes    []*FileChange             // recent changes
+	watched    map[string][]*watchedEdit // edit tool calls awaiting their results
+	mu         sync.RWMutex
 }
 
 // Global coordinator instance
@@ -69,6 +75,13 @@ func (c *Coordinator) IsEnabled() bool {
 	return c.config != nil && c.config.Enabled
 }
 
+// LockWait returns how long lock requests queue by default.
+func (c *Coordinator) LockWait() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return time.Duration(c.config.LockWaitSec) * time.Second
+}
+
 // UpdateConfig updates the coordinator configuration.
 func (c *Coordinator) UpdateConfig(cfg *config.CoordinatorConfig) {
 	c.mu.Lock()
@@ -79,55 +92,88 @@ func (c *Coordinator) UpdateConfig(cfg *config.CoordinatorConfig) {
 // AcquireLock attempts to acquire a lock on a file.
 // Returns (success, existing lock holder session ID).
 func (c *Coordinator) AcquireLock(path, sessionID string) (bool, string) {
+	_, err := c.Acquire(path, sessionID, LockOptions{})
+	var conflict *LockConflictError
+	if errors.As(err, &conflict) {
+		return false, conflict.Held.SessionID
+	}
+	return err == nil, ""
+}
+
+// Acquire locks a file, a directory subtree (a path ending in "/") or a glob
+// such as "src/api/**" for a session. It fails with a *LockConflictError
+// when another session holds, or is queued for, a lock covering any of the
+// same paths. Taking
+// a lock the session already holds extends it and updates its metadata.
+// When the coordinator is disabled it returns a nil lock and no error.
+func (c *Coordinator) Acquire(path, sessionID string, opts LockOptions) (*FileLock, error) {
 	if !c.IsEnabled() {
-		return true, ""
+		return nil, nil
+	}
+	key, scope, err := parseLockTarget(path)
+	if err != nil {
+		return nil, err
 	}
 
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	// Clean expired locks first
-	c.cleanExpiredLocksLocked()
+	found := c.settleLocked()
+	lock, err := c.acquireLocked(key, scope, sessionID, opts)
+	c.mu.Unlock()
+	c.notifyDeadlocks(found)
+	return lock, err
+}
 
-	// Check if already locked
-	if existing, ok := c.locks[path]; ok {
-		if existing.SessionID == sessionID {
-			// Extend the lock
-			existing.ExpiresAt = time.Now().Add(time.Duration(c.config.LockTimeoutSec) * time.Second)
-			return true, ""
-		}
-		return false, existing.SessionID
+// acquireLocked takes a lock unless other sessions hold or queued first for
+// an overlapping one. Must be called with lock held.
+func (c *Coordinator) acquireLocked(key, scope, sessionID string, opts LockOptions) (*FileLock, error) {
+	now := time.Now()
+	expires := now.Add(time.Duration(c.config.LockTimeoutSec) * time.Second)
+	if existing, ok := c.locks[key]; ok && existing.SessionID == sessionID {
+		// Extend the lock
+		existing.ExpiresAt = expires
+		existing.TaskID, existing.Reason = opts.TaskID, opts.Reason
+		return existing, nil
 	}
 
-	// Acquire new lock
-	c.locks[path] = &FileLock{
-		Path:      path,
+	lock := &FileLock{
+		Path:      key,
+		Scope:     scope,
 		SessionID: sessionID,
-		LockedAt:  time.Now(),
-		ExpiresAt: time.Now().Add(time.Duration(c.config.LockTimeoutSec) * time.Second),
+		TaskID:    opts.TaskID,
+		Reason:    opts.Reason,
+		LockedAt:  now,
+		ExpiresAt: expires,
 	}
-	return true, ""
+	if blockers := c.blockersLocked(lock, len(c.waiters)); len(blockers) > 0 {
+		return nil, &LockConflictError{Held: blockers[0]}
+	}
+	c.locks[key] = lock
+	return lock, nil
 }
 
-// ReleaseLock releases a lock on a file.
+// ReleaseLock releases a lock on a file, directory or glob.
 func (c *Coordinator) ReleaseLock(path, sessionID string) bool {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	key, _, err := parseLockTarget(path)
+	if err != nil {
+		return false
+	}
 
-	if lock, ok := c.locks[path]; ok {
-		if lock.SessionID == sessionID {
-			delete(c.locks, path)
-			return true
-		}
+	c.mu.Lock()
+	lock, ok := c.locks[key]
+	released := ok && lock.SessionID == sessionID
+	var found []Deadlock
+	if released {
+		delete(c.locks, key)
+		found = c.settleLocked()
 	}
-	return false
+	c.mu.Unlock()
+	c.notifyDeadlocks(found)
+	return released
 }
 
 // ReleaseAllLocks releases all locks held by a session.
 func (c *Coordinator) ReleaseAllLocks(sessionID string) int {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	count := 0
 	for path, lock := range c.locks {
 		if lock.SessionID == sessionID {
@@ -135,14 +181,37 @@ func (c *Coordinator) ReleaseAllLocks(sessionID string) int {
 			count++
 		}
 	}
+	found := c.settleLocked()
+	c.mu.Unlock()
+	c.notifyDeadlocks(found)
 	return count
 }
 
-// GetLock returns the lock for a file, if any.
+// GetLock returns the lock taken on exactly this file, directory or glob,
+// if any.
 func (c *Coordinator) GetLock(path string) *FileLock {
+	key, _, err := parseLockTarget(path)
+	if err != nil {
+		return nil
+	}
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	return c.locks[path]
+	return c.locks[key]
+}
+
+// LocksCovering returns the locks that cover a file path, whatever their
+// scope.
+func (c *Coordinator) LocksCovering(path string) []*FileLock {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var locks []*FileLock
+	for _, lock := range c.locks {
+		if lockCovers(lock, path) {
+			locks = append(locks, lock)
+		}
+	}
+	return locks
 }
 
 // GetAllLocks returns all current locks.
@@ -181,46 +250,47 @@ func (c *Coordinator) RecordChange(path, sessionID, changeType, summary string)
 	defer c.mu.Unlock()
 
 	change := &FileChange{
+		ID:         generateChangeID(),
 		Path:       path,
 		SessionID:  sessionID,
 		ChangeType: changeType,
 		Summary:    summary,
+		Status:     ChangePending,
 		Timestamp:  time.Now(),
 	}
-	c.changes = append(c.changes, change)
-
-	// Keep only last 500 changes
-	if len(c.changes) > 500 {
-		c.changes = c.changes[len(c.changes)-500:]
-	}
+	c.addChangeLocked(change)
 }
 
 // GetRecentChanges returns recent file changes.
 func (c *Coordinator) GetRecentChanges(limit int) []*FileChange {
+	return c.ListChanges("", "", limit)
+}
+
+// ListChanges returns the most recent changes, oldest first, optionally
+// only those of a session or with a review status. limit caps the number
+// returned when positive.
+func (c *Coordinator) ListChanges(sessionID, status string, limit int) []*FileChange {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	if limit <= 0 || limit > len(c.changes) {
-		limit = len(c.changes)
+	result := make([]*FileChange, 0)
+	for i := len(c.changes) - 1; i >= 0 && (limit <= 0 || len(result) < limit); i-- {
+		change := c.changes[i]
+		if (sessionID != "" && change.SessionID != sessionID) || (status != "" && change.Status != status) {
+			continue
+		}
+		cp := *change
+		result = append(result, &cp)
+	}
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
 	}
-
-	result := make([]*FileChange, limit)
-	copy(result, c.changes[len(c.changes)-limit:])
 	return result
 }
 
 // GetChangesForSession returns changes made by a specific session.
 func (c *Coordinator) GetChangesForSession(sessionID string) []*FileChange {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	var result []*FileChange
-	for _, change := range c.changes {
-		if change.SessionID == sessionID {
-			result = append(result, change)
-		}
-	}
-	return result
+	return c.ListChanges(sessionID, "", 0)
 }
 
 // GetChangesForFile returns changes to a specific file.
@@ -231,7 +301,8 @@ func (c *Coordinator) GetChangesForFile(path string) []*FileChange {
 	var result []*FileChange
 	for _, change := range c.changes {
 		if change.Path == path {
-			result = append(result, change)
+			cp := *change
+			result = append(result, &cp)
 		}
 	}
 	return result
@@ -245,7 +316,8 @@ func (c *Coordinator) GetRecentChangesExcluding(sessionID string, limit int) []*
 	var result []*FileChange
 	for i := len(c.changes) - 1; i >= 0 && len(result) < limit; i-- {
 		if c.changes[i].SessionID != sessionID {
-			result = append(result, c.changes[i])
+			cp := *c.changes[i]
+			result = append(result, &cp)
 		}
 	}
 	return result
@@ -265,7 +337,14 @@ func (c *Coordinator) GenerateContextWarning(sessionID string) string {
 	// Check for files locked by other sessions
 	for path, lock := range c.locks {
 		if lock.SessionID != sessionID {
-			warnings = append(warnings, fmt.Sprintf("- File '%s' is being modified by another agent session", path))
+			what := "File"
+			switch lock.Scope {
+			case LockScopeDir:
+				what = "Directory"
+			case LockScopeGlob:
+				what = "Files matching"
+			}
+			warnings = append(warnings, fmt.Sprintf("- %s '%s' is being modified by another agent session", what, path))
 		}
 	}
 
@@ -302,9 +381,11 @@ func (c *Coordinator) cleanExpiredLocksLocked() {
 	}
 }
 
-// CleanExpiredLocks removes expired locks.
+// CleanExpiredLocks removes expired locks and grants queued requests they
+// blocked.
 func (c *Coordinator) CleanExpiredLocks() {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.cleanExpiredLocksLocked()
+	found := c.settleLocked()
+	c.mu.Unlock()
+	c.notifyDeadlocks(found)
 }
@@ -0,0 +1,191 @@
+package agent
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	// diffContext is the number of unchanged lines around each hunk.
+	diffContext = 3
+	// maxDiffCells bounds the line comparison of a diff. Files whose
+	// changed regions are larger are diffed as a whole replacement.
+	maxDiffCells = 4 << 20
+)
+
+// diffOp is one line of a diff: ' ' kept, '-' removed or '+' added.
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// unifiedDiff returns a unified diff of a file's change, or "" when the
+// content did not change. name is the path shown in the headers.
+func unifiedDiff(name string, before, after fileSnapshot) string {
+	if before.exists == after.exists && bytes.Equal(before.data, after.data) {
+		return ""
+	}
+	from, to := "a/"+name, "b/"+name
+	if !before.exists {
+		from = "/dev/null"
+	}
+	if !after.exists {
+		to = "/dev/null"
+	}
+	if isBinary(before.data) || isBinary(after.data) {
+		return fmt.Sprintf("Binary files %s and %s differ\n", from, to)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", from, to)
+	writeHunks(&b, diffLines(splitLines(string(before.data)), splitLines(string(after.data))))
+	return b.String()
+}
+
+// diffStat counts the lines a diff adds and removes.
+func diffStat(diff string) (added, removed int) {
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+		case strings.HasPrefix(line, "+"):
+			added++
+		case strings.HasPrefix(line, "-"):
+			removed++
+		}
+	}
+	return added, removed
+}
+
+func isBinary(data []byte) bool {
+	return bytes.IndexByte(data, 0) >= 0
+}
+
+// splitLines splits text into lines, each keeping its newline.
+func splitLines(s string) []string {
+	var lines []string
+	for s != "" {
+		i := strings.IndexByte(s, '\n')
+		if i < 0 {
+			lines = append(lines, s)
+			break
+		}
+		lines = append(lines, s[:i+1])
+		s = s[i+1:]
+	}
+	return lines
+}
+
+// diffLines returns the edit script turning a into b, from the longest
+// common subsequence of their lines.
+func diffLines(a, b []string) []diffOp {
+	var ops []diffOp
+	// Common prefix and suffix need no comparison
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		ops = append(ops, diffOp{' ', a[prefix]})
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	if len(ma)*len(mb) > maxDiffCells {
+		for _, l := range ma {
+			ops = append(ops, diffOp{'-', l})
+		}
+		for _, l := range mb {
+			ops = append(ops, diffOp{'+', l})
+		}
+	} else {
+		// lcs[i][j] is the common subsequence length of ma[i:] and mb[j:]
+		lcs := make([][]int, len(ma)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(mb)+1)
+		}
+		for i := len(ma) - 1; i >= 0; i-- {
+			for j := len(mb) - 1; j >= 0; j-- {
+				if ma[i] == mb[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < len(ma) || j < len(mb) {
+			switch {
+			case i < len(ma) && j < len(mb) && ma[i] == mb[j]:
+				ops = append(ops, diffOp{' ', ma[i]})
+				i++
+				j++
+			case i < len(ma) && (j == len(mb) || lcs[i+1][j] >= lcs[i][j+1]):
+				ops = append(ops, diffOp{'-', ma[i]})
+				i++
+			default:
+				ops = append(ops, diffOp{'+', mb[j]})
+				j++
+			}
+		}
+	}
+
+	for _, l := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', l})
+	}
+	return ops
+}
+
+// writeHunks writes the changes of an edit script as unified diff hunks,
+// merging changes whose context overlaps.
+func writeHunks(b *strings.Builder, ops []diffOp) {
+	// aLine[k] and bLine[k] count the old and new lines before ops[k]
+	aLine, bLine := make([]int, len(ops)+1), make([]int, len(ops)+1)
+	for k, op := range ops {
+		aLine[k+1], bLine[k+1] = aLine[k], bLine[k]
+		if op.kind != '+' {
+			aLine[k+1]++
+		}
+		if op.kind != '-' {
+			bLine[k+1]++
+		}
+	}
+
+	for k := 0; k < len(ops); {
+		if ops[k].kind == ' ' {
+			k++
+			continue
+		}
+		start := max(k-diffContext, 0)
+		last := k
+		for j := k; j < len(ops) && j-last <= 2*diffContext; j++ {
+			if ops[j].kind != ' ' {
+				last = j
+			}
+		}
+		end := min(last+diffContext+1, len(ops))
+
+		fmt.Fprintf(b, "@@ -%s +%s @@\n", hunkRange(aLine[start], aLine[end]-aLine[start]), hunkRange(bLine[start], bLine[end]-bLine[start]))
+		for _, op := range ops[start:end] {
+			b.WriteByte(op.kind)
+			b.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				b.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		k = end
+	}
+}
+
+// hunkRange formats the start and length of one side of a hunk. An empty
+// side starts at the line before it.
+func hunkRange(before, n int) string {
+	if n == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	if n == 1 {
+		return fmt.Sprintf("%d", before+1)
+	}
+	return fmt.Sprintf("%d,%d", before+1, n)
+}
@@ -15,6 +15,9 @@ type Guardrails struct {
 	spending   map[string]float64          // session ID -> total spent
 	requests   map[string][]time.Time      // session ID -> request timestamps
 	operations []*SensitiveOperation       // recent sensitive operations
+	policy     []*policyRule
+	ruleHits   map[string]*PolicyRuleStats // rule name -> hits
+	velocity   map[string]*spendVelocity   // session ID -> spending rate
 	mu         sync.RWMutex
 }
 
@@ -62,19 +65,29 @@ func NewGuardrails(cfg *config.GuardrailsConfig) *Guardrails {
 		spending:   make(map[string]float64),
 		requests:   make(map[string][]time.Time),
 		operations: make([]*SensitiveOperation, 0),
+		policy:     compilePolicy(cfg.Policy),
+		ruleHits:   make(map[string]*PolicyRuleStats),
+		velocity:   make(map[string]*spendVelocity),
 	}
 }
 
 // IsEnabled returns whether guardrails are enabled.
 func (g *Guardrails) IsEnabled() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
 	return g.config != nil && g.config.Enabled
 }
 
-// UpdateConfig updates the guardrails configuration.
+// UpdateConfig updates the guardrails configuration. Hit counts carry over
+// for policy rules that keep their name.
 func (g *Guardrails) UpdateConfig(cfg *config.GuardrailsConfig) {
+	if cfg == nil {
+		cfg = &config.GuardrailsConfig{}
+	}
 	g.mu.Lock()
 	defer g.mu.Unlock()
 	g.config = cfg
+	g.policy = compilePolicy(cfg.Policy)
 }
 
 // CheckRequest checks if a request should be allowed.
@@ -136,11 +149,13 @@ func (g *Guardrails) GetSpending(sessionID string) float64 {
 	return g.spending[sessionID]
 }
 
-// ResetSpending resets spending for a session.
+// ResetSpending resets spending for a session, along with its learned
+// spending rate.
 func (g *Guardrails) ResetSpending(sessionID string) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 	delete(g.spending, sessionID)
+	delete(g.velocity, sessionID)
 }
 
 // CheckSensitiveOperation checks if a request contains sensitive operations.
@@ -221,17 +236,23 @@ func (g *Guardrails) CheckSensitiveOperation(sessionID string, requestBody []byt
 	// Record operations
 	if len(ops) > 0 {
 		g.mu.Lock()
-		g.operations = append(g.operations, ops...)
-		// Keep only last 100 operations
-		if len(g.operations) > 100 {
-			g.operations = g.operations[len(g.operations)-100:]
-		}
+		g.recordOperationsLocked(ops)
 		g.mu.Unlock()
 	}
 
 	return ops
 }
 
+// recordOperationsLocked adds to the recent operations. It must be called
+// with g.mu held.
+func (g *Guardrails) recordOperationsLocked(ops []*SensitiveOperation) {
+	g.operations = append(g.operations, ops...)
+	// Keep only last 100 operations
+	if len(g.operations) > 100 {
+		g.operations = g.operations[len(g.operations)-100:]
+	}
+}
+
 // GetRecentOperations returns recent sensitive operations.
 func (g *Guardrails) GetRecentOperations(limit int) []*SensitiveOperation {
 	g.mu.RLock()
@@ -0,0 +1,409 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// maxDeadlockHistory bounds the deadlocks kept for the locks API.
+const maxDeadlockHistory = 50
+
+// ErrLockWaitTimeout is returned when a queued lock request is not granted
+// in time.
+var ErrLockWaitTimeout = errors.New("timed out waiting for lock")
+
+// Deadlock is a cycle of agent sessions each waiting for a lock held or
+// requested earlier by the next.
+type Deadlock struct {
+	Sessions   []string  `json:"sessions"` // each waits for the next, the last for the first
+	Paths      []string  `json:"paths"`    // the lock each session waits for
+	Policy     string    `json:"policy"`
+	Victim     string    `json:"victim,omitempty"` // session aborted to break the cycle
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+// DeadlockError is returned to the session aborted to break a deadlock.
+// Its locks have been released; it should retry.
+type DeadlockError struct {
+	Deadlock Deadlock
+}
+
+func (e *DeadlockError) Error() string {
+	return fmt.Sprintf("deadlock between sessions %s: session %s aborted",
+		strings.Join(e.Deadlock.Sessions, ", "), e.Deadlock.Victim)
+}
+
+// LockWait is a queued lock request, for the locks API.
+type LockWait struct {
+	FileLock
+	Since     time.Time `json:"since"`
+	BlockedBy []string  `json:"blocked_by"` // sessions it waits for
+}
+
+// lockWaiter is a lock request in the FIFO queue.
+type lockWaiter struct {
+	lock   *FileLock
+	since  time.Time
+	result chan error // receives nil once granted, or why it failed
+}
+
+// AcquireWait is Acquire that queues when the lock is taken, for up to wait.
+// Queued requests are granted first come, first served as overlapping locks
+// are released or expire. A wait fails with ErrLockWaitTimeout, the
+// context's error, or a *DeadlockError when the session is aborted to break
+// a deadlock.
+func (c *Coordinator) AcquireWait(ctx context.Context, path, sessionID string, opts LockOptions, wait time.Duration) (*FileLock, error) {
+	if !c.IsEnabled() {
+		return nil, nil
+	}
+	key, scope, err := parseLockTarget(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	found := c.settleLocked()
+	lock, err := c.acquireLocked(key, scope, sessionID, opts)
+	var conflict *LockConflictError
+	if wait <= 0 || !errors.As(err, &conflict) {
+		c.mu.Unlock()
+		c.notifyDeadlocks(found)
+		return lock, err
+	}
+
+	w := &lockWaiter{
+		lock:   &FileLock{Path: key, Scope: scope, SessionID: sessionID, TaskID: opts.TaskID, Reason: opts.Reason},
+		since:  time.Now(),
+		result: make(chan error, 1),
+	}
+	c.waiters = append(c.waiters, w)
+	c.scheduleExpiryLocked(w)
+	found = append(found, c.settleLocked()...)
+	c.mu.Unlock()
+	c.notifyDeadlocks(found)
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case err := <-w.result:
+		return w.granted(err)
+	case <-timer.C:
+		return c.cancelWait(w, ErrLockWaitTimeout)
+	case <-ctx.Done():
+		return c.cancelWait(w, ctx.Err())
+	}
+}
+
+func (w *lockWaiter) granted(err error) (*FileLock, error) {
+	if err != nil {
+		return nil, err
+	}
+	return w.lock, nil
+}
+
+// cancelWait takes a waiter out of the queue, unless it was granted or
+// aborted in the meantime.
+func (c *Coordinator) cancelWait(w *lockWaiter, reason error) (*FileLock, error) {
+	c.mu.Lock()
+	i := slices.Index(c.waiters, w)
+	if i < 0 {
+		c.mu.Unlock()
+		return w.granted(<-w.result)
+	}
+	c.waiters = slices.Delete(c.waiters, i, i+1)
+	// Requests queued behind it may be free now
+	found := c.settleLocked()
+	c.mu.Unlock()
+	c.notifyDeadlocks(found)
+	return nil, reason
+}
+
+// scheduleExpiryLocked arranges for the queue to be rechecked when the
+// earliest held lock blocking w expires.
+func (c *Coordinator) scheduleExpiryLocked(w *lockWaiter) {
+	var first time.Time
+	for _, b := range c.blockersLocked(w.lock, len(c.waiters)-1) {
+		if held, ok := c.locks[b.Path]; ok && held == b && (first.IsZero() || b.ExpiresAt.Before(first)) {
+			first = b.ExpiresAt
+		}
+	}
+	if !first.IsZero() {
+		time.AfterFunc(time.Until(first)+time.Millisecond, c.CleanExpiredLocks)
+	}
+}
+
+// blockersLocked returns what a lock request waits for: overlapping locks
+// other sessions hold and, to keep the queue fair, overlapping requests
+// other sessions queued before it. queued is the request's place in the
+// queue, or len(c.waiters) for a new request.
+func (c *Coordinator) blockersLocked(lock *FileLock, queued int) []*FileLock {
+	var blockers []*FileLock
+	for _, held := range c.locks {
+		if held.SessionID != lock.SessionID && locksOverlap(held, lock) {
+			blockers = append(blockers, held)
+		}
+	}
+	for _, w := range c.waiters[:queued] {
+		if w.lock.SessionID != lock.SessionID && locksOverlap(w.lock, lock) {
+			blockers = append(blockers, w.lock)
+		}
+	}
+	return blockers
+}
+
+// settleLocked drops expired locks, grants queued requests that are no
+// longer blocked and deals with deadlocks by the configured policy,
+// returning those newly found.
+func (c *Coordinator) settleLocked() []Deadlock {
+	c.cleanExpiredLocksLocked()
+	var found []Deadlock
+	for {
+		c.grantWaitersLocked()
+		cycles := c.findCyclesLocked()
+		policy := c.config.GetDeadlockPolicy()
+		if policy == config.DeadlockNotify {
+			// Report each cycle once for as long as it lasts
+			current := make(map[string]bool)
+			for _, cycle := range cycles {
+				key := strings.Join(cycle, "\x00")
+				current[key] = true
+				if !c.reported[key] {
+					found = append(found, c.deadlockLocked(cycle, policy, ""))
+				}
+			}
+			c.reported = current
+			return found
+		}
+		if len(cycles) == 0 {
+			return found
+		}
+		victim := c.youngestLocked(cycles[0])
+		d := c.deadlockLocked(cycles[0], policy, victim)
+		c.abortLocked(victim, &DeadlockError{Deadlock: d})
+		found = append(found, d)
+	}
+}
+
+// grantWaitersLocked hands locks to queued requests in FIFO order.
+func (c *Coordinator) grantWaitersLocked() {
+	for i := 0; i < len(c.waiters); {
+		w := c.waiters[i]
+		if len(c.blockersLocked(w.lock, i)) > 0 {
+			i++
+			continue
+		}
+		c.waiters = slices.Delete(c.waiters, i, i+1)
+		now := time.Now()
+		w.lock.LockedAt = now
+		w.lock.ExpiresAt = now.Add(time.Duration(c.config.LockTimeoutSec) * time.Second)
+		c.locks[w.lock.Path] = w.lock
+		w.result <- nil
+	}
+}
+
+// waitsForLocked builds the wait-for graph: session -> sessions it waits on.
+func (c *Coordinator) waitsForLocked() map[string]map[string]bool {
+	graph := make(map[string]map[string]bool)
+	for i, w := range c.waiters {
+		for _, b := range c.blockersLocked(w.lock, i) {
+			if graph[w.lock.SessionID] == nil {
+				graph[w.lock.SessionID] = make(map[string]bool)
+			}
+			graph[w.lock.SessionID][b.SessionID] = true
+		}
+	}
+	return graph
+}
+
+// findCyclesLocked returns the distinct cycles in the wait-for graph, each
+// the shortest through its sessions and starting at its smallest ID.
+func (c *Coordinator) findCyclesLocked() [][]string {
+	graph := c.waitsForLocked()
+	starts := make([]string, 0, len(graph))
+	for s := range graph {
+		starts = append(starts, s)
+	}
+	sort.Strings(starts)
+
+	seen := make(map[string]bool)
+	var cycles [][]string
+	for _, start := range starts {
+		cycle := shortestCycle(graph, start)
+		if cycle == nil {
+			continue
+		}
+		// Rotate to start at the smallest ID so each cycle has one key
+		m := 0
+		for i := range cycle {
+			if cycle[i] < cycle[m] {
+				m = i
+			}
+		}
+		cycle = append(cycle[m:], cycle[:m]...)
+		if key := strings.Join(cycle, "\x00"); !seen[key] {
+			seen[key] = true
+			cycles = append(cycles, cycle)
+		}
+	}
+	return cycles
+}
+
+// shortestCycle finds the shortest path from start back to itself by
+// breadth-first search, or nil.
+func shortestCycle(graph map[string]map[string]bool, start string) []string {
+	prev := map[string]string{}
+	queue := []string{start}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		next := make([]string, 0, len(graph[s]))
+		for n := range graph[s] {
+			next = append(next, n)
+		}
+		sort.Strings(next)
+		for _, n := range next {
+			if n == start {
+				cycle := []string{s}
+				for s != start {
+					s = prev[s]
+					cycle = append([]string{s}, cycle...)
+				}
+				return cycle
+			}
+			if _, ok := prev[n]; !ok {
+				prev[n] = s
+				queue = append(queue, n)
+			}
+		}
+	}
+	return nil
+}
+
+// youngestLocked picks the session of a cycle that has held its locks the
+// shortest time, the one with the least work to lose. Sessions holding no
+// lock count as youngest; ties go to the one that started waiting last.
+func (c *Coordinator) youngestLocked(cycle []string) string {
+	oldest := make(map[string]time.Time)
+	for _, l := range c.locks {
+		if t, ok := oldest[l.SessionID]; !ok || l.LockedAt.Before(t) {
+			oldest[l.SessionID] = l.LockedAt
+		}
+	}
+	waiting := make(map[string]time.Time)
+	for _, w := range c.waiters {
+		if t, ok := waiting[w.lock.SessionID]; !ok || w.since.Before(t) {
+			waiting[w.lock.SessionID] = w.since
+		}
+	}
+	age := func(s string) time.Time {
+		if t, ok := oldest[s]; ok {
+			return t
+		}
+		return time.Now()
+	}
+
+	victim := cycle[0]
+	for _, s := range cycle[1:] {
+		if a, b := age(s), age(victim); a.After(b) || (a.Equal(b) && waiting[s].After(waiting[victim])) {
+			victim = s
+		}
+	}
+	return victim
+}
+
+// deadlockLocked describes a cycle and records it in the history.
+func (c *Coordinator) deadlockLocked(cycle []string, policy, victim string) Deadlock {
+	d := Deadlock{
+		Sessions:   cycle,
+		Paths:      make([]string, len(cycle)),
+		Policy:     policy,
+		Victim:     victim,
+		DetectedAt: time.Now(),
+	}
+	for i, s := range cycle {
+		for _, w := range c.waiters {
+			if w.lock.SessionID == s {
+				d.Paths[i] = w.lock.Path
+				break
+			}
+		}
+	}
+	c.deadlocks = append(c.deadlocks, d)
+	if len(c.deadlocks) > maxDeadlockHistory {
+		c.deadlocks = c.deadlocks[len(c.deadlocks)-maxDeadlockHistory:]
+	}
+	return d
+}
+
+// abortLocked fails a session's queued requests with err and releases its
+// locks.
+func (c *Coordinator) abortLocked(sessionID string, err error) {
+	c.waiters = slices.DeleteFunc(c.waiters, func(w *lockWaiter) bool {
+		if w.lock.SessionID != sessionID {
+			return false
+		}
+		w.result <- err
+		return true
+	})
+	for path, lock := range c.locks {
+		if lock.SessionID == sessionID {
+			delete(c.locks, path)
+		}
+	}
+}
+
+// OnDeadlock sets a function called when a deadlock is found. It must not
+// call back into the coordinator synchronously.
+func (c *Coordinator) OnDeadlock(fn func(Deadlock)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onDeadlock = fn
+}
+
+// notifyDeadlocks reports deadlocks. It must be called without c.mu held.
+func (c *Coordinator) notifyDeadlocks(found []Deadlock) {
+	if len(found) == 0 {
+		return
+	}
+	c.mu.RLock()
+	fn := c.onDeadlock
+	c.mu.RUnlock()
+	if fn == nil {
+		return
+	}
+	for _, d := range found {
+		fn(d)
+	}
+}
+
+// GetWaiting returns the queued lock requests, in queue order.
+func (c *Coordinator) GetWaiting() []LockWait {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	waits := make([]LockWait, 0, len(c.waiters))
+	for i, w := range c.waiters {
+		lw := LockWait{FileLock: *w.lock, Since: w.since, BlockedBy: []string{}}
+		for _, b := range c.blockersLocked(w.lock, i) {
+			if !slices.Contains(lw.BlockedBy, b.SessionID) {
+				lw.BlockedBy = append(lw.BlockedBy, b.SessionID)
+			}
+		}
+		waits = append(waits, lw)
+	}
+	return waits
+}
+
+// GetDeadlocks returns the most recent deadlocks, oldest first.
+func (c *Coordinator) GetDeadlocks() []Deadlock {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return slices.Clone(c.deadlocks)
+}
@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Lock scopes. A file lock covers one path, a directory lock the whole
+// subtree under it, and a glob lock every path its pattern matches. In a
+// pattern "*" matches within one path element and "**" any number of them.
+const (
+	LockScopeFile = "file"
+	LockScopeDir  = "dir"
+	LockScopeGlob = "glob"
+)
+
+// parseLockTarget normalizes a lock target and works out its scope. A
+// trailing "/" makes a directory lock; "*", "?" or "[" a glob lock. The
+// returned key names the lock: directories keep their trailing "/" so they
+// don't collide with a file lock on the same path.
+func parseLockTarget(target string) (key, scope string, err error) {
+	target = filepath.ToSlash(strings.TrimSpace(target))
+	if target == "" {
+		return "", "", fmt.Errorf("lock path is empty")
+	}
+	isDir := strings.HasSuffix(target, "/")
+	key = path.Clean(target)
+	switch {
+	case strings.ContainsAny(key, "*?["):
+		for _, elem := range strings.Split(key, "/") {
+			if _, err := path.Match(elem, ""); err != nil {
+				return "", "", fmt.Errorf("lock pattern %q is invalid: %w", target, err)
+			}
+		}
+		return key, LockScopeGlob, nil
+	case isDir:
+		if key != "/" {
+			key += "/"
+		}
+		return key, LockScopeDir, nil
+	}
+	return key, LockScopeFile, nil
+}
+
+// lockElems returns the pattern elements a lock covers.
+func lockElems(key, scope string) []string {
+	key = strings.TrimSuffix(key, "/")
+	elems := strings.Split(key, "/")
+	if scope == LockScopeDir {
+		elems = append(elems, "**")
+	}
+	return elems
+}
+
+// locksOverlap reports whether two locks could cover a common path.
+func locksOverlap(a, b *FileLock) bool {
+	return elemsOverlap(lockElems(a.Path, a.Scope), lockElems(b.Path, b.Scope))
+}
+
+// lockCovers reports whether a lock covers a file path.
+func lockCovers(lock *FileLock, file string) bool {
+	return elemsOverlap(lockElems(lock.Path, lock.Scope), strings.Split(path.Clean(filepath.ToSlash(file)), "/"))
+}
+
+// elemsOverlap reports whether two element patterns could match a common
+// path. Two wildcard elements are assumed to overlap when neither literal
+// prefix rules it out, so the answer errs on the side of a conflict.
+func elemsOverlap(a, b []string) bool {
+	switch {
+	case len(a) > 0 && a[0] == "**":
+		return elemsOverlap(a[1:], b) || (len(b) > 0 && elemsOverlap(a, b[1:]))
+	case len(b) > 0 && b[0] == "**":
+		return elemsOverlap(a, b[1:]) || (len(a) > 0 && elemsOverlap(a[1:], b))
+	case len(a) == 0 || len(b) == 0:
+		return len(a) == len(b)
+	}
+	return elemOverlap(a[0], b[0]) && elemsOverlap(a[1:], b[1:])
+}
+
+func elemOverlap(a, b string) bool {
+	aWild, bWild := strings.ContainsAny(a, "*?["), strings.ContainsAny(b, "*?[")
+	switch {
+	case !aWild && !bWild:
+		return a == b
+	case !aWild:
+		ok, _ := path.Match(b, a)
+		return ok
+	case !bWild:
+		ok, _ := path.Match(a, b)
+		return ok
+	}
+	pa, pb := literalPrefix(a), literalPrefix(b)
+	return strings.HasPrefix(pa, pb) || strings.HasPrefix(pb, pa)
+}
+
+func literalPrefix(elem string) string {
+	if i := strings.IndexAny(elem, "*?[\\"); i >= 0 {
+		return elem[:i]
+	}
+	return elem
+}
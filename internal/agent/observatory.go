@@ -11,9 +11,22 @@ import (
 type Observatory struct {
 	config   *config.ObservatoryConfig
 	sessions map[string]*ObservedSession
+	onChange func(SessionChange)
 	mu       sync.RWMutex
 }
 
+// SessionRemoved is the status reported by a SessionChange for a session
+// that is no longer monitored.
+const SessionRemoved = "removed"
+
+// SessionChange reports a session being registered, removed, or changing
+// status.
+type SessionChange struct {
+	ID      string `json:"id"`
+	Profile string `json:"profile,omitempty"`
+	Status  string `json:"status"`
+}
+
 // Global observatory instance
 var (
 	globalObservatory     *Observatory
@@ -45,22 +58,32 @@ func GetGlobalObservatory() *Observatory {
 // NewObservatory creates a new observatory.
 func NewObservatory(cfg *config.ObservatoryConfig) *Observatory {
 	if cfg == nil {
-		cfg = &config.ObservatoryConfig{
-			Enabled:        false,
-			StuckThreshold: 5,
-			IdleTimeoutMin: 30,
-		}
+		cfg = &config.ObservatoryConfig{Enabled: false}
+	}
+	return &Observatory{
+		config:   withObservatoryDefaults(cfg),
+		sessions: make(map[string]*ObservedSession),
 	}
+}
+
+// withObservatoryDefaults fills in the thresholds left unset in cfg.
+func withObservatoryDefaults(cfg *config.ObservatoryConfig) *config.ObservatoryConfig {
 	if cfg.StuckThreshold == 0 {
 		cfg.StuckThreshold = 5
 	}
 	if cfg.IdleTimeoutMin == 0 {
 		cfg.IdleTimeoutMin = 30
 	}
-	return &Observatory{
-		config:   cfg,
-		sessions: make(map[string]*ObservedSession),
+	if cfg.LoopThreshold == 0 {
+		cfg.LoopThreshold = 5
+	}
+	if cfg.NoProgressMin == 0 {
+		cfg.NoProgressMin = 15
+	}
+	if cfg.NoProgressTokens == 0 {
+		cfg.NoProgressTokens = 200000
 	}
+	return cfg
 }
 
 // IsEnabled returns whether the observatory is enabled.
@@ -72,14 +95,44 @@ func (o *Observatory) IsEnabled() bool {
 func (o *Observatory) UpdateConfig(cfg *config.ObservatoryConfig) {
 	o.mu.Lock()
 	defer o.mu.Unlock()
-	o.config = cfg
+	if cfg == nil {
+		cfg = &config.ObservatoryConfig{Enabled: false}
+	}
+	o.config = withObservatoryDefaults(cfg)
 }
 
-// RegisterSession registers a new session for monitoring.
-func (o *Observatory) RegisterSession(id, profile, client, projectPath string) *ObservedSession {
+// OnSessionChange sets a function called when a session is registered,
+// removed, or changes status.
+func (o *Observatory) OnSessionChange(fn func(SessionChange)) {
 	o.mu.Lock()
 	defer o.mu.Unlock()
+	o.onChange = fn
+}
+
+// notify reports a session change. It must be called without o.mu held.
+func (o *Observatory) notify(id, profile, status string) {
+	o.mu.RLock()
+	fn := o.onChange
+	o.mu.RUnlock()
+	if fn != nil {
+		fn(SessionChange{ID: id, Profile: profile, Status: status})
+	}
+}
+
+// setStatus sets the status of a session and reports the change.
+func (o *Observatory) setStatus(session *ObservedSession, status string) {
+	session.mu.Lock()
+	changed := session.Status != status
+	session.Status = status
+	session.mu.Unlock()
+	if changed {
+		o.notify(session.ID, session.Profile, status)
+	}
+}
 
+// RegisterSession registers a new session for monitoring.
+func (o *Observatory) RegisterSession(id, profile, client, projectPath string) *ObservedSession {
+	o.mu.Lock()
 	session := &ObservedSession{
 		ID:           id,
 		Profile:      profile,
@@ -91,6 +144,9 @@ func (o *Observatory) RegisterSession(id, profile, client, projectPath string) *
 		LastErrors:   make([]string, 0),
 	}
 	o.sessions[id] = session
+	o.mu.Unlock()
+
+	o.notify(id, profile, SessionStatusActive)
 	return session
 }
 
@@ -130,15 +186,20 @@ func (o *Observatory) GetActiveSessions() []*ObservedSession {
 // RemoveSession removes a session from monitoring.
 func (o *Observatory) RemoveSession(id string) {
 	o.mu.Lock()
-	defer o.mu.Unlock()
+	session, ok := o.sessions[id]
 	delete(o.sessions, id)
+	o.mu.Unlock()
+
+	if ok {
+		o.notify(id, session.Profile, SessionRemoved)
+	}
 }
 
 // RecordRequest records a request for a session.
 func (o *Observatory) RecordRequest(sessionID string, tokens int, cost float64, err error) {
 	o.mu.RLock()
 	session, ok := o.sessions[sessionID]
-	stuckThreshold := o.config.StuckThreshold
+	cfg := *o.config
 	o.mu.RUnlock()
 
 	if !ok {
@@ -146,12 +207,12 @@ func (o *Observatory) RecordRequest(sessionID string, tokens int, cost float64,
 	}
 
 	session.mu.Lock()
-	defer session.mu.Unlock()
-
+	prev := session.Status
 	session.LastActivity = time.Now()
 	session.RequestCount++
 	session.TotalTokens += tokens
 	session.TotalCost += cost
+	session.TokensSinceEdit += tokens
 
 	if err != nil {
 		session.ErrorCount++
@@ -160,14 +221,15 @@ func (o *Observatory) RecordRequest(sessionID string, tokens int, cost float64,
 			session.LastErrors = session.LastErrors[1:]
 		}
 		session.RetryCount++
-
-		// Check for stuck condition
-		if session.RetryCount >= stuckThreshold {
-			session.Status = SessionStatusStuck
-		}
 	} else {
 		session.RetryCount = 0
-		session.Status = SessionStatusActive
+	}
+	session.assess(&cfg, session.LastActivity)
+	status := session.Status
+	session.mu.Unlock()
+
+	if status != prev {
+		o.notify(sessionID, session.Profile, status)
 	}
 }
 
@@ -197,9 +259,7 @@ func (o *Observatory) KillSession(sessionID string) bool {
 		return false
 	}
 
-	session.mu.Lock()
-	defer session.mu.Unlock()
-	session.Status = SessionStatusKilled
+	o.setStatus(session, SessionStatusKilled)
 	return true
 }
 
@@ -213,9 +273,7 @@ func (o *Observatory) PauseSession(sessionID string) bool {
 		return false
 	}
 
-	session.mu.Lock()
-	defer session.mu.Unlock()
-	session.Status = SessionStatusPaused
+	o.setStatus(session, SessionStatusPaused)
 	return true
 }
 
@@ -230,12 +288,13 @@ func (o *Observatory) ResumeSession(sessionID string) bool {
 	}
 
 	session.mu.Lock()
-	defer session.mu.Unlock()
-	if session.Status == SessionStatusPaused {
-		session.Status = SessionStatusActive
-		return true
+	paused := session.Status == SessionStatusPaused
+	session.mu.Unlock()
+	if !paused {
+		return false
 	}
-	return false
+	o.setStatus(session, SessionStatusActive)
+	return true
 }
 
 // IsSessionKilled checks if a session has been killed.
@@ -271,18 +330,23 @@ func (o *Observatory) IsSessionPaused(sessionID string) bool {
 // CheckIdleSessions marks sessions as idle if inactive.
 func (o *Observatory) CheckIdleSessions() {
 	o.mu.RLock()
-	defer o.mu.RUnlock()
-
 	idleTimeout := time.Duration(o.config.IdleTimeoutMin) * time.Minute
 	now := time.Now()
 
+	var idle []*ObservedSession
 	for _, session := range o.sessions {
 		session.mu.Lock()
 		if session.Status == SessionStatusActive && now.Sub(session.LastActivity) > idleTimeout {
 			session.Status = SessionStatusIdle
+			idle = append(idle, session)
 		}
 		session.mu.Unlock()
 	}
+	o.mu.RUnlock()
+
+	for _, session := range idle {
+		o.notify(session.ID, session.Profile, SessionStatusIdle)
+	}
 }
 
 // GetStats returns aggregate statistics.
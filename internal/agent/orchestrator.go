@@ -0,0 +1,293 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dopejs/gozen/internal/crashreport"
+)
+
+// executeMultiAgentTask runs a task with sub-agents. The runtime acts as
+// supervisor: a planner splits the task into steps and names the files each
+// one changes, an implementer per step carries it out while holding
+// coordinator locks on those files, and a reviewer checks the implementers'
+// outputs merged in plan order. Up to MaxParallel implementers run at once.
+// Every sub-agent's tokens and cost count towards the task.
+func (r *Runtime) executeMultiAgentTask(task *RuntimeTask) {
+	defer r.recoverTask(task)
+
+	// Planning
+	planner := r.spawnSubAgent(task, RolePlanner, 0, nil)
+	response, err := r.runSubAgent(task, planner, "planning", fmt.Sprintf(`You are the planner of a team of agents. Break down the following task into clear, actionable steps.
+Each step is carried out by a separate implementer that does not see the other steps' work, so make each one self-contained.
+Return ONLY a JSON array of objects with the step description and the files the step changes, nothing else.
+
+Task: %s
+
+Example output format:
+[{"step": "Step 1 description", "files": ["path/to/file"]}, {"step": "Step 2 description", "files": []}]`, task.Description))
+	if err != nil {
+		r.failTask(task, fmt.Errorf("planning failed: %w", err))
+		return
+	}
+	plan := parsePlan(response, task.Description)
+
+	r.mu.Lock()
+	task.Plan = plan
+	r.mu.Unlock()
+
+	if r.isTaskCancelled(task.ID) {
+		return
+	}
+
+	// Execution
+	r.mu.Lock()
+	task.Status = RuntimeStatusExecuting
+	r.mu.Unlock()
+
+	parallel := r.config.MaxParallel
+	if parallel <= 0 {
+		parallel = 1
+	}
+	outputs := make([]string, len(plan.Steps))
+	errs := make([]error, len(plan.Steps))
+	slots := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, step := range plan.Steps {
+		slots <- struct{}{}
+		if err := r.checkTaskLimits(task); err != nil {
+			errs[i] = err
+			<-slots
+			break
+		}
+		if r.isTaskCancelled(task.ID) {
+			<-slots
+			break
+		}
+
+		var files []string
+		if i < len(plan.Files) {
+			files = plan.Files[i]
+		}
+		r.mu.Lock()
+		task.Plan.CurrentStep = i
+		r.mu.Unlock()
+
+		wg.Add(1)
+		go func(i int, step string, files []string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			defer func() {
+				if p := recover(); p != nil {
+					crashreport.Write("agent", p, debug.Stack())
+					errs[i] = fmt.Errorf("step %d panicked: %v", i+1, p)
+				}
+			}()
+			outputs[i], errs[i] = r.implementStep(task, plan, i, step, files)
+		}(i, step, files)
+	}
+	wg.Wait()
+
+	if r.isTaskCancelled(task.ID) {
+		return
+	}
+	for _, err := range errs {
+		if err != nil {
+			r.failTask(task, err)
+			return
+		}
+	}
+
+	// Review
+	merged := mergeStepOutputs(plan, outputs)
+	r.mu.Lock()
+	task.Status = RuntimeStatusValidating
+	r.mu.Unlock()
+
+	reviewer := r.spawnSubAgent(task, RoleReviewer, 0, nil)
+	verdict, err := r.runSubAgent(task, reviewer, "validation", fmt.Sprintf(`You are the reviewer of a team of agents. Each step of the task below was carried out by a separate implementer; their outputs follow in order.
+Determine if, together, they complete the task successfully.
+
+Original task: %s
+
+Output:
+%s
+
+Respond with ONLY "VALID" if the task was completed successfully, or "INVALID: <reason>" if not.`, task.Description, merged))
+	if err != nil {
+		r.failTask(task, fmt.Errorf("validation failed: %w", err))
+		return
+	}
+	verdict = strings.TrimSpace(verdict)
+	valid := strings.HasPrefix(strings.ToUpper(verdict), "VALID")
+
+	r.mu.Lock()
+	task.Status = RuntimeStatusCompleted
+	task.CompletedAt = time.Now()
+	task.Result = &TaskResult{
+		Success: valid,
+		Output:  merged,
+		Tokens:  task.TotalTokens,
+		Cost:    task.TotalCost,
+	}
+	if !valid {
+		task.Result.Error = verdict
+	}
+	r.mu.Unlock()
+}
+
+// implementStep runs an implementer sub-agent for one plan step. The
+// implementer holds coordinator locks on the step's files while it works,
+// waiting its turn when another session holds them.
+func (r *Runtime) implementStep(task *RuntimeTask, plan *TaskPlan, i int, step string, files []string) (string, error) {
+	sub := r.spawnSubAgent(task, RoleImplementer, i+1, files)
+
+	if coord := r.coordinator(); coord != nil && coord.IsEnabled() && len(files) > 0 {
+		defer coord.ReleaseAllLocks(sub.SessionID)
+		// Lock in a fixed order so implementers sharing files don't deadlock
+		sorted := append([]string(nil), files...)
+		sort.Strings(sorted)
+		for _, f := range sorted {
+			_, err := coord.AcquireWait(context.Background(), f, sub.SessionID, LockOptions{
+				TaskID: task.ID,
+				Reason: fmt.Sprintf("step %d: %s", i+1, step),
+			}, coord.LockWait())
+			if err != nil {
+				err = fmt.Errorf("step %d: locking %s: %w", i+1, f, err)
+				r.finishSubAgent(sub, "", err)
+				return "", err
+			}
+		}
+	}
+
+	var steps strings.Builder
+	for n, s := range plan.Steps {
+		fmt.Fprintf(&steps, "%d. %s\n", n+1, s)
+	}
+	prompt := fmt.Sprintf(`You are an implementer in a team of agents working on this task:
+%s
+
+The plan:
+%s
+Other implementers carry out the other steps. Execute step %d only and provide the result.
+
+Step %d: %s`, task.Description, steps.String(), i+1, i+1, step)
+	if len(files) > 0 {
+		prompt += "\n\nFiles this step changes: " + strings.Join(files, ", ")
+	}
+	output, err := r.runSubAgent(task, sub, "execution", prompt)
+	if err != nil {
+		return "", fmt.Errorf("execution of step %d failed: %w", i+1, err)
+	}
+	return output, nil
+}
+
+// mergeStepOutputs is the supervisor's merge of the implementers' outputs,
+// in plan order.
+func mergeStepOutputs(plan *TaskPlan, outputs []string) string {
+	var b strings.Builder
+	for i, step := range plan.Steps {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		fmt.Fprintf(&b, "## Step %d: %s\n\n%s", i+1, step, strings.TrimSpace(outputs[i]))
+	}
+	return b.String()
+}
+
+// spawnSubAgent adds a sub-agent for a role to a task, with the role's
+// profile and model.
+func (r *Runtime) spawnSubAgent(task *RuntimeTask, role string, step int, files []string) *SubAgent {
+	profile, model := r.roleRoute(role)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sub := &SubAgent{
+		ID:        fmt.Sprintf("%s-%d", role, len(task.SubAgents)+1),
+		Role:      role,
+		Profile:   profile,
+		Model:     model,
+		Step:      step,
+		Files:     files,
+		Status:    RuntimeStatusExecuting,
+		StartedAt: time.Now(),
+	}
+	sub.SessionID = "runtime-" + task.ID + "-" + sub.ID
+	task.SubAgents = append(task.SubAgents, sub)
+	return sub
+}
+
+// roleRoute returns the profile and model a role runs with: those set under
+// roles, else the default profile and the model of the role's phase.
+func (r *Runtime) roleRoute(role string) (profile, model string) {
+	profile = "default"
+	switch role {
+	case RolePlanner:
+		model = r.config.PlanningModel
+	case RoleReviewer:
+		model = r.config.ValidationModel
+	default:
+		model = r.config.ExecutionModel
+	}
+	if rc := r.config.Roles[role]; rc != nil {
+		if rc.Profile != "" {
+			profile = rc.Profile
+		}
+		if rc.Model != "" {
+			model = rc.Model
+		}
+	}
+	return profile, model
+}
+
+// runSubAgent sends a sub-agent's prompt and counts its usage towards both
+// the sub-agent and the task.
+func (r *Runtime) runSubAgent(task *RuntimeTask, sub *SubAgent, phase, prompt string) (string, error) {
+	response, tokens, cost, err := r.sendRequest(task, sub, sub.Model, phase, prompt)
+
+	r.mu.Lock()
+	sub.Tokens += tokens
+	sub.Cost += cost
+	task.TotalTokens += tokens
+	task.TotalCost += cost
+	r.mu.Unlock()
+
+	r.finishSubAgent(sub, response, err)
+	return response, err
+}
+
+// finishSubAgent records how a sub-agent ended.
+func (r *Runtime) finishSubAgent(sub *SubAgent, output string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sub.CompletedAt = time.Now()
+	if err != nil {
+		sub.Status = RuntimeStatusFailed
+		sub.Error = err.Error()
+		return
+	}
+	sub.Status = RuntimeStatusCompleted
+	sub.Output = output
+}
+
+// checkTaskLimits fails once a task has used up its turns or tokens.
+func (r *Runtime) checkTaskLimits(task *RuntimeTask) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(task.Turns) >= r.config.MaxTurns || task.TotalTokens >= r.config.MaxTokens {
+		return fmt.Errorf("task limits exceeded")
+	}
+	return nil
+}
+
+// coordinator returns the coordinator sub-agents take file locks from.
+func (r *Runtime) coordinator() *Coordinator {
+	if r.coord != nil {
+		return r.coord
+	}
+	return GetGlobalCoordinator()
+}
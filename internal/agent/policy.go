@@ -0,0 +1,377 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// PolicyDecision is the outcome of checking a tool call against the
+// guardrail policy: the most severe action among the rules that matched.
+type PolicyDecision struct {
+	Action string `json:"action"`
+	Rule   string `json:"rule"`
+	Reason string `json:"reason"`
+}
+
+// Blocks reports whether the decision refuses the tool call outright.
+func (d *PolicyDecision) Blocks() bool {
+	return d != nil && d.Action == config.GuardrailBlock
+}
+
+// PolicyRuleStats reports how often a policy rule matched.
+type PolicyRuleStats struct {
+	Name    string    `json:"name"`
+	Action  string    `json:"action"`
+	Hits    int       `json:"hits"`
+	LastHit time.Time `json:"last_hit,omitempty"`
+}
+
+// policyRule is a config.GuardrailRule ready for matching.
+type policyRule struct {
+	*config.GuardrailRule
+	commands []*regexp.Regexp
+}
+
+// actionSeverity orders rule actions; the highest wins.
+var actionSeverity = map[string]int{
+	config.GuardrailLog:   1,
+	config.GuardrailAsk:   2,
+	config.GuardrailBlock: 3,
+}
+
+// shellTools are the tool names, across the supported clients, that run a
+// shell command.
+var shellTools = map[string]bool{
+	"Bash":             true,
+	"bash":             true,
+	"shell":            true,
+	"local_shell":      true,
+	"exec_command":     true,
+	"run_terminal_cmd": true,
+	"run_command":      true,
+}
+
+// compilePolicy prepares the rules of a policy. Invalid command patterns
+// are skipped; config validation reports them.
+func compilePolicy(rules []*config.GuardrailRule) []*policyRule {
+	var compiled []*policyRule
+	for _, r := range rules {
+		if r == nil || actionSeverity[r.Action] == 0 {
+			continue
+		}
+		pr := &policyRule{GuardrailRule: r}
+		for _, pattern := range r.DenyCommands {
+			if re, err := regexp.Compile(pattern); err == nil {
+				pr.commands = append(pr.commands, re)
+			}
+		}
+		compiled = append(compiled, pr)
+	}
+	return compiled
+}
+
+// CheckToolCall checks a tool call against the guardrail policy, counting a
+// hit for every rule that matches and recording it as a sensitive
+// operation. It returns nil if no rule matches.
+func (g *Guardrails) CheckToolCall(sessionID, projectPath string, call ToolCall) *PolicyDecision {
+	if !g.IsEnabled() {
+		return nil
+	}
+	g.mu.RLock()
+	rules := g.policy
+	g.mu.RUnlock()
+	if len(rules) == 0 {
+		return nil
+	}
+
+	paths := toolCallPaths(call, projectPath)
+	command := toolCallCommand(call)
+	var decision *PolicyDecision
+	var ops []*SensitiveOperation
+	var matched []string
+	now := time.Now()
+	for _, rule := range rules {
+		reason := rule.match(call.Name, paths, command, projectPath)
+		if reason == "" {
+			continue
+		}
+		matched = append(matched, rule.Name)
+		ops = append(ops, &SensitiveOperation{
+			SessionID:   sessionID,
+			Type:        SensitiveOpPolicy,
+			Description: fmt.Sprintf("%s: %s (%s)", rule.Name, reason, rule.Action),
+			Path:        strings.Join(paths, ", "),
+			Timestamp:   now,
+			Blocked:     rule.Action != config.GuardrailLog,
+		})
+		if decision == nil || actionSeverity[rule.Action] > actionSeverity[decision.Action] {
+			decision = &PolicyDecision{Action: rule.Action, Rule: rule.Name, Reason: reason}
+		}
+	}
+	if decision == nil {
+		return nil
+	}
+
+	g.mu.Lock()
+	for _, name := range matched {
+		stats := g.ruleHits[name]
+		if stats == nil {
+			stats = &PolicyRuleStats{Name: name}
+			g.ruleHits[name] = stats
+		}
+		stats.Hits++
+		stats.LastHit = now
+	}
+	g.recordOperationsLocked(ops)
+	g.mu.Unlock()
+	return decision
+}
+
+// GetPolicyStats returns the policy rules with their hit counts, in policy
+// order.
+func (g *Guardrails) GetPolicyStats() []PolicyRuleStats {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	stats := make([]PolicyRuleStats, 0, len(g.policy))
+	for _, rule := range g.policy {
+		s := PolicyRuleStats{Name: rule.Name, Action: rule.Action}
+		if hits := g.ruleHits[rule.Name]; hits != nil {
+			s.Hits, s.LastHit = hits.Hits, hits.LastHit
+		}
+		stats = append(stats, s)
+	}
+	return stats
+}
+
+// match returns why the rule matches a tool call, or "" if it doesn't.
+func (r *policyRule) match(tool string, paths []string, command, projectPath string) string {
+	if len(r.Tools) > 0 && !containsFold(r.Tools, tool) {
+		return ""
+	}
+	for _, p := range paths {
+		if len(r.AllowPaths) > 0 && !matchesAnyPath(r.AllowPaths, p, projectPath) {
+			return fmt.Sprintf("path %s is outside the allowed paths", p)
+		}
+		if pattern := firstMatchingPath(r.DenyPaths, p, projectPath); pattern != "" {
+			return fmt.Sprintf("path %s matches denied pattern %s", p, pattern)
+		}
+	}
+	if command == "" {
+		return ""
+	}
+	for _, re := range r.commands {
+		if re.MatchString(command) {
+			return fmt.Sprintf("command matches denied pattern %s", re)
+		}
+	}
+	for _, arg := range commandPathArgs(command) {
+		if pattern := firstMatchingPath(r.DenyPaths, arg, projectPath); pattern != "" {
+			return fmt.Sprintf("command touches %s, which matches denied pattern %s", arg, pattern)
+		}
+	}
+	if branch := protectedBranchTouched(command, r.ProtectedBranches); branch != "" {
+		return fmt.Sprintf("command changes protected branch %s", branch)
+	}
+	return ""
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// toolCallPaths returns the files a tool call names, made absolute against
+// the project directory when it is known.
+func toolCallPaths(call ToolCall, projectPath string) []string {
+	var paths []string
+	if p := inputString(call.Input, "file_path", "path", "notebook_path"); p != "" {
+		paths = append(paths, p)
+	}
+	// apply_patch names its files inside the patch text
+	for _, line := range strings.Split(inputString(call.Input, "input", "patch"), "\n") {
+		for _, prefix := range []string{"*** Add File: ", "*** Update File: ", "*** Delete File: ", "*** Move to: "} {
+			if p, ok := strings.CutPrefix(line, prefix); ok {
+				paths = append(paths, strings.TrimSpace(p))
+			}
+		}
+	}
+	for i, p := range paths {
+		paths[i] = resolvePath(p, projectPath)
+	}
+	return paths
+}
+
+// toolCallCommand returns the shell command a tool call runs, or "".
+func toolCallCommand(call ToolCall) string {
+	if !shellTools[call.Name] {
+		return ""
+	}
+	var fields map[string]interface{}
+	if json.Unmarshal(call.Input, &fields) != nil {
+		return ""
+	}
+	for _, key := range []string{"command", "cmd"} {
+		switch v := fields[key].(type) {
+		case string:
+			return v
+		case []interface{}:
+			// Codex sends ["bash", "-lc", "<script>"]
+			parts := make([]string, 0, len(v))
+			for _, p := range v {
+				if s, ok := p.(string); ok {
+					parts = append(parts, s)
+				}
+			}
+			return strings.Join(parts, " ")
+		}
+	}
+	return ""
+}
+
+// commandPathArgs returns the arguments of a shell command that look like
+// paths.
+func commandPathArgs(command string) []string {
+	var args []string
+	for _, f := range strings.Fields(command) {
+		f = strings.Trim(f, `"'`)
+		if strings.ContainsRune(f, '/') || strings.HasPrefix(f, ".") || strings.HasPrefix(f, "~") {
+			args = append(args, f)
+		}
+	}
+	return args
+}
+
+// resolvePath expands ~ and makes a relative path absolute against the
+// project directory, if known.
+func resolvePath(p, projectPath string) string {
+	if rest, ok := strings.CutPrefix(p, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			p = filepath.Join(home, rest)
+		}
+	}
+	if !filepath.IsAbs(p) && projectPath != "" {
+		p = filepath.Join(projectPath, p)
+	}
+	return filepath.ToSlash(filepath.Clean(p))
+}
+
+// matchesAnyPath reports whether p matches one of the patterns.
+func matchesAnyPath(patterns []string, p, projectPath string) bool {
+	return firstMatchingPath(patterns, p, projectPath) != ""
+}
+
+// firstMatchingPath returns the first pattern that matches p or one of its
+// parent directories. An absolute pattern (or one starting with ~) is
+// matched from the root; a relative one at any depth, like a .gitignore
+// entry, so ".env" matches every .env file.
+func firstMatchingPath(patterns []string, p, projectPath string) string {
+	target := strings.Split(resolvePath(p, projectPath), "/")
+	for _, pattern := range patterns {
+		full := pattern
+		if strings.HasPrefix(full, "~/") {
+			full = resolvePath(full, "")
+		}
+		full = path.Clean(filepath.ToSlash(full))
+		var elems []string
+		if strings.HasPrefix(full, "/") {
+			elems = strings.Split(full, "/")
+		} else {
+			elems = append([]string{"**"}, strings.Split(full, "/")...)
+		}
+		if elemsOverlap(append(elems, "**"), target) {
+			return pattern
+		}
+	}
+	return ""
+}
+
+// gitCommandPattern splits a shell script into simple commands.
+var gitCommandPattern = regexp.MustCompile(`\s*(?:&&|\|\||;|\||\n)\s*`)
+
+// protectedBranchTouched returns the protected branch a git command in the
+// script pushes to, deletes or renames, or "". A plain "git push" without a
+// refspec is not caught, since the branch it pushes depends on the checkout.
+func protectedBranchTouched(command string, branches []string) string {
+	if len(branches) == 0 {
+		return ""
+	}
+	for _, simple := range gitCommandPattern.Split(command, -1) {
+		args := strings.Fields(simple)
+		for len(args) > 0 && args[0] != "git" {
+			args = args[1:]
+		}
+		if len(args) < 2 {
+			continue
+		}
+		args = args[1:]
+		// Skip global options such as -C dir or -c key=value
+		for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+			if args[0] == "-C" || args[0] == "-c" {
+				args = args[1:]
+			}
+			if len(args) > 0 {
+				args = args[1:]
+			}
+		}
+		if len(args) == 0 {
+			continue
+		}
+		sub, rest := args[0], args[1:]
+
+		var targets []string
+		switch sub {
+		case "push":
+			var positional []string
+			for _, a := range rest {
+				if !strings.HasPrefix(a, "-") {
+					positional = append(positional, a)
+				}
+			}
+			if len(positional) > 1 {
+				for _, refspec := range positional[1:] {
+					refspec = strings.TrimPrefix(refspec, "+")
+					if _, dst, ok := strings.Cut(refspec, ":"); ok {
+						refspec = dst
+					}
+					targets = append(targets, strings.TrimPrefix(refspec, "refs/heads/"))
+				}
+			}
+		case "branch":
+			changes := false
+			for _, a := range rest {
+				switch a {
+				case "-d", "-D", "--delete", "-m", "-M", "--move", "-f", "--force":
+					changes = true
+				default:
+					if !strings.HasPrefix(a, "-") {
+						targets = append(targets, a)
+					}
+				}
+			}
+			if !changes {
+				targets = nil
+			}
+		}
+		for _, t := range targets {
+			for _, b := range branches {
+				if t == b {
+					return b
+				}
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,264 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+const (
+	// sessionReportDir holds the stored session reports, under the config
+	// directory.
+	sessionReportDir = "agent-reports"
+	// maxSessionReports bounds the reports kept; the oldest go first.
+	maxSessionReports = 200
+	// maxReportErrors bounds the errors a report lists.
+	maxReportErrors = 10
+)
+
+// SessionReport summarizes an agent session's work: the tasks it ran, the
+// files it touched, the tests it ran, what it cost and what went wrong.
+// Tool calls, file edits and test runs come from the session's timeline, so
+// a long session reports its last 500 steps.
+type SessionReport struct {
+	SessionID       string       `json:"session_id"`
+	Profile         string       `json:"profile"`
+	Client          string       `json:"client"`
+	ProjectPath     string       `json:"project_path,omitempty"`
+	Status          string       `json:"status"` // the session's status when the report was made
+	StartTime       time.Time    `json:"start_time"`
+	LastActivity    time.Time    `json:"last_activity"`
+	GeneratedAt     time.Time    `json:"generated_at"`
+	Requests        int          `json:"requests"`
+	Tokens          int          `json:"tokens"`
+	Cost            float64      `json:"cost"` // USD
+	ToolCalls       int          `json:"tool_calls"`
+	FailedToolCalls int          `json:"failed_tool_calls"`
+	Tasks           []ReportTask `json:"tasks"`
+	Files           []ReportFile `json:"files"`
+	Tests           []ReportTest `json:"tests"`
+	Errors          []string     `json:"errors"`
+}
+
+// ReportTask is a task a session worked on.
+type ReportTask struct {
+	ID          string `json:"id,omitempty"`
+	Description string `json:"description"`
+	Status      string `json:"status,omitempty"`
+	Source      string `json:"source"` // "queue", "runtime" or "session"
+}
+
+// ReportFile is a file a session edited, with the lines its recorded
+// changes added and removed.
+type ReportFile struct {
+	Path    string `json:"path"`
+	Edits   int    `json:"edits"`
+	Added   int    `json:"added"`
+	Removed int    `json:"removed"`
+}
+
+// ReportTest is a test run of a session.
+type ReportTest struct {
+	Time       time.Time `json:"time"`
+	Command    string    `json:"command"`
+	Failed     bool      `json:"failed"`
+	DurationMs int64     `json:"duration_ms"`
+	Detail     string    `json:"detail,omitempty"`
+}
+
+// ReportSession builds a report of a session from the global observatory,
+// coordinator, task queue and runtime. It returns nil if the session is
+// unknown.
+func ReportSession(sessionID string) *SessionReport {
+	return BuildSessionReport(GetGlobalObservatory(), GetGlobalCoordinator(), GetGlobalTaskQueue(), GetGlobalRuntime(), sessionID)
+}
+
+// BuildSessionReport builds a report of a session from its observatory
+// record, the file changes the coordinator recorded for it and the tasks
+// the task queue and runtime ran for it. coord, tq and rt may be nil. It
+// returns nil if the observatory doesn't know the session.
+func BuildSessionReport(obs *Observatory, coord *Coordinator, tq *TaskQueue, rt *Runtime, sessionID string) *SessionReport {
+	if obs == nil {
+		return nil
+	}
+	session := obs.GetSession(sessionID)
+	if session == nil {
+		return nil
+	}
+
+	r := &SessionReport{
+		SessionID:   sessionID,
+		GeneratedAt: time.Now(),
+		Tasks:       make([]ReportTask, 0),
+		Files:       make([]ReportFile, 0),
+		Tests:       make([]ReportTest, 0),
+		Errors:      make([]string, 0),
+	}
+	files := make(map[string]*ReportFile)
+	file := func(path string) *ReportFile {
+		path = resolvePath(path, r.ProjectPath)
+		f := files[path]
+		if f == nil {
+			f = &ReportFile{Path: path}
+			files[path] = f
+		}
+		return f
+	}
+
+	session.mu.RLock()
+	r.Profile, r.Client, r.ProjectPath = session.Profile, session.Client, session.ProjectPath
+	r.Status, r.StartTime, r.LastActivity = session.Status, session.StartTime, session.LastActivity
+	r.Requests, r.Tokens, r.Cost = session.RequestCount, session.TotalTokens, session.TotalCost
+	if session.CurrentTask != "" {
+		r.Tasks = append(r.Tasks, ReportTask{Description: session.CurrentTask, Source: "session"})
+	}
+	for _, e := range session.timeline {
+		switch e.Type {
+		case TimelineToolStarted:
+			r.ToolCalls++
+		case TimelineToolFinished:
+			if e.Failed {
+				r.FailedToolCalls++
+			}
+		case TimelineFileEdited:
+			if e.Path != "" {
+				file(e.Path).Edits++
+			}
+		case TimelineTestRun:
+			r.Tests = append(r.Tests, ReportTest{Time: e.Time, Command: e.Command, Failed: e.Failed, DurationMs: e.DurationMs, Detail: e.Detail})
+		}
+	}
+	r.Errors = append(r.Errors, session.LastErrors...)
+	if session.LikelyStuck && session.StuckReason != "" {
+		r.Errors = append(r.Errors, "likely stuck: "+session.StuckReason)
+	}
+	session.mu.RUnlock()
+
+	if coord != nil {
+		changes := make(map[string]int)
+		for _, ch := range coord.GetChangesForSession(sessionID) {
+			if ch.Status == ChangeReverted {
+				continue
+			}
+			f := file(ch.Path)
+			added, removed := diffStat(ch.Diff)
+			f.Added += added
+			f.Removed += removed
+			changes[f.Path]++
+		}
+		for path, n := range changes {
+			// Edits made without an observed tool call, such as a runtime
+			// task's, show only as changes
+			files[path].Edits = max(files[path].Edits, n)
+		}
+	}
+	if tq != nil {
+		tq.mu.RLock()
+		for _, t := range tq.tasks {
+			if t.AssignedTo == sessionID {
+				r.Tasks = append(r.Tasks, ReportTask{ID: t.ID, Description: t.Description, Status: t.Status, Source: "queue"})
+			}
+		}
+		tq.mu.RUnlock()
+	}
+	if rt != nil {
+		rt.mu.RLock()
+		for _, t := range rt.tasks {
+			if !runtimeTaskHasSession(t, sessionID) {
+				continue
+			}
+			r.Tasks = append(r.Tasks, ReportTask{ID: t.ID, Description: t.Description, Status: t.Status, Source: "runtime"})
+			if t.Result != nil && t.Result.Error != "" {
+				r.Errors = append(r.Errors, fmt.Sprintf("task %s: %s", t.ID, t.Result.Error))
+			}
+		}
+		rt.mu.RUnlock()
+	}
+
+	for _, f := range files {
+		r.Files = append(r.Files, *f)
+	}
+	sort.Slice(r.Files, func(i, j int) bool { return r.Files[i].Path < r.Files[j].Path })
+	if len(r.Errors) > maxReportErrors {
+		r.Errors = r.Errors[len(r.Errors)-maxReportErrors:]
+	}
+	return r
+}
+
+// runtimeTaskHasSession reports whether a runtime task, or one of its
+// sub-agents, talks to the model as the session. It must be called with
+// the runtime's lock held.
+func runtimeTaskHasSession(t *RuntimeTask, sessionID string) bool {
+	if runtimeSession(t, nil) == sessionID {
+		return true
+	}
+	for _, sub := range t.SubAgents {
+		if sub.SessionID == sessionID {
+			return true
+		}
+	}
+	return false
+}
+
+func sessionReportPath(sessionID string) string {
+	return filepath.Join(config.ConfigDirPath(), sessionReportDir, url.PathEscape(sessionID)+".json")
+}
+
+// SaveSessionReport stores a report, replacing any earlier report of the
+// session, and drops the oldest reports beyond the last 200.
+func SaveSessionReport(r *SessionReport) error {
+	path := sessionReportPath(r.SessionID)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+	pruneSessionReports(filepath.Dir(path))
+	return nil
+}
+
+// LoadSessionReport returns the stored report of a session. The error is
+// os.ErrNotExist when there is none.
+func LoadSessionReport(sessionID string) (*SessionReport, error) {
+	data, err := os.ReadFile(sessionReportPath(sessionID))
+	if err != nil {
+		return nil, err
+	}
+	var r SessionReport
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func pruneSessionReports(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) <= maxSessionReports {
+		return
+	}
+	type report struct {
+		name    string
+		modTime time.Time
+	}
+	reports := make([]report, 0, len(entries))
+	for _, e := range entries {
+		if info, err := e.Info(); err == nil && filepath.Ext(e.Name()) == ".json" {
+			reports = append(reports, report{e.Name(), info.ModTime()})
+		}
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].modTime.After(reports[j].modTime) })
+	for _, old := range reports[min(maxSessionReports, len(reports)):] {
+		os.Remove(filepath.Join(dir, old.name))
+	}
+}
@@ -2,17 +2,22 @@ package agent
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"path/filepath"
+	"runtime/debug"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/crashreport"
 )
 
 // Runtime manages autonomous agent task execution.
@@ -21,6 +26,7 @@ type Runtime struct {
 	tasks     map[string]*RuntimeTask
 	client    *http.Client
 	proxyPort int
+	coord     *Coordinator // for sub-agent file locks; nil uses the global one
 	mu        sync.RWMutex
 }
 
@@ -102,11 +108,41 @@ func (r *Runtime) UpdateConfig(cfg *config.RuntimeConfig) {
 	r.config = cfg
 }
 
+// RuntimeTaskOptions are the settings of a task beyond its description.
+type RuntimeTaskOptions struct {
+	// ProjectPath gives the task the runtime tools, run in this directory
+	// through the sandbox of its project binding.
+	ProjectPath string
+	// MultiAgent runs the task with sub-agents; see executeMultiAgentTask.
+	MultiAgent bool
+}
+
 // StartTask starts a new autonomous task.
 func (r *Runtime) StartTask(description string) (*RuntimeTask, error) {
+	return r.StartTaskWithOptions(description, RuntimeTaskOptions{})
+}
+
+// StartMultiAgentTask starts a task run by sub-agents: a planner splits it
+// into steps, an implementer carries out each step, and a reviewer checks
+// the merged result. See executeMultiAgentTask.
+func (r *Runtime) StartMultiAgentTask(description string) (*RuntimeTask, error) {
+	return r.StartTaskWithOptions(description, RuntimeTaskOptions{MultiAgent: true})
+}
+
+// StartTaskWithOptions starts a new autonomous task with the given options.
+func (r *Runtime) StartTaskWithOptions(description string, opts RuntimeTaskOptions) (*RuntimeTask, error) {
 	if !r.IsEnabled() {
 		return nil, fmt.Errorf("runtime is not enabled")
 	}
+	if opts.ProjectPath != "" {
+		if !filepath.IsAbs(opts.ProjectPath) {
+			return nil, fmt.Errorf("project path %s is not absolute", opts.ProjectPath)
+		}
+		if info, err := os.Stat(opts.ProjectPath); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("project path %s is not a directory", opts.ProjectPath)
+		}
+		opts.ProjectPath = filepath.Clean(opts.ProjectPath)
+	}
 
 	task := &RuntimeTask{
 		ID:          generateRuntimeTaskID(),
@@ -115,6 +151,8 @@ func (r *Runtime) StartTask(description string) (*RuntimeTask, error) {
 		CreatedAt:   time.Now(),
 		StartedAt:   time.Now(),
 		Turns:       make([]*AgentTurn, 0),
+		ProjectPath: opts.ProjectPath,
+		MultiAgent:  opts.MultiAgent,
 	}
 
 	r.mu.Lock()
@@ -122,7 +160,11 @@ func (r *Runtime) StartTask(description string) (*RuntimeTask, error) {
 	r.mu.Unlock()
 
 	// Start execution in background
-	go r.executeTask(task)
+	if opts.MultiAgent {
+		go r.executeMultiAgentTask(task)
+	} else {
+		go r.executeTask(task)
+	}
 
 	return task, nil
 }
@@ -146,6 +188,21 @@ func (r *Runtime) GetAllTasks() []*RuntimeTask {
 	return tasks
 }
 
+// RunningTasks returns how many tasks have not finished.
+func (r *Runtime) RunningTasks() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	n := 0
+	for _, t := range r.tasks {
+		switch t.Status {
+		case RuntimeStatusPlanning, RuntimeStatusExecuting, RuntimeStatusValidating:
+			n++
+		}
+	}
+	return n
+}
+
 // CancelTask cancels a running task.
 func (r *Runtime) CancelTask(id string) bool {
 	r.mu.Lock()
@@ -164,20 +221,25 @@ func (r *Runtime) CancelTask(id string) bool {
 	return false
 }
 
+// recoverTask marks a task failed if its execution panicked. It must be
+// deferred.
+func (r *Runtime) recoverTask(task *RuntimeTask) {
+	if rec := recover(); rec != nil {
+		crashreport.Write("agent", rec, debug.Stack())
+		r.mu.Lock()
+		task.Status = RuntimeStatusFailed
+		task.Result = &TaskResult{
+			Success: false,
+			Error:   fmt.Sprintf("panic: %v", rec),
+		}
+		task.CompletedAt = time.Now()
+		r.mu.Unlock()
+	}
+}
+
 // executeTask runs the autonomous task execution loop.
 func (r *Runtime) executeTask(task *RuntimeTask) {
-	defer func() {
-		if rec := recover(); rec != nil {
-			r.mu.Lock()
-			task.Status = RuntimeStatusFailed
-			task.Result = &TaskResult{
-				Success: false,
-				Error:   fmt.Sprintf("panic: %v", rec),
-			}
-			task.CompletedAt = time.Now()
-			r.mu.Unlock()
-		}
-	}()
+	defer r.recoverTask(task)
 
 	// Phase 1: Planning
 	plan, err := r.planTask(task)
@@ -264,7 +326,7 @@ Task: %s
 Example output format:
 ["Step 1 description", "Step 2 description", "Step 3 description"]`, task.Description)
 
-	response, tokens, cost, err := r.sendRequest(task, r.config.PlanningModel, "planning", prompt)
+	response, tokens, cost, err := r.sendRequest(task, nil, r.config.PlanningModel, "planning", prompt)
 	if err != nil {
 		return nil, fmt.Errorf("planning failed: %w", err)
 	}
@@ -274,32 +336,43 @@ Example output format:
 	task.TotalCost += cost
 	r.mu.Unlock()
 
-	// Parse steps from response
-	var steps []string
-	// Try to extract JSON array from response
+	return parsePlan(response, task.Description), nil
+}
+
+// parsePlan reads the steps from a planner's response: a JSON array of step
+// descriptions, or of {"step", "files"} objects naming the files each step
+// changes. A response without a usable array makes a one-step plan of the
+// task itself.
+func parsePlan(response, description string) *TaskPlan {
 	response = strings.TrimSpace(response)
-	if err := json.Unmarshal([]byte(response), &steps); err != nil {
-		// Try to find JSON in response
-		start := strings.Index(response, "[")
-		end := strings.LastIndex(response, "]")
-		if start >= 0 && end > start {
-			if err := json.Unmarshal([]byte(response[start:end+1]), &steps); err != nil {
-				// Fallback: treat entire response as single step
-				steps = []string{task.Description}
+	// Try to find JSON in response
+	if start, end := strings.Index(response, "["), strings.LastIndex(response, "]"); start >= 0 && end > start {
+		response = response[start : end+1]
+	}
+
+	plan := &TaskPlan{}
+	var steps []string
+	var detailed []struct {
+		Step  string   `json:"step"`
+		Files []string `json:"files"`
+	}
+	if err := json.Unmarshal([]byte(response), &steps); err == nil {
+		plan.Steps = steps
+	} else if err := json.Unmarshal([]byte(response), &detailed); err == nil {
+		for _, d := range detailed {
+			if d.Step == "" {
+				continue
 			}
-		} else {
-			steps = []string{task.Description}
+			plan.Steps = append(plan.Steps, d.Step)
+			plan.Files = append(plan.Files, d.Files)
 		}
 	}
 
-	if len(steps) == 0 {
-		steps = []string{task.Description}
+	if len(plan.Steps) == 0 {
+		// Fallback: treat the whole task as a single step
+		return &TaskPlan{Steps: []string{description}}
 	}
-
-	return &TaskPlan{
-		Steps:       steps,
-		CurrentStep: 0,
-	}, nil
+	return plan
 }
 
 // executeStep executes a single step of the plan.
@@ -324,7 +397,7 @@ Current step to execute:
 Execute this step and provide the result.`, step)
 	}
 
-	response, tokens, cost, err := r.sendRequest(task, r.config.ExecutionModel, "execution", prompt)
+	response, tokens, cost, err := r.sendRequest(task, nil, r.config.ExecutionModel, "execution", prompt)
 	if err != nil {
 		return "", fmt.Errorf("execution failed: %w", err)
 	}
@@ -349,7 +422,7 @@ Output:
 
 Respond with ONLY "VALID" if the task was completed successfully, or "INVALID: <reason>" if not.`, task.Description, output)
 
-	response, tokens, cost, err := r.sendRequest(task, r.config.ValidationModel, "validation", prompt)
+	response, tokens, cost, err := r.sendRequest(task, nil, r.config.ValidationModel, "validation", prompt)
 	if err != nil {
 		return false, fmt.Errorf("validation failed: %w", err)
 	}
@@ -362,84 +435,175 @@ Respond with ONLY "VALID" if the task was completed successfully, or "INVALID: <
 	return strings.HasPrefix(strings.ToUpper(strings.TrimSpace(response)), "VALID"), nil
 }
 
-// sendRequest sends a request to the AI model.
-func (r *Runtime) sendRequest(task *RuntimeTask, model, phase, prompt string) (string, int, float64, error) {
-	reqBody := map[string]interface{}{
-		"model":      model,
-		"max_tokens": 4096,
-		"messages": []map[string]string{
-			{"role": "user", "content": prompt},
-		},
+// sendRequest sends a request to the AI model, as the task's own session or,
+// for a sub-agent, as the sub-agent's session on its role's profile. Usage
+// is tagged with the task ID either way, so the proxy attributes all of a
+// task's cost to it.
+//
+// In the execution phase of a task with a project, the model is given the
+// runtime tools. Its tool calls run through the project's sandbox and the
+// results go back to it until it answers without calling a tool; the
+// returned tokens and cost cover every turn.
+func (r *Runtime) sendRequest(task *RuntimeTask, sub *SubAgent, model, phase, prompt string) (string, int, float64, error) {
+	var exec *toolExecutor
+	if phase == "execution" {
+		exec = executorFor(task.ProjectPath)
+	}
+	messages := []interface{}{
+		map[string]interface{}{"role": "user", "content": prompt},
+	}
+
+	var totalTokens int
+	var totalCost float64
+	for {
+		reqBody := map[string]interface{}{
+			"model":      model,
+			"max_tokens": 4096,
+			"messages":   messages,
+		}
+		if exec != nil {
+			reqBody["tools"] = runtimeTools
+		}
+		respData, tokens, cost, err := r.postMessages(task, sub, model, phase, reqBody)
+		totalTokens += tokens
+		totalCost += cost
+		if err != nil {
+			return "", totalTokens, totalCost, err
+		}
+
+		var text []string
+		var results []interface{}
+		for _, block := range respData.Content {
+			switch block.Type {
+			case "text":
+				text = append(text, block.Text)
+			case "tool_use":
+				if exec == nil {
+					continue
+				}
+				// Record what the call changes for review
+				coord := r.coordinator()
+				if coord != nil {
+					coord.WatchEdit(runtimeSession(task, sub), task.ProjectPath, ToolCall{ID: block.ID, Name: block.Name, Input: block.Input})
+				}
+				output, failed := exec.Run(context.Background(), block.Name, block.Input)
+				if coord != nil {
+					coord.FinishEdit(runtimeSession(task, sub), ToolResult{ToolUseID: block.ID, IsError: failed})
+				}
+				results = append(results, map[string]interface{}{
+					"type":        "tool_result",
+					"tool_use_id": block.ID,
+					"content":     output,
+					"is_error":    failed,
+				})
+			}
+		}
+		if len(results) == 0 {
+			if len(text) == 0 {
+				return "", totalTokens, totalCost, fmt.Errorf("empty response")
+			}
+			return strings.Join(text, "\n"), totalTokens, totalCost, nil
+		}
+		if err := r.checkTaskLimits(task); err != nil {
+			return "", totalTokens, totalCost, err
+		}
+		messages = append(messages,
+			map[string]interface{}{"role": "assistant", "content": respData.Content},
+			map[string]interface{}{"role": "user", "content": results},
+		)
+	}
+}
+
+// runtimeSession is the proxy session a task, or one of its sub-agents,
+// talks to the model as.
+func runtimeSession(task *RuntimeTask, sub *SubAgent) string {
+	if sub != nil {
+		return sub.SessionID
 	}
+	return "runtime-" + task.ID
+}
+
+// messagesResponse is the part of a Messages API response the runtime reads.
+type messagesResponse struct {
+	Content []struct {
+		Type  string          `json:"type"`
+		Text  string          `json:"text,omitempty"`
+		ID    string          `json:"id,omitempty"`
+		Name  string          `json:"name,omitempty"`
+		Input json.RawMessage `json:"input,omitempty"`
+	} `json:"content"`
+	Usage struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
 
+// postMessages sends one Messages request through the proxy and records it
+// as a turn of the task.
+func (r *Runtime) postMessages(task *RuntimeTask, sub *SubAgent, model, phase string, reqBody map[string]interface{}) (*messagesResponse, int, float64, error) {
 	reqData, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", 0, 0, err
+		return nil, 0, 0, err
 	}
 
-	url := fmt.Sprintf("http://127.0.0.1:%d/default/runtime-%s/v1/messages", r.proxyPort, task.ID)
+	profile, session, tags := "default", runtimeSession(task, sub), "task:"+task.ID
+	if sub != nil {
+		profile, tags = sub.Profile, tags+",role:"+sub.Role
+	}
+	url := fmt.Sprintf("http://127.0.0.1:%d/%s/%s/v1/messages", r.proxyPort, profile, session)
 	req, err := http.NewRequest("POST", url, bytes.NewReader(reqData))
 	if err != nil {
-		return "", 0, 0, err
+		return nil, 0, 0, err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("anthropic-version", "2023-06-01")
+	req.Header.Set("X-Zen-Tags", tags)
 
 	resp, err := r.client.Do(req)
 	if err != nil {
-		return "", 0, 0, err
+		return nil, 0, 0, err
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", 0, 0, err
+		return nil, 0, 0, err
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", 0, 0, fmt.Errorf("request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, 0, 0, fmt.Errorf("request failed with status %d: %s", resp.StatusCode, string(body))
 	}
 
 	// Parse response
-	var respData struct {
-		Content []struct {
-			Type string `json:"type"`
-			Text string `json:"text"`
-		} `json:"content"`
-		Usage struct {
-			InputTokens  int `json:"input_tokens"`
-			OutputTokens int `json:"output_tokens"`
-		} `json:"usage"`
-	}
-
+	var respData messagesResponse
 	if err := json.Unmarshal(body, &respData); err != nil {
-		return "", 0, 0, err
+		return nil, 0, 0, err
 	}
 
+	// Calculate cost (simplified)
+	tokens := respData.Usage.InputTokens + respData.Usage.OutputTokens
+	cost := float64(respData.Usage.InputTokens)*0.003/1000 + float64(respData.Usage.OutputTokens)*0.015/1000
+
 	// Record turn
 	turn := &AgentTurn{
 		Model:     model,
 		Phase:     phase,
 		Request:   reqData,
 		Response:  body,
-		Tokens:    respData.Usage.InputTokens + respData.Usage.OutputTokens,
+		Tokens:    tokens,
+		Cost:      cost,
 		Timestamp: time.Now(),
 	}
+	if sub != nil {
+		turn.SubAgent = sub.ID
+	}
 
 	r.mu.Lock()
 	task.Turns = append(task.Turns, turn)
 	r.mu.Unlock()
 
-	// Extract text
-	if len(respData.Content) == 0 {
-		return "", 0, 0, fmt.Errorf("empty response")
-	}
-
-	// Calculate cost (simplified)
-	cost := float64(respData.Usage.InputTokens)*0.003/1000 + float64(respData.Usage.OutputTokens)*0.015/1000
-
-	return respData.Content[0].Text, respData.Usage.InputTokens + respData.Usage.OutputTokens, cost, nil
+	return &respData, tokens, cost, nil
 }
 
 // failTask marks a task as failed.
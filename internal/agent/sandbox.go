@@ -0,0 +1,240 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// ExecBackend runs the runtime's tool calls for a project: where its shell
+// commands run and how its files are reached. Each backend wraps a shell
+// script in whatever isolates it.
+type ExecBackend interface {
+	// Name is the backend's config name, such as "docker".
+	Name() string
+	// Command returns a command that runs script with sh -c in the
+	// project's working tree, passing args as $1, $2 and so on.
+	Command(ctx context.Context, script string, args ...string) *exec.Cmd
+}
+
+// defaultSandboxTimeout bounds a tool call unless the sandbox sets
+// timeout_sec.
+const defaultSandboxTimeout = 120 * time.Second
+
+// maxToolOutput bounds the tool output sent back to the model.
+const maxToolOutput = 16 * 1024
+
+// hostBackend runs tool calls directly in the project directory.
+type hostBackend struct {
+	dir string
+}
+
+func (b *hostBackend) Name() string { return config.SandboxHost }
+
+func (b *hostBackend) Command(ctx context.Context, script string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "sh", append([]string{"-c", script, "sh"}, args...)...)
+	cmd.Dir = b.dir
+	return cmd
+}
+
+// dockerBackend runs each tool call in a throwaway container with the
+// project mounted at /workspace.
+type dockerBackend struct {
+	dir string
+	cfg *config.SandboxConfig
+}
+
+func (b *dockerBackend) Name() string { return config.SandboxDocker }
+
+func (b *dockerBackend) Command(ctx context.Context, script string, args ...string) *exec.Cmd {
+	argv := []string{"run", "--rm", "-i", "-v", b.dir + ":/workspace", "-w", "/workspace"}
+	// Keep files written to the mount owned by the user running GoZen
+	if uid, gid := os.Getuid(), os.Getgid(); uid >= 0 && gid >= 0 {
+		argv = append(argv, "--user", fmt.Sprintf("%d:%d", uid, gid))
+	}
+	if !b.cfg.Network {
+		argv = append(argv, "--network", "none")
+	}
+	argv = append(argv, b.cfg.Args...)
+	argv = append(argv, b.cfg.Image, "sh", "-c", script, "sh")
+	return exec.CommandContext(ctx, "docker", append(argv, args...)...)
+}
+
+// firejailBackend runs tool calls under firejail, with the project
+// directory the only part of the home directory it can see.
+type firejailBackend struct {
+	dir string
+	cfg *config.SandboxConfig
+}
+
+func (b *firejailBackend) Name() string { return config.SandboxFirejail }
+
+func (b *firejailBackend) Command(ctx context.Context, script string, args ...string) *exec.Cmd {
+	argv := []string{"--quiet", "--whitelist=" + b.dir}
+	if !b.cfg.Network {
+		argv = append(argv, "--net=none")
+	}
+	argv = append(argv, b.cfg.Args...)
+	argv = append(argv, "sh", "-c", script, "sh")
+	cmd := exec.CommandContext(ctx, "firejail", append(argv, args...)...)
+	cmd.Dir = b.dir
+	return cmd
+}
+
+// newExecBackend returns the backend a sandbox config selects for a project
+// directory. A nil config runs on the host.
+func newExecBackend(dir string, sb *config.SandboxConfig) ExecBackend {
+	if sb == nil {
+		return &hostBackend{dir: dir}
+	}
+	switch sb.Backend {
+	case config.SandboxDocker:
+		return &dockerBackend{dir: dir, cfg: sb}
+	case config.SandboxFirejail:
+		return &firejailBackend{dir: dir, cfg: sb}
+	}
+	return &hostBackend{dir: dir}
+}
+
+// toolExecutor carries out the runtime's tool calls for a task's project.
+type toolExecutor struct {
+	backend ExecBackend
+	timeout time.Duration
+}
+
+// executorFor returns the executor for a task's project, using the sandbox
+// of the project's binding, or nil when the task has no project.
+func executorFor(projectPath string) *toolExecutor {
+	if projectPath == "" {
+		return nil
+	}
+	var sb *config.SandboxConfig
+	if _, binding := config.FindProjectBinding(projectPath); binding != nil {
+		sb = binding.Sandbox
+	}
+	e := &toolExecutor{backend: newExecBackend(projectPath, sb), timeout: defaultSandboxTimeout}
+	if sb != nil && sb.TimeoutSec > 0 {
+		e.timeout = time.Duration(sb.TimeoutSec) * time.Second
+	}
+	return e
+}
+
+// runtimeTools are the tools a runtime task with a project can call.
+var runtimeTools = []map[string]interface{}{
+	{
+		"name":        "bash",
+		"description": "Run a shell command in the project directory and return its output and exit code.",
+		"input_schema": map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"command": map[string]string{"type": "string"}},
+			"required":   []string{"command"},
+		},
+	},
+	{
+		"name":        "read_file",
+		"description": "Read a file, by its path relative to the project directory.",
+		"input_schema": map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"path": map[string]string{"type": "string"}},
+			"required":   []string{"path"},
+		},
+	},
+	{
+		"name":        "write_file",
+		"description": "Create or replace a file, by its path relative to the project directory.",
+		"input_schema": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path":    map[string]string{"type": "string"},
+				"content": map[string]string{"type": "string"},
+			},
+			"required": []string{"path", "content"},
+		},
+	},
+}
+
+// Run carries out one tool call and returns the text to send back to the
+// model, and whether the call failed.
+func (e *toolExecutor) Run(ctx context.Context, name string, input json.RawMessage) (string, bool) {
+	var in struct {
+		Command string `json:"command"`
+		Path    string `json:"path"`
+		Content string `json:"content"`
+	}
+	if err := json.Unmarshal(input, &in); err != nil {
+		return "invalid tool input: " + err.Error(), true
+	}
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	switch name {
+	case "bash":
+		if strings.TrimSpace(in.Command) == "" {
+			return "command is empty", true
+		}
+		return e.run(ctx, nil, in.Command)
+	case "read_file":
+		p, err := projectRelPath(in.Path)
+		if err != nil {
+			return err.Error(), true
+		}
+		return e.run(ctx, nil, `cat -- "$1"`, p)
+	case "write_file":
+		p, err := projectRelPath(in.Path)
+		if err != nil {
+			return err.Error(), true
+		}
+		out, failed := e.run(ctx, strings.NewReader(in.Content), `mkdir -p -- "$(dirname -- "$1")" && cat > "$1"`, p)
+		if failed {
+			return out, true
+		}
+		return fmt.Sprintf("wrote %d bytes to %s", len(in.Content), p), false
+	}
+	return "unknown tool " + name, true
+}
+
+// run runs a script through the backend and returns its combined output,
+// with the exit status when it failed.
+func (e *toolExecutor) run(ctx context.Context, stdin *strings.Reader, script string, args ...string) (string, bool) {
+	cmd := e.backend.Command(ctx, script, args...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	err := cmd.Run()
+
+	text := out.String()
+	if len(text) > maxToolOutput {
+		// Keep the end: that is where a command says how it finished
+		text = "[output truncated]\n" + strings.ToValidUTF8(text[len(text)-maxToolOutput:], "")
+	}
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		return text + fmt.Sprintf("\n[timed out after %s]", e.timeout), true
+	case err != nil:
+		return text + fmt.Sprintf("\n[%s sandbox: %v]", e.backend.Name(), err), true
+	}
+	return text, false
+}
+
+// projectRelPath checks that a tool's file path stays inside the project
+// directory and returns it cleaned.
+func projectRelPath(p string) (string, error) {
+	if p == "" {
+		return "", fmt.Errorf("path is empty")
+	}
+	clean := path.Clean(strings.ReplaceAll(p, `\`, "/"))
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("path %s is outside the project directory", p)
+	}
+	return clean, nil
+}
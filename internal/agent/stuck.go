@@ -0,0 +1,168 @@
+package agent
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// Activity is what one request shows of an agent session's work: the tool
+// calls the model made in its last turn, the results the client sent back,
+// and the tokens the request used.
+type Activity struct {
+	SessionID   string
+	Client      string
+	ProjectPath string
+	Timestamp   time.Time
+	Tokens      int
+	Cost        float64
+	ToolCalls   []ToolCall
+	ToolResults []ToolResult
+	Compression *Compression // set when the request was compressed
+}
+
+// ToolCall is a tool invocation by the model. Input holds the arguments as
+// JSON with object keys sorted, so identical calls have identical inputs.
+type ToolCall struct {
+	ID    string
+	Name  string
+	Input []byte
+}
+
+// ToolResult is the client's answer to a tool call.
+type ToolResult struct {
+	ToolUseID string
+	IsError   bool
+	Output    string // the end of the tool's output
+}
+
+// Compression describes context compression applied to a request.
+type Compression struct {
+	BytesBefore int
+	BytesAfter  int
+}
+
+// editTools are the tool names, across the supported clients, that modify
+// files. A call to one of them counts as progress.
+var editTools = map[string]bool{
+	"Edit":                        true,
+	"MultiEdit":                   true,
+	"Write":                       true,
+	"NotebookEdit":                true,
+	"str_replace_editor":          true,
+	"str_replace_based_edit_tool": true,
+	"apply_patch":                 true,
+	"edit_file":                   true,
+	"write_file":                  true,
+	"create_file":                 true,
+}
+
+// maxSeenCalls bounds the tool call IDs remembered per session. Clients
+// resend the whole conversation with every request, so the same call shows
+// up again whenever a request is retried.
+const maxSeenCalls = 64
+
+// RecordActivity records a request's activity for a session, registering
+// the session the first time it is seen, adds what it shows to the
+// session's timeline, and re-runs the stuck heuristics.
+func (o *Observatory) RecordActivity(a Activity) {
+	if a.SessionID == "" {
+		return
+	}
+	if a.Timestamp.IsZero() {
+		a.Timestamp = time.Now()
+	}
+
+	o.mu.RLock()
+	session, ok := o.sessions[a.SessionID]
+	cfg := *o.config
+	o.mu.RUnlock()
+	if !ok {
+		if !cfg.Enabled {
+			return
+		}
+		session = o.RegisterSession(a.SessionID, "", a.Client, a.ProjectPath)
+	}
+
+	session.mu.Lock()
+	prev := session.Status
+	wasStuck := session.LikelyStuck
+	session.recordTimeline(a)
+	session.LastActivity = a.Timestamp
+	session.RequestCount++
+	session.TotalTokens += a.Tokens
+	session.TotalCost += a.Cost
+	session.TokensSinceEdit += a.Tokens
+	for _, call := range a.ToolCalls {
+		session.recordToolCall(call, a.Timestamp)
+	}
+	session.assess(&cfg, a.Timestamp)
+	if session.LikelyStuck && !wasStuck {
+		session.addEvent(TimelineEvent{Time: a.Timestamp, Type: TimelineStuck, Detail: session.StuckReason})
+	}
+	status := session.Status
+	session.mu.Unlock()
+
+	if status != prev {
+		o.notify(session.ID, session.Profile, status)
+	}
+}
+
+// recordToolCall tracks repeated calls and file edits. It must be called
+// with s.mu held.
+func (s *ObservedSession) recordToolCall(call ToolCall, at time.Time) {
+	if call.ID != "" {
+		for _, id := range s.seenCalls {
+			if id == call.ID {
+				return
+			}
+		}
+		s.seenCalls = append(s.seenCalls, call.ID)
+		if len(s.seenCalls) > maxSeenCalls {
+			s.seenCalls = s.seenCalls[1:]
+		}
+	}
+
+	key := call.Name + "\\x00" + string(call.Input)
+	if key == s.lastCall {
+		s.callRepeats++
+	} else {
+		s.lastCall, s.lastCallName, s.callRepeats = key, call.Name, 1
+	}
+	if editTools[call.Name] {
+		s.LastFileEdit = at
+		s.TokensSinceEdit = 0
+	}
+}
+
+// assess runs the stuck heuristics and updates LikelyStuck, StuckReason and
+// Status. A paused or killed session keeps its status. It must be called
+// with s.mu held.
+func (s *ObservedSession) assess(cfg *config.ObservatoryConfig, now time.Time) {
+	reason := ""
+	since := s.LastFileEdit
+	if since.IsZero() {
+		since = s.StartTime
+	}
+	idle := now.Sub(since)
+	switch {
+	case s.RetryCount >= cfg.StuckThreshold:
+		reason = fmt.Sprintf("%d consecutive errors", s.RetryCount)
+	case cfg.LoopThreshold > 0 && s.callRepeats >= cfg.LoopThreshold:
+		reason = fmt.Sprintf("called %s with identical arguments %d times in a row", s.lastCallName, s.callRepeats)
+	case idle >= time.Duration(cfg.NoProgressMin)*time.Minute && s.TokensSinceEdit >= cfg.NoProgressTokens:
+		reason = fmt.Sprintf("no file modified in %d minutes while using %d tokens", int(idle.Minutes()), s.TokensSinceEdit)
+	}
+	s.LikelyStuck = reason != ""
+	s.StuckReason = reason
+
+	if s.Status == SessionStatusPaused || s.Status == SessionStatusKilled {
+		return
+	}
+	if s.LikelyStuck {
+		s.Status = SessionStatusStuck
+	} else {
+		s.Status = SessionStatusActive
+	}
+}
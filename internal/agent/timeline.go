@@ -0,0 +1,186 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Timeline event types.
+const (
+	TimelineToolStarted  = "tool_call_started"
+	TimelineToolFinished = "tool_call_finished"
+	TimelineFileEdited   = "file_edited"
+	TimelineTestRun      = "test_run"
+	TimelineCompression  = "compression_applied"
+	TimelineStuck        = "likely_stuck"
+)
+
+// maxTimelineEvents bounds the timeline kept per session.
+const maxTimelineEvents = 500
+
+// TimelineEvent is one step of an agent session.
+type TimelineEvent struct {
+	Seq        int       `json:"seq"`
+	Time       time.Time `json:"time"`
+	Type       string    `json:"type"`
+	Tool       string    `json:"tool,omitempty"`
+	ToolUseID  string    `json:"tool_use_id,omitempty"`
+	Path       string    `json:"path,omitempty"`
+	Command    string    `json:"command,omitempty"`
+	Failed     bool      `json:"failed,omitempty"`
+	DurationMs int64     `json:"duration_ms,omitempty"`
+	Detail     string    `json:"detail,omitempty"`
+}
+
+// pendingCall is a tool call whose result has not been seen yet.
+type pendingCall struct {
+	name    string
+	command string // for test runs
+	started time.Time
+}
+
+// testCommandPattern matches shell commands that run a test suite.
+var testCommandPattern = regexp.MustCompile(`(^|[\s;&|(])(go test|cargo test|pytest|py\.test|jest|vitest|mocha|rspec|phpunit|ctest|(npm|pnpm|yarn|bun)( run)? test|make (test|check)|mvn (test|verify)|gradlew? test|dotnet test|mix test)\b`)
+
+// GetTimeline returns a session's timeline events after seq, oldest first.
+// limit caps the number of events returned when positive; the most recent
+// events are kept. It returns false if the session is unknown.
+func (o *Observatory) GetTimeline(sessionID string, after, limit int) ([]TimelineEvent, bool) {
+	session := o.GetSession(sessionID)
+	if session == nil {
+		return nil, false
+	}
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+
+	events := make([]TimelineEvent, 0)
+	for _, e := range session.timeline {
+		if e.Seq > after {
+			events = append(events, e)
+		}
+	}
+	if limit > 0 && len(events) > limit {
+		events = events[len(events)-limit:]
+	}
+	return events, true
+}
+
+// addEvent appends an event to the timeline. It must be called with s.mu
+// held.
+func (s *ObservedSession) addEvent(e TimelineEvent) {
+	s.timelineSeq++
+	e.Seq = s.timelineSeq
+	s.timeline = append(s.timeline, e)
+	if len(s.timeline) > maxTimelineEvents {
+		s.timeline = s.timeline[len(s.timeline)-maxTimelineEvents:]
+	}
+}
+
+// recordTimeline adds the events a request shows. A tool call was made by
+// the response to the previous request, so it started when that request
+// finished; its result arrives with this one. It must be called with s.mu
+// held, before LastActivity is updated.
+func (s *ObservedSession) recordTimeline(a Activity) {
+	if c := a.Compression; c != nil {
+		s.addEvent(TimelineEvent{
+			Time:   a.Timestamp,
+			Type:   TimelineCompression,
+			Detail: fmt.Sprintf("%d → %d bytes", c.BytesBefore, c.BytesAfter),
+		})
+	}
+
+	started := s.LastActivity
+	if started.IsZero() || started.After(a.Timestamp) {
+		started = a.Timestamp
+	}
+	for _, call := range a.ToolCalls {
+		if call.ID == "" || s.pending[call.ID] != nil || s.sawCall(call.ID) {
+			continue
+		}
+		p := &pendingCall{name: call.Name, started: started}
+		e := TimelineEvent{Time: started, Type: TimelineToolStarted, Tool: call.Name, ToolUseID: call.ID}
+		if cmd := inputString(call.Input, "command", "cmd"); testCommandPattern.MatchString(cmd) {
+			p.command = cmd
+			e.Command = cmd
+		}
+		s.addEvent(e)
+		if editTools[call.Name] {
+			s.addEvent(TimelineEvent{
+				Time:      started,
+				Type:      TimelineFileEdited,
+				Tool:      call.Name,
+				ToolUseID: call.ID,
+				Path:      inputString(call.Input, "file_path", "path", "notebook_path"),
+			})
+		}
+		if s.pending == nil {
+			s.pending = make(map[string]*pendingCall)
+		}
+		if len(s.pending) < maxSeenCalls {
+			s.pending[call.ID] = p
+		}
+	}
+
+	for _, result := range a.ToolResults {
+		p := s.pending[result.ToolUseID]
+		if p == nil {
+			continue
+		}
+		delete(s.pending, result.ToolUseID)
+		duration := a.Timestamp.Sub(p.started).Milliseconds()
+		s.addEvent(TimelineEvent{
+			Time:       a.Timestamp,
+			Type:       TimelineToolFinished,
+			Tool:       p.name,
+			ToolUseID:  result.ToolUseID,
+			Failed:     result.IsError,
+			DurationMs: duration,
+		})
+		if p.command != "" {
+			s.addEvent(TimelineEvent{
+				Time:       a.Timestamp,
+				Type:       TimelineTestRun,
+				Tool:       p.name,
+				ToolUseID:  result.ToolUseID,
+				Command:    p.command,
+				Failed:     result.IsError,
+				DurationMs: duration,
+				Detail:     lastLine(result.Output),
+			})
+		}
+	}
+}
+
+// sawCall reports whether a tool call ID was already recorded. It must be
+// called with s.mu held.
+func (s *ObservedSession) sawCall(id string) bool {
+	for _, seen := range s.seenCalls {
+		if seen == id {
+			return true
+		}
+	}
+	return false
+}
+
+// inputString returns the first of keys that is a string in a tool input.
+func inputString(input []byte, keys ...string) string {
+	var fields map[string]interface{}
+	if json.Unmarshal(input, &fields) != nil {
+		return ""
+	}
+	for _, k := range keys {
+		if v, ok := fields[k].(string); ok {
+			return v
+		}
+	}
+	return ""
+}
+
+// lastLine returns the last non-empty line of a tool's output.
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -3,25 +3,56 @@
 package agent
 
 import (
+	"fmt"
 	"sync"
 	"time"
 )
 
-// FileLock represents a lock on a file by an agent session.
+// FileLock represents a lock on a file, directory subtree or glob by an
+// agent session.
 type FileLock struct {
-	Path      string    `json:"path"`
+	Path      string    `json:"path"`  // file path, directory ending in "/" or glob pattern
+	Scope     string    `json:"scope"` // "file", "dir", "glob"
 	SessionID string    `json:"session_id"`
+	TaskID    string    `json:"task_id,omitempty"` // task the lock was taken for
+	Reason    string    `json:"reason,omitempty"`
 	LockedAt  time.Time `json:"locked_at"`
 	ExpiresAt time.Time `json:"expires_at"`
 }
 
-// FileChange represents a file change made by an agent.
+// LockOptions carries what a session records about why it takes a lock.
+type LockOptions struct {
+	TaskID string
+	Reason string
+}
+
+// LockConflictError is returned when a lock overlaps one held by another
+// session.
+type LockConflictError struct {
+	Held *FileLock
+}
+
+func (e *LockConflictError) Error() string {
+	return fmt.Sprintf("%s is locked by session %s", e.Held.Path, e.Held.SessionID)
+}
+
+// FileChange represents a file change made by an agent. A change recorded
+// from the file's content before and after carries a unified diff of it and
+// can be reverted until it is.
 type FileChange struct {
-	Path       string    `json:"path"`
-	SessionID  string    `json:"session_id"`
-	ChangeType string    `json:"change_type"` // "create", "modify", "delete"
-	Summary    string    `json:"summary"`
-	Timestamp  time.Time `json:"timestamp"`
+	ID          string     `json:"id"`
+	Path        string     `json:"path"`
+	SessionID   string     `json:"session_id"`
+	ProjectPath string     `json:"project_path,omitempty"`
+	ChangeType  string     `json:"change_type"` // "create", "modify", "delete"
+	Summary     string     `json:"summary"`
+	Diff        string     `json:"diff,omitempty"`
+	Status      string     `json:"status"` // "pending", "approved", "reverted"
+	Revertable  bool       `json:"revertable"`
+	Timestamp   time.Time  `json:"timestamp"`
+	ReviewedAt  *time.Time `json:"reviewed_at,omitempty"`
+
+	before, after fileSnapshot
 }
 
 // ObservedSession represents a monitored agent session.
@@ -44,8 +75,23 @@ type ObservedSession struct {
 	Status      string `json:"status"` // "active", "idle", "stuck", "paused", "killed"
 
 	// Stuck detection
-	LastErrors []string `json:"last_errors,omitempty"`
-	RetryCount int      `json:"retry_count"`
+	LastErrors  []string `json:"last_errors,omitempty"`
+	RetryCount  int      `json:"retry_count"`
+	LikelyStuck bool     `json:"likely_stuck"`
+	StuckReason string   `json:"stuck_reason,omitempty"`
+
+	// Progress tracking for the stuck heuristics
+	LastFileEdit    time.Time `json:"last_file_edit,omitempty"`
+	TokensSinceEdit int       `json:"tokens_since_edit"`
+	lastCall        string    // tool name and arguments of the last tool call
+	lastCallName    string
+	callRepeats     int      // times lastCall was made in a row
+	seenCalls       []string // recent tool_use IDs, so retried requests don't count twice
+
+	// Timeline of the session's steps, served separately
+	timeline    []TimelineEvent
+	timelineSeq int
+	pending     map[string]*pendingCall // tool calls awaiting their result
 
 	// Internal
 	mu sync.RWMutex `json:"-"`
@@ -79,27 +125,51 @@ type TaskResult struct {
 type RuntimeTask struct {
 	ID          string       `json:"id"`
 	Description string       `json:"description"`
-	Status      string       `json:"status"` // "planning", "executing", "validating", "completed", "failed", "cancelled"
+	ProjectPath string       `json:"project_path,omitempty"` // where its tool calls run; none without it
+	Status      string       `json:"status"`                 // "planning", "executing", "validating", "completed", "failed", "cancelled"
 	Plan        *TaskPlan    `json:"plan,omitempty"`
 	Turns       []*AgentTurn `json:"turns,omitempty"`
 	Result      *TaskResult  `json:"result,omitempty"`
 	CreatedAt   time.Time    `json:"created_at"`
 	StartedAt   time.Time    `json:"started_at,omitempty"`
 	CompletedAt time.Time    `json:"completed_at,omitempty"`
-	TotalTokens int          `json:"total_tokens"`
+	TotalTokens int          `json:"total_tokens"` // across all sub-agents
 	TotalCost   float64      `json:"total_cost"`
+	MultiAgent  bool         `json:"multi_agent,omitempty"`
+	SubAgents   []*SubAgent  `json:"sub_agents,omitempty"`
 }
 
 // TaskPlan holds the execution plan for a task.
 type TaskPlan struct {
-	Steps       []string `json:"steps"`
-	CurrentStep int      `json:"current_step"`
+	Steps       []string   `json:"steps"`
+	Files       [][]string `json:"files,omitempty"` // files each step changes, when the planner named them
+	CurrentStep int        `json:"current_step"`
+}
+
+// SubAgent is an agent a multi-agent runtime task spawns for one role. It
+// talks to the model through its role's profile, as its own proxy session.
+type SubAgent struct {
+	ID          string    `json:"id"`
+	Role        string    `json:"role"` // "planner", "implementer", "reviewer"
+	Profile     string    `json:"profile"`
+	Model       string    `json:"model"`
+	SessionID   string    `json:"session_id"`
+	Step        int       `json:"step,omitempty"`  // 1-based plan step, for implementers
+	Files       []string  `json:"files,omitempty"` // files it held coordinator locks on
+	Status      string    `json:"status"`          // "executing", "completed", "failed", "cancelled"
+	Output      string    `json:"output,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	Tokens      int       `json:"tokens"`
+	Cost        float64   `json:"cost"`
+	StartedAt   time.Time `json:"started_at"`
+	CompletedAt time.Time `json:"completed_at,omitempty"`
 }
 
 // AgentTurn represents a single turn in an agent conversation.
 type AgentTurn struct {
 	Model     string    `json:"model"`
-	Phase     string    `json:"phase"` // "planning", "execution", "validation"
+	Phase     string    `json:"phase"`               // "planning", "execution", "validation"
+	SubAgent  string    `json:"sub_agent,omitempty"` // ID of the sub-agent that made the turn
 	Request   []byte    `json:"request,omitempty"`
 	Response  []byte    `json:"response,omitempty"`
 	Tokens    int       `json:"tokens"`
@@ -111,7 +181,7 @@ type AgentTurn struct {
 // SensitiveOperation represents a detected sensitive operation.
 type SensitiveOperation struct {
 	SessionID   string    `json:"session_id"`
-	Type        string    `json:"type"` // "file_delete", "config_modify", "db_operation", "network_call"
+	Type        string    `json:"type"` // "file_delete", "config_modify", "db_operation", "network_call", "policy"
 	Description string    `json:"description"`
 	Path        string    `json:"path,omitempty"`
 	Timestamp   time.Time `json:"timestamp"`
@@ -146,6 +216,13 @@ const (
 	RuntimeStatusCancelled  = "cancelled"
 )
 
+// Sub-agent roles
+const (
+	RolePlanner     = "planner"
+	RoleImplementer = "implementer"
+	RoleReviewer    = "reviewer"
+)
+
 // ChangeType constants
 const (
 	ChangeTypeCreate = "create"
@@ -153,10 +230,18 @@ const (
 	ChangeTypeDelete = "delete"
 )
 
+// Change review status constants
+const (
+	ChangePending  = "pending"
+	ChangeApproved = "approved"
+	ChangeReverted = "reverted"
+)
+
 // SensitiveOpType constants
 const (
 	SensitiveOpFileDelete   = "file_delete"
 	SensitiveOpConfigModify = "config_modify"
 	SensitiveOpDBOperation  = "db_operation"
 	SensitiveOpNetworkCall  = "network_call"
+	SensitiveOpPolicy       = "policy" // a guardrail policy rule matched a tool call
 )
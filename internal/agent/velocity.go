@@ -0,0 +1,120 @@
+package agent
+
+import (
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+const (
+	// spikeLearnWindows is how many windows of a session's spending are
+	// learned before a spike can be flagged.
+	spikeLearnWindows = 3
+	// spikeSmoothing weighs the latest window in the learned rate.
+	spikeSmoothing = 0.3
+	// A window must spend at least this much to count as a spike, so that
+	// a session that has barely spent anything yet is not flagged.
+	minSpikeCost   = 0.25
+	minSpikeTokens = 50000
+)
+
+// SpendAnomaly reports a session spending far faster than it normally does.
+// Rates are per minute; costs are in USD.
+type SpendAnomaly struct {
+	SessionID         string    `json:"session_id"`
+	CostRate          float64   `json:"cost_rate"`
+	BaselineCostRate  float64   `json:"baseline_cost_rate"`
+	TokenRate         float64   `json:"token_rate"`
+	BaselineTokenRate float64   `json:"baseline_token_rate"`
+	Multiple          float64   `json:"multiple"` // the configured spike multiple
+	Reason            string    `json:"reason"`   // "cost" or "tokens"
+	Timestamp         time.Time `json:"timestamp"`
+}
+
+// spendVelocity learns a session's usual spending rate. Spending is summed
+// over windows of SpikeWindowMin; each finished window is folded into a
+// moving average, which the current window is compared against.
+type spendVelocity struct {
+	windowStart  time.Time
+	cost         float64 // spent in the current window
+	tokens       float64
+	baseCost     float64 // learned per-minute rates
+	baseTokens   float64
+	learned      int  // windows folded into the learned rates
+	flagged      bool // the current window was already reported
+	lastActivity time.Time
+}
+
+// RecordUsage adds a request's cost and tokens to a session's spending and
+// returns an anomaly when the session's current window spends more than
+// SpikeMultiple times its learned rate. Each window is reported at most
+// once, and a flagged window is not learned from.
+func (g *Guardrails) RecordUsage(sessionID string, cost float64, tokens int, now time.Time) *SpendAnomaly {
+	if !g.IsEnabled() {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.spending[sessionID] += cost
+	multiple := g.config.SpikeMultiple
+	if multiple <= 0 {
+		return nil
+	}
+	window := time.Duration(g.config.SpikeWindowMin) * time.Minute
+	if window <= 0 {
+		window = config.DefaultSpikeWindowMin * time.Minute
+	}
+
+	v := g.velocity[sessionID]
+	if v == nil {
+		v = &spendVelocity{windowStart: now}
+		g.velocity[sessionID] = v
+	}
+	if elapsed := now.Sub(v.windowStart); elapsed >= window {
+		// A window the session sat idle through says nothing about its
+		// working rate
+		if !v.flagged && now.Sub(v.lastActivity) < window {
+			v.learn(v.cost/elapsed.Minutes(), v.tokens/elapsed.Minutes())
+		}
+		v.windowStart, v.cost, v.tokens, v.flagged = now, 0, 0, false
+	}
+	v.cost += cost
+	v.tokens += float64(tokens)
+	v.lastActivity = now
+
+	if v.flagged || v.learned < spikeLearnWindows {
+		return nil
+	}
+	minutes := window.Minutes()
+	a := &SpendAnomaly{
+		SessionID:         sessionID,
+		CostRate:          v.cost / minutes,
+		BaselineCostRate:  v.baseCost,
+		TokenRate:         v.tokens / minutes,
+		BaselineTokenRate: v.baseTokens,
+		Multiple:          multiple,
+		Timestamp:         now,
+	}
+	switch {
+	case v.baseCost > 0 && v.cost >= minSpikeCost && a.CostRate > multiple*v.baseCost:
+		a.Reason = "cost"
+	case v.baseTokens > 0 && v.tokens >= minSpikeTokens && a.TokenRate > multiple*v.baseTokens:
+		a.Reason = "tokens"
+	default:
+		return nil
+	}
+	v.flagged = true
+	return a
+}
+
+// learn folds a finished window's rates into the learned rates.
+func (v *spendVelocity) learn(costRate, tokenRate float64) {
+	if v.learned == 0 {
+		v.baseCost, v.baseTokens = costRate, tokenRate
+	} else {
+		v.baseCost += spikeSmoothing * (costRate - v.baseCost)
+		v.baseTokens += spikeSmoothing * (tokenRate - v.baseTokens)
+	}
+	v.learned++
+}
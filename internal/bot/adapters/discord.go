@@ -13,6 +13,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/dopejs/gozen/internal/crashreport"
 	"github.com/gorilla/websocket"
 )
 
@@ -59,7 +60,7 @@ func (a *DiscordAdapter) Start(ctx context.Context) error {
 
 	// Start Gateway WebSocket connection
 	a.wg.Add(1)
-	go a.gatewayLoop()
+	crashreport.Go("bot:discord", a.gatewayLoop)
 
 	return nil
 }
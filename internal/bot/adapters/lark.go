@@ -14,6 +14,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/dopejs/gozen/internal/crashreport"
 	"github.com/gorilla/websocket"
 )
 
@@ -61,7 +62,7 @@ func (a *LarkAdapter) Start(ctx context.Context) error {
 
 	// Start WebSocket event subscription
 	a.wg.Add(1)
-	go a.websocketLoop()
+	crashreport.Go("bot:lark", a.websocketLoop)
 
 	return nil
 }
@@ -12,6 +12,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/dopejs/gozen/internal/crashreport"
 	"github.com/gorilla/websocket"
 )
 
@@ -66,7 +67,7 @@ func (a *SlackAdapter) Start(ctx context.Context) error {
 	// Start Socket Mode if app_token is configured
 	if a.config.AppToken != "" {
 		a.wg.Add(1)
-		go a.socketModeLoop()
+		crashreport.Go("bot:slack", a.socketModeLoop)
 	} else {
 		log.Printf("[slack] app_token not configured, Socket Mode disabled (bot can only send messages)")
 	}
@@ -11,6 +11,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/dopejs/gozen/internal/crashreport"
 )
 
 // TelegramAdapter implements the Adapter interface for Telegram.
@@ -52,7 +54,7 @@ func (a *TelegramAdapter) Start(ctx context.Context) error {
 
 	a.ctx, a.cancel = context.WithCancel(ctx)
 	a.wg.Add(1)
-	go a.pollUpdates()
+	crashreport.Go("bot:telegram", a.pollUpdates)
 
 	return nil
 }
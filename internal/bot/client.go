@@ -5,9 +5,10 @@ import (
 	"fmt"
 	"net"
 	"os"
-	"path/filepath"
 	"sync"
 	"time"
+
+	"github.com/dopejs/gozen/internal/config"
 )
 
 // Client is the bot client for zen processes to communicate with the gateway.
@@ -32,7 +33,7 @@ type ClientHandlers struct {
 // NewClient creates a new bot client.
 func NewClient(processPath, gatewayPath string) *Client {
 	if gatewayPath == "" {
-		gatewayPath = filepath.Join(os.TempDir(), "zen-gateway.sock")
+		gatewayPath = config.BotSocketPath()
 	}
 
 	processID := fmt.Sprintf("zen-%d", os.Getpid())
@@ -7,12 +7,12 @@ import (
 	"log"
 	"net"
 	"os"
-	"path/filepath"
 	"sync"
 	"time"
 
 	"github.com/dopejs/gozen/internal/bot/adapters"
 	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/crashreport"
 )
 
 // GatewayConfig is the configuration for the bot gateway.
@@ -92,7 +92,7 @@ type Gateway struct {
 // NewGateway creates a new bot gateway.
 func NewGateway(cfg *GatewayConfig, logger *log.Logger) *Gateway {
 	if cfg.SocketPath == "" {
-		cfg.SocketPath = filepath.Join(os.TempDir(), "zen-gateway.sock")
+		cfg.SocketPath = config.BotSocketPath()
 	}
 
 	if cfg.MemoryDir == "" {
@@ -222,6 +222,15 @@ func (g *Gateway) ListAllProcesses() []*ProcessInfo {
 	return processes
 }
 
+// ConnectedPlatforms returns the platforms whose adapters started successfully.
+func (g *Gateway) ConnectedPlatforms() []string {
+	platforms := make([]string, 0, len(g.adapters))
+	for _, a := range g.adapters {
+		platforms = append(platforms, string(a.Platform()))
+	}
+	return platforms
+}
+
 // Skills returns the gateway's skill registry.
 func (g *Gateway) Skills() *SkillRegistry {
 	return g.skills
@@ -365,7 +374,7 @@ func (g *Gateway) acceptConnections() {
 		}
 
 		g.wg.Add(1)
-		go g.handleConnection(conn)
+		crashreport.Go("bot:ipc", func() { g.handleConnection(conn) })
 	}
 }
 
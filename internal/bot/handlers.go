@@ -3,15 +3,19 @@ package bot
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/dopejs/gozen/internal/bot/adapters"
+	"github.com/dopejs/gozen/internal/crashreport"
 )
 
 // handleMessage processes incoming messages from any platform.
 func (g *Gateway) handleMessage(msg *Message) {
+	defer crashreport.Recover("bot:" + string(msg.Platform))
+
 	// Determine if we should respond
 	requireMention := g.config.Interaction.RequireMention
 	if msg.IsDirectMsg {
@@ -369,8 +373,7 @@ func (g *Gateway) handleApprovalResponse(intent *ParsedIntent, session *Session,
 		UserID:    session.UserID,
 	}
 
-	g.sendIPCMessage(approval.ProcessID, IPCApprovalResp, approval.ID, response)
-	g.approvals.Remove(approval.ID)
+	g.resolveApproval(approval, response)
 
 	status := "rejected"
 	if response.Approved {
@@ -383,6 +386,8 @@ func (g *Gateway) handleApprovalResponse(intent *ParsedIntent, session *Session,
 
 // handleButtonClick processes button click events.
 func (g *Gateway) handleButtonClick(click *ButtonClick) {
+	defer crashreport.Recover("bot:" + string(click.Platform))
+
 	// Check if it's an approval button
 	if strings.HasPrefix(click.ButtonID, "approve_") || strings.HasPrefix(click.ButtonID, "reject_") {
 		approvalID := click.Data
@@ -398,8 +403,7 @@ func (g *Gateway) handleButtonClick(click *ButtonClick) {
 			UserID:    click.UserID,
 		}
 
-		g.sendIPCMessage(approval.ProcessID, IPCApprovalResp, approvalID, response)
-		g.approvals.Remove(approvalID)
+		g.resolveApproval(approval, response)
 
 		// Update the message to show result
 		status := "❌ Rejected"
@@ -428,17 +432,7 @@ func (g *Gateway) handleNotification(processID string, payload *NotificationPayl
 	}
 
 	// Build notification message
-	icon := "ℹ️"
-	switch payload.Level {
-	case NotifyWarning:
-		icon = "⚠️"
-	case NotifyError:
-		icon = "🔴"
-	case NotifySuccess:
-		icon = "✅"
-	}
-
-	text := fmt.Sprintf("%s **%s** [%s]\n\n%s", icon, payload.Title, process.Name, payload.Message)
+	text := fmt.Sprintf("%s **%s** [%s]\n\n%s", notificationIcon(payload.Level), payload.Title, process.Name, payload.Message)
 
 	// Send to default chat if configured
 	if g.config.Notifications.DefaultChat != nil {
@@ -454,6 +448,38 @@ func (g *Gateway) handleNotification(processID string, payload *NotificationPayl
 	}
 }
 
+// Notify sends a notification from the daemon itself (rather than a
+// process) to the default chat, honouring quiet hours like process
+// notifications do.
+func (g *Gateway) Notify(level, title, message string) {
+	if g.config.Notifications.DefaultChat == nil {
+		return
+	}
+	if g.isQuietHours() && level != NotifyError {
+		return
+	}
+	g.sendMessage(ReplyContext{
+		Platform: g.config.Notifications.DefaultChat.Platform,
+		ChatID:   g.config.Notifications.DefaultChat.ChatID,
+	}, &OutgoingMessage{
+		Text:   fmt.Sprintf("%s **%s**\n\n%s", notificationIcon(level), title, message),
+		Format: "markdown",
+	})
+}
+
+// notificationIcon returns the icon shown for a notification level.
+func notificationIcon(level string) string {
+	switch level {
+	case NotifyWarning:
+		return "⚠️"
+	case NotifyError:
+		return "🔴"
+	case NotifySuccess:
+		return "✅"
+	}
+	return "ℹ️"
+}
+
 // handleApprovalRequest handles approval requests from processes.
 func (g *Gateway) handleApprovalRequest(processID string, payload *ApprovalPayload) {
 	process := g.registry.Get(processID)
@@ -461,9 +487,58 @@ func (g *Gateway) handleApprovalRequest(processID string, payload *ApprovalPaylo
 		return
 	}
 
+	if _, err := g.postApproval(process.Name, processID, payload, nil); err != nil {
+		g.logger.Printf("Cannot send approval request: %v", err)
+	}
+}
+
+// Errors returned by RequestApproval.
+var (
+	ErrNoApprovalChat  = errors.New("no default chat configured")
+	ErrApprovalTimeout = errors.New("approval timed out")
+)
+
+// RequestApproval posts an approval request to the default chat on behalf
+// of source and waits until someone approves or rejects it, the payload's
+// timeout passes, or ctx is done.
+func (g *Gateway) RequestApproval(ctx context.Context, source string, payload ApprovalPayload) (*ApprovalResponsePayload, error) {
+	result := make(chan ApprovalResponsePayload, 1)
+	approval, err := g.postApproval(source, "", &payload, func(r ApprovalResponsePayload) {
+		result <- r
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var timeout <-chan time.Time
+	if payload.Timeout > 0 {
+		timer := time.NewTimer(time.Duration(payload.Timeout) * time.Second)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case r := <-result:
+		return &r, nil
+	case <-timeout:
+		err = ErrApprovalTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	g.approvals.Remove(payload.ID)
+	g.editMessage(approval.ReplyTo, approval.MessageID, &OutgoingMessage{
+		Text:   fmt.Sprintf("⌛ **Expired** [%s]\n\n**Action:** %s\n\n%s", source, payload.Action, payload.Description),
+		Format: "markdown",
+	})
+	return nil, err
+}
+
+// postApproval sends an approval request with approve and reject buttons
+// to the default chat and tracks it. The answer goes to respond if it is
+// set, otherwise to the process over IPC.
+func (g *Gateway) postApproval(source, processID string, payload *ApprovalPayload, respond func(ApprovalResponsePayload)) (*PendingApproval, error) {
 	if g.config.Notifications.DefaultChat == nil {
-		g.logger.Println("Cannot send approval request: no default chat configured")
-		return
+		return nil, ErrNoApprovalChat
 	}
 
 	replyTo := ReplyContext{
@@ -472,7 +547,7 @@ func (g *Gateway) handleApprovalRequest(processID string, payload *ApprovalPaylo
 	}
 
 	text := fmt.Sprintf("🔔 **Approval Request** [%s]\n\n**Action:** %s\n\n%s",
-		process.Name, payload.Action, payload.Description)
+		source, payload.Action, payload.Description)
 
 	if payload.Details != "" {
 		text += fmt.Sprintf("\n\n```\n%s\n```", payload.Details)
@@ -483,11 +558,15 @@ func (g *Gateway) handleApprovalRequest(processID string, payload *ApprovalPaylo
 		{ID: "reject_" + payload.ID, Label: "❌ Reject", Style: "danger", Data: payload.ID},
 	}
 
-	msgID, _ := g.sendMessage(replyTo, &OutgoingMessage{
+	msgID, err := g.sendMessage(replyTo, &OutgoingMessage{
 		Text:    text,
 		Format:  "markdown",
 		Buttons: buttons,
 	})
+	if err != nil && respond != nil {
+		// Nobody will see the request, so don't wait for an answer
+		return nil, err
+	}
 
 	// Track pending approval
 	timeout := time.Time{}
@@ -495,14 +574,28 @@ func (g *Gateway) handleApprovalRequest(processID string, payload *ApprovalPaylo
 		timeout = time.Now().Add(time.Duration(payload.Timeout) * time.Second)
 	}
 
-	g.approvals.Add(&PendingApproval{
+	approval := &PendingApproval{
 		ID:        payload.ID,
 		ProcessID: processID,
 		ReplyTo:   replyTo,
 		MessageID: msgID,
 		CreatedAt: time.Now(),
 		Timeout:   timeout,
-	})
+		respond:   respond,
+	}
+	g.approvals.Add(approval)
+	return approval, nil
+}
+
+// resolveApproval delivers the answer to an approval request and stops
+// tracking it.
+func (g *Gateway) resolveApproval(approval *PendingApproval, response ApprovalResponsePayload) {
+	g.approvals.Remove(approval.ID)
+	if approval.respond != nil {
+		approval.respond(response)
+		return
+	}
+	g.sendIPCMessage(approval.ProcessID, IPCApprovalResp, approval.ID, response)
 }
 
 // handleProcessResponse handles responses from processes.
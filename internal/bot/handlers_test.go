@@ -863,6 +863,33 @@ func TestGateway_handleNotification(t *testing.T) {
 	}
 }
 
+func TestGateway_Notify(t *testing.T) {
+	g := newTestGateway()
+	adapter := newMockAdapter(adapters.PlatformTelegram)
+	g.adapters = append(g.adapters, adapter)
+
+	// Without a default chat there is nowhere to send
+	g.Notify(NotifyInfo, "Daily Summary", "12 requests")
+	if len(adapter.sentMessages) != 0 {
+		t.Fatalf("expected no message without a default chat, got %d", len(adapter.sentMessages))
+	}
+
+	g.config.Notifications.DefaultChat = &struct {
+		Platform Platform `json:"platform"`
+		ChatID   string   `json:"chat_id"`
+	}{
+		Platform: PlatformTelegram,
+		ChatID:   "default-chat",
+	}
+	g.Notify(NotifyInfo, "Daily Summary", "12 requests")
+	if len(adapter.sentMessages) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(adapter.sentMessages))
+	}
+	if msg := adapter.sentMessages[0]; !contains(msg.Text, "Daily Summary") || !contains(msg.Text, "12 requests") {
+		t.Errorf("unexpected message: %s", msg.Text)
+	}
+}
+
 func TestGateway_handleNotification_NoDefaultChat(t *testing.T) {
 	g := newTestGateway()
 	adapter := newMockAdapter(adapters.PlatformTelegram)
@@ -1030,6 +1057,81 @@ func TestGateway_handleApprovalRequest_NoDefaultChat(t *testing.T) {
 	}
 }
 
+func TestGateway_RequestApproval(t *testing.T) {
+	newGateway := func() (*Gateway, *mockAdapter) {
+		g := newTestGateway()
+		adapter := newMockAdapter(adapters.PlatformTelegram)
+		g.adapters = append(g.adapters, adapter)
+		g.config.Notifications.DefaultChat = &struct {
+			Platform Platform `json:"platform"`
+			ChatID   string   `json:"chat_id"`
+		}{Platform: PlatformTelegram, ChatID: "default-chat"}
+		return g, adapter
+	}
+	payload := ApprovalPayload{ID: "guard-1", Action: "Run Bash", Description: "push to main", Timeout: 60}
+
+	t.Run("approved", func(t *testing.T) {
+		g, adapter := newGateway()
+		type result struct {
+			resp *ApprovalResponsePayload
+			err  error
+		}
+		done := make(chan result, 1)
+		go func() {
+			resp, err := g.RequestApproval(context.Background(), "guardrails", payload)
+			done <- result{resp, err}
+		}()
+
+		deadline := time.Now().Add(2 * time.Second)
+		for g.approvals.Get("guard-1") == nil {
+			if time.Now().After(deadline) {
+				t.Fatal("approval request was not posted")
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		if len(adapter.sentMessages) != 1 || !contains(adapter.sentMessages[0].Text, "[guardrails]") {
+			t.Fatalf("sent messages = %+v", adapter.sentMessages)
+		}
+		g.handleButtonClick(&ButtonClick{
+			Platform: PlatformTelegram,
+			ChatID:   "default-chat",
+			UserID:   "user-1",
+			ButtonID: "approve_guard-1",
+			Data:     "guard-1",
+		})
+
+		r := <-done
+		if r.err != nil || !r.resp.Approved || r.resp.UserID != "user-1" {
+			t.Errorf("RequestApproval = %+v, %v", r.resp, r.err)
+		}
+		if g.approvals.Get("guard-1") != nil {
+			t.Error("approval still pending")
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		g, adapter := newGateway()
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if _, err := g.RequestApproval(ctx, "guardrails", payload); err != context.DeadlineExceeded {
+			t.Errorf("err = %v, want deadline exceeded", err)
+		}
+		if g.approvals.Get("guard-1") != nil {
+			t.Error("approval still pending")
+		}
+		if edited := adapter.editedMsgs["msg-default-chat"]; edited == nil || !contains(edited.Text, "Expired") {
+			t.Errorf("request message not marked expired: %+v", edited)
+		}
+	})
+
+	t.Run("no default chat", func(t *testing.T) {
+		g := newTestGateway()
+		if _, err := g.RequestApproval(context.Background(), "guardrails", payload); err != ErrNoApprovalChat {
+			t.Errorf("err = %v, want ErrNoApprovalChat", err)
+		}
+	})
+}
+
 func TestGateway_handleProcessResponse(t *testing.T) {
 	g := newTestGateway()
 
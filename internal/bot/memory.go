@@ -7,6 +7,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/dopejs/gozen/internal/config"
 )
 
 // ChatMessage represents a single message in conversation history.
@@ -69,14 +71,9 @@ func (b *ConversationBuffer) Len() int {
 }
 
 // MemoryDir returns the default bots memory directory path.
-// Respects GOZEN_CONFIG_DIR for dev environments.
+// Respects ZEN_HOME and GOZEN_CONFIG_DIR.
 func MemoryDir() string {
-	base := os.Getenv("GOZEN_CONFIG_DIR")
-	if base == "" {
-		home, _ := os.UserHomeDir()
-		base = filepath.Join(home, ".zen")
-	}
-	return filepath.Join(base, "bots")
+	return filepath.Join(config.ConfigDirPath(), "bots")
 }
 
 // MemoryFilePath returns the path to memory.md in the given directory.
@@ -144,6 +144,8 @@ type PendingApproval struct {
 	MessageID string       `json:"message_id"` // The message with buttons
 	CreatedAt time.Time    `json:"created_at"`
 	Timeout   time.Time    `json:"timeout,omitempty"`
+
+	respond func(ApprovalResponsePayload) // set for requests made in-process
 }
 
 // ApprovalManager manages pending approval requests.
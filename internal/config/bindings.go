@@ -0,0 +1,326 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Project bindings are keyed by absolute directory path, or by the git
+// remote of a repository so a binding follows the repo across worktrees and
+// machines:
+//
+//	/home/me/work/api          the directory itself
+//	git:github.com/acme/api    any checkout whose remote is this URL
+//	repo:api                   any checkout whose origin repo is named "api"
+//	~/work/*                   any directory matching the pattern
+//
+// When several match, a path binding wins over a remote binding, which wins
+// over a repo-name binding, which wins over a pattern. Among patterns the
+// one with the longest literal part wins.
+const (
+	BindingRemotePrefix = "git:"
+	BindingRepoPrefix   = "repo:"
+)
+
+// IsPatternBinding reports whether a binding key is a path pattern. In a
+// pattern "*" matches within one path element, "**" any number of elements,
+// and a leading "~" is the home directory.
+func IsPatternBinding(key string) bool {
+	return IsPathBinding(key) && (strings.ContainsAny(key, "*?[") || strings.HasPrefix(key, "~"))
+}
+
+// ValidateBindingKey checks that a binding key can match: an absolute path
+// or pattern, a git remote or a repo name.
+func ValidateBindingKey(key string) error {
+	switch {
+	case strings.TrimSpace(key) == "":
+		return fmt.Errorf("binding path is empty")
+	case strings.HasPrefix(key, BindingRemotePrefix):
+		if NormalizeRemoteURL(strings.TrimPrefix(key, BindingRemotePrefix)) == "." {
+			return fmt.Errorf("binding %q has no remote URL", key)
+		}
+	case strings.HasPrefix(key, BindingRepoPrefix):
+		if RepoBindingKey(strings.TrimPrefix(key, BindingRepoPrefix)) == BindingRepoPrefix {
+			return fmt.Errorf("binding %q has no repo name", key)
+		}
+	case IsPatternBinding(key):
+		pattern := expandHome(key)
+		if !filepath.IsAbs(pattern) {
+			return fmt.Errorf("binding pattern %q must be absolute or start with ~/", key)
+		}
+		if _, err := path.Match(filepath.ToSlash(pattern), ""); err != nil {
+			return fmt.Errorf("binding pattern %q is invalid: %w", key, err)
+		}
+	case !filepath.IsAbs(key):
+		return fmt.Errorf("binding path %q must be absolute", key)
+	}
+	return nil
+}
+
+// IsPathBinding reports whether a binding key is a directory path rather
+// than a git remote or repo name.
+func IsPathBinding(key string) bool {
+	return !strings.HasPrefix(key, BindingRemotePrefix) && !strings.HasPrefix(key, BindingRepoPrefix)
+}
+
+// RemoteBindingKey returns the binding key for a git remote URL. The same
+// repository reached over SSH or HTTPS gets the same key.
+func RemoteBindingKey(remote string) string {
+	return BindingRemotePrefix + NormalizeRemoteURL(remote)
+}
+
+// RepoBindingKey returns the binding key for a repository name.
+func RepoBindingKey(name string) string {
+	return BindingRepoPrefix + strings.ToLower(strings.TrimSpace(name))
+}
+
+// normalizeBindingKey cleans a binding key: paths are resolved, remote URLs
+// and repo names normalized.
+func normalizeBindingKey(key string) string {
+	switch {
+	case strings.HasPrefix(key, BindingRemotePrefix):
+		return RemoteBindingKey(strings.TrimPrefix(key, BindingRemotePrefix))
+	case strings.HasPrefix(key, BindingRepoPrefix):
+		return RepoBindingKey(strings.TrimPrefix(key, BindingRepoPrefix))
+	case IsPatternBinding(key):
+		return filepath.Clean(key)
+	}
+	return resolveProjectPath(key)
+}
+
+// expandHome replaces a leading "~" with the home directory.
+func expandHome(p string) string {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		if home := os.Getenv("HOME"); home != "" {
+			return filepath.Join(home, p[1:])
+		}
+	}
+	return p
+}
+
+// matchPattern reports whether dir matches a binding pattern.
+func matchPattern(pattern, dir string) bool {
+	pattern = filepath.ToSlash(expandHome(pattern))
+	return matchElems(strings.Split(pattern, "/"), strings.Split(filepath.ToSlash(dir), "/"))
+}
+
+func matchElems(pattern, elems []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Any number of elements, including none
+			for i := 0; i <= len(elems); i++ {
+				if matchElems(pattern[1:], elems[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(elems) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], elems[0]); err != nil || !ok {
+			return false
+		}
+		pattern, elems = pattern[1:], elems[1:]
+	}
+	return len(elems) == 0
+}
+
+// patternLiteral returns the number of literal characters in a pattern,
+// which ranks patterns matching the same directory.
+func patternLiteral(pattern string) int {
+	n := 0
+	for _, r := range expandHome(pattern) {
+		if r != '*' && r != '?' {
+			n++
+		}
+	}
+	return n
+}
+
+// matchingPattern returns the pattern key of bindings that best matches dir,
+// or "". The pattern with the longest literal part wins; ties go to the
+// lexically smaller key so the choice is stable.
+func matchingPattern(bindings map[string]*ProjectBinding, dir string) string {
+	candidates := []string{filepath.Clean(dir)}
+	if resolved := resolveProjectPath(dir); resolved != candidates[0] {
+		candidates = append(candidates, resolved)
+	}
+	best, bestLen := "", -1
+	for key, pb := range bindings {
+		if pb == nil || !IsPatternBinding(key) {
+			continue
+		}
+		for _, c := range candidates {
+			if !matchPattern(key, c) {
+				continue
+			}
+			if n := patternLiteral(key); n > bestLen || (n == bestLen && key < best) {
+				best, bestLen = key, n
+			}
+			break
+		}
+	}
+	return best
+}
+
+// NormalizeRemoteURL reduces a git remote URL to "host/owner/repo", dropping
+// the scheme, user, port and ".git" suffix, so that
+// git@github.com:acme/api.git and https://github.com/acme/api are equal.
+// Local paths are returned cleaned.
+func NormalizeRemoteURL(remote string) string {
+	remote = strings.TrimSpace(remote)
+	var host, repoPath string
+	if strings.Contains(remote, "://") {
+		u, err := url.Parse(remote)
+		if err != nil {
+			return strings.ToLower(remote)
+		}
+		host, repoPath = u.Hostname(), u.Path
+	} else if i := strings.Index(remote, ":"); i > 0 && !strings.Contains(remote[:i], "/") {
+		// scp-like syntax: [user@]host:owner/repo
+		host, repoPath = remote[:i], remote[i+1:]
+		if at := strings.LastIndex(host, "@"); at >= 0 {
+			host = host[at+1:]
+		}
+	} else {
+		return filepath.Clean(remote)
+	}
+	repoPath = strings.TrimSuffix(strings.Trim(repoPath, "/"), ".git")
+	return strings.ToLower(host + "/" + repoPath)
+}
+
+// GitRemotes returns the remote URLs of the git repository containing dir,
+// origin first, or nil if dir is not in a repository. Worktrees and
+// submodules, whose .git is a file, are followed to their repository.
+func GitRemotes(dir string) []string {
+	gitDir := findGitDir(dir)
+	if gitDir == "" {
+		return nil
+	}
+	// Linked worktrees keep the shared config in the common directory
+	if data, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		common := strings.TrimSpace(string(data))
+		if !filepath.IsAbs(common) {
+			common = filepath.Join(gitDir, common)
+		}
+		gitDir = common
+	}
+	f, err := os.Open(filepath.Join(gitDir, "config"))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var remotes []string
+	var section string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			section = strings.TrimSpace(strings.Trim(line, "[]"))
+			continue
+		}
+		name, ok := strings.CutPrefix(section, "remote ")
+		if !ok {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || !strings.EqualFold(strings.TrimSpace(key), "url") {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+		if strings.Trim(name, `"`) == "origin" {
+			remotes = append([]string{value}, remotes...)
+		} else {
+			remotes = append(remotes, value)
+		}
+	}
+	return remotes
+}
+
+// findGitDir returns the git directory of the repository containing dir.
+func findGitDir(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		dotGit := filepath.Join(dir, ".git")
+		if info, err := os.Stat(dotGit); err == nil {
+			if info.IsDir() {
+				return dotGit
+			}
+			data, err := os.ReadFile(dotGit)
+			if err != nil {
+				return ""
+			}
+			gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+			if !ok {
+				return ""
+			}
+			gitDir = strings.TrimSpace(gitDir)
+			if !filepath.IsAbs(gitDir) {
+				gitDir = filepath.Join(dir, gitDir)
+			}
+			return gitDir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// bindingCandidates returns the binding keys that apply to dir, most
+// specific first.
+func bindingCandidates(dir string, withGit bool) []string {
+	keys := []string{resolveProjectPath(dir)}
+	if !withGit {
+		return keys
+	}
+	remotes := GitRemotes(dir)
+	for _, remote := range remotes {
+		keys = append(keys, RemoteBindingKey(remote))
+	}
+	if len(remotes) > 0 {
+		keys = append(keys, RepoBindingKey(path.Base(NormalizeRemoteURL(remotes[0]))))
+	}
+	return keys
+}
+
+// FindProjectBinding returns the binding that applies to directory dir and
+// the key it is stored under, or "" and nil. A binding for the directory
+// itself takes precedence over one for its git remote or repo name, and
+// those over patterns.
+func (s *Store) FindProjectBinding(dir string) (string, *ProjectBinding) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil || len(s.config.ProjectBindings) == 0 {
+		return "", nil
+	}
+	// Only read the git config when a binding could use it
+	withGit := false
+	for key := range s.config.ProjectBindings {
+		if !IsPathBinding(key) && s.config.ProjectBindings[key] != nil {
+			withGit = true
+			break
+		}
+	}
+	for _, key := range bindingCandidates(dir, withGit) {
+		if pb := s.config.ProjectBindings[key]; pb != nil {
+			return key, pb.Clone()
+		}
+	}
+	if key := matchingPattern(s.config.ProjectBindings, dir); key != "" {
+		return key, s.config.ProjectBindings[key].Clone()
+	}
+	return "", nil
+}
@@ -155,6 +155,156 @@ func TestUnbindNonexistentPath(t *testing.T) {
 	}
 }
 
+func TestProjectCompressionOverride(t *testing.T) {
+	home := setTestHome(t)
+	testPath := filepath.Join(home, "monorepo")
+
+	enabled := true
+	override := &CompressionOverride{Enabled: &enabled, ThresholdTokens: 20000}
+	if err := SetProjectCompression(testPath, override); err == nil {
+		t.Error("SetProjectCompression() on an unbound project should error")
+	}
+	if err := BindProject(testPath, "", ""); err != nil {
+		t.Fatalf("BindProject() error: %v", err)
+	}
+	if err := SetProjectCompression(testPath, override); err != nil {
+		t.Fatalf("SetProjectCompression() error: %v", err)
+	}
+
+	// Rebinding keeps the override
+	if err := BindProject(testPath, "", "codex"); err != nil {
+		t.Fatalf("BindProject() error: %v", err)
+	}
+	binding := GetProjectBinding(testPath)
+	if binding.Compression == nil || binding.Compression.ThresholdTokens != 20000 || !*binding.Compression.Enabled {
+		t.Fatalf("binding.Compression = %+v", binding.Compression)
+	}
+
+	cfg := binding.Compression.Apply(&CompressionConfig{Enabled: false, ThresholdTokens: 50000, TargetTokens: 10000})
+	if !cfg.Enabled || cfg.ThresholdTokens != 20000 || cfg.TargetTokens != 10000 {
+		t.Errorf("Apply() = %+v", cfg)
+	}
+
+	if err := SetProjectCompression(testPath, nil); err != nil {
+		t.Fatalf("SetProjectCompression(nil) error: %v", err)
+	}
+	if GetProjectBinding(testPath).Compression != nil {
+		t.Error("expected override to be removed")
+	}
+}
+
+func TestProjectSandbox(t *testing.T) {
+	home := setTestHome(t)
+	testPath := filepath.Join(home, "service")
+
+	sandbox := &SandboxConfig{Backend: SandboxDocker, Image: "golang:1.23", Args: []string{"--memory=2g"}}
+	if err := BindProject(testPath, "", ""); err != nil {
+		t.Fatalf("BindProject() error: %v", err)
+	}
+	if err := SetProjectSandbox(testPath, &SandboxConfig{Backend: SandboxDocker}); err == nil {
+		t.Error("SetProjectSandbox() without an image should error")
+	}
+	if err := SetProjectSandbox(testPath, sandbox); err != nil {
+		t.Fatalf("SetProjectSandbox() error: %v", err)
+	}
+
+	// Rebinding keeps the sandbox
+	if err := BindProject(testPath, "", "codex"); err != nil {
+		t.Fatalf("BindProject() error: %v", err)
+	}
+	if sb := GetProjectBinding(testPath).Sandbox; sb == nil || sb.Image != "golang:1.23" || len(sb.Args) != 1 {
+		t.Fatalf("binding.Sandbox = %+v", sb)
+	}
+
+	if err := SetProjectSandbox(testPath, nil); err != nil {
+		t.Fatalf("SetProjectSandbox(nil) error: %v", err)
+	}
+	if GetProjectBinding(testPath).Sandbox != nil {
+		t.Error("expected sandbox to be removed")
+	}
+}
+
+func TestProjectClaudeSettings(t *testing.T) {
+	home := setTestHome(t)
+	testPath := filepath.Join(home, "service")
+
+	if err := SetProjectClaudeSettings(testPath, &ClaudeSettingsConfig{Enabled: true}); err == nil {
+		t.Error("SetProjectClaudeSettings() on an unbound project should error")
+	}
+	if err := BindProject(testPath, "", ""); err != nil {
+		t.Fatalf("BindProject() error: %v", err)
+	}
+	cs := &ClaudeSettingsConfig{Enabled: true, Model: "opus", Env: map[string]string{"A": "1"}, MCPServers: []string{"github"}}
+	if err := SetProjectClaudeSettings(testPath, cs); err != nil {
+		t.Fatalf("SetProjectClaudeSettings() error: %v", err)
+	}
+	cs.Env["A"] = "changed"
+
+	// Rebinding keeps the settings, which are a copy of the caller's
+	if err := BindProject(testPath, "", "claude"); err != nil {
+		t.Fatalf("BindProject() error: %v", err)
+	}
+	got := GetProjectBinding(testPath).ClaudeSettings
+	if got == nil || !got.Enabled || got.Model != "opus" || got.Env["A"] != "1" || len(got.MCPServers) != 1 {
+		t.Fatalf("binding.ClaudeSettings = %+v", got)
+	}
+
+	if err := SetProjectClaudeSettings(testPath, nil); err != nil {
+		t.Fatalf("SetProjectClaudeSettings(nil) error: %v", err)
+	}
+	if GetProjectBinding(testPath).ClaudeSettings != nil {
+		t.Error("expected Claude Code settings to be removed")
+	}
+}
+
+func TestProjectMCPServers(t *testing.T) {
+	home := setTestHome(t)
+	testPath := filepath.Join(home, "service")
+	if err := BindProject(testPath, "", ""); err != nil {
+		t.Fatalf("BindProject() error: %v", err)
+	}
+
+	for name, c := range map[string]*MCPServerConfig{
+		"bad__name":  {Command: "npx"},
+		"no-command": {},
+	} {
+		if err := SetProjectMCPServers(testPath, map[string]*MCPServerConfig{name: c}); err == nil {
+			t.Errorf("SetProjectMCPServers(%q) should error", name)
+		}
+	}
+
+	servers := map[string]*MCPServerConfig{
+		"github": {Command: "npx", Args: []string{"-y", "@modelcontextprotocol/server-github"}, Env: map[string]string{"GITHUB_TOKEN": "env://GH_TOKEN"}},
+	}
+	if err := SetProjectMCPServers(testPath, servers); err != nil {
+		t.Fatalf("SetProjectMCPServers() error: %v", err)
+	}
+	if err := SetProjectSandbox(testPath, &SandboxConfig{Backend: SandboxHost}); err != nil {
+		t.Fatalf("SetProjectSandbox() error: %v", err)
+	}
+
+	// The servers, like the binding's other settings, survive a reload and
+	// rebinding
+	ResetDefaultStore()
+	if err := BindProject(testPath, "", "claude"); err != nil {
+		t.Fatalf("BindProject() error: %v", err)
+	}
+	b := GetProjectBinding(testPath)
+	if got := b.MCPServers["github"]; got == nil || got.Command != "npx" || len(got.Args) != 2 || got.Env["GITHUB_TOKEN"] != "env://GH_TOKEN" {
+		t.Fatalf("binding.MCPServers = %+v", b.MCPServers)
+	}
+	if b.Sandbox == nil || b.Sandbox.Backend != SandboxHost {
+		t.Errorf("binding.Sandbox after reload = %+v", b.Sandbox)
+	}
+
+	if err := SetProjectMCPServers(testPath, nil); err != nil {
+		t.Fatalf("SetProjectMCPServers(nil) error: %v", err)
+	}
+	if GetProjectBinding(testPath).MCPServers != nil {
+		t.Error("expected MCP servers to be removed")
+	}
+}
+
 func TestProjectBindingPersistence(t *testing.T) {
 	home := setTestHome(t)
 
@@ -335,3 +485,166 @@ func TestConfigVersionWithBindings(t *testing.T) {
 		t.Errorf("binding.Client = %q, want %q", binding.Client, "codex")
 	}
 }
+
+func TestNormalizeRemoteURL(t *testing.T) {
+	tests := []struct {
+		remote string
+		want   string
+	}{
+		{"git@github.com:Acme/API.git", "github.com/acme/api"},
+		{"https://github.com/acme/api", "github.com/acme/api"},
+		{"https://user:pw@github.com/acme/api.git/", "github.com/acme/api"},
+		{"ssh://git@gitlab.example.com:2222/group/sub/api.git", "gitlab.example.com/group/sub/api"},
+		{"/srv/git/api.git", "/srv/git/api.git"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeRemoteURL(tt.remote); got != tt.want {
+			t.Errorf("NormalizeRemoteURL(%q) = %q, want %q", tt.remote, got, tt.want)
+		}
+	}
+}
+
+// writeGitRepo creates a minimal repository at dir with the given remotes.
+func writeGitRepo(t *testing.T, dir string, remotes map[string]string) {
+	t.Helper()
+	gitDir := filepath.Join(dir, ".git")
+	if err := os.MkdirAll(gitDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	cfg := "[core]\n\tbare = false\n"
+	for name, url := range remotes {
+		cfg += "[remote \"" + name + "\"]\n\turl = " + url + "\n\tfetch = +refs/heads/*:refs/remotes/" + name + "/*\n"
+	}
+	if err := os.WriteFile(filepath.Join(gitDir, "config"), []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGitRemotes(t *testing.T) {
+	root := t.TempDir()
+	repo := filepath.Join(root, "api")
+	writeGitRepo(t, repo, map[string]string{"upstream": "https://github.com/other/api", "origin": "git@github.com:acme/api.git"})
+	sub := filepath.Join(repo, "internal", "pkg")
+	os.MkdirAll(sub, 0755)
+
+	remotes := GitRemotes(sub)
+	if len(remotes) != 2 || remotes[0] != "git@github.com:acme/api.git" {
+		t.Errorf("GitRemotes() = %v, want origin first", remotes)
+	}
+
+	// A linked worktree points at the repository through .git files
+	wtGitDir := filepath.Join(repo, ".git", "worktrees", "feature")
+	os.MkdirAll(wtGitDir, 0755)
+	os.WriteFile(filepath.Join(wtGitDir, "commondir"), []byte("../..\n"), 0644)
+	worktree := filepath.Join(root, "api-feature")
+	os.MkdirAll(worktree, 0755)
+	os.WriteFile(filepath.Join(worktree, ".git"), []byte("gitdir: "+wtGitDir+"\n"), 0644)
+	if got := GitRemotes(worktree); len(got) != 2 || got[0] != remotes[0] {
+		t.Errorf("GitRemotes(worktree) = %v, want %v", got, remotes)
+	}
+
+	if got := GitRemotes(root); got != nil {
+		t.Errorf("GitRemotes(non-repo) = %v, want nil", got)
+	}
+}
+
+func TestFindProjectBindingByRemote(t *testing.T) {
+	home := setTestHome(t)
+	SetProvider("p", &ProviderConfig{BaseURL: "https://api.example.com", AuthToken: "t"})
+	for _, name := range []string{"default", "by-path", "by-remote", "by-repo"} {
+		SetProfileConfig(name, &ProfileConfig{Providers: []string{"p"}})
+	}
+
+	checkout := filepath.Join(home, "checkout")
+	other := filepath.Join(home, "fork")
+	writeGitRepo(t, checkout, map[string]string{"origin": "git@github.com:acme/api.git"})
+	writeGitRepo(t, other, map[string]string{"origin": "https://github.com/someone/api"})
+
+	if err := BindProject("git:https://github.com/Acme/api.git", "by-remote", ""); err != nil {
+		t.Fatalf("BindProject(remote) error: %v", err)
+	}
+	if err := BindProject("repo:API", "by-repo", ""); err != nil {
+		t.Fatalf("BindProject(repo) error: %v", err)
+	}
+	if GetProjectBinding("git:github.com/acme/api") == nil {
+		t.Error("remote binding key was not normalized")
+	}
+
+	if key, b := FindProjectBinding(checkout); b == nil || b.Profile != "by-remote" || key != "git:github.com/acme/api" {
+		t.Errorf("FindProjectBinding(checkout) = %q, %+v, want the remote binding", key, b)
+	}
+	if _, b := FindProjectBinding(other); b == nil || b.Profile != "by-repo" {
+		t.Errorf("FindProjectBinding(fork) = %+v, want the repo-name binding", b)
+	}
+
+	// A path binding takes precedence
+	BindProject(checkout, "by-path", "")
+	if _, b := FindProjectBinding(checkout); b == nil || b.Profile != "by-path" {
+		t.Errorf("FindProjectBinding(checkout) = %+v, want the path binding", b)
+	}
+	if _, b := FindProjectBinding(home); b != nil {
+		t.Errorf("FindProjectBinding(outside) = %+v, want nil", b)
+	}
+}
+
+func TestMatchPattern(t *testing.T) {
+	t.Setenv("HOME", "/home/me")
+	tests := []struct {
+		pattern, dir string
+		want         bool
+	}{
+		{"~/work/*", "/home/me/work/api", true},
+		{"~/work/*", "/home/me/work/api/sub", false},
+		{"~/work/*", "/home/me/work", false},
+		{"/srv/repos/**", "/srv/repos/a/b/c", true},
+		{"/srv/repos/**", "/srv/repos", true},
+		{"/srv/**/api", "/srv/x/y/api", true},
+		{"/srv/**/api", "/srv/x/y/web", false},
+		{"/srv/repos/api-?", "/srv/repos/api-1", true},
+	}
+	for _, tt := range tests {
+		if got := matchPattern(tt.pattern, tt.dir); got != tt.want {
+			t.Errorf("matchPattern(%q, %q) = %v, want %v", tt.pattern, tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestFindProjectBindingByPattern(t *testing.T) {
+	home := setTestHome(t)
+	SetProvider("p", &ProviderConfig{BaseURL: "https://api.example.com", AuthToken: "t"})
+	for _, name := range []string{"default", "work", "apis", "exact", "remote"} {
+		SetProfileConfig(name, &ProfileConfig{Providers: []string{"p"}})
+	}
+	work := filepath.Join(home, "work")
+
+	BindProject("~/work/**", "work", "")
+	BindProject(filepath.Join(work, "api-*"), "apis", "")
+
+	if key, b := FindProjectBinding(filepath.Join(work, "web")); b == nil || b.Profile != "work" || key != "~/work/**" {
+		t.Errorf("FindProjectBinding(web) = %q, %+v, want ~/work/**", key, b)
+	}
+	// The pattern with the longest literal part wins
+	api := filepath.Join(work, "api-gateway")
+	if _, b := FindProjectBinding(api); b == nil || b.Profile != "apis" {
+		t.Errorf("FindProjectBinding(api-gateway) = %+v, want apis", b)
+	}
+	// Remote and exact path bindings win over patterns
+	writeGitRepo(t, api, map[string]string{"origin": "git@github.com:acme/gateway.git"})
+	BindProject("git:github.com/acme/gateway", "remote", "")
+	if _, b := FindProjectBinding(api); b == nil || b.Profile != "remote" {
+		t.Errorf("FindProjectBinding(api-gateway) = %+v, want the remote binding", b)
+	}
+	BindProject(api, "exact", "")
+	if _, b := FindProjectBinding(api); b == nil || b.Profile != "exact" {
+		t.Errorf("FindProjectBinding(api-gateway) = %+v, want the path binding", b)
+	}
+	if _, b := FindProjectBinding(home); b != nil {
+		t.Errorf("FindProjectBinding(home) = %+v, want nil", b)
+	}
+
+	for _, key := range []string{"work/*", "/srv/[x", "", "git:", "repo: ", "relative"} {
+		if err := BindProject(key, "work", ""); err == nil {
+			t.Errorf("BindProject(%q) should fail", key)
+		}
+	}
+}
@@ -151,6 +151,32 @@ func GetProjectBinding(path string) *ProjectBinding {
 	return DefaultStore().GetProjectBinding(path)
 }
 
+// FindProjectBinding returns the binding that applies to a directory, by
+// path or git remote, and the key it is stored under.
+func FindProjectBinding(dir string) (string, *ProjectBinding) {
+	return DefaultStore().FindProjectBinding(dir)
+}
+
+// SetProjectCompression sets the compression override of a bound project.
+func SetProjectCompression(path string, o *CompressionOverride) error {
+	return DefaultStore().SetProjectCompression(path, o)
+}
+
+// SetProjectSandbox sets the sandbox of a bound project.
+func SetProjectSandbox(path string, sb *SandboxConfig) error {
+	return DefaultStore().SetProjectSandbox(path, sb)
+}
+
+// SetProjectClaudeSettings sets the managed Claude Code settings of a bound project.
+func SetProjectClaudeSettings(path string, cs *ClaudeSettingsConfig) error {
+	return DefaultStore().SetProjectClaudeSettings(path, cs)
+}
+
+// SetProjectMCPServers sets the MCP servers of a bound project.
+func SetProjectMCPServers(path string, servers map[string]*MCPServerConfig) error {
+	return DefaultStore().SetProjectMCPServers(path, servers)
+}
+
 // GetAllProjectBindings returns all project bindings.
 func GetAllProjectBindings() map[string]*ProjectBinding {
 	return DefaultStore().GetAllProjectBindings()
@@ -168,6 +194,16 @@ func SetWebPasswordHash(hash string) error {
 	return DefaultStore().SetWebPasswordHash(hash)
 }
 
+// GetWebViewerPasswordHash returns the stored read-only password hash.
+func GetWebViewerPasswordHash() string {
+	return DefaultStore().GetWebViewerPasswordHash()
+}
+
+// SetWebViewerPasswordHash sets the read-only password hash.
+func SetWebViewerPasswordHash(hash string) error {
+	return DefaultStore().SetWebViewerPasswordHash(hash)
+}
+
 // --- Sync Config convenience functions ---
 
 // GetSyncConfig returns the sync configuration, or nil if not configured.
@@ -192,6 +228,26 @@ func SetPricing(pricing map[string]*ModelPricing) error {
 	return DefaultStore().SetPricing(pricing)
 }
 
+// GetPricingOverrides returns the custom model pricing overrides.
+func GetPricingOverrides() map[string]*ModelPricing {
+	return DefaultStore().GetPricingOverrides()
+}
+
+// GetPricingSync returns the pricing sync configuration.
+func GetPricingSync() *PricingSyncConfig {
+	return DefaultStore().GetPricingSync()
+}
+
+// SetPricingSync sets the pricing sync configuration.
+func SetPricingSync(ps *PricingSyncConfig) error {
+	return DefaultStore().SetPricingSync(ps)
+}
+
+// ApplySyncedPricing records the prices of a synced pricing manifest.
+func ApplySyncedPricing(version string, prices map[string]*ModelPricing) error {
+	return DefaultStore().ApplySyncedPricing(version, prices)
+}
+
 // --- Budget convenience functions ---
 
 // GetBudgets returns the budget configuration.
@@ -204,6 +260,75 @@ func SetBudgets(budgets *BudgetConfig) error {
 	return DefaultStore().SetBudgets(budgets)
 }
 
+// --- Currency convenience functions ---
+
+// GetCurrency returns the display currency configuration.
+func GetCurrency() *CurrencyConfig {
+	return DefaultStore().GetCurrency()
+}
+
+// SetCurrency sets the display currency configuration.
+func SetCurrency(c *CurrencyConfig) error {
+	return DefaultStore().SetCurrency(c)
+}
+
+// SetFetchedCurrencyRate records a rate fetched from the currency rate_url.
+func SetFetchedCurrencyRate(rate float64) error {
+	return DefaultStore().SetFetchedCurrencyRate(rate)
+}
+
+// --- Usage report convenience functions ---
+
+// GetUsageReports returns the scheduled usage report configuration.
+func GetUsageReports() *UsageReportConfig {
+	return DefaultStore().GetUsageReports()
+}
+
+// SetUsageReports sets the scheduled usage report configuration.
+func SetUsageReports(reports *UsageReportConfig) error {
+	return DefaultStore().SetUsageReports(reports)
+}
+
+// --- Alert convenience functions ---
+
+// GetAlerts returns the alert threshold configuration.
+func GetAlerts() *AlertConfig {
+	return DefaultStore().GetAlerts()
+}
+
+// SetAlerts sets the alert threshold configuration.
+func SetAlerts(alerts *AlertConfig) error {
+	return DefaultStore().SetAlerts(alerts)
+}
+
+// GetContextWindows returns the model context window overrides.
+func GetContextWindows() map[string]int {
+	return DefaultStore().GetContextWindows()
+}
+
+// SetContextWindows sets the model context window overrides.
+func SetContextWindows(windows map[string]int) error {
+	return DefaultStore().SetContextWindows(windows)
+}
+
+// ContextWindow returns the context window of a model, with the configured
+// overrides applied.
+func ContextWindow(model string) int {
+	return ContextWindowFor(model, GetContextWindows())
+}
+
+// --- Usage retention convenience functions ---
+
+// GetUsageRetention returns the usage database retention configuration.
+func GetUsageRetention() *UsageRetentionConfig {
+	return DefaultStore().GetUsageRetention()
+}
+
+// SetUsageRetention sets the usage database retention configuration.
+func SetUsageRetention(rc *UsageRetentionConfig) error {
+	return DefaultStore().SetUsageRetention(rc)
+}
+
 // --- Webhook convenience functions ---
 
 // GetWebhooks returns all webhook configurations.
@@ -243,6 +368,107 @@ func SetHealthCheck(hc *HealthCheckConfig) error {
 	return DefaultStore().SetHealthCheck(hc)
 }
 
+// --- Body capture convenience functions ---
+
+// GetBodyCapture returns the body capture configuration.
+func GetBodyCapture() *BodyCaptureConfig {
+	return DefaultStore().GetBodyCapture()
+}
+
+// --- Model alias convenience functions ---
+
+// GetModelAliases returns the model rewrite rules.
+func GetModelAliases() []*ModelAlias {
+	return DefaultStore().GetModelAliases()
+}
+
+// SetModelAliases replaces the model rewrite rules.
+func SetModelAliases(aliases []*ModelAlias) error {
+	return DefaultStore().SetModelAliases(aliases)
+}
+
+// --- Response cache convenience functions ---
+
+// GetResponseCache returns the response cache configuration.
+func GetResponseCache() *ResponseCacheConfig {
+	return DefaultStore().GetResponseCache()
+}
+
+// --- Tracing convenience functions ---
+
+// GetTracing returns the OpenTelemetry tracing configuration.
+func GetTracing() *TracingConfig {
+	return DefaultStore().GetTracing()
+}
+
+// --- TLS convenience functions ---
+
+// GetTLS returns the TLS configuration of the Web UI and proxy listeners.
+func GetTLS() *TLSConfig {
+	return DefaultStore().GetTLS()
+}
+
+// GetLog returns the daemon log configuration.
+func GetLog() *LogConfig {
+	return DefaultStore().GetLog()
+}
+
+// GetAccessLog returns the proxy access log configuration.
+func GetAccessLog() *AccessLogConfig {
+	return DefaultStore().GetAccessLog()
+}
+
+// GetIdle returns the idle sleep configuration.
+func GetIdle() *IdleConfig {
+	return DefaultStore().GetIdle()
+}
+
+// --- Trusted header auth convenience functions ---
+
+// GetTrustedHeaderAuth returns the trusted-header auth configuration.
+func GetTrustedHeaderAuth() *TrustedHeaderAuthConfig {
+	return DefaultStore().GetTrustedHeaderAuth()
+}
+
+// SetTrustedHeaderAuth sets the trusted-header auth configuration.
+func SetTrustedHeaderAuth(th *TrustedHeaderAuthConfig) error {
+	return DefaultStore().SetTrustedHeaderAuth(th)
+}
+
+// --- Override header convenience functions ---
+
+// GetOverrideHeaders returns the per-request override header configuration.
+func GetOverrideHeaders() *OverrideHeadersConfig {
+	return DefaultStore().GetOverrideHeaders()
+}
+
+// SetOverrideHeaders sets the per-request override header configuration.
+func SetOverrideHeaders(oh *OverrideHeadersConfig) error {
+	return DefaultStore().SetOverrideHeaders(oh)
+}
+
+// --- Ingress key convenience functions ---
+
+// GetIngress returns the ingress key configuration.
+func GetIngress() *IngressConfig {
+	return DefaultStore().GetIngress()
+}
+
+// SetIngress sets the ingress key configuration.
+func SetIngress(ic *IngressConfig) error {
+	return DefaultStore().SetIngress(ic)
+}
+
+// AddIngressKey issues a new ingress key for user.
+func AddIngressKey(user string) (*IngressKey, error) {
+	return DefaultStore().AddIngressKey(user)
+}
+
+// DeleteIngressKey revokes an ingress key by ID.
+func DeleteIngressKey(id string) (bool, error) {
+	return DefaultStore().DeleteIngressKey(id)
+}
+
 // --- Compression convenience functions (BETA) ---
 
 // GetCompression returns the compression configuration.
@@ -279,6 +505,28 @@ func SetAgent(ac *AgentConfig) error {
 	return DefaultStore().SetAgent(ac)
 }
 
+// --- Secret references ---
+
+// GetSecretRefs returns the config fields that hold secret references.
+func GetSecretRefs() []SecretRef {
+	return DefaultStore().SecretRefs()
+}
+
+// EnableEncryption encrypts the tokens and credentials in zen.json.
+func EnableEncryption(source, passphrase string) error {
+	return DefaultStore().EnableEncryption(source, passphrase)
+}
+
+// DisableEncryption writes zen.json back in plaintext.
+func DisableEncryption() error {
+	return DefaultStore().DisableEncryption()
+}
+
+// IsEncrypted reports whether zen.json is encrypted at rest.
+func IsEncrypted() bool {
+	return DefaultStore().IsEncrypted()
+}
+
 // --- Bot convenience functions (BETA) ---
 
 // GetBot returns the bot configuration.
@@ -347,3 +595,8 @@ func GetDisabledProviders() map[string]*UnavailableMarking {
 func IsProviderDisabled(name string) bool {
 	return DefaultStore().IsProviderDisabled(name)
 }
+
+// GetConfigOverrides returns the ZEN_ environment overrides in effect.
+func GetConfigOverrides() []ConfigOverride {
+	return DefaultStore().Overrides()
+}
@@ -4,9 +4,15 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"net"
+	"net/netip"
 	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
+	"text/template"
 	"time"
 	"unicode"
 )
@@ -24,6 +30,8 @@ const (
 	DefaultProxyPort = 19841
 	DaemonPidFile    = "zend.pid"
 	DaemonLogFile    = "zend.log"
+	DaemonCrashFile  = "zend.crashes"
+	MCPTokenFile     = "mcp.token"
 
 	DefaultProfileName  = "default"
 	DefaultClientName   = "claude"
@@ -32,14 +40,17 @@ const (
 	ClientClaude   = "claude"
 	ClientCodex    = "codex"
 	ClientOpenCode = "opencode"
+	ClientGemini   = "gemini"
+	ClientAider    = "aider"
 
 	// Provider API types
 	ProviderTypeAnthropic = "anthropic"
 	ProviderTypeOpenAI    = "openai"
+	ProviderTypeGemini    = "gemini"
 )
 
 // AvailableClients is the canonical list of supported client names.
-var AvailableClients = []string{ClientClaude, ClientCodex, ClientOpenCode}
+var AvailableClients = []string{ClientClaude, ClientCodex, ClientOpenCode, ClientGemini, ClientAider}
 
 // IsValidClient reports whether name is a supported client name.
 func IsValidClient(name string) bool {
@@ -53,20 +64,109 @@ func IsValidClient(name string) bool {
 
 // ProviderConfig holds connection and model settings for a single API provider.
 type ProviderConfig struct {
-	Type            string            `json:"type,omitempty"` // "anthropic" (default) or "openai"
-	BaseURL         string            `json:"base_url"`
-	AuthToken       string            `json:"auth_token"`
-	ProxyURL        string            `json:"proxy_url,omitempty"`
-	Model           string            `json:"model,omitempty"`
-	ReasoningModel  string            `json:"reasoning_model,omitempty"`
-	HaikuModel      string            `json:"haiku_model,omitempty"`
-	OpusModel       string            `json:"opus_model,omitempty"`
-	SonnetModel     string            `json:"sonnet_model,omitempty"`
-	Weight          int               `json:"weight,omitempty"`            // Weight for weighted load balancing (0 = equal weight)
-	EnvVars         map[string]string `json:"env_vars,omitempty"`          // Claude Code env vars (legacy, for backward compat)
-	ClaudeEnvVars   map[string]string `json:"claude_env_vars,omitempty"`   // Claude Code specific env vars
-	CodexEnvVars    map[string]string `json:"codex_env_vars,omitempty"`    // Codex specific env vars
-	OpenCodeEnvVars map[string]string `json:"opencode_env_vars,omitempty"` // OpenCode specific env vars
+	Type            string              `json:"type,omitempty"` // "anthropic" (default), "openai", or "gemini"
+	BaseURL         string              `json:"base_url"`
+	AuthToken       string              `json:"auth_token"`
+	ProxyURL        string              `json:"proxy_url,omitempty"`
+	Model           string              `json:"model,omitempty"`
+	ReasoningModel  string              `json:"reasoning_model,omitempty"`
+	HaikuModel      string              `json:"haiku_model,omitempty"`
+	OpusModel       string              `json:"opus_model,omitempty"`
+	SonnetModel     string              `json:"sonnet_model,omitempty"`
+	Weight          int                 `json:"weight,omitempty"`            // Weight for weighted load balancing (0 = equal weight)
+	EnvVars         map[string]string   `json:"env_vars,omitempty"`          // Claude Code env vars (legacy, for backward compat)
+	ClaudeEnvVars   map[string]string   `json:"claude_env_vars,omitempty"`   // Claude Code specific env vars
+	CodexEnvVars    map[string]string   `json:"codex_env_vars,omitempty"`    // Codex specific env vars
+	OpenCodeEnvVars map[string]string   `json:"opencode_env_vars,omitempty"` // OpenCode specific env vars
+	GeminiEnvVars   map[string]string   `json:"gemini_env_vars,omitempty"`   // Gemini CLI specific env vars
+	AiderEnvVars    map[string]string   `json:"aider_env_vars,omitempty"`    // Aider specific env vars
+	SafetySettings  map[string]string   `json:"safety_settings,omitempty"`   // Gemini harm category -> block threshold
+	CostModel       *CostModel          `json:"cost_model,omitempty"`        // non-token pricing formula (nil = per-model token pricing)
+	Transforms      *ProviderTransforms `json:"transforms,omitempty"`        // declarative header/body rewrites for quirky providers
+	CaptureBodies   bool                `json:"capture_bodies,omitempty"`    // store sanitized request/response bodies for debugging
+	MaxConcurrent   int                 `json:"max_concurrent,omitempty"`    // max in-flight requests (0 = unlimited)
+	QueueSize       int                 `json:"queue_size,omitempty"`        // requests allowed to wait for a slot (default: 100)
+	QueueTimeoutSec int                 `json:"queue_timeout_sec,omitempty"` // max wait for a slot (default: 30)
+	Keys            []*ProviderKey      `json:"keys,omitempty"`              // fallback tokens tried in priority order when auth_token is rejected
+}
+
+// PrimaryKeyID identifies a provider's auth_token among its keys.
+const PrimaryKeyID = "primary"
+
+// ProviderKey is an additional auth token for a provider. The proxy falls
+// back to these, lowest priority first, when the provider rejects the token
+// in use with 401 or 403, so a new key can be added before the old one is
+// retired.
+type ProviderKey struct {
+	ID       string    `json:"id"`
+	Token    string    `json:"token"`
+	Priority int       `json:"priority,omitempty"`
+	AddedAt  time.Time `json:"added_at,omitempty"`
+}
+
+// FallbackKeys returns the provider's keys in the order they are tried.
+func (p *ProviderConfig) FallbackKeys() []*ProviderKey {
+	keys := make([]*ProviderKey, 0, len(p.Keys))
+	for _, k := range p.Keys {
+		if k != nil {
+			keys = append(keys, k)
+		}
+	}
+	sort.SliceStable(keys, func(i, j int) bool { return keys[i].Priority < keys[j].Priority })
+	return keys
+}
+
+// Key returns the key with the given ID, or nil.
+func (p *ProviderConfig) Key(id string) *ProviderKey {
+	for _, k := range p.Keys {
+		if k != nil && k.ID == id {
+			return k
+		}
+	}
+	return nil
+}
+
+// ValidateKeys checks that every key has a token and a unique ID.
+func (p *ProviderConfig) ValidateKeys() error {
+	seen := map[string]bool{PrimaryKeyID: true}
+	for i, k := range p.Keys {
+		if k == nil {
+			return fmt.Errorf("keys[%d] is nil", i)
+		}
+		if k.ID == "" {
+			return fmt.Errorf("keys[%d]: id is required", i)
+		}
+		if seen[k.ID] {
+			return fmt.Errorf("keys[%d]: duplicate id %q", i, k.ID)
+		}
+		seen[k.ID] = true
+		if k.Token == "" {
+			return fmt.Errorf("key %q: token is required", k.ID)
+		}
+	}
+	return nil
+}
+
+// Defaults for the per-provider request queue used when max_concurrent is set.
+const (
+	DefaultProviderQueueSize       = 100
+	DefaultProviderQueueTimeoutSec = 30
+)
+
+// GetQueueSize returns how many requests may wait for a slot, applying the default.
+func (p *ProviderConfig) GetQueueSize() int {
+	if p.QueueSize <= 0 {
+		return DefaultProviderQueueSize
+	}
+	return p.QueueSize
+}
+
+// GetQueueTimeout returns the maximum wait for a slot, applying the default.
+func (p *ProviderConfig) GetQueueTimeout() time.Duration {
+	if p.QueueTimeoutSec <= 0 {
+		return DefaultProviderQueueTimeoutSec * time.Second
+	}
+	return time.Duration(p.QueueTimeoutSec) * time.Second
 }
 
 // GetType returns the provider type, defaulting to "anthropic".
@@ -89,6 +189,14 @@ func (p *ProviderConfig) GetEnvVarsForClient(client string) map[string]string {
 		if len(p.OpenCodeEnvVars) > 0 {
 			return p.OpenCodeEnvVars
 		}
+	case "gemini":
+		if len(p.GeminiEnvVars) > 0 {
+			return p.GeminiEnvVars
+		}
+	case "aider":
+		if len(p.AiderEnvVars) > 0 {
+			return p.AiderEnvVars
+		}
 	default: // claude
 		if len(p.ClaudeEnvVars) > 0 {
 			return p.ClaudeEnvVars
@@ -104,16 +212,20 @@ func (p *ProviderConfig) Clone() *ProviderConfig {
 		return nil
 	}
 	clone := &ProviderConfig{
-		Type:           p.Type,
-		BaseURL:        p.BaseURL,
-		AuthToken:      p.AuthToken,
-		ProxyURL:       p.ProxyURL,
-		Model:          p.Model,
-		ReasoningModel: p.ReasoningModel,
-		HaikuModel:     p.HaikuModel,
-		OpusModel:      p.OpusModel,
-		SonnetModel:    p.SonnetModel,
-		Weight:         p.Weight,
+		Type:            p.Type,
+		BaseURL:         p.BaseURL,
+		AuthToken:       p.AuthToken,
+		ProxyURL:        p.ProxyURL,
+		Model:           p.Model,
+		ReasoningModel:  p.ReasoningModel,
+		HaikuModel:      p.HaikuModel,
+		OpusModel:       p.OpusModel,
+		SonnetModel:     p.SonnetModel,
+		Weight:          p.Weight,
+		CaptureBodies:   p.CaptureBodies,
+		MaxConcurrent:   p.MaxConcurrent,
+		QueueSize:       p.QueueSize,
+		QueueTimeoutSec: p.QueueTimeoutSec,
 	}
 	if p.EnvVars != nil {
 		clone.EnvVars = make(map[string]string, len(p.EnvVars))
@@ -139,13 +251,190 @@ func (p *ProviderConfig) Clone() *ProviderConfig {
 			clone.OpenCodeEnvVars[k] = v
 		}
 	}
+	if p.GeminiEnvVars != nil {
+		clone.GeminiEnvVars = make(map[string]string, len(p.GeminiEnvVars))
+		for k, v := range p.GeminiEnvVars {
+			clone.GeminiEnvVars[k] = v
+		}
+	}
+	if p.AiderEnvVars != nil {
+		clone.AiderEnvVars = make(map[string]string, len(p.AiderEnvVars))
+		for k, v := range p.AiderEnvVars {
+			clone.AiderEnvVars[k] = v
+		}
+	}
+	if p.SafetySettings != nil {
+		clone.SafetySettings = make(map[string]string, len(p.SafetySettings))
+		for k, v := range p.SafetySettings {
+			clone.SafetySettings[k] = v
+		}
+	}
+	if p.CostModel != nil {
+		cm := *p.CostModel
+		cm.Tiers = append([]PricingTier(nil), p.CostModel.Tiers...)
+		clone.CostModel = &cm
+	}
+	clone.Transforms = p.Transforms.Clone()
+	if p.Keys != nil {
+		clone.Keys = make([]*ProviderKey, len(p.Keys))
+		for i, k := range p.Keys {
+			if k != nil {
+				kc := *k
+				clone.Keys[i] = &kc
+			}
+		}
+	}
 	return clone
 }
 
+// ProviderTransforms declares lightweight rewrites the proxy applies to
+// traffic for a single provider, after protocol translation on the way out
+// and before it on the way back.
+type ProviderTransforms struct {
+	Request  *TransformRules `json:"request,omitempty"`
+	Response *TransformRules `json:"response,omitempty"`
+}
+
+// TransformRules is a set of header and JSON body rewrites.
+// Field paths use dots to reach into nested objects (e.g. "usage.prompt_tokens").
+// Body rules run in order: remove, rename, defaults, set.
+type TransformRules struct {
+	SetHeaders    map[string]string      `json:"set_headers,omitempty"`
+	RemoveHeaders []string               `json:"remove_headers,omitempty"`
+	RemoveFields  []string               `json:"remove_fields,omitempty"`
+	RenameFields  map[string]string      `json:"rename_fields,omitempty"`  // old path -> new path
+	DefaultFields map[string]interface{} `json:"default_fields,omitempty"` // set only when absent
+	SetFields     map[string]interface{} `json:"set_fields,omitempty"`     // always overwrite
+}
+
+// IsEmpty reports whether the rules do nothing.
+func (r *TransformRules) IsEmpty() bool {
+	return r == nil || (len(r.SetHeaders) == 0 && len(r.RemoveHeaders) == 0 &&
+		len(r.RemoveFields) == 0 && len(r.RenameFields) == 0 &&
+		len(r.DefaultFields) == 0 && len(r.SetFields) == 0)
+}
+
+// HasBodyRules reports whether the rules rewrite the JSON body.
+func (r *TransformRules) HasBodyRules() bool {
+	return r != nil && (len(r.RemoveFields) > 0 || len(r.RenameFields) > 0 ||
+		len(r.DefaultFields) > 0 || len(r.SetFields) > 0)
+}
+
+// Validate checks that all field paths and header names are non-empty.
+func (r *TransformRules) Validate() error {
+	if r == nil {
+		return nil
+	}
+	for name := range r.SetHeaders {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("set_headers: empty header name")
+		}
+	}
+	for _, name := range r.RemoveHeaders {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("remove_headers: empty header name")
+		}
+	}
+	for _, path := range r.RemoveFields {
+		if !validFieldPath(path) {
+			return fmt.Errorf("remove_fields: invalid path %q", path)
+		}
+	}
+	for from, to := range r.RenameFields {
+		if !validFieldPath(from) || !validFieldPath(to) {
+			return fmt.Errorf("rename_fields: invalid mapping %q -> %q", from, to)
+		}
+	}
+	for path := range r.DefaultFields {
+		if !validFieldPath(path) {
+			return fmt.Errorf("default_fields: invalid path %q", path)
+		}
+	}
+	for path := range r.SetFields {
+		if !validFieldPath(path) {
+			return fmt.Errorf("set_fields: invalid path %q", path)
+		}
+	}
+	return nil
+}
+
+// validFieldPath reports whether a dotted field path has no empty segments.
+func validFieldPath(path string) bool {
+	if path == "" {
+		return false
+	}
+	for _, seg := range strings.Split(path, ".") {
+		if seg == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate checks both request and response rules.
+func (t *ProviderTransforms) Validate() error {
+	if t == nil {
+		return nil
+	}
+	if err := t.Request.Validate(); err != nil {
+		return fmt.Errorf("request: %w", err)
+	}
+	if err := t.Response.Validate(); err != nil {
+		return fmt.Errorf("response: %w", err)
+	}
+	return nil
+}
+
+// Clone returns a deep copy of the transforms.
+func (t *ProviderTransforms) Clone() *ProviderTransforms {
+	if t == nil {
+		return nil
+	}
+	return &ProviderTransforms{
+		Request:  t.Request.clone(),
+		Response: t.Response.clone(),
+	}
+}
+
+func (r *TransformRules) clone() *TransformRules {
+	if r == nil {
+		return nil
+	}
+	c := &TransformRules{
+		RemoveHeaders: append([]string(nil), r.RemoveHeaders...),
+		RemoveFields:  append([]string(nil), r.RemoveFields...),
+	}
+	if r.SetHeaders != nil {
+		c.SetHeaders = make(map[string]string, len(r.SetHeaders))
+		for k, v := range r.SetHeaders {
+			c.SetHeaders[k] = v
+		}
+	}
+	if r.RenameFields != nil {
+		c.RenameFields = make(map[string]string, len(r.RenameFields))
+		for k, v := range r.RenameFields {
+			c.RenameFields[k] = v
+		}
+	}
+	if r.DefaultFields != nil {
+		c.DefaultFields = make(map[string]interface{}, len(r.DefaultFields))
+		for k, v := range r.DefaultFields {
+			c.DefaultFields[k] = v
+		}
+	}
+	if r.SetFields != nil {
+		c.SetFields = make(map[string]interface{}, len(r.SetFields))
+		for k, v := range r.SetFields {
+			c.SetFields[k] = v
+		}
+	}
+	return c
+}
+
 // ExportToEnv sets all ANTHROPIC_* environment variables from this provider config.
 func (p *ProviderConfig) ExportToEnv() {
 	os.Setenv("ANTHROPIC_BASE_URL", p.BaseURL)
-	os.Setenv("ANTHROPIC_AUTH_TOKEN", p.AuthToken)
+	os.Setenv("ANTHROPIC_AUTH_TOKEN", SecretValue(p.AuthToken))
 
 	// Clear optional model vars first to avoid stale values from previous provider
 	os.Unsetenv("ANTHROPIC_MODEL")
@@ -326,6 +615,54 @@ type RoutePolicy struct {
 	ProviderWeights      map[string]int          `json:"provider_weights,omitempty"`      // per-scenario weights
 	LongContextThreshold *int                    `json:"long_context_threshold,omitempty"` // per-scenario threshold (nil = use profile default)
 	FallbackToDefault    *bool                   `json:"fallback_to_default,omitempty"`   // whether to fallback to default route on failure
+	Match                *ScenarioMatch          `json:"match,omitempty"`                 // rules that select a custom scenario
+}
+
+// ScenarioMatch defines when a custom scenario applies to a request.
+// Every condition that is set must hold.
+type ScenarioMatch struct {
+	SystemPattern string   `json:"system_pattern,omitempty"` // regular expression matched against the system prompt
+	Tools         []string `json:"tools,omitempty"`          // matches when any of these tool names is present
+	MinTokens     int      `json:"min_tokens,omitempty"`     // estimated request tokens at least this
+	MaxTokens     int      `json:"max_tokens,omitempty"`     // estimated request tokens at most this (0 = no limit)
+}
+
+// Validate checks that the rules are well-formed and select something.
+func (m *ScenarioMatch) Validate() error {
+	if m == nil {
+		return nil
+	}
+	if m.SystemPattern == "" && len(m.Tools) == 0 && m.MinTokens == 0 && m.MaxTokens == 0 {
+		return fmt.Errorf("match has no conditions")
+	}
+	if m.SystemPattern != "" {
+		if _, err := regexp.Compile(m.SystemPattern); err != nil {
+			return fmt.Errorf("invalid system_pattern: %w", err)
+		}
+	}
+	for _, name := range m.Tools {
+		if name == "" {
+			return fmt.Errorf("tools contains an empty name")
+		}
+	}
+	if m.MinTokens < 0 || m.MaxTokens < 0 {
+		return fmt.Errorf("token bounds must not be negative")
+	}
+	if m.MaxTokens > 0 && m.MinTokens > m.MaxTokens {
+		return fmt.Errorf("min_tokens %d exceeds max_tokens %d", m.MinTokens, m.MaxTokens)
+	}
+	return nil
+}
+
+// IsBuiltinScenario reports whether key names one of the builtin scenarios.
+// Aliases such as "long-context" are recognized.
+func IsBuiltinScenario(key string) bool {
+	switch Scenario(NormalizeScenarioKey(key)) {
+	case ScenarioThink, ScenarioImage, ScenarioLongContext, ScenarioWebSearch,
+		ScenarioBackground, ScenarioCode, ScenarioDefault:
+		return true
+	}
+	return false
 }
 
 // ProviderNames returns the list of provider names in order.
@@ -357,6 +694,92 @@ type ProfileConfig struct {
 	Strategy             LoadBalanceStrategy         `json:"strategy,omitempty"`               // load balancing strategy
 	ProviderWeights      map[string]int              `json:"provider_weights,omitempty"`       // weights for weighted strategy
 	ScenarioPriority     []string                    `json:"scenario_priority,omitempty"`      // scenario priority order for builtin classifier
+	CacheAffinity        bool                        `json:"cache_affinity,omitempty"`         // prefer the provider holding a session's prompt cache
+	Mirror               *MirrorConfig               `json:"mirror,omitempty"`                 // shadow traffic to a provider under evaluation
+	RequestTransform     *RequestTransform           `json:"request_transform,omitempty"`      // body rewrites applied to every request
+}
+
+// RequestTransform rewrites the body of every request a profile serves,
+// in the client's API format and before routing. It enforces org-wide
+// instructions and limits without touching every project.
+type RequestTransform struct {
+	SystemPrepend string   `json:"system_prepend,omitempty"` // text added before the system prompt
+	SystemAppend  string   `json:"system_append,omitempty"`  // text added after the system prompt
+	StripSystem   []string `json:"strip_system,omitempty"`   // regexes removed from the system prompt
+	StripTools    []string `json:"strip_tools,omitempty"`    // tool names to remove, * wildcards allowed
+	MaxTokensCap  int      `json:"max_tokens_cap,omitempty"` // upper bound on the requested output tokens
+	Temperature   *float64 `json:"temperature,omitempty"`    // forced sampling temperature
+}
+
+// IsEmpty reports whether the transform changes nothing.
+func (t *RequestTransform) IsEmpty() bool {
+	return t == nil || (t.SystemPrepend == "" && t.SystemAppend == "" &&
+		len(t.StripSystem) == 0 && len(t.StripTools) == 0 &&
+		t.MaxTokensCap == 0 && t.Temperature == nil)
+}
+
+// Validate checks the strip patterns and numeric limits.
+func (t *RequestTransform) Validate() error {
+	if t == nil {
+		return nil
+	}
+	for _, pattern := range t.StripSystem {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("strip_system: invalid pattern %q: %w", pattern, err)
+		}
+	}
+	for _, name := range t.StripTools {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("strip_tools: empty tool name")
+		}
+	}
+	if t.MaxTokensCap < 0 {
+		return fmt.Errorf("max_tokens_cap must not be negative, got %d", t.MaxTokensCap)
+	}
+	if t.Temperature != nil && (*t.Temperature < 0 || *t.Temperature > 2) {
+		return fmt.Errorf("temperature must be in [0, 2], got %v", *t.Temperature)
+	}
+	return nil
+}
+
+// Clone returns a deep copy of the transform.
+func (t *RequestTransform) Clone() *RequestTransform {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	c.StripSystem = append([]string(nil), t.StripSystem...)
+	c.StripTools = append([]string(nil), t.StripTools...)
+	if t.Temperature != nil {
+		temp := *t.Temperature
+		c.Temperature = &temp
+	}
+	return &c
+}
+
+// MirrorConfig duplicates a share of a profile's requests to a secondary
+// provider. Mirrored responses are discarded; only latency, cost and errors
+// are compared against the primary response.
+type MirrorConfig struct {
+	Provider string  `json:"provider"`
+	Percent  float64 `json:"percent"` // share of requests to mirror, 0-100
+}
+
+// Validate checks the mirror settings against the configured providers.
+func (m *MirrorConfig) Validate(providers map[string]*ProviderConfig) error {
+	if m == nil {
+		return nil
+	}
+	if m.Provider == "" {
+		return fmt.Errorf("mirror provider is required")
+	}
+	if _, ok := providers[m.Provider]; !ok {
+		return fmt.Errorf("mirror provider %q does not exist", m.Provider)
+	}
+	if m.Percent <= 0 || m.Percent > 100 {
+		return fmt.Errorf("mirror percent must be in (0, 100], got %v", m.Percent)
+	}
+	return nil
 }
 
 // Clone returns a deep copy of the ProfileConfig.
@@ -367,7 +790,13 @@ func (pc *ProfileConfig) Clone() *ProfileConfig {
 	clone := &ProfileConfig{
 		LongContextThreshold: pc.LongContextThreshold,
 		Strategy:             pc.Strategy,
+		CacheAffinity:        pc.CacheAffinity,
 	}
+	if pc.Mirror != nil {
+		m := *pc.Mirror
+		clone.Mirror = &m
+	}
+	clone.RequestTransform = pc.RequestTransform.Clone()
 	if pc.Providers != nil {
 		clone.Providers = make([]string, len(pc.Providers))
 		copy(clone.Providers, pc.Providers)
@@ -411,6 +840,11 @@ func (pc *ProfileConfig) Clone() *ProfileConfig {
 						routeClone.ProviderWeights[pk] = pv
 					}
 				}
+				if v.Match != nil {
+					m := *v.Match
+					m.Tools = append([]string(nil), v.Match.Tools...)
+					routeClone.Match = &m
+				}
 				clone.Routing[k] = routeClone
 			}
 		}
@@ -482,11 +916,130 @@ type AutoPermissionConfig struct {
 // --- Model Pricing ---
 
 // ModelPricing defines the cost per million tokens for a model.
+// Cache write and read rates default to multiples of the input rate when
+// unset. BatchDiscount is the fraction taken off requests served by a batch
+// API, e.g. 0.5 for half price.
 type ModelPricing struct {
+	InputPerMillion      float64 `json:"input_per_million"`
+	OutputPerMillion     float64 `json:"output_per_million"`
+	CacheWritePerMillion float64 `json:"cache_write_per_million,omitempty"`
+	CacheReadPerMillion  float64 `json:"cache_read_per_million,omitempty"`
+	BatchDiscount        float64 `json:"batch_discount,omitempty"`
+}
+
+// Validate reports whether the pricing's rates are usable.
+func (p *ModelPricing) Validate() error {
+	if p.InputPerMillion < 0 || p.OutputPerMillion < 0 || p.CacheWritePerMillion < 0 || p.CacheReadPerMillion < 0 {
+		return fmt.Errorf("pricing values must be non-negative")
+	}
+	if p.BatchDiscount < 0 || p.BatchDiscount > 1 {
+		return fmt.Errorf("batch_discount must be between 0 and 1")
+	}
+	return nil
+}
+
+// DefaultPricingSyncIntervalHours is how often a scheduled pricing sync runs
+// when interval_hours is not set.
+const DefaultPricingSyncIntervalHours = 24
+
+// PricingSyncConfig configures fetching model prices from a signed pricing
+// manifest. Synced prices replace the built-in defaults; custom pricing
+// overrides still take precedence over them.
+type PricingSyncConfig struct {
+	Enabled       bool   `json:"enabled"`                  // sync on a schedule
+	URL           string `json:"url"`                      // manifest URL; its signature is read from URL + ".sig"
+	PublicKey     string `json:"public_key"`               // base64 ed25519 key the manifest must be signed with
+	IntervalHours int    `json:"interval_hours,omitempty"` // default: 24
+
+	// The last applied manifest, maintained by the sync.
+	Version  string                   `json:"version,omitempty"`
+	SyncedAt time.Time                `json:"synced_at"`
+	Prices   map[string]*ModelPricing `json:"prices,omitempty"`
+}
+
+// GetInterval returns the scheduled sync interval, applying the default.
+func (c *PricingSyncConfig) GetInterval() time.Duration {
+	if c == nil || c.IntervalHours <= 0 {
+		return DefaultPricingSyncIntervalHours * time.Hour
+	}
+	return time.Duration(c.IntervalHours) * time.Hour
+}
+
+// Cost model types for provider-level pricing formulas.
+const (
+	CostModelToken      = "token"       // per-million token pricing from the model pricing table (default)
+	CostModelPerRequest = "per_request" // flat fee per request
+	CostModelPerSecond  = "per_second"  // billed by request duration
+	CostModelTiered     = "tiered"      // token pricing that depends on prompt size
+)
+
+// CostModel defines how a provider bills requests when per-model token
+// pricing does not apply.
+type CostModel struct {
+	Type       string        `json:"type"`
+	PerRequest float64       `json:"per_request,omitempty"` // USD per request (per_request)
+	PerSecond  float64       `json:"per_second,omitempty"`  // USD per second of request duration (per_second)
+	Tiers      []PricingTier `json:"tiers,omitempty"`       // ascending by UpToInputTokens (tiered)
+}
+
+// PricingTier is one band of a tiered cost model. The first tier whose
+// UpToInputTokens covers the request's input tokens prices the whole request.
+// An UpToInputTokens of 0 means unbounded and is only valid for the last tier.
+type PricingTier struct {
+	UpToInputTokens  int     `json:"up_to_input_tokens,omitempty"`
 	InputPerMillion  float64 `json:"input_per_million"`
 	OutputPerMillion float64 `json:"output_per_million"`
 }
 
+// GetType returns the cost model type, defaulting to "token".
+func (c *CostModel) GetType() string {
+	if c == nil || c.Type == "" {
+		return CostModelToken
+	}
+	return c.Type
+}
+
+// Validate checks that the cost model is well-formed.
+func (c *CostModel) Validate() error {
+	if c == nil {
+		return nil
+	}
+	switch c.GetType() {
+	case CostModelToken:
+	case CostModelPerRequest:
+		if c.PerRequest < 0 {
+			return fmt.Errorf("per_request must not be negative")
+		}
+	case CostModelPerSecond:
+		if c.PerSecond < 0 {
+			return fmt.Errorf("per_second must not be negative")
+		}
+	case CostModelTiered:
+		if len(c.Tiers) == 0 {
+			return fmt.Errorf("tiered cost model requires at least one tier")
+		}
+		prev := 0
+		for i, tier := range c.Tiers {
+			if tier.InputPerMillion < 0 || tier.OutputPerMillion < 0 {
+				return fmt.Errorf("tier %d: prices must not be negative", i)
+			}
+			if tier.UpToInputTokens == 0 {
+				if i != len(c.Tiers)-1 {
+					return fmt.Errorf("tier %d: only the last tier may be unbounded", i)
+				}
+				continue
+			}
+			if tier.UpToInputTokens <= prev {
+				return fmt.Errorf("tier %d: up_to_input_tokens must be ascending", i)
+			}
+			prev = tier.UpToInputTokens
+		}
+	default:
+		return fmt.Errorf("unknown cost model type %q", c.Type)
+	}
+	return nil
+}
+
 // DefaultModelPricing provides built-in pricing for common Claude models.
 var DefaultModelPricing = map[string]*ModelPricing{
 	// Anthropic Claude models
@@ -564,6 +1117,127 @@ var DefaultModelPricing = map[string]*ModelPricing{
 	"qwen-coder-turbo": {InputPerMillion: 0.28, OutputPerMillion: 1.12},
 }
 
+// --- Currency Configuration ---
+
+// CurrencyConfig sets the currency costs are displayed and budgeted in.
+// Costs are tracked in USD and converted at the configured rate.
+type CurrencyConfig struct {
+	Code        string    `json:"code"`                   // ISO 4217 code, e.g. "EUR" (default: USD)
+	Rate        float64   `json:"rate,omitempty"`         // units of code per USD; a fixed rate wins over rate_url
+	RateURL     string    `json:"rate_url,omitempty"`     // JSON rates per USD, e.g. {"rates": {"EUR": 0.92}}
+	FetchedRate float64   `json:"fetched_rate,omitempty"` // last rate fetched from rate_url
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+// GetCode returns the display currency code, defaulting to USD.
+func (c *CurrencyConfig) GetCode() string {
+	if c == nil || c.Code == "" {
+		return "USD"
+	}
+	return strings.ToUpper(c.Code)
+}
+
+// GetRate returns the units of the display currency per USD: the fixed
+// rate, else the last fetched rate, else 1.
+func (c *CurrencyConfig) GetRate() float64 {
+	switch {
+	case c == nil || c.GetCode() == "USD":
+		return 1
+	case c.Rate > 0:
+		return c.Rate
+	case c.FetchedRate > 0:
+		return c.FetchedRate
+	}
+	return 1
+}
+
+// FromUSD converts a USD amount to the display currency.
+func (c *CurrencyConfig) FromUSD(usd float64) float64 {
+	return usd * c.GetRate()
+}
+
+// ToUSD converts an amount in the display currency to USD.
+func (c *CurrencyConfig) ToUSD(amount float64) float64 {
+	return amount / c.GetRate()
+}
+
+// Format formats an amount already in the display currency.
+func (c *CurrencyConfig) Format(amount float64) string {
+	return FormatAmount(c.GetCode(), amount)
+}
+
+// currencySymbols are the symbols of common currencies. Others are shown
+// with their code.
+var currencySymbols = map[string]string{
+	"USD": "$",
+	"EUR": "€",
+	"GBP": "£",
+	"JPY": "¥",
+	"CNY": "¥",
+	"INR": "₹",
+	"KRW": "₩",
+}
+
+// FormatAmount formats an amount in the currency with the given code, e.g.
+// "$1.50", "€1.50" or "CHF 1.50". An empty code means USD.
+func FormatAmount(code string, amount float64) string {
+	code = strings.ToUpper(code)
+	if code == "" {
+		code = "USD"
+	}
+	if sym, ok := currencySymbols[code]; ok {
+		return fmt.Sprintf("%s%.2f", sym, amount)
+	}
+	return fmt.Sprintf("%s %.2f", code, amount)
+}
+
+// DefaultContextWindow is the context window assumed for models not in
+// DefaultModelContextWindows or the context_windows config.
+const DefaultContextWindow = 200000
+
+// DefaultModelContextWindows gives the context window, in tokens, of common
+// models. Keys match a model name exactly or as a prefix; the longest wins.
+var DefaultModelContextWindows = map[string]int{
+	"claude-": 200000,
+
+	"gpt-3.5-turbo": 16385,
+	"gpt-4":         8192,
+	"gpt-4-32k":     32768,
+	"gpt-4-turbo":   128000,
+	"gpt-4o":        128000,
+	"gpt-4.1":       1047576,
+	"gpt-5":         400000,
+	"o1":            200000,
+	"o1-mini":       128000,
+	"o3":            200000,
+	"o4-mini":       200000,
+
+	"deepseek-":  128000,
+	"glm-4":      128000,
+	"glm-4-long": 1000000,
+
+	"gemini-":        1048576,
+	"gemini-1.5-pro": 2097152,
+}
+
+// ContextWindowFor returns the context window of a model, looking it up in
+// overrides and then in DefaultModelContextWindows. Keys of both match the
+// model exactly or as a prefix, the longest match winning.
+func ContextWindowFor(model string, overrides map[string]int) int {
+	for _, windows := range []map[string]int{overrides, DefaultModelContextWindows} {
+		best, window := -1, 0
+		for key, w := range windows {
+			if w > 0 && strings.HasPrefix(model, key) && len(key) > best {
+				best, window = len(key), w
+			}
+		}
+		if best >= 0 {
+			return window
+		}
+	}
+	return DefaultContextWindow
+}
+
 // --- Budget Configuration ---
 
 // BudgetAction defines what happens when a budget limit is reached.
@@ -581,12 +1255,108 @@ type BudgetLimit struct {
 	Action BudgetAction `json:"action,omitempty"`
 }
 
+// BudgetEnforcement defines how strictly block limits are enforced.
+type BudgetEnforcement string
+
+const (
+	// BudgetEnforcementAdmission rejects new requests once a block limit is reached.
+	BudgetEnforcementAdmission BudgetEnforcement = "admission"
+	// BudgetEnforcementStreaming also cuts off a streaming response whose
+	// accrued cost would exceed the remaining budget.
+	BudgetEnforcementStreaming BudgetEnforcement = "streaming"
+)
+
+// BudgetLimits holds daily, weekly and monthly limits for one provider or model.
+type BudgetLimits struct {
+	Daily   *BudgetLimit `json:"daily,omitempty"`
+	Weekly  *BudgetLimit `json:"weekly,omitempty"`
+	Monthly *BudgetLimit `json:"monthly,omitempty"`
+}
+
 // BudgetConfig holds budget limits for different time periods.
 type BudgetConfig struct {
-	Daily      *BudgetLimit `json:"daily,omitempty"`
-	Weekly     *BudgetLimit `json:"weekly,omitempty"`
-	Monthly    *BudgetLimit `json:"monthly,omitempty"`
-	PerProject bool         `json:"per_project,omitempty"`
+	Daily       *BudgetLimit      `json:"daily,omitempty"`
+	Weekly      *BudgetLimit      `json:"weekly,omitempty"`
+	Monthly     *BudgetLimit      `json:"monthly,omitempty"`
+	PerProject  bool              `json:"per_project,omitempty"`
+	Enforcement BudgetEnforcement `json:"enforcement,omitempty"` // "admission" (default) or "streaming"
+
+	// Providers limits spending per provider name.
+	Providers map[string]*BudgetLimits `json:"providers,omitempty"`
+	// Models limits spending per model; a key matches any model whose name
+	// contains it, case-insensitively (e.g. "opus").
+	Models map[string]*BudgetLimits `json:"models,omitempty"`
+	// Users limits spending per user, as labelled by ingress keys.
+	Users map[string]*BudgetLimits `json:"users,omitempty"`
+
+	// Downgrade lists the model substitutions made once a downgrade limit is
+	// reached. Empty means every model is downgraded to Haiku.
+	Downgrade []*BudgetDowngrade `json:"downgrade,omitempty"`
+}
+
+// BudgetDowngrade maps a model to the cheaper model used once a downgrade
+// limit is reached. Rules are checked in order and the first match wins,
+// so a chain such as opus→sonnet→haiku is written as one rule per step.
+type BudgetDowngrade struct {
+	Match    string `json:"match"`              // exact model name, or a pattern where * matches any characters
+	Target   string `json:"target"`             // model to use instead
+	Provider string `json:"provider,omitempty"` // only for this provider (empty = all providers)
+}
+
+// --- Usage Report Configuration ---
+
+// UsageReportConfig schedules usage summaries, sent to webhooks subscribed to
+// daily_summary or weekly_summary and optionally to the bot.
+type UsageReportConfig struct {
+	Daily   bool `json:"daily,omitempty"`    // summarize the previous day
+	Weekly  bool `json:"weekly,omitempty"`   // summarize the previous week, sent on Sundays
+	HourUTC int  `json:"hour_utc,omitempty"` // hour of day to send at, in UTC (default: 0)
+	Bot     bool `json:"bot,omitempty"`      // also post to the bot's notification chat
+}
+
+// --- Usage Retention Configuration ---
+
+// Default retention periods for the usage database.
+const (
+	DefaultRetentionRawDays       = 30
+	DefaultRetentionHourlyDays    = 365
+	DefaultRetentionIntervalHours = 24
+)
+
+// UsageRetentionConfig limits how long the usage database keeps data. Raw
+// rows are requests, logs, provider metrics and captured bodies; usage stays
+// in the hourly rollups until hourly_days and in the daily rollups for good.
+type UsageRetentionConfig struct {
+	Enabled       bool `json:"enabled"`                  // compact on a schedule
+	RawDays       int  `json:"raw_days,omitempty"`       // default: 30
+	HourlyDays    int  `json:"hourly_days,omitempty"`    // default: 365
+	IntervalHours int  `json:"interval_hours,omitempty"` // default: 24
+}
+
+// GetRawDays returns how many days raw rows are kept, applying the default.
+func (c *UsageRetentionConfig) GetRawDays() int {
+	if c == nil || c.RawDays <= 0 {
+		return DefaultRetentionRawDays
+	}
+	return c.RawDays
+}
+
+// GetHourlyDays returns how many days hourly rollups are kept, applying
+// the default.
+func (c *UsageRetentionConfig) GetHourlyDays() int {
+	if c == nil || c.HourlyDays <= 0 {
+		return DefaultRetentionHourlyDays
+	}
+	return c.HourlyDays
+}
+
+// GetInterval returns how often scheduled compaction runs, applying the
+// default.
+func (c *UsageRetentionConfig) GetInterval() time.Duration {
+	if c == nil || c.IntervalHours <= 0 {
+		return DefaultRetentionIntervalHours * time.Hour
+	}
+	return time.Duration(c.IntervalHours) * time.Hour
 }
 
 // --- Webhook Configuration ---
@@ -601,30 +1371,634 @@ const (
 	WebhookEventProviderUp     WebhookEvent = "provider_up"
 	WebhookEventFailover       WebhookEvent = "failover"
 	WebhookEventDailySummary   WebhookEvent = "daily_summary"
+	WebhookEventWeeklySummary  WebhookEvent = "weekly_summary"
+
+	WebhookEventRequestFailureBurst WebhookEvent = "request_failure_burst"
+	WebhookEventBudgetForecast      WebhookEvent = "budget_forecast"
+	WebhookEventCertExpiry          WebhookEvent = "cert_expiry"
+	WebhookEventDaemonRestart       WebhookEvent = "daemon_restart"
+	WebhookEventKeyInvalid          WebhookEvent = "key_invalid"
+	WebhookEventSpendSpike          WebhookEvent = "spend_spike"
 )
 
-// WebhookConfig defines a webhook endpoint configuration.
-type WebhookConfig struct {
-	Name    string            `json:"name"`
-	URL     string            `json:"url"`
-	Events  []WebhookEvent    `json:"events"`
-	Headers map[string]string `json:"headers,omitempty"`
-	Enabled bool              `json:"enabled"`
+// Defaults for the alert thresholds.
+const (
+	DefaultAlertFailureBurstCount   = 5
+	DefaultAlertFailureBurstMinutes = 5
+	DefaultAlertCertExpiryDays      = 14
+	DefaultAlertContextWarnPercent  = 80
+)
+
+// AlertConfig sets the thresholds of the webhook and Web UI events raised by
+// watching traffic, budgets and certificates.
+type AlertConfig struct {
+	FailureBurstCount   int `json:"failure_burst_count,omitempty"`   // failures from one provider that raise request_failure_burst (default 5)
+	FailureBurstMinutes int `json:"failure_burst_minutes,omitempty"` // within this many minutes (default 5)
+	CertExpiryDays      int `json:"cert_expiry_days,omitempty"`      // raise cert_expiry this many days before a provider's certificate expires (default 14)
+	ContextWarnPercent  int `json:"context_warn_percent,omitempty"`  // raise context_warning when a session fills this much of its model's context window (default 80)
 }
 
-// --- Health Check Configuration ---
+// GetFailureBurstCount returns the failure count that raises
+// request_failure_burst.
+func (c *AlertConfig) GetFailureBurstCount() int {
+	if c == nil || c.FailureBurstCount <= 0 {
+		return DefaultAlertFailureBurstCount
+	}
+	return c.FailureBurstCount
+}
 
-// HealthCheckConfig defines settings for provider health monitoring.
-type HealthCheckConfig struct {
-	Enabled      bool `json:"enabled"`
-	IntervalSecs int  `json:"interval_secs,omitempty"`
-	TimeoutSecs  int  `json:"timeout_secs,omitempty"`
+// GetFailureBurstWindow returns the window the failures are counted in.
+func (c *AlertConfig) GetFailureBurstWindow() time.Duration {
+	if c == nil || c.FailureBurstMinutes <= 0 {
+		return DefaultAlertFailureBurstMinutes * time.Minute
+	}
+	return time.Duration(c.FailureBurstMinutes) * time.Minute
 }
 
-// --- Context Compression Configuration (BETA) ---
+// GetCertExpiryDays returns how many days ahead of expiry cert_expiry is
+// raised.
+func (c *AlertConfig) GetCertExpiryDays() int {
+	if c == nil || c.CertExpiryDays <= 0 {
+		return DefaultAlertCertExpiryDays
+	}
+	return c.CertExpiryDays
+}
 
-// CompressionConfig holds context compression settings.
-// [BETA] This feature is experimental and disabled by default.
+// GetContextWarnPercent returns the share of a model's context window, in
+// percent, at which a session raises context_warning.
+func (c *AlertConfig) GetContextWarnPercent() int {
+	if c == nil || c.ContextWarnPercent <= 0 {
+		return DefaultAlertContextWarnPercent
+	}
+	return c.ContextWarnPercent
+}
+
+// DefaultWebhookMaxRetries is how often a failed webhook delivery is retried
+// when max_retries is not set.
+const DefaultWebhookMaxRetries = 5
+
+// WebhookFormat names a built-in webhook payload format.
+type WebhookFormat string
+
+const (
+	WebhookFormatGeneric   WebhookFormat = "generic"
+	WebhookFormatSlack     WebhookFormat = "slack"
+	WebhookFormatDiscord   WebhookFormat = "discord"
+	WebhookFormatPagerDuty WebhookFormat = "pagerduty"
+)
+
+// WebhookConfig defines a webhook endpoint configuration.
+type WebhookConfig struct {
+	Name       string            `json:"name"`
+	URL        string            `json:"url"`
+	Events     []WebhookEvent    `json:"events"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Enabled    bool              `json:"enabled"`
+	MaxRetries int               `json:"max_retries,omitempty"` // retries before a delivery is dead-lettered (default 5)
+	Format     WebhookFormat     `json:"format,omitempty"`      // payload preset; detected from the URL if empty
+	Template   string            `json:"template,omitempty"`    // Go template for the payload; overrides format
+	RoutingKey string            `json:"routing_key,omitempty"` // PagerDuty integration key, for the pagerduty format
+	Secret     string            `json:"secret,omitempty"`      // signs deliveries with X-Zen-Signature when set
+	ClientCert string            `json:"client_cert,omitempty"` // PEM client certificate file, for mTLS
+	ClientKey  string            `json:"client_key,omitempty"`  // PEM private key file of client_cert
+	CACert     string            `json:"ca_cert,omitempty"`     // PEM CA file to verify a receiver with a private CA
+}
+
+// GetFormat returns the webhook's payload format, detecting Slack, Discord
+// and PagerDuty from the URL when none is set.
+func (w *WebhookConfig) GetFormat() WebhookFormat {
+	if w.Format != "" {
+		return w.Format
+	}
+	switch {
+	case strings.Contains(w.URL, "slack.com"):
+		return WebhookFormatSlack
+	case strings.Contains(w.URL, "discord.com"):
+		return WebhookFormatDiscord
+	case strings.Contains(w.URL, "events.pagerduty.com"):
+		return WebhookFormatPagerDuty
+	}
+	return WebhookFormatGeneric
+}
+
+// webhookTemplateFuncs are the functions available to webhook templates.
+var webhookTemplateFuncs = template.FuncMap{
+	// json renders a value as JSON, for embedding in a JSON payload
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// ParseTemplate parses the webhook's payload template.
+func (w *WebhookConfig) ParseTemplate() (*template.Template, error) {
+	return template.New(w.Name).Funcs(webhookTemplateFuncs).Option("missingkey=zero").Parse(w.Template)
+}
+
+// Validate checks the webhook's format, template and client certificate
+// settings.
+func (w *WebhookConfig) Validate() error {
+	if w.MaxRetries < 0 {
+		return fmt.Errorf("max_retries must not be negative")
+	}
+	switch w.Format {
+	case "", WebhookFormatGeneric, WebhookFormatSlack, WebhookFormatDiscord, WebhookFormatPagerDuty:
+	default:
+		return fmt.Errorf("unknown format %q (want generic, slack, discord or pagerduty)", w.Format)
+	}
+	if w.Template != "" {
+		if _, err := w.ParseTemplate(); err != nil {
+			return fmt.Errorf("invalid template: %w", err)
+		}
+	} else if w.GetFormat() == WebhookFormatPagerDuty && w.RoutingKey == "" {
+		return fmt.Errorf("the pagerduty format requires routing_key")
+	}
+	if (w.ClientCert == "") != (w.ClientKey == "") {
+		return fmt.Errorf("client_cert and client_key must be set together")
+	}
+	return nil
+}
+
+// GetMaxRetries returns how often a failed delivery is retried.
+func (w *WebhookConfig) GetMaxRetries() int {
+	if w == nil || w.MaxRetries <= 0 {
+		return DefaultWebhookMaxRetries
+	}
+	return w.MaxRetries
+}
+
+// --- Health Check Configuration ---
+
+// HealthCheckConfig defines settings for provider health monitoring.
+type HealthCheckConfig struct {
+	Enabled      bool         `json:"enabled"`
+	IntervalSecs int          `json:"interval_secs,omitempty"`
+	TimeoutSecs  int          `json:"timeout_secs,omitempty"`
+	Probe        *ProbeConfig `json:"probe,omitempty"` // active synthetic probes
+}
+
+// Defaults for synthetic health probes.
+const (
+	DefaultProbeIntervalSecs = 300
+	DefaultProbePrompt       = "ping"
+	DefaultProbeMaxTokens    = 1
+)
+
+// ProbeConfig defines active health probes: a tiny completion request sent
+// to each provider on an interval, so its health is known even when no real
+// traffic flows. Probes cost tokens; keep the prompt and token cap small.
+type ProbeConfig struct {
+	Enabled      bool              `json:"enabled"`
+	IntervalSecs int               `json:"interval_secs,omitempty"` // default 300
+	Model        string            `json:"model,omitempty"`         // default: the provider's smallest configured model
+	Models       map[string]string `json:"models,omitempty"`        // per-provider model, overrides model
+	Prompt       string            `json:"prompt,omitempty"`        // default "ping"
+	MaxTokens    int               `json:"max_tokens,omitempty"`    // default 1
+	Providers    []string          `json:"providers,omitempty"`     // providers to probe; all if empty
+}
+
+// GetInterval returns the time between probes of a provider.
+func (p *ProbeConfig) GetInterval() time.Duration {
+	if p == nil || p.IntervalSecs <= 0 {
+		return DefaultProbeIntervalSecs * time.Second
+	}
+	return time.Duration(p.IntervalSecs) * time.Second
+}
+
+// GetPrompt returns the prompt sent by probes.
+func (p *ProbeConfig) GetPrompt() string {
+	if p == nil || p.Prompt == "" {
+		return DefaultProbePrompt
+	}
+	return p.Prompt
+}
+
+// GetMaxTokens returns the output token cap of a probe.
+func (p *ProbeConfig) GetMaxTokens() int {
+	if p == nil || p.MaxTokens <= 0 {
+		return DefaultProbeMaxTokens
+	}
+	return p.MaxTokens
+}
+
+// ModelFor returns the model to probe provider pc with: the per-provider
+// model, the probe model, or the provider's haiku or default model.
+func (p *ProbeConfig) ModelFor(name string, pc *ProviderConfig) string {
+	if p != nil {
+		if m := p.Models[name]; m != "" {
+			return m
+		}
+		if p.Model != "" {
+			return p.Model
+		}
+	}
+	if pc.HaikuModel != "" {
+		return pc.HaikuModel
+	}
+	if pc.Model != "" {
+		return pc.Model
+	}
+	if pc.GetType() == ProviderTypeAnthropic {
+		return "claude-haiku-4-5"
+	}
+	return ""
+}
+
+// Probes reports whether the named provider is probed.
+func (p *ProbeConfig) Probes(name string) bool {
+	if p == nil || !p.Enabled {
+		return false
+	}
+	if len(p.Providers) == 0 {
+		return true
+	}
+	for _, n := range p.Providers {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// --- Body Capture Configuration ---
+
+// DefaultBodyCaptureMaxBytes caps each captured request or response body.
+const DefaultBodyCaptureMaxBytes = 64 * 1024
+
+// BodyCaptureConfig holds settings for debug body capture. Capture itself is
+// opt-in per provider (capture_bodies) or per request (X-Zen-Capture header).
+type BodyCaptureConfig struct {
+	MaxBytes int `json:"max_bytes,omitempty"` // per-body byte cap (default: 65536)
+}
+
+// GetMaxBytes returns the per-body byte cap, applying the default.
+func (c *BodyCaptureConfig) GetMaxBytes() int {
+	if c == nil || c.MaxBytes <= 0 {
+		return DefaultBodyCaptureMaxBytes
+	}
+	return c.MaxBytes
+}
+
+// --- Model Alias Configuration ---
+
+// ModelAlias rewrites a requested model name before it is forwarded.
+// Rules are checked in order and the first match wins; a matching rule
+// takes precedence over the provider's own model mapping.
+type ModelAlias struct {
+	Match     string `json:"match"`                // exact model name, or a pattern where * matches any characters
+	Target    string `json:"target,omitempty"`     // replacement model (empty keeps the requested name)
+	Provider  string `json:"provider,omitempty"`   // only rewrite for this provider (empty = all providers)
+	StripDate bool   `json:"strip_date,omitempty"` // remove a trailing date suffix such as -20241022
+}
+
+// Validate checks that the rule has a pattern and changes something.
+func (a *ModelAlias) Validate() error {
+	if a == nil {
+		return fmt.Errorf("alias must not be null")
+	}
+	if strings.TrimSpace(a.Match) == "" {
+		return fmt.Errorf("match must not be empty")
+	}
+	if a.Target == "" && !a.StripDate {
+		return fmt.Errorf("alias for %q needs a target or strip_date", a.Match)
+	}
+	return nil
+}
+
+// --- Response Cache Configuration ---
+
+const (
+	DefaultResponseCacheTTLSecs    = 3600
+	DefaultResponseCacheMaxEntries = 500
+)
+
+// ResponseCacheConfig controls the proxy-level response cache. Only
+// non-streaming requests that set temperature to 0 are cached, so exact
+// repeats (e.g. CI runs) are answered without calling a provider.
+type ResponseCacheConfig struct {
+	Enabled    bool `json:"enabled"`
+	TTLSecs    int  `json:"ttl_secs,omitempty"`    // default: 3600
+	MaxEntries int  `json:"max_entries,omitempty"` // default: 500
+}
+
+// GetTTL returns how long a cached response stays valid, applying the default.
+func (c *ResponseCacheConfig) GetTTL() time.Duration {
+	if c == nil || c.TTLSecs <= 0 {
+		return DefaultResponseCacheTTLSecs * time.Second
+	}
+	return time.Duration(c.TTLSecs) * time.Second
+}
+
+// GetMaxEntries returns the cache size limit, applying the default.
+func (c *ResponseCacheConfig) GetMaxEntries() int {
+	if c == nil || c.MaxEntries <= 0 {
+		return DefaultResponseCacheMaxEntries
+	}
+	return c.MaxEntries
+}
+
+// OverrideHeadersConfig controls the X-Zen-Provider, X-Zen-Model and
+// X-Zen-Profile request headers, which pin a single request to a provider,
+// model or profile. Each list is an allowlist of names; * matches any run
+// of characters. A header whose list is empty is rejected.
+type OverrideHeadersConfig struct {
+	Enabled   bool     `json:"enabled"`
+	Providers []string `json:"providers,omitempty"` // allowed X-Zen-Provider values
+	Models    []string `json:"models,omitempty"`    // allowed X-Zen-Model values
+	Profiles  []string `json:"profiles,omitempty"`  // allowed X-Zen-Profile values
+}
+
+// IngressConfig holds the zen-issued API keys clients send to the proxy, so
+// usage on a shared daemon can be attributed to the user each key belongs to.
+// Clients send a key as x-api-key or as an Authorization bearer token.
+type IngressConfig struct {
+	Required bool          `json:"required,omitempty"` // reject requests without a valid key
+	Keys     []*IngressKey `json:"keys,omitempty"`
+}
+
+// IngressKey maps an API key to the user label recorded with its usage.
+type IngressKey struct {
+	ID        string    `json:"id"`
+	Key       string    `json:"key"`
+	User      string    `json:"user"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TracingConfig controls OpenTelemetry tracing of the proxy pipeline.
+// Spans are exported over OTLP/HTTP to a collector such as Jaeger or Tempo.
+type TracingConfig struct {
+	Enabled     bool              `json:"enabled"`
+	Endpoint    string            `json:"endpoint,omitempty"`     // OTLP/HTTP endpoint (default: localhost:4318)
+	Insecure    bool              `json:"insecure,omitempty"`     // use plain HTTP instead of HTTPS
+	Headers     map[string]string `json:"headers,omitempty"`      // extra export headers, e.g. auth
+	ServiceName string            `json:"service_name,omitempty"` // default: "zen"
+	SampleRatio *float64          `json:"sample_ratio,omitempty"` // share of new traces sampled (default: 1)
+}
+
+// Default tracing settings.
+const (
+	DefaultTracingEndpoint    = "localhost:4318"
+	DefaultTracingServiceName = "zen"
+)
+
+// GetEndpoint returns the OTLP endpoint, applying the default.
+func (c *TracingConfig) GetEndpoint() string {
+	if c == nil || c.Endpoint == "" {
+		return DefaultTracingEndpoint
+	}
+	return c.Endpoint
+}
+
+// GetServiceName returns the service name reported on spans.
+func (c *TracingConfig) GetServiceName() string {
+	if c == nil || c.ServiceName == "" {
+		return DefaultTracingServiceName
+	}
+	return c.ServiceName
+}
+
+// GetSampleRatio returns the share of new traces to sample, clamped to [0, 1].
+func (c *TracingConfig) GetSampleRatio() float64 {
+	if c == nil || c.SampleRatio == nil {
+		return 1
+	}
+	r := *c.SampleRatio
+	if r < 0 {
+		return 0
+	}
+	if r > 1 {
+		return 1
+	}
+	return r
+}
+
+// Daemon log defaults.
+const (
+	DefaultLogLevel       = "info"
+	DefaultLogFormat      = "console"
+	DefaultLogMaxSizeMB   = 10
+	DefaultLogRotateHours = 24
+	DefaultLogMaxBackups  = 5
+	DefaultLogMaxAgeDays  = 14
+)
+
+// LogLevels are the daemon log levels, from most to least verbose.
+var LogLevels = []string{"debug", "info", "warn", "error"}
+
+// LogConfig controls zend.log: which lines are written, in what format,
+// and when the file is rotated.
+type LogConfig struct {
+	Level       string `json:"level,omitempty"`        // debug, info, warn or error (default: info)
+	Format      string `json:"format,omitempty"`       // console or json (default: console)
+	MaxSizeMB   int    `json:"max_size_mb,omitempty"`  // rotate once the file passes this size (default: 10)
+	RotateHours int    `json:"rotate_hours,omitempty"` // also rotate this often; -1 rotates on size only (default: 24)
+	MaxBackups  int    `json:"max_backups,omitempty"`  // rotated files to keep (default: 5)
+	MaxAgeDays  int    `json:"max_age_days,omitempty"` // remove rotated files older than this (default: 14)
+}
+
+// GetLevel returns the log level, applying the default.
+func (c *LogConfig) GetLevel() string {
+	if c == nil || c.Level == "" {
+		return DefaultLogLevel
+	}
+	return c.Level
+}
+
+// GetFormat returns the log format, applying the default.
+func (c *LogConfig) GetFormat() string {
+	if c == nil || c.Format == "" {
+		return DefaultLogFormat
+	}
+	return c.Format
+}
+
+// GetMaxSize returns the size in bytes past which the log is rotated,
+// applying the default.
+func (c *LogConfig) GetMaxSize() int64 {
+	mb := DefaultLogMaxSizeMB
+	if c != nil && c.MaxSizeMB > 0 {
+		mb = c.MaxSizeMB
+	}
+	return int64(mb) << 20
+}
+
+// GetRotateInterval returns how often the log is rotated regardless of its
+// size, applying the default. Zero means it is rotated on size only.
+func (c *LogConfig) GetRotateInterval() time.Duration {
+	if c == nil || c.RotateHours == 0 {
+		return DefaultLogRotateHours * time.Hour
+	}
+	if c.RotateHours < 0 {
+		return 0
+	}
+	return time.Duration(c.RotateHours) * time.Hour
+}
+
+// GetMaxBackups returns how many rotated logs are kept, applying the
+// default.
+func (c *LogConfig) GetMaxBackups() int {
+	if c == nil || c.MaxBackups <= 0 {
+		return DefaultLogMaxBackups
+	}
+	return c.MaxBackups
+}
+
+// GetMaxAge returns how long rotated logs are kept, applying the default.
+func (c *LogConfig) GetMaxAge() time.Duration {
+	days := DefaultLogMaxAgeDays
+	if c != nil && c.MaxAgeDays > 0 {
+		days = c.MaxAgeDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// Access log formats.
+const (
+	AccessLogFormatCommon = "common"
+	AccessLogFormatJSON   = "json"
+)
+
+// AccessLogConfig controls the proxy access log: one line per proxied
+// request, written to a file of its own for log pipelines to ingest. The
+// file is rotated with the settings of the log section.
+type AccessLogConfig struct {
+	Enabled bool   `json:"enabled"`
+	Path    string `json:"path,omitempty"`   // default: access.log in the config directory
+	Format  string `json:"format,omitempty"` // common or json (default: common)
+}
+
+// GetPath returns the access log path, applying the default.
+func (c *AccessLogConfig) GetPath() string {
+	if c == nil || c.Path == "" {
+		return filepath.Join(ConfigDirPath(), "access.log")
+	}
+	return expandHome(c.Path)
+}
+
+// GetFormat returns the access log format, applying the default.
+func (c *AccessLogConfig) GetFormat() string {
+	if c == nil || c.Format == "" {
+		return AccessLogFormatCommon
+	}
+	return c.Format
+}
+
+// DefaultIdleAfterMinutes is how long zend waits without traffic before it
+// sleeps, when idle sleep is on.
+const DefaultIdleAfterMinutes = 30
+
+// IdleConfig lets zend sleep when nothing uses it, for laptops: after a
+// while with no proxied requests and no agent sessions it closes its
+// database connections and pauses health probes, and it wakes on the next
+// connection to either port.
+type IdleConfig struct {
+	Enabled      bool `json:"enabled"`
+	AfterMinutes int  `json:"after_minutes,omitempty"` // idle time before sleeping (default: 30)
+}
+
+// GetAfter returns the idle time before zend sleeps, applying the default.
+func (c *IdleConfig) GetAfter() time.Duration {
+	if c == nil || c.AfterMinutes <= 0 {
+		return DefaultIdleAfterMinutes * time.Minute
+	}
+	return time.Duration(c.AfterMinutes) * time.Minute
+}
+
+// TLSConfig serves the Web UI and the proxy over HTTPS, for setups that
+// expose zen beyond the local machine. Plain HTTP keeps working for
+// loopback clients, so local tools need no changes.
+type TLSConfig struct {
+	Enabled  bool     `json:"enabled"`
+	CertFile string   `json:"cert_file,omitempty"` // PEM certificate; empty uses a generated self-signed one
+	KeyFile  string   `json:"key_file,omitempty"`  // PEM private key for cert_file
+	Listen   string   `json:"listen,omitempty"`    // "0.0.0.0" or "::" to listen on all interfaces (default: 127.0.0.1)
+	Hosts    []string `json:"hosts,omitempty"`     // extra names and IPs for the self-signed certificate
+}
+
+// DefaultListenAddress is the address zen listens on unless TLS sets another.
+const DefaultListenAddress = "127.0.0.1"
+
+// IsEnabled reports whether TLS is configured and enabled.
+func (c *TLSConfig) IsEnabled() bool {
+	return c != nil && c.Enabled
+}
+
+// GetListen returns the address the Web UI and proxy listen on. Listening
+// beyond loopback requires TLS, so the default is used while it is off.
+// Only all-interface and loopback addresses are used, since the CLI always
+// reaches zen over 127.0.0.1.
+func (c *TLSConfig) GetListen() string {
+	if !c.IsEnabled() || !ValidListenAddress(c.Listen) {
+		return DefaultListenAddress
+	}
+	return c.Listen
+}
+
+// ValidListenAddress reports whether addr can be used as tls.listen.
+func ValidListenAddress(addr string) bool {
+	ip := net.ParseIP(addr)
+	return ip != nil && (ip.IsUnspecified() || ip.IsLoopback())
+}
+
+// TrustedHeaderAuthConfig lets a reverse proxy that authenticates users,
+// such as Tailscale Serve or oauth2-proxy, log them in to the Web UI by
+// passing their identity in a header. The header is only trusted on
+// connections from the proxy's addresses.
+type TrustedHeaderAuthConfig struct {
+	Enabled        bool     `json:"enabled"`
+	Header         string   `json:"header,omitempty"`          // identity header (default: X-Forwarded-User)
+	TrustedProxies []string `json:"trusted_proxies,omitempty"` // IPs or CIDRs the header is accepted from (default: loopback)
+	Role           string   `json:"role,omitempty"`            // role of the users: "admin" (default) or "viewer"
+}
+
+// DefaultTrustedHeader is the identity header read when header is not set.
+// oauth2-proxy sets it; Tailscale Serve sends Tailscale-User-Login.
+const DefaultTrustedHeader = "X-Forwarded-User"
+
+// DefaultTrustedProxies are the addresses the identity header is accepted
+// from when trusted_proxies is not set: a proxy on the same machine.
+var DefaultTrustedProxies = []string{"127.0.0.0/8", "::1/128"}
+
+// IsEnabled reports whether trusted-header auth is configured and enabled.
+func (c *TrustedHeaderAuthConfig) IsEnabled() bool {
+	return c != nil && c.Enabled
+}
+
+// GetHeader returns the identity header name, applying the default.
+func (c *TrustedHeaderAuthConfig) GetHeader() string {
+	if c == nil || c.Header == "" {
+		return DefaultTrustedHeader
+	}
+	return c.Header
+}
+
+// GetTrustedProxies returns the addresses the header is accepted from,
+// applying the default.
+func (c *TrustedHeaderAuthConfig) GetTrustedProxies() []string {
+	if c == nil || len(c.TrustedProxies) == 0 {
+		return DefaultTrustedProxies
+	}
+	return c.TrustedProxies
+}
+
+// ParsePrefix parses an IP address or CIDR, treating a bare address as a
+// single-address prefix.
+func ParsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		p, err := netip.ParsePrefix(s)
+		return p.Masked(), err
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// --- Context Compression Configuration (BETA) ---
+
+// CompressionConfig holds context compression settings.
+// [BETA] This feature is experimental and disabled by default.
 type CompressionConfig struct {
 	Enabled         bool   `json:"enabled"`          // default: false (BETA)
 	ThresholdTokens int    `json:"threshold_tokens"` // trigger compression above this (default: 50000)
@@ -632,6 +2006,20 @@ type CompressionConfig struct {
 	SummaryModel    string `json:"summary_model"`    // model for summarization (default: "claude-3-haiku-20240307")
 	PreserveRecent  int    `json:"preserve_recent"`  // keep last N messages uncompressed (default: 4)
 	SummaryProvider string `json:"summary_provider"` // provider to use for summarization (default: first healthy)
+
+	Strategy  string           `json:"strategy,omitempty"`  // "summarize" (default) or "retrieval"
+	Retrieval *RetrievalConfig `json:"retrieval,omitempty"` // settings for the retrieval strategy
+}
+
+// RetrievalConfig configures the retrieval compression strategy, which embeds
+// older turns and re-injects only those relevant to the latest user message.
+type RetrievalConfig struct {
+	EmbeddingProvider string `json:"embedding_provider,omitempty"` // provider whose base URL and token are used
+	EmbeddingURL      string `json:"embedding_url,omitempty"`      // OpenAI-compatible base URL, when no provider is set
+	EmbeddingAPIKey   string `json:"embedding_api_key,omitempty"`  // API key for embedding_url
+	EmbeddingModel    string `json:"embedding_model,omitempty"`    // default: "text-embedding-3-small"
+	ChunkTokens       int    `json:"chunk_tokens,omitempty"`       // approximate chunk size (default: 500)
+	TopK              int    `json:"top_k,omitempty"`              // most chunks to re-inject (default: 8)
 }
 
 // --- Middleware Pipeline Configuration (BETA) ---
@@ -647,10 +2035,29 @@ type MiddlewareConfig struct {
 type MiddlewareEntry struct {
 	Name    string          `json:"name"`             // middleware identifier
 	Enabled bool            `json:"enabled"`          // can disable individual middleware
-	Source  string          `json:"source,omitempty"` // "builtin", "local", "remote"
-	Path    string          `json:"path,omitempty"`   // path for local plugins
-	URL     string          `json:"url,omitempty"`    // URL for remote plugins
+	Source  string          `json:"source,omitempty"` // "builtin", "local", "remote", "wasm", "script", "http"
+	Path    string          `json:"path,omitempty"`   // path for local, wasm and script plugins
+	URL     string          `json:"url,omitempty"`    // URL for remote plugins and http callouts
 	Config  json.RawMessage `json:"config,omitempty"` // middleware-specific config
+
+	When *MiddlewareMatch `json:"when,omitempty"` // run only for matching requests (default: all)
+}
+
+// MiddlewareMatch restricts a middleware to matching requests. All set
+// conditions must hold. Patterns are case-insensitive and * matches any run
+// of characters.
+type MiddlewareMatch struct {
+	Providers        []string          `json:"providers,omitempty"`         // provider name patterns
+	ExcludeProviders []string          `json:"exclude_providers,omitempty"` // provider name patterns to skip
+	Models           []string          `json:"models,omitempty"`            // requested model patterns
+	Projects         []string          `json:"projects,omitempty"`          // project directories (and their subdirectories) or patterns
+	Headers          map[string]string `json:"headers,omitempty"`           // request header name -> value pattern
+}
+
+// HasProviderCondition reports whether the match depends on the provider,
+// which is only known once a provider is being tried.
+func (m *MiddlewareMatch) HasProviderCondition() bool {
+	return m != nil && (len(m.Providers) > 0 || len(m.ExcludeProviders) > 0)
 }
 
 // --- Agent Infrastructure Configuration (BETA) ---
@@ -668,27 +2075,74 @@ type AgentConfig struct {
 
 // CoordinatorConfig holds agent coordinator settings.
 type CoordinatorConfig struct {
-	Enabled        bool `json:"enabled"`
-	LockTimeoutSec int  `json:"lock_timeout_sec,omitempty"` // default: 300
-	InjectWarnings bool `json:"inject_warnings,omitempty"`  // inject lock info into context
+	Enabled        bool   `json:"enabled"`
+	LockTimeoutSec int    `json:"lock_timeout_sec,omitempty"` // default: 300
+	LockWaitSec    int    `json:"lock_wait_sec,omitempty"`    // how long a lock request queues by default (default: 0, fail at once)
+	DeadlockPolicy string `json:"deadlock_policy,omitempty"`  // "abort_youngest" (default) or "notify"
+	InjectWarnings bool   `json:"inject_warnings,omitempty"`  // inject lock info into context
+}
+
+// Deadlock resolution policies of the agent coordinator.
+const (
+	DeadlockAbortYoungest = "abort_youngest" // fail the youngest session's wait and release its locks
+	DeadlockNotify        = "notify"         // only report the deadlock; waits end by timeout
+)
+
+// GetDeadlockPolicy returns the deadlock resolution policy, defaulting to
+// DeadlockAbortYoungest.
+func (c *CoordinatorConfig) GetDeadlockPolicy() string {
+	if c == nil || c.DeadlockPolicy == "" {
+		return DeadlockAbortYoungest
+	}
+	return c.DeadlockPolicy
 }
 
 // ObservatoryConfig holds agent observatory settings.
 type ObservatoryConfig struct {
-	Enabled        bool `json:"enabled"`
-	StuckThreshold int  `json:"stuck_threshold,omitempty"` // consecutive errors before marking stuck (default: 5)
-	IdleTimeoutMin int  `json:"idle_timeout_min,omitempty"` // minutes before marking idle (default: 30)
+	Enabled          bool `json:"enabled"`
+	StuckThreshold   int  `json:"stuck_threshold,omitempty"`    // consecutive errors before marking stuck (default: 5)
+	IdleTimeoutMin   int  `json:"idle_timeout_min,omitempty"`   // minutes before marking idle (default: 30)
+	LoopThreshold    int  `json:"loop_threshold,omitempty"`     // identical tool calls in a row before a session looks stuck (default: 5)
+	NoProgressMin    int  `json:"no_progress_min,omitempty"`    // minutes without a file edit before a session looks stuck (default: 15)
+	NoProgressTokens int  `json:"no_progress_tokens,omitempty"` // tokens that must be used in that time (default: 200000)
+	ReportBot        bool `json:"report_bot,omitempty"`         // post end-of-session reports to the bot's notification chat
 }
 
 // GuardrailsConfig holds agent guardrails settings.
 type GuardrailsConfig struct {
-	Enabled            bool    `json:"enabled"`
-	SessionSpendingCap float64 `json:"session_spending_cap,omitempty"` // max $ per session
-	RequestRateLimit   int     `json:"request_rate_limit,omitempty"`   // max requests per minute
-	SensitiveOpsDetect bool    `json:"sensitive_ops_detect,omitempty"` // detect dangerous operations
-	AutoPauseOnCap     bool    `json:"auto_pause_on_cap,omitempty"`    // pause when cap hit
+	Enabled            bool             `json:"enabled"`
+	SessionSpendingCap float64          `json:"session_spending_cap,omitempty"` // max $ per session
+	RequestRateLimit   int              `json:"request_rate_limit,omitempty"`   // max requests per minute
+	SensitiveOpsDetect bool             `json:"sensitive_ops_detect,omitempty"` // detect dangerous operations
+	AutoPauseOnCap     bool             `json:"auto_pause_on_cap,omitempty"`    // pause when cap hit
+	Policy             []*GuardrailRule `json:"policy,omitempty"`               // rules checked against tool calls
+	ApprovalTimeoutSec int              `json:"approval_timeout_sec,omitempty"` // how long an "ask" rule waits for approval (default: 300)
+	SpikeMultiple      float64          `json:"spike_multiple,omitempty"`       // flag a session spending this many times its usual rate (0: off)
+	SpikeWindowMin     int              `json:"spike_window_min,omitempty"`     // minutes over which the spending rate is measured (default: 5)
+}
+
+// GuardrailRule is a policy rule checked against the tool calls a model
+// makes. It matches a call when any of its conditions does.
+type GuardrailRule struct {
+	Name              string   `json:"name"`
+	Action            string   `json:"action"`                       // "block", "ask" or "log"
+	Tools             []string `json:"tools,omitempty"`              // tool names the rule applies to (default: all)
+	AllowPaths        []string `json:"allow_paths,omitempty"`        // matches file tools touching a path outside these globs
+	DenyPaths         []string `json:"deny_paths,omitempty"`         // matches file tools and shell commands touching these globs
+	DenyCommands      []string `json:"deny_commands,omitempty"`      // regular expressions matched against shell commands
+	ProtectedBranches []string `json:"protected_branches,omitempty"` // matches git commands that push to, delete or rename these branches
 }
 
+// Guardrail rule actions, from most to least severe.
+const (
+	GuardrailBlock = "block" // refuse the tool call
+	GuardrailAsk   = "ask"   // refuse the tool call unless a person approves it
+	GuardrailLog   = "log"   // allow the tool call and record it
+)
+
+// DefaultSpikeWindowMin is the default spike_window_min.
+const DefaultSpikeWindowMin = 5
+
 // TaskQueueConfig holds task queue settings.
 type TaskQueueConfig struct {
 	Enabled    bool `json:"enabled"`
@@ -704,6 +2158,17 @@ type RuntimeConfig struct {
 	ValidationModel string `json:"validation_model,omitempty"` // model for validation phase
 	MaxTurns        int    `json:"max_turns,omitempty"`        // max conversation turns (default: 50)
 	MaxTokens       int    `json:"max_tokens,omitempty"`       // max total tokens (default: 500000)
+
+	// Multi-agent tasks
+	Roles       map[string]*RuntimeRoleConfig `json:"roles,omitempty"`        // profile and model per sub-agent role: "planner", "implementer", "reviewer"
+	MaxParallel int                           `json:"max_parallel,omitempty"` // implementer sub-agents run at once (default: 1)
+}
+
+// RuntimeRoleConfig maps a sub-agent role to the profile and model it runs
+// with.
+type RuntimeRoleConfig struct {
+	Profile string `json:"profile,omitempty"` // default: "default"
+	Model   string `json:"model,omitempty"`   // default: the model of the role's phase
 }
 
 // --- Skills Configuration ---
@@ -899,8 +2364,212 @@ func NewUnavailableMarking(markingType string) (*UnavailableMarking, error) {
 
 // ProjectBinding holds the configuration for a project directory.
 type ProjectBinding struct {
-	Profile string `json:"profile,omitempty"` // profile name (empty = use default)
-	Client  string `json:"client,omitempty"`  // client name (empty = use default)
+	Profile     string               `json:"profile,omitempty"`     // profile name (empty = use default)
+	Client      string               `json:"client,omitempty"`      // client name (empty = use default)
+	Compression *CompressionOverride `json:"compression,omitempty"` // project-specific compression settings
+	Sandbox     *SandboxConfig       `json:"sandbox,omitempty"`     // where agent runtime tool calls run
+
+	ClaudeSettings *ClaudeSettingsConfig       `json:"claude_settings,omitempty"` // zen-managed Claude Code settings
+	MCPServers     map[string]*MCPServerConfig `json:"mcp_servers,omitempty"`     // MCP servers zen runs for the project, by name
+}
+
+// Clone returns a deep copy of the binding.
+func (b *ProjectBinding) Clone() *ProjectBinding {
+	if b == nil {
+		return nil
+	}
+	return &ProjectBinding{
+		Profile:     b.Profile,
+		Client:      b.Client,
+		Compression: b.Compression.Clone(),
+		Sandbox:     b.Sandbox.Clone(),
+
+		ClaudeSettings: b.ClaudeSettings.Clone(),
+		MCPServers:     CloneMCPServers(b.MCPServers),
+	}
+}
+
+// Sandbox backends for agent runtime tool calls.
+const (
+	SandboxHost     = "host"     // run directly in the project directory
+	SandboxDocker   = "docker"   // run in a throwaway container with the project mounted
+	SandboxFirejail = "firejail" // run under firejail, with only the project directory visible
+)
+
+// SandboxConfig directs the shell commands and file edits the agent runtime
+// makes for a project into a sandbox instead of running them on the host.
+type SandboxConfig struct {
+	Backend    string   `json:"backend"`               // "host", "docker" or "firejail"
+	Image      string   `json:"image,omitempty"`       // container image (docker only)
+	Network    bool     `json:"network,omitempty"`     // allow network access (default: off)
+	Args       []string `json:"args,omitempty"`        // extra arguments for docker run or firejail
+	TimeoutSec int      `json:"timeout_sec,omitempty"` // per command (default: 120)
+}
+
+// Validate checks the sandbox settings. A nil sandbox is valid.
+func (c *SandboxConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	switch c.Backend {
+	case SandboxHost, SandboxFirejail:
+	case SandboxDocker:
+		if c.Image == "" {
+			return fmt.Errorf("docker sandbox requires an image")
+		}
+	default:
+		return fmt.Errorf("invalid sandbox backend %q (want host, docker or firejail)", c.Backend)
+	}
+	if c.TimeoutSec < 0 {
+		return fmt.Errorf("sandbox timeout_sec cannot be negative")
+	}
+	return nil
+}
+
+// Clone returns a deep copy of the sandbox settings.
+func (c *SandboxConfig) Clone() *SandboxConfig {
+	if c == nil {
+		return nil
+	}
+	clone := *c
+	clone.Args = append([]string(nil), c.Args...)
+	return &clone
+}
+
+// ClaudeSettingsConfig makes zen manage Claude Code's settings for a bound
+// project while Claude Code runs there. zen writes the proxy environment,
+// the providers' Claude Code env vars and these settings into the project's
+// .claude/settings.local.json, and restores the user's own values on exit.
+type ClaudeSettingsConfig struct {
+	Enabled    bool              `json:"enabled"`
+	Model      string            `json:"model,omitempty"`       // pins Claude Code's model (e.g. "opus" or a model ID)
+	Env        map[string]string `json:"env,omitempty"`         // extra env entries
+	MCPServers []string          `json:"mcp_servers,omitempty"` // .mcp.json servers to enable, besides the user's
+}
+
+// Clone returns a deep copy of the Claude Code settings.
+func (c *ClaudeSettingsConfig) Clone() *ClaudeSettingsConfig {
+	if c == nil {
+		return nil
+	}
+	clone := *c
+	if c.Env != nil {
+		clone.Env = make(map[string]string, len(c.Env))
+		for k, v := range c.Env {
+			clone.Env[k] = v
+		}
+	}
+	clone.MCPServers = append([]string(nil), c.MCPServers...)
+	return &clone
+}
+
+// MCPServerConfig declares a stdio MCP server of a bound project. zen
+// launches it in the project directory and serves its tools, with those of
+// the project's other servers, from a single MCP endpoint.
+type MCPServerConfig struct {
+	Command  string            `json:"command"`
+	Args     []string          `json:"args,omitempty"`
+	Env      map[string]string `json:"env,omitempty"`      // values may be secret references
+	Disabled bool              `json:"disabled,omitempty"` // keep the entry but don't run it
+}
+
+// mcpServerNamePattern limits server names to what can prefix a tool name.
+var mcpServerNamePattern = regexp.MustCompile(`^[A-Za-z0-9-]+(_[A-Za-z0-9-]+)*$`)
+
+// ValidateMCPServer checks an MCP server entry and its name. Names prefix
+// the server's tool names with a double underscore, so they can't contain
+// one.
+func ValidateMCPServer(name string, c *MCPServerConfig) error {
+	if !mcpServerNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid MCP server name %q (use letters, digits, - and single _)", name)
+	}
+	if c == nil || strings.TrimSpace(c.Command) == "" {
+		return fmt.Errorf("MCP server %q requires a command", name)
+	}
+	return nil
+}
+
+// Clone returns a deep copy of the MCP server entry.
+func (c *MCPServerConfig) Clone() *MCPServerConfig {
+	if c == nil {
+		return nil
+	}
+	clone := *c
+	clone.Args = append([]string(nil), c.Args...)
+	if c.Env != nil {
+		clone.Env = make(map[string]string, len(c.Env))
+		for k, v := range c.Env {
+			clone.Env[k] = v
+		}
+	}
+	return &clone
+}
+
+// CloneMCPServers returns a deep copy of a binding's MCP servers.
+func CloneMCPServers(servers map[string]*MCPServerConfig) map[string]*MCPServerConfig {
+	if servers == nil {
+		return nil
+	}
+	clone := make(map[string]*MCPServerConfig, len(servers))
+	for name, c := range servers {
+		clone[name] = c.Clone()
+	}
+	return clone
+}
+
+// CompressionOverride overrides the global compression settings for a bound
+// project. Unset fields keep the global value.
+type CompressionOverride struct {
+	Enabled         *bool  `json:"enabled,omitempty"`
+	ThresholdTokens int    `json:"threshold_tokens,omitempty"`
+	TargetTokens    int    `json:"target_tokens,omitempty"`
+	SummaryModel    string `json:"summary_model,omitempty"`
+	PreserveRecent  int    `json:"preserve_recent,omitempty"`
+	Strategy        string `json:"strategy,omitempty"`
+}
+
+// Apply returns a copy of base with the override's set fields replaced.
+// base may be nil.
+func (o *CompressionOverride) Apply(base *CompressionConfig) *CompressionConfig {
+	cfg := &CompressionConfig{}
+	if base != nil {
+		*cfg = *base
+	}
+	if o == nil {
+		return cfg
+	}
+	if o.Enabled != nil {
+		cfg.Enabled = *o.Enabled
+	}
+	if o.ThresholdTokens > 0 {
+		cfg.ThresholdTokens = o.ThresholdTokens
+	}
+	if o.TargetTokens > 0 {
+		cfg.TargetTokens = o.TargetTokens
+	}
+	if o.SummaryModel != "" {
+		cfg.SummaryModel = o.SummaryModel
+	}
+	if o.PreserveRecent > 0 {
+		cfg.PreserveRecent = o.PreserveRecent
+	}
+	if o.Strategy != "" {
+		cfg.Strategy = o.Strategy
+	}
+	return cfg
+}
+
+// Clone returns a deep copy of the override.
+func (o *CompressionOverride) Clone() *CompressionOverride {
+	if o == nil {
+		return nil
+	}
+	c := *o
+	if o.Enabled != nil {
+		enabled := *o.Enabled
+		c.Enabled = &enabled
+	}
+	return &c
 }
 
 // SyncConfig holds configuration for remote config sync.
@@ -922,6 +2591,20 @@ type SyncConfig struct {
 	Passphrase   string `json:"passphrase,omitempty"`      // encryption passphrase (local only)
 	AutoPull     bool   `json:"auto_pull,omitempty"`       // enable periodic pull
 	PullInterval int    `json:"pull_interval,omitempty"`   // seconds (default: 300)
+	AutoPush     bool   `json:"auto_push,omitempty"`       // push local changes automatically
+	PushDebounce int    `json:"push_debounce,omitempty"`   // seconds to wait for further changes (default: 10)
+}
+
+// DefaultPushDebounce is how long auto-push waits after a change for
+// further changes before pushing.
+const DefaultPushDebounce = 10 * time.Second
+
+// GetPushDebounce returns the auto-push debounce window, applying the default.
+func (c *SyncConfig) GetPushDebounce() time.Duration {
+	if c == nil || c.PushDebounce <= 0 {
+		return DefaultPushDebounce
+	}
+	return time.Duration(c.PushDebounce) * time.Second
 }
 
 // OpenCCConfig is the top-level configuration structure stored in opencc.json.
@@ -932,6 +2615,7 @@ type OpenCCConfig struct {
 	ProxyPort              int                         `json:"proxy_port,omitempty"`               // proxy port (defaults to 19841)
 	WebPort                int                         `json:"web_port,omitempty"`                 // web UI port (defaults to 19840)
 	WebPasswordHash        string                      `json:"web_password_hash,omitempty"`        // bcrypt hash for Web UI access password
+	WebViewerPasswordHash  string                      `json:"web_viewer_password_hash,omitempty"` // bcrypt hash for read-only Web UI access
 	ClaudeAutoPermission   *AutoPermissionConfig       `json:"claude_auto_permission,omitempty"`   // auto-permission config for Claude Code
 	CodexAutoPermission    *AutoPermissionConfig       `json:"codex_auto_permission,omitempty"`    // auto-permission config for Codex
 	OpenCodeAutoPermission *AutoPermissionConfig       `json:"opencode_auto_permission,omitempty"` // auto-permission config for OpenCode
@@ -941,14 +2625,33 @@ type OpenCCConfig struct {
 	ProjectBindings        map[string]*ProjectBinding  `json:"project_bindings,omitempty"`         // directory path -> binding config
 	Sync                   *SyncConfig                 `json:"sync,omitempty"`                     // remote sync configuration
 	Pricing                map[string]*ModelPricing    `json:"pricing,omitempty"`                  // custom model pricing overrides
+	ContextWindows         map[string]int              `json:"context_windows,omitempty"`          // model context window overrides, by model name or prefix
+	PricingSync            *PricingSyncConfig          `json:"pricing_sync,omitempty"`             // signed pricing manifest updates
 	Budgets                *BudgetConfig               `json:"budgets,omitempty"`                  // budget configuration
+	Team                   *SyncConfig                 `json:"team,omitempty"`                     // where the shared team layer is pulled from
+	Currency               *CurrencyConfig             `json:"currency,omitempty"`                 // display currency for costs and budgets
+	UsageReports           *UsageReportConfig          `json:"usage_reports,omitempty"`            // scheduled usage summaries
+	UsageRetention         *UsageRetentionConfig       `json:"usage_retention,omitempty"`          // usage database retention and compaction
 	Webhooks               []*WebhookConfig            `json:"webhooks,omitempty"`                 // webhook configurations
+	Alerts                 *AlertConfig                `json:"alerts,omitempty"`                   // thresholds for alert webhook events
 	HealthCheck            *HealthCheckConfig          `json:"health_check,omitempty"`             // health check configuration
+	BodyCapture            *BodyCaptureConfig          `json:"body_capture,omitempty"`             // debug body capture limits
+	ModelAliases           []*ModelAlias               `json:"model_aliases,omitempty"`            // model rewrite rules
+	ResponseCache          *ResponseCacheConfig        `json:"response_cache,omitempty"`           // cache for deterministic requests
+	OverrideHeaders        *OverrideHeadersConfig      `json:"override_headers,omitempty"`         // per-request override header allowlist
+	Ingress                *IngressConfig              `json:"ingress,omitempty"`                  // client API keys for usage attribution
+	Tracing                *TracingConfig              `json:"tracing,omitempty"`                  // OpenTelemetry trace export
+	TLS                    *TLSConfig                  `json:"tls,omitempty"`                      // HTTPS for the Web UI and proxy
+	Log                    *LogConfig                  `json:"log,omitempty"`                      // zend.log level, format and rotation
+	AccessLog              *AccessLogConfig            `json:"access_log,omitempty"`               // per-request proxy access log
+	Idle                   *IdleConfig                 `json:"idle,omitempty"`                     // sleep when unused
+	TrustedHeaderAuth      *TrustedHeaderAuthConfig    `json:"trusted_header_auth,omitempty"`      // Web UI login by reverse proxy identity header
 	Compression            *CompressionConfig          `json:"compression,omitempty"`              // [BETA] context compression
 	Middleware             *MiddlewareConfig           `json:"middleware,omitempty"`               // [BETA] middleware pipeline
 	Agent                  *AgentConfig                `json:"agent,omitempty"`                    // [BETA] agent infrastructure
 	Bot                    *BotConfig                  `json:"bot,omitempty"`                      // [BETA] bot gateway configuration
 	DisabledProviders      map[string]*UnavailableMarking `json:"disabled_providers,omitempty"`    // manually disabled providers
+	Encryption             *EncryptionConfig           `json:"encryption,omitempty"`               // at-rest encryption of tokens and credentials
 }
 
 // UnmarshalJSON supports multiple config versions:
@@ -967,6 +2670,7 @@ func (c *OpenCCConfig) UnmarshalJSON(data []byte) error {
 		ProxyPort              int                            `json:"proxy_port,omitempty"`
 		WebPort                int                            `json:"web_port,omitempty"`
 		WebPasswordHash        string                         `json:"web_password_hash,omitempty"`        // v7+
+		WebViewerPasswordHash  string                         `json:"web_viewer_password_hash,omitempty"`
 		ShowProviderTag        bool                           `json:"show_provider_tag,omitempty"`        // v11+ (deprecated)
 		ClaudeAutoPermission   *AutoPermissionConfig          `json:"claude_auto_permission,omitempty"`   // v12+
 		CodexAutoPermission    *AutoPermissionConfig          `json:"codex_auto_permission,omitempty"`    // v12+
@@ -977,14 +2681,33 @@ func (c *OpenCCConfig) UnmarshalJSON(data []byte) error {
 		ProjectBindings        map[string]json.RawMessage     `json:"project_bindings,omitempty"`
 		Sync                   *SyncConfig                    `json:"sync,omitempty"`
 		Pricing                map[string]*ModelPricing       `json:"pricing,omitempty"`
+		ContextWindows         map[string]int                 `json:"context_windows,omitempty"`
+		PricingSync            *PricingSyncConfig             `json:"pricing_sync,omitempty"`
 		Budgets                *BudgetConfig                  `json:"budgets,omitempty"`
+		Team                   *SyncConfig                    `json:"team,omitempty"`
+		Currency               *CurrencyConfig                `json:"currency,omitempty"`
+		UsageReports           *UsageReportConfig             `json:"usage_reports,omitempty"`
+		UsageRetention         *UsageRetentionConfig          `json:"usage_retention,omitempty"`
 		Webhooks               []*WebhookConfig               `json:"webhooks,omitempty"`
+		Alerts                 *AlertConfig                   `json:"alerts,omitempty"`
 		HealthCheck            *HealthCheckConfig             `json:"health_check,omitempty"`
+		BodyCapture            *BodyCaptureConfig             `json:"body_capture,omitempty"`
+		ModelAliases           []*ModelAlias                  `json:"model_aliases,omitempty"`
+		ResponseCache          *ResponseCacheConfig           `json:"response_cache,omitempty"`
+		OverrideHeaders        *OverrideHeadersConfig         `json:"override_headers,omitempty"`
+		Ingress                *IngressConfig                 `json:"ingress,omitempty"`
+		Tracing                *TracingConfig                 `json:"tracing,omitempty"`
+		TLS                    *TLSConfig                     `json:"tls,omitempty"`
+		Log                    *LogConfig                     `json:"log,omitempty"`
+		AccessLog              *AccessLogConfig               `json:"access_log,omitempty"`
+		Idle                   *IdleConfig                    `json:"idle,omitempty"`
+		TrustedHeaderAuth      *TrustedHeaderAuthConfig       `json:"trusted_header_auth,omitempty"`
 		Compression            *CompressionConfig             `json:"compression,omitempty"`
 		Middleware             *MiddlewareConfig              `json:"middleware,omitempty"`
 		Agent                  *AgentConfig                   `json:"agent,omitempty"`
 		Bot                    *BotConfig                     `json:"bot,omitempty"`
 		DisabledProviders      map[string]*UnavailableMarking `json:"disabled_providers,omitempty"` // v14+
+		Encryption             *EncryptionConfig              `json:"encryption,omitempty"`
 	}
 	if err := json.Unmarshal(data, &raw); err != nil {
 		return err
@@ -995,6 +2718,7 @@ func (c *OpenCCConfig) UnmarshalJSON(data []byte) error {
 	c.ProxyPort = raw.ProxyPort
 	c.WebPort = raw.WebPort
 	c.WebPasswordHash = raw.WebPasswordHash
+	c.WebViewerPasswordHash = raw.WebViewerPasswordHash
 	// Note: ShowProviderTag is parsed but ignored (deprecated field)
 	c.ClaudeAutoPermission = raw.ClaudeAutoPermission
 	c.CodexAutoPermission = raw.CodexAutoPermission
@@ -1004,14 +2728,33 @@ func (c *OpenCCConfig) UnmarshalJSON(data []byte) error {
 	c.Profiles = raw.Profiles
 	c.Sync = raw.Sync
 	c.Pricing = raw.Pricing
+	c.ContextWindows = raw.ContextWindows
+	c.PricingSync = raw.PricingSync
 	c.Budgets = raw.Budgets
+	c.Team = raw.Team
+	c.Currency = raw.Currency
+	c.UsageReports = raw.UsageReports
+	c.UsageRetention = raw.UsageRetention
 	c.Webhooks = raw.Webhooks
+	c.Alerts = raw.Alerts
 	c.HealthCheck = raw.HealthCheck
+	c.BodyCapture = raw.BodyCapture
+	c.ModelAliases = raw.ModelAliases
+	c.ResponseCache = raw.ResponseCache
+	c.OverrideHeaders = raw.OverrideHeaders
+	c.Ingress = raw.Ingress
+	c.Tracing = raw.Tracing
+	c.TLS = raw.TLS
+	c.Log = raw.Log
+	c.AccessLog = raw.AccessLog
+	c.Idle = raw.Idle
+	c.TrustedHeaderAuth = raw.TrustedHeaderAuth
 	c.Compression = raw.Compression
 	c.Middleware = raw.Middleware
 	c.Agent = raw.Agent
 	c.Bot = raw.Bot
 	c.DisabledProviders = raw.DisabledProviders
+	c.Encryption = raw.Encryption
 
 	// Migrate default_cli → default_client
 	c.DefaultClient = raw.DefaultClient
@@ -1025,22 +2768,18 @@ func (c *OpenCCConfig) UnmarshalJSON(data []byte) error {
 		for path, msg := range raw.ProjectBindings {
 			// Try as object first (v5+ format with "cli"/"client" keys, or empty object)
 			var pbRaw struct {
-				Profile string `json:"profile,omitempty"`
-				Client  string `json:"client,omitempty"` // v7+
-				CLI     string `json:"cli,omitempty"`    // v5-v6 compat
+				ProjectBinding
+				CLI string `json:"cli,omitempty"` // v5-v6 compat
 			}
 			// Check if it's a JSON object (starts with '{')
 			trimmed := bytes.TrimSpace(msg)
 			if len(trimmed) > 0 && trimmed[0] == '{' {
 				if err := json.Unmarshal(msg, &pbRaw); err == nil {
-					client := pbRaw.Client
-					if client == "" {
-						client = pbRaw.CLI
-					}
-					c.ProjectBindings[path] = &ProjectBinding{
-						Profile: pbRaw.Profile,
-						Client:  client,
+					binding := pbRaw.ProjectBinding
+					if binding.Client == "" {
+						binding.Client = pbRaw.CLI
 					}
+					c.ProjectBindings[path] = &binding
 					continue
 				}
 			}
@@ -959,6 +959,52 @@ func TestConfigDirPath(t *testing.T) {
 	}
 }
 
+func TestConfigDirPathZenHome(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("GOZEN_CONFIG_DIR", "")
+	t.Setenv(HomeEnv, "")
+
+	if id := InstanceID(); id != "" {
+		t.Errorf("InstanceID() = %q for the default home, want empty", id)
+	}
+	if web, proxy := InstancePorts(); web != DefaultWebPort || proxy != DefaultProxyPort {
+		t.Errorf("InstancePorts() = %d, %d for the default home", web, proxy)
+	}
+	if got := BotSocketPath(); filepath.Base(got) != "zen-gateway.sock" {
+		t.Errorf("BotSocketPath() = %q for the default home", got)
+	}
+
+	// ZEN_HOME set to ~/.zen is still the default instance
+	t.Setenv(HomeEnv, filepath.Join(home, ConfigDir))
+	if id := InstanceID(); id != "" {
+		t.Errorf("InstanceID() = %q for ZEN_HOME=~/.zen, want empty", id)
+	}
+
+	work, personal := filepath.Join(home, "work"), filepath.Join(home, "personal")
+	t.Setenv("GOZEN_CONFIG_DIR", filepath.Join(home, "dev"))
+	t.Setenv(HomeEnv, work)
+	if got := ConfigDirPath(); got != work {
+		t.Errorf("ConfigDirPath() = %q, want ZEN_HOME %q over GOZEN_CONFIG_DIR", got, work)
+	}
+	workID := InstanceID()
+	workWeb, workProxy := InstancePorts()
+	if len(workID) != 8 {
+		t.Errorf("InstanceID() = %q, want 8 hex digits", workID)
+	}
+	if workWeb == DefaultWebPort || workProxy == DefaultProxyPort || workProxy != workWeb+1 {
+		t.Errorf("InstancePorts() = %d, %d, want a pair above the defaults", workWeb, workProxy)
+	}
+	if got := BotSocketPath(); filepath.Base(got) != "zen-gateway-"+workID+".sock" {
+		t.Errorf("BotSocketPath() = %q, want the instance ID in it", got)
+	}
+
+	t.Setenv(HomeEnv, personal)
+	if id := InstanceID(); id == workID {
+		t.Errorf("InstanceID() = %q for two homes", id)
+	}
+}
+
 func TestConfigFilePath(t *testing.T) {
 	dir := t.TempDir()
 	t.Setenv("HOME", dir)
@@ -3024,3 +3070,131 @@ func TestValidateRoutingConfig_CustomScenarios(t *testing.T) {
 		})
 	}
 }
+
+func TestMirrorConfigValidate(t *testing.T) {
+	providers := map[string]*ProviderConfig{"candidate": {BaseURL: "https://example.com"}}
+	tests := []struct {
+		name    string
+		m       *MirrorConfig
+		wantErr bool
+	}{
+		{"nil", nil, false},
+		{"valid", &MirrorConfig{Provider: "candidate", Percent: 10}, false},
+		{"full mirror", &MirrorConfig{Provider: "candidate", Percent: 100}, false},
+		{"missing provider", &MirrorConfig{Percent: 10}, true},
+		{"unknown provider", &MirrorConfig{Provider: "other", Percent: 10}, true},
+		{"zero percent", &MirrorConfig{Provider: "candidate"}, true},
+		{"over 100", &MirrorConfig{Provider: "candidate", Percent: 101}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.m.Validate(providers); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRequestTransformValidate(t *testing.T) {
+	cold, hot := 0.0, 2.5
+	tests := []struct {
+		name    string
+		rt      *RequestTransform
+		wantErr bool
+	}{
+		{"nil", nil, false},
+		{"valid", &RequestTransform{SystemPrepend: "x", StripSystem: []string{`<env>.*</env>`}, StripTools: []string{"mcp__*"}, MaxTokensCap: 100, Temperature: &cold}, false},
+		{"bad regex", &RequestTransform{StripSystem: []string{"("}}, true},
+		{"empty tool", &RequestTransform{StripTools: []string{""}}, true},
+		{"negative cap", &RequestTransform{MaxTokensCap: -1}, true},
+		{"temperature out of range", &RequestTransform{Temperature: &hot}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.rt.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	orig := &RequestTransform{StripTools: []string{"a"}, Temperature: &cold}
+	clone := orig.Clone()
+	clone.StripTools[0] = "b"
+	*clone.Temperature = 1
+	if orig.StripTools[0] != "a" || *orig.Temperature != 0 {
+		t.Error("Clone shares slices or pointers with the original")
+	}
+}
+
+func TestCurrencyConfig(t *testing.T) {
+	var none *CurrencyConfig
+	if none.GetCode() != "USD" || none.FromUSD(2) != 2 || none.Format(1.5) != "$1.50" {
+		t.Errorf("nil currency should be USD")
+	}
+
+	eur := &CurrencyConfig{Code: "eur", Rate: 0.9, FetchedRate: 0.8}
+	if eur.GetCode() != "EUR" || eur.GetRate() != 0.9 {
+		t.Errorf("fixed rate should win: %s %v", eur.GetCode(), eur.GetRate())
+	}
+	eur.Rate = 0
+	if eur.FromUSD(10) != 8 || eur.ToUSD(8) != 10 || eur.Format(8) != "€8.00" {
+		t.Errorf("fetched rate: %v %v %q", eur.FromUSD(10), eur.ToUSD(8), eur.Format(8))
+	}
+	if (&CurrencyConfig{Code: "CHF"}).GetRate() != 1 {
+		t.Error("a currency without a rate should not convert")
+	}
+	if got := FormatAmount("CHF", 3); got != "CHF 3.00" {
+		t.Errorf("FormatAmount(CHF) = %q", got)
+	}
+}
+
+func TestContextWindowFor(t *testing.T) {
+	overrides := map[string]int{"my-finetune": 32000, "claude-": 1000000}
+	for _, tc := range []struct {
+		model     string
+		overrides map[string]int
+		want      int
+	}{
+		{"claude-sonnet-4-5", nil, 200000},
+		{"gpt-4o-mini", nil, 128000},
+		{"gpt-4-32k-0613", nil, 32768},
+		{"gpt-4-0613", nil, 8192},
+		{"gemini-1.5-pro-002", nil, 2097152},
+		{"unknown-model", nil, DefaultContextWindow},
+		{"claude-sonnet-4-5", overrides, 1000000},
+		{"my-finetune-v2", overrides, 32000},
+		{"gpt-4o", overrides, 128000},
+	} {
+		if got := ContextWindowFor(tc.model, tc.overrides); got != tc.want {
+			t.Errorf("ContextWindowFor(%q) = %d, want %d", tc.model, got, tc.want)
+		}
+	}
+
+	var none *AlertConfig
+	if none.GetContextWarnPercent() != DefaultAlertContextWarnPercent {
+		t.Errorf("nil alerts: context warn percent = %d", none.GetContextWarnPercent())
+	}
+}
+
+func TestLogConfigDefaults(t *testing.T) {
+	var none *LogConfig
+	if none.GetLevel() != "info" || none.GetFormat() != "console" {
+		t.Errorf("defaults = %q, %q", none.GetLevel(), none.GetFormat())
+	}
+	if none.GetMaxSize() != 10<<20 || none.GetRotateInterval() != 24*time.Hour ||
+		none.GetMaxBackups() != 5 || none.GetMaxAge() != 14*24*time.Hour {
+		t.Errorf("rotation defaults = %d, %v, %d, %v", none.GetMaxSize(), none.GetRotateInterval(), none.GetMaxBackups(), none.GetMaxAge())
+	}
+
+	c := &LogConfig{Level: "debug", Format: "json", MaxSizeMB: 1, RotateHours: -1, MaxBackups: 2, MaxAgeDays: 3}
+	if c.GetLevel() != "debug" || c.GetFormat() != "json" {
+		t.Errorf("level, format = %q, %q", c.GetLevel(), c.GetFormat())
+	}
+	if c.GetMaxSize() != 1<<20 || c.GetRotateInterval() != 0 || c.GetMaxBackups() != 2 || c.GetMaxAge() != 72*time.Hour {
+		t.Errorf("rotation = %d, %v, %d, %v", c.GetMaxSize(), c.GetRotateInterval(), c.GetMaxBackups(), c.GetMaxAge())
+	}
+
+	var decoded OpenCCConfig
+	if err := json.Unmarshal([]byte(`{"log":{"level":"warn","rotate_hours":6}}`), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Log == nil || decoded.Log.GetLevel() != "warn" || decoded.Log.GetRotateInterval() != 6*time.Hour {
+		t.Errorf("decoded log = %+v", decoded.Log)
+	}
+}
@@ -0,0 +1,385 @@
+package config
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Key sources for config encryption.
+const (
+	EncryptionKeyPassphrase = "passphrase" // derived from ZEN_CONFIG_PASSPHRASE
+	EncryptionKeyKeychain   = "keychain"   // random key kept in the OS keyring
+)
+
+// ConfigPassphraseEnv names the environment variable holding the passphrase
+// of a config encrypted with the passphrase key source.
+const ConfigPassphraseEnv = "ZEN_CONFIG_PASSPHRASE"
+
+const (
+	sealedPrefix         = "enc:v1:"
+	encryptionCheckValue = "gozen"
+	configKDFIterations  = 600_000
+	configKeySize        = 32 // AES-256
+
+	keychainService = "gozen"
+	keychainAccount = "config-key"
+)
+
+// EncryptionConfig turns on encryption at rest of the tokens and
+// credentials in zen.json. Encrypted values are written as
+// "enc:v1:<base64>" and decrypted when the config is loaded, so the rest of
+// zen, including the Web UI, only ever sees plaintext.
+type EncryptionConfig struct {
+	Enabled   bool   `json:"enabled"`
+	KeySource string `json:"key_source,omitempty"` // "passphrase" (default) or "keychain"
+	Salt      string `json:"salt,omitempty"`       // base64 PBKDF2 salt of a passphrase key
+	Check     string `json:"check,omitempty"`      // a known value sealed with the key, to detect a wrong key
+}
+
+// IsEnabled reports whether config encryption is on.
+func (e *EncryptionConfig) IsEnabled() bool {
+	return e != nil && e.Enabled
+}
+
+// GetKeySource returns the key source, defaulting to "passphrase".
+func (e *EncryptionConfig) GetKeySource() string {
+	if e == nil || e.KeySource == "" {
+		return EncryptionKeyPassphrase
+	}
+	return e.KeySource
+}
+
+// IsSealed reports whether v is an encrypted config value.
+func IsSealed(v string) bool {
+	return strings.HasPrefix(v, sealedPrefix)
+}
+
+var (
+	configKeysMu sync.Mutex
+	configKeys   = make(map[string][]byte) // key source and salt -> key
+
+	// keychainStore writes an OS keyring entry. Replaced in tests.
+	keychainStore = storeKeychain
+)
+
+// sensitiveFields returns the fields of cfg that are encrypted at rest: the
+// token fields plus the sync and team sync credentials.
+func sensitiveFields(cfg *OpenCCConfig) []tokenField {
+	fields := tokenFields(cfg)
+	syncs := []struct {
+		prefix string
+		sc     *SyncConfig
+	}{{"sync", cfg.Sync}, {"team", cfg.Team}}
+	for _, s := range syncs {
+		prefix, sc := s.prefix, s.sc
+		if sc == nil {
+			continue
+		}
+		fields = append(fields,
+			tokenField{name: prefix + ".token", value: &sc.Token},
+			tokenField{name: prefix + ".access_key", value: &sc.AccessKey},
+			tokenField{name: prefix + ".secret_key", value: &sc.SecretKey},
+			tokenField{name: prefix + ".passphrase", value: &sc.Passphrase},
+		)
+	}
+	return fields
+}
+
+// configKey returns the key of an encrypted config, checking it against
+// e.Check. Keys are cached for the life of the process.
+func configKey(e *EncryptionConfig) ([]byte, error) {
+	cacheKey := e.GetKeySource() + "\x00" + e.Salt
+	configKeysMu.Lock()
+	key := configKeys[cacheKey]
+	configKeysMu.Unlock()
+
+	if key == nil {
+		switch e.GetKeySource() {
+		case EncryptionKeyPassphrase:
+			passphrase := os.Getenv(ConfigPassphraseEnv)
+			if passphrase == "" {
+				return nil, fmt.Errorf("config is encrypted: set %s to its passphrase", ConfigPassphraseEnv)
+			}
+			salt, err := base64.StdEncoding.DecodeString(e.Salt)
+			if err != nil || len(salt) == 0 {
+				return nil, fmt.Errorf("config encryption salt is invalid")
+			}
+			key = deriveConfigKey(passphrase, salt)
+		case EncryptionKeyKeychain:
+			encoded, err := keychainLookup(keychainService, keychainAccount)
+			if err != nil {
+				return nil, fmt.Errorf("config is encrypted: read key from keychain: %w", err)
+			}
+			if key, err = base64.StdEncoding.DecodeString(encoded); err != nil || len(key) != configKeySize {
+				return nil, fmt.Errorf("config is encrypted: keychain key is invalid")
+			}
+		default:
+			return nil, fmt.Errorf("unknown encryption key_source %q", e.KeySource)
+		}
+	}
+
+	if check, err := unseal(key, e.Check); err != nil || check != encryptionCheckValue {
+		return nil, fmt.Errorf("config is encrypted with a different key")
+	}
+	configKeysMu.Lock()
+	configKeys[cacheKey] = key
+	configKeysMu.Unlock()
+	return key, nil
+}
+
+func deriveConfigKey(passphrase string, salt []byte) []byte {
+	return pbkdf2.Key([]byte(passphrase), salt, configKDFIterations, configKeySize, sha256.New)
+}
+
+// seal encrypts v with AES-256-GCM.
+func seal(key []byte, v string) (string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	return sealedPrefix + base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(v), nil)), nil
+}
+
+// unseal decrypts a value produced by seal.
+func unseal(key []byte, v string) (string, error) {
+	if !IsSealed(v) {
+		return "", errors.New("value is not encrypted")
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(v, sealedPrefix))
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("ciphertext too short")
+	}
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// openConfig decrypts the sealed fields of cfg in place and returns the
+// ciphertext of each plaintext, so unchanged values are written back as
+// they were. Without the key the fields stay sealed.
+func openConfig(cfg *OpenCCConfig) (map[string]string, error) {
+	if !cfg.Encryption.IsEnabled() {
+		return nil, nil
+	}
+	key, err := configKey(cfg.Encryption)
+	if err != nil {
+		return nil, err
+	}
+	sealed := make(map[string]string)
+	for _, f := range sensitiveFields(cfg) {
+		if !IsSealed(*f.value) {
+			continue
+		}
+		plaintext, err := unseal(key, *f.value)
+		if err != nil {
+			return sealed, fmt.Errorf("decrypt %s: %w", f.name, err)
+		}
+		sealed[plaintext] = *f.value
+		*f.value = plaintext
+	}
+	return sealed, nil
+}
+
+// marshalConfig encodes cfg for zen.json, encrypting its sensitive fields
+// when encryption is enabled. Plaintexts found in sealed reuse their
+// ciphertext; new ciphertexts are added to it.
+func marshalConfig(cfg *OpenCCConfig, sealed map[string]string) ([]byte, error) {
+	if !cfg.Encryption.IsEnabled() {
+		return json.MarshalIndent(cfg, "", "  ")
+	}
+
+	// Encrypt a copy; the in-memory config stays in plaintext
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var out OpenCCConfig
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	var key []byte
+	for _, f := range sensitiveFields(&out) {
+		v := *f.value
+		if v == "" || IsSealed(v) || IsSecretRef(v) {
+			continue
+		}
+		if c, ok := sealed[v]; ok {
+			*f.value = c
+			continue
+		}
+		if key == nil {
+			if key, err = configKey(cfg.Encryption); err != nil {
+				return nil, fmt.Errorf("cannot encrypt %s: %w", f.name, err)
+			}
+		}
+		if *f.value, err = seal(key, v); err != nil {
+			return nil, fmt.Errorf("encrypt %s: %w", f.name, err)
+		}
+		sealed[v] = *f.value
+	}
+	return json.MarshalIndent(&out, "", "  ")
+}
+
+// sealSnapshot returns a saved zen.json with its secrets sealed under enc.
+// Snapshots already encrypted are returned unchanged.
+func sealSnapshot(data []byte, enc *EncryptionConfig, sealed map[string]string) ([]byte, error) {
+	var cfg OpenCCConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Encryption.IsEnabled() {
+		return data, nil
+	}
+	cfg.Encryption = enc
+	out, err := marshalConfig(&cfg, sealed)
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// EnableEncryption turns on encryption at rest and rewrites zen.json with
+// its tokens and credentials encrypted. With the passphrase key source the
+// key is derived from passphrase, which must then be provided in
+// ZEN_CONFIG_PASSPHRASE whenever the config is loaded; with the keychain
+// source a random key is stored in the OS keyring.
+func (s *Store) EnableEncryption(source, passphrase string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	s.ensureConfig()
+	if s.config.Encryption.IsEnabled() {
+		return fmt.Errorf("config is already encrypted")
+	}
+
+	enc := &EncryptionConfig{Enabled: true, KeySource: source}
+	var key []byte
+	switch enc.GetKeySource() {
+	case EncryptionKeyPassphrase:
+		if passphrase == "" {
+			return fmt.Errorf("a passphrase is required")
+		}
+		salt := make([]byte, 16)
+		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+			return err
+		}
+		enc.Salt = base64.StdEncoding.EncodeToString(salt)
+		key = deriveConfigKey(passphrase, salt)
+	case EncryptionKeyKeychain:
+		key = make([]byte, configKeySize)
+		if _, err := io.ReadFull(rand.Reader, key); err != nil {
+			return err
+		}
+		if err := keychainStore(keychainService, keychainAccount, base64.StdEncoding.EncodeToString(key)); err != nil {
+			return fmt.Errorf("store key in keychain: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown key source %q", source)
+	}
+	check, err := seal(key, encryptionCheckValue)
+	if err != nil {
+		return err
+	}
+	enc.Check = check
+
+	configKeysMu.Lock()
+	configKeys[enc.GetKeySource()+"\x00"+enc.Salt] = key
+	configKeysMu.Unlock()
+	s.config.Encryption = enc
+	s.sealed = make(map[string]string)
+	if err := s.saveLocked(); err != nil {
+		s.config.Encryption = nil
+		return err
+	}
+	// Earlier versions of the config in the history hold the secrets too
+	s.sealSnapshotsLocked()
+	return nil
+}
+
+// DisableEncryption turns off encryption at rest and rewrites zen.json in
+// plaintext. The config must have been decrypted, i.e. its key must be
+// available.
+func (s *Store) DisableEncryption() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	s.ensureConfig()
+	if !s.config.Encryption.IsEnabled() {
+		return fmt.Errorf("config is not encrypted")
+	}
+	if _, err := configKey(s.config.Encryption); err != nil {
+		return err
+	}
+	enc := s.config.Encryption
+	s.config.Encryption = nil
+	if err := s.saveLocked(); err != nil {
+		s.config.Encryption = enc
+		return err
+	}
+	return nil
+}
+
+// IsEncrypted reports whether the config is encrypted at rest.
+func (s *Store) IsEncrypted() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	return s.config != nil && s.config.Encryption.IsEnabled()
+}
+
+// storeKeychain writes a password to the macOS Keychain with security(1),
+// or to the Secret Service on Linux with secret-tool(1).
+func storeKeychain(service, account, value string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), secretLookupTimeout)
+	defer cancel()
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.CommandContext(ctx, "security", "add-generic-password", "-U", "-s", service, "-a", account, "-w", value)
+	case "linux":
+		cmd = exec.CommandContext(ctx, "secret-tool", "store", "--label=GoZen config key", "service", service, "account", account)
+		cmd.Stdin = strings.NewReader(value)
+	default:
+		return fmt.Errorf("the keychain is not supported on %s", runtime.GOOS)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w: %s", cmd.Path, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// clearConfigKeys forgets the cached config keys, as a new process would.
+func clearConfigKeys() {
+	configKeysMu.Lock()
+	configKeys = make(map[string][]byte)
+	configKeysMu.Unlock()
+}
+
+func TestConfigEncryption(t *testing.T) {
+	home := setTestHome(t)
+	clearConfigKeys()
+	t.Cleanup(clearConfigKeys)
+	path := filepath.Join(home, ConfigDir, ConfigFile)
+
+	s := &Store{path: path}
+	s.SetProvider("p", &ProviderConfig{BaseURL: "https://api.example.com", AuthToken: "sk-secret", Keys: []*ProviderKey{{ID: "next", Token: "sk-next"}}})
+	s.SetProvider("ref", &ProviderConfig{BaseURL: "https://api.example.com", AuthToken: "env://ZEN_TEST_TOKEN"})
+	s.SetSyncConfig(&SyncConfig{Backend: "gist", Token: "ghp-secret", Passphrase: "sync-pass"})
+
+	if err := s.EnableEncryption(EncryptionKeyPassphrase, ""); err == nil {
+		t.Error("EnableEncryption() without a passphrase should fail")
+	}
+	if err := s.EnableEncryption(EncryptionKeyPassphrase, "hunter2"); err != nil {
+		t.Fatalf("EnableEncryption() error: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	for _, plaintext := range []string{"sk-secret", "sk-next", "ghp-secret", "sync-pass"} {
+		if strings.Contains(string(data), plaintext) {
+			t.Errorf("zen.json contains %q in plaintext", plaintext)
+		}
+	}
+	if !strings.Contains(string(data), "env://ZEN_TEST_TOKEN") {
+		t.Error("secret references should not be encrypted")
+	}
+	if got := s.GetProvider("p").AuthToken; got != "sk-secret" {
+		t.Errorf("in-memory token = %q, want plaintext", got)
+	}
+
+	// A new process without the passphrase sees the values sealed
+	clearConfigKeys()
+	t.Setenv(ConfigPassphraseEnv, "")
+	locked := &Store{path: path}
+	locked.Load()
+	if got := locked.GetProvider("p").AuthToken; !IsSealed(got) {
+		t.Errorf("token without the key = %q, want it sealed", got)
+	}
+	if err := locked.SetProvider("new", &ProviderConfig{BaseURL: "https://x.example.com", AuthToken: "sk-new"}); err == nil {
+		t.Error("saving a new token without the key should fail")
+	}
+
+	// With the wrong passphrase the key is rejected
+	t.Setenv(ConfigPassphraseEnv, "wrong")
+	if _, err := configKey(locked.config.Encryption); err == nil {
+		t.Error("configKey() with the wrong passphrase should fail")
+	}
+
+	// With the passphrase the values are decrypted transparently
+	t.Setenv(ConfigPassphraseEnv, "hunter2")
+	s2 := &Store{path: path}
+	s2.Load()
+	if got := s2.GetProvider("p"); got.AuthToken != "sk-secret" || got.Keys[0].Token != "sk-next" {
+		t.Errorf("decrypted tokens = %q, %q", got.AuthToken, got.Keys[0].Token)
+	}
+	if got := s2.GetSyncConfig(); got.Token != "ghp-secret" || got.Passphrase != "sync-pass" {
+		t.Errorf("decrypted sync config = %+v", got)
+	}
+
+	// Unchanged values keep their ciphertext across saves
+	before, _ := os.ReadFile(path)
+	s2.SetProvider("other", &ProviderConfig{BaseURL: "https://o.example.com"})
+	after, _ := os.ReadFile(path)
+	var cfgBefore, cfgAfter OpenCCConfig
+	cfgBefore.UnmarshalJSON(before)
+	cfgAfter.UnmarshalJSON(after)
+	if cfgBefore.Providers["p"].AuthToken != cfgAfter.Providers["p"].AuthToken {
+		t.Error("saving re-encrypted an unchanged token")
+	}
+
+	if err := s2.DisableEncryption(); err != nil {
+		t.Fatalf("DisableEncryption() error: %v", err)
+	}
+	data, _ = os.ReadFile(path)
+	if !strings.Contains(string(data), "sk-secret") || strings.Contains(string(data), sealedPrefix) {
+		t.Error("zen.json should be plaintext after DisableEncryption()")
+	}
+}
+
+func TestEnableEncryptionSealsHistory(t *testing.T) {
+	home := setTestHome(t)
+	clearConfigKeys()
+	t.Cleanup(clearConfigKeys)
+	dir := filepath.Join(home, ConfigDir)
+	path := filepath.Join(dir, ConfigFile)
+
+	// A plaintext config with a history of its own
+	os.MkdirAll(dir, 0755)
+	os.WriteFile(path, []byte(`{"version": 1}`), 0600)
+	s := &Store{path: path}
+	s.Load()
+	s.SetProvider("p", &ProviderConfig{BaseURL: "https://api.example.com", AuthToken: "sk-secret"})
+	s.SetProvider("q", &ProviderConfig{BaseURL: "https://q.example.com"})
+	snapshots, _ := s.Snapshots()
+	if len(snapshots) < 2 {
+		t.Fatalf("expected a history before encrypting, got %d snapshots", len(snapshots))
+	}
+
+	if err := s.EnableEncryption(EncryptionKeyPassphrase, "hunter2"); err != nil {
+		t.Fatalf("EnableEncryption() error: %v", err)
+	}
+	filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		if data, _ := os.ReadFile(p); strings.Contains(string(data), "sk-secret") {
+			t.Errorf("%s contains the token in plaintext", p)
+		}
+		return nil
+	})
+
+	// Rolling back to a version from before encryption keeps it encrypted
+	snapshots, _ = s.Snapshots()
+	if err := s.Rollback(snapshots[1].ID); err != nil {
+		t.Fatalf("Rollback() error: %v", err)
+	}
+	if data, _ := os.ReadFile(path); strings.Contains(string(data), "sk-secret") || !strings.Contains(string(data), sealedPrefix) {
+		t.Errorf("zen.json after rollback:\n%s", data)
+	}
+	if got := s.GetProvider("p").AuthToken; got != "sk-secret" {
+		t.Errorf("token after rollback = %q, want sk-secret", got)
+	}
+}
+
+func TestConfigEncryptionKeychain(t *testing.T) {
+	home := setTestHome(t)
+	clearConfigKeys()
+	t.Cleanup(clearConfigKeys)
+
+	keyring := make(map[string]string)
+	origStore, origLookup := keychainStore, keychainLookup
+	keychainStore = func(service, account, value string) error {
+		keyring[service+"/"+account] = value
+		return nil
+	}
+	keychainLookup = func(service, account string) (string, error) {
+		return keyring[service+"/"+account], nil
+	}
+	t.Cleanup(func() { keychainStore, keychainLookup = origStore, origLookup })
+
+	path := filepath.Join(home, ConfigDir, ConfigFile)
+	s := &Store{path: path}
+	s.SetProvider("p", &ProviderConfig{BaseURL: "https://api.example.com", AuthToken: "sk-secret"})
+	if err := s.EnableEncryption(EncryptionKeyKeychain, ""); err != nil {
+		t.Fatalf("EnableEncryption(keychain) error: %v", err)
+	}
+	if keyring["gozen/config-key"] == "" {
+		t.Fatal("the key was not stored in the keychain")
+	}
+
+	clearConfigKeys()
+	s2 := &Store{path: path}
+	s2.Load()
+	if got := s2.GetProvider("p").AuthToken; got != "sk-secret" {
+		t.Errorf("decrypted token = %q, want sk-secret", got)
+	}
+}
@@ -0,0 +1,259 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Config history keeps a copy of zen.json after every save, so an edit can
+// be diffed against earlier versions and rolled back.
+const (
+	ConfigHistoryDir   = "history" // under the config directory
+	ConfigHistoryLimit = 50        // snapshots kept; older ones are pruned
+
+	// CurrentSnapshotID names the config file as it is now in diffs.
+	CurrentSnapshotID = "current"
+
+	snapshotPrefix     = "zen-"
+	snapshotSuffix     = ".json"
+	snapshotTimeFormat = "20060102T150405.000000Z"
+)
+
+// ErrSnapshotNotFound is returned for an unknown snapshot ID.
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// ConfigSnapshot describes a saved version of zen.json.
+type ConfigSnapshot struct {
+	ID   string    `json:"id"`
+	Time time.Time `json:"time"`
+	Size int64     `json:"size"`
+}
+
+// Config change operations.
+const (
+	ConfigChangeAdded   = "added"
+	ConfigChangeRemoved = "removed"
+	ConfigChangeChanged = "changed"
+)
+
+// ConfigChange is one difference between two versions of the config.
+type ConfigChange struct {
+	Path string      `json:"path"` // dotted path, e.g. "providers.anthropic.base_url"
+	Op   string      `json:"op"`   // "added", "removed" or "changed"
+	From interface{} `json:"from,omitempty"`
+	To   interface{} `json:"to,omitempty"`
+}
+
+func (s *Store) historyDir() string {
+	return filepath.Join(filepath.Dir(s.path), ConfigHistoryDir)
+}
+
+// snapshotLocked records data, the config just written, in the history.
+// When the history is empty the file being replaced is recorded first, so
+// the very first edit can be rolled back too; if it was written before
+// encryption was enabled its secrets are sealed first. Failures are logged:
+// history must never block a save.
+func (s *Store) snapshotLocked(previous, data []byte) {
+	dir := s.historyDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		log.Printf("Warning: config history: %v", err)
+		return
+	}
+	snapshots, _ := s.listSnapshotsLocked()
+	now := time.Now().UTC()
+	if len(snapshots) == 0 && len(previous) > 0 {
+		if s.config.Encryption.IsEnabled() {
+			var err error
+			if previous, err = sealSnapshot(previous, s.config.Encryption, s.sealed); err != nil {
+				log.Printf("Warning: config history: not recording the previous config: %v", err)
+			}
+		}
+		if previous != nil {
+			s.writeSnapshot(now.Add(-time.Microsecond), previous)
+		}
+	}
+	s.writeSnapshot(now, data)
+	s.pruneSnapshotsLocked()
+}
+
+// sealSnapshotsLocked seals the secrets of the snapshots written before
+// encryption was enabled. A snapshot that can't be sealed is removed
+// rather than left in plaintext.
+func (s *Store) sealSnapshotsLocked() {
+	snapshots, _ := s.listSnapshotsLocked()
+	for _, snap := range snapshots {
+		path := filepath.Join(s.historyDir(), snapshotPrefix+snap.ID+snapshotSuffix)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		sealed, err := sealSnapshot(data, s.config.Encryption, s.sealed)
+		if err == nil && !bytes.Equal(sealed, data) {
+			err = os.WriteFile(path, sealed, 0600)
+		}
+		if err != nil {
+			log.Printf("Warning: config history: removing snapshot %s: %v", snap.ID, err)
+			os.Remove(path)
+		}
+	}
+}
+
+func (s *Store) writeSnapshot(t time.Time, data []byte) {
+	name := snapshotPrefix + t.Format(snapshotTimeFormat) + snapshotSuffix
+	if err := os.WriteFile(filepath.Join(s.historyDir(), name), data, 0600); err != nil {
+		log.Printf("Warning: config history: %v", err)
+	}
+}
+
+func (s *Store) pruneSnapshotsLocked() {
+	snapshots, err := s.listSnapshotsLocked()
+	if err != nil || len(snapshots) <= ConfigHistoryLimit {
+		return
+	}
+	for _, snap := range snapshots[ConfigHistoryLimit:] {
+		os.Remove(filepath.Join(s.historyDir(), snapshotPrefix+snap.ID+snapshotSuffix))
+	}
+}
+
+// listSnapshotsLocked returns the snapshots, newest first.
+func (s *Store) listSnapshotsLocked() ([]ConfigSnapshot, error) {
+	entries, err := os.ReadDir(s.historyDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var snapshots []ConfigSnapshot
+	for _, e := range entries {
+		id, ok := strings.CutPrefix(e.Name(), snapshotPrefix)
+		if !ok || !strings.HasSuffix(id, snapshotSuffix) {
+			continue
+		}
+		id = strings.TrimSuffix(id, snapshotSuffix)
+		t, err := time.Parse(snapshotTimeFormat, id)
+		if err != nil {
+			continue
+		}
+		snap := ConfigSnapshot{ID: id, Time: t}
+		if info, err := e.Info(); err == nil {
+			snap.Size = info.Size()
+		}
+		snapshots = append(snapshots, snap)
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Time.After(snapshots[j].Time) })
+	return snapshots, nil
+}
+
+// Snapshots returns the saved versions of the config, newest first.
+func (s *Store) Snapshots() ([]ConfigSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.listSnapshotsLocked()
+}
+
+// SnapshotData returns the contents of a snapshot, or of the config file
+// for CurrentSnapshotID.
+func (s *Store) SnapshotData(id string) ([]byte, error) {
+	if id == CurrentSnapshotID {
+		return os.ReadFile(s.path)
+	}
+	if _, err := time.Parse(snapshotTimeFormat, id); err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrSnapshotNotFound, id)
+	}
+	data, err := os.ReadFile(filepath.Join(s.historyDir(), snapshotPrefix+id+snapshotSuffix))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %q", ErrSnapshotNotFound, id)
+	}
+	return data, err
+}
+
+// Rollback restores the config saved in a snapshot. The restored config is
+// validated and saved like any other edit, so it becomes the newest
+// snapshot and the rollback can itself be undone.
+func (s *Store) Rollback(id string) error {
+	data, err := s.SnapshotData(id)
+	if err != nil {
+		return err
+	}
+	var cfg OpenCCConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("snapshot %q: %w", id, err)
+	}
+	sealed, err := openConfig(&cfg)
+	if err != nil {
+		return fmt.Errorf("snapshot %q: %w", id, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev, prevSealed, prevOverrides := s.config, s.sealed, s.overrides
+	s.config, s.sealed, s.overrides = &cfg, sealed, nil
+	s.ensureConfig()
+	if err := s.saveLocked(); err != nil {
+		s.config, s.sealed, s.overrides = prev, prevSealed, prevOverrides
+		return err
+	}
+	// Layer the environment overrides over the restored config again
+	merged, overrides, _ := loadOverrides(s.config)
+	s.config, s.overrides = merged, overrides
+	return nil
+}
+
+// DiffConfigs lists the differences between two versions of zen.json.
+// Objects are compared field by field; arrays and other values as a whole.
+func DiffConfigs(from, to []byte) ([]ConfigChange, error) {
+	var a, b interface{}
+	if err := json.Unmarshal(from, &a); err != nil {
+		return nil, fmt.Errorf("parse from: %w", err)
+	}
+	if err := json.Unmarshal(to, &b); err != nil {
+		return nil, fmt.Errorf("parse to: %w", err)
+	}
+	var changes []ConfigChange
+	diffValues("", a, b, &changes)
+	return changes, nil
+}
+
+func diffValues(path string, a, b interface{}, changes *[]ConfigChange) {
+	am, aok := a.(map[string]interface{})
+	bm, bok := b.(map[string]interface{})
+	if !aok || !bok {
+		if !reflect.DeepEqual(a, b) {
+			*changes = append(*changes, ConfigChange{Path: path, Op: ConfigChangeChanged, From: a, To: b})
+		}
+		return
+	}
+	keys := make(map[string]bool)
+	for k := range am {
+		keys[k] = true
+	}
+	for k := range bm {
+		keys[k] = true
+	}
+	for _, k := range sortedKeys(keys) {
+		p := k
+		if path != "" {
+			p = path + "." + k
+		}
+		av, inA := am[k]
+		bv, inB := bm[k]
+		switch {
+		case !inA:
+			*changes = append(*changes, ConfigChange{Path: p, Op: ConfigChangeAdded, To: bv})
+		case !inB:
+			*changes = append(*changes, ConfigChange{Path: p, Op: ConfigChangeRemoved, From: av})
+		default:
+			diffValues(p, av, bv, changes)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigHistory(t *testing.T) {
+	home := setTestHome(t)
+	s := &Store{path: filepath.Join(home, ConfigDir, ConfigFile)}
+
+	s.SetProvider("a", &ProviderConfig{BaseURL: "https://a.example.com", AuthToken: "k"})
+	s.SetProvider("a", &ProviderConfig{BaseURL: "https://b.example.com", AuthToken: "k"})
+	snapshots, err := s.Snapshots()
+	if err != nil || len(snapshots) != 2 {
+		t.Fatalf("Snapshots() = %d, %v; want 2", len(snapshots), err)
+	}
+	newest, first := snapshots[0], snapshots[1]
+	if !newest.Time.After(first.Time) {
+		t.Error("snapshots should be listed newest first")
+	}
+
+	fromData, _ := s.SnapshotData(first.ID)
+	toData, _ := s.SnapshotData(CurrentSnapshotID)
+	changes, err := DiffConfigs(fromData, toData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Path != "providers.a.base_url" || changes[0].Op != ConfigChangeChanged ||
+		changes[0].From != "https://a.example.com" || changes[0].To != "https://b.example.com" {
+		t.Errorf("changes = %+v", changes)
+	}
+
+	if err := s.Rollback(first.ID); err != nil {
+		t.Fatalf("Rollback() error: %v", err)
+	}
+	if got := s.GetProvider("a").BaseURL; got != "https://a.example.com" {
+		t.Errorf("base_url after rollback = %s", got)
+	}
+	if snapshots, _ = s.Snapshots(); len(snapshots) != 3 {
+		t.Errorf("a rollback should be recorded as a new snapshot, have %d", len(snapshots))
+	}
+
+	if err := s.Rollback("20000101T000000.000000Z"); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Errorf("Rollback(unknown) error = %v", err)
+	}
+	if _, err := s.SnapshotData("../zen"); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Errorf("SnapshotData(../zen) error = %v", err)
+	}
+
+	// History is bounded
+	for i := 0; i < ConfigHistoryLimit; i++ {
+		s.SetDefaultProfile("default")
+	}
+	if snapshots, _ = s.Snapshots(); len(snapshots) != ConfigHistoryLimit {
+		t.Errorf("history holds %d snapshots, want %d", len(snapshots), ConfigHistoryLimit)
+	}
+}
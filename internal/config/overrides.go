@@ -0,0 +1,305 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Any config key can be overridden with a ZEN_ environment variable named
+// after its path, so container and CI deployments need no templated
+// zen.json:
+//
+//	ZEN_PROXY_PORT=29841                      proxy_port
+//	ZEN_DEFAULT_PROFILE=ci                    default_profile
+//	ZEN_PROVIDERS_FOO_AUTH_TOKEN=sk-...       providers.foo.auth_token
+//	ZEN_PROFILES_CI_PROVIDERS=foo,bar         profiles.ci.providers
+//	ZEN_SYNC='{"backend":"gist",...}'         sync, as JSON
+//
+// Names are matched case-insensitively, with "_" standing for "_" or "-" in
+// map keys such as provider names. Lists take comma-separated values and
+// objects take JSON. Overrides are layered over zen.json when it is loaded
+// and are never written back to it.
+const EnvOverridePrefix = "ZEN_"
+
+// reservedEnv are ZEN_ variables zen reads for other purposes.
+var reservedEnv = map[string]bool{
+	"ZEN_API_KEY":       true,
+	ConfigPassphraseEnv: true,
+	HomeEnv:             true,
+}
+
+// ConfigOverride is a config value set by an environment variable.
+type ConfigOverride struct {
+	Env  string `json:"env"`
+	Path string `json:"path"` // dotted, e.g. "providers.foo.auth_token"
+
+	path    []string
+	value   interface{} // as decoded from JSON
+	orig    interface{} // the value in zen.json
+	hadOrig bool
+}
+
+// envOverrides parses the ZEN_ variables in environ that name a config key
+// of cfg. Variables that name no key are ignored; values that don't fit
+// their key are reported.
+func envOverrides(cfg *OpenCCConfig, environ []string) ([]*ConfigOverride, []error) {
+	tree, err := configTree(cfg)
+	if err != nil {
+		return nil, []error{err}
+	}
+	var overrides []*ConfigOverride
+	var errs []error
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(strings.ToUpper(name), EnvOverridePrefix) || reservedEnv[name] {
+			continue
+		}
+		tokens := strings.Split(strings.ToLower(name[len(EnvOverridePrefix):]), "_")
+		path, leaf, ok := resolveOverride(tokens, reflect.TypeOf(cfg), tree)
+		if !ok {
+			continue
+		}
+		v, err := overrideValue(value, leaf)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		overrides = append(overrides, &ConfigOverride{Env: name, Path: strings.Join(path, "."), path: path, value: v})
+	}
+	sort.Slice(overrides, func(i, j int) bool { return overrides[i].Env < overrides[j].Env })
+	return overrides, errs
+}
+
+// resolveOverride maps the "_"-separated tokens of a variable name to a
+// config path by walking type t. node is the current JSON value at this
+// point, used to match existing map keys.
+func resolveOverride(tokens []string, t reflect.Type, node interface{}) ([]string, reflect.Type, bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if len(tokens) == 0 {
+		return nil, t, true
+	}
+	obj, _ := node.(map[string]interface{})
+	switch t.Kind() {
+	case reflect.Struct:
+		fields := jsonFields(t)
+		// Longest field name first: "auth_token" before "auth"
+		for k := len(tokens); k >= 1; k-- {
+			name := strings.Join(tokens[:k], "_")
+			ft, ok := fields[name]
+			if !ok {
+				continue
+			}
+			if rest, leaf, ok := resolveOverride(tokens[k:], ft, obj[name]); ok {
+				return append([]string{name}, rest...), leaf, true
+			}
+		}
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, nil, false
+		}
+		for k := 1; k <= len(tokens); k++ {
+			key := strings.Join(tokens[:k], "_")
+			for existing := range obj {
+				if strings.ReplaceAll(strings.ToLower(existing), "-", "_") == key {
+					key = existing
+					break
+				}
+			}
+			if rest, leaf, ok := resolveOverride(tokens[k:], t.Elem(), obj[key]); ok {
+				return append([]string{key}, rest...), leaf, true
+			}
+		}
+	}
+	return nil, nil, false
+}
+
+// jsonFields maps the JSON names of a struct's fields to their types.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// overrideValue converts a variable's value to the JSON value of type t.
+func overrideValue(s string, t reflect.Type) (interface{}, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == durationType:
+		if d, err := time.ParseDuration(s); err == nil {
+			return float64(d), nil
+		}
+	case t.Kind() == reflect.String:
+		return s, nil
+	case t.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, fmt.Errorf("expected true or false")
+		}
+		return b, nil
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected a number")
+		}
+		return f, nil
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(s), "["):
+		items := []interface{}{}
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items, nil
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return nil, fmt.Errorf("expected JSON: %w", err)
+	}
+	return v, nil
+}
+
+// configTree returns cfg as a tree of JSON values.
+func configTree(cfg *OpenCCConfig) (map[string]interface{}, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var tree map[string]interface{}
+	err = json.Unmarshal(data, &tree)
+	return tree, err
+}
+
+func configFromTree(tree map[string]interface{}) (*OpenCCConfig, error) {
+	data, err := json.Marshal(tree)
+	if err != nil {
+		return nil, err
+	}
+	var cfg OpenCCConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func treeGet(tree map[string]interface{}, path []string) (interface{}, bool) {
+	var node interface{} = tree
+	for _, key := range path {
+		obj, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if node, ok = obj[key]; !ok {
+			return nil, false
+		}
+	}
+	return node, true
+}
+
+func treeSet(tree map[string]interface{}, path []string, v interface{}) {
+	obj := tree
+	for _, key := range path[:len(path)-1] {
+		next, ok := obj[key].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			obj[key] = next
+		}
+		obj = next
+	}
+	obj[path[len(path)-1]] = v
+}
+
+func treeDelete(tree map[string]interface{}, path []string) {
+	parent, ok := treeGet(tree, path[:len(path)-1])
+	if obj, isObj := parent.(map[string]interface{}); ok && isObj {
+		delete(obj, path[len(path)-1])
+	}
+}
+
+// applyOverrides returns cfg with the overrides applied, recording the
+// values they replace.
+func applyOverrides(cfg *OpenCCConfig, overrides []*ConfigOverride) (*OpenCCConfig, error) {
+	tree, err := configTree(cfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range overrides {
+		o.orig, o.hadOrig = treeGet(tree, o.path)
+		treeSet(tree, o.path, o.value)
+	}
+	out, err := configFromTree(tree)
+	if err != nil {
+		return nil, fmt.Errorf("apply environment overrides: %w", err)
+	}
+	return out, nil
+}
+
+// withoutOverrides returns cfg with the values of zen.json restored wherever
+// an override is still in effect. Values changed since the overrides were
+// applied are kept, so edits made while overridden are saved.
+func withoutOverrides(cfg *OpenCCConfig, overrides []*ConfigOverride) (*OpenCCConfig, error) {
+	tree, err := configTree(cfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range overrides {
+		if cur, ok := treeGet(tree, o.path); !ok || !reflect.DeepEqual(cur, o.value) {
+			continue
+		}
+		if o.hadOrig {
+			treeSet(tree, o.path, o.orig)
+		} else {
+			treeDelete(tree, o.path)
+		}
+	}
+	return configFromTree(tree)
+}
+
+// Overrides returns the environment overrides in effect.
+func (s *Store) Overrides() []ConfigOverride {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	overrides := make([]ConfigOverride, len(s.overrides))
+	for i, o := range s.overrides {
+		overrides[i] = ConfigOverride{Env: o.Env, Path: o.Path}
+	}
+	return overrides
+}
+
+// loadOverrides layers the environment overrides over cfg.
+func loadOverrides(cfg *OpenCCConfig) (*OpenCCConfig, []*ConfigOverride, []error) {
+	overrides, errs := envOverrides(cfg, os.Environ())
+	if len(overrides) == 0 {
+		return cfg, nil, errs
+	}
+	out, err := applyOverrides(cfg, overrides)
+	if err != nil {
+		return cfg, nil, append(errs, err)
+	}
+	return out, overrides, errs
+}
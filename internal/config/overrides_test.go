@@ -0,0 +1,118 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEnvOverrides(t *testing.T) {
+	cfg := &OpenCCConfig{
+		Providers: map[string]*ProviderConfig{
+			"my-provider": {BaseURL: "https://api.example.com", AuthToken: "sk-file"},
+		},
+		Profiles: map[string]*ProfileConfig{"default": {Providers: []string{"my-provider"}}},
+	}
+	overrides, errs := envOverrides(cfg, []string{
+		"ZEN_PROXY_PORT=29841",
+		"ZEN_DEFAULT_PROFILE=ci",
+		"ZEN_PROVIDERS_MY_PROVIDER_AUTH_TOKEN=sk-env",
+		"ZEN_PROVIDERS_NEW_BASE_URL=https://new.example.com",
+		"ZEN_PROFILES_CI_PROVIDERS=my-provider, new",
+		"ZEN_WEB_PORT=not-a-port",
+		"ZEN_API_KEY=reserved",
+		"ZEN_NO_SUCH_KEY=ignored",
+		"HOME=/tmp",
+	})
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "ZEN_WEB_PORT") {
+		t.Errorf("errs = %v, want one for ZEN_WEB_PORT", errs)
+	}
+	paths := make(map[string]string)
+	for _, o := range overrides {
+		paths[o.Env] = o.Path
+	}
+	want := map[string]string{
+		"ZEN_DEFAULT_PROFILE":                  "default_profile",
+		"ZEN_PROFILES_CI_PROVIDERS":            "profiles.ci.providers",
+		"ZEN_PROVIDERS_MY_PROVIDER_AUTH_TOKEN": "providers.my-provider.auth_token",
+		"ZEN_PROVIDERS_NEW_BASE_URL":           "providers.new.base_url",
+		"ZEN_PROXY_PORT":                       "proxy_port",
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("override paths = %v, want %v", paths, want)
+	}
+
+	got, err := applyOverrides(cfg, overrides)
+	if err != nil {
+		t.Fatalf("applyOverrides() error: %v", err)
+	}
+	if got.ProxyPort != 29841 || got.DefaultProfile != "ci" {
+		t.Errorf("proxy_port = %d, default_profile = %q", got.ProxyPort, got.DefaultProfile)
+	}
+	if got.Providers["my-provider"].AuthToken != "sk-env" || got.Providers["my-provider"].BaseURL != "https://api.example.com" {
+		t.Errorf("my-provider = %+v, want only the token overridden", got.Providers["my-provider"])
+	}
+	if got.Providers["new"] == nil || got.Providers["new"].BaseURL != "https://new.example.com" {
+		t.Errorf("new provider = %+v", got.Providers["new"])
+	}
+	if p := got.Profiles["ci"]; p == nil || !reflect.DeepEqual(p.Providers, []string{"my-provider", "new"}) {
+		t.Errorf("profile ci = %+v", p)
+	}
+}
+
+func TestStoreEnvOverrides(t *testing.T) {
+	home := setTestHome(t)
+	path := filepath.Join(home, ConfigDir, ConfigFile)
+
+	s := &Store{path: path}
+	s.SetProvider("p", &ProviderConfig{BaseURL: "https://api.example.com", AuthToken: "sk-file"})
+
+	t.Setenv("ZEN_PROVIDERS_P_AUTH_TOKEN", "sk-env")
+	t.Setenv("ZEN_PROXY_PORT", "29841")
+	s = &Store{path: path}
+	if err := s.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if got := s.GetProvider("p").AuthToken; got != "sk-env" {
+		t.Errorf("token = %q, want the override", got)
+	}
+	if got := s.GetProxyPort(); got != 29841 {
+		t.Errorf("proxy port = %d, want the override", got)
+	}
+	if got := len(s.Overrides()); got != 2 {
+		t.Errorf("Overrides() has %d entries, want 2", got)
+	}
+
+	// Saving keeps the overrides out of the file, but not other edits
+	if err := s.SetProvider("q", &ProviderConfig{BaseURL: "https://q.example.com", AuthToken: "sk-q"}); err != nil {
+		t.Fatalf("SetProvider() error: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "sk-env") || strings.Contains(string(data), "29841") {
+		t.Errorf("zen.json contains override values:\n%s", data)
+	}
+	if !strings.Contains(string(data), "sk-file") || !strings.Contains(string(data), "sk-q") {
+		t.Errorf("zen.json lost file values:\n%s", data)
+	}
+
+	// A value changed while overridden is saved
+	if err := s.SetProxyPort(29851); err != nil {
+		t.Fatalf("SetProxyPort() error: %v", err)
+	}
+	data, _ = os.ReadFile(path)
+	if !strings.Contains(string(data), "29851") {
+		t.Errorf("zen.json lacks the edited port:\n%s", data)
+	}
+
+	// Without a config file, overrides alone configure zen
+	t.Setenv("ZEN_PROVIDERS_P_BASE_URL", "https://env.example.com")
+	fresh := &Store{path: filepath.Join(home, "missing", ConfigFile)}
+	if err := fresh.Load(); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if p := fresh.GetProvider("p"); p == nil || p.BaseURL != "https://env.example.com" || p.AuthToken != "sk-env" {
+		t.Errorf("provider from environment = %+v", p)
+	}
+}
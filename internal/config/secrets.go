@@ -0,0 +1,368 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Secret references stand in for a token in the config, so the token itself
+// never has to be written to zen.json:
+//
+//	env://NAME                  environment variable NAME
+//	file:///path/to/token       contents of a file (file://~/... for the home directory)
+//	keychain://service/account  OS keyring entry (macOS Keychain, Secret Service on Linux)
+//	vault://path#field          HashiCorp Vault secret, via VAULT_ADDR and VAULT_TOKEN
+//
+// They are accepted wherever a provider or bot token is configured and
+// resolved when the token is used.
+const (
+	SecretSchemeEnv      = "env"
+	SecretSchemeFile     = "file"
+	SecretSchemeKeychain = "keychain"
+	SecretSchemeVault    = "vault"
+)
+
+// SecretCacheTTL is how long a resolved secret is reused before it is looked
+// up again.
+const SecretCacheTTL = 5 * time.Minute
+
+// secretLookupTimeout bounds a keychain or Vault lookup.
+const secretLookupTimeout = 10 * time.Second
+
+var secretSchemes = []string{SecretSchemeEnv, SecretSchemeFile, SecretSchemeKeychain, SecretSchemeVault}
+
+// SecretRef is a config field that holds a secret reference.
+type SecretRef struct {
+	Field string `json:"field"` // e.g. "providers.anthropic.auth_token"
+	Ref   string `json:"ref"`
+}
+
+type cachedSecret struct {
+	value   string
+	expires time.Time
+}
+
+var (
+	secretCacheMu sync.Mutex
+	secretCache   = make(map[string]cachedSecret)
+
+	// keychainLookup reads an OS keyring entry. Replaced in tests.
+	keychainLookup = lookupKeychain
+	// vaultClient is used for Vault lookups.
+	vaultClient = &http.Client{Timeout: secretLookupTimeout}
+)
+
+// secretScheme returns the scheme of a secret reference, or "" when v is a
+// literal value.
+func secretScheme(v string) string {
+	for _, scheme := range secretSchemes {
+		if strings.HasPrefix(v, scheme+"://") {
+			return scheme
+		}
+	}
+	return ""
+}
+
+// IsSecretRef reports whether v is a secret reference rather than a literal
+// value.
+func IsSecretRef(v string) bool {
+	return secretScheme(v) != ""
+}
+
+// ValidateSecretRef checks the syntax of a secret reference without
+// resolving it. Literal values are always valid.
+func ValidateSecretRef(v string) error {
+	scheme := secretScheme(v)
+	if scheme == "" {
+		return nil
+	}
+	rest := strings.TrimPrefix(v, scheme+"://")
+	switch scheme {
+	case SecretSchemeKeychain:
+		if service, account, ok := strings.Cut(rest, "/"); !ok || service == "" || account == "" {
+			return fmt.Errorf("%s: expected keychain://service/account", v)
+		}
+	case SecretSchemeVault:
+		if path, _, _ := strings.Cut(rest, "#"); path == "" {
+			return fmt.Errorf("%s: expected vault://path#field", v)
+		}
+	case SecretSchemeEnv:
+		if rest == "" {
+			return fmt.Errorf("%s: expected env://NAME", v)
+		}
+	case SecretSchemeFile:
+		if rest == "" {
+			return fmt.Errorf("%s: expected file:///path", v)
+		}
+	}
+	return nil
+}
+
+// ResolveSecret returns the value a secret reference points to, or v itself
+// when it is a literal value. Resolved values are cached for SecretCacheTTL;
+// failed lookups are not cached.
+func ResolveSecret(v string) (string, error) {
+	scheme := secretScheme(v)
+	if scheme == "" {
+		return v, nil
+	}
+
+	secretCacheMu.Lock()
+	cached, ok := secretCache[v]
+	secretCacheMu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.value, nil
+	}
+
+	if err := ValidateSecretRef(v); err != nil {
+		return "", err
+	}
+	rest := strings.TrimPrefix(v, scheme+"://")
+	var value string
+	var err error
+	switch scheme {
+	case SecretSchemeEnv:
+		var found bool
+		if value, found = os.LookupEnv(rest); !found {
+			err = fmt.Errorf("environment variable %s is not set", rest)
+		}
+	case SecretSchemeFile:
+		value, err = readSecretFile(rest)
+	case SecretSchemeKeychain:
+		service, account, _ := strings.Cut(rest, "/")
+		value, err = keychainLookup(service, account)
+	case SecretSchemeVault:
+		path, field, _ := strings.Cut(rest, "#")
+		value, err = lookupVault(path, field)
+	}
+	if err == nil && value == "" {
+		err = fmt.Errorf("secret is empty")
+	}
+	if err != nil {
+		return "", fmt.Errorf("resolve %s: %w", v, err)
+	}
+
+	secretCacheMu.Lock()
+	secretCache[v] = cachedSecret{value: value, expires: time.Now().Add(SecretCacheTTL)}
+	secretCacheMu.Unlock()
+	return value, nil
+}
+
+// SecretValue is ResolveSecret for callers that cannot report an error: a
+// reference that fails to resolve yields "".
+func SecretValue(v string) string {
+	value, err := ResolveSecret(v)
+	if err != nil {
+		return ""
+	}
+	return value
+}
+
+// ResolveTokens returns a copy of the provider with the secret references in
+// its auth token and keys resolved. A token that fails to resolve is left
+// empty in the copy and reported in the error.
+func (p *ProviderConfig) ResolveTokens() (*ProviderConfig, error) {
+	resolved := p.Clone()
+	var errs []error
+	var err error
+	if resolved.AuthToken, err = ResolveSecret(p.AuthToken); err != nil {
+		errs = append(errs, err)
+	}
+	for _, k := range resolved.Keys {
+		if k == nil {
+			continue
+		}
+		if k.Token, err = ResolveSecret(k.Token); err != nil {
+			errs = append(errs, fmt.Errorf("key %s: %w", k.ID, err))
+		}
+	}
+	return resolved, errors.Join(errs...)
+}
+
+// ClearSecretCache drops all cached secrets, so the next use of each
+// reference looks it up again.
+func ClearSecretCache() {
+	secretCacheMu.Lock()
+	defer secretCacheMu.Unlock()
+	secretCache = make(map[string]cachedSecret)
+}
+
+func readSecretFile(path string) (string, error) {
+	if strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(home, path[2:])
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// lookupKeychain reads a password from the macOS Keychain with security(1),
+// or from the Secret Service on Linux with secret-tool(1).
+func lookupKeychain(service, account string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), secretLookupTimeout)
+	defer cancel()
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.CommandContext(ctx, "security", "find-generic-password", "-s", service, "-a", account, "-w")
+	case "linux":
+		cmd = exec.CommandContext(ctx, "secret-tool", "lookup", "service", service, "account", account)
+	default:
+		return "", fmt.Errorf("keychain references are not supported on %s", runtime.GOOS)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", cmd.Path, err)
+	}
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+// lookupVault reads a field of a Vault secret. KV version 2 mounts nest the
+// fields under data.data; both layouts are accepted. Without a field, a
+// secret holding a single field yields that field.
+func lookupVault(path, field string) (string, error) {
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		return "", fmt.Errorf("VAULT_ADDR is not set")
+	}
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(addr, "/")+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	if token := os.Getenv("VAULT_TOKEN"); token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if ns := os.Getenv("VAULT_NAMESPACE"); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+	resp, err := vaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned status %d", resp.StatusCode)
+	}
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("decode vault response: %w", err)
+	}
+	fields := body.Data
+	if nested, ok := fields["data"].(map[string]interface{}); ok {
+		if _, hasMeta := fields["metadata"]; hasMeta {
+			fields = nested
+		}
+	}
+	if field == "" {
+		if len(fields) != 1 {
+			return "", fmt.Errorf("secret has %d fields; name one with #field", len(fields))
+		}
+		for k := range fields {
+			field = k
+		}
+	}
+	value, ok := fields[field].(string)
+	if !ok {
+		return "", fmt.Errorf("secret has no string field %q", field)
+	}
+	return value, nil
+}
+
+// tokenField is a config field holding a token.
+type tokenField struct {
+	name  string // e.g. "providers.anthropic.auth_token"
+	value *string
+}
+
+// tokenFields returns the provider and bot token fields of cfg, which accept
+// secret references.
+func tokenFields(cfg *OpenCCConfig) []tokenField {
+	var fields []tokenField
+	add := func(name string, v *string) {
+		fields = append(fields, tokenField{name: name, value: v})
+	}
+	for name, p := range cfg.Providers {
+		if p == nil {
+			continue
+		}
+		add("providers."+name+".auth_token", &p.AuthToken)
+		for _, k := range p.Keys {
+			if k != nil {
+				add("providers."+name+".keys."+k.ID, &k.Token)
+			}
+		}
+	}
+	if c := cfg.Compression; c != nil && c.Retrieval != nil {
+		add("compression.retrieval.embedding_api_key", &c.Retrieval.EmbeddingAPIKey)
+	}
+	if cfg.Bot != nil && cfg.Bot.Platforms != nil {
+		pl := cfg.Bot.Platforms
+		if pl.Telegram != nil {
+			add("bot.platforms.telegram.token", &pl.Telegram.Token)
+		}
+		if pl.Discord != nil {
+			add("bot.platforms.discord.token", &pl.Discord.Token)
+		}
+		if pl.Slack != nil {
+			add("bot.platforms.slack.bot_token", &pl.Slack.BotToken)
+			add("bot.platforms.slack.app_token", &pl.Slack.AppToken)
+		}
+		if pl.Lark != nil {
+			add("bot.platforms.lark.app_secret", &pl.Lark.AppSecret)
+		}
+		if pl.FBMessenger != nil {
+			add("bot.platforms.fbmessenger.page_token", &pl.FBMessenger.PageToken)
+			add("bot.platforms.fbmessenger.verify_token", &pl.FBMessenger.VerifyToken)
+			add("bot.platforms.fbmessenger.app_secret", &pl.FBMessenger.AppSecret)
+		}
+	}
+	return fields
+}
+
+// SecretRefs lists the fields of cfg that hold secret references, sorted by
+// field.
+func SecretRefs(cfg *OpenCCConfig) []SecretRef {
+	var refs []SecretRef
+	for _, f := range tokenFields(cfg) {
+		if IsSecretRef(*f.value) {
+			refs = append(refs, SecretRef{Field: f.name, Ref: *f.value})
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Field < refs[j].Field })
+	return refs
+}
+
+// CheckSecretRefs resolves each reference, bypassing the cache, and returns
+// the failures keyed by field. Resolved values are cached, so a check at
+// startup also warms the cache.
+func CheckSecretRefs(refs []SecretRef) map[string]error {
+	failed := make(map[string]error)
+	for _, ref := range refs {
+		secretCacheMu.Lock()
+		delete(secretCache, ref.Ref)
+		secretCacheMu.Unlock()
+		if _, err := ResolveSecret(ref.Ref); err != nil {
+			failed[ref.Field] = err
+		}
+	}
+	return failed
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveSecret(t *testing.T) {
+	ClearSecretCache()
+	t.Cleanup(ClearSecretCache)
+
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	os.WriteFile(tokenFile, []byte("sk-from-file\n"), 0600)
+	t.Setenv("ZEN_TEST_TOKEN", "sk-from-env")
+
+	orig := keychainLookup
+	keychainLookup = func(service, account string) (string, error) {
+		if service == "gozen" && account == "anthropic" {
+			return "sk-from-keychain", nil
+		}
+		return "", fmt.Errorf("not found")
+	}
+	t.Cleanup(func() { keychainLookup = orig })
+
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/gozen": // KV v2
+			w.Write([]byte(`{"data":{"data":{"anthropic":"sk-from-vault","openai":"sk-other"},"metadata":{"version":3}}}`))
+		case "/v1/kv/single": // KV v1
+			w.Write([]byte(`{"data":{"token":"sk-only"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer vault.Close()
+	t.Setenv("VAULT_ADDR", vault.URL)
+	t.Setenv("VAULT_TOKEN", "root")
+
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{ref: "sk-literal", want: "sk-literal"},
+		{ref: "env://ZEN_TEST_TOKEN", want: "sk-from-env"},
+		{ref: "env://ZEN_TEST_MISSING", wantErr: true},
+		{ref: "file://" + tokenFile, want: "sk-from-file"},
+		{ref: "file://" + filepath.Join(dir, "missing"), wantErr: true},
+		{ref: "keychain://gozen/anthropic", want: "sk-from-keychain"},
+		{ref: "keychain://gozen", wantErr: true},
+		{ref: "vault://secret/data/gozen#anthropic", want: "sk-from-vault"},
+		{ref: "vault://secret/data/gozen", wantErr: true}, // two fields, none named
+		{ref: "vault://kv/single", want: "sk-only"},
+		{ref: "vault://kv/missing#token", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ResolveSecret(tt.ref)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ResolveSecret(%q) = %q, %v; want %q, error %v", tt.ref, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	// Resolved values are cached until cleared
+	t.Setenv("ZEN_TEST_TOKEN", "sk-rotated")
+	if got := SecretValue("env://ZEN_TEST_TOKEN"); got != "sk-from-env" {
+		t.Errorf("cached value = %q, want sk-from-env", got)
+	}
+	ClearSecretCache()
+	if got := SecretValue("env://ZEN_TEST_TOKEN"); got != "sk-rotated" {
+		t.Errorf("value after ClearSecretCache = %q, want sk-rotated", got)
+	}
+}
+
+func TestSecretRefs(t *testing.T) {
+	ClearSecretCache()
+	t.Cleanup(ClearSecretCache)
+	t.Setenv("ZEN_TEST_PRIMARY", "sk-primary")
+
+	cfg := &OpenCCConfig{
+		Providers: map[string]*ProviderConfig{
+			"a": {AuthToken: "env://ZEN_TEST_PRIMARY", Keys: []*ProviderKey{{ID: "next", Token: "env://ZEN_TEST_NEXT"}}},
+			"b": {AuthToken: "sk-literal"},
+		},
+		Bot: &BotConfig{Platforms: &BotPlatformsConfig{Telegram: &BotTelegramConfig{Token: "keychain://gozen/telegram"}}},
+	}
+	refs := SecretRefs(cfg)
+	want := []string{"bot.platforms.telegram.token", "providers.a.auth_token", "providers.a.keys.next"}
+	if len(refs) != len(want) {
+		t.Fatalf("SecretRefs() = %+v, want fields %v", refs, want)
+	}
+	for i, ref := range refs {
+		if ref.Field != want[i] {
+			t.Errorf("refs[%d].Field = %s, want %s", i, ref.Field, want[i])
+		}
+	}
+
+	resolved, err := cfg.Providers["a"].ResolveTokens()
+	if err == nil {
+		t.Error("ResolveTokens() with an unset variable should fail")
+	}
+	if resolved.AuthToken != "sk-primary" || resolved.Keys[0].Token != "" {
+		t.Errorf("resolved = %q, %q", resolved.AuthToken, resolved.Keys[0].Token)
+	}
+	if cfg.Providers["a"].AuthToken != "env://ZEN_TEST_PRIMARY" {
+		t.Error("ResolveTokens() changed the stored reference")
+	}
+}
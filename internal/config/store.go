@@ -1,12 +1,21 @@
 package config
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"log"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -14,15 +23,65 @@ import (
 
 // --- Path helpers ---
 
+// HomeEnv names the environment variable that points zen at another home
+// directory instead of ~/.zen, to run an isolated instance with its own
+// config, ports, PID file, sockets and databases.
+const HomeEnv = "ZEN_HOME"
+
 // ConfigDirPath returns the config directory path.
-// Uses GOZEN_CONFIG_DIR environment variable if set, otherwise ~/.zen
+// Uses ZEN_HOME or GOZEN_CONFIG_DIR environment variable if set, otherwise ~/.zen
 func ConfigDirPath() string {
+	if dir := os.Getenv(HomeEnv); dir != "" {
+		if abs, err := filepath.Abs(dir); err == nil {
+			return abs
+		}
+		return dir
+	}
 	if dir := os.Getenv("GOZEN_CONFIG_DIR"); dir != "" {
 		return dir
 	}
 	return filepath.Join(os.Getenv("HOME"), ConfigDir)
 }
 
+// InstanceID identifies the instance whose home ZEN_HOME selects, as 8 hex
+// digits derived from its path. It is empty for the default instance, in
+// ~/.zen, which keeps the default ports, socket and service names.
+func InstanceID() string {
+	if os.Getenv(HomeEnv) == "" {
+		return ""
+	}
+	dir := ConfigDirPath()
+	if dir == filepath.Join(os.Getenv("HOME"), ConfigDir) {
+		return ""
+	}
+	h := fnv.New32a()
+	h.Write([]byte(dir))
+	return fmt.Sprintf("%08x", h.Sum32())
+}
+
+// InstancePorts returns the web and proxy ports used when zen.json sets
+// none: 19840 and 19841 for the default instance, and a pair above them
+// picked from the instance ID otherwise, so instances don't collide.
+func InstancePorts() (web, proxy int) {
+	id := InstanceID()
+	if id == "" {
+		return DefaultWebPort, DefaultProxyPort
+	}
+	n, _ := strconv.ParseUint(id, 16, 32)
+	offset := 2 * (1 + int(n%500))
+	return DefaultWebPort + offset, DefaultProxyPort + offset
+}
+
+// BotSocketPath returns the default path of the bot gateway socket, which
+// carries the instance ID for instances other than the default one.
+func BotSocketPath() string {
+	name := "zen-gateway.sock"
+	if id := InstanceID(); id != "" {
+		name = "zen-gateway-" + id + ".sock"
+	}
+	return filepath.Join(os.TempDir(), name)
+}
+
 // ConfigFilePath returns ~/.zen/zen.json
 func ConfigFilePath() string {
 	return filepath.Join(ConfigDirPath(), ConfigFile)
@@ -52,11 +111,18 @@ func legacyOpenCCFilePath() string {
 
 // Store manages reading and writing the unified JSON config.
 type Store struct {
-	mu       sync.Mutex
-	path     string
-	config   *OpenCCConfig
-	modTime  time.Time // last known modification time of config file
-	onSave   func()    // called after saveLocked() succeeds
+	mu      sync.Mutex
+	path    string
+	config  *OpenCCConfig
+	modTime time.Time         // last known modification time of config file
+	onSave  func()            // called after saveLocked() succeeds
+	sealed  map[string]string // plaintext -> ciphertext of encrypted fields, see marshalConfig
+
+	overrides []*ConfigOverride // ZEN_ environment overrides applied at load
+
+	team        *TeamLayer             // team layer zen.json is laid over, see team.go
+	teamLocal   map[string]interface{} // team sections of zen.json as loaded
+	teamModTime time.Time
 }
 
 var (
@@ -97,6 +163,21 @@ func ResetDefaultStore() {
 	defaultStore = nil
 }
 
+// ReloadDefaultStore loads the config file into a new Store and swaps it in
+// for the global Store. If the file fails to load or validate, the current
+// Store is kept, so a half-written or invalid file never replaces a working
+// config.
+func ReloadDefaultStore() error {
+	s := &Store{path: ConfigFilePath()}
+	if err := s.Load(); err != nil {
+		return err
+	}
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultStore = s
+	return nil
+}
+
 // --- Provider operations ---
 
 // GetProvider returns a copy of the config for a named provider, or nil.
@@ -356,13 +437,14 @@ func (s *Store) SetDefaultClient(client string) error {
 }
 
 // GetWebPort returns the configured web UI port.
-// Returns DefaultWebPort if not set.
+// Returns the instance's default web port if not set.
 func (s *Store) GetWebPort() int {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.reloadIfModified()
 	if s.config == nil || s.config.WebPort == 0 {
-		return DefaultWebPort
+		web, _ := InstancePorts()
+		return web
 	}
 	return s.config.WebPort
 }
@@ -378,13 +460,14 @@ func (s *Store) SetWebPort(port int) error {
 }
 
 // GetProxyPort returns the configured proxy port.
-// Returns DefaultProxyPort if not set.
+// Returns the instance's default proxy port if not set.
 func (s *Store) GetProxyPort() int {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.reloadIfModified()
 	if s.config == nil || s.config.ProxyPort == 0 {
-		return DefaultProxyPort
+		_, proxy := InstancePorts()
+		return proxy
 	}
 	return s.config.ProxyPort
 }
@@ -399,7 +482,7 @@ func (s *Store) SetProxyPort(port int) error {
 	return s.saveLocked()
 }
 
-// EnsureProxyPort persists the default proxy port if it is currently unset (0).
+// EnsureProxyPort persists the instance's default proxy port if it is currently unset (0).
 // This ensures the port value is always written to disk so it survives restarts.
 func (s *Store) EnsureProxyPort() error {
 	s.mu.Lock()
@@ -407,7 +490,7 @@ func (s *Store) EnsureProxyPort() error {
 	s.reloadIfModified()
 	s.ensureConfig()
 	if s.config.ProxyPort == 0 {
-		s.config.ProxyPort = DefaultProxyPort
+		_, s.config.ProxyPort = InstancePorts()
 		return s.saveLocked()
 	}
 	return nil
@@ -434,6 +517,29 @@ func (s *Store) SetWebPasswordHash(hash string) error {
 	return s.saveLocked()
 }
 
+// GetWebViewerPasswordHash returns the stored bcrypt hash of the read-only
+// Web UI password.
+func (s *Store) GetWebViewerPasswordHash() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return ""
+	}
+	return s.config.WebViewerPasswordHash
+}
+
+// SetWebViewerPasswordHash sets the read-only password hash and saves. An
+// empty hash turns viewer logins off.
+func (s *Store) SetWebViewerPasswordHash(hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	s.ensureConfig()
+	s.config.WebViewerPasswordHash = hash
+	return s.saveLocked()
+}
+
 // --- I/O ---
 
 // reloadIfModified checks if the config file has been modified since last load
@@ -443,8 +549,12 @@ func (s *Store) reloadIfModified() {
 		if info.ModTime().After(s.modTime) {
 			// File has been modified, reload (ignore errors to avoid breaking operations)
 			s.loadLocked()
+			return
 		}
 	}
+	if s.teamModified() {
+		s.loadLocked()
+	}
 }
 
 // ValidateConfig performs comprehensive validation of the entire configuration.
@@ -471,6 +581,22 @@ func ValidateConfig(cfg *OpenCCConfig) (errors []error, warnings []string) {
 		if provider.AuthToken == "" {
 			warnings = append(warnings, fmt.Sprintf("provider %q: auth_token is empty", name))
 		}
+		if err := provider.CostModel.Validate(); err != nil {
+			errors = append(errors, fmt.Errorf("provider %q: cost_model: %w", name, err))
+		}
+		if err := provider.Transforms.Validate(); err != nil {
+			errors = append(errors, fmt.Errorf("provider %q: transforms: %w", name, err))
+		}
+		if err := provider.ValidateKeys(); err != nil {
+			errors = append(errors, fmt.Errorf("provider %q: %w", name, err))
+		}
+	}
+
+	// Validate secret references
+	for _, ref := range SecretRefs(cfg) {
+		if err := ValidateSecretRef(ref.Ref); err != nil {
+			errors = append(errors, fmt.Errorf("%s: %w", ref.Field, err))
+		}
 	}
 
 	// Validate profiles
@@ -497,6 +623,16 @@ func ValidateConfig(cfg *OpenCCConfig) (errors []error, warnings []string) {
 				errors = append(errors, err)
 			}
 		}
+
+		// Validate shadow traffic mirror
+		if err := profile.Mirror.Validate(cfg.Providers); err != nil {
+			errors = append(errors, fmt.Errorf("profile %q: %w", profileName, err))
+		}
+
+		// Validate request transform
+		if err := profile.RequestTransform.Validate(); err != nil {
+			errors = append(errors, fmt.Errorf("profile %q: request_transform: %w", profileName, err))
+		}
 	}
 
 	// Validate default profile exists
@@ -522,6 +658,273 @@ func ValidateConfig(cfg *OpenCCConfig) (errors []error, warnings []string) {
 		if binding.Client != "" && !IsValidClient(binding.Client) {
 			errors = append(errors, fmt.Errorf("project binding %q has invalid client %q", path, binding.Client))
 		}
+		if IsPatternBinding(path) {
+			if err := ValidateBindingKey(path); err != nil {
+				errors = append(errors, err)
+			}
+		}
+		if o := binding.Compression; o != nil {
+			if o.ThresholdTokens < 0 || o.TargetTokens < 0 || o.PreserveRecent < 0 {
+				errors = append(errors, fmt.Errorf("project binding %q: compression token counts must not be negative", path))
+			}
+			if o.Strategy != "" && o.Strategy != "summarize" && o.Strategy != "retrieval" {
+				errors = append(errors, fmt.Errorf("project binding %q has invalid compression strategy %q", path, o.Strategy))
+			}
+		}
+		if err := binding.Sandbox.Validate(); err != nil {
+			errors = append(errors, fmt.Errorf("project binding %q: %w", path, err))
+		}
+		for name, server := range binding.MCPServers {
+			if err := ValidateMCPServer(name, server); err != nil {
+				errors = append(errors, fmt.Errorf("project binding %q: %w", path, err))
+			}
+		}
+	}
+
+	// Validate budgets
+	if b := cfg.Budgets; b != nil {
+		switch b.Enforcement {
+		case "", BudgetEnforcementAdmission, BudgetEnforcementStreaming:
+		default:
+			errors = append(errors, fmt.Errorf("budgets: invalid enforcement %q", b.Enforcement))
+		}
+		for name := range b.Providers {
+			if _, exists := cfg.Providers[name]; !exists {
+				warnings = append(warnings, fmt.Sprintf("budgets reference non-existent provider %q", name))
+			}
+		}
+		for key := range b.Models {
+			if strings.TrimSpace(key) == "" {
+				errors = append(errors, fmt.Errorf("budgets: empty model key"))
+			}
+		}
+		for i, d := range b.Downgrade {
+			if d == nil || strings.TrimSpace(d.Match) == "" || strings.TrimSpace(d.Target) == "" {
+				errors = append(errors, fmt.Errorf("budgets: downgrade rule %d needs a match and a target", i))
+			}
+		}
+	}
+
+	// Validate ingress keys
+	if ic := cfg.Ingress; ic != nil {
+		ids := make(map[string]bool)
+		keys := make(map[string]bool)
+		for i, k := range ic.Keys {
+			if k == nil || k.Key == "" || strings.TrimSpace(k.User) == "" {
+				errors = append(errors, fmt.Errorf("ingress: key %d needs a key and a user", i))
+				continue
+			}
+			if ids[k.ID] || keys[k.Key] {
+				errors = append(errors, fmt.Errorf("ingress: duplicate key %q", k.ID))
+			}
+			ids[k.ID], keys[k.Key] = true, true
+		}
+		if ic.Required && len(ic.Keys) == 0 {
+			warnings = append(warnings, "ingress keys are required but none are configured; all requests will be rejected")
+		}
+	}
+
+	// Validate pricing overrides
+	for model, p := range cfg.Pricing {
+		if p == nil {
+			errors = append(errors, fmt.Errorf("pricing: missing price for %q", model))
+		} else if err := p.Validate(); err != nil {
+			errors = append(errors, fmt.Errorf("pricing: %q: %w", model, err))
+		}
+	}
+
+	// Validate config sync
+	if sc := cfg.Sync; sc != nil {
+		if sc.PullInterval < 0 {
+			errors = append(errors, fmt.Errorf("sync: pull_interval must not be negative"))
+		}
+		if sc.PushDebounce < 0 {
+			errors = append(errors, fmt.Errorf("sync: push_debounce must not be negative"))
+		}
+	}
+
+	// Validate team sync
+	if tc := cfg.Team; tc != nil && tc.PullInterval < 0 {
+		errors = append(errors, fmt.Errorf("team: pull_interval must not be negative"))
+	}
+
+	// Validate pricing sync
+	if ps := cfg.PricingSync; ps != nil {
+		if ps.Enabled || ps.URL != "" {
+			if u, err := url.Parse(ps.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				errors = append(errors, fmt.Errorf("pricing_sync: url must be an http(s) URL"))
+			}
+			if key, err := base64.StdEncoding.DecodeString(ps.PublicKey); err != nil || len(key) != ed25519.PublicKeySize {
+				errors = append(errors, fmt.Errorf("pricing_sync: public_key must be a base64 ed25519 public key"))
+			}
+		}
+		if ps.IntervalHours < 0 {
+			errors = append(errors, fmt.Errorf("pricing_sync: interval_hours must not be negative"))
+		}
+		for model, p := range ps.Prices {
+			if p == nil || p.Validate() != nil {
+				errors = append(errors, fmt.Errorf("pricing_sync: invalid synced price for %q", model))
+			}
+		}
+	}
+
+	// Validate currency
+	if c := cfg.Currency; c != nil {
+		if code := c.GetCode(); len(code) != 3 {
+			errors = append(errors, fmt.Errorf("currency: code must be a 3-letter ISO 4217 code"))
+		}
+		if c.Rate < 0 {
+			errors = append(errors, fmt.Errorf("currency: rate must not be negative"))
+		}
+		if c.RateURL != "" {
+			if u, err := url.Parse(c.RateURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				errors = append(errors, fmt.Errorf("currency: rate_url must be an http(s) URL"))
+			}
+		}
+		if c.GetCode() != "USD" && c.Rate == 0 && c.RateURL == "" {
+			warnings = append(warnings, fmt.Sprintf("currency %s has no rate or rate_url; costs are shown unconverted", c.GetCode()))
+		}
+	}
+
+	// Validate usage reports
+	if ur := cfg.UsageReports; ur != nil && (ur.HourUTC < 0 || ur.HourUTC > 23) {
+		errors = append(errors, fmt.Errorf("usage_reports: hour_utc must be between 0 and 23"))
+	}
+
+	// Validate usage retention
+	if rc := cfg.UsageRetention; rc != nil {
+		if rc.RawDays < 0 || rc.HourlyDays < 0 || rc.IntervalHours < 0 {
+			errors = append(errors, fmt.Errorf("usage_retention: raw_days, hourly_days and interval_hours must not be negative"))
+		} else if rc.GetHourlyDays() < rc.GetRawDays() {
+			warnings = append(warnings, "usage_retention: hourly_days is shorter than raw_days; charts lose hours whose requests are still kept")
+		}
+	}
+
+	// Validate alerts
+	if a := cfg.Alerts; a != nil && (a.FailureBurstCount < 0 || a.FailureBurstMinutes < 0 || a.CertExpiryDays < 0) {
+		errors = append(errors, fmt.Errorf("alerts: failure_burst_count, failure_burst_minutes and cert_expiry_days must not be negative"))
+	}
+	if a := cfg.Alerts; a != nil && (a.ContextWarnPercent < 0 || a.ContextWarnPercent > 100) {
+		errors = append(errors, fmt.Errorf("alerts: context_warn_percent must be between 0 and 100"))
+	}
+
+	// Validate agent observatory
+	if a := cfg.Agent; a != nil && a.Observatory != nil {
+		o := a.Observatory
+		if o.StuckThreshold < 0 || o.IdleTimeoutMin < 0 || o.LoopThreshold < 0 || o.NoProgressMin < 0 || o.NoProgressTokens < 0 {
+			errors = append(errors, fmt.Errorf("agent.observatory: thresholds must not be negative"))
+		}
+	}
+
+	// Validate agent runtime roles
+	if a := cfg.Agent; a != nil && a.Runtime != nil {
+		if a.Runtime.MaxParallel < 0 {
+			errors = append(errors, fmt.Errorf("agent.runtime: max_parallel cannot be negative"))
+		}
+		for role, rc := range a.Runtime.Roles {
+			switch role {
+			case "planner", "implementer", "reviewer":
+			default:
+				errors = append(errors, fmt.Errorf("agent.runtime.roles: unknown role %q (want planner, implementer or reviewer)", role))
+				continue
+			}
+			if rc == nil || rc.Profile == "" {
+				continue
+			}
+			if _, exists := cfg.Profiles[rc.Profile]; !exists {
+				errors = append(errors, fmt.Errorf("agent.runtime.roles.%s: profile %q does not exist", role, rc.Profile))
+			}
+		}
+	}
+
+	// Validate agent guardrail policy
+	if a := cfg.Agent; a != nil && a.Guardrails != nil {
+		// A held response must finish within the proxy's 10 minute write timeout
+		if t := a.Guardrails.ApprovalTimeoutSec; t < 0 || t > 540 {
+			errors = append(errors, fmt.Errorf("agent.guardrails: approval_timeout_sec must be between 0 and 540"))
+		}
+		if m := a.Guardrails.SpikeMultiple; m < 0 || (m > 0 && m <= 1) {
+			errors = append(errors, fmt.Errorf("agent.guardrails: spike_multiple must be 0 (off) or greater than 1"))
+		}
+		if a.Guardrails.SpikeWindowMin < 0 {
+			errors = append(errors, fmt.Errorf("agent.guardrails: spike_window_min cannot be negative"))
+		}
+		names := make(map[string]bool)
+		for i, rule := range a.Guardrails.Policy {
+			if rule == nil {
+				continue
+			}
+			if rule.Name == "" {
+				errors = append(errors, fmt.Errorf("agent.guardrails.policy[%d]: name is required", i))
+			} else if names[rule.Name] {
+				errors = append(errors, fmt.Errorf("agent.guardrails.policy: duplicate rule name %q", rule.Name))
+			}
+			names[rule.Name] = true
+			switch rule.Action {
+			case GuardrailBlock, GuardrailAsk, GuardrailLog:
+			default:
+				errors = append(errors, fmt.Errorf("agent.guardrails.policy %q: action must be %q, %q or %q", rule.Name, GuardrailBlock, GuardrailAsk, GuardrailLog))
+			}
+			for _, pattern := range rule.DenyCommands {
+				if _, err := regexp.Compile(pattern); err != nil {
+					errors = append(errors, fmt.Errorf("agent.guardrails.policy %q: invalid command pattern %q: %v", rule.Name, pattern, err))
+				}
+			}
+			for _, pattern := range append(append([]string{}, rule.AllowPaths...), rule.DenyPaths...) {
+				if _, err := path.Match(strings.ReplaceAll(pattern, "**", "*"), ""); err != nil {
+					errors = append(errors, fmt.Errorf("agent.guardrails.policy %q: invalid path pattern %q", rule.Name, pattern))
+				}
+			}
+		}
+	}
+
+	// Validate agent coordinator
+	if a := cfg.Agent; a != nil && a.Coordinator != nil {
+		c := a.Coordinator
+		if c.LockTimeoutSec < 0 || c.LockWaitSec < 0 {
+			errors = append(errors, fmt.Errorf("agent.coordinator: lock_timeout_sec and lock_wait_sec must not be negative"))
+		}
+		if p := c.DeadlockPolicy; p != "" && p != DeadlockAbortYoungest && p != DeadlockNotify {
+			errors = append(errors, fmt.Errorf("agent.coordinator: deadlock_policy must be %q or %q", DeadlockAbortYoungest, DeadlockNotify))
+		}
+	}
+
+	// Validate context windows
+	for model, window := range cfg.ContextWindows {
+		if model == "" || window <= 0 {
+			errors = append(errors, fmt.Errorf("context_windows: %q must map a model to a positive token count", model))
+		}
+	}
+
+	// Validate health probes
+	if hc := cfg.HealthCheck; hc != nil && hc.Probe != nil {
+		probe := hc.Probe
+		if probe.IntervalSecs < 0 || probe.MaxTokens < 0 {
+			errors = append(errors, fmt.Errorf("health_check.probe: interval_secs and max_tokens must not be negative"))
+		}
+		for _, name := range probe.Providers {
+			if _, ok := cfg.Providers[name]; !ok {
+				errors = append(errors, fmt.Errorf("health_check.probe: provider %q does not exist", name))
+			}
+		}
+		for name := range probe.Models {
+			if _, ok := cfg.Providers[name]; !ok {
+				warnings = append(warnings, fmt.Sprintf("health_check.probe: models lists unknown provider %q", name))
+			}
+		}
+		if probe.Enabled && !hc.Enabled {
+			warnings = append(warnings, "health_check.probe is enabled but health_check is not; probes run only while health checks are enabled")
+		}
+	}
+
+	// Validate webhooks
+	for _, wh := range cfg.Webhooks {
+		if wh == nil {
+			continue
+		}
+		if err := wh.Validate(); err != nil {
+			errors = append(errors, fmt.Errorf("webhook %q: %w", wh.Name, err))
+		}
 	}
 
 	return errors, warnings
@@ -612,6 +1015,16 @@ func ValidateRoutingConfig(cfg *OpenCCConfig, profileName string) error {
 		if policy.LongContextThreshold != nil && *policy.LongContextThreshold < 0 {
 			return fmt.Errorf("profile %q: scenario %q has negative long_context_threshold %d", profileName, scenarioKey, *policy.LongContextThreshold)
 		}
+
+		// Validate custom scenario match rules (builtin scenarios use builtin detection)
+		if policy.Match != nil {
+			if IsBuiltinScenario(scenarioKey) {
+				return fmt.Errorf("profile %q: builtin scenario %q cannot have match rules", profileName, scenarioKey)
+			}
+			if err := policy.Match.Validate(); err != nil {
+				return fmt.Errorf("profile %q: scenario %q: %w", profileName, scenarioKey, err)
+			}
+		}
 	}
 
 	// Validate scenario_priority if specified (FR-005)
@@ -697,6 +1110,20 @@ func (s *Store) loadLocked() error {
 			}
 		}
 
+		// Decrypt encrypted tokens and credentials
+		sealed, err := openConfig(&cfg)
+		if err != nil {
+			log.Printf("Warning: %v", err)
+		}
+
+		// Lay the file over the team layer, and ZEN_ environment overrides
+		// over both
+		merged, overrides, overrideErrs := loadOverrides(s.loadTeam(&cfg))
+		for _, err := range overrideErrs {
+			log.Printf("Warning: environment override %v", err)
+		}
+		cfg = *merged
+
 		if cfg.Providers == nil {
 			cfg.Providers = make(map[string]*ProviderConfig)
 		}
@@ -718,6 +1145,8 @@ func (s *Store) loadLocked() error {
 		}
 
 		s.config = &cfg
+		s.sealed = sealed
+		s.overrides = overrides
 		// Update modification time
 		if info, statErr := os.Stat(s.path); statErr == nil {
 			s.modTime = info.ModTime()
@@ -765,6 +1194,14 @@ func (s *Store) loadLocked() error {
 		Providers: make(map[string]*ProviderConfig),
 		Profiles:  make(map[string]*ProfileConfig),
 	}
+	// Environment overrides or a team layer alone can configure zen, e.g.
+	// in a container or on a new team member's machine
+	merged, overrides, overrideErrs := loadOverrides(s.loadTeam(s.config))
+	for _, err := range overrideErrs {
+		log.Printf("Warning: environment override %v", err)
+	}
+	s.config, s.overrides = merged, overrides
+	s.ensureConfig()
 	s.modTime = time.Time{} // zero time for non-existent file
 	return nil
 }
@@ -806,7 +1243,24 @@ func (s *Store) saveLocked() error {
 		return fmt.Errorf("failed to create config dir: %w", err)
 	}
 
-	data, err := json.MarshalIndent(s.config, "", "  ")
+	if s.sealed == nil {
+		s.sealed = make(map[string]string)
+	}
+	// Environment overrides and the team layer are never written to the file
+	file := s.config
+	if len(s.overrides) > 0 {
+		var err error
+		if file, err = withoutOverrides(file, s.overrides); err != nil {
+			return fmt.Errorf("failed to marshal config: %w", err)
+		}
+	}
+	if s.team != nil {
+		var err error
+		if file, err = withoutTeam(file, s.team, s.teamLocal); err != nil {
+			return fmt.Errorf("failed to marshal config: %w", err)
+		}
+	}
+	data, err := marshalConfig(file, s.sealed)
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
@@ -833,10 +1287,13 @@ func (s *Store) saveLocked() error {
 		return fmt.Errorf("failed to close temp file: %w", err)
 	}
 
+	previous, _ := os.ReadFile(s.path)
 	if err := os.Rename(tmpName, s.path); err != nil {
 		os.Remove(tmpName)
 		return fmt.Errorf("failed to rename config file: %w", err)
 	}
+	s.snapshotLocked(previous, data)
+	notifySaveListeners(previous, data)
 	// Update modification time after successful save
 	if info, statErr := os.Stat(s.path); statErr == nil {
 		s.modTime = info.ModTime()
@@ -889,6 +1346,29 @@ func (s *Store) SetOnSave(fn func()) {
 	s.onSave = fn
 }
 
+var (
+	saveListenersMu sync.Mutex
+	saveListeners   []func(previous, data []byte)
+)
+
+// AddSaveListener registers fn to be called after every save of the config
+// file with its previous and new contents. fn runs with the store locked, so
+// it must be quick and must not use the store.
+func AddSaveListener(fn func(previous, data []byte)) {
+	saveListenersMu.Lock()
+	defer saveListenersMu.Unlock()
+	saveListeners = append(saveListeners, fn)
+}
+
+func notifySaveListeners(previous, data []byte) {
+	saveListenersMu.Lock()
+	listeners := saveListeners
+	saveListenersMu.Unlock()
+	for _, fn := range listeners {
+		fn(previous, data)
+	}
+}
+
 // --- Sync Config ---
 
 // GetSyncConfig returns the sync configuration, or nil if not configured.
@@ -945,10 +1425,14 @@ func resolveProjectPath(path string) string {
 	return filepath.Clean(path)
 }
 
-// BindProject binds a directory path to a profile and/or CLI.
+// BindProject binds a directory path, path pattern, git remote or repo name
+// (see BindingRemotePrefix) to a profile and/or CLI.
 // Either profile or cli can be empty to use the default.
 func (s *Store) BindProject(path string, profile string, cli string) error {
-	path = resolveProjectPath(path)
+	if err := ValidateBindingKey(path); err != nil {
+		return err
+	}
+	path = normalizeBindingKey(path)
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.reloadIfModified()
@@ -966,16 +1450,102 @@ func (s *Store) BindProject(path string, profile string, cli string) error {
 		return fmt.Errorf("invalid client '%s' (must be %v)", cli, AvailableClients)
 	}
 
-	s.config.ProjectBindings[path] = &ProjectBinding{
+	binding := &ProjectBinding{
 		Profile: profile,
 		Client:  cli,
 	}
+	if existing := s.config.ProjectBindings[path]; existing != nil {
+		binding.Compression = existing.Compression
+		binding.Sandbox = existing.Sandbox
+		binding.ClaudeSettings = existing.ClaudeSettings
+		binding.MCPServers = existing.MCPServers
+	}
+	s.config.ProjectBindings[path] = binding
+	return s.saveLocked()
+}
+
+// SetProjectCompression sets the compression override of a bound project.
+// A nil override removes it.
+func (s *Store) SetProjectCompression(path string, o *CompressionOverride) error {
+	path = normalizeBindingKey(path)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	s.ensureConfig()
+
+	binding := s.config.ProjectBindings[path]
+	if binding == nil {
+		return fmt.Errorf("project '%s' is not bound", path)
+	}
+	binding.Compression = o.Clone()
+	return s.saveLocked()
+}
+
+// SetProjectSandbox sets the sandbox of a bound project. A nil sandbox
+// removes it, so the agent runtime runs the project's tool calls on the host.
+func (s *Store) SetProjectSandbox(path string, sb *SandboxConfig) error {
+	if err := sb.Validate(); err != nil {
+		return err
+	}
+	path = normalizeBindingKey(path)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	s.ensureConfig()
+
+	binding := s.config.ProjectBindings[path]
+	if binding == nil {
+		return fmt.Errorf("project '%s' is not bound", path)
+	}
+	binding.Sandbox = sb.Clone()
+	return s.saveLocked()
+}
+
+// SetProjectClaudeSettings sets the managed Claude Code settings of a bound
+// project. Nil settings remove them.
+func (s *Store) SetProjectClaudeSettings(path string, cs *ClaudeSettingsConfig) error {
+	path = normalizeBindingKey(path)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	s.ensureConfig()
+
+	binding := s.config.ProjectBindings[path]
+	if binding == nil {
+		return fmt.Errorf("project '%s' is not bound", path)
+	}
+	binding.ClaudeSettings = cs.Clone()
+	return s.saveLocked()
+}
+
+// SetProjectMCPServers sets the MCP servers of a bound project. Nil or
+// empty servers remove them.
+func (s *Store) SetProjectMCPServers(path string, servers map[string]*MCPServerConfig) error {
+	for name, c := range servers {
+		if err := ValidateMCPServer(name, c); err != nil {
+			return err
+		}
+	}
+	path = normalizeBindingKey(path)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	s.ensureConfig()
+
+	binding := s.config.ProjectBindings[path]
+	if binding == nil {
+		return fmt.Errorf("project '%s' is not bound", path)
+	}
+	binding.MCPServers = nil
+	if len(servers) > 0 {
+		binding.MCPServers = CloneMCPServers(servers)
+	}
 	return s.saveLocked()
 }
 
 // UnbindProject removes the binding for a directory path.
 func (s *Store) UnbindProject(path string) error {
-	path = resolveProjectPath(path)
+	path = normalizeBindingKey(path)
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.reloadIfModified()
@@ -985,25 +1555,18 @@ func (s *Store) UnbindProject(path string) error {
 	return s.saveLocked()
 }
 
-// GetProjectBinding returns a copy of the binding for a directory path.
-// Returns nil if no binding exists.
+// GetProjectBinding returns a copy of the binding stored under a directory
+// path, git remote or repo name. Returns nil if no binding exists. Use
+// FindProjectBinding to find the binding that applies to a directory.
 func (s *Store) GetProjectBinding(path string) *ProjectBinding {
-	path = resolveProjectPath(path)
+	path = normalizeBindingKey(path)
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.reloadIfModified()
 	if s.config == nil || s.config.ProjectBindings == nil {
 		return nil
 	}
-	pb := s.config.ProjectBindings[path]
-	if pb == nil {
-		return nil
-	}
-	// Return a copy
-	return &ProjectBinding{
-		Profile: pb.Profile,
-		Client:  pb.Client,
-	}
+	return s.config.ProjectBindings[path].Clone()
 }
 
 // GetAllProjectBindings returns a deep copy of all project bindings.
@@ -1018,10 +1581,7 @@ func (s *Store) GetAllProjectBindings() map[string]*ProjectBinding {
 	bindings := make(map[string]*ProjectBinding, len(s.config.ProjectBindings))
 	for k, v := range s.config.ProjectBindings {
 		if v != nil {
-			bindings[k] = &ProjectBinding{
-				Profile: v.Profile,
-				Client:  v.Client,
-			}
+			bindings[k] = v.Clone()
 		}
 	}
 	return bindings
@@ -1035,11 +1595,16 @@ func (s *Store) GetPricing() map[string]*ModelPricing {
 	defer s.mu.Unlock()
 	s.reloadIfModified()
 
-	// Start with defaults
+	// Start with defaults, updated by the last pricing sync
 	result := make(map[string]*ModelPricing, len(DefaultModelPricing))
 	for k, v := range DefaultModelPricing {
 		result[k] = v
 	}
+	if s.config != nil && s.config.PricingSync != nil {
+		for k, v := range s.config.PricingSync.Prices {
+			result[k] = v
+		}
+	}
 
 	// Override with custom pricing
 	if s.config != nil && s.config.Pricing != nil {
@@ -1060,8 +1625,91 @@ func (s *Store) SetPricing(pricing map[string]*ModelPricing) error {
 	return s.saveLocked()
 }
 
+// GetPricingOverrides returns the custom model pricing overrides.
+func (s *Store) GetPricingOverrides() map[string]*ModelPricing {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return nil
+	}
+	return s.config.Pricing
+}
+
+// GetPricingSync returns the pricing sync configuration.
+func (s *Store) GetPricingSync() *PricingSyncConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return nil
+	}
+	return s.config.PricingSync
+}
+
+// SetPricingSync sets the pricing sync configuration and saves.
+func (s *Store) SetPricingSync(ps *PricingSyncConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	s.ensureConfig()
+	s.config.PricingSync = ps
+	return s.saveLocked()
+}
+
+// ApplySyncedPricing records the prices of a synced pricing manifest and saves.
+func (s *Store) ApplySyncedPricing(version string, prices map[string]*ModelPricing) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	s.ensureConfig()
+	if s.config.PricingSync == nil {
+		s.config.PricingSync = &PricingSyncConfig{}
+	}
+	s.config.PricingSync.Version = version
+	s.config.PricingSync.SyncedAt = time.Now().UTC()
+	s.config.PricingSync.Prices = prices
+	return s.saveLocked()
+}
+
 // --- Budgets ---
 
+// GetCurrency returns the display currency configuration.
+func (s *Store) GetCurrency() *CurrencyConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return nil
+	}
+	return s.config.Currency
+}
+
+// SetCurrency sets the display currency configuration and saves.
+func (s *Store) SetCurrency(c *CurrencyConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	s.ensureConfig()
+	s.config.Currency = c
+	return s.saveLocked()
+}
+
+// SetFetchedCurrencyRate records a rate fetched from the currency rate_url
+// and saves.
+func (s *Store) SetFetchedCurrencyRate(rate float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	s.ensureConfig()
+	if s.config.Currency == nil {
+		return nil
+	}
+	s.config.Currency.FetchedRate = rate
+	s.config.Currency.FetchedAt = time.Now().UTC()
+	return s.saveLocked()
+}
+
 // GetBudgets returns the budget configuration.
 func (s *Store) GetBudgets() *BudgetConfig {
 	s.mu.Lock()
@@ -1083,6 +1731,98 @@ func (s *Store) SetBudgets(budgets *BudgetConfig) error {
 	return s.saveLocked()
 }
 
+// --- Usage Reports ---
+
+// GetUsageReports returns the scheduled usage report configuration.
+func (s *Store) GetUsageReports() *UsageReportConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return nil
+	}
+	return s.config.UsageReports
+}
+
+// SetUsageReports sets the scheduled usage report configuration and saves.
+func (s *Store) SetUsageReports(reports *UsageReportConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	s.ensureConfig()
+	s.config.UsageReports = reports
+	return s.saveLocked()
+}
+
+// --- Alerts ---
+
+// GetAlerts returns the alert threshold configuration.
+func (s *Store) GetAlerts() *AlertConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return nil
+	}
+	return s.config.Alerts
+}
+
+// SetAlerts sets the alert threshold configuration and saves.
+func (s *Store) SetAlerts(alerts *AlertConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	s.ensureConfig()
+	s.config.Alerts = alerts
+	return s.saveLocked()
+}
+
+// --- Context Windows ---
+
+// GetContextWindows returns the model context window overrides.
+func (s *Store) GetContextWindows() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return nil
+	}
+	return s.config.ContextWindows
+}
+
+// SetContextWindows sets the model context window overrides and saves.
+func (s *Store) SetContextWindows(windows map[string]int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	s.ensureConfig()
+	s.config.ContextWindows = windows
+	return s.saveLocked()
+}
+
+// --- Usage Retention ---
+
+// GetUsageRetention returns the usage database retention configuration.
+func (s *Store) GetUsageRetention() *UsageRetentionConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return nil
+	}
+	return s.config.UsageRetention
+}
+
+// SetUsageRetention sets the usage database retention configuration and saves.
+func (s *Store) SetUsageRetention(rc *UsageRetentionConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	s.ensureConfig()
+	s.config.UsageRetention = rc
+	return s.saveLocked()
+}
+
 // --- Webhooks ---
 
 // GetWebhooks returns all webhook configurations.
@@ -1182,6 +1922,245 @@ func (s *Store) SetHealthCheck(hc *HealthCheckConfig) error {
 	return s.saveLocked()
 }
 
+// --- Body Capture ---
+
+// GetBodyCapture returns the body capture configuration.
+func (s *Store) GetBodyCapture() *BodyCaptureConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return nil
+	}
+	return s.config.BodyCapture
+}
+
+// --- Model Aliases ---
+
+// GetModelAliases returns the model rewrite rules in evaluation order.
+func (s *Store) GetModelAliases() []*ModelAlias {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return nil
+	}
+	return s.config.ModelAliases
+}
+
+// SetModelAliases replaces the model rewrite rules and saves.
+func (s *Store) SetModelAliases(aliases []*ModelAlias) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	s.ensureConfig()
+	s.config.ModelAliases = aliases
+	return s.saveLocked()
+}
+
+// --- Response Cache ---
+
+// GetResponseCache returns the response cache configuration.
+func (s *Store) GetResponseCache() *ResponseCacheConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return nil
+	}
+	return s.config.ResponseCache
+}
+
+// --- Tracing ---
+
+// GetTracing returns the OpenTelemetry tracing configuration.
+func (s *Store) GetTracing() *TracingConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return nil
+	}
+	return s.config.Tracing
+}
+
+// --- TLS ---
+
+// GetTLS returns the TLS configuration of the Web UI and proxy listeners.
+func (s *Store) GetTLS() *TLSConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return nil
+	}
+	return s.config.TLS
+}
+
+// --- Daemon Log ---
+
+// GetLog returns the daemon log configuration.
+func (s *Store) GetLog() *LogConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return nil
+	}
+	return s.config.Log
+}
+
+// GetAccessLog returns the proxy access log configuration.
+func (s *Store) GetAccessLog() *AccessLogConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return nil
+	}
+	return s.config.AccessLog
+}
+
+// GetIdle returns the idle sleep configuration.
+func (s *Store) GetIdle() *IdleConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return nil
+	}
+	return s.config.Idle
+}
+
+// --- Trusted Header Auth ---
+
+// GetTrustedHeaderAuth returns the trusted-header auth configuration.
+func (s *Store) GetTrustedHeaderAuth() *TrustedHeaderAuthConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return nil
+	}
+	return s.config.TrustedHeaderAuth
+}
+
+// SetTrustedHeaderAuth sets the trusted-header auth configuration and saves.
+func (s *Store) SetTrustedHeaderAuth(th *TrustedHeaderAuthConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	s.ensureConfig()
+	s.config.TrustedHeaderAuth = th
+	return s.saveLocked()
+}
+
+// --- Override Headers ---
+
+// GetOverrideHeaders returns the per-request override header configuration.
+func (s *Store) GetOverrideHeaders() *OverrideHeadersConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return nil
+	}
+	return s.config.OverrideHeaders
+}
+
+// --- Ingress keys ---
+
+// GetIngress returns the ingress key configuration.
+func (s *Store) GetIngress() *IngressConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return nil
+	}
+	return s.config.Ingress
+}
+
+// SetIngress sets the ingress key configuration and saves.
+func (s *Store) SetIngress(ic *IngressConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	s.ensureConfig()
+	s.config.Ingress = ic
+	return s.saveLocked()
+}
+
+// AddIngressKey issues a new ingress key for user and saves.
+func (s *Store) AddIngressKey(user string) (*IngressKey, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("generate key: %w", err)
+	}
+	secret := hex.EncodeToString(b)
+	key := &IngressKey{
+		ID:        secret[:8],
+		Key:       "zen-" + secret,
+		User:      user,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	s.ensureConfig()
+	if s.config.Ingress == nil {
+		s.config.Ingress = &IngressConfig{}
+	}
+	s.config.Ingress.Keys = append(s.config.Ingress.Keys, key)
+	if err := s.saveLocked(); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// DeleteIngressKey revokes an ingress key by ID. It reports whether the key
+// existed.
+func (s *Store) DeleteIngressKey(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	s.ensureConfig()
+	if s.config.Ingress == nil {
+		return false, nil
+	}
+	for i, k := range s.config.Ingress.Keys {
+		if k != nil && k.ID == id {
+			s.config.Ingress.Keys = append(s.config.Ingress.Keys[:i], s.config.Ingress.Keys[i+1:]...)
+			return true, s.saveLocked()
+		}
+	}
+	return false, nil
+}
+
+// SetOverrideHeaders sets the per-request override header configuration and saves.
+func (s *Store) SetOverrideHeaders(oh *OverrideHeadersConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	s.ensureConfig()
+	s.config.OverrideHeaders = oh
+	return s.saveLocked()
+}
+
+// --- Secret references ---
+
+// SecretRefs returns the config fields that hold secret references.
+func (s *Store) SecretRefs() []SecretRef {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return nil
+	}
+	return SecretRefs(s.config)
+}
+
 // --- Compression (BETA) ---
 
 // GetCompression returns the compression configuration.
@@ -539,3 +539,53 @@ func TestValidateRoutingConfig_ScenarioPriority(t *testing.T) {
 		})
 	}
 }
+
+// Test custom scenario match rule validation
+func TestValidateRoutingConfig_ScenarioMatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		scenario    string
+		match       *ScenarioMatch
+		errContains string
+	}{
+		{name: "valid system pattern", scenario: "review", match: &ScenarioMatch{SystemPattern: "(?i)review"}},
+		{name: "valid tools and tokens", scenario: "browser", match: &ScenarioMatch{Tools: []string{"browse"}, MinTokens: 10, MaxTokens: 100}},
+		{name: "no conditions", scenario: "review", match: &ScenarioMatch{}, errContains: "no conditions"},
+		{name: "invalid regex", scenario: "review", match: &ScenarioMatch{SystemPattern: "("}, errContains: "system_pattern"},
+		{name: "empty tool name", scenario: "review", match: &ScenarioMatch{Tools: []string{""}}, errContains: "empty name"},
+		{name: "min above max", scenario: "review", match: &ScenarioMatch{MinTokens: 200, MaxTokens: 100}, errContains: "exceeds"},
+		{name: "builtin scenario", scenario: "long-context", match: &ScenarioMatch{MinTokens: 1}, errContains: "builtin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &OpenCCConfig{
+				Providers: map[string]*ProviderConfig{
+					"provider1": {BaseURL: "http://example.com", AuthToken: "test"},
+				},
+				Profiles: map[string]*ProfileConfig{
+					"test": {
+						Providers: []string{"provider1"},
+						Routing: map[string]*RoutePolicy{
+							tt.scenario: {
+								Providers: []*ProviderRoute{{Name: "provider1"}},
+								Match:     tt.match,
+							},
+						},
+					},
+				},
+			}
+
+			err := ValidateRoutingConfig(cfg, "test")
+			if tt.errContains == "" {
+				if err != nil {
+					t.Errorf("ValidateRoutingConfig() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("ValidateRoutingConfig() error = %v, want error containing %q", err, tt.errContains)
+			}
+		})
+	}
+}
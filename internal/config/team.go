@@ -0,0 +1,374 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+)
+
+// A team can share providers, profiles and budgets through a team layer,
+// published by a team lead to a sync backend and cached in team.json. The
+// team layer sits under zen.json:
+//
+//	team.json    providers, profiles, budgets       (shared)
+//	zen.json     anything, overriding the team      (this machine)
+//	ZEN_*        environment overrides              (this process)
+//
+// Entries in zen.json override team entries field by field, so a developer
+// can add a personal auth_token to a team provider without copying the
+// rest of it. Saves write only what differs from the team layer back to
+// zen.json, so later team changes still apply.
+const TeamFile = "team.json"
+
+// teamSections are the config keys a team layer can set.
+var teamSections = []string{"providers", "profiles", "budgets"}
+
+// TeamLayer is the shared part of the config published by a team.
+type TeamLayer struct {
+	UpdatedAt time.Time                  `json:"updated_at"`
+	UpdatedBy string                     `json:"updated_by,omitempty"`
+	Providers map[string]*ProviderConfig `json:"providers,omitempty"`
+	Profiles  map[string]*ProfileConfig  `json:"profiles,omitempty"`
+	Budgets   *BudgetConfig              `json:"budgets,omitempty"`
+}
+
+// TeamStatus describes the team layer in effect.
+type TeamStatus struct {
+	Configured bool      `json:"configured"` // a team source is set in zen.json
+	Loaded     bool      `json:"loaded"`     // team.json has been pulled
+	UpdatedAt  time.Time `json:"updated_at,omitempty"`
+	UpdatedBy  string    `json:"updated_by,omitempty"`
+	Providers  []string  `json:"providers,omitempty"`
+	Profiles   []string  `json:"profiles,omitempty"`
+	Budgets    bool      `json:"budgets,omitempty"`
+}
+
+// TeamFilePath returns the path of the cached team layer.
+func TeamFilePath() string {
+	return filepath.Join(ConfigDirPath(), TeamFile)
+}
+
+// readTeamLayer reads a team layer, returning nil if the file doesn't exist.
+func readTeamLayer(path string) (*TeamLayer, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var layer TeamLayer
+	if err := json.Unmarshal(data, &layer); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &layer, nil
+}
+
+// teamTree returns the sections of a team layer as JSON values.
+func teamTree(layer *TeamLayer) (map[string]interface{}, error) {
+	data, err := json.Marshal(layer)
+	if err != nil {
+		return nil, err
+	}
+	var tree map[string]interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	out := make(map[string]interface{}, len(teamSections))
+	for _, section := range teamSections {
+		if v, ok := tree[section]; ok {
+			out[section] = v
+		}
+	}
+	return out, nil
+}
+
+// layerTeam returns cfg laid over the team layer, and the team sections of
+// cfg as they were, which withoutTeam needs to write them back.
+func layerTeam(cfg *OpenCCConfig, layer *TeamLayer) (*OpenCCConfig, map[string]interface{}, error) {
+	team, err := teamTree(layer)
+	if err != nil {
+		return nil, nil, err
+	}
+	tree, err := configTree(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	local := make(map[string]interface{}, len(teamSections))
+	for _, section := range teamSections {
+		if v, ok := tree[section]; ok && !isEmptyValue(v) {
+			local[section] = withoutEmpty(v)
+		}
+		if base, ok := team[section]; ok {
+			tree[section] = mergeTree(base, tree[section])
+		}
+	}
+	out, err := configFromTree(tree)
+	if err != nil {
+		return nil, nil, fmt.Errorf("apply team layer: %w", err)
+	}
+	return out, local, nil
+}
+
+// mergeTree lays over on top of base. Objects are merged key by key; any
+// other value in over replaces base unless it is empty, since zen.json can't
+// tell an empty value from an unset one.
+func mergeTree(base, over interface{}) interface{} {
+	if isEmptyValue(over) {
+		return base
+	}
+	baseObj, ok1 := base.(map[string]interface{})
+	overObj, ok2 := over.(map[string]interface{})
+	if !ok1 || !ok2 {
+		return over
+	}
+	out := make(map[string]interface{}, len(baseObj)+len(overObj))
+	for k, v := range baseObj {
+		out[k] = v
+	}
+	for k, v := range overObj {
+		out[k] = mergeTree(baseObj[k], v)
+	}
+	return out
+}
+
+// isEmptyValue reports whether a JSON value is null, "", 0, false or [].
+func isEmptyValue(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case float64:
+		return v == 0
+	case bool:
+		return !v
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// withoutEmpty returns v with empty values removed from its objects.
+func withoutEmpty(v interface{}) interface{} {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+	out := make(map[string]interface{}, len(obj))
+	for k, child := range obj {
+		if !isEmptyValue(child) {
+			out[k] = withoutEmpty(child)
+		}
+	}
+	return out
+}
+
+// withoutTeam returns cfg with the team layer taken out of its team
+// sections: values equal to the team's are dropped unless zen.json set them
+// itself. Team entries removed from cfg come back on the next load.
+func withoutTeam(cfg *OpenCCConfig, layer *TeamLayer, local map[string]interface{}) (*OpenCCConfig, error) {
+	team, err := teamTree(layer)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := configTree(cfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, section := range teamSections {
+		base, ok := team[section]
+		cur, has := tree[section]
+		if !ok || !has {
+			continue
+		}
+		orig, hadOrig := local[section]
+		if v, keep := diffTree(cur, base, orig, hadOrig); keep {
+			tree[section] = v
+		} else {
+			delete(tree, section)
+		}
+	}
+	return configFromTree(tree)
+}
+
+// diffTree returns the part of cur that differs from base, keeping values
+// that were present in orig. It reports whether anything is left.
+func diffTree(cur, base, orig interface{}, hadOrig bool) (interface{}, bool) {
+	curObj, ok1 := cur.(map[string]interface{})
+	baseObj, ok2 := base.(map[string]interface{})
+	if !ok1 || !ok2 {
+		return cur, hadOrig || !reflect.DeepEqual(cur, base)
+	}
+	origObj, _ := orig.(map[string]interface{})
+	out := make(map[string]interface{})
+	for k, v := range curObj {
+		b, inBase := baseObj[k]
+		if !inBase {
+			out[k] = v
+			continue
+		}
+		o, inOrig := origObj[k]
+		if sub, keep := diffTree(v, b, o, inOrig); keep {
+			out[k] = sub
+		}
+	}
+	return out, len(out) > 0 || hadOrig
+}
+
+// loadTeam lays cfg over the cached team layer, if there is one.
+func (s *Store) loadTeam(cfg *OpenCCConfig) *OpenCCConfig {
+	s.team, s.teamLocal = nil, nil
+	s.teamModTime = time.Time{}
+	path := s.teamPath()
+	layer, err := readTeamLayer(path)
+	if err != nil {
+		log.Printf("Warning: team layer ignored: %v", err)
+		return cfg
+	}
+	if info, err := os.Stat(path); err == nil {
+		s.teamModTime = info.ModTime()
+	}
+	if layer == nil {
+		return cfg
+	}
+	merged, local, err := layerTeam(cfg, layer)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return cfg
+	}
+	s.team, s.teamLocal = layer, local
+	return merged
+}
+
+// teamPath returns the team layer file next to the config file.
+func (s *Store) teamPath() string {
+	return filepath.Join(filepath.Dir(s.path), TeamFile)
+}
+
+// teamModified reports whether the team layer file changed since it was
+// loaded.
+func (s *Store) teamModified() bool {
+	info, err := os.Stat(s.teamPath())
+	if err != nil {
+		return !s.teamModTime.IsZero()
+	}
+	return !info.ModTime().Equal(s.teamModTime)
+}
+
+// SetTeamLayer caches a pulled team layer in team.json and reloads the
+// config over it. A nil layer leaves the team: the team's settings are
+// written into zen.json, so local overrides of them stay complete, and
+// team.json is removed.
+func (s *Store) SetTeamLayer(layer *TeamLayer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path := s.teamPath()
+	if layer == nil {
+		if s.team != nil {
+			s.team, s.teamLocal = nil, nil
+			if err := s.saveLocked(); err != nil {
+				return err
+			}
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return s.loadLocked()
+	}
+	data, err := json.MarshalIndent(layer, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, data) {
+		return nil // unchanged, don't trigger reloads
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return s.loadLocked()
+}
+
+// TeamStatus returns the team layer in effect.
+func (s *Store) TeamStatus() *TeamStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	status := &TeamStatus{}
+	if s.config != nil && s.config.Team != nil && s.config.Team.Backend != "" {
+		status.Configured = true
+	}
+	if s.team == nil {
+		return status
+	}
+	status.Loaded = true
+	status.UpdatedAt = s.team.UpdatedAt
+	status.UpdatedBy = s.team.UpdatedBy
+	status.Providers = sortedKeys(s.team.Providers)
+	status.Profiles = sortedKeys(s.team.Profiles)
+	status.Budgets = s.team.Budgets != nil
+	return status
+}
+
+// GetTeamSource returns the sync backend the team layer is pulled from, or
+// nil if there is none.
+func (s *Store) GetTeamSource() *SyncConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	if s.config == nil {
+		return nil
+	}
+	return s.config.Team
+}
+
+// TeamLayerFromConfig builds a team layer from the shareable part of the
+// current config: providers, profiles and budgets. Provider tokens are left
+// out unless withTokens is set, so each developer supplies their own.
+func (s *Store) TeamLayerFromConfig(withTokens bool) *TeamLayer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfModified()
+	s.ensureConfig()
+	layer := &TeamLayer{
+		UpdatedAt: time.Now().UTC(),
+		Providers: make(map[string]*ProviderConfig, len(s.config.Providers)),
+		Profiles:  make(map[string]*ProfileConfig, len(s.config.Profiles)),
+	}
+	for name, p := range s.config.Providers {
+		if p == nil {
+			continue
+		}
+		cp := *p
+		if !withTokens {
+			cp.AuthToken = ""
+			cp.Keys = nil
+		}
+		layer.Providers[name] = &cp
+	}
+	for name, p := range s.config.Profiles {
+		if p != nil {
+			layer.Profiles[name] = p
+		}
+	}
+	layer.Budgets = s.config.Budgets
+	// Round-trip so the layer shares nothing with the live config
+	data, _ := json.Marshal(layer)
+	out := &TeamLayer{}
+	json.Unmarshal(data, out)
+	return out
+}
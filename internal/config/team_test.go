@@ -0,0 +1,123 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStoreTeamLayer(t *testing.T) {
+	home := setTestHome(t)
+	path := filepath.Join(home, ConfigDir, ConfigFile)
+
+	// This machine has its own provider and its own port
+	s := &Store{path: path}
+	s.SetProvider("personal", &ProviderConfig{BaseURL: "https://personal.example.com", AuthToken: "sk-personal"})
+	s.SetProxyPort(29841)
+
+	layer := &TeamLayer{
+		UpdatedBy: "lead",
+		Providers: map[string]*ProviderConfig{
+			"team-api": {BaseURL: "https://team.example.com", Model: "team-model"},
+			"shared":   {BaseURL: "https://shared.example.com", AuthToken: "sk-shared"},
+		},
+		Profiles: map[string]*ProfileConfig{"team": {Providers: []string{"team-api", "shared"}}},
+	}
+	if err := s.SetTeamLayer(layer); err != nil {
+		t.Fatalf("SetTeamLayer() error: %v", err)
+	}
+
+	// Add a personal token to the team provider
+	p := s.GetProvider("team-api")
+	p.AuthToken = "sk-mine"
+	if err := s.SetProvider("team-api", p); err != nil {
+		t.Fatalf("SetProvider() error: %v", err)
+	}
+
+	p = s.GetProvider("team-api")
+	if p == nil || p.BaseURL != "https://team.example.com" || p.Model != "team-model" || p.AuthToken != "sk-mine" {
+		t.Fatalf("team-api = %+v, want team settings with the personal token", p)
+	}
+	if s.GetProvider("shared") == nil || s.GetProvider("personal") == nil {
+		t.Error("team and personal providers should both be present")
+	}
+	if got := s.GetProfileOrder("team"); len(got) != 2 {
+		t.Errorf("team profile = %v", got)
+	}
+	if got := s.GetProxyPort(); got != 29841 {
+		t.Errorf("proxy port = %d, want the local one", got)
+	}
+	status := s.TeamStatus()
+	if !status.Loaded || status.UpdatedBy != "lead" || len(status.Providers) != 2 {
+		t.Errorf("TeamStatus() = %+v", status)
+	}
+
+	// Saving keeps the team layer out of zen.json
+	if err := s.SetProvider("personal2", &ProviderConfig{BaseURL: "https://p2.example.com", AuthToken: "sk-p2"}); err != nil {
+		t.Fatalf("SetProvider() error: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	for _, team := range []string{"team.example.com", "sk-shared", `"team"`} {
+		if strings.Contains(string(data), team) {
+			t.Errorf("zen.json contains team value %s:\n%s", team, data)
+		}
+	}
+	if !strings.Contains(string(data), "sk-mine") || !strings.Contains(string(data), "sk-p2") {
+		t.Errorf("zen.json lost local values:\n%s", data)
+	}
+
+	// A team change applies on top of what's saved locally
+	layer.Providers["team-api"].BaseURL = "https://team2.example.com"
+	if err := s.SetTeamLayer(layer); err != nil {
+		t.Fatalf("SetTeamLayer() error: %v", err)
+	}
+	if p := s.GetProvider("team-api"); p.BaseURL != "https://team2.example.com" || p.AuthToken != "sk-mine" {
+		t.Errorf("team-api = %+v after team update", p)
+	}
+
+	// A local edit of a team provider is saved as an override
+	edited := s.GetProvider("team-api")
+	edited.Model = "my-model"
+	if err := s.SetProvider("team-api", edited); err != nil {
+		t.Fatalf("SetProvider() error: %v", err)
+	}
+	data, _ = os.ReadFile(path)
+	if !strings.Contains(string(data), "my-model") || strings.Contains(string(data), "team2.example.com") {
+		t.Errorf("zen.json should hold only the edited field:\n%s", data)
+	}
+
+	// Leaving the team keeps its settings in zen.json
+	if err := s.SetTeamLayer(nil); err != nil {
+		t.Fatalf("SetTeamLayer(nil) error: %v", err)
+	}
+	if p := s.GetProvider("team-api"); p == nil || p.BaseURL != "https://team2.example.com" || p.AuthToken != "sk-mine" {
+		t.Errorf("team-api = %+v after leaving the team", p)
+	}
+	if _, err := os.Stat(filepath.Join(home, ConfigDir, TeamFile)); !os.IsNotExist(err) {
+		t.Error("team.json should be removed")
+	}
+	if s.TeamStatus().Loaded {
+		t.Error("TeamStatus().Loaded should be false after leaving the team")
+	}
+}
+
+func TestTeamLayerFromConfig(t *testing.T) {
+	setTestHome(t)
+	s := &Store{path: filepath.Join(t.TempDir(), ConfigFile)}
+	s.SetProvider("p", &ProviderConfig{
+		BaseURL:   "https://api.example.com",
+		AuthToken: "sk-secret",
+		Keys:      []*ProviderKey{{ID: "backup", Token: "sk-backup"}},
+	})
+	layer := s.TeamLayerFromConfig(false)
+	if p := layer.Providers["p"]; p.AuthToken != "" || len(p.Keys) != 0 || p.BaseURL == "" {
+		t.Errorf("provider = %+v, want it without tokens", p)
+	}
+	if s.GetProvider("p").AuthToken != "sk-secret" {
+		t.Error("building the layer must not change the config")
+	}
+	if layer := s.TeamLayerFromConfig(true); layer.Providers["p"].AuthToken != "sk-secret" {
+		t.Error("tokens should be included when asked")
+	}
+}
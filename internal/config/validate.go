@@ -0,0 +1,304 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+)
+
+// Validation issue severities.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// ValidationIssue is one problem found in the config.
+type ValidationIssue struct {
+	Severity string `json:"severity"`        // "error" or "warning"
+	Field    string `json:"field,omitempty"` // dotted path, e.g. "profiles.default.providers"
+	Message  string `json:"message"`
+	Hint     string `json:"hint,omitempty"` // how to fix it
+}
+
+// ValidationReport is the result of CheckConfig.
+type ValidationReport struct {
+	Valid    bool              `json:"valid"` // no errors; warnings are allowed
+	Errors   []ValidationIssue `json:"errors"`
+	Warnings []ValidationIssue `json:"warnings"`
+}
+
+func (r *ValidationReport) add(severity, field, hint, format string, args ...interface{}) {
+	issue := ValidationIssue{Severity: severity, Field: field, Message: fmt.Sprintf(format, args...), Hint: hint}
+	if severity == SeverityError {
+		r.Errors = append(r.Errors, issue)
+	} else {
+		r.Warnings = append(r.Warnings, issue)
+	}
+}
+
+// has reports whether the report already holds an issue with this message.
+func (r *ValidationReport) has(message string) bool {
+	for _, list := range [][]ValidationIssue{r.Errors, r.Warnings} {
+		for _, issue := range list {
+			if issue.Message == message {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CheckConfig validates cfg and reports each problem with the field it
+// concerns and a hint on fixing it. Besides everything ValidateConfig
+// checks, it reports problems zen tolerates at load time but that make part
+// of the config ineffective, such as invalid ports and conflicting bindings.
+func CheckConfig(cfg *OpenCCConfig) *ValidationReport {
+	r := &ValidationReport{Errors: []ValidationIssue{}, Warnings: []ValidationIssue{}}
+	if cfg == nil {
+		r.add(SeverityError, "", "", "config is nil")
+		return r
+	}
+
+	// Profiles referencing unknown providers
+	for _, profileName := range sortedKeys(cfg.Profiles) {
+		profile := cfg.Profiles[profileName]
+		if profile == nil {
+			continue
+		}
+		for _, providerName := range profile.Providers {
+			if _, exists := cfg.Providers[providerName]; !exists {
+				r.add(SeverityWarning, "profiles."+profileName+".providers",
+					fmt.Sprintf("add provider %q or remove it from the profile; it is skipped at runtime", providerName),
+					"profile %q references non-existent provider %q", profileName, providerName)
+			}
+		}
+
+		// Routing pointing at missing providers
+		for _, scenario := range sortedKeys(profile.Routing) {
+			policy := profile.Routing[scenario]
+			if policy == nil {
+				continue
+			}
+			field := "profiles." + profileName + ".routing." + scenario
+			for _, pr := range policy.Providers {
+				if pr != nil && pr.Name != "" {
+					if _, exists := cfg.Providers[pr.Name]; !exists {
+						r.add(SeverityError, field+".providers",
+							fmt.Sprintf("add provider %q or remove it from the route", pr.Name),
+							"profile %q: scenario %q references non-existent provider %q", profileName, scenario, pr.Name)
+					}
+				}
+			}
+			for _, providerName := range sortedKeys(policy.ProviderWeights) {
+				if _, exists := cfg.Providers[providerName]; !exists {
+					r.add(SeverityError, field+".provider_weights",
+						fmt.Sprintf("remove the weight for %q", providerName),
+						"profile %q: scenario %q has weight for non-existent provider %q", profileName, scenario, providerName)
+				}
+			}
+		}
+	}
+
+	// Default profile
+	defaultProfile := cfg.DefaultProfile
+	if defaultProfile == "" {
+		defaultProfile = DefaultProfileName
+	}
+	if _, exists := cfg.Profiles[defaultProfile]; !exists && len(cfg.Profiles) > 0 {
+		r.add(SeverityWarning, "default_profile", "set default_profile to an existing profile (zen config default-profile)",
+			"default profile %q does not exist", defaultProfile)
+	}
+
+	// Ports
+	proxyPort, webPort := cfg.ProxyPort, cfg.WebPort
+	defaultWeb, defaultProxy := InstancePorts()
+	if proxyPort == 0 {
+		proxyPort = defaultProxy
+	}
+	if webPort == 0 {
+		webPort = defaultWeb
+	}
+	if proxyPort < 1024 || proxyPort > 65535 {
+		r.add(SeverityError, "proxy_port", "use a port between 1024 and 65535 (zen config set proxy_port <port>)",
+			"proxy_port %d is out of range", proxyPort)
+	}
+	if webPort < 1024 || webPort > 65535 {
+		r.add(SeverityError, "web_port", "use a port between 1024 and 65535",
+			"web_port %d is out of range", webPort)
+	}
+	if proxyPort == webPort {
+		r.add(SeverityError, "web_port", "give the proxy and the Web UI different ports",
+			"proxy_port and web_port are both %d", proxyPort)
+	}
+
+	// TLS
+	if tlsCfg := cfg.TLS; tlsCfg.IsEnabled() {
+		if (tlsCfg.CertFile == "") != (tlsCfg.KeyFile == "") {
+			r.add(SeverityError, "tls", "set both cert_file and key_file, or neither to use a self-signed certificate",
+				"tls needs both cert_file and key_file")
+		}
+		if tlsCfg.Listen != "" && !ValidListenAddress(tlsCfg.Listen) {
+			r.add(SeverityError, "tls.listen", "use 0.0.0.0 or :: to listen on all interfaces; the CLI needs 127.0.0.1 reachable",
+				"tls.listen %q is not an all-interface or loopback address", tlsCfg.Listen)
+		}
+	}
+
+	// Daemon log
+	if logCfg := cfg.Log; logCfg != nil {
+		if logCfg.Level != "" && !slices.Contains(LogLevels, logCfg.Level) {
+			r.add(SeverityError, "log.level", "use debug, info, warn or error",
+				"log.level %q is not a log level", logCfg.Level)
+		}
+		if logCfg.Format != "" && logCfg.Format != "console" && logCfg.Format != "json" {
+			r.add(SeverityError, "log.format", `use "console" or "json"`,
+				"log.format %q is not a log format", logCfg.Format)
+		}
+	}
+
+	// Proxy access log
+	if al := cfg.AccessLog; al != nil {
+		if al.Format != "" && al.Format != AccessLogFormatCommon && al.Format != AccessLogFormatJSON {
+			r.add(SeverityError, "access_log.format", `use "common" or "json"`,
+				"access_log.format %q is not an access log format", al.Format)
+		}
+	}
+
+	// Idle sleep
+	if idle := cfg.Idle; idle != nil && idle.AfterMinutes < 0 {
+		r.add(SeverityError, "idle.after_minutes", "use a number of minutes, or leave it out for the default of 30",
+			"idle.after_minutes %d is negative", idle.AfterMinutes)
+	}
+
+	// Trusted header auth
+	if th := cfg.TrustedHeaderAuth; th.IsEnabled() {
+		for _, p := range th.TrustedProxies {
+			if _, err := ParsePrefix(p); err != nil {
+				r.add(SeverityError, "trusted_header_auth.trusted_proxies", "use IPs or CIDRs such as 127.0.0.1 or 100.64.0.0/10",
+					"trusted proxy %q is not an IP or CIDR", p)
+			}
+		}
+		if th.Role != "" && th.Role != "admin" && th.Role != "viewer" {
+			r.add(SeverityError, "trusted_header_auth.role", `use "admin" or "viewer"`,
+				"trusted_header_auth.role %q is not a role", th.Role)
+		}
+	}
+
+	// Bindings
+	resolved := make(map[string]string) // resolved directory -> binding path
+	for _, path := range sortedKeys(cfg.ProjectBindings) {
+		binding := cfg.ProjectBindings[path]
+		if binding == nil {
+			continue
+		}
+		field := "project_bindings." + path
+		if binding.Profile != "" {
+			if _, exists := cfg.Profiles[binding.Profile]; !exists {
+				r.add(SeverityError, field+".profile",
+					fmt.Sprintf("create profile %q or rebind the project (zen bind)", binding.Profile),
+					"project binding %q references non-existent profile %q", path, binding.Profile)
+			}
+		}
+		if IsPatternBinding(path) {
+			if err := ValidateBindingKey(path); err != nil {
+				r.add(SeverityError, field, "use an absolute pattern such as /srv/repos/* or ~/work/**", "%s", err.Error())
+			}
+			continue
+		}
+		if !IsPathBinding(path) {
+			if normalizeBindingKey(path) != path {
+				r.add(SeverityWarning, field, fmt.Sprintf("rebind as %q", normalizeBindingKey(path)),
+					"project binding %q is not normalized and never matches", path)
+			}
+			continue
+		}
+		if !filepath.IsAbs(path) {
+			r.add(SeverityWarning, field, "bind the project by its absolute path",
+				"project binding %q is not an absolute path and never matches", path)
+			continue
+		}
+		dir := resolveProjectPath(path)
+		if other, ok := resolved[dir]; ok {
+			prev := cfg.ProjectBindings[other]
+			if prev.Profile != binding.Profile || prev.Client != binding.Client {
+				r.add(SeverityError, field, fmt.Sprintf("remove one of the bindings for %s", dir),
+					"project bindings %q and %q point at the same directory with different settings", other, path)
+			}
+			continue
+		}
+		resolved[dir] = path
+	}
+
+	// Everything else ValidateConfig checks
+	errs, warnings := ValidateConfig(cfg)
+	for _, err := range errs {
+		if !r.has(err.Error()) {
+			r.add(SeverityError, "", "", "%s", err.Error())
+		}
+	}
+	for _, w := range warnings {
+		if !r.has(w) {
+			r.add(SeverityWarning, "", "", "%s", w)
+		}
+	}
+
+	r.Valid = len(r.Errors) == 0
+	return r
+}
+
+// CheckConfigFile validates the config file at path, which need not be the
+// loaded config: a file the daemon rejected on reload can be checked too.
+// JSON syntax errors are reported with their line and column. A missing
+// file is checked as an empty config.
+func CheckConfigFile(path string) (*ValidationReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	var cfg OpenCCConfig
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			r := &ValidationReport{Errors: []ValidationIssue{}, Warnings: []ValidationIssue{}}
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			switch {
+			case errors.As(err, &syntaxErr):
+				// Offset counts the offending byte
+				line, col := lineColumn(data, syntaxErr.Offset-1)
+				r.add(SeverityError, "", "fix the JSON syntax near that position",
+					"%s: line %d, column %d: %v", filepath.Base(path), line, col, syntaxErr)
+			case errors.As(err, &typeErr):
+				line, col := lineColumn(data, typeErr.Offset)
+				r.add(SeverityError, typeErr.Field, fmt.Sprintf("%s must be a %s", typeErr.Field, typeErr.Type),
+					"%s: line %d, column %d: %s has the wrong type (%s)", filepath.Base(path), line, col, typeErr.Field, typeErr.Value)
+			default:
+				r.add(SeverityError, "", "", "%s: %v", filepath.Base(path), err)
+			}
+			return r, nil
+		}
+	}
+	return CheckConfig(&cfg), nil
+}
+
+// lineColumn converts a byte offset in data to a 1-based line and column.
+func lineColumn(data []byte, offset int64) (line, col int) {
+	offset = max(0, min(offset, int64(len(data))))
+	before := data[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	col = int(offset) - bytes.LastIndexByte(before, '\n')
+	return line, col
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -27,6 +29,233 @@ func TestValidateConfig(t *testing.T) {
 			wantErrorCount: 0,
 			wantWarnCount:  0,
 		},
+		{
+			name: "invalid cost model",
+			cfg: &OpenCCConfig{
+				Providers: map[string]*ProviderConfig{
+					"provider1": {BaseURL: "https://api.example.com", AuthToken: "token1", CostModel: &CostModel{
+						Type:  CostModelTiered,
+						Tiers: []PricingTier{{InputPerMillion: 1}, {UpToInputTokens: 1000, InputPerMillion: 2}},
+					}},
+				},
+				Profiles: map[string]*ProfileConfig{
+					"default": {Providers: []string{"provider1"}},
+				},
+			},
+			wantErrorCount: 1,
+			errorContains:  "only the last tier may be unbounded",
+		},
+		{
+			name: "duplicate provider key id",
+			cfg: &OpenCCConfig{
+				Providers: map[string]*ProviderConfig{
+					"provider1": {BaseURL: "https://api.example.com", AuthToken: "token1", Keys: []*ProviderKey{
+						{ID: "next", Token: "token2"}, {ID: "next", Token: "token3"},
+					}},
+				},
+				Profiles: map[string]*ProfileConfig{
+					"default": {Providers: []string{"provider1"}},
+				},
+			},
+			wantErrorCount: 1,
+			errorContains:  `duplicate id "next"`,
+		},
+		{
+			name: "malformed secret reference",
+			cfg: &OpenCCConfig{
+				Providers: map[string]*ProviderConfig{
+					"provider1": {BaseURL: "https://api.example.com", AuthToken: "keychain://gozen"},
+				},
+				Profiles: map[string]*ProfileConfig{
+					"default": {Providers: []string{"provider1"}},
+				},
+			},
+			wantErrorCount: 1,
+			errorContains:  "providers.provider1.auth_token",
+		},
+		{
+			name: "pricing sync without public key",
+			cfg: &OpenCCConfig{
+				Providers: map[string]*ProviderConfig{
+					"provider1": {BaseURL: "https://api.example.com", AuthToken: "token1"},
+				},
+				Profiles: map[string]*ProfileConfig{
+					"default": {Providers: []string{"provider1"}},
+				},
+				PricingSync: &PricingSyncConfig{Enabled: true, URL: "https://example.com/pricing.json"},
+			},
+			wantErrorCount: 1,
+			errorContains:  "public_key must be a base64 ed25519 public key",
+		},
+		{
+			name: "unknown deadlock policy",
+			cfg: &OpenCCConfig{
+				Providers: map[string]*ProviderConfig{
+					"provider1": {BaseURL: "https://api.example.com", AuthToken: "token1"},
+				},
+				Profiles: map[string]*ProfileConfig{
+					"default": {Providers: []string{"provider1"}},
+				},
+				Agent: &AgentConfig{Coordinator: &CoordinatorConfig{DeadlockPolicy: "kill_all", LockWaitSec: 10}},
+			},
+			wantErrorCount: 1,
+			errorContains:  "deadlock_policy",
+		},
+		{
+			name: "negative observatory threshold",
+			cfg: &OpenCCConfig{
+				Providers: map[string]*ProviderConfig{
+					"provider1": {BaseURL: "https://api.example.com", AuthToken: "token1"},
+				},
+				Profiles: map[string]*ProfileConfig{
+					"default": {Providers: []string{"provider1"}},
+				},
+				Agent: &AgentConfig{Observatory: &ObservatoryConfig{LoopThreshold: -1}},
+			},
+			wantErrorCount: 1,
+			errorContains:  "agent.observatory",
+		},
+		{
+			name: "invalid guardrail rule",
+			cfg: &OpenCCConfig{
+				Providers: map[string]*ProviderConfig{
+					"provider1": {BaseURL: "https://api.example.com", AuthToken: "token1"},
+				},
+				Profiles: map[string]*ProfileConfig{
+					"default": {Providers: []string{"provider1"}},
+				},
+				Agent: &AgentConfig{Guardrails: &GuardrailsConfig{ApprovalTimeoutSec: 3600, Policy: []*GuardrailRule{
+					{Name: "a", Action: "deny"},
+					{Name: "b", Action: GuardrailBlock, DenyCommands: []string{"rm ("}},
+				}}},
+			},
+			wantErrorCount: 3,
+			errorContains:  "agent.guardrails.policy",
+		},
+		{
+			name: "invalid runtime roles",
+			cfg: &OpenCCConfig{
+				Providers: map[string]*ProviderConfig{
+					"provider1": {BaseURL: "https://api.example.com", AuthToken: "token1"},
+				},
+				Profiles: map[string]*ProfileConfig{
+					"default": {Providers: []string{"provider1"}},
+				},
+				Agent: &AgentConfig{Runtime: &RuntimeConfig{Roles: map[string]*RuntimeRoleConfig{
+					"planner":  {Profile: "default", Model: "claude-opus-4"},
+					"reviewer": {Profile: "missing"},
+					"tester":   {},
+				}}},
+			},
+			wantErrorCount: 2,
+			errorContains:  "agent.runtime.roles",
+		},
+		{
+			name: "guardrail spike multiple too low",
+			cfg: &OpenCCConfig{
+				Providers: map[string]*ProviderConfig{
+					"provider1": {BaseURL: "https://api.example.com", AuthToken: "token1"},
+				},
+				Profiles: map[string]*ProfileConfig{
+					"default": {Providers: []string{"provider1"}},
+				},
+				Agent: &AgentConfig{Guardrails: &GuardrailsConfig{SpikeMultiple: 0.5}},
+			},
+			wantErrorCount: 1,
+			errorContains:  "spike_multiple",
+		},
+		{
+			name: "batch discount out of range",
+			cfg: &OpenCCConfig{
+				Providers: map[string]*ProviderConfig{
+					"provider1": {BaseURL: "https://api.example.com", AuthToken: "token1"},
+				},
+				Profiles: map[string]*ProfileConfig{
+					"default": {Providers: []string{"provider1"}},
+				},
+				Pricing: map[string]*ModelPricing{"m": {InputPerMillion: 1, OutputPerMillion: 2, BatchDiscount: 1.5}},
+			},
+			wantErrorCount: 1,
+			errorContains:  "batch_discount must be between 0 and 1",
+		},
+		{
+			name: "negative retention",
+			cfg: &OpenCCConfig{
+				Providers: map[string]*ProviderConfig{
+					"provider1": {BaseURL: "https://api.example.com", AuthToken: "token1"},
+				},
+				Profiles: map[string]*ProfileConfig{
+					"default": {Providers: []string{"provider1"}},
+				},
+				UsageRetention: &UsageRetentionConfig{Enabled: true, RawDays: -1},
+			},
+			wantErrorCount: 1,
+			errorContains:  "usage_retention",
+		},
+		{
+			name: "negative webhook retries",
+			cfg: &OpenCCConfig{
+				Providers: map[string]*ProviderConfig{
+					"provider1": {BaseURL: "https://api.example.com", AuthToken: "token1"},
+				},
+				Profiles: map[string]*ProfileConfig{
+					"default": {Providers: []string{"provider1"}},
+				},
+				Webhooks: []*WebhookConfig{{Name: "ops", URL: "https://example.com/hook", MaxRetries: -1}},
+			},
+			wantErrorCount: 1,
+			errorContains:  "max_retries",
+		},
+		{
+			name: "pagerduty webhook without routing key",
+			cfg: &OpenCCConfig{
+				Providers: map[string]*ProviderConfig{
+					"provider1": {BaseURL: "https://api.example.com", AuthToken: "token1"},
+				},
+				Profiles: map[string]*ProfileConfig{
+					"default": {Providers: []string{"provider1"}},
+				},
+				Webhooks: []*WebhookConfig{
+					{Name: "pd", URL: "https://events.pagerduty.com/v2/enqueue"},
+					{Name: "custom", URL: "https://example.com/hook", Template: "{{.Event"},
+				},
+			},
+			wantErrorCount: 2,
+			errorContains:  "routing_key",
+		},
+		{
+			name: "webhook client certificate without key",
+			cfg: &OpenCCConfig{
+				Providers: map[string]*ProviderConfig{
+					"provider1": {BaseURL: "https://api.example.com", AuthToken: "token1"},
+				},
+				Profiles: map[string]*ProfileConfig{
+					"default": {Providers: []string{"provider1"}},
+				},
+				Webhooks: []*WebhookConfig{
+					{Name: "mtls", URL: "https://example.com/hook", ClientCert: "/etc/zen/client.pem"},
+				},
+			},
+			wantErrorCount: 1,
+			errorContains:  "client_key",
+		},
+		{
+			name: "health probe of unknown provider",
+			cfg: &OpenCCConfig{
+				Providers: map[string]*ProviderConfig{
+					"provider1": {BaseURL: "https://api.example.com", AuthToken: "token1"},
+				},
+				Profiles: map[string]*ProfileConfig{
+					"default": {Providers: []string{"provider1"}},
+				},
+				HealthCheck: &HealthCheckConfig{
+					Enabled: true,
+					Probe:   &ProbeConfig{Enabled: true, Providers: []string{"missing"}},
+				},
+			},
+			wantErrorCount: 1,
+			errorContains:  "missing",
+		},
 		{
 			name:           "nil config",
 			cfg:            nil,
@@ -299,3 +528,115 @@ func TestValidateOnSave(t *testing.T) {
 		})
 	}
 }
+
+func TestProviderTransformsValidate(t *testing.T) {
+	valid := &ProviderTransforms{Request: &TransformRules{
+		RenameFields:  map[string]string{"a.b": "c"},
+		DefaultFields: map[string]interface{}{"temperature": 0.5},
+	}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("valid transforms: %v", err)
+	}
+
+	invalid := []*ProviderTransforms{
+		{Request: &TransformRules{RemoveFields: []string{"a..b"}}},
+		{Response: &TransformRules{RenameFields: map[string]string{"a": ""}}},
+		{Request: &TransformRules{SetHeaders: map[string]string{" ": "x"}}},
+	}
+	for i, tr := range invalid {
+		if err := tr.Validate(); err == nil {
+			t.Errorf("case %d: expected error", i)
+		}
+	}
+
+	var nilTransforms *ProviderTransforms
+	if err := nilTransforms.Validate(); err != nil {
+		t.Errorf("nil transforms: %v", err)
+	}
+}
+
+func TestCheckConfig(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &OpenCCConfig{
+		ProxyPort: 19840, // same as the default web port
+		Providers: map[string]*ProviderConfig{
+			"a": {BaseURL: "https://api.example.com", AuthToken: "k"},
+		},
+		Profiles: map[string]*ProfileConfig{
+			"default": {
+				Providers: []string{"a", "gone"},
+				Routing:   map[string]*RoutePolicy{"think": {Providers: []*ProviderRoute{{Name: "missing"}}}},
+			},
+		},
+		ProjectBindings: map[string]*ProjectBinding{
+			dir:             {Profile: "default"},
+			dir + "/":       {Client: "codex"},
+			"relative/path": {Profile: "default"},
+		},
+		TLS:       &TLSConfig{Enabled: true, CertFile: "/etc/zen/cert.pem", Listen: "192.168.1.10"},
+		Log:       &LogConfig{Level: "verbose", Format: "logfmt"},
+		AccessLog: &AccessLogConfig{Enabled: true, Format: "combined"},
+		Idle:      &IdleConfig{Enabled: true, AfterMinutes: -5},
+	}
+	r := CheckConfig(cfg)
+	if r.Valid {
+		t.Fatal("config with errors reported as valid")
+	}
+
+	want := map[string]string{ // field -> severity
+		"profiles.default.providers":               SeverityWarning,
+		"profiles.default.routing.think.providers": SeverityError,
+		"web_port":                       SeverityError,
+		"project_bindings.relative/path": SeverityWarning,
+		"project_bindings." + dir + "/":  SeverityError,
+		"tls":                            SeverityError,
+		"tls.listen":                     SeverityError,
+		"log.level":                      SeverityError,
+		"log.format":                     SeverityError,
+		"access_log.format":              SeverityError,
+		"idle.after_minutes":             SeverityError,
+	}
+	got := make(map[string]string)
+	for _, issue := range append(append([]ValidationIssue{}, r.Errors...), r.Warnings...) {
+		if issue.Field != "" {
+			got[issue.Field] = issue.Severity
+			if issue.Hint == "" {
+				t.Errorf("%s: no hint", issue.Field)
+			}
+		}
+	}
+	for field, severity := range want {
+		if got[field] != severity {
+			t.Errorf("%s: severity %q, want %q (report %+v)", field, got[field], severity, r)
+		}
+	}
+
+	// Issues found by both passes are reported once
+	n := 0
+	for _, issue := range r.Errors {
+		if strings.Contains(issue.Message, `non-existent provider "missing"`) {
+			n++
+		}
+	}
+	if n != 1 {
+		t.Errorf("missing route provider reported %d times, want 1", n)
+	}
+}
+
+func TestCheckConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "zen.json")
+	os.WriteFile(path, []byte("{\n  \"providers\": {\n    \"a\": {,}\n  }\n}"), 0600)
+	r, err := CheckConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Valid || len(r.Errors) != 1 || !strings.Contains(r.Errors[0].Message, "line 3, column 11") {
+		t.Errorf("syntax error report = %+v", r)
+	}
+
+	os.WriteFile(path, []byte(`{"proxy_port": "19841"}`), 0600)
+	r, _ = CheckConfigFile(path)
+	if r.Valid || r.Errors[0].Field != "proxy_port" {
+		t.Errorf("type error report = %+v", r)
+	}
+}
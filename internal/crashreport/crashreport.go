@@ -0,0 +1,220 @@
+// Package crashreport writes a report to ~/.zen/crashes when a goroutine
+// panics: the panic, its stack and the last lines zend logged, with
+// credentials redacted. Reports stay on disk until they are removed; they
+// are only sent anywhere by an explicit `zen crash submit`.
+package crashreport
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// DirName is the directory under the config directory holding reports.
+const DirName = "crashes"
+
+// maxReports caps the reports kept, so a panic that repeats on every
+// request does not fill the disk. The oldest are removed first.
+const maxReports = 50
+
+// Report describes one recovered panic.
+type Report struct {
+	ID        string     `json:"id"`
+	Time      time.Time  `json:"time"`
+	Component string     `json:"component"` // where it panicked: "proxy", "web", "bot:telegram", "agent", ...
+	Panic     string     `json:"panic"`
+	Stack     string     `json:"stack"`
+	Version   string     `json:"version"`
+	GoVersion string     `json:"go_version"`
+	OS        string     `json:"os"`
+	Arch      string     `json:"arch"`
+	LogTail   []string   `json:"log_tail,omitempty"` // last lines of the daemon log before the panic
+	Submitted *time.Time `json:"submitted,omitempty"`
+}
+
+var (
+	mu      sync.Mutex
+	enabled bool
+	version string
+	logger  *log.Logger
+)
+
+// Init turns report writing on, for the running zend of the given version.
+// New reports are noted in logger. Until Init is called, as in tests,
+// panics are only recovered.
+func Init(v string, l *log.Logger) {
+	mu.Lock()
+	enabled, version, logger = true, v, l
+	mu.Unlock()
+}
+
+// Dir returns the directory holding crash reports.
+func Dir() string {
+	return filepath.Join(config.ConfigDirPath(), DirName)
+}
+
+// Write records a panic recovered in component. The panic value, stack and
+// log tail are redacted before they are written. It does nothing before
+// Init.
+func Write(component string, p interface{}, stack []byte) (*Report, error) {
+	mu.Lock()
+	on, v, l := enabled, version, logger
+	mu.Unlock()
+	if !on {
+		return nil, nil
+	}
+
+	now := time.Now()
+	r := &Report{
+		ID:        newID(now),
+		Time:      now.UTC(),
+		Component: component,
+		Panic:     Redact(fmt.Sprint(p)),
+		Stack:     Redact(string(stack)),
+		Version:   v,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+	}
+	for _, line := range recentLog.lines() {
+		r.LogTail = append(r.LogTail, Redact(line))
+	}
+
+	if err := save(r); err != nil {
+		return r, err
+	}
+	prune()
+	if l != nil {
+		l.Printf("[crash] %s panicked: %s (report %s written to %s)", component, r.Panic, r.ID, Dir())
+	}
+	return r, nil
+}
+
+// Recover writes a report for a panic in progress and stops it. It must be
+// deferred directly:
+//
+//	defer crashreport.Recover("bot:telegram")
+func Recover(component string) {
+	if p := recover(); p != nil {
+		Write(component, p, debug.Stack())
+	}
+}
+
+// Go runs fn on a new goroutine, writing a report instead of crashing the
+// process if it panics.
+func Go(component string, fn func()) {
+	go func() {
+		defer Recover(component)
+		fn()
+	}()
+}
+
+func newID(t time.Time) string {
+	b := make([]byte, 3)
+	rand.Read(b)
+	return t.UTC().Format("20060102-150405") + "-" + hex.EncodeToString(b)
+}
+
+func path(id string) string {
+	return filepath.Join(Dir(), id+".json")
+}
+
+func save(r *Report) error {
+	if err := os.MkdirAll(Dir(), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	p := path(r.ID)
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
+
+// List returns the reports, newest first.
+func List() ([]*Report, error) {
+	paths, err := filepath.Glob(filepath.Join(Dir(), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var reports []*Report
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		var r Report
+		if json.Unmarshal(data, &r) != nil || r.ID == "" {
+			continue
+		}
+		reports = append(reports, &r)
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].ID > reports[j].ID })
+	return reports, nil
+}
+
+// Pending returns how many reports have not been submitted.
+func Pending() int {
+	reports, _ := List()
+	n := 0
+	for _, r := range reports {
+		if r.Submitted == nil {
+			n++
+		}
+	}
+	return n
+}
+
+// Load returns the report with the given ID, or the newest report whose
+// ID starts with it.
+func Load(id string) (*Report, error) {
+	reports, err := List()
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range reports {
+		if r.ID == id || (id != "" && strings.HasPrefix(r.ID, id)) {
+			return r, nil
+		}
+	}
+	return nil, fmt.Errorf("crash report %q not found", id)
+}
+
+// MarkSubmitted records that a report has been submitted.
+func MarkSubmitted(r *Report) error {
+	now := time.Now().UTC()
+	r.Submitted = &now
+	return save(r)
+}
+
+// Remove deletes a report.
+func Remove(id string) error {
+	return os.Remove(path(id))
+}
+
+// prune removes the oldest reports beyond maxReports.
+func prune() {
+	reports, err := List()
+	if err != nil {
+		return
+	}
+	for _, r := range reports[min(len(reports), maxReports):] {
+		Remove(r.ID)
+	}
+}
@@ -0,0 +1,52 @@
+package crashreport
+
+import (
+	"io"
+	"strings"
+	"sync"
+)
+
+// logTailLines is how many log lines a report carries.
+const logTailLines = 50
+
+// recentLog keeps the last lines written to the daemon log.
+var recentLog = &logRing{}
+
+// LogTail returns a writer that keeps the last lines written to it for
+// crash reports. The daemon log is teed into it.
+func LogTail() io.Writer {
+	return recentLog
+}
+
+type logRing struct {
+	mu    sync.Mutex
+	buf   []string
+	next  int
+	extra string // a line not yet ended
+}
+
+func (r *logRing) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.extra + string(p)
+	lines := strings.Split(s, "\n")
+	r.extra = lines[len(lines)-1]
+	for _, line := range lines[:len(lines)-1] {
+		if len(r.buf) < logTailLines {
+			r.buf = append(r.buf, line)
+			continue
+		}
+		r.buf[r.next] = line
+		r.next = (r.next + 1) % logTailLines
+	}
+	return len(p), nil
+}
+
+// lines returns the kept lines, oldest first.
+func (r *logRing) lines() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]string, 0, len(r.buf))
+	out = append(out, r.buf[r.next:]...)
+	return append(out, r.buf[:r.next]...)
+}
@@ -0,0 +1,42 @@
+package crashreport
+
+import (
+	"os"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// secretPatterns match credentials that may turn up in a panic value, a
+// stack or a log line. Their groups, when they have any, are kept around
+// the mask.
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+	regexp.MustCompile(`sk-ant-[A-Za-z0-9_-]{8,}`),
+	regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{16,}`),
+	regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{20,}|github_pat_[A-Za-z0-9_]{20,})`),
+	regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+	regexp.MustCompile(`\benc:v1:[A-Za-z0-9+/=_-]+`),
+	regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/=-]{8,}`),
+	regexp.MustCompile(`(://[^/\s:@]+:)[^/\s@]+(@)`),
+	regexp.MustCompile(`(?i)((?:api[_-]?key|auth[_-]?token|access[_-]?token|token|secret|password|passphrase|authorization|x-api-key)["']?\s*[:=]\s*["']?)[^\s"',;&}]+`),
+}
+
+// Redact masks credentials in s and replaces the home directory with ~.
+func Redact(s string) string {
+	for _, re := range secretPatterns {
+		if re.NumSubexp() == 0 {
+			s = re.ReplaceAllString(s, redacted)
+			continue
+		}
+		s = re.ReplaceAllStringFunc(s, func(m string) string {
+			sub := re.FindStringSubmatch(m)
+			return sub[1] + redacted + strings.Join(sub[2:], "")
+		})
+	}
+	if home := os.Getenv("HOME"); len(home) > 1 {
+		s = strings.ReplaceAll(s, home, "~")
+	}
+	return s
+}
@@ -0,0 +1,48 @@
+package daemon
+
+import (
+	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/proxy"
+)
+
+// applyAccessLog opens, switches or closes the proxy access log to match
+// the config. The file is rotated with the settings of the log section as
+// they were when it was opened.
+func (d *Daemon) applyAccessLog() {
+	cfg := config.GetAccessLog()
+	if cfg == nil || !cfg.Enabled {
+		d.closeAccessLog()
+		return
+	}
+	want := config.AccessLogConfig{Enabled: true, Path: cfg.GetPath(), Format: cfg.GetFormat()}
+	if d.accessLogFile != nil && want == d.accessLogCfg {
+		return
+	}
+
+	file := d.accessLogFile
+	if file == nil || want.Path != d.accessLogCfg.Path {
+		f, err := OpenRotatingFile(want.Path, config.GetLog())
+		if err != nil {
+			d.logger.Printf("Warning: failed to open access log %s: %v", want.Path, err)
+			d.closeAccessLog()
+			return
+		}
+		file = f
+	}
+	proxy.SetGlobalAccessLog(proxy.NewAccessLog(file, want.Format))
+	if d.accessLogFile != nil && d.accessLogFile != file {
+		d.accessLogFile.Close()
+	}
+	d.accessLogFile, d.accessLogCfg = file, want
+	d.logger.Printf("access log: writing %s lines to %s", want.Format, want.Path)
+}
+
+// closeAccessLog stops writing the proxy access log.
+func (d *Daemon) closeAccessLog() {
+	proxy.SetGlobalAccessLog(nil)
+	if d.accessLogFile != nil {
+		d.accessLogFile.Close()
+		d.accessLogFile = nil
+		d.accessLogCfg = config.AccessLogConfig{}
+	}
+}
@@ -0,0 +1,66 @@
+package daemon
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/proxy"
+)
+
+func TestApplyAccessLog(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	zenDir := filepath.Join(dir, ".zen")
+	os.MkdirAll(zenDir, 0755)
+	writeConfig := func(accessLog string) {
+		t.Helper()
+		data := fmt.Sprintf(`{"version": %d, "access_log": %s}`, config.CurrentConfigVersion, accessLog)
+		if err := os.WriteFile(filepath.Join(zenDir, "zen.json"), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+		config.ResetDefaultStore()
+	}
+	t.Cleanup(config.ResetDefaultStore)
+
+	d := &Daemon{logger: log.New(io.Discard, "", 0)}
+	defer d.closeAccessLog()
+
+	writeConfig(`{"enabled": true}`)
+	d.applyAccessLog()
+	if proxy.GetGlobalAccessLog() == nil {
+		t.Fatal("access log not set")
+	}
+	if _, err := os.Stat(filepath.Join(zenDir, "access.log")); err != nil {
+		t.Errorf("access.log not created: %v", err)
+	}
+	first := d.accessLogFile
+
+	// A format change keeps the file
+	writeConfig(`{"enabled": true, "format": "json"}`)
+	d.applyAccessLog()
+	if d.accessLogFile != first || d.accessLogCfg.Format != "json" {
+		t.Errorf("format change: file reopened or format %q not applied", d.accessLogCfg.Format)
+	}
+
+	// A path change moves to a new file
+	other := filepath.Join(dir, "logs", "proxy.log")
+	writeConfig(fmt.Sprintf(`{"enabled": true, "path": %q}`, other))
+	d.applyAccessLog()
+	if d.accessLogFile == first {
+		t.Error("path change: file not reopened")
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("%s not created: %v", other, err)
+	}
+
+	writeConfig(`{"enabled": false}`)
+	d.applyAccessLog()
+	if proxy.GetGlobalAccessLog() != nil || d.accessLogFile != nil {
+		t.Error("access log still on after it was disabled")
+	}
+}
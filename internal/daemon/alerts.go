@@ -0,0 +1,212 @@
+package daemon
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/notify"
+	"github.com/dopejs/gozen/internal/proxy"
+)
+
+// certCheckInterval is how often provider certificates are checked.
+const certCheckInterval = 12 * time.Hour
+
+// failureBurst counts recent request failures per provider to raise
+// request_failure_burst.
+type failureBurst struct {
+	mu        sync.Mutex
+	failures  map[string][]time.Time
+	lastFired map[string]time.Time
+}
+
+func newFailureBurst() *failureBurst {
+	return &failureBurst{
+		failures:  make(map[string][]time.Time),
+		lastFired: make(map[string]time.Time),
+	}
+}
+
+// record adds a failure of provider at now and returns the failures within
+// window. fire is set when they reach count, at most once per window for
+// each provider.
+func (b *failureBurst) record(provider string, now time.Time, count int, window time.Duration) (n int, fire bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	recent := b.failures[provider][:0]
+	for _, t := range b.failures[provider] {
+		if now.Sub(t) < window {
+			recent = append(recent, t)
+		}
+	}
+	recent = append(recent, now)
+	b.failures[provider] = recent
+
+	if len(recent) < count || now.Sub(b.lastFired[provider]) < window {
+		return len(recent), false
+	}
+	b.lastFired[provider] = now
+	return len(recent), true
+}
+
+// keyInvalidInterval is how often key_invalid is raised at most for the same
+// provider key.
+const keyInvalidInterval = time.Hour
+
+// alertingRecorder records request metrics and raises request_failure_burst
+// when a provider keeps failing, and key_invalid when it rejects a key.
+type alertingRecorder struct {
+	*Metrics
+	bursts *failureBurst
+
+	keysMu   sync.Mutex
+	keysSent map[string]time.Time // provider and key ID -> last raised
+}
+
+// RecordRequest implements proxy.MetricsRecorder.
+func (r *alertingRecorder) RecordRequest(provider string, latency time.Duration, err error) {
+	r.Metrics.RecordRequest(provider, latency, err)
+	if err == nil || provider == "" || !notify.GetGlobalDispatcher().Subscribed(config.WebhookEventRequestFailureBurst) {
+		return
+	}
+	alerts := config.GetAlerts()
+	window := alerts.GetFailureBurstWindow()
+	if n, fire := r.bursts.record(provider, time.Now(), alerts.GetFailureBurstCount(), window); fire {
+		notify.NotifyFailureBurst(provider, n, window, err.Error())
+	}
+}
+
+// RecordKeyRejected implements proxy.KeyRejectionRecorder.
+func (r *alertingRecorder) RecordKeyRejected(provider, keyID string, status int, nextKeyID string) {
+	key := provider + "\x00" + keyID
+	now := time.Now()
+	r.keysMu.Lock()
+	if now.Sub(r.keysSent[key]) < keyInvalidInterval {
+		r.keysMu.Unlock()
+		return
+	}
+	if r.keysSent == nil {
+		r.keysSent = make(map[string]time.Time)
+	}
+	r.keysSent[key] = now
+	r.keysMu.Unlock()
+	notify.NotifyKeyInvalid(provider, keyID, status, nextKeyID)
+}
+
+// alertLoop raises budget_forecast when spending is on track to exceed a
+// global budget limit, and cert_expiry when a provider's TLS certificate is
+// about to expire.
+func (d *Daemon) alertLoop(ctx context.Context) {
+	defer d.bgWG.Done()
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+	forecastSent := make(map[string]bool) // period and its end
+	certSent := make(map[string]string)   // host -> date last raised
+	var lastCertCheck time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		dispatcher := notify.GetGlobalDispatcher()
+		now := time.Now().UTC()
+
+		if dispatcher.Subscribed(config.WebhookEventBudgetForecast) {
+			d.checkBudgetForecast(now, forecastSent)
+		}
+		if dispatcher.Subscribed(config.WebhookEventCertExpiry) && now.Sub(lastCertCheck) >= certCheckInterval {
+			lastCertCheck = now
+			d.checkCertExpiry(ctx, now, certSent)
+		}
+	}
+}
+
+// checkBudgetForecast raises budget_forecast once per period for each
+// global limit projected to be exceeded.
+func (d *Daemon) checkBudgetForecast(now time.Time, sent map[string]bool) {
+	checker := proxy.GetGlobalBudgetChecker()
+	if checker == nil {
+		return
+	}
+	forecasts, err := checker.Forecast("", now)
+	if err != nil {
+		d.logger.Printf("budget forecast failed: %v", err)
+		return
+	}
+	for _, f := range forecasts {
+		key := f.Period + " " + f.PeriodEnd.Format(time.RFC3339)
+		if f.BreachAt == nil || sent[key] {
+			continue
+		}
+		sent[key] = true
+		notify.NotifyBudgetForecast(f.Period, f.Spent, f.Limit, f.Projected, *f.BreachAt)
+	}
+}
+
+// checkCertExpiry raises cert_expiry, at most daily for each host, for the
+// HTTPS providers whose certificate expires within alerts.cert_expiry_days.
+func (d *Daemon) checkCertExpiry(ctx context.Context, now time.Time, sent map[string]string) {
+	threshold := config.GetAlerts().GetCertExpiryDays()
+	today := now.Format("2006-01-02")
+	checked := make(map[string]bool)
+	for name, p := range config.DefaultStore().ProviderMap() {
+		u, err := url.Parse(p.BaseURL)
+		if err != nil || u.Scheme != "https" || checked[u.Host] {
+			continue
+		}
+		checked[u.Host] = true
+		if sent[u.Host] == today {
+			continue
+		}
+		expires, err := certExpiry(ctx, u)
+		if err != nil {
+			d.logger.Printf("certificate check for %s failed: %v", u.Host, err)
+			continue
+		}
+		if daysLeft := int(expires.Sub(now).Hours() / 24); daysLeft <= threshold {
+			sent[u.Host] = today
+			notify.NotifyCertExpiry(name, u.Hostname(), expires, daysLeft)
+		}
+	}
+}
+
+// certExpiry returns when the certificate served for u's host expires.
+func certExpiry(ctx context.Context, u *url.URL) (time.Time, error) {
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "443")
+	}
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: 10 * time.Second},
+		// Only the expiry is read; an expired or untrusted certificate
+		// still has to be reported rather than fail the handshake.
+		Config: &tls.Config{ServerName: u.Hostname(), InsecureSkipVerify: true},
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer conn.Close()
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return time.Time{}, fmt.Errorf("no certificate presented")
+	}
+	return certs[0].NotAfter, nil
+}
+
+// notifyStart raises daemon_restart for this start.
+func (d *Daemon) notifyStart() {
+	reason := d.startReason
+	if reason == "" {
+		reason = "start"
+	}
+	notify.NotifyDaemonRestart(reason, d.startDetail, d.version, os.Getpid())
+}
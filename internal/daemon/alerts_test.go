@@ -0,0 +1,48 @@
+package daemon
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestFailureBurst(t *testing.T) {
+	b := newFailureBurst()
+	start := time.Date(2026, 3, 5, 10, 0, 0, 0, time.UTC)
+
+	var fired []int
+	for i := 0; i < 8; i++ {
+		// One failure a minute: the window holds at most five
+		if n, fire := b.record("p", start.Add(time.Duration(i)*time.Minute), 3, 5*time.Minute); fire {
+			fired = append(fired, i)
+			if n != 3 && n != 5 {
+				t.Errorf("failure %d fired with %d failures in the window", i, n)
+			}
+		}
+	}
+	// Fires on the third failure, then not again until a window later
+	if len(fired) != 2 || fired[0] != 2 || fired[1] != 7 {
+		t.Errorf("fired on failures %v, want [2 7]", fired)
+	}
+
+	if _, fire := b.record("q", start, 3, 5*time.Minute); fire {
+		t.Error("providers should be counted separately")
+	}
+}
+
+func TestCertExpiry(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	expires, err := certExpiry(context.Background(), u)
+	if err != nil {
+		t.Fatalf("certExpiry() error: %v", err)
+	}
+	if want := server.Certificate().NotAfter; !expires.Equal(want) {
+		t.Errorf("certExpiry() = %v, want %v", expires, want)
+	}
+}
@@ -27,6 +27,15 @@ type daemonStatusResponse struct {
 	WebPort        int                  `json:"web_port"`
 	ActiveSessions int                  `json:"active_sessions"`
 	FeatureGates   *config.FeatureGates `json:"feature_gates,omitempty"`
+	Bot            *daemonBotStatus     `json:"bot,omitempty"`
+	TLS            *daemonTLSStatus     `json:"tls,omitempty"`
+	Crashes        *CrashRecord         `json:"crashes,omitempty"`
+}
+
+type daemonBotStatus struct {
+	Enabled   bool     `json:"enabled"`
+	Running   bool     `json:"running"`
+	Platforms []string `json:"platforms,omitempty"` // platforms with a connected adapter
 }
 
 type daemonMemoryStats struct {
@@ -57,6 +66,7 @@ type daemonHealthResponse struct {
 	HealthCheckEnabled bool                   `json:"health_check_enabled"`
 	HealthCheckRunning bool                   `json:"health_check_running"`
 	Providers          []daemonProviderHealth `json:"providers"`
+	Crashes            *CrashRecord           `json:"crashes,omitempty"`
 }
 
 func (d *Daemon) handleDaemonStatus(w http.ResponseWriter, r *http.Request) {
@@ -65,9 +75,18 @@ func (d *Daemon) handleDaemonStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Crash-looping wins over running, but a draining daemon is on its way out
+	crashes := ReadCrashRecord()
+	status := "running"
+	if crashes.CrashLooping() {
+		status = "degraded"
+	}
+	if d.draining.Load() {
+		status = "draining"
+	}
 	uptime := time.Since(d.startTime)
 	writeJSON(w, http.StatusOK, daemonStatusResponse{
-		Status:         "running",
+		Status:         status,
 		Version:        d.version,
 		Uptime:         uptime.Truncate(time.Second).String(),
 		UptimeSeconds:  int64(uptime.Seconds()),
@@ -75,9 +94,25 @@ func (d *Daemon) handleDaemonStatus(w http.ResponseWriter, r *http.Request) {
 		WebPort:        d.webPort,
 		ActiveSessions: d.ActiveSessionCount(),
 		FeatureGates:   config.GetFeatureGates(),
+		Bot:            d.botStatus(),
+		TLS:            d.tlsStatus,
+		Crashes:        crashes,
 	})
 }
 
+// botStatus reports whether the bot gateway is configured and connected.
+func (d *Daemon) botStatus() *daemonBotStatus {
+	status := &daemonBotStatus{}
+	if cfg := config.GetBot(); cfg != nil {
+		status.Enabled = cfg.Enabled
+	}
+	if gw := d.botGateway; gw != nil {
+		status.Running = true
+		status.Platforms = gw.ConnectedPlatforms()
+	}
+	return status
+}
+
 func (d *Daemon) handleDaemonHealth(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
@@ -121,6 +156,10 @@ func (d *Daemon) handleDaemonHealth(w http.ResponseWriter, r *http.Request) {
 	if degradedCount > 0 || unhealthyCount > 0 {
 		overallStatus = "degraded"
 	}
+	crashes := ReadCrashRecord()
+	if crashes.CrashLooping() {
+		overallStatus = "degraded"
+	}
 	if len(providers) > 0 && unhealthyCount == len(providers) {
 		overallStatus = "unhealthy"
 	}
@@ -141,9 +180,81 @@ func (d *Daemon) handleDaemonHealth(w http.ResponseWriter, r *http.Request) {
 		HealthCheckEnabled: cfg != nil && cfg.Enabled,
 		HealthCheckRunning: running,
 		Providers:          providers,
+		Crashes:            crashes,
 	})
 }
 
+// --- Log Level API ---
+
+type logLevelRequest struct {
+	Level string `json:"level"`
+}
+
+type logLevelResponse struct {
+	Level  string `json:"level"`
+	Format string `json:"format"`
+}
+
+// handleLogLevel reports the level and format zend logs with, and changes
+// the level at runtime on PUT. The change lasts until zend restarts or
+// log.level changes in the config.
+func (d *Daemon) handleLogLevel(w http.ResponseWriter, r *http.Request) {
+	if d.logSink == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "this zend logs without a level filter"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req logLevelRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+			return
+		}
+		level, err := ParseLogLevel(req.Level)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		if old := d.logSink.Level(); old != level {
+			d.logSink.SetLevel(level)
+			d.logger.Printf("log level changed from %s to %s", old, level)
+		}
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, logLevelResponse{
+		Level:  d.logSink.Level().String(),
+		Format: d.logSink.Format(),
+	})
+}
+
+// --- Container Health Check ---
+
+// handleHealthz handles GET /healthz, a cheap liveness and readiness probe
+// for Docker HEALTHCHECK and Kubernetes. It answers 200 while zend serves
+// requests and 503 once it is shutting down or draining, so orchestrators
+// stop routing to it. Provider health does not affect it, since restarting
+// zend does not fix an upstream outage.
+func (d *Daemon) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	switch {
+	case d.draining.Load():
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "draining"})
+	case d.stopping.Load():
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "stopping"})
+	default:
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	}
+}
+
 // --- Daemon Metrics API ---
 
 func (d *Daemon) handleDaemonMetrics(w http.ResponseWriter, r *http.Request) {
@@ -184,6 +295,71 @@ func (d *Daemon) handleDaemonShutdown(w http.ResponseWriter, r *http.Request) {
 	}()
 }
 
+// --- Daemon Restart API ---
+
+type restartRequest struct {
+	DrainTimeoutSecs int `json:"drain_timeout_secs,omitempty"`
+}
+
+type restartResponse struct {
+	Status string `json:"status,omitempty"`
+	PID    int    `json:"pid,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// handleDaemonRestart hands the listening sockets to a new zend process,
+// then drains this one: it stops accepting connections and shuts down once
+// in-flight requests finish or the drain timeout passes.
+func (d *Daemon) handleDaemonRestart(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	var req restartRequest
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&req)
+	}
+
+	if !d.draining.CompareAndSwap(false, true) {
+		writeJSON(w, http.StatusConflict, restartResponse{Error: "restart already in progress"})
+		return
+	}
+
+	pid, err := d.spawnSuccessor()
+	if err != nil {
+		d.draining.Store(false)
+		d.logger.Printf("graceful restart failed: %v", err)
+		writeJSON(w, http.StatusInternalServerError, restartResponse{Error: err.Error()})
+		return
+	}
+
+	if req.DrainTimeoutSecs > 0 {
+		d.drainTimeout.Store(int64(time.Duration(req.DrainTimeoutSecs) * time.Second))
+	}
+	d.logger.Printf("handed off listeners to new zend (PID %d), draining (timeout %s)", pid, d.DrainTimeout())
+	writeJSON(w, http.StatusAccepted, restartResponse{Status: "draining", PID: pid})
+
+	// Trigger shutdown in background so the response is sent first
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		d.shutdownOnce.Do(func() { close(d.shutdownCh) })
+	}()
+}
+
+// DrainTimeout returns how long shutdown waits for in-flight requests.
+func (d *Daemon) DrainTimeout() time.Duration {
+	if t := d.drainTimeout.Load(); t > 0 {
+		return time.Duration(t)
+	}
+	return defaultDrainTimeout
+}
+
+// SetDrainTimeout sets how long shutdown waits for in-flight requests.
+func (d *Daemon) SetDrainTimeout(t time.Duration) {
+	d.drainTimeout.Store(int64(t))
+}
+
 // ShutdownCh returns a channel that is closed when shutdown is requested via API.
 func (d *Daemon) ShutdownCh() <-chan struct{} {
 	return d.shutdownCh
@@ -209,9 +385,10 @@ func (d *Daemon) handleDaemonReload(w http.ResponseWriter, r *http.Request) {
 // --- Daemon Sessions API ---
 
 type registerSessionRequest struct {
-	SessionID  string `json:"session_id"`
-	Profile    string `json:"profile"`
-	ClientType string `json:"client_type"`
+	SessionID   string `json:"session_id"`
+	Profile     string `json:"profile"`
+	ClientType  string `json:"client_type"`
+	ProjectPath string `json:"project_path,omitempty"`
 }
 
 func (d *Daemon) handleDaemonSessions(w http.ResponseWriter, r *http.Request) {
@@ -253,7 +430,7 @@ func (d *Daemon) handleRegisterSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	d.RegisterSession(req.SessionID, req.Profile, req.ClientType)
+	d.RegisterSession(req.SessionID, req.Profile, req.ClientType, req.ProjectPath)
 
 	// Register with bot bridge
 	if bridge := getBotBridge(); bridge != nil {
@@ -0,0 +1,149 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/crashreport"
+)
+
+const (
+	// CrashWindow is how long crashes count towards a crash loop. A crash
+	// after a quieter period starts a new count, and an instance that stays
+	// up this long clears the record.
+	CrashWindow = 10 * time.Minute
+
+	// crashLoopThreshold is the number of crashes within CrashWindow at
+	// which zend reports itself degraded and backs off before starting.
+	crashLoopThreshold = 3
+
+	// maxCrashBackoff caps the delay before restarting after a crash.
+	maxCrashBackoff = 5 * time.Minute
+)
+
+// CrashRecord counts recent daemon crashes. It is kept next to zend.pid so
+// the count survives the process, whether zend restarts itself or is
+// restarted by launchd or systemd.
+type CrashRecord struct {
+	Restarts   int       `json:"restarts"`
+	FirstCrash time.Time `json:"first_crash"`
+	LastCrash  time.Time `json:"last_crash"`
+	LastError  string    `json:"last_error"`
+}
+
+// CrashRecordPath returns the path to the zend crash record.
+func CrashRecordPath() string {
+	return filepath.Join(config.ConfigDirPath(), config.DaemonCrashFile)
+}
+
+// ReadCrashRecord returns the crash record, or nil if zend has not crashed
+// within CrashWindow.
+func ReadCrashRecord() *CrashRecord {
+	data, err := os.ReadFile(CrashRecordPath())
+	if err != nil {
+		return nil
+	}
+	var r CrashRecord
+	if err := json.Unmarshal(data, &r); err != nil || r.Restarts == 0 {
+		return nil
+	}
+	if time.Since(r.LastCrash) > CrashWindow {
+		return nil
+	}
+	return &r
+}
+
+// RecordCrash adds a crash to the record and returns the updated record.
+func RecordCrash(reason string) (*CrashRecord, error) {
+	now := time.Now()
+	r := ReadCrashRecord()
+	if r == nil {
+		r = &CrashRecord{FirstCrash: now}
+	}
+	r.Restarts++
+	r.LastCrash = now
+	r.LastError = reason
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return r, err
+	}
+	dir := config.ConfigDirPath()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return r, err
+	}
+	path := CrashRecordPath()
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return r, err
+	}
+	return r, os.Rename(tmp, path)
+}
+
+// ClearCrashRecord removes the crash record.
+func ClearCrashRecord() {
+	_ = os.Remove(CrashRecordPath())
+}
+
+// CrashLooping reports whether zend crashed often enough within
+// CrashWindow to be considered degraded.
+func (r *CrashRecord) CrashLooping() bool {
+	return r != nil && r.Restarts >= crashLoopThreshold
+}
+
+// Backoff returns how long to wait before starting again: 1s after the
+// first crash, doubling with each crash up to maxCrashBackoff.
+func (r *CrashRecord) Backoff() time.Duration {
+	if r == nil || r.Restarts == 0 {
+		return 0
+	}
+	backoff := time.Second
+	for i := 1; i < r.Restarts && backoff < maxCrashBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxCrashBackoff {
+		backoff = maxCrashBackoff
+	}
+	return backoff
+}
+
+// Summary describes the record in one line, such as
+// "3 crashes in the last 4m12s, last: proxy server error: ...".
+func (r *CrashRecord) Summary() string {
+	noun := "crashes"
+	if r.Restarts == 1 {
+		noun = "crash"
+	}
+	return fmt.Sprintf("%d %s in the last %s, last: %s", r.Restarts, noun,
+		time.Since(r.FirstCrash).Truncate(time.Second), r.LastError)
+}
+
+// StartRecovered runs Start, turning a panic in it into an error. The panic
+// and its stack are logged, and the daemon is shut down so its listeners
+// are released before a restart.
+func (d *Daemon) StartRecovered() (err error) {
+	defer func() {
+		p := recover()
+		if p == nil {
+			return
+		}
+		stack := debug.Stack()
+		d.logger.Printf("[daemon] panic: %v\n%s", p, stack)
+		crashreport.Write("daemon", p, stack)
+		err = fmt.Errorf("panic: %v", p)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		func() {
+			defer func() { recover() }()
+			d.Shutdown(ctx)
+		}()
+	}()
+	return d.Start()
+}
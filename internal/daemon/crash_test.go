@@ -0,0 +1,111 @@
+package daemon
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordCrash(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if r := ReadCrashRecord(); r != nil {
+		t.Fatalf("ReadCrashRecord() = %+v, want nil", r)
+	}
+	for i := 1; i <= 3; i++ {
+		r, err := RecordCrash("proxy server error")
+		if err != nil {
+			t.Fatalf("RecordCrash() error = %v", err)
+		}
+		if r.Restarts != i {
+			t.Errorf("Restarts = %d, want %d", r.Restarts, i)
+		}
+		if r.CrashLooping() != (i >= crashLoopThreshold) {
+			t.Errorf("CrashLooping() = %v after %d crashes", r.CrashLooping(), i)
+		}
+	}
+	info, err := os.Stat(CrashRecordPath())
+	if err != nil {
+		t.Fatalf("crash record not written: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("crash record mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	ClearCrashRecord()
+	if r := ReadCrashRecord(); r != nil {
+		t.Errorf("ReadCrashRecord() after clear = %+v, want nil", r)
+	}
+}
+
+func TestRecordCrashWindow(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	// Crashes older than the window start a new count
+	old := CrashRecord{Restarts: 4, FirstCrash: time.Now().Add(-time.Hour), LastCrash: time.Now().Add(-CrashWindow - time.Minute), LastError: "old"}
+	data, _ := json.Marshal(old)
+	if err := os.MkdirAll(filepath.Dir(CrashRecordPath()), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(CrashRecordPath(), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if r := ReadCrashRecord(); r != nil {
+		t.Errorf("ReadCrashRecord() = %+v, want nil for a stale record", r)
+	}
+	r, err := RecordCrash("new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Restarts != 1 || r.LastError != "new" {
+		t.Errorf("RecordCrash() = %+v, want a fresh count", r)
+	}
+}
+
+func TestCrashBackoff(t *testing.T) {
+	var none *CrashRecord
+	if none.Backoff() != 0 || none.CrashLooping() {
+		t.Error("a nil record should have no backoff and not be looping")
+	}
+	for restarts, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second, 20: maxCrashBackoff} {
+		if got := (&CrashRecord{Restarts: restarts}).Backoff(); got != want {
+			t.Errorf("Backoff() after %d crashes = %v, want %v", restarts, got, want)
+		}
+	}
+}
+
+func TestDaemonStatusDegradedWhenCrashLooping(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	d := NewDaemon("test-version", testLogger())
+
+	status := func() (string, *CrashRecord) {
+		w := httptest.NewRecorder()
+		d.handleDaemonStatus(w, httptest.NewRequest("GET", "/api/v1/daemon/status", nil))
+		var resp daemonStatusResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to parse response JSON: %v", err)
+		}
+		return resp.Status, resp.Crashes
+	}
+	if got, crashes := status(); got != "running" || crashes != nil {
+		t.Errorf("status = %q, crashes = %+v; want running without crashes", got, crashes)
+	}
+	for i := 0; i < crashLoopThreshold; i++ {
+		RecordCrash("panic: boom")
+	}
+	got, crashes := status()
+	if got != "degraded" || crashes == nil || crashes.Restarts != crashLoopThreshold {
+		t.Errorf("status = %q, crashes = %+v; want degraded", got, crashes)
+	}
+
+	w := httptest.NewRecorder()
+	d.handleDaemonHealth(w, httptest.NewRequest("GET", "/api/v1/daemon/health", nil))
+	var health daemonHealthResponse
+	json.Unmarshal(w.Body.Bytes(), &health)
+	if health.Status != "degraded" || health.Crashes == nil {
+		t.Errorf("health status = %q, crashes = %+v; want degraded", health.Status, health.Crashes)
+	}
+}
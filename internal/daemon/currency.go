@@ -0,0 +1,81 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// currencyRateMaxAge is how long a rate fetched from the currency rate_url
+// is used before it is fetched again.
+const currencyRateMaxAge = 12 * time.Hour
+
+var currencyRateClient = &http.Client{Timeout: 15 * time.Second}
+
+// currencyRateDue reports whether the display currency's rate should be
+// fetched at now, given when the last attempt was made.
+func currencyRateDue(cfg *config.CurrencyConfig, now, lastAttempt time.Time) bool {
+	if cfg == nil || cfg.RateURL == "" || cfg.Rate > 0 || cfg.GetCode() == "USD" {
+		return false
+	}
+	return now.Sub(cfg.FetchedAt) >= currencyRateMaxAge && now.Sub(lastAttempt) >= time.Hour
+}
+
+// fetchCurrencyRate fetches the units of code per USD from a rates document
+// such as {"base": "USD", "rates": {"EUR": 0.92}}.
+func fetchCurrencyRate(ctx context.Context, url, code string) (float64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := currencyRateClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	var doc struct {
+		Rates map[string]float64 `json:"rates"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&doc); err != nil {
+		return 0, fmt.Errorf("invalid rates: %w", err)
+	}
+	rate := doc.Rates[code]
+	if rate <= 0 {
+		return 0, fmt.Errorf("no rate for %s", code)
+	}
+	return rate, nil
+}
+
+// currencyRateLoop keeps the fetched rate of the display currency current.
+func (d *Daemon) currencyRateLoop(ctx context.Context) {
+	defer d.bgWG.Done()
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+	var lastAttempt time.Time
+	for {
+		cfg := config.GetCurrency()
+		if now := time.Now(); currencyRateDue(cfg, now, lastAttempt) {
+			lastAttempt = now
+			rate, err := fetchCurrencyRate(ctx, cfg.RateURL, cfg.GetCode())
+			if err != nil {
+				d.logger.Printf("currency rate fetch from %s failed: %v", cfg.RateURL, err)
+			} else if err := config.SetFetchedCurrencyRate(rate); err != nil {
+				d.logger.Printf("failed to save currency rate: %v", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package daemon
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+func TestCurrencyRateDue(t *testing.T) {
+	now := time.Now()
+	cfg := &config.CurrencyConfig{Code: "EUR", RateURL: "https://example.com/rates.json"}
+	if !currencyRateDue(cfg, now, time.Time{}) {
+		t.Error("expected a fetch when no rate has been fetched")
+	}
+	if currencyRateDue(cfg, now, now.Add(-time.Minute)) {
+		t.Error("expected a failed fetch to wait before retrying")
+	}
+	fresh := *cfg
+	fresh.FetchedAt = now.Add(-time.Hour)
+	if currencyRateDue(&fresh, now, time.Time{}) {
+		t.Error("expected a recent rate to be reused")
+	}
+	fixed := *cfg
+	fixed.Rate = 0.9
+	if currencyRateDue(&fixed, now, time.Time{}) {
+		t.Error("expected a fixed rate not to be fetched")
+	}
+}
+
+func TestFetchCurrencyRate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"base":"USD","rates":{"EUR":0.92,"GBP":0.79}}`))
+	}))
+	defer srv.Close()
+
+	rate, err := fetchCurrencyRate(context.Background(), srv.URL, "GBP")
+	if err != nil || rate != 0.79 {
+		t.Errorf("fetchCurrencyRate(GBP) = %v, %v; want 0.79", rate, err)
+	}
+	if _, err := fetchCurrencyRate(context.Background(), srv.URL, "CHF"); err == nil {
+		t.Error("expected an error for a missing currency")
+	}
+}
@@ -42,6 +42,14 @@ func RemoveDaemonPid() {
 	_ = os.Remove(DaemonPidPath())
 }
 
+// RemoveOwnDaemonPid removes the PID file only if it records this process,
+// so a daemon that handed off to a successor leaves the new PID in place.
+func RemoveOwnDaemonPid() {
+	if pid, err := ReadDaemonPid(); err != nil || pid == os.Getpid() {
+		RemoveDaemonPid()
+	}
+}
+
 // CleanupLegacyPidFiles removes old web daemon PID files from previous versions.
 func CleanupLegacyPidFiles() {
 	dir := config.ConfigDirPath()
@@ -6,15 +6,27 @@ import (
 	"os/exec"
 	"path/filepath"
 	"text/template"
+
+	"github.com/dopejs/gozen/internal/config"
 )
 
 const launchdLabel = "com.dopejs.zend"
 const legacyLaunchdLabel = "com.dopejs.opencc-web"
 const legacyZenWebLabel = "com.dopejs.zen-web"
 
+// instanceLaunchdLabel returns the launchd label, which carries the
+// instance ID for instances other than ~/.zen so each can run as its own
+// agent.
+func instanceLaunchdLabel() string {
+	if id := config.InstanceID(); id != "" {
+		return launchdLabel + "." + id
+	}
+	return launchdLabel
+}
+
 func launchdPlistPath() string {
 	home, _ := os.UserHomeDir()
-	return filepath.Join(home, "Library", "LaunchAgents", launchdLabel+".plist")
+	return filepath.Join(home, "Library", "LaunchAgents", instanceLaunchdLabel()+".plist")
 }
 
 const plistTemplate = `<?xml version="1.0" encoding="UTF-8"?>
@@ -31,6 +43,13 @@ const plistTemplate = `<?xml version="1.0" encoding="UTF-8"?>
     <string>start</string>
     <string>--foreground</string>
   </array>
+{{- if .Home}}
+  <key>EnvironmentVariables</key>
+  <dict>
+    <key>ZEN_HOME</key>
+    <string>{{.Home}}</string>
+  </dict>
+{{- end}}
   <key>RunAtLoad</key>
   <true/>
   <key>KeepAlive</key>
@@ -79,15 +98,21 @@ func EnableService() error {
 		return err
 	}
 
+	var zenHome string
+	if config.InstanceID() != "" {
+		zenHome = config.ConfigDirPath()
+	}
 	tmpl := template.Must(template.New("plist").Parse(plistTemplate))
 	if err := tmpl.Execute(f, struct {
 		Label      string
 		Executable string
 		LogPath    string
+		Home       string
 	}{
-		Label:      launchdLabel,
+		Label:      instanceLaunchdLabel(),
 		Executable: exe,
 		LogPath:    DaemonLogPath(),
+		Home:       zenHome,
 	}); err != nil {
 		f.Close()
 		return err
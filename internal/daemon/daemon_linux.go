@@ -6,11 +6,22 @@ import (
 	"os/exec"
 	"path/filepath"
 	"text/template"
+
+	"github.com/dopejs/gozen/internal/config"
 )
 
+// systemdUnitName returns the unit name, which carries the instance ID for
+// instances other than ~/.zen so each can run as its own service.
+func systemdUnitName() string {
+	if id := config.InstanceID(); id != "" {
+		return "zend-" + id + ".service"
+	}
+	return "zend.service"
+}
+
 func systemdUnitPath() string {
 	home, _ := os.UserHomeDir()
-	return filepath.Join(home, ".config", "systemd", "user", "zend.service")
+	return filepath.Join(home, ".config", "systemd", "user", systemdUnitName())
 }
 
 const unitTemplate = `[Unit]
@@ -19,7 +30,8 @@ After=network.target
 
 [Service]
 Type=simple
-ExecStart={{.Executable}} daemon start --foreground
+{{if .Home}}Environment="ZEN_HOME={{.Home}}"
+{{end}}ExecStart={{.Executable}} daemon start --foreground
 Restart=always
 RestartSec=5
 
@@ -57,10 +69,16 @@ func EnableService() error {
 	}
 
 	tmpl := template.Must(template.New("unit").Parse(unitTemplate))
+	var zenHome string
+	if config.InstanceID() != "" {
+		zenHome = config.ConfigDirPath()
+	}
 	if err := tmpl.Execute(f, struct {
 		Executable string
+		Home       string
 	}{
 		Executable: exe,
+		Home:       zenHome,
 	}); err != nil {
 		f.Close()
 		return err
@@ -70,7 +88,7 @@ func EnableService() error {
 	if out, err := exec.Command("systemctl", "--user", "daemon-reload").CombinedOutput(); err != nil {
 		return fmt.Errorf("systemctl daemon-reload failed: %s: %w", string(out), err)
 	}
-	if out, err := exec.Command("systemctl", "--user", "enable", "--now", "zend.service").CombinedOutput(); err != nil {
+	if out, err := exec.Command("systemctl", "--user", "enable", "--now", systemdUnitName()).CombinedOutput(); err != nil {
 		return fmt.Errorf("systemctl enable failed: %s: %w", string(out), err)
 	}
 
@@ -81,8 +99,8 @@ func EnableService() error {
 func DisableService() error {
 	unitPath := systemdUnitPath()
 
-	exec.Command("systemctl", "--user", "stop", "zend.service").Run()
-	exec.Command("systemctl", "--user", "disable", "zend.service").Run()
+	exec.Command("systemctl", "--user", "stop", systemdUnitName()).Run()
+	exec.Command("systemctl", "--user", "disable", systemdUnitName()).Run()
 
 	if err := os.Remove(unitPath); err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to remove unit file: %w", err)
@@ -6,10 +6,21 @@ import (
 	"os/exec"
 	"syscall"
 	"time"
+
+	"github.com/dopejs/gozen/internal/config"
 )
 
 const taskName = "zend"
 
+// instanceTaskName returns the scheduled task name, which carries the
+// instance ID for instances other than ~/.zen so each can run on its own.
+func instanceTaskName() string {
+	if id := config.InstanceID(); id != "" {
+		return taskName + "-" + id
+	}
+	return taskName
+}
+
 // EnableService creates a Windows scheduled task that runs at logon.
 func EnableService() error {
 	// Clean up legacy tasks
@@ -21,10 +32,14 @@ func EnableService() error {
 		return fmt.Errorf("cannot determine executable path: %w", err)
 	}
 
+	command := fmt.Sprintf(`"%s" daemon start --foreground`, exe)
+	if config.InstanceID() != "" {
+		command = fmt.Sprintf(`"%s" --home "%s" daemon start --foreground`, exe, config.ConfigDirPath())
+	}
 	out, err := exec.Command("schtasks", "/create",
-		"/tn", taskName,
+		"/tn", instanceTaskName(),
 		"/sc", "onlogon",
-		"/tr", fmt.Sprintf(`"%s" daemon start --foreground`, exe),
+		"/tr", command,
 		"/f",
 	).CombinedOutput()
 	if err != nil {
@@ -37,7 +52,7 @@ func EnableService() error {
 // DisableService removes the Windows scheduled task.
 func DisableService() error {
 	out, err := exec.Command("schtasks", "/delete",
-		"/tn", taskName,
+		"/tn", instanceTaskName(),
 		"/f",
 	).CombinedOutput()
 	if err != nil {
@@ -58,6 +73,11 @@ func StopDaemonProcess(timeout time.Duration) error {
 	return fmt.Errorf("stopping zend is not supported on Windows; disable the scheduled task instead")
 }
 
+// RestartDaemonProcess is not supported on Windows.
+func RestartDaemonProcess(drainTimeout time.Duration) (int, error) {
+	return 0, errHandoffUnsupported
+}
+
 const _CREATE_NEW_PROCESS_GROUP = 0x00000200
 
 // DaemonSysProcAttr returns SysProcAttr for detaching the child process on Windows.
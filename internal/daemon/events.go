@@ -0,0 +1,74 @@
+package daemon
+
+import (
+	"sync/atomic"
+
+	"github.com/dopejs/gozen/internal/agent"
+	"github.com/dopejs/gozen/internal/proxy"
+	"github.com/dopejs/gozen/internal/web"
+)
+
+// watchEvents forwards request completions, budget status changes, context
+// window warnings, provider health transitions, agent session changes and
+// agent lock deadlocks to the Web UI event stream. An agent session that
+// ends also gets its report written.
+func (d *Daemon) watchEvents() {
+	budget := &budgetWatch{}
+	proxy.GetGlobalRequestMonitor().OnAdd(func(rec proxy.RequestRecord) {
+		d.broadcast(web.EventRequestCompleted, rec)
+		if rec.Cost > 0 {
+			budget.check(d.broadcast)
+		}
+	})
+	proxy.OnContextWarning(func(a proxy.ContextAlert) {
+		d.broadcast(web.EventContextWarning, a)
+	})
+	if checker := proxy.GetGlobalHealthChecker(); checker != nil {
+		checker.OnTransition(func(t proxy.HealthTransition) {
+			d.broadcast(web.EventProviderHealth, t)
+		})
+	}
+	if obs := agent.GetGlobalObservatory(); obs != nil {
+		obs.OnSessionChange(func(c agent.SessionChange) {
+			d.broadcast(web.EventAgentSession, c)
+			if c.Status == agent.SessionStatusIdle || c.Status == agent.SessionStatusKilled {
+				d.reportAgentSession(c.ID)
+			}
+		})
+	}
+	if coord := agent.GetGlobalCoordinator(); coord != nil {
+		coord.OnDeadlock(func(dl agent.Deadlock) {
+			d.broadcast(web.EventAgentDeadlock, dl)
+		})
+	}
+}
+
+// budgetWatch raises budget_warning when the global budget status changes.
+// Checks run off the request's goroutine, and one at a time: requests
+// finishing while a check runs are covered by the next one.
+type budgetWatch struct {
+	running atomic.Bool
+	last    string // message last sent
+}
+
+func (w *budgetWatch) check(send func(event string, data interface{})) {
+	if !w.running.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer w.running.Store(false)
+		checker := proxy.GetGlobalBudgetChecker()
+		if checker == nil {
+			return
+		}
+		status, err := checker.Check("")
+		if err != nil {
+			return
+		}
+		if status.Message == w.last {
+			return
+		}
+		w.last = status.Message
+		send(web.EventBudgetWarning, status)
+	}()
+}
@@ -0,0 +1,152 @@
+//go:build !windows
+
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// handoffEnv is set on a successor daemon that inherits the listening
+// sockets of the daemon it replaces. The proxy and web listeners arrive as
+// file descriptors 3 and 4; descriptor 5 is a pipe the successor writes to
+// once it is serving.
+const handoffEnv = "GOZEN_HANDOFF"
+
+const (
+	handoffProxyFD = 3
+	handoffWebFD   = 4
+	handoffReadyFD = 5
+)
+
+// handoffReadyTimeout bounds how long a daemon waits for its successor.
+const handoffReadyTimeout = 15 * time.Second
+
+// StartedByHandoff reports whether this process is the successor of a
+// daemon that handed its listeners over, before Start consumes them.
+func StartedByHandoff() bool {
+	return os.Getenv(handoffEnv) == "1"
+}
+
+// inheritedListeners returns the listeners passed down by a previous
+// daemon and the pipe used to report readiness, or nils when this process
+// was started normally. They are only returned once per process so a
+// crash-restart binds fresh sockets.
+func inheritedListeners() (proxyLn, webLn net.Listener, ready *os.File, err error) {
+	if os.Getenv(handoffEnv) != "1" {
+		return nil, nil, nil, nil
+	}
+	os.Unsetenv(handoffEnv)
+
+	ready = os.NewFile(handoffReadyFD, "handoff-ready")
+	proxyLn, err = fileListener(handoffProxyFD, "proxy-listener")
+	if err != nil {
+		ready.Close()
+		return nil, nil, nil, fmt.Errorf("inherit proxy listener: %w", err)
+	}
+	webLn, err = fileListener(handoffWebFD, "web-listener")
+	if err != nil {
+		proxyLn.Close()
+		ready.Close()
+		return nil, nil, nil, fmt.Errorf("inherit web listener: %w", err)
+	}
+	return proxyLn, webLn, ready, nil
+}
+
+func fileListener(fd uintptr, name string) (net.Listener, error) {
+	f := os.NewFile(fd, name)
+	defer f.Close()
+	return net.FileListener(f)
+}
+
+// spawnSuccessor starts a new zend process from the current executable,
+// passing it the proxy and web listeners, and waits until it is serving.
+// Both processes accept connections until this one drains and exits.
+func (d *Daemon) spawnSuccessor() (int, error) {
+	if d.proxyListener == nil || d.webServer == nil || d.webServer.Listener() == nil {
+		return 0, errors.New("listeners are not ready")
+	}
+	proxyFile, err := listenerFile(d.proxyListener)
+	if err != nil {
+		return 0, err
+	}
+	defer proxyFile.Close()
+	webFile, err := listenerFile(d.webServer.Listener())
+	if err != nil {
+		return 0, err
+	}
+	defer webFile.Close()
+
+	exe, err := os.Executable()
+	if err != nil {
+		return 0, fmt.Errorf("cannot determine executable path: %w", err)
+	}
+	logFile, err := os.OpenFile(DaemonLogPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("cannot open log file: %w", err)
+	}
+	defer logFile.Close()
+
+	readyR, readyW, err := os.Pipe()
+	if err != nil {
+		return 0, err
+	}
+	defer readyR.Close()
+
+	child := exec.Command(exe, "daemon", "start")
+	child.Env = append(os.Environ(), "GOZEN_DAEMON=1", handoffEnv+"=1")
+	child.Stdout = logFile
+	child.Stderr = logFile
+	child.ExtraFiles = []*os.File{proxyFile, webFile, readyW}
+	child.SysProcAttr = DaemonSysProcAttr()
+	if err := child.Start(); err != nil {
+		readyW.Close()
+		return 0, fmt.Errorf("failed to start zend: %w", err)
+	}
+	readyW.Close()
+
+	// The successor writes a byte once serving; EOF means it exited first
+	readyCh := make(chan error, 1)
+	go func() {
+		_, err := readyR.Read(make([]byte, 1))
+		readyCh <- err
+	}()
+	select {
+	case err := <-readyCh:
+		if err != nil {
+			child.Process.Kill()
+			child.Wait()
+			return 0, errors.New("new zend exited before it was ready")
+		}
+	case <-time.After(handoffReadyTimeout):
+		child.Process.Kill()
+		child.Wait()
+		return 0, fmt.Errorf("new zend did not become ready within %s", handoffReadyTimeout)
+	}
+
+	pid := child.Process.Pid
+	child.Process.Release()
+	return pid, nil
+}
+
+// listenerFile returns a duplicate descriptor of a TCP listener.
+func listenerFile(ln net.Listener) (*os.File, error) {
+	tcp, ok := ln.(*net.TCPListener)
+	if !ok {
+		return nil, fmt.Errorf("cannot hand off %T", ln)
+	}
+	return tcp.File()
+}
+
+// notifyHandoffReady tells the previous daemon that this one is serving.
+func notifyHandoffReady(ready *os.File) {
+	if ready == nil {
+		return
+	}
+	ready.Write([]byte{1})
+	ready.Close()
+}
@@ -0,0 +1,26 @@
+package daemon
+
+import (
+	"errors"
+	"net"
+	"os"
+)
+
+// errHandoffUnsupported is returned when listeners cannot be handed to a
+// new process on this platform.
+var errHandoffUnsupported = errors.New("graceful restart is not supported on Windows")
+
+// StartedByHandoff is always false on Windows.
+func StartedByHandoff() bool { return false }
+
+// inheritedListeners always returns nils on Windows.
+func inheritedListeners() (proxyLn, webLn net.Listener, ready *os.File, err error) {
+	return nil, nil, nil, nil
+}
+
+// spawnSuccessor is not supported on Windows.
+func (d *Daemon) spawnSuccessor() (int, error) {
+	return 0, errHandoffUnsupported
+}
+
+func notifyHandoffReady(ready *os.File) {}
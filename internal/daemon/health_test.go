@@ -176,3 +176,38 @@ func TestHealthEndpointPerformanceUnderLoad(t *testing.T) {
 		t.Errorf("average response time = %dµs, want <100ms", avgDuration)
 	}
 }
+
+// TestHealthzEndpoint verifies /healthz answers 200 while serving and 503
+// once zend is draining or shutting down
+func TestHealthzEndpoint(t *testing.T) {
+	d := NewDaemon("test-version", testLogger())
+
+	healthz := func(method string) (int, string) {
+		w := httptest.NewRecorder()
+		d.handleHealthz(w, httptest.NewRequest(method, "/healthz", nil))
+		var resp map[string]string
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp["status"]
+	}
+
+	if code, status := healthz("GET"); code != http.StatusOK || status != "ok" {
+		t.Errorf("GET /healthz = %d %q, want 200 ok", code, status)
+	}
+	if code, _ := healthz("HEAD"); code != http.StatusOK {
+		t.Errorf("HEAD /healthz = %d, want 200", code)
+	}
+	if code, _ := healthz("POST"); code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /healthz = %d, want 405", code)
+	}
+
+	d.draining.Store(true)
+	if code, status := healthz("GET"); code != http.StatusServiceUnavailable || status != "draining" {
+		t.Errorf("draining: GET /healthz = %d %q, want 503 draining", code, status)
+	}
+	d.draining.Store(false)
+
+	d.stopping.Store(true)
+	if code, status := healthz("GET"); code != http.StatusServiceUnavailable || status != "stopping" {
+		t.Errorf("stopping: GET /healthz = %d %q, want 503 stopping", code, status)
+	}
+}
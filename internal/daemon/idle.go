@@ -0,0 +1,149 @@
+package daemon
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dopejs/gozen/internal/agent"
+	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/proxy"
+)
+
+// idleCheckInterval is how often zend checks whether it has been idle long
+// enough to sleep.
+const idleCheckInterval = 30 * time.Second
+
+// idleState tracks proxied traffic for the idle policy, and whether zend
+// is asleep.
+type idleState struct {
+	mu         sync.Mutex // serializes falling asleep and waking
+	asleep     atomic.Bool
+	asleepAt   time.Time
+	lastActive atomic.Int64 // unix nanoseconds of the last proxied request
+	inflight   atomic.Int64 // proxied requests in progress
+}
+
+// trackProxied wraps the proxy handler so proxied requests keep zend awake
+// for as long as they run, and wake it if it is asleep.
+func (d *Daemon) trackProxied(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		d.idle.inflight.Add(1)
+		d.markActive()
+		defer func() {
+			d.idle.inflight.Add(-1)
+			d.markActive()
+		}()
+		next(w, r)
+	}
+}
+
+func (d *Daemon) markActive() {
+	d.idle.lastActive.Store(time.Now().UnixNano())
+	d.wake("proxied request")
+}
+
+// idleConnState is the ConnState hook of both servers: any connection or
+// request wakes a sleeping zend before it is served.
+func (d *Daemon) idleConnState(_ net.Conn, state http.ConnState) {
+	if state == http.StateNew || state == http.StateActive {
+		d.wake("new connection")
+	}
+}
+
+// idleLoop puts zend to sleep once the idle policy allows it.
+func (d *Daemon) idleLoop(ctx context.Context) {
+	defer d.bgWG.Done()
+	d.idle.lastActive.Store(time.Now().UnixNano())
+	ticker := time.NewTicker(idleCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		d.checkIdle(time.Now())
+	}
+}
+
+// checkIdle sleeps if, as of now, the idle policy is on and zend has had no
+// proxied requests and no agent sessions for the configured time. Turning
+// the policy off wakes it.
+func (d *Daemon) checkIdle(now time.Time) {
+	cfg := config.GetIdle()
+	if cfg == nil || !cfg.Enabled {
+		d.wake("idle sleep disabled")
+		return
+	}
+	if d.idle.asleep.Load() {
+		return
+	}
+	if d.idle.inflight.Load() > 0 || activeAgentSessions() > 0 {
+		d.idle.lastActive.Store(now.UnixNano())
+		return
+	}
+	idleFor := now.Sub(time.Unix(0, d.idle.lastActive.Load()))
+	if idleFor >= cfg.GetAfter() {
+		d.sleep(idleFor)
+	}
+}
+
+// activeAgentSessions counts the agent sessions and runtime tasks still
+// working.
+func activeAgentSessions() int {
+	n := 0
+	if obs := agent.GetGlobalObservatory(); obs != nil {
+		for _, s := range obs.GetActiveSessions() {
+			if s.Status == agent.SessionStatusActive {
+				n++
+			}
+		}
+	}
+	if rt := agent.GetGlobalRuntime(); rt != nil {
+		n += rt.RunningTasks()
+	}
+	return n
+}
+
+// sleep pauses health checks and probes, closes the proxy's database
+// connections and stops the projects' MCP servers. Listeners stay open, so
+// the next connection wakes zend.
+func (d *Daemon) sleep(idleFor time.Duration) {
+	d.idle.mu.Lock()
+	defer d.idle.mu.Unlock()
+	if d.idle.asleep.Load() {
+		return
+	}
+	// Set first, so a connection arriving now waits to wake zend
+	d.idle.asleep.Store(true)
+	d.idle.asleepAt = time.Now()
+	proxy.StopGlobalHealthChecker()
+	proxy.SetIdle(true)
+	if d.mcp != nil {
+		d.mcp.Prune(0) // they start again on next use
+	}
+	debug.FreeOSMemory()
+	d.logger.Printf("[idle] no proxied requests or agent sessions for %s, sleeping", idleFor.Truncate(time.Second))
+}
+
+// wake undoes sleep. It returns at once if zend is awake.
+func (d *Daemon) wake(reason string) {
+	if !d.idle.asleep.Load() {
+		return
+	}
+	d.idle.mu.Lock()
+	defer d.idle.mu.Unlock()
+	if !d.idle.asleep.Load() {
+		return
+	}
+	d.idle.lastActive.Store(time.Now().UnixNano())
+	proxy.SetIdle(false)
+	proxy.StartGlobalHealthChecker()
+	d.idle.asleep.Store(false)
+	d.logger.Printf("[idle] woke after %s asleep (%s)", time.Since(d.idle.asleepAt).Truncate(time.Second), reason)
+}
@@ -0,0 +1,84 @@
+package daemon
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+func TestIdleSleepAndWake(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	zenDir := filepath.Join(dir, ".zen")
+	os.MkdirAll(zenDir, 0755)
+	writeConfig := func(idle string) {
+		t.Helper()
+		data := fmt.Sprintf(`{"version": %d, "idle": %s}`, config.CurrentConfigVersion, idle)
+		if err := os.WriteFile(filepath.Join(zenDir, "zen.json"), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+		config.ResetDefaultStore()
+	}
+	t.Cleanup(config.ResetDefaultStore)
+
+	d := &Daemon{logger: log.New(io.Discard, "", 0)}
+	now := time.Now()
+	d.idle.lastActive.Store(now.Add(-2 * time.Minute).UnixNano())
+
+	// Off by default
+	writeConfig(`{"enabled": false}`)
+	d.checkIdle(now)
+	if d.idle.asleep.Load() {
+		t.Fatal("slept with idle sleep disabled")
+	}
+
+	writeConfig(`{"enabled": true, "after_minutes": 5}`)
+	d.checkIdle(now)
+	if d.idle.asleep.Load() {
+		t.Fatal("slept before after_minutes passed")
+	}
+
+	// A request in progress keeps zend awake however long it runs
+	d.idle.inflight.Store(1)
+	d.checkIdle(now.Add(10 * time.Minute))
+	if d.idle.asleep.Load() {
+		t.Fatal("slept with a proxied request in progress")
+	}
+	d.idle.inflight.Store(0)
+
+	d.checkIdle(now.Add(20 * time.Minute))
+	if !d.idle.asleep.Load() {
+		t.Fatal("did not sleep after after_minutes without traffic")
+	}
+
+	// The next proxied request wakes it before it is handled
+	var awake bool
+	handler := d.trackProxied(func(w http.ResponseWriter, r *http.Request) {
+		awake = !d.idle.asleep.Load()
+	})
+	handler(httptest.NewRecorder(), httptest.NewRequest("POST", "/default/s1/v1/messages", nil))
+	if !awake {
+		t.Fatal("request handled while asleep")
+	}
+
+	// So does a new connection, and turning the policy off
+	d.checkIdle(time.Now().Add(20 * time.Minute))
+	d.idleConnState(nil, http.StateNew)
+	if d.idle.asleep.Load() {
+		t.Fatal("new connection did not wake zend")
+	}
+	d.checkIdle(time.Now().Add(20 * time.Minute))
+	writeConfig(`{"enabled": false}`)
+	d.checkIdle(time.Now())
+	if d.idle.asleep.Load() {
+		t.Fatal("disabling idle sleep did not wake zend")
+	}
+}
@@ -30,6 +30,12 @@ type logEntry struct {
 
 // log writes a log entry with the given level, event, and fields
 func (l *StructuredLogger) log(level, event string, fields map[string]interface{}) {
+	// A sink applies its own level and format
+	if sink, ok := l.writer.(*LogSink); ok {
+		sink.event(level, event, fields)
+		return
+	}
+
 	entry := logEntry{
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
 		Level:     level,
@@ -0,0 +1,157 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// RotatingFile is an append-only log file that is renamed aside, to
+// zend.log.<timestamp>, once it grows past a size or its rotation period
+// ends. Only the newest rotated files are kept, and none older than the
+// maximum age.
+type RotatingFile struct {
+	path       string
+	maxSize    int64
+	interval   time.Duration // 0 rotates on size only
+	maxBackups int
+	maxAge     time.Duration
+	now        func() time.Time
+
+	mu     sync.Mutex
+	f      *os.File
+	info   os.FileInfo
+	size   int64
+	period time.Time // start of the rotation period the file belongs to
+}
+
+// OpenRotatingFile opens the log at path for appending, rotating it as cfg
+// says.
+func OpenRotatingFile(path string, cfg *config.LogConfig) (*RotatingFile, error) {
+	r := &RotatingFile{
+		path:       path,
+		maxSize:    cfg.GetMaxSize(),
+		interval:   cfg.GetRotateInterval(),
+		maxBackups: cfg.GetMaxBackups(),
+		maxAge:     cfg.GetMaxAge(),
+		now:        time.Now,
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *RotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.f, r.info, r.size = f, info, info.Size()
+	// A file last written in an earlier period is rotated on the next write
+	r.period = r.periodStart(info.ModTime())
+	return nil
+}
+
+func (r *RotatingFile) periodStart(t time.Time) time.Time {
+	if r.interval <= 0 {
+		return time.Time{}
+	}
+	return t.Truncate(r.interval)
+}
+
+// Write appends p, rotating the file first if p would take it past the
+// size limit or the rotation period has ended.
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return 0, os.ErrClosed
+	}
+	sizeDue := r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize
+	periodDue := r.interval > 0 && r.size > 0 && r.periodStart(r.now()).After(r.period)
+	if sizeDue || periodDue {
+		if err := r.rotate(); err != nil && r.f == nil {
+			return 0, err
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate renames the current file aside and opens a new one. Another zend
+// writing the same log, as during a handoff, may have rotated it already;
+// then the new file is just reopened.
+func (r *RotatingFile) rotate() error {
+	r.f.Close()
+	r.f = nil
+	if info, err := os.Stat(r.path); err == nil && os.SameFile(info, r.info) {
+		if err := os.Rename(r.path, r.backupPath()); err != nil {
+			r.open()
+			return err
+		}
+	}
+	if err := r.open(); err != nil {
+		return err
+	}
+	r.prune()
+	return nil
+}
+
+func (r *RotatingFile) backupPath() string {
+	base := r.path + "." + r.now().Format("2006-01-02T15-04-05")
+	path := base
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+// Backups returns the rotated files of the log, newest first.
+func (r *RotatingFile) Backups() []string {
+	paths, _ := filepath.Glob(r.path + ".*")
+	sort.Sort(sort.Reverse(sort.StringSlice(paths)))
+	return paths
+}
+
+// prune removes rotated files beyond the newest maxBackups and those older
+// than maxAge.
+func (r *RotatingFile) prune() {
+	for i, path := range r.Backups() {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if i >= r.maxBackups || (r.maxAge > 0 && r.now().Sub(info.ModTime()) > r.maxAge) {
+			os.Remove(path)
+		}
+	}
+}
+
+// Close closes the file.
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return nil
+	}
+	err := r.f.Close()
+	r.f = nil
+	return err
+}
@@ -0,0 +1,97 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+func TestRotatingFileSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "zend.log")
+	r, err := OpenRotatingFile(path, &config.LogConfig{MaxSizeMB: 1, RotateHours: -1, MaxBackups: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	r.maxSize = 10 // bytes, for the test
+
+	clock := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { clock = clock.Add(time.Second); return clock }
+
+	for _, line := range []string{"aaaaaa\n", "bbbbbb\n", "cccccc\n", "dddddd\n"} {
+		if _, err := r.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, _ := os.ReadFile(path)
+	if string(data) != "dddddd\n" {
+		t.Errorf("current log = %q, want the last line", data)
+	}
+	backups := r.Backups()
+	if len(backups) != 2 {
+		t.Fatalf("backups = %v, want the newest 2", backups)
+	}
+	newest, _ := os.ReadFile(backups[0])
+	if string(newest) != "cccccc\n" {
+		t.Errorf("newest backup = %q", newest)
+	}
+	if !strings.HasPrefix(filepath.Base(backups[0]), "zend.log.2026-10-16T") {
+		t.Errorf("backup name = %s", backups[0])
+	}
+}
+
+func TestRotatingFilePeriod(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "zend.log")
+	r, err := OpenRotatingFile(path, &config.LogConfig{RotateHours: 24})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	now := time.Now()
+	r.now = func() time.Time { return now }
+	r.Write([]byte("today\n"))
+	if len(r.Backups()) != 0 {
+		t.Fatalf("rotated within the period: %v", r.Backups())
+	}
+
+	now = now.Add(25 * time.Hour)
+	r.Write([]byte("tomorrow\n"))
+	if len(r.Backups()) != 1 {
+		t.Fatalf("backups = %v, want one after the period ended", r.Backups())
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "tomorrow\n" {
+		t.Errorf("current log = %q", data)
+	}
+}
+
+func TestRotatingFilePrunesOldBackups(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "zend.log")
+	old := path + ".2026-01-01T00-00-00"
+	os.WriteFile(old, []byte("old\n"), 0644)
+	month := time.Now().Add(-30 * 24 * time.Hour)
+	os.Chtimes(old, month, month)
+
+	r, err := OpenRotatingFile(path, &config.LogConfig{MaxAgeDays: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	r.maxSize = 4
+	r.Write([]byte("one\n"))
+	r.Write([]byte("two\n"))
+
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Error("backup older than max_age_days was kept")
+	}
+	if len(r.Backups()) != 1 {
+		t.Errorf("backups = %v, want just the new one", r.Backups())
+	}
+}
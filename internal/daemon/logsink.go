@@ -0,0 +1,203 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LogLevel is the severity of a zend log line.
+type LogLevel int32
+
+const (
+	LevelDebug LogLevel = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var logLevelNames = [...]string{"debug", "info", "warn", "error"}
+
+func (l LogLevel) String() string {
+	if l < LevelDebug || l > LevelError {
+		return fmt.Sprintf("level(%d)", int32(l))
+	}
+	return logLevelNames[l]
+}
+
+// ParseLogLevel parses "debug", "info", "warn" (or "warning") or "error".
+func ParseLogLevel(s string) (LogLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q (use debug, info, warn or error)", s)
+}
+
+// LogSink writes zend's log, both the plain lines of its *log.Logger and
+// the events of its StructuredLogger, in one format: "console" lines for
+// people or "json" lines for log shippers. Lines below its level, which can
+// be changed while zend runs, are dropped.
+type LogSink struct {
+	mu   sync.Mutex
+	out  io.Writer
+	json bool
+
+	level atomic.Int32
+	now   func() time.Time
+}
+
+// NewLogSink creates a sink writing to out in format ("console" or "json").
+func NewLogSink(out io.Writer, format string, level LogLevel) *LogSink {
+	s := &LogSink{out: out, json: format == "json", now: time.Now}
+	s.level.Store(int32(level))
+	return s
+}
+
+// Level returns the lowest level written.
+func (s *LogSink) Level() LogLevel {
+	return LogLevel(s.level.Load())
+}
+
+// SetLevel changes the lowest level written.
+func (s *LogSink) SetLevel(level LogLevel) {
+	s.level.Store(int32(level))
+}
+
+// Format returns "console" or "json".
+func (s *LogSink) Format() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.json {
+		return "json"
+	}
+	return "console"
+}
+
+// SetFormat switches between "console" and "json" lines.
+func (s *LogSink) SetFormat(format string) {
+	s.mu.Lock()
+	s.json = format == "json"
+	s.mu.Unlock()
+}
+
+// Logger returns a logger whose lines go to the sink. Their level comes
+// from their wording, see lineLevel.
+func (s *LogSink) Logger() *log.Logger {
+	return log.New(s, "", 0)
+}
+
+// Write takes one plain log line, as *log.Logger writes them.
+func (s *LogSink) Write(p []byte) (int, error) {
+	msg := strings.TrimRight(string(p), "\n")
+	level := lineLevel(msg)
+	if level < s.Level() {
+		return len(p), nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	var err error
+	if s.json {
+		err = s.writeJSON(map[string]interface{}{
+			"timestamp": now.UTC().Format(time.RFC3339),
+			"level":     level.String(),
+			"message":   msg,
+		})
+	} else {
+		_, err = fmt.Fprintf(s.out, "%s %-5s %s\n", now.Format("2006/01/02 15:04:05"), strings.ToUpper(level.String()), msg)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// event writes a StructuredLogger event.
+func (s *LogSink) event(levelName, event string, fields map[string]interface{}) {
+	level, err := ParseLogLevel(levelName)
+	if err != nil {
+		level = LevelInfo
+	}
+	if level < s.Level() {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if s.json {
+		data := make(map[string]interface{}, len(fields)+3)
+		for k, v := range fields {
+			data[k] = v
+		}
+		data["timestamp"] = now.UTC().Format(time.RFC3339)
+		data["level"] = level.String()
+		data["event"] = event
+		s.writeJSON(data)
+		return
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %-5s %s", now.Format("2006/01/02 15:04:05"), strings.ToUpper(level.String()), event)
+	for _, k := range keys {
+		v := fmt.Sprint(fields[k])
+		if strings.ContainsAny(v, " \t\n\"=") {
+			v = fmt.Sprintf("%q", v)
+		}
+		fmt.Fprintf(&b, " %s=%s", k, v)
+	}
+	b.WriteByte('\n')
+	io.WriteString(s.out, b.String())
+}
+
+func (s *LogSink) writeJSON(v map[string]interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = s.out.Write(append(data, '\n'))
+	return err
+}
+
+// lineLevel classes a plain log line by its wording, since zend's
+// *log.Logger call sites carry no level: lines that start with "Warning"
+// are warn, "Debug" are debug, lines that mention an error, a failure, a
+// panic or a crash are error, and the rest are info.
+func lineLevel(msg string) LogLevel {
+	lower := strings.ToLower(msg)
+	// Skip a "[component] " tag
+	if strings.HasPrefix(lower, "[") {
+		if i := strings.Index(lower, "] "); i > 0 {
+			lower = lower[i+2:]
+		}
+	}
+	switch {
+	case strings.HasPrefix(lower, "warning"):
+		return LevelWarn
+	case strings.HasPrefix(lower, "debug"):
+		return LevelDebug
+	}
+	for _, word := range []string{"error", "failed", "cannot ", "panic", "crash"} {
+		if strings.Contains(lower, word) {
+			return LevelError
+		}
+	}
+	return LevelInfo
+}
@@ -0,0 +1,131 @@
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLineLevel(t *testing.T) {
+	tests := map[string]LogLevel{
+		"zend started: proxy=:19841 web=:19840":                LevelInfo,
+		"Warning: failed to persist proxy port: denied":        LevelWarn,
+		"WARNING: proxy_port changed 1→2 in config, reverting": LevelWarn,
+		"[daemon] cannot record crash: read-only":              LevelError,
+		"proxy server error: accept: too many open files":      LevelError,
+		"[daemon] panic: boom":                                 LevelError,
+		"Debug: probe sent":                                    LevelDebug,
+	}
+	for line, want := range tests {
+		if got := lineLevel(line); got != want {
+			t.Errorf("lineLevel(%q) = %s, want %s", line, got, want)
+		}
+	}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	for s, want := range map[string]LogLevel{"debug": LevelDebug, "INFO": LevelInfo, "warning": LevelWarn, "error": LevelError} {
+		if got, err := ParseLogLevel(s); err != nil || got != want {
+			t.Errorf("ParseLogLevel(%q) = %s, %v; want %s", s, got, err, want)
+		}
+	}
+	if _, err := ParseLogLevel("verbose"); err == nil {
+		t.Error("ParseLogLevel(verbose) should fail")
+	}
+}
+
+func TestLogSinkConsole(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewLogSink(&buf, "console", LevelInfo)
+	sink.now = func() time.Time { return time.Date(2026, 10, 16, 9, 30, 0, 0, time.Local) }
+
+	logger := sink.Logger()
+	logger.Printf("zend started")
+	logger.Printf("Debug: hidden at info")
+	NewStructuredLogger(sink).Warn("routing_fallback", map[string]interface{}{"reason": "no match", "provider": "b"})
+	NewStructuredLogger(sink).Debug("protocol_detection", map[string]interface{}{"session_id": "s"})
+
+	want := "2026/10/16 09:30:00 INFO  zend started\n" +
+		"2026/10/16 09:30:00 WARN  routing_fallback provider=b reason=\"no match\"\n"
+	if got := buf.String(); got != want {
+		t.Errorf("console output:\n%s\nwant:\n%s", got, want)
+	}
+
+	buf.Reset()
+	sink.SetLevel(LevelError)
+	logger.Printf("zend started")
+	logger.Printf("proxy server error: closed")
+	if got := buf.String(); !strings.HasSuffix(got, "ERROR proxy server error: closed\n") || strings.Contains(got, "zend started") {
+		t.Errorf("at error level got %q", got)
+	}
+}
+
+func TestLogSinkJSON(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewLogSink(&buf, "json", LevelDebug)
+	sink.Logger().Printf("Warning: slow provider")
+	NewStructuredLogger(sink).Info("daemon_started", map[string]interface{}{"pid": 42})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines: %q", len(lines), buf.String())
+	}
+	var line, event map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &line); err != nil {
+		t.Fatalf("line is not JSON: %v", err)
+	}
+	if line["level"] != "warn" || line["message"] != "Warning: slow provider" || line["timestamp"] == nil {
+		t.Errorf("line = %v", line)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &event); err != nil {
+		t.Fatalf("event is not JSON: %v", err)
+	}
+	if event["level"] != "info" || event["event"] != "daemon_started" || event["pid"] != float64(42) {
+		t.Errorf("event = %v", event)
+	}
+}
+
+func TestLogLevelAPI(t *testing.T) {
+	d := NewDaemon("test-version", testLogger())
+
+	w := httptest.NewRecorder()
+	d.handleLogLevel(w, httptest.NewRequest("GET", "/api/v1/settings/log-level", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("without a sink: status = %d, want 503", w.Code)
+	}
+
+	var buf bytes.Buffer
+	sink := NewLogSink(&buf, "json", LevelInfo)
+	d.SetLogSink(sink)
+	d.logger = sink.Logger()
+
+	w = httptest.NewRecorder()
+	d.handleLogLevel(w, httptest.NewRequest("PUT", "/api/v1/settings/log-level", strings.NewReader(`{"level":"debug"}`)))
+	var resp logLevelResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || resp.Level != "debug" || resp.Format != "json" {
+		t.Errorf("PUT = %d %+v, want 200 debug json", w.Code, resp)
+	}
+	if sink.Level() != LevelDebug {
+		t.Errorf("sink level = %s, want debug", sink.Level())
+	}
+	if !strings.Contains(buf.String(), "log level changed from info to debug") {
+		t.Errorf("change not logged: %q", buf.String())
+	}
+
+	w = httptest.NewRecorder()
+	d.handleLogLevel(w, httptest.NewRequest("PUT", "/api/v1/settings/log-level", strings.NewReader(`{"level":"loud"}`)))
+	if w.Code != http.StatusBadRequest || sink.Level() != LevelDebug {
+		t.Errorf("invalid level: status = %d, level = %s", w.Code, sink.Level())
+	}
+
+	w = httptest.NewRecorder()
+	d.handleLogLevel(w, httptest.NewRequest("POST", "/api/v1/settings/log-level", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want 405", w.Code)
+	}
+}
@@ -0,0 +1,84 @@
+package daemon
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/dopejs/gozen/internal/mcp"
+	"github.com/dopejs/gozen/internal/proxy"
+)
+
+// mcpIdleTimeout is how long the MCP servers of a project keep running
+// after the endpoint last served it.
+const mcpIdleTimeout = 30 * time.Minute
+
+// handleMCP serves the MCP endpoint, which multiplexes the MCP servers of
+// bound projects. Calling a tool runs code as the user, so the endpoint
+// takes only the daemon's MCP token or a configured ingress key, and turns
+// away browsers on other origins, as the Streamable HTTP transport
+// requires to keep web pages from reaching it.
+func (d *Daemon) handleMCP(w http.ResponseWriter, r *http.Request) {
+	if origin := r.Header.Get("Origin"); origin != "" && !d.mcpOriginAllowed(origin) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "origin not allowed"})
+		return
+	}
+	if !mcpKeyValid(proxy.RequestAPIKey(r.Header)) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "a valid zen MCP token or API key is required"})
+		return
+	}
+	d.mcp.ServeHTTP(w, r)
+}
+
+// mcpKeyValid reports whether key is the daemon's MCP token or an ingress
+// key.
+func mcpKeyValid(key string) bool {
+	if key == "" {
+		return false
+	}
+	if token, err := mcp.LoadToken(); err == nil && subtle.ConstantTimeCompare([]byte(token), []byte(key)) == 1 {
+		return true
+	}
+	return proxy.IsIngressKey(key)
+}
+
+// mcpOriginAllowed reports whether a browser origin may use the MCP
+// endpoint: only the web UI's own, on a loopback address.
+func (d *Daemon) mcpOriginAllowed(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || d.webPort == 0 {
+		return false
+	}
+	if u.Port() != strconv.Itoa(d.webPort) {
+		return false
+	}
+	switch u.Hostname() {
+	case "localhost", "127.0.0.1", "::1":
+		return true
+	}
+	return false
+}
+
+// handleMCPStatus reports the MCP servers zend runs, by project.
+func (d *Daemon) handleMCPStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"projects": d.mcp.Status()})
+}
+
+// checkMCPToolCall rules on a tool call made through the MCP endpoint with
+// the guardrail policy, as the proxy does for the tool calls of models.
+func (d *Daemon) checkMCPToolCall(ctx context.Context, c mcp.ToolCall) (bool, string) {
+	v := d.ruleOnToolCall(ctx, proxy.ToolCallCheck{
+		SessionID:   c.SessionID,
+		ProjectPath: c.ProjectPath,
+		Name:        c.Name,
+		Input:       c.Input,
+	})
+	return v.Blocked, v.Reason
+}
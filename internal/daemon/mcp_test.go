@@ -0,0 +1,88 @@
+package daemon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/mcp"
+)
+
+func TestMCPEndpointAccess(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	config.ResetDefaultStore()
+	t.Cleanup(config.ResetDefaultStore)
+	project := filepath.Join(home, "project")
+	os.MkdirAll(project, 0755)
+	if err := config.BindProject(project, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	servers := map[string]*config.MCPServerConfig{"fs": {Command: filepath.Join(home, "no-such-server")}}
+	if err := config.SetProjectMCPServers(project, servers); err != nil {
+		t.Fatal(err)
+	}
+	ingress, err := config.AddIngressKey("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := mcp.LoadToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(mcp.TokenPath()); err != nil {
+		t.Fatal(err)
+	} else if info.Mode().Perm() != 0600 {
+		t.Errorf("token file mode = %v, want 0600", info.Mode().Perm())
+	}
+	if again, _ := mcp.LoadToken(); again != token {
+		t.Error("LoadToken should return the stored token")
+	}
+
+	d := newTestDaemon()
+	defer d.mcp.Close()
+	endpoint := "/mcp?project=" + url.QueryEscape(project)
+	body := `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`
+
+	tests := []struct {
+		name        string
+		key         string
+		origin      string
+		contentType string
+		want        int
+	}{
+		{"no key", "", "", "application/json", http.StatusUnauthorized},
+		{"wrong key", "zen-proxy", "", "application/json", http.StatusUnauthorized},
+		{"cross origin", token, "https://evil.example", "application/json", http.StatusForbidden},
+		{"loopback origin on another port", token, "http://127.0.0.1:8080", "application/json", http.StatusForbidden},
+		{"text/plain", token, "", "text/plain", http.StatusUnsupportedMediaType},
+		{"token", token, "", "application/json", http.StatusOK},
+		{"ingress key from the web UI", ingress.Key, "http://localhost:19840", "application/json; charset=utf-8", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, endpoint, strings.NewReader(body))
+			r.Header.Set("Content-Type", tt.contentType)
+			if tt.key != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.key)
+			}
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			w := httptest.NewRecorder()
+			d.handleMCP(w, r)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+			// Rejected requests never reach the project's servers
+			if tt.want != http.StatusOK && len(d.mcp.Status()) != 0 {
+				t.Errorf("servers started for a rejected request: %+v", d.mcp.Status())
+			}
+		})
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/dopejs/gozen/internal/proxy"
 )
 
 // Metrics tracks request statistics for the daemon
@@ -35,17 +37,18 @@ type Metrics struct {
 
 // MetricsStats is the response schema for GET /api/v1/daemon/metrics
 type MetricsStats struct {
-	TotalRequests    int64            `json:"total_requests"`
-	SuccessCount     int64            `json:"success_count"`
-	ErrorCount       int64            `json:"error_count"`
-	LatencyP50Ms     float64          `json:"latency_p50_ms"`
-	LatencyP95Ms     float64          `json:"latency_p95_ms"`
-	LatencyP99Ms     float64          `json:"latency_p99_ms"`
-	ErrorsByProvider map[string]int64 `json:"errors_by_provider"`
-	ErrorsByType     map[string]int64 `json:"errors_by_type"`
-	PeakGoroutines   int              `json:"peak_goroutines"`
-	PeakMemoryMB     int64            `json:"peak_memory_mb"`
-	UptimeSeconds    int64            `json:"uptime_seconds"`
+	TotalRequests    int64                               `json:"total_requests"`
+	SuccessCount     int64                               `json:"success_count"`
+	ErrorCount       int64                               `json:"error_count"`
+	LatencyP50Ms     float64                             `json:"latency_p50_ms"`
+	LatencyP95Ms     float64                             `json:"latency_p95_ms"`
+	LatencyP99Ms     float64                             `json:"latency_p99_ms"`
+	ErrorsByProvider map[string]int64                    `json:"errors_by_provider"`
+	ErrorsByType     map[string]int64                    `json:"errors_by_type"`
+	PeakGoroutines   int                                 `json:"peak_goroutines"`
+	PeakMemoryMB     int64                               `json:"peak_memory_mb"`
+	UptimeSeconds    int64                               `json:"uptime_seconds"`
+	ProviderQueues   map[string]proxy.ProviderQueueStats `json:"provider_queues,omitempty"` // providers with max_concurrent set
 }
 
 // RequestError represents an error from a request
@@ -183,6 +186,7 @@ func (m *Metrics) GetStats() MetricsStats {
 		PeakGoroutines:   m.peakGoroutines,
 		PeakMemoryMB:     m.peakMemoryMB,
 		UptimeSeconds:    int64(time.Since(m.startTime).Seconds()),
+		ProviderQueues:   proxy.GetProviderQueueStats(),
 	}
 }
 
@@ -0,0 +1,49 @@
+package daemon
+
+import (
+	"context"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/proxy"
+)
+
+// pricingSyncRetry is how long a failed scheduled pricing sync waits before
+// trying again.
+const pricingSyncRetry = time.Hour
+
+// pricingSyncDue reports whether a scheduled pricing sync should run at now,
+// given when the last attempt was made.
+func pricingSyncDue(cfg *config.PricingSyncConfig, now, lastAttempt time.Time) bool {
+	if cfg == nil || !cfg.Enabled || cfg.URL == "" {
+		return false
+	}
+	return now.Sub(cfg.SyncedAt) >= cfg.GetInterval() && now.Sub(lastAttempt) >= pricingSyncRetry
+}
+
+// pricingSyncLoop applies the signed pricing manifest configured under
+// pricing_sync on its schedule.
+func (d *Daemon) pricingSyncLoop(ctx context.Context) {
+	defer d.bgWG.Done()
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+	var lastAttempt time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		now := time.Now()
+		if !pricingSyncDue(config.GetPricingSync(), now, lastAttempt) {
+			continue
+		}
+		lastAttempt = now
+		result, err := proxy.SyncPricing(ctx, false)
+		if err != nil {
+			d.logger.Printf("pricing sync failed: %v", err)
+			continue
+		}
+		d.logger.Printf("pricing sync applied version %q (%d changes)", result.Version, len(result.Changes))
+	}
+}
@@ -0,0 +1,36 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+func TestPricingSyncDue(t *testing.T) {
+	now := time.Date(2026, 3, 8, 12, 0, 0, 0, time.UTC)
+	cfg := &config.PricingSyncConfig{Enabled: true, URL: "https://example.com/pricing.json", SyncedAt: now.Add(-25 * time.Hour)}
+
+	if !pricingSyncDue(cfg, now, time.Time{}) {
+		t.Error("expected a sync to be due a day after the last one")
+	}
+	if pricingSyncDue(cfg, now, now.Add(-10*time.Minute)) {
+		t.Error("expected a failed attempt to wait before retrying")
+	}
+
+	recent := *cfg
+	recent.SyncedAt = now.Add(-time.Hour)
+	if pricingSyncDue(&recent, now, time.Time{}) {
+		t.Error("expected no sync within the interval")
+	}
+	recent.IntervalHours = 1
+	if !pricingSyncDue(&recent, now, time.Time{}) {
+		t.Error("expected interval_hours to shorten the interval")
+	}
+
+	disabled := *cfg
+	disabled.Enabled = false
+	if pricingSyncDue(&disabled, now, time.Time{}) || pricingSyncDue(nil, now, time.Time{}) {
+		t.Error("expected no sync when disabled")
+	}
+}
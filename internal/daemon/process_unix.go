@@ -3,6 +3,8 @@
 package daemon
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
@@ -154,6 +156,40 @@ func shutdownViaHTTP(timeout time.Duration) bool {
 	return false
 }
 
+// RestartDaemonProcess asks the running daemon to hand its listening sockets
+// to a new zend process started from the current executable. The old daemon
+// stops accepting connections and exits once in-flight requests finish or
+// drainTimeout passes. Returns the PID of the new daemon.
+func RestartDaemonProcess(drainTimeout time.Duration) (int, error) {
+	body, _ := json.Marshal(restartRequest{DrainTimeoutSecs: int(drainTimeout.Seconds())})
+	urls := []string{
+		fmt.Sprintf("http://127.0.0.1:%d/api/v1/daemon/restart", config.GetWebPort()),
+		fmt.Sprintf("http://127.0.0.1:%d/api/v1/daemon/restart", config.GetProxyPort()),
+	}
+
+	client := &http.Client{Timeout: handoffReadyTimeout + 5*time.Second}
+	lastErr := fmt.Errorf("zend is not reachable")
+	for _, u := range urls {
+		resp, err := client.Post(u, "application/json", bytes.NewReader(body))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		var out restartResponse
+		json.NewDecoder(resp.Body).Decode(&out)
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusAccepted {
+			return out.PID, nil
+		}
+		if out.Error != "" {
+			lastErr = fmt.Errorf("%s", out.Error)
+		} else {
+			lastErr = fmt.Errorf("restart API returned %d", resp.StatusCode)
+		}
+	}
+	return 0, lastErr
+}
+
 // GetProcessOnPort returns the PID and process name of the process listening on
 // the given port. Returns an error if no process is found.
 func GetProcessOnPort(port int) (pid int, name string, err error) {
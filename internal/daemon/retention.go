@@ -0,0 +1,46 @@
+package daemon
+
+import (
+	"context"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/proxy"
+)
+
+// compactionDue reports whether scheduled usage database compaction should
+// run at now, given when it last ran.
+func compactionDue(cfg *config.UsageRetentionConfig, now, lastRun time.Time) bool {
+	if cfg == nil || !cfg.Enabled {
+		return false
+	}
+	return now.Sub(lastRun) >= cfg.GetInterval()
+}
+
+// usageCompactionLoop applies the usage_retention policy to the usage
+// database on its schedule.
+func (d *Daemon) usageCompactionLoop(ctx context.Context) {
+	defer d.bgWG.Done()
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+	var lastRun time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		now := time.Now()
+		if !compactionDue(config.GetUsageRetention(), now, lastRun) {
+			continue
+		}
+		lastRun = now
+		result, err := proxy.CompactUsage()
+		if err != nil {
+			d.logger.Printf("usage compaction failed: %v", err)
+			continue
+		}
+		d.logger.Printf("usage compaction removed %d usage rows, %d log rows and %d hourly rows (%d -> %d bytes)",
+			result.UsageRows, result.LogRows, result.HourlyRows, result.BytesBefore, result.BytesAfter)
+	}
+}
@@ -0,0 +1,28 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+func TestCompactionDue(t *testing.T) {
+	now := time.Date(2026, 3, 8, 12, 0, 0, 0, time.UTC)
+	cfg := &config.UsageRetentionConfig{Enabled: true}
+
+	if !compactionDue(cfg, now, time.Time{}) {
+		t.Error("expected compaction to be due when it has not run yet")
+	}
+	if compactionDue(cfg, now, now.Add(-time.Hour)) {
+		t.Error("expected no compaction within the interval")
+	}
+	short := *cfg
+	short.IntervalHours = 1
+	if !compactionDue(&short, now, now.Add(-time.Hour)) {
+		t.Error("expected interval_hours to shorten the interval")
+	}
+	if compactionDue(&config.UsageRetentionConfig{}, now, time.Time{}) || compactionDue(nil, now, time.Time{}) {
+		t.Error("expected no compaction when disabled")
+	}
+}
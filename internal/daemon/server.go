@@ -2,6 +2,7 @@ package daemon
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -11,9 +12,12 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -22,7 +26,9 @@ import (
 	"github.com/dopejs/gozen/internal/bot/adapters"
 	"github.com/dopejs/gozen/internal/config"
 	"github.com/dopejs/gozen/internal/httpx"
+	"github.com/dopejs/gozen/internal/mcp"
 	"github.com/dopejs/gozen/internal/middleware"
+	"github.com/dopejs/gozen/internal/notify"
 	"github.com/dopejs/gozen/internal/proxy"
 	gosync "github.com/dopejs/gozen/internal/sync"
 	"github.com/dopejs/gozen/internal/web"
@@ -56,6 +62,7 @@ type Daemon struct {
 	botGateway     *bot.Gateway
 	logger         *log.Logger
 	structuredLog  *StructuredLogger
+	logSink        *LogSink // nil when the log level is fixed
 	version        string
 	watcher        *ConfigWatcher
 
@@ -70,6 +77,7 @@ type Daemon struct {
 	// Sync
 	syncMgr       *gosync.SyncManager
 	syncCancel    context.CancelFunc // cancels auto-pull ticker
+	teamCancel    context.CancelFunc // cancels team layer auto-pull ticker
 	pushTimer     *time.Timer        // debounced auto-push
 	pushMu        sync.Mutex          // protects pushTimer
 	pushCtx       context.Context    // controls pushTimer callback lifecycle
@@ -79,9 +87,20 @@ type Daemon struct {
 	proxyPort int
 	webPort   int
 
+	// Why this instance started, reported by the daemon_restart event
+	startReason string
+	startDetail string
+
 	// Feature gates tracking (for detecting changes on reload)
 	currentGates *config.FeatureGates
 
+	// Log settings, to apply level and format changes on reload
+	currentLog *config.LogConfig
+
+	// Proxy access log file and the settings it was opened with
+	accessLogFile *RotatingFile
+	accessLogCfg  config.AccessLogConfig
+
 	// Shutdown channel - closed when shutdown is requested via API
 	shutdownCh   chan struct{}
 	shutdownOnce sync.Once
@@ -98,13 +117,37 @@ type Daemon struct {
 
 	// Proxy server error channel for crash detection
 	proxyErrCh chan error
+
+	// Graceful restart: listeners inherited from a previous daemon, and
+	// drain state once listeners have been handed to a successor
+	proxyListener  net.Listener
+	inheritedProxy net.Listener
+	inheritedWeb   net.Listener
+	handoffReady   *os.File
+	draining       atomic.Bool
+	stopping       atomic.Bool  // set once Shutdown starts, fails /healthz
+	drainTimeout   atomic.Int64 // nanoseconds; 0 uses defaultDrainTimeout
+
+	// HTTPS for both listeners; nil when TLS is off
+	tlsConfig *tls.Config
+	tlsStatus *daemonTLSStatus
+
+	// Idle sleep: recent traffic and whether zend is asleep
+	idle idleState
+
+	// MCP servers of bound projects, served from the /mcp endpoint
+	mcp *mcp.Manager
 }
 
+// defaultDrainTimeout is how long shutdown waits for in-flight requests.
+const defaultDrainTimeout = 30 * time.Second
+
 // SessionInfo tracks an active client session.
 type SessionInfo struct {
 	ID        string    `json:"id"`
 	Profile   string    `json:"profile"`
 	Client    string    `json:"client"`
+	Project   string    `json:"project_path,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 	LastSeen  time.Time `json:"last_seen"`
 }
@@ -144,9 +187,25 @@ func NewDaemon(version string, logger *log.Logger) *Daemon {
 		runCancel:     runCancel,
 		metrics:       NewMetrics(),
 		proxyErrCh:    make(chan error, 1), // buffered to avoid blocking
+		mcp:           mcp.NewManager(version, logger),
 	}
 }
 
+// SetStartReason records why the daemon is starting: "crash" when it is
+// restarted after a crash, or "unclean_exit" when the previous daemon died
+// without cleaning up. It is reported by the daemon_restart webhook event.
+func (d *Daemon) SetStartReason(reason, detail string) {
+	d.startReason, d.startDetail = reason, detail
+}
+
+// SetLogSink routes structured daemon events through sink, which also
+// backs the daemon's logger, and lets the log level be changed at runtime.
+// Events go to stderr without one. Must be called before Start().
+func (d *Daemon) SetLogSink(sink *LogSink) {
+	d.logSink = sink
+	d.structuredLog = NewStructuredLogger(sink)
+}
+
 // Start initializes and starts both the proxy and web servers.
 func (d *Daemon) Start() error {
 	d.startTime = time.Now()
@@ -159,8 +218,16 @@ func (d *Daemon) Start() error {
 	d.proxyPort = config.GetProxyPort()
 	d.webPort = config.GetWebPort()
 
+	// Take over the sockets of a daemon that is handing off to us
+	proxyLn, webLn, ready, err := inheritedListeners()
+	if err != nil {
+		d.logger.Printf("Warning: %v, binding new listeners", err)
+	}
+	d.inheritedProxy, d.inheritedWeb, d.handoffReady = proxyLn, webLn, ready
+
 	// Initialize current feature gates for change detection
 	d.currentGates = config.GetFeatureGates()
+	d.currentLog = config.GetLog()
 
 	// Initialize structured logger for proxy logs (SQLite)
 	if err := proxy.InitGlobalLogger(config.ConfigDirPath()); err != nil {
@@ -180,6 +247,20 @@ func (d *Daemon) Start() error {
 
 	// Initialize context compressor (BETA)
 	proxy.InitGlobalCompressor(nil) // providers will be set per-request
+	if compressor := proxy.GetGlobalCompressor(); compressor != nil {
+		compressor.SetIndexDir(config.ConfigDirPath())
+	}
+
+	// Initialize response cache for deterministic requests
+	proxy.InitGlobalResponseCache()
+
+	// Open the proxy access log
+	d.applyAccessLog()
+
+	// Initialize OpenTelemetry trace export
+	if err := proxy.InitGlobalTracing(config.GetTracing()); err != nil {
+		d.logger.Printf("Warning: failed to initialize tracing: %v", err)
+	}
 
 	// Initialize middleware registry (BETA)
 	middleware.InitGlobalRegistry(d.logger)
@@ -195,6 +276,9 @@ func (d *Daemon) Start() error {
 	agent.InitGlobalCoordinator()
 	agent.InitGlobalTaskQueue()
 	agent.InitGlobalRuntime(d.proxyPort)
+	proxy.OnAgentActivity(d.onAgentActivity)
+	proxy.SetToolCallPolicy(d.checkToolCall)
+	d.mcp.SetPolicy(d.checkMCPToolCall)
 
 	// Start health checker if enabled
 	proxy.StartGlobalHealthChecker()
@@ -208,6 +292,16 @@ func (d *Daemon) Start() error {
 		}
 	}
 
+	// Load the TLS certificate before either listener starts
+	tlsConfig, tlsStatus, err := loadTLS(config.GetTLS())
+	if err != nil {
+		return &FatalError{Err: err}
+	}
+	d.tlsConfig, d.tlsStatus = tlsConfig, tlsStatus
+	if tlsStatus != nil {
+		d.logger.Printf("TLS enabled on %s, certificate SHA-256 fingerprint: %s", tlsStatus.Listen, tlsStatus.Fingerprint)
+	}
+
 	// Start proxy server
 	if err := d.startProxy(); err != nil {
 		return fmt.Errorf("proxy server: %w", err)
@@ -215,31 +309,73 @@ func (d *Daemon) Start() error {
 
 	// Start web server (includes daemon API routes)
 	d.webServer = web.NewServer(d.version, d.logger, 0)
+	d.webServer.SetTLS(d.tlsConfig)
+	d.webServer.SetConnState(d.idleConnState)
 
 	// Register daemon API routes on the web server
 	d.webServer.HandleFunc("/api/v1/daemon/status", d.handleDaemonStatus)
 	d.webServer.HandleFunc("/api/v1/daemon/health", d.handleDaemonHealth)
 	d.webServer.HandleFunc("/api/v1/daemon/metrics", d.handleDaemonMetrics)
 	d.webServer.HandleFunc("/api/v1/daemon/shutdown", d.handleDaemonShutdown)
+	d.webServer.HandleFunc("/api/v1/daemon/restart", d.handleDaemonRestart)
 	d.webServer.HandleFunc("/api/v1/daemon/reload", d.handleDaemonReload)
 	d.webServer.HandleFunc("/api/v1/daemon/sessions", d.handleDaemonSessions)
 	d.webServer.HandleFunc("/api/v1/profiles/temp", d.handleTempProfiles)
 	d.webServer.HandleFunc("/api/v1/profiles/temp/", d.handleTempProfile)
+	d.webServer.HandleFunc("/api/v1/settings/log-level", d.handleLogLevel)
+	d.webServer.HandleFunc("/api/v1/daemon/mcp", d.handleMCPStatus)
+	d.webServer.HandleFunc("/healthz", d.handleHealthz)
+
+	// Stream live updates to the Web UI
+	d.watchEvents()
 
 	// Start config watcher
 	d.watcher = NewConfigWatcher(d.logger, d.onConfigReload)
+	d.watcher.OnPluginChange(d.reloadMiddleware)
 	go d.watcher.Start()
 
 	// Start session cleanup goroutine
 	d.bgWG.Add(1)
 	go d.sessionCleanupLoop(d.runCtx)
 
+	// Start scheduled usage reports
+	d.bgWG.Add(1)
+	go d.usageReportLoop(d.runCtx)
+
+	// Start scheduled pricing sync
+	d.bgWG.Add(1)
+	go d.pricingSyncLoop(d.runCtx)
+
+	// Keep the display currency's exchange rate current
+	d.bgWG.Add(1)
+	go d.currencyRateLoop(d.runCtx)
+
+	// Apply the usage database retention policy
+	d.bgWG.Add(1)
+	go d.usageCompactionLoop(d.runCtx)
+
+	// Retry failed webhook deliveries
+	d.bgWG.Add(1)
+	go d.webhookRetryLoop(d.runCtx)
+
+	// Raise budget forecast and certificate expiry alerts
+	d.bgWG.Add(1)
+	go d.alertLoop(d.runCtx)
+
+	// Sleep when unused, if the idle policy is on
+	d.bgWG.Add(1)
+	go d.idleLoop(d.runCtx)
+	d.notifyStart()
+
 	// Start goroutine leak detection monitor
 	d.baselineGoroutines = runtime.NumGoroutine()
 	d.leakCheckTicker = time.NewTicker(1 * time.Minute)
 	d.bgWG.Add(1)
 	go d.goroutineLeakMonitor(d.runCtx)
 
+	// Resolve secret references up front, so bad ones are logged at startup
+	d.checkSecrets()
+
 	// Initialize sync if configured
 	d.initSync()
 
@@ -250,13 +386,24 @@ func (d *Daemon) Start() error {
 	d.initBot()
 
 	d.logger.Printf("zend started: proxy=:%d web=:%d", d.proxyPort, d.webPort)
+	for _, o := range config.GetConfigOverrides() {
+		d.logger.Printf("config override: %s from %s", o.Path, o.Env)
+	}
 
 	// Start web server in a goroutine
 	webErrCh := make(chan error, 1)
 	go func() {
+		if d.inheritedWeb != nil {
+			webErrCh <- d.webServer.Serve(d.inheritedWeb)
+			return
+		}
 		webErrCh <- d.webServer.Start()
 	}()
 
+	// Both listeners are accepting; let the previous daemon start draining
+	notifyHandoffReady(d.handoffReady)
+	d.handoffReady = nil
+
 	// Block until either proxy or web server exits
 	select {
 	case err := <-d.proxyErrCh:
@@ -276,6 +423,9 @@ func (d *Daemon) Start() error {
 		if d.syncCancel != nil {
 			d.syncCancel()
 		}
+		if d.teamCancel != nil {
+			d.teamCancel()
+		}
 		d.pushMu.Lock()
 		if d.pushCtxCancel != nil {
 			d.pushCtxCancel() // Cancel any pending push callbacks
@@ -300,6 +450,11 @@ func (d *Daemon) Start() error {
 			d.profileProxy.Close()
 		}
 
+		// Stop the projects' MCP servers
+		if d.mcp != nil {
+			d.mcp.Close()
+		}
+
 		// Cancel background goroutines
 		d.runCancel()
 
@@ -359,24 +514,43 @@ func (d *Daemon) startProxy() error {
 	// Create profile-based proxy router
 	d.profileProxy = proxy.NewProfileProxy(d.logger)
 	d.profileProxy.TempProfiles = d
-	d.profileProxy.MetricsRecorder = d.metrics
+	d.profileProxy.MetricsRecorder = &alertingRecorder{Metrics: d.metrics, bursts: newFailureBurst()}
+	if checker := proxy.GetGlobalHealthChecker(); checker != nil {
+		// Let synthetic probes take failing providers out of rotation
+		checker.SetBreaker(d.profileProxy)
+	}
 
 	// Daemon API routes on the proxy mux (for internal use)
 	d.proxyMux.HandleFunc("/api/v1/daemon/status", d.handleDaemonStatus)
 	d.proxyMux.HandleFunc("/api/v1/daemon/health", d.handleDaemonHealth)
 	d.proxyMux.HandleFunc("/api/v1/daemon/metrics", d.handleDaemonMetrics)
 	d.proxyMux.HandleFunc("/api/v1/daemon/shutdown", d.handleDaemonShutdown)
+	d.proxyMux.HandleFunc("/api/v1/daemon/restart", d.handleDaemonRestart)
 	d.proxyMux.HandleFunc("/api/v1/daemon/reload", d.handleDaemonReload)
 	d.proxyMux.HandleFunc("/api/v1/daemon/sessions", d.handleDaemonSessions)
 	d.proxyMux.HandleFunc("/api/v1/profiles/temp", d.handleTempProfiles)
 	d.proxyMux.HandleFunc("/api/v1/profiles/temp/", d.handleTempProfile)
+	d.proxyMux.HandleFunc("/api/v1/daemon/mcp", d.handleMCPStatus)
+	d.proxyMux.HandleFunc("/healthz", d.handleHealthz)
+
+	// MCP endpoint serving the tools of the project's MCP servers
+	d.proxyMux.HandleFunc("/mcp", d.trackProxied(d.handleMCP))
 
 	// Default handler: profile-based proxy routing
 	// URL format: /<profile>/<session>/v1/messages
-	d.proxyMux.HandleFunc("/", d.profileProxy.ServeHTTP)
-
-	addr := fmt.Sprintf("127.0.0.1:%d", d.proxyPort)
-	ln, err := net.Listen("tcp", addr)
+	d.proxyMux.HandleFunc("/", d.trackProxied(d.profileProxy.ServeHTTP))
+
+	addr := net.JoinHostPort(config.GetTLS().GetListen(), strconv.Itoa(d.proxyPort))
+	var ln net.Listener
+	var err error
+	if d.inheritedProxy != nil {
+		ln = d.inheritedProxy
+		d.inheritedProxy = nil
+		d.startReason, d.startDetail = "handoff", "took over the listeners of the previous zend"
+		d.logger.Printf("proxy server inherited listener from previous zend")
+	} else {
+		ln, err = net.Listen("tcp", addr)
+	}
 	if err != nil {
 		// Port is busy — use multi-layer detection to identify the process
 		pid, procName, identErr := GetProcessOnPort(d.proxyPort)
@@ -421,6 +595,7 @@ func (d *Daemon) startProxy() error {
 		}
 	}
 
+	d.proxyListener = ln
 	d.proxyServer = &http.Server{
 		Handler:           httpx.Recover(d.logger, "proxy", d.proxyMux),
 		ReadHeaderTimeout: 15 * time.Second,
@@ -428,10 +603,15 @@ func (d *Daemon) startProxy() error {
 		WriteTimeout:      10 * time.Minute,
 		IdleTimeout:       90 * time.Second,
 		MaxHeaderBytes:    1 << 20,
+		ConnState:         d.idleConnState,
 	}
 
+	serveLn := ln
+	if d.tlsConfig != nil {
+		serveLn = httpx.TLSListener(ln, d.tlsConfig)
+	}
 	go func() {
-		if err := d.proxyServer.Serve(ln); err != nil && err != http.ErrServerClosed {
+		if err := d.proxyServer.Serve(serveLn); err != nil && err != http.ErrServerClosed {
 			d.logger.Printf("proxy server error: %v", err)
 			// Send error to channel for crash detection
 			select {
@@ -554,6 +734,7 @@ func isProcessListeningOnPort(pid int, port int) (bool, error) {
 // Shutdown gracefully stops the daemon.
 func (d *Daemon) Shutdown(ctx context.Context) error {
 	d.logger.Println("shutting down zend...")
+	d.stopping.Store(true)
 
 	// Stop bot gateway
 	if d.botGateway != nil {
@@ -567,6 +748,9 @@ func (d *Daemon) Shutdown(ctx context.Context) error {
 	if d.syncCancel != nil {
 		d.syncCancel()
 	}
+	if d.teamCancel != nil {
+		d.teamCancel()
+	}
 	d.pushMu.Lock()
 	if d.pushCtxCancel != nil {
 		d.pushCtxCancel() // Cancel any pending push callbacks
@@ -597,6 +781,9 @@ func (d *Daemon) Shutdown(ctx context.Context) error {
 	if d.profileProxy != nil {
 		d.profileProxy.Close()
 	}
+	if d.mcp != nil {
+		d.mcp.Close()
+	}
 
 	// Shutdown web server
 	if d.webServer != nil {
@@ -605,14 +792,25 @@ func (d *Daemon) Shutdown(ctx context.Context) error {
 		}
 	}
 
-	// Remove PID file
-	os.Remove(DaemonPidPath())
+	// Flush pending trace spans
+	if err := proxy.ShutdownGlobalTracing(ctx); err != nil {
+		d.logger.Printf("tracing shutdown error: %v", err)
+	}
+
+	d.closeAccessLog()
+
+	// Remove PID file unless a successor has already replaced it
+	RemoveOwnDaemonPid()
 
 	// Log daemon_shutdown event with structured logging
+	reason := "graceful_shutdown"
+	if d.draining.Load() {
+		reason = "handoff"
+	}
 	uptime := time.Since(d.startTime)
 	d.structuredLog.Info("daemon_shutdown", map[string]interface{}{
 		"uptime_seconds": uptime.Seconds(),
-		"reason":         "graceful_shutdown",
+		"reason":         reason,
 	})
 
 	d.logger.Println("zend stopped")
@@ -627,18 +825,25 @@ func (d *Daemon) ActiveSessionCount() int {
 }
 
 // RegisterSession registers a new client session.
-func (d *Daemon) RegisterSession(id, profile, client string) {
+func (d *Daemon) RegisterSession(id, profile, client, project string) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
+	proxy.SetSessionProject(sessionKey(profile, id), project)
 	d.sessions[id] = &SessionInfo{
 		ID:        id,
 		Profile:   profile,
 		Client:    client,
+		Project:   project,
 		CreatedAt: time.Now(),
 		LastSeen:  time.Now(),
 	}
 }
 
+// sessionKey returns the key the proxy knows a launcher session by.
+func sessionKey(profile, id string) string {
+	return (&proxy.RouteInfo{Profile: profile, SessionID: id}).CacheKey()
+}
+
 // TouchSession updates the last-seen time for a session.
 func (d *Daemon) TouchSession(id string) {
 	d.mu.Lock()
@@ -652,6 +857,9 @@ func (d *Daemon) TouchSession(id string) {
 func (d *Daemon) RemoveSession(id string) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
+	if s, ok := d.sessions[id]; ok {
+		proxy.SetSessionProject(sessionKey(s.Profile, id), "")
+	}
 	delete(d.sessions, id)
 }
 
@@ -671,6 +879,7 @@ func (d *Daemon) sessionCleanupLoop(ctx context.Context) {
 		for id, s := range d.sessions {
 			// Remove sessions not seen for 2 hours
 			if now.Sub(s.LastSeen) > 2*time.Hour {
+				proxy.SetSessionProject(sessionKey(s.Profile, id), "")
 				delete(d.sessions, id)
 				d.logger.Printf("cleaned up stale session: %s", id)
 			}
@@ -679,6 +888,16 @@ func (d *Daemon) sessionCleanupLoop(ctx context.Context) {
 
 		// Also clean up proxy session cache
 		proxy.CleanupOldSessions(2 * time.Hour)
+
+		// Stop the MCP servers of projects no longer in use
+		if n := d.mcp.Prune(mcpIdleTimeout); n > 0 {
+			d.logger.Printf("stopped the MCP servers of %d idle projects", n)
+		}
+
+		// Sessions going idle end, and get their reports written
+		if obs := agent.GetGlobalObservatory(); obs != nil && obs.IsEnabled() {
+			obs.CheckIdleSessions()
+		}
 	}
 }
 
@@ -726,10 +945,19 @@ func (d *Daemon) logFeatureGateChanges(old, new *config.FeatureGates) {
 func (d *Daemon) onConfigReload() {
 	d.logger.Println("config file changed, reloading...")
 
+	// Wake first, so the reload restarts what sleep paused
+	d.wake("config reload")
+
 	// Capture old feature gates from daemon state (before reload)
 	oldGates := d.currentGates
+	oldMiddleware := config.GetMiddleware()
 
-	config.ResetDefaultStore()
+	// Swap in the new config only if it loads cleanly
+	if err := config.ReloadDefaultStore(); err != nil {
+		d.logger.Printf("config reload failed, keeping previous config: %v", err)
+		d.broadcast(web.EventConfigReload, map[string]string{"status": "error", "error": err.Error()})
+		return
+	}
 
 	// Detect and log feature gate changes
 	newGates := config.GetFeatureGates()
@@ -740,6 +968,21 @@ func (d *Daemon) onConfigReload() {
 	// Update current gates
 	d.currentGates = newGates
 
+	// Apply a changed log level or format; rotation limits apply on restart
+	newLog := config.GetLog()
+	if d.logSink != nil {
+		if newLog.GetLevel() != d.currentLog.GetLevel() {
+			if level, err := ParseLogLevel(newLog.GetLevel()); err == nil {
+				d.logSink.SetLevel(level)
+				d.logger.Printf("log level set to %s", level)
+			}
+		}
+		if newLog.GetFormat() != d.currentLog.GetFormat() {
+			d.logSink.SetFormat(newLog.GetFormat())
+		}
+	}
+	d.currentLog = newLog
+
 	// Protect running ports: revert any port changes to preserve active sessions.
 	// Port changes only take effect on daemon restart.
 	if newProxy := config.GetProxyPort(); newProxy != d.proxyPort {
@@ -756,6 +999,31 @@ func (d *Daemon) onConfigReload() {
 		d.profileProxy.InvalidateCache()
 	}
 
+	// Apply response cache settings
+	proxy.UpdateGlobalResponseCacheConfig(config.GetResponseCache())
+
+	// Apply access log settings
+	d.applyAccessLog()
+
+	// Apply guardrail settings
+	if gr := agent.GetGlobalGuardrails(); gr != nil {
+		var grCfg *config.GuardrailsConfig
+		if a := config.GetAgent(); a != nil {
+			grCfg = a.Guardrails
+		}
+		gr.UpdateConfig(grCfg)
+	}
+
+	// Bring the projects' MCP servers in line with their bindings
+	if d.mcp != nil {
+		d.mcp.Reload()
+	}
+
+	// Apply tracing settings
+	if err := proxy.UpdateGlobalTracingConfig(config.GetTracing()); err != nil {
+		d.logger.Printf("Warning: failed to reload tracing: %v", err)
+	}
+
 	// Reload health checker: stop if disabled, start if enabled
 	if checker := proxy.GetGlobalHealthChecker(); checker != nil {
 		checker.ReloadConfig()
@@ -768,15 +1036,59 @@ func (d *Daemon) onConfigReload() {
 		}
 	}
 
+	// Look secret references up again, they may point elsewhere now
+	config.ClearSecretCache()
+	d.checkSecrets()
+
 	// Reinitialize sync if config changed
 	d.initSync()
 	// Reinitialize bot gateway if config changed
 	d.reinitBot()
+
+	// Rebuild the middleware pipeline if its config changed
+	if !reflect.DeepEqual(oldMiddleware, config.GetMiddleware()) {
+		d.reloadMiddleware()
+	}
+
 	d.logger.Println("config reloaded successfully")
+	d.broadcast(web.EventConfigReload, map[string]string{"status": "ok"})
+}
+
+// reloadMiddleware reloads the middleware pipeline from config, picking up
+// changed plugin files.
+func (d *Daemon) reloadMiddleware() {
+	registry := middleware.GetGlobalRegistry()
+	if registry == nil {
+		return
+	}
+	if err := registry.Reload(); err != nil {
+		d.logger.Printf("Warning: failed to reload middleware: %v", err)
+	}
+}
+
+// broadcast sends an event to the connected Web UIs.
+func (d *Daemon) broadcast(event string, data interface{}) {
+	if d.webServer != nil {
+		d.webServer.Broadcast(event, data)
+	}
+}
+
+// checkSecrets resolves the secret references in the config, warming the
+// secret cache, and logs those that fail.
+func (d *Daemon) checkSecrets() {
+	refs := config.GetSecretRefs()
+	failed := config.CheckSecretRefs(refs)
+	for _, ref := range refs {
+		if err := failed[ref.Field]; err != nil {
+			d.logger.Printf("Warning: %s: %v", ref.Field, err)
+		}
+	}
 }
 
 // initSync initializes or reinitializes the sync manager from current config.
 func (d *Daemon) initSync() {
+	d.initTeamSync()
+
 	// Stop existing auto-pull
 	if d.syncCancel != nil {
 		d.syncCancel()
@@ -816,46 +1128,23 @@ func (d *Daemon) initSync() {
 		d.webServer.SetSyncManager(mgr)
 	}
 
-	// Create new context for push timer callbacks
-	d.pushMu.Lock()
-	d.pushCtx, d.pushCtxCancel = context.WithCancel(context.Background())
-	pushCtx := d.pushCtx // capture for closure
-	d.pushMu.Unlock()
-
-	// Register auto-push hook (debounced)
-	store := config.DefaultStore()
-	store.SetOnSave(func() {
-		if mgr.IsPulling() {
-			return
-		}
+	// Auto-push: push local changes once they settle. Saves made by this
+	// process trigger it directly; edits by other processes arrive through
+	// a config reload, which reinitializes sync and lands here again.
+	if cfg.AutoPush {
 		d.pushMu.Lock()
-		if d.pushTimer != nil {
-			d.pushTimer.Stop()
-		}
-		d.pushTimer = time.AfterFunc(2*time.Second, func() {
-			// Check if context is still valid (not cancelled)
-			select {
-			case <-pushCtx.Done():
-				// Context cancelled, don't execute push
-				return
-			default:
-			}
-
-			// Use pushCtx as parent so cancellation propagates to mgr.Push()
-			ctx, cancel := context.WithTimeout(pushCtx, 30*time.Second)
-			defer cancel()
-			if err := mgr.Push(ctx); err != nil {
-				// Ignore context cancelled errors (expected during shutdown)
-				if ctx.Err() == context.Canceled {
-					return
-				}
-				d.logger.Printf("sync auto-push failed: %v", err)
-			} else {
-				d.logger.Println("sync auto-push completed")
-			}
-		})
+		d.pushCtx, d.pushCtxCancel = context.WithCancel(context.Background())
+		pushCtx := d.pushCtx // capture for closure
 		d.pushMu.Unlock()
-	})
+
+		debounce := cfg.GetPushDebounce()
+		schedule := func() { d.scheduleAutoPush(mgr, pushCtx, debounce) }
+		config.DefaultStore().SetOnSave(schedule)
+		schedule()
+		d.logger.Printf("sync auto-push enabled (debounce: %s)", debounce)
+	} else {
+		config.DefaultStore().SetOnSave(nil)
+	}
 
 	// Start auto-pull ticker if enabled
 	if cfg.AutoPull {
@@ -888,6 +1177,90 @@ func (d *Daemon) initSync() {
 	d.logger.Printf("sync initialized (backend: %s)", cfg.Backend)
 }
 
+// initTeamSync starts pulling the team layer every pull_interval when the
+// team source has auto_pull set. A team layer that was never pulled is
+// pulled right away.
+func (d *Daemon) initTeamSync() {
+	if d.teamCancel != nil {
+		d.teamCancel()
+		d.teamCancel = nil
+	}
+	cfg := config.DefaultStore().GetTeamSource()
+	if cfg == nil || cfg.Backend == "" || !cfg.AutoPull {
+		return
+	}
+	interval := time.Duration(cfg.PullInterval) * time.Second
+	if interval < 60*time.Second {
+		interval = 5 * time.Minute // default 5 min
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	d.teamCancel = cancel
+	pull := func() {
+		pullCtx, pullCancel := context.WithTimeout(ctx, 30*time.Second)
+		defer pullCancel()
+		if _, err := gosync.PullTeam(pullCtx, cfg); err != nil && ctx.Err() == nil {
+			d.logger.Printf("team auto-pull failed: %v", err)
+		}
+	}
+	loaded := config.DefaultStore().TeamStatus().Loaded
+	go func() {
+		if !loaded {
+			pull()
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				pull()
+			}
+		}
+	}()
+	d.logger.Printf("team auto-pull enabled (backend: %s, interval: %s)", cfg.Backend, interval)
+}
+
+// scheduleAutoPush pushes the local config after debounce, restarting the
+// window if called again first. Configs that are unchanged since the last
+// pull or push, such as the saves made by a pull, are not pushed.
+func (d *Daemon) scheduleAutoPush(mgr *gosync.SyncManager, pushCtx context.Context, debounce time.Duration) {
+	if mgr.IsPulling() {
+		return
+	}
+	d.pushMu.Lock()
+	defer d.pushMu.Unlock()
+	if d.pushTimer != nil {
+		d.pushTimer.Stop()
+	}
+	mgr.SetPushPending(true)
+	d.pushTimer = time.AfterFunc(debounce, func() {
+		defer mgr.SetPushPending(false)
+		// Check if context is still valid (not cancelled)
+		select {
+		case <-pushCtx.Done():
+			// Context cancelled, don't execute push
+			return
+		default:
+		}
+
+		// Use pushCtx as parent so cancellation propagates to the push
+		ctx, cancel := context.WithTimeout(pushCtx, 30*time.Second)
+		defer cancel()
+		pushed, err := mgr.PushIfChanged(ctx)
+		switch {
+		case err != nil:
+			// Ignore context cancelled errors (expected during shutdown)
+			if ctx.Err() == context.Canceled {
+				return
+			}
+			d.logger.Printf("sync auto-push failed: %v", err)
+		case pushed:
+			d.logger.Println("sync auto-push completed")
+		}
+	})
+}
+
 // --- Temporary Profiles ---
 
 // RegisterTempProfile creates a temporary profile and returns its ID.
@@ -987,7 +1360,7 @@ func (d *Daemon) initBot() {
 					AllowedUsers: cfg.Platforms.Telegram.AllowedUsers,
 					AllowedChats: cfg.Platforms.Telegram.AllowedChats,
 				},
-				Token: cfg.Platforms.Telegram.Token,
+				Token: config.SecretValue(cfg.Platforms.Telegram.Token),
 			}
 		}
 
@@ -998,7 +1371,7 @@ func (d *Daemon) initBot() {
 					AllowedUsers:    cfg.Platforms.Discord.AllowedUsers,
 					AllowedChannels: cfg.Platforms.Discord.AllowedChannels,
 				},
-				Token:         cfg.Platforms.Discord.Token,
+				Token:         config.SecretValue(cfg.Platforms.Discord.Token),
 				AllowedGuilds: cfg.Platforms.Discord.AllowedGuilds,
 			}
 		}
@@ -1010,8 +1383,8 @@ func (d *Daemon) initBot() {
 					AllowedUsers:    cfg.Platforms.Slack.AllowedUsers,
 					AllowedChannels: cfg.Platforms.Slack.AllowedChannels,
 				},
-				BotToken: cfg.Platforms.Slack.BotToken,
-				AppToken: cfg.Platforms.Slack.AppToken,
+				BotToken: config.SecretValue(cfg.Platforms.Slack.BotToken),
+				AppToken: config.SecretValue(cfg.Platforms.Slack.AppToken),
 			}
 		}
 
@@ -1023,7 +1396,7 @@ func (d *Daemon) initBot() {
 					AllowedChats: cfg.Platforms.Lark.AllowedChats,
 				},
 				AppID:     cfg.Platforms.Lark.AppID,
-				AppSecret: cfg.Platforms.Lark.AppSecret,
+				AppSecret: config.SecretValue(cfg.Platforms.Lark.AppSecret),
 			}
 		}
 
@@ -1033,9 +1406,9 @@ func (d *Daemon) initBot() {
 					Enabled:      cfg.Platforms.FBMessenger.Enabled,
 					AllowedUsers: cfg.Platforms.FBMessenger.AllowedUsers,
 				},
-				PageToken:   cfg.Platforms.FBMessenger.PageToken,
-				VerifyToken: cfg.Platforms.FBMessenger.VerifyToken,
-				AppSecret:   cfg.Platforms.FBMessenger.AppSecret,
+				PageToken:   config.SecretValue(cfg.Platforms.FBMessenger.PageToken),
+				VerifyToken: config.SecretValue(cfg.Platforms.FBMessenger.VerifyToken),
+				AppSecret:   config.SecretValue(cfg.Platforms.FBMessenger.AppSecret),
 			}
 		}
 	}
@@ -1139,3 +1512,158 @@ func (d *Daemon) goroutineLeakMonitor(ctx context.Context) {
 		}
 	}
 }
+
+// observeAgentActivity passes what a proxied request shows of an agent
+// session's work on to the observatory.
+func observeAgentActivity(a proxy.AgentActivity) {
+	obs := agent.GetGlobalObservatory()
+	if obs == nil || !obs.IsEnabled() {
+		return
+	}
+	activity := agent.Activity{
+		SessionID:   a.SessionID,
+		Client:      a.ClientType,
+		ProjectPath: a.ProjectPath,
+		Timestamp:   a.Timestamp,
+		Tokens:      a.InputTokens + a.OutputTokens,
+		Cost:        a.Cost,
+	}
+	for _, c := range a.ToolCalls {
+		activity.ToolCalls = append(activity.ToolCalls, agent.ToolCall{ID: c.ID, Name: c.Name, Input: c.Input})
+	}
+	for _, r := range a.ToolResults {
+		activity.ToolResults = append(activity.ToolResults, agent.ToolResult{ToolUseID: r.ToolUseID, IsError: r.IsError, Output: r.Output})
+	}
+	if c := a.Compression; c != nil {
+		activity.Compression = &agent.Compression{BytesBefore: c.BytesBefore, BytesAfter: c.BytesAfter}
+	}
+	obs.RecordActivity(activity)
+}
+
+// onAgentActivity feeds what a proxied request shows of an agent session
+// to the observatory, the coordinator and the guardrails.
+func (d *Daemon) onAgentActivity(a proxy.AgentActivity) {
+	observeAgentActivity(a)
+	recordAgentChanges(a)
+	d.checkSpendVelocity(a)
+}
+
+// recordAgentChanges records the file changes of the edit tool calls whose
+// results a request carries.
+func recordAgentChanges(a proxy.AgentActivity) {
+	coord := agent.GetGlobalCoordinator()
+	if coord == nil {
+		return
+	}
+	for _, r := range a.ToolResults {
+		coord.FinishEdit(a.SessionID, agent.ToolResult{ToolUseID: r.ToolUseID, IsError: r.IsError})
+	}
+}
+
+// checkSpendVelocity records a session's spending with the guardrails. When
+// the session spends far faster than it usually does, it raises spend_spike,
+// tells the bot's notification chat and, with auto_pause_on_cap, pauses the
+// session.
+func (d *Daemon) checkSpendVelocity(a proxy.AgentActivity) {
+	gr := agent.GetGlobalGuardrails()
+	if gr == nil {
+		return
+	}
+	anomaly := gr.RecordUsage(a.SessionID, a.Cost, a.InputTokens+a.OutputTokens, a.Timestamp)
+	if anomaly == nil {
+		return
+	}
+	paused := false
+	if gr.GetConfig().AutoPauseOnCap {
+		if obs := agent.GetGlobalObservatory(); obs != nil {
+			paused = obs.PauseSession(a.SessionID)
+		}
+	}
+	d.logger.Printf("[guardrails] session %s spending spiked (%s): $%.4f/min and %.0f tokens/min against $%.4f/min and %.0f tokens/min, paused: %v",
+		a.SessionID, anomaly.Reason, anomaly.CostRate, anomaly.TokenRate, anomaly.BaselineCostRate, anomaly.BaselineTokenRate, paused)
+
+	data := &notify.SpendSpikeData{
+		SessionID:         a.SessionID,
+		Project:           a.ProjectPath,
+		CostRate:          anomaly.CostRate,
+		BaselineCostRate:  anomaly.BaselineCostRate,
+		TokenRate:         anomaly.TokenRate,
+		BaselineTokenRate: anomaly.BaselineTokenRate,
+		Multiple:          anomaly.Multiple,
+		Paused:            paused,
+	}
+	notify.NotifySpendSpike(data)
+	if gw := d.botGateway; gw != nil {
+		gw.Notify(bot.NotifyWarning, "Spend Spike", data.Text())
+	}
+}
+
+// checkToolCall rules on a tool call before the client sees it. The files
+// an edit that goes ahead changes are snapshotted, so the change can be
+// reviewed once the client reports the call done.
+func (d *Daemon) checkToolCall(ctx context.Context, c proxy.ToolCallCheck) proxy.ToolCallVerdict {
+	v := d.ruleOnToolCall(ctx, c)
+	if !v.Blocked {
+		if coord := agent.GetGlobalCoordinator(); coord != nil {
+			coord.WatchEdit(c.SessionID, c.ProjectPath, agent.ToolCall{ID: c.ID, Name: c.Name, Input: c.Input})
+		}
+	}
+	return v
+}
+
+// ruleOnToolCall rules on a tool call with the guardrail policy. A call an
+// "ask" rule matches waits for approval through the bot gateway; without a
+// gateway, or when nobody answers in time, it is refused.
+func (d *Daemon) ruleOnToolCall(ctx context.Context, c proxy.ToolCallCheck) proxy.ToolCallVerdict {
+	gr := agent.GetGlobalGuardrails()
+	if gr == nil {
+		return proxy.ToolCallVerdict{}
+	}
+	dec := gr.CheckToolCall(c.SessionID, c.ProjectPath, agent.ToolCall{ID: c.ID, Name: c.Name, Input: c.Input})
+	if dec == nil || dec.Action == config.GuardrailLog {
+		return proxy.ToolCallVerdict{}
+	}
+	reason := dec.Reason + " (rule " + dec.Rule + ")"
+	if dec.Action == config.GuardrailBlock {
+		return proxy.ToolCallVerdict{Blocked: true, Reason: reason}
+	}
+
+	gw := d.botGateway
+	if gw == nil {
+		return proxy.ToolCallVerdict{Blocked: true, Reason: reason + ", and no approval channel is configured"}
+	}
+	timeout := gr.GetConfig().ApprovalTimeoutSec
+	if timeout <= 0 {
+		timeout = 300
+	}
+	id := c.ID
+	if id == "" {
+		id = fmt.Sprintf("tool-%d", time.Now().UnixNano())
+	}
+	description := fmt.Sprintf("Guardrail %q: %s.", dec.Rule, dec.Reason)
+	if c.SessionID != "" {
+		description += "\nSession: " + c.SessionID
+	}
+	if c.ProjectPath != "" {
+		description += "\nProject: " + c.ProjectPath
+	}
+	details := string(c.Input)
+	if len(details) > 1000 {
+		details = strings.ToValidUTF8(details[:1000], "") + "…"
+	}
+	resp, err := gw.RequestApproval(ctx, "GoZen guardrails", bot.ApprovalPayload{
+		ID:          "guardrail_" + id,
+		Action:      "Run " + c.Name,
+		Description: description,
+		Details:     details,
+		Timeout:     timeout,
+	})
+	switch {
+	case err != nil:
+		d.logger.Printf("[guardrails] %s tool call refused: approval failed: %v", c.Name, err)
+		return proxy.ToolCallVerdict{Blocked: true, Reason: reason + ", and it was not approved: " + err.Error()}
+	case !resp.Approved:
+		return proxy.ToolCallVerdict{Blocked: true, Reason: reason + ", and the user rejected it"}
+	}
+	return proxy.ToolCallVerdict{}
+}
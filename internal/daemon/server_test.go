@@ -3,6 +3,7 @@ package daemon
 import (
 	"context"
 	"encoding/json"
+	"io"
 	"log"
 	"net"
 	"net/http"
@@ -14,6 +15,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/dopejs/gozen/internal/agent"
 	"github.com/dopejs/gozen/internal/config"
 	"github.com/dopejs/gozen/internal/proxy"
 )
@@ -39,11 +41,14 @@ func TestDaemonStatusAPI(t *testing.T) {
 	if resp.Version != "test" {
 		t.Errorf("version = %q, want test", resp.Version)
 	}
+	if resp.Bot == nil || resp.Bot.Running {
+		t.Errorf("bot = %+v, want not running", resp.Bot)
+	}
 }
 
 func TestDaemonHealthAPI(t *testing.T) {
 	d := newTestDaemon()
-	d.RegisterSession("s1", "default", "claude")
+	d.RegisterSession("s1", "default", "claude", "")
 
 	w := httptest.NewRecorder()
 	r := httptest.NewRequest("GET", "/api/v1/daemon/health", nil)
@@ -83,8 +88,8 @@ func TestDaemonStatusAPIMethodNotAllowed(t *testing.T) {
 
 func TestDaemonStatusWithSessions(t *testing.T) {
 	d := newTestDaemon()
-	d.RegisterSession("s1", "default", "claude")
-	d.RegisterSession("s2", "work", "codex")
+	d.RegisterSession("s1", "default", "claude", "")
+	d.RegisterSession("s2", "work", "codex", "")
 
 	w := httptest.NewRecorder()
 	r := httptest.NewRequest("GET", "/api/v1/daemon/status", nil)
@@ -107,7 +112,7 @@ func TestDaemonSessionsAPI(t *testing.T) {
 	d := newTestDaemon()
 
 	// Register a session
-	d.RegisterSession("abc123", "default", "claude")
+	d.RegisterSession("abc123", "default", "claude", "")
 
 	w := httptest.NewRecorder()
 	r := httptest.NewRequest("GET", "/api/v1/daemon/sessions", nil)
@@ -281,8 +286,8 @@ func TestSessionManagement(t *testing.T) {
 		t.Fatalf("expected 0 sessions, got %d", d.ActiveSessionCount())
 	}
 
-	d.RegisterSession("s1", "default", "claude")
-	d.RegisterSession("s2", "work", "codex")
+	d.RegisterSession("s1", "default", "claude", "")
+	d.RegisterSession("s2", "work", "codex", "")
 
 	if d.ActiveSessionCount() != 2 {
 		t.Fatalf("expected 2 sessions, got %d", d.ActiveSessionCount())
@@ -427,37 +432,34 @@ func TestIsDaemonPortListening(t *testing.T) {
 func TestConfigWatcher(t *testing.T) {
 	dir := t.TempDir()
 	t.Setenv("HOME", dir)
+	config.ResetDefaultStore()
+	t.Cleanup(func() { config.ResetDefaultStore() })
 
 	zenDir := filepath.Join(dir, ".zen")
 	os.MkdirAll(zenDir, 0755)
 	configPath := filepath.Join(zenDir, "zen.json")
 	os.WriteFile(configPath, []byte(`{}`), 0600)
+	config.GetMiddleware() // load, and migrate, the config before watching it
 
-	reloaded := make(chan struct{}, 1)
+	reloads := 0
 	logger := log.New(os.Stderr, "[test] ", 0)
-	w := NewConfigWatcher(logger, func() {
-		select {
-		case reloaded <- struct{}{}:
-		default:
-		}
-	})
+	w := NewConfigWatcher(logger, func() { reloads++ })
+	w.debounce = 20 * time.Millisecond
+	w.scanPlugins()
 
-	// Start watcher in background
-	go w.Start()
-	defer w.Stop()
+	// Touch the config file
+	mtime := time.Now().Add(time.Second)
+	os.Chtimes(configPath, mtime, mtime)
 
-	// Modify the config file
-	time.Sleep(50 * time.Millisecond)
-	os.WriteFile(configPath, []byte(`{"version":7}`), 0600)
-
-	// Manually trigger check (don't wait for ticker)
+	// The change is seen, then reloaded once it settles
 	w.check()
-
-	select {
-	case <-reloaded:
-		// OK
-	case <-time.After(time.Second):
-		t.Error("expected reload callback after config change")
+	if reloads != 0 {
+		t.Fatalf("reloaded before the debounce interval")
+	}
+	time.Sleep(30 * time.Millisecond)
+	w.check()
+	if reloads != 1 {
+		t.Errorf("reloads = %d, want 1 after config change", reloads)
 	}
 }
 
@@ -670,10 +672,10 @@ func TestSessionCleanupRemovesStale(t *testing.T) {
 	d := newTestDaemon()
 
 	// Add a fresh session
-	d.RegisterSession("fresh", "default", "claude")
+	d.RegisterSession("fresh", "default", "claude", "")
 
 	// Add a stale session (manually set LastSeen to past)
-	d.RegisterSession("stale", "default", "claude")
+	d.RegisterSession("stale", "default", "claude", "")
 	d.mu.Lock()
 	d.sessions["stale"].LastSeen = time.Now().Add(-3 * time.Hour)
 	d.mu.Unlock()
@@ -1212,3 +1214,161 @@ func TestOnConfigReloadDetectsFeatureGateChanges(t *testing.T) {
 		})
 	}
 }
+
+func TestDaemonRestartAPI(t *testing.T) {
+	d := newTestDaemon()
+
+	w := httptest.NewRecorder()
+	d.handleDaemonRestart(w, httptest.NewRequest("GET", "/api/v1/daemon/restart", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET: expected 405, got %d", w.Code)
+	}
+
+	// Without listeners there is nothing to hand off; drain mode is not entered
+	w = httptest.NewRecorder()
+	d.handleDaemonRestart(w, httptest.NewRequest("POST", "/api/v1/daemon/restart", strings.NewReader(`{"drain_timeout_secs":60}`)))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("no listeners: expected 500, got %d", w.Code)
+	}
+	if d.draining.Load() {
+		t.Error("expected draining to be reset after a failed handoff")
+	}
+	if d.DrainTimeout() != defaultDrainTimeout {
+		t.Errorf("DrainTimeout = %s, want default after a failed handoff", d.DrainTimeout())
+	}
+
+	d.draining.Store(true)
+	w = httptest.NewRecorder()
+	d.handleDaemonRestart(w, httptest.NewRequest("POST", "/api/v1/daemon/restart", nil))
+	if w.Code != http.StatusConflict {
+		t.Errorf("while draining: expected 409, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	d.handleDaemonStatus(w, httptest.NewRequest("GET", "/api/v1/daemon/status", nil))
+	var resp daemonStatusResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != "draining" {
+		t.Errorf("status = %q, want draining", resp.Status)
+	}
+}
+
+func TestRemoveOwnDaemonPid(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+
+	// A successor's PID is left in place
+	WriteDaemonPid(os.Getpid() + 1)
+	RemoveOwnDaemonPid()
+	if _, err := ReadDaemonPid(); err != nil {
+		t.Error("expected another process's PID file to be kept")
+	}
+
+	WriteDaemonPid(os.Getpid())
+	RemoveOwnDaemonPid()
+	if _, err := ReadDaemonPid(); err == nil {
+		t.Error("expected own PID file to be removed")
+	}
+}
+
+// Test that a config file that fails to load doesn't replace the running config.
+func TestDaemonOnConfigReloadKeepsConfigOnError(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+	config.ResetDefaultStore()
+	t.Cleanup(func() { config.ResetDefaultStore() })
+
+	config.SetProvider("p", &config.ProviderConfig{BaseURL: "https://api.example.com", AuthToken: "k"})
+	d := newTestDaemon()
+	d.proxyPort = config.GetProxyPort()
+	d.webPort = config.GetWebPort()
+
+	path := filepath.Join(tmpDir, config.ConfigDir, config.ConfigFile)
+	os.WriteFile(path, []byte(`{"providers": {`), 0600)
+	future := time.Now().Add(time.Second)
+	os.Chtimes(path, future, future)
+	d.onConfigReload()
+
+	if config.GetProvider("p") == nil {
+		t.Error("a truncated config file should not replace the running config")
+	}
+}
+
+func TestConfigWatcherDebounce(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+	config.ResetDefaultStore()
+	t.Cleanup(func() { config.ResetDefaultStore() })
+
+	plugin := filepath.Join(tmpDir, "plugin.js")
+	os.WriteFile(plugin, []byte("// v1"), 0644)
+	config.SetMiddleware(&config.MiddlewareConfig{Middlewares: []*config.MiddlewareEntry{{Name: "p", Source: "script", Path: plugin}}})
+
+	reloads, pluginChanges := 0, 0
+	w := NewConfigWatcher(log.New(io.Discard, "", 0), func() { reloads++ })
+	w.OnPluginChange(func() { pluginChanges++ })
+	w.debounce = 50 * time.Millisecond
+	w.scanPlugins()
+
+	// A burst of writes triggers one reload once the file settles
+	path := filepath.Join(tmpDir, config.ConfigDir, config.ConfigFile)
+	for i := 1; i <= 3; i++ {
+		mtime := time.Now().Add(time.Duration(i) * time.Second)
+		os.Chtimes(path, mtime, mtime)
+		w.check()
+	}
+	if reloads != 0 {
+		t.Fatalf("reloaded %d times before the debounce interval", reloads)
+	}
+	time.Sleep(60 * time.Millisecond)
+	w.check()
+	w.check()
+	if reloads != 1 {
+		t.Errorf("reloads = %d, want 1", reloads)
+	}
+
+	mtime := time.Now().Add(time.Minute)
+	os.Chtimes(plugin, mtime, mtime)
+	w.check()
+	time.Sleep(60 * time.Millisecond)
+	w.check()
+	if pluginChanges != 1 || reloads != 1 {
+		t.Errorf("after plugin edit: pluginChanges = %d, reloads = %d; want 1, 1", pluginChanges, reloads)
+	}
+}
+
+func TestCheckToolCall(t *testing.T) {
+	agent.InitGlobalGuardrails()
+	gr := agent.GetGlobalGuardrails()
+	prev := gr.GetConfig()
+	gr.UpdateConfig(&config.GuardrailsConfig{
+		Enabled: true,
+		Policy: []*config.GuardrailRule{
+			{Name: "no-sudo", Action: config.GuardrailBlock, DenyCommands: []string{`^sudo `}},
+			{Name: "push", Action: config.GuardrailAsk, ProtectedBranches: []string{"main"}},
+			{Name: "curl", Action: config.GuardrailLog, DenyCommands: []string{`curl`}},
+		},
+	})
+	t.Cleanup(func() { gr.UpdateConfig(prev) })
+
+	d := newTestDaemon()
+	check := func(command string) proxy.ToolCallVerdict {
+		input, _ := json.Marshal(map[string]string{"command": command})
+		return d.checkToolCall(context.Background(), proxy.ToolCallCheck{SessionID: "s1", ID: "t1", Name: "Bash", Input: input})
+	}
+
+	if v := check("sudo rm -rf /"); !v.Blocked || !strings.Contains(v.Reason, "rule no-sudo") {
+		t.Errorf("block rule: %+v", v)
+	}
+	if v := check("git push origin main"); !v.Blocked || !strings.Contains(v.Reason, "no approval channel") {
+		t.Errorf("ask rule without a bot gateway: %+v", v)
+	}
+	if v := check("curl https://example.com"); v.Blocked {
+		t.Errorf("log rule blocked the call: %+v", v)
+	}
+	if v := check("ls"); v.Blocked {
+		t.Errorf("unmatched call blocked: %+v", v)
+	}
+}
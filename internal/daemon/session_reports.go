@@ -0,0 +1,79 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dopejs/gozen/internal/agent"
+	"github.com/dopejs/gozen/internal/bot"
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// reportAgentSession stores the report of an agent session that has gone
+// idle or been killed and, with report_bot, posts it to the bot's
+// notification chat. A session that comes back and ends again is
+// reported again, replacing the earlier report.
+func (d *Daemon) reportAgentSession(id string) {
+	report := agent.ReportSession(id)
+	if report == nil || report.Requests == 0 {
+		return
+	}
+	if err := agent.SaveSessionReport(report); err != nil {
+		d.logger.Printf("[observatory] saving report of session %s failed: %v", id, err)
+	}
+	cfg := config.GetAgent()
+	if gw := d.botGateway; gw != nil && cfg != nil && cfg.Observatory != nil && cfg.Observatory.ReportBot {
+		gw.Notify(bot.NotifyInfo, "Agent Session Report", sessionReportText(report, config.GetCurrency()))
+	}
+}
+
+// sessionReportText formats a session report for chat.
+func sessionReportText(r *agent.SessionReport, currency *config.CurrencyConfig) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Session %s", r.SessionID)
+	if r.ProjectPath != "" {
+		fmt.Fprintf(&b, " in %s", r.ProjectPath)
+	}
+	fmt.Fprintf(&b, " is %s after %s: %d requests, %d tokens, %s",
+		r.Status, r.LastActivity.Sub(r.StartTime).Round(time.Minute), r.Requests, r.Tokens,
+		config.FormatAmount(currency.GetCode(), currency.FromUSD(r.Cost)))
+
+	for _, t := range r.Tasks {
+		fmt.Fprintf(&b, "\n• Task: %s", t.Description)
+		if t.Status != "" {
+			fmt.Fprintf(&b, " (%s)", t.Status)
+		}
+	}
+	if len(r.Files) > 0 {
+		names := make([]string, 0, 5)
+		for _, f := range r.Files[:min(len(r.Files), 5)] {
+			names = append(names, f.Path)
+		}
+		if len(r.Files) > 5 {
+			names = append(names, fmt.Sprintf("and %d more", len(r.Files)-5))
+		}
+		fmt.Fprintf(&b, "\n• Files touched: %d (%s)", len(r.Files), strings.Join(names, ", "))
+	}
+	if len(r.Tests) > 0 {
+		failed := 0
+		for _, t := range r.Tests {
+			if t.Failed {
+				failed++
+			}
+		}
+		last := r.Tests[len(r.Tests)-1]
+		outcome := "passed"
+		if last.Failed {
+			outcome = "failed"
+		}
+		fmt.Fprintf(&b, "\n• Tests: %d runs, %d failed; the last one %s", len(r.Tests), failed, outcome)
+	}
+	if r.ToolCalls > 0 {
+		fmt.Fprintf(&b, "\n• Tool calls: %d, %d failed", r.ToolCalls, r.FailedToolCalls)
+	}
+	if len(r.Errors) > 0 {
+		fmt.Fprintf(&b, "\n• Errors: %d, the last: %s", len(r.Errors), r.Errors[len(r.Errors)-1])
+	}
+	return b.String()
+}
@@ -0,0 +1,39 @@
+package daemon
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dopejs/gozen/internal/agent"
+)
+
+func TestSessionReportText(t *testing.T) {
+	start := time.Date(2026, 3, 7, 10, 0, 0, 0, time.UTC)
+	text := sessionReportText(&agent.SessionReport{
+		SessionID:    "s1",
+		ProjectPath:  "/work/api",
+		Status:       agent.SessionStatusIdle,
+		StartTime:    start,
+		LastActivity: start.Add(42 * time.Minute),
+		Requests:     12,
+		Tokens:       34000,
+		Cost:         1.5,
+		Tasks:        []agent.ReportTask{{Description: "fix the parser", Status: "completed", Source: "queue"}},
+		Files:        []agent.ReportFile{{Path: "/work/api/a.go"}, {Path: "/work/api/b.go"}},
+		Tests:        []agent.ReportTest{{Command: "go test ./...", Failed: true}, {Command: "go test ./..."}},
+		Errors:       []string{"upstream timeout"},
+	}, nil)
+
+	for _, want := range []string{
+		"Session s1 in /work/api is idle after 42m0s: 12 requests, 34000 tokens, $1.50",
+		"• Task: fix the parser (completed)",
+		"• Files touched: 2 (/work/api/a.go, /work/api/b.go)",
+		"• Tests: 2 runs, 1 failed; the last one passed",
+		"• Errors: 1, the last: upstream timeout",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("report text lacks %q:\n%s", want, text)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package daemon
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/httpx"
+)
+
+// TLSDir holds the generated self-signed certificate, under the config dir.
+const TLSDir = "tls"
+
+type daemonTLSStatus struct {
+	Listen      string `json:"listen"`
+	SelfSigned  bool   `json:"self_signed"`
+	Fingerprint string `json:"fingerprint"` // SHA-256 of the certificate
+}
+
+// loadTLS loads the certificate the listeners serve HTTPS with, generating a
+// self-signed one when no cert_file is configured. It returns nil when TLS
+// is off.
+func loadTLS(cfg *config.TLSConfig) (*tls.Config, *daemonTLSStatus, error) {
+	if !cfg.IsEnabled() {
+		return nil, nil, nil
+	}
+	status := &daemonTLSStatus{Listen: cfg.GetListen()}
+
+	var cert tls.Certificate
+	var err error
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err = tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("load TLS certificate: %w", err)
+		}
+	} else {
+		hosts := append([]string{}, cfg.Hosts...)
+		if name, err := os.Hostname(); err == nil && name != "" {
+			hosts = append(hosts, name)
+		}
+		hosts = append(hosts, localIPs()...)
+		cert, err = httpx.SelfSignedCertificate(filepath.Join(config.ConfigDirPath(), TLSDir), hosts)
+		if err != nil {
+			return nil, nil, fmt.Errorf("generate self-signed certificate: %w", err)
+		}
+		status.SelfSigned = true
+	}
+	status.Fingerprint = httpx.Fingerprint(cert)
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}, status, nil
+}
+
+// localIPs returns this machine's non-loopback interface addresses, which
+// LAN clients connect to.
+func localIPs() []string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil
+	}
+	var ips []string
+	for _, a := range addrs {
+		if ipnet, ok := a.(*net.IPNet); ok && !ipnet.IP.IsLoopback() && !ipnet.IP.IsLinkLocalUnicast() {
+			ips = append(ips, ipnet.IP.String())
+		}
+	}
+	return ips
+}
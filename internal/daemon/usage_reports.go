@@ -0,0 +1,122 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dopejs/gozen/internal/bot"
+	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/notify"
+	"github.com/dopejs/gozen/internal/proxy"
+)
+
+// usageReport is a scheduled summary of usage in [since, until).
+type usageReport struct {
+	event config.WebhookEvent
+	since time.Time
+	until time.Time
+}
+
+// dueUsageReports returns the reports due at now, skipping any already
+// recorded in sent (keyed by event, holding the date each was last sent).
+// Reports cover whole UTC days ending at the start of today: the previous
+// day, and on Sundays the previous seven days.
+func dueUsageReports(cfg *config.UsageReportConfig, now time.Time, sent map[config.WebhookEvent]string) []usageReport {
+	now = now.UTC()
+	if cfg == nil || now.Hour() != cfg.HourUTC {
+		return nil
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	date := today.Format("2006-01-02")
+
+	var due []usageReport
+	if cfg.Daily && sent[config.WebhookEventDailySummary] != date {
+		due = append(due, usageReport{config.WebhookEventDailySummary, today.AddDate(0, 0, -1), today})
+	}
+	if cfg.Weekly && now.Weekday() == time.Sunday && sent[config.WebhookEventWeeklySummary] != date {
+		due = append(due, usageReport{config.WebhookEventWeeklySummary, today.AddDate(0, 0, -7), today})
+	}
+	return due
+}
+
+// usageReportLoop sends the scheduled usage summaries configured under
+// usage_reports.
+func (d *Daemon) usageReportLoop(ctx context.Context) {
+	defer d.bgWG.Done()
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	sent := make(map[config.WebhookEvent]string)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		cfg := config.GetUsageReports()
+		now := time.Now().UTC()
+		for _, r := range dueUsageReports(cfg, now, sent) {
+			sent[r.event] = now.Format("2006-01-02")
+			if err := d.sendUsageReport(cfg, r); err != nil {
+				d.logger.Printf("usage report %s failed: %v", r.event, err)
+			}
+		}
+	}
+}
+
+// sendUsageReport summarizes the report's period and sends it to webhooks
+// and, if configured, the bot.
+func (d *Daemon) sendUsageReport(cfg *config.UsageReportConfig, r usageReport) error {
+	tracker := proxy.GetGlobalUsageTracker()
+	if tracker == nil {
+		return fmt.Errorf("usage tracking not initialized")
+	}
+	summary, err := tracker.GetSummaryByTimeRange(r.since, r.until, "")
+	if err != nil {
+		return err
+	}
+	summary.ConvertCurrency(config.GetCurrency())
+
+	data := &notify.DailySummaryData{
+		Date:          r.since.Format("2006-01-02"),
+		TotalCost:     summary.TotalCost,
+		TotalRequests: summary.RequestCount,
+		TotalInput:    summary.TotalInputTokens,
+		TotalOutput:   summary.TotalOutputTokens,
+		ByProvider:    make(map[string]float64, len(summary.ByProvider)),
+		ByModel:       make(map[string]float64, len(summary.ByModel)),
+		Currency:      summary.Currency,
+	}
+	if r.event == config.WebhookEventWeeklySummary {
+		data.EndDate = r.until.AddDate(0, 0, -1).Format("2006-01-02")
+	}
+	for name, stats := range summary.ByProvider {
+		data.ByProvider[name] = stats.Cost
+	}
+	for name, stats := range summary.ByModel {
+		data.ByModel[name] = stats.Cost
+	}
+	notify.DispatchEvent(r.event, data)
+
+	if gw := d.botGateway; cfg.Bot && gw != nil {
+		gw.Notify(bot.NotifyInfo, "Usage Report", usageReportText(data))
+	}
+	return nil
+}
+
+// usageReportText formats a summary for chat, with the cost per provider.
+func usageReportText(data *notify.DailySummaryData) string {
+	var b strings.Builder
+	b.WriteString(data.Text())
+	names := make([]string, 0, len(data.ByProvider))
+	for name := range data.ByProvider {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return data.ByProvider[names[i]] > data.ByProvider[names[j]] })
+	for _, name := range names {
+		fmt.Fprintf(&b, "\n• %s: %s", name, config.FormatAmount(data.Currency, data.ByProvider[name]))
+	}
+	return b.String()
+}
@@ -0,0 +1,64 @@
+package daemon
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/notify"
+)
+
+func TestDueUsageReports(t *testing.T) {
+	cfg := &config.UsageReportConfig{Daily: true, Weekly: true, HourUTC: 8}
+	sunday := time.Date(2026, 3, 8, 8, 15, 0, 0, time.UTC)
+	sent := make(map[config.WebhookEvent]string)
+
+	due := dueUsageReports(cfg, sunday, sent)
+	if len(due) != 2 {
+		t.Fatalf("expected daily and weekly reports on Sunday, got %d", len(due))
+	}
+	if due[0].event != config.WebhookEventDailySummary ||
+		!due[0].since.Equal(time.Date(2026, 3, 7, 0, 0, 0, 0, time.UTC)) ||
+		!due[0].until.Equal(time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected daily report: %+v", due[0])
+	}
+	if due[1].event != config.WebhookEventWeeklySummary ||
+		!due[1].since.Equal(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected weekly report: %+v", due[1])
+	}
+
+	// Already sent today
+	sent[config.WebhookEventDailySummary] = "2026-03-08"
+	sent[config.WebhookEventWeeklySummary] = "2026-03-08"
+	if due := dueUsageReports(cfg, sunday, sent); len(due) != 0 {
+		t.Errorf("expected nothing due after sending, got %d", len(due))
+	}
+
+	// Wrong hour, and weekly only on Sundays
+	if due := dueUsageReports(cfg, sunday.Add(time.Hour), map[config.WebhookEvent]string{}); len(due) != 0 {
+		t.Errorf("expected nothing due outside hour_utc, got %d", len(due))
+	}
+	monday := sunday.AddDate(0, 0, 1)
+	if due := dueUsageReports(cfg, monday, sent); len(due) != 1 || due[0].event != config.WebhookEventDailySummary {
+		t.Errorf("expected only the daily report on Monday, got %+v", due)
+	}
+
+	if due := dueUsageReports(nil, sunday, map[config.WebhookEvent]string{}); len(due) != 0 {
+		t.Errorf("expected nothing due without config, got %d", len(due))
+	}
+}
+
+func TestUsageReportText(t *testing.T) {
+	text := usageReportText(&notify.DailySummaryData{
+		Date:       "2026-03-07",
+		TotalCost:  3.5,
+		ByProvider: map[string]float64{"cheap": 0.5, "main": 3},
+	})
+	if !strings.HasPrefix(text, "📊 Daily Summary (2026-03-07)") {
+		t.Errorf("unexpected summary line: %q", text)
+	}
+	if i, j := strings.Index(text, "main: $3.00"), strings.Index(text, "cheap: $0.50"); i < 0 || j < i {
+		t.Errorf("expected providers by cost, got %q", text)
+	}
+}
@@ -9,14 +9,33 @@ import (
 	"github.com/dopejs/gozen/internal/config"
 )
 
-// ConfigWatcher watches the config file for changes and triggers a reload callback.
+const (
+	// watchInterval is how often the watched files are polled.
+	watchInterval = 250 * time.Millisecond
+	// watchDebounce is how long the files must stay unchanged before a
+	// reload, so an editor's burst of writes triggers a single reload.
+	watchDebounce = 300 * time.Millisecond
+)
+
+// ConfigWatcher watches the config file, and the files of local middleware
+// plugins, for changes and triggers a reload callback once they settle.
 // Uses polling instead of fsnotify to avoid the external dependency.
 type ConfigWatcher struct {
-	logger   *log.Logger
-	onReload func()
-	stop     chan struct{}
-	path     string
-	modTime  time.Time
+	logger         *log.Logger
+	onReload       func()
+	onPluginChange func()
+	stop           chan struct{}
+	path           string
+	modTime        time.Time
+	teamPath       string // cached team layer, laid under the config
+	teamModTime    time.Time
+	plugins        map[string]time.Time // plugin file -> last seen modification time
+
+	interval       time.Duration
+	debounce       time.Duration
+	configPending  bool
+	pluginsPending bool
+	lastChange     time.Time
 }
 
 // NewConfigWatcher creates a new config file watcher.
@@ -26,18 +45,35 @@ func NewConfigWatcher(logger *log.Logger, onReload func()) *ConfigWatcher {
 	if info, err := os.Stat(path); err == nil {
 		modTime = info.ModTime()
 	}
+	teamPath := config.TeamFilePath()
+	var teamModTime time.Time
+	if info, err := os.Stat(teamPath); err == nil {
+		teamModTime = info.ModTime()
+	}
 	return &ConfigWatcher{
-		logger:   logger,
-		onReload: onReload,
-		stop:     make(chan struct{}),
-		path:     path,
-		modTime:  modTime,
+		logger:      logger,
+		onReload:    onReload,
+		stop:        make(chan struct{}),
+		path:        path,
+		modTime:     modTime,
+		teamPath:    teamPath,
+		teamModTime: teamModTime,
+		plugins:     make(map[string]time.Time),
+		interval:    watchInterval,
+		debounce:    watchDebounce,
 	}
 }
 
+// OnPluginChange sets the callback run when a local middleware plugin file
+// changes. Must be called before Start.
+func (w *ConfigWatcher) OnPluginChange(fn func()) {
+	w.onPluginChange = fn
+}
+
 // Start begins watching the config file. Blocks until Stop is called.
 func (w *ConfigWatcher) Start() {
-	ticker := time.NewTicker(2 * time.Second)
+	w.scanPlugins()
+	ticker := time.NewTicker(w.interval)
 	defer ticker.Stop()
 
 	for {
@@ -60,16 +96,70 @@ func (w *ConfigWatcher) Stop() {
 	}
 }
 
-// check polls the config file for modifications.
+// check polls the watched files for modifications and runs the callbacks
+// once no change has been seen for the debounce interval.
 func (w *ConfigWatcher) check() {
-	info, err := os.Stat(w.path)
-	if err != nil {
+	now := time.Now()
+	if info, err := os.Stat(w.path); err == nil && !info.ModTime().Equal(w.modTime) {
+		w.modTime = info.ModTime()
+		w.configPending = true
+		w.lastChange = now
+	}
+	if w.teamPath != "" {
+		var teamModTime time.Time
+		if info, err := os.Stat(w.teamPath); err == nil {
+			teamModTime = info.ModTime()
+		}
+		if !teamModTime.Equal(w.teamModTime) {
+			w.teamModTime = teamModTime
+			w.configPending = true
+			w.lastChange = now
+		}
+	}
+	if w.scanPlugins() {
+		w.pluginsPending = true
+		w.lastChange = now
+	}
+
+	if (!w.configPending && !w.pluginsPending) || now.Sub(w.lastChange) < w.debounce {
 		return
 	}
+	configChanged, pluginsChanged := w.configPending, w.pluginsPending
+	w.configPending, w.pluginsPending = false, false
 
-	if info.ModTime().After(w.modTime) {
-		w.modTime = info.ModTime()
+	if configChanged {
 		w.logger.Printf("config file modified, triggering reload")
 		w.onReload()
+		// The reload may have changed which plugin files are configured
+		w.scanPlugins()
+	}
+	if pluginsChanged && w.onPluginChange != nil {
+		w.logger.Printf("middleware plugin file modified, reloading middleware")
+		w.onPluginChange()
+	}
+}
+
+// scanPlugins stats the files of the configured local, wasm and script
+// middleware plugins and reports whether any known file changed. Newly
+// configured files are recorded without counting as a change.
+func (w *ConfigWatcher) scanPlugins() bool {
+	seen := make(map[string]time.Time)
+	changed := false
+	if mw := config.GetMiddleware(); mw != nil {
+		for _, entry := range mw.Middlewares {
+			if entry == nil || entry.Path == "" {
+				continue
+			}
+			info, err := os.Stat(entry.Path)
+			if err != nil {
+				continue
+			}
+			seen[entry.Path] = info.ModTime()
+			if prev, ok := w.plugins[entry.Path]; ok && !prev.Equal(info.ModTime()) {
+				changed = true
+			}
+		}
 	}
+	w.plugins = seen
+	return changed
 }
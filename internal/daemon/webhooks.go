@@ -0,0 +1,24 @@
+package daemon
+
+import (
+	"context"
+	"time"
+
+	"github.com/dopejs/gozen/internal/notify"
+)
+
+// webhookRetryLoop retries failed webhook deliveries once their backoff has
+// elapsed, including those still pending from before a restart.
+func (d *Daemon) webhookRetryLoop(ctx context.Context) {
+	defer d.bgWG.Done()
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		notify.GetGlobalDispatcher().RetryDue(time.Now())
+	}
+}
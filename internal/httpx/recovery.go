@@ -1,12 +1,16 @@
 package httpx
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"runtime/debug"
 	"sync"
+
+	"github.com/dopejs/gozen/internal/crashreport"
 )
 
 type statusWriter struct {
@@ -24,6 +28,29 @@ func (w *statusWriter) Write(p []byte) (int, error) {
 	return w.ResponseWriter.Write(p)
 }
 
+// Flush implements http.Flusher, so streaming handlers work behind Recover.
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.wroteHeader = true
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker, so WebSocket handlers work behind Recover.
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response does not implement http.Hijacker")
+	}
+	w.wroteHeader = true
+	return h.Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 // Recover wraps an HTTP handler and prevents panics from crashing the process.
 func Recover(logger *log.Logger, component string, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -38,6 +65,10 @@ func Recover(logger *log.Logger, component string, next http.Handler) http.Handl
 
 				// Log panic_recovered event (T069)
 				logPanicRecovered(rec, stack, r.URL.Path)
+				// http.ErrAbortHandler aborts a response on purpose
+				if rec != http.ErrAbortHandler {
+					crashreport.Write(component, fmt.Sprintf("%v (%s %s)", rec, r.Method, r.URL.Path), []byte(stack))
+				}
 
 				if sw.wroteHeader {
 					return
@@ -0,0 +1,118 @@
+package httpx
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestTLSListenerServesTLSAndLoopbackHTTP(t *testing.T) {
+	cert, err := SelfSignedCertificate(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("SelfSignedCertificate: %v", err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil {
+			io.WriteString(w, "https")
+		} else {
+			io.WriteString(w, "http")
+		}
+	})}
+	go srv.Serve(TLSListener(ln, &tls.Config{Certificates: []tls.Certificate{cert}}))
+	defer srv.Close()
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(leaf)
+	httpsClient := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+
+	get := func(client *http.Client, url string) string {
+		t.Helper()
+		resp, err := client.Get(url)
+		if err != nil {
+			t.Fatalf("GET %s: %v", url, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	addr := ln.Addr().String()
+	if got := get(httpsClient, "https://"+addr); got != "https" {
+		t.Errorf("https body = %q, want https", got)
+	}
+	if got := get(http.DefaultClient, "http://"+addr); got != "http" {
+		t.Errorf("http body = %q, want http", got)
+	}
+}
+
+func TestSelfSignedCertificateReuse(t *testing.T) {
+	dir := t.TempDir()
+	first, err := SelfSignedCertificate(dir, []string{"zen.lan"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := SelfSignedCertificate(dir, []string{"zen.lan"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Fingerprint(first) != Fingerprint(again) {
+		t.Error("certificate regenerated although it covers the hosts")
+	}
+
+	// A new host needs a new certificate
+	wider, err := SelfSignedCertificate(dir, []string{"zen.lan", "192.168.1.10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Fingerprint(wider) == Fingerprint(first) {
+		t.Error("certificate not regenerated for a new host")
+	}
+	if !certCovers(wider, []string{"localhost", "127.0.0.1", "zen.lan", "192.168.1.10"}) {
+		t.Error("regenerated certificate does not cover all hosts")
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	cert, err := SelfSignedCertificate(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fp := Fingerprint(cert)
+	if parts := strings.Split(fp, ":"); len(parts) != 32 {
+		t.Errorf("fingerprint %q has %d parts, want 32", fp, len(parts))
+	}
+	if Fingerprint(tls.Certificate{}) != "" {
+		t.Error("empty certificate should have no fingerprint")
+	}
+}
+
+func TestIsLoopback(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"127.0.0.1:1234":   true,
+		"[::1]:1234":       true,
+		"192.168.1.5:1234": false,
+		"[fe80::1]:1234":   false,
+		"not an address":   false,
+	} {
+		if got := isLoopback(fakeAddr(addr)); got != want {
+			t.Errorf("isLoopback(%q) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+type fakeAddr string
+
+func (a fakeAddr) Network() string { return "tcp" }
+func (a fakeAddr) String() string  { return string(a) }
@@ -0,0 +1,437 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// maxBodyBytes caps a request to the endpoint.
+const maxBodyBytes = 16 << 20
+
+// ToolCall is a tool call made through the endpoint, as the guardrail
+// policy sees it. The tool is named as Claude Code names the tools of an
+// MCP server it runs itself: mcp__<server>__<tool>.
+type ToolCall struct {
+	SessionID   string
+	ProjectPath string
+	Name        string
+	Input       json.RawMessage
+}
+
+// Policy rules on a tool call before it reaches its server. A blocked call
+// returns the reason to the client as a failed tool result.
+type Policy func(ctx context.Context, call ToolCall) (blocked bool, reason string)
+
+// ProjectStatus reports the servers zen runs for a project directory.
+type ProjectStatus struct {
+	Project  string         `json:"project"`
+	Servers  []ServerStatus `json:"servers"`
+	LastUsed time.Time      `json:"last_used"`
+}
+
+// Manager runs the MCP servers of the projects clients use the endpoint
+// from, one set per project directory, started on first use.
+type Manager struct {
+	version string
+	logger  *log.Logger
+
+	mu       sync.Mutex
+	projects map[string]*project
+	policy   Policy
+	stopping sync.WaitGroup // servers being stopped in the background
+}
+
+// NewManager creates a manager. version is reported to servers and
+// clients.
+func NewManager(version string, logger *log.Logger) *Manager {
+	return &Manager{
+		version:  version,
+		logger:   logger,
+		projects: make(map[string]*project),
+	}
+}
+
+// SetPolicy sets the policy tool calls are checked against.
+func (m *Manager) SetPolicy(p Policy) {
+	m.mu.Lock()
+	m.policy = p
+	m.mu.Unlock()
+}
+
+// project is the set of servers run for one project directory.
+type project struct {
+	dir      string
+	servers  map[string]*server
+	lastUsed atomic.Int64 // unix nanoseconds
+}
+
+// enabledServers returns the MCP servers of a binding zen should run.
+func enabledServers(b *config.ProjectBinding) map[string]*config.MCPServerConfig {
+	if b == nil {
+		return nil
+	}
+	servers := make(map[string]*config.MCPServerConfig)
+	for name, c := range b.MCPServers {
+		if c != nil && !c.Disabled {
+			servers[name] = c
+		}
+	}
+	return servers
+}
+
+// project returns the servers of a project directory, starting them on
+// first use and bringing them in line with the directory's binding.
+func (m *Manager) project(dir string) (*project, error) {
+	if dir == "" || !filepath.IsAbs(dir) {
+		return nil, fmt.Errorf("project must be an absolute directory path")
+	}
+	dir = filepath.Clean(dir)
+	_, binding := config.FindProjectBinding(dir)
+	servers := enabledServers(binding)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p := m.projects[dir]
+	if len(servers) == 0 {
+		if p != nil {
+			m.stopLocked(p, nil)
+			delete(m.projects, dir)
+		}
+		return nil, fmt.Errorf("no MCP servers are configured for %s", dir)
+	}
+	if p == nil {
+		p = &project{dir: dir, servers: make(map[string]*server)}
+		m.projects[dir] = p
+	}
+	m.updateLocked(p, servers)
+	p.lastUsed.Store(time.Now().UnixNano())
+	return p, nil
+}
+
+// updateLocked starts the servers added to a project's config, stops the
+// removed ones and restarts the changed ones.
+func (m *Manager) updateLocked(p *project, servers map[string]*config.MCPServerConfig) {
+	for name, s := range p.servers {
+		if c := servers[name]; c == nil || !reflect.DeepEqual(c, s.cfg) {
+			m.stopLocked(p, []string{name})
+		}
+	}
+	for name, c := range servers {
+		if p.servers[name] == nil {
+			p.servers[name] = startServer(name, c, p.dir, m.version, m.logger)
+		}
+	}
+}
+
+// stopLocked stops the named servers of a project, or all of them, in the
+// background.
+func (m *Manager) stopLocked(p *project, names []string) {
+	if names == nil {
+		for name := range p.servers {
+			names = append(names, name)
+		}
+	}
+	for _, name := range names {
+		s := p.servers[name]
+		if s == nil {
+			continue
+		}
+		delete(p.servers, name)
+		m.stopping.Add(1)
+		go func() {
+			defer m.stopping.Done()
+			s.stop()
+		}()
+	}
+}
+
+// Reload brings the running servers in line with the config, stopping
+// those no longer configured.
+func (m *Manager) Reload() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for dir, p := range m.projects {
+		_, binding := config.FindProjectBinding(dir)
+		if servers := enabledServers(binding); len(servers) > 0 {
+			m.updateLocked(p, servers)
+			continue
+		}
+		m.stopLocked(p, nil)
+		delete(m.projects, dir)
+	}
+}
+
+// Prune stops the servers of projects the endpoint hasn't served for
+// maxIdle. They start again on next use. It returns how many projects it
+// stopped.
+func (m *Manager) Prune(maxIdle time.Duration) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cutoff := time.Now().Add(-maxIdle).UnixNano()
+	n := 0
+	for dir, p := range m.projects {
+		if p.lastUsed.Load() <= cutoff {
+			m.stopLocked(p, nil)
+			delete(m.projects, dir)
+			n++
+		}
+	}
+	return n
+}
+
+// Close stops every server and waits for them to exit.
+func (m *Manager) Close() {
+	m.mu.Lock()
+	for dir, p := range m.projects {
+		m.stopLocked(p, nil)
+		delete(m.projects, dir)
+	}
+	m.mu.Unlock()
+	m.stopping.Wait()
+}
+
+// Status reports the servers of every project, by project directory.
+func (m *Manager) Status() []ProjectStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	statuses := make([]ProjectStatus, 0, len(m.projects))
+	for _, p := range m.projects {
+		ps := ProjectStatus{Project: p.dir, LastUsed: time.Unix(0, p.lastUsed.Load())}
+		for _, s := range p.servers {
+			ps.Servers = append(ps.Servers, s.status())
+		}
+		sort.Slice(ps.Servers, func(i, j int) bool { return ps.Servers[i].Name < ps.Servers[j].Name })
+		statuses = append(statuses, ps)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Project < statuses[j].Project })
+	return statuses
+}
+
+// serverNamed returns a running project server by name.
+func (m *Manager) serverNamed(p *project, name string) *server {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return p.servers[name]
+}
+
+// tools merges the tools of a project's servers, each named
+// <server>__<tool>. Servers that fail to start are left out.
+func (m *Manager) tools(ctx context.Context, p *project) []json.RawMessage {
+	m.mu.Lock()
+	servers := make([]*server, 0, len(p.servers))
+	for _, s := range p.servers {
+		servers = append(servers, s)
+	}
+	m.mu.Unlock()
+	sort.Slice(servers, func(i, j int) bool { return servers[i].name < servers[j].name })
+
+	lists := make([][]json.RawMessage, len(servers))
+	var wg sync.WaitGroup
+	for i, s := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tools, err := s.listedTools(ctx)
+			if err != nil {
+				m.logger.Printf("[mcp] leaving out the tools of %s: %v", s.name, err)
+				return
+			}
+			for _, raw := range tools {
+				if tool, ok := prefixTool(s.name, raw); ok {
+					lists[i] = append(lists[i], tool)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	merged := make([]json.RawMessage, 0)
+	for _, list := range lists {
+		merged = append(merged, list...)
+	}
+	return merged
+}
+
+// prefixTool renames a server's tool to <server>__<tool>.
+func prefixTool(serverName string, raw json.RawMessage) (json.RawMessage, bool) {
+	var tool map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &tool); err != nil {
+		return nil, false
+	}
+	var name string
+	if err := json.Unmarshal(tool["name"], &name); err != nil || name == "" {
+		return nil, false
+	}
+	tool["name"], _ = json.Marshal(serverName + toolSeparator + name)
+	out, err := json.Marshal(tool)
+	return out, err == nil
+}
+
+// handle answers a client message. Notifications and responses get no
+// reply.
+func (m *Manager) handle(ctx context.Context, p *project, sessionID string, msg *message) *message {
+	if !msg.isRequest() {
+		return nil
+	}
+	if msg.JSONRPC != "2.0" {
+		return errorMessage(msg.ID, codeInvalidRequest, "jsonrpc must be 2.0")
+	}
+	switch msg.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		json.Unmarshal(msg.Params, &params)
+		version := ProtocolVersion
+		if supportedVersions[params.ProtocolVersion] {
+			version = params.ProtocolVersion
+		}
+		return resultMessage(msg.ID, map[string]interface{}{
+			"protocolVersion": version,
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+			"serverInfo":      map[string]string{"name": "gozen", "version": m.version},
+		})
+	case "ping":
+		return resultMessage(msg.ID, struct{}{})
+	case "tools/list":
+		return resultMessage(msg.ID, map[string]interface{}{"tools": m.tools(ctx, p)})
+	case "tools/call":
+		return m.callTool(ctx, p, sessionID, msg)
+	}
+	return errorMessage(msg.ID, codeMethodNotFound, "method not found: "+msg.Method)
+}
+
+// callTool checks a tool call against the policy and forwards it to the
+// server the tool belongs to.
+func (m *Manager) callTool(ctx context.Context, p *project, sessionID string, msg *message) *message {
+	var params struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments,omitempty"`
+		Meta      json.RawMessage `json:"_meta,omitempty"`
+	}
+	if err := json.Unmarshal(msg.Params, &params); err != nil || params.Name == "" {
+		return errorMessage(msg.ID, codeInvalidParams, "tools/call requires a tool name")
+	}
+	serverName, tool, ok := strings.Cut(params.Name, toolSeparator)
+	s := m.serverNamed(p, serverName)
+	if !ok || tool == "" || s == nil {
+		return errorMessage(msg.ID, codeInvalidParams, "unknown tool: "+params.Name)
+	}
+
+	m.mu.Lock()
+	policy := m.policy
+	m.mu.Unlock()
+	if policy != nil {
+		call := ToolCall{
+			SessionID:   sessionID,
+			ProjectPath: p.dir,
+			Name:        "mcp" + toolSeparator + serverName + toolSeparator + tool,
+			Input:       params.Arguments,
+		}
+		if blocked, reason := policy(ctx, call); blocked {
+			m.logger.Printf("[mcp] %s tool call blocked: %s", call.Name, reason)
+			return resultMessage(msg.ID, toolResultError("Blocked by zen guardrails: "+reason))
+		}
+	}
+
+	forward := map[string]interface{}{"name": tool}
+	if len(params.Arguments) > 0 {
+		forward["arguments"] = params.Arguments
+	}
+	if len(params.Meta) > 0 {
+		forward["_meta"] = params.Meta
+	}
+	result, err := s.call(ctx, "tools/call", forward)
+	if err != nil {
+		var rpcErr *rpcError
+		if errors.As(err, &rpcErr) {
+			return errorMessage(msg.ID, rpcErr.Code, rpcErr.Message)
+		}
+		return resultMessage(msg.ID, toolResultError(err.Error()))
+	}
+	return &message{JSONRPC: "2.0", ID: msg.ID, Result: result}
+}
+
+// ServeHTTP serves the MCP endpoint over the Streamable HTTP transport,
+// answering every POST with JSON; the endpoint sends no server-initiated
+// messages. The project query parameter names the project directory whose
+// servers to use, and session the client session calls belong to.
+// Messages must be sent as application/json, which a web page can't do
+// across origins without a preflight.
+func (m *Manager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		http.Error(w, "Content-Type must be application/json", http.StatusUnsupportedMediaType)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes))
+	if err != nil {
+		writeMessage(w, http.StatusBadRequest, errorMessage(nil, codeParseError, err.Error()))
+		return
+	}
+	body = bytes.TrimSpace(body)
+	batch := len(body) > 0 && body[0] == '['
+	var msgs []*message
+	if batch {
+		err = json.Unmarshal(body, &msgs)
+	} else {
+		msg := &message{}
+		err = json.Unmarshal(body, msg)
+		msgs = []*message{msg}
+	}
+	if err != nil || len(msgs) == 0 {
+		writeMessage(w, http.StatusBadRequest, errorMessage(nil, codeParseError, "invalid JSON-RPC message"))
+		return
+	}
+
+	q := r.URL.Query()
+	p, err := m.project(q.Get("project"))
+	if err != nil {
+		writeMessage(w, http.StatusNotFound, errorMessage(msgs[0].ID, codeInvalidRequest, err.Error()))
+		return
+	}
+	var replies []*message
+	for _, msg := range msgs {
+		if msg == nil {
+			replies = append(replies, errorMessage(nil, codeInvalidRequest, "invalid JSON-RPC message"))
+			continue
+		}
+		if reply := m.handle(r.Context(), p, q.Get("session"), msg); reply != nil {
+			replies = append(replies, reply)
+		}
+	}
+	switch {
+	case len(replies) == 0:
+		w.WriteHeader(http.StatusAccepted)
+	case batch:
+		writeMessage(w, http.StatusOK, replies)
+	default:
+		writeMessage(w, http.StatusOK, replies[0])
+	}
+}
+
+func writeMessage(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,212 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// TestMain doubles as a fake stdio MCP server when the test binary is run
+// with GOZEN_FAKE_MCP_SERVER set.
+func TestMain(m *testing.M) {
+	if os.Getenv("GOZEN_FAKE_MCP_SERVER") != "" {
+		fakeServer()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// fakeServer serves an echo tool and a crash tool, which exits.
+func fakeServer() {
+	fmt.Println("fake server starting") // stray output a server may print
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var msg message
+		if json.Unmarshal(scanner.Bytes(), &msg) != nil || !msg.isRequest() {
+			continue
+		}
+		var result interface{}
+		switch msg.Method {
+		case "initialize":
+			result = map[string]interface{}{"protocolVersion": ProtocolVersion, "capabilities": map[string]interface{}{"tools": map[string]interface{}{}}}
+		case "tools/list":
+			result = map[string]interface{}{"tools": []map[string]interface{}{
+				{"name": "echo", "description": "Echo text", "inputSchema": map[string]interface{}{"type": "object"}},
+				{"name": "crash", "inputSchema": map[string]interface{}{"type": "object"}},
+			}}
+		case "tools/call":
+			var params struct {
+				Name      string            `json:"name"`
+				Arguments map[string]string `json:"arguments"`
+			}
+			json.Unmarshal(msg.Params, &params)
+			if params.Name == "crash" {
+				os.Exit(1)
+			}
+			text := os.Getenv("FAKE_PREFIX") + params.Arguments["text"]
+			result = map[string]interface{}{"content": []map[string]string{{"type": "text", "text": text}}}
+		}
+		data, _ := json.Marshal(resultMessage(msg.ID, result))
+		fmt.Println(string(data))
+	}
+}
+
+func setupProject(t *testing.T, servers string) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	project := t.TempDir()
+	zenDir := filepath.Join(home, ".zen")
+	os.MkdirAll(zenDir, 0755)
+	data := fmt.Sprintf(`{"version": %d, "project_bindings": {%q: {"mcp_servers": %s}}}`, config.CurrentConfigVersion, project, servers)
+	if err := os.WriteFile(filepath.Join(zenDir, "zen.json"), []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config.ResetDefaultStore()
+	t.Cleanup(config.ResetDefaultStore)
+	return project
+}
+
+func fakeServerConfig(t *testing.T, prefix string) string {
+	t.Helper()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := config.MCPServerConfig{Command: exe, Env: map[string]string{"GOZEN_FAKE_MCP_SERVER": "1", "FAKE_PREFIX": prefix}}
+	data, _ := json.Marshal(c)
+	return string(data)
+}
+
+func rpc(t *testing.T, endpoint, body string) map[string]interface{} {
+	t.Helper()
+	resp, err := http.Post(endpoint, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var reply map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		t.Fatalf("decode reply to %s: %v", body, err)
+	}
+	return reply
+}
+
+func toolText(t *testing.T, reply map[string]interface{}) (string, bool) {
+	t.Helper()
+	result, ok := reply["result"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected a result, got %v", reply)
+	}
+	content := result["content"].([]interface{})
+	isError, _ := result["isError"].(bool)
+	return content[0].(map[string]interface{})["text"].(string), isError
+}
+
+func TestEndpointMergesToolsAndAppliesPolicy(t *testing.T) {
+	project := setupProject(t, fmt.Sprintf(`{"alpha": %s, "beta": %s}`, fakeServerConfig(t, "a:"), fakeServerConfig(t, "b:")))
+
+	m := NewManager("test", log.New(io.Discard, "", 0))
+	defer m.Close()
+	var checked []ToolCall
+	m.SetPolicy(func(ctx context.Context, call ToolCall) (bool, string) {
+		checked = append(checked, call)
+		return strings.Contains(string(call.Input), "rm -rf"), "denied command"
+	})
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+	endpoint := srv.URL + "/mcp?session=s1&project=" + url.QueryEscape(project)
+
+	init := rpc(t, endpoint, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26"}}`)
+	if v := init["result"].(map[string]interface{})["protocolVersion"]; v != "2025-03-26" {
+		t.Errorf("negotiated protocol version = %v", v)
+	}
+
+	list := rpc(t, endpoint, `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)
+	var names []string
+	for _, tool := range list["result"].(map[string]interface{})["tools"].([]interface{}) {
+		names = append(names, tool.(map[string]interface{})["name"].(string))
+	}
+	if got := strings.Join(names, ","); got != "alpha__echo,alpha__crash,beta__echo,beta__crash" {
+		t.Errorf("merged tools = %s", got)
+	}
+
+	call := rpc(t, endpoint, `{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"beta__echo","arguments":{"text":"hi"}}}`)
+	if text, isError := toolText(t, call); text != "b:hi" || isError {
+		t.Errorf("beta__echo = %q (error %v), want b:hi", text, isError)
+	}
+	if len(checked) != 1 || checked[0].Name != "mcp__beta__echo" || checked[0].SessionID != "s1" || checked[0].ProjectPath != project {
+		t.Errorf("policy saw %+v", checked)
+	}
+
+	blocked := rpc(t, endpoint, `{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"alpha__echo","arguments":{"text":"rm -rf /"}}}`)
+	if text, isError := toolText(t, blocked); !isError || !strings.Contains(text, "denied command") {
+		t.Errorf("blocked call = %q (error %v)", text, isError)
+	}
+
+	unknown := rpc(t, endpoint, `{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"gamma__echo"}}`)
+	if unknown["error"] == nil {
+		t.Errorf("expected an error for an unknown server, got %v", unknown)
+	}
+
+	resp, err := http.Post(endpoint, "application/json", strings.NewReader(`{"jsonrpc":"2.0","method":"notifications/initialized"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("notification status = %d, want 202", resp.StatusCode)
+	}
+}
+
+func TestServerRestartsAfterExit(t *testing.T) {
+	project := setupProject(t, fmt.Sprintf(`{"alpha": %s}`, fakeServerConfig(t, "")))
+
+	m := NewManager("test", log.New(io.Discard, "", 0))
+	defer m.Close()
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+	endpoint := srv.URL + "/mcp?project=" + url.QueryEscape(project)
+
+	crash := rpc(t, endpoint, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"alpha__crash"}}`)
+	if _, isError := toolText(t, crash); !isError {
+		t.Fatalf("a call the server died on should fail, got %v", crash)
+	}
+
+	// The supervisor restarts it after the backoff delay
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		call := rpc(t, endpoint, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"alpha__echo","arguments":{"text":"back"}}}`)
+		if text, isError := toolText(t, call); !isError && text == "back" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server did not come back: %v", call)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	status := m.Status()
+	if len(status) != 1 || status[0].Servers[0].Restarts != 1 || status[0].Servers[0].State != StateRunning {
+		t.Errorf("status = %+v", status)
+	}
+
+	// Dropping the server from the binding stops it
+	config.SetProjectMCPServers(project, nil)
+	m.Reload()
+	if status := m.Status(); len(status) != 0 {
+		t.Errorf("status after removal = %+v", status)
+	}
+}
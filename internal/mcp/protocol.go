@@ -0,0 +1,89 @@
+// Package mcp multiplexes the MCP servers of bound projects. zen launches
+// and supervises the stdio servers a project binding declares, merges their
+// tools and serves them from a single MCP endpoint, with every tool call
+// checked against the guardrail policy first.
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// ProtocolVersion is the MCP revision zen speaks to the servers it runs,
+// and offers clients that ask for one it doesn't know.
+const ProtocolVersion = "2025-06-18"
+
+// supportedVersions are the MCP revisions the endpoint accepts.
+var supportedVersions = map[string]bool{
+	"2024-11-05": true,
+	"2025-03-26": true,
+	"2025-06-18": true,
+}
+
+// JSON-RPC error codes.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+// toolSeparator joins a server's name and a tool's name in the tool names
+// the endpoint serves.
+const toolSeparator = "__"
+
+// message is a JSON-RPC 2.0 request, notification or response.
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// isRequest reports whether the message expects a response.
+func (m *message) isRequest() bool {
+	return m.Method != "" && len(m.ID) > 0 && !bytes.Equal(m.ID, []byte("null"))
+}
+
+// isResponse reports whether the message answers a request.
+func (m *message) isResponse() bool {
+	return m.Method == "" && len(m.ID) > 0
+}
+
+// rpcError is a JSON-RPC error object.
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+func resultMessage(id json.RawMessage, result interface{}) *message {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return errorMessage(id, codeInternalError, err.Error())
+	}
+	return &message{JSONRPC: "2.0", ID: id, Result: data}
+}
+
+func errorMessage(id json.RawMessage, code int, msg string) *message {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	return &message{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: code, Message: msg}}
+}
+
+// toolResultError is a tools/call result reporting a failed call, which
+// the model sees, unlike a JSON-RPC error.
+func toolResultError(text string) map[string]interface{} {
+	return map[string]interface{}{
+		"content": []map[string]string{{"type": "text", "text": text}},
+		"isError": true,
+	}
+}
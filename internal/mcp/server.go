@@ -0,0 +1,521 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// startupTimeout bounds a server's initialize handshake, and how long a
+// call waits for a server that is still starting.
+const startupTimeout = 30 * time.Second
+
+// stopTimeout is how long a server has to exit once its stdin is closed
+// before it is killed.
+const stopTimeout = 5 * time.Second
+
+// Restart backoff for servers that exit. A server that ran for longer than
+// the maximum delay starts over from the minimum.
+const (
+	minRestartDelay = time.Second
+	maxRestartDelay = time.Minute
+)
+
+// Server states.
+const (
+	StateStarting = "starting"
+	StateRunning  = "running"
+	StateFailed   = "failed" // exited, waiting to restart
+	StateStopped  = "stopped"
+)
+
+// ServerStatus reports a supervised MCP server.
+type ServerStatus struct {
+	Name      string    `json:"name"`
+	State     string    `json:"state"`
+	PID       int       `json:"pid,omitempty"`
+	Tools     int       `json:"tools"`
+	Restarts  int       `json:"restarts"`
+	LastError string    `json:"last_error,omitempty"`
+	StartedAt time.Time `json:"started_at,omitempty"`
+}
+
+// server supervises one stdio MCP server: it runs it in the project
+// directory, restarts it with backoff when it exits, and keeps its tools.
+type server struct {
+	name    string
+	cfg     *config.MCPServerConfig
+	dir     string
+	version string
+	logger  *log.Logger
+
+	cancel context.CancelFunc
+	done   chan struct{} // closed once supervision ends
+
+	mu        sync.Mutex
+	conn      *conn // nil unless running
+	tools     []json.RawMessage
+	state     string
+	pid       int
+	restarts  int
+	lastErr   string
+	startedAt time.Time
+	changed   chan struct{} // closed and replaced on every state change
+}
+
+// startServer starts supervising a server.
+func startServer(name string, cfg *config.MCPServerConfig, dir, version string, logger *log.Logger) *server {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &server{
+		name:    name,
+		cfg:     cfg,
+		dir:     dir,
+		version: version,
+		logger:  logger,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		state:   StateStarting,
+		changed: make(chan struct{}),
+	}
+	go s.supervise(ctx)
+	return s
+}
+
+// stop ends supervision and waits for the server to exit.
+func (s *server) stop() {
+	s.cancel()
+	<-s.done
+}
+
+func (s *server) supervise(ctx context.Context) {
+	defer close(s.done)
+	delay := minRestartDelay
+	for {
+		started := time.Now()
+		err := s.run(ctx)
+		if ctx.Err() != nil {
+			s.setState(StateStopped, "")
+			return
+		}
+		if time.Since(started) > maxRestartDelay {
+			delay = minRestartDelay
+		}
+		s.logger.Printf("[mcp] server %s exited: %v, restarting in %s", s.name, err, delay)
+		s.setState(StateFailed, err.Error())
+
+		select {
+		case <-ctx.Done():
+			s.setState(StateStopped, "")
+			return
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > maxRestartDelay {
+			delay = maxRestartDelay
+		}
+		s.mu.Lock()
+		s.restarts++
+		s.mu.Unlock()
+		s.setState(StateStarting, "")
+	}
+}
+
+// setState records a state change and wakes the calls waiting for one. An
+// empty errMsg keeps the last error.
+func (s *server) setState(state, errMsg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state = state
+	if errMsg != "" {
+		s.lastErr = errMsg
+	}
+	if state != StateRunning {
+		s.conn = nil
+		s.pid = 0
+	}
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// run runs the server once, until it exits or ctx is cancelled.
+func (s *server) run(ctx context.Context) error {
+	env, err := s.environ()
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, s.cfg.Command, s.cfg.Args...)
+	cmd.Dir = s.dir
+	cmd.Env = env
+	cmd.Stderr = &lineLogger{logger: s.logger, prefix: "[mcp] " + s.name + ": "}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	// Closing stdin asks a stdio server to exit; it is killed if it doesn't
+	cmd.Cancel = stdin.Close
+	cmd.WaitDelay = stopTimeout
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	c := newConn(stdin)
+	exited := make(chan error, 1)
+	go func() {
+		c.readLoop(stdout, func(m *message) { s.handleServerMessage(c, m) })
+		exited <- cmd.Wait()
+	}()
+
+	initCtx, cancel := context.WithTimeout(ctx, startupTimeout)
+	tools, err := s.initialize(initCtx, c)
+	cancel()
+	if err != nil {
+		stdin.Close()
+		cmd.Process.Kill()
+		<-exited
+		return fmt.Errorf("initialize: %w", err)
+	}
+
+	s.mu.Lock()
+	s.tools = tools
+	s.conn = c
+	s.pid = cmd.Process.Pid
+	s.startedAt = time.Now()
+	s.lastErr = ""
+	s.mu.Unlock()
+	s.setState(StateRunning, "")
+	s.logger.Printf("[mcp] server %s started (PID %d) in %s with %d tools", s.name, cmd.Process.Pid, s.dir, len(tools))
+
+	err = <-exited
+	if err == nil {
+		err = errors.New("server exited")
+	}
+	return err
+}
+
+// environ returns the server's environment: zen's, with the entries of the
+// server config, their secret references resolved.
+func (s *server) environ() ([]string, error) {
+	env := os.Environ()
+	for k, v := range s.cfg.Env {
+		resolved, err := config.ResolveSecret(v)
+		if err != nil {
+			return nil, fmt.Errorf("env %s: %w", k, err)
+		}
+		env = append(env, k+"="+resolved)
+	}
+	return env, nil
+}
+
+// initialize performs the MCP handshake and fetches the server's tools.
+func (s *server) initialize(ctx context.Context, c *conn) ([]json.RawMessage, error) {
+	_, err := c.call(ctx, "initialize", map[string]interface{}{
+		"protocolVersion": ProtocolVersion,
+		"capabilities":    map[string]interface{}{"roots": map[string]interface{}{}},
+		"clientInfo":      map[string]string{"name": "gozen", "version": s.version},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := c.notify("notifications/initialized", nil); err != nil {
+		return nil, err
+	}
+	return listTools(ctx, c)
+}
+
+// listTools fetches every page of a server's tools. A server without tools
+// has none.
+func listTools(ctx context.Context, c *conn) ([]json.RawMessage, error) {
+	var tools []json.RawMessage
+	cursor := ""
+	for {
+		params := map[string]interface{}{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		raw, err := c.call(ctx, "tools/list", params)
+		var rpcErr *rpcError
+		if errors.As(err, &rpcErr) && rpcErr.Code == codeMethodNotFound {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Tools      []json.RawMessage `json:"tools"`
+			NextCursor string            `json:"nextCursor"`
+		}
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return nil, fmt.Errorf("invalid tools/list result: %w", err)
+		}
+		tools = append(tools, page.Tools...)
+		if page.NextCursor == "" || page.NextCursor == cursor {
+			return tools, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// handleServerMessage answers the requests and notifications a server
+// sends zen as its client.
+func (s *server) handleServerMessage(c *conn, m *message) {
+	switch {
+	case m.Method == "notifications/tools/list_changed":
+		ctx, cancel := context.WithTimeout(context.Background(), startupTimeout)
+		defer cancel()
+		tools, err := listTools(ctx, c)
+		if err != nil {
+			s.logger.Printf("[mcp] server %s: refreshing tools failed: %v", s.name, err)
+			return
+		}
+		s.mu.Lock()
+		s.tools = tools
+		s.mu.Unlock()
+	case !m.isRequest():
+	case m.Method == "ping":
+		c.send(resultMessage(m.ID, struct{}{}))
+	case m.Method == "roots/list":
+		c.send(resultMessage(m.ID, map[string]interface{}{
+			"roots": []map[string]string{{"uri": "file://" + filepath.ToSlash(s.dir), "name": filepath.Base(s.dir)}},
+		}))
+	default:
+		c.send(errorMessage(m.ID, codeMethodNotFound, "method not supported by zen: "+m.Method))
+	}
+}
+
+// ready returns the connection of a running server, waiting for one that
+// is starting.
+func (s *server) ready(ctx context.Context) (*conn, error) {
+	timer := time.NewTimer(startupTimeout)
+	defer timer.Stop()
+	for {
+		s.mu.Lock()
+		c, state, lastErr, changed := s.conn, s.state, s.lastErr, s.changed
+		s.mu.Unlock()
+		switch {
+		case c != nil:
+			return c, nil
+		case state == StateStopped:
+			return nil, fmt.Errorf("MCP server %s is stopped", s.name)
+		case state == StateFailed:
+			return nil, fmt.Errorf("MCP server %s is not running: %s", s.name, lastErr)
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+			return nil, fmt.Errorf("MCP server %s did not start within %s", s.name, startupTimeout)
+		}
+	}
+}
+
+// call sends a request to the server once it is running.
+func (s *server) call(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	c, err := s.ready(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.call(ctx, method, params)
+}
+
+// listedTools returns the server's tools once it is running.
+func (s *server) listedTools(ctx context.Context) ([]json.RawMessage, error) {
+	if _, err := s.ready(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tools, nil
+}
+
+func (s *server) status() ServerStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return ServerStatus{
+		Name:      s.name,
+		State:     s.state,
+		PID:       s.pid,
+		Tools:     len(s.tools),
+		Restarts:  s.restarts,
+		LastError: s.lastErr,
+		StartedAt: s.startedAt,
+	}
+}
+
+// conn is a JSON-RPC connection to a running server over its stdio, one
+// message per line.
+type conn struct {
+	w       io.Writer
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	nextID  int64
+	pending map[int64]chan *message
+	done    chan struct{} // closed when the server's output ends
+	err     error
+}
+
+func newConn(w io.Writer) *conn {
+	return &conn{w: w, pending: make(map[int64]chan *message), done: make(chan struct{})}
+}
+
+func (c *conn) send(m *message) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err = c.w.Write(append(data, '\n'))
+	return err
+}
+
+// call sends a request and waits for its response. A cancelled call is
+// reported to the server.
+func (c *conn) call(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return nil, c.err
+	}
+	c.nextID++
+	id := c.nextID
+	ch := make(chan *message, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	m := &message{JSONRPC: "2.0", ID: json.RawMessage(strconv.FormatInt(id, 10)), Method: method}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return nil, err
+		}
+		m.Params = data
+	}
+	if err := c.send(m); err != nil {
+		return nil, err
+	}
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return nil, resp.Error
+		}
+		return resp.Result, nil
+	case <-c.done:
+		return nil, c.err
+	case <-ctx.Done():
+		c.notify("notifications/cancelled", map[string]interface{}{"requestId": id, "reason": ctx.Err().Error()})
+		return nil, ctx.Err()
+	}
+}
+
+func (c *conn) notify(method string, params interface{}) error {
+	m := &message{JSONRPC: "2.0", Method: method}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		m.Params = data
+	}
+	return c.send(m)
+}
+
+// readLoop reads the server's messages until its output ends, delivering
+// responses to their calls and everything else to handle. Lines that
+// aren't JSON, such as stray log output, are skipped.
+func (c *conn) readLoop(r io.Reader, handle func(*message)) {
+	reader := bufio.NewReaderSize(r, 64*1024)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			var m message
+			if json.Unmarshal(line, &m) == nil {
+				if m.isResponse() {
+					c.deliver(&m)
+				} else {
+					go handle(&m)
+				}
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				err = errors.New("server closed its output")
+			}
+			c.close(err)
+			return
+		}
+	}
+}
+
+func (c *conn) deliver(m *message) {
+	id, err := strconv.ParseInt(string(m.ID), 10, 64)
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	ch := c.pending[id]
+	c.mu.Unlock()
+	if ch != nil {
+		select {
+		case ch <- m:
+		default: // a duplicate response
+		}
+	}
+}
+
+func (c *conn) close(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil {
+		c.err = err
+		close(c.done)
+	}
+}
+
+// lineLogger logs a server's stderr line by line.
+type lineLogger struct {
+	logger *log.Logger
+	prefix string
+	buf    []byte
+}
+
+func (l *lineLogger) Write(p []byte) (int, error) {
+	l.buf = append(l.buf, p...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if line := bytes.TrimRight(l.buf[:i], "\r"); len(line) > 0 {
+			l.logger.Printf("%s%s", l.prefix, line)
+		}
+		l.buf = l.buf[i+1:]
+	}
+	if len(l.buf) > 4096 {
+		l.logger.Printf("%s%s", l.prefix, l.buf)
+		l.buf = l.buf[:0]
+	}
+	return len(p), nil
+}
@@ -0,0 +1,73 @@
+package mcp
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// TokenPath returns the path of the file holding the endpoint's token.
+func TokenPath() string {
+	return filepath.Join(config.ConfigDirPath(), config.MCPTokenFile)
+}
+
+// LoadToken returns the token clients authenticate to the endpoint with,
+// creating it on first use. The file is readable by the user only, so only
+// their own processes can call the servers zen runs for them.
+func LoadToken() (string, error) {
+	path := TokenPath()
+	if token, err := readToken(path); err == nil {
+		return token, nil
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate MCP token: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), config.MCPTokenFile+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.WriteString(hex.EncodeToString(b) + "\n")
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", err
+	}
+	// Link fails if another process created the token first; theirs wins
+	// unless it is unreadable
+	if err := os.Link(tmp.Name(), path); err != nil {
+		if !os.IsExist(err) {
+			return "", err
+		}
+		if token, err := readToken(path); err == nil {
+			return token, nil
+		}
+		if err := os.Rename(tmp.Name(), path); err != nil {
+			return "", err
+		}
+	}
+	return readToken(path)
+}
+
+func readToken(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("empty MCP token file %s", path)
+	}
+	return token, nil
+}
@@ -10,6 +10,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/dopejs/gozen/internal/config"
 )
 
 // SessionMemoryConfig holds configuration for the session memory middleware.
@@ -72,9 +74,9 @@ func (m *SessionMemoryMiddleware) Priority() int {
 	return 15 // After context-injection, before request-logger
 }
 
-func (m *SessionMemoryMiddleware) Init(config json.RawMessage) error {
-	if len(config) > 0 {
-		if err := json.Unmarshal(config, &m.config); err != nil {
+func (m *SessionMemoryMiddleware) Init(raw json.RawMessage) error {
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &m.config); err != nil {
 			return err
 		}
 	}
@@ -91,8 +93,7 @@ func (m *SessionMemoryMiddleware) Init(config json.RawMessage) error {
 	if m.config.StoragePath != "" {
 		m.storePath = m.config.StoragePath
 	} else {
-		home, _ := os.UserHomeDir()
-		m.storePath = filepath.Join(home, ".zen", "memory")
+		m.storePath = filepath.Join(config.ConfigDirPath(), "memory")
 	}
 	os.MkdirAll(m.storePath, 0755)
 
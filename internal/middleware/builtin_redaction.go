@@ -0,0 +1,257 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Redaction actions.
+const (
+	RedactionMask  = "mask"  // replace matches with a placeholder
+	RedactionBlock = "block" // reject the request
+)
+
+// RedactionConfig holds configuration for the redaction middleware.
+type RedactionConfig struct {
+	Action   string             `json:"action,omitempty"`   // default action: "mask" (default) or "block"
+	Mask     string             `json:"mask,omitempty"`     // placeholder, {name} is replaced by the pattern name (default: "[REDACTED:{name}]")
+	Builtins []string           `json:"builtins,omitempty"` // builtin patterns to enable (default: all)
+	Patterns []RedactionPattern `json:"patterns,omitempty"` // custom patterns
+}
+
+// RedactionPattern is a named pattern scanned for in request bodies.
+type RedactionPattern struct {
+	Name   string `json:"name"`
+	Regex  string `json:"regex"`
+	Action string `json:"action,omitempty"` // overrides the default action
+}
+
+// RedactionPatternStats counts what a pattern has caught since the
+// middleware was loaded.
+type RedactionPatternStats struct {
+	Name     string `json:"name"`
+	Action   string `json:"action"`
+	Matches  int64  `json:"matches"`  // occurrences found
+	Requests int64  `json:"requests"` // requests with at least one occurrence
+	Blocked  int64  `json:"blocked"`  // requests rejected by this pattern
+}
+
+// BuiltinRedactionPatterns are the patterns enabled when no builtins are
+// configured. Broader patterns come after the narrower ones they overlap.
+var BuiltinRedactionPatterns = []RedactionPattern{
+	{Name: "anthropic_key", Regex: `sk-ant-[A-Za-z0-9_-]{20,}`},
+	{Name: "openai_key", Regex: `\bsk-(?:proj-|svcacct-)?[A-Za-z0-9_-]{20,}`},
+	{Name: "aws_access_key", Regex: `\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`},
+	{Name: "aws_secret_key", Regex: `(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?[A-Za-z0-9/+]{40}`},
+	{Name: "github_token", Regex: `\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})`},
+	{Name: "private_key", Regex: `-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`},
+	{Name: "email", Regex: `\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`},
+}
+
+type redactionRule struct {
+	re    *regexp.Regexp
+	stats RedactionPatternStats
+}
+
+// RedactionMiddleware masks or blocks secrets and PII in outgoing requests.
+type RedactionMiddleware struct {
+	config RedactionConfig
+	rules  []*redactionRule
+	mu     sync.Mutex // guards rule stats
+}
+
+// NewRedaction creates a new redaction middleware.
+func NewRedaction() Middleware {
+	return &RedactionMiddleware{}
+}
+
+func (m *RedactionMiddleware) Name() string {
+	return "redaction"
+}
+
+func (m *RedactionMiddleware) Version() string {
+	return "1.0.0"
+}
+
+func (m *RedactionMiddleware) Description() string {
+	return "Masks or blocks API keys, secrets and PII in outgoing requests"
+}
+
+func (m *RedactionMiddleware) Priority() int {
+	return 90 // Late among built-ins so injected context is scanned too
+}
+
+func (m *RedactionMiddleware) Init(config json.RawMessage) error {
+	if len(config) > 0 {
+		if err := json.Unmarshal(config, &m.config); err != nil {
+			return err
+		}
+	}
+	if m.config.Action == "" {
+		m.config.Action = RedactionMask
+	}
+	if m.config.Mask == "" {
+		m.config.Mask = "[REDACTED:{name}]"
+	}
+
+	patterns := BuiltinRedactionPatterns
+	if len(m.config.Builtins) > 0 {
+		patterns = nil
+		for _, name := range m.config.Builtins {
+			p, ok := builtinRedactionPattern(name)
+			if !ok {
+				return fmt.Errorf("unknown builtin pattern %q", name)
+			}
+			patterns = append(patterns, p)
+		}
+	}
+	patterns = append(append([]RedactionPattern{}, patterns...), m.config.Patterns...)
+
+	m.rules = nil
+	for _, p := range patterns {
+		if p.Name == "" {
+			return fmt.Errorf("pattern %q has no name", p.Regex)
+		}
+		re, err := regexp.Compile(p.Regex)
+		if err != nil {
+			return fmt.Errorf("pattern %s: %w", p.Name, err)
+		}
+		action := p.Action
+		if action == "" {
+			action = m.config.Action
+		}
+		if action != RedactionMask && action != RedactionBlock {
+			return fmt.Errorf("pattern %s: unknown action %q", p.Name, action)
+		}
+		m.rules = append(m.rules, &redactionRule{re: re, stats: RedactionPatternStats{Name: p.Name, Action: action}})
+	}
+	return nil
+}
+
+func builtinRedactionPattern(name string) (RedactionPattern, bool) {
+	for _, p := range BuiltinRedactionPatterns {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return RedactionPattern{}, false
+}
+
+func (m *RedactionMiddleware) ProcessRequest(ctx *RequestContext) (*RequestContext, error) {
+	if len(ctx.Body) == 0 || len(m.rules) == 0 {
+		return ctx, nil
+	}
+	var body interface{}
+	if err := json.Unmarshal(ctx.Body, &body); err != nil {
+		return ctx, nil
+	}
+
+	// Rules apply in order, so text masked by one rule (an Anthropic key)
+	// is not counted again by a broader one (an OpenAI key)
+	counts := make([]int, len(m.rules))
+	body = walkStrings(body, func(s string) string {
+		for i, rule := range m.rules {
+			n := len(rule.re.FindAllStringIndex(s, -1))
+			if n == 0 {
+				continue
+			}
+			counts[i] += n
+			s = rule.re.ReplaceAllLiteralString(s, strings.ReplaceAll(m.config.Mask, "{name}", rule.stats.Name))
+		}
+		return s
+	})
+
+	var blocked []string
+	found := false
+	m.mu.Lock()
+	for i, rule := range m.rules {
+		if counts[i] == 0 {
+			continue
+		}
+		found = true
+		rule.stats.Matches += int64(counts[i])
+		rule.stats.Requests++
+		if rule.stats.Action == RedactionBlock {
+			rule.stats.Blocked++
+			blocked = append(blocked, rule.stats.Name)
+		}
+	}
+	m.mu.Unlock()
+
+	if len(blocked) > 0 {
+		return nil, fmt.Errorf("request blocked: found %s; remove it from the conversation and retry", strings.Join(blocked, ", "))
+	}
+	if !found {
+		return ctx, nil
+	}
+
+	newBody, err := json.Marshal(body)
+	if err != nil {
+		return ctx, nil
+	}
+
+	ctx.Body = newBody
+	if bodyMap, ok := body.(map[string]interface{}); ok {
+		updateContextMessages(ctx, bodyMap)
+	}
+	return ctx, nil
+}
+
+// updateContextMessages refreshes ctx.Messages from a rewritten body.
+func updateContextMessages(ctx *RequestContext, body map[string]interface{}) {
+	msgs, ok := body["messages"].([]interface{})
+	if !ok {
+		return
+	}
+	ctx.Messages = make([]Message, 0, len(msgs))
+	for _, msg := range msgs {
+		if msgMap, ok := msg.(map[string]interface{}); ok {
+			role, _ := msgMap["role"].(string)
+			ctx.Messages = append(ctx.Messages, Message{Role: role, Content: msgMap["content"]})
+		}
+	}
+}
+
+// walkStrings replaces every string in a decoded JSON value with fn's
+// result. Base64 payloads such as images are left alone.
+func walkStrings(v interface{}, fn func(string) string) interface{} {
+	switch val := v.(type) {
+	case string:
+		return fn(val)
+	case []interface{}:
+		for i, item := range val {
+			val[i] = walkStrings(item, fn)
+		}
+	case map[string]interface{}:
+		isBase64 := val["type"] == "base64"
+		for k, item := range val {
+			if isBase64 && k == "data" {
+				continue
+			}
+			val[k] = walkStrings(item, fn)
+		}
+	}
+	return v
+}
+
+func (m *RedactionMiddleware) ProcessResponse(ctx *ResponseContext) (*ResponseContext, error) {
+	return ctx, nil
+}
+
+func (m *RedactionMiddleware) Close() error {
+	return nil
+}
+
+// Stats returns per-pattern statistics in configuration order.
+func (m *RedactionMiddleware) Stats() interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := make([]RedactionPatternStats, len(m.rules))
+	for i, rule := range m.rules {
+		stats[i] = rule.stats
+	}
+	return stats
+}
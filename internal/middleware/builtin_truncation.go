@@ -0,0 +1,207 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ToolResultTruncationConfig holds configuration for the tool-result
+// truncation middleware.
+type ToolResultTruncationConfig struct {
+	MaxBytes       int    `json:"max_bytes,omitempty"`       // largest tool result left intact (default: 32768)
+	HeadBytes      int    `json:"head_bytes,omitempty"`      // bytes kept from the start (default: two thirds of max_bytes)
+	Marker         string `json:"marker,omitempty"`          // inserted at the cut, {omitted} is replaced by the bytes removed
+	PreserveLatest bool   `json:"preserve_latest,omitempty"` // leave tool results in the last message intact
+}
+
+// ToolResultTruncationStats counts what the middleware has cut.
+type ToolResultTruncationStats struct {
+	Requests     int64 `json:"requests"`      // requests with at least one truncated result
+	Truncated    int64 `json:"truncated"`     // tool results truncated
+	BytesRemoved int64 `json:"bytes_removed"` // bytes cut from tool results
+}
+
+const (
+	defaultToolResultMaxBytes = 32 << 10
+	defaultToolResultMarker   = "\n\n[... {omitted} bytes truncated ...]\n\n"
+)
+
+// ToolResultTruncationMiddleware shortens oversized tool results (file reads,
+// command output) to their head and tail before they are sent upstream.
+// It handles Anthropic tool_result blocks, OpenAI tool messages and
+// Responses API function_call_output items.
+type ToolResultTruncationMiddleware struct {
+	config ToolResultTruncationConfig
+	mu     sync.Mutex // guards stats
+	stats  ToolResultTruncationStats
+}
+
+// NewToolResultTruncation creates a new tool-result truncation middleware.
+func NewToolResultTruncation() Middleware {
+	return &ToolResultTruncationMiddleware{}
+}
+
+func (m *ToolResultTruncationMiddleware) Name() string {
+	return "tool-result-truncation"
+}
+
+func (m *ToolResultTruncationMiddleware) Version() string {
+	return "1.0.0"
+}
+
+func (m *ToolResultTruncationMiddleware) Description() string {
+	return "Truncates oversized tool results, keeping their head and tail"
+}
+
+func (m *ToolResultTruncationMiddleware) Priority() int {
+	return 5 // First, so later middleware and compression see the smaller body
+}
+
+func (m *ToolResultTruncationMiddleware) Init(config json.RawMessage) error {
+	if len(config) > 0 {
+		if err := json.Unmarshal(config, &m.config); err != nil {
+			return err
+		}
+	}
+	if m.config.MaxBytes == 0 {
+		m.config.MaxBytes = defaultToolResultMaxBytes
+	}
+	if m.config.Marker == "" {
+		m.config.Marker = defaultToolResultMarker
+	}
+	if m.config.HeadBytes == 0 {
+		m.config.HeadBytes = m.config.MaxBytes * 2 / 3
+	}
+	if m.config.MaxBytes < 0 || m.config.HeadBytes < 0 || m.config.HeadBytes > m.config.MaxBytes {
+		return fmt.Errorf("invalid limits: max_bytes %d, head_bytes %d", m.config.MaxBytes, m.config.HeadBytes)
+	}
+	return nil
+}
+
+func (m *ToolResultTruncationMiddleware) ProcessRequest(ctx *RequestContext) (*RequestContext, error) {
+	if len(ctx.Body) <= m.config.MaxBytes {
+		return ctx, nil
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(ctx.Body, &body); err != nil {
+		return ctx, nil
+	}
+
+	var truncated, removed int64
+	cut := func(s string) string {
+		out, n := m.truncate(s)
+		if n > 0 {
+			truncated++
+			removed += int64(n)
+		}
+		return out
+	}
+
+	if msgs, ok := body["messages"].([]interface{}); ok {
+		last := len(msgs) - 1
+		for i, msg := range msgs {
+			msgMap, ok := msg.(map[string]interface{})
+			if !ok || (m.config.PreserveLatest && i == last) {
+				continue
+			}
+			if msgMap["role"] == "tool" {
+				// OpenAI chat tool message
+				msgMap["content"] = truncateContent(msgMap["content"], cut)
+				continue
+			}
+			blocks, ok := msgMap["content"].([]interface{})
+			if !ok {
+				continue
+			}
+			for _, block := range blocks {
+				if b, ok := block.(map[string]interface{}); ok && b["type"] == "tool_result" {
+					b["content"] = truncateContent(b["content"], cut)
+				}
+			}
+		}
+	}
+	if items, ok := body["input"].([]interface{}); ok {
+		last := len(items) - 1
+		for i, item := range items {
+			itemMap, ok := item.(map[string]interface{})
+			if !ok || itemMap["type"] != "function_call_output" || (m.config.PreserveLatest && i == last) {
+				continue
+			}
+			itemMap["output"] = truncateContent(itemMap["output"], cut)
+		}
+	}
+
+	if truncated == 0 {
+		return ctx, nil
+	}
+	newBody, err := json.Marshal(body)
+	if err != nil {
+		return ctx, nil
+	}
+
+	m.mu.Lock()
+	m.stats.Requests++
+	m.stats.Truncated += truncated
+	m.stats.BytesRemoved += removed
+	m.mu.Unlock()
+
+	ctx.Body = newBody
+	updateContextMessages(ctx, body)
+	return ctx, nil
+}
+
+// truncateContent applies cut to tool result content, which is either a
+// string or a list of content blocks whose text is cut individually.
+func truncateContent(content interface{}, cut func(string) string) interface{} {
+	switch v := content.(type) {
+	case string:
+		return cut(v)
+	case []interface{}:
+		for _, block := range v {
+			if b, ok := block.(map[string]interface{}); ok {
+				if text, ok := b["text"].(string); ok {
+					b["text"] = cut(text)
+				}
+			}
+		}
+	}
+	return content
+}
+
+// truncate keeps the head and tail of s if it is over the limit, returning
+// the result and the number of bytes removed.
+func (m *ToolResultTruncationMiddleware) truncate(s string) (string, int) {
+	if len(s) <= m.config.MaxBytes {
+		return s, 0
+	}
+	head := m.config.HeadBytes
+	tail := m.config.MaxBytes - head
+	for head > 0 && !utf8.RuneStart(s[head]) {
+		head--
+	}
+	tailStart := len(s) - tail
+	for tailStart < len(s) && !utf8.RuneStart(s[tailStart]) {
+		tailStart++
+	}
+	omitted := tailStart - head
+	marker := strings.ReplaceAll(m.config.Marker, "{omitted}", fmt.Sprint(omitted))
+	return s[:head] + marker + s[tailStart:], omitted
+}
+
+func (m *ToolResultTruncationMiddleware) ProcessResponse(ctx *ResponseContext) (*ResponseContext, error) {
+	return ctx, nil
+}
+
+func (m *ToolResultTruncationMiddleware) Close() error {
+	return nil
+}
+
+// Stats implements StatsReporter.
+func (m *ToolResultTruncationMiddleware) Stats() interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stats
+}
@@ -0,0 +1,226 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Callout failure policies.
+const (
+	FailOpen   = "open"   // let the request through unchanged
+	FailClosed = "closed" // reject the request
+)
+
+// CalloutConfig configures an HTTP callout middleware.
+type CalloutConfig struct {
+	Priority      int               `json:"priority,omitempty"`       // execution order (default: 100)
+	TimeoutMs     int               `json:"timeout_ms,omitempty"`     // per callout (default: 2000)
+	FailurePolicy string            `json:"failure_policy,omitempty"` // "open" (default) or "closed"
+	Secret        string            `json:"secret,omitempty"`         // HMAC-SHA256 key for X-Zen-Signature
+	OnResponse    bool              `json:"on_response,omitempty"`    // also call out for responses
+	Headers       map[string]string `json:"headers,omitempty"`        // extra headers sent with each callout
+}
+
+// CalloutStats counts callout outcomes.
+type CalloutStats struct {
+	Calls      int64 `json:"calls"`
+	Modified   int64 `json:"modified"`
+	Rejected   int64 `json:"rejected"`
+	Failures   int64 `json:"failures"`
+	FailedOpen int64 `json:"failed_open"`
+}
+
+const defaultCalloutTimeout = 2 * time.Second
+
+// CalloutMiddleware POSTs each request (and optionally response) to an
+// external endpoint, which can modify or reject it.
+//
+// The callout body is the same JSON document WASM plugins receive, with the
+// phase in the X-Zen-Phase header ("request" or "response"). The endpoint
+// answers 204 (or an empty 200) to leave things unchanged, or 200 with
+// {"body": ..., "headers": {...}, "error": ...}. When a secret is set, each
+// callout carries X-Zen-Timestamp and X-Zen-Signature: sha256=HMAC(secret,
+// timestamp + "." + body).
+type CalloutMiddleware struct {
+	name   string
+	url    string
+	config CalloutConfig
+	client *http.Client
+	logger *log.Logger
+
+	mu    sync.Mutex // guards stats
+	stats CalloutStats
+}
+
+// NewCallout creates an HTTP callout middleware for the given endpoint.
+func NewCallout(name, url string, logger *log.Logger) *CalloutMiddleware {
+	return &CalloutMiddleware{name: name, url: url, logger: logger}
+}
+
+func (m *CalloutMiddleware) Name() string {
+	return m.name
+}
+
+func (m *CalloutMiddleware) Version() string {
+	return "http"
+}
+
+func (m *CalloutMiddleware) Description() string {
+	return "HTTP callout to " + m.url
+}
+
+func (m *CalloutMiddleware) Priority() int {
+	return m.config.Priority
+}
+
+func (m *CalloutMiddleware) Init(config json.RawMessage) error {
+	if len(config) > 0 {
+		if err := json.Unmarshal(config, &m.config); err != nil {
+			return err
+		}
+	}
+	if m.config.Priority == 0 {
+		m.config.Priority = defaultPluginPriority
+	}
+	switch m.config.FailurePolicy {
+	case "":
+		m.config.FailurePolicy = FailOpen
+	case FailOpen, FailClosed:
+	default:
+		return fmt.Errorf("unknown failure_policy %q (want %q or %q)", m.config.FailurePolicy, FailOpen, FailClosed)
+	}
+	timeout := defaultCalloutTimeout
+	if m.config.TimeoutMs > 0 {
+		timeout = time.Duration(m.config.TimeoutMs) * time.Millisecond
+	}
+	m.client = &http.Client{Timeout: timeout}
+	return nil
+}
+
+func (m *CalloutMiddleware) ProcessRequest(ctx *RequestContext) (*RequestContext, error) {
+	result, err := m.call("request", newPluginRequest(ctx))
+	if err != nil || result == nil {
+		return ctx, err
+	}
+	return ctx, result.applyRequest(ctx)
+}
+
+func (m *CalloutMiddleware) ProcessResponse(ctx *ResponseContext) (*ResponseContext, error) {
+	if !m.config.OnResponse {
+		return ctx, nil
+	}
+	result, err := m.call("response", newPluginResponse(ctx))
+	if err != nil || result == nil {
+		return ctx, err
+	}
+	return ctx, result.applyResponse(ctx)
+}
+
+// call sends doc to the endpoint. Transport and endpoint failures follow the
+// failure policy; a nil result means the endpoint left things unchanged.
+func (m *CalloutMiddleware) call(phase string, doc interface{}) (*pluginResult, error) {
+	m.count(func(s *CalloutStats) { s.Calls++ })
+	result, err := m.post(phase, doc)
+	if err != nil {
+		if m.config.FailurePolicy == FailClosed {
+			m.count(func(s *CalloutStats) { s.Failures++ })
+			return nil, fmt.Errorf("callout failed: %w", err)
+		}
+		m.count(func(s *CalloutStats) { s.Failures++; s.FailedOpen++ })
+		if m.logger != nil {
+			m.logger.Printf("[middleware:%s] callout failed, letting %s through: %v", m.name, phase, err)
+		}
+		return nil, nil
+	}
+	switch {
+	case result == nil:
+	case result.Error != "":
+		m.count(func(s *CalloutStats) { s.Rejected++ })
+	default:
+		m.count(func(s *CalloutStats) { s.Modified++ })
+	}
+	return result, nil
+}
+
+func (m *CalloutMiddleware) post(phase string, doc interface{}) (*pluginResult, error) {
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, m.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Zen-Phase", phase)
+	for k, v := range m.config.Headers {
+		req.Header.Set(k, v)
+	}
+	if m.config.Secret != "" {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set("X-Zen-Timestamp", ts)
+		req.Header.Set("X-Zen-Signature", "sha256="+SignCallout(m.config.Secret, ts, body))
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 32<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+	if resp.StatusCode == http.StatusNoContent || len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+	var result pluginResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, errors.New("endpoint returned invalid JSON")
+	}
+	return &result, nil
+}
+
+// SignCallout returns the hex HMAC-SHA256 signature of a callout body, for
+// endpoints verifying X-Zen-Signature.
+func SignCallout(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (m *CalloutMiddleware) count(fn func(*CalloutStats)) {
+	m.mu.Lock()
+	fn(&m.stats)
+	m.mu.Unlock()
+}
+
+// Stats implements StatsReporter.
+func (m *CalloutMiddleware) Stats() interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stats
+}
+
+func (m *CalloutMiddleware) Close() error {
+	if m.client != nil {
+		m.client.CloseIdleConnections()
+	}
+	return nil
+}
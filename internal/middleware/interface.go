@@ -39,6 +39,16 @@ type Middleware interface {
 	Close() error
 }
 
+// defaultPluginPriority is the priority of user middleware that does not
+// set one.
+const defaultPluginPriority = 100
+
+// StatsReporter is implemented by middleware that exposes runtime
+// statistics through the middleware API.
+type StatsReporter interface {
+	Stats() interface{}
+}
+
 // RequestContext contains all request information passed through the middleware pipeline.
 type RequestContext struct {
 	// Request metadata
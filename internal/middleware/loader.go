@@ -11,6 +11,8 @@ import (
 	"path/filepath"
 	"plugin"
 	"runtime"
+
+	"github.com/dopejs/gozen/internal/config"
 )
 
 // PluginLoader handles loading middleware plugins from various sources.
@@ -21,8 +23,7 @@ type PluginLoader struct {
 // NewPluginLoader creates a new plugin loader.
 func NewPluginLoader(pluginDir string) *PluginLoader {
 	if pluginDir == "" {
-		home, _ := os.UserHomeDir()
-		pluginDir = filepath.Join(home, ".zen", "plugins")
+		pluginDir = filepath.Join(config.ConfigDirPath(), "plugins")
 	}
 	os.MkdirAll(pluginDir, 0755)
 	return &PluginLoader{pluginDir: pluginDir}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// matchRequest reports whether ctx satisfies every condition of when. A nil
+// match accepts all requests.
+func matchRequest(when *config.MiddlewareMatch, ctx *RequestContext) bool {
+	if when == nil {
+		return true
+	}
+	if len(when.Providers) > 0 && !matchAny(when.Providers, ctx.Provider) {
+		return false
+	}
+	if len(when.ExcludeProviders) > 0 && matchAny(when.ExcludeProviders, ctx.Provider) {
+		return false
+	}
+	if len(when.Models) > 0 && !matchAny(when.Models, ctx.Model) {
+		return false
+	}
+	if len(when.Projects) > 0 && !matchProject(when.Projects, ctx.ProjectPath) {
+		return false
+	}
+	for name, pattern := range when.Headers {
+		if !matchGlob(pattern, ctx.Headers.Get(name)) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchAny(patterns []string, value string) bool {
+	for _, p := range patterns {
+		if matchGlob(p, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchProject matches a project directory against directories, which also
+// cover their subdirectories, and patterns.
+func matchProject(patterns []string, dir string) bool {
+	if dir == "" {
+		return false
+	}
+	for _, p := range patterns {
+		if strings.Contains(p, "*") {
+			if matchGlob(p, dir) {
+				return true
+			}
+			continue
+		}
+		p = strings.TrimSuffix(p, "/")
+		if dir == p || strings.HasPrefix(dir, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob reports whether value matches pattern, ignoring case.
+// A * in the pattern matches any run of characters, including none.
+func matchGlob(pattern, value string) bool {
+	pattern = strings.ToLower(pattern)
+	value = strings.ToLower(value)
+	if !strings.Contains(pattern, "*") {
+		return pattern == value
+	}
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(value, parts[0]) {
+		return false
+	}
+	value = value[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(value, part)
+		if i < 0 {
+			return false
+		}
+		value = value[i+len(part):]
+	}
+	return strings.HasSuffix(value, last)
+}
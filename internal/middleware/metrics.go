@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+)
+
+// PhaseStats summarizes the calls of one middleware phase.
+type PhaseStats struct {
+	Calls   int64   `json:"calls"`
+	Errors  int64   `json:"errors"`
+	TotalMs float64 `json:"total_ms"`
+	AvgMs   float64 `json:"avg_ms"`
+	MaxMs   float64 `json:"max_ms"`
+	LastMs  float64 `json:"last_ms"`
+}
+
+// MiddlewareStats reports how long a middleware spends in each phase and how
+// often it fails, since it was loaded.
+type MiddlewareStats struct {
+	Name       string     `json:"name"`
+	Request    PhaseStats `json:"request"`
+	Response   PhaseStats `json:"response"`
+	Rejections int64      `json:"rejections"` // requests aborted by ProcessRequest errors
+	Since      time.Time  `json:"since"`
+}
+
+// middlewareMetrics accumulates MiddlewareStats for a loaded middleware.
+type middlewareMetrics struct {
+	mu    sync.Mutex
+	stats MiddlewareStats
+}
+
+func newMiddlewareMetrics(name string) *middlewareMetrics {
+	return &middlewareMetrics{stats: MiddlewareStats{Name: name, Since: time.Now()}}
+}
+
+func (mm *middlewareMetrics) record(response bool, d time.Duration, err error) {
+	ms := float64(d) / float64(time.Millisecond)
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	ps := &mm.stats.Request
+	if response {
+		ps = &mm.stats.Response
+	}
+	ps.Calls++
+	ps.TotalMs += ms
+	ps.LastMs = ms
+	if ms > ps.MaxMs {
+		ps.MaxMs = ms
+	}
+	if err != nil {
+		ps.Errors++
+		if !response {
+			mm.stats.Rejections++
+		}
+	}
+}
+
+func (mm *middlewareMetrics) snapshot() MiddlewareStats {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	s := mm.stats
+	for _, ps := range []*PhaseStats{&s.Request, &s.Response} {
+		if ps.Calls > 0 {
+			ps.AvgMs = ps.TotalMs / float64(ps.Calls)
+		}
+	}
+	return s
+}
@@ -5,11 +5,21 @@ import (
 	"log"
 	"sort"
 	"sync"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
 )
 
+// middlewareState is what the pipeline tracks for each loaded middleware.
+type middlewareState struct {
+	metrics *middlewareMetrics
+	when    *config.MiddlewareMatch
+}
+
 // Pipeline manages the middleware execution chain.
 type Pipeline struct {
 	middlewares []Middleware
+	state       map[string]*middlewareState // keyed by middleware name
 	enabled     bool
 	mu          sync.RWMutex
 	logger      *log.Logger
@@ -19,6 +29,7 @@ type Pipeline struct {
 func NewPipeline(logger *log.Logger) *Pipeline {
 	return &Pipeline{
 		middlewares: make([]Middleware, 0),
+		state:       make(map[string]*middlewareState),
 		enabled:     false,
 		logger:      logger,
 	}
@@ -40,9 +51,15 @@ func (p *Pipeline) IsEnabled() bool {
 
 // Add adds a middleware to the pipeline.
 func (p *Pipeline) Add(m Middleware) {
+	p.AddWhen(m, nil)
+}
+
+// AddWhen adds a middleware that only runs for requests matching when.
+func (p *Pipeline) AddWhen(m Middleware, when *config.MiddlewareMatch) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	p.middlewares = append(p.middlewares, m)
+	p.state[m.Name()] = &middlewareState{metrics: newMiddlewareMetrics(m.Name()), when: when}
 	p.sortMiddlewares()
 }
 
@@ -53,6 +70,7 @@ func (p *Pipeline) Remove(name string) {
 	for i, m := range p.middlewares {
 		if m.Name() == name {
 			p.middlewares = append(p.middlewares[:i], p.middlewares[i+1:]...)
+			delete(p.state, name)
 			return
 		}
 	}
@@ -90,6 +108,51 @@ func (p *Pipeline) Clear() {
 		}
 	}
 	p.middlewares = make([]Middleware, 0)
+	p.state = make(map[string]*middlewareState)
+}
+
+// Stats returns the latency and error counts of a loaded middleware.
+func (p *Pipeline) Stats(name string) (MiddlewareStats, bool) {
+	p.mu.RLock()
+	st, ok := p.state[name]
+	p.mu.RUnlock()
+	if !ok {
+		return MiddlewareStats{}, false
+	}
+	return st.metrics.snapshot(), true
+}
+
+// HasProviderConditions reports whether any middleware is restricted by
+// provider, and so must run through ProcessProviderRequest.
+func (p *Pipeline) HasProviderConditions() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if !p.enabled {
+		return false
+	}
+	for _, st := range p.state {
+		if st.when.HasProviderCondition() {
+			return true
+		}
+	}
+	return false
+}
+
+// snapshot copies the middleware chain with each middleware's state.
+func (p *Pipeline) snapshot() ([]Middleware, []*middlewareState) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	middlewares := make([]Middleware, len(p.middlewares))
+	copy(middlewares, p.middlewares)
+	state := make([]*middlewareState, len(middlewares))
+	for i, m := range middlewares {
+		if st, ok := p.state[m.Name()]; ok {
+			state[i] = st
+		} else {
+			state[i] = &middlewareState{metrics: newMiddlewareMetrics(m.Name())}
+		}
+	}
+	return middlewares, state
 }
 
 // sortMiddlewares sorts middlewares by priority (lower = earlier).
@@ -99,24 +162,39 @@ func (p *Pipeline) sortMiddlewares() {
 	})
 }
 
-// ProcessRequest runs all middlewares' ProcessRequest in priority order.
+// ProcessRequest runs all middlewares' ProcessRequest in priority order,
+// skipping those whose match conditions exclude the request. Middleware
+// restricted by provider is left to ProcessProviderRequest.
 // Returns the modified context or an error if any middleware fails.
 func (p *Pipeline) ProcessRequest(ctx *RequestContext) (*RequestContext, error) {
+	return p.processRequest(ctx, false)
+}
+
+// ProcessProviderRequest runs the middleware restricted by provider, for a
+// request about to be sent to ctx.Provider.
+func (p *Pipeline) ProcessProviderRequest(ctx *RequestContext) (*RequestContext, error) {
+	return p.processRequest(ctx, true)
+}
+
+func (p *Pipeline) processRequest(ctx *RequestContext, providerPhase bool) (*RequestContext, error) {
 	if !p.IsEnabled() {
 		return ctx, nil
 	}
 
-	p.mu.RLock()
-	middlewares := make([]Middleware, len(p.middlewares))
-	copy(middlewares, p.middlewares)
-	p.mu.RUnlock()
+	middlewares, state := p.snapshot()
 
 	var err error
-	for _, m := range middlewares {
+	for i, m := range middlewares {
+		st := state[i]
+		if st.when.HasProviderCondition() != providerPhase || !matchRequest(st.when, ctx) {
+			continue
+		}
 		if p.logger != nil {
 			p.logger.Printf("[middleware] %s.ProcessRequest", m.Name())
 		}
+		start := time.Now()
 		ctx, err = m.ProcessRequest(ctx)
+		st.metrics.record(false, time.Since(start), err)
 		if err != nil {
 			return nil, fmt.Errorf("middleware %s: %w", m.Name(), err)
 		}
@@ -131,19 +209,22 @@ func (p *Pipeline) ProcessResponse(ctx *ResponseContext) (*ResponseContext, erro
 		return ctx, nil
 	}
 
-	p.mu.RLock()
-	middlewares := make([]Middleware, len(p.middlewares))
-	copy(middlewares, p.middlewares)
-	p.mu.RUnlock()
+	middlewares, state := p.snapshot()
 
 	// Process in reverse order for response
 	var err error
 	for i := len(middlewares) - 1; i >= 0; i-- {
 		m := middlewares[i]
+		st := state[i]
+		if ctx.Request != nil && !matchRequest(st.when, ctx.Request) {
+			continue
+		}
 		if p.logger != nil {
 			p.logger.Printf("[middleware] %s.ProcessResponse", m.Name())
 		}
+		start := time.Now()
 		ctx, err = m.ProcessResponse(ctx)
+		st.metrics.record(true, time.Since(start), err)
 		if err != nil {
 			return nil, fmt.Errorf("middleware %s: %w", m.Name(), err)
 		}
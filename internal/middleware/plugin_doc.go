@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+)
+
+// pluginRequest is the JSON document external middleware (WASM plugins and
+// HTTP callouts) receive for a request.
+type pluginRequest struct {
+	SessionID     string      `json:"session_id"`
+	Profile       string      `json:"profile"`
+	Provider      string      `json:"provider"`
+	ClientType    string      `json:"client_type"`
+	ProjectPath   string      `json:"project_path"`
+	Method        string      `json:"method"`
+	Path          string      `json:"path"`
+	Model         string      `json:"model"`
+	RequestFormat string      `json:"request_format,omitempty"`
+	Headers       http.Header `json:"headers"`
+	Body          string      `json:"body"`
+}
+
+// pluginResponse is the JSON document external middleware receive for a
+// response.
+type pluginResponse struct {
+	Request      pluginRequest `json:"request"`
+	StatusCode   int           `json:"status_code"`
+	Headers      http.Header   `json:"headers"`
+	Body         string        `json:"body"`
+	InputTokens  int           `json:"input_tokens"`
+	OutputTokens int           `json:"output_tokens"`
+}
+
+// pluginResult is what external middleware return. Absent fields leave the
+// context unchanged; a non-empty Error rejects the request.
+type pluginResult struct {
+	Body    *string           `json:"body,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Error   string            `json:"error,omitempty"`
+}
+
+func newPluginRequest(ctx *RequestContext) pluginRequest {
+	return pluginRequest{
+		SessionID:     ctx.SessionID,
+		Profile:       ctx.Profile,
+		Provider:      ctx.Provider,
+		ClientType:    ctx.ClientType,
+		ProjectPath:   ctx.ProjectPath,
+		Method:        ctx.Method,
+		Path:          ctx.Path,
+		Model:         ctx.Model,
+		RequestFormat: ctx.RequestFormat,
+		Headers:       ctx.Headers,
+		Body:          string(ctx.Body),
+	}
+}
+
+func newPluginResponse(ctx *ResponseContext) pluginResponse {
+	doc := pluginResponse{
+		StatusCode:   ctx.StatusCode,
+		Headers:      ctx.Headers,
+		Body:         string(ctx.Body),
+		InputTokens:  ctx.InputTokens,
+		OutputTokens: ctx.OutputTokens,
+	}
+	if ctx.Request != nil {
+		doc.Request = newPluginRequest(ctx.Request)
+	}
+	return doc
+}
+
+func (r *pluginResult) apply(body *[]byte, headers *http.Header) error {
+	if r.Error != "" {
+		return errors.New(r.Error)
+	}
+	if r.Body != nil {
+		*body = []byte(*r.Body)
+	}
+	for k, v := range r.Headers {
+		if *headers == nil {
+			*headers = make(http.Header)
+		}
+		headers.Set(k, v)
+	}
+	return nil
+}
+
+func (r *pluginResult) applyRequest(ctx *RequestContext) error {
+	return r.apply(&ctx.Body, &ctx.Headers)
+}
+
+func (r *pluginResult) applyResponse(ctx *ResponseContext) error {
+	return r.apply(&ctx.Body, &ctx.Headers)
+}
@@ -73,6 +73,8 @@ func (r *Registry) registerBuiltins() {
 	r.builtins["context-injection"] = NewContextInjection
 	r.builtins["session-memory"] = NewSessionMemory
 	r.builtins["orchestration"] = NewOrchestration
+	r.builtins["redaction"] = NewRedaction
+	r.builtins["tool-result-truncation"] = NewToolResultTruncation
 }
 
 // RegisterBuiltin registers a built-in middleware factory.
@@ -185,6 +187,24 @@ func (r *Registry) loadMiddleware(entry *config.MiddlewareEntry) error {
 			return fmt.Errorf("failed to load remote plugin: %w", err)
 		}
 
+	case "wasm":
+		if entry.Path == "" {
+			return fmt.Errorf("wasm middleware requires 'path' field")
+		}
+		m, err = LoadWasm(entry.Name, entry.Path, r.logger)
+		if err != nil {
+			return fmt.Errorf("failed to load wasm plugin: %w", err)
+		}
+
+	case "script":
+		m = NewScript(entry.Name, entry.Path, r.logger)
+
+	case "http":
+		if entry.URL == "" {
+			return fmt.Errorf("http middleware requires 'url' field")
+		}
+		m = NewCallout(entry.Name, entry.URL, r.logger)
+
 	default:
 		return fmt.Errorf("unknown middleware source: %s", entry.Source)
 	}
@@ -194,7 +214,7 @@ func (r *Registry) loadMiddleware(entry *config.MiddlewareEntry) error {
 		return fmt.Errorf("init failed: %w", err)
 	}
 
-	r.pipeline.Add(m)
+	r.pipeline.AddWhen(m, entry.When)
 	if r.logger != nil {
 		r.logger.Printf("[middleware] loaded %s v%s (source=%s, priority=%d)", m.Name(), m.Version(), entry.Source, m.Priority())
 	}
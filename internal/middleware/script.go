@@ -0,0 +1,398 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"runtime/metrics"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+// ScriptConfig configures a script middleware.
+type ScriptConfig struct {
+	Script       string `json:"script,omitempty"`         // inline JavaScript source
+	File         string `json:"file,omitempty"`           // path to a .js file, used when script is empty
+	Priority     int    `json:"priority,omitempty"`       // execution order (default: 100)
+	TimeoutMs    int    `json:"timeout_ms,omitempty"`     // per hook call (default: 50)
+	MaxMemoryMB  int    `json:"max_memory_mb,omitempty"`  // heap growth allowed per hook call (default: 32)
+	MaxCallStack int    `json:"max_call_stack,omitempty"` // JS call depth (default: 256)
+}
+
+const (
+	defaultScriptTimeout   = 50 * time.Millisecond
+	defaultScriptMemoryMB  = 32
+	defaultScriptCallStack = 256
+)
+
+var (
+	errScriptTimeout = errors.New("script exceeded its time budget")
+	errScriptMemory  = errors.New("script exceeded its memory budget")
+)
+
+// ScriptMiddleware runs a JavaScript snippet against request and response
+// JSON. The script defines onRequest(req) and/or onResponse(res); each may
+// modify its argument in place or return a replacement, and throws to reject
+// the request. req.body and res.body are parsed JSON when the body is JSON.
+type ScriptMiddleware struct {
+	name    string
+	path    string
+	config  ScriptConfig
+	program *goja.Program
+	logger  *log.Logger
+	timeout time.Duration
+	pool    sync.Pool // *scriptRuntime
+}
+
+// scriptRuntime is a JS runtime with the script loaded. Runtimes are not
+// safe for concurrent use, so each request borrows one from the pool.
+type scriptRuntime struct {
+	vm          *goja.Runtime
+	onRequest   goja.Callable
+	onResponse  goja.Callable
+	parse       goja.Callable
+	stringify   goja.Callable
+	budgetError error
+}
+
+// NewScript creates a script middleware. path, if set, is used as the
+// script file when the config has neither script nor file.
+func NewScript(name, path string, logger *log.Logger) *ScriptMiddleware {
+	return &ScriptMiddleware{name: name, path: path, logger: logger}
+}
+
+func (m *ScriptMiddleware) Name() string {
+	return m.name
+}
+
+func (m *ScriptMiddleware) Version() string {
+	return "script"
+}
+
+func (m *ScriptMiddleware) Description() string {
+	return "JavaScript middleware"
+}
+
+func (m *ScriptMiddleware) Priority() int {
+	return m.config.Priority
+}
+
+func (m *ScriptMiddleware) Init(config json.RawMessage) error {
+	if len(config) > 0 {
+		if err := json.Unmarshal(config, &m.config); err != nil {
+			return err
+		}
+	}
+	if m.config.Priority == 0 {
+		m.config.Priority = defaultPluginPriority
+	}
+	if m.config.MaxMemoryMB <= 0 {
+		m.config.MaxMemoryMB = defaultScriptMemoryMB
+	}
+	if m.config.MaxCallStack <= 0 {
+		m.config.MaxCallStack = defaultScriptCallStack
+	}
+	m.timeout = defaultScriptTimeout
+	if m.config.TimeoutMs > 0 {
+		m.timeout = time.Duration(m.config.TimeoutMs) * time.Millisecond
+	}
+
+	src := m.config.Script
+	file := m.config.File
+	if file == "" {
+		file = m.path
+	}
+	if src == "" && file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("read script: %w", err)
+		}
+		src = string(data)
+	}
+	if strings.TrimSpace(src) == "" {
+		return errors.New("script middleware requires 'script' or 'file'")
+	}
+
+	program, err := goja.Compile(m.name, src, true)
+	if err != nil {
+		return fmt.Errorf("compile script: %w", err)
+	}
+	m.program = program
+
+	// Load once up front so a broken script fails to load
+	rt, err := m.newRuntime()
+	if err != nil {
+		return err
+	}
+	if rt.onRequest == nil && rt.onResponse == nil {
+		return errors.New("script defines neither onRequest nor onResponse")
+	}
+	m.pool.Put(rt)
+	return nil
+}
+
+func (m *ScriptMiddleware) newRuntime() (*scriptRuntime, error) {
+	vm := goja.New()
+	vm.SetMaxCallStackSize(m.config.MaxCallStack)
+	rt := &scriptRuntime{vm: vm}
+	vm.Set("log", func(call goja.FunctionCall) goja.Value {
+		if m.logger != nil {
+			parts := make([]string, len(call.Arguments))
+			for i, arg := range call.Arguments {
+				parts[i] = arg.String()
+			}
+			m.logger.Printf("[middleware:%s] %s", m.name, strings.Join(parts, " "))
+		}
+		return goja.Undefined()
+	})
+
+	if err := m.withBudget(rt, func() error {
+		_, err := vm.RunProgram(m.program)
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("run script: %w", err)
+	}
+
+	rt.onRequest, _ = goja.AssertFunction(vm.Get("onRequest"))
+	rt.onResponse, _ = goja.AssertFunction(vm.Get("onResponse"))
+	jsonObj := vm.Get("JSON").ToObject(vm)
+	rt.parse, _ = goja.AssertFunction(jsonObj.Get("parse"))
+	rt.stringify, _ = goja.AssertFunction(jsonObj.Get("stringify"))
+	return rt, nil
+}
+
+// withBudget runs fn, interrupting the script when it exceeds its time
+// budget or grows the heap by more than its memory budget. Heap growth is
+// sampled process-wide, so the memory budget is approximate.
+func (m *ScriptMiddleware) withBudget(rt *scriptRuntime, fn func() error) error {
+	rt.budgetError = nil
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		limit := uint64(m.config.MaxMemoryMB) << 20
+		start := heapBytes()
+		deadline := time.NewTimer(m.timeout)
+		defer deadline.Stop()
+		ticker := time.NewTicker(5 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-deadline.C:
+				rt.budgetError = errScriptTimeout
+				rt.vm.Interrupt(errScriptTimeout)
+				return
+			case <-ticker.C:
+				if heap := heapBytes(); heap > start && heap-start > limit {
+					rt.budgetError = errScriptMemory
+					rt.vm.Interrupt(errScriptMemory)
+					return
+				}
+			}
+		}
+	}()
+
+	err := fn()
+	close(done)
+	wg.Wait()
+	rt.vm.ClearInterrupt()
+	if rt.budgetError != nil {
+		return rt.budgetError
+	}
+	return err
+}
+
+func heapBytes() uint64 {
+	sample := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
+
+func (m *ScriptMiddleware) acquire() (*scriptRuntime, error) {
+	if rt, ok := m.pool.Get().(*scriptRuntime); ok {
+		return rt, nil
+	}
+	return m.newRuntime()
+}
+
+// call passes doc to a hook and returns the resulting document as JSON, or
+// nil when the hook is absent or left the document unchanged.
+func (m *ScriptMiddleware) call(hook func(*scriptRuntime) goja.Callable, doc map[string]interface{}) (map[string]json.RawMessage, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	data := bytes.TrimSpace(buf.Bytes())
+	rt, err := m.acquire()
+	if err != nil {
+		return nil, err
+	}
+	fn := hook(rt)
+	if fn == nil {
+		m.pool.Put(rt)
+		return nil, nil
+	}
+
+	var out string
+	err = m.withBudget(rt, func() error {
+		arg, err := rt.parse(goja.Undefined(), rt.vm.ToValue(string(data)))
+		if err != nil {
+			return err
+		}
+		ret, err := fn(goja.Undefined(), arg)
+		if err != nil {
+			return err
+		}
+		if obj, ok := ret.(*goja.Object); ok {
+			arg = obj
+		}
+		s, err := rt.stringify(goja.Undefined(), arg)
+		if err != nil {
+			return err
+		}
+		out = s.String()
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, errScriptTimeout) || errors.Is(err, errScriptMemory) {
+			// An interrupted script may have left its globals half-updated
+			return nil, err
+		}
+		m.pool.Put(rt)
+		var exc *goja.Exception
+		if errors.As(err, &exc) {
+			return nil, errors.New(exc.Value().String())
+		}
+		return nil, err
+	}
+	m.pool.Put(rt)
+
+	var in, result map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(out), &result); err != nil {
+		return nil, fmt.Errorf("script returned invalid JSON: %w", err)
+	}
+	// Drop unchanged fields so untouched bodies keep their exact bytes
+	json.Unmarshal(data, &in)
+	for k, v := range result {
+		if bytes.Equal(v, in[k]) {
+			delete(result, k)
+		}
+	}
+	if len(result) == 0 {
+		return nil, nil
+	}
+	return result, nil
+}
+
+// scriptBody returns body as parsed JSON when it is JSON, else as a string.
+func scriptBody(body []byte) interface{} {
+	if len(body) > 0 && json.Valid(body) {
+		return json.RawMessage(body)
+	}
+	return string(body)
+}
+
+func scriptHeaders(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for k := range h {
+		out[k] = h.Get(k)
+	}
+	return out
+}
+
+func scriptRequest(ctx *RequestContext) map[string]interface{} {
+	return map[string]interface{}{
+		"session_id":     ctx.SessionID,
+		"profile":        ctx.Profile,
+		"provider":       ctx.Provider,
+		"client_type":    ctx.ClientType,
+		"project_path":   ctx.ProjectPath,
+		"method":         ctx.Method,
+		"path":           ctx.Path,
+		"model":          ctx.Model,
+		"request_format": ctx.RequestFormat,
+		"headers":        scriptHeaders(ctx.Headers),
+		"body":           scriptBody(ctx.Body),
+	}
+}
+
+// applyScriptResult copies the body and headers of a script result back.
+func applyScriptResult(result map[string]json.RawMessage, body *[]byte, headers *http.Header) error {
+	if raw, ok := result["body"]; ok {
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			*body = []byte(s)
+		} else {
+			*body = raw
+		}
+	}
+	if raw, ok := result["headers"]; ok {
+		var h map[string]string
+		if err := json.Unmarshal(raw, &h); err != nil {
+			return fmt.Errorf("script returned invalid headers: %w", err)
+		}
+		next := make(http.Header, len(h))
+		for k, v := range h {
+			next.Set(k, v)
+		}
+		// Keep multi-value headers the script did not touch
+		for k, vs := range *headers {
+			if len(vs) > 1 && next.Get(k) == vs[0] {
+				next[k] = vs
+			}
+		}
+		*headers = next
+	}
+	return nil
+}
+
+func (m *ScriptMiddleware) ProcessRequest(ctx *RequestContext) (*RequestContext, error) {
+	result, err := m.call(func(rt *scriptRuntime) goja.Callable { return rt.onRequest }, scriptRequest(ctx))
+	if err != nil || result == nil {
+		return ctx, err
+	}
+	if err := applyScriptResult(result, &ctx.Body, &ctx.Headers); err != nil {
+		return ctx, err
+	}
+	return ctx, nil
+}
+
+func (m *ScriptMiddleware) ProcessResponse(ctx *ResponseContext) (*ResponseContext, error) {
+	doc := map[string]interface{}{
+		"status_code":   ctx.StatusCode,
+		"headers":       scriptHeaders(ctx.Headers),
+		"body":          scriptBody(ctx.Body),
+		"input_tokens":  ctx.InputTokens,
+		"output_tokens": ctx.OutputTokens,
+	}
+	if ctx.Request != nil {
+		doc["request"] = scriptRequest(ctx.Request)
+	}
+	result, err := m.call(func(rt *scriptRuntime) goja.Callable { return rt.onResponse }, doc)
+	if err != nil || result == nil {
+		return ctx, err
+	}
+	if err := applyScriptResult(result, &ctx.Body, &ctx.Headers); err != nil {
+		return ctx, err
+	}
+	return ctx, nil
+}
+
+func (m *ScriptMiddleware) Close() error {
+	return nil
+}
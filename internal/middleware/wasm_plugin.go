@@ -0,0 +1,345 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// WasmPluginConfig holds the host settings of a WASM middleware. The
+// plugin's own settings are passed to its init export as JSON.
+type WasmPluginConfig struct {
+	Priority    int             `json:"priority,omitempty"`      // execution order (default: 100)
+	MaxMemoryMB int             `json:"max_memory_mb,omitempty"` // linear memory limit (default: 16)
+	TimeoutMs   int             `json:"timeout_ms,omitempty"`    // per hook call (default: 50)
+	Instances   int             `json:"instances,omitempty"`     // concurrent instances (default: 4)
+	Config      json.RawMessage `json:"config,omitempty"`        // passed to the plugin's init export
+}
+
+const (
+	defaultWasmMemoryMB  = 16
+	defaultWasmTimeout   = 50 * time.Millisecond
+	defaultWasmInstances = 4
+
+	wasmPageSize    = 64 * 1024
+	maxWasmMemoryMB = 4096 // the whole 32-bit address space
+)
+
+var errWasmTimeout = errors.New("plugin exceeded its time budget")
+
+// WasmMiddleware runs a WebAssembly plugin on wazero, with no access to the
+// filesystem, network or clock, a linear memory limit and a time budget per
+// hook call.
+//
+// Plugin ABI: the module exports "memory" and alloc(size i32) -> i32, and
+// optionally init(ptr, len i32) -> i32, on_request(ptr, len i32) -> i64 and
+// on_response(ptr, len i32) -> i64. The host copies a JSON document into
+// memory obtained from alloc and calls the hook; a hook returns 0 to leave
+// the request unchanged, or ptr<<32|len of a JSON result. init receives the
+// plugin config and returns non-zero on failure. Plugins may import
+// zen.log(ptr, len i32) to write to the daemon log.
+type WasmMiddleware struct {
+	name    string
+	path    string
+	binary  []byte
+	config  WasmPluginConfig
+	logger  *log.Logger
+	timeout time.Duration
+
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+
+	// pool holds an idle instance, or nil for a slot to instantiate, per
+	// allowed instance
+	pool chan api.Module
+}
+
+// LoadWasm reads and validates a WASM plugin. It is compiled and
+// instantiated by Init, once its memory limit is known.
+func LoadWasm(name, path string, logger *log.Logger) (*WasmMiddleware, error) {
+	bin, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read plugin: %w", err)
+	}
+	ctx := context.Background()
+	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfigInterpreter())
+	defer r.Close(ctx)
+	compiled, err := r.CompileModule(ctx, bin)
+	if err != nil {
+		return nil, fmt.Errorf("compile plugin: %w", err)
+	}
+	if err := checkWasmABI(compiled); err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = filepath.Base(path)
+	}
+	return &WasmMiddleware{name: name, path: path, binary: bin, logger: logger}, nil
+}
+
+func (m *WasmMiddleware) Name() string {
+	return m.name
+}
+
+func (m *WasmMiddleware) Version() string {
+	return "wasm"
+}
+
+func (m *WasmMiddleware) Description() string {
+	return "WASM plugin " + filepath.Base(m.path)
+}
+
+func (m *WasmMiddleware) Priority() int {
+	return m.config.Priority
+}
+
+func (m *WasmMiddleware) Init(config json.RawMessage) error {
+	if len(config) > 0 {
+		if err := json.Unmarshal(config, &m.config); err != nil {
+			return err
+		}
+	}
+	if m.config.Priority == 0 {
+		m.config.Priority = defaultPluginPriority
+	}
+	if m.config.MaxMemoryMB <= 0 {
+		m.config.MaxMemoryMB = defaultWasmMemoryMB
+	}
+	if m.config.MaxMemoryMB > maxWasmMemoryMB {
+		return fmt.Errorf("max_memory_mb must be at most %d", maxWasmMemoryMB)
+	}
+	m.timeout = defaultWasmTimeout
+	if m.config.TimeoutMs > 0 {
+		m.timeout = time.Duration(m.config.TimeoutMs) * time.Millisecond
+	}
+	if m.config.Instances <= 0 {
+		m.config.Instances = defaultWasmInstances
+	}
+
+	ctx := context.Background()
+	m.runtime = wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(uint32(m.config.MaxMemoryMB*1024*1024/wasmPageSize)).
+		WithCloseOnContextDone(true))
+	_, err := m.runtime.NewHostModuleBuilder("zen").
+		NewFunctionBuilder().WithFunc(m.hostLog).Export("log").
+		Instantiate(ctx)
+	if err == nil {
+		m.compiled, err = m.runtime.CompileModule(ctx, m.binary)
+	}
+	if err != nil {
+		m.runtime.Close(ctx)
+		return fmt.Errorf("compile plugin: %w", err)
+	}
+
+	// Instantiate once up front so a broken plugin fails to load
+	inst, err := m.newInstance()
+	if err != nil {
+		m.runtime.Close(ctx)
+		return err
+	}
+	m.pool = make(chan api.Module, m.config.Instances)
+	m.pool <- inst
+	for i := 1; i < m.config.Instances; i++ {
+		m.pool <- nil
+	}
+	return nil
+}
+
+// wasmExports are the signatures of the functions of the plugin ABI.
+var wasmExports = map[string]struct {
+	params, results []api.ValueType
+	required        bool
+}{
+	"alloc":       {[]api.ValueType{api.ValueTypeI32}, []api.ValueType{api.ValueTypeI32}, true},
+	"init":        {[]api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, []api.ValueType{api.ValueTypeI32}, false},
+	"on_request":  {[]api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, []api.ValueType{api.ValueTypeI64}, false},
+	"on_response": {[]api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, []api.ValueType{api.ValueTypeI64}, false},
+}
+
+// checkWasmABI reports whether a plugin exports memory and the ABI
+// functions it exports have the right signatures.
+func checkWasmABI(compiled wazero.CompiledModule) error {
+	if _, ok := compiled.ExportedMemories()["memory"]; !ok {
+		return errors.New("plugin does not export memory")
+	}
+	funcs := compiled.ExportedFunctions()
+	for name, sig := range wasmExports {
+		def, ok := funcs[name]
+		if !ok {
+			if sig.required {
+				return fmt.Errorf("plugin does not export %s", name)
+			}
+			continue
+		}
+		if !bytes.Equal(def.ParamTypes(), sig.params) || !bytes.Equal(def.ResultTypes(), sig.results) {
+			return fmt.Errorf("plugin export %s has the wrong signature", name)
+		}
+	}
+	return nil
+}
+
+// newInstance instantiates the plugin and runs its init export.
+func (m *WasmMiddleware) newInstance() (api.Module, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+
+	// Instances are anonymous so the runtime holds any number of them
+	inst, err := m.runtime.InstantiateModule(ctx, m.compiled, wazero.NewModuleConfig().WithName(""))
+	if err != nil {
+		return nil, fmt.Errorf("instantiate plugin: %w", wasmError(ctx, err))
+	}
+	if initFn := inst.ExportedFunction("init"); initFn != nil {
+		cfg := m.config.Config
+		if len(cfg) == 0 {
+			cfg = json.RawMessage("{}")
+		}
+		res, err := m.callWith(ctx, inst, initFn, cfg)
+		if err == nil && uint32(res) != 0 {
+			err = fmt.Errorf("plugin init failed with code %d", int32(res))
+		} else if err != nil {
+			err = fmt.Errorf("plugin init: %w", err)
+		}
+		if err != nil {
+			inst.Close(ctx)
+			return nil, err
+		}
+	}
+	return inst, nil
+}
+
+func (m *WasmMiddleware) hostLog(ctx context.Context, mod api.Module, ptr, n uint32) {
+	if msg, ok := mod.Memory().Read(ptr, n); ok && m.logger != nil {
+		m.logger.Printf("[middleware:%s] %s", m.name, msg)
+	}
+}
+
+// acquire takes an idle instance, instantiating one for an empty slot.
+func (m *WasmMiddleware) acquire() (api.Module, error) {
+	inst := <-m.pool
+	if inst != nil {
+		return inst, nil
+	}
+	inst, err := m.newInstance()
+	if err != nil {
+		m.pool <- nil
+	}
+	return inst, err
+}
+
+// discard closes an instance whose state can no longer be trusted and frees
+// its slot.
+func (m *WasmMiddleware) discard(inst api.Module) {
+	inst.Close(context.Background())
+	m.pool <- nil
+}
+
+// callWith copies data into the instance's memory and passes it to fn as
+// (ptr, len), returning fn's result. The ABI check guarantees the
+// signatures.
+func (m *WasmMiddleware) callWith(ctx context.Context, inst api.Module, fn api.Function, data []byte) (uint64, error) {
+	res, err := inst.ExportedFunction("alloc").Call(ctx, uint64(len(data)))
+	if err != nil {
+		return 0, fmt.Errorf("alloc: %w", wasmError(ctx, err))
+	}
+	ptr := uint32(res[0])
+	if !inst.Memory().Write(ptr, data) {
+		return 0, errors.New("plugin alloc returned an invalid pointer")
+	}
+	res, err = fn.Call(ctx, uint64(ptr), uint64(len(data)))
+	if err != nil {
+		return 0, wasmError(ctx, err)
+	}
+	return res[0], nil
+}
+
+// wasmError reports a call the deadline cut short as errWasmTimeout.
+func wasmError(ctx context.Context, err error) error {
+	var exit *sys.ExitError
+	if (errors.As(err, &exit) && exit.ExitCode() == sys.ExitCodeDeadlineExceeded) || ctx.Err() != nil {
+		return errWasmTimeout
+	}
+	return err
+}
+
+// callHook passes input to an exported hook and decodes its result. A nil
+// result means the hook is absent or left the context unchanged.
+func (m *WasmMiddleware) callHook(hook string, input interface{}) (*pluginResult, error) {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+	inst, err := m.acquire()
+	if err != nil {
+		return nil, err
+	}
+	fn := inst.ExportedFunction(hook)
+	if fn == nil {
+		m.pool <- inst
+		return nil, nil
+	}
+
+	out, err := m.runHook(inst, fn, data)
+	if err != nil {
+		// A trap can leave plugin memory inconsistent; start fresh next time
+		m.discard(inst)
+		return nil, fmt.Errorf("%s: %w", hook, err)
+	}
+	m.pool <- inst
+	if out == nil {
+		return nil, nil
+	}
+	var result pluginResult
+	if err := json.Unmarshal(out, &result); err != nil {
+		return nil, fmt.Errorf("%s returned invalid JSON: %w", hook, err)
+	}
+	return &result, nil
+}
+
+func (m *WasmMiddleware) runHook(inst api.Module, fn api.Function, data []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	res, err := m.callWith(ctx, inst, fn, data)
+	if err != nil || res == 0 {
+		return nil, err
+	}
+	out, ok := inst.Memory().Read(uint32(res>>32), uint32(res))
+	if !ok {
+		return nil, errors.New("returned an out of bounds result")
+	}
+	// The view is into memory the next call reuses
+	return bytes.Clone(out), nil
+}
+
+func (m *WasmMiddleware) ProcessRequest(ctx *RequestContext) (*RequestContext, error) {
+	result, err := m.callHook("on_request", newPluginRequest(ctx))
+	if err != nil || result == nil {
+		return ctx, err
+	}
+	return ctx, result.applyRequest(ctx)
+}
+
+func (m *WasmMiddleware) ProcessResponse(ctx *ResponseContext) (*ResponseContext, error) {
+	result, err := m.callHook("on_response", newPluginResponse(ctx))
+	if err != nil || result == nil {
+		return ctx, err
+	}
+	return ctx, result.applyResponse(ctx)
+}
+
+// Close frees the plugin's instances. Calls still running fail.
+func (m *WasmMiddleware) Close() error {
+	if m.runtime == nil {
+		return nil
+	}
+	return m.runtime.Close(context.Background())
+}
@@ -0,0 +1,134 @@
+package migrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// ccrProfileName is the profile that receives claude-code-router routes.
+const ccrProfileName = "ccr"
+
+type ccrConfig struct {
+	Providers []ccrProvider `json:"Providers"`
+	Router    ccrRouter     `json:"Router"`
+}
+
+type ccrProvider struct {
+	Name        string          `json:"name"`
+	APIBaseURL  string          `json:"api_base_url"`
+	APIKey      string          `json:"api_key"`
+	Models      []string        `json:"models"`
+	Transformer json.RawMessage `json:"transformer,omitempty"`
+}
+
+type ccrRouter struct {
+	Default              string `json:"default"`
+	Background           string `json:"background"`
+	Think                string `json:"think"`
+	LongContext          string `json:"longContext"`
+	LongContextThreshold int    `json:"longContextThreshold"`
+	WebSearch            string `json:"webSearch"`
+	Image                string `json:"image"`
+}
+
+// ImportCCR converts a claude-code-router config.json. Each CCR provider
+// becomes a zen provider and the Router section becomes the scenario
+// routing of a "ccr" profile.
+func ImportCCR(data []byte) (*Result, error) {
+	var cfg ccrConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse claude-code-router config: %w", err)
+	}
+	if len(cfg.Providers) == 0 {
+		return nil, fmt.Errorf("claude-code-router config has no providers")
+	}
+
+	res := newResult()
+	names := make(map[string]string) // CCR provider name -> zen provider name
+	var order []string
+
+	defaultProvider, defaultModel := splitCCRRoute(cfg.Router.Default)
+
+	for _, p := range cfg.Providers {
+		name := sanitizeName(p.Name)
+		if name == "" {
+			res.warnf("skipped provider with empty name")
+			continue
+		}
+		name = uniqueName(name, func(n string) bool { _, ok := res.Providers[n]; return ok })
+		names[p.Name] = name
+
+		token, ok := resolveSecret(p.APIKey)
+		if !ok {
+			res.warnf("provider %q: environment variable in api_key is not set", name)
+		}
+
+		pc := &config.ProviderConfig{
+			Type:      providerTypeForURL(p.APIBaseURL),
+			BaseURL:   trimEndpoint(p.APIBaseURL),
+			AuthToken: token,
+		}
+		if p.Name == defaultProvider && defaultModel != "" {
+			pc.Model = defaultModel
+		} else if len(p.Models) > 0 {
+			pc.Model = p.Models[0]
+		}
+		if len(p.Transformer) > 0 && string(p.Transformer) != "null" {
+			res.warnf("provider %q: CCR transformers are not imported; use provider transforms if needed", name)
+		}
+
+		res.Providers[name] = pc
+		if p.Name == defaultProvider {
+			order = append([]string{name}, order...)
+		} else {
+			order = append(order, name)
+		}
+	}
+
+	profile := &config.ProfileConfig{
+		Providers:            order,
+		LongContextThreshold: cfg.Router.LongContextThreshold,
+	}
+
+	scenarios := []struct {
+		key   config.Scenario
+		route string
+	}{
+		{config.ScenarioBackground, cfg.Router.Background},
+		{config.ScenarioThink, cfg.Router.Think},
+		{config.ScenarioLongContext, cfg.Router.LongContext},
+		{config.ScenarioWebSearch, cfg.Router.WebSearch},
+		{config.ScenarioImage, cfg.Router.Image},
+	}
+	for _, sc := range scenarios {
+		if sc.route == "" {
+			continue
+		}
+		provider, model := splitCCRRoute(sc.route)
+		name, ok := names[provider]
+		if !ok {
+			res.warnf("route %q references unknown provider %q", sc.key, provider)
+			continue
+		}
+		if profile.Routing == nil {
+			profile.Routing = make(map[string]*config.RoutePolicy)
+		}
+		profile.Routing[string(sc.key)] = &config.RoutePolicy{
+			Providers: []*config.ProviderRoute{{Name: name, Model: model}},
+		}
+	}
+
+	if len(order) > 0 {
+		res.Profiles[ccrProfileName] = profile
+	}
+	return res, nil
+}
+
+// splitCCRRoute splits a "provider,model" route string.
+func splitCCRRoute(route string) (provider, model string) {
+	provider, model, _ = strings.Cut(route, ",")
+	return strings.TrimSpace(provider), strings.TrimSpace(model)
+}
@@ -0,0 +1,196 @@
+package migrate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dopejs/gozen/internal/config"
+	"go.yaml.in/yaml/v3"
+)
+
+type litellmConfig struct {
+	ModelList       []litellmModel `yaml:"model_list"`
+	RouterSettings  litellmRouter  `yaml:"router_settings"`
+	LiteLLMSettings struct {
+		Fallbacks []map[string][]string `yaml:"fallbacks"`
+	} `yaml:"litellm_settings"`
+}
+
+type litellmModel struct {
+	ModelName     string `yaml:"model_name"`
+	LiteLLMParams struct {
+		Model   string `yaml:"model"`
+		APIBase string `yaml:"api_base"`
+		APIKey  string `yaml:"api_key"`
+		Weight  int    `yaml:"weight"`
+	} `yaml:"litellm_params"`
+}
+
+type litellmRouter struct {
+	RoutingStrategy string                `yaml:"routing_strategy"`
+	Fallbacks       []map[string][]string `yaml:"fallbacks"`
+}
+
+// litellmBaseURLs are the default endpoints for litellm provider prefixes
+// that speak a protocol zen can forward to directly.
+var litellmBaseURLs = map[string]string{
+	"openai":      "https://api.openai.com/v1",
+	"anthropic":   "https://api.anthropic.com",
+	"gemini":      "https://generativelanguage.googleapis.com",
+	"deepseek":    "https://api.deepseek.com",
+	"openrouter":  "https://openrouter.ai/api/v1",
+	"groq":        "https://api.groq.com/openai/v1",
+	"mistral":     "https://api.mistral.ai/v1",
+	"together_ai": "https://api.together.xyz/v1",
+	"xai":         "https://api.x.ai/v1",
+	"ollama":      "http://localhost:11434/v1",
+	"ollama_chat": "http://localhost:11434/v1",
+}
+
+// litellmStrategies maps litellm routing strategies to zen load balancing.
+var litellmStrategies = map[string]config.LoadBalanceStrategy{
+	"simple-shuffle":        config.LoadBalanceWeighted,
+	"latency-based-routing": config.LoadBalanceLeastLatency,
+	"cost-based-routing":    config.LoadBalanceLeastCost,
+	"least-busy":            config.LoadBalanceRoundRobin,
+	"usage-based-routing":   config.LoadBalanceRoundRobin,
+}
+
+// ImportLiteLLM converts a litellm proxy config.yaml. Each model_list
+// deployment becomes a provider; deployments sharing a model_name become
+// a profile, with litellm fallbacks appended to the profile's provider order.
+func ImportLiteLLM(data []byte) (*Result, error) {
+	var cfg litellmConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse litellm config: %w", err)
+	}
+	if len(cfg.ModelList) == 0 {
+		return nil, fmt.Errorf("litellm config has no model_list entries")
+	}
+
+	res := newResult()
+	groups := make(map[string][]string) // model_name -> provider names
+	weights := make(map[string]map[string]int)
+	var groupOrder []string
+
+	for _, m := range cfg.ModelList {
+		params := m.LiteLLMParams
+		prefix, model, found := strings.Cut(params.Model, "/")
+		if !found {
+			prefix, model = "openai", params.Model
+		}
+
+		baseURL := params.APIBase
+		if baseURL == "" {
+			baseURL = litellmBaseURLs[prefix]
+		}
+		if baseURL == "" {
+			res.warnf("model %q: provider %q needs an api_base, skipped", m.ModelName, prefix)
+			continue
+		}
+
+		var providerType string
+		switch prefix {
+		case "anthropic":
+			providerType = config.ProviderTypeAnthropic
+		case "gemini":
+			providerType = config.ProviderTypeGemini
+		case "azure":
+			providerType = config.ProviderTypeOpenAI
+			res.warnf("model %q: Azure deployments need api-version and api-key header transforms", m.ModelName)
+		default:
+			providerType = providerTypeForURL(baseURL)
+		}
+
+		name := sanitizeName(m.ModelName)
+		if name == "" {
+			name = sanitizeName(model)
+		}
+		name = uniqueName(name, func(n string) bool { _, ok := res.Providers[n]; return ok })
+
+		token, ok := resolveSecret(params.APIKey)
+		if !ok {
+			res.warnf("provider %q: environment variable in api_key is not set", name)
+		}
+
+		res.Providers[name] = &config.ProviderConfig{
+			Type:      providerType,
+			BaseURL:   trimEndpoint(baseURL),
+			AuthToken: token,
+			Model:     model,
+		}
+
+		if _, seen := groups[m.ModelName]; !seen {
+			groupOrder = append(groupOrder, m.ModelName)
+		}
+		groups[m.ModelName] = append(groups[m.ModelName], name)
+		if params.Weight > 0 {
+			if weights[m.ModelName] == nil {
+				weights[m.ModelName] = make(map[string]int)
+			}
+			weights[m.ModelName][name] = params.Weight
+		}
+	}
+
+	strategy := config.LoadBalanceFailover
+	if s := cfg.RouterSettings.RoutingStrategy; s != "" {
+		mapped, ok := litellmStrategies[s]
+		if !ok {
+			res.warnf("routing strategy %q has no zen equivalent, using failover", s)
+		} else {
+			strategy = mapped
+		}
+	}
+
+	fallbacks := make(map[string][]string)
+	for _, list := range [][]map[string][]string{cfg.LiteLLMSettings.Fallbacks, cfg.RouterSettings.Fallbacks} {
+		for _, entry := range list {
+			for from, to := range entry {
+				fallbacks[from] = append(fallbacks[from], to...)
+			}
+		}
+	}
+
+	for _, modelName := range groupOrder {
+		providers := append([]string(nil), groups[modelName]...)
+		for _, fb := range fallbacks[modelName] {
+			fbProviders, ok := groups[fb]
+			if !ok {
+				res.warnf("fallback %q for %q is not in model_list", fb, modelName)
+				continue
+			}
+			for _, p := range fbProviders {
+				if !containsString(providers, p) {
+					providers = append(providers, p)
+				}
+			}
+		}
+
+		profile := &config.ProfileConfig{Providers: providers}
+		if len(groups[modelName]) > 1 {
+			profile.Strategy = strategy
+			if strategy == config.LoadBalanceWeighted {
+				if len(weights[modelName]) > 0 {
+					profile.ProviderWeights = weights[modelName]
+				} else {
+					profile.Strategy = config.LoadBalanceRoundRobin
+				}
+			}
+		}
+
+		name := sanitizeName(modelName)
+		name = uniqueName(name, func(n string) bool { _, ok := res.Profiles[n]; return ok })
+		res.Profiles[name] = profile
+	}
+
+	return res, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+// Package migrate converts configuration from other LLM proxy tools into
+// GoZen providers and profiles.
+package migrate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// Supported import formats.
+const (
+	FormatCCR        = "ccr"        // claude-code-router config.json
+	FormatLiteLLM    = "litellm"    // litellm proxy config.yaml
+	FormatOpenRouter = "openrouter" // OpenRouter-style JSON provider list
+)
+
+// Formats lists the supported import formats.
+var Formats = []string{FormatCCR, FormatLiteLLM, FormatOpenRouter}
+
+// Result holds the providers and profiles produced by an import.
+type Result struct {
+	Providers map[string]*config.ProviderConfig
+	Profiles  map[string]*config.ProfileConfig
+	Warnings  []string
+}
+
+func newResult() *Result {
+	return &Result{
+		Providers: make(map[string]*config.ProviderConfig),
+		Profiles:  make(map[string]*config.ProfileConfig),
+	}
+}
+
+func (r *Result) warnf(format string, args ...interface{}) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+// ProviderNames returns the imported provider names, sorted.
+func (r *Result) ProviderNames() []string {
+	names := make([]string, 0, len(r.Providers))
+	for name := range r.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ProfileNames returns the imported profile names, sorted.
+func (r *Result) ProfileNames() []string {
+	names := make([]string, 0, len(r.Profiles))
+	for name := range r.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Import parses data in the given format.
+func Import(format string, data []byte) (*Result, error) {
+	switch format {
+	case FormatCCR:
+		return ImportCCR(data)
+	case FormatLiteLLM:
+		return ImportLiteLLM(data)
+	case FormatOpenRouter:
+		return ImportProviderList(data)
+	default:
+		return nil, fmt.Errorf("unsupported import format %q (supported: %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+// DefaultPath returns the conventional config location for a format, or "" if none.
+func DefaultPath(format string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	switch format {
+	case FormatCCR:
+		return filepath.Join(home, ".claude-code-router", "config.json")
+	}
+	return ""
+}
+
+// resolveSecret expands environment variable references in an API key.
+// Both shell-style ("$VAR", "${VAR}") and litellm-style ("os.environ/VAR")
+// references are supported. ok is false when a referenced variable is unset.
+func resolveSecret(value string) (string, bool) {
+	if name, found := strings.CutPrefix(value, "os.environ/"); found {
+		v, ok := os.LookupEnv(name)
+		return v, ok
+	}
+	if strings.HasPrefix(value, "$") {
+		missing := false
+		expanded := os.Expand(value, func(name string) string {
+			v, ok := os.LookupEnv(name)
+			if !ok {
+				missing = true
+			}
+			return v
+		})
+		return expanded, !missing
+	}
+	return value, true
+}
+
+// providerTypeForURL infers the provider protocol from its base URL.
+func providerTypeForURL(baseURL string) string {
+	lower := strings.ToLower(baseURL)
+	switch {
+	case strings.Contains(lower, "generativelanguage.googleapis.com"):
+		return config.ProviderTypeGemini
+	case strings.Contains(lower, "anthropic"), strings.HasSuffix(lower, "/messages"):
+		return config.ProviderTypeAnthropic
+	default:
+		return config.ProviderTypeOpenAI
+	}
+}
+
+// trimEndpoint strips a known API endpoint suffix so the URL can be used as a base_url.
+func trimEndpoint(rawURL string) string {
+	u := strings.TrimRight(rawURL, "/")
+	for _, suffix := range []string{"/chat/completions", "/responses", "/messages", "/completions"} {
+		if strings.HasSuffix(u, suffix) {
+			return strings.TrimSuffix(u, suffix)
+		}
+	}
+	return u
+}
+
+// sanitizeName converts an arbitrary label into a provider/profile name.
+func sanitizeName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('-')
+		}
+	}
+	return strings.Trim(b.String(), "-")
+}
+
+// uniqueName returns name, or name-2, name-3, ... if already taken.
+func uniqueName(name string, taken func(string) bool) string {
+	if !taken(name) {
+		return name
+	}
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s-%d", name, i)
+		if !taken(candidate) {
+			return candidate
+		}
+	}
+}
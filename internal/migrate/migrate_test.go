@@ -0,0 +1,238 @@
+package migrate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+const ccrSample = `{
+  "Providers": [
+    {
+      "name": "openrouter",
+      "api_base_url": "https://openrouter.ai/api/v1/chat/completions",
+      "api_key": "$TEST_CCR_KEY",
+      "models": ["google/gemini-2.5-pro-preview", "anthropic/claude-sonnet-4"],
+      "transformer": {"use": ["openrouter"]}
+    },
+    {
+      "name": "deepseek",
+      "api_base_url": "https://api.deepseek.com/chat/completions",
+      "api_key": "sk-deepseek",
+      "models": ["deepseek-chat", "deepseek-reasoner"]
+    }
+  ],
+  "Router": {
+    "default": "deepseek,deepseek-chat",
+    "think": "deepseek,deepseek-reasoner",
+    "longContext": "openrouter,google/gemini-2.5-pro-preview",
+    "longContextThreshold": 60000,
+    "webSearch": "missing,some-model"
+  }
+}`
+
+func TestImportCCR(t *testing.T) {
+	t.Setenv("TEST_CCR_KEY", "sk-or")
+
+	res, err := ImportCCR([]byte(ccrSample))
+	if err != nil {
+		t.Fatalf("ImportCCR() error = %v", err)
+	}
+
+	or := res.Providers["openrouter"]
+	if or == nil {
+		t.Fatal("openrouter provider missing")
+	}
+	if or.BaseURL != "https://openrouter.ai/api/v1" || or.Type != config.ProviderTypeOpenAI {
+		t.Errorf("openrouter = %+v", or)
+	}
+	if or.AuthToken != "sk-or" {
+		t.Errorf("AuthToken = %q, want env-resolved sk-or", or.AuthToken)
+	}
+	if ds := res.Providers["deepseek"]; ds == nil || ds.Model != "deepseek-chat" {
+		t.Errorf("deepseek = %+v", ds)
+	}
+
+	profile := res.Profiles["ccr"]
+	if profile == nil {
+		t.Fatal("ccr profile missing")
+	}
+	if strings.Join(profile.Providers, ",") != "deepseek,openrouter" {
+		t.Errorf("profile providers = %v, want default provider first", profile.Providers)
+	}
+	if profile.LongContextThreshold != 60000 {
+		t.Errorf("LongContextThreshold = %d", profile.LongContextThreshold)
+	}
+	think := profile.Routing["think"]
+	if think == nil || think.Providers[0].Name != "deepseek" || think.Providers[0].Model != "deepseek-reasoner" {
+		t.Errorf("think route = %+v", think)
+	}
+	if _, ok := profile.Routing["webSearch"]; ok {
+		t.Error("route to unknown provider should be dropped")
+	}
+
+	warnings := strings.Join(res.Warnings, "\n")
+	if !strings.Contains(warnings, "transformers are not imported") || !strings.Contains(warnings, `unknown provider "missing"`) {
+		t.Errorf("warnings = %v", res.Warnings)
+	}
+}
+
+func TestImportCCR_Invalid(t *testing.T) {
+	if _, err := ImportCCR([]byte("{")); err == nil {
+		t.Error("expected parse error")
+	}
+	if _, err := ImportCCR([]byte(`{"Providers": []}`)); err == nil {
+		t.Error("expected error for empty providers")
+	}
+}
+
+const litellmSample = `
+model_list:
+  - model_name: gpt-4o
+    litellm_params:
+      model: openai/gpt-4o
+      api_key: os.environ/TEST_LITELLM_OPENAI
+      weight: 3
+  - model_name: gpt-4o
+    litellm_params:
+      model: openai/gpt-4o
+      api_base: https://proxy.example.com/v1
+      api_key: sk-proxy
+      weight: 1
+  - model_name: claude-sonnet
+    litellm_params:
+      model: anthropic/claude-sonnet-4-20250514
+      api_key: sk-ant
+  - model_name: local
+    litellm_params:
+      model: huggingface/some-model
+router_settings:
+  routing_strategy: simple-shuffle
+litellm_settings:
+  fallbacks: [{"gpt-4o": ["claude-sonnet"]}]
+`
+
+func TestImportLiteLLM(t *testing.T) {
+	t.Setenv("TEST_LITELLM_OPENAI", "sk-openai")
+
+	res, err := ImportLiteLLM([]byte(litellmSample))
+	if err != nil {
+		t.Fatalf("ImportLiteLLM() error = %v", err)
+	}
+
+	if got := res.ProviderNames(); strings.Join(got, ",") != "claude-sonnet,gpt-4o,gpt-4o-2" {
+		t.Fatalf("providers = %v", got)
+	}
+	first := res.Providers["gpt-4o"]
+	if first.BaseURL != "https://api.openai.com/v1" || first.AuthToken != "sk-openai" || first.Model != "gpt-4o" {
+		t.Errorf("gpt-4o = %+v", first)
+	}
+	if res.Providers["claude-sonnet"].Type != config.ProviderTypeAnthropic {
+		t.Errorf("claude-sonnet type = %q", res.Providers["claude-sonnet"].Type)
+	}
+
+	profile := res.Profiles["gpt-4o"]
+	if profile == nil {
+		t.Fatal("gpt-4o profile missing")
+	}
+	if strings.Join(profile.Providers, ",") != "gpt-4o,gpt-4o-2,claude-sonnet" {
+		t.Errorf("profile providers = %v, want deployments then fallbacks", profile.Providers)
+	}
+	if profile.Strategy != config.LoadBalanceWeighted || profile.ProviderWeights["gpt-4o"] != 3 {
+		t.Errorf("strategy = %q weights = %v", profile.Strategy, profile.ProviderWeights)
+	}
+	if single := res.Profiles["claude-sonnet"]; single == nil || single.Strategy != "" {
+		t.Errorf("single-deployment profile = %+v", single)
+	}
+
+	if !strings.Contains(strings.Join(res.Warnings, "\n"), `provider "huggingface" needs an api_base`) {
+		t.Errorf("warnings = %v", res.Warnings)
+	}
+}
+
+const providerListSample = `{
+  "providers": [
+    {
+      "name": "OpenRouter",
+      "apiKey": "$TEST_OR_KEY",
+      "models": [{"id": "anthropic/claude-sonnet-4"}, {"id": "google/gemini-2.5-pro"}],
+      "headers": {"HTTP-Referer": "https://example.com"}
+    },
+    {
+      "id": "kimi",
+      "api_base": "https://api.moonshot.cn/v1/chat/completions",
+      "key": "sk-kimi",
+      "models": ["kimi-k2"],
+      "context_length": 128000
+    },
+    {"name": "local", "enabled": false, "base_url": "http://localhost:8080"},
+    {"name": "nowhere", "api_key": "sk"}
+  ]
+}`
+
+func TestImportProviderList(t *testing.T) {
+	t.Setenv("TEST_OR_KEY", "sk-or")
+
+	res, err := Import(FormatOpenRouter, []byte(providerListSample))
+	if err != nil {
+		t.Fatalf("ImportProviderList() error = %v", err)
+	}
+	if got := res.ProviderNames(); strings.Join(got, ",") != "kimi,openrouter" {
+		t.Fatalf("providers = %v", got)
+	}
+	or := res.Providers["openrouter"]
+	if or.BaseURL != "https://openrouter.ai/api/v1" || or.AuthToken != "sk-or" || or.Model != "anthropic/claude-sonnet-4" {
+		t.Errorf("openrouter = %+v", or)
+	}
+	if or.Transforms == nil || or.Transforms.Request.SetHeaders["HTTP-Referer"] != "https://example.com" {
+		t.Errorf("openrouter headers not imported: %+v", or.Transforms)
+	}
+	kimi := res.Providers["kimi"]
+	if kimi.BaseURL != "https://api.moonshot.cn/v1" || kimi.AuthToken != "sk-kimi" || kimi.Model != "kimi-k2" || kimi.Type != config.ProviderTypeOpenAI {
+		t.Errorf("kimi = %+v", kimi)
+	}
+	if profile := res.Profiles["openrouter"]; profile == nil || strings.Join(profile.Providers, ",") != "openrouter,kimi" {
+		t.Errorf("profile = %+v", profile)
+	}
+
+	warnings := strings.Join(res.Warnings, "\n")
+	for _, want := range []string{
+		`only the first of 2 models`,
+		`provider "kimi": field "context_length" is not imported`,
+		`provider "local" is disabled`,
+		`provider "nowhere": no base URL`,
+	} {
+		if !strings.Contains(warnings, want) {
+			t.Errorf("warnings lack %q:\n%s", want, warnings)
+		}
+	}
+
+	// A bare array is accepted too
+	res, err = ImportProviderList([]byte(`[{"name": "deepseek", "api_key": "sk-ds"}]`))
+	if err != nil || res.Providers["deepseek"] == nil || res.Providers["deepseek"].BaseURL != "https://api.deepseek.com" {
+		t.Errorf("array import = %+v, %v", res, err)
+	}
+	if _, err := ImportProviderList([]byte(`[]`)); err == nil {
+		t.Error("expected error for an empty list")
+	}
+}
+
+func TestImport_UnknownFormat(t *testing.T) {
+	if _, err := Import("bogus", nil); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestTrimEndpoint(t *testing.T) {
+	tests := map[string]string{
+		"https://api.deepseek.com/chat/completions": "https://api.deepseek.com",
+		"https://api.anthropic.com/v1/messages":     "https://api.anthropic.com/v1",
+		"https://host/v1/":                          "https://host/v1",
+	}
+	for in, want := range tests {
+		if got := trimEndpoint(in); got != want {
+			t.Errorf("trimEndpoint(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
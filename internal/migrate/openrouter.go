@@ -0,0 +1,199 @@
+package migrate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// providerListProfileName is the profile that receives an imported provider
+// list, in list order.
+const providerListProfileName = "openrouter"
+
+// providerListAliases maps the field names used by OpenRouter-style provider
+// lists to the field they stand for.
+var providerListAliases = map[string]string{
+	"name":         "name",
+	"id":           "name",
+	"provider":     "name",
+	"base_url":     "base_url",
+	"baseURL":      "base_url",
+	"api_base":     "base_url",
+	"api_base_url": "base_url",
+	"url":          "base_url",
+	"api_key":      "api_key",
+	"apiKey":       "api_key",
+	"key":          "api_key",
+	"type":         "type",
+	"model":        "model",
+	"models":       "models",
+	"headers":      "headers",
+	"weight":       "weight",
+	"enabled":      "enabled",
+}
+
+type providerListEntry struct {
+	Name    string            `json:"name"`
+	BaseURL string            `json:"base_url"`
+	APIKey  string            `json:"api_key"`
+	Type    string            `json:"type"`
+	Model   string            `json:"model"`
+	Models  []json.RawMessage `json:"models"`
+	Headers map[string]string `json:"headers"`
+	Weight  int               `json:"weight"`
+	Enabled *bool             `json:"enabled"`
+}
+
+// ImportProviderList converts an OpenRouter-style provider list: a JSON
+// array of provider objects, or an object holding one under "providers".
+// Each entry becomes a provider, and an "openrouter" profile lists them in
+// order. Models may be given as strings or as objects with an "id".
+func ImportProviderList(data []byte) (*Result, error) {
+	var entries []map[string]json.RawMessage
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("parse provider list: %w", err)
+		}
+	} else {
+		var wrapper struct {
+			Providers []map[string]json.RawMessage `json:"providers"`
+		}
+		if err := json.Unmarshal(data, &wrapper); err != nil {
+			return nil, fmt.Errorf("parse provider list: %w", err)
+		}
+		entries = wrapper.Providers
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("provider list is empty")
+	}
+
+	res := newResult()
+	var order []string
+	for i, raw := range entries {
+		entry, unknown, err := decodeProviderListEntry(raw)
+		if err != nil {
+			res.warnf("entry %d: %v, skipped", i+1, err)
+			continue
+		}
+		name := sanitizeName(entry.Name)
+		if name == "" {
+			res.warnf("entry %d: no name, skipped", i+1)
+			continue
+		}
+		if entry.Enabled != nil && !*entry.Enabled {
+			res.warnf("provider %q is disabled, skipped", name)
+			continue
+		}
+		baseURL := entry.BaseURL
+		if baseURL == "" {
+			baseURL = litellmBaseURLs[name]
+		}
+		if baseURL == "" {
+			res.warnf("provider %q: no base URL, skipped", name)
+			continue
+		}
+		name = uniqueName(name, func(n string) bool { _, ok := res.Providers[n]; return ok })
+
+		token, ok := resolveSecret(entry.APIKey)
+		if !ok {
+			res.warnf("provider %q: environment variable in api_key is not set", name)
+		}
+
+		pc := &config.ProviderConfig{
+			Type:      entry.Type,
+			BaseURL:   trimEndpoint(baseURL),
+			AuthToken: token,
+			Model:     entry.Model,
+			Weight:    entry.Weight,
+		}
+		switch pc.Type {
+		case "", config.ProviderTypeAnthropic, config.ProviderTypeOpenAI, config.ProviderTypeGemini:
+		default:
+			res.warnf("provider %q: type %q is not supported, inferred from the URL", name, pc.Type)
+			pc.Type = ""
+		}
+		if pc.Type == "" {
+			pc.Type = providerTypeForURL(baseURL)
+		}
+		models := providerListModels(entry.Models)
+		if pc.Model == "" && len(models) > 0 {
+			pc.Model = models[0]
+		}
+		if len(models) > 1 {
+			res.warnf("provider %q: only the first of %d models is imported (%s)", name, len(models), pc.Model)
+		}
+		if len(entry.Headers) > 0 {
+			pc.Transforms = &config.ProviderTransforms{
+				Request: &config.TransformRules{SetHeaders: entry.Headers},
+			}
+		}
+		for _, field := range unknown {
+			res.warnf("provider %q: field %q is not imported", name, field)
+		}
+
+		res.Providers[name] = pc
+		order = append(order, name)
+	}
+
+	if len(order) > 0 {
+		profile := &config.ProfileConfig{Providers: order}
+		for _, name := range order {
+			if res.Providers[name].Weight > 0 {
+				profile.Strategy = config.LoadBalanceWeighted
+				break
+			}
+		}
+		res.Profiles[providerListProfileName] = profile
+	}
+	return res, nil
+}
+
+// decodeProviderListEntry maps an entry's fields through their aliases and
+// returns the names of fields it does not know, sorted.
+func decodeProviderListEntry(raw map[string]json.RawMessage) (*providerListEntry, []string, error) {
+	canonical := make(map[string]json.RawMessage, len(raw))
+	var unknown []string
+	for field, value := range raw {
+		name, ok := providerListAliases[field]
+		if !ok {
+			unknown = append(unknown, field)
+			continue
+		}
+		if _, taken := canonical[name]; !taken || field == name {
+			canonical[name] = value
+		}
+	}
+	sort.Strings(unknown)
+	data, err := json.Marshal(canonical)
+	if err != nil {
+		return nil, nil, err
+	}
+	var entry providerListEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, nil, err
+	}
+	return &entry, unknown, nil
+}
+
+// providerListModels reads a models array of strings or {"id": ...} objects.
+func providerListModels(raw []json.RawMessage) []string {
+	var models []string
+	for _, m := range raw {
+		var id string
+		if err := json.Unmarshal(m, &id); err != nil {
+			var obj struct {
+				ID string `json:"id"`
+			}
+			if json.Unmarshal(m, &obj) == nil {
+				id = obj.ID
+			}
+		}
+		if id != "" {
+			models = append(models, id)
+		}
+	}
+	return models
+}
@@ -0,0 +1,316 @@
+package notify
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// WebhookDeliveryFile keeps deliveries awaiting a retry, the dead-letter list
+// and per-webhook delivery stats across daemon restarts.
+const WebhookDeliveryFile = "webhook_deliveries.json"
+
+const (
+	webhookRetryBase   = 30 * time.Second
+	webhookRetryMax    = time.Hour
+	maxWebhookFailures = 200 // dead-lettered deliveries kept, oldest dropped first
+)
+
+// WebhookDelivery is one event posted to one webhook.
+type WebhookDelivery struct {
+	ID          string              `json:"id"`
+	Webhook     string              `json:"webhook"`
+	Event       config.WebhookEvent `json:"event"`
+	Body        string              `json:"body"`
+	Attempts    int                 `json:"attempts"`
+	CreatedAt   time.Time           `json:"created_at"`
+	NextAttempt time.Time           `json:"next_attempt,omitempty"`
+	LastError   string              `json:"last_error,omitempty"`
+	FailedAt    *time.Time          `json:"failed_at,omitempty"`
+}
+
+// WebhookStats counts the deliveries to a webhook. Failed deliveries are
+// those that ran out of retries.
+type WebhookStats struct {
+	Delivered       int64      `json:"delivered"`
+	Failed          int64      `json:"failed"`
+	Retries         int64      `json:"retries"`
+	LastDeliveredAt *time.Time `json:"last_delivered_at,omitempty"`
+	LastError       string     `json:"last_error,omitempty"`
+	LastErrorAt     *time.Time `json:"last_error_at,omitempty"`
+}
+
+// SuccessRate returns the share of finished deliveries that succeeded, or 1
+// if none have finished.
+func (s WebhookStats) SuccessRate() float64 {
+	if s.Delivered+s.Failed == 0 {
+		return 1
+	}
+	return float64(s.Delivered) / float64(s.Delivered+s.Failed)
+}
+
+// deliveryState is the persisted part of the dispatcher.
+type deliveryState struct {
+	Pending  []*WebhookDelivery       `json:"pending,omitempty"`
+	Failures []*WebhookDelivery       `json:"failures,omitempty"`
+	Stats    map[string]*WebhookStats `json:"stats,omitempty"`
+}
+
+func loadDeliveryState(path string) deliveryState {
+	var state deliveryState
+	if data, err := os.ReadFile(path); err == nil {
+		// Ignore unmarshal errors - start over with an empty queue
+		_ = json.Unmarshal(data, &state)
+	}
+	return state
+}
+
+// saveLocked writes the delivery state to disk. The caller holds stateMu.
+func (d *WebhookDispatcher) saveLocked() {
+	if d.statePath == "" {
+		return
+	}
+	data, err := json.MarshalIndent(d.state, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(d.statePath), 0755); err != nil {
+		return
+	}
+	_ = os.WriteFile(d.statePath, append(data, '\n'), 0600)
+}
+
+func (d *WebhookDispatcher) statsLocked(webhook string) *WebhookStats {
+	if d.state.Stats == nil {
+		d.state.Stats = make(map[string]*WebhookStats)
+	}
+	s := d.state.Stats[webhook]
+	if s == nil {
+		s = &WebhookStats{}
+		d.state.Stats[webhook] = s
+	}
+	return s
+}
+
+// enqueue records a new delivery as pending, so it survives a restart
+// before it goes out, and marks it in flight.
+func (d *WebhookDispatcher) enqueue(wh *config.WebhookConfig, event config.WebhookEvent, body []byte) *WebhookDelivery {
+	dl := newDelivery(wh, event, body)
+
+	d.stateMu.Lock()
+	defer d.stateMu.Unlock()
+	d.state.Pending = append(d.state.Pending, dl)
+	d.markInFlightLocked(dl.ID)
+	d.saveLocked()
+	return dl
+}
+
+func newDelivery(wh *config.WebhookConfig, event config.WebhookEvent, body []byte) *WebhookDelivery {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return &WebhookDelivery{
+		ID:        hex.EncodeToString(id),
+		Webhook:   wh.Name,
+		Event:     event,
+		Body:      string(body),
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+func (d *WebhookDispatcher) markInFlightLocked(id string) {
+	if d.inflight == nil {
+		d.inflight = make(map[string]bool)
+	}
+	d.inflight[id] = true
+}
+
+// deliver makes one attempt at a pending delivery. A failed attempt is
+// retried with exponential backoff until the webhook's max_retries is used
+// up or the webhook rejects the request outright; the delivery is then
+// moved to the dead-letter list.
+func (d *WebhookDispatcher) deliver(wh *config.WebhookConfig, dl *WebhookDelivery) {
+	status, err := d.post(wh, []byte(dl.Body))
+	if err == nil && status >= 400 {
+		err = fmt.Errorf("webhook returned status %d", status)
+	}
+	now := time.Now().UTC()
+
+	d.stateMu.Lock()
+	defer d.stateMu.Unlock()
+	delete(d.inflight, dl.ID)
+	dl.Attempts++
+	stats := d.statsLocked(dl.Webhook)
+
+	if err == nil {
+		stats.Delivered++
+		stats.LastDeliveredAt = &now
+		d.removePendingLocked(dl.ID)
+		d.saveLocked()
+		return
+	}
+
+	dl.LastError = err.Error()
+	stats.LastError, stats.LastErrorAt = dl.LastError, &now
+	if retryableStatus(status) && dl.Attempts <= wh.GetMaxRetries() {
+		stats.Retries++
+		dl.NextAttempt = now.Add(webhookRetryDelay(dl.Attempts))
+	} else {
+		d.deadLetterLocked(dl, now)
+	}
+	d.saveLocked()
+}
+
+// retryableStatus reports whether a failed attempt may succeed when
+// repeated. Status is 0 when the request did not get a response.
+func retryableStatus(status int) bool {
+	return status == 0 || status == http.StatusRequestTimeout || status == http.StatusTooManyRequests || status >= 500
+}
+
+// webhookRetryDelay returns the backoff after the given number of failed
+// attempts: 30s, doubling each time, up to an hour.
+func webhookRetryDelay(attempts int) time.Duration {
+	delay := webhookRetryBase
+	for i := 1; i < attempts && delay < webhookRetryMax; i++ {
+		delay *= 2
+	}
+	if delay > webhookRetryMax {
+		delay = webhookRetryMax
+	}
+	return delay
+}
+
+func (d *WebhookDispatcher) removePendingLocked(id string) {
+	for i, p := range d.state.Pending {
+		if p.ID == id {
+			d.state.Pending = append(d.state.Pending[:i], d.state.Pending[i+1:]...)
+			return
+		}
+	}
+}
+
+// deadLetterLocked moves a pending delivery to the dead-letter list.
+func (d *WebhookDispatcher) deadLetterLocked(dl *WebhookDelivery, now time.Time) {
+	d.removePendingLocked(dl.ID)
+	dl.NextAttempt = time.Time{}
+	dl.FailedAt = &now
+	d.statsLocked(dl.Webhook).Failed++
+	d.state.Failures = append(d.state.Failures, dl)
+	if n := len(d.state.Failures); n > maxWebhookFailures {
+		d.state.Failures = append([]*WebhookDelivery(nil), d.state.Failures[n-maxWebhookFailures:]...)
+	}
+}
+
+// RetryDue retries the pending deliveries whose backoff has elapsed, one at
+// a time. Deliveries to webhooks that were removed or disabled meanwhile are
+// dead-lettered.
+func (d *WebhookDispatcher) RetryDue(now time.Time) {
+	d.mu.RLock()
+	webhooks := make(map[string]*config.WebhookConfig, len(d.webhooks))
+	for _, wh := range d.webhooks {
+		webhooks[wh.Name] = wh
+	}
+	d.mu.RUnlock()
+
+	type retry struct {
+		wh *config.WebhookConfig
+		dl *WebhookDelivery
+	}
+	var due []retry
+	d.stateMu.Lock()
+	changed := false
+	for _, dl := range append([]*WebhookDelivery(nil), d.state.Pending...) {
+		if d.inflight[dl.ID] || dl.NextAttempt.After(now) {
+			continue
+		}
+		wh := webhooks[dl.Webhook]
+		if wh == nil || !wh.Enabled {
+			dl.LastError = "webhook removed or disabled"
+			d.deadLetterLocked(dl, now.UTC())
+			changed = true
+			continue
+		}
+		d.markInFlightLocked(dl.ID)
+		due = append(due, retry{wh, dl})
+	}
+	if changed {
+		d.saveLocked()
+	}
+	d.stateMu.Unlock()
+
+	for _, r := range due {
+		d.deliver(r.wh, r.dl)
+	}
+}
+
+// Failures returns the dead-lettered deliveries, newest first.
+func (d *WebhookDispatcher) Failures() []WebhookDelivery {
+	d.stateMu.Lock()
+	defer d.stateMu.Unlock()
+	result := make([]WebhookDelivery, 0, len(d.state.Failures))
+	for i := len(d.state.Failures) - 1; i >= 0; i-- {
+		result = append(result, *d.state.Failures[i])
+	}
+	return result
+}
+
+// RequeueFailures moves dead-lettered deliveries back to the pending queue
+// with a fresh retry budget, to go out on the next RetryDue. An empty id
+// requeues all of them. It returns how many were requeued.
+func (d *WebhookDispatcher) RequeueFailures(id string) int {
+	d.stateMu.Lock()
+	defer d.stateMu.Unlock()
+	kept := d.state.Failures[:0]
+	n := 0
+	for _, dl := range d.state.Failures {
+		if id != "" && dl.ID != id {
+			kept = append(kept, dl)
+			continue
+		}
+		dl.Attempts, dl.FailedAt, dl.NextAttempt = 0, nil, time.Time{}
+		d.state.Pending = append(d.state.Pending, dl)
+		n++
+	}
+	d.state.Failures = kept
+	if n > 0 {
+		d.saveLocked()
+	}
+	return n
+}
+
+// ClearFailures empties the dead-letter list and returns how many
+// deliveries it held.
+func (d *WebhookDispatcher) ClearFailures() int {
+	d.stateMu.Lock()
+	defer d.stateMu.Unlock()
+	n := len(d.state.Failures)
+	d.state.Failures = nil
+	if n > 0 {
+		d.saveLocked()
+	}
+	return n
+}
+
+// Stats returns the delivery stats of the named webhook.
+func (d *WebhookDispatcher) Stats(webhook string) WebhookStats {
+	d.stateMu.Lock()
+	defer d.stateMu.Unlock()
+	if s := d.state.Stats[webhook]; s != nil {
+		return *s
+	}
+	return WebhookStats{}
+}
+
+// PendingCount returns how many deliveries await their first attempt or a
+// retry.
+func (d *WebhookDispatcher) PendingCount() int {
+	d.stateMu.Lock()
+	defer d.stateMu.Unlock()
+	return len(d.state.Pending)
+}
@@ -0,0 +1,143 @@
+package notify
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+func newTestDispatcher(t *testing.T, webhooks ...*config.WebhookConfig) *WebhookDispatcher {
+	t.Helper()
+	return &WebhookDispatcher{
+		webhooks:  webhooks,
+		client:    &http.Client{Timeout: 5 * time.Second},
+		statePath: filepath.Join(t.TempDir(), WebhookDeliveryFile),
+	}
+}
+
+// waitDelivered waits for the in-flight first attempts of a Dispatch.
+func waitDelivered(t *testing.T, d *WebhookDispatcher) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		d.stateMu.Lock()
+		n := len(d.inflight)
+		d.stateMu.Unlock()
+		if n == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("delivery still in flight")
+}
+
+func TestWebhookDeliveryRetries(t *testing.T) {
+	var calls int32
+	failFirst := int32(2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= failFirst {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	wh := &config.WebhookConfig{Name: "ops", URL: server.URL, Enabled: true, Events: []config.WebhookEvent{config.WebhookEventProviderDown}}
+	d := newTestDispatcher(t, wh)
+	d.Dispatch(config.WebhookEventProviderDown, &ProviderEventData{Provider: "p"})
+	waitDelivered(t, d)
+
+	if d.PendingCount() != 1 {
+		t.Fatalf("pending = %d after a failed attempt, want 1", d.PendingCount())
+	}
+	// Not due until the backoff has elapsed
+	d.RetryDue(time.Now())
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Fatalf("retried before the backoff elapsed")
+	}
+
+	// The queue survives a restart
+	restarted := newTestDispatcher(t, wh)
+	restarted.statePath = d.statePath
+	restarted.state = loadDeliveryState(d.statePath)
+	for i := 0; i < 2; i++ {
+		restarted.RetryDue(time.Now().Add(time.Hour))
+	}
+	if restarted.PendingCount() != 0 || len(restarted.Failures()) != 0 {
+		t.Errorf("pending = %d, failures = %d after delivery; want none", restarted.PendingCount(), len(restarted.Failures()))
+	}
+	stats := restarted.Stats("ops")
+	if stats.Delivered != 1 || stats.Retries != 2 || stats.LastError == "" || stats.SuccessRate() != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestWebhookDeadLetter(t *testing.T) {
+	var calls int32
+	status := int32(http.StatusInternalServerError)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer server.Close()
+
+	wh := &config.WebhookConfig{Name: "ops", URL: server.URL, Enabled: true, MaxRetries: 1, Events: []config.WebhookEvent{config.WebhookEventFailover}}
+	d := newTestDispatcher(t, wh)
+	d.Dispatch(config.WebhookEventFailover, &FailoverEventData{FromProvider: "a", ToProvider: "b"})
+	waitDelivered(t, d)
+	d.RetryDue(time.Now().Add(time.Hour))
+
+	failures := d.Failures()
+	if atomic.LoadInt32(&calls) != 2 || len(failures) != 1 || d.PendingCount() != 0 {
+		t.Fatalf("calls = %d, failures = %d, pending = %d; want 2, 1, 0", calls, len(failures), d.PendingCount())
+	}
+	if failures[0].Attempts != 2 || failures[0].FailedAt == nil || failures[0].LastError != "webhook returned status 500" {
+		t.Errorf("failure = %+v", failures[0])
+	}
+
+	// A rejected request is not retried
+	atomic.StoreInt32(&status, http.StatusBadRequest)
+	d.Dispatch(config.WebhookEventFailover, &FailoverEventData{})
+	waitDelivered(t, d)
+	if len(d.Failures()) != 2 || d.PendingCount() != 0 {
+		t.Errorf("4xx response should be dead-lettered at once")
+	}
+	if stats := d.Stats("ops"); stats.Failed != 2 || stats.SuccessRate() != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+
+	// Requeued deliveries go out on the next retry
+	atomic.StoreInt32(&status, http.StatusOK)
+	if n := d.RequeueFailures(failures[0].ID); n != 1 {
+		t.Fatalf("RequeueFailures() = %d, want 1", n)
+	}
+	d.RetryDue(time.Now())
+	if len(d.Failures()) != 1 || d.PendingCount() != 0 || d.Stats("ops").Delivered != 1 {
+		t.Errorf("requeued delivery was not delivered")
+	}
+	if n := d.ClearFailures(); n != 1 || len(d.Failures()) != 0 {
+		t.Errorf("ClearFailures() = %d, want 1", n)
+	}
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{4, 4 * time.Minute},
+		{20, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := webhookRetryDelay(tt.attempts); got != tt.want {
+			t.Errorf("webhookRetryDelay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package notify
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+func TestWebhookSignature(t *testing.T) {
+	var header string
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get(SignatureHeader)
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	d := newTestDispatcher(t)
+	wh := &config.WebhookConfig{Name: "signed", URL: server.URL, Secret: "s3cret"}
+	if _, err := d.post(wh, []byte(`{"event":"test"}`)); err != nil {
+		t.Fatalf("post() error: %v", err)
+	}
+	now := time.Now()
+	if err := VerifySignature("s3cret", header, body, 5*time.Minute, now); err != nil {
+		t.Errorf("VerifySignature(%q) error: %v", header, err)
+	}
+	if err := VerifySignature("other", header, body, 5*time.Minute, now); err == nil {
+		t.Error("VerifySignature() with the wrong secret should fail")
+	}
+	if err := VerifySignature("s3cret", header, []byte(`{"event":"forged"}`), 5*time.Minute, now); err == nil {
+		t.Error("VerifySignature() of a changed body should fail")
+	}
+	if err := VerifySignature("s3cret", header, body, 5*time.Minute, now.Add(time.Hour)); err == nil {
+		t.Error("VerifySignature() of a stale signature should fail")
+	}
+	if err := VerifySignature("s3cret", "v1=abc", body, 0, now); err == nil {
+		t.Error("VerifySignature() without a timestamp should fail")
+	}
+
+	wh.Secret = ""
+	if _, err := d.post(wh, body); err != nil {
+		t.Fatalf("post() error: %v", err)
+	}
+	if header != "" {
+		t.Errorf("unsigned webhook sent %s: %q", SignatureHeader, header)
+	}
+}
+
+// writeTestCert writes a self-signed certificate and its key as PEM files.
+func writeTestCert(t *testing.T, dir, name string) (certFile, keyFile string, cert *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile = filepath.Join(dir, name+".pem")
+	keyFile = filepath.Join(dir, name+"-key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	cert, _ = x509.ParseCertificate(der)
+	return certFile, keyFile, cert
+}
+
+func TestWebhookClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, clientCert := writeTestCert(t, dir, "client")
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	var peer string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		peer = r.TLS.PeerCertificates[0].Subject.CommonName
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+
+	caFile := filepath.Join(dir, "server-ca.pem")
+	os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600)
+
+	d := newTestDispatcher(t)
+	wh := &config.WebhookConfig{Name: "mtls", URL: server.URL, CACert: caFile}
+	if _, err := d.post(wh, []byte(`{}`)); err == nil {
+		t.Error("post() without a client certificate should fail")
+	}
+
+	wh.ClientCert, wh.ClientKey = certFile, keyFile
+	status, err := d.post(wh, []byte(`{}`))
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("post() = %d, %v; want 200", status, err)
+	}
+	if peer != "client" {
+		t.Errorf("server saw client certificate %q, want client", peer)
+	}
+
+	wh.ClientKey = filepath.Join(dir, "missing.pem")
+	if _, err := d.post(wh, []byte(`{}`)); err == nil {
+		t.Error("post() with a missing key should fail")
+	}
+}
@@ -0,0 +1,60 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// WebhookTemplateData is what a webhook's payload template is executed
+// with.
+type WebhookTemplateData struct {
+	Event     config.WebhookEvent
+	Timestamp time.Time
+	// Message is the one-line description the Slack and Discord formats use.
+	Message string
+	// Data is the event data keyed by its JSON field names, as in the
+	// generic format.
+	Data map[string]interface{}
+}
+
+// formatTemplate renders the payload with the webhook's template.
+func (d *WebhookDispatcher) formatTemplate(wh *config.WebhookConfig, payload WebhookPayload) ([]byte, error) {
+	tmpl, err := wh.ParseTemplate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+
+	data := WebhookTemplateData{
+		Event:     payload.Event,
+		Timestamp: payload.Timestamp,
+		Message:   d.formatMessage(payload),
+	}
+	if raw, err := json.Marshal(payload.Data); err == nil {
+		json.Unmarshal(raw, &data.Data)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("render template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// renderFailed dead-letters an event the webhook's template could not
+// render, so the failure shows up with the webhook's other failures.
+func (d *WebhookDispatcher) renderFailed(wh *config.WebhookConfig, event config.WebhookEvent, err error) {
+	dl := newDelivery(wh, event, nil)
+	dl.LastError = err.Error()
+	now := time.Now().UTC()
+
+	d.stateMu.Lock()
+	defer d.stateMu.Unlock()
+	stats := d.statsLocked(dl.Webhook)
+	stats.LastError, stats.LastErrorAt = dl.LastError, &now
+	d.deadLetterLocked(dl, now)
+	d.saveLocked()
+}
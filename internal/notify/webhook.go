@@ -5,7 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"strings"
+	"path/filepath"
 	"sync"
 	"time"
 
@@ -19,14 +19,16 @@ type WebhookPayload struct {
 	Data      interface{}         `json:"data"`
 }
 
-// BudgetEventData contains data for budget-related events.
+// BudgetEventData contains data for budget-related events. Amounts are in
+// Currency, the display currency.
 type BudgetEventData struct {
-	Period     string  `json:"period"`
-	Spent      float64 `json:"spent"`
-	Limit      float64 `json:"limit"`
-	Percent    float64 `json:"percent"`
-	Action     string  `json:"action,omitempty"`
-	Project    string  `json:"project,omitempty"`
+	Period   string  `json:"period"`
+	Spent    float64 `json:"spent"`
+	Limit    float64 `json:"limit"`
+	Percent  float64 `json:"percent"`
+	Action   string  `json:"action,omitempty"`
+	Project  string  `json:"project,omitempty"`
+	Currency string  `json:"currency,omitempty"`
 }
 
 // ProviderEventData contains data for provider-related events.
@@ -45,30 +47,132 @@ type FailoverEventData struct {
 	SessionID    string `json:"session_id,omitempty"`
 }
 
-// DailySummaryData contains data for daily summary events.
+// DailySummaryData contains data for daily and weekly summary events.
+// EndDate is set for weekly summaries, which cover Date through EndDate.
+// Costs are in Currency, the display currency.
 type DailySummaryData struct {
 	Date          string             `json:"date"`
+	EndDate       string             `json:"end_date,omitempty"`
 	TotalCost     float64            `json:"total_cost"`
 	TotalRequests int                `json:"total_requests"`
 	TotalInput    int                `json:"total_input_tokens"`
 	TotalOutput   int                `json:"total_output_tokens"`
 	ByProvider    map[string]float64 `json:"by_provider,omitempty"`
+	ByModel       map[string]float64 `json:"by_model,omitempty"`
+	Currency      string             `json:"currency,omitempty"`
 }
 
-// WebhookDispatcher sends notifications to configured webhooks.
+// FailureBurstData contains data for request_failure_burst events: Failures
+// requests to Provider failed within WindowMinutes.
+type FailureBurstData struct {
+	Provider      string `json:"provider"`
+	Failures      int    `json:"failures"`
+	WindowMinutes int    `json:"window_minutes"`
+	LastError     string `json:"last_error,omitempty"`
+}
+
+// BudgetForecastData contains data for budget_forecast events. Projected is
+// the spending expected by the end of the period at the current rate, and
+// BreachAt when it crosses Limit. Amounts are in Currency, the display
+// currency.
+type BudgetForecastData struct {
+	Period    string    `json:"period"`
+	Spent     float64   `json:"spent"`
+	Limit     float64   `json:"limit"`
+	Projected float64   `json:"projected"`
+	BreachAt  time.Time `json:"breach_at"`
+	Currency  string    `json:"currency,omitempty"`
+}
+
+// CertExpiryData contains data for cert_expiry events.
+type CertExpiryData struct {
+	Provider  string    `json:"provider"`
+	Host      string    `json:"host"`
+	ExpiresAt time.Time `json:"expires_at"`
+	DaysLeft  int       `json:"days_left"`
+}
+
+// DaemonRestartData contains data for daemon_restart events. Reason is
+// "start", "crash", "unclean_exit" or "handoff".
+type DaemonRestartData struct {
+	Reason  string `json:"reason"`
+	Detail  string `json:"detail,omitempty"`
+	Version string `json:"version"`
+	PID     int    `json:"pid"`
+}
+
+// KeyInvalidData contains data for key_invalid events. NextKeyID is the key
+// the proxy moved on to, empty when the provider had none left.
+type KeyInvalidData struct {
+	Provider  string `json:"provider"`
+	KeyID     string `json:"key_id"`
+	Status    int    `json:"status"`
+	NextKeyID string `json:"next_key_id,omitempty"`
+}
+
+// SpendSpikeData contains data for spend_spike events: a session spending
+// far faster than its usual rate. Rates are per minute; costs are in
+// Currency, the display currency.
+type SpendSpikeData struct {
+	SessionID         string  `json:"session_id"`
+	Project           string  `json:"project,omitempty"`
+	CostRate          float64 `json:"cost_rate"`
+	BaselineCostRate  float64 `json:"baseline_cost_rate"`
+	TokenRate         float64 `json:"token_rate"`
+	BaselineTokenRate float64 `json:"baseline_token_rate"`
+	Multiple          float64 `json:"multiple"`
+	Paused            bool    `json:"paused"`
+	Currency          string  `json:"currency,omitempty"`
+}
+
+// Text returns a one-line description of the spike.
+func (s *SpendSpikeData) Text() string {
+	msg := fmt.Sprintf("🚨 Spend Spike: session %s is spending %s/min and %.0f tokens/min, against a usual %s/min and %.0f tokens/min",
+		s.SessionID, config.FormatAmount(s.Currency, s.CostRate), s.TokenRate,
+		config.FormatAmount(s.Currency, s.BaselineCostRate), s.BaselineTokenRate)
+	if s.Paused {
+		msg += ". The session was paused"
+	}
+	return msg
+}
+
+// Text returns a one-line description of the summary.
+func (s *DailySummaryData) Text() string {
+	if s.EndDate != "" {
+		return fmt.Sprintf("📊 Weekly Summary (%s to %s): %d requests, %s total cost, %d input / %d output tokens",
+			s.Date, s.EndDate, s.TotalRequests, config.FormatAmount(s.Currency, s.TotalCost), s.TotalInput, s.TotalOutput)
+	}
+	return fmt.Sprintf("📊 Daily Summary (%s): %d requests, %s total cost, %d input / %d output tokens",
+		s.Date, s.TotalRequests, config.FormatAmount(s.Currency, s.TotalCost), s.TotalInput, s.TotalOutput)
+}
+
+// WebhookDispatcher sends notifications to configured webhooks. Failed
+// deliveries are retried with backoff; see RetryDue.
 type WebhookDispatcher struct {
 	mu       sync.RWMutex
 	webhooks []*config.WebhookConfig
 	client   *http.Client
+
+	clientsMu  sync.Mutex
+	tlsClients map[string]*http.Client // by client certificate, key and CA path
+
+	stateMu   sync.Mutex
+	state     deliveryState
+	statePath string // empty keeps the delivery state in memory only
+	inflight  map[string]bool
 }
 
-// NewWebhookDispatcher creates a new webhook dispatcher.
+// NewWebhookDispatcher creates a new webhook dispatcher, picking up the
+// deliveries still pending from WebhookDeliveryFile.
 func NewWebhookDispatcher() *WebhookDispatcher {
+	statePath := filepath.Join(config.ConfigDirPath(), WebhookDeliveryFile)
 	return &WebhookDispatcher{
 		webhooks: config.GetWebhooks(),
 		client: &http.Client{
 			Timeout: 10 * time.Second,
 		},
+		state:     loadDeliveryState(statePath),
+		statePath: statePath,
 	}
 }
 
@@ -77,6 +181,11 @@ func (d *WebhookDispatcher) ReloadConfig() {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 	d.webhooks = config.GetWebhooks()
+
+	// Pick up replaced certificates
+	d.clientsMu.Lock()
+	d.tlsClients = nil
+	d.clientsMu.Unlock()
 }
 
 // Dispatch sends an event to all matching webhooks.
@@ -99,10 +208,29 @@ func (d *WebhookDispatcher) Dispatch(event config.WebhookEvent, data interface{}
 			continue
 		}
 
-		go d.send(wh, payload)
+		body, err := d.format(wh, payload)
+		if err != nil {
+			d.renderFailed(wh, event, err)
+			continue
+		}
+		dl := d.enqueue(wh, event, body)
+		go d.deliver(wh, dl)
 	}
 }
 
+// Subscribed reports whether an enabled webhook subscribes to the event, so
+// callers can skip work whose only purpose is raising it.
+func (d *WebhookDispatcher) Subscribed(event config.WebhookEvent) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, wh := range d.webhooks {
+		if wh.Enabled && d.matchesEvent(wh, event) {
+			return true
+		}
+	}
+	return false
+}
+
 func (d *WebhookDispatcher) matchesEvent(wh *config.WebhookConfig, event config.WebhookEvent) bool {
 	for _, e := range wh.Events {
 		if e == event {
@@ -112,40 +240,52 @@ func (d *WebhookDispatcher) matchesEvent(wh *config.WebhookConfig, event config.
 	return false
 }
 
-func (d *WebhookDispatcher) send(wh *config.WebhookConfig, payload WebhookPayload) {
-	var body []byte
-	var contentType string
-
-	// Detect webhook type from URL and format accordingly
-	if strings.Contains(wh.URL, "slack.com") {
-		body = d.formatSlack(payload)
-		contentType = "application/json"
-	} else if strings.Contains(wh.URL, "discord.com") {
-		body = d.formatDiscord(payload)
-		contentType = "application/json"
-	} else {
-		body = d.formatGeneric(payload)
-		contentType = "application/json"
+// format renders the payload with the webhook's template or, without one,
+// its format preset.
+func (d *WebhookDispatcher) format(wh *config.WebhookConfig, payload WebhookPayload) ([]byte, error) {
+	if wh.Template != "" {
+		return d.formatTemplate(wh, payload)
 	}
+	switch wh.GetFormat() {
+	case config.WebhookFormatSlack:
+		return d.formatSlack(payload), nil
+	case config.WebhookFormatDiscord:
+		return d.formatDiscord(payload), nil
+	case config.WebhookFormatPagerDuty:
+		return d.formatPagerDuty(wh, payload), nil
+	}
+	return d.formatGeneric(payload), nil
+}
 
+// post sends body to the webhook and returns the response status. Each
+// attempt is signed afresh, so retries carry a current timestamp.
+func (d *WebhookDispatcher) post(wh *config.WebhookConfig, body []byte) (int, error) {
+	client, err := d.clientFor(wh)
+	if err != nil {
+		return 0, err
+	}
 	req, err := http.NewRequest(http.MethodPost, wh.URL, bytes.NewReader(body))
 	if err != nil {
-		return
+		return 0, err
 	}
 
-	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", "GoZen-Webhook/1.0")
 
 	// Add custom headers
 	for k, v := range wh.Headers {
 		req.Header.Set(k, v)
 	}
+	if wh.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(wh.Secret, time.Now(), body))
+	}
 
-	resp, err := d.client.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
-		return
+		return 0, err
 	}
 	defer resp.Body.Close()
+	return resp.StatusCode, nil
 }
 
 // formatSlack formats the payload for Slack webhooks.
@@ -197,6 +337,37 @@ func (d *WebhookDispatcher) formatDiscord(payload WebhookPayload) []byte {
 	return data
 }
 
+// formatPagerDuty formats the payload as a PagerDuty Events API v2 event.
+// provider_down triggers an incident per provider that provider_up resolves.
+func (d *WebhookDispatcher) formatPagerDuty(wh *config.WebhookConfig, payload WebhookPayload) []byte {
+	msg := map[string]interface{}{
+		"routing_key":  wh.RoutingKey,
+		"event_action": "trigger",
+	}
+	if data, ok := payload.Data.(*ProviderEventData); ok {
+		msg["dedup_key"] = "gozen-provider-" + data.Provider
+	}
+
+	if payload.Event == config.WebhookEventProviderUp {
+		msg["event_action"] = "resolve"
+	} else {
+		msg["payload"] = map[string]interface{}{
+			"summary":        d.formatMessage(payload),
+			"source":         "gozen",
+			"severity":       d.getSeverityForEvent(payload.Event),
+			"timestamp":      payload.Timestamp.Format(time.RFC3339),
+			"class":          string(payload.Event),
+			"custom_details": payload.Data,
+		}
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return []byte(`{"event_action":"trigger"}`)
+	}
+	return data
+}
+
 // formatGeneric formats the payload as generic JSON.
 func (d *WebhookDispatcher) formatGeneric(payload WebhookPayload) []byte {
 	data, err := json.Marshal(payload)
@@ -211,14 +382,14 @@ func (d *WebhookDispatcher) formatMessage(payload WebhookPayload) string {
 	switch payload.Event {
 	case config.WebhookEventBudgetWarning:
 		if data, ok := payload.Data.(*BudgetEventData); ok {
-			return fmt.Sprintf("⚠️ Budget Warning: %s budget at %.1f%% ($%.2f / $%.2f)",
-				data.Period, data.Percent, data.Spent, data.Limit)
+			return fmt.Sprintf("⚠️ Budget Warning: %s budget at %.1f%% (%s / %s)",
+				data.Period, data.Percent, config.FormatAmount(data.Currency, data.Spent), config.FormatAmount(data.Currency, data.Limit))
 		}
 
 	case config.WebhookEventBudgetExceeded:
 		if data, ok := payload.Data.(*BudgetEventData); ok {
-			return fmt.Sprintf("🚫 Budget Exceeded: %s limit of $%.2f reached (spent: $%.2f). Action: %s",
-				data.Period, data.Limit, data.Spent, data.Action)
+			return fmt.Sprintf("🚫 Budget Exceeded: %s limit of %s reached (spent: %s). Action: %s",
+				data.Period, config.FormatAmount(data.Currency, data.Limit), config.FormatAmount(data.Currency, data.Spent), data.Action)
 		}
 
 	case config.WebhookEventProviderDown:
@@ -239,10 +410,52 @@ func (d *WebhookDispatcher) formatMessage(payload WebhookPayload) string {
 				data.FromProvider, data.ToProvider, data.Reason)
 		}
 
-	case config.WebhookEventDailySummary:
+	case config.WebhookEventDailySummary, config.WebhookEventWeeklySummary:
 		if data, ok := payload.Data.(*DailySummaryData); ok {
-			return fmt.Sprintf("📊 Daily Summary (%s): %d requests, $%.2f total cost, %d input / %d output tokens",
-				data.Date, data.TotalRequests, data.TotalCost, data.TotalInput, data.TotalOutput)
+			return data.Text()
+		}
+
+	case config.WebhookEventRequestFailureBurst:
+		if data, ok := payload.Data.(*FailureBurstData); ok {
+			return fmt.Sprintf("🔥 Failure Burst: %d requests to %s failed in %d minutes. Last error: %s",
+				data.Failures, data.Provider, data.WindowMinutes, data.LastError)
+		}
+
+	case config.WebhookEventBudgetForecast:
+		if data, ok := payload.Data.(*BudgetForecastData); ok {
+			return fmt.Sprintf("📈 Budget Forecast: %s spending on track for %s against a %s limit (spent: %s), reached around %s",
+				data.Period, config.FormatAmount(data.Currency, data.Projected), config.FormatAmount(data.Currency, data.Limit),
+				config.FormatAmount(data.Currency, data.Spent), data.BreachAt.Format("2006-01-02 15:04 UTC"))
+		}
+
+	case config.WebhookEventCertExpiry:
+		if data, ok := payload.Data.(*CertExpiryData); ok {
+			return fmt.Sprintf("🔐 Certificate Expiry: %s certificate for %s expires in %d days (%s)",
+				data.Provider, data.Host, data.DaysLeft, data.ExpiresAt.Format("2006-01-02"))
+		}
+
+	case config.WebhookEventDaemonRestart:
+		if data, ok := payload.Data.(*DaemonRestartData); ok {
+			msg := fmt.Sprintf("♻️ Daemon Restart: zend %s started (PID %d, reason: %s)", data.Version, data.PID, data.Reason)
+			if data.Detail != "" {
+				msg += ". " + data.Detail
+			}
+			return msg
+		}
+
+	case config.WebhookEventSpendSpike:
+		if data, ok := payload.Data.(*SpendSpikeData); ok {
+			return data.Text()
+		}
+
+	case config.WebhookEventKeyInvalid:
+		if data, ok := payload.Data.(*KeyInvalidData); ok {
+			if data.NextKeyID == "" {
+				return fmt.Sprintf("🔑 Key Invalid: %s rejected key %s with status %d and has no fallback key",
+					data.Provider, data.KeyID, data.Status)
+			}
+			return fmt.Sprintf("🔑 Key Invalid: %s rejected key %s with status %d, now using key %s",
+				data.Provider, data.KeyID, data.Status, data.NextKeyID)
 		}
 	}
 
@@ -263,13 +476,31 @@ func (d *WebhookDispatcher) getColorForEvent(event config.WebhookEvent) int {
 		return 0x86EFAC // Sage/Green
 	case config.WebhookEventFailover:
 		return 0xC4B5FD // Lavender
-	case config.WebhookEventDailySummary:
+	case config.WebhookEventDailySummary, config.WebhookEventWeeklySummary:
 		return 0x5EEAD4 // Teal
+	case config.WebhookEventRequestFailureBurst, config.WebhookEventKeyInvalid, config.WebhookEventSpendSpike:
+		return 0xFB7185 // Red
+	case config.WebhookEventBudgetForecast, config.WebhookEventCertExpiry:
+		return 0xFBBF24 // Amber
 	default:
 		return 0x93C5FD // Blue
 	}
 }
 
+// getSeverityForEvent returns a PagerDuty severity for the event type.
+func (d *WebhookDispatcher) getSeverityForEvent(event config.WebhookEvent) string {
+	switch event {
+	case config.WebhookEventProviderDown:
+		return "critical"
+	case config.WebhookEventBudgetExceeded, config.WebhookEventRequestFailureBurst, config.WebhookEventKeyInvalid, config.WebhookEventSpendSpike:
+		return "error"
+	case config.WebhookEventBudgetWarning, config.WebhookEventFailover, config.WebhookEventBudgetForecast, config.WebhookEventCertExpiry:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
 // TestWebhook sends a test message to a webhook.
 func (d *WebhookDispatcher) TestWebhook(wh *config.WebhookConfig) error {
 	payload := WebhookPayload{
@@ -280,35 +511,16 @@ func (d *WebhookDispatcher) TestWebhook(wh *config.WebhookConfig) error {
 		},
 	}
 
-	var body []byte
-	if strings.Contains(wh.URL, "slack.com") {
-		body = d.formatSlack(payload)
-	} else if strings.Contains(wh.URL, "discord.com") {
-		body = d.formatDiscord(payload)
-	} else {
-		body = d.formatGeneric(payload)
-	}
-
-	req, err := http.NewRequest(http.MethodPost, wh.URL, bytes.NewReader(body))
+	body, err := d.format(wh, payload)
 	if err != nil {
 		return err
 	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("User-Agent", "GoZen-Webhook/1.0")
-
-	for k, v := range wh.Headers {
-		req.Header.Set(k, v)
-	}
-
-	resp, err := d.client.Do(req)
+	status, err := d.post(wh, body)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode >= 400 {
-		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	if status >= 400 {
+		return fmt.Errorf("webhook returned status %d", status)
 	}
 
 	return nil
@@ -334,26 +546,30 @@ func DispatchEvent(event config.WebhookEvent, data interface{}) {
 
 // --- Event helper functions ---
 
-// NotifyBudgetWarning sends a budget warning notification.
+// NotifyBudgetWarning sends a budget warning notification. Amounts are in
+// the display currency.
 func NotifyBudgetWarning(period string, spent, limit, percent float64, project string) {
 	DispatchEvent(config.WebhookEventBudgetWarning, &BudgetEventData{
-		Period:  period,
-		Spent:   spent,
-		Limit:   limit,
-		Percent: percent,
-		Project: project,
+		Period:   period,
+		Spent:    spent,
+		Limit:    limit,
+		Percent:  percent,
+		Project:  project,
+		Currency: config.GetCurrency().GetCode(),
 	})
 }
 
-// NotifyBudgetExceeded sends a budget exceeded notification.
+// NotifyBudgetExceeded sends a budget exceeded notification. Amounts are in
+// the display currency.
 func NotifyBudgetExceeded(period string, spent, limit float64, action, project string) {
 	DispatchEvent(config.WebhookEventBudgetExceeded, &BudgetEventData{
-		Period:  period,
-		Spent:   spent,
-		Limit:   limit,
-		Percent: 100,
-		Action:  action,
-		Project: project,
+		Period:   period,
+		Spent:    spent,
+		Limit:    limit,
+		Percent:  100,
+		Action:   action,
+		Project:  project,
+		Currency: config.GetCurrency().GetCode(),
 	})
 }
 
@@ -385,9 +601,11 @@ func NotifyFailover(from, to, reason, sessionID string) {
 	})
 }
 
-// NotifyDailySummary sends a daily summary notification.
+// NotifyDailySummary sends a daily summary notification. Costs are in the
+// display currency.
 func NotifyDailySummary(date string, cost float64, requests, input, output int, byProvider map[string]float64) {
 	DispatchEvent(config.WebhookEventDailySummary, &DailySummaryData{
+		Currency:      config.GetCurrency().GetCode(),
 		Date:          date,
 		TotalCost:     cost,
 		TotalRequests: requests,
@@ -396,3 +614,81 @@ func NotifyDailySummary(date string, cost float64, requests, input, output int,
 		ByProvider:    byProvider,
 	})
 }
+
+// NotifyWeeklySummary sends a weekly summary notification covering start
+// through end. Costs are in the display currency.
+func NotifyWeeklySummary(start, end string, cost float64, requests, input, output int, byProvider map[string]float64) {
+	DispatchEvent(config.WebhookEventWeeklySummary, &DailySummaryData{
+		Currency:      config.GetCurrency().GetCode(),
+		Date:          start,
+		EndDate:       end,
+		TotalCost:     cost,
+		TotalRequests: requests,
+		TotalInput:    input,
+		TotalOutput:   output,
+		ByProvider:    byProvider,
+	})
+}
+
+// NotifyFailureBurst sends a request_failure_burst notification.
+func NotifyFailureBurst(provider string, failures int, window time.Duration, lastError string) {
+	DispatchEvent(config.WebhookEventRequestFailureBurst, &FailureBurstData{
+		Provider:      provider,
+		Failures:      failures,
+		WindowMinutes: int(window.Minutes()),
+		LastError:     lastError,
+	})
+}
+
+// NotifyBudgetForecast sends a budget_forecast notification. Amounts are in
+// the display currency.
+func NotifyBudgetForecast(period string, spent, limit, projected float64, breachAt time.Time) {
+	DispatchEvent(config.WebhookEventBudgetForecast, &BudgetForecastData{
+		Period:    period,
+		Spent:     spent,
+		Limit:     limit,
+		Projected: projected,
+		BreachAt:  breachAt,
+		Currency:  config.GetCurrency().GetCode(),
+	})
+}
+
+// NotifyCertExpiry sends a cert_expiry notification.
+func NotifyCertExpiry(provider, host string, expiresAt time.Time, daysLeft int) {
+	DispatchEvent(config.WebhookEventCertExpiry, &CertExpiryData{
+		Provider:  provider,
+		Host:      host,
+		ExpiresAt: expiresAt,
+		DaysLeft:  daysLeft,
+	})
+}
+
+// NotifyDaemonRestart sends a daemon_restart notification.
+func NotifyDaemonRestart(reason, detail, version string, pid int) {
+	DispatchEvent(config.WebhookEventDaemonRestart, &DaemonRestartData{
+		Reason:  reason,
+		Detail:  detail,
+		Version: version,
+		PID:     pid,
+	})
+}
+
+// NotifyKeyInvalid sends a key_invalid notification.
+func NotifyKeyInvalid(provider, keyID string, status int, nextKeyID string) {
+	DispatchEvent(config.WebhookEventKeyInvalid, &KeyInvalidData{
+		Provider:  provider,
+		KeyID:     keyID,
+		Status:    status,
+		NextKeyID: nextKeyID,
+	})
+}
+
+// NotifySpendSpike sends a spend_spike notification. data.Currency is set to
+// the display currency and its costs, given in USD, converted to it.
+func NotifySpendSpike(data *SpendSpikeData) {
+	currency := config.GetCurrency()
+	data.Currency = currency.GetCode()
+	data.CostRate = currency.FromUSD(data.CostRate)
+	data.BaselineCostRate = currency.FromUSD(data.BaselineCostRate)
+	DispatchEvent(config.WebhookEventSpendSpike, data)
+}
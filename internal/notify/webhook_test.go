@@ -168,6 +168,59 @@ func TestFormatGeneric(t *testing.T) {
 	}
 }
 
+func TestFormatPagerDuty(t *testing.T) {
+	d := NewWebhookDispatcher()
+	wh := &config.WebhookConfig{URL: "https://events.pagerduty.com/v2/enqueue", RoutingKey: "rk"}
+	if wh.GetFormat() != config.WebhookFormatPagerDuty {
+		t.Fatalf("GetFormat() = %q, want pagerduty", wh.GetFormat())
+	}
+
+	var down, up map[string]interface{}
+	body, _ := d.format(wh, WebhookPayload{Event: config.WebhookEventProviderDown, Data: &ProviderEventData{Provider: "anthropic", Error: "timeout"}})
+	if err := json.Unmarshal(body, &down); err != nil {
+		t.Fatalf("failed to unmarshal PagerDuty event: %v", err)
+	}
+	body, _ = d.format(wh, WebhookPayload{Event: config.WebhookEventProviderUp, Data: &ProviderEventData{Provider: "anthropic"}})
+	if err := json.Unmarshal(body, &up); err != nil {
+		t.Fatalf("failed to unmarshal PagerDuty event: %v", err)
+	}
+
+	if down["routing_key"] != "rk" || down["event_action"] != "trigger" || down["dedup_key"] != "gozen-provider-anthropic" {
+		t.Errorf("provider_down event = %v", down)
+	}
+	if p, _ := down["payload"].(map[string]interface{}); p == nil || p["severity"] != "critical" || p["source"] != "gozen" {
+		t.Errorf("provider_down payload = %v", down["payload"])
+	}
+	if up["event_action"] != "resolve" || up["dedup_key"] != down["dedup_key"] || up["payload"] != nil {
+		t.Errorf("provider_up event = %v", up)
+	}
+}
+
+func TestFormatTemplate(t *testing.T) {
+	d := NewWebhookDispatcher()
+	wh := &config.WebhookConfig{
+		Name:     "custom",
+		URL:      "https://hooks.slack.com/services/X",
+		Template: `{"text": {{json .Message}}, "provider": "{{.Data.provider}}", "event": "{{.Event}}"}`,
+	}
+	body, err := d.format(wh, WebhookPayload{Event: config.WebhookEventProviderDown, Data: &ProviderEventData{Provider: "anthropic", Error: "timeout"}})
+	if err != nil {
+		t.Fatalf("format() error: %v", err)
+	}
+	var msg map[string]string
+	if err := json.Unmarshal(body, &msg); err != nil {
+		t.Fatalf("template output is not JSON: %v: %s", err, body)
+	}
+	if msg["provider"] != "anthropic" || msg["event"] != "provider_down" || !contains(msg["text"], "Provider Down") {
+		t.Errorf("rendered template = %v", msg)
+	}
+
+	wh.Template = `{{.Data.provider.name}}`
+	if _, err := d.format(wh, WebhookPayload{Event: config.WebhookEventProviderDown, Data: &ProviderEventData{Provider: "anthropic"}}); err == nil {
+		t.Error("expected an error rendering a broken template")
+	}
+}
+
 func TestFormatMessage(t *testing.T) {
 	d := NewWebhookDispatcher()
 
@@ -250,6 +303,70 @@ func TestFormatMessage(t *testing.T) {
 			},
 			contains: "Daily Summary",
 		},
+		{
+			name: "weekly summary",
+			payload: WebhookPayload{
+				Event: config.WebhookEventWeeklySummary,
+				Data: &DailySummaryData{
+					Date:          "2026-03-01",
+					EndDate:       "2026-03-07",
+					TotalCost:     125.00,
+					TotalRequests: 700,
+				},
+			},
+			contains: "Weekly Summary (2026-03-01 to 2026-03-07)",
+		},
+		{
+			name: "request failure burst",
+			payload: WebhookPayload{
+				Event: config.WebhookEventRequestFailureBurst,
+				Data:  &FailureBurstData{Provider: "anthropic", Failures: 5, WindowMinutes: 5, LastError: "502"},
+			},
+			contains: "5 requests to anthropic failed in 5 minutes",
+		},
+		{
+			name: "budget forecast",
+			payload: WebhookPayload{
+				Event: config.WebhookEventBudgetForecast,
+				Data: &BudgetForecastData{
+					Period: "monthly", Spent: 60, Limit: 100, Projected: 180,
+					BreachAt: time.Date(2026, 3, 17, 9, 0, 0, 0, time.UTC),
+				},
+			},
+			contains: "reached around 2026-03-17 09:00 UTC",
+		},
+		{
+			name: "cert expiry",
+			payload: WebhookPayload{
+				Event: config.WebhookEventCertExpiry,
+				Data:  &CertExpiryData{Provider: "relay", Host: "relay.example.com", DaysLeft: 6, ExpiresAt: time.Date(2026, 3, 11, 0, 0, 0, 0, time.UTC)},
+			},
+			contains: "relay.example.com expires in 6 days",
+		},
+		{
+			name: "daemon restart",
+			payload: WebhookPayload{
+				Event: config.WebhookEventDaemonRestart,
+				Data:  &DaemonRestartData{Reason: "crash", Detail: "restart 1/5", Version: "3.1.0", PID: 4242},
+			},
+			contains: "reason: crash). restart 1/5",
+		},
+		{
+			name: "key invalid",
+			payload: WebhookPayload{
+				Event: config.WebhookEventKeyInvalid,
+				Data:  &KeyInvalidData{Provider: "anthropic", KeyID: "primary", Status: 401, NextKeyID: "2026-q2"},
+			},
+			contains: "rejected key primary with status 401, now using key 2026-q2",
+		},
+		{
+			name: "spend spike",
+			payload: WebhookPayload{
+				Event: config.WebhookEventSpendSpike,
+				Data:  &SpendSpikeData{SessionID: "s1", CostRate: 1.5, BaselineCostRate: 0.1, TokenRate: 90000, BaselineTokenRate: 8000, Multiple: 5, Paused: true},
+			},
+			contains: "session s1 is spending $1.50/min",
+		},
 	}
 
 	for _, tt := range tests {
@@ -278,6 +395,7 @@ func TestGetColorForEvent(t *testing.T) {
 		{config.WebhookEventProviderUp, 0x86EFAC},
 		{config.WebhookEventFailover, 0xC4B5FD},
 		{config.WebhookEventDailySummary, 0x5EEAD4},
+		{config.WebhookEventWeeklySummary, 0x5EEAD4},
 		{"unknown", 0x93C5FD},
 	}
 
@@ -448,6 +566,10 @@ func TestHelperFunctions(t *testing.T) {
 			"openai":    10.5,
 		})
 	})
+
+	t.Run("NotifyWeeklySummary", func(t *testing.T) {
+		NotifyWeeklySummary("2026-03-01", "2026-03-07", 125.0, 700, 350000, 70000, nil)
+	})
 }
 
 // Helper function
@@ -0,0 +1,187 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// AccessLog writes one line per proxied request, in the Common Log Format
+// followed by zen's own fields, or as a JSON object.
+//
+// A common line reads:
+//
+//	127.0.0.1 - alice [16/Oct/2026:22:06:07 +0000] "POST /v1/messages HTTP/1.1" 200 5321 "/src/app" "anthropic" "claude-sonnet-4-5" 1834 1200 350
+//
+// that is host, ident, user, time, request line, status and response bytes,
+// then project, provider and model (quoted, "-" when unknown), latency in
+// milliseconds, and input and output tokens.
+type AccessLog struct {
+	mu   sync.Mutex
+	out  io.Writer
+	json bool
+}
+
+// NewAccessLog creates an access log writing to out in format ("common" or
+// "json").
+func NewAccessLog(out io.Writer, format string) *AccessLog {
+	return &AccessLog{out: out, json: format == config.AccessLogFormatJSON}
+}
+
+// AccessLogEntry is what the access log records of one request.
+type AccessLogEntry struct {
+	Timestamp    time.Time `json:"timestamp"`
+	RemoteAddr   string    `json:"remote_addr"`
+	User         string    `json:"user,omitempty"`
+	Method       string    `json:"method"`
+	Path         string    `json:"path"`
+	Protocol     string    `json:"protocol"`
+	Status       int       `json:"status"`
+	Bytes        int64     `json:"bytes"`
+	LatencyMs    int64     `json:"latency_ms"`
+	RequestID    string    `json:"request_id,omitempty"`
+	SessionID    string    `json:"session_id,omitempty"`
+	Client       string    `json:"client,omitempty"`
+	Project      string    `json:"project,omitempty"`
+	Provider     string    `json:"provider,omitempty"`
+	Model        string    `json:"model,omitempty"`
+	InputTokens  int       `json:"input_tokens"`
+	OutputTokens int       `json:"output_tokens"`
+}
+
+// Log writes one entry.
+func (l *AccessLog) Log(e AccessLogEntry) error {
+	var line []byte
+	if l.json {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		line = append(data, '\n')
+	} else {
+		line = []byte(formatCommonLine(e))
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err := l.out.Write(line)
+	return err
+}
+
+func formatCommonLine(e AccessLogEntry) string {
+	host := e.RemoteAddr
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return fmt.Sprintf("%s - %s [%s] \"%s %s %s\" %d %d %s %s %s %d %d %d\n",
+		orDash(host), orDash(strings.ReplaceAll(e.User, " ", "_")),
+		e.Timestamp.Format("02/Jan/2006:15:04:05 -0700"),
+		e.Method, e.Path, e.Protocol, e.Status, e.Bytes,
+		quoteField(e.Project), quoteField(e.Provider), quoteField(e.Model),
+		e.LatencyMs, e.InputTokens, e.OutputTokens)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// quoteField quotes s for a common line, escaping quotes and backslashes.
+func quoteField(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(orDash(s))
+	return `"` + s + `"`
+}
+
+var (
+	accessLogMu sync.RWMutex
+	accessLog   *AccessLog
+)
+
+// SetGlobalAccessLog sets the access log proxied requests are written to,
+// or turns it off when l is nil.
+func SetGlobalAccessLog(l *AccessLog) {
+	accessLogMu.Lock()
+	accessLog = l
+	accessLogMu.Unlock()
+}
+
+// GetGlobalAccessLog returns the access log, or nil when it is off.
+func GetGlobalAccessLog() *AccessLog {
+	accessLogMu.RLock()
+	defer accessLogMu.RUnlock()
+	return accessLog
+}
+
+// accessEntry collects a request's access log fields while it is served.
+type accessEntry struct {
+	AccessLogEntry
+	stream *sseUsageExtractor // tokens of a streamed response, known once it ends
+}
+
+type accessEntryKey struct{}
+
+func withAccessEntry(ctx context.Context, e *accessEntry) context.Context {
+	return context.WithValue(ctx, accessEntryKey{}, e)
+}
+
+// requestAccessEntry returns the access log entry of a request, or nil when
+// the access log is off.
+func requestAccessEntry(r *http.Request) *accessEntry {
+	e, _ := r.Context().Value(accessEntryKey{}).(*accessEntry)
+	return e
+}
+
+// finish completes the entry once the response has been written.
+func (e *accessEntry) finish(w *accessRecorder) AccessLogEntry {
+	entry := e.AccessLogEntry
+	entry.Status = w.statusCode
+	if entry.Status == 0 {
+		entry.Status = http.StatusOK
+	}
+	entry.Bytes = w.bytes
+	entry.LatencyMs = time.Since(entry.Timestamp).Milliseconds()
+	entry.Project = GetSessionProject(entry.SessionID)
+	if e.stream != nil {
+		entry.InputTokens, entry.OutputTokens = e.stream.inputTok, e.stream.outputTok
+	}
+	return entry
+}
+
+// accessRecorder wraps a ResponseWriter to note the status and size of the
+// response for the access log.
+type accessRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	bytes      int64
+}
+
+func (r *accessRecorder) WriteHeader(code int) {
+	if r.statusCode == 0 {
+		r.statusCode = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *accessRecorder) Write(p []byte) (int, error) {
+	if r.statusCode == 0 {
+		r.statusCode = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(p)
+	r.bytes += int64(n)
+	return n, err
+}
+
+func (r *accessRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
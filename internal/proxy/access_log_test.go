@@ -0,0 +1,104 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+func TestAccessLogCommonLine(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewAccessLog(&buf, config.AccessLogFormatCommon)
+	ts := time.Date(2026, 10, 16, 22, 6, 7, 0, time.UTC)
+	l.Log(AccessLogEntry{
+		Timestamp:    ts,
+		RemoteAddr:   "127.0.0.1:51234",
+		User:         "alice",
+		Method:       "POST",
+		Path:         "/v1/messages",
+		Protocol:     "HTTP/1.1",
+		Status:       200,
+		Bytes:        5321,
+		LatencyMs:    1834,
+		Project:      `/src/my "app"`,
+		Provider:     "anthropic",
+		Model:        "claude-sonnet-4-5",
+		InputTokens:  1200,
+		OutputTokens: 350,
+	})
+	l.Log(AccessLogEntry{Timestamp: ts, Method: "POST", Path: "/v1/messages", Protocol: "HTTP/1.1", Status: 502})
+
+	want := `127.0.0.1 - alice [16/Oct/2026:22:06:07 +0000] "POST /v1/messages HTTP/1.1" 200 5321 "/src/my \"app\"" "anthropic" "claude-sonnet-4-5" 1834 1200 350` + "\n" +
+		`- - - [16/Oct/2026:22:06:07 +0000] "POST /v1/messages HTTP/1.1" 502 0 "-" "-" "-" 0 0 0` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("lines =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestProxyAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	SetGlobalAccessLog(NewAccessLog(&buf, config.AccessLogFormatJSON))
+	defer SetGlobalAccessLog(nil)
+
+	fail := false
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"msg_1","content":[{"type":"text","text":"hi"}],"usage":{"input_tokens":12,"output_tokens":5}}`)
+	}))
+	defer backend.Close()
+
+	u, _ := url.Parse(backend.URL)
+	providers := []*Provider{{Name: "p1", BaseURL: u, Token: "t", Healthy: true}}
+	srv := NewProxyServer(providers, discardLogger(), config.LoadBalanceFailover, nil)
+
+	send := func() {
+		req := httptest.NewRequest("POST", "/v1/messages", strings.NewReader(`{"model":"claude-sonnet-4-6","messages":[{"role":"user","content":"hi"}]}`))
+		req.Header.Set("X-Zen-Session", "access-log-test")
+		srv.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	send()
+	fail = true
+	send()
+
+	var entries []AccessLogEntry
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var e AccessLogEntry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+
+	ok := entries[0]
+	if ok.Status != 200 || ok.Provider != "p1" || ok.Model != "claude-sonnet-4-6" || ok.Method != "POST" || ok.Path != "/v1/messages" {
+		t.Errorf("success entry = %+v", ok)
+	}
+	if ok.InputTokens != 12 || ok.OutputTokens != 5 {
+		t.Errorf("tokens = %d/%d, want 12/5", ok.InputTokens, ok.OutputTokens)
+	}
+	if ok.SessionID != "access-log-test" || ok.RequestID == "" || ok.Bytes == 0 {
+		t.Errorf("success entry = %+v", ok)
+	}
+
+	failed := entries[1]
+	if failed.Status < 500 || failed.Provider != "" || failed.Model != "claude-sonnet-4-6" {
+		t.Errorf("failed entry = %+v", failed)
+	}
+}
@@ -0,0 +1,154 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AgentActivity is what one proxied request shows of an agent session's
+// work: the tool calls the model made in its last turn, the results the
+// client sent back for them, and the tokens the request used.
+type AgentActivity struct {
+	SessionID    string
+	ClientType   string
+	ProjectPath  string
+	Timestamp    time.Time
+	InputTokens  int // unknown (0) for streams that end early
+	OutputTokens int
+	Cost         float64 // in USD
+	ToolCalls    []AgentToolCall
+	ToolResults  []AgentToolResult
+	Compression  *AgentCompression // set when the proxy compressed the request
+}
+
+// AgentToolCall is a tool_use block from the model.
+type AgentToolCall struct {
+	ID    string
+	Name  string
+	Input json.RawMessage // with object keys sorted, so equal inputs compare equal
+}
+
+// AgentToolResult is a tool_result block from the client.
+type AgentToolResult struct {
+	ToolUseID string
+	IsError   bool
+	Output    string // text of the result, cut to maxToolResultOutput bytes
+}
+
+// AgentCompression describes context compression applied to a request.
+type AgentCompression struct {
+	BytesBefore int
+	BytesAfter  int
+}
+
+type compressionKey struct{}
+
+func withCompression(ctx context.Context, c AgentCompression) context.Context {
+	return context.WithValue(ctx, compressionKey{}, c)
+}
+
+// requestCompression returns the compression stored in a request's context,
+// or nil if the request was sent as is.
+func requestCompression(r *http.Request) *AgentCompression {
+	if c, ok := r.Context().Value(compressionKey{}).(AgentCompression); ok {
+		return &c
+	}
+	return nil
+}
+
+var (
+	agentActivityMu sync.RWMutex
+	onAgentActivity func(AgentActivity)
+)
+
+// OnAgentActivity sets a function called after each successful request
+// with what it shows of the session's work. It runs on the request's
+// goroutine, so it should not block.
+func OnAgentActivity(fn func(AgentActivity)) {
+	agentActivityMu.Lock()
+	defer agentActivityMu.Unlock()
+	onAgentActivity = fn
+}
+
+func agentActivityHook() func(AgentActivity) {
+	agentActivityMu.RLock()
+	defer agentActivityMu.RUnlock()
+	return onAgentActivity
+}
+
+// agentActivityFor reads the last assistant turn's tool calls and the tool
+// results that follow it from an Anthropic Messages request body. Earlier
+// turns were already reported with the requests that carried them.
+func agentActivityFor(sessionID, clientType string, reqData map[string]interface{}) AgentActivity {
+	a := AgentActivity{
+		SessionID:   sessionID,
+		ClientType:  clientType,
+		ProjectPath: GetSessionProject(sessionID),
+		Timestamp:   time.Now(),
+	}
+	messages, _ := reqData["messages"].([]interface{})
+	last := -1
+	for i := len(messages) - 1; i >= 0; i-- {
+		if m, ok := messages[i].(map[string]interface{}); ok && m["role"] == "assistant" {
+			last = i
+			break
+		}
+	}
+	if last < 0 {
+		return a
+	}
+	for i, msg := range messages[last:] {
+		m, _ := msg.(map[string]interface{})
+		blocks, _ := m["content"].([]interface{})
+		for _, block := range blocks {
+			b, _ := block.(map[string]interface{})
+			switch b["type"] {
+			case "tool_use":
+				if i > 0 {
+					continue
+				}
+				id, _ := b["id"].(string)
+				name, _ := b["name"].(string)
+				input, _ := json.Marshal(b["input"])
+				a.ToolCalls = append(a.ToolCalls, AgentToolCall{ID: id, Name: name, Input: input})
+			case "tool_result":
+				id, _ := b["tool_use_id"].(string)
+				isError, _ := b["is_error"].(bool)
+				a.ToolResults = append(a.ToolResults, AgentToolResult{ToolUseID: id, IsError: isError, Output: toolResultText(b["content"])})
+			}
+		}
+	}
+	return a
+}
+
+// maxToolResultOutput bounds the tool output kept per result.
+const maxToolResultOutput = 2048
+
+// toolResultText returns the text of a tool_result's content, which is a
+// string or a list of content blocks.
+func toolResultText(content interface{}) string {
+	var text string
+	switch c := content.(type) {
+	case string:
+		text = c
+	case []interface{}:
+		var parts []string
+		for _, block := range c {
+			if b, ok := block.(map[string]interface{}); ok && b["type"] == "text" {
+				if t, ok := b["text"].(string); ok {
+					parts = append(parts, t)
+				}
+			}
+		}
+		text = strings.Join(parts, "\n")
+	}
+	if len(text) > maxToolResultOutput {
+		// Keep the end: that is where a command says how it finished
+		text = strings.ToValidUTF8(text[len(text)-maxToolResultOutput:], "")
+	}
+	return text
+}
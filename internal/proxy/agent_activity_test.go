@@ -0,0 +1,82 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAgentActivityFor(t *testing.T) {
+	var reqData map[string]interface{}
+	body := `{"messages": [
+		{"role": "user", "content": "fix the build"},
+		{"role": "assistant", "content": [
+			{"type": "tool_use", "id": "old", "name": "Read", "input": {"file_path": "go.mod"}}
+		]},
+		{"role": "user", "content": [{"type": "tool_result", "tool_use_id": "old"}]},
+		{"role": "assistant", "content": [
+			{"type": "text", "text": "Running the tests."},
+			{"type": "tool_use", "id": "t1", "name": "Bash", "input": {"timeout": 60, "command": "go test"}},
+			{"type": "tool_use", "id": "t2", "name": "Edit", "input": {"file_path": "main.go"}}
+		]},
+		{"role": "user", "content": [
+			{"type": "tool_result", "tool_use_id": "t1", "is_error": true, "content": [{"type": "text", "text": "FAIL\tpkg"}]},
+			{"type": "tool_result", "tool_use_id": "t2"}
+		]}
+	]}`
+	if err := json.Unmarshal([]byte(body), &reqData); err != nil {
+		t.Fatal(err)
+	}
+
+	a := agentActivityFor("sess-1", "claude", reqData)
+	if a.SessionID != "sess-1" || a.ClientType != "claude" {
+		t.Errorf("SessionID, ClientType = %q, %q", a.SessionID, a.ClientType)
+	}
+	if len(a.ToolCalls) != 2 || a.ToolCalls[0].ID != "t1" || a.ToolCalls[1].Name != "Edit" {
+		t.Fatalf("ToolCalls = %+v, want t1 and t2 from the last turn", a.ToolCalls)
+	}
+	if got, want := string(a.ToolCalls[0].Input), `{"command":"go test","timeout":60}`; got != want {
+		t.Errorf("Input = %s, want %s", got, want)
+	}
+	if len(a.ToolResults) != 2 || !a.ToolResults[0].IsError || a.ToolResults[0].Output != "FAIL\tpkg" || a.ToolResults[1].IsError {
+		t.Errorf("ToolResults = %+v", a.ToolResults)
+	}
+
+	if a := agentActivityFor("sess-1", "claude", map[string]interface{}{}); len(a.ToolCalls) != 0 || len(a.ToolResults) != 0 {
+		t.Errorf("empty request: %+v", a)
+	}
+}
+
+func TestToolResultText(t *testing.T) {
+	long := strings.Repeat("x", maxToolResultOutput) + "PASS"
+	tests := []struct {
+		content interface{}
+		want    string
+	}{
+		{"ok", "ok"},
+		{[]interface{}{
+			map[string]interface{}{"type": "text", "text": "a"},
+			map[string]interface{}{"type": "image"},
+			map[string]interface{}{"type": "text", "text": "b"},
+		}, "a\nb"},
+		{nil, ""},
+		{long, long[4:]},
+	}
+	for _, tt := range tests {
+		if got := toolResultText(tt.content); got != tt.want {
+			t.Errorf("toolResultText(%.20v) = %.20q, want %.20q", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestRequestCompression(t *testing.T) {
+	r := httptest.NewRequest("POST", "/v1/messages", nil)
+	if requestCompression(r) != nil {
+		t.Error("uncompressed request reports compression")
+	}
+	r = r.WithContext(withCompression(r.Context(), AgentCompression{BytesBefore: 900, BytesAfter: 300}))
+	if c := requestCompression(r); c == nil || c.BytesBefore != 900 || c.BytesAfter != 300 {
+		t.Errorf("requestCompression = %+v", c)
+	}
+}
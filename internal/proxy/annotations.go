@@ -0,0 +1,220 @@
+package proxy
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kinds of things that can be annotated.
+const (
+	AnnotationSession = "session"
+	AnnotationLog     = "log"
+)
+
+// Limits on what an annotation may hold.
+const (
+	maxAnnotationLabels   = 20
+	maxAnnotationLabelLen = 64
+	maxAnnotationNoteLen  = 4096
+)
+
+// Annotation holds the labels, note and bookmark a user attached to a
+// session or a request log entry so it can be found again.
+type Annotation struct {
+	Labels     []string  `json:"labels"`
+	Note       string    `json:"note,omitempty"`
+	Bookmarked bool      `json:"bookmarked,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// IsEmpty reports whether the annotation holds nothing.
+func (a *Annotation) IsEmpty() bool {
+	return a == nil || (len(a.Labels) == 0 && a.Note == "" && !a.Bookmarked)
+}
+
+// Normalize trims, deduplicates and sorts the labels, and checks the
+// annotation against the size limits.
+func (a *Annotation) Normalize() error {
+	seen := make(map[string]bool)
+	labels := []string{}
+	for _, l := range a.Labels {
+		l = strings.TrimSpace(l)
+		if l == "" || seen[l] {
+			continue
+		}
+		if len(l) > maxAnnotationLabelLen {
+			return fmt.Errorf("label %q is longer than %d characters", l, maxAnnotationLabelLen)
+		}
+		seen[l] = true
+		labels = append(labels, l)
+	}
+	if len(labels) > maxAnnotationLabels {
+		return fmt.Errorf("at most %d labels are allowed", maxAnnotationLabels)
+	}
+	sort.Strings(labels)
+	a.Labels = labels
+	a.Note = strings.TrimSpace(a.Note)
+	if len(a.Note) > maxAnnotationNoteLen {
+		return fmt.Errorf("note is longer than %d characters", maxAnnotationNoteLen)
+	}
+	return nil
+}
+
+// AnnotationFilter selects annotated sessions or log entries. Empty fields
+// match everything.
+type AnnotationFilter struct {
+	Label      string
+	Bookmarked bool
+}
+
+// IsEmpty reports whether the filter selects everything, annotated or not.
+func (f AnnotationFilter) IsEmpty() bool {
+	return f.Label == "" && !f.Bookmarked
+}
+
+// Match reports whether an annotation, possibly nil, passes the filter.
+func (f AnnotationFilter) Match(a *Annotation) bool {
+	if f.IsEmpty() {
+		return true
+	}
+	if a == nil || (f.Bookmarked && !a.Bookmarked) {
+		return false
+	}
+	if f.Label == "" {
+		return true
+	}
+	for _, l := range a.Labels {
+		if l == f.Label {
+			return true
+		}
+	}
+	return false
+}
+
+const annotationsTable = `
+	CREATE TABLE IF NOT EXISTS annotations (
+		kind       TEXT NOT NULL,
+		target     TEXT NOT NULL,
+		labels     TEXT DEFAULT '[]',
+		note       TEXT DEFAULT '',
+		bookmarked INTEGER DEFAULT 0,
+		updated_at DATETIME NOT NULL,
+		PRIMARY KEY (kind, target)
+	)
+`
+
+// migrateV10ToV11 adds the annotations table.
+func migrateV10ToV11(tx *sql.Tx) error {
+	_, err := tx.Exec(annotationsTable)
+	return err
+}
+
+// SetAnnotation stores the annotation of a session or log entry, replacing
+// any earlier one. An empty annotation removes it.
+func (ldb *LogDB) SetAnnotation(kind, target string, a *Annotation) error {
+	if a.IsEmpty() {
+		if _, err := ldb.db.Exec("DELETE FROM annotations WHERE kind = ? AND target = ?", kind, target); err != nil {
+			return fmt.Errorf("delete annotation: %w", err)
+		}
+		return nil
+	}
+	labels, err := json.Marshal(a.Labels)
+	if err != nil {
+		return err
+	}
+	if _, err := ldb.db.Exec(`
+		INSERT INTO annotations (kind, target, labels, note, bookmarked, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (kind, target) DO UPDATE SET
+			labels = excluded.labels, note = excluded.note,
+			bookmarked = excluded.bookmarked, updated_at = excluded.updated_at
+	`, kind, target, string(labels), a.Note, a.Bookmarked, a.UpdatedAt.UTC().Format(time.RFC3339Nano)); err != nil {
+		return fmt.Errorf("store annotation: %w", err)
+	}
+	return nil
+}
+
+// GetAnnotation returns the annotation of a session or log entry, or nil.
+func (ldb *LogDB) GetAnnotation(kind, target string) (*Annotation, error) {
+	all, err := ldb.queryAnnotations("WHERE kind = ? AND target = ?", kind, target)
+	if err != nil {
+		return nil, err
+	}
+	return all[target], nil
+}
+
+// Annotations returns every annotation of a kind, keyed by target.
+func (ldb *LogDB) Annotations(kind string) (map[string]*Annotation, error) {
+	return ldb.queryAnnotations("WHERE kind = ?", kind)
+}
+
+// logAnnotations returns the annotations of the given log entries.
+func (ldb *LogDB) logAnnotations(entries []LogEntry) (map[string]*Annotation, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	placeholders := make([]string, len(entries))
+	args := []interface{}{AnnotationLog}
+	for i, e := range entries {
+		placeholders[i] = "?"
+		args = append(args, strconv.FormatInt(e.ID, 10))
+	}
+	return ldb.queryAnnotations("WHERE kind = ? AND target IN ("+strings.Join(placeholders, ", ")+")", args...)
+}
+
+func (ldb *LogDB) queryAnnotations(where string, args ...interface{}) (map[string]*Annotation, error) {
+	rows, err := ldb.db.Query("SELECT target, labels, note, bookmarked, updated_at FROM annotations "+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query annotations: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[string]*Annotation)
+	for rows.Next() {
+		var target, labels, tsStr string
+		a := &Annotation{}
+		if err := rows.Scan(&target, &labels, &a.Note, &a.Bookmarked, &tsStr); err != nil {
+			continue
+		}
+		if err := json.Unmarshal([]byte(labels), &a.Labels); err != nil || a.Labels == nil {
+			a.Labels = []string{}
+		}
+		if t, err := time.Parse(time.RFC3339Nano, tsStr); err == nil {
+			a.UpdatedAt = t
+		}
+		result[target] = a
+	}
+	return result, rows.Err()
+}
+
+// annotationConditions returns the WHERE conditions selecting log entries
+// whose annotation passes the filter.
+func annotationConditions(filter AnnotationFilter) ([]string, []interface{}) {
+	if filter.IsEmpty() {
+		return nil, nil
+	}
+	cond := "CAST(id AS TEXT) IN (SELECT target FROM annotations WHERE kind = ?"
+	args := []interface{}{AnnotationLog}
+	if filter.Bookmarked {
+		cond += " AND bookmarked = 1"
+	}
+	if filter.Label != "" {
+		cond += " AND EXISTS (SELECT 1 FROM json_each(annotations.labels) WHERE value = ?)"
+		args = append(args, filter.Label)
+	}
+	return []string{cond + ")"}, args
+}
+
+// LogEntryExists reports whether the log database holds an entry with id.
+func (ldb *LogDB) LogEntryExists(id int64) (bool, error) {
+	var n int
+	if err := ldb.db.QueryRow("SELECT COUNT(*) FROM logs WHERE id = ?", id).Scan(&n); err != nil {
+		return false, fmt.Errorf("query logs: %w", err)
+	}
+	return n > 0, nil
+}
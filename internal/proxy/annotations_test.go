@@ -0,0 +1,108 @@
+package proxy
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestAnnotationNormalize(t *testing.T) {
+	a := &Annotation{Labels: []string{" flaky ", "", "bug", "flaky"}, Note: "  look at this \n"}
+	if err := a.Normalize(); err != nil {
+		t.Fatalf("Normalize: %v", err)
+	}
+	if len(a.Labels) != 2 || a.Labels[0] != "bug" || a.Labels[1] != "flaky" || a.Note != "look at this" {
+		t.Errorf("normalized = %+v", a)
+	}
+
+	long := make([]string, maxAnnotationLabels+1)
+	for i := range long {
+		long[i] = strconv.Itoa(i)
+	}
+	if err := (&Annotation{Labels: long}).Normalize(); err == nil {
+		t.Error("expected error for too many labels")
+	}
+	if (&Annotation{Labels: []string{}}).IsEmpty() != true {
+		t.Error("annotation without labels, note or bookmark should be empty")
+	}
+}
+
+func TestLogDBAnnotations(t *testing.T) {
+	db, err := OpenLogDB(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenLogDB: %v", err)
+	}
+	defer db.Close()
+
+	for i := 1; i <= 3; i++ {
+		db.Insert(LogEntry{Timestamp: time.Now(), Level: LogLevelError, Provider: "p", Message: "m" + strconv.Itoa(i)})
+	}
+	time.Sleep(700 * time.Millisecond)
+
+	entries, _, err := db.QueryPage(LogFilter{}, Page{Limit: 10})
+	if err != nil || len(entries) != 3 {
+		t.Fatalf("QueryPage = %d entries, %v", len(entries), err)
+	}
+	oldest := strconv.FormatInt(entries[2].ID, 10)
+	newest := strconv.FormatInt(entries[0].ID, 10)
+	now := time.Now()
+	if err := db.SetAnnotation(AnnotationLog, oldest, &Annotation{Labels: []string{"bug", "timeout"}, Note: "first", UpdatedAt: now}); err != nil {
+		t.Fatalf("SetAnnotation: %v", err)
+	}
+	if err := db.SetAnnotation(AnnotationLog, newest, &Annotation{Labels: []string{"bug"}, Bookmarked: true, UpdatedAt: now}); err != nil {
+		t.Fatalf("SetAnnotation: %v", err)
+	}
+
+	// Listed entries carry their annotation
+	entries, _, err = db.QueryPage(LogFilter{}, Page{Limit: 10})
+	if err != nil {
+		t.Fatalf("QueryPage: %v", err)
+	}
+	if entries[2].Annotation == nil || entries[2].Annotation.Note != "first" || entries[1].Annotation != nil {
+		t.Errorf("annotations = %+v, %+v", entries[2].Annotation, entries[1].Annotation)
+	}
+
+	for _, tc := range []struct {
+		filter AnnotationFilter
+		want   int
+	}{
+		{AnnotationFilter{Label: "bug"}, 2},
+		{AnnotationFilter{Label: "timeout"}, 1},
+		{AnnotationFilter{Bookmarked: true}, 1},
+		{AnnotationFilter{Label: "timeout", Bookmarked: true}, 0},
+		{AnnotationFilter{Label: "missing"}, 0},
+	} {
+		if n, err := db.Count(LogFilter{Annotation: tc.filter}); err != nil || n != tc.want {
+			t.Errorf("Count(%+v) = %d, %v; want %d", tc.filter, n, err, tc.want)
+		}
+	}
+
+	// Session annotations are kept apart from log annotations
+	if err := db.SetAnnotation(AnnotationSession, oldest, &Annotation{Note: "session", UpdatedAt: now}); err != nil {
+		t.Fatalf("SetAnnotation: %v", err)
+	}
+	if a, err := db.GetAnnotation(AnnotationLog, oldest); err != nil || a == nil || a.Note != "first" {
+		t.Errorf("GetAnnotation(log) = %+v, %v", a, err)
+	}
+
+	// Storing an empty annotation removes it
+	if err := db.SetAnnotation(AnnotationLog, oldest, &Annotation{}); err != nil {
+		t.Fatalf("SetAnnotation: %v", err)
+	}
+	if a, err := db.GetAnnotation(AnnotationLog, oldest); err != nil || a != nil {
+		t.Errorf("GetAnnotation after removal = %+v, %v", a, err)
+	}
+	if all, err := db.Annotations(AnnotationSession); err != nil || len(all) != 1 {
+		t.Errorf("Annotations(session) = %v, %v", all, err)
+	}
+}
+
+func TestLogFilterMatchAnnotation(t *testing.T) {
+	f := LogFilter{Annotation: AnnotationFilter{Label: "bug"}}
+	if f.Match(LogEntry{}) {
+		t.Error("entry without annotation should not match a label filter")
+	}
+	if !f.Match(LogEntry{Annotation: &Annotation{Labels: []string{"bug"}}}) {
+		t.Error("labelled entry should match")
+	}
+}
@@ -0,0 +1,173 @@
+package proxy
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// AuditEntry records one change made through the web API. Audit rows are
+// only ever appended: they are not compacted by the retention policy.
+type AuditEntry struct {
+	ID        int64                 `json:"id"`
+	Timestamp time.Time             `json:"timestamp"`
+	Actor     string                `json:"actor"` // who: "local", "token:<name>", a trusted-header user, or "session"
+	Role      string                `json:"role"`
+	ClientIP  string                `json:"client_ip"`
+	Method    string                `json:"method"`
+	Path      string                `json:"path"`
+	Status    int                   `json:"status"`
+	Changes   []config.ConfigChange `json:"changes"` // config fields changed, secrets masked
+}
+
+// AuditFilter selects audit entries. Empty fields match everything.
+type AuditFilter struct {
+	Actor      string
+	PathPrefix string
+	Method     string
+	ConfigPath string // entries that changed this config path or one below it
+	Since      time.Time
+	Until      time.Time
+}
+
+const auditLogTable = `
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id         INTEGER PRIMARY KEY AUTOINCREMENT,
+		timestamp  DATETIME NOT NULL,
+		actor      TEXT NOT NULL,
+		role       TEXT DEFAULT '',
+		client_ip  TEXT DEFAULT '',
+		method     TEXT NOT NULL,
+		path       TEXT NOT NULL,
+		status     INTEGER DEFAULT 0,
+		changes    TEXT DEFAULT '[]'
+	)
+`
+
+// migrateV9ToV10 adds the audit_log table.
+func migrateV9ToV10(tx *sql.Tx) error {
+	for _, stmt := range []string{
+		auditLogTable,
+		"CREATE INDEX IF NOT EXISTS idx_audit_log_timestamp ON audit_log(timestamp)",
+	} {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InsertAudit appends an entry to the audit log.
+func (ldb *LogDB) InsertAudit(e AuditEntry) error {
+	changes := e.Changes
+	if changes == nil {
+		changes = []config.ConfigChange{}
+	}
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return fmt.Errorf("encode audit changes: %w", err)
+	}
+	if _, err := ldb.db.Exec(`
+		INSERT INTO audit_log (timestamp, actor, role, client_ip, method, path, status, changes)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		e.Timestamp.UTC().Format(time.RFC3339Nano),
+		e.Actor,
+		e.Role,
+		e.ClientIP,
+		e.Method,
+		e.Path,
+		e.Status,
+		string(data),
+	); err != nil {
+		return fmt.Errorf("insert audit entry: %w", err)
+	}
+	return nil
+}
+
+// ListAudit returns a page of audit entries matching filter, newest first,
+// and whether there are more past it.
+func (ldb *LogDB) ListAudit(filter AuditFilter, page Page) ([]AuditEntry, bool, error) {
+	conds, args := auditConditions(filter)
+	conds, args = page.where(conds, args)
+	query := `SELECT id, timestamp, actor, role, client_ip, method, path, status, changes FROM audit_log`
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	order, limit := page.orderLimit()
+	rows, err := ldb.db.Query(query+order, append(args, limit)...)
+	if err != nil {
+		return nil, false, fmt.Errorf("query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var e AuditEntry
+		var tsStr, changes string
+		if err := rows.Scan(&e.ID, &tsStr, &e.Actor, &e.Role, &e.ClientIP, &e.Method, &e.Path, &e.Status, &changes); err != nil {
+			continue
+		}
+		if t, err := time.Parse(time.RFC3339Nano, tsStr); err == nil {
+			e.Timestamp = t
+		}
+		if err := json.Unmarshal([]byte(changes), &e.Changes); err != nil || e.Changes == nil {
+			e.Changes = []config.ConfigChange{}
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	entries, more := trimPage(page, entries)
+	return entries, more, nil
+}
+
+// CountAudit returns the number of audit entries matching filter.
+func (ldb *LogDB) CountAudit(filter AuditFilter) (int, error) {
+	conds, args := auditConditions(filter)
+	query := "SELECT COUNT(*) FROM audit_log"
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	var n int
+	if err := ldb.db.QueryRow(query, args...).Scan(&n); err != nil {
+		return 0, fmt.Errorf("count audit log: %w", err)
+	}
+	return n, nil
+}
+
+func auditConditions(filter AuditFilter) ([]string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	if filter.Actor != "" {
+		conds = append(conds, "actor = ?")
+		args = append(args, filter.Actor)
+	}
+	if filter.PathPrefix != "" {
+		conds = append(conds, "substr(path, 1, ?) = ?")
+		args = append(args, len(filter.PathPrefix), filter.PathPrefix)
+	}
+	if filter.Method != "" {
+		conds = append(conds, "method = ?")
+		args = append(args, strings.ToUpper(filter.Method))
+	}
+	if filter.ConfigPath != "" {
+		// Match the path itself, and paths below it, in the changes JSON
+		conds = append(conds, "EXISTS (SELECT 1 FROM json_each(audit_log.changes) WHERE json_extract(value, '$.path') = ? OR substr(json_extract(value, '$.path'), 1, ?) = ?)")
+		args = append(args, filter.ConfigPath, len(filter.ConfigPath)+1, filter.ConfigPath+".")
+	}
+	if !filter.Since.IsZero() {
+		conds = append(conds, "timestamp >= ?")
+		args = append(args, filter.Since.UTC().Format(time.RFC3339Nano))
+	}
+	if !filter.Until.IsZero() {
+		conds = append(conds, "timestamp <= ?")
+		args = append(args, filter.Until.UTC().Format(time.RFC3339Nano))
+	}
+	return conds, args
+}
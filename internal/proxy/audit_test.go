@@ -0,0 +1,80 @@
+package proxy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+func TestLogDBAudit(t *testing.T) {
+	db, err := OpenLogDB(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenLogDB: %v", err)
+	}
+	defer db.Close()
+
+	yesterday := time.Now().Add(-24 * time.Hour)
+	for _, e := range []AuditEntry{
+		{Timestamp: yesterday, Actor: "alice", Role: "admin", Method: "PUT", Path: "/api/v1/settings", Status: 200,
+			Changes: []config.ConfigChange{{Path: "default_profile", Op: config.ConfigChangeChanged, From: "default", To: "work"}}},
+		{Timestamp: time.Now(), Actor: "token:ci", Role: "admin", Method: "POST", Path: "/api/v1/providers", Status: 201,
+			Changes: []config.ConfigChange{{Path: "providers.new", Op: config.ConfigChangeAdded, To: map[string]interface{}{"base_url": "https://x"}}}},
+		{Timestamp: time.Now(), Actor: "local", Role: "admin", Method: "DELETE", Path: "/api/v1/auth/tokens/abc", Status: 200},
+	} {
+		if err := db.InsertAudit(e); err != nil {
+			t.Fatalf("InsertAudit: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter AuditFilter
+		want   []string // actors, newest first
+	}{
+		{"all", AuditFilter{}, []string{"local", "token:ci", "alice"}},
+		{"actor", AuditFilter{Actor: "alice"}, []string{"alice"}},
+		{"path prefix", AuditFilter{PathPrefix: "/api/v1/auth/"}, []string{"local"}},
+		{"method", AuditFilter{Method: "post"}, []string{"token:ci"}},
+		{"config path", AuditFilter{ConfigPath: "default_profile"}, []string{"alice"}},
+		{"config path parent", AuditFilter{ConfigPath: "providers"}, []string{"token:ci"}},
+		{"config path no partial match", AuditFilter{ConfigPath: "provider"}, nil},
+		{"since", AuditFilter{Since: time.Now().Add(-time.Hour)}, []string{"local", "token:ci"}},
+		{"until", AuditFilter{Until: time.Now().Add(-time.Hour)}, []string{"alice"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, _, err := db.ListAudit(tt.filter, Page{})
+			if err != nil {
+				t.Fatalf("ListAudit: %v", err)
+			}
+			var got []string
+			for _, e := range entries {
+				got = append(got, e.Actor)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("actors = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("actors = %v, want %v", got, tt.want)
+				}
+			}
+			if n, err := db.CountAudit(tt.filter); err != nil || n != len(tt.want) {
+				t.Errorf("CountAudit = %d, %v; want %d", n, err, len(tt.want))
+			}
+		})
+	}
+
+	entries, more, err := db.ListAudit(AuditFilter{}, Page{Limit: 1})
+	if err != nil || len(entries) != 1 || !more {
+		t.Fatalf("first page: %d entries, more=%v, err=%v", len(entries), more, err)
+	}
+	if len(entries[0].Changes) != 0 {
+		t.Errorf("entry without changes has %v", entries[0].Changes)
+	}
+	entries, _, _ = db.ListAudit(AuditFilter{Actor: "alice"}, Page{})
+	if c := entries[0].Changes; len(c) != 1 || c[0].From != "default" || c[0].To != "work" {
+		t.Errorf("changes = %+v", c)
+	}
+}
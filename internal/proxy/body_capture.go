@@ -0,0 +1,297 @@
+package proxy
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// captureHeader lets a client opt a single request into body capture.
+const captureHeader = "X-Zen-Capture"
+
+// maxBodyCaptures is how many captured exchanges the log DB keeps;
+// older rows are pruned on insert.
+const maxBodyCaptures = 500
+
+// redactedValue replaces the value of sensitive JSON fields in captures.
+const redactedValue = "[REDACTED]"
+
+// BodyCapture is one provider attempt's request and response bodies.
+type BodyCapture struct {
+	RequestID         string    `json:"request_id"`
+	Timestamp         time.Time `json:"timestamp"`
+	Provider          string    `json:"provider"`
+	SessionID         string    `json:"session_id,omitempty"`
+	StatusCode        int       `json:"status_code"`
+	RequestBody       string    `json:"request_body"`
+	ResponseBody      string    `json:"response_body"`
+	RequestTruncated  bool      `json:"request_truncated,omitempty"`
+	ResponseTruncated bool      `json:"response_truncated,omitempty"`
+}
+
+// captureRequested reports whether a header value asks for capture.
+func captureRequested(v string) bool {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "1", "true", "yes", "on":
+		return true
+	}
+	return false
+}
+
+// sensitiveField reports whether a JSON key likely holds a credential.
+func sensitiveField(key string) bool {
+	k := strings.ToLower(key)
+	for _, s := range []string{"api_key", "apikey", "token", "secret", "password", "authorization"} {
+		if strings.Contains(k, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitizeBody redacts credential-like fields from a JSON body.
+// Non-JSON bodies (and SSE streams) are returned unchanged.
+// Token count fields such as input_tokens are left alone.
+func sanitizeBody(body []byte) []byte {
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return body
+	}
+	if !redactValue(data) {
+		return body
+	}
+	out, err := json.Marshal(data)
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+// redactValue walks decoded JSON and redacts sensitive string fields in place.
+// It reports whether anything was changed.
+func redactValue(v interface{}) bool {
+	changed := false
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, child := range t {
+			if s, ok := child.(string); ok && s != "" && sensitiveField(k) && !strings.HasSuffix(strings.ToLower(k), "_tokens") {
+				t[k] = redactedValue
+				changed = true
+				continue
+			}
+			if redactValue(child) {
+				changed = true
+			}
+		}
+	case []interface{}:
+		for _, child := range t {
+			if redactValue(child) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// capBody truncates body to max bytes, reporting whether it was cut.
+func capBody(body []byte, max int) ([]byte, bool) {
+	if len(body) <= max {
+		return body, false
+	}
+	return body[:max], true
+}
+
+// shouldCapture reports whether bodies of an attempt against p are captured.
+func shouldCapture(requested bool, p *Provider) bool {
+	return requested || p.CaptureBodies
+}
+
+// upstreamRequestBody returns the body actually sent to the provider,
+// falling back to the client body when the request is unavailable.
+func upstreamRequestBody(resp *http.Response, clientBody []byte) []byte {
+	if resp != nil && resp.Request != nil && resp.Request.GetBody != nil {
+		if rc, err := resp.Request.GetBody(); err == nil {
+			defer rc.Close()
+			if b, err := io.ReadAll(rc); err == nil {
+				return b
+			}
+		}
+	}
+	return clientBody
+}
+
+// newBodyCapture builds a sanitized, size-capped capture record.
+func newBodyCapture(requestID, provider, sessionID string, statusCode int, reqBody, respBody []byte) BodyCapture {
+	max := config.GetBodyCapture().GetMaxBytes()
+	reqBody, reqCut := capBody(sanitizeBody(reqBody), max)
+	respBody, respCut := capBody(sanitizeBody(respBody), max)
+	return BodyCapture{
+		RequestID:         requestID,
+		Timestamp:         time.Now(),
+		Provider:          provider,
+		SessionID:         sessionID,
+		StatusCode:        statusCode,
+		RequestBody:       string(reqBody),
+		ResponseBody:      string(respBody),
+		RequestTruncated:  reqCut,
+		ResponseTruncated: respCut,
+	}
+}
+
+// captureExchange stores the bodies of an attempt whose response body has
+// already been read (error responses).
+func (s *ProxyServer) captureExchange(requestID, provider, sessionID string, resp *http.Response, clientBody, respBody []byte) {
+	db := GetGlobalLogDB()
+	if db == nil {
+		return
+	}
+	c := newBodyCapture(requestID, provider, sessionID, resp.StatusCode, upstreamRequestBody(resp, clientBody), respBody)
+	if err := db.InsertBodyCapture(c); err != nil {
+		s.Logger.Printf("[capture] failed to store bodies for %s: %v", requestID, err)
+	}
+}
+
+// captureResponse arranges for a successful response's body to be captured
+// as it is streamed to the client. Only the first max bytes are buffered.
+func (s *ProxyServer) captureResponse(requestID, provider, sessionID string, resp *http.Response, clientBody []byte) {
+	db := GetGlobalLogDB()
+	if db == nil {
+		return
+	}
+	reqBody := upstreamRequestBody(resp, clientBody)
+	statusCode := resp.StatusCode
+	resp.Body = &captureReader{
+		r:   resp.Body,
+		max: config.GetBodyCapture().GetMaxBytes(),
+		save: func(respBody []byte, truncated bool) {
+			c := newBodyCapture(requestID, provider, sessionID, statusCode, reqBody, respBody)
+			c.ResponseTruncated = c.ResponseTruncated || truncated
+			if err := db.InsertBodyCapture(c); err != nil {
+				s.Logger.Printf("[capture] failed to store bodies for %s: %v", requestID, err)
+			}
+		},
+	}
+}
+
+// captureReader tees up to max bytes of a response body and hands them to
+// save once, when the body hits EOF or is closed.
+type captureReader struct {
+	r         io.ReadCloser
+	max       int
+	buf       bytes.Buffer
+	truncated bool
+	once      sync.Once
+	save      func(body []byte, truncated bool)
+}
+
+func (c *captureReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if n > 0 {
+		if room := c.max - c.buf.Len(); room >= n {
+			c.buf.Write(p[:n])
+		} else {
+			if room > 0 {
+				c.buf.Write(p[:room])
+			}
+			c.truncated = true
+		}
+	}
+	if err == io.EOF {
+		c.flush()
+	}
+	return n, err
+}
+
+func (c *captureReader) Close() error {
+	c.flush()
+	return c.r.Close()
+}
+
+func (c *captureReader) flush() {
+	c.once.Do(func() { c.save(c.buf.Bytes(), c.truncated) })
+}
+
+// InsertBodyCapture stores a captured exchange and prunes old captures.
+func (ldb *LogDB) InsertBodyCapture(c BodyCapture) error {
+	if _, err := ldb.db.Exec(`
+		INSERT INTO request_bodies (request_id, timestamp, provider, session_id, status_code, request_body, response_body, request_truncated, response_truncated)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		c.RequestID,
+		c.Timestamp.UTC().Format(time.RFC3339Nano),
+		c.Provider,
+		c.SessionID,
+		c.StatusCode,
+		c.RequestBody,
+		c.ResponseBody,
+		c.RequestTruncated,
+		c.ResponseTruncated,
+	); err != nil {
+		return fmt.Errorf("insert body capture: %w", err)
+	}
+	_, err := ldb.db.Exec(`DELETE FROM request_bodies WHERE id <= (SELECT MAX(id) FROM request_bodies) - ?`, maxBodyCaptures)
+	return err
+}
+
+// GetBodyCaptures returns the captured attempts for a request ID, oldest first.
+// It returns an empty slice when nothing was captured.
+func (ldb *LogDB) GetBodyCaptures(requestID string) ([]BodyCapture, error) {
+	rows, err := ldb.db.Query(`
+		SELECT request_id, timestamp, provider, session_id, status_code, request_body, response_body, request_truncated, response_truncated
+		FROM request_bodies WHERE request_id = ? ORDER BY id ASC
+	`, requestID)
+	if err != nil {
+		return nil, fmt.Errorf("query body captures: %w", err)
+	}
+	defer rows.Close()
+
+	captures := []BodyCapture{}
+	for rows.Next() {
+		var c BodyCapture
+		var tsStr string
+		var reqCut, respCut sql.NullBool
+		if err := rows.Scan(&c.RequestID, &tsStr, &c.Provider, &c.SessionID, &c.StatusCode, &c.RequestBody, &c.ResponseBody, &reqCut, &respCut); err != nil {
+			continue
+		}
+		c.RequestTruncated = reqCut.Bool
+		c.ResponseTruncated = respCut.Bool
+		if t, err := time.Parse(time.RFC3339Nano, tsStr); err == nil {
+			c.Timestamp = t
+		}
+		captures = append(captures, c)
+	}
+	return captures, rows.Err()
+}
+
+// LatestSessionCapture returns the most recently captured attempt of a
+// session, matching either the full "<profile>:<session>" key or the bare
+// session ID. It returns nil when nothing was captured.
+func (ldb *LogDB) LatestSessionCapture(sessionID string) (*BodyCapture, error) {
+	var c BodyCapture
+	var tsStr string
+	var reqCut, respCut sql.NullBool
+	err := ldb.db.QueryRow(`
+		SELECT request_id, timestamp, provider, session_id, status_code, request_body, response_body, request_truncated, response_truncated
+		FROM request_bodies WHERE session_id = ? OR session_id LIKE ? ORDER BY id DESC LIMIT 1
+	`, sessionID, "%:"+sessionID).Scan(&c.RequestID, &tsStr, &c.Provider, &c.SessionID, &c.StatusCode, &c.RequestBody, &c.ResponseBody, &reqCut, &respCut)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query body capture: %w", err)
+	}
+	c.RequestTruncated = reqCut.Bool
+	c.ResponseTruncated = respCut.Bool
+	if t, err := time.Parse(time.RFC3339Nano, tsStr); err == nil {
+		c.Timestamp = t
+	}
+	return &c, nil
+}
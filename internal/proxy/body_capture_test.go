@@ -0,0 +1,152 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+func TestSanitizeBody(t *testing.T) {
+	in := []byte(`{"model":"m","api_key":"sk-secret","metadata":{"auth_token":"abc","user_id":"u1"},"usage":{"input_tokens":5}}`)
+	out := string(sanitizeBody(in))
+	if strings.Contains(out, "sk-secret") || strings.Contains(out, `"abc"`) {
+		t.Errorf("credentials not redacted: %s", out)
+	}
+	if !strings.Contains(out, `"user_id":"u1"`) || !strings.Contains(out, `"input_tokens":5`) {
+		t.Errorf("unrelated fields changed: %s", out)
+	}
+
+	sse := []byte("data: {\"type\":\"ping\"}\n\n")
+	if got := sanitizeBody(sse); string(got) != string(sse) {
+		t.Errorf("non-JSON body changed: %q", got)
+	}
+}
+
+func TestCaptureReader(t *testing.T) {
+	var saved string
+	var truncated bool
+	r := &captureReader{
+		r:   io.NopCloser(strings.NewReader("0123456789")),
+		max: 4,
+		save: func(body []byte, cut bool) {
+			saved, truncated = string(body), cut
+		},
+	}
+	all, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll() error: %v", err)
+	}
+	r.Close()
+	if string(all) != "0123456789" {
+		t.Errorf("passthrough = %q, want full body", all)
+	}
+	if saved != "0123" || !truncated {
+		t.Errorf("saved = %q truncated=%v, want \"0123\" true", saved, truncated)
+	}
+}
+
+func TestLogDBBodyCaptures(t *testing.T) {
+	db, err := OpenLogDB(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenLogDB() error: %v", err)
+	}
+	defer db.Close()
+
+	for i := 0; i < 2; i++ {
+		c := newBodyCapture("req_1", fmt.Sprintf("p%d", i), "s1", 200+i, []byte(`{"a":1}`), []byte(`{"b":2}`))
+		if err := db.InsertBodyCapture(c); err != nil {
+			t.Fatalf("InsertBodyCapture() error: %v", err)
+		}
+	}
+	captures, err := db.GetBodyCaptures("req_1")
+	if err != nil {
+		t.Fatalf("GetBodyCaptures() error: %v", err)
+	}
+	if len(captures) != 2 || captures[0].Provider != "p0" || captures[1].StatusCode != 201 {
+		t.Errorf("captures = %+v, want two attempts in order", captures)
+	}
+	if captures, _ := db.GetBodyCaptures("missing"); len(captures) != 0 {
+		t.Errorf("missing request returned %d captures", len(captures))
+	}
+
+	// The latest capture of a session is found by full key or bare ID
+	db.InsertBodyCapture(newBodyCapture("req_2", "p", "work:s2", 200, []byte(`{"n":1}`), nil))
+	db.InsertBodyCapture(newBodyCapture("req_3", "p", "work:s2", 200, []byte(`{"n":2}`), nil))
+	for _, id := range []string{"work:s2", "s2"} {
+		if c, err := db.LatestSessionCapture(id); err != nil || c == nil || c.RequestID != "req_3" {
+			t.Errorf("LatestSessionCapture(%q) = %+v, %v; want req_3", id, c, err)
+		}
+	}
+	if c, err := db.LatestSessionCapture("none"); c != nil || err != nil {
+		t.Errorf("LatestSessionCapture(none) = %+v, %v", c, err)
+	}
+
+	// Old captures are pruned past the limit
+	for i := 0; i < maxBodyCaptures+5; i++ {
+		db.InsertBodyCapture(newBodyCapture(fmt.Sprintf("bulk_%d", i), "p", "", 200, nil, nil))
+	}
+	if captures, _ := db.GetBodyCaptures("req_1"); len(captures) != 0 {
+		t.Errorf("expected req_1 to be pruned, got %d captures", len(captures))
+	}
+}
+
+func TestProxyCapturesBodies(t *testing.T) {
+	if err := InitGlobalLogger(t.TempDir()); err != nil {
+		t.Fatalf("InitGlobalLogger() error: %v", err)
+	}
+	db := GetGlobalLogDB()
+
+	var forwardedCapture string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwardedCapture = r.Header.Get(captureHeader)
+		io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"msg_1","content":[{"type":"text","text":"hi"}],"usage":{"input_tokens":3,"output_tokens":1}}`))
+	}))
+	defer backend.Close()
+
+	u, _ := url.Parse(backend.URL)
+	run := func(name string, captureBodies bool, header string) []BodyCapture {
+		providers := []*Provider{{Name: name, BaseURL: u, Token: "t", Healthy: true, CaptureBodies: captureBodies}}
+		srv := NewProxyServer(providers, discardLogger(), config.LoadBalanceFailover, nil)
+		req := httptest.NewRequest("POST", "/v1/messages", strings.NewReader(`{"model":"claude-sonnet-4-6","messages":[]}`))
+		if header != "" {
+			req.Header.Set(captureHeader, header)
+		}
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		if w.Code != 200 {
+			t.Fatalf("status = %d, body: %s", w.Code, w.Body.String())
+		}
+
+		var requestID string
+		db.db.QueryRow("SELECT request_id FROM request_bodies WHERE provider = ?", name).Scan(&requestID)
+		captures, _ := db.GetBodyCaptures(requestID)
+		return captures
+	}
+
+	if captures := run("capture-off", false, ""); len(captures) != 0 {
+		t.Errorf("capture disabled but got %d captures", len(captures))
+	}
+
+	captures := run("capture-provider", true, "")
+	if len(captures) != 1 {
+		t.Fatalf("got %d captures, want 1", len(captures))
+	}
+	if !strings.Contains(captures[0].RequestBody, "claude-sonnet-4-6") || !strings.Contains(captures[0].ResponseBody, "msg_1") {
+		t.Errorf("capture = %+v, want request and response bodies", captures[0])
+	}
+
+	if captures := run("capture-header", false, "1"); len(captures) != 1 {
+		t.Errorf("header opt-in got %d captures, want 1", len(captures))
+	}
+	if forwardedCapture != "" {
+		t.Errorf("%s header leaked upstream: %q", captureHeader, forwardedCapture)
+	}
+}
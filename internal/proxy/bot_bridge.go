@@ -1,11 +1,11 @@
 package proxy
 
 import (
-	"path/filepath"
 	"sync"
 	"time"
 
 	"github.com/dopejs/gozen/internal/bot"
+	"github.com/dopejs/gozen/internal/config"
 )
 
 // BotBridge connects proxy sessions to the bot gateway.
@@ -40,7 +40,7 @@ func InitBotBridge(gatewayPath string) error {
 	var initErr error
 	globalBridgeOnce.Do(func() {
 		if gatewayPath == "" {
-			gatewayPath = filepath.Join("/tmp", "zen-gateway.sock")
+			gatewayPath = config.BotSocketPath()
 		}
 		globalBridge = &BotBridge{
 			sessions: make(map[string]*bridgeSession),
@@ -1,13 +1,20 @@
 package proxy
 
 import (
+	"fmt"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/dopejs/gozen/internal/config"
 )
 
-// BudgetStatus represents the current budget status.
+// BudgetStatus represents the current budget status. Amounts are in the
+// display currency, which budget limits are also set in.
 type BudgetStatus struct {
+	Currency string `json:"currency"`
+
 	DailySpent     float64 `json:"daily_spent"`
 	DailyLimit     float64 `json:"daily_limit,omitempty"`
 	DailyRemaining float64 `json:"daily_remaining,omitempty"`
@@ -28,6 +35,42 @@ type BudgetStatus struct {
 	ShouldBlock     bool                `json:"should_block"`
 	ActiveAction    config.BudgetAction `json:"active_action,omitempty"`
 	Message         string              `json:"message,omitempty"`
+
+	// Scoped reports provider, model and user limits. They only apply to
+	// requests for that provider, model or user, so they do not set the flags
+	// above.
+	Scoped []ScopedBudgetStatus `json:"scoped,omitempty"`
+
+	// Downgrades lists the model substitutions made by downgrade limits.
+	Downgrades []*config.BudgetDowngrade `json:"downgrades,omitempty"`
+	// DowngradeModel is the model a request for the model passed to
+	// CheckModel would be sent as once a downgrade limit is reached.
+	DowngradeModel string `json:"downgrade_model,omitempty"`
+}
+
+// defaultDowngradeModel is the model used when no downgrade rules are
+// configured.
+const defaultDowngradeModel = "claude-3-5-haiku-20241022"
+
+// defaultDowngrades is used when BudgetConfig.Downgrade is empty.
+var defaultDowngrades = []*config.BudgetDowngrade{{Match: "*", Target: defaultDowngradeModel}}
+
+// ScopedBudgetStatus reports one period of a provider, model or user limit.
+type ScopedBudgetStatus struct {
+	Scope     string              `json:"scope"` // "provider", "model" or "user"
+	Name      string              `json:"name"`
+	Period    string              `json:"period"`
+	Spent     float64             `json:"spent"`
+	Limit     float64             `json:"limit"`
+	Remaining float64             `json:"remaining"`
+	Percent   float64             `json:"percent"`
+	Action    config.BudgetAction `json:"action"`
+	Exceeded  bool                `json:"exceeded"`
+}
+
+// message describes an exceeded scoped limit.
+func (s ScopedBudgetStatus) message() string {
+	return fmt.Sprintf("%s budget for %s %q exceeded", s.Period, s.Scope, s.Name)
 }
 
 // BudgetChecker checks spending against configured budget limits.
@@ -52,14 +95,46 @@ func (c *BudgetChecker) ReloadConfig() {
 	c.config = config.GetBudgets()
 }
 
-// Check returns the current budget status for a project.
+// Check returns the current budget status for a project, including the
+// status of every provider, model and user limit.
 // If projectPath is empty and PerProject is false, checks global budget.
 func (c *BudgetChecker) Check(projectPath string) (*BudgetStatus, error) {
 	c.mu.RLock()
 	cfg := c.config
 	c.mu.RUnlock()
 
-	status := &BudgetStatus{}
+	status, err := c.checkGlobal(cfg, projectPath)
+	if err != nil {
+		return nil, err
+	}
+	status.Downgrades = downgradeRules(cfg)
+	if cfg == nil || c.tracker == nil {
+		return status, nil
+	}
+	status.Scoped, err = c.checkScoped(cfg, "", "", "", true)
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// CheckModel returns Check's status along with the model a request for
+// model would be downgraded to on provider (empty = any provider).
+func (c *BudgetChecker) CheckModel(projectPath, provider, model string) (*BudgetStatus, error) {
+	status, err := c.Check(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	if model != "" {
+		status.DowngradeModel = c.DowngradeModel(provider, model)
+	}
+	return status, nil
+}
+
+// checkGlobal returns the status of the daily, weekly and monthly limits.
+func (c *BudgetChecker) checkGlobal(cfg *config.BudgetConfig, projectPath string) (*BudgetStatus, error) {
+	cur := config.GetCurrency()
+	status := &BudgetStatus{Currency: cur.GetCode()}
 
 	if c.tracker == nil {
 		return status, nil
@@ -87,6 +162,9 @@ func (c *BudgetChecker) Check(projectPath string) (*BudgetStatus, error) {
 	if err != nil {
 		return nil, err
 	}
+	status.DailySpent = cur.FromUSD(status.DailySpent)
+	status.WeeklySpent = cur.FromUSD(status.WeeklySpent)
+	status.MonthlySpent = cur.FromUSD(status.MonthlySpent)
 
 	if cfg == nil {
 		return status, nil
@@ -131,6 +209,144 @@ func (c *BudgetChecker) Check(projectPath string) (*BudgetStatus, error) {
 	return status, nil
 }
 
+// CheckScope returns the status of the limits that apply to requests for a
+// provider and model sent by user. An empty provider, model or user skips
+// those limits.
+func (c *BudgetChecker) CheckScope(provider, model, user string) ([]ScopedBudgetStatus, error) {
+	c.mu.RLock()
+	cfg := c.config
+	c.mu.RUnlock()
+	if cfg == nil || c.tracker == nil {
+		return nil, nil
+	}
+	return c.checkScoped(cfg, provider, model, user, false)
+}
+
+// checkScoped evaluates provider, model and user limits, all of them when
+// all is set, or otherwise those matching provider, model and user.
+func (c *BudgetChecker) checkScoped(cfg *config.BudgetConfig, provider, model, user string, all bool) ([]ScopedBudgetStatus, error) {
+	var result []ScopedBudgetStatus
+	for _, name := range sortedBudgetKeys(cfg.Providers) {
+		if !all && name != provider {
+			continue
+		}
+		statuses, err := c.checkLimits("provider", name, cfg.Providers[name], costFilter{provider: name})
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, statuses...)
+	}
+	lowerModel := strings.ToLower(model)
+	for _, key := range sortedBudgetKeys(cfg.Models) {
+		if !all && (model == "" || !strings.Contains(lowerModel, strings.ToLower(key))) {
+			continue
+		}
+		statuses, err := c.checkLimits("model", key, cfg.Models[key], costFilter{model: key})
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, statuses...)
+	}
+	for _, name := range sortedBudgetKeys(cfg.Users) {
+		if !all && name != user {
+			continue
+		}
+		statuses, err := c.checkLimits("user", name, cfg.Users[name], costFilter{user: name})
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, statuses...)
+	}
+	return result, nil
+}
+
+// checkLimits evaluates the periods of one provider, model or user limit.
+func (c *BudgetChecker) checkLimits(scope, name string, limits *config.BudgetLimits, f costFilter) ([]ScopedBudgetStatus, error) {
+	if limits == nil {
+		return nil, nil
+	}
+	periods := []struct {
+		name  string
+		limit *config.BudgetLimit
+		since func() time.Time
+	}{
+		{"daily", limits.Daily, startOfDay},
+		{"weekly", limits.Weekly, startOfWeek},
+		{"monthly", limits.Monthly, startOfMonth},
+	}
+	cur := config.GetCurrency()
+	var result []ScopedBudgetStatus
+	for _, p := range periods {
+		if p.limit == nil || p.limit.Amount <= 0 {
+			continue
+		}
+		spentUSD, err := c.tracker.getCostSince(p.since(), f)
+		if err != nil {
+			return nil, err
+		}
+		spent := cur.FromUSD(spentUSD)
+		st := ScopedBudgetStatus{
+			Scope:     scope,
+			Name:      name,
+			Period:    p.name,
+			Spent:     spent,
+			Limit:     p.limit.Amount,
+			Remaining: p.limit.Amount - spent,
+			Percent:   (spent / p.limit.Amount) * 100,
+			Action:    p.limit.Action,
+			Exceeded:  spent >= p.limit.Amount,
+		}
+		if st.Action == "" {
+			st.Action = config.BudgetActionWarn
+		}
+		if st.Remaining < 0 {
+			st.Remaining = 0
+		}
+		result = append(result, st)
+	}
+	return result, nil
+}
+
+// AdmissionBlocked reports whether a new request for model from user should
+// be rejected, either by a global block limit or by one for the model or
+// user, and why.
+func (c *BudgetChecker) AdmissionBlocked(projectPath, model, user string) (string, bool) {
+	c.mu.RLock()
+	cfg := c.config
+	c.mu.RUnlock()
+	if status, err := c.checkGlobal(cfg, projectPath); err == nil && status.ShouldBlock {
+		return status.Message, true
+	}
+	if model == "" && user == "" {
+		return "", false
+	}
+	return c.ScopeBlocked("", model, user)
+}
+
+// ScopeBlocked reports whether a block limit for provider, model or user has
+// been reached, and which.
+func (c *BudgetChecker) ScopeBlocked(provider, model, user string) (string, bool) {
+	statuses, err := c.CheckScope(provider, model, user)
+	if err != nil {
+		return "", false
+	}
+	for _, st := range statuses {
+		if st.Exceeded && st.Action == config.BudgetActionBlock {
+			return st.message() + ", requests blocked", true
+		}
+	}
+	return "", false
+}
+
+func sortedBudgetKeys(m map[string]*config.BudgetLimits) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // checkLimit updates status based on a single limit check.
 func (c *BudgetChecker) checkLimit(status *BudgetStatus, spent float64, limit *config.BudgetLimit, period string) {
 	if limit == nil || limit.Amount <= 0 {
@@ -174,9 +390,82 @@ func (c *BudgetChecker) checkLimit(status *BudgetStatus, spent float64, limit *c
 	}
 }
 
+// BudgetForecast projects the spending under a global limit to the end of
+// its period at the rate spent so far. Amounts are in the display currency.
+type BudgetForecast struct {
+	Period    string     `json:"period"`
+	Spent     float64    `json:"spent"`
+	Limit     float64    `json:"limit"`
+	Projected float64    `json:"projected"`
+	PeriodEnd time.Time  `json:"period_end"`
+	BreachAt  *time.Time `json:"breach_at,omitempty"` // when the limit is reached at this rate; nil if it holds
+}
+
+// minForecastElapsed is the share of a period that must have passed before
+// its spending rate is projected.
+const minForecastElapsed = 0.1
+
+// Forecast projects the daily, weekly and monthly limits at now. Limits
+// already reached are left out, as are periods less than a tenth over, whose
+// rate says little yet.
+func (c *BudgetChecker) Forecast(projectPath string, now time.Time) ([]BudgetForecast, error) {
+	c.mu.RLock()
+	cfg := c.config
+	c.mu.RUnlock()
+	if cfg == nil {
+		return nil, nil
+	}
+	status, err := c.checkGlobal(cfg, projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	now = now.UTC()
+	day := now.Truncate(24 * time.Hour)
+	week := day.AddDate(0, 0, -int(now.Weekday()))
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	periods := []struct {
+		name       string
+		limit      *config.BudgetLimit
+		spent      float64
+		start, end time.Time
+	}{
+		{"daily", cfg.Daily, status.DailySpent, day, day.AddDate(0, 0, 1)},
+		{"weekly", cfg.Weekly, status.WeeklySpent, week, week.AddDate(0, 0, 7)},
+		{"monthly", cfg.Monthly, status.MonthlySpent, month, month.AddDate(0, 1, 0)},
+	}
+
+	var result []BudgetForecast
+	for _, p := range periods {
+		if p.limit == nil || p.limit.Amount <= 0 || p.spent >= p.limit.Amount {
+			continue
+		}
+		elapsed := now.Sub(p.start)
+		if float64(elapsed) < minForecastElapsed*float64(p.end.Sub(p.start)) {
+			continue
+		}
+		f := BudgetForecast{
+			Period:    p.name,
+			Spent:     p.spent,
+			Limit:     p.limit.Amount,
+			Projected: p.spent * float64(p.end.Sub(p.start)) / float64(elapsed),
+			PeriodEnd: p.end,
+		}
+		if f.Projected >= f.Limit {
+			breach := p.start.Add(time.Duration(float64(elapsed) * f.Limit / f.Spent))
+			f.BreachAt = &breach
+		}
+		result = append(result, f)
+	}
+	return result, nil
+}
+
 // ShouldBlock returns true if requests should be blocked due to budget.
 func (c *BudgetChecker) ShouldBlock(projectPath string) bool {
-	status, err := c.Check(projectPath)
+	c.mu.RLock()
+	cfg := c.config
+	c.mu.RUnlock()
+	status, err := c.checkGlobal(cfg, projectPath)
 	if err != nil {
 		return false
 	}
@@ -185,17 +474,111 @@ func (c *BudgetChecker) ShouldBlock(projectPath string) bool {
 
 // ShouldDowngrade returns true if model should be downgraded due to budget.
 func (c *BudgetChecker) ShouldDowngrade(projectPath string) bool {
-	status, err := c.Check(projectPath)
+	c.mu.RLock()
+	cfg := c.config
+	c.mu.RUnlock()
+	status, err := c.checkGlobal(cfg, projectPath)
 	if err != nil {
 		return false
 	}
 	return status.ShouldDowngrade
 }
 
+// StreamingBudget returns the smallest budget remaining under a block limit
+// that applies to a request for provider and model from user, when streaming
+// enforcement is enabled, so an in-flight stream can be cut off before it
+// overspends. remaining is in USD, like request costs. ok is false when
+// streams are not limited.
+func (c *BudgetChecker) StreamingBudget(projectPath, provider, model, user string) (remaining float64, ok bool) {
+	c.mu.RLock()
+	cfg := c.config
+	c.mu.RUnlock()
+	if cfg == nil || cfg.Enforcement != config.BudgetEnforcementStreaming {
+		return 0, false
+	}
+
+	status, err := c.checkGlobal(cfg, projectPath)
+	if err != nil {
+		return 0, false
+	}
+	scoped, err := c.CheckScope(provider, model, user)
+	if err != nil {
+		return 0, false
+	}
+	consider := func(limit *config.BudgetLimit, left float64) {
+		if limit == nil || limit.Amount <= 0 || limit.Action != config.BudgetActionBlock {
+			return
+		}
+		if !ok || left < remaining {
+			remaining, ok = left, true
+		}
+	}
+	consider(cfg.Daily, status.DailyRemaining)
+	consider(cfg.Weekly, status.WeeklyRemaining)
+	consider(cfg.Monthly, status.MonthlyRemaining)
+	for _, st := range scoped {
+		consider(&config.BudgetLimit{Amount: st.Limit, Action: st.Action}, st.Remaining)
+	}
+	return config.GetCurrency().ToUSD(remaining), ok
+}
+
 // GetDowngradeModel returns a cheaper model to use when budget is exceeded.
 func (c *BudgetChecker) GetDowngradeModel(currentModel string) string {
-	// Downgrade to haiku as the cheapest option
-	return "claude-3-5-haiku-20241022"
+	return c.DowngradeModel("", currentModel)
+}
+
+// DowngradeModel returns the model to send a request for model to provider
+// as once a downgrade limit is reached. Models no rule matches are kept.
+func (c *BudgetChecker) DowngradeModel(provider, model string) string {
+	c.mu.RLock()
+	cfg := c.config
+	c.mu.RUnlock()
+	for _, d := range downgradeRules(cfg) {
+		if d == nil || (d.Provider != "" && d.Provider != provider) {
+			continue
+		}
+		if matchModelPattern(d.Match, model) {
+			return d.Target
+		}
+	}
+	return model
+}
+
+// DowngradeFor reports whether a request for model on provider from user
+// should be downgraded, because a global limit or one for the provider, model
+// or user has been reached with the downgrade action, and to which model.
+func (c *BudgetChecker) DowngradeFor(projectPath, provider, model, user string) (string, bool) {
+	c.mu.RLock()
+	cfg := c.config
+	c.mu.RUnlock()
+	if cfg == nil {
+		return "", false
+	}
+
+	downgrade := false
+	if status, err := c.checkGlobal(cfg, projectPath); err == nil && status.ShouldDowngrade {
+		downgrade = true
+	} else if scoped, err := c.CheckScope(provider, model, user); err == nil {
+		for _, st := range scoped {
+			if st.Exceeded && st.Action == config.BudgetActionDowngrade {
+				downgrade = true
+				break
+			}
+		}
+	}
+	if !downgrade {
+		return "", false
+	}
+	target := c.DowngradeModel(provider, model)
+	return target, target != model
+}
+
+// downgradeRules returns the configured downgrade rules, or the default.
+func downgradeRules(cfg *config.BudgetConfig) []*config.BudgetDowngrade {
+	if cfg == nil || len(cfg.Downgrade) == 0 {
+		return defaultDowngrades
+	}
+	return cfg.Downgrade
 }
 
 func formatPercent(p float64) string {
@@ -0,0 +1,175 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dopejs/gozen/internal/proxy/transform"
+)
+
+// budgetExceededType is the error type reported when a budget rejects a
+// request or cuts off a stream.
+const budgetExceededType = "budget_exceeded"
+
+// writeBudgetExceededError writes a 429 JSON error for a request rejected
+// because a block limit has been reached.
+func writeBudgetExceededError(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"type": "error",
+		"error": map[string]interface{}{
+			"type":    budgetExceededType,
+			"message": message,
+		},
+	})
+}
+
+// budgetStreamGuard wraps a streaming response in the client's format and
+// estimates the cost accrued as events arrive. Once the estimate exceeds the
+// remaining budget it closes the upstream connection and ends the stream with
+// a budget_exceeded error event.
+type budgetStreamGuard struct {
+	r         io.Reader
+	upstream  io.Closer
+	format    string  // client request format, for the error event
+	remaining float64 // budget left when the stream started
+	cost      func(u *sseUsageExtractor) float64
+	usage     sseUsageExtractor // token counts seen so far
+	cut       bool
+	accrued   float64 // estimated cost when cut off
+	tail      []byte  // error event still to be delivered
+}
+
+// newBudgetStreamGuard returns a guard for a streaming response from
+// provider, or nil when streams are not limited by the budget.
+func newBudgetStreamGuard(resp *http.Response, provider string, body []byte, sessionID, user, requestFormat string, start time.Time) *budgetStreamGuard {
+	if !strings.Contains(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return nil
+	}
+	checker := GetGlobalBudgetChecker()
+	tracker := GetGlobalUsageTracker()
+	if checker == nil || tracker == nil {
+		return nil
+	}
+	var req struct {
+		Model string `json:"model"`
+	}
+	json.Unmarshal(body, &req)
+	remaining, ok := checker.StreamingBudget(GetSessionProject(sessionID), provider, req.Model, user)
+	if !ok {
+		return nil
+	}
+	return &budgetStreamGuard{
+		upstream:  resp.Body,
+		format:    requestFormat,
+		remaining: remaining,
+		cost: func(u *sseUsageExtractor) float64 {
+			// Output usage is usually only reported at the end of a stream,
+			// so estimate it from the text generated so far
+			output := u.outputTok
+			if est := int(float64(u.outputChars) * tokensPerChar); est > output {
+				output = est
+			}
+			return tracker.CalculateRequestCost(provider, CostInput{
+				Model:               req.Model,
+				InputTokens:         u.inputTok,
+				OutputTokens:        output,
+				CacheCreationTokens: u.cacheWrite,
+				CacheReadTokens:     u.cacheRead,
+				Duration:            time.Since(start),
+			})
+		},
+	}
+}
+
+func (g *budgetStreamGuard) Read(p []byte) (int, error) {
+	if g.cut {
+		if len(g.tail) == 0 {
+			return 0, io.EOF
+		}
+		n := copy(p, g.tail)
+		g.tail = g.tail[n:]
+		return n, nil
+	}
+
+	n, err := g.r.Read(p)
+	if n > 0 {
+		g.usage.processChunk(p[:n])
+		if cost := g.cost(&g.usage); cost > g.remaining {
+			g.cut = true
+			g.accrued = cost
+			g.upstream.Close()
+			g.tail = budgetErrorEvent(g.format, "budget exceeded, response cut off")
+			// Hold back any incomplete line so the error event starts cleanly
+			return bytes.LastIndexByte(p[:n], '\n') + 1, nil
+		}
+	}
+	return n, err
+}
+
+// budgetErrorEvent returns an SSE error event in the client's format.
+func budgetErrorEvent(format, message string) []byte {
+	var event string
+	var data map[string]interface{}
+	switch {
+	case format == transform.FormatOpenAIResponses:
+		event = "error"
+		data = map[string]interface{}{"type": "error", "code": budgetExceededType, "message": message}
+	case transform.NormalizeFormat(format) == "openai":
+		data = map[string]interface{}{"error": map[string]interface{}{"type": budgetExceededType, "code": budgetExceededType, "message": message}}
+	default:
+		event = "error"
+		data = map[string]interface{}{"type": "error", "error": map[string]interface{}{"type": budgetExceededType, "message": message}}
+	}
+
+	payload, _ := json.Marshal(data)
+	var buf bytes.Buffer
+	buf.WriteString("\n")
+	if event != "" {
+		buf.WriteString("event: " + event + "\n")
+	}
+	buf.WriteString("data: ")
+	buf.Write(payload)
+	buf.WriteString("\n\n")
+	return buf.Bytes()
+}
+
+// deltaChars returns the length of generated text carried by an SSE event.
+func deltaChars(evType string, ev map[string]interface{}) int {
+	switch evType {
+	case "content_block_delta":
+		delta, _ := ev["delta"].(map[string]interface{})
+		n := 0
+		for _, key := range []string{"text", "thinking", "partial_json"} {
+			s, _ := delta[key].(string)
+			n += len(s)
+		}
+		return n
+	case "response.output_text.delta", "response.function_call_arguments.delta":
+		s, _ := ev["delta"].(string)
+		return len(s)
+	case "":
+		n := 0
+		choices, _ := ev["choices"].([]interface{})
+		for _, c := range choices {
+			choice, _ := c.(map[string]interface{})
+			delta, _ := choice["delta"].(map[string]interface{})
+			content, _ := delta["content"].(string)
+			n += len(content)
+			calls, _ := delta["tool_calls"].([]interface{})
+			for _, tc := range calls {
+				call, _ := tc.(map[string]interface{})
+				fn, _ := call["function"].(map[string]interface{})
+				args, _ := fn["arguments"].(string)
+				n += len(args)
+			}
+		}
+		return n
+	}
+	return 0
+}
@@ -1,11 +1,19 @@
 package proxy
 
 import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
 
 	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/proxy/transform"
 )
 
 func TestNewBudgetChecker(t *testing.T) {
@@ -306,3 +314,363 @@ func TestBudgetChecker_Check_AllLimits(t *testing.T) {
 		t.Errorf("Expected monthly limit 200.0, got %f", status.MonthlyLimit)
 	}
 }
+
+func TestBudgetChecker_StreamingBudget(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	config.ResetDefaultStore()
+	defer config.ResetDefaultStore()
+
+	db, err := OpenLogDB(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenLogDB() error: %v", err)
+	}
+	defer db.Close()
+	tracker := NewUsageTracker(db)
+	tracker.Record(UsageEntry{Timestamp: time.Now(), Provider: "p", Model: "m", CostUSD: 3})
+
+	budgets := &config.BudgetConfig{
+		Daily:   &config.BudgetLimit{Amount: 10, Action: config.BudgetActionBlock},
+		Weekly:  &config.BudgetLimit{Amount: 5, Action: config.BudgetActionWarn},
+		Monthly: &config.BudgetLimit{Amount: 20, Action: config.BudgetActionBlock},
+	}
+	config.SetBudgets(budgets)
+	checker := NewBudgetChecker(tracker)
+	if _, ok := checker.StreamingBudget("", "", "", ""); ok {
+		t.Error("expected streams to be unlimited with admission enforcement")
+	}
+
+	budgets.Enforcement = config.BudgetEnforcementStreaming
+	config.SetBudgets(budgets)
+	checker.ReloadConfig()
+	remaining, ok := checker.StreamingBudget("", "", "", "")
+	if !ok || remaining != 7 {
+		t.Errorf("StreamingBudget() = %v, %v; want 7, true", remaining, ok)
+	}
+}
+
+func TestBudgetChecker_Forecast(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	config.ResetDefaultStore()
+	defer config.ResetDefaultStore()
+
+	db, err := OpenLogDB(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenLogDB() error: %v", err)
+	}
+	defer db.Close()
+	tracker := NewUsageTracker(db)
+	tracker.Record(UsageEntry{Timestamp: time.Now(), Provider: "p", Model: "m", CostUSD: 3})
+
+	config.SetBudgets(&config.BudgetConfig{
+		Daily:   &config.BudgetLimit{Amount: 5},
+		Weekly:  &config.BudgetLimit{Amount: 100},
+		Monthly: &config.BudgetLimit{Amount: 2},
+	})
+	checker := NewBudgetChecker(tracker)
+
+	// Halfway through the day, $3 spent projects to $6
+	day := time.Now().UTC().Truncate(24 * time.Hour)
+	forecasts, err := checker.Forecast("", day.Add(12*time.Hour))
+	if err != nil {
+		t.Fatalf("Forecast() error: %v", err)
+	}
+	byPeriod := make(map[string]BudgetForecast)
+	for _, f := range forecasts {
+		byPeriod[f.Period] = f
+	}
+	daily, ok := byPeriod["daily"]
+	if !ok || daily.Projected != 6 || daily.BreachAt == nil || !daily.BreachAt.Equal(day.Add(20*time.Hour)) {
+		t.Errorf("daily forecast = %+v, want $6 projected, breached at 20:00", daily)
+	}
+	if weekly, ok := byPeriod["weekly"]; ok && weekly.BreachAt != nil {
+		t.Errorf("weekly forecast = %+v, want no breach", weekly)
+	}
+	if _, ok := byPeriod["monthly"]; ok {
+		t.Error("monthly limit is already exceeded and should not be forecast")
+	}
+
+	// Too early in the day to project
+	forecasts, _ = checker.Forecast("", day.Add(time.Hour))
+	for _, f := range forecasts {
+		if f.Period == "daily" {
+			t.Errorf("daily forecast an hour into the day: %+v", f)
+		}
+	}
+}
+
+func TestProxyServer_BudgetBlocksAdmission(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	config.ResetDefaultStore()
+	defer config.ResetDefaultStore()
+
+	db, err := OpenLogDB(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenLogDB() error: %v", err)
+	}
+	defer db.Close()
+	tracker := NewUsageTracker(db)
+	tracker.Record(UsageEntry{Timestamp: time.Now(), Provider: "p", Model: "m", CostUSD: 12})
+	config.SetBudgets(&config.BudgetConfig{Daily: &config.BudgetLimit{Amount: 10, Action: config.BudgetActionBlock}})
+
+	oldGlobal := globalBudgetChecker
+	defer func() { globalBudgetChecker = oldGlobal }()
+	InitGlobalBudgetChecker(tracker)
+
+	var upstreamCalls int
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamCalls++
+	}))
+	defer upstream.Close()
+	u, _ := url.Parse(upstream.URL)
+	srv := NewProxyServer([]*Provider{{Name: "p", BaseURL: u, Token: "t", Healthy: true}}, discardLogger(), config.LoadBalanceFailover, nil)
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest("POST", "/v1/messages", strings.NewReader(`{"model":"m","messages":[]}`)))
+	if rec.Code != http.StatusTooManyRequests || !strings.Contains(rec.Body.String(), "budget_exceeded") {
+		t.Errorf("got %d %s, want 429 budget_exceeded", rec.Code, rec.Body.String())
+	}
+	if upstreamCalls != 0 {
+		t.Errorf("expected no upstream call, got %d", upstreamCalls)
+	}
+}
+
+type closeRecorder struct{ closed bool }
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestBudgetStreamGuard_CutsOffStream(t *testing.T) {
+	var stream strings.Builder
+	stream.WriteString("event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"usage\":{\"input_tokens\":100}}}\n\n")
+	for i := 0; i < 50; i++ {
+		stream.WriteString("event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"" + strings.Repeat("x", 40) + "\"}}\n\n")
+	}
+	stream.WriteString("event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n")
+
+	upstream := &closeRecorder{}
+	guard := &budgetStreamGuard{
+		r:         iotest.OneByteReader(strings.NewReader(stream.String())),
+		upstream:  upstream,
+		format:    config.ProviderTypeAnthropic,
+		remaining: 0.5,
+		cost: func(u *sseUsageExtractor) float64 {
+			return float64(u.inputTok)*0.001 + float64(u.outputChars)*0.001
+		},
+	}
+	out, err := io.ReadAll(guard)
+	if err != nil {
+		t.Fatalf("ReadAll() error: %v", err)
+	}
+	if !guard.cut || !upstream.closed {
+		t.Fatalf("expected the stream to be cut off and upstream closed")
+	}
+	got := string(out)
+	if strings.Contains(got, "message_stop") {
+		t.Error("expected events after the cutoff to be dropped")
+	}
+	if !strings.HasSuffix(got, "\nevent: error\ndata: {\"error\":{\"message\":\"budget exceeded, response cut off\",\"type\":\"budget_exceeded\"},\"type\":\"error\"}\n\n") {
+		t.Errorf("expected a budget_exceeded error event at the end, got %q", got[len(got)-200:])
+	}
+	if n := strings.Count(got, "content_block_delta\ndata"); n >= 50 {
+		t.Errorf("expected fewer than 50 deltas before cutoff, got %d", n)
+	}
+}
+
+func TestBudgetErrorEvent_OpenAI(t *testing.T) {
+	got := string(budgetErrorEvent(transform.FormatOpenAIChat, "over"))
+	if got != "\ndata: {\"error\":{\"code\":\"budget_exceeded\",\"message\":\"over\",\"type\":\"budget_exceeded\"}}\n\n" {
+		t.Errorf("chat event = %q", got)
+	}
+	got = string(budgetErrorEvent(transform.FormatOpenAIResponses, "over"))
+	if !strings.HasPrefix(got, "\nevent: error\ndata: {\"code\":\"budget_exceeded\"") {
+		t.Errorf("responses event = %q", got)
+	}
+}
+
+func TestBudgetChecker_ScopedLimits(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	config.ResetDefaultStore()
+	defer config.ResetDefaultStore()
+
+	db, err := OpenLogDB(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenLogDB() error: %v", err)
+	}
+	defer db.Close()
+	tracker := NewUsageTracker(db)
+	tracker.Record(UsageEntry{Timestamp: time.Now(), Provider: "anthropic", Model: "claude-opus-4-20250514", CostUSD: 25})
+	tracker.Record(UsageEntry{Timestamp: time.Now(), Provider: "openrouter", Model: "claude-sonnet-4", CostUSD: 2})
+
+	config.SetBudgets(&config.BudgetConfig{
+		Providers: map[string]*config.BudgetLimits{
+			"openrouter": {Daily: &config.BudgetLimit{Amount: 1, Action: config.BudgetActionBlock}},
+		},
+		Models: map[string]*config.BudgetLimits{
+			"Opus":   {Daily: &config.BudgetLimit{Amount: 20, Action: config.BudgetActionBlock}},
+			"sonnet": {Monthly: &config.BudgetLimit{Amount: 100}},
+		},
+	})
+	checker := NewBudgetChecker(tracker)
+
+	status, err := checker.Check("")
+	if err != nil {
+		t.Fatalf("Check() error: %v", err)
+	}
+	if status.ShouldBlock {
+		t.Error("scoped limits should not block all requests")
+	}
+	if len(status.Scoped) != 3 {
+		t.Fatalf("expected 3 scoped statuses, got %+v", status.Scoped)
+	}
+	opus := status.Scoped[1]
+	if opus.Scope != "model" || opus.Name != "Opus" || opus.Spent != 25 || !opus.Exceeded || opus.Remaining != 0 {
+		t.Errorf("opus status = %+v", opus)
+	}
+	if sonnet := status.Scoped[2]; sonnet.Exceeded || sonnet.Action != config.BudgetActionWarn || sonnet.Spent != 2 {
+		t.Errorf("sonnet status = %+v", sonnet)
+	}
+
+	if _, blocked := checker.AdmissionBlocked("", "claude-opus-4-1", ""); !blocked {
+		t.Error("expected opus requests to be blocked")
+	}
+	if _, blocked := checker.AdmissionBlocked("", "claude-sonnet-4", ""); blocked {
+		t.Error("expected sonnet requests to be admitted")
+	}
+	if msg, blocked := checker.ScopeBlocked("openrouter", "", ""); !blocked || !strings.Contains(msg, `provider "openrouter"`) {
+		t.Errorf("ScopeBlocked(openrouter) = %q, %v", msg, blocked)
+	}
+	if _, blocked := checker.ScopeBlocked("anthropic", "", ""); blocked {
+		t.Error("expected anthropic to have no provider limit")
+	}
+}
+
+func TestBudgetChecker_UserLimits(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	config.ResetDefaultStore()
+	defer config.ResetDefaultStore()
+
+	db, err := OpenLogDB(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenLogDB() error: %v", err)
+	}
+	defer db.Close()
+	tracker := NewUsageTracker(db)
+	tracker.Record(UsageEntry{Timestamp: time.Now(), Provider: "anthropic", Model: "claude-sonnet-4", CostUSD: 12, User: "alice"})
+	tracker.Record(UsageEntry{Timestamp: time.Now(), Provider: "anthropic", Model: "claude-sonnet-4", CostUSD: 3, User: "bob"})
+
+	config.SetBudgets(&config.BudgetConfig{
+		Users: map[string]*config.BudgetLimits{
+			"alice": {Daily: &config.BudgetLimit{Amount: 10, Action: config.BudgetActionBlock}},
+			"bob":   {Daily: &config.BudgetLimit{Amount: 10, Action: config.BudgetActionBlock}},
+		},
+	})
+	checker := NewBudgetChecker(tracker)
+
+	if msg, blocked := checker.AdmissionBlocked("", "claude-sonnet-4", "alice"); !blocked || !strings.Contains(msg, `user "alice"`) {
+		t.Errorf("AdmissionBlocked(alice) = %q, %v", msg, blocked)
+	}
+	if _, blocked := checker.AdmissionBlocked("", "claude-sonnet-4", "bob"); blocked {
+		t.Error("expected bob to be under budget")
+	}
+	if _, blocked := checker.AdmissionBlocked("", "claude-sonnet-4", ""); blocked {
+		t.Error("expected requests without a user to ignore user limits")
+	}
+
+	summary, err := tracker.GetSummary("day", "")
+	if err != nil {
+		t.Fatalf("GetSummary() error: %v", err)
+	}
+	if len(summary.ByUser) != 2 || summary.ByUser["alice"].Cost != 12 || summary.ByUser["bob"].RequestCount != 1 {
+		t.Errorf("by_user = %+v", summary.ByUser)
+	}
+}
+
+func TestBudgetChecker_Currency(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	config.ResetDefaultStore()
+	defer config.ResetDefaultStore()
+
+	db, err := OpenLogDB(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenLogDB() error: %v", err)
+	}
+	defer db.Close()
+	tracker := NewUsageTracker(db)
+	tracker.Record(UsageEntry{Timestamp: time.Now(), Provider: "anthropic", Model: "claude-sonnet-4", CostUSD: 10})
+
+	// Limits are in the display currency: 8 EUR is $16 at 0.5 EUR per USD
+	config.SetCurrency(&config.CurrencyConfig{Code: "EUR", Rate: 0.5})
+	config.SetBudgets(&config.BudgetConfig{
+		Daily:       &config.BudgetLimit{Amount: 8, Action: config.BudgetActionBlock},
+		Enforcement: config.BudgetEnforcementStreaming,
+	})
+	checker := NewBudgetChecker(tracker)
+
+	status, err := checker.Check("")
+	if err != nil {
+		t.Fatalf("Check() error: %v", err)
+	}
+	if status.Currency != "EUR" || status.DailySpent != 5 || status.DailyRemaining != 3 || status.ShouldBlock {
+		t.Errorf("status = %s %v spent, %v remaining, block %v; want EUR 5 spent, 3 remaining", status.Currency, status.DailySpent, status.DailyRemaining, status.ShouldBlock)
+	}
+	if remaining, ok := checker.StreamingBudget("", "anthropic", "claude-sonnet-4", ""); !ok || remaining != 6 {
+		t.Errorf("StreamingBudget() = %v, %v; want $6 remaining", remaining, ok)
+	}
+}
+
+func TestBudgetChecker_DowngradeRules(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	config.ResetDefaultStore()
+	defer config.ResetDefaultStore()
+
+	db, err := OpenLogDB(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenLogDB() error: %v", err)
+	}
+	defer db.Close()
+	tracker := NewUsageTracker(db)
+	tracker.Record(UsageEntry{Timestamp: time.Now(), Provider: "anthropic", Model: "claude-opus-4-20250514", CostUSD: 25})
+
+	config.SetBudgets(&config.BudgetConfig{
+		Models: map[string]*config.BudgetLimits{
+			"opus": {Daily: &config.BudgetLimit{Amount: 20, Action: config.BudgetActionDowngrade}},
+		},
+		Downgrade: []*config.BudgetDowngrade{
+			{Match: "*opus*", Target: "deepseek-chat", Provider: "deepseek"},
+			{Match: "*opus*", Target: "claude-sonnet-4-20250514"},
+			{Match: "*sonnet*", Target: "claude-3-5-haiku-20241022"},
+		},
+	})
+	checker := NewBudgetChecker(tracker)
+
+	tests := []struct {
+		provider, model, want string
+	}{
+		{"anthropic", "claude-opus-4-1", "claude-sonnet-4-20250514"},
+		{"deepseek", "claude-opus-4-1", "deepseek-chat"},
+		{"", "claude-sonnet-4", "claude-3-5-haiku-20241022"},
+		{"", "gpt-4o", "gpt-4o"},
+	}
+	for _, tt := range tests {
+		if got := checker.DowngradeModel(tt.provider, tt.model); got != tt.want {
+			t.Errorf("DowngradeModel(%q, %q) = %q, want %q", tt.provider, tt.model, got, tt.want)
+		}
+	}
+
+	if target, ok := checker.DowngradeFor("", "anthropic", "claude-opus-4-1", ""); !ok || target != "claude-sonnet-4-20250514" {
+		t.Errorf("DowngradeFor(opus) = %q, %v", target, ok)
+	}
+	if _, ok := checker.DowngradeFor("", "anthropic", "claude-sonnet-4", ""); ok {
+		t.Error("expected sonnet not to be downgraded while only opus is over budget")
+	}
+
+	status, err := checker.CheckModel("", "", "claude-opus-4-1")
+	if err != nil {
+		t.Fatalf("CheckModel() error: %v", err)
+	}
+	if len(status.Downgrades) != 3 || status.DowngradeModel != "claude-sonnet-4-20250514" {
+		t.Errorf("status downgrades = %v, downgrade_model = %q", status.Downgrades, status.DowngradeModel)
+	}
+}
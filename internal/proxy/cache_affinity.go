@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"sync"
+	"time"
+)
+
+// cacheAffinityTTL approximates how long a provider keeps a prompt cache
+// alive. Anthropic's default ephemeral cache lives 5 minutes and is
+// refreshed on every hit, so each cached response extends the affinity.
+const cacheAffinityTTL = 5 * time.Minute
+
+// maxCacheAffinityEntries bounds the affinity table; expired entries are
+// pruned when it fills up.
+const maxCacheAffinityEntries = 1000
+
+type cacheAffinityEntry struct {
+	provider string
+	expires  time.Time
+}
+
+// cacheAffinityTable remembers which provider holds each session's prompt cache.
+type cacheAffinityTable struct {
+	mu      sync.Mutex
+	entries map[string]cacheAffinityEntry
+}
+
+var globalCacheAffinity = &cacheAffinityTable{
+	entries: make(map[string]cacheAffinityEntry),
+}
+
+// RecordCacheAffinity notes that provider served a response for the session
+// which created or read a prompt cache.
+func RecordCacheAffinity(sessionID, provider string) {
+	if sessionID == "" || provider == "" {
+		return
+	}
+	now := time.Now()
+	t := globalCacheAffinity
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, exists := t.entries[sessionID]; !exists && len(t.entries) >= maxCacheAffinityEntries {
+		for id, e := range t.entries {
+			if now.After(e.expires) {
+				delete(t.entries, id)
+			}
+		}
+		if len(t.entries) >= maxCacheAffinityEntries {
+			return
+		}
+	}
+	t.entries[sessionID] = cacheAffinityEntry{provider: provider, expires: now.Add(cacheAffinityTTL)}
+}
+
+// GetCacheAffinity returns the provider holding the session's prompt cache,
+// or "" if none is known or the cache has likely expired.
+func GetCacheAffinity(sessionID string) string {
+	if sessionID == "" {
+		return ""
+	}
+	t := globalCacheAffinity
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e, ok := t.entries[sessionID]
+	if !ok {
+		return ""
+	}
+	if time.Now().After(e.expires) {
+		delete(t.entries, sessionID)
+		return ""
+	}
+	return e.provider
+}
+
+// preferCachedProvider moves the provider holding the session's prompt cache
+// to the front of the list, keeping the order of the rest. The input slice
+// is not modified.
+func preferCachedProvider(providers []*Provider, sessionID string) []*Provider {
+	name := GetCacheAffinity(sessionID)
+	if name == "" || len(providers) < 2 || providers[0].Name == name {
+		return providers
+	}
+	for i, p := range providers {
+		if p.Name != name {
+			continue
+		}
+		reordered := make([]*Provider, 0, len(providers))
+		reordered = append(reordered, p)
+		reordered = append(reordered, providers[:i]...)
+		reordered = append(reordered, providers[i+1:]...)
+		return reordered
+	}
+	return providers
+}
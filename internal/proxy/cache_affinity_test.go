@@ -0,0 +1,57 @@
+package proxy
+
+import (
+	"testing"
+	"time"
+)
+
+func providerNames(providers []*Provider) []string {
+	names := make([]string, len(providers))
+	for i, p := range providers {
+		names[i] = p.Name
+	}
+	return names
+}
+
+func TestPreferCachedProvider(t *testing.T) {
+	providers := []*Provider{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+
+	t.Run("no affinity keeps order", func(t *testing.T) {
+		got := providerNames(preferCachedProvider(providers, "affinity-none"))
+		if got[0] != "a" || got[1] != "b" || got[2] != "c" {
+			t.Errorf("order = %v, want [a b c]", got)
+		}
+	})
+
+	t.Run("cached provider moves to front", func(t *testing.T) {
+		RecordCacheAffinity("affinity-c", "c")
+		got := providerNames(preferCachedProvider(providers, "affinity-c"))
+		if got[0] != "c" || got[1] != "a" || got[2] != "b" {
+			t.Errorf("order = %v, want [c a b]", got)
+		}
+		if providers[0].Name != "a" {
+			t.Error("input slice was modified")
+		}
+	})
+
+	t.Run("unknown provider keeps order", func(t *testing.T) {
+		RecordCacheAffinity("affinity-gone", "z")
+		got := providerNames(preferCachedProvider(providers, "affinity-gone"))
+		if got[0] != "a" {
+			t.Errorf("order = %v, want a first", got)
+		}
+	})
+
+	t.Run("expired affinity is ignored", func(t *testing.T) {
+		RecordCacheAffinity("affinity-expired", "b")
+		globalCacheAffinity.mu.Lock()
+		e := globalCacheAffinity.entries["affinity-expired"]
+		e.expires = time.Now().Add(-time.Second)
+		globalCacheAffinity.entries["affinity-expired"] = e
+		globalCacheAffinity.mu.Unlock()
+
+		if p := GetCacheAffinity("affinity-expired"); p != "" {
+			t.Errorf("GetCacheAffinity() = %q, want empty", p)
+		}
+	})
+}
@@ -19,6 +19,13 @@ const (
 	DefaultTargetTokens    = 20000
 	DefaultPreserveRecent  = 4
 	DefaultSummaryModel    = "claude-3-haiku-20240307"
+	DefaultEmbeddingModel  = "text-embedding-3-small"
+	DefaultChunkTokens     = 500
+	DefaultRetrievalTopK   = 8
+
+	// Compression strategies
+	CompressionStrategySummarize = "summarize"
+	CompressionStrategyRetrieval = "retrieval"
 
 	// Approximate tokens per character (conservative estimate)
 	tokensPerChar = 0.25
@@ -38,6 +45,10 @@ type ContextCompressor struct {
 	providers []*Provider
 	mu        sync.RWMutex
 
+	// Embedding index for the retrieval strategy, opened on first use
+	indexDir string
+	index    *vectorIndex
+
 	// Stats
 	requestsCompressed int64
 	tokensSaved        int64
@@ -134,6 +145,31 @@ func (c *ContextCompressor) SetProviders(providers []*Provider) {
 	c.providers = providers
 }
 
+// SetIndexDir sets the directory of the retrieval strategy's embedding index.
+// Without one, embeddings are only kept in memory.
+func (c *ContextCompressor) SetIndexDir(dir string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.indexDir = dir
+}
+
+// ConfigFor returns the compression settings for the project at projectPath:
+// the global settings with the override of the project's binding applied.
+func (c *ContextCompressor) ConfigFor(projectPath string) *config.CompressionConfig {
+	c.mu.RLock()
+	cfg := c.config
+	c.mu.RUnlock()
+	if projectPath != "" {
+		if _, binding := config.FindProjectBinding(projectPath); binding != nil && binding.Compression != nil {
+			return binding.Compression.Apply(cfg)
+		}
+	}
+	if cfg == nil {
+		return &config.CompressionConfig{}
+	}
+	return cfg
+}
+
 // IsEnabled returns whether compression is enabled.
 func (c *ContextCompressor) IsEnabled() bool {
 	c.mu.RLock()
@@ -153,14 +189,18 @@ func (c *ContextCompressor) GetStats() CompressionStats {
 
 // ShouldCompress determines if the messages should be compressed.
 func (c *ContextCompressor) ShouldCompress(messages []Message) bool {
-	if !c.IsEnabled() {
-		return false
-	}
-
 	c.mu.RLock()
-	threshold := c.config.ThresholdTokens
+	cfg := c.config
 	c.mu.RUnlock()
+	return c.shouldCompress(cfg, messages)
+}
 
+func (c *ContextCompressor) shouldCompress(cfg *config.CompressionConfig, messages []Message) bool {
+	if cfg == nil || !cfg.Enabled {
+		return false
+	}
+
+	threshold := cfg.ThresholdTokens
 	if threshold <= 0 {
 		threshold = DefaultThresholdTokens
 	}
@@ -203,20 +243,65 @@ func estimateContentLength(content interface{}) int {
 	}
 }
 
+// messageText renders message content as text, with structured content as JSON.
+func messageText(content interface{}) string {
+	switch v := content.(type) {
+	case string:
+		return v
+	default:
+		if data, err := json.Marshal(v); err == nil {
+			return string(data)
+		}
+		return ""
+	}
+}
+
 // __CONTINUE_HERE__
 
-// Compress compresses the messages by summarizing older messages.
+// Compress compresses the messages by summarizing older messages, or with the
+// retrieval strategy by keeping only the older parts relevant to the latest
+// user message.
 // Returns the compressed messages and any error.
 func (c *ContextCompressor) Compress(messages []Message) ([]Message, error) {
-	if !c.IsEnabled() || len(messages) == 0 {
+	c.mu.RLock()
+	cfg := c.config
+	c.mu.RUnlock()
+	return c.compressCounted(cfg, messages)
+}
+
+// compressCounted compresses messages per cfg, counting it in the stats.
+func (c *ContextCompressor) compressCounted(cfg *config.CompressionConfig, messages []Message) ([]Message, error) {
+	if cfg == nil || !cfg.Enabled || len(messages) == 0 {
 		return messages, nil
 	}
 
-	c.mu.RLock()
-	preserveRecent := c.config.PreserveRecent
-	targetTokens := c.config.TargetTokens
-	c.mu.RUnlock()
+	compressed, _, err := c.compress(cfg, messages)
+	if err != nil {
+		// On error, return original messages
+		return messages, err
+	}
+	if compressed == nil {
+		return messages, nil
+	}
 
+	// Update stats
+	tokensBefore := c.EstimateTokens(messages)
+	tokensAfter := c.EstimateTokens(compressed)
+	c.mu.Lock()
+	c.requestsCompressed++
+	c.tokensSaved += int64(tokensBefore - tokensAfter)
+	c.mu.Unlock()
+
+	return compressed, nil
+}
+
+// compress replaces the older messages with a summary, or with the parts
+// relevant to the latest user message, per cfg. It returns the compressed
+// messages and the text that replaced the older ones, or nil messages when
+// there are too few messages to compress.
+func (c *ContextCompressor) compress(cfg *config.CompressionConfig, messages []Message) ([]Message, string, error) {
+	preserveRecent := cfg.PreserveRecent
+	targetTokens := cfg.TargetTokens
 	if preserveRecent <= 0 {
 		preserveRecent = DefaultPreserveRecent
 	}
@@ -226,49 +311,110 @@ func (c *ContextCompressor) Compress(messages []Message) ([]Message, error) {
 
 	// If we don't have enough messages to compress, return as-is
 	if len(messages) <= preserveRecent {
-		return messages, nil
+		return nil, "", nil
 	}
 
 	// Split messages: older ones to summarize, recent ones to preserve
 	toSummarize := messages[:len(messages)-preserveRecent]
 	toPreserve := messages[len(messages)-preserveRecent:]
 
-	// Estimate tokens before compression
-	tokensBefore := c.EstimateTokens(messages)
-
-	// Summarize older messages
-	summary, err := c.Summarize(toSummarize)
-	if err != nil {
-		// On error, return original messages
-		return messages, fmt.Errorf("compression failed: %w", err)
+	var summary string
+	var summaryMsg Message
+	if cfg.Strategy == CompressionStrategyRetrieval {
+		relevant, err := c.retrieve(cfg.Retrieval, toSummarize, toPreserve, targetTokens-c.EstimateTokens(toPreserve))
+		if err != nil {
+			return nil, "", fmt.Errorf("compression failed: %w", err)
+		}
+		summary = relevant
+		summaryMsg = Message{
+			Role:    "user",
+			Content: fmt.Sprintf("[Relevant earlier conversation]\n%s\n[End of earlier conversation]", relevant),
+		}
+	} else {
+		var err error
+		summary, err = c.summarize(cfg, toSummarize)
+		if err != nil {
+			return nil, "", fmt.Errorf("compression failed: %w", err)
+		}
+		summaryMsg = Message{
+			Role:    "user",
+			Content: fmt.Sprintf("[Previous conversation summary]\n%s\n[End of summary]", summary),
+		}
 	}
 
 	// Build compressed message list
 	compressed := make([]Message, 0, len(toPreserve)+1)
-
-	// Add summary as a system message or user message
-	summaryMsg := Message{
-		Role:    "user",
-		Content: fmt.Sprintf("[Previous conversation summary]\n%s\n[End of summary]", summary),
-	}
 	compressed = append(compressed, summaryMsg)
 	compressed = append(compressed, toPreserve...)
+	return compressed, summary, nil
+}
 
-	// Update stats
-	tokensAfter := c.EstimateTokens(compressed)
-	c.mu.Lock()
-	c.requestsCompressed++
-	c.tokensSaved += int64(tokensBefore - tokensAfter)
-	c.mu.Unlock()
+// CompressionPreview is the outcome of a dry-run compression.
+type CompressionPreview struct {
+	Strategy        string `json:"strategy"`
+	ThresholdTokens int    `json:"threshold_tokens"`
+	WouldCompress   bool   `json:"would_compress"` // whether the messages exceed the threshold
+	TokensBefore    int    `json:"tokens_before"`
+	TokensAfter     int    `json:"tokens_after"`
+	MessagesBefore  int    `json:"messages_before"`
+	MessagesAfter   int    `json:"messages_after"`
+	Summary         string `json:"summary,omitempty"` // generated summary or retrieved context
+}
 
-	return compressed, nil
+// Preview compresses messages without applying the result or counting it in
+// the stats, using cfg instead of the configured settings when given. Unlike
+// Compress it runs even when compression is disabled or the messages are
+// below the threshold, so settings can be tried out before enabling them.
+func (c *ContextCompressor) Preview(messages []Message, cfg *config.CompressionConfig) (*CompressionPreview, error) {
+	if cfg == nil {
+		c.mu.RLock()
+		cfg = c.config
+		c.mu.RUnlock()
+	}
+
+	threshold := cfg.ThresholdTokens
+	if threshold <= 0 {
+		threshold = DefaultThresholdTokens
+	}
+	strategy := cfg.Strategy
+	if strategy == "" {
+		strategy = CompressionStrategySummarize
+	}
+	tokensBefore := c.EstimateTokens(messages)
+	preview := &CompressionPreview{
+		Strategy:        strategy,
+		ThresholdTokens: threshold,
+		WouldCompress:   tokensBefore > threshold,
+		TokensBefore:    tokensBefore,
+		TokensAfter:     tokensBefore,
+		MessagesBefore:  len(messages),
+		MessagesAfter:   len(messages),
+	}
+
+	compressed, summary, err := c.compress(cfg, messages)
+	if err != nil {
+		return nil, err
+	}
+	if compressed != nil {
+		preview.TokensAfter = c.EstimateTokens(compressed)
+		preview.MessagesAfter = len(compressed)
+		preview.Summary = summary
+	}
+	return preview, nil
 }
 
 // Summarize generates a summary of the given messages using a cheap model.
 func (c *ContextCompressor) Summarize(messages []Message) (string, error) {
 	c.mu.RLock()
-	summaryModel := c.config.SummaryModel
-	summaryProvider := c.config.SummaryProvider
+	cfg := c.config
+	c.mu.RUnlock()
+	return c.summarize(cfg, messages)
+}
+
+func (c *ContextCompressor) summarize(cfg *config.CompressionConfig, messages []Message) (string, error) {
+	c.mu.RLock()
+	summaryModel := cfg.SummaryModel
+	summaryProvider := cfg.SummaryProvider
 	providers := c.providers
 	c.mu.RUnlock()
 
@@ -307,14 +453,7 @@ func (c *ContextCompressor) Summarize(messages []Message) (string, error) {
 	var convBuilder strings.Builder
 	for _, msg := range messages {
 		convBuilder.WriteString(fmt.Sprintf("%s: ", msg.Role))
-		switch v := msg.Content.(type) {
-		case string:
-			convBuilder.WriteString(v)
-		default:
-			if data, err := json.Marshal(v); err == nil {
-				convBuilder.WriteString(string(data))
-			}
-		}
+		convBuilder.WriteString(messageText(msg.Content))
 		convBuilder.WriteString("\n\n")
 	}
 
@@ -352,8 +491,9 @@ Conversation:
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", provider.Token)
-	req.Header.Set("Authorization", "Bearer "+provider.Token)
+	token := provider.AuthToken()
+	req.Header.Set("x-api-key", token)
+	req.Header.Set("Authorization", "Bearer "+token)
 	req.Header.Set("anthropic-version", "2023-06-01")
 
 	resp, err := c.client.Do(req)
@@ -389,7 +529,14 @@ Conversation:
 // CompressRequestBody compresses the request body if needed.
 // Returns the potentially modified body and whether compression was applied.
 func (c *ContextCompressor) CompressRequestBody(body []byte) ([]byte, bool, error) {
-	if !c.IsEnabled() {
+	return c.CompressRequestBodyFor(body, "")
+}
+
+// CompressRequestBodyFor is CompressRequestBody with the settings that apply
+// to the project at projectPath.
+func (c *ContextCompressor) CompressRequestBodyFor(body []byte, projectPath string) ([]byte, bool, error) {
+	cfg := c.ConfigFor(projectPath)
+	if !cfg.Enabled {
 		return body, false, nil
 	}
 
@@ -413,11 +560,11 @@ func (c *ContextCompressor) CompressRequestBody(body []byte) ([]byte, bool, erro
 		return body, false, nil
 	}
 
-	if !c.ShouldCompress(messages) {
+	if !c.shouldCompress(cfg, messages) {
 		return body, false, nil
 	}
 
-	compressed, err := c.Compress(messages)
+	compressed, err := c.compressCounted(cfg, messages)
 	if err != nil {
 		return body, false, err
 	}
@@ -0,0 +1,192 @@
+package proxy
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// vectorIndexMaxAge is how long unused embeddings stay in the index.
+	vectorIndexMaxAge = 30 * 24 * time.Hour
+	// vectorIndexMaxMemory caps the in-memory index, which is reset when full.
+	vectorIndexMaxMemory = 50000
+)
+
+// vectorIndex stores chunk embeddings by model and content hash. It is backed
+// by SQLite when opened with a directory, and by memory otherwise or when the
+// database cannot be opened.
+type vectorIndex struct {
+	mu  sync.Mutex
+	db  *sql.DB
+	mem map[string][]float32 // keyed by model + "\x00" + hash
+}
+
+// chunkIndex returns the compressor's vector index, opening it on first use.
+func (c *ContextCompressor) chunkIndex() *vectorIndex {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.index == nil {
+		c.index = openVectorIndex(c.indexDir)
+	}
+	return c.index
+}
+
+// closeIndex closes the vector index; it is opened again on next use.
+func (c *ContextCompressor) closeIndex() {
+	c.mu.Lock()
+	ix := c.index
+	c.index = nil
+	c.mu.Unlock()
+	if ix == nil {
+		return
+	}
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.db != nil {
+		ix.db.Close()
+		ix.db = nil
+	}
+}
+
+func openVectorIndex(dir string) *vectorIndex {
+	ix := &vectorIndex{mem: make(map[string][]float32)}
+	if dir == "" {
+		return ix
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return ix
+	}
+	dbPath := filepath.Join(dir, "compression.db")
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		return ix
+	}
+	_ = os.Chmod(dbPath, 0600)
+	stmts := []string{
+		"PRAGMA busy_timeout=5000",
+		`CREATE TABLE IF NOT EXISTS chunk_embeddings (
+			model   TEXT NOT NULL,
+			hash    TEXT NOT NULL,
+			vector  BLOB NOT NULL,
+			used_at INTEGER NOT NULL,
+			PRIMARY KEY (model, hash)
+		)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			db.Close()
+			return ix
+		}
+	}
+	db.Exec("DELETE FROM chunk_embeddings WHERE used_at < ?", time.Now().Add(-vectorIndexMaxAge).Unix())
+	ix.db = db
+	return ix
+}
+
+// get returns the stored embeddings of the given hashes, marking them used.
+func (ix *vectorIndex) get(model string, hashes []string) map[string][]float32 {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	found := make(map[string][]float32, len(hashes))
+	if ix.db == nil {
+		for _, h := range hashes {
+			if v, ok := ix.mem[model+"\x00"+h]; ok {
+				found[h] = v
+			}
+		}
+		return found
+	}
+
+	// Query in batches to stay under SQLite's variable limit
+	now := time.Now().Unix()
+	for start := 0; start < len(hashes); start += 500 {
+		end := start + 500
+		if end > len(hashes) {
+			end = len(hashes)
+		}
+		batch := hashes[start:end]
+		args := make([]interface{}, 0, len(batch)+1)
+		args = append(args, model)
+		for _, h := range batch {
+			args = append(args, h)
+		}
+		in := strings.TrimSuffix(strings.Repeat("?,", len(batch)), ",")
+		rows, err := ix.db.Query("SELECT hash, vector FROM chunk_embeddings WHERE model = ? AND hash IN ("+in+")", args...)
+		if err != nil {
+			continue
+		}
+		for rows.Next() {
+			var h string
+			var blob []byte
+			if rows.Scan(&h, &blob) == nil {
+				found[h] = decodeVector(blob)
+			}
+		}
+		rows.Close()
+		ix.db.Exec("UPDATE chunk_embeddings SET used_at = ? WHERE model = ? AND hash IN ("+in+")", append([]interface{}{now}, args...)...)
+	}
+	return found
+}
+
+// put stores embeddings by hash.
+func (ix *vectorIndex) put(model string, vectors map[string][]float32) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.db == nil {
+		if len(ix.mem)+len(vectors) > vectorIndexMaxMemory {
+			ix.mem = make(map[string][]float32)
+		}
+		for h, v := range vectors {
+			ix.mem[model+"\x00"+h] = v
+		}
+		return
+	}
+
+	tx, err := ix.db.Begin()
+	if err != nil {
+		return
+	}
+	now := time.Now().Unix()
+	for h, v := range vectors {
+		if _, err := tx.Exec("INSERT OR REPLACE INTO chunk_embeddings (model, hash, vector, used_at) VALUES (?, ?, ?, ?)",
+			model, h, encodeVector(v), now); err != nil {
+			tx.Rollback()
+			return
+		}
+	}
+	tx.Commit()
+}
+
+// close closes the index database, if any.
+func (ix *vectorIndex) close() error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.db == nil {
+		return nil
+	}
+	err := ix.db.Close()
+	ix.db = nil
+	return err
+}
+
+func encodeVector(v []float32) []byte {
+	buf := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(f))
+	}
+	return buf
+}
+
+func decodeVector(buf []byte) []float32 {
+	v := make([]float32, len(buf)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return v
+}
@@ -0,0 +1,317 @@
+package proxy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// maxEmbeddingBatch is the most inputs sent in one embeddings request.
+const maxEmbeddingBatch = 64
+
+// conversationChunk is a run of consecutive conversation text embedded as one
+// unit by the retrieval strategy.
+type conversationChunk struct {
+	index int // position in the conversation
+	text  string
+	hash  string
+}
+
+// retrieve returns the parts of older that are most relevant to the latest
+// user message in recent, in conversation order and within about budget
+// tokens. Chunk embeddings are cached in the vector index, so each part of a
+// conversation is embedded once rather than on every request.
+func (c *ContextCompressor) retrieve(rc *config.RetrievalConfig, older, recent []Message, budget int) (string, error) {
+	var cfg config.RetrievalConfig
+	if rc != nil {
+		cfg = *rc
+	}
+
+	if cfg.EmbeddingModel == "" {
+		cfg.EmbeddingModel = DefaultEmbeddingModel
+	}
+	if cfg.ChunkTokens <= 0 {
+		cfg.ChunkTokens = DefaultChunkTokens
+	}
+	if cfg.TopK <= 0 {
+		cfg.TopK = DefaultRetrievalTopK
+	}
+
+	chunks := chunkMessages(older, cfg.ChunkTokens)
+	if len(chunks) == 0 {
+		return "", fmt.Errorf("nothing to retrieve from")
+	}
+	query := retrievalQuery(recent, cfg.ChunkTokens)
+	if query == "" {
+		return "", fmt.Errorf("no user message to retrieve for")
+	}
+
+	index := c.chunkIndex()
+	hashes := make([]string, len(chunks))
+	for i, ch := range chunks {
+		hashes[i] = ch.hash
+	}
+	vectors := index.get(cfg.EmbeddingModel, hashes)
+
+	// Embed the query together with any chunks not yet in the index
+	inputs := []string{query}
+	var missing []conversationChunk
+	for _, ch := range chunks {
+		if _, ok := vectors[ch.hash]; !ok {
+			inputs = append(inputs, ch.text)
+			missing = append(missing, ch)
+		}
+	}
+	embedded, err := c.embed(&cfg, inputs)
+	if err != nil {
+		return "", err
+	}
+	queryVec := embedded[0]
+	if len(missing) > 0 {
+		added := make(map[string][]float32, len(missing))
+		for i, ch := range missing {
+			vectors[ch.hash] = embedded[i+1]
+			added[ch.hash] = embedded[i+1]
+		}
+		index.put(cfg.EmbeddingModel, added)
+	}
+
+	type scored struct {
+		chunk conversationChunk
+		score float64
+	}
+	ranked := make([]scored, len(chunks))
+	for i, ch := range chunks {
+		ranked[i] = scored{ch, cosineSimilarity(queryVec, vectors[ch.hash])}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].score > ranked[j].score })
+
+	// Take the best chunks that fit the budget, always keeping at least one
+	var selected []conversationChunk
+	tokens := 0
+	for _, r := range ranked {
+		if len(selected) == cfg.TopK {
+			break
+		}
+		t := int(float64(len(r.chunk.text)) * tokensPerChar)
+		if len(selected) > 0 && tokens+t > budget {
+			continue
+		}
+		selected = append(selected, r.chunk)
+		tokens += t
+	}
+	sort.Slice(selected, func(i, j int) bool { return selected[i].index < selected[j].index })
+
+	parts := make([]string, len(selected))
+	for i, ch := range selected {
+		parts[i] = ch.text
+	}
+	return strings.Join(parts, "\n...\n"), nil
+}
+
+// chunkMessages splits messages into chunks of about chunkTokens tokens,
+// keeping short consecutive messages together and splitting long ones.
+func chunkMessages(messages []Message, chunkTokens int) []conversationChunk {
+	maxChars := int(float64(chunkTokens) / tokensPerChar)
+	var chunks []conversationChunk
+	var cur strings.Builder
+	flush := func() {
+		text := strings.TrimSpace(cur.String())
+		cur.Reset()
+		if text == "" {
+			return
+		}
+		sum := sha256.Sum256([]byte(text))
+		chunks = append(chunks, conversationChunk{
+			index: len(chunks),
+			text:  text,
+			hash:  hex.EncodeToString(sum[:]),
+		})
+	}
+
+	for _, msg := range messages {
+		text := strings.TrimSpace(messageText(msg.Content))
+		if text == "" {
+			continue
+		}
+		entry := msg.Role + ": " + text + "\n\n"
+		if len(entry) > maxChars {
+			flush()
+			for _, part := range splitText(entry, maxChars) {
+				cur.WriteString(part)
+				flush()
+			}
+			continue
+		}
+		if cur.Len()+len(entry) > maxChars {
+			flush()
+		}
+		cur.WriteString(entry)
+	}
+	flush()
+	return chunks
+}
+
+// splitText splits s into pieces of at most n bytes on rune boundaries.
+func splitText(s string, n int) []string {
+	var parts []string
+	for len(s) > n {
+		i := n
+		for i > 0 && !utf8.RuneStart(s[i]) {
+			i--
+		}
+		if i == 0 {
+			i = n
+		}
+		parts = append(parts, s[:i])
+		s = s[i:]
+	}
+	if s != "" {
+		parts = append(parts, s)
+	}
+	return parts
+}
+
+// retrievalQuery returns the latest user message of recent, truncated to
+// about chunkTokens tokens, to rank older chunks against.
+func retrievalQuery(recent []Message, chunkTokens int) string {
+	for i := len(recent) - 1; i >= 0; i-- {
+		if recent[i].Role != "user" {
+			continue
+		}
+		text := strings.TrimSpace(messageText(recent[i].Content))
+		if text == "" {
+			continue
+		}
+		return splitText(text, int(float64(chunkTokens)/tokensPerChar))[0]
+	}
+	return ""
+}
+
+// embed returns embeddings of inputs from the configured OpenAI-compatible
+// embeddings endpoint.
+func (c *ContextCompressor) embed(cfg *config.RetrievalConfig, inputs []string) ([][]float32, error) {
+	url, apiKey, err := embeddingEndpoint(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	vectors := make([][]float32, 0, len(inputs))
+	for start := 0; start < len(inputs); start += maxEmbeddingBatch {
+		end := start + maxEmbeddingBatch
+		if end > len(inputs) {
+			end = len(inputs)
+		}
+		batch, err := c.embedBatch(url, apiKey, cfg.EmbeddingModel, inputs[start:end])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+func (c *ContextCompressor) embedBatch(url, apiKey, model string, inputs []string) ([][]float32, error) {
+	reqData, err := json.Marshal(map[string]interface{}{
+		"model": model,
+		"input": inputs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewReader(reqData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("embedding request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("embedding failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var respData struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&respData); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(respData.Data) != len(inputs) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(inputs), len(respData.Data))
+	}
+
+	vectors := make([][]float32, len(inputs))
+	for _, d := range respData.Data {
+		if d.Index < 0 || d.Index >= len(inputs) {
+			return nil, fmt.Errorf("embedding index %d out of range", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
+
+// embeddingEndpoint resolves the embeddings URL and API key, preferring the
+// configured embedding provider.
+func embeddingEndpoint(cfg *config.RetrievalConfig) (string, string, error) {
+	base, apiKey := cfg.EmbeddingURL, cfg.EmbeddingAPIKey
+	if cfg.EmbeddingProvider != "" {
+		p := config.GetProvider(cfg.EmbeddingProvider)
+		if p == nil {
+			return "", "", fmt.Errorf("embedding provider %q not found", cfg.EmbeddingProvider)
+		}
+		base, apiKey = p.BaseURL, p.AuthToken
+	}
+	if base == "" {
+		return "", "", fmt.Errorf("no embedding provider or URL configured")
+	}
+	apiKey, err := config.ResolveSecret(apiKey)
+	if err != nil {
+		return "", "", err
+	}
+	base = strings.TrimSuffix(base, "/")
+	if !strings.HasSuffix(base, "/v1") {
+		base += "/v1"
+	}
+	return base + "/embeddings", apiKey, nil
+}
+
+// cosineSimilarity returns the cosine of the angle between a and b, or 0 for
+// mismatched or zero vectors.
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
@@ -0,0 +1,207 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// newEmbeddingServer returns an embeddings endpoint whose vectors mark which
+// of a few topic words each input mentions, and a count of embedded inputs.
+func newEmbeddingServer(t *testing.T) (*httptest.Server, *int64) {
+	t.Helper()
+	topics := []string{"database", "frontend", "deploy"}
+	var embedded int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" || r.Header.Get("Authorization") != "Bearer test-key" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		atomic.AddInt64(&embedded, int64(len(req.Input)))
+
+		type item struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		}
+		var data []item
+		for i, in := range req.Input {
+			vec := make([]float32, len(topics)+1)
+			vec[len(topics)] = 0.1
+			for j, topic := range topics {
+				if strings.Contains(strings.ToLower(in), topic) {
+					vec[j] = 1
+				}
+			}
+			data = append(data, item{Index: i, Embedding: vec})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &embedded
+}
+
+func TestContextCompressor_Compress_Retrieval(t *testing.T) {
+	srv, embedded := newEmbeddingServer(t)
+	compressor := NewContextCompressor(&config.CompressionConfig{
+		Enabled:        true,
+		Strategy:       CompressionStrategyRetrieval,
+		PreserveRecent: 2,
+		Retrieval: &config.RetrievalConfig{
+			EmbeddingURL:    srv.URL,
+			EmbeddingAPIKey: "test-key",
+			ChunkTokens:     10,
+			TopK:            1,
+		},
+	}, nil)
+
+	messages := []Message{
+		{Role: "user", Content: "Let's design the database schema"},
+		{Role: "assistant", Content: "Tables: users, orders"},
+		{Role: "user", Content: "Now style the frontend buttons"},
+		{Role: "assistant", Content: "Done with CSS"},
+		{Role: "user", Content: "Which database tables did we add?"},
+		{Role: "assistant", Content: "Checking"},
+	}
+
+	result, err := compressor.Compress(messages)
+	if err != nil {
+		t.Fatalf("Compress: %v", err)
+	}
+	if len(result) != 3 {
+		t.Fatalf("expected retrieved context + 2 recent messages, got %d", len(result))
+	}
+	injected, _ := result[0].Content.(string)
+	if !strings.Contains(injected, "database schema") {
+		t.Errorf("expected the database turn to be retrieved, got %q", injected)
+	}
+	if strings.Contains(injected, "frontend") {
+		t.Errorf("expected the frontend turn to be left out, got %q", injected)
+	}
+	if result[1].Content != "Which database tables did we add?" {
+		t.Errorf("expected recent messages preserved, got %v", result[1].Content)
+	}
+
+	// Older chunks are embedded once; later requests only embed the query
+	first := atomic.LoadInt64(embedded)
+	if _, err := compressor.Compress(messages); err != nil {
+		t.Fatalf("Compress: %v", err)
+	}
+	if got := atomic.LoadInt64(embedded) - first; got != 1 {
+		t.Errorf("expected only the query to be embedded again, got %d inputs", got)
+	}
+}
+
+func TestContextCompressor_Preview(t *testing.T) {
+	srv, _ := newEmbeddingServer(t)
+	compressor := NewContextCompressor(&config.CompressionConfig{Enabled: false}, nil)
+	messages := []Message{
+		{Role: "user", Content: "database schema please"},
+		{Role: "assistant", Content: "here it is"},
+		{Role: "user", Content: "and the database indexes?"},
+	}
+
+	preview, err := compressor.Preview(messages, &config.CompressionConfig{
+		Strategy:        CompressionStrategyRetrieval,
+		ThresholdTokens: 5,
+		PreserveRecent:  1,
+		Retrieval:       &config.RetrievalConfig{EmbeddingURL: srv.URL, EmbeddingAPIKey: "test-key"},
+	})
+	if err != nil {
+		t.Fatalf("Preview: %v", err)
+	}
+	if !preview.WouldCompress || preview.ThresholdTokens != 5 || preview.MessagesAfter != 2 {
+		t.Errorf("preview = %+v", preview)
+	}
+	if !strings.Contains(preview.Summary, "database schema") {
+		t.Errorf("summary = %q", preview.Summary)
+	}
+	if stats := compressor.GetStats(); stats.RequestsCompressed != 0 {
+		t.Errorf("expected preview not to count in stats, got %+v", stats)
+	}
+}
+
+func TestContextCompressor_retrieve_NoEndpoint(t *testing.T) {
+	compressor := NewContextCompressor(nil, nil)
+	older := []Message{{Role: "user", Content: "hello"}}
+	recent := []Message{{Role: "user", Content: "again"}}
+	if _, err := compressor.retrieve(nil, older, recent, 1000); err == nil {
+		t.Error("expected error without an embedding endpoint")
+	}
+}
+
+func TestChunkMessages(t *testing.T) {
+	messages := []Message{
+		{Role: "user", Content: "short one"},
+		{Role: "assistant", Content: "short two"},
+		{Role: "user", Content: strings.Repeat("é", 100)},
+		{Role: "assistant", Content: ""},
+	}
+	chunks := chunkMessages(messages, 10) // 40 characters
+	if len(chunks) < 3 {
+		t.Fatalf("expected the long message to be split, got %d chunks", len(chunks))
+	}
+	if !strings.Contains(chunks[0].text, "short one") || !strings.Contains(chunks[0].text, "short two") {
+		t.Errorf("expected short messages to share a chunk, got %q", chunks[0].text)
+	}
+	for i, ch := range chunks {
+		if ch.index != i || ch.hash == "" {
+			t.Errorf("chunk %d: index %d, hash %q", i, ch.index, ch.hash)
+		}
+		if !strings.HasPrefix(ch.text, "user") && !strings.HasPrefix(ch.text, "assistant") && !strings.HasPrefix(ch.text, "é") {
+			t.Errorf("chunk %d split inside a rune: %q", i, ch.text)
+		}
+	}
+}
+
+func TestVectorIndex_SQLite(t *testing.T) {
+	dir := t.TempDir()
+	ix := openVectorIndex(dir)
+	if ix.db == nil {
+		t.Fatal("expected a SQLite-backed index")
+	}
+	ix.put("m", map[string][]float32{"a": {1, -2.5, 3}})
+	ix.close()
+
+	ix = openVectorIndex(dir)
+	defer ix.close()
+	got := ix.get("m", []string{"a", "b"})
+	if len(got) != 1 || len(got["a"]) != 3 || got["a"][1] != -2.5 {
+		t.Errorf("get = %v", got)
+	}
+	if len(ix.get("other-model", []string{"a"})) != 0 {
+		t.Error("expected embeddings to be kept per model")
+	}
+}
+
+func TestEmbeddingEndpoint(t *testing.T) {
+	tests := []struct {
+		base string
+		want string
+	}{
+		{"http://localhost:11434", "http://localhost:11434/v1/embeddings"},
+		{"https://api.openai.com/v1/", "https://api.openai.com/v1/embeddings"},
+	}
+	for _, tt := range tests {
+		got, _, err := embeddingEndpoint(&config.RetrievalConfig{EmbeddingURL: tt.base})
+		if err != nil || got != tt.want {
+			t.Errorf("embeddingEndpoint(%q) = %q, %v; want %q", tt.base, got, err, tt.want)
+		}
+	}
+
+	t.Setenv("HOME", t.TempDir())
+	config.ResetDefaultStore()
+	defer config.ResetDefaultStore()
+	if _, _, err := embeddingEndpoint(&config.RetrievalConfig{EmbeddingProvider: "missing-provider"}); err == nil {
+		t.Error("expected error for unknown provider")
+	}
+}
@@ -1,6 +1,7 @@
 package proxy
 
 import (
+	"path/filepath"
 	"testing"
 
 	"github.com/dopejs/gozen/internal/config"
@@ -438,3 +439,36 @@ func TestContextCompressor_Compress_DefaultPreserveRecent(t *testing.T) {
 		t.Error("Expected messages unchanged")
 	}
 }
+
+func TestContextCompressor_ConfigFor(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	config.ResetDefaultStore()
+	defer config.ResetDefaultStore()
+
+	project := filepath.Join(home, "monorepo")
+	if err := config.BindProject(project, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	enabled := true
+	if err := config.SetProjectCompression(project, &config.CompressionOverride{Enabled: &enabled, ThresholdTokens: 10}); err != nil {
+		t.Fatal(err)
+	}
+
+	compressor := NewContextCompressor(&config.CompressionConfig{Enabled: false, ThresholdTokens: 50000}, nil)
+	if cfg := compressor.ConfigFor(project); !cfg.Enabled || cfg.ThresholdTokens != 10 {
+		t.Errorf("ConfigFor(bound project) = %+v", cfg)
+	}
+	if cfg := compressor.ConfigFor(filepath.Join(home, "other")); cfg.Enabled {
+		t.Errorf("ConfigFor(unbound project) = %+v", cfg)
+	}
+
+	// The project's override enables compression even though it is off globally
+	body := []byte(`{"messages": [{"role": "user", "content": "this message is well over ten tokens long"}]}`)
+	if _, compressed, _ := compressor.CompressRequestBodyFor(body, ""); compressed {
+		t.Error("expected no compression without a project")
+	}
+	if _, compressed, err := compressor.CompressRequestBodyFor(body, project); err != nil || !compressed {
+		t.Errorf("expected compression for the project, got %v, %v", compressed, err)
+	}
+}
@@ -0,0 +1,106 @@
+package proxy
+
+import (
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// maxContextPoints bounds the utilization timeline kept per session.
+const maxContextPoints = 200
+
+// ContextPoint is how much of its model's context window a session's prompt
+// filled on one turn.
+type ContextPoint struct {
+	Timestamp     time.Time `json:"timestamp"`
+	Model         string    `json:"model,omitempty"`
+	ContextTokens int       `json:"context_tokens"` // prompt tokens, cached ones included
+	Window        int       `json:"window"`
+	Percent       float64   `json:"percent"`
+}
+
+// ContextUtilization is a session's context window use over its turns.
+type ContextUtilization struct {
+	SessionID   string         `json:"session_id"`
+	Current     ContextPoint   `json:"current"`
+	PeakPercent float64        `json:"peak_percent"`
+	WarnPercent int            `json:"warn_percent"`
+	Timeline    []ContextPoint `json:"timeline"` // oldest first, limited history
+}
+
+// ContextAlert is raised when a session's prompt grows past the configured
+// share of its model's context window.
+type ContextAlert struct {
+	SessionID   string `json:"session_id"`
+	WarnPercent int    `json:"warn_percent"`
+	ContextPoint
+}
+
+// contextPointFor measures a turn against its model's context window.
+func contextPointFor(turn TurnUsage) ContextPoint {
+	tokens := turn.ContextTokens
+	if tokens == 0 {
+		tokens = turn.InputTokens
+	}
+	window := config.ContextWindow(turn.Model)
+	return ContextPoint{
+		Timestamp:     turn.Timestamp,
+		Model:         turn.Model,
+		ContextTokens: tokens,
+		Window:        window,
+		Percent:       float64(tokens) / float64(window) * 100,
+	}
+}
+
+// recordContextPoint appends a point to the session's timeline and reports
+// whether it crossed warnPercent: the session's previous turn was under it,
+// or compaction brought the session back under it since the last warning.
+// The caller holds globalSessionCache.mu.
+func recordContextPoint(usage *SessionUsage, point ContextPoint, warnPercent int) bool {
+	var prev float64
+	if n := len(usage.Context); n > 0 {
+		prev = usage.Context[n-1].Percent
+	}
+	usage.Context = append(usage.Context, point)
+	if len(usage.Context) > maxContextPoints {
+		usage.Context = usage.Context[len(usage.Context)-maxContextPoints:]
+	}
+	return prev < float64(warnPercent) && point.Percent >= float64(warnPercent)
+}
+
+// OnContextWarning sets a function called when a session's context use
+// crosses alerts.context_warn_percent. It runs on the request's goroutine,
+// so it should not block.
+func OnContextWarning(fn func(ContextAlert)) {
+	globalSessionCache.mu.Lock()
+	defer globalSessionCache.mu.Unlock()
+	globalSessionCache.onContextWarning = fn
+}
+
+// GetContextUtilization returns a session's context window use over time,
+// or nil for a session not in the cache or without turns.
+func GetContextUtilization(sessionID string) *ContextUtilization {
+	globalSessionCache.mu.Lock()
+	val, ok := globalSessionCache.data.Load(sessionID)
+	if !ok {
+		globalSessionCache.mu.Unlock()
+		return nil
+	}
+	timeline := make([]ContextPoint, len(val.(*SessionUsage).Context))
+	copy(timeline, val.(*SessionUsage).Context)
+	globalSessionCache.mu.Unlock()
+
+	if len(timeline) == 0 {
+		return nil
+	}
+	u := &ContextUtilization{
+		SessionID:   sessionID,
+		Current:     timeline[len(timeline)-1],
+		WarnPercent: config.GetAlerts().GetContextWarnPercent(),
+		Timeline:    timeline,
+	}
+	for _, p := range timeline {
+		u.PeakPercent = max(u.PeakPercent, p.Percent)
+	}
+	return u
+}
@@ -0,0 +1,50 @@
+package proxy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+func TestContextUtilization(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	config.ResetDefaultStore()
+	t.Cleanup(config.ResetDefaultStore)
+	config.SetAlerts(&config.AlertConfig{ContextWarnPercent: 50})
+	config.SetContextWindows(map[string]int{"ctx-model": 1000})
+
+	globalSessionCache = &SessionCache{maxSize: defaultMaxCacheSize}
+	var alerts []ContextAlert
+	OnContextWarning(func(a ContextAlert) { alerts = append(alerts, a) })
+
+	if GetContextUtilization("ctx-session") != nil {
+		t.Fatal("unknown session should have no utilization")
+	}
+
+	// Cached prompt tokens count; a compaction and a second crossing warn again
+	for _, tokens := range []int{200, 400, 600, 700, 100, 900} {
+		AddTurnToSession("ctx-session", TurnUsage{InputTokens: 10, ContextTokens: tokens, Model: "ctx-model", Timestamp: time.Now()})
+	}
+
+	if len(alerts) != 2 || alerts[0].ContextTokens != 600 || alerts[1].ContextTokens != 900 || alerts[0].WarnPercent != 50 {
+		t.Fatalf("alerts = %+v", alerts)
+	}
+	u := GetContextUtilization("ctx-session")
+	if u == nil || len(u.Timeline) != 6 || u.Current.Percent != 90 || u.PeakPercent != 90 || u.Current.Window != 1000 {
+		t.Fatalf("utilization = %+v", u)
+	}
+
+	// Without context tokens the input tokens are used
+	AddTurnToSession("ctx-plain", TurnUsage{InputTokens: 100, Model: "ctx-model", Timestamp: time.Now()})
+	if u := GetContextUtilization("ctx-plain"); u == nil || u.Current.ContextTokens != 100 || u.Current.Percent != 10 {
+		t.Errorf("utilization from input tokens = %+v", u)
+	}
+
+	for i := 0; i < maxContextPoints+10; i++ {
+		AddTurnToSession("ctx-long", TurnUsage{InputTokens: 1, Model: "ctx-model", Timestamp: time.Now()})
+	}
+	if u := GetContextUtilization("ctx-long"); len(u.Timeline) != maxContextPoints {
+		t.Errorf("timeline length = %d, want %d", len(u.Timeline), maxContextPoints)
+	}
+}
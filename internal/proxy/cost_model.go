@@ -0,0 +1,106 @@
+package proxy
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// CostInput carries the request attributes a cost formula may bill on.
+type CostInput struct {
+	Model               string
+	InputTokens         int
+	OutputTokens        int
+	CacheCreationTokens int
+	CacheReadTokens     int
+	Duration            time.Duration
+	Batch               bool // served by a batch API at a discount
+}
+
+// CostFormula computes the USD cost of a single request.
+type CostFormula interface {
+	Cost(in CostInput) float64
+}
+
+// CostFormulaFactory builds a CostFormula from a provider's cost model config.
+type CostFormulaFactory func(cm *config.CostModel) (CostFormula, error)
+
+var (
+	costFormulasMu sync.RWMutex
+	costFormulas   = map[string]CostFormulaFactory{
+		config.CostModelPerRequest: func(cm *config.CostModel) (CostFormula, error) {
+			return perRequestCost{fee: cm.PerRequest}, nil
+		},
+		config.CostModelPerSecond: func(cm *config.CostModel) (CostFormula, error) {
+			return perSecondCost{rate: cm.PerSecond}, nil
+		},
+		config.CostModelTiered: func(cm *config.CostModel) (CostFormula, error) {
+			return tieredTokenCost{tiers: cm.Tiers}, nil
+		},
+	}
+)
+
+// RegisterCostFormula registers a factory for a cost model type,
+// replacing any existing factory with the same name.
+func RegisterCostFormula(name string, factory CostFormulaFactory) {
+	costFormulasMu.Lock()
+	defer costFormulasMu.Unlock()
+	costFormulas[name] = factory
+}
+
+// NewCostFormula builds the formula for a cost model. It returns nil for the
+// default "token" type, meaning the per-model pricing table applies.
+func NewCostFormula(cm *config.CostModel) (CostFormula, error) {
+	typ := cm.GetType()
+	if typ == config.CostModelToken {
+		return nil, nil
+	}
+	costFormulasMu.RLock()
+	factory, ok := costFormulas[typ]
+	costFormulasMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown cost model type %q", typ)
+	}
+	return factory(cm)
+}
+
+// perRequestCost charges a flat fee for every request.
+type perRequestCost struct {
+	fee float64
+}
+
+func (c perRequestCost) Cost(CostInput) float64 {
+	return c.fee
+}
+
+// perSecondCost charges for the wall-clock duration of the request.
+type perSecondCost struct {
+	rate float64
+}
+
+func (c perSecondCost) Cost(in CostInput) float64 {
+	return in.Duration.Seconds() * c.rate
+}
+
+// tieredTokenCost prices the whole request at the tier matching its input size.
+type tieredTokenCost struct {
+	tiers []config.PricingTier
+}
+
+func (c tieredTokenCost) Cost(in CostInput) float64 {
+	if len(c.tiers) == 0 {
+		return 0
+	}
+	tier := c.tiers[len(c.tiers)-1]
+	for _, t := range c.tiers {
+		if t.UpToInputTokens == 0 || in.InputTokens <= t.UpToInputTokens {
+			tier = t
+			break
+		}
+	}
+	inputCost := float64(in.InputTokens) / 1_000_000 * tier.InputPerMillion
+	outputCost := float64(in.OutputTokens) / 1_000_000 * tier.OutputPerMillion
+	return inputCost + outputCost
+}
@@ -0,0 +1,119 @@
+package proxy
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+func TestNewCostFormula(t *testing.T) {
+	tests := []struct {
+		name    string
+		cm      *config.CostModel
+		in      CostInput
+		want    float64
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "nil model uses token pricing", cm: nil, wantNil: true},
+		{name: "token type uses token pricing", cm: &config.CostModel{Type: config.CostModelToken}, wantNil: true},
+		{
+			name: "per request",
+			cm:   &config.CostModel{Type: config.CostModelPerRequest, PerRequest: 0.02},
+			in:   CostInput{InputTokens: 1_000_000, OutputTokens: 1_000_000},
+			want: 0.02,
+		},
+		{
+			name: "per second",
+			cm:   &config.CostModel{Type: config.CostModelPerSecond, PerSecond: 0.001},
+			in:   CostInput{Duration: 2500 * time.Millisecond},
+			want: 0.0025,
+		},
+		{
+			name: "tiered lower band",
+			cm: &config.CostModel{Type: config.CostModelTiered, Tiers: []config.PricingTier{
+				{UpToInputTokens: 200_000, InputPerMillion: 1.25, OutputPerMillion: 10},
+				{InputPerMillion: 2.5, OutputPerMillion: 15},
+			}},
+			in:   CostInput{InputTokens: 200_000, OutputTokens: 100_000},
+			want: 0.25 + 1.0,
+		},
+		{
+			name: "tiered unbounded band",
+			cm: &config.CostModel{Type: config.CostModelTiered, Tiers: []config.PricingTier{
+				{UpToInputTokens: 200_000, InputPerMillion: 1.25, OutputPerMillion: 10},
+				{InputPerMillion: 2.5, OutputPerMillion: 15},
+			}},
+			in:   CostInput{InputTokens: 400_000, OutputTokens: 100_000},
+			want: 1.0 + 1.5,
+		},
+		{name: "unknown type", cm: &config.CostModel{Type: "bogus"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formula, err := NewCostFormula(tt.cm)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewCostFormula() error = %v", err)
+			}
+			if tt.wantNil {
+				if formula != nil {
+					t.Errorf("expected nil formula, got %T", formula)
+				}
+				return
+			}
+			if got := formula.Cost(tt.in); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Cost() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type fixedCost float64
+
+func (c fixedCost) Cost(CostInput) float64 { return float64(c) }
+
+func TestRegisterCostFormula(t *testing.T) {
+	RegisterCostFormula("fixed-test", func(*config.CostModel) (CostFormula, error) {
+		return fixedCost(42), nil
+	})
+	defer func() {
+		costFormulasMu.Lock()
+		delete(costFormulas, "fixed-test")
+		costFormulasMu.Unlock()
+	}()
+
+	formula, err := NewCostFormula(&config.CostModel{Type: "fixed-test"})
+	if err != nil {
+		t.Fatalf("NewCostFormula() error = %v", err)
+	}
+	if got := formula.Cost(CostInput{}); got != 42 {
+		t.Errorf("Cost() = %v, want 42", got)
+	}
+}
+
+func TestUsageTracker_CalculateRequestCost(t *testing.T) {
+	tracker := &UsageTracker{
+		pricing: map[string]*config.ModelPricing{
+			"test-model": {InputPerMillion: 1.0, OutputPerMillion: 2.0},
+		},
+		providerCosts: map[string]CostFormula{
+			"flat": perRequestCost{fee: 0.5},
+		},
+	}
+
+	if got := tracker.CalculateRequestCost("flat", CostInput{Model: "test-model", InputTokens: 1_000_000, OutputTokens: 1_000_000, Duration: time.Second}); got != 0.5 {
+		t.Errorf("flat provider cost = %v, want 0.5", got)
+	}
+	if got := tracker.CalculateRequestCost("other", CostInput{Model: "test-model", InputTokens: 1_000_000, OutputTokens: 1_000_000, Duration: time.Second}); got != 3.0 {
+		t.Errorf("token-priced provider cost = %v, want 3.0", got)
+	}
+}
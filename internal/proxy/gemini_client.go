@@ -0,0 +1,211 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dopejs/gozen/internal/proxy/transform"
+)
+
+// geminiClientWriter serves a Gemini API client (such as Gemini CLI) whose
+// request was converted to Anthropic Messages: it converts the Anthropic
+// response the proxy writes back to the Gemini API format.
+type geminiClientWriter struct {
+	http.ResponseWriter
+	method      string
+	status      int
+	wroteHeader bool
+	buf         bytes.Buffer   // non-streaming response, converted on finish
+	pw          *io.PipeWriter // streaming response, converted as it arrives
+	done        chan struct{}
+}
+
+// adaptGeminiClient converts a Gemini API request for model to an Anthropic
+// Messages request in place, and returns the writer that converts the
+// response. The client's Gemini API key is moved to x-api-key, where
+// ingress auth looks for it.
+func adaptGeminiClient(w http.ResponseWriter, r *http.Request, model, method string) (*geminiClientWriter, error) {
+	switch method {
+	case transform.GeminiMethodGenerate, transform.GeminiMethodStreamGenerate, transform.GeminiMethodCountTokens:
+	default:
+		return nil, fmt.Errorf("method %s is not supported by the proxy", method)
+	}
+
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("read request body: %w", err)
+	}
+	body, err = transform.GeminiRequestToAnthropic(body, model, method == transform.GeminiMethodStreamGenerate)
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	r.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	r.Header.Set("Content-Type", "application/json")
+
+	if r.Header.Get("x-api-key") == "" {
+		key := r.Header.Get("x-goog-api-key")
+		if key == "" {
+			key = r.URL.Query().Get("key")
+		}
+		if key != "" {
+			r.Header.Set("x-api-key", key)
+		}
+	}
+	r.Header.Del("x-goog-api-key")
+	r.URL.RawQuery = ""
+	r.URL.Path = "/v1/messages"
+
+	return &geminiClientWriter{ResponseWriter: w, method: method}, nil
+}
+
+// serveCountTokens answers countTokens locally from the converted request,
+// as not every provider can count tokens.
+func (g *geminiClientWriter) serveCountTokens(r *http.Request) {
+	var body map[string]interface{}
+	data, _ := io.ReadAll(r.Body)
+	if err := json.Unmarshal(data, &body); err != nil {
+		writeGeminiError(g.ResponseWriter, http.StatusBadRequest, err.Error())
+		return
+	}
+	n, _ := calculateTokenCount(body)
+	g.ResponseWriter.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(g.ResponseWriter).Encode(map[string]interface{}{"totalTokens": n})
+}
+
+func (g *geminiClientWriter) WriteHeader(code int) {
+	if g.wroteHeader {
+		return
+	}
+	g.wroteHeader = true
+	g.status = code
+	g.Header().Del("Content-Length")
+
+	if code != http.StatusOK || !strings.Contains(g.Header().Get("Content-Type"), "text/event-stream") {
+		return
+	}
+	// Stream: convert Anthropic SSE to Gemini SSE as it is written
+	pr, pw := io.Pipe()
+	g.pw = pw
+	g.done = make(chan struct{})
+	st := &transform.StreamTransformer{
+		ClientFormat:   transform.FormatGemini,
+		ProviderFormat: transform.FormatAnthropicMessages,
+	}
+	out := st.TransformSSEStream(pr)
+	g.ResponseWriter.WriteHeader(code)
+	go func() {
+		defer close(g.done)
+		flusher, _ := g.ResponseWriter.(http.Flusher)
+		buf := make([]byte, 4096)
+		for {
+			n, err := out.Read(buf)
+			if n > 0 {
+				g.ResponseWriter.Write(buf[:n])
+				if flusher != nil {
+					flusher.Flush()
+				}
+			}
+			if err != nil {
+				// Drain so the proxy never blocks writing a stream we dropped
+				io.Copy(io.Discard, out)
+				return
+			}
+		}
+	}()
+}
+
+func (g *geminiClientWriter) Write(p []byte) (int, error) {
+	if !g.wroteHeader {
+		g.WriteHeader(http.StatusOK)
+	}
+	if g.pw != nil {
+		return g.pw.Write(p)
+	}
+	return g.buf.Write(p)
+}
+
+// Flush is a no-op: streams are flushed as they are converted, and other
+// responses when finished.
+func (g *geminiClientWriter) Flush() {}
+
+// finish ends a streamed response, or converts and writes a buffered one.
+func (g *geminiClientWriter) finish() {
+	if g.pw != nil {
+		g.pw.Close()
+		<-g.done
+		return
+	}
+	if !g.wroteHeader {
+		return
+	}
+	if g.status != http.StatusOK {
+		writeGeminiError(g.ResponseWriter, g.status, upstreamErrorMessage(g.buf.Bytes()))
+		return
+	}
+	body, err := transform.AnthropicResponseToGemini(g.buf.Bytes())
+	if err != nil {
+		writeGeminiError(g.ResponseWriter, http.StatusBadGateway, err.Error())
+		return
+	}
+	g.ResponseWriter.Header().Set("Content-Type", "application/json")
+	g.ResponseWriter.WriteHeader(http.StatusOK)
+	g.ResponseWriter.Write(body)
+}
+
+// upstreamErrorMessage extracts the message of an Anthropic or OpenAI style
+// error body, falling back to the body itself.
+func upstreamErrorMessage(body []byte) string {
+	var e struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &e) == nil && e.Error.Message != "" {
+		return e.Error.Message
+	}
+	return strings.TrimSpace(string(body))
+}
+
+// writeGeminiError writes an error in the Gemini API format.
+func writeGeminiError(w http.ResponseWriter, status int, message string) {
+	w.Header().Del("Content-Length")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    status,
+			"message": message,
+			"status":  geminiErrorStatus(status),
+		},
+	})
+}
+
+// geminiErrorStatus maps an HTTP status to a Google API error status.
+func geminiErrorStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "INVALID_ARGUMENT"
+	case http.StatusUnauthorized:
+		return "UNAUTHENTICATED"
+	case http.StatusForbidden:
+		return "PERMISSION_DENIED"
+	case http.StatusNotFound:
+		return "NOT_FOUND"
+	case http.StatusTooManyRequests:
+		return "RESOURCE_EXHAUSTED"
+	case http.StatusServiceUnavailable:
+		return "UNAVAILABLE"
+	case http.StatusGatewayTimeout:
+		return "DEADLINE_EXCEEDED"
+	default:
+		return "INTERNAL"
+	}
+}
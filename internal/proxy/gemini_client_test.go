@@ -0,0 +1,110 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+func TestProfileProxyGeminiClient(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	config.ResetDefaultStore()
+	t.Cleanup(config.ResetDefaultStore)
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("upstream path = %s, want /v1/messages", r.URL.Path)
+		}
+		var req map[string]interface{}
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &req)
+		if req["system"] != "be brief" || req["messages"] == nil || req["max_tokens"] == nil {
+			t.Errorf("upstream request not converted to Anthropic Messages: %s", body)
+		}
+		if strings.Contains(string(body), "fail") {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"type":"error","error":{"type":"invalid_request_error","message":"bad prompt"}}`))
+			return
+		}
+		if req["stream"] == true {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte("event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\",\"model\":\"claude-sonnet-4-5\",\"usage\":{\"input_tokens\":12}}}\n\n" +
+				"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Hi\"}}\n\n" +
+				"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"output_tokens\":3}}\n\n" +
+				"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"msg_1","model":"claude-sonnet-4-5","content":[{"type":"text","text":"Hi"}],"stop_reason":"end_turn","usage":{"input_tokens":12,"output_tokens":3}}`))
+	}))
+	defer backend.Close()
+	config.SetProvider("p", &config.ProviderConfig{BaseURL: backend.URL, AuthToken: "upstream-token"})
+	config.SetProfileConfig("main", &config.ProfileConfig{Providers: []string{"p"}})
+
+	pp := NewProfileProxy(discardLogger())
+	send := func(method, prompt string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/main/s1/v1beta/models/gemini-2.5-pro:"+method,
+			strings.NewReader(`{"systemInstruction":{"parts":[{"text":"be brief"}]},"contents":[{"role":"user","parts":[{"text":"`+prompt+`"}]}]}`))
+		r.Header.Set("x-goog-api-key", "zen-proxy")
+		w := httptest.NewRecorder()
+		pp.ServeHTTP(w, r)
+		return w
+	}
+
+	w := send("generateContent", "hello")
+	if w.Code != http.StatusOK {
+		t.Fatalf("generateContent: status = %d, body %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Candidates []struct {
+			Content struct {
+				Parts []struct {
+					Text string `json:"text"`
+				} `json:"parts"`
+			} `json:"content"`
+			FinishReason string `json:"finishReason"`
+		} `json:"candidates"`
+		UsageMetadata struct {
+			TotalTokenCount int `json:"totalTokenCount"`
+		} `json:"usageMetadata"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("generateContent response is not JSON: %v", err)
+	}
+	if len(resp.Candidates) != 1 || resp.Candidates[0].Content.Parts[0].Text != "Hi" ||
+		resp.Candidates[0].FinishReason != "STOP" || resp.UsageMetadata.TotalTokenCount != 15 {
+		t.Errorf("unexpected generateContent response: %s", w.Body.String())
+	}
+
+	w = send("streamGenerateContent", "hello")
+	if w.Code != http.StatusOK {
+		t.Fatalf("streamGenerateContent: status = %d", w.Code)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, `"text":"Hi"`) || !strings.Contains(body, `"finishReason":"STOP"`) ||
+		!strings.Contains(body, `"totalTokenCount":15`) || strings.Contains(body, "event:") {
+		t.Errorf("unexpected Gemini stream:\n%s", body)
+	}
+
+	w = send("generateContent", "fail")
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"status":"INVALID_ARGUMENT"`) ||
+		!strings.Contains(w.Body.String(), "bad prompt") {
+		t.Errorf("upstream error: status = %d, body %s", w.Code, w.Body.String())
+	}
+
+	w = send("countTokens", "hello")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"totalTokens"`) {
+		t.Errorf("countTokens: status = %d, body %s", w.Code, w.Body.String())
+	}
+
+	w = send("embedContent", "hello")
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"status":"INVALID_ARGUMENT"`) {
+		t.Errorf("unsupported method: status = %d, body %s", w.Code, w.Body.String())
+	}
+}
@@ -31,15 +31,37 @@ type ProviderHealthStatus struct {
 	SuccessRate  float64      `json:"success_rate"`
 	CheckCount   int          `json:"check_count"`
 	FailCount    int          `json:"fail_count"`
+
+	// Streaming metrics from recent proxied streaming requests
+	AvgTTFTMs     float64 `json:"avg_ttft_ms,omitempty"`
+	TokensPerSec  float64 `json:"tokens_per_sec,omitempty"`
+	StreamSamples int     `json:"stream_samples,omitempty"`
+
+	// Latest synthetic probe, when probes are enabled
+	LastProbe      *time.Time `json:"last_probe,omitempty"`
+	ProbeLatencyMs int        `json:"probe_latency_ms,omitempty"`
+	ProbeError     string     `json:"probe_error,omitempty"`
+}
+
+// HealthTransition is a change in a provider's health status.
+type HealthTransition struct {
+	Provider string       `json:"provider"`
+	From     HealthStatus `json:"from"`
+	To       HealthStatus `json:"to"`
+	Error    string       `json:"error,omitempty"`
 }
 
 // HealthResult represents the result of a single health check.
 type HealthResult struct {
-	Provider  string
-	Healthy   bool
-	LatencyMs int
-	Error     string
-	Timestamp time.Time
+	Provider   string
+	Healthy    bool
+	LatencyMs  int
+	Error      string
+	Timestamp  time.Time
+	Probe      bool // from a synthetic completion request
+	StatusCode int  // response status of a probe, 0 if none
+
+	unsent bool // the probe request could not be built
 }
 
 // HealthChecker performs periodic health checks on providers.
@@ -51,6 +73,9 @@ type HealthChecker struct {
 	wg       sync.WaitGroup
 	mu       sync.RWMutex
 	statuses map[string]*ProviderHealthStatus
+	streams  map[string]*streamWindow
+	breaker  ProviderBreaker
+	onChange func(HealthTransition)
 	running  bool
 	stopped  bool // tracks if stopCh has been closed
 }
@@ -70,6 +95,7 @@ func NewHealthChecker(db *LogDB) *HealthChecker {
 		client:   &http.Client{Timeout: timeout},
 		stopCh:   make(chan struct{}),
 		statuses: make(map[string]*ProviderHealthStatus),
+		streams:  make(map[string]*streamWindow),
 	}
 }
 
@@ -100,8 +126,9 @@ func (h *HealthChecker) Start() {
 	}
 
 	h.running = true
-	h.wg.Add(1)
+	h.wg.Add(2)
 	go h.checkLoop()
+	go h.probeLoop()
 }
 
 // Stop stops the health checker.
@@ -231,8 +258,6 @@ func (h *HealthChecker) CheckProvider(name string, baseURL string, proxyURL stri
 
 func (h *HealthChecker) updateStatus(result *HealthResult) {
 	h.mu.Lock()
-	defer h.mu.Unlock()
-
 	status, ok := h.statuses[result.Provider]
 	if !ok {
 		status = &ProviderHealthStatus{
@@ -247,6 +272,12 @@ func (h *HealthChecker) updateStatus(result *HealthResult) {
 	status.CheckCount++
 	status.LatencyMs = result.LatencyMs
 
+	if result.Probe {
+		status.LastProbe = &now
+		status.ProbeLatencyMs = result.LatencyMs
+		status.ProbeError = result.Error
+	}
+
 	if result.Healthy {
 		status.LastSuccess = &now
 		status.LastErrorMsg = ""
@@ -262,12 +293,33 @@ func (h *HealthChecker) updateStatus(result *HealthResult) {
 	}
 
 	// Determine overall status
+	prev := status.Status
 	status.Status = h.determineStatus(status)
+	next := status.Status
+	onChange := h.onChange
+	h.mu.Unlock()
 
 	// Record metric in database
 	if h.db != nil {
 		h.db.RecordMetric(result.Provider, result.LatencyMs, 0, !result.Healthy, false)
 	}
+
+	if onChange != nil && next != prev {
+		onChange(HealthTransition{
+			Provider: result.Provider,
+			From:     prev,
+			To:       next,
+			Error:    result.Error,
+		})
+	}
+}
+
+// OnTransition sets a function called when a provider's health status
+// changes.
+func (h *HealthChecker) OnTransition(fn func(HealthTransition)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.onChange = fn
 }
 
 func (h *HealthChecker) determineStatus(status *ProviderHealthStatus) HealthStatus {
@@ -309,13 +361,16 @@ func (h *HealthChecker) GetStatus(provider string) *ProviderHealthStatus {
 	if status, ok := h.statuses[provider]; ok {
 		// Return a copy
 		copy := *status
+		h.applyStreamMetrics(&copy)
 		return &copy
 	}
 
-	return &ProviderHealthStatus{
+	status := &ProviderHealthStatus{
 		Provider: provider,
 		Status:   HealthStatusUnknown,
 	}
+	h.applyStreamMetrics(status)
+	return status
 }
 
 // GetAllStatus returns health status for all known providers.
@@ -326,12 +381,133 @@ func (h *HealthChecker) GetAllStatus() []*ProviderHealthStatus {
 	result := make([]*ProviderHealthStatus, 0, len(h.statuses))
 	for _, status := range h.statuses {
 		copy := *status
+		h.applyStreamMetrics(&copy)
 		result = append(result, &copy)
 	}
 
+	// Providers that have only served streams since the last check
+	for provider := range h.streams {
+		if _, ok := h.statuses[provider]; ok {
+			continue
+		}
+		status := &ProviderHealthStatus{
+			Provider: provider,
+			Status:   HealthStatusUnknown,
+		}
+		h.applyStreamMetrics(status)
+		result = append(result, status)
+	}
+
 	return result
 }
 
+// --- Streaming metrics ---
+
+// streamWindowSize is how many recent streams are averaged per provider.
+const streamWindowSize = 100
+
+// minStreamSamples is how many streams a provider needs before its
+// time-to-first-token is used for least-latency selection.
+const minStreamSamples = 10
+
+// StreamMetrics summarizes recent streaming performance of a provider.
+type StreamMetrics struct {
+	AvgTTFTMs    float64 `json:"avg_ttft_ms"`
+	TokensPerSec float64 `json:"tokens_per_sec"`
+	Samples      int     `json:"samples"`
+}
+
+// streamSample is the timing of one completed stream.
+type streamSample struct {
+	ttft         time.Duration
+	tokensPerSec float64 // 0 when the stream reported no output tokens
+}
+
+// streamWindow is a ring of the most recent stream samples.
+type streamWindow struct {
+	samples []streamSample
+	next    int
+}
+
+func (w *streamWindow) add(s streamSample) {
+	if len(w.samples) < streamWindowSize {
+		w.samples = append(w.samples, s)
+		return
+	}
+	w.samples[w.next] = s
+	w.next = (w.next + 1) % streamWindowSize
+}
+
+func (w *streamWindow) metrics() StreamMetrics {
+	m := StreamMetrics{Samples: len(w.samples)}
+	if m.Samples == 0 {
+		return m
+	}
+	var ttft time.Duration
+	var rate float64
+	rated := 0
+	for _, s := range w.samples {
+		ttft += s.ttft
+		if s.tokensPerSec > 0 {
+			rate += s.tokensPerSec
+			rated++
+		}
+	}
+	m.AvgTTFTMs = float64(ttft.Microseconds()) / 1000 / float64(m.Samples)
+	if rated > 0 {
+		m.TokensPerSec = rate / float64(rated)
+	}
+	return m
+}
+
+// RecordStream records the time to first token and output rate of a
+// completed streaming response. generation is the time from the first
+// token to the end of the stream.
+func (h *HealthChecker) RecordStream(provider string, ttft time.Duration, outputTokens int, generation time.Duration) {
+	if provider == "" || ttft <= 0 {
+		return
+	}
+	sample := streamSample{ttft: ttft}
+	if outputTokens > 0 && generation > 0 {
+		sample.tokensPerSec = float64(outputTokens) / generation.Seconds()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.streams == nil {
+		h.streams = make(map[string]*streamWindow)
+	}
+	w, ok := h.streams[provider]
+	if !ok {
+		w = &streamWindow{}
+		h.streams[provider] = w
+	}
+	w.add(sample)
+}
+
+// GetStreamMetrics returns the recent streaming metrics for a provider.
+func (h *HealthChecker) GetStreamMetrics(provider string) StreamMetrics {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if w, ok := h.streams[provider]; ok {
+		return w.metrics()
+	}
+	return StreamMetrics{}
+}
+
+// applyStreamMetrics copies streaming metrics into a status. The caller
+// must hold h.mu.
+func (h *HealthChecker) applyStreamMetrics(status *ProviderHealthStatus) {
+	w, ok := h.streams[status.Provider]
+	if !ok {
+		return
+	}
+	m := w.metrics()
+	status.AvgTTFTMs = m.AvgTTFTMs
+	status.TokensPerSec = m.TokensPerSec
+	status.StreamSamples = m.Samples
+}
+
 // GetStatusFromMetrics returns health status based on historical metrics.
 func (h *HealthChecker) GetStatusFromMetrics(provider string, since time.Time) (*ProviderHealthStatus, error) {
 	if h.db == nil {
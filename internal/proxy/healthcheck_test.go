@@ -386,6 +386,32 @@ func TestHealthChecker_UpdateStatus(t *testing.T) {
 	}
 }
 
+func TestHealthChecker_OnTransition(t *testing.T) {
+	hc := &HealthChecker{
+		statuses: make(map[string]*ProviderHealthStatus),
+	}
+	var got []HealthTransition
+	hc.OnTransition(func(tr HealthTransition) { got = append(got, tr) })
+
+	check := func(healthy bool, errMsg string) {
+		hc.updateStatus(&HealthResult{Provider: "test", Healthy: healthy, Error: errMsg, Timestamp: time.Now()})
+	}
+	check(true, "")
+	check(true, "") // no change
+	check(false, "connection refused")
+	check(false, "connection refused") // no change
+
+	if len(got) != 2 {
+		t.Fatalf("got %d transitions, want 2: %+v", len(got), got)
+	}
+	if got[0].From != HealthStatusUnknown || got[0].To != HealthStatusHealthy {
+		t.Errorf("first transition = %+v", got[0])
+	}
+	if got[1].From != HealthStatusHealthy || got[1].To == HealthStatusHealthy || got[1].Error != "connection refused" {
+		t.Errorf("second transition = %+v", got[1])
+	}
+}
+
 func TestHealthChecker_CheckAllProviders(t *testing.T) {
 	// Create test servers
 	healthyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -561,3 +587,53 @@ func TestHealthChecker_MultipleStopRestart(t *testing.T) {
 		time.Sleep(50 * time.Millisecond)
 	}
 }
+
+func TestHealthChecker_StreamMetrics(t *testing.T) {
+	hc := NewHealthChecker(nil)
+
+	hc.RecordStream("p1", 200*time.Millisecond, 100, 2*time.Second)
+	hc.RecordStream("p1", 400*time.Millisecond, 300, 2*time.Second)
+	hc.RecordStream("p1", 300*time.Millisecond, 0, time.Second) // no usage reported
+	hc.RecordStream("", time.Second, 10, time.Second)           // ignored
+
+	m := hc.GetStreamMetrics("p1")
+	if m.Samples != 3 {
+		t.Errorf("Samples = %d, want 3", m.Samples)
+	}
+	if m.AvgTTFTMs != 300 {
+		t.Errorf("AvgTTFTMs = %v, want 300", m.AvgTTFTMs)
+	}
+	if m.TokensPerSec != 100 {
+		t.Errorf("TokensPerSec = %v, want 100", m.TokensPerSec)
+	}
+
+	// Streams are reported even before the first health check
+	all := hc.GetAllStatus()
+	if len(all) != 1 || all[0].Provider != "p1" || all[0].StreamSamples != 3 {
+		t.Fatalf("GetAllStatus = %+v, want p1 with 3 stream samples", all)
+	}
+	if all[0].Status != HealthStatusUnknown {
+		t.Errorf("Status = %s, want unknown", all[0].Status)
+	}
+	if got := hc.GetStatus("p1"); got.AvgTTFTMs != 300 {
+		t.Errorf("GetStatus AvgTTFTMs = %v, want 300", got.AvgTTFTMs)
+	}
+}
+
+func TestHealthChecker_StreamMetricsWindow(t *testing.T) {
+	hc := NewHealthChecker(nil)
+	for i := 0; i < streamWindowSize; i++ {
+		hc.RecordStream("p1", time.Second, 0, 0)
+	}
+	for i := 0; i < streamWindowSize; i++ {
+		hc.RecordStream("p1", 100*time.Millisecond, 0, 0)
+	}
+
+	m := hc.GetStreamMetrics("p1")
+	if m.Samples != streamWindowSize {
+		t.Errorf("Samples = %d, want %d", m.Samples, streamWindowSize)
+	}
+	if m.AvgTTFTMs != 100 {
+		t.Errorf("AvgTTFTMs = %v, want 100 after older samples rotate out", m.AvgTTFTMs)
+	}
+}
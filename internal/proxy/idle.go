@@ -0,0 +1,17 @@
+package proxy
+
+// SetIdle releases the databases the proxy keeps open while zend sleeps:
+// the request log database closes its connections between uses and the
+// compression index is closed until it is next used. SetIdle(false)
+// restores connection pooling when zend wakes.
+func SetIdle(idle bool) {
+	if ldb := GetGlobalLogDB(); ldb != nil {
+		ldb.SetIdle(idle)
+	}
+	if !idle {
+		return
+	}
+	if c := GetGlobalCompressor(); c != nil {
+		c.closeIndex()
+	}
+}
@@ -0,0 +1,125 @@
+package proxy
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// userHeader carries the user label resolved from the client's ingress key
+// from ProfileProxy to ProxyServer. Clients cannot set it themselves.
+const userHeader = "X-Zen-User"
+
+// tagsHeader carries comma-separated tags a client attaches to a request,
+// e.g. "ci,nightly", so its usage can be filtered and grouped by tag.
+const tagsHeader = "X-Zen-Tags"
+
+// Limits on the tags accepted from a single request.
+const (
+	maxRequestTags = 16
+	maxTagLength   = 64
+)
+
+// parseTags splits a tags header value into trimmed, lowercased, unique
+// tags. Empty and over-long tags are dropped and at most maxRequestTags are
+// kept.
+func parseTags(value string) []string {
+	var tags []string
+	for _, tag := range strings.Split(value, ",") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || len(tag) > maxTagLength {
+			continue
+		}
+		dup := false
+		for _, t := range tags {
+			if t == tag {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			tags = append(tags, tag)
+		}
+		if len(tags) == maxRequestTags {
+			break
+		}
+	}
+	return tags
+}
+
+// ingressKey returns the API key a client sent, from x-api-key or an
+// Authorization bearer token.
+func ingressKey(h http.Header) string {
+	if key := strings.TrimSpace(h.Get("x-api-key")); key != "" {
+		return key
+	}
+	auth := strings.TrimSpace(h.Get("Authorization"))
+	if len(auth) > 7 && strings.EqualFold(auth[:7], "bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	return ""
+}
+
+// resolveIngressUser returns the user label of the ingress key sent with a
+// request, or an empty label for an unknown key. When keys are required an
+// unknown or missing key is an error.
+func resolveIngressUser(cfg *config.IngressConfig, h http.Header) (string, error) {
+	if cfg == nil {
+		return "", nil
+	}
+	if k := matchIngressKey(cfg, ingressKey(h)); k != nil {
+		return k.User, nil
+	}
+	if cfg.Required {
+		return "", fmt.Errorf("a valid zen API key is required")
+	}
+	return "", nil
+}
+
+// RequestAPIKey returns the API key a request carries, from x-api-key or an
+// Authorization bearer token.
+func RequestAPIKey(h http.Header) string {
+	return ingressKey(h)
+}
+
+// IsIngressKey reports whether key is one of the configured ingress keys.
+// It lets the daemon's other client-facing endpoints take the keys the
+// proxy takes.
+func IsIngressKey(key string) bool {
+	return matchIngressKey(config.GetIngress(), key) != nil
+}
+
+// matchIngressKey returns the configured ingress key equal to key, if any.
+func matchIngressKey(cfg *config.IngressConfig, key string) *config.IngressKey {
+	if cfg == nil || key == "" {
+		return nil
+	}
+	for _, k := range cfg.Keys {
+		if k != nil && k.Key != "" && subtle.ConstantTimeCompare([]byte(k.Key), []byte(key)) == 1 {
+			return k
+		}
+	}
+	return nil
+}
+
+// usageAttribution identifies who a request's usage is recorded against.
+type usageAttribution struct {
+	User string
+	Tags []string
+}
+
+type usageAttributionKey struct{}
+
+func withUsageAttribution(ctx context.Context, a usageAttribution) context.Context {
+	return context.WithValue(ctx, usageAttributionKey{}, a)
+}
+
+// requestAttribution returns the attribution stored in a request's context.
+func requestAttribution(r *http.Request) usageAttribution {
+	a, _ := r.Context().Value(usageAttributionKey{}).(usageAttribution)
+	return a
+}
@@ -0,0 +1,126 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+func TestResolveIngressUser(t *testing.T) {
+	cfg := &config.IngressConfig{Keys: []*config.IngressKey{
+		{ID: "a1", Key: "zen-alice", User: "alice"},
+		{ID: "b2", Key: "zen-bob", User: "bob"},
+	}}
+	tests := []struct {
+		name     string
+		cfg      *config.IngressConfig
+		headers  map[string]string
+		wantUser string
+		wantErr  bool
+	}{
+		{"no config", nil, map[string]string{"x-api-key": "zen-alice"}, "", false},
+		{"x-api-key", cfg, map[string]string{"x-api-key": "zen-alice"}, "alice", false},
+		{"bearer token", cfg, map[string]string{"Authorization": "Bearer zen-bob"}, "bob", false},
+		{"unknown key", cfg, map[string]string{"x-api-key": "zen-proxy"}, "", false},
+		{"missing key", cfg, nil, "", false},
+		{"required, unknown key", &config.IngressConfig{Required: true, Keys: cfg.Keys}, map[string]string{"x-api-key": "zen-proxy"}, "", true},
+		{"required, valid key", &config.IngressConfig{Required: true, Keys: cfg.Keys}, map[string]string{"Authorization": "bearer zen-alice"}, "alice", false},
+	}
+	for _, tt := range tests {
+		h := http.Header{}
+		for k, v := range tt.headers {
+			h.Set(k, v)
+		}
+		user, err := resolveIngressUser(tt.cfg, h)
+		if user != tt.wantUser || (err != nil) != tt.wantErr {
+			t.Errorf("%s: resolveIngressUser() = %q, %v; want %q, error %v", tt.name, user, err, tt.wantUser, tt.wantErr)
+		}
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{"", nil},
+		{"ci", []string{"ci"}},
+		{" CI , nightly,,ci ", []string{"ci", "nightly"}},
+		{strings.Repeat("x", maxTagLength+1) + ",ok", []string{"ok"}},
+	}
+	for _, tt := range tests {
+		if got := parseTags(tt.value); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("parseTags(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+	many := make([]string, maxRequestTags+5)
+	for i := range many {
+		many[i] = "t" + strconv.Itoa(i)
+	}
+	if got := parseTags(strings.Join(many, ",")); len(got) != maxRequestTags {
+		t.Errorf("expected at most %d tags, got %d", maxRequestTags, len(got))
+	}
+}
+
+func TestProfileProxyIngressKeys(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	config.ResetDefaultStore()
+	t.Cleanup(config.ResetDefaultStore)
+
+	db, err := OpenLogDB(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenLogDB() error: %v", err)
+	}
+	defer db.Close()
+	defer func(prev *UsageTracker) { globalUsageTracker = prev }(globalUsageTracker)
+	InitGlobalUsageTracker(db)
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(userHeader) != "" || r.Header.Get(tagsHeader) != "" || r.Header.Get("x-api-key") != "upstream-token" {
+			t.Error("ingress key, user label and tags should not be forwarded upstream")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"msg_1","content":[],"usage":{"input_tokens":10,"output_tokens":5}}`))
+	}))
+	defer backend.Close()
+	config.SetProvider("p", &config.ProviderConfig{BaseURL: backend.URL, AuthToken: "upstream-token"})
+	config.SetProfileConfig("main", &config.ProfileConfig{Providers: []string{"p"}})
+	config.SetIngress(&config.IngressConfig{
+		Required: true,
+		Keys:     []*config.IngressKey{{ID: "a1", Key: "zen-alice", User: "alice", CreatedAt: time.Now()}},
+	})
+
+	pp := NewProfileProxy(discardLogger())
+	send := func(key, spoofedUser string) int {
+		r := httptest.NewRequest("POST", "/main/s1/v1/messages",
+			strings.NewReader(`{"model":"claude-sonnet-4-5","messages":[{"role":"user","content":"hi"}],"max_tokens":10}`))
+		r.Header.Set("x-api-key", key)
+		r.Header.Set(tagsHeader, "ci")
+		if spoofedUser != "" {
+			r.Header.Set(userHeader, spoofedUser)
+		}
+		w := httptest.NewRecorder()
+		pp.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := send("zen-proxy", "mallory"); code != http.StatusUnauthorized {
+		t.Fatalf("unknown key: status = %d, want 401", code)
+	}
+	if code := send("zen-alice", "mallory"); code != http.StatusOK {
+		t.Fatalf("valid key: status = %d, want 200", code)
+	}
+
+	entries, err := GetGlobalUsageTracker().GetRecentUsage(10)
+	if err != nil {
+		t.Fatalf("GetRecentUsage() error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].User != "alice" || len(entries[0].Tags) != 1 || entries[0].Tags[0] != "ci" {
+		t.Fatalf("expected one ci-tagged usage row for alice, got %+v", entries)
+	}
+}
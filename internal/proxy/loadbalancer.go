@@ -67,7 +67,10 @@ func (lb *LoadBalancer) Select(providers []*Provider, strategy config.LoadBalanc
 		result = lb.selectLeastLatency(providers)
 		if len(result) > 0 {
 			metrics := lb.getMetricsCache()
-			if m, ok := metrics[result[0].Name]; ok {
+			if streams := streamMetricsFor(providers); streams != nil {
+				m := streams[result[0].Name]
+				reason = fmt.Sprintf("lowest TTFT: %.2fms (%.1f tok/s)", m.AvgTTFTMs, m.TokensPerSec)
+			} else if m, ok := metrics[result[0].Name]; ok {
 				reason = fmt.Sprintf("lowest latency: %.2fms", m.AvgLatencyMs)
 			} else {
 				reason = "insufficient samples, using configured order"
@@ -216,8 +219,11 @@ func (lb *LoadBalancer) selectRoundRobin(providers []*Provider, profile string)
 }
 
 // selectLeastLatency orders providers by average latency (lowest first).
+// When every provider has enough recent streams, time to first token is
+// used instead, since streaming responses record no total latency.
 func (lb *LoadBalancer) selectLeastLatency(providers []*Provider) []*Provider {
 	metrics := lb.getMetricsCache()
+	streams := streamMetricsFor(providers)
 
 	// Create a copy with latency info
 	type providerLatency struct {
@@ -234,7 +240,9 @@ func (lb *LoadBalancer) selectLeastLatency(providers []*Provider) []*Provider {
 			healthy:  p.IsHealthy(),
 		}
 
-		if m, ok := metrics[p.Name]; ok && m.TotalRequests > 0 {
+		if streams != nil {
+			items[i].latency = streams[p.Name].AvgTTFTMs
+		} else if m, ok := metrics[p.Name]; ok && m.TotalRequests > 0 {
 			items[i].latency = m.AvgLatencyMs
 		}
 	}
@@ -270,6 +278,25 @@ func (lb *LoadBalancer) selectLeastLatency(providers []*Provider) []*Provider {
 	return result
 }
 
+// streamMetricsFor returns the streaming metrics of each provider, or nil
+// unless all of them have at least minStreamSamples streams so TTFT is
+// compared like for like.
+func streamMetricsFor(providers []*Provider) map[string]StreamMetrics {
+	checker := GetGlobalHealthChecker()
+	if checker == nil {
+		return nil
+	}
+	result := make(map[string]StreamMetrics, len(providers))
+	for _, p := range providers {
+		m := checker.GetStreamMetrics(p.Name)
+		if m.Samples < minStreamSamples {
+			return nil
+		}
+		result[p.Name] = m
+	}
+	return result
+}
+
 // selectLeastCost orders providers by cost for the given model (lowest first).
 // modelOverrides maps provider name → override model (from scenario routes).
 func (lb *LoadBalancer) selectLeastCost(providers []*Provider, model string, modelOverrides map[string]string) []*Provider {
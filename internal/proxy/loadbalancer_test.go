@@ -1192,3 +1192,33 @@ func TestLoadBalancer_PerScenarioStrategy(t *testing.T) {
 // T047: Test per-scenario weights (already covered by existing weighted tests)
 // The existing TestLoadBalancer_Select_Weighted* tests cover this functionality
 
+
+func TestLoadBalancer_SelectLeastLatencyPrefersTTFT(t *testing.T) {
+	oldGlobal := globalHealthChecker
+	defer func() { globalHealthChecker = oldGlobal }()
+	globalHealthChecker = NewHealthChecker(nil)
+
+	lb := NewLoadBalancer(nil)
+	providers := []*Provider{
+		{Name: "p1", Healthy: true},
+		{Name: "p2", Healthy: true},
+	}
+
+	// Too few streams on p2: configured order is kept
+	for i := 0; i < minStreamSamples; i++ {
+		globalHealthChecker.RecordStream("p1", 500*time.Millisecond, 100, time.Second)
+	}
+	globalHealthChecker.RecordStream("p2", 100*time.Millisecond, 100, time.Second)
+	result := lb.Select(providers, config.LoadBalanceLeastLatency, "", "", nil, nil)
+	if result[0].Name != "p1" {
+		t.Errorf("first = %s, want p1 with insufficient stream samples", result[0].Name)
+	}
+
+	for i := 1; i < minStreamSamples; i++ {
+		globalHealthChecker.RecordStream("p2", 100*time.Millisecond, 100, time.Second)
+	}
+	result = lb.Select(providers, config.LoadBalanceLeastLatency, "", "", nil, nil)
+	if result[0].Name != "p2" || result[1].Name != "p1" {
+		t.Errorf("order = [%s, %s], want [p2, p1] by TTFT", result[0].Name, result[1].Name)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -15,13 +16,29 @@ import (
 //   v1: original schema (logs table with basic fields)
 //   v2: add session_id and client_type columns + indexes
 //   v3: add usage, provider_metrics, usage_hourly tables for v2.2 observability
-const currentSchemaVersion = 3
+//   v4: add prompt cache creation/read token columns to usage
+//   v5: add request_bodies table for debug body capture
+//   v6: add user_label column to usage for per-user attribution
+//   v7: add usage_tags table for tag-based attribution
+//   v8: add batch flag and cost breakdown columns to usage
+//   v9: replace usage_hourly with incrementally maintained hourly/daily rollups
+//   v10: add audit_log table for web API changes
+//   v11: add annotations table for session and log entry labels and notes
+const currentSchemaVersion = 11
 
 // migrations is an ordered list of schema upgrade functions.
 // migrations[0] upgrades v1 → v2, migrations[1] upgrades v2 → v3, etc.
 var migrations = []func(tx *sql.Tx) error{
 	migrateV1ToV2,
 	migrateV2ToV3,
+	migrateV3ToV4,
+	migrateV4ToV5,
+	migrateV5ToV6,
+	migrateV6ToV7,
+	migrateV7ToV8,
+	migrateV8ToV9,
+	migrateV9ToV10,
+	migrateV10ToV11,
 }
 
 // LogDB provides SQLite-backed log storage with batched writes.
@@ -176,12 +193,26 @@ func initFreshSchema(db *sql.DB) error {
 			cost_usd      REAL NOT NULL,
 			latency_ms    INTEGER DEFAULT 0,
 			project_path  TEXT DEFAULT '',
-			client_type   TEXT DEFAULT ''
+			client_type   TEXT DEFAULT '',
+			cache_creation_tokens INTEGER DEFAULT 0,
+			cache_read_tokens     INTEGER DEFAULT 0,
+			user_label            TEXT DEFAULT '',
+			batch                 INTEGER DEFAULT 0,
+			input_cost_usd        REAL DEFAULT 0,
+			output_cost_usd       REAL DEFAULT 0,
+			cache_write_cost_usd  REAL DEFAULT 0,
+			cache_read_cost_usd   REAL DEFAULT 0,
+			batch_discount_usd    REAL DEFAULT 0
 		)
 	`); err != nil {
 		return fmt.Errorf("create usage table: %w", err)
 	}
 
+	// Create usage_tags table for tags sent with X-Zen-Tags
+	if _, err := db.Exec(usageTagsTable); err != nil {
+		return fmt.Errorf("create usage_tags table: %w", err)
+	}
+
 	// Create provider_metrics table for health monitoring
 	if _, err := db.Exec(`
 		CREATE TABLE IF NOT EXISTS provider_metrics (
@@ -197,22 +228,39 @@ func initFreshSchema(db *sql.DB) error {
 		return fmt.Errorf("create provider_metrics table: %w", err)
 	}
 
-	// Create usage_hourly table for aggregated dashboard data
+	// Create rollup tables for the usage charts and summaries
+	for _, stmt := range usageRollupSchema {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("create usage rollups: %w", err)
+		}
+	}
+
+	// Create request_bodies table for opt-in debug body capture
 	if _, err := db.Exec(`
-		CREATE TABLE IF NOT EXISTS usage_hourly (
-			id            INTEGER PRIMARY KEY AUTOINCREMENT,
-			hour          DATETIME NOT NULL,
-			provider      TEXT NOT NULL,
-			model         TEXT NOT NULL,
-			project_path  TEXT DEFAULT '',
-			total_input   INTEGER NOT NULL,
-			total_output  INTEGER NOT NULL,
-			total_cost    REAL NOT NULL,
-			request_count INTEGER NOT NULL,
-			UNIQUE(hour, provider, model, project_path)
+		CREATE TABLE IF NOT EXISTS request_bodies (
+			id                 INTEGER PRIMARY KEY AUTOINCREMENT,
+			request_id         TEXT NOT NULL,
+			timestamp          DATETIME NOT NULL,
+			provider           TEXT DEFAULT '',
+			session_id         TEXT DEFAULT '',
+			status_code        INTEGER DEFAULT 0,
+			request_body       TEXT DEFAULT '',
+			response_body      TEXT DEFAULT '',
+			request_truncated  INTEGER DEFAULT 0,
+			response_truncated INTEGER DEFAULT 0
 		)
 	`); err != nil {
-		return fmt.Errorf("create usage_hourly table: %w", err)
+		return fmt.Errorf("create request_bodies table: %w", err)
+	}
+
+	// Create audit_log table for changes made through the web API
+	if _, err := db.Exec(auditLogTable); err != nil {
+		return fmt.Errorf("create audit_log table: %w", err)
+	}
+
+	// Create annotations table for labels and notes on sessions and logs
+	if _, err := db.Exec(annotationsTable); err != nil {
+		return fmt.Errorf("create annotations table: %w", err)
 	}
 
 	for _, idx := range []string{
@@ -225,9 +273,12 @@ func initFreshSchema(db *sql.DB) error {
 		"CREATE INDEX IF NOT EXISTS idx_usage_session_id ON usage(session_id)",
 		"CREATE INDEX IF NOT EXISTS idx_usage_provider ON usage(provider)",
 		"CREATE INDEX IF NOT EXISTS idx_usage_project_path ON usage(project_path)",
+		"CREATE INDEX IF NOT EXISTS idx_usage_user_label ON usage(user_label)",
+		"CREATE INDEX IF NOT EXISTS idx_usage_tags_tag ON usage_tags(tag)",
 		"CREATE INDEX IF NOT EXISTS idx_provider_metrics_timestamp ON provider_metrics(timestamp)",
 		"CREATE INDEX IF NOT EXISTS idx_provider_metrics_provider ON provider_metrics(provider)",
-		"CREATE INDEX IF NOT EXISTS idx_usage_hourly_hour ON usage_hourly(hour)",
+		"CREATE INDEX IF NOT EXISTS idx_request_bodies_request_id ON request_bodies(request_id)",
+		"CREATE INDEX IF NOT EXISTS idx_audit_log_timestamp ON audit_log(timestamp)",
 	} {
 		if _, err := db.Exec(idx); err != nil {
 			return fmt.Errorf("create index: %w", err)
@@ -330,6 +381,123 @@ func migrateV2ToV3(tx *sql.Tx) error {
 	return nil
 }
 
+// migrateV3ToV4 adds prompt cache token columns to the usage table.
+func migrateV3ToV4(tx *sql.Tx) error {
+	for _, stmt := range []string{
+		"ALTER TABLE usage ADD COLUMN cache_creation_tokens INTEGER DEFAULT 0",
+		"ALTER TABLE usage ADD COLUMN cache_read_tokens INTEGER DEFAULT 0",
+	} {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateV4ToV5 adds the request_bodies table for debug body capture.
+func migrateV4ToV5(tx *sql.Tx) error {
+	if _, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS request_bodies (
+			id                 INTEGER PRIMARY KEY AUTOINCREMENT,
+			request_id         TEXT NOT NULL,
+			timestamp          DATETIME NOT NULL,
+			provider           TEXT DEFAULT '',
+			session_id         TEXT DEFAULT '',
+			status_code        INTEGER DEFAULT 0,
+			request_body       TEXT DEFAULT '',
+			response_body      TEXT DEFAULT '',
+			request_truncated  INTEGER DEFAULT 0,
+			response_truncated INTEGER DEFAULT 0
+		)
+	`); err != nil {
+		return err
+	}
+	_, err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_request_bodies_request_id ON request_bodies(request_id)")
+	return err
+}
+
+// migrateV5ToV6 adds the user_label column to usage.
+func migrateV5ToV6(tx *sql.Tx) error {
+	for _, stmt := range []string{
+		"ALTER TABLE usage ADD COLUMN user_label TEXT DEFAULT ''",
+		"CREATE INDEX IF NOT EXISTS idx_usage_user_label ON usage(user_label)",
+	} {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// usageTagsTable holds the tags of each usage row, one row per tag.
+const usageTagsTable = `
+	CREATE TABLE IF NOT EXISTS usage_tags (
+		usage_id INTEGER NOT NULL,
+		tag      TEXT NOT NULL,
+		PRIMARY KEY (usage_id, tag)
+	)
+`
+
+// migrateV6ToV7 adds the usage_tags table.
+func migrateV6ToV7(tx *sql.Tx) error {
+	for _, stmt := range []string{
+		usageTagsTable,
+		"CREATE INDEX IF NOT EXISTS idx_usage_tags_tag ON usage_tags(tag)",
+	} {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateV7ToV8 adds the batch flag and cost breakdown columns to usage.
+func migrateV7ToV8(tx *sql.Tx) error {
+	for _, stmt := range []string{
+		"ALTER TABLE usage ADD COLUMN batch INTEGER DEFAULT 0",
+		"ALTER TABLE usage ADD COLUMN input_cost_usd REAL DEFAULT 0",
+		"ALTER TABLE usage ADD COLUMN output_cost_usd REAL DEFAULT 0",
+		"ALTER TABLE usage ADD COLUMN cache_write_cost_usd REAL DEFAULT 0",
+		"ALTER TABLE usage ADD COLUMN cache_read_cost_usd REAL DEFAULT 0",
+		"ALTER TABLE usage ADD COLUMN batch_discount_usd REAL DEFAULT 0",
+	} {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateV8ToV9 replaces usage_hourly with the hourly and daily rollups,
+// backfilled from the usage rows. Hours kept only in usage_hourly, after
+// their rows were compacted away, are carried over as they are.
+func migrateV8ToV9(tx *sql.Tx) error {
+	stmts := append([]string{}, usageRollupSchema...)
+	stmts = append(stmts,
+		`INSERT INTO usage_rollup_hourly (bucket, provider, model, project_path, user_label, input_tokens, output_tokens, cache_creation_tokens, cache_read_tokens, cost_usd, request_count)
+		SELECT strftime('%Y-%m-%d %H:00:00', timestamp) AS bucket, provider, model, project_path, COALESCE(user_label, ''),
+			SUM(input_tokens), SUM(output_tokens), COALESCE(SUM(cache_creation_tokens), 0), COALESCE(SUM(cache_read_tokens), 0), SUM(cost_usd), COUNT(*)
+		FROM usage
+		GROUP BY bucket, provider, model, project_path, COALESCE(user_label, '')`,
+		`INSERT OR IGNORE INTO usage_rollup_hourly (bucket, provider, model, project_path, user_label, input_tokens, output_tokens, cache_creation_tokens, cache_read_tokens, cost_usd, request_count)
+		SELECT hour, provider, model, project_path, '', total_input, total_output, 0, 0, total_cost, request_count
+		FROM usage_hourly
+		WHERE hour NOT IN (SELECT DISTINCT bucket FROM usage_rollup_hourly)`,
+		`INSERT INTO usage_rollup_daily (bucket, provider, model, project_path, user_label, input_tokens, output_tokens, cache_creation_tokens, cache_read_tokens, cost_usd, request_count)
+		SELECT substr(bucket, 1, 10) AS day, provider, model, project_path, user_label,
+			SUM(input_tokens), SUM(output_tokens), SUM(cache_creation_tokens), SUM(cache_read_tokens), SUM(cost_usd), SUM(request_count)
+		FROM usage_rollup_hourly
+		GROUP BY day, provider, model, project_path, user_label`,
+		"DROP TABLE IF EXISTS usage_hourly",
+	)
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // --- Schema version helpers ---
 
 func getSchemaVersion(db *sql.DB) int {
@@ -439,6 +607,73 @@ func (ldb *LogDB) flushBatch(batch []LogEntry) {
 
 // Query returns log entries matching the filter, newest first.
 func (ldb *LogDB) Query(filter LogFilter) ([]LogEntry, error) {
+	entries, _, err := ldb.QueryPage(filter, Page{Limit: filter.Limit})
+	return entries, err
+}
+
+// QueryPage returns a page of the log entries matching the filter, newest
+// first, and whether there are more past it in the direction paged.
+func (ldb *LogDB) QueryPage(filter LogFilter, page Page) ([]LogEntry, bool, error) {
+	conditions, args := page.where(logConditions(filter))
+	query := "SELECT id, timestamp, level, provider, message, status_code, method, path, error, response_body, session_id, client_type FROM logs"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	order, limit := page.orderLimit()
+	query += order
+	args = append(args, limit)
+
+	rows, err := ldb.db.Query(query, args...)
+	if err != nil {
+		return nil, false, fmt.Errorf("query logs: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []LogEntry
+	for rows.Next() {
+		var e LogEntry
+		var tsStr string
+		var level string
+		if err := rows.Scan(&e.ID, &tsStr, &level, &e.Provider, &e.Message, &e.StatusCode, &e.Method, &e.Path, &e.Error, &e.ResponseBody, &e.SessionID, &e.ClientType); err != nil {
+			continue
+		}
+		e.Level = LogLevel(level)
+		if t, err := time.Parse(time.RFC3339Nano, tsStr); err == nil {
+			e.Timestamp = t
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	entries, more := trimPage(page, entries)
+	annotations, err := ldb.logAnnotations(entries)
+	if err != nil {
+		return nil, false, err
+	}
+	for i := range entries {
+		entries[i].Annotation = annotations[strconv.FormatInt(entries[i].ID, 10)]
+	}
+	return entries, more, nil
+}
+
+// Count returns the number of log entries matching the filter.
+func (ldb *LogDB) Count(filter LogFilter) (int, error) {
+	conditions, args := logConditions(filter)
+	query := "SELECT COUNT(*) FROM logs"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	var n int
+	if err := ldb.db.QueryRow(query, args...).Scan(&n); err != nil {
+		return 0, fmt.Errorf("count logs: %w", err)
+	}
+	return n, nil
+}
+
+// logConditions returns the WHERE conditions selecting the log entries
+// matching the filter.
+func logConditions(filter LogFilter) ([]string, []interface{}) {
 	var conditions []string
 	var args []interface{}
 
@@ -474,42 +709,14 @@ func (ldb *LogDB) Query(filter LogFilter) ([]LogEntry, error) {
 		conditions = append(conditions, "client_type = ?")
 		args = append(args, filter.ClientType)
 	}
-
-	query := "SELECT timestamp, level, provider, message, status_code, method, path, error, response_body, session_id, client_type FROM logs"
-	if len(conditions) > 0 {
-		query += " WHERE " + strings.Join(conditions, " AND ")
-	}
-	query += " ORDER BY timestamp DESC"
-
-	limit := filter.Limit
-	if limit <= 0 {
-		limit = 100
+	if !filter.Since.IsZero() {
+		conditions = append(conditions, "timestamp >= ?")
+		args = append(args, filter.Since.UTC().Format(time.RFC3339Nano))
 	}
-	query += " LIMIT ?"
-	args = append(args, limit)
-
-	rows, err := ldb.db.Query(query, args...)
-	if err != nil {
-		return nil, fmt.Errorf("query logs: %w", err)
-	}
-	defer rows.Close()
-
-	var entries []LogEntry
-	for rows.Next() {
-		var e LogEntry
-		var tsStr string
-		var level string
-		if err := rows.Scan(&tsStr, &level, &e.Provider, &e.Message, &e.StatusCode, &e.Method, &e.Path, &e.Error, &e.ResponseBody, &e.SessionID, &e.ClientType); err != nil {
-			continue
-		}
-		e.Level = LogLevel(level)
-		if t, err := time.Parse(time.RFC3339Nano, tsStr); err == nil {
-			e.Timestamp = t
-		}
-		entries = append(entries, e)
-	}
-
-	return entries, rows.Err()
+	annConds, annArgs := annotationConditions(filter.Annotation)
+	conditions = append(conditions, annConds...)
+	args = append(args, annArgs...)
+	return conditions, args
 }
 
 // GetProviders returns distinct provider names from the log database.
@@ -537,3 +744,17 @@ func (ldb *LogDB) Close() error {
 	<-ldb.done
 	return ldb.db.Close()
 }
+
+// defaultMaxIdleConns is database/sql's default number of idle connections.
+const defaultMaxIdleConns = 2
+
+// SetIdle closes the database's idle connections and, while idle, closes
+// each connection once it is returned, so the database files are not held
+// open between uses. SetIdle(false) restores the default pooling.
+func (ldb *LogDB) SetIdle(idle bool) {
+	if idle {
+		ldb.db.SetMaxIdleConns(0)
+	} else {
+		ldb.db.SetMaxIdleConns(defaultMaxIdleConns)
+	}
+}
@@ -2,8 +2,10 @@ package proxy
 
 import (
 	"database/sql"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -124,6 +126,67 @@ func TestLogDBNewestFirst(t *testing.T) {
 	}
 }
 
+func TestLogDBQueryPage(t *testing.T) {
+	dir := t.TempDir()
+	db, err := OpenLogDB(dir)
+	if err != nil {
+		t.Fatalf("OpenLogDB: %v", err)
+	}
+	defer db.Close()
+
+	for i := 1; i <= 5; i++ {
+		db.Insert(LogEntry{Timestamp: time.Now(), Level: LogLevelInfo, Provider: "p", Message: fmt.Sprintf("m%d", i)})
+	}
+	db.Insert(LogEntry{Timestamp: time.Now(), Level: LogLevelInfo, Provider: "other", Message: "skip"})
+	time.Sleep(700 * time.Millisecond)
+
+	messages := func(entries []LogEntry) string {
+		var m []string
+		for _, e := range entries {
+			m = append(m, e.Message)
+		}
+		return strings.Join(m, ",")
+	}
+	filter := LogFilter{Provider: "p"}
+
+	first, more, err := db.QueryPage(filter, Page{Limit: 2})
+	if err != nil {
+		t.Fatalf("QueryPage: %v", err)
+	}
+	if got := messages(first); got != "m5,m4" || !more {
+		t.Fatalf("first page = %s (more %v), want m5,m4 with more", got, more)
+	}
+
+	second, more, err := db.QueryPage(filter, Page{Limit: 2, Before: first[1].ID})
+	if err != nil {
+		t.Fatalf("QueryPage: %v", err)
+	}
+	if got := messages(second); got != "m3,m2" || !more {
+		t.Fatalf("second page = %s (more %v), want m3,m2 with more", got, more)
+	}
+
+	last, more, err := db.QueryPage(filter, Page{Limit: 2, Before: second[1].ID})
+	if err != nil {
+		t.Fatalf("QueryPage: %v", err)
+	}
+	if got := messages(last); got != "m1" || more {
+		t.Fatalf("last page = %s (more %v), want m1 without more", got, more)
+	}
+
+	// Paging back towards the newest entries keeps them newest first
+	newer, more, err := db.QueryPage(filter, Page{Limit: 2, After: last[0].ID})
+	if err != nil {
+		t.Fatalf("QueryPage: %v", err)
+	}
+	if got := messages(newer); got != "m3,m2" || !more {
+		t.Errorf("newer page = %s (more %v), want m3,m2 with more", got, more)
+	}
+
+	if n, err := db.Count(filter); err != nil || n != 5 {
+		t.Errorf("Count = %d, %v; want 5", n, err)
+	}
+}
+
 func TestLogDBGetProviders(t *testing.T) {
 	dir := t.TempDir()
 	db, err := OpenLogDB(dir)
@@ -404,6 +467,11 @@ func TestSchemaVersionSetAfterMigration(t *testing.T) {
 	if ver != currentSchemaVersion {
 		t.Errorf("migrated DB version = %d, want %d", ver, currentSchemaVersion)
 	}
+
+	// v3 → v4 adds the cache token columns to usage
+	if _, err := rawDB.Exec("SELECT cache_creation_tokens, cache_read_tokens FROM usage LIMIT 1"); err != nil {
+		t.Errorf("cache token columns missing after migration: %v", err)
+	}
 }
 
 func TestSchemaVersionAlreadyCurrent(t *testing.T) {
@@ -20,17 +20,19 @@ const (
 
 // LogEntry represents a structured log entry.
 type LogEntry struct {
-	Timestamp    time.Time `json:"timestamp"`
-	Level        LogLevel  `json:"level"`
-	Provider     string    `json:"provider,omitempty"`
-	Message      string    `json:"message"`
-	StatusCode   int       `json:"status_code,omitempty"`
-	Method       string    `json:"method,omitempty"`
-	Path         string    `json:"path,omitempty"`
-	Error        string    `json:"error,omitempty"`
-	ResponseBody string    `json:"response_body,omitempty"`
-	SessionID    string    `json:"session_id,omitempty"`
-	ClientType   string    `json:"client_type,omitempty"`
+	ID           int64       `json:"id,omitempty"` // row ID in the log database, 0 until stored
+	Timestamp    time.Time   `json:"timestamp"`
+	Level        LogLevel    `json:"level"`
+	Provider     string      `json:"provider,omitempty"`
+	Message      string      `json:"message"`
+	StatusCode   int         `json:"status_code,omitempty"`
+	Method       string      `json:"method,omitempty"`
+	Path         string      `json:"path,omitempty"`
+	Error        string      `json:"error,omitempty"`
+	ResponseBody string      `json:"response_body,omitempty"`
+	SessionID    string      `json:"session_id,omitempty"`
+	ClientType   string      `json:"client_type,omitempty"`
+	Annotation   *Annotation `json:"annotation,omitempty"` // labels and note, when listed from the log database
 }
 
 // StructuredLogger provides structured logging with separate error log file.
@@ -42,6 +44,7 @@ type StructuredLogger struct {
 	logDir     string
 	entries    []LogEntry
 	maxEntries int
+	subs       map[chan LogEntry]struct{}
 }
 
 // NewStructuredLogger creates a new structured logger.
@@ -121,6 +124,14 @@ func (l *StructuredLogger) Log(entry LogEntry) {
 	}
 	l.entries = append(l.entries, entry)
 
+	// Hand to live subscribers, dropping for any that fall behind
+	for ch := range l.subs {
+		select {
+		case ch <- entry:
+		default:
+		}
+	}
+
 	// Write to log file (human-readable format)
 	line := l.formatEntry(entry)
 	if l.logFile != nil {
@@ -140,6 +151,24 @@ func (l *StructuredLogger) Log(entry LogEntry) {
 	}
 }
 
+// Subscribe returns a channel receiving each entry logged from now on, and a
+// function that ends the subscription. A subscriber that falls behind misses
+// entries rather than slowing logging down.
+func (l *StructuredLogger) Subscribe() (<-chan LogEntry, func()) {
+	ch := make(chan LogEntry, 64)
+	l.mu.Lock()
+	if l.subs == nil {
+		l.subs = make(map[chan LogEntry]struct{})
+	}
+	l.subs[ch] = struct{}{}
+	l.mu.Unlock()
+	return ch, func() {
+		l.mu.Lock()
+		delete(l.subs, ch)
+		l.mu.Unlock()
+	}
+}
+
 // formatEntry formats a log entry as a string.
 func (l *StructuredLogger) formatEntry(entry LogEntry) string {
 	ts := entry.Timestamp.Format("2006/01/02 15:04:05")
@@ -300,15 +329,17 @@ func (l *StructuredLogger) GetProviders() []string {
 
 // LogFilter defines criteria for filtering log entries.
 type LogFilter struct {
-	Provider   string   `json:"provider,omitempty"`
-	Level      LogLevel `json:"level,omitempty"`       // empty means all levels
-	ErrorsOnly bool     `json:"errors_only,omitempty"` // only error and warn levels
-	StatusCode int      `json:"status_code,omitempty"` // filter by specific status code
-	StatusMin  int      `json:"status_min,omitempty"`  // filter by status code range (min)
-	StatusMax  int      `json:"status_max,omitempty"`  // filter by status code range (max)
-	SessionID  string   `json:"session_id,omitempty"`
-	ClientType string   `json:"client_type,omitempty"`
-	Limit      int      `json:"limit,omitempty"` // max entries to return
+	Provider   string           `json:"provider,omitempty"`
+	Level      LogLevel         `json:"level,omitempty"`       // empty means all levels
+	ErrorsOnly bool             `json:"errors_only,omitempty"` // only error and warn levels
+	StatusCode int              `json:"status_code,omitempty"` // filter by specific status code
+	StatusMin  int              `json:"status_min,omitempty"`  // filter by status code range (min)
+	StatusMax  int              `json:"status_max,omitempty"`  // filter by status code range (max)
+	SessionID  string           `json:"session_id,omitempty"`
+	ClientType string           `json:"client_type,omitempty"`
+	Since      time.Time        `json:"-"`               // only entries logged at or after this time
+	Annotation AnnotationFilter `json:"-"`               // only entries annotated so; needs the log database
+	Limit      int              `json:"limit,omitempty"` // max entries to return
 }
 
 // Match checks if a log entry matches the filter criteria.
@@ -351,18 +382,29 @@ func (f LogFilter) Match(entry LogEntry) bool {
 		return false
 	}
 
+	// Time filter
+	if !f.Since.IsZero() && entry.Timestamp.Before(f.Since) {
+		return false
+	}
+
+	// Annotation filter
+	if !f.Annotation.Match(entry.Annotation) {
+		return false
+	}
+
 	return true
 }
 
 // LogsResponse is the API response for log queries.
 type LogsResponse struct {
-	Entries   []LogEntry `json:"entries"`
-	Total     int        `json:"total"`
-	Providers []string   `json:"providers"`
+	Entries    []LogEntry `json:"entries"`
+	Total      int        `json:"total"`
+	Providers  []string   `json:"providers"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+	PrevCursor string     `json:"prev_cursor,omitempty"`
 }
 
 // ToJSON serializes a log entry to JSON.
 func (e LogEntry) ToJSON() ([]byte, error) {
 	return json.Marshal(e)
 }
-
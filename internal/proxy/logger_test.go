@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestStructuredLoggerBasic(t *testing.T) {
@@ -136,6 +137,7 @@ func TestStructuredLoggerFormatEntry(t *testing.T) {
 
 func TestLogFilterMatch(t *testing.T) {
 	entry := LogEntry{
+		Timestamp:  time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
 		Level:      LogLevelError,
 		Provider:   "p1",
 		StatusCode: 500,
@@ -162,6 +164,12 @@ func TestLogFilterMatch(t *testing.T) {
 	if (LogFilter{StatusCode: 200}).Match(entry) {
 		t.Error("should not match status 200")
 	}
+	if !(LogFilter{Since: entry.Timestamp}).Match(entry) {
+		t.Error("should match entry at since")
+	}
+	if (LogFilter{Since: entry.Timestamp.Add(time.Second)}).Match(entry) {
+		t.Error("should not match entry before since")
+	}
 }
 
 func TestProviderGetEnvVarsForClient(t *testing.T) {
@@ -0,0 +1,60 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/dopejs/gozen/internal/middleware"
+)
+
+// newMiddlewareRequest builds the middleware context for a proxied request.
+func (s *ProxyServer) newMiddlewareRequest(r *http.Request, body []byte, sessionID, clientType, requestFormat string) *middleware.RequestContext {
+	reqCtx := &middleware.RequestContext{
+		SessionID:     sessionID,
+		ClientType:    clientType,
+		Method:        r.Method,
+		Path:          r.URL.Path,
+		Headers:       r.Header.Clone(),
+		Body:          body,
+		Metadata:      make(map[string]interface{}),
+		RequestFormat: requestFormat,
+		Profile:       s.Profile,
+		ProjectPath:   GetSessionProject(sessionID),
+	}
+
+	// Parse model and messages for middleware
+	var bodyMap map[string]interface{}
+	if err := json.Unmarshal(body, &bodyMap); err == nil {
+		if model, ok := bodyMap["model"].(string); ok {
+			reqCtx.Model = model
+		}
+		if msgs, ok := bodyMap["messages"].([]interface{}); ok {
+			for _, m := range msgs {
+				if msgMap, ok := m.(map[string]interface{}); ok {
+					role, _ := msgMap["role"].(string)
+					reqCtx.Messages = append(reqCtx.Messages, middleware.Message{
+						Role:    role,
+						Content: msgMap["content"],
+					})
+				}
+			}
+		}
+	}
+	return reqCtx
+}
+
+// applyProviderMiddleware runs the middleware restricted to particular
+// providers on a request about to be sent to p, returning the body to send.
+func (s *ProxyServer) applyProviderMiddleware(r *http.Request, p *Provider, body []byte, sessionID, clientType, requestFormat string) ([]byte, error) {
+	pipeline := middleware.GetGlobalPipeline()
+	if pipeline == nil || !pipeline.HasProviderConditions() {
+		return body, nil
+	}
+	reqCtx := s.newMiddlewareRequest(r, body, sessionID, clientType, requestFormat)
+	reqCtx.Provider = p.Name
+	processed, err := pipeline.ProcessProviderRequest(reqCtx)
+	if err != nil {
+		return nil, err
+	}
+	return processed.Body, nil
+}
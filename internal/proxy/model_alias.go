@@ -0,0 +1,63 @@
+package proxy
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// dateSuffixPattern matches model version dates such as "-20241022",
+// "@20240620" (Vertex) or "-2024-08-06" (OpenAI).
+var dateSuffixPattern = regexp.MustCompile(`[-@](\d{8}|\d{4}-\d{2}-\d{2})$`)
+
+// stripDateSuffix removes a trailing version date from a model name.
+func stripDateSuffix(model string) string {
+	return dateSuffixPattern.ReplaceAllString(model, "")
+}
+
+// matchModelPattern reports whether model matches pattern, ignoring case.
+// A * in the pattern matches any run of characters, including none.
+func matchModelPattern(pattern, model string) bool {
+	pattern = strings.ToLower(pattern)
+	model = strings.ToLower(model)
+	if !strings.Contains(pattern, "*") {
+		return pattern == model
+	}
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(model, parts[0]) {
+		return false
+	}
+	model = model[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(model, part)
+		if i < 0 {
+			return false
+		}
+		model = model[i+len(part):]
+	}
+	return strings.HasSuffix(model, last)
+}
+
+// resolveModelAlias applies the first alias rule matching model for the
+// given provider. It reports whether a rule matched.
+func resolveModelAlias(aliases []*config.ModelAlias, model, provider string) (string, bool) {
+	for _, a := range aliases {
+		if a == nil || (a.Provider != "" && a.Provider != provider) {
+			continue
+		}
+		if !matchModelPattern(a.Match, model) {
+			continue
+		}
+		target := a.Target
+		if target == "" {
+			target = model
+		}
+		if a.StripDate {
+			target = stripDateSuffix(target)
+		}
+		return target, true
+	}
+	return model, false
+}
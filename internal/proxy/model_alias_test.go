@@ -0,0 +1,116 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+func TestMatchModelPattern(t *testing.T) {
+	tests := []struct {
+		pattern, model string
+		want           bool
+	}{
+		{"claude-sonnet-4-5", "claude-sonnet-4-5", true},
+		{"claude-sonnet-4-5", "claude-sonnet-4-5-20250929", false},
+		{"claude-3-5-sonnet*", "claude-3-5-sonnet-20241022", true},
+		{"claude-3-5-sonnet*", "Claude-3-5-Sonnet-Latest", true},
+		{"claude-3-5-sonnet*", "claude-3-opus", false},
+		{"*-haiku-*", "claude-3-5-haiku-20241022", true},
+		{"*mini", "gpt-4o-mini", true},
+		{"gpt-*-mini", "gpt-4o", false},
+		{"ab*ba", "aba", false},
+		{"*", "anything", true},
+	}
+	for _, tt := range tests {
+		if got := matchModelPattern(tt.pattern, tt.model); got != tt.want {
+			t.Errorf("matchModelPattern(%q, %q) = %v, want %v", tt.pattern, tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestStripDateSuffix(t *testing.T) {
+	tests := map[string]string{
+		"claude-3-5-sonnet-20241022": "claude-3-5-sonnet",
+		"claude-3-5-sonnet@20240620": "claude-3-5-sonnet",
+		"gpt-4o-2024-08-06":          "gpt-4o",
+		"claude-sonnet-4-5":          "claude-sonnet-4-5",
+		"model-123":                  "model-123",
+	}
+	for in, want := range tests {
+		if got := stripDateSuffix(in); got != want {
+			t.Errorf("stripDateSuffix(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestResolveModelAlias(t *testing.T) {
+	aliases := []*config.ModelAlias{
+		{Match: "claude-3-5-sonnet*", Target: "deepseek-chat", Provider: "deepseek"},
+		{Match: "claude-*", StripDate: true},
+		{Match: "gpt-4o", Target: "gpt-4o-2024-08-06"},
+	}
+	tests := []struct {
+		model, provider string
+		want            string
+		matched         bool
+	}{
+		{"claude-3-5-sonnet-20241022", "deepseek", "deepseek-chat", true},
+		{"claude-3-5-sonnet-20241022", "anthropic", "claude-3-5-sonnet", true},
+		{"claude-sonnet-4-5", "anthropic", "claude-sonnet-4-5", true},
+		{"gpt-4o", "openai", "gpt-4o-2024-08-06", true},
+		{"gemini-2.5-pro", "google", "gemini-2.5-pro", false},
+	}
+	for _, tt := range tests {
+		got, ok := resolveModelAlias(aliases, tt.model, tt.provider)
+		if got != tt.want || ok != tt.matched {
+			t.Errorf("resolveModelAlias(%q, %q) = %q, %v; want %q, %v", tt.model, tt.provider, got, ok, tt.want, tt.matched)
+		}
+	}
+}
+
+func TestProxyAppliesModelAliases(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	config.ResetDefaultStore()
+	t.Cleanup(config.ResetDefaultStore)
+	if err := config.SetModelAliases([]*config.ModelAlias{
+		{Match: "claude-3-5-sonnet*", Target: "deepseek-chat", Provider: "deepseek"},
+	}); err != nil {
+		t.Fatalf("SetModelAliases() error: %v", err)
+	}
+
+	var gotModel string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var data map[string]interface{}
+		json.Unmarshal(body, &data)
+		gotModel, _ = data["model"].(string)
+		w.WriteHeader(200)
+	}))
+	defer backend.Close()
+	u, _ := url.Parse(backend.URL)
+
+	send := func(p *Provider) {
+		srv := NewProxyServer([]*Provider{p}, discardLogger(), config.LoadBalanceFailover, nil)
+		req := httptest.NewRequest("POST", "/v1/messages", strings.NewReader(`{"model":"claude-3-5-sonnet-20241022","messages":[]}`))
+		srv.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// The alias wins over the provider's own mapping
+	send(&Provider{Name: "deepseek", BaseURL: u, Token: "t", Model: "deepseek-reasoner", Healthy: true})
+	if gotModel != "deepseek-chat" {
+		t.Errorf("deepseek model = %q, want deepseek-chat", gotModel)
+	}
+
+	// Other providers keep their usual mapping
+	send(&Provider{Name: "other", BaseURL: u, Token: "t", SonnetModel: "my-sonnet", Healthy: true})
+	if gotModel != "my-sonnet" {
+		t.Errorf("other model = %q, want my-sonnet", gotModel)
+	}
+}
@@ -0,0 +1,146 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// modelListTimeout bounds a provider's whole model listing, all pages.
+const modelListTimeout = 15 * time.Second
+
+// modelListMaxPages stops runaway paging through a provider's model list.
+const modelListMaxPages = 20
+
+// ListProviderModels asks a provider which models it serves: GET /v1/models
+// for Anthropic and OpenAI style APIs, /v1beta/models for Gemini. The IDs
+// are returned sorted, without Gemini's "models/" prefix.
+func ListProviderModels(ctx context.Context, pc *config.ProviderConfig) ([]string, error) {
+	if pc.BaseURL == "" {
+		return nil, fmt.Errorf("no base URL configured")
+	}
+	base, err := url.Parse(pc.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	token, err := config.ResolveSecret(pc.AuthToken)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: modelListTimeout}
+	if pc.ProxyURL != "" {
+		client, err = NewHTTPClientWithProxy(pc.ProxyURL, modelListTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("proxy client error: %w", err)
+		}
+		defer closeHTTPClientIdleConnections(client)
+	}
+	ctx, cancel := context.WithTimeout(ctx, modelListTimeout)
+	defer cancel()
+
+	path := "/v1/models"
+	if pc.GetType() == config.ProviderTypeGemini {
+		path = "/v1beta/models"
+	}
+	target := singleJoiningSlash(base.String(), dedupVersionPrefix(base.Path, path))
+
+	var models []string
+	cursor := ""
+	for page := 0; page < modelListMaxPages; page++ {
+		ids, next, err := listModelsPage(ctx, client, pc.GetType(), target, token, cursor)
+		if err != nil {
+			return nil, err
+		}
+		models = append(models, ids...)
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	sort.Strings(models)
+	return models, nil
+}
+
+// listModelsPage fetches one page of a model list and returns its IDs and
+// the cursor for the next page, empty on the last one.
+func listModelsPage(ctx context.Context, client *http.Client, typ, target, token, cursor string) ([]string, string, error) {
+	if cursor != "" {
+		u, err := url.Parse(target)
+		if err != nil {
+			return nil, "", err
+		}
+		q := u.Query()
+		switch typ {
+		case config.ProviderTypeGemini:
+			q.Set("pageToken", cursor)
+		default:
+			q.Set("after_id", cursor)
+		}
+		u.RawQuery = q.Encode()
+		target = u.String()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	setAuthHeaders(req, typ, token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+	if err != nil {
+		return nil, "", err
+	}
+	if resp.StatusCode/100 != 2 {
+		snippet := string(body)
+		if len(snippet) > 512 {
+			snippet = snippet[:512]
+		}
+		return nil, "", fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(snippet))
+	}
+
+	// Anthropic and OpenAI: {"data": [{"id"}], "has_more", "last_id"}
+	// Gemini: {"models": [{"name": "models/..."}], "nextPageToken"}
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+		HasMore bool   `json:"has_more"`
+		LastID  string `json:"last_id"`
+		Models  []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+		NextPageToken string `json:"nextPageToken"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, "", fmt.Errorf("unexpected model list response: %w", err)
+	}
+	var ids []string
+	for _, m := range list.Data {
+		if m.ID != "" {
+			ids = append(ids, m.ID)
+		}
+	}
+	for _, m := range list.Models {
+		if id := strings.TrimPrefix(m.Name, "models/"); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	next := list.NextPageToken
+	if list.HasMore && list.LastID != "" && list.LastID != cursor {
+		next = list.LastID
+	}
+	return ids, next, nil
+}
@@ -0,0 +1,61 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+func TestListProviderModels(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/models":
+			if r.Header.Get("x-api-key") != "sk-test" && r.Header.Get("Authorization") != "Bearer sk-test" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.URL.Query().Get("after_id") == "" {
+				w.Write([]byte(`{"data":[{"id":"claude-b"}],"has_more":true,"last_id":"claude-b"}`))
+			} else {
+				w.Write([]byte(`{"data":[{"id":"claude-a"}],"has_more":false}`))
+			}
+		case "/v1beta/models":
+			if r.Header.Get("x-goog-api-key") != "sk-test" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"models":[{"name":"models/gemini-pro"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer upstream.Close()
+
+	tests := []struct {
+		name    string
+		pc      *config.ProviderConfig
+		want    []string
+		wantErr bool
+	}{
+		{"anthropic pages", &config.ProviderConfig{BaseURL: upstream.URL, AuthToken: "sk-test"}, []string{"claude-a", "claude-b"}, false},
+		{"openai with /v1 base", &config.ProviderConfig{Type: config.ProviderTypeOpenAI, BaseURL: upstream.URL + "/v1", AuthToken: "sk-test"}, []string{"claude-a", "claude-b"}, false},
+		{"gemini", &config.ProviderConfig{Type: config.ProviderTypeGemini, BaseURL: upstream.URL, AuthToken: "sk-test"}, []string{"gemini-pro"}, false},
+		{"rejected", &config.ProviderConfig{BaseURL: upstream.URL, AuthToken: "wrong"}, nil, true},
+		{"no base URL", &config.ProviderConfig{AuthToken: "sk-test"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ListProviderModels(context.Background(), tt.pc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("models = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// Request headers that pin a single request to a provider, model or profile.
+const (
+	providerOverrideHeader = "X-Zen-Provider"
+	modelOverrideHeader    = "X-Zen-Model"
+	profileOverrideHeader  = "X-Zen-Profile"
+)
+
+// requestOverrides holds the override header values of one request.
+type requestOverrides struct {
+	Provider string
+	Model    string
+	Profile  string
+}
+
+// IsEmpty reports whether no override was requested.
+func (o requestOverrides) IsEmpty() bool {
+	return o.Provider == "" && o.Model == "" && o.Profile == ""
+}
+
+// extractRequestOverrides reads and strips the override headers so they
+// are never forwarded upstream.
+func extractRequestOverrides(h http.Header) requestOverrides {
+	o := requestOverrides{
+		Provider: strings.TrimSpace(h.Get(providerOverrideHeader)),
+		Model:    strings.TrimSpace(h.Get(modelOverrideHeader)),
+		Profile:  strings.TrimSpace(h.Get(profileOverrideHeader)),
+	}
+	h.Del(providerOverrideHeader)
+	h.Del(modelOverrideHeader)
+	h.Del(profileOverrideHeader)
+	return o
+}
+
+// checkRequestOverrides verifies every requested override against the
+// configured allowlists.
+func checkRequestOverrides(cfg *config.OverrideHeadersConfig, o requestOverrides) error {
+	if o.IsEmpty() {
+		return nil
+	}
+	if cfg == nil || !cfg.Enabled {
+		return fmt.Errorf("override headers are disabled")
+	}
+	checks := []struct {
+		header, value string
+		allowed       []string
+	}{
+		{providerOverrideHeader, o.Provider, cfg.Providers},
+		{modelOverrideHeader, o.Model, cfg.Models},
+		{profileOverrideHeader, o.Profile, cfg.Profiles},
+	}
+	for _, c := range checks {
+		if c.value != "" && !overrideAllowed(c.allowed, c.value) {
+			return fmt.Errorf("%s %q is not in the allowlist", c.header, c.value)
+		}
+	}
+	return nil
+}
+
+// overrideAllowed reports whether value matches an allowlist entry.
+func overrideAllowed(allowed []string, value string) bool {
+	for _, pattern := range allowed {
+		if matchModelPattern(pattern, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// pinModelOverrides returns a copy of overrides with model set for every
+// provider, taking precedence over scenario models and middleware hints.
+// The input map may be shared route config, so it is never modified.
+func pinModelOverrides(overrides map[string]string, providers []*Provider, model string) map[string]string {
+	pinned := make(map[string]string, len(overrides)+len(providers))
+	for name, m := range overrides {
+		pinned[name] = m
+	}
+	for _, p := range providers {
+		pinned[p.Name] = model
+	}
+	return pinned
+}
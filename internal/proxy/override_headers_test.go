@@ -0,0 +1,119 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+func TestCheckRequestOverrides(t *testing.T) {
+	cfg := &config.OverrideHeadersConfig{
+		Enabled:   true,
+		Providers: []string{"anthropic", "deepseek"},
+		Models:    []string{"claude-*"},
+	}
+	tests := []struct {
+		name    string
+		cfg     *config.OverrideHeadersConfig
+		o       requestOverrides
+		wantErr bool
+	}{
+		{"no overrides", nil, requestOverrides{}, false},
+		{"disabled", nil, requestOverrides{Provider: "anthropic"}, true},
+		{"allowed provider", cfg, requestOverrides{Provider: "deepseek"}, false},
+		{"unlisted provider", cfg, requestOverrides{Provider: "openai"}, true},
+		{"model glob", cfg, requestOverrides{Model: "claude-opus-4-5"}, false},
+		{"unlisted model", cfg, requestOverrides{Model: "gpt-4o"}, true},
+		{"empty profile allowlist", cfg, requestOverrides{Profile: "work"}, true},
+	}
+	for _, tt := range tests {
+		if err := checkRequestOverrides(tt.cfg, tt.o); (err != nil) != tt.wantErr {
+			t.Errorf("%s: checkRequestOverrides() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestPinModelOverridesCopies(t *testing.T) {
+	shared := map[string]string{"a": "scenario-model"}
+	pinned := pinModelOverrides(shared, []*Provider{{Name: "a"}, {Name: "b"}}, "pinned")
+	if pinned["a"] != "pinned" || pinned["b"] != "pinned" {
+		t.Errorf("pinned = %v", pinned)
+	}
+	if shared["a"] != "scenario-model" {
+		t.Error("input map was modified")
+	}
+}
+
+func TestProfileProxyOverrideHeaders(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	config.ResetDefaultStore()
+	t.Cleanup(config.ResetDefaultStore)
+
+	type hit struct{ provider, model string }
+	var got hit
+	newBackend := func(name string) string {
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			var data map[string]interface{}
+			json.Unmarshal(body, &data)
+			model, _ := data["model"].(string)
+			got = hit{name, model}
+			if r.Header.Get(providerOverrideHeader) != "" || r.Header.Get(modelOverrideHeader) != "" {
+				t.Error("override headers should not be forwarded upstream")
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"id":"msg_1","content":[],"usage":{"input_tokens":1,"output_tokens":1}}`))
+		}))
+		t.Cleanup(backend.Close)
+		return backend.URL
+	}
+	config.SetProvider("pa", &config.ProviderConfig{BaseURL: newBackend("pa"), AuthToken: "t"})
+	config.SetProvider("pb", &config.ProviderConfig{BaseURL: newBackend("pb"), AuthToken: "t"})
+	config.SetProfileConfig("main", &config.ProfileConfig{Providers: []string{"pa"}})
+	config.SetProfileConfig("alt", &config.ProfileConfig{Providers: []string{"pb"}})
+
+	pp := NewProfileProxy(discardLogger())
+	send := func(headers map[string]string) int {
+		r := httptest.NewRequest("POST", "/main/s1/v1/messages",
+			strings.NewReader(`{"model":"claude-sonnet-4-5","messages":[{"role":"user","content":"hi"}],"max_tokens":10}`))
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		pp.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	// Disabled by default
+	if code := send(map[string]string{providerOverrideHeader: "pb"}); code != http.StatusForbidden {
+		t.Fatalf("disabled override: status = %d, want 403", code)
+	}
+
+	config.SetOverrideHeaders(&config.OverrideHeadersConfig{
+		Enabled:   true,
+		Providers: []string{"pb"},
+		Models:    []string{"pinned-*"},
+		Profiles:  []string{"alt"},
+	})
+
+	if code := send(nil); code != 200 || got != (hit{"pa", "claude-sonnet-4-5"}) {
+		t.Errorf("no override: status %d, hit %+v", code, got)
+	}
+	if code := send(map[string]string{providerOverrideHeader: "pb"}); code != 200 || got != (hit{"pb", "claude-sonnet-4-5"}) {
+		t.Errorf("provider override: status %d, hit %+v", code, got)
+	}
+	if code := send(map[string]string{modelOverrideHeader: "pinned-model"}); code != 200 || got != (hit{"pa", "pinned-model"}) {
+		t.Errorf("model override: status %d, hit %+v", code, got)
+	}
+	if code := send(map[string]string{profileOverrideHeader: "alt"}); code != 200 || got != (hit{"pb", "claude-sonnet-4-5"}) {
+		t.Errorf("profile override: status %d, hit %+v", code, got)
+	}
+	if code := send(map[string]string{providerOverrideHeader: "pa"}); code != http.StatusForbidden {
+		t.Errorf("unlisted provider: status = %d, want 403", code)
+	}
+}
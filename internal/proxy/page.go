@@ -0,0 +1,58 @@
+package proxy
+
+// Page selects a page of rows listed newest first. Before and After are row
+// IDs taken from a previous page: Before reads the rows older than it, After
+// the rows newer than it. Zero means no bound; After wins if both are set.
+type Page struct {
+	Limit  int
+	Before int64
+	After  int64
+}
+
+// defaultPageLimit is the page size used when Page.Limit is not set.
+const defaultPageLimit = 100
+
+func (p Page) limit() int {
+	if p.Limit <= 0 {
+		return defaultPageLimit
+	}
+	return p.Limit
+}
+
+// where adds the page's bound to the conditions of a query on a table with
+// an id column.
+func (p Page) where(conds []string, args []interface{}) ([]string, []interface{}) {
+	switch {
+	case p.After > 0:
+		return append(conds, "id > ?"), append(args, p.After)
+	case p.Before > 0:
+		return append(conds, "id < ?"), append(args, p.Before)
+	}
+	return conds, args
+}
+
+// orderLimit returns the ORDER BY and LIMIT clauses reading the page, and
+// the LIMIT argument. One row more than the page holds is read, so trimPage
+// can tell whether there are more.
+func (p Page) orderLimit() (string, int) {
+	if p.After > 0 {
+		// Read the rows nearest the cursor, then put them newest first
+		return " ORDER BY id ASC LIMIT ?", p.limit() + 1
+	}
+	return " ORDER BY id DESC LIMIT ?", p.limit() + 1
+}
+
+// trimPage cuts rows read with orderLimit to the page, newest first, and
+// reports whether there were more rows past it in the direction read.
+func trimPage[T any](p Page, rows []T) ([]T, bool) {
+	more := len(rows) > p.limit()
+	if more {
+		rows = rows[:p.limit()]
+	}
+	if p.After > 0 {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+	return rows, more
+}
@@ -0,0 +1,185 @@
+package proxy
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// maxPricingManifestBytes caps the size of a fetched pricing manifest.
+const maxPricingManifestBytes = 1 << 20
+
+var pricingSyncClient = &http.Client{Timeout: 30 * time.Second}
+
+// PricingManifest is a maintained list of model prices, signed by its
+// publisher.
+type PricingManifest struct {
+	Version string                          `json:"version"`
+	Models  map[string]*config.ModelPricing `json:"models"`
+}
+
+// PricingChange is one model whose price a manifest changes. Old is nil for
+// a model the manifest adds and New is nil for one it drops. Overridden
+// marks models whose custom pricing override keeps the change from taking
+// effect.
+type PricingChange struct {
+	Model      string               `json:"model"`
+	Old        *config.ModelPricing `json:"old,omitempty"`
+	New        *config.ModelPricing `json:"new,omitempty"`
+	Overridden bool                 `json:"overridden,omitempty"`
+}
+
+// PricingSyncResult describes a pricing sync.
+type PricingSyncResult struct {
+	Version string          `json:"version"`
+	Changes []PricingChange `json:"changes"`
+	Applied bool            `json:"applied"`
+}
+
+// FetchPricingManifest downloads the manifest at cfg.URL and verifies its
+// signature, read from cfg.URL + ".sig", against cfg.PublicKey.
+func FetchPricingManifest(ctx context.Context, cfg *config.PricingSyncConfig) (*PricingManifest, error) {
+	if cfg == nil || cfg.URL == "" {
+		return nil, fmt.Errorf("pricing sync URL is not configured")
+	}
+	key, err := base64.StdEncoding.DecodeString(cfg.PublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("pricing sync public_key must be a base64 ed25519 public key")
+	}
+
+	body, err := fetchPricingFile(ctx, cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	sigText, err := fetchPricingFile(ctx, cfg.URL+".sig")
+	if err != nil {
+		return nil, err
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sigText)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest signature: %w", err)
+	}
+	if !ed25519.Verify(ed25519.PublicKey(key), body, sig) {
+		return nil, fmt.Errorf("manifest signature does not match public_key")
+	}
+
+	var m PricingManifest
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, fmt.Errorf("invalid pricing manifest: %w", err)
+	}
+	if len(m.Models) == 0 {
+		return nil, fmt.Errorf("pricing manifest lists no models")
+	}
+	for model, p := range m.Models {
+		if p == nil || p.Validate() != nil {
+			return nil, fmt.Errorf("pricing manifest has an invalid price for %q", model)
+		}
+	}
+	return &m, nil
+}
+
+func fetchPricingFile(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := pricingSyncClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch %s: status %d", url, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPricingManifestBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxPricingManifestBytes {
+		return nil, fmt.Errorf("fetch %s: larger than %d bytes", url, maxPricingManifestBytes)
+	}
+	return body, nil
+}
+
+// DiffPricing returns how replacing the synced prices in current with next
+// changes the built-in price of each model, sorted by model. overrides are
+// the custom pricing overrides, which win over both.
+func DiffPricing(current, next, overrides map[string]*config.ModelPricing) []PricingChange {
+	base := func(synced map[string]*config.ModelPricing, model string) *config.ModelPricing {
+		if p, ok := synced[model]; ok {
+			return p
+		}
+		return config.DefaultModelPricing[model]
+	}
+
+	models := make(map[string]bool, len(current)+len(next))
+	for model := range current {
+		models[model] = true
+	}
+	for model := range next {
+		models[model] = true
+	}
+
+	changes := []PricingChange{}
+	for model := range models {
+		was, now := base(current, model), base(next, model)
+		if was != nil && now != nil && *was == *now {
+			continue
+		}
+		_, overridden := overrides[model]
+		changes = append(changes, PricingChange{Model: model, Old: was, New: now, Overridden: overridden})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Model < changes[j].Model })
+	return changes
+}
+
+// SyncPricing fetches the configured pricing manifest and, unless dryRun is
+// set, applies it, reloads pricing and records an audit entry in the log.
+func SyncPricing(ctx context.Context, dryRun bool) (*PricingSyncResult, error) {
+	cfg := config.GetPricingSync()
+	m, err := FetchPricingManifest(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PricingSyncResult{
+		Version: m.Version,
+		Changes: DiffPricing(cfg.Prices, m.Models, config.GetPricingOverrides()),
+	}
+	if dryRun {
+		return result, nil
+	}
+
+	if err := config.ApplySyncedPricing(m.Version, m.Models); err != nil {
+		return nil, err
+	}
+	result.Applied = true
+
+	if tracker := GetGlobalUsageTracker(); tracker != nil {
+		tracker.ReloadPricing()
+	}
+	if lb := GetGlobalLoadBalancer(); lb != nil {
+		lb.ReloadPricing()
+	}
+
+	overridden := 0
+	for _, c := range result.Changes {
+		if c.Overridden {
+			overridden++
+		}
+	}
+	if logger := GetGlobalLogger(); logger != nil {
+		logger.Info("", fmt.Sprintf("[AUDIT] action=pricing_sync version=%q url=%s changes=%d overridden=%d",
+			m.Version, cfg.URL, len(result.Changes), overridden))
+	}
+	return result, nil
+}
@@ -0,0 +1,108 @@
+package proxy
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// servePricingManifest serves manifest and its ed25519 signature by priv.
+func servePricingManifest(t *testing.T, manifest string, priv ed25519.PrivateKey) *httptest.Server {
+	t.Helper()
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, []byte(manifest)))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pricing.json":
+			w.Write([]byte(manifest))
+		case "/pricing.json.sig":
+			w.Write([]byte(sig + "\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFetchPricingManifest(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	srv := servePricingManifest(t, `{"version":"2026.03","models":{"new-model":{"input_per_million":1,"output_per_million":2}}}`, priv)
+	cfg := &config.PricingSyncConfig{URL: srv.URL + "/pricing.json", PublicKey: base64.StdEncoding.EncodeToString(pub)}
+
+	m, err := FetchPricingManifest(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("FetchPricingManifest() error: %v", err)
+	}
+	if m.Version != "2026.03" || m.Models["new-model"].OutputPerMillion != 2 {
+		t.Errorf("unexpected manifest: %+v", m)
+	}
+
+	otherPub, _, _ := ed25519.GenerateKey(nil)
+	cfg.PublicKey = base64.StdEncoding.EncodeToString(otherPub)
+	if _, err := FetchPricingManifest(context.Background(), cfg); err == nil {
+		t.Error("expected a manifest signed by another key to be rejected")
+	}
+}
+
+func TestDiffPricing(t *testing.T) {
+	current := map[string]*config.ModelPricing{"gpt-4o": {InputPerMillion: 2, OutputPerMillion: 8}}
+	next := map[string]*config.ModelPricing{
+		"gpt-4o-mini": {InputPerMillion: 0.15, OutputPerMillion: 0.6}, // same as default
+		"new-model":   {InputPerMillion: 1, OutputPerMillion: 2},
+		"o1":          {InputPerMillion: 10, OutputPerMillion: 40},
+	}
+	overrides := map[string]*config.ModelPricing{"o1": {InputPerMillion: 1, OutputPerMillion: 1}}
+
+	changes := DiffPricing(current, next, overrides)
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 changes, got %+v", changes)
+	}
+	// gpt-4o falls back to its default price once the manifest drops it
+	if c := changes[0]; c.Model != "gpt-4o" || c.Old.InputPerMillion != 2 || c.New != config.DefaultModelPricing["gpt-4o"] {
+		t.Errorf("unexpected gpt-4o change: %+v", c)
+	}
+	if c := changes[1]; c.Model != "new-model" || c.Old != nil || c.New.InputPerMillion != 1 {
+		t.Errorf("unexpected new-model change: %+v", c)
+	}
+	if c := changes[2]; c.Model != "o1" || !c.Overridden {
+		t.Errorf("expected the o1 change to be marked overridden: %+v", c)
+	}
+}
+
+func TestSyncPricing(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	config.ResetDefaultStore()
+	t.Cleanup(config.ResetDefaultStore)
+
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	srv := servePricingManifest(t, `{"version":"v2","models":{"gpt-4o":{"input_per_million":2,"output_per_million":8},"o1":{"input_per_million":10,"output_per_million":40}}}`, priv)
+	config.SetPricingSync(&config.PricingSyncConfig{URL: srv.URL + "/pricing.json", PublicKey: base64.StdEncoding.EncodeToString(pub)})
+	config.SetPricing(map[string]*config.ModelPricing{"o1": {InputPerMillion: 1, OutputPerMillion: 1}})
+
+	result, err := SyncPricing(context.Background(), true)
+	if err != nil {
+		t.Fatalf("SyncPricing(dry run) error: %v", err)
+	}
+	if result.Applied || len(result.Changes) != 2 || config.GetPricing()["gpt-4o"].InputPerMillion != 2.5 {
+		t.Fatalf("dry run should report changes without applying them: %+v", result)
+	}
+
+	if _, err := SyncPricing(context.Background(), false); err != nil {
+		t.Fatalf("SyncPricing() error: %v", err)
+	}
+	pricing := config.GetPricing()
+	if pricing["gpt-4o"].InputPerMillion != 2 {
+		t.Errorf("synced gpt-4o input price = %v, want 2", pricing["gpt-4o"].InputPerMillion)
+	}
+	if pricing["o1"].InputPerMillion != 1 {
+		t.Errorf("custom override should win over synced price, got %v", pricing["o1"].InputPerMillion)
+	}
+	if ps := config.GetPricingSync(); ps.Version != "v2" || ps.SyncedAt.IsZero() {
+		t.Errorf("expected the applied version to be recorded, got %+v", ps)
+	}
+}
@@ -0,0 +1,240 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/proxy/transform"
+)
+
+// probeTick is how often the probe loop looks for providers due a probe.
+const probeTick = 10 * time.Second
+
+// ProviderBreaker takes providers out of rotation, or puts them back, by
+// name. ProfileProxy implements it for the providers of its cached profiles.
+type ProviderBreaker interface {
+	SetProviderHealth(name string, healthy bool)
+}
+
+// SetBreaker sets where probe results trip or reset provider backoff.
+func (h *HealthChecker) SetBreaker(b ProviderBreaker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.breaker = b
+}
+
+// probeLoop sends a synthetic completion request to each probed provider
+// once per probe interval, while probes are enabled.
+func (h *HealthChecker) probeLoop() {
+	defer h.wg.Done()
+
+	ticker := time.NewTicker(probeTick)
+	defer ticker.Stop()
+	lastProbe := make(map[string]time.Time)
+
+	for {
+		select {
+		case <-h.stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		h.mu.RLock()
+		cfg := h.config
+		h.mu.RUnlock()
+		if cfg == nil || !cfg.Enabled || cfg.Probe == nil || !cfg.Probe.Enabled {
+			continue
+		}
+
+		now := time.Now()
+		var wg sync.WaitGroup
+		for _, name := range config.ProviderNames() {
+			if !cfg.Probe.Probes(name) || now.Sub(lastProbe[name]) < cfg.Probe.GetInterval() {
+				continue
+			}
+			pc := config.GetProvider(name)
+			if pc == nil {
+				continue
+			}
+			lastProbe[name] = now
+			wg.Add(1)
+			go func(name string, pc *config.ProviderConfig) {
+				defer wg.Done()
+				h.recordProbe(h.ProbeProvider(name, pc, cfg.Probe))
+			}(name, pc)
+		}
+		wg.Wait()
+	}
+}
+
+// ProbeProvider sends a minimal completion request to a provider and
+// reports whether it answered successfully.
+func (h *HealthChecker) ProbeProvider(name string, pc *config.ProviderConfig, probe *config.ProbeConfig) *HealthResult {
+	result := &HealthResult{
+		Provider:  name,
+		Probe:     true,
+		Timestamp: time.Now(),
+	}
+
+	client := h.client
+	if pc.ProxyURL != "" {
+		proxyClient, err := NewHTTPClientWithProxy(pc.ProxyURL, h.client.Timeout)
+		if err != nil {
+			result.Error = "proxy client error: " + err.Error()
+			result.unsent = true
+			return result
+		}
+		client = proxyClient
+		defer closeHTTPClientIdleConnections(client)
+	}
+
+	token, err := config.ResolveSecret(pc.AuthToken)
+	if err != nil {
+		result.Error = err.Error()
+		result.unsent = true
+		return result
+	}
+	resolved := *pc
+	resolved.AuthToken = token
+
+	ctx, cancel := context.WithTimeout(context.Background(), client.Timeout)
+	defer cancel()
+	req, err := probeRequest(ctx, &resolved, probe.ModelFor(name, pc), probe.GetPrompt(), probe.GetMaxTokens())
+	if err != nil {
+		result.Error = err.Error()
+		result.unsent = true
+		return result
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		result.LatencyMs = int(time.Since(start).Milliseconds())
+		result.Error = err.Error()
+		return result
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	result.LatencyMs = int(time.Since(start).Milliseconds())
+	result.StatusCode = resp.StatusCode
+
+	if resp.StatusCode/100 == 2 {
+		result.Healthy = true
+	} else {
+		result.Error = fmt.Sprintf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return result
+}
+
+// probeRequest builds a completion request in the provider's own API format.
+func probeRequest(ctx context.Context, pc *config.ProviderConfig, model, prompt string, maxTokens int) (*http.Request, error) {
+	if pc.BaseURL == "" {
+		return nil, fmt.Errorf("no base URL configured")
+	}
+	if model == "" {
+		return nil, fmt.Errorf("no model to probe with; set health_check.probe.model")
+	}
+	base, err := url.Parse(pc.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+
+	var path string
+	var payload interface{}
+	messages := []map[string]string{{"role": "user", "content": prompt}}
+	switch pc.GetType() {
+	case config.ProviderTypeOpenAI:
+		path = "/v1/chat/completions"
+		payload = map[string]interface{}{"model": model, "max_tokens": maxTokens, "messages": messages}
+	case config.ProviderTypeGemini:
+		path = transform.GeminiPath(model, false)
+		payload = map[string]interface{}{
+			"contents":         []map[string]interface{}{{"role": "user", "parts": []map[string]string{{"text": prompt}}}},
+			"generationConfig": map[string]int{"maxOutputTokens": maxTokens},
+		}
+	default:
+		path = "/v1/messages"
+		payload = map[string]interface{}{"model": model, "max_tokens": maxTokens, "messages": messages}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	target := singleJoiningSlash(base.String(), dedupVersionPrefix(base.Path, path))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	setAuthHeaders(req, pc.GetType(), pc.AuthToken)
+	return req, nil
+}
+
+// setAuthHeaders authenticates a request the daemon makes on its own
+// behalf, in the way the provider type expects.
+func setAuthHeaders(req *http.Request, providerType, token string) {
+	switch providerType {
+	case config.ProviderTypeGemini:
+		req.Header.Set("x-goog-api-key", token)
+	case config.ProviderTypeOpenAI:
+		req.Header.Set("Authorization", "Bearer "+token)
+	default:
+		req.Header.Set("x-api-key", token)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("anthropic-version", "2023-06-01")
+	}
+}
+
+// recordProbe feeds a probe result to the health tracker and the breaker.
+// Failures that would make the proxy fail over (no response, auth errors,
+// rate limits and server errors) take the provider out of rotation; other
+// rejections, such as an unknown probe model, and probes that could not be
+// sent are only tracked.
+func (h *HealthChecker) recordProbe(result *HealthResult) {
+	h.updateStatus(result)
+
+	h.mu.RLock()
+	breaker := h.breaker
+	h.mu.RUnlock()
+	if breaker == nil || result.unsent {
+		return
+	}
+	switch {
+	case result.Healthy:
+		breaker.SetProviderHealth(result.Provider, true)
+	case result.StatusCode == 0, result.StatusCode == http.StatusUnauthorized, result.StatusCode == http.StatusPaymentRequired,
+		result.StatusCode == http.StatusForbidden, result.StatusCode == http.StatusTooManyRequests, result.StatusCode >= 500:
+		breaker.SetProviderHealth(result.Provider, false)
+	}
+}
+
+// SetProviderHealth marks the named provider healthy or failed in every
+// cached profile, as a proxied request to it would.
+func (pp *ProfileProxy) SetProviderHealth(name string, healthy bool) {
+	pp.mu.RLock()
+	defer pp.mu.RUnlock()
+	seen := make(map[*Provider]bool)
+	for _, srv := range pp.cache {
+		for _, p := range srv.allProviders() {
+			if p.Name != name || seen[p] {
+				continue
+			}
+			seen[p] = true
+			if healthy {
+				p.MarkHealthy()
+			} else {
+				p.MarkFailed()
+			}
+		}
+	}
+}
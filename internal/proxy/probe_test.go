@@ -0,0 +1,125 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+func TestProbeRequest(t *testing.T) {
+	tests := []struct {
+		name       string
+		pc         *config.ProviderConfig
+		wantURL    string
+		wantHeader string
+		wantBody   string
+	}{
+		{
+			name:       "anthropic",
+			pc:         &config.ProviderConfig{BaseURL: "https://api.anthropic.com", AuthToken: "k"},
+			wantURL:    "https://api.anthropic.com/v1/messages",
+			wantHeader: "x-api-key",
+			wantBody:   "max_tokens",
+		},
+		{
+			name:       "openai with /v1 base",
+			pc:         &config.ProviderConfig{Type: config.ProviderTypeOpenAI, BaseURL: "https://api.openai.com/v1", AuthToken: "k"},
+			wantURL:    "https://api.openai.com/v1/chat/completions",
+			wantHeader: "Authorization",
+			wantBody:   "max_tokens",
+		},
+		{
+			name:       "gemini",
+			pc:         &config.ProviderConfig{Type: config.ProviderTypeGemini, BaseURL: "https://generativelanguage.googleapis.com", AuthToken: "k"},
+			wantURL:    "https://generativelanguage.googleapis.com/v1beta/models/m:generateContent",
+			wantHeader: "x-goog-api-key",
+			wantBody:   "generationConfig",
+		},
+	}
+	for _, tt := range tests {
+		req, err := probeRequest(context.Background(), tt.pc, "m", "ping", 1)
+		if err != nil {
+			t.Fatalf("%s: probeRequest() error: %v", tt.name, err)
+		}
+		if req.URL.String() != tt.wantURL {
+			t.Errorf("%s: URL = %s, want %s", tt.name, req.URL, tt.wantURL)
+		}
+		if req.Header.Get(tt.wantHeader) == "" {
+			t.Errorf("%s: missing %s header", tt.name, tt.wantHeader)
+		}
+		body, _ := io.ReadAll(req.Body)
+		var payload map[string]interface{}
+		if err := json.Unmarshal(body, &payload); err != nil || payload[tt.wantBody] == nil {
+			t.Errorf("%s: body %s lacks %s", tt.name, body, tt.wantBody)
+		}
+	}
+
+	if _, err := probeRequest(context.Background(), &config.ProviderConfig{Type: config.ProviderTypeOpenAI, BaseURL: "https://x"}, "", "ping", 1); err == nil {
+		t.Error("probeRequest() without a model should fail")
+	}
+}
+
+func TestProbeModelFor(t *testing.T) {
+	probe := &config.ProbeConfig{Models: map[string]string{"a": "pinned"}}
+	if got := probe.ModelFor("a", &config.ProviderConfig{}); got != "pinned" {
+		t.Errorf("ModelFor(a) = %q, want pinned", got)
+	}
+	if got := probe.ModelFor("b", &config.ProviderConfig{HaikuModel: "small", Model: "big"}); got != "small" {
+		t.Errorf("ModelFor(b) = %q, want the haiku model", got)
+	}
+	if got := probe.ModelFor("c", &config.ProviderConfig{Type: config.ProviderTypeOpenAI, Model: "gpt"}); got != "gpt" {
+		t.Errorf("ModelFor(c) = %q, want the default model", got)
+	}
+}
+
+func TestHealthChecker_ProbeFeedsBreaker(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	provider := &Provider{Name: "p", Healthy: true}
+	pp := NewProfileProxy(log.New(io.Discard, "", 0))
+	pp.cache["default"] = &ProxyServer{Providers: []*Provider{provider}}
+
+	hc := &HealthChecker{
+		client:   &http.Client{Timeout: 5 * time.Second},
+		statuses: make(map[string]*ProviderHealthStatus),
+	}
+	hc.SetBreaker(pp)
+	pc := &config.ProviderConfig{BaseURL: server.URL, AuthToken: "k"}
+	probe := &config.ProbeConfig{Enabled: true}
+
+	status = http.StatusServiceUnavailable
+	hc.recordProbe(hc.ProbeProvider("p", pc, probe))
+	if provider.Healthy {
+		t.Error("a failed probe should take the provider out of rotation")
+	}
+	if s := hc.GetStatus("p"); s.LastProbe == nil || s.ProbeError == "" || s.FailCount != 1 {
+		t.Errorf("status after failed probe = %+v", s)
+	}
+
+	status = http.StatusOK
+	hc.recordProbe(hc.ProbeProvider("p", pc, probe))
+	if !provider.Healthy || provider.Backoff != 0 {
+		t.Errorf("a successful probe should reset the provider, got healthy=%v backoff=%v", provider.Healthy, provider.Backoff)
+	}
+	if s := hc.GetStatus("p"); s.ProbeError != "" || s.CheckCount != 2 {
+		t.Errorf("status after successful probe = %+v", s)
+	}
+
+	// A rejected probe request says nothing about real traffic
+	status = http.StatusBadRequest
+	hc.recordProbe(hc.ProbeProvider("p", pc, probe))
+	if !provider.Healthy {
+		t.Error("a 400 probe response should not trip the breaker")
+	}
+}
@@ -35,6 +35,13 @@ type MetricsRecorder interface {
 	RecordRequest(provider string, latency time.Duration, err error)
 }
 
+// KeyRejectionRecorder is implemented by a MetricsRecorder that wants to
+// know when a provider rejects one of its keys. nextKeyID is the key the
+// request is retried with, or "" when the provider has none left.
+type KeyRejectionRecorder interface {
+	RecordKeyRejected(provider, keyID string, status int, nextKeyID string)
+}
+
 // NewProfileProxy creates a new profile-based proxy router.
 func NewProfileProxy(logger *log.Logger) *ProfileProxy {
 	return &ProfileProxy{
@@ -52,10 +59,46 @@ func (pp *ProfileProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Gemini API clients are converted to Anthropic Messages here, so they
+	// can be routed to any provider
+	var gemini *geminiClientWriter
+	if model, method, ok := transform.ParseGeminiClientPath(route.Remainder); ok {
+		if gemini, err = adaptGeminiClient(w, r, model, method); err != nil {
+			writeGeminiError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		route.Remainder = r.URL.Path
+		w = gemini
+		defer gemini.finish()
+	}
+
 	// Extract and strip X-Zen-Client header (from original request)
 	clientType := r.Header.Get("X-Zen-Client")
 	r.Header.Del("X-Zen-Client")
 
+	// Attribute usage to the owner of the client's ingress key
+	r.Header.Del(userHeader)
+	user, err := resolveIngressUser(config.GetIngress(), r.Header)
+	if err != nil {
+		pp.writeError(w, http.StatusUnauthorized, "invalid_api_key", err.Error())
+		return
+	}
+	if gemini != nil && gemini.method == transform.GeminiMethodCountTokens {
+		gemini.serveCountTokens(r)
+		return
+	}
+
+	// Extract and check per-request provider/model/profile overrides
+	overrides := extractRequestOverrides(r.Header)
+	if err := checkRequestOverrides(config.GetOverrideHeaders(), overrides); err != nil {
+		pp.writeError(w, http.StatusForbidden, "override_not_allowed", err.Error())
+		return
+	}
+	if overrides.Profile != "" {
+		pp.Logger.Printf("[route] profile overridden by %s: %s → %s", profileOverrideHeader, route.Profile, overrides.Profile)
+		route.Profile = overrides.Profile
+	}
+
 	// Auto-detect client format from request path if not explicitly set
 	clientFormat := detectClientFormat(route.Remainder, clientType)
 
@@ -105,6 +148,14 @@ func (pp *ProfileProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 				LongContextThreshold: sr.LongContextThreshold,
 				FallbackToDefault:    sr.FallbackToDefault,
 			}
+			if sr.Match != nil {
+				matcher, err := NewScenarioMatcher(sr.Match)
+				if err != nil {
+					pp.Logger.Printf("[routing] warning: invalid match rules for scenario %s: %v", scenario, err)
+				} else {
+					scenarioRoutes[scenario].Matcher = matcher
+				}
+			}
 		}
 		if len(scenarioRoutes) > 0 {
 			routing = &RoutingConfig{
@@ -116,8 +167,31 @@ func (pp *ProfileProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// A pinned provider replaces the profile's providers and routing
+	cacheKey := route.Profile
+	if overrides.Provider != "" {
+		pinned, err := pp.buildProviders([]string{overrides.Provider}, nil)
+		if err != nil {
+			pp.writeError(w, http.StatusBadRequest, "provider_error", err.Error())
+			return
+		}
+		providers, routing = pinned, nil
+		cacheKey = route.Profile + "@" + overrides.Provider
+	}
+
+	// Build the shadow traffic target if the profile mirrors requests
+	extras := proxyExtras{transform: profileCfg.requestTransform}
+	if m := profileCfg.mirror; m != nil && m.Provider != "" {
+		mirrored, err := pp.buildProviders([]string{m.Provider}, nil)
+		if err != nil {
+			pp.Logger.Printf("[shadow] warning: failed to build mirror provider for profile %s: %v", route.Profile, err)
+		} else {
+			extras.shadow = &shadowTarget{provider: mirrored[0], percent: m.Percent}
+		}
+	}
+
 	// Get or create a proxy server for this profile
-	srv := pp.getOrCreateProxy(route.Profile, providers, routing, profileCfg.strategy)
+	srv := pp.getOrCreateProxyFor(cacheKey, route.Profile, providers, routing, profileCfg.strategy, profileCfg.cacheAffinity, extras)
 
 	// Rewrite the request URL to strip profile/session prefix
 	r.URL.Path = route.Remainder
@@ -136,6 +210,19 @@ func (pp *ProfileProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		r.Header.Set("X-Zen-Client", clientType)
 	}
 
+	// Pass the user label to ProxyServer for usage attribution
+	if user != "" {
+		r.Header.Set(userHeader, user)
+	}
+
+	// Pass validated pins to ProxyServer
+	if overrides.Provider != "" {
+		r.Header.Set(providerOverrideHeader, overrides.Provider)
+	}
+	if overrides.Model != "" {
+		r.Header.Set(modelOverrideHeader, overrides.Model)
+	}
+
 	// Wrap response writer to capture status code
 	mrw := &metricsResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
 
@@ -153,6 +240,9 @@ type profileInfo struct {
 	strategy             config.LoadBalanceStrategy
 	providerWeights      map[string]int
 	scenarioPriority     []string
+	cacheAffinity        bool
+	mirror               *config.MirrorConfig
+	requestTransform     *config.RequestTransform
 }
 
 // resolveProfileConfig looks up provider names and routing config for a profile.
@@ -184,6 +274,9 @@ func (pp *ProfileProxy) resolveProfileConfig(route *RouteInfo) (*profileInfo, er
 		strategy:             pc.Strategy,
 		providerWeights:      pc.ProviderWeights,
 		scenarioPriority:     pc.ScenarioPriority,
+		cacheAffinity:        pc.CacheAffinity,
+		mirror:               pc.Mirror,
+		requestTransform:     pc.RequestTransform,
 	}, nil
 }
 
@@ -204,10 +297,13 @@ func (pp *ProfileProxy) buildProviders(names []string, profileWeights map[string
 		if err != nil {
 			return nil, fmt.Errorf("provider %q: invalid base URL: %w", name, err)
 		}
+		if pc, err = pc.ResolveTokens(); err != nil {
+			pp.Logger.Printf("[%s] warning: %v", name, err)
+		}
 
 		// Only fill Anthropic default model names for Anthropic providers.
-		// OpenAI providers should leave empty tier fields as-is so mapModel()
-		// falls through to the provider's default model.
+		// OpenAI and Gemini providers should leave empty tier fields as-is so
+		// mapModel() falls through to the provider's default model.
 		isAnthropic := pc.GetType() == config.ProviderTypeAnthropic
 
 		model := pc.Model
@@ -232,7 +328,7 @@ func (pp *ProfileProxy) buildProviders(names []string, profileWeights map[string
 		}
 
 		if !isAnthropic {
-			pp.Logger.Printf("[%s] openai provider: using model=%q, skipping Anthropic tier defaults", name, model)
+			pp.Logger.Printf("[%s] %s provider: using model=%q, skipping Anthropic tier defaults", name, pc.GetType(), model)
 		}
 
 		// Determine weight: profile-level weights take precedence over provider-level
@@ -248,6 +344,7 @@ func (pp *ProfileProxy) buildProviders(names []string, profileWeights map[string
 			Type:            pc.GetType(),
 			BaseURL:         baseURL,
 			Token:           pc.AuthToken,
+			Keys:            pc.FallbackKeys(),
 			Model:           model,
 			ReasoningModel:  reasoningModel,
 			HaikuModel:      haikuModel,
@@ -257,7 +354,15 @@ func (pp *ProfileProxy) buildProviders(names []string, profileWeights map[string
 			ClaudeEnvVars:   pc.ClaudeEnvVars,
 			CodexEnvVars:    pc.CodexEnvVars,
 			OpenCodeEnvVars: pc.OpenCodeEnvVars,
+			GeminiEnvVars:   pc.GeminiEnvVars,
+			AiderEnvVars:    pc.AiderEnvVars,
 			ProxyURL:        pc.ProxyURL,
+			SafetySettings:  pc.SafetySettings,
+			Transforms:      pc.Transforms,
+			CaptureBodies:   pc.CaptureBodies,
+			MaxConcurrent:   pc.MaxConcurrent,
+			QueueSize:       pc.GetQueueSize(),
+			QueueTimeout:    pc.GetQueueTimeout(),
 			Weight:          weight,
 			Healthy:         true,
 		}
@@ -282,9 +387,22 @@ func (pp *ProfileProxy) buildProviders(names []string, profileWeights map[string
 }
 
 // getOrCreateProxy returns a cached ProxyServer for the profile, or creates one.
-func (pp *ProfileProxy) getOrCreateProxy(profile string, providers []*Provider, routing *RoutingConfig, strategy config.LoadBalanceStrategy) *ProxyServer {
+func (pp *ProfileProxy) getOrCreateProxy(profile string, providers []*Provider, routing *RoutingConfig, strategy config.LoadBalanceStrategy, cacheAffinity bool) *ProxyServer {
+	return pp.getOrCreateProxyFor(profile, profile, providers, routing, strategy, cacheAffinity, proxyExtras{})
+}
+
+// proxyExtras holds optional per-profile request handling for a new ProxyServer.
+type proxyExtras struct {
+	shadow    *shadowTarget
+	transform *config.RequestTransform
+}
+
+// getOrCreateProxyFor is getOrCreateProxy with a cache key distinct from the
+// profile name, used for requests pinned to a provider, and optional
+// shadow traffic and request transform settings.
+func (pp *ProfileProxy) getOrCreateProxyFor(key, profile string, providers []*Provider, routing *RoutingConfig, strategy config.LoadBalanceStrategy, cacheAffinity bool, extras proxyExtras) *ProxyServer {
 	pp.mu.RLock()
-	if srv, ok := pp.cache[profile]; ok {
+	if srv, ok := pp.cache[key]; ok {
 		pp.mu.RUnlock()
 		return srv
 	}
@@ -294,7 +412,7 @@ func (pp *ProfileProxy) getOrCreateProxy(profile string, providers []*Provider,
 	defer pp.mu.Unlock()
 
 	// Double-check after acquiring write lock
-	if srv, ok := pp.cache[profile]; ok {
+	if srv, ok := pp.cache[key]; ok {
 		return srv
 	}
 
@@ -308,11 +426,14 @@ func (pp *ProfileProxy) getOrCreateProxy(profile string, providers []*Provider,
 		srv = NewProxyServer(providers, pp.Logger, strategy, lb)
 	}
 	srv.Profile = profile
+	srv.CacheAffinity = cacheAffinity
+	srv.Shadow = extras.shadow
+	srv.RequestTransform = extras.transform
 	// Set concurrency limiter (100 concurrent requests as per spec)
 	srv.Limiter = NewLimiter(100)
 	// Pass through metrics recorder from ProfileProxy to ProxyServer
 	srv.MetricsRecorder = pp.MetricsRecorder
-	pp.cache[profile] = srv
+	pp.cache[key] = srv
 	return srv
 }
 
@@ -179,19 +179,19 @@ func TestProfileProxyGetOrCreateProxy(t *testing.T) {
 	}
 
 	// First call creates
-	srv1 := pp.getOrCreateProxy("prof1", providers, nil, config.LoadBalanceFailover)
+	srv1 := pp.getOrCreateProxy("prof1", providers, nil, config.LoadBalanceFailover, false)
 	if srv1 == nil {
 		t.Fatal("expected non-nil proxy server")
 	}
 
 	// Second call returns cached
-	srv2 := pp.getOrCreateProxy("prof1", providers, nil, config.LoadBalanceFailover)
+	srv2 := pp.getOrCreateProxy("prof1", providers, nil, config.LoadBalanceFailover, false)
 	if srv1 != srv2 {
 		t.Error("expected same cached proxy server")
 	}
 
 	// Different profile creates new
-	srv3 := pp.getOrCreateProxy("prof2", providers, nil, config.LoadBalanceFailover)
+	srv3 := pp.getOrCreateProxy("prof2", providers, nil, config.LoadBalanceFailover, false)
 	if srv3 == srv1 {
 		t.Error("expected different proxy server for different profile")
 	}
@@ -217,7 +217,7 @@ func TestProfileProxyGetOrCreateProxyWithRouting(t *testing.T) {
 		},
 	}
 
-	srv := pp.getOrCreateProxy("routed", defaultProviders, routing, config.LoadBalanceFailover)
+	srv := pp.getOrCreateProxy("routed", defaultProviders, routing, config.LoadBalanceFailover, false)
 	if srv == nil {
 		t.Fatal("expected non-nil proxy server")
 	}
@@ -22,28 +22,77 @@ const (
 
 type Provider struct {
 	Name            string
-	Type            string // "anthropic" or "openai"
+	Type            string // "anthropic", "openai", or "gemini"
 	BaseURL         *url.URL
 	Token           string
+	Keys            []*config.ProviderKey // Fallback tokens, in the order they are tried
 	Model           string
 	ReasoningModel  string
 	HaikuModel      string
 	OpusModel       string
 	SonnetModel     string
-	EnvVars         map[string]string // Legacy env vars (for backward compat)
-	ClaudeEnvVars   map[string]string // Claude Code specific
-	CodexEnvVars    map[string]string // Codex specific
-	OpenCodeEnvVars map[string]string // OpenCode specific
-	ProxyURL        string            // Proxy server URL (http/https/socks5)
-	Client          *http.Client      // Per-provider HTTP client (nil = use shared)
-	Weight          int               // Weight for weighted load balancing (0 = equal weight)
+	EnvVars         map[string]string          // Legacy env vars (for backward compat)
+	ClaudeEnvVars   map[string]string          // Claude Code specific
+	CodexEnvVars    map[string]string          // Codex specific
+	OpenCodeEnvVars map[string]string          // OpenCode specific
+	GeminiEnvVars   map[string]string          // Gemini CLI specific
+	AiderEnvVars    map[string]string          // Aider specific
+	ProxyURL        string                     // Proxy server URL (http/https/socks5)
+	SafetySettings  map[string]string          // Gemini safety settings (category → threshold)
+	Transforms      *config.ProviderTransforms // Declarative header/body rewrites
+	CaptureBodies   bool                       // Store request/response bodies for debugging
+	MaxConcurrent   int                        // Max in-flight requests (0 = unlimited)
+	QueueSize       int                        // Requests allowed to wait for a slot when MaxConcurrent is reached
+	QueueTimeout    time.Duration              // Max wait for a slot
+	Client          *http.Client               // Per-provider HTTP client (nil = use shared)
+	Weight          int                        // Weight for weighted load balancing (0 = equal weight)
 	Healthy         bool
 	AuthFailed      bool
 	FailedAt        time.Time
 	Backoff         time.Duration
+	keyIndex        int // 0 = Token, i = Keys[i-1]
 	mu              sync.Mutex
 }
 
+// AuthToken returns the token requests are currently sent with.
+func (p *Provider) AuthToken() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.keyIndex == 0 {
+		return p.Token
+	}
+	return p.Keys[p.keyIndex-1].Token
+}
+
+// KeyID returns the ID of the key requests are currently sent with.
+func (p *Provider) KeyID() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.keyIDLocked()
+}
+
+func (p *Provider) keyIDLocked() string {
+	if p.keyIndex == 0 {
+		return config.PrimaryKeyID
+	}
+	return p.Keys[p.keyIndex-1].ID
+}
+
+// NextKey moves on from a key the provider rejected and returns the ID of
+// the key to retry with, or "" when there is no fallback left. If another
+// request already moved on from rejected, the key it moved to is returned.
+func (p *Provider) NextKey(rejected string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.keyIDLocked() == rejected {
+		if p.keyIndex >= len(p.Keys) {
+			return ""
+		}
+		p.keyIndex++
+	}
+	return p.keyIDLocked()
+}
+
 // GetType returns the provider type, defaulting to "anthropic".
 func (p *Provider) GetType() string {
 	if p.Type == "" {
@@ -63,6 +112,14 @@ func (p *Provider) GetEnvVarsForClient(client string) map[string]string {
 		if len(p.OpenCodeEnvVars) > 0 {
 			return p.OpenCodeEnvVars
 		}
+	case "gemini":
+		if len(p.GeminiEnvVars) > 0 {
+			return p.GeminiEnvVars
+		}
+	case "aider":
+		if len(p.AiderEnvVars) > 0 {
+			return p.AiderEnvVars
+		}
 	default: // claude
 		if len(p.ClaudeEnvVars) > 0 {
 			return p.ClaudeEnvVars
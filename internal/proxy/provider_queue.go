@@ -0,0 +1,228 @@
+package proxy
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// errQueueFull is returned when a provider's wait queue is at capacity.
+var errQueueFull = errors.New("provider queue full")
+
+// ProviderQueue limits in-flight requests to one provider. Requests over the
+// limit wait in a bounded FIFO until a slot frees up or their timeout expires.
+type ProviderQueue struct {
+	mu          sync.Mutex
+	maxInFlight int
+	maxQueue    int
+	timeout     time.Duration
+	inFlight    int
+	waiters     *list.List // of chan struct{}, oldest first
+
+	queued    int64
+	admitted  int64
+	rejected  int64
+	timedOut  int64
+	totalWait time.Duration
+	maxWait   time.Duration
+}
+
+// ProviderQueueStats holds queue depth and wait metrics for one provider.
+type ProviderQueueStats struct {
+	MaxConcurrent int     `json:"max_concurrent"`
+	InFlight      int     `json:"in_flight"`
+	Waiting       int     `json:"waiting"`
+	QueueSize     int     `json:"queue_size"`
+	Queued        int64   `json:"queued"`
+	Rejected      int64   `json:"rejected"`
+	TimedOut      int64   `json:"timed_out"`
+	AvgWaitMs     float64 `json:"avg_wait_ms"`
+	MaxWaitMs     float64 `json:"max_wait_ms"`
+}
+
+// providerQueues holds one queue per provider name, shared by every profile
+// that routes to the provider.
+var providerQueues = struct {
+	mu     sync.Mutex
+	queues map[string]*ProviderQueue
+}{queues: make(map[string]*ProviderQueue)}
+
+// providerQueueFor returns the queue for p, creating it or applying changed
+// limits as needed. It returns nil when p has no concurrency limit.
+func providerQueueFor(p *Provider) *ProviderQueue {
+	providerQueues.mu.Lock()
+	defer providerQueues.mu.Unlock()
+	q := providerQueues.queues[p.Name]
+	if p.MaxConcurrent <= 0 {
+		if q != nil {
+			// Limit removed: let anyone still waiting through.
+			q.setLimits(0, 0, 0)
+			delete(providerQueues.queues, p.Name)
+		}
+		return nil
+	}
+	if q == nil {
+		q = NewProviderQueue(p.MaxConcurrent, p.QueueSize, p.QueueTimeout)
+		providerQueues.queues[p.Name] = q
+		return q
+	}
+	q.setLimits(p.MaxConcurrent, p.QueueSize, p.QueueTimeout)
+	return q
+}
+
+// GetProviderQueueStats returns queue metrics for every provider with a
+// concurrency limit, keyed by provider name.
+func GetProviderQueueStats() map[string]ProviderQueueStats {
+	providerQueues.mu.Lock()
+	defer providerQueues.mu.Unlock()
+	stats := make(map[string]ProviderQueueStats, len(providerQueues.queues))
+	for name, q := range providerQueues.queues {
+		stats[name] = q.Stats()
+	}
+	return stats
+}
+
+// NewProviderQueue creates a queue allowing maxInFlight concurrent requests
+// and up to maxQueue waiters, each waiting at most timeout (0 = no timeout).
+func NewProviderQueue(maxInFlight, maxQueue int, timeout time.Duration) *ProviderQueue {
+	return &ProviderQueue{
+		maxInFlight: maxInFlight,
+		maxQueue:    maxQueue,
+		timeout:     timeout,
+		waiters:     list.New(),
+	}
+}
+
+// setLimits updates the queue's limits, admitting waiters if the
+// concurrency limit grew. A maxInFlight of 0 admits everyone.
+func (q *ProviderQueue) setLimits(maxInFlight, maxQueue int, timeout time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.maxInFlight = maxInFlight
+	q.maxQueue = maxQueue
+	q.timeout = timeout
+	for q.waiters.Len() > 0 && (maxInFlight <= 0 || q.inFlight < maxInFlight) {
+		q.admitLocked()
+	}
+}
+
+// admitLocked hands a slot to the oldest waiter.
+func (q *ProviderQueue) admitLocked() {
+	el := q.waiters.Front()
+	q.waiters.Remove(el)
+	q.inFlight++
+	close(el.Value.(chan struct{}))
+}
+
+// Acquire takes a slot, waiting in line if the provider is at capacity.
+// It fails immediately when the queue is full, and after the queue timeout
+// or when ctx is cancelled while waiting.
+func (q *ProviderQueue) Acquire(ctx context.Context) error {
+	q.mu.Lock()
+	if q.maxInFlight <= 0 || (q.inFlight < q.maxInFlight && q.waiters.Len() == 0) {
+		q.inFlight++
+		q.mu.Unlock()
+		return nil
+	}
+	if q.waiters.Len() >= q.maxQueue {
+		q.rejected++
+		q.mu.Unlock()
+		return errQueueFull
+	}
+	ready := make(chan struct{})
+	el := q.waiters.PushBack(ready)
+	q.queued++
+	timeout := q.timeout
+	q.mu.Unlock()
+
+	start := time.Now()
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	var err error
+	select {
+	case <-ready:
+	case <-expired:
+		err = fmt.Errorf("timed out after %v waiting for a provider slot", timeout)
+	case <-ctx.Done():
+		err = fmt.Errorf("request cancelled while waiting for a provider slot: %w", ctx.Err())
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err != nil {
+		select {
+		case <-ready:
+			// Admitted just as we gave up; pass the slot on.
+			q.releaseLocked()
+		default:
+			q.waiters.Remove(el)
+		}
+		if ctx.Err() == nil {
+			q.timedOut++
+		}
+		return err
+	}
+	wait := time.Since(start)
+	q.admitted++
+	q.totalWait += wait
+	if wait > q.maxWait {
+		q.maxWait = wait
+	}
+	return nil
+}
+
+// Release frees a slot, admitting the oldest waiter if there is one.
+func (q *ProviderQueue) Release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.releaseLocked()
+}
+
+func (q *ProviderQueue) releaseLocked() {
+	q.inFlight--
+	if q.waiters.Len() > 0 && (q.maxInFlight <= 0 || q.inFlight < q.maxInFlight) {
+		q.admitLocked()
+	}
+}
+
+// Stats returns the queue's current depth and wait metrics.
+func (q *ProviderQueue) Stats() ProviderQueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	stats := ProviderQueueStats{
+		MaxConcurrent: q.maxInFlight,
+		InFlight:      q.inFlight,
+		Waiting:       q.waiters.Len(),
+		QueueSize:     q.maxQueue,
+		Queued:        q.queued,
+		Rejected:      q.rejected,
+		TimedOut:      q.timedOut,
+		MaxWaitMs:     float64(q.maxWait.Microseconds()) / 1000,
+	}
+	if q.admitted > 0 {
+		stats.AvgWaitMs = float64(q.totalWait.Microseconds()) / 1000 / float64(q.admitted)
+	}
+	return stats
+}
+
+// releaseOnClose releases a provider slot when the response body is closed.
+type releaseOnClose struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (r *releaseOnClose) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(r.release)
+	return err
+}
@@ -0,0 +1,174 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+func TestProviderQueueFIFO(t *testing.T) {
+	q := NewProviderQueue(1, 10, time.Second)
+	if err := q.Acquire(context.Background()); err != nil {
+		t.Fatalf("Acquire() error: %v", err)
+	}
+
+	var mu sync.Mutex
+	var order []int
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := q.Acquire(context.Background()); err != nil {
+				t.Errorf("waiter %d: %v", i, err)
+				return
+			}
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+			q.Release()
+		}(i)
+		// Make sure waiters enqueue in order
+		for q.Stats().Waiting != i+1 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	q.Release()
+	wg.Wait()
+	if len(order) != 3 || order[0] != 0 || order[1] != 1 || order[2] != 2 {
+		t.Errorf("admission order = %v, want [0 1 2]", order)
+	}
+	stats := q.Stats()
+	if stats.InFlight != 0 || stats.Waiting != 0 || stats.Queued != 3 {
+		t.Errorf("stats = %+v, want idle queue with 3 queued", stats)
+	}
+}
+
+func TestProviderQueueFullAndTimeout(t *testing.T) {
+	q := NewProviderQueue(1, 1, 20*time.Millisecond)
+	q.Acquire(context.Background())
+
+	done := make(chan error, 1)
+	go func() { done <- q.Acquire(context.Background()) }()
+	for q.Stats().Waiting != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := q.Acquire(context.Background()); err != errQueueFull {
+		t.Errorf("Acquire() on full queue = %v, want errQueueFull", err)
+	}
+	if err := <-done; err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("queued Acquire() = %v, want timeout", err)
+	}
+
+	stats := q.Stats()
+	if stats.Rejected != 1 || stats.TimedOut != 1 || stats.Waiting != 0 || stats.InFlight != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+
+	// A cancelled waiter leaves the queue without counting as a timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() { done <- q.Acquire(ctx) }()
+	for q.Stats().Waiting != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err == nil {
+		t.Error("cancelled Acquire() should fail")
+	}
+	if stats := q.Stats(); stats.TimedOut != 1 || stats.Waiting != 0 {
+		t.Errorf("stats after cancel = %+v", stats)
+	}
+}
+
+func TestProviderQueueSetLimitsAdmitsWaiters(t *testing.T) {
+	q := NewProviderQueue(1, 10, 0)
+	q.Acquire(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- q.Acquire(context.Background()) }()
+	for q.Stats().Waiting != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	q.setLimits(2, 10, 0)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Acquire() error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("raising the limit did not admit the waiter")
+	}
+	if stats := q.Stats(); stats.InFlight != 2 {
+		t.Errorf("in flight = %d, want 2", stats.InFlight)
+	}
+}
+
+func TestProxyProviderQueue(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, peak := 0, 0
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > peak {
+			peak = inFlight
+		}
+		mu.Unlock()
+		io.ReadAll(r.Body)
+		time.Sleep(20 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"msg_1","content":[],"usage":{"input_tokens":1,"output_tokens":1}}`))
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	}))
+	defer backend.Close()
+
+	u, _ := url.Parse(backend.URL)
+	providers := []*Provider{{Name: "queued-provider", BaseURL: u, Token: "t", Healthy: true, MaxConcurrent: 2, QueueSize: 10, QueueTimeout: 5 * time.Second}}
+	srv := NewProxyServer(providers, discardLogger(), config.LoadBalanceFailover, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest("POST", "/v1/messages", strings.NewReader(`{"model":"m","messages":[]}`))
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, req)
+			if w.Code != 200 {
+				t.Errorf("status = %d, body: %s", w.Code, w.Body.String())
+			}
+		}()
+	}
+	wg.Wait()
+
+	if peak > 2 {
+		t.Errorf("peak upstream concurrency = %d, want <= 2", peak)
+	}
+	stats, ok := GetProviderQueueStats()["queued-provider"]
+	if !ok {
+		t.Fatal("no queue stats for provider")
+	}
+	if stats.InFlight != 0 || stats.MaxConcurrent != 2 {
+		t.Errorf("stats = %+v, want all slots released", stats)
+	}
+
+	// Removing the limit drops the queue
+	providers[0].MaxConcurrent = 0
+	if providerQueueFor(providers[0]) != nil {
+		t.Error("provider without a limit should have no queue")
+	}
+	if _, ok := GetProviderQueueStats()["queued-provider"]; ok {
+		t.Error("queue stats should be dropped with the limit")
+	}
+}
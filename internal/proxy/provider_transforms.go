@@ -0,0 +1,158 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// applyHeaderRules removes and sets headers according to the rules.
+// Removals run first so a header can be replaced by removing and setting it.
+func applyHeaderRules(h http.Header, rules *config.TransformRules) {
+	if rules == nil {
+		return
+	}
+	for _, name := range rules.RemoveHeaders {
+		h.Del(name)
+	}
+	for name, value := range rules.SetHeaders {
+		h.Set(name, value)
+	}
+}
+
+// applyBodyRules rewrites a JSON object body according to the rules.
+// Non-object or unparseable bodies are returned unchanged.
+func applyBodyRules(body []byte, rules *config.TransformRules) []byte {
+	if !rules.HasBodyRules() || len(body) == 0 {
+		return body
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return body
+	}
+	rewriteFields(data, rules)
+	out, err := json.Marshal(data)
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+// rewriteFields applies remove, rename, default and set rules in that order.
+func rewriteFields(data map[string]interface{}, rules *config.TransformRules) {
+	for _, path := range rules.RemoveFields {
+		deleteField(data, path)
+	}
+
+	// Rename in sorted order so overlapping rules behave deterministically.
+	from := make([]string, 0, len(rules.RenameFields))
+	for k := range rules.RenameFields {
+		from = append(from, k)
+	}
+	sort.Strings(from)
+	for _, oldPath := range from {
+		if v, ok := getField(data, oldPath); ok {
+			deleteField(data, oldPath)
+			setField(data, rules.RenameFields[oldPath], v)
+		}
+	}
+
+	for path, v := range rules.DefaultFields {
+		if _, ok := getField(data, path); !ok {
+			setField(data, path, v)
+		}
+	}
+	for path, v := range rules.SetFields {
+		setField(data, path, v)
+	}
+}
+
+// getField looks up a dotted path in nested JSON objects.
+func getField(data map[string]interface{}, path string) (interface{}, bool) {
+	parts := strings.Split(path, ".")
+	cur := data
+	for i, key := range parts {
+		v, ok := cur[key]
+		if !ok {
+			return nil, false
+		}
+		if i == len(parts)-1 {
+			return v, true
+		}
+		next, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		cur = next
+	}
+	return nil, false
+}
+
+// setField assigns a dotted path, creating intermediate objects as needed.
+// A non-object value in the way is replaced.
+func setField(data map[string]interface{}, path string, value interface{}) {
+	parts := strings.Split(path, ".")
+	cur := data
+	for _, key := range parts[:len(parts)-1] {
+		next, ok := cur[key].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			cur[key] = next
+		}
+		cur = next
+	}
+	cur[parts[len(parts)-1]] = value
+}
+
+// deleteField removes a dotted path if present.
+func deleteField(data map[string]interface{}, path string) {
+	parts := strings.Split(path, ".")
+	cur := data
+	for _, key := range parts[:len(parts)-1] {
+		next, ok := cur[key].(map[string]interface{})
+		if !ok {
+			return
+		}
+		cur = next
+	}
+	delete(cur, parts[len(parts)-1])
+}
+
+// applySSEBodyRules rewrites the JSON payload of each SSE data line.
+// Lines that are not JSON objects (e.g. "data: [DONE]") pass through untouched.
+func applySSEBodyRules(r io.Reader, rules *config.TransformRules) io.Reader {
+	if !rules.HasBodyRules() {
+		return r
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		defer pw.Close()
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+		for scanner.Scan() {
+			line := scanner.Bytes()
+			if payload, ok := bytes.CutPrefix(line, []byte("data:")); ok {
+				payload = bytes.TrimSpace(payload)
+				if len(payload) > 0 && payload[0] == '{' {
+					line = append([]byte("data: "), applyBodyRules(payload, rules)...)
+				}
+			}
+			if _, err := pw.Write(line); err != nil {
+				return
+			}
+			if _, err := pw.Write([]byte("\n")); err != nil {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			pw.CloseWithError(err)
+		}
+	}()
+	return pr
+}
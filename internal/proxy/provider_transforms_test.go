@@ -0,0 +1,154 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+func TestApplyBodyRules(t *testing.T) {
+	rules := &config.TransformRules{
+		RemoveFields:  []string{"metadata", "stream_options.include_usage"},
+		RenameFields:  map[string]string{"max_completion_tokens": "max_tokens"},
+		DefaultFields: map[string]interface{}{"temperature": 0.7, "top_p": 0.9},
+		SetFields:     map[string]interface{}{"extra.safe_mode": true},
+	}
+	body := `{"model":"m","max_completion_tokens":100,"top_p":0.5,"metadata":{"user_id":"u"},"stream_options":{"include_usage":true}}`
+
+	var out map[string]interface{}
+	if err := json.Unmarshal(applyBodyRules([]byte(body), rules), &out); err != nil {
+		t.Fatalf("invalid JSON output: %v", err)
+	}
+
+	if _, ok := out["metadata"]; ok {
+		t.Error("metadata should be removed")
+	}
+	if so := out["stream_options"].(map[string]interface{}); len(so) != 0 {
+		t.Errorf("stream_options.include_usage should be removed, got %v", so)
+	}
+	if _, ok := out["max_completion_tokens"]; ok {
+		t.Error("max_completion_tokens should be renamed")
+	}
+	if out["max_tokens"] != float64(100) {
+		t.Errorf("max_tokens = %v, want 100", out["max_tokens"])
+	}
+	if out["temperature"] != 0.7 {
+		t.Errorf("temperature = %v, want default 0.7", out["temperature"])
+	}
+	if out["top_p"] != 0.5 {
+		t.Errorf("top_p = %v, want existing 0.5 kept", out["top_p"])
+	}
+	if out["extra"].(map[string]interface{})["safe_mode"] != true {
+		t.Errorf("extra.safe_mode not set: %v", out["extra"])
+	}
+}
+
+func TestApplyBodyRules_NoRulesOrInvalidBody(t *testing.T) {
+	body := []byte(`{"a":1}`)
+	if got := applyBodyRules(body, nil); string(got) != string(body) {
+		t.Errorf("nil rules changed body: %s", got)
+	}
+	notJSON := []byte("not json")
+	rules := &config.TransformRules{SetFields: map[string]interface{}{"a": 2}}
+	if got := applyBodyRules(notJSON, rules); string(got) != string(notJSON) {
+		t.Errorf("invalid body changed: %s", got)
+	}
+}
+
+func TestApplyHeaderRules(t *testing.T) {
+	h := http.Header{}
+	h.Set("Authorization", "Bearer x")
+	h.Set("X-Keep", "1")
+	applyHeaderRules(h, &config.TransformRules{
+		RemoveHeaders: []string{"authorization"},
+		SetHeaders:    map[string]string{"api-key": "secret"},
+	})
+	if h.Get("Authorization") != "" {
+		t.Error("Authorization should be removed")
+	}
+	if h.Get("Api-Key") != "secret" {
+		t.Errorf("api-key = %q, want secret", h.Get("Api-Key"))
+	}
+	if h.Get("X-Keep") != "1" {
+		t.Error("unrelated header should be kept")
+	}
+}
+
+func TestApplySSEBodyRules(t *testing.T) {
+	input := "event: message\ndata: {\"reasoning\":\"x\",\"text\":\"hi\"}\n\ndata: [DONE]\n\n"
+	rules := &config.TransformRules{RenameFields: map[string]string{"reasoning": "reasoning_content"}}
+
+	out, _ := io.ReadAll(applySSEBodyRules(strings.NewReader(input), rules))
+	got := string(out)
+	if !strings.Contains(got, `"reasoning_content":"x"`) {
+		t.Errorf("data payload not rewritten: %q", got)
+	}
+	if !strings.Contains(got, "event: message\n") || !strings.Contains(got, "data: [DONE]\n\n") {
+		t.Errorf("non-JSON lines should pass through: %q", got)
+	}
+}
+
+func TestProviderTransforms_E2E(t *testing.T) {
+	var receivedBody map[string]interface{}
+	var receivedAuth, receivedKey string
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedAuth = r.Header.Get("Authorization")
+		receivedKey = r.Header.Get("api-key")
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &receivedBody)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Internal-Trace", "abc")
+		w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"text","text":"ok"}],"stop_reason":"done","usage":{"input_tokens":1,"output_tokens":1}}`))
+	}))
+	defer backend.Close()
+
+	u, _ := url.Parse(backend.URL)
+	providers := []*Provider{{
+		Name:    "quirky",
+		BaseURL: u,
+		Token:   "tok",
+		Healthy: true,
+		Transforms: &config.ProviderTransforms{
+			Request: &config.TransformRules{
+				RemoveHeaders: []string{"Authorization"},
+				SetHeaders:    map[string]string{"api-key": "tok"},
+				DefaultFields: map[string]interface{}{"temperature": 0.2},
+			},
+			Response: &config.TransformRules{
+				RemoveHeaders: []string{"X-Internal-Trace"},
+				SetFields:     map[string]interface{}{"stop_reason": "end_turn"},
+			},
+		},
+	}}
+	srv := NewProxyServer(providers, discardLogger(), config.LoadBalanceFailover, nil)
+
+	req := httptest.NewRequest("POST", "/v1/messages", strings.NewReader(
+		`{"model":"claude-sonnet-4-6","max_tokens":10,"messages":[{"role":"user","content":"hi"}]}`))
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("status = %d, body: %s", w.Code, w.Body.String())
+	}
+	if receivedAuth != "" || receivedKey != "tok" {
+		t.Errorf("headers: Authorization=%q api-key=%q", receivedAuth, receivedKey)
+	}
+	if receivedBody["temperature"] != 0.2 {
+		t.Errorf("temperature = %v, want 0.2", receivedBody["temperature"])
+	}
+	if w.Header().Get("X-Internal-Trace") != "" {
+		t.Error("response header should be removed")
+	}
+	var resp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp["stop_reason"] != "end_turn" {
+		t.Errorf("stop_reason = %v, want end_turn", resp["stop_reason"])
+	}
+}
@@ -0,0 +1,369 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/proxy/transform"
+)
+
+// replayTimeout bounds a replayed request.
+const replayTimeout = 5 * time.Minute
+
+// ReplayOptions says where to re-send a captured request. Empty fields keep
+// the captured provider and model.
+type ReplayOptions struct {
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
+}
+
+// ReplayResponse is one side of a replay: the captured response or the new one.
+type ReplayResponse struct {
+	Provider   string `json:"provider"`
+	Model      string `json:"model,omitempty"`
+	StatusCode int    `json:"status_code"`
+	LatencyMs  int    `json:"latency_ms,omitempty"`
+	Text       string `json:"text"`
+	Body       string `json:"body,omitempty"` // raw body when no text could be read from it
+	Error      string `json:"error,omitempty"`
+}
+
+// DiffLine is one line of a line diff. Op is "=" for a line both sides
+// have, "-" for one only the original has and "+" for one only the replay has.
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// ReplayResult compares a captured exchange with its replay.
+type ReplayResult struct {
+	RequestID string         `json:"request_id"`
+	SessionID string         `json:"session_id,omitempty"`
+	Original  ReplayResponse `json:"original"`
+	Replay    ReplayResponse `json:"replay"`
+	Identical bool           `json:"identical"`
+	Diff      []DiffLine     `json:"diff"`
+}
+
+// Replay re-sends a captured request, straight to the provider rather than
+// through the proxy, and diffs the text of the new response against the
+// captured one. The request is sent without streaming. Replaying against a
+// provider of another type translates the request; requests captured from
+// Gemini providers can only be replayed against Gemini providers.
+func Replay(ctx context.Context, capture *BodyCapture, opts ReplayOptions) (*ReplayResult, error) {
+	if capture.RequestTruncated {
+		return nil, fmt.Errorf("captured request is truncated")
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal([]byte(capture.RequestBody), &body); err != nil {
+		return nil, fmt.Errorf("captured request is not valid JSON")
+	}
+
+	sourceType := config.ProviderTypeAnthropic
+	if pc := config.GetProvider(capture.Provider); pc != nil {
+		sourceType = pc.GetType()
+	}
+	targetName := capture.Provider
+	if opts.Provider != "" {
+		targetName = opts.Provider
+	}
+	target := config.GetProvider(targetName)
+	if target == nil {
+		return nil, fmt.Errorf("provider %q not found", targetName)
+	}
+	targetType := target.GetType()
+	if sourceType == config.ProviderTypeGemini && targetType != config.ProviderTypeGemini {
+		return nil, fmt.Errorf("requests captured from Gemini providers can only be replayed against Gemini providers")
+	}
+
+	originalModel, _ := body["model"].(string)
+	model := opts.Model
+	if model == "" {
+		model = originalModel
+		if targetName != capture.Provider || model == "" {
+			model = target.Model
+		}
+	}
+	if model == "" {
+		return nil, fmt.Errorf("no model to replay with; pass one")
+	}
+
+	result := &ReplayResult{
+		RequestID: capture.RequestID,
+		SessionID: capture.SessionID,
+		Original: ReplayResponse{
+			Provider:   capture.Provider,
+			Model:      originalModel,
+			StatusCode: capture.StatusCode,
+		},
+		Replay: ReplayResponse{Provider: targetName, Model: model},
+	}
+	result.Original.Text, result.Original.Body = textOrBody([]byte(capture.ResponseBody))
+
+	if sourceType != config.ProviderTypeGemini {
+		body["model"] = model
+		delete(body, "stream")
+		delete(body, "stream_options")
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	if sourceType != targetType {
+		data, err = transform.GetTransformer(targetType).TransformRequest(data, sourceType)
+		if err != nil {
+			return nil, fmt.Errorf("translate request for %s: %w", targetType, err)
+		}
+	}
+
+	resp, latency, err := sendReplay(ctx, target, model, data)
+	result.Replay.LatencyMs = latency
+	if err != nil {
+		result.Replay.Error = err.Error()
+	} else {
+		result.Replay.StatusCode = resp.status
+		result.Replay.Text, result.Replay.Body = textOrBody(resp.body)
+		if resp.status/100 != 2 {
+			result.Replay.Error = fmt.Sprintf("status %d", resp.status)
+		}
+	}
+
+	result.Diff = DiffLines(result.Original.Text, result.Replay.Text)
+	result.Identical = result.Replay.Error == "" && result.Original.Text == result.Replay.Text
+	return result, nil
+}
+
+type replayResponse struct {
+	status int
+	body   []byte
+}
+
+// sendReplay posts a request body to the provider's completion endpoint.
+func sendReplay(ctx context.Context, pc *config.ProviderConfig, model string, body []byte) (*replayResponse, int, error) {
+	if pc.BaseURL == "" {
+		return nil, 0, fmt.Errorf("no base URL configured")
+	}
+	base, err := url.Parse(pc.BaseURL)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid base URL: %w", err)
+	}
+	token, err := config.ResolveSecret(pc.AuthToken)
+	if err != nil {
+		return nil, 0, err
+	}
+	client := &http.Client{Timeout: replayTimeout}
+	if pc.ProxyURL != "" {
+		client, err = NewHTTPClientWithProxy(pc.ProxyURL, replayTimeout)
+		if err != nil {
+			return nil, 0, fmt.Errorf("proxy client error: %w", err)
+		}
+		defer closeHTTPClientIdleConnections(client)
+	}
+
+	var path string
+	switch pc.GetType() {
+	case config.ProviderTypeOpenAI:
+		path = "/v1/chat/completions"
+	case config.ProviderTypeGemini:
+		path = transform.GeminiPath(model, false)
+	default:
+		path = "/v1/messages"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		singleJoiningSlash(base.String(), dedupVersionPrefix(base.Path, path)), bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	setAuthHeaders(req, pc.GetType(), token)
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, int(time.Since(start).Milliseconds()), err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+	latency := int(time.Since(start).Milliseconds())
+	if err != nil {
+		return nil, latency, err
+	}
+	return &replayResponse{status: resp.StatusCode, body: data}, latency, nil
+}
+
+// textOrBody returns the text of a response, or, when it has none, the raw
+// body so the caller can still see what came back.
+func textOrBody(body []byte) (string, string) {
+	if text, ok := ResponseText(body); ok {
+		return text, ""
+	}
+	return "", string(body)
+}
+
+// ResponseText reads the generated text from a completion response in any
+// of the supported API formats, streamed or not. ok is false if the body is
+// not a completion response.
+func ResponseText(body []byte) (text string, ok bool) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return "", false
+	}
+	if trimmed[0] == '{' || trimmed[0] == '[' {
+		var v interface{}
+		if json.Unmarshal(trimmed, &v) != nil {
+			return "", false
+		}
+		var sb strings.Builder
+		found := false
+		// Gemini may answer a non-streaming request with an array of chunks
+		if chunks, isArray := v.([]interface{}); isArray {
+			for _, c := range chunks {
+				if eventText(c, &sb) {
+					found = true
+				}
+			}
+			return sb.String(), found
+		}
+		found = eventText(v, &sb)
+		return sb.String(), found
+	}
+
+	// Server-sent events: read the text deltas of every data line
+	var sb strings.Builder
+	found := false
+	scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+	scanner.Buffer(make([]byte, 64*1024), 8<<20)
+	for scanner.Scan() {
+		data, isData := strings.CutPrefix(scanner.Text(), "data:")
+		if !isData {
+			continue
+		}
+		var v interface{}
+		if json.Unmarshal([]byte(strings.TrimSpace(data)), &v) != nil {
+			continue
+		}
+		if eventText(v, &sb) {
+			found = true
+		}
+	}
+	return sb.String(), found
+}
+
+// eventText appends the text in one response or stream event to sb and
+// reports whether the value looked like a completion response or event.
+func eventText(v interface{}, sb *strings.Builder) bool {
+	m, isMap := v.(map[string]interface{})
+	if !isMap {
+		return false
+	}
+	str := func(v interface{}) string {
+		s, _ := v.(string)
+		return s
+	}
+
+	// Anthropic message: content[].text
+	if content, has := m["content"].([]interface{}); has && m["type"] == "message" {
+		for _, block := range content {
+			if b, isMap := block.(map[string]interface{}); isMap {
+				sb.WriteString(str(b["text"]))
+			}
+		}
+		return true
+	}
+	// Anthropic stream: content_block_delta with a text delta
+	if t := str(m["type"]); strings.HasPrefix(t, "message_") || strings.HasPrefix(t, "content_block_") || t == "ping" {
+		if delta, has := m["delta"].(map[string]interface{}); has && t == "content_block_delta" {
+			sb.WriteString(str(delta["text"]))
+		}
+		return true
+	}
+	// OpenAI chat: choices[].message.content, or choices[].delta.content when streaming
+	if choices, has := m["choices"].([]interface{}); has {
+		for _, c := range choices {
+			choice, isMap := c.(map[string]interface{})
+			if !isMap {
+				continue
+			}
+			for _, key := range []string{"message", "delta"} {
+				if msg, has := choice[key].(map[string]interface{}); has {
+					sb.WriteString(str(msg["content"]))
+				}
+			}
+		}
+		return true
+	}
+	// Gemini: candidates[].content.parts[].text
+	if candidates, has := m["candidates"].([]interface{}); has {
+		for _, c := range candidates {
+			cand, _ := c.(map[string]interface{})
+			content, _ := cand["content"].(map[string]interface{})
+			parts, _ := content["parts"].([]interface{})
+			for _, p := range parts {
+				if part, isMap := p.(map[string]interface{}); isMap {
+					sb.WriteString(str(part["text"]))
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// DiffLines returns a line diff turning a into b.
+func DiffLines(a, b string) []DiffLine {
+	x, y := splitLines(a), splitLines(b)
+	// lcs[i][j] is the length of the longest common subsequence of x[i:] and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	diff := []DiffLine{}
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			diff = append(diff, DiffLine{Op: "=", Text: x[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, DiffLine{Op: "-", Text: x[i]})
+			i++
+		default:
+			diff = append(diff, DiffLine{Op: "+", Text: y[j]})
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		diff = append(diff, DiffLine{Op: "-", Text: x[i]})
+	}
+	for ; j < len(y); j++ {
+		diff = append(diff, DiffLine{Op: "+", Text: y[j]})
+	}
+	return diff
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -0,0 +1,119 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+func TestResponseText(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+		ok   bool
+	}{
+		{"anthropic", `{"type":"message","content":[{"type":"text","text":"hello"},{"type":"tool_use"}]}`, "hello", true},
+		{"openai", `{"choices":[{"message":{"content":"hi there"}}]}`, "hi there", true},
+		{"gemini", `{"candidates":[{"content":{"parts":[{"text":"a"},{"text":"b"}]}}]}`, "ab", true},
+		{"anthropic stream", "event: message_start\ndata: {\"type\":\"message_start\"}\n\n" +
+			"data: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"Hel\"}}\n\n" +
+			"data: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"lo\"}}\n\n", "Hello", true},
+		{"openai stream", "data: {\"choices\":[{\"delta\":{\"content\":\"a\"}}]}\n\ndata: {\"choices\":[{\"delta\":{\"content\":\"b\"}}]}\n\ndata: [DONE]\n\n", "ab", true},
+		{"error", `{"error":{"message":"overloaded"}}`, "", false},
+		{"not json", "bad gateway", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ResponseText([]byte(tt.body))
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("ResponseText = %q, %v; want %q, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	got := DiffLines("a\nb\nc", "a\nx\nc\nd")
+	want := []DiffLine{{"=", "a"}, {"-", "b"}, {"+", "x"}, {"=", "c"}, {"+", "d"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffLines = %v, want %v", got, want)
+	}
+	if got := DiffLines("", ""); len(got) != 0 {
+		t.Errorf("DiffLines of empty texts = %v", got)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	config.ResetDefaultStore()
+	t.Cleanup(config.ResetDefaultStore)
+
+	var got map[string]interface{}
+	var gotPath string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		body, _ := io.ReadAll(r.Body)
+		got = nil
+		json.Unmarshal(body, &got)
+		if r.URL.Path == "/v1/chat/completions" {
+			w.Write([]byte(`{"choices":[{"message":{"content":"line one\nline 2"}}]}`))
+			return
+		}
+		w.Write([]byte(`{"type":"message","content":[{"type":"text","text":"line one\nline two"}]}`))
+	}))
+	defer upstream.Close()
+	config.SetProvider("claude", &config.ProviderConfig{BaseURL: upstream.URL, AuthToken: "t", Model: "claude-sonnet"})
+	config.SetProvider("gpt", &config.ProviderConfig{Type: config.ProviderTypeOpenAI, BaseURL: upstream.URL, AuthToken: "t", Model: "gpt-default"})
+
+	capture := &BodyCapture{
+		RequestID:    "req_1",
+		Provider:     "claude",
+		SessionID:    "default:s1",
+		StatusCode:   200,
+		RequestBody:  `{"model":"claude-sonnet","stream":true,"max_tokens":100,"messages":[{"role":"user","content":"hi"}]}`,
+		ResponseBody: "data: {\"type\":\"content_block_delta\",\"delta\":{\"text\":\"line one\\nline two\"}}\n\n",
+	}
+
+	// Same provider: identical answer
+	res, err := Replay(context.Background(), capture, ReplayOptions{})
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if !res.Identical || gotPath != "/v1/messages" || got["stream"] != nil || got["model"] != "claude-sonnet" {
+		t.Errorf("same-provider replay: identical=%v path=%s body=%v", res.Identical, gotPath, got)
+	}
+
+	// Another provider type: translated request, provider's default model
+	res, err = Replay(context.Background(), capture, ReplayOptions{Provider: "gpt"})
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if gotPath != "/v1/chat/completions" || got["model"] != "gpt-default" {
+		t.Errorf("cross-provider replay: path=%s body=%v", gotPath, got)
+	}
+	want := []DiffLine{{"=", "line one"}, {"-", "line two"}, {"+", "line 2"}}
+	if res.Identical || !reflect.DeepEqual(res.Diff, want) {
+		t.Errorf("diff = %v, want %v", res.Diff, want)
+	}
+
+	// Model override
+	if _, err := Replay(context.Background(), capture, ReplayOptions{Model: "claude-opus"}); err != nil || got["model"] != "claude-opus" {
+		t.Errorf("model override: model=%v err=%v", got["model"], err)
+	}
+
+	if _, err := Replay(context.Background(), capture, ReplayOptions{Provider: "missing"}); err == nil {
+		t.Error("replay against a missing provider succeeded")
+	}
+	truncated := *capture
+	truncated.RequestTruncated = true
+	if _, err := Replay(context.Background(), &truncated, ReplayOptions{}); err == nil {
+		t.Error("replay of a truncated request succeeded")
+	}
+}
@@ -1,6 +1,8 @@
 package proxy
 
 import (
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 )
@@ -25,6 +27,12 @@ type RequestRecord struct {
 	ErrorMessage  string            `json:"error_message,omitempty"`
 }
 
+// Failed reports whether the request failed: it got an error status, or no
+// response at all.
+func (r RequestRecord) Failed() bool {
+	return r.StatusCode >= 400 || (r.StatusCode == 0 && r.ErrorMessage != "")
+}
+
 // ProviderAttempt represents a single attempt to forward a request to a provider
 // (part of failover chain).
 type ProviderAttempt struct {
@@ -41,17 +49,20 @@ type RequestMonitor struct {
 	mu         sync.RWMutex
 	records    []RequestRecord
 	maxRecords int
+	onAdd      func(RequestRecord)
 }
 
 // RequestFilter defines criteria for filtering request records.
 type RequestFilter struct {
-	Provider  string    // Filter by provider name (empty = all)
-	SessionID string    // Filter by session ID (empty = all)
-	MinStatus int       // Minimum status code (0 = no filter)
-	MaxStatus int       // Maximum status code (0 = no filter)
-	StartTime time.Time // Start of time range (zero = no filter)
-	EndTime   time.Time // End of time range (zero = no filter)
-	Model     string    // Filter by model name (empty = all)
+	Provider   string    // Filter by provider name (empty = all)
+	SessionID  string    // Filter by session ID (empty = all)
+	MinStatus  int       // Minimum status code (0 = no filter)
+	MaxStatus  int       // Maximum status code (0 = no filter)
+	StartTime  time.Time // Start of time range (zero = no filter)
+	EndTime    time.Time // End of time range (zero = no filter)
+	Model      string    // Filter by model name (empty = all)
+	ErrorsOnly bool      // Only failed requests: an error status or no response
+	Project    string    // Only sessions in this project directory or below it (empty = all)
 }
 
 // NewRequestMonitor creates a new RequestMonitor with the specified buffer size.
@@ -66,7 +77,6 @@ func NewRequestMonitor(maxRecords int) *RequestMonitor {
 // If the buffer is full, it evicts the oldest 20% of records (LRU eviction).
 func (rm *RequestMonitor) Add(record RequestRecord) {
 	rm.mu.Lock()
-	defer rm.mu.Unlock()
 
 	// If buffer is full, evict oldest 20%
 	if len(rm.records) >= rm.maxRecords {
@@ -78,6 +88,20 @@ func (rm *RequestMonitor) Add(record RequestRecord) {
 	}
 
 	rm.records = append(rm.records, record)
+	onAdd := rm.onAdd
+	rm.mu.Unlock()
+
+	if onAdd != nil {
+		onAdd(record)
+	}
+}
+
+// OnAdd sets a function called with each record added, after it is stored.
+// It runs on the request's goroutine, so it should not block.
+func (rm *RequestMonitor) OnAdd(fn func(RequestRecord)) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.onAdd = fn
 }
 
 // GetRecent returns the most recent request records matching the filter criteria,
@@ -86,6 +110,11 @@ func (rm *RequestMonitor) GetRecent(limit int, filter RequestFilter) []RequestRe
 	rm.mu.RLock()
 	defer rm.mu.RUnlock()
 
+	project := filter.Project
+	if project != "" {
+		project = filepath.Clean(project)
+	}
+
 	// Filter records
 	var filtered []RequestRecord
 	for i := len(rm.records) - 1; i >= 0; i-- {
@@ -113,6 +142,15 @@ func (rm *RequestMonitor) GetRecent(limit int, filter RequestFilter) []RequestRe
 		if !filter.EndTime.IsZero() && record.Timestamp.After(filter.EndTime) {
 			continue
 		}
+		if filter.ErrorsOnly && !record.Failed() {
+			continue
+		}
+		if project != "" {
+			p := GetSessionProject(record.SessionID)
+			if p != project && !strings.HasPrefix(p, project+string(filepath.Separator)) {
+				continue
+			}
+		}
 
 		filtered = append(filtered, record)
 
@@ -2,6 +2,7 @@ package proxy
 
 import (
 	"encoding/json"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -57,6 +58,24 @@ func TestRequestMonitor_Add(t *testing.T) {
 	})
 }
 
+// TestRequestMonitor_OnAdd verifies the add hook sees each stored record
+func TestRequestMonitor_OnAdd(t *testing.T) {
+	monitor := NewRequestMonitor(10)
+	var seen []string
+	monitor.OnAdd(func(rec RequestRecord) {
+		// The record is stored before the hook runs
+		if got := monitor.GetRecent(1, RequestFilter{}); len(got) != 1 || got[0].ID != rec.ID {
+			t.Errorf("record %s not stored before hook", rec.ID)
+		}
+		seen = append(seen, rec.ID)
+	})
+	monitor.Add(RequestRecord{ID: "1"})
+	monitor.Add(RequestRecord{ID: "2"})
+	if len(seen) != 2 || seen[0] != "1" || seen[1] != "2" {
+		t.Errorf("seen = %v", seen)
+	}
+}
+
 // TestRequestMonitor_GetRecent verifies reverse chronological order
 func TestRequestMonitor_GetRecent(t *testing.T) {
 	monitor := NewRequestMonitor(100)
@@ -300,6 +319,45 @@ func TestRequestMonitor_FilterByModel(t *testing.T) {
 	})
 }
 
+func TestRequestMonitor_FilterErrorsAndProject(t *testing.T) {
+	monitor := NewRequestMonitor(100)
+	now := time.Now()
+	SetSessionProject("mon-app", "/work/app")
+	SetSessionProject("mon-sub", "/work/app/web")
+	SetSessionProject("mon-other", "/work/application")
+	t.Cleanup(func() {
+		for _, id := range []string{"mon-app", "mon-sub", "mon-other"} {
+			SetSessionProject(id, "")
+		}
+	})
+
+	for _, r := range []RequestRecord{
+		{ID: "req1", Timestamp: now, SessionID: "mon-app", StatusCode: 200},
+		{ID: "req2", Timestamp: now, SessionID: "mon-sub", StatusCode: 529},
+		{ID: "req3", Timestamp: now, SessionID: "mon-other", ErrorMessage: "connection refused"},
+		{ID: "req4", Timestamp: now, SessionID: "mon-none", StatusCode: 200},
+	} {
+		monitor.Add(r)
+	}
+
+	ids := func(records []RequestRecord) string {
+		var s []string
+		for _, r := range records {
+			s = append(s, r.ID)
+		}
+		return strings.Join(s, ",")
+	}
+	if got := ids(monitor.GetRecent(10, RequestFilter{ErrorsOnly: true})); got != "req3,req2" {
+		t.Errorf("errors only = %s, want req3,req2", got)
+	}
+	if got := ids(monitor.GetRecent(10, RequestFilter{Project: "/work/app/"})); got != "req2,req1" {
+		t.Errorf("project = %s, want req2,req1", got)
+	}
+	if got := ids(monitor.GetRecent(10, RequestFilter{Project: "/work/app", ErrorsOnly: true})); got != "req2" {
+		t.Errorf("project errors = %s, want req2", got)
+	}
+}
+
 // TestRequestMonitor_FilterCombinations verifies multiple filters work together
 func TestRequestMonitor_FilterCombinations(t *testing.T) {
 	monitor := NewRequestMonitor(100)
@@ -0,0 +1,231 @@
+package proxy
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/proxy/transform"
+)
+
+// applyRequestTransform rewrites a request body with a profile's transform.
+// requestFormat selects where the system prompt, tools and token limit live.
+// Bodies that are not JSON objects are returned unchanged.
+func applyRequestTransform(body []byte, t *config.RequestTransform, requestFormat string) []byte {
+	if t.IsEmpty() || len(body) == 0 {
+		return body
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return body
+	}
+
+	strip := compileStripPatterns(t.StripSystem)
+	switch requestFormat {
+	case transform.FormatOpenAIChat:
+		transformChatSystem(data, t, strip)
+	case transform.FormatOpenAIResponses:
+		data["instructions"] = rewriteSystemText(stringField(data, "instructions"), t, strip)
+		if data["instructions"] == "" {
+			delete(data, "instructions")
+		}
+	default:
+		transformAnthropicSystem(data, t, strip)
+	}
+
+	stripTools(data, t.StripTools)
+	capMaxTokens(data, t.MaxTokensCap, requestFormat)
+	if t.Temperature != nil {
+		data["temperature"] = *t.Temperature
+	}
+
+	out, err := json.Marshal(data)
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+// compileStripPatterns compiles the strip patterns, skipping invalid ones
+// (config validation rejects them before they get here).
+func compileStripPatterns(patterns []string) []*regexp.Regexp {
+	var res []*regexp.Regexp
+	for _, p := range patterns {
+		if re, err := regexp.Compile(p); err == nil {
+			res = append(res, re)
+		}
+	}
+	return res
+}
+
+func stripText(text string, strip []*regexp.Regexp) string {
+	for _, re := range strip {
+		text = re.ReplaceAllString(text, "")
+	}
+	return text
+}
+
+// rewriteSystemText strips patterns from a plain-text system prompt and adds
+// the configured prefix and suffix.
+func rewriteSystemText(text string, t *config.RequestTransform, strip []*regexp.Regexp) string {
+	text = strings.TrimSpace(stripText(text, strip))
+	var parts []string
+	for _, p := range []string{t.SystemPrepend, text, t.SystemAppend} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+func stringField(data map[string]interface{}, key string) string {
+	s, _ := data[key].(string)
+	return s
+}
+
+// transformAnthropicSystem rewrites the Anthropic "system" field, which is
+// either a string or a list of content blocks.
+func transformAnthropicSystem(data map[string]interface{}, t *config.RequestTransform, strip []*regexp.Regexp) {
+	blocks, ok := data["system"].([]interface{})
+	if !ok {
+		if text := rewriteSystemText(stringField(data, "system"), t, strip); text != "" {
+			data["system"] = text
+		} else {
+			delete(data, "system")
+		}
+		return
+	}
+
+	result := make([]interface{}, 0, len(blocks)+2)
+	if t.SystemPrepend != "" {
+		result = append(result, map[string]interface{}{"type": "text", "text": t.SystemPrepend})
+	}
+	for _, b := range blocks {
+		if block, ok := b.(map[string]interface{}); ok && len(strip) > 0 {
+			if text, ok := block["text"].(string); ok {
+				block["text"] = stripText(text, strip)
+			}
+		}
+		result = append(result, b)
+	}
+	if t.SystemAppend != "" {
+		result = append(result, map[string]interface{}{"type": "text", "text": t.SystemAppend})
+	}
+	data["system"] = result
+}
+
+// transformChatSystem rewrites the system and developer messages of an
+// OpenAI Chat Completions request. The prefix becomes the first message and
+// the suffix follows the leading system messages.
+func transformChatSystem(data map[string]interface{}, t *config.RequestTransform, strip []*regexp.Regexp) {
+	messages, _ := data["messages"].([]interface{})
+	leading := 0
+	for i, m := range messages {
+		msg, _ := m.(map[string]interface{})
+		role, _ := msg["role"].(string)
+		if role != "system" && role != "developer" {
+			continue
+		}
+		if i == leading {
+			leading++
+		}
+		if len(strip) == 0 {
+			continue
+		}
+		switch content := msg["content"].(type) {
+		case string:
+			msg["content"] = stripText(content, strip)
+		case []interface{}:
+			for _, p := range content {
+				if part, ok := p.(map[string]interface{}); ok {
+					if text, ok := part["text"].(string); ok {
+						part["text"] = stripText(text, strip)
+					}
+				}
+			}
+		}
+	}
+
+	result := make([]interface{}, 0, len(messages)+2)
+	if t.SystemPrepend != "" {
+		result = append(result, map[string]interface{}{"role": "system", "content": t.SystemPrepend})
+	}
+	result = append(result, messages[:leading]...)
+	if t.SystemAppend != "" {
+		result = append(result, map[string]interface{}{"role": "system", "content": t.SystemAppend})
+	}
+	result = append(result, messages[leading:]...)
+	data["messages"] = result
+}
+
+// toolName returns the name of a tool definition or tool_choice in any of
+// the supported formats.
+func toolName(v interface{}) string {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	if name, ok := m["name"].(string); ok {
+		return name
+	}
+	if fn, ok := m["function"].(map[string]interface{}); ok {
+		name, _ := fn["name"].(string)
+		return name
+	}
+	return ""
+}
+
+// stripTools removes tools whose names match a pattern. A tool_choice that
+// forces a removed tool, or any tool_choice once no tools remain, is dropped.
+func stripTools(data map[string]interface{}, patterns []string) {
+	tools, ok := data["tools"].([]interface{})
+	if !ok || len(patterns) == 0 {
+		return
+	}
+	kept := make([]interface{}, 0, len(tools))
+	for _, tool := range tools {
+		if name := toolName(tool); name == "" || !overrideAllowed(patterns, name) {
+			kept = append(kept, tool)
+		}
+	}
+	if len(kept) == len(tools) {
+		return
+	}
+	if len(kept) == 0 {
+		delete(data, "tools")
+		delete(data, "tool_choice")
+		return
+	}
+	data["tools"] = kept
+	if name := toolName(data["tool_choice"]); name != "" && overrideAllowed(patterns, name) {
+		delete(data, "tool_choice")
+	}
+}
+
+// capMaxTokens lowers the requested output limit to limit. OpenAI requests
+// may omit the limit, so one is added when none is set.
+func capMaxTokens(data map[string]interface{}, limit int, requestFormat string) {
+	if limit <= 0 {
+		return
+	}
+	fields, optional := []string{"max_tokens"}, false
+	switch requestFormat {
+	case transform.FormatOpenAIChat:
+		fields, optional = []string{"max_completion_tokens", "max_tokens"}, true
+	case transform.FormatOpenAIResponses:
+		fields, optional = []string{"max_output_tokens"}, true
+	}
+	found := false
+	for _, f := range fields {
+		if v, ok := data[f].(float64); ok {
+			found = true
+			if v > float64(limit) {
+				data[f] = limit
+			}
+		}
+	}
+	if !found && optional {
+		data[fields[len(fields)-1]] = limit
+	}
+}
@@ -0,0 +1,137 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/proxy/transform"
+)
+
+func transformBody(t *testing.T, body string, rt *config.RequestTransform, format string) map[string]interface{} {
+	t.Helper()
+	var data map[string]interface{}
+	if err := json.Unmarshal(applyRequestTransform([]byte(body), rt, format), &data); err != nil {
+		t.Fatalf("transformed body is not JSON: %v", err)
+	}
+	return data
+}
+
+func TestRequestTransformAnthropic(t *testing.T) {
+	temp := 0.2
+	rt := &config.RequestTransform{
+		SystemPrepend: "ORG",
+		SystemAppend:  "END",
+		StripSystem:   []string{`(?s)<env>.*?</env>`},
+		StripTools:    []string{"WebSearch", "mcp__browser__*"},
+		MaxTokensCap:  1000,
+		Temperature:   &temp,
+	}
+
+	body := `{"model":"m","max_tokens":32000,"system":"You are helpful.<env>secret</env>",` +
+		`"tools":[{"name":"Read"},{"name":"WebSearch"},{"name":"mcp__browser__click"}],` +
+		`"tool_choice":{"type":"tool","name":"WebSearch"},"messages":[]}`
+	data := transformBody(t, body, rt, config.ProviderTypeAnthropic)
+	if data["system"] != "ORG\n\nYou are helpful.\n\nEND" {
+		t.Errorf("system = %q", data["system"])
+	}
+	if tools := data["tools"].([]interface{}); len(tools) != 1 || toolName(tools[0]) != "Read" {
+		t.Errorf("tools = %v, want only Read", tools)
+	}
+	if _, ok := data["tool_choice"]; ok {
+		t.Error("tool_choice forcing a stripped tool should be removed")
+	}
+	if data["max_tokens"] != float64(1000) || data["temperature"] != 0.2 {
+		t.Errorf("max_tokens = %v, temperature = %v", data["max_tokens"], data["temperature"])
+	}
+
+	// Block-form system prompts keep their blocks (and cache_control)
+	body = `{"max_tokens":10,"system":[{"type":"text","text":"A<env>x</env>","cache_control":{"type":"ephemeral"}}],"messages":[]}`
+	data = transformBody(t, body, rt, transform.FormatAnthropicMessages)
+	blocks := data["system"].([]interface{})
+	if len(blocks) != 3 {
+		t.Fatalf("system blocks = %v, want 3", blocks)
+	}
+	first, mid, last := blocks[0].(map[string]interface{}), blocks[1].(map[string]interface{}), blocks[2].(map[string]interface{})
+	if first["text"] != "ORG" || mid["text"] != "A" || mid["cache_control"] == nil || last["text"] != "END" {
+		t.Errorf("system blocks = %v", blocks)
+	}
+	if data["max_tokens"] != float64(10) {
+		t.Errorf("max_tokens under the cap changed to %v", data["max_tokens"])
+	}
+}
+
+func TestRequestTransformOpenAIChat(t *testing.T) {
+	rt := &config.RequestTransform{
+		SystemPrepend: "ORG",
+		SystemAppend:  "END",
+		StripTools:    []string{"search"},
+		MaxTokensCap:  500,
+	}
+	body := `{"messages":[{"role":"system","content":"sys"},{"role":"user","content":"hi"}],` +
+		`"tools":[{"type":"function","function":{"name":"search"}}],"tool_choice":"auto"}`
+	data := transformBody(t, body, rt, transform.FormatOpenAIChat)
+
+	var roles, contents []string
+	for _, m := range data["messages"].([]interface{}) {
+		msg := m.(map[string]interface{})
+		roles = append(roles, msg["role"].(string))
+		contents = append(contents, msg["content"].(string))
+	}
+	if strings.Join(roles, ",") != "system,system,system,user" || strings.Join(contents, ",") != "ORG,sys,END,hi" {
+		t.Errorf("messages = %v %v", roles, contents)
+	}
+	if _, ok := data["tools"]; ok {
+		t.Error("empty tools should be removed")
+	}
+	if _, ok := data["tool_choice"]; ok {
+		t.Error("tool_choice without tools should be removed")
+	}
+	if data["max_tokens"] != float64(500) {
+		t.Errorf("max_tokens = %v, want cap added", data["max_tokens"])
+	}
+}
+
+func TestRequestTransformOpenAIResponses(t *testing.T) {
+	rt := &config.RequestTransform{SystemPrepend: "ORG", MaxTokensCap: 100}
+	data := transformBody(t, `{"input":"hi","max_output_tokens":4000}`, rt, transform.FormatOpenAIResponses)
+	if data["instructions"] != "ORG" || data["max_output_tokens"] != float64(100) {
+		t.Errorf("instructions = %v, max_output_tokens = %v", data["instructions"], data["max_output_tokens"])
+	}
+}
+
+func TestRequestTransformInvalidBody(t *testing.T) {
+	rt := &config.RequestTransform{SystemPrepend: "ORG"}
+	if got := applyRequestTransform([]byte("not json"), rt, config.ProviderTypeAnthropic); string(got) != "not json" {
+		t.Errorf("invalid body changed to %q", got)
+	}
+}
+
+func TestProxyAppliesRequestTransform(t *testing.T) {
+	var upstreamBody []byte
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamBody, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"ok","content":[],"usage":{"input_tokens":1,"output_tokens":1}}`))
+	}))
+	defer upstream.Close()
+
+	u, _ := url.Parse(upstream.URL)
+	srv := NewProxyServer([]*Provider{{Name: "p", BaseURL: u, Token: "t", Healthy: true}}, discardLogger(), config.LoadBalanceFailover, nil)
+	srv.RequestTransform = &config.RequestTransform{SystemPrepend: "ORG"}
+
+	req := httptest.NewRequest("POST", "/v1/messages", strings.NewReader(`{"model":"claude-sonnet-4-5","max_tokens":10,"messages":[]}`))
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != 200 {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(string(upstreamBody), `"system":"ORG"`) {
+		t.Errorf("upstream body = %s, want system prompt injected", upstreamBody)
+	}
+}
@@ -0,0 +1,299 @@
+package proxy
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// responseCacheHeader reports whether a response was served from the cache.
+const responseCacheHeader = "X-Zen-Cache"
+
+// maxCachedResponseBytes bounds a single cached response body; larger
+// responses are passed through without being stored.
+const maxCachedResponseBytes = 1024 * 1024
+
+// cachedResponse is a stored upstream response.
+type cachedResponse struct {
+	key          string
+	statusCode   int
+	contentType  string
+	body         []byte
+	inputTokens  int
+	outputTokens int
+	expires      time.Time
+}
+
+// ResponseCacheStats holds response cache counters since the daemon started.
+type ResponseCacheStats struct {
+	Enabled           bool    `json:"enabled"`
+	Entries           int     `json:"entries"`
+	Hits              int64   `json:"hits"`
+	Misses            int64   `json:"misses"`
+	HitRate           float64 `json:"hit_rate"`
+	SavedInputTokens  int64   `json:"saved_input_tokens"`
+	SavedOutputTokens int64   `json:"saved_output_tokens"`
+}
+
+// ResponseCache answers exact repeats of deterministic requests from memory.
+// Entries expire after the configured TTL and the least recently used entry
+// is evicted once the cache is full.
+type ResponseCache struct {
+	mu      sync.Mutex
+	config  *config.ResponseCacheConfig
+	entries map[string]*list.Element
+	lru     *list.List
+
+	hits              int64
+	misses            int64
+	savedInputTokens  int64
+	savedOutputTokens int64
+}
+
+// Global response cache instance
+var (
+	globalResponseCache     *ResponseCache
+	globalResponseCacheOnce sync.Once
+	globalResponseCacheMu   sync.RWMutex
+)
+
+// InitGlobalResponseCache initializes the global response cache from config.
+func InitGlobalResponseCache() {
+	globalResponseCacheOnce.Do(func() {
+		globalResponseCacheMu.Lock()
+		globalResponseCache = NewResponseCache(config.GetResponseCache())
+		globalResponseCacheMu.Unlock()
+	})
+}
+
+// GetGlobalResponseCache returns the global response cache.
+func GetGlobalResponseCache() *ResponseCache {
+	globalResponseCacheMu.RLock()
+	defer globalResponseCacheMu.RUnlock()
+	return globalResponseCache
+}
+
+// UpdateGlobalResponseCacheConfig updates the global response cache configuration.
+func UpdateGlobalResponseCacheConfig(cfg *config.ResponseCacheConfig) {
+	globalResponseCacheMu.RLock()
+	defer globalResponseCacheMu.RUnlock()
+	if globalResponseCache != nil {
+		globalResponseCache.UpdateConfig(cfg)
+	}
+}
+
+// NewResponseCache creates a response cache. A nil config disables caching.
+func NewResponseCache(cfg *config.ResponseCacheConfig) *ResponseCache {
+	return &ResponseCache{
+		config:  cfg,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// UpdateConfig replaces the configuration. Disabling the cache drops all
+// entries; a smaller size limit evicts the oldest ones.
+func (c *ResponseCache) UpdateConfig(cfg *config.ResponseCacheConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.config = cfg
+	if cfg == nil || !cfg.Enabled {
+		c.entries = make(map[string]*list.Element)
+		c.lru.Init()
+		return
+	}
+	c.evictLocked(cfg.GetMaxEntries())
+}
+
+// IsEnabled reports whether the cache is turned on.
+func (c *ResponseCache) IsEnabled() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.config != nil && c.config.Enabled
+}
+
+// Get returns the cached response for key, or nil on a miss.
+func (c *ResponseCache) Get(key string) *cachedResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if ok {
+		entry := el.Value.(*cachedResponse)
+		if time.Now().Before(entry.expires) {
+			c.lru.MoveToFront(el)
+			c.hits++
+			c.savedInputTokens += int64(entry.inputTokens)
+			c.savedOutputTokens += int64(entry.outputTokens)
+			return entry
+		}
+		c.lru.Remove(el)
+		delete(c.entries, key)
+	}
+	c.misses++
+	return nil
+}
+
+// Put stores a successful JSON response under key.
+func (c *ResponseCache) Put(key string, statusCode int, contentType string, body []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.config == nil || !c.config.Enabled {
+		return
+	}
+	entry := &cachedResponse{
+		key:         key,
+		statusCode:  statusCode,
+		contentType: contentType,
+		body:        body,
+		expires:     time.Now().Add(c.config.GetTTL()),
+	}
+	entry.inputTokens, entry.outputTokens = responseUsage(body)
+
+	if el, ok := c.entries[key]; ok {
+		el.Value = entry
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	c.evictLocked(c.config.GetMaxEntries())
+}
+
+// evictLocked drops least recently used entries until at most max remain.
+func (c *ResponseCache) evictLocked(max int) {
+	for c.lru.Len() > max {
+		el := c.lru.Back()
+		c.lru.Remove(el)
+		delete(c.entries, el.Value.(*cachedResponse).key)
+	}
+}
+
+// Stats returns the cache counters.
+func (c *ResponseCache) Stats() ResponseCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := ResponseCacheStats{
+		Enabled:           c.config != nil && c.config.Enabled,
+		Entries:           c.lru.Len(),
+		Hits:              c.hits,
+		Misses:            c.misses,
+		SavedInputTokens:  c.savedInputTokens,
+		SavedOutputTokens: c.savedOutputTokens,
+	}
+	if total := c.hits + c.misses; total > 0 {
+		stats.HitRate = float64(c.hits) / float64(total)
+	}
+	return stats
+}
+
+// responseCacheKey returns the cache key for a request, or "" when the
+// request is not cacheable. Only non-streaming requests that explicitly set
+// temperature to 0 are cached. The key covers the profile, the endpoint and
+// the body with keys sorted; request metadata (which carries per-session
+// user IDs) is ignored so identical prompts from different runs match.
+func responseCacheKey(profile, path string, body []byte) string {
+	var data map[string]interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return ""
+	}
+	if stream, _ := data["stream"].(bool); stream {
+		return ""
+	}
+	if temp, ok := data["temperature"].(float64); !ok || temp != 0 {
+		return ""
+	}
+	delete(data, "metadata")
+	canonical, err := json.Marshal(data)
+	if err != nil {
+		return ""
+	}
+	h := sha256.New()
+	h.Write([]byte(profile))
+	h.Write([]byte{0})
+	h.Write([]byte(path))
+	h.Write([]byte{0})
+	h.Write(canonical)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseUsage extracts input and output token counts from an Anthropic or
+// OpenAI response body.
+func responseUsage(body []byte) (int, int) {
+	var resp struct {
+		Usage struct {
+			InputTokens      int `json:"input_tokens"`
+			OutputTokens     int `json:"output_tokens"`
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return 0, 0
+	}
+	u := resp.Usage
+	return u.InputTokens + u.PromptTokens, u.OutputTokens + u.CompletionTokens
+}
+
+// writeCachedResponse replays a cached response to the client.
+func writeCachedResponse(w http.ResponseWriter, entry *cachedResponse) {
+	if entry.contentType != "" {
+		w.Header().Set("Content-Type", entry.contentType)
+	}
+	w.Header().Set(responseCacheHeader, "hit")
+	w.WriteHeader(entry.statusCode)
+	w.Write(entry.body)
+}
+
+// responseCacheRecorder passes a response through to the client while
+// keeping a copy of the body for the cache.
+type responseCacheRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	buf        bytes.Buffer
+	overflow   bool
+}
+
+func (r *responseCacheRecorder) WriteHeader(code int) {
+	if r.statusCode == 0 {
+		r.statusCode = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *responseCacheRecorder) Write(p []byte) (int, error) {
+	if r.statusCode == 0 {
+		r.statusCode = http.StatusOK
+	}
+	if !r.overflow {
+		if r.buf.Len()+len(p) > maxCachedResponseBytes {
+			r.overflow = true
+			r.buf.Reset()
+		} else {
+			r.buf.Write(p)
+		}
+	}
+	return r.ResponseWriter.Write(p)
+}
+
+func (r *responseCacheRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// cacheable reports whether the recorded response should be stored: a
+// complete, uncompressed 2xx JSON body.
+func (r *responseCacheRecorder) cacheable() bool {
+	h := r.Header()
+	return r.statusCode >= 200 && r.statusCode < 300 &&
+		!r.overflow && r.buf.Len() > 0 &&
+		h.Get("Content-Encoding") == "" &&
+		strings.Contains(h.Get("Content-Type"), "json")
+}
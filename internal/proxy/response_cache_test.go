@@ -0,0 +1,146 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+func TestResponseCacheKey(t *testing.T) {
+	base := `{"model":"m","temperature":0,"messages":[{"role":"user","content":"hi"}]}`
+	key := responseCacheKey("p", "/v1/messages", []byte(base))
+	if key == "" {
+		t.Fatal("temperature 0 request should be cacheable")
+	}
+
+	reordered := `{"messages":[{"role":"user","content":"hi"}],"metadata":{"user_id":"session_x"},"temperature":0,"model":"m"}`
+	if got := responseCacheKey("p", "/v1/messages", []byte(reordered)); got != key {
+		t.Error("key order and metadata should not affect the key")
+	}
+
+	tests := []struct {
+		name    string
+		profile string
+		path    string
+		body    string
+	}{
+		{"no temperature", "p", "/v1/messages", `{"model":"m","messages":[]}`},
+		{"nonzero temperature", "p", "/v1/messages", `{"model":"m","temperature":0.7,"messages":[]}`},
+		{"streaming", "p", "/v1/messages", `{"model":"m","temperature":0,"stream":true,"messages":[]}`},
+		{"invalid json", "p", "/v1/messages", `not json`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := responseCacheKey(tt.profile, tt.path, []byte(tt.body)); got != "" {
+				t.Errorf("responseCacheKey() = %q, want not cacheable", got)
+			}
+		})
+	}
+
+	if responseCacheKey("other", "/v1/messages", []byte(base)) == key {
+		t.Error("different profiles should not share a key")
+	}
+	if responseCacheKey("p", "/v1/chat/completions", []byte(base)) == key {
+		t.Error("different endpoints should not share a key")
+	}
+}
+
+func TestResponseCacheEvictionAndTTL(t *testing.T) {
+	c := NewResponseCache(&config.ResponseCacheConfig{Enabled: true, MaxEntries: 2})
+	body := []byte(`{"usage":{"input_tokens":10,"output_tokens":3}}`)
+	c.Put("a", 200, "application/json", body)
+	c.Put("b", 200, "application/json", body)
+	c.Get("a") // a is now most recently used
+	c.Put("c", 200, "application/json", body)
+
+	if c.Get("b") != nil {
+		t.Error("least recently used entry should have been evicted")
+	}
+	if c.Get("a") == nil || c.Get("c") == nil {
+		t.Error("recent entries should still be cached")
+	}
+
+	stats := c.Stats()
+	if stats.Entries != 2 || stats.Hits != 3 || stats.Misses != 1 {
+		t.Errorf("stats = %+v, want 2 entries, 3 hits, 1 miss", stats)
+	}
+	if stats.SavedInputTokens != 30 || stats.SavedOutputTokens != 9 {
+		t.Errorf("saved tokens = %d/%d, want 30/9", stats.SavedInputTokens, stats.SavedOutputTokens)
+	}
+
+	// Expired entries are treated as misses
+	c.mu.Lock()
+	c.entries["a"].Value.(*cachedResponse).expires = time.Now().Add(-time.Second)
+	c.mu.Unlock()
+	if c.Get("a") != nil {
+		t.Error("expired entry should not be served")
+	}
+
+	c.UpdateConfig(&config.ResponseCacheConfig{Enabled: false})
+	if c.IsEnabled() || c.Stats().Entries != 0 {
+		t.Error("disabling the cache should drop all entries")
+	}
+	c.Put("d", 200, "application/json", body)
+	if c.Stats().Entries != 0 {
+		t.Error("disabled cache should not store entries")
+	}
+}
+
+func TestProxyResponseCache(t *testing.T) {
+	old := globalResponseCache
+	globalResponseCache = NewResponseCache(&config.ResponseCacheConfig{Enabled: true})
+	defer func() { globalResponseCache = old }()
+
+	calls := 0
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":"msg_%d","content":[{"type":"text","text":"hi"}],"usage":{"input_tokens":3,"output_tokens":1}}`, calls)
+	}))
+	defer backend.Close()
+
+	u, _ := url.Parse(backend.URL)
+	providers := []*Provider{{Name: "p1", BaseURL: u, Token: "t", Healthy: true}}
+	srv := NewProxyServer(providers, discardLogger(), config.LoadBalanceFailover, nil)
+
+	send := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/v1/messages", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		if w.Code != 200 {
+			t.Fatalf("status = %d, body: %s", w.Code, w.Body.String())
+		}
+		return w
+	}
+
+	deterministic := `{"model":"claude-sonnet-4-6","temperature":0,"messages":[{"role":"user","content":"hi"}]}`
+	first := send(deterministic)
+	if got := first.Header().Get(responseCacheHeader); got != "miss" {
+		t.Errorf("first %s = %q, want miss", responseCacheHeader, got)
+	}
+	second := send(deterministic)
+	if got := second.Header().Get(responseCacheHeader); got != "hit" {
+		t.Errorf("second %s = %q, want hit", responseCacheHeader, got)
+	}
+	if calls != 1 {
+		t.Errorf("backend called %d times, want 1", calls)
+	}
+	if second.Body.String() != first.Body.String() {
+		t.Errorf("cached body = %s, want %s", second.Body.String(), first.Body.String())
+	}
+
+	// Non-deterministic requests always go upstream
+	send(`{"model":"claude-sonnet-4-6","messages":[{"role":"user","content":"hi"}]}`)
+	send(`{"model":"claude-sonnet-4-6","messages":[{"role":"user","content":"hi"}]}`)
+	if calls != 3 {
+		t.Errorf("backend called %d times, want 3", calls)
+	}
+}
@@ -0,0 +1,155 @@
+package proxy
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// CompactionResult reports what a usage database compaction removed.
+// Row counts are the rows deleted from each table.
+type CompactionResult struct {
+	RawCutoff    time.Time `json:"raw_cutoff"`
+	HourlyCutoff time.Time `json:"hourly_cutoff"`
+	UsageRows    int64     `json:"usage_rows"`
+	LogRows      int64     `json:"log_rows"`
+	MetricRows   int64     `json:"metric_rows"`
+	BodyRows     int64     `json:"body_rows"`
+	HourlyRows   int64     `json:"hourly_rows"`
+	BytesBefore  int64     `json:"bytes_before"`
+	BytesAfter   int64     `json:"bytes_after"`
+	DurationMs   int64     `json:"duration_ms"`
+}
+
+// DiskUsage reports the size of the usage database and its tables.
+type DiskUsage struct {
+	Path      string           `json:"path"`
+	FileBytes int64            `json:"file_bytes"` // database file plus write-ahead log
+	FreeBytes int64            `json:"free_bytes"` // unused pages a compaction would reclaim
+	Rows      map[string]int64 `json:"rows"`
+	Oldest    *time.Time       `json:"oldest_usage,omitempty"`
+}
+
+// retentionTables are the tables reported by DiskUsage.
+var retentionTables = []string{"usage", "usage_tags", "usage_rollup_hourly", "usage_rollup_daily", "logs", "provider_metrics", "request_bodies"}
+
+// CompactUsage applies the configured retention to the global log database.
+func CompactUsage() (*CompactionResult, error) {
+	db := GetGlobalLogDB()
+	if db == nil {
+		return nil, fmt.Errorf("usage database is not open")
+	}
+	rc := config.GetUsageRetention()
+	now := time.Now().UTC()
+	return db.Compact(
+		now.AddDate(0, 0, -rc.GetRawDays()),
+		now.AddDate(0, 0, -rc.GetHourlyDays()),
+	)
+}
+
+// Compact deletes raw rows older than rawCutoff and hourly rollups older
+// than hourlyCutoff, then vacuums the database. The rollups already cover
+// the deleted usage, so charts and summaries keep reporting it; daily
+// rollups are kept. Both cutoffs are rounded down to the hour.
+func (ldb *LogDB) Compact(rawCutoff, hourlyCutoff time.Time) (*CompactionResult, error) {
+	if ldb == nil || ldb.db == nil {
+		return nil, fmt.Errorf("usage database is not open")
+	}
+	start := time.Now()
+	rawCutoff = rawCutoff.UTC().Truncate(time.Hour)
+	hourlyCutoff = hourlyCutoff.UTC().Truncate(time.Hour)
+	result := &CompactionResult{RawCutoff: rawCutoff, HourlyCutoff: hourlyCutoff}
+	if usage, err := ldb.DiskUsage(); err == nil {
+		result.BytesBefore = usage.FileBytes
+	}
+
+	raw := rawCutoff.Format(time.RFC3339Nano)
+	tx, err := ldb.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM usage_tags WHERE usage_id IN (SELECT id FROM usage WHERE timestamp < ?)`, raw); err != nil {
+		return nil, fmt.Errorf("delete usage tags: %w", err)
+	}
+	for _, d := range []struct {
+		table string
+		count *int64
+	}{
+		{"usage", &result.UsageRows},
+		{"logs", &result.LogRows},
+		{"provider_metrics", &result.MetricRows},
+		{"request_bodies", &result.BodyRows},
+	} {
+		res, err := tx.Exec(`DELETE FROM `+d.table+` WHERE timestamp < ?`, raw)
+		if err != nil {
+			return nil, fmt.Errorf("delete from %s: %w", d.table, err)
+		}
+		*d.count, _ = res.RowsAffected()
+	}
+	res, err := tx.Exec(`DELETE FROM usage_rollup_hourly WHERE bucket < ?`, hourlyCutoff.Format(rollupHourLayout))
+	if err != nil {
+		return nil, fmt.Errorf("delete from usage_rollup_hourly: %w", err)
+	}
+	result.HourlyRows, _ = res.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	// Return freed pages to the filesystem. A busy database is left as is;
+	// SQLite reuses the freed pages either way.
+	if _, err := ldb.db.Exec(`VACUUM`); err == nil {
+		ldb.db.Exec(`PRAGMA wal_checkpoint(TRUNCATE)`)
+	}
+
+	if usage, err := ldb.DiskUsage(); err == nil {
+		result.BytesAfter = usage.FileBytes
+	}
+	result.DurationMs = time.Since(start).Milliseconds()
+	return result, nil
+}
+
+// DiskUsage reports the database's size on disk and the row count of each
+// table.
+func (ldb *LogDB) DiskUsage() (*DiskUsage, error) {
+	if ldb == nil || ldb.db == nil {
+		return nil, fmt.Errorf("usage database is not open")
+	}
+	usage := &DiskUsage{Rows: make(map[string]int64, len(retentionTables))}
+
+	var seq int
+	var name string
+	if err := ldb.db.QueryRow(`PRAGMA database_list`).Scan(&seq, &name, &usage.Path); err != nil {
+		return nil, err
+	}
+	for _, path := range []string{usage.Path, usage.Path + "-wal"} {
+		if fi, err := os.Stat(path); err == nil {
+			usage.FileBytes += fi.Size()
+		}
+	}
+
+	var pageSize, freePages int64
+	ldb.db.QueryRow(`PRAGMA page_size`).Scan(&pageSize)
+	ldb.db.QueryRow(`PRAGMA freelist_count`).Scan(&freePages)
+	usage.FreeBytes = pageSize * freePages
+
+	for _, table := range retentionTables {
+		var n int64
+		if err := ldb.db.QueryRow(`SELECT COUNT(*) FROM ` + table).Scan(&n); err != nil {
+			return nil, err
+		}
+		usage.Rows[table] = n
+	}
+
+	var oldest string
+	if err := ldb.db.QueryRow(`SELECT COALESCE(MIN(timestamp), '') FROM usage`).Scan(&oldest); err == nil && oldest != "" {
+		if ts, err := time.Parse(time.RFC3339Nano, oldest); err == nil {
+			usage.Oldest = &ts
+		}
+	}
+	return usage, nil
+}
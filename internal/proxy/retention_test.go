@@ -0,0 +1,63 @@
+package proxy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+func TestLogDB_Compact(t *testing.T) {
+	ldb, err := OpenLogDB(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenLogDB() error: %v", err)
+	}
+	defer ldb.Close()
+	tracker := &UsageTracker{db: ldb, pricing: make(map[string]*config.ModelPricing)}
+
+	now := time.Now().UTC()
+	old := now.AddDate(0, 0, -40).Truncate(time.Hour).Add(10 * time.Minute)
+	for _, e := range []UsageEntry{
+		{Timestamp: old, SessionID: "a", Provider: "p", Model: "m", InputTokens: 100, OutputTokens: 10, CostUSD: 1.0, Tags: []string{"ci"}},
+		{Timestamp: old.Add(5 * time.Minute), SessionID: "a", Provider: "p", Model: "m", InputTokens: 200, OutputTokens: 20, CostUSD: 2.0},
+		{Timestamp: now, SessionID: "b", Provider: "p", Model: "m", InputTokens: 50, OutputTokens: 5, CostUSD: 0.5, Tags: []string{"ci"}},
+	} {
+		if err := tracker.Record(e); err != nil {
+			t.Fatalf("Record() error: %v", err)
+		}
+	}
+	if _, err := ldb.db.Exec(`INSERT INTO usage_rollup_hourly (bucket, provider, model, request_count) VALUES (?, 'p', 'm', 1)`,
+		now.AddDate(-2, 0, 0).Format(rollupHourLayout)); err != nil {
+		t.Fatalf("insert hourly rollup: %v", err)
+	}
+
+	result, err := ldb.Compact(now.AddDate(0, 0, -30), now.AddDate(-1, 0, 0))
+	if err != nil {
+		t.Fatalf("Compact() error: %v", err)
+	}
+	if result.UsageRows != 2 || result.HourlyRows != 1 {
+		t.Errorf("Compact() = %+v, want 2 usage rows and 1 hourly rollup removed", result)
+	}
+
+	// The rollups still cover the deleted rows
+	var input, count int
+	var cost float64
+	if err := ldb.db.QueryRow(`SELECT input_tokens, cost_usd, request_count FROM usage_rollup_hourly WHERE bucket = ?`,
+		old.Format(rollupHourLayout)).Scan(&input, &cost, &count); err != nil {
+		t.Fatalf("expected the deleted usage in its hourly rollup: %v", err)
+	}
+	if input != 300 || cost != 3.0 || count != 2 {
+		t.Errorf("hourly rollup = %d input, $%v, %d requests; want 300, $3, 2", input, cost, count)
+	}
+
+	usage, err := ldb.DiskUsage()
+	if err != nil {
+		t.Fatalf("DiskUsage() error: %v", err)
+	}
+	if usage.Rows["usage"] != 1 || usage.Rows["usage_tags"] != 1 || usage.Rows["usage_rollup_hourly"] != 2 || usage.Rows["usage_rollup_daily"] != 2 {
+		t.Errorf("rows after compaction = %v, want 1 usage row, 1 tag and 2 hourly and daily rollups", usage.Rows)
+	}
+	if usage.FileBytes == 0 || usage.Path == "" || usage.Oldest == nil {
+		t.Errorf("DiskUsage() = %+v, want a path, size and oldest usage", usage)
+	}
+}
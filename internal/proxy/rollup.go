@@ -0,0 +1,218 @@
+package proxy
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// Usage rollups pre-aggregate the usage table into hourly and daily buckets,
+// keyed by provider, model, project and user. Record keeps them current, so
+// charts and summaries read a few rows per bucket instead of every request.
+
+// Bucket formats of the rollup tables, in UTC.
+const (
+	rollupHourLayout = "2006-01-02 15:00:00"
+	rollupDayLayout  = "2006-01-02"
+)
+
+// usageRollupSchema creates the rollup tables and their indexes.
+var usageRollupSchema = []string{
+	usageRollupTable("usage_rollup_hourly"),
+	usageRollupTable("usage_rollup_daily"),
+	"CREATE INDEX IF NOT EXISTS idx_usage_rollup_hourly_bucket ON usage_rollup_hourly(bucket)",
+	"CREATE INDEX IF NOT EXISTS idx_usage_rollup_daily_bucket ON usage_rollup_daily(bucket)",
+}
+
+func usageRollupTable(name string) string {
+	return `
+	CREATE TABLE IF NOT EXISTS ` + name + ` (
+		bucket                TEXT NOT NULL,
+		provider              TEXT NOT NULL,
+		model                 TEXT NOT NULL,
+		project_path          TEXT NOT NULL DEFAULT '',
+		user_label            TEXT NOT NULL DEFAULT '',
+		input_tokens          INTEGER NOT NULL DEFAULT 0,
+		output_tokens         INTEGER NOT NULL DEFAULT 0,
+		cache_creation_tokens INTEGER NOT NULL DEFAULT 0,
+		cache_read_tokens     INTEGER NOT NULL DEFAULT 0,
+		cost_usd              REAL NOT NULL DEFAULT 0,
+		request_count         INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (bucket, provider, model, project_path, user_label)
+	)
+`
+}
+
+// addToRollups adds a usage entry to its hourly and daily buckets.
+func addToRollups(tx *sql.Tx, entry UsageEntry) error {
+	ts := entry.Timestamp.UTC()
+	for _, r := range []struct {
+		table, bucket string
+	}{
+		{"usage_rollup_hourly", ts.Format(rollupHourLayout)},
+		{"usage_rollup_daily", ts.Format(rollupDayLayout)},
+	} {
+		if _, err := tx.Exec(`
+			INSERT INTO `+r.table+` (bucket, provider, model, project_path, user_label, input_tokens, output_tokens, cache_creation_tokens, cache_read_tokens, cost_usd, request_count)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 1)
+			ON CONFLICT (bucket, provider, model, project_path, user_label) DO UPDATE SET
+				input_tokens = input_tokens + excluded.input_tokens,
+				output_tokens = output_tokens + excluded.output_tokens,
+				cache_creation_tokens = cache_creation_tokens + excluded.cache_creation_tokens,
+				cache_read_tokens = cache_read_tokens + excluded.cache_read_tokens,
+				cost_usd = cost_usd + excluded.cost_usd,
+				request_count = request_count + 1
+		`,
+			r.bucket, entry.Provider, entry.Model, entry.ProjectPath, entry.User,
+			entry.InputTokens, entry.OutputTokens, entry.CacheCreationTokens, entry.CacheReadTokens, entry.CostUSD,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rollupColumns are the columns every part of a rollupSource yields.
+const rollupColumns = "provider, model, project_path, user_label, input_tokens, output_tokens, cache_creation_tokens, cache_read_tokens, cost_usd, request_count"
+
+// rollupSource returns a subquery over the usage in [since, until), with one
+// row per rollup bucket or raw request, and its args. Whole days are read
+// from the daily rollup and whole hours from the hourly rollup; only the
+// partial hours at either end read raw usage rows.
+func rollupSource(since, until time.Time) (string, []interface{}) {
+	since, until = since.UTC(), until.UTC()
+	var parts []string
+	var args []interface{}
+
+	raw := func(from, to time.Time) {
+		if !from.Before(to) {
+			return
+		}
+		parts = append(parts, `SELECT provider, model, project_path, COALESCE(user_label, '') AS user_label, input_tokens, output_tokens,
+			COALESCE(cache_creation_tokens, 0) AS cache_creation_tokens, COALESCE(cache_read_tokens, 0) AS cache_read_tokens, cost_usd, 1 AS request_count
+			FROM usage WHERE timestamp >= ? AND timestamp < ?`)
+		args = append(args, from.Format(time.RFC3339Nano), to.Format(time.RFC3339Nano))
+	}
+	rollup := func(table, layout string, from, to time.Time) {
+		if !from.Before(to) {
+			return
+		}
+		parts = append(parts, `SELECT `+rollupColumns+` FROM `+table+` WHERE bucket >= ? AND bucket < ?`)
+		args = append(args, from.Format(layout), to.Format(layout))
+	}
+
+	firstHour := ceilTime(since, time.Hour)
+	lastHour := until.Truncate(time.Hour)
+	if !firstHour.Before(lastHour) {
+		raw(since, until)
+	} else {
+		firstDay := ceilTime(firstHour, 24*time.Hour)
+		lastDay := lastHour.Truncate(24 * time.Hour)
+		raw(since, firstHour)
+		if firstDay.Before(lastDay) {
+			rollup("usage_rollup_hourly", rollupHourLayout, firstHour, firstDay)
+			rollup("usage_rollup_daily", rollupDayLayout, firstDay, lastDay)
+			rollup("usage_rollup_hourly", rollupHourLayout, lastDay, lastHour)
+		} else {
+			rollup("usage_rollup_hourly", rollupHourLayout, firstHour, lastHour)
+		}
+		raw(lastHour, until)
+	}
+	if len(parts) == 0 {
+		return `(SELECT ` + rollupColumns + ` FROM usage_rollup_hourly WHERE 0)`, nil
+	}
+	return "(" + strings.Join(parts, " UNION ALL ") + ")", args
+}
+
+// ceilTime rounds t up to a multiple of d.
+func ceilTime(t time.Time, d time.Duration) time.Time {
+	if r := t.Truncate(d); r.Before(t) {
+		return r.Add(d)
+	}
+	return t
+}
+
+// queryRollupSummary summarizes the usage in [since, until) from the
+// rollups. projectPath filters by project (empty string for all projects).
+// Rollups don't record tags, so ByTag is left for the caller.
+func (t *UsageTracker) queryRollupSummary(since, until time.Time, projectPath string) (*UsageSummary, error) {
+	src, args := rollupSource(since, until)
+	where := ""
+	if projectPath != "" {
+		where = " WHERE project_path = ?"
+		args = append(args, projectPath)
+	}
+
+	summary := &UsageSummary{}
+	err := t.db.db.QueryRow(`SELECT COALESCE(SUM(input_tokens), 0), COALESCE(SUM(output_tokens), 0), COALESCE(SUM(cache_creation_tokens), 0),
+		COALESCE(SUM(cache_read_tokens), 0), COALESCE(SUM(cost_usd), 0), COALESCE(SUM(request_count), 0) FROM `+src+where, args...).
+		Scan(&summary.TotalInputTokens, &summary.TotalOutputTokens, &summary.TotalCacheCreationTokens, &summary.TotalCacheReadTokens, &summary.TotalCost, &summary.RequestCount)
+	if err != nil {
+		return nil, err
+	}
+	summary.CacheHitRate = cacheHitRate(summary.TotalInputTokens, summary.TotalCacheCreationTokens, summary.TotalCacheReadTokens)
+
+	for _, g := range []struct {
+		column    string
+		skipEmpty bool
+		into      *map[string]*UsageStats
+	}{
+		{"provider", false, &summary.ByProvider},
+		{"model", false, &summary.ByModel},
+		{"project_path", true, &summary.ByProject},
+		{"user_label", true, &summary.ByUser},
+	} {
+		if *g.into, err = t.rollupGroupedStats(g.column, g.skipEmpty, src, where, args); err != nil {
+			return nil, err
+		}
+	}
+	return summary, nil
+}
+
+// rollupGroupedStats returns usage stats from a rollupSource grouped by
+// column, leaving out rows where it is empty if skipEmpty is set.
+func (t *UsageTracker) rollupGroupedStats(column string, skipEmpty bool, src, where string, args []interface{}) (map[string]*UsageStats, error) {
+	if skipEmpty && where == "" {
+		where = " WHERE " + column + " != ''"
+	} else if skipEmpty {
+		where += " AND " + column + " != ''"
+	}
+	rows, err := t.db.db.Query(`SELECT `+column+`, SUM(input_tokens), SUM(output_tokens), SUM(cache_creation_tokens), SUM(cache_read_tokens), SUM(cost_usd), SUM(request_count)
+		FROM `+src+where+` GROUP BY `+column, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[string]*UsageStats)
+	for rows.Next() {
+		var key string
+		var stats UsageStats
+		if err := rows.Scan(&key, &stats.InputTokens, &stats.OutputTokens, &stats.CacheCreationTokens, &stats.CacheReadTokens, &stats.Cost, &stats.RequestCount); err != nil {
+			continue
+		}
+		stats.CacheHitRate = cacheHitRate(stats.InputTokens, stats.CacheCreationTokens, stats.CacheReadTokens)
+		result[key] = &stats
+	}
+	return result, rows.Err()
+}
+
+// queryRollupSummaryWithTags is queryRollupSummary with ByTag filled in from
+// the request-level rows.
+func (t *UsageTracker) queryRollupSummaryWithTags(since, until time.Time, projectPath string) (*UsageSummary, error) {
+	summary, err := t.queryRollupSummary(since, until, projectPath)
+	if err != nil {
+		return nil, err
+	}
+	where := " WHERE timestamp >= ? AND timestamp < ?"
+	args := []interface{}{since.UTC().Format(time.RFC3339Nano), until.UTC().Format(time.RFC3339Nano)}
+	if projectPath != "" {
+		where += " AND project_path = ?"
+		args = append(args, projectPath)
+	}
+	summary.ByTag, err = t.queryGroupedStats("usage_tags.tag", usageWithTags, where, args)
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
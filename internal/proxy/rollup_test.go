@@ -0,0 +1,134 @@
+package proxy
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+func TestUsageRollups(t *testing.T) {
+	ldb, err := OpenLogDB(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenLogDB() error: %v", err)
+	}
+	defer ldb.Close()
+	tracker := &UsageTracker{db: ldb, pricing: make(map[string]*config.ModelPricing)}
+
+	// Spread requests over three days, including partial hours at both ends
+	day := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -3)
+	times := []time.Time{
+		day.Add(5*time.Hour + 10*time.Minute),
+		day.Add(5*time.Hour + 50*time.Minute),
+		day.Add(30 * time.Hour),
+		day.Add(52*time.Hour + 20*time.Minute),
+		day.Add(60*time.Hour + 45*time.Minute),
+	}
+	for i, ts := range times {
+		if err := tracker.Record(UsageEntry{
+			Timestamp: ts, SessionID: "s", Provider: "p", Model: "m", ProjectPath: "/proj", User: "alice",
+			InputTokens: 100, OutputTokens: 10, CacheReadTokens: 50, CostUSD: float64(i + 1),
+		}); err != nil {
+			t.Fatalf("Record() error: %v", err)
+		}
+	}
+
+	var hourly, daily int
+	ldb.db.QueryRow(`SELECT COUNT(*) FROM usage_rollup_hourly`).Scan(&hourly)
+	ldb.db.QueryRow(`SELECT COUNT(*) FROM usage_rollup_daily`).Scan(&daily)
+	if hourly != 4 || daily != 3 {
+		t.Errorf("rollups = %d hourly, %d daily buckets; want 4 and 3", hourly, daily)
+	}
+
+	tests := []struct {
+		name         string
+		since, until time.Time
+		wantRequests int
+		wantCost     float64
+	}{
+		{"everything", day, day.Add(72 * time.Hour), 5, 15},
+		{"partial hours at both ends", day.Add(5*time.Hour + 30*time.Minute), day.Add(60*time.Hour + 50*time.Minute), 4, 14},
+		{"within one hour", day.Add(5*time.Hour + 5*time.Minute), day.Add(5*time.Hour + 20*time.Minute), 1, 1},
+		{"whole hours only", day.Add(6 * time.Hour), day.Add(53 * time.Hour), 2, 7},
+	}
+	for _, tt := range tests {
+		summary, err := tracker.GetSummaryByTimeRange(tt.since, tt.until, "")
+		if err != nil {
+			t.Fatalf("%s: GetSummaryByTimeRange() error: %v", tt.name, err)
+		}
+		if summary.RequestCount != tt.wantRequests || summary.TotalCost != tt.wantCost {
+			t.Errorf("%s: %d requests costing %v, want %d costing %v", tt.name, summary.RequestCount, summary.TotalCost, tt.wantRequests, tt.wantCost)
+		}
+		if tt.wantRequests > 0 {
+			if u := summary.ByUser["alice"]; u == nil || u.RequestCount != tt.wantRequests || u.CacheReadTokens != 50*tt.wantRequests {
+				t.Errorf("%s: by_user = %+v", tt.name, summary.ByUser)
+			}
+			if p := summary.ByProject["/proj"]; p == nil || p.RequestCount != tt.wantRequests {
+				t.Errorf("%s: by_project = %+v", tt.name, summary.ByProject)
+			}
+		}
+	}
+
+	summary, err := tracker.GetSummary("all", "/other")
+	if err != nil || summary.RequestCount != 0 {
+		t.Errorf("GetSummary(other project) = %+v, %v; want no requests", summary, err)
+	}
+
+	byModel, err := tracker.GetHourlySummaryByModel(day, day.Add(72*time.Hour))
+	if err != nil || len(byModel) != 4 || byModel[0].RequestCount != 2 {
+		t.Errorf("GetHourlySummaryByModel() = %+v, %v; want 4 buckets, 2 requests in the first", byModel, err)
+	}
+}
+
+func TestSchemaMigrationV8ToV9(t *testing.T) {
+	dir := t.TempDir()
+	createV1Database(t, dir)
+
+	rawDB, err := sql.Open("sqlite", filepath.Join(dir, "logs.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	for _, migrate := range migrations[:7] {
+		tx, _ := rawDB.Begin()
+		if err := migrate(tx); err != nil {
+			t.Fatalf("migration error: %v", err)
+		}
+		tx.Commit()
+	}
+	rawDB.Exec(`CREATE TABLE IF NOT EXISTS schema_version (version INTEGER NOT NULL)`)
+	setSchemaVersion(rawDB, 8)
+
+	hour := time.Date(2026, 3, 8, 14, 0, 0, 0, time.UTC)
+	for _, ts := range []time.Time{hour.Add(time.Minute), hour.Add(2 * time.Minute)} {
+		if _, err := rawDB.Exec(`INSERT INTO usage (timestamp, session_id, provider, model, input_tokens, output_tokens, cost_usd, user_label)
+			VALUES (?, 's', 'p', 'm', 100, 10, 0.5, 'alice')`, ts.Format(time.RFC3339Nano)); err != nil {
+			t.Fatalf("insert usage: %v", err)
+		}
+	}
+	// An hour whose rows were already compacted away
+	if _, err := rawDB.Exec(`INSERT INTO usage_hourly (hour, provider, model, project_path, total_input, total_output, total_cost, request_count)
+		VALUES ('2026-01-02 03:00:00', 'p', 'm', '', 1000, 100, 5, 7)`); err != nil {
+		t.Fatalf("insert usage_hourly: %v", err)
+	}
+	rawDB.Close()
+
+	ldb, err := OpenLogDB(dir)
+	if err != nil {
+		t.Fatalf("OpenLogDB: %v", err)
+	}
+	defer ldb.Close()
+	tracker := &UsageTracker{db: ldb, pricing: make(map[string]*config.ModelPricing)}
+
+	summary, err := tracker.GetSummary("all", "")
+	if err != nil {
+		t.Fatalf("GetSummary() error: %v", err)
+	}
+	if summary.RequestCount != 9 || summary.TotalInputTokens != 1200 || summary.ByUser["alice"] == nil || summary.ByUser["alice"].RequestCount != 2 {
+		t.Errorf("summary after migration = %+v, want 9 requests with 2 from alice", summary)
+	}
+	if tableExists(ldb.db, "usage_hourly") {
+		t.Error("usage_hourly should be dropped")
+	}
+}
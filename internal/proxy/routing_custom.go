@@ -0,0 +1,153 @@
+package proxy
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// ScenarioMatcher evaluates the match rules of a user-defined scenario.
+type ScenarioMatcher struct {
+	match    *config.ScenarioMatch
+	systemRe *regexp.Regexp
+}
+
+// NewScenarioMatcher compiles a scenario's match rules.
+func NewScenarioMatcher(m *config.ScenarioMatch) (*ScenarioMatcher, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	matcher := &ScenarioMatcher{match: m}
+	if m.SystemPattern != "" {
+		re, err := regexp.Compile(m.SystemPattern)
+		if err != nil {
+			return nil, err
+		}
+		matcher.systemRe = re
+	}
+	return matcher, nil
+}
+
+// Matches reports whether a request satisfies every rule that is set.
+func (m *ScenarioMatcher) Matches(systemPrompt string, tools []string, tokens int) bool {
+	if m.systemRe != nil && !m.systemRe.MatchString(systemPrompt) {
+		return false
+	}
+	if len(m.match.Tools) > 0 && !containsAny(tools, m.match.Tools) {
+		return false
+	}
+	if tokens < m.match.MinTokens {
+		return false
+	}
+	if m.match.MaxTokens > 0 && tokens > m.match.MaxTokens {
+		return false
+	}
+	return true
+}
+
+// containsAny reports whether have and want share an element.
+func containsAny(have, want []string) bool {
+	for _, h := range have {
+		for _, w := range want {
+			if h == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// requestToolNames returns the tool names declared in an Anthropic, OpenAI
+// Chat or OpenAI Responses request body.
+func requestToolNames(body map[string]interface{}) []string {
+	tools, ok := body["tools"].([]interface{})
+	if !ok {
+		return nil
+	}
+	var names []string
+	for _, t := range tools {
+		tool, ok := t.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if name, ok := tool["name"].(string); ok && name != "" {
+			names = append(names, name)
+			continue
+		}
+		if fn, ok := tool["function"].(map[string]interface{}); ok {
+			if name, ok := fn["name"].(string); ok && name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// matchCustomScenario returns a routing decision for the first user-defined
+// scenario whose match rules accept the request, or nil if none does.
+// Scenarios named in priority are checked first, in that order; the rest
+// follow alphabetically so the outcome does not depend on map order.
+func matchCustomScenario(
+	routes map[string]*ScenarioProviders,
+	priority []string,
+	normalized *NormalizedRequest,
+	features *RequestFeatures,
+	body map[string]interface{},
+) *RoutingDecision {
+	var names []string
+	for name, route := range routes {
+		if route != nil && route.Matcher != nil {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+
+	ordered := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, p := range priority {
+		for _, name := range names {
+			if !seen[name] && config.NormalizeScenarioKey(name) == config.NormalizeScenarioKey(p) {
+				ordered = append(ordered, name)
+				seen[name] = true
+			}
+		}
+	}
+	for _, name := range names {
+		if !seen[name] {
+			ordered = append(ordered, name)
+		}
+	}
+
+	var systemPrompt string
+	if normalized != nil {
+		systemPrompt = normalized.SystemPrompt
+	}
+	tokens := 0
+	if features != nil {
+		tokens = features.TotalTokens
+	} else if body != nil {
+		if n, err := calculateTokenCount(body); err == nil {
+			tokens = n
+		} else {
+			tokens = estimateTokensFromChars(body)
+		}
+	}
+	tools := requestToolNames(body)
+
+	for _, name := range ordered {
+		if routes[name].Matcher.Matches(systemPrompt, tools, tokens) {
+			return &RoutingDecision{
+				Scenario:   name,
+				Source:     "custom:match",
+				Reason:     fmt.Sprintf("custom scenario %q rules matched", name),
+				Confidence: 0.9,
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+func TestScenarioMatcher(t *testing.T) {
+	m, err := NewScenarioMatcher(&config.ScenarioMatch{
+		SystemPattern: "(?i)code review",
+		Tools:         []string{"read_file", "grep"},
+		MinTokens:     10,
+		MaxTokens:     100,
+	})
+	if err != nil {
+		t.Fatalf("NewScenarioMatcher() error: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		system string
+		tools  []string
+		tokens int
+		want   bool
+	}{
+		{"all conditions hold", "You do Code Review.", []string{"grep"}, 50, true},
+		{"system prompt mismatch", "You write code.", []string{"grep"}, 50, false},
+		{"no matching tool", "code review", []string{"bash"}, 50, false},
+		{"too few tokens", "code review", []string{"grep"}, 5, false},
+		{"too many tokens", "code review", []string{"grep"}, 500, false},
+	}
+	for _, tt := range tests {
+		if got := m.Matches(tt.system, tt.tools, tt.tokens); got != tt.want {
+			t.Errorf("%s: Matches() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := NewScenarioMatcher(&config.ScenarioMatch{SystemPattern: "("}); err == nil {
+		t.Error("NewScenarioMatcher() should reject an invalid pattern")
+	}
+}
+
+func TestRequestToolNames(t *testing.T) {
+	var body map[string]interface{}
+	json.Unmarshal([]byte(`{"tools":[
+		{"name":"anthropic_tool","input_schema":{}},
+		{"type":"function","function":{"name":"openai_tool"}},
+		{"type":"function","name":"responses_tool"},
+		{"type":"web_search_preview"}
+	]}`), &body)
+
+	got := requestToolNames(body)
+	want := []string{"anthropic_tool", "openai_tool", "responses_tool"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("requestToolNames() = %v, want %v", got, want)
+	}
+}
+
+func TestMatchCustomScenarioOrder(t *testing.T) {
+	matcher := func(m *config.ScenarioMatch) *ScenarioMatcher {
+		sm, err := NewScenarioMatcher(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sm
+	}
+	routes := map[string]*ScenarioProviders{
+		"alpha": {Matcher: matcher(&config.ScenarioMatch{MinTokens: 1})},
+		"beta":  {Matcher: matcher(&config.ScenarioMatch{MinTokens: 1})},
+		"think": {},
+	}
+	features := &RequestFeatures{TotalTokens: 10}
+
+	if d := matchCustomScenario(routes, nil, nil, features, nil); d == nil || d.Scenario != "alpha" {
+		t.Errorf("without priority, decision = %+v, want alpha", d)
+	}
+	d := matchCustomScenario(routes, []string{"think", "beta"}, nil, features, nil)
+	if d == nil || d.Scenario != "beta" || d.Source != "custom:match" {
+		t.Errorf("with priority, decision = %+v, want beta from custom:match", d)
+	}
+	if d := matchCustomScenario(routes, nil, nil, &RequestFeatures{}, nil); d != nil {
+		t.Errorf("decision = %+v, want no match", d)
+	}
+}
+
+func TestProxyRoutesCustomScenario(t *testing.T) {
+	hits := map[string]int{}
+	newBackend := func(name string) *Provider {
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits[name]++
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"id":"msg_1","content":[],"usage":{"input_tokens":1,"output_tokens":1}}`))
+		}))
+		t.Cleanup(backend.Close)
+		u, _ := url.Parse(backend.URL)
+		return &Provider{Name: name, BaseURL: u, Token: "t", Healthy: true}
+	}
+	def := newBackend("default")
+	review := newBackend("review")
+
+	matcher, err := NewScenarioMatcher(&config.ScenarioMatch{SystemPattern: "reviewer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := NewProxyServer([]*Provider{def}, discardLogger(), config.LoadBalanceFailover, nil)
+	srv.Routing = &RoutingConfig{
+		DefaultProviders: []*Provider{def},
+		ScenarioRoutes: map[string]*ScenarioProviders{
+			"review": {Providers: []*Provider{review}, Matcher: matcher},
+		},
+	}
+
+	send := func(body string) {
+		req := httptest.NewRequest("POST", "/v1/messages", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		if w.Code != 200 {
+			t.Fatalf("status = %d, body: %s", w.Code, w.Body.String())
+		}
+	}
+
+	send(`{"model":"claude-sonnet-4-5","system":"You are a careful reviewer.","messages":[{"role":"user","content":"hi"}]}`)
+	send(`{"model":"claude-sonnet-4-5","system":"You are a helper.","messages":[{"role":"user","content":"hi"}]}`)
+
+	if hits["review"] != 1 || hits["default"] != 1 {
+		t.Errorf("hits = %v, want one request to each provider", hits)
+	}
+}
@@ -34,27 +34,34 @@ func ResolveRoutingDecision(
 	decision := classifier.Classify(normalized, features, hints, sessionID, body)
 
 	// Apply middleware overrides to builtin classifier decision
-	if middlewareDecision != nil {
-		if middlewareDecision.ModelHint != nil {
-			decision.ModelHint = middlewareDecision.ModelHint
-		}
-		if middlewareDecision.StrategyOverride != nil {
-			decision.StrategyOverride = middlewareDecision.StrategyOverride
-		}
-		if middlewareDecision.ThresholdOverride != nil {
-			decision.ThresholdOverride = middlewareDecision.ThresholdOverride
-		}
-		if len(middlewareDecision.ProviderAllowlist) > 0 {
-			decision.ProviderAllowlist = middlewareDecision.ProviderAllowlist
-		}
-		if len(middlewareDecision.ProviderDenylist) > 0 {
-			decision.ProviderDenylist = middlewareDecision.ProviderDenylist
-		}
-	}
+	applyMiddlewareOverrides(decision, middlewareDecision)
 
 	return decision
 }
 
+// applyMiddlewareOverrides copies the hints and overrides a middleware set
+// without choosing a scenario onto a decision made elsewhere.
+func applyMiddlewareOverrides(decision, middlewareDecision *RoutingDecision) {
+	if middlewareDecision == nil {
+		return
+	}
+	if middlewareDecision.ModelHint != nil {
+		decision.ModelHint = middlewareDecision.ModelHint
+	}
+	if middlewareDecision.StrategyOverride != nil {
+		decision.StrategyOverride = middlewareDecision.StrategyOverride
+	}
+	if middlewareDecision.ThresholdOverride != nil {
+		decision.ThresholdOverride = middlewareDecision.ThresholdOverride
+	}
+	if len(middlewareDecision.ProviderAllowlist) > 0 {
+		decision.ProviderAllowlist = middlewareDecision.ProviderAllowlist
+	}
+	if len(middlewareDecision.ProviderDenylist) > 0 {
+		decision.ProviderDenylist = middlewareDecision.ProviderDenylist
+	}
+}
+
 // ResolveRoutePolicy looks up the RoutePolicy for a given scenario in the profile config.
 // Returns nil if no route is configured for that scenario.
 // Falls back to default providers if scenario not found and fallback is enabled.
@@ -17,6 +17,10 @@ import (
 	"github.com/dopejs/gozen/internal/config"
 	"github.com/dopejs/gozen/internal/middleware"
 	"github.com/dopejs/gozen/internal/proxy/transform"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // ProxyError represents a categorized error from the proxy
@@ -127,6 +131,7 @@ type ScenarioProviders struct {
 	ProviderWeights      map[string]int
 	LongContextThreshold *int
 	FallbackToDefault    *bool
+	Matcher              *ScenarioMatcher // match rules for custom scenarios
 }
 
 // providerFailure tracks details of a failed provider attempt.
@@ -148,6 +153,9 @@ type ProxyServer struct {
 	Strategy         config.LoadBalanceStrategy // load balancing strategy
 	LoadBalancer     *LoadBalancer              // for strategy-based provider selection
 	Profile          string                     // profile name for per-profile strategy state
+	CacheAffinity    bool                       // prefer the provider holding the session's prompt cache
+	Shadow           *shadowTarget              // optional mirror provider for shadow traffic
+	RequestTransform *config.RequestTransform   // optional per-profile request body rewrite
 }
 
 func (s *ProxyServer) Close() {
@@ -246,6 +254,36 @@ func (s *ProxyServer) writeAllProvidersUnavailableError(w http.ResponseWriter, d
 func (s *ProxyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	requestStart := time.Now()
 
+	// Trace the request, joining the caller's trace if it sent one
+	ctx, span := startSpan(tracePropagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header)), "zen.request",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("zen.profile", s.Profile),
+			attribute.String("http.request.method", r.Method),
+			attribute.String("url.path", r.URL.Path),
+		))
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	// Write the access log line once the response is done
+	if al := GetGlobalAccessLog(); al != nil {
+		entry := &accessEntry{AccessLogEntry: AccessLogEntry{
+			Timestamp:  requestStart,
+			RemoteAddr: r.RemoteAddr,
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Protocol:   r.Proto,
+		}}
+		rec := &accessRecorder{ResponseWriter: w}
+		w = rec
+		r = r.WithContext(withAccessEntry(r.Context(), entry))
+		defer func() {
+			if err := al.Log(entry.finish(rec)); err != nil {
+				s.Logger.Printf("[access-log] write failed: %v", err)
+			}
+		}()
+	}
+
 	// Acquire concurrency slot if limiter is configured
 	// Pass request context so limiter respects client cancellation
 	if s.Limiter != nil {
@@ -288,6 +326,29 @@ func (s *ProxyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	clientType := r.Header.Get("X-Zen-Client")
 	r.Header.Del("X-Zen-Client")
 
+	// Extract the user label resolved from the ingress key (set by ProfileProxy)
+	// and the client's usage tags; neither is forwarded upstream.
+	attr := usageAttribution{User: r.Header.Get(userHeader), Tags: parseTags(r.Header.Get(tagsHeader))}
+	r.Header.Del(userHeader)
+	r.Header.Del(tagsHeader)
+	r = r.WithContext(withUsageAttribution(r.Context(), attr))
+
+	span.SetAttributes(attribute.String("zen.session", sessionID), attribute.String("zen.client", clientType))
+	if e := requestAccessEntry(r); e != nil {
+		e.SessionID, e.Client, e.User = sessionID, clientType, attr.User
+		e.Model, _ = requestModelAndStream(bodyBytes)
+	}
+
+	// Reject new requests once a block limit has been reached
+	if checker := GetGlobalBudgetChecker(); checker != nil && !strings.HasSuffix(r.URL.Path, "/count_tokens") {
+		model, _ := requestModelAndStream(bodyBytes)
+		if message, blocked := checker.AdmissionBlocked(GetSessionProject(sessionID), model, attr.User); blocked {
+			s.Logger.Printf("[budget] rejecting request: %s", message)
+			writeBudgetExceededError(w, message)
+			return
+		}
+	}
+
 	// Mark session as busy in bot bridge
 	if bridge := GetBotBridge(); bridge != nil && sessionID != "" {
 		bridge.MarkSessionBusy(sessionID, clientType)
@@ -300,6 +361,21 @@ func (s *ProxyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		requestFormat = config.ProviderTypeAnthropic // Default
 	}
 
+	// Per-request opt-in to debug body capture
+	capture := captureRequested(r.Header.Get(captureHeader))
+	r.Header.Del(captureHeader)
+
+	// Provider and model pins (checked against the allowlist by ProfileProxy)
+	providerPin := r.Header.Get(providerOverrideHeader)
+	modelPin := r.Header.Get(modelOverrideHeader)
+	r.Header.Del(providerOverrideHeader)
+	r.Header.Del(modelOverrideHeader)
+
+	// Apply the profile's request transform before routing sees the body
+	if !s.RequestTransform.IsEmpty() && !strings.HasSuffix(r.URL.Path, "/count_tokens") {
+		bodyBytes = applyRequestTransform(bodyBytes, s.RequestTransform, requestFormat)
+	}
+
 	// Detect protocol and normalize request for routing (T023-T024)
 	var bodyMap map[string]interface{}
 	var normalized *NormalizedRequest
@@ -339,54 +415,20 @@ func (s *ProxyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// [BETA] Apply context compression if enabled
-	if compressor := GetGlobalCompressor(); compressor != nil && compressor.IsEnabled() {
-		compressedBody, compressed, err := compressor.CompressRequestBody(bodyBytes)
-		if err != nil {
-			s.Logger.Printf("[compression] error: %v", err)
-		} else if compressed {
-			s.Logger.Printf("[compression] compressed request body from %d to %d bytes", len(bodyBytes), len(compressedBody))
-			bodyBytes = compressedBody
-		}
-	}
-
 	// [BETA] Apply middleware pipeline if enabled
 	var processedCtx *middleware.RequestContext
 	if pipeline := middleware.GetGlobalPipeline(); pipeline != nil && pipeline.IsEnabled() {
-		reqCtx := &middleware.RequestContext{
-			SessionID:         sessionID,
-			ClientType:        clientType,
-			Method:            r.Method,
-			Path:              r.URL.Path,
-			Headers:           r.Header.Clone(),
-			Body:              bodyBytes,
-			Metadata:          make(map[string]interface{}),
-			RequestFormat:     requestFormat,
-			NormalizedRequest: normalized,
-			Profile:           s.Profile,
-		}
-
-		// Parse model and messages for middleware
-		var bodyMap map[string]interface{}
-		if err := json.Unmarshal(bodyBytes, &bodyMap); err == nil {
-			if model, ok := bodyMap["model"].(string); ok {
-				reqCtx.Model = model
-			}
-			if msgs, ok := bodyMap["messages"].([]interface{}); ok {
-				for _, m := range msgs {
-					if msgMap, ok := m.(map[string]interface{}); ok {
-						role, _ := msgMap["role"].(string)
-						reqCtx.Messages = append(reqCtx.Messages, middleware.Message{
-							Role:    role,
-							Content: msgMap["content"],
-						})
-					}
-				}
-			}
-		}
+		reqCtx := s.newMiddlewareRequest(r, bodyBytes, sessionID, clientType, requestFormat)
+		reqCtx.NormalizedRequest = normalized
 
 		var err error
+		_, mwSpan := startSpan(r.Context(), "zen.middleware")
 		processedCtx, err = pipeline.ProcessRequest(reqCtx)
+		if err != nil {
+			mwSpan.RecordError(err)
+			mwSpan.SetStatus(codes.Error, err.Error())
+		}
+		mwSpan.End()
 		if err != nil {
 			s.Logger.Printf("[middleware] request processing error: %v", err)
 			http.Error(w, fmt.Sprintf("middleware error: %v", err), http.StatusBadRequest)
@@ -431,6 +473,50 @@ func (s *ProxyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// [BETA] Apply context compression if enabled. This runs after the
+	// middleware so truncated or redacted content is what gets summarized.
+	if compressor := GetGlobalCompressor(); compressor != nil {
+		compressor.SetProviders(s.Providers)
+		compressedBody, compressed, err := compressor.CompressRequestBodyFor(bodyBytes, GetSessionProject(sessionID))
+		if err != nil {
+			s.Logger.Printf("[compression] error: %v", err)
+		} else if compressed {
+			s.Logger.Printf("[compression] compressed request body from %d to %d bytes", len(bodyBytes), len(compressedBody))
+			r = r.WithContext(withCompression(r.Context(), AgentCompression{BytesBefore: len(bodyBytes), BytesAfter: len(compressedBody)}))
+			bodyBytes = compressedBody
+		}
+	}
+
+	// Serve exact repeats of deterministic requests from the response cache
+	if cache := GetGlobalResponseCache(); cache != nil && cache.IsEnabled() {
+		cacheScope := s.Profile
+		if providerPin != "" || modelPin != "" {
+			cacheScope += "|" + providerPin + "|" + modelPin
+		}
+		if key := responseCacheKey(cacheScope, r.URL.Path, bodyBytes); key != "" {
+			if entry := cache.Get(key); entry != nil {
+				s.Logger.Printf("[cache] serving cached response for %s", r.URL.Path)
+				writeCachedResponse(w, entry)
+				return
+			}
+			rec := &responseCacheRecorder{ResponseWriter: w}
+			rec.Header().Set(responseCacheHeader, "miss")
+			w = rec
+			defer func() {
+				if rec.cacheable() {
+					cache.Put(key, rec.statusCode, rec.Header().Get("Content-Type"), rec.buf.Bytes())
+				}
+			}()
+		}
+	}
+
+	// Mirror a share of requests to the profile's shadow provider
+	if trial := s.startShadow(r, bodyBytes, requestFormat); trial != nil {
+		rec := &shadowRecorder{ResponseWriter: w}
+		w = rec
+		defer trial.finish(rec)
+	}
+
 	// T034-T036: Extract routing decision and hints from middleware context
 	var middlewareDecision *RoutingDecision
 	var routingHints *RoutingHints
@@ -444,6 +530,7 @@ func (s *ProxyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// T035: Resolve routing decision (middleware > builtin classifier)
+	_, routingSpan := startSpan(r.Context(), "zen.routing")
 	// Use longContext route's threshold if available, otherwise use profile threshold
 	threshold := defaultLongContextThreshold
 	if s.Routing != nil && s.Routing.LongContextThreshold > 0 {
@@ -477,20 +564,37 @@ func (s *ProxyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		scenarioPriority = s.Routing.ScenarioPriority
 	}
 
-	decision := ResolveRoutingDecision(
-		middlewareDecision,
-		normalized,
-		features,
-		routingHints,
-		threshold,
-		scenarioPriority,
-		sessionID,
-		bodyMap,
-	)
+	// Custom scenarios with match rules take precedence over the builtin
+	// classifier, but not over a scenario chosen by middleware
+	var decision *RoutingDecision
+	if (middlewareDecision == nil || middlewareDecision.Scenario == "") && s.Routing != nil {
+		decision = matchCustomScenario(s.Routing.ScenarioRoutes, scenarioPriority, normalized, features, bodyMap)
+		if decision != nil {
+			applyMiddlewareOverrides(decision, middlewareDecision)
+		}
+	}
+	if decision == nil {
+		decision = ResolveRoutingDecision(
+			middlewareDecision,
+			normalized,
+			features,
+			routingHints,
+			threshold,
+			scenarioPriority,
+			sessionID,
+			bodyMap,
+		)
+	}
 
 	// T036: Log routing decision
 	s.Logger.Printf("[routing] scenario=%s, source=%s, reason=%s, confidence=%.2f",
 		decision.Scenario, decision.Source, decision.Reason, decision.Confidence)
+	routingSpan.SetAttributes(
+		attribute.String("zen.scenario", decision.Scenario),
+		attribute.String("zen.routing.source", decision.Source),
+		attribute.Float64("zen.routing.confidence", decision.Confidence),
+	)
+	routingSpan.End()
 
 	// T044-T045: Look up scenario route (with fallback to default)
 	providers := s.Providers
@@ -533,6 +637,12 @@ func (s *ProxyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// A pinned model wins over scenario models and hints
+	if modelPin != "" {
+		modelOverrides = pinModelOverrides(modelOverrides, providers, modelPin)
+		s.Logger.Printf("[routing] model pinned by %s: %s", modelOverrideHeader, modelPin)
+	}
+
 	// Apply provider allowlist/denylist filters
 	if len(decision.ProviderAllowlist) > 0 {
 		allowSet := make(map[string]bool)
@@ -641,11 +751,17 @@ func (s *ProxyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		providers = s.LoadBalancer.Select(providers, strategy, model, rrKey, modelOverrides, weights)
 	}
 
+	// Prompt cache affinity: try the provider that already holds this
+	// conversation's cache first, ahead of the strategy's choice.
+	if s.CacheAffinity {
+		providers = preferCachedProvider(providers, sessionID)
+	}
+
 	// Track provider failure details for error reporting
 	var failures []providerFailure
 
 	// Try scenario providers first, then fallback to default if all fail
-	success := s.tryProviders(w, r, providers, modelOverrides, bodyBytes, sessionID, clientType, requestFormat, &failures, requestStart)
+	success := s.tryProviders(w, r, providers, modelOverrides, bodyBytes, sessionID, clientType, requestFormat, capture, &failures, requestStart)
 	if success {
 		// Log request_received only if duration >1s (selective logging per T067)
 		duration := time.Since(requestStart)
@@ -711,7 +827,14 @@ func (s *ProxyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			}
 			defaultProviders = s.LoadBalancer.Select(defaultProviders, s.Strategy, model, s.Profile, nil, nil)
 		}
-		success = s.tryProviders(w, r, defaultProviders, nil, bodyBytes, sessionID, clientType, requestFormat, &failures, requestStart)
+		if s.CacheAffinity {
+			defaultProviders = preferCachedProvider(defaultProviders, sessionID)
+		}
+		var defaultOverrides map[string]string
+		if modelPin != "" {
+			defaultOverrides = pinModelOverrides(nil, defaultProviders, modelPin)
+		}
+		success = s.tryProviders(w, r, defaultProviders, defaultOverrides, bodyBytes, sessionID, clientType, requestFormat, capture, &failures, requestStart)
 		if success {
 			// Log request_received only if duration >1s (selective logging per T067)
 			duration := time.Since(requestStart)
@@ -746,11 +869,14 @@ func (s *ProxyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 // tryProviders attempts to forward the request to each provider in order.
 // Returns true if a provider successfully handled the request.
-func (s *ProxyServer) tryProviders(w http.ResponseWriter, r *http.Request, providers []*Provider, modelOverrides map[string]string, bodyBytes []byte, sessionID, clientType, requestFormat string, failures *[]providerFailure, requestStart time.Time) bool {
+// When capture is set (or the provider has capture_bodies), each attempt's
+// request and response bodies are stored for debugging.
+func (s *ProxyServer) tryProviders(w http.ResponseWriter, r *http.Request, providers []*Provider, modelOverrides map[string]string, bodyBytes []byte, sessionID, clientType, requestFormat string, capture bool, failures *[]providerFailure, requestStart time.Time) bool {
 	// Generate request ID for monitoring
 	requestID := generateRequestID()
 
-	for i, p := range providers {
+	for i := 0; i < len(providers); i++ {
+		p := providers[i]
 		isLast := i == len(providers)-1
 
 		// Skip manually disabled providers (checked via config, lazy evaluation)
@@ -761,6 +887,16 @@ func (s *ProxyServer) tryProviders(w http.ResponseWriter, r *http.Request, provi
 			continue
 		}
 
+		// Skip providers whose own budget is exhausted
+		if checker := GetGlobalBudgetChecker(); checker != nil {
+			if reason, blocked := checker.ScopeBlocked(p.Name, "", ""); blocked {
+				msg := "skipping (" + reason + ")"
+				s.Logger.Printf("[%s] %s", p.Name, msg)
+				s.logStructured(p.Name, r.Method, r.URL.Path, 0, LogLevelInfo, msg, sessionID, clientType)
+				continue
+			}
+		}
+
 		if !p.IsHealthy() && !isLast {
 			msg := fmt.Sprintf("skipping (unhealthy, backoff %v)", p.Backoff)
 			s.Logger.Printf("[%s] %s", p.Name, msg)
@@ -772,20 +908,76 @@ func (s *ProxyServer) tryProviders(w http.ResponseWriter, r *http.Request, provi
 			s.Logger.Printf("[%s] last provider, forcing request despite unhealthy (backoff %v)", p.Name, p.Backoff)
 		}
 
+		trace.SpanFromContext(r.Context()).AddEvent("zen.attempt", trace.WithAttributes(
+			attribute.String("zen.provider", p.Name),
+			attribute.Int("zen.attempt", len(*failures)+1),
+		))
+
 		// Get model override for this specific provider
 		var modelOverride string
 		if modelOverrides != nil {
 			modelOverride = modelOverrides[p.Name]
 		}
 
+		// Run middleware restricted to particular providers
+		sendBody, err := s.applyProviderMiddleware(r, p, bodyBytes, sessionID, clientType, requestFormat)
+		if err != nil {
+			s.Logger.Printf("[middleware] request processing error: %v", err)
+			http.Error(w, fmt.Sprintf("middleware error: %v", err), http.StatusBadRequest)
+			return true
+		}
+
+		// Send a cheaper model once a downgrade limit has been reached
+		if checker := GetGlobalBudgetChecker(); checker != nil {
+			if model, _ := requestModelAndStream(sendBody); model != "" {
+				if target, ok := checker.DowngradeFor(GetSessionProject(sessionID), p.Name, model, requestAttribution(r).User); ok {
+					s.Logger.Printf("[%s] budget downgrade: %s → %s", p.Name, model, target)
+					sendBody = s.applyModelOverride(sendBody, target, p.Name)
+				}
+			}
+		}
+
+		// Wait for a slot if the provider limits concurrent requests
+		queue := providerQueueFor(p)
+		if queue != nil {
+			waitStart := time.Now()
+			if err := queue.Acquire(r.Context()); err != nil {
+				if r.Context().Err() != nil {
+					s.Logger.Printf("[%s] request canceled by client while queued", p.Name)
+					return true
+				}
+				msg := fmt.Sprintf("skipping (%v)", err)
+				s.Logger.Printf("[%s] %s", p.Name, msg)
+				s.logStructured(p.Name, r.Method, r.URL.Path, 0, LogLevelWarn, msg, sessionID, clientType)
+				*failures = append(*failures, providerFailure{Name: p.Name, StatusCode: 0, Body: err.Error(), Elapsed: time.Since(waitStart)})
+				if s.MetricsRecorder != nil {
+					s.MetricsRecorder.RecordRequest(p.Name, time.Since(waitStart), &ProxyError{
+						Provider: p.Name,
+						ErrType:  ErrorTypeConcurrency,
+						Err:      err,
+					})
+				}
+				continue
+			}
+		}
+
 		if p.ProxyURL != "" {
 			s.Logger.Printf("[%s] trying %s %s via proxy %s", p.Name, r.Method, r.URL.Path, config.MaskProxyURL(p.ProxyURL))
 		} else {
 			s.Logger.Printf("[%s] trying %s %s", p.Name, r.Method, r.URL.Path)
 		}
+		keyID := p.KeyID()
 		start := time.Now()
-		resp, err := s.forwardRequest(r, p, bodyBytes, modelOverride, requestFormat)
+		resp, err := s.forwardRequest(r, p, sendBody, modelOverride, requestFormat)
 		elapsed := time.Since(start)
+		if queue != nil {
+			// Hold the slot until the response body has been fully relayed
+			if err != nil {
+				queue.Release()
+			} else {
+				resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: queue.Release}
+			}
+		}
 		if err != nil {
 			// Check if this is a transform error - don't mark provider unhealthy
 			var transformErr *TransformError
@@ -842,6 +1034,24 @@ func (s *ProxyServer) tryProviders(w http.ResponseWriter, r *http.Request, provi
 		if resp.StatusCode == 401 || resp.StatusCode == 402 || resp.StatusCode == 403 {
 			errBody, _ := io.ReadAll(resp.Body)
 			resp.Body.Close()
+			if shouldCapture(capture, p) {
+				s.captureExchange(requestID, p.Name, sessionID, resp, sendBody, errBody)
+			}
+
+			// A rejected key → retry the same provider with its next key
+			if resp.StatusCode != 402 {
+				next := p.NextKey(keyID)
+				if rec, ok := s.MetricsRecorder.(KeyRejectionRecorder); ok {
+					rec.RecordKeyRejected(p.Name, keyID, resp.StatusCode, next)
+				}
+				if next != "" {
+					msg := fmt.Sprintf("got %d with key %s, retrying with key %s", resp.StatusCode, keyID, next)
+					s.Logger.Printf("[%s] %s response=%s", p.Name, msg, string(errBody))
+					s.logStructuredWithResponse(p.Name, r.Method, r.URL.Path, resp.StatusCode, msg, errBody, sessionID, clientType)
+					i--
+					continue
+				}
+			}
 			msg := fmt.Sprintf("got %d (auth/account error), failing over", resp.StatusCode)
 			s.Logger.Printf("[%s] %s response=%s", p.Name, msg, string(errBody))
 			s.logStructuredWithResponse(p.Name, r.Method, r.URL.Path, resp.StatusCode, msg, errBody, sessionID, clientType)
@@ -864,6 +1074,9 @@ func (s *ProxyServer) tryProviders(w http.ResponseWriter, r *http.Request, provi
 		if resp.StatusCode == 429 {
 			errBody, _ := io.ReadAll(resp.Body)
 			resp.Body.Close()
+			if shouldCapture(capture, p) {
+				s.captureExchange(requestID, p.Name, sessionID, resp, sendBody, errBody)
+			}
 			msg := fmt.Sprintf("got %d (rate limited), failing over", resp.StatusCode)
 			s.Logger.Printf("[%s] %s response=%s", p.Name, msg, string(errBody))
 			s.logStructuredWithResponse(p.Name, r.Method, r.URL.Path, resp.StatusCode, msg, errBody, sessionID, clientType)
@@ -887,11 +1100,14 @@ func (s *ProxyServer) tryProviders(w http.ResponseWriter, r *http.Request, provi
 			// Read body to check error type
 			errBody, _ := io.ReadAll(resp.Body)
 			resp.Body.Close()
+			if shouldCapture(capture, p) {
+				s.captureExchange(requestID, p.Name, sessionID, resp, sendBody, errBody)
+			}
 
 			// Check if provider expects Responses API format (not Chat Completions)
 			if isResponsesAPIRequired(errBody) && p.GetType() == config.ProviderTypeOpenAI {
 				s.Logger.Printf("[%s] got 'input is required', retrying with Responses API format", p.Name)
-				retryResp, retryErr := s.retryWithResponsesAPI(r, p, bodyBytes, modelOverride, requestFormat)
+				retryResp, retryErr := s.retryWithResponsesAPI(r, p, sendBody, modelOverride, requestFormat)
 				if retryErr != nil {
 					s.Logger.Printf("[%s] Responses API retry error: %v", p.Name, retryErr)
 					*failures = append(*failures, providerFailure{Name: p.Name, StatusCode: 0, Body: retryErr.Error(), Elapsed: time.Since(start)})
@@ -906,7 +1122,7 @@ func (s *ProxyServer) tryProviders(w http.ResponseWriter, r *http.Request, provi
 					s.updateSessionCache(sessionID, retryResp)
 
 					// Record usage and metrics
-					s.recordUsageAndMetrics(p.Name, sessionID, clientType, bodyBytes, retryResp, requestID, requestStart, requestFormat, failures)
+					s.recordUsageAndMetrics(p.Name, sessionID, clientType, requestAttribution(r), requestCompression(r), requestAccessEntry(r), sendBody, retryResp, requestID, requestStart, requestFormat, failures)
 
 					// Record daemon-level metrics if recorder is available
 					if s.MetricsRecorder != nil {
@@ -926,7 +1142,7 @@ func (s *ProxyServer) tryProviders(w http.ResponseWriter, r *http.Request, provi
 
 			if isRequestRelatedError(errBody) {
 				// Request-related error (e.g., context too long) - failover without marking unhealthy
-				msg := fmt.Sprintf("got %d (request-related error), failing over without backoff, request_body_size=%d", resp.StatusCode, len(bodyBytes))
+				msg := fmt.Sprintf("got %d (request-related error), failing over without backoff, request_body_size=%d", resp.StatusCode, len(sendBody))
 				s.Logger.Printf("[%s] %s response=%s", p.Name, msg, string(errBody))
 				s.logStructuredWithResponse(p.Name, r.Method, r.URL.Path, resp.StatusCode, msg, errBody, sessionID, clientType)
 				// Log provider_failed event (T068)
@@ -969,22 +1185,29 @@ func (s *ProxyServer) tryProviders(w http.ResponseWriter, r *http.Request, provi
 
 		// Update session cache with token usage from response.
 		// For SSE (streaming), wrap the body with an extractor that parses
-		// usage events in-flight so longContext routing stays accurate.
-		if sessionID != "" && strings.Contains(resp.Header.Get("Content-Type"), "text/event-stream") {
-			resp.Body = &sseUsageExtractor{r: resp.Body, sessionID: sessionID}
+		// usage events in-flight so longContext routing stays accurate and
+		// the stream's TTFT and output rate reach the health tracker.
+		if strings.Contains(resp.Header.Get("Content-Type"), "text/event-stream") {
+			resp.Body = &sseUsageExtractor{r: resp.Body, sessionID: sessionID, provider: p.Name, start: start}
 		} else {
 			s.updateSessionCache(sessionID, resp)
 		}
 
 		// Record usage and metrics
-		s.recordUsageAndMetrics(p.Name, sessionID, clientType, bodyBytes, resp, requestID, requestStart, requestFormat, failures)
+		s.recordUsageAndMetrics(p.Name, sessionID, clientType, requestAttribution(r), requestCompression(r), requestAccessEntry(r), sendBody, resp, requestID, requestStart, requestFormat, failures)
 
 		// Record daemon-level metrics if recorder is available
 		if s.MetricsRecorder != nil {
 			s.MetricsRecorder.RecordRequest(p.Name, time.Since(requestStart), nil)
 		}
 
-		s.copyResponse(w, resp, p, requestFormat)
+		if shouldCapture(capture, p) {
+			s.captureResponse(requestID, p.Name, sessionID, resp, sendBody)
+		}
+
+		guard := newBudgetStreamGuard(resp, p.Name, sendBody, sessionID, requestAttribution(r).User, requestFormat, start)
+		tools := newToolCallFilter(r.Context(), resp, sessionID, requestFormat)
+		s.copyResponse(w, resp, p, requestFormat, guard, tools)
 		return true
 	}
 
@@ -1057,10 +1280,18 @@ func (s *ProxyServer) forwardRequest(r *http.Request, p *Provider, body []byte,
 		modifiedBody = s.applyModelMapping(body, p)
 	}
 
-	// Apply request transformation if needed
+	// Gemini carries the model and stream flag in the URL rather than the
+	// body, so capture them before the body is transformed.
 	providerFormat := p.GetType()
+	var geminiModel string
+	var geminiStream bool
+	if providerFormat == config.ProviderTypeGemini {
+		geminiModel, geminiStream = requestModelAndStream(modifiedBody)
+	}
+
+	// Apply request transformation if needed
 	if transform.NeedsTransform(requestFormat, providerFormat) {
-		transformer := transform.GetTransformer(providerFormat)
+		transformer := transformerFor(p)
 		transformed, err := transformer.TransformRequest(modifiedBody, requestFormat)
 		if err != nil {
 			s.Logger.Printf("[%s] transform request error: %v", p.Name, err)
@@ -1070,6 +1301,13 @@ func (s *ProxyServer) forwardRequest(r *http.Request, p *Provider, body []byte,
 		modifiedBody = transformed
 	}
 
+	// Apply per-provider declarative rewrites on the provider's wire format
+	var requestRules *config.TransformRules
+	if p.Transforms != nil {
+		requestRules = p.Transforms.Request
+	}
+	modifiedBody = applyBodyRules(modifiedBody, requestRules)
+
 	// Transform path if needed (e.g., /responses → /v1/messages)
 	targetPath := r.URL.Path
 	if transform.NeedsTransform(requestFormat, providerFormat) {
@@ -1079,19 +1317,23 @@ func (s *ProxyServer) forwardRequest(r *http.Request, p *Provider, body []byte,
 		}
 	}
 
+	rawQuery := r.URL.RawQuery
+	if providerFormat == config.ProviderTypeGemini {
+		targetPath = transform.GeminiPath(geminiModel, geminiStream)
+		rawQuery = transform.GeminiQuery(rawQuery, geminiStream)
+	}
+
 	// Deduplicate /v1 prefix when base_url already ends with /v1
 	// e.g., base_url "https://host/v1" + targetPath "/v1/chat/completions"
 	// should produce "https://host/v1/chat/completions", not "https://host/v1/v1/chat/completions"
-	basePath := strings.TrimSuffix(p.BaseURL.Path, "/")
-	if strings.HasSuffix(basePath, "/v1") && strings.HasPrefix(targetPath, "/v1") {
-		originalTarget := targetPath
-		targetPath = targetPath[3:] // strip "/v1", keep e.g. "/chat/completions"
-		s.Logger.Printf("[%s] path dedup: %s → %s (base_url has /v1)", p.Name, originalTarget, targetPath)
+	if deduped := dedupVersionPrefix(p.BaseURL.Path, targetPath); deduped != targetPath {
+		s.Logger.Printf("[%s] path dedup: %s → %s (base_url has %s)", p.Name, targetPath, deduped, strings.TrimSuffix(targetPath, deduped))
+		targetPath = deduped
 	}
 
 	targetURL := singleJoiningSlash(p.BaseURL.String(), targetPath)
-	if r.URL.RawQuery != "" {
-		targetURL += "?" + r.URL.RawQuery
+	if rawQuery != "" {
+		targetURL += "?" + rawQuery
 	}
 
 	req, err := http.NewRequestWithContext(r.Context(), r.Method, targetURL, bytes.NewReader(modifiedBody))
@@ -1106,20 +1348,60 @@ func (s *ProxyServer) forwardRequest(r *http.Request, p *Provider, body []byte,
 		}
 	}
 
-	// Override auth
-	req.Header.Set("x-api-key", p.Token)
-	req.Header.Set("Authorization", "Bearer "+p.Token)
+	// Override auth (Gemini rejects a non-OAuth bearer token, so only send its API key header)
+	if providerFormat == config.ProviderTypeGemini {
+		req.Header.Del("x-api-key")
+		req.Header.Del("Authorization")
+		req.Header.Set("x-goog-api-key", p.AuthToken())
+	} else {
+		token := p.AuthToken()
+		req.Header.Set("x-api-key", token)
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
 	req.Header.Set("Content-Length", fmt.Sprintf("%d", len(modifiedBody)))
 
 	// Apply environment variable headers
 	s.applyEnvVarsHeaders(req, p.EnvVars)
+	applyHeaderRules(req.Header, requestRules)
 
 	// Use per-provider client if available, otherwise fall back to shared client
 	client := s.Client
 	if p.Client != nil {
 		client = p.Client
 	}
-	return client.Do(req)
+	return doUpstream(client, req, p, "forward")
+}
+
+// dedupVersionPrefix strips the API version prefix from targetPath when
+// the base URL path already ends with it.
+func dedupVersionPrefix(basePath, targetPath string) string {
+	basePath = strings.TrimSuffix(basePath, "/")
+	if strings.HasSuffix(basePath, "/v1beta") && strings.HasPrefix(targetPath, "/v1beta/") {
+		return targetPath[7:] // strip "/v1beta", keep e.g. "/models/..."
+	}
+	if strings.HasSuffix(basePath, "/v1") && strings.HasPrefix(targetPath, "/v1") {
+		return targetPath[3:] // strip "/v1", keep e.g. "/chat/completions"
+	}
+	return targetPath
+}
+
+// transformerFor returns the format transformer for a provider, carrying
+// provider-specific settings (e.g. Gemini safety settings) where needed.
+func transformerFor(p *Provider) transform.Transformer {
+	if p.GetType() == config.ProviderTypeGemini {
+		return &transform.GeminiTransformer{SafetySettings: p.SafetySettings}
+	}
+	return transform.GetTransformer(p.GetType())
+}
+
+// requestModelAndStream extracts the model name and stream flag from a request body.
+func requestModelAndStream(body []byte) (string, bool) {
+	var data struct {
+		Model  string `json:"model"`
+		Stream bool   `json:"stream"`
+	}
+	json.Unmarshal(body, &data)
+	return data.Model, data.Stream
 }
 
 // retryWithResponsesAPI re-sends a request using the Responses API format
@@ -1181,8 +1463,9 @@ func (s *ProxyServer) retryWithResponsesAPI(r *http.Request, p *Provider, origin
 	}
 
 	// Override auth
-	req.Header.Set("x-api-key", p.Token)
-	req.Header.Set("Authorization", "Bearer "+p.Token)
+	token := p.AuthToken()
+	req.Header.Set("x-api-key", token)
+	req.Header.Set("Authorization", "Bearer "+token)
 	req.Header.Set("Content-Length", fmt.Sprintf("%d", len(responsesBody)))
 
 	s.applyEnvVarsHeaders(req, p.EnvVars)
@@ -1191,7 +1474,7 @@ func (s *ProxyServer) retryWithResponsesAPI(r *http.Request, p *Provider, origin
 	if p.Client != nil {
 		client = p.Client
 	}
-	return client.Do(req)
+	return doUpstream(client, req, p, "responses_retry")
 }
 
 // copyResponseFromResponsesAPI transforms a Responses API response to the client's
@@ -1291,13 +1574,21 @@ func (s *ProxyServer) copyResponseFromResponsesAPI(w http.ResponseWriter, resp *
 	}
 }
 
-func (s *ProxyServer) copyResponse(w http.ResponseWriter, resp *http.Response, p *Provider, requestFormat string) {
+// copyResponse writes resp to the client, transforming it to the client's
+// format. A non-nil guard cuts off a stream that would exceed the budget,
+// and non-nil tools holds back the tool calls the policy blocks.
+func (s *ProxyServer) copyResponse(w http.ResponseWriter, resp *http.Response, p *Provider, requestFormat string, guard *budgetStreamGuard, tools *toolCallFilter) {
 	defer resp.Body.Close()
 
 	// Check if response transformation is needed
 	providerFormat := p.GetType()
 	needsTransform := transform.NeedsTransform(requestFormat, providerFormat)
 
+	var responseRules *config.TransformRules
+	if p.Transforms != nil {
+		responseRules = p.Transforms.Response
+	}
+
 	// Stream SSE responses
 	if strings.Contains(resp.Header.Get("Content-Type"), "text/event-stream") {
 		for k, vv := range resp.Header {
@@ -1305,20 +1596,28 @@ func (s *ProxyServer) copyResponse(w http.ResponseWriter, resp *http.Response, p
 				w.Header().Add(k, v)
 			}
 		}
+		applyHeaderRules(w.Header(), responseRules)
 		w.WriteHeader(resp.StatusCode)
 
 		flusher, ok := w.(http.Flusher)
 
-		// Apply stream transformation if needed
-		var reader io.Reader = resp.Body
+		// Apply per-provider rewrites, then stream transformation if needed
+		reader := applySSEBodyRules(resp.Body, responseRules)
 		if needsTransform {
 			st := &transform.StreamTransformer{
 				ClientFormat:   requestFormat,
 				ProviderFormat: providerFormat,
 			}
-			reader = st.TransformSSEStream(resp.Body)
+			reader = st.TransformSSEStream(reader)
 			s.Logger.Printf("[%s] transforming SSE stream: %s → %s", p.Name, providerFormat, requestFormat)
 		}
+		if tools != nil {
+			reader = tools.stream(reader)
+		}
+		if guard != nil {
+			guard.r = reader
+			reader = guard
+		}
 
 		buf := make([]byte, 4096)
 		for {
@@ -1336,6 +1635,9 @@ func (s *ProxyServer) copyResponse(w http.ResponseWriter, resp *http.Response, p
 				break
 			}
 		}
+		if guard != nil && guard.cut {
+			s.Logger.Printf("[%s] stream cut off at estimated $%.4f, budget remaining $%.4f", p.Name, guard.accrued, guard.remaining)
+		}
 		return
 	}
 
@@ -1345,10 +1647,11 @@ func (s *ProxyServer) copyResponse(w http.ResponseWriter, resp *http.Response, p
 		http.Error(w, "failed to read response", http.StatusBadGateway)
 		return
 	}
+	body = applyBodyRules(body, responseRules)
 
 	// Apply response transformation if needed
 	if needsTransform && len(body) > 0 {
-		transformer := transform.GetTransformer(providerFormat)
+		transformer := transformerFor(p)
 		transformed, err := transformer.TransformResponse(body, requestFormat)
 		if err != nil {
 			s.Logger.Printf("[%s] transform response error: %v", p.Name, err)
@@ -1368,6 +1671,9 @@ func (s *ProxyServer) copyResponse(w http.ResponseWriter, resp *http.Response, p
 		s.Logger.Printf("[%s] transformed response: %s → %s", p.Name, providerFormat, requestFormat)
 		body = transformed
 	}
+	if tools != nil {
+		body = tools.filterBody(body)
+	}
 
 	// Copy headers (except Content-Length which may have changed)
 	for k, vv := range resp.Header {
@@ -1378,6 +1684,7 @@ func (s *ProxyServer) copyResponse(w http.ResponseWriter, resp *http.Response, p
 			w.Header().Add(k, v)
 		}
 	}
+	applyHeaderRules(w.Header(), responseRules)
 	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(body)))
 	w.WriteHeader(resp.StatusCode)
 	w.Write(body)
@@ -1425,6 +1732,20 @@ func (s *ProxyServer) applyModelMapping(body []byte, p *Provider) []byte {
 		return body
 	}
 
+	// Alias rules take precedence over the provider's own mapping
+	if aliased, ok := resolveModelAlias(config.GetModelAliases(), originalModel, p.Name); ok {
+		if aliased == originalModel {
+			return body
+		}
+		s.Logger.Printf("[%s] model alias: %s → %s", p.Name, originalModel, aliased)
+		data["model"] = aliased
+		modified, err := json.Marshal(data)
+		if err != nil {
+			return body
+		}
+		return modified
+	}
+
 	mapped := s.mapModel(originalModel, data, p)
 	if mapped == originalModel {
 		return body
@@ -1538,32 +1859,65 @@ func (s *ProxyServer) updateSessionCache(sessionID string, resp *http.Response)
 	}
 
 	// Extract usage from response
-	usage, ok := respData["usage"].(map[string]interface{})
-	if !ok {
+	var inputTokens, outputTokens, cacheCreation, cacheRead float64
+	batch := false
+	if usage, ok := respData["usage"].(map[string]interface{}); ok {
+		// Anthropic reports cache writes and reads separately from input_tokens.
+		inputTokens, _ = usage["input_tokens"].(float64)
+		outputTokens, _ = usage["output_tokens"].(float64)
+		cacheCreation, _ = usage["cache_creation_input_tokens"].(float64)
+		cacheRead, _ = usage["cache_read_input_tokens"].(float64)
+		// Anthropic reports the tier in the usage block, OpenAI at the top level
+		tier, _ := usage["service_tier"].(string)
+		if tier == "" {
+			tier, _ = respData["service_tier"].(string)
+		}
+		batch = tier == "batch"
+	} else if usage, ok := respData["usageMetadata"].(map[string]interface{}); ok {
+		// Gemini: {"usageMetadata":{"promptTokenCount":N,"candidatesTokenCount":M,"cachedContentTokenCount":C}}
+		// promptTokenCount includes cached tokens.
+		inputTokens, _ = usage["promptTokenCount"].(float64)
+		outputTokens, _ = usage["candidatesTokenCount"].(float64)
+		cacheRead, _ = usage["cachedContentTokenCount"].(float64)
+		inputTokens -= cacheRead
+	} else {
 		return
 	}
 
-	inputTokens, _ := usage["input_tokens"].(float64)
-	outputTokens, _ := usage["output_tokens"].(float64)
-
-	if inputTokens > 0 || outputTokens > 0 {
+	if inputTokens > 0 || outputTokens > 0 || cacheCreation > 0 || cacheRead > 0 {
 		UpdateSessionUsage(sessionID, &SessionUsage{
-			InputTokens:  int(inputTokens),
-			OutputTokens: int(outputTokens),
+			InputTokens:         int(inputTokens),
+			OutputTokens:        int(outputTokens),
+			CacheCreationTokens: int(cacheCreation),
+			CacheReadTokens:     int(cacheRead),
+			Batch:               batch,
 		})
-		s.Logger.Printf("[session] updated cache for %s: input=%d, output=%d",
-			sessionID, int(inputTokens), int(outputTokens))
+		s.Logger.Printf("[session] updated cache for %s: input=%d, output=%d, cache_write=%d, cache_read=%d",
+			sessionID, int(inputTokens), int(outputTokens), int(cacheCreation), int(cacheRead))
 	}
 }
 
 // recordUsageAndMetrics records usage data and provider metrics after a successful request.
-func (s *ProxyServer) recordUsageAndMetrics(providerName, sessionID, clientType string, requestBody []byte, resp *http.Response, requestID string, requestStart time.Time, requestFormat string, failures *[]providerFailure) {
+func (s *ProxyServer) recordUsageAndMetrics(providerName, sessionID, clientType string, attr usageAttribution, compression *AgentCompression, access *accessEntry, requestBody []byte, resp *http.Response, requestID string, requestStart time.Time, requestFormat string, failures *[]providerFailure) {
 	// Extract model from request
 	var reqData map[string]interface{}
 	model := ""
 	if err := json.Unmarshal(requestBody, &reqData); err == nil {
 		model, _ = reqData["model"].(string)
 	}
+	if access != nil {
+		access.RequestID, access.Provider, access.Model = requestID, providerName, model
+	}
+
+	// Report the agent session's work once the tokens it used are known
+	var activity *AgentActivity
+	reportActivity := func() {}
+	if hook := agentActivityHook(); hook != nil && sessionID != "" {
+		a := agentActivityFor(sessionID, clientType, reqData)
+		a.Compression = compression
+		activity = &a
+		reportActivity = func() { hook(*activity) }
+	}
 
 	// Calculate total duration
 	duration := time.Since(requestStart)
@@ -1577,6 +1931,29 @@ func (s *ProxyServer) recordUsageAndMetrics(providerName, sessionID, clientType
 			db.RecordMetric(providerName, 0, resp.StatusCode, false, false)
 		}
 
+		// The stream reports the activity, and its tokens reach the access
+		// log, when it ends
+		if ex, ok := resp.Body.(*sseUsageExtractor); ok {
+			if access != nil {
+				access.stream = ex
+			}
+			ex.activity = activity
+			if tracker := GetGlobalUsageTracker(); tracker != nil && activity != nil {
+				ex.activityCost = func(e *sseUsageExtractor) float64 {
+					return tracker.CalculateRequestCost(providerName, CostInput{
+						Model:               model,
+						InputTokens:         e.inputTok,
+						OutputTokens:        e.outputTok,
+						CacheCreationTokens: e.cacheWrite,
+						CacheReadTokens:     e.cacheRead,
+						Duration:            time.Since(requestStart),
+					})
+				}
+			}
+		} else {
+			reportActivity()
+		}
+
 		// Record request to monitor (streaming, no token info yet)
 		monitor := GetGlobalRequestMonitor()
 		monitor.Add(RequestRecord{
@@ -1598,6 +1975,8 @@ func (s *ProxyServer) recordUsageAndMetrics(providerName, sessionID, clientType
 	// Get usage from session cache (was just updated by updateSessionCache)
 	usage := GetSessionUsage(sessionID)
 	if usage == nil {
+		reportActivity()
+
 		// Record request without token info
 		monitor := GetGlobalRequestMonitor()
 		monitor.Add(RequestRecord{
@@ -1616,26 +1995,56 @@ func (s *ProxyServer) recordUsageAndMetrics(providerName, sessionID, clientType
 		return
 	}
 
+	if access != nil {
+		access.InputTokens, access.OutputTokens = usage.InputTokens, usage.OutputTokens
+	}
+
 	// Calculate cost
 	tracker := GetGlobalUsageTracker()
 	if tracker == nil {
+		reportActivity()
 		return
 	}
 
-	cost := tracker.CalculateCost(model, usage.InputTokens, usage.OutputTokens)
+	costInput := CostInput{
+		Model:               model,
+		InputTokens:         usage.InputTokens,
+		OutputTokens:        usage.OutputTokens,
+		CacheCreationTokens: usage.CacheCreationTokens,
+		CacheReadTokens:     usage.CacheReadTokens,
+		Duration:            duration,
+		Batch:               usage.Batch,
+	}
+	cost, breakdown, _ := tracker.RequestCost(providerName, costInput)
 
 	// Record usage entry
 	entry := UsageEntry{
-		Timestamp:    time.Now(),
-		SessionID:    sessionID,
-		Provider:     providerName,
-		Model:        model,
-		InputTokens:  usage.InputTokens,
-		OutputTokens: usage.OutputTokens,
-		CostUSD:      cost,
-		ClientType:   clientType,
+		Timestamp:           time.Now(),
+		SessionID:           sessionID,
+		Provider:            providerName,
+		Model:               model,
+		InputTokens:         usage.InputTokens,
+		OutputTokens:        usage.OutputTokens,
+		CacheCreationTokens: usage.CacheCreationTokens,
+		CacheReadTokens:     usage.CacheReadTokens,
+		CostUSD:             cost,
+		ProjectPath:         GetSessionProject(sessionID),
+		ClientType:          clientType,
+		User:                attr.User,
+		Tags:                attr.Tags,
+		Batch:               usage.Batch,
+		Breakdown:           breakdown,
 	}
 	tracker.Record(entry)
+	if activity != nil {
+		activity.InputTokens, activity.OutputTokens, activity.Cost = usage.InputTokens, usage.OutputTokens, cost
+		reportActivity()
+	}
+
+	// Remember where this conversation's prompt cache lives
+	if usage.CacheCreationTokens > 0 || usage.CacheReadTokens > 0 {
+		RecordCacheAffinity(sessionID, providerName)
+	}
 
 	// Record provider metric
 	if db := GetGlobalLogDB(); db != nil {
@@ -1644,12 +2053,13 @@ func (s *ProxyServer) recordUsageAndMetrics(providerName, sessionID, clientType
 
 	// Update session with turn info
 	AddTurnToSession(sessionID, TurnUsage{
-		InputTokens:  usage.InputTokens,
-		OutputTokens: usage.OutputTokens,
-		Cost:         cost,
-		Model:        model,
-		Provider:     providerName,
-		Timestamp:    time.Now(),
+		InputTokens:   usage.InputTokens,
+		OutputTokens:  usage.OutputTokens,
+		Cost:          cost,
+		Model:         model,
+		Provider:      providerName,
+		ContextTokens: usage.InputTokens + usage.CacheCreationTokens + usage.CacheReadTokens,
+		Timestamp:     time.Now(),
 	})
 
 	// Update bot bridge with session status
@@ -1884,13 +2294,23 @@ func GetDaemonLogger() daemonLogger {
 
 // sseUsageExtractor wraps an SSE response body, parsing Anthropic SSE events
 // in-flight to extract token usage. When the stream ends, it updates the
-// session cache so longContext routing has accurate usage for streaming turns.
+// session cache so longContext routing has accurate usage for streaming turns,
+// and records the stream's time to first token and output rate.
 type sseUsageExtractor struct {
-	r         io.ReadCloser
-	sessionID string
-	partial   []byte      // incomplete line buffer
-	inputTok  int
-	outputTok int
+	r          io.ReadCloser
+	sessionID  string
+	provider   string    // provider serving the stream, for cache affinity
+	start      time.Time // when the upstream request was sent
+	firstToken time.Time // when the first content event arrived
+	partial    []byte    // incomplete line buffer
+	inputTok   int
+	outputTok  int
+	cacheWrite int
+	cacheRead  int
+	// generated text seen so far, for estimating cost mid-stream
+	outputChars  int
+	activity     *AgentActivity                   // reported once the stream ends
+	activityCost func(*sseUsageExtractor) float64 // prices the activity from the stream's usage
 }
 
 func (e *sseUsageExtractor) Read(p []byte) (n int, err error) {
@@ -1901,15 +2321,53 @@ func (e *sseUsageExtractor) Read(p []byte) (n int, err error) {
 	if err == io.EOF {
 		if e.sessionID != "" && (e.inputTok > 0 || e.outputTok > 0) {
 			UpdateSessionUsage(e.sessionID, &SessionUsage{
-				InputTokens:  e.inputTok,
-				OutputTokens: e.outputTok,
+				InputTokens:         e.inputTok,
+				OutputTokens:        e.outputTok,
+				CacheCreationTokens: e.cacheWrite,
+				CacheReadTokens:     e.cacheRead,
 			})
 		}
+		if e.cacheWrite > 0 || e.cacheRead > 0 {
+			RecordCacheAffinity(e.sessionID, e.provider)
+		}
+		e.recordStreamRate()
+		e.reportActivity()
 	}
 	return
 }
 
-func (e *sseUsageExtractor) Close() error { return e.r.Close() }
+// reportActivity passes the agent activity of the request, with the tokens
+// the stream used, to the OnAgentActivity hook.
+func (e *sseUsageExtractor) reportActivity() {
+	if e.activity == nil {
+		return
+	}
+	a := *e.activity
+	e.activity = nil
+	a.InputTokens, a.OutputTokens = e.inputTok, e.outputTok
+	if e.activityCost != nil {
+		a.Cost = e.activityCost(e)
+	}
+	if hook := agentActivityHook(); hook != nil {
+		hook(a)
+	}
+}
+
+// recordStreamRate reports the completed stream's timing to the health tracker.
+func (e *sseUsageExtractor) recordStreamRate() {
+	if e.start.IsZero() || e.firstToken.IsZero() {
+		return
+	}
+	if checker := GetGlobalHealthChecker(); checker != nil {
+		checker.RecordStream(e.provider, e.firstToken.Sub(e.start), e.outputTok, time.Since(e.firstToken))
+	}
+	e.start = time.Time{} // record once even if Read is called again after EOF
+}
+
+func (e *sseUsageExtractor) Close() error {
+	e.reportActivity() // a stream closed early reports what it saw
+	return e.r.Close()
+}
 
 // processChunk scans raw SSE bytes for usage data events.
 func (e *sseUsageExtractor) processChunk(data []byte) {
@@ -1935,14 +2393,24 @@ func (e *sseUsageExtractor) processChunk(data []byte) {
 			continue
 		}
 		evType, _ := ev["type"].(string)
+		if e.firstToken.IsZero() && isTokenEvent(evType, ev) {
+			e.firstToken = time.Now()
+		}
+		e.outputChars += deltaChars(evType, ev)
 		switch evType {
 		case "message_start":
-			// Anthropic: {"type":"message_start","message":{"usage":{"input_tokens":N}}}
+			// Anthropic: {"type":"message_start","message":{"usage":{"input_tokens":N,"cache_read_input_tokens":R}}}
 			if msg, ok := ev["message"].(map[string]interface{}); ok {
 				if u, ok := msg["usage"].(map[string]interface{}); ok {
 					if v, ok := u["input_tokens"].(float64); ok {
 						e.inputTok += int(v)
 					}
+					if v, ok := u["cache_creation_input_tokens"].(float64); ok {
+						e.cacheWrite += int(v)
+					}
+					if v, ok := u["cache_read_input_tokens"].(float64); ok {
+						e.cacheRead += int(v)
+					}
 				}
 			}
 		case "message_delta":
@@ -1976,8 +2444,44 @@ func (e *sseUsageExtractor) processChunk(data []byte) {
 						e.outputTok += int(v)
 					}
 				}
+				// Gemini chunks carry cumulative usageMetadata, so keep the latest.
+				if u, ok := ev["usageMetadata"].(map[string]interface{}); ok {
+					if v, ok := u["promptTokenCount"].(float64); ok {
+						e.inputTok = int(v)
+					}
+					if v, ok := u["candidatesTokenCount"].(float64); ok {
+						e.outputTok = int(v)
+					}
+				}
 			}
 		}
 	}
 	e.partial = buf
 }
+
+// isTokenEvent reports whether an SSE event carries generated content, which
+// marks the first token of a stream.
+func isTokenEvent(evType string, ev map[string]interface{}) bool {
+	switch evType {
+	case "content_block_delta", "response.output_text.delta", "response.function_call_arguments.delta":
+		return true
+	case "":
+		// OpenAI Chat Completions: the first chunk may carry only the role
+		if choices, ok := ev["choices"].([]interface{}); ok {
+			for _, c := range choices {
+				choice, _ := c.(map[string]interface{})
+				delta, _ := choice["delta"].(map[string]interface{})
+				if content, _ := delta["content"].(string); content != "" {
+					return true
+				}
+				if _, ok := delta["tool_calls"]; ok {
+					return true
+				}
+			}
+		}
+		// Gemini
+		_, ok := ev["candidates"]
+		return ok
+	}
+	return false
+}
@@ -1020,6 +1020,69 @@ func TestServeHTTPFailoverOn402(t *testing.T) {
 	}
 }
 
+// keyRecorder records key rejections for TestServeHTTPRotatesKeyOn401.
+type keyRecorder struct {
+	rejected []string
+}
+
+func (r *keyRecorder) RecordRequest(string, time.Duration, error) {}
+
+func (r *keyRecorder) RecordKeyRejected(provider, keyID string, status int, nextKeyID string) {
+	r.rejected = append(r.rejected, fmt.Sprintf("%s/%s/%d->%s", provider, keyID, status, nextKeyID))
+}
+
+// TestServeHTTPRotatesKeyOn401 tests that a rejected key falls back to the
+// provider's next key before failing over to another provider.
+func TestServeHTTPRotatesKeyOn401(t *testing.T) {
+	var tokens []string
+	backend1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokens = append(tokens, r.Header.Get("x-api-key"))
+		if r.Header.Get("x-api-key") != "new-token" {
+			w.WriteHeader(401)
+			return
+		}
+		w.WriteHeader(200)
+	}))
+	defer backend1.Close()
+	backend2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request should not fail over to p2")
+	}))
+	defer backend2.Close()
+
+	u1, _ := url.Parse(backend1.URL)
+	u2, _ := url.Parse(backend2.URL)
+	p1 := &Provider{Name: "p1", BaseURL: u1, Token: "old-token", Model: "m", Healthy: true,
+		Keys: []*config.ProviderKey{{ID: "stale", Token: "stale-token"}, {ID: "new", Token: "new-token"}}}
+	providers := []*Provider{p1, {Name: "p2", BaseURL: u2, Token: "t2", Model: "m", Healthy: true}}
+
+	rec := &keyRecorder{}
+	srv := NewProxyServer(providers, discardLogger(), config.LoadBalanceFailover, nil)
+	srv.MetricsRecorder = rec
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("POST", "/v1/messages", strings.NewReader(`{}`)))
+		if w.Code != 200 {
+			t.Fatalf("request %d: status = %d, want 200", i, w.Code)
+		}
+	}
+
+	// The second request goes straight to the key that worked
+	if got := strings.Join(tokens, ","); got != "old-token,stale-token,new-token,new-token" {
+		t.Errorf("tokens sent = %s", got)
+	}
+	if got := strings.Join(rec.rejected, ","); got != "p1/primary/401->stale,p1/stale/401->new" {
+		t.Errorf("rejections = %s", got)
+	}
+	if !p1.IsHealthy() || p1.KeyID() != "new" {
+		t.Errorf("p1 healthy=%v key=%s, want healthy on key new", p1.IsHealthy(), p1.KeyID())
+	}
+
+	// With no key left the provider fails over as before
+	if next := p1.NextKey("new"); next != "" {
+		t.Errorf("NextKey() past the last key = %q, want empty", next)
+	}
+}
+
 // TestAuthFailedLongBackoff tests that auth failure (401/403) uses long backoff.
 func TestAuthFailedLongBackoff(t *testing.T) {
 	u, _ := url.Parse("https://api.example.com")
@@ -2490,6 +2553,79 @@ func TestE2E_AnthropicToOpenAI_NonStreaming(t *testing.T) {
 	}
 }
 
+// TestE2E_AnthropicToGemini_NonStreaming tests the Anthropic→Gemini pipeline:
+// model-in-path routing, API key header, request and response transformation.
+func TestE2E_AnthropicToGemini_NonStreaming(t *testing.T) {
+	var receivedPath, receivedKey, receivedAuth string
+	var receivedBody map[string]interface{}
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedPath = r.URL.Path
+		receivedKey = r.Header.Get("x-goog-api-key")
+		receivedAuth = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &receivedBody)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write([]byte(`{
+			"candidates": [{"content": {"role": "model", "parts": [{"text": "Hello from Gemini!"}]}, "finishReason": "STOP"}],
+			"usageMetadata": {"promptTokenCount": 10, "candidatesTokenCount": 5},
+			"modelVersion": "gemini-test"
+		}`))
+	}))
+	defer backend.Close()
+
+	u, _ := url.Parse(backend.URL + "/v1beta")
+	providers := []*Provider{{
+		Name:    "gemini-e2e",
+		Type:    config.ProviderTypeGemini,
+		BaseURL: u,
+		Token:   "test-key",
+		Model:   "gemini-test",
+		Healthy: true,
+	}}
+
+	srv := NewProxyServer(providers, discardLogger(), config.LoadBalanceFailover, nil)
+
+	req := httptest.NewRequest("POST", "/v1/messages", strings.NewReader(
+		`{"model":"claude-sonnet-4-6","max_tokens":100,"messages":[{"role":"user","content":"Hello"}]}`))
+	req.Header.Set("X-Zen-Request-Format", "anthropic")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("status = %d, want 200, body: %s", w.Code, w.Body.String())
+	}
+
+	// Model travels in the path; /v1beta must not be duplicated
+	if receivedPath != "/v1beta/models/gemini-test:generateContent" {
+		t.Errorf("path = %q, want /v1beta/models/gemini-test:generateContent", receivedPath)
+	}
+	if receivedKey != "test-key" {
+		t.Errorf("x-goog-api-key = %q, want test-key", receivedKey)
+	}
+	if receivedAuth != "" {
+		t.Errorf("Authorization = %q, want empty", receivedAuth)
+	}
+	if _, ok := receivedBody["contents"]; !ok {
+		t.Error("request should carry Gemini contents")
+	}
+	if _, ok := receivedBody["model"]; ok {
+		t.Error("request body should not carry model for Gemini")
+	}
+
+	var respData map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &respData)
+	if respData["type"] != "message" || respData["stop_reason"] != "end_turn" {
+		t.Errorf("response = %v", respData)
+	}
+	content, _ := respData["content"].([]interface{})
+	if len(content) == 0 || content[0].(map[string]interface{})["text"] != "Hello from Gemini!" {
+		t.Errorf("content = %v", respData["content"])
+	}
+}
+
 // TestE2E_AnthropicToOpenAI_Streaming tests SSE streaming transformation.
 func TestE2E_AnthropicToOpenAI_Streaming(t *testing.T) {
 	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -3372,6 +3508,37 @@ func TestSSEUsageExtractor(t *testing.T) {
 		}
 	})
 
+	t.Run("extracts_cache_tokens_and_records_affinity", func(t *testing.T) {
+		sse := strings.Join([]string{
+			`data: {"type":"message_start","message":{"usage":{"input_tokens":5,"cache_creation_input_tokens":100,"cache_read_input_tokens":2000}}}`,
+			``,
+			`data: {"type":"message_delta","usage":{"output_tokens":7}}`,
+			``,
+		}, "\n")
+		sessionID := "test-sse-cache-session"
+		ClearSessionUsage(sessionID)
+
+		extractor := &sseUsageExtractor{
+			r:         io.NopCloser(strings.NewReader(sse)),
+			sessionID: sessionID,
+			provider:  "cached-provider",
+		}
+		if _, err := io.ReadAll(extractor); err != nil {
+			t.Fatalf("unexpected read error: %v", err)
+		}
+
+		got := GetSessionUsage(sessionID)
+		if got == nil {
+			t.Fatal("expected session usage to be updated, got nil")
+		}
+		if got.CacheCreationTokens != 100 || got.CacheReadTokens != 2000 {
+			t.Errorf("cache tokens = %d/%d, want 100/2000", got.CacheCreationTokens, got.CacheReadTokens)
+		}
+		if p := GetCacheAffinity(sessionID); p != "cached-provider" {
+			t.Errorf("GetCacheAffinity() = %q, want cached-provider", p)
+		}
+	})
+
 	t.Run("no_update_on_empty_session", func(t *testing.T) {
 		sse := "data: {\"type\":\"message_start\",\"message\":{\"usage\":{\"input_tokens\":10}}}\n\n"
 		extractor := &sseUsageExtractor{
@@ -3455,3 +3622,61 @@ func TestSSEUsageExtractor(t *testing.T) {
 		}
 	})
 }
+
+func TestSSEUsageExtractorRecordsStreamRate(t *testing.T) {
+	oldGlobal := globalHealthChecker
+	defer func() { globalHealthChecker = oldGlobal }()
+	globalHealthChecker = NewHealthChecker(nil)
+
+	sse := strings.Join([]string{
+		`data: {"type":"message_start","message":{"usage":{"input_tokens":5}}}`,
+		``,
+		`data: {"type":"content_block_delta","delta":{"type":"text_delta","text":"hi"}}`,
+		``,
+		`data: {"type":"message_delta","usage":{"output_tokens":20}}`,
+		``,
+	}, "\n")
+	extractor := &sseUsageExtractor{
+		r:        io.NopCloser(strings.NewReader(sse)),
+		provider: "p1",
+		start:    time.Now().Add(-250 * time.Millisecond),
+	}
+	if _, err := io.ReadAll(extractor); err != nil {
+		t.Fatalf("unexpected read error: %v", err)
+	}
+
+	m := globalHealthChecker.GetStreamMetrics("p1")
+	if m.Samples != 1 {
+		t.Fatalf("Samples = %d, want 1", m.Samples)
+	}
+	if m.AvgTTFTMs < 250 {
+		t.Errorf("AvgTTFTMs = %v, want >= 250", m.AvgTTFTMs)
+	}
+	if m.TokensPerSec <= 0 {
+		t.Errorf("TokensPerSec = %v, want > 0", m.TokensPerSec)
+	}
+}
+
+func TestIsTokenEvent(t *testing.T) {
+	tests := []struct {
+		payload string
+		want    bool
+	}{
+		{`{"type":"message_start","message":{}}`, false},
+		{`{"type":"content_block_delta","delta":{"text":"x"}}`, true},
+		{`{"type":"response.output_text.delta","delta":"x"}`, true},
+		{`{"choices":[{"delta":{"role":"assistant"}}]}`, false},
+		{`{"choices":[{"delta":{"content":"x"}}]}`, true},
+		{`{"candidates":[{"content":{"parts":[{"text":"x"}]}}]}`, true},
+	}
+	for _, tt := range tests {
+		var ev map[string]interface{}
+		if err := json.Unmarshal([]byte(tt.payload), &ev); err != nil {
+			t.Fatal(err)
+		}
+		evType, _ := ev["type"].(string)
+		if got := isTokenEvent(evType, ev); got != tt.want {
+			t.Errorf("isTokenEvent(%s) = %v, want %v", tt.payload, got, tt.want)
+		}
+	}
+}
@@ -4,17 +4,20 @@ import (
 	"fmt"
 	"sync"
 	"time"
+
+	"github.com/dopejs/gozen/internal/config"
 )
 
 // TurnUsage represents token usage for a single conversation turn.
 type TurnUsage struct {
-	InputTokens  int       `json:"input_tokens"`
-	OutputTokens int       `json:"output_tokens"`
-	Cost         float64   `json:"cost"`
-	Model        string    `json:"model,omitempty"`
-	Provider     string    `json:"provider,omitempty"`
-	LatencyMs    int       `json:"latency_ms,omitempty"`
-	Timestamp    time.Time `json:"timestamp"`
+	InputTokens   int       `json:"input_tokens"`
+	OutputTokens  int       `json:"output_tokens"`
+	Cost          float64   `json:"cost"`
+	Model         string    `json:"model,omitempty"`
+	Provider      string    `json:"provider,omitempty"`
+	LatencyMs     int       `json:"latency_ms,omitempty"`
+	ContextTokens int       `json:"context_tokens,omitempty"` // prompt tokens, cached ones included
+	Timestamp     time.Time `json:"timestamp"`
 }
 
 // SessionUsage stores token usage information for a session.
@@ -28,38 +31,43 @@ type TurnUsage struct {
 // This means InputTokens reflects the real token count that was billed,
 // not the original uncompacted context size.
 type SessionUsage struct {
-	InputTokens  int         `json:"input_tokens"`  // Actual input tokens sent to API (after compaction)
-	OutputTokens int         `json:"output_tokens"` // Output tokens generated by API
-	TotalCost    float64     `json:"total_cost"`    // Total cost in USD
-	TurnCount    int         `json:"turn_count"`    // Number of conversation turns
-	Turns        []TurnUsage `json:"turns,omitempty"` // Per-turn details (limited history)
-	Timestamp    time.Time   `json:"timestamp"`     // When this usage was last updated
+	InputTokens         int            `json:"input_tokens"`                    // Actual input tokens sent to API (after compaction)
+	OutputTokens        int            `json:"output_tokens"`                   // Output tokens generated by API
+	CacheCreationTokens int            `json:"cache_creation_tokens,omitempty"` // Prompt tokens written to the provider's cache
+	CacheReadTokens     int            `json:"cache_read_tokens,omitempty"`     // Prompt tokens served from the provider's cache
+	Batch               bool           `json:"batch,omitempty"`                 // Last response was served by a batch API
+	TotalCost           float64        `json:"total_cost"`                      // Total cost in USD
+	TurnCount           int            `json:"turn_count"`                      // Number of conversation turns
+	Turns               []TurnUsage    `json:"turns,omitempty"`                 // Per-turn details (limited history)
+	Context             []ContextPoint `json:"context,omitempty"`               // Context window use per turn (limited history)
+	Timestamp           time.Time      `json:"timestamp"`                       // When this usage was last updated
 }
 
 // SessionInsight provides detailed insights about a session.
 type SessionInsight struct {
-	SessionID       string       `json:"session_id"`
-	TotalInput      int          `json:"total_input"`
-	TotalOutput     int          `json:"total_output"`
-	TotalCost       float64      `json:"total_cost"`
-	TurnCount       int          `json:"turn_count"`
-	AvgInputPerTurn float64      `json:"avg_input_per_turn"`
+	SessionID        string      `json:"session_id"`
+	TotalInput       int         `json:"total_input"`
+	TotalOutput      int         `json:"total_output"`
+	TotalCost        float64     `json:"total_cost"`
+	TurnCount        int         `json:"turn_count"`
+	AvgInputPerTurn  float64     `json:"avg_input_per_turn"`
 	AvgOutputPerTurn float64     `json:"avg_output_per_turn"`
-	AvgCostPerTurn  float64      `json:"avg_cost_per_turn"`
-	RecentTurns     []TurnUsage  `json:"recent_turns,omitempty"`
-	StartTime       *time.Time   `json:"start_time,omitempty"`
-	LastActivity    *time.Time   `json:"last_activity,omitempty"`
-	Duration        string       `json:"duration,omitempty"`
+	AvgCostPerTurn   float64     `json:"avg_cost_per_turn"`
+	RecentTurns      []TurnUsage `json:"recent_turns,omitempty"`
+	StartTime        *time.Time  `json:"start_time,omitempty"`
+	LastActivity     *time.Time  `json:"last_activity,omitempty"`
+	Duration         string      `json:"duration,omitempty"`
+	Annotation       *Annotation `json:"annotation,omitempty"` // labels and note, filled in by the web API
 }
 
 // ContextWarning provides a warning when context is getting large.
 type ContextWarning struct {
-	SessionID       string  `json:"session_id"`
-	CurrentTokens   int     `json:"current_tokens"`
-	Threshold       int     `json:"threshold"`
-	PercentUsed     float64 `json:"percent_used"`
-	Warning         string  `json:"warning"`
-	Recommendation  string  `json:"recommendation"`
+	SessionID      string  `json:"session_id"`
+	CurrentTokens  int     `json:"current_tokens"`
+	Threshold      int     `json:"threshold"`
+	PercentUsed    float64 `json:"percent_used"`
+	Warning        string  `json:"warning"`
+	Recommendation string  `json:"recommendation"`
 }
 
 // SessionCache manages session usage data with LRU eviction.
@@ -68,6 +76,8 @@ type SessionCache struct {
 	mu       sync.Mutex
 	maxSize  int
 	keyOrder []string // Track insertion order for LRU
+
+	onContextWarning func(ContextAlert)
 }
 
 const defaultMaxCacheSize = 1000 // Maximum number of sessions to cache
@@ -76,6 +86,28 @@ var globalSessionCache = &SessionCache{
 	maxSize: defaultMaxCacheSize,
 }
 
+// sessionProjects maps session IDs to the directory the client was
+// launched from, as registered by the launcher.
+var sessionProjects sync.Map
+
+// SetSessionProject records the project directory of a session. An empty
+// dir forgets it.
+func SetSessionProject(sessionID, dir string) {
+	if dir == "" {
+		sessionProjects.Delete(sessionID)
+		return
+	}
+	sessionProjects.Store(sessionID, dir)
+}
+
+// GetSessionProject returns the project directory of a session, if known.
+func GetSessionProject(sessionID string) string {
+	if v, ok := sessionProjects.Load(sessionID); ok {
+		return v.(string)
+	}
+	return ""
+}
+
 // GetSessionUsage retrieves the last usage for a session.
 func GetSessionUsage(sessionID string) *SessionUsage {
 	if sessionID == "" {
@@ -202,15 +234,29 @@ func extractSessionID(body map[string]interface{}) string {
 	return ""
 }
 
-// AddTurnToSession adds a turn to the session's history.
+// AddTurnToSession adds a turn to the session's history and context
+// window timeline.
 func AddTurnToSession(sessionID string, turn TurnUsage) {
 	if sessionID == "" {
 		return
 	}
+	point := contextPointFor(turn)
+	warnPercent := config.GetAlerts().GetContextWarnPercent()
 
 	globalSessionCache.mu.Lock()
-	defer globalSessionCache.mu.Unlock()
+	usage := addTurnLocked(sessionID, turn)
+	crossed := recordContextPoint(usage, point, warnPercent)
+	onWarning := globalSessionCache.onContextWarning
+	globalSessionCache.mu.Unlock()
 
+	if crossed && onWarning != nil {
+		onWarning(ContextAlert{SessionID: sessionID, WarnPercent: warnPercent, ContextPoint: point})
+	}
+}
+
+// addTurnLocked adds a turn to the session's usage, creating the session if
+// needed. The caller holds globalSessionCache.mu.
+func addTurnLocked(sessionID string, turn TurnUsage) *SessionUsage {
 	val, ok := globalSessionCache.data.Load(sessionID)
 	if !ok {
 		// Create new session
@@ -224,7 +270,7 @@ func AddTurnToSession(sessionID string, turn TurnUsage) {
 		}
 		globalSessionCache.keyOrder = append(globalSessionCache.keyOrder, sessionID)
 		globalSessionCache.data.Store(sessionID, usage)
-		return
+		return usage
 	}
 
 	usage := val.(*SessionUsage)
@@ -242,6 +288,7 @@ func AddTurnToSession(sessionID string, turn TurnUsage) {
 	}
 
 	globalSessionCache.data.Store(sessionID, usage)
+	return usage
 }
 
 // GetSessionInsight returns detailed insights for a session.
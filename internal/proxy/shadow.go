@@ -0,0 +1,284 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// shadowTimeout bounds how long a mirrored request may run.
+const shadowTimeout = 5 * time.Minute
+
+// maxShadowRecordBytes caps how much of the primary response is kept to
+// read its token usage.
+const maxShadowRecordBytes = 4 << 20
+
+// shadowTarget is the mirror provider of a profile and the share of
+// requests it receives.
+type shadowTarget struct {
+	provider *Provider
+	percent  float64
+}
+
+// shadowResult is the outcome of one side of a mirrored request.
+type shadowResult struct {
+	latency      time.Duration
+	statusCode   int
+	err          bool
+	inputTokens  int
+	outputTokens int
+	cost         float64
+}
+
+// shadowTrial tracks one mirrored request while the primary is served.
+type shadowTrial struct {
+	profile  string
+	provider string
+	model    string
+	start    time.Time
+	mirror   chan shadowResult
+}
+
+// startShadow sends a copy of the request to the profile's mirror provider
+// if this request was sampled. The mirror runs detached from the client
+// request and its response is discarded.
+func (s *ProxyServer) startShadow(r *http.Request, body []byte, requestFormat string) *shadowTrial {
+	target := s.Shadow
+	if target == nil || target.provider == nil || rand.Float64()*100 >= target.percent {
+		return nil
+	}
+	model, _ := requestModelAndStream(body)
+	trial := &shadowTrial{
+		profile:  s.Profile,
+		provider: target.provider.Name,
+		model:    model,
+		start:    time.Now(),
+		mirror:   make(chan shadowResult, 1),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shadowTimeout)
+	mr := r.Clone(ctx)
+	go func() {
+		defer cancel()
+		trial.mirror <- s.runShadow(mr, target.provider, body, requestFormat, model)
+	}()
+	return trial
+}
+
+// runShadow forwards the mirrored request and measures it.
+func (s *ProxyServer) runShadow(r *http.Request, p *Provider, body []byte, requestFormat, model string) shadowResult {
+	start := time.Now()
+	resp, err := s.forwardRequest(r, p, body, "", requestFormat)
+	if err != nil {
+		s.Logger.Printf("[shadow] %s: %v", p.Name, err)
+		return shadowResult{latency: time.Since(start), err: true}
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxShadowRecordBytes))
+	io.Copy(io.Discard, resp.Body)
+
+	result := shadowResult{
+		latency:    time.Since(start),
+		statusCode: resp.StatusCode,
+		err:        resp.StatusCode >= 400,
+	}
+	result.inputTokens, result.outputTokens = shadowUsage(resp.Header.Get("Content-Type"), respBody)
+	if tracker := GetGlobalUsageTracker(); tracker != nil {
+		result.cost = tracker.CalculateRequestCost(p.Name, CostInput{
+			Model:        model,
+			InputTokens:  result.inputTokens,
+			OutputTokens: result.outputTokens,
+			Duration:     result.latency,
+		})
+	}
+	return result
+}
+
+// finish measures the primary response once it has been relayed and
+// records the comparison when the mirror completes.
+func (t *shadowTrial) finish(rec *shadowRecorder) {
+	primary := shadowResult{
+		latency:    time.Since(t.start),
+		statusCode: rec.statusCode,
+		err:        rec.statusCode == 0 || rec.statusCode >= 400,
+	}
+	primary.inputTokens, primary.outputTokens = shadowUsage(rec.Header().Get("Content-Type"), rec.buf.Bytes())
+	if tracker := GetGlobalUsageTracker(); tracker != nil {
+		primary.cost = tracker.CalculateCost(t.model, primary.inputTokens, primary.outputTokens)
+	}
+	go func() {
+		globalExperiments.record(t.profile, t.provider, primary, <-t.mirror)
+	}()
+}
+
+// shadowUsage extracts token usage from a JSON body or an SSE stream.
+func shadowUsage(contentType string, body []byte) (int, int) {
+	if !strings.Contains(contentType, "text/event-stream") {
+		return responseUsage(body)
+	}
+	var input, output int
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 64*1024), maxShadowRecordBytes)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "" || data == "[DONE]" {
+			continue
+		}
+		// Anthropic reports input tokens in message_start
+		var event struct {
+			Message struct {
+				Usage struct {
+					InputTokens int `json:"input_tokens"`
+				} `json:"usage"`
+			} `json:"message"`
+		}
+		if json.Unmarshal([]byte(data), &event) == nil && event.Message.Usage.InputTokens > 0 {
+			input = event.Message.Usage.InputTokens
+		}
+		// message_delta and OpenAI's final chunk carry cumulative usage
+		if in, out := responseUsage([]byte(data)); in > 0 || out > 0 {
+			if in > 0 {
+				input = in
+			}
+			if out > 0 {
+				output = out
+			}
+		}
+	}
+	return input, output
+}
+
+// shadowRecorder relays the primary response while keeping a copy of the
+// body to read its token usage.
+type shadowRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	buf        bytes.Buffer
+}
+
+func (r *shadowRecorder) WriteHeader(code int) {
+	if r.statusCode == 0 {
+		r.statusCode = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *shadowRecorder) Write(p []byte) (int, error) {
+	if r.statusCode == 0 {
+		r.statusCode = http.StatusOK
+	}
+	if r.buf.Len()+len(p) <= maxShadowRecordBytes {
+		r.buf.Write(p)
+	}
+	return r.ResponseWriter.Write(p)
+}
+
+func (r *shadowRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// ExperimentSide aggregates one side of a shadow traffic experiment.
+type ExperimentSide struct {
+	Errors       int64   `json:"errors"`
+	ErrorRate    float64 `json:"error_rate"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+
+	totalLatency time.Duration
+}
+
+func (e *ExperimentSide) add(r shadowResult) {
+	if r.err {
+		e.Errors++
+	}
+	e.totalLatency += r.latency
+	e.InputTokens += int64(r.inputTokens)
+	e.OutputTokens += int64(r.outputTokens)
+	e.CostUSD += r.cost
+}
+
+// ExperimentReport compares a profile's primary traffic with its mirror.
+type ExperimentReport struct {
+	Profile        string         `json:"profile"`
+	MirrorProvider string         `json:"mirror_provider"`
+	Samples        int64          `json:"samples"`
+	Primary        ExperimentSide `json:"primary"`
+	Mirror         ExperimentSide `json:"mirror"`
+	LatencyDiffMs  float64        `json:"latency_diff_ms"` // mirror minus primary, average per request
+	CostDiffUSD    float64        `json:"cost_diff_usd"`   // mirror minus primary, total
+	ErrorRateDiff  float64        `json:"error_rate_diff"` // mirror minus primary
+	LastSampleAt   time.Time      `json:"last_sample_at"`
+}
+
+// experimentRegistry holds comparison reports keyed by profile and mirror.
+type experimentRegistry struct {
+	mu      sync.Mutex
+	reports map[string]*ExperimentReport
+}
+
+var globalExperiments = &experimentRegistry{reports: make(map[string]*ExperimentReport)}
+
+func (e *experimentRegistry) record(profile, provider string, primary, mirror shadowResult) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	key := profile + "\x00" + provider
+	rep, ok := e.reports[key]
+	if !ok {
+		rep = &ExperimentReport{Profile: profile, MirrorProvider: provider}
+		e.reports[key] = rep
+	}
+	rep.Samples++
+	rep.Primary.add(primary)
+	rep.Mirror.add(mirror)
+	rep.LastSampleAt = time.Now()
+}
+
+// GetExperimentReports returns the shadow traffic comparison reports,
+// sorted by profile and mirror provider.
+func GetExperimentReports() []ExperimentReport {
+	globalExperiments.mu.Lock()
+	defer globalExperiments.mu.Unlock()
+	reports := make([]ExperimentReport, 0, len(globalExperiments.reports))
+	for _, rep := range globalExperiments.reports {
+		r := *rep
+		n := float64(r.Samples)
+		for _, side := range []*ExperimentSide{&r.Primary, &r.Mirror} {
+			side.ErrorRate = float64(side.Errors) / n
+			side.AvgLatencyMs = float64(side.totalLatency.Microseconds()) / 1000 / n
+		}
+		r.LatencyDiffMs = r.Mirror.AvgLatencyMs - r.Primary.AvgLatencyMs
+		r.CostDiffUSD = r.Mirror.CostUSD - r.Primary.CostUSD
+		r.ErrorRateDiff = r.Mirror.ErrorRate - r.Primary.ErrorRate
+		reports = append(reports, r)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].Profile != reports[j].Profile {
+			return reports[i].Profile < reports[j].Profile
+		}
+		return reports[i].MirrorProvider < reports[j].MirrorProvider
+	})
+	return reports
+}
+
+// ResetExperimentReports clears all shadow traffic comparison reports.
+func ResetExperimentReports() {
+	globalExperiments.mu.Lock()
+	defer globalExperiments.mu.Unlock()
+	globalExperiments.reports = make(map[string]*ExperimentReport)
+}
@@ -0,0 +1,95 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+func TestShadowUsage(t *testing.T) {
+	in, out := shadowUsage("application/json", []byte(`{"usage":{"input_tokens":12,"output_tokens":34}}`))
+	if in != 12 || out != 34 {
+		t.Errorf("JSON usage = %d/%d, want 12/34", in, out)
+	}
+
+	anthropic := "event: message_start\n" +
+		`data: {"type":"message_start","message":{"usage":{"input_tokens":20,"output_tokens":1}}}` + "\n\n" +
+		"event: message_delta\n" +
+		`data: {"type":"message_delta","usage":{"output_tokens":40}}` + "\n\n"
+	if in, out := shadowUsage("text/event-stream", []byte(anthropic)); in != 20 || out != 40 {
+		t.Errorf("Anthropic stream usage = %d/%d, want 20/40", in, out)
+	}
+
+	openai := `data: {"choices":[{"delta":{"content":"hi"}}]}` + "\n\n" +
+		`data: {"choices":[],"usage":{"prompt_tokens":7,"completion_tokens":9}}` + "\n\n" +
+		"data: [DONE]\n\n"
+	if in, out := shadowUsage("text/event-stream", []byte(openai)); in != 7 || out != 9 {
+		t.Errorf("OpenAI stream usage = %d/%d, want 7/9", in, out)
+	}
+}
+
+func TestProxyShadowTraffic(t *testing.T) {
+	ResetExperimentReports()
+	t.Cleanup(ResetExperimentReports)
+
+	primaryHits := 0
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		primaryHits++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"primary","content":[],"usage":{"input_tokens":10,"output_tokens":5}}`))
+	}))
+	defer primary.Close()
+	mirrorHits := make(chan struct{}, 10)
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mirrorHits <- struct{}{}
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":"boom"}`))
+	}))
+	defer mirror.Close()
+
+	pu, _ := url.Parse(primary.URL)
+	mu, _ := url.Parse(mirror.URL)
+	srv := NewProxyServer([]*Provider{{Name: "primary", BaseURL: pu, Token: "t", Healthy: true}}, discardLogger(), config.LoadBalanceFailover, nil)
+	srv.Profile = "shadowed"
+	srv.Shadow = &shadowTarget{provider: &Provider{Name: "candidate", BaseURL: mu, Token: "t", Healthy: true}, percent: 100}
+
+	req := httptest.NewRequest("POST", "/v1/messages", strings.NewReader(`{"model":"claude-sonnet-4-5","messages":[]}`))
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != 200 || !strings.Contains(w.Body.String(), `"primary"`) {
+		t.Fatalf("client got %d: %s", w.Code, w.Body.String())
+	}
+	select {
+	case <-mirrorHits:
+	case <-time.After(2 * time.Second):
+		t.Fatal("request was not mirrored")
+	}
+
+	var reports []ExperimentReport
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if reports = GetExperimentReports(); len(reports) > 0 {
+			break
+		}
+	}
+	if len(reports) != 1 {
+		t.Fatalf("reports = %+v, want one", reports)
+	}
+	rep := reports[0]
+	if rep.Profile != "shadowed" || rep.MirrorProvider != "candidate" || rep.Samples != 1 {
+		t.Errorf("report = %+v", rep)
+	}
+	if rep.Primary.Errors != 0 || rep.Mirror.Errors != 1 || rep.ErrorRateDiff != 1 {
+		t.Errorf("error comparison = primary %d, mirror %d, diff %v", rep.Primary.Errors, rep.Mirror.Errors, rep.ErrorRateDiff)
+	}
+	if rep.Primary.InputTokens != 10 || rep.Primary.OutputTokens != 5 {
+		t.Errorf("primary tokens = %d/%d, want 10/5", rep.Primary.InputTokens, rep.Primary.OutputTokens)
+	}
+	if primaryHits != 1 {
+		t.Errorf("primary hits = %d, want 1", primaryHits)
+	}
+}
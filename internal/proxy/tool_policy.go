@@ -0,0 +1,282 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/proxy/transform"
+)
+
+// ToolCallCheck is a tool call from a model response, offered to the tool
+// call policy before the client sees it.
+type ToolCallCheck struct {
+	SessionID   string
+	ProjectPath string
+	ID          string
+	Name        string
+	Input       json.RawMessage // with object keys sorted
+}
+
+// ToolCallVerdict is the tool call policy's answer. A blocked call is
+// replaced by a text block telling the model why.
+type ToolCallVerdict struct {
+	Blocked bool
+	Reason  string
+}
+
+var (
+	toolPolicyMu sync.RWMutex
+	toolPolicy   func(context.Context, ToolCallCheck) ToolCallVerdict
+)
+
+// SetToolCallPolicy sets the function that decides whether the tool calls
+// in Anthropic-format responses reach the client. It may block while a
+// decision is pending, for instance on a person's approval; the response is
+// held back until it returns. Its context ends when the client goes away.
+func SetToolCallPolicy(fn func(context.Context, ToolCallCheck) ToolCallVerdict) {
+	toolPolicyMu.Lock()
+	defer toolPolicyMu.Unlock()
+	toolPolicy = fn
+}
+
+// toolCallFilter applies the tool call policy to one response.
+type toolCallFilter struct {
+	ctx         context.Context
+	sessionID   string
+	projectPath string
+	check       func(context.Context, ToolCallCheck) ToolCallVerdict
+}
+
+// newToolCallFilter returns a filter for a response in the client's
+// format, or nil when no policy is set or the format carries no
+// Anthropic tool_use blocks.
+func newToolCallFilter(ctx context.Context, resp *http.Response, sessionID, requestFormat string) *toolCallFilter {
+	toolPolicyMu.RLock()
+	check := toolPolicy
+	toolPolicyMu.RUnlock()
+	if check == nil || resp.StatusCode != http.StatusOK || transform.NormalizeFormat(requestFormat) != config.ProviderTypeAnthropic {
+		return nil
+	}
+	return &toolCallFilter{ctx: ctx, sessionID: sessionID, projectPath: GetSessionProject(sessionID), check: check}
+}
+
+// verdict asks the policy about one tool call.
+func (f *toolCallFilter) verdict(id, name string, input interface{}) ToolCallVerdict {
+	normalized, _ := json.Marshal(input)
+	return f.check(f.ctx, ToolCallCheck{
+		SessionID:   f.sessionID,
+		ProjectPath: f.projectPath,
+		ID:          id,
+		Name:        name,
+		Input:       normalized,
+	})
+}
+
+// blockedToolText is what the model reads in place of a blocked tool call.
+func blockedToolText(name, reason string) string {
+	return fmt.Sprintf("[GoZen guardrails] The %s tool call was blocked: %s. Do not retry it; ask the user how to proceed.", name, reason)
+}
+
+// filterBody applies the policy to a non-streaming Messages response.
+func (f *toolCallFilter) filterBody(body []byte) []byte {
+	var msg map[string]interface{}
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return body
+	}
+	content, _ := msg["content"].([]interface{})
+	blocked, allowed := 0, 0
+	for i, block := range content {
+		b, _ := block.(map[string]interface{})
+		if b["type"] != "tool_use" {
+			continue
+		}
+		id, _ := b["id"].(string)
+		name, _ := b["name"].(string)
+		if v := f.verdict(id, name, b["input"]); v.Blocked {
+			content[i] = map[string]interface{}{"type": "text", "text": blockedToolText(name, v.Reason)}
+			blocked++
+		} else {
+			allowed++
+		}
+	}
+	if blocked == 0 {
+		return body
+	}
+	if allowed == 0 && msg["stop_reason"] == "tool_use" {
+		msg["stop_reason"] = "end_turn"
+	}
+	out, err := json.Marshal(msg)
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+// stream applies the policy to a Messages event stream. Each tool_use
+// block is held back until it is complete and the policy has ruled on it;
+// everything else passes straight through.
+func (f *toolCallFilter) stream(r io.Reader) io.Reader {
+	return &toolCallStream{filter: f, src: bufio.NewReader(r)}
+}
+
+type toolCallStream struct {
+	filter  *toolCallFilter
+	src     *bufio.Reader
+	out     bytes.Buffer
+	err     error
+	held    *heldToolBlock
+	blocked int
+	allowed int
+}
+
+// heldToolBlock is a tool_use block being buffered.
+type heldToolBlock struct {
+	index  int
+	id     string
+	name   string
+	input  strings.Builder
+	events bytes.Buffer
+}
+
+// streamEvent is the part of a Messages stream event the filter reads.
+type streamEvent struct {
+	Type         string `json:"type"`
+	Index        int    `json:"index"`
+	ContentBlock struct {
+		Type string `json:"type"`
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"content_block"`
+	Delta struct {
+		Type        string `json:"type"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta"`
+}
+
+func (s *toolCallStream) Read(p []byte) (int, error) {
+	for s.out.Len() == 0 && s.err == nil {
+		event, err := s.readEvent()
+		if len(event) > 0 {
+			s.process(event)
+		}
+		if err != nil {
+			if s.held != nil {
+				// The stream ended inside a tool_use block: pass on what came
+				s.out.Write(s.held.events.Bytes())
+				s.held = nil
+			}
+			s.err = err
+		}
+	}
+	if s.out.Len() > 0 {
+		return s.out.Read(p)
+	}
+	return 0, s.err
+}
+
+// readEvent reads up to and including the blank line that ends an event.
+func (s *toolCallStream) readEvent() ([]byte, error) {
+	var event []byte
+	for {
+		line, err := s.src.ReadBytes('\n')
+		event = append(event, line...)
+		if err != nil || len(bytes.TrimRight(line, "\r\n")) == 0 {
+			return event, err
+		}
+	}
+}
+
+func (s *toolCallStream) process(event []byte) {
+	data := eventData(event)
+	var e streamEvent
+	if data == nil || json.Unmarshal(data, &e) != nil {
+		s.out.Write(event)
+		return
+	}
+
+	if h := s.held; h != nil {
+		h.events.Write(event)
+		switch {
+		case e.Type == "content_block_delta" && e.Index == h.index && e.Delta.Type == "input_json_delta":
+			h.input.WriteString(e.Delta.PartialJSON)
+		case e.Type == "content_block_stop" && e.Index == h.index:
+			s.held = nil
+			s.release(h)
+		}
+		return
+	}
+
+	switch {
+	case e.Type == "content_block_start" && e.ContentBlock.Type == "tool_use":
+		s.held = &heldToolBlock{index: e.Index, id: e.ContentBlock.ID, name: e.ContentBlock.Name}
+		s.held.events.Write(event)
+		return
+	case e.Type == "message_delta" && e.Delta.StopReason == "tool_use" && s.blocked > 0 && s.allowed == 0:
+		// Every tool call was blocked, so the turn is over
+		var msg map[string]interface{}
+		if json.Unmarshal(data, &msg) == nil {
+			if delta, ok := msg["delta"].(map[string]interface{}); ok {
+				delta["stop_reason"] = "end_turn"
+				s.writeEvent("message_delta", msg)
+				return
+			}
+		}
+	}
+	s.out.Write(event)
+}
+
+// release rules on a complete tool_use block and writes it, or a text block
+// in its place.
+func (s *toolCallStream) release(h *heldToolBlock) {
+	var input interface{} = map[string]interface{}{}
+	if raw := h.input.String(); raw != "" {
+		if json.Unmarshal([]byte(raw), &input) != nil {
+			input = raw
+		}
+	}
+	v := s.filter.verdict(h.id, h.name, input)
+	if !v.Blocked {
+		s.allowed++
+		s.out.Write(h.events.Bytes())
+		return
+	}
+	s.blocked++
+	s.writeEvent("content_block_start", map[string]interface{}{
+		"type": "content_block_start", "index": h.index,
+		"content_block": map[string]interface{}{"type": "text", "text": ""},
+	})
+	s.writeEvent("content_block_delta", map[string]interface{}{
+		"type": "content_block_delta", "index": h.index,
+		"delta": map[string]interface{}{"type": "text_delta", "text": blockedToolText(h.name, v.Reason)},
+	})
+	s.writeEvent("content_block_stop", map[string]interface{}{"type": "content_block_stop", "index": h.index})
+}
+
+func (s *toolCallStream) writeEvent(name string, data interface{}) {
+	payload, _ := json.Marshal(data)
+	fmt.Fprintf(&s.out, "event: %s\ndata: %s\n\n", name, payload)
+}
+
+// eventData returns the data of an SSE event, or nil if it has none.
+func eventData(event []byte) []byte {
+	var data []byte
+	for _, line := range bytes.Split(event, []byte("\n")) {
+		line = bytes.TrimRight(line, "\r")
+		if rest, ok := bytes.CutPrefix(line, []byte("data:")); ok {
+			if data != nil {
+				data = append(data, '\n')
+			}
+			data = append(data, bytes.TrimPrefix(rest, []byte(" "))...)
+		}
+	}
+	return data
+}
@@ -0,0 +1,129 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// blockBash blocks Bash tool calls and records what it was asked.
+func blockBash(seen *[]ToolCallCheck) *toolCallFilter {
+	return &toolCallFilter{ctx: context.Background(), sessionID: "s1", check: func(_ context.Context, c ToolCallCheck) ToolCallVerdict {
+		*seen = append(*seen, c)
+		if c.Name == "Bash" {
+			return ToolCallVerdict{Blocked: true, Reason: "no shell"}
+		}
+		return ToolCallVerdict{}
+	}}
+}
+
+func TestToolCallFilterBody(t *testing.T) {
+	var seen []ToolCallCheck
+	f := blockBash(&seen)
+	body := `{"content":[{"type":"text","text":"Let me check."},{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"ls"}}],"stop_reason":"tool_use"}`
+
+	var got struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		StopReason string `json:"stop_reason"`
+	}
+	if err := json.Unmarshal(f.filterBody([]byte(body)), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Content) != 2 || got.Content[1].Type != "text" || !strings.Contains(got.Content[1].Text, "no shell") {
+		t.Errorf("content = %+v, want the tool call replaced by text", got.Content)
+	}
+	if got.StopReason != "end_turn" {
+		t.Errorf("stop_reason = %q, want end_turn", got.StopReason)
+	}
+	if len(seen) != 1 || string(seen[0].Input) != `{"command":"ls"}` || seen[0].SessionID != "s1" {
+		t.Errorf("checks = %+v", seen)
+	}
+
+	allowed := `{"content":[{"type":"tool_use","id":"t2","name":"Read","input":{}}],"stop_reason":"tool_use"}`
+	if out := f.filterBody([]byte(allowed)); string(out) != allowed {
+		t.Errorf("allowed body changed: %s", out)
+	}
+}
+
+func TestToolCallFilterStream(t *testing.T) {
+	events := []string{
+		`event: message_start` + "\n" + `data: {"type":"message_start","message":{"id":"m1"}}`,
+		`event: content_block_start` + "\n" + `data: {"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"t1","name":"Read","input":{}}}`,
+		`event: content_block_delta` + "\n" + `data: {"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"file_path\":"}}`,
+		`event: content_block_delta` + "\n" + `data: {"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"\"a.go\"}"}}`,
+		`event: content_block_stop` + "\n" + `data: {"type":"content_block_stop","index":0}`,
+		`event: content_block_start` + "\n" + `data: {"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"t2","name":"Bash","input":{}}}`,
+		`event: ping` + "\n" + `data: {"type":"ping"}`,
+		`event: content_block_delta` + "\n" + `data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"command\":\"rm -rf /\"}"}}`,
+		`event: content_block_stop` + "\n" + `data: {"type":"content_block_stop","index":1}`,
+		`event: message_delta` + "\n" + `data: {"type":"message_delta","delta":{"stop_reason":"tool_use"}}`,
+	}
+	stream := strings.Join(events, "\n\n") + "\n\n"
+
+	var seen []ToolCallCheck
+	out, err := io.ReadAll(blockBash(&seen).stream(strings.NewReader(stream)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(out)
+
+	// The allowed call passes through unchanged
+	if !strings.Contains(got, strings.Join(events[1:5], "\n\n")) {
+		t.Errorf("allowed tool_use block was altered:\n%s", got)
+	}
+	// The blocked call becomes a text block at the same index
+	if strings.Contains(got, `"id":"t2"`) || strings.Contains(got, "rm -rf") {
+		t.Errorf("blocked tool_use block leaked:\n%s", got)
+	}
+	if !strings.Contains(got, `"content_block":{"text":"","type":"text"},"index":1`) || !strings.Contains(got, "no shell") {
+		t.Errorf("missing replacement text block:\n%s", got)
+	}
+	// One call still runs, so the turn still ends for tool use
+	if !strings.Contains(got, `"stop_reason":"tool_use"`) {
+		t.Errorf("stop_reason changed although a tool call was allowed:\n%s", got)
+	}
+	if len(seen) != 2 || string(seen[0].Input) != `{"file_path":"a.go"}` {
+		t.Errorf("checks = %+v", seen)
+	}
+}
+
+func TestToolCallFilterStreamAllBlocked(t *testing.T) {
+	stream := strings.Join([]string{
+		`event: content_block_start` + "\n" + `data: {"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"t1","name":"Bash","input":{}}}`,
+		`event: content_block_stop` + "\n" + `data: {"type":"content_block_stop","index":0}`,
+		`event: message_delta` + "\n" + `data: {"type":"message_delta","delta":{"stop_reason":"tool_use"}}`,
+	}, "\n\n") + "\n\n"
+
+	var seen []ToolCallCheck
+	out, _ := io.ReadAll(blockBash(&seen).stream(strings.NewReader(stream)))
+	if !strings.Contains(string(out), `"stop_reason":"end_turn"`) {
+		t.Errorf("stop_reason not rewritten:\n%s", out)
+	}
+}
+
+func TestNewToolCallFilter(t *testing.T) {
+	ctx := context.Background()
+	resp := &http.Response{StatusCode: http.StatusOK}
+	SetToolCallPolicy(nil)
+	if newToolCallFilter(ctx, resp, "s1", "anthropic") != nil {
+		t.Error("filter without a policy")
+	}
+
+	SetToolCallPolicy(func(context.Context, ToolCallCheck) ToolCallVerdict { return ToolCallVerdict{} })
+	t.Cleanup(func() { SetToolCallPolicy(nil) })
+	if newToolCallFilter(ctx, resp, "s1", "anthropic") == nil {
+		t.Error("no filter for an Anthropic response")
+	}
+	if newToolCallFilter(ctx, resp, "s1", "openai-chat") != nil {
+		t.Error("filter for an OpenAI response")
+	}
+	if newToolCallFilter(ctx, &http.Response{StatusCode: http.StatusBadRequest}, "s1", "anthropic") != nil {
+		t.Error("filter for an error response")
+	}
+}
@@ -0,0 +1,143 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// tracerName identifies the proxy's instrumentation scope.
+const tracerName = "github.com/dopejs/gozen/internal/proxy"
+
+// tracePropagator reads W3C trace context from incoming requests so zen
+// spans join the caller's trace.
+var tracePropagator = propagation.TraceContext{}
+
+var (
+	tracingMu      sync.RWMutex
+	tracer         trace.Tracer = noop.NewTracerProvider().Tracer(tracerName)
+	tracerProvider *sdktrace.TracerProvider
+)
+
+// InitGlobalTracing sets up span export from the tracing config.
+// Tracing is a no-op when it is disabled.
+func InitGlobalTracing(cfg *config.TracingConfig) error {
+	return UpdateGlobalTracingConfig(cfg)
+}
+
+// UpdateGlobalTracingConfig replaces the tracer provider with one built from
+// cfg, flushing and shutting down the previous one.
+func UpdateGlobalTracingConfig(cfg *config.TracingConfig) error {
+	var tp *sdktrace.TracerProvider
+	if cfg != nil && cfg.Enabled {
+		exporter, err := otlptracehttp.New(context.Background(), otlpOptions(cfg)...)
+		if err != nil {
+			return err
+		}
+		tp = sdktrace.NewTracerProvider(
+			sdktrace.WithBatcher(exporter),
+			sdktrace.WithResource(resource.NewSchemaless(
+				attribute.String("service.name", cfg.GetServiceName()),
+			)),
+			sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.GetSampleRatio()))),
+		)
+	}
+
+	tracingMu.Lock()
+	old := tracerProvider
+	tracerProvider = tp
+	if tp != nil {
+		tracer = tp.Tracer(tracerName)
+	} else {
+		tracer = noop.NewTracerProvider().Tracer(tracerName)
+	}
+	tracingMu.Unlock()
+
+	if old != nil {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			old.Shutdown(ctx)
+		}()
+	}
+	return nil
+}
+
+// ShutdownGlobalTracing flushes pending spans and stops export.
+func ShutdownGlobalTracing(ctx context.Context) error {
+	tracingMu.Lock()
+	tp := tracerProvider
+	tracerProvider = nil
+	tracer = noop.NewTracerProvider().Tracer(tracerName)
+	tracingMu.Unlock()
+	if tp == nil {
+		return nil
+	}
+	return tp.Shutdown(ctx)
+}
+
+// otlpOptions converts the tracing config to exporter options. The endpoint
+// may be a bare host:port or a full URL.
+func otlpOptions(cfg *config.TracingConfig) []otlptracehttp.Option {
+	var opts []otlptracehttp.Option
+	endpoint := cfg.GetEndpoint()
+	if strings.Contains(endpoint, "://") {
+		opts = append(opts, otlptracehttp.WithEndpointURL(endpoint))
+	} else {
+		opts = append(opts, otlptracehttp.WithEndpoint(endpoint))
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+	}
+	if len(cfg.Headers) > 0 {
+		opts = append(opts, otlptracehttp.WithHeaders(cfg.Headers))
+	}
+	return opts
+}
+
+// startSpan starts a span with the current tracer.
+func startSpan(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	tracingMu.RLock()
+	t := tracer
+	tracingMu.RUnlock()
+	return t.Start(ctx, name, opts...)
+}
+
+// doUpstream sends a request to a provider inside an upstream span, which
+// ends once the response headers arrive.
+func doUpstream(client *http.Client, req *http.Request, p *Provider, op string) (*http.Response, error) {
+	ctx, span := startSpan(req.Context(), "zen.upstream",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("zen.provider", p.Name),
+			attribute.String("zen.provider.type", p.GetType()),
+			attribute.String("zen.upstream.op", op),
+			attribute.String("http.request.method", req.Method),
+			attribute.String("url.full", req.URL.Redacted()),
+		))
+	defer span.End()
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
+	return resp, nil
+}
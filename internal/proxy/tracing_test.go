@@ -0,0 +1,116 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/dopejs/gozen/internal/config"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans routes spans to an in-memory recorder for the test.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	tracingMu.Lock()
+	old := tracer
+	tracer = tp.Tracer(tracerName)
+	tracingMu.Unlock()
+	t.Cleanup(func() {
+		tracingMu.Lock()
+		tracer = old
+		tracingMu.Unlock()
+	})
+	return rec
+}
+
+func TestProxyTracingSpans(t *testing.T) {
+	rec := recordSpans(t)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer failing.Close()
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"msg_1","content":[],"usage":{"input_tokens":1,"output_tokens":1}}`))
+	}))
+	defer ok.Close()
+	fu, _ := url.Parse(failing.URL)
+	ou, _ := url.Parse(ok.URL)
+
+	srv := NewProxyServer([]*Provider{
+		{Name: "limited", BaseURL: fu, Token: "t", Healthy: true},
+		{Name: "backup", BaseURL: ou, Token: "t", Healthy: true},
+	}, discardLogger(), config.LoadBalanceFailover, nil)
+	srv.Profile = "traced"
+
+	req := httptest.NewRequest("POST", "/v1/messages", strings.NewReader(`{"model":"claude-sonnet-4-5","messages":[]}`))
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != 200 {
+		t.Fatalf("status = %d, body: %s", w.Code, w.Body.String())
+	}
+
+	spans := rec.Ended()
+	byName := map[string][]sdktrace.ReadOnlySpan{}
+	for _, s := range spans {
+		byName[s.Name()] = append(byName[s.Name()], s)
+	}
+	if len(byName["zen.request"]) != 1 || len(byName["zen.routing"]) != 1 || len(byName["zen.upstream"]) != 2 {
+		t.Fatalf("spans = %v", byName)
+	}
+
+	root := byName["zen.request"][0]
+	if got := root.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace ID = %s, want the caller's", got)
+	}
+	if root.SpanKind() != trace.SpanKindServer {
+		t.Errorf("root span kind = %v", root.SpanKind())
+	}
+	for _, s := range spans {
+		if s != root && s.Parent().SpanID() != root.SpanContext().SpanID() {
+			t.Errorf("span %s is not a child of zen.request", s.Name())
+		}
+	}
+
+	statuses := map[string]int64{}
+	for _, s := range byName["zen.upstream"] {
+		var provider string
+		var status int64
+		for _, kv := range s.Attributes() {
+			switch kv.Key {
+			case "zen.provider":
+				provider = kv.Value.AsString()
+			case "http.response.status_code":
+				status = kv.Value.AsInt64()
+			}
+		}
+		statuses[provider] = status
+	}
+	if statuses["limited"] != 429 || statuses["backup"] != 200 {
+		t.Errorf("upstream statuses = %v", statuses)
+	}
+}
+
+func TestTracingConfigDefaults(t *testing.T) {
+	var cfg *config.TracingConfig
+	if cfg.GetEndpoint() != config.DefaultTracingEndpoint || cfg.GetServiceName() != "zen" || cfg.GetSampleRatio() != 1 {
+		t.Error("nil config should use defaults")
+	}
+	ratio := 2.0
+	cfg = &config.TracingConfig{SampleRatio: &ratio}
+	if cfg.GetSampleRatio() != 1 {
+		t.Errorf("sample ratio = %v, want clamped to 1", cfg.GetSampleRatio())
+	}
+	if err := UpdateGlobalTracingConfig(nil); err != nil {
+		t.Errorf("disabling tracing: %v", err)
+	}
+}
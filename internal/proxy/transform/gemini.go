@@ -0,0 +1,683 @@
+package transform
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FormatGemini is the provider format for Google Gemini generateContent API.
+const FormatGemini = "gemini"
+
+// GeminiTransformer handles Google Gemini generateContent API format.
+// Requests are converted to contents/parts; responses are converted back to
+// Anthropic Messages (and further to OpenAI formats when the client needs it).
+type GeminiTransformer struct {
+	// SafetySettings maps a harm category (e.g. HARM_CATEGORY_HARASSMENT)
+	// to a block threshold (e.g. BLOCK_NONE). Empty means provider defaults.
+	SafetySettings map[string]string
+}
+
+func (t *GeminiTransformer) Name() string {
+	return FormatGemini
+}
+
+// GeminiPath returns the generateContent endpoint path for a model.
+// Streaming requests use streamGenerateContent, which must be paired with
+// the alt=sse query parameter (see GeminiQuery).
+func GeminiPath(model string, stream bool) string {
+	model = strings.TrimPrefix(model, "models/")
+	method := "generateContent"
+	if stream {
+		method = "streamGenerateContent"
+	}
+	return "/v1beta/models/" + url.PathEscape(model) + ":" + method
+}
+
+// GeminiQuery returns the query string for a Gemini request, merging alt=sse
+// into the client's raw query when streaming.
+func GeminiQuery(rawQuery string, stream bool) string {
+	if !stream {
+		return rawQuery
+	}
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		values = url.Values{}
+	}
+	values.Set("alt", "sse")
+	return values.Encode()
+}
+
+// TransformRequest transforms an Anthropic or OpenAI request to Gemini format.
+// OpenAI requests are first normalized to Anthropic Messages so there is a
+// single conversion path into contents/parts.
+func (t *GeminiTransformer) TransformRequest(body []byte, clientFormat string) ([]byte, error) {
+	normalized := NormalizeFormat(clientFormat)
+	if normalized == FormatGemini {
+		return body, nil
+	}
+
+	if normalized == "openai" {
+		converted, err := (&AnthropicTransformer{}).TransformRequest(body, clientFormat)
+		if err != nil {
+			return nil, err
+		}
+		body = converted
+	}
+
+	data, err := parseJSON(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request JSON: %w", err)
+	}
+
+	out := map[string]interface{}{}
+
+	// System prompt → systemInstruction
+	if systemText := extractTextFromContent(data["system"]); systemText != "" {
+		out["systemInstruction"] = map[string]interface{}{
+			"parts": []interface{}{map[string]interface{}{"text": systemText}},
+		}
+	}
+
+	// Messages → contents (tool_result needs the function name from the
+	// matching tool_use block, so track ids as we go)
+	toolNames := make(map[string]string)
+	var contents []interface{}
+	if messages, ok := data["messages"].([]interface{}); ok {
+		for _, m := range messages {
+			msg, ok := m.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			role, _ := msg["role"].(string)
+			geminiRole := "user"
+			if role == "assistant" {
+				geminiRole = "model"
+			}
+			parts := geminiParts(msg["content"], toolNames)
+			if len(parts) == 0 {
+				continue
+			}
+			// Gemini rejects consecutive turns with the same role; merge them.
+			if n := len(contents); n > 0 {
+				if prev, ok := contents[n-1].(map[string]interface{}); ok && prev["role"] == geminiRole {
+					prev["parts"] = append(prev["parts"].([]interface{}), parts...)
+					continue
+				}
+			}
+			contents = append(contents, map[string]interface{}{
+				"role":  geminiRole,
+				"parts": parts,
+			})
+		}
+	}
+	if contents == nil {
+		contents = []interface{}{}
+	}
+	out["contents"] = contents
+
+	// Tools → functionDeclarations
+	if tools, ok := data["tools"].([]interface{}); ok && len(tools) > 0 {
+		var decls []interface{}
+		for _, tool := range tools {
+			toolMap, ok := tool.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _ := toolMap["name"].(string)
+			if name == "" {
+				continue
+			}
+			decl := map[string]interface{}{"name": name}
+			if desc, ok := toolMap["description"].(string); ok && desc != "" {
+				decl["description"] = desc
+			}
+			if schema, ok := toolMap["input_schema"]; ok && schema != nil {
+				decl["parameters"] = sanitizeGeminiSchema(schema)
+			}
+			decls = append(decls, decl)
+		}
+		if len(decls) > 0 {
+			out["tools"] = []interface{}{map[string]interface{}{"functionDeclarations": decls}}
+		}
+	}
+
+	// tool_choice → toolConfig
+	if tc, ok := data["tool_choice"].(map[string]interface{}); ok {
+		fcc := map[string]interface{}{}
+		switch tc["type"] {
+		case "auto":
+			fcc["mode"] = "AUTO"
+		case "any":
+			fcc["mode"] = "ANY"
+		case "none":
+			fcc["mode"] = "NONE"
+		case "tool":
+			fcc["mode"] = "ANY"
+			if name, ok := tc["name"].(string); ok && name != "" {
+				fcc["allowedFunctionNames"] = []interface{}{name}
+			}
+		}
+		if len(fcc) > 0 {
+			out["toolConfig"] = map[string]interface{}{"functionCallingConfig": fcc}
+		}
+	}
+
+	// Sampling parameters → generationConfig
+	gen := map[string]interface{}{}
+	if v, ok := data["max_tokens"]; ok {
+		gen["maxOutputTokens"] = v
+	}
+	if v, ok := data["temperature"]; ok {
+		gen["temperature"] = v
+	}
+	if v, ok := data["top_p"]; ok {
+		gen["topP"] = v
+	}
+	if v, ok := data["top_k"]; ok {
+		gen["topK"] = v
+	}
+	if v, ok := data["stop_sequences"]; ok {
+		gen["stopSequences"] = v
+	}
+	if thinking, ok := data["thinking"].(map[string]interface{}); ok && thinking["type"] == "enabled" {
+		if budget, ok := thinking["budget_tokens"]; ok {
+			gen["thinkingConfig"] = map[string]interface{}{"thinkingBudget": budget}
+		}
+	}
+	if len(gen) > 0 {
+		out["generationConfig"] = gen
+	}
+
+	// Safety settings (sorted for deterministic output)
+	if len(t.SafetySettings) > 0 {
+		categories := make([]string, 0, len(t.SafetySettings))
+		for c := range t.SafetySettings {
+			categories = append(categories, c)
+		}
+		sort.Strings(categories)
+		settings := make([]interface{}, 0, len(categories))
+		for _, c := range categories {
+			settings = append(settings, map[string]interface{}{
+				"category":  c,
+				"threshold": t.SafetySettings[c],
+			})
+		}
+		out["safetySettings"] = settings
+	}
+
+	return json.Marshal(out)
+}
+
+// geminiParts converts Anthropic message content to Gemini parts.
+func geminiParts(content interface{}, toolNames map[string]string) []interface{} {
+	if s, ok := content.(string); ok {
+		if s == "" {
+			return nil
+		}
+		return []interface{}{map[string]interface{}{"text": s}}
+	}
+
+	blocks, ok := content.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	var parts []interface{}
+	for _, b := range blocks {
+		block, ok := b.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		switch block["type"] {
+		case "text":
+			if text, ok := block["text"].(string); ok && text != "" {
+				parts = append(parts, map[string]interface{}{"text": text})
+			}
+		case "image":
+			source, _ := block["source"].(map[string]interface{})
+			if source == nil {
+				continue
+			}
+			switch source["type"] {
+			case "base64":
+				parts = append(parts, map[string]interface{}{
+					"inlineData": map[string]interface{}{
+						"mimeType": source["media_type"],
+						"data":     source["data"],
+					},
+				})
+			case "url":
+				parts = append(parts, map[string]interface{}{
+					"fileData": map[string]interface{}{
+						"fileUri": source["url"],
+					},
+				})
+			}
+		case "tool_use":
+			name, _ := block["name"].(string)
+			if id, ok := block["id"].(string); ok {
+				toolNames[id] = name
+			}
+			args := block["input"]
+			if args == nil {
+				args = map[string]interface{}{}
+			}
+			parts = append(parts, map[string]interface{}{
+				"functionCall": map[string]interface{}{
+					"name": name,
+					"args": args,
+				},
+			})
+		case "tool_result":
+			id, _ := block["tool_use_id"].(string)
+			name := toolNames[id]
+			if name == "" {
+				name = id
+			}
+			response := map[string]interface{}{
+				"content": extractTextFromContent(block["content"]),
+			}
+			if isErr, ok := block["is_error"].(bool); ok && isErr {
+				response["error"] = true
+			}
+			parts = append(parts, map[string]interface{}{
+				"functionResponse": map[string]interface{}{
+					"name":     name,
+					"response": response,
+				},
+			})
+		}
+	}
+	return parts
+}
+
+// geminiUnsupportedSchemaKeys lists JSON Schema keywords rejected by Gemini's
+// OpenAPI-subset schema validator.
+var geminiUnsupportedSchemaKeys = map[string]bool{
+	"$schema":              true,
+	"$id":                  true,
+	"$ref":                 true,
+	"$defs":                true,
+	"definitions":          true,
+	"additionalProperties": true,
+	"default":              true,
+	"examples":             true,
+	"const":                true,
+}
+
+// sanitizeGeminiSchema recursively removes unsupported keywords from a tool schema.
+func sanitizeGeminiSchema(schema interface{}) interface{} {
+	switch v := schema.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			if geminiUnsupportedSchemaKeys[k] {
+				continue
+			}
+			if k == "properties" {
+				if props, ok := val.(map[string]interface{}); ok {
+					cleaned := make(map[string]interface{}, len(props))
+					for name, p := range props {
+						cleaned[name] = sanitizeGeminiSchema(p)
+					}
+					out[k] = cleaned
+					continue
+				}
+			}
+			out[k] = sanitizeGeminiSchema(val)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = sanitizeGeminiSchema(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// TransformResponse transforms a Gemini response to the client's format.
+func (t *GeminiTransformer) TransformResponse(body []byte, clientFormat string) ([]byte, error) {
+	normalized := NormalizeFormat(clientFormat)
+	if normalized == FormatGemini {
+		return body, nil
+	}
+
+	data, err := parseJSON(body)
+	if err != nil {
+		return body, nil
+	}
+
+	anthropicBody, err := toJSON(geminiToAnthropicMessage(data))
+	if err != nil {
+		return nil, err
+	}
+	if normalized == "openai" {
+		return (&AnthropicTransformer{}).TransformResponse(anthropicBody, clientFormat)
+	}
+	return anthropicBody, nil
+}
+
+// geminiToAnthropicMessage converts a Gemini GenerateContentResponse to an
+// Anthropic Messages response object.
+func geminiToAnthropicMessage(data map[string]interface{}) map[string]interface{} {
+	id, _ := data["responseId"].(string)
+	if id == "" {
+		id = fmt.Sprintf("msg_gemini_%d", time.Now().UnixNano())
+	}
+	msg := map[string]interface{}{
+		"id":            id,
+		"type":          "message",
+		"role":          "assistant",
+		"model":         data["modelVersion"],
+		"stop_sequence": nil,
+	}
+
+	var content []interface{}
+	stopReason := "end_turn"
+	hasToolUse := false
+
+	if candidates, ok := data["candidates"].([]interface{}); ok && len(candidates) > 0 {
+		if cand, ok := candidates[0].(map[string]interface{}); ok {
+			if c, ok := cand["content"].(map[string]interface{}); ok {
+				if parts, ok := c["parts"].([]interface{}); ok {
+					for i, p := range parts {
+						part, ok := p.(map[string]interface{})
+						if !ok {
+							continue
+						}
+						if thought, _ := part["thought"].(bool); thought {
+							continue
+						}
+						if text, ok := part["text"].(string); ok && text != "" {
+							content = append(content, map[string]interface{}{
+								"type": "text",
+								"text": text,
+							})
+						}
+						if fc, ok := part["functionCall"].(map[string]interface{}); ok {
+							hasToolUse = true
+							content = append(content, map[string]interface{}{
+								"type":  "tool_use",
+								"id":    geminiToolCallID(fc, i),
+								"name":  fc["name"],
+								"input": geminiArgs(fc["args"]),
+							})
+						}
+					}
+				}
+			}
+			if fr, ok := cand["finishReason"].(string); ok {
+				stopReason = mapGeminiFinishReason(fr)
+			}
+		}
+	} else if feedback, ok := data["promptFeedback"].(map[string]interface{}); ok && feedback["blockReason"] != nil {
+		stopReason = "refusal"
+	}
+
+	if hasToolUse && stopReason == "end_turn" {
+		stopReason = "tool_use"
+	}
+	if content == nil {
+		content = []interface{}{}
+	}
+	msg["content"] = content
+	msg["stop_reason"] = stopReason
+
+	inputTokens, outputTokens := geminiUsage(data)
+	msg["usage"] = map[string]interface{}{
+		"input_tokens":  inputTokens,
+		"output_tokens": outputTokens,
+	}
+	return msg
+}
+
+// geminiUsage returns prompt and output token counts from usageMetadata.
+// Thinking tokens are billed as output, so they are included.
+func geminiUsage(data map[string]interface{}) (int, int) {
+	usage, ok := data["usageMetadata"].(map[string]interface{})
+	if !ok {
+		return 0, 0
+	}
+	prompt, _ := usage["promptTokenCount"].(float64)
+	candidates, _ := usage["candidatesTokenCount"].(float64)
+	thoughts, _ := usage["thoughtsTokenCount"].(float64)
+	return int(prompt), int(candidates + thoughts)
+}
+
+// geminiToolCallID returns the function call id, synthesizing one when the
+// API does not provide it (older Gemini models).
+func geminiToolCallID(fc map[string]interface{}, index int) string {
+	if id, ok := fc["id"].(string); ok && id != "" {
+		return id
+	}
+	name, _ := fc["name"].(string)
+	return fmt.Sprintf("toolu_%s_%d", name, index)
+}
+
+func geminiArgs(args interface{}) interface{} {
+	if args == nil {
+		return map[string]interface{}{}
+	}
+	return args
+}
+
+// mapGeminiFinishReason maps Gemini finishReason to Anthropic stop_reason.
+func mapGeminiFinishReason(reason string) string {
+	switch reason {
+	case "MAX_TOKENS":
+		return "max_tokens"
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII", "IMAGE_SAFETY":
+		return "refusal"
+	default:
+		return "end_turn"
+	}
+}
+
+// transformGeminiToAnthropic converts a Gemini streamGenerateContent SSE
+// stream (alt=sse) to Anthropic Messages SSE events.
+func (st *StreamTransformer) transformGeminiToAnthropic(r io.Reader, w io.Writer) {
+	scanner := bufio.NewScanner(r)
+	buf := make([]byte, 64*1024)
+	scanner.Buffer(buf, 1024*1024)
+
+	var messageStarted bool
+	var textOpen bool
+	var hasToolUse bool
+	var inputTokens, outputTokens int
+	stopReason := ""
+	nextIndex := 0
+	toolCount := 0
+
+	closeText := func() {
+		if textOpen {
+			fmt.Fprint(w, formatSSEEvent("content_block_stop", map[string]interface{}{
+				"type":  "content_block_stop",
+				"index": nextIndex - 1,
+			}))
+			textOpen = false
+		}
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var chunk map[string]interface{}
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &chunk); err != nil {
+			continue
+		}
+
+		// usageMetadata is cumulative in every chunk; keep the latest values.
+		if _, ok := chunk["usageMetadata"]; ok {
+			inputTokens, outputTokens = geminiUsage(chunk)
+		}
+
+		if !messageStarted {
+			if id, ok := chunk["responseId"].(string); ok && id != "" {
+				st.MessageID = id
+			}
+			if st.MessageID == "" {
+				st.MessageID = fmt.Sprintf("msg_gemini_%d", time.Now().UnixNano())
+			}
+			if model, ok := chunk["modelVersion"].(string); ok {
+				st.Model = model
+			}
+			fmt.Fprint(w, formatSSEEvent("message_start", map[string]interface{}{
+				"type": "message_start",
+				"message": map[string]interface{}{
+					"id":            st.MessageID,
+					"type":          "message",
+					"role":          "assistant",
+					"content":       []interface{}{},
+					"model":         st.Model,
+					"stop_reason":   nil,
+					"stop_sequence": nil,
+					"usage": map[string]interface{}{
+						"input_tokens":  inputTokens,
+						"output_tokens": 0,
+					},
+				},
+			}))
+			messageStarted = true
+		}
+
+		candidates, ok := chunk["candidates"].([]interface{})
+		if !ok || len(candidates) == 0 {
+			if feedback, ok := chunk["promptFeedback"].(map[string]interface{}); ok && feedback["blockReason"] != nil {
+				stopReason = "refusal"
+			}
+			continue
+		}
+		cand, ok := candidates[0].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if c, ok := cand["content"].(map[string]interface{}); ok {
+			parts, _ := c["parts"].([]interface{})
+			for _, p := range parts {
+				part, ok := p.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if thought, _ := part["thought"].(bool); thought {
+					continue
+				}
+				if text, ok := part["text"].(string); ok && text != "" {
+					if !textOpen {
+						fmt.Fprint(w, formatSSEEvent("content_block_start", map[string]interface{}{
+							"type":  "content_block_start",
+							"index": nextIndex,
+							"content_block": map[string]interface{}{
+								"type": "text",
+								"text": "",
+							},
+						}))
+						nextIndex++
+						textOpen = true
+					}
+					fmt.Fprint(w, formatSSEEvent("content_block_delta", map[string]interface{}{
+						"type":  "content_block_delta",
+						"index": nextIndex - 1,
+						"delta": map[string]interface{}{
+							"type": "text_delta",
+							"text": text,
+						},
+					}))
+				}
+				if fc, ok := part["functionCall"].(map[string]interface{}); ok {
+					// Gemini delivers function calls whole, so each one is a
+					// complete start/delta/stop sequence.
+					closeText()
+					hasToolUse = true
+					index := nextIndex
+					nextIndex++
+					fmt.Fprint(w, formatSSEEvent("content_block_start", map[string]interface{}{
+						"type":  "content_block_start",
+						"index": index,
+						"content_block": map[string]interface{}{
+							"type":  "tool_use",
+							"id":    geminiToolCallID(fc, toolCount),
+							"name":  fc["name"],
+							"input": map[string]interface{}{},
+						},
+					}))
+					toolCount++
+					args, _ := json.Marshal(geminiArgs(fc["args"]))
+					fmt.Fprint(w, formatSSEEvent("content_block_delta", map[string]interface{}{
+						"type":  "content_block_delta",
+						"index": index,
+						"delta": map[string]interface{}{
+							"type":         "input_json_delta",
+							"partial_json": string(args),
+						},
+					}))
+					fmt.Fprint(w, formatSSEEvent("content_block_stop", map[string]interface{}{
+						"type":  "content_block_stop",
+						"index": index,
+					}))
+				}
+			}
+		}
+
+		if fr, ok := cand["finishReason"].(string); ok && fr != "" {
+			stopReason = mapGeminiFinishReason(fr)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		st.writeStreamError(w, err)
+		return
+	}
+	if !messageStarted {
+		return
+	}
+
+	closeText()
+	if stopReason == "" || (hasToolUse && stopReason == "end_turn") {
+		if hasToolUse {
+			stopReason = "tool_use"
+		} else {
+			stopReason = "end_turn"
+		}
+	}
+	fmt.Fprint(w, formatSSEEvent("message_delta", map[string]interface{}{
+		"type": "message_delta",
+		"delta": map[string]interface{}{
+			"stop_reason":   stopReason,
+			"stop_sequence": nil,
+		},
+		"usage": map[string]interface{}{
+			"input_tokens":  inputTokens,
+			"output_tokens": outputTokens,
+		},
+	}))
+	fmt.Fprint(w, formatSSEEvent("message_stop", map[string]interface{}{
+		"type": "message_stop",
+	}))
+}
+
+// transformGeminiToOpenAI converts a Gemini SSE stream to an OpenAI format by
+// chaining the Gemini→Anthropic and Anthropic→OpenAI stream transformers.
+func (st *StreamTransformer) transformGeminiToOpenAI(r io.Reader, w io.Writer) {
+	pr, pw := io.Pipe()
+	go func() {
+		defer pw.Close()
+		st.transformGeminiToAnthropic(r, pw)
+	}()
+	next := &StreamTransformer{
+		ClientFormat:   st.ClientFormat,
+		ProviderFormat: "anthropic",
+	}
+	io.Copy(w, next.TransformSSEStream(pr))
+}
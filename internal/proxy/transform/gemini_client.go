@@ -0,0 +1,566 @@
+package transform
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Gemini API methods a client such as Gemini CLI calls on a model.
+const (
+	GeminiMethodGenerate       = "generateContent"
+	GeminiMethodStreamGenerate = "streamGenerateContent"
+	GeminiMethodCountTokens    = "countTokens"
+)
+
+// defaultGeminiClientMaxTokens is the max_tokens used when a Gemini client
+// leaves maxOutputTokens unset, which Anthropic Messages requires.
+const defaultGeminiClientMaxTokens = 8192
+
+// ParseGeminiClientPath splits a Gemini API path such as
+// /v1beta/models/gemini-2.5-pro:streamGenerateContent into the model and
+// the method. ok is false for any other path.
+func ParseGeminiClientPath(path string) (model, method string, ok bool) {
+	rest := ""
+	for _, prefix := range []string{"/v1beta/models/", "/v1/models/", "/v1alpha/models/"} {
+		if strings.HasPrefix(path, prefix) {
+			rest = strings.TrimPrefix(path, prefix)
+			break
+		}
+	}
+	i := strings.LastIndex(rest, ":")
+	if i <= 0 || i == len(rest)-1 {
+		return "", "", false
+	}
+	return rest[:i], rest[i+1:], true
+}
+
+// GeminiRequestToAnthropic converts a Gemini generateContent request from a
+// client to an Anthropic Messages request, so it can be routed to any
+// provider. The model comes from the request path.
+func GeminiRequestToAnthropic(body []byte, model string, stream bool) ([]byte, error) {
+	data, err := parseJSON(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request JSON: %w", err)
+	}
+	// countTokens may wrap a full generateContent request
+	if inner, ok := data["generateContentRequest"].(map[string]interface{}); ok {
+		data = inner
+	}
+
+	out := map[string]interface{}{
+		"model":  model,
+		"stream": stream,
+	}
+
+	// systemInstruction → system
+	if si, ok := data["systemInstruction"].(map[string]interface{}); ok {
+		if text := geminiPartsText(si["parts"]); text != "" {
+			out["system"] = text
+		}
+	}
+
+	// contents → messages. Gemini function calls may lack ids, so pair each
+	// functionResponse with the oldest unanswered call of the same name.
+	pending := map[string][]string{}
+	toolCount := 0
+	var messages []interface{}
+	if contents, ok := data["contents"].([]interface{}); ok {
+		for _, c := range contents {
+			content, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			role := "user"
+			if content["role"] == "model" {
+				role = "assistant"
+			}
+			blocks := anthropicBlocksFromGemini(content["parts"], pending, &toolCount)
+			if len(blocks) == 0 {
+				continue
+			}
+			// Anthropic expects alternating roles; merge consecutive turns.
+			if n := len(messages); n > 0 {
+				if prev := messages[n-1].(map[string]interface{}); prev["role"] == role {
+					prev["content"] = append(prev["content"].([]interface{}), blocks...)
+					continue
+				}
+			}
+			messages = append(messages, map[string]interface{}{
+				"role":    role,
+				"content": blocks,
+			})
+		}
+	}
+	if messages == nil {
+		messages = []interface{}{}
+	}
+	out["messages"] = messages
+
+	// functionDeclarations → tools
+	var tools []interface{}
+	if list, ok := data["tools"].([]interface{}); ok {
+		for _, t := range list {
+			tool, ok := t.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			decls, _ := tool["functionDeclarations"].([]interface{})
+			for _, d := range decls {
+				decl, ok := d.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				name, _ := decl["name"].(string)
+				if name == "" {
+					continue
+				}
+				schema := decl["parametersJsonSchema"]
+				if schema == nil {
+					schema = lowerSchemaTypes(decl["parameters"])
+				}
+				if schema == nil {
+					schema = map[string]interface{}{"type": "object"}
+				}
+				anthropicTool := map[string]interface{}{
+					"name":         name,
+					"input_schema": schema,
+				}
+				if desc, ok := decl["description"].(string); ok && desc != "" {
+					anthropicTool["description"] = desc
+				}
+				tools = append(tools, anthropicTool)
+			}
+		}
+	}
+	if len(tools) > 0 {
+		out["tools"] = tools
+	}
+
+	// toolConfig → tool_choice
+	if tc, ok := data["toolConfig"].(map[string]interface{}); ok && len(tools) > 0 {
+		fcc, _ := tc["functionCallingConfig"].(map[string]interface{})
+		switch fcc["mode"] {
+		case "AUTO":
+			out["tool_choice"] = map[string]interface{}{"type": "auto"}
+		case "NONE":
+			out["tool_choice"] = map[string]interface{}{"type": "none"}
+		case "ANY":
+			allowed, _ := fcc["allowedFunctionNames"].([]interface{})
+			if len(allowed) == 1 {
+				out["tool_choice"] = map[string]interface{}{"type": "tool", "name": allowed[0]}
+			} else {
+				out["tool_choice"] = map[string]interface{}{"type": "any"}
+			}
+		}
+	}
+
+	// generationConfig → sampling parameters
+	maxTokens := defaultGeminiClientMaxTokens
+	if gen, ok := data["generationConfig"].(map[string]interface{}); ok {
+		if v, ok := gen["maxOutputTokens"].(float64); ok && v > 0 {
+			maxTokens = int(v)
+		}
+		if v, ok := gen["temperature"]; ok {
+			out["temperature"] = v
+		}
+		if v, ok := gen["topP"]; ok {
+			out["top_p"] = v
+		}
+		if v, ok := gen["topK"]; ok {
+			out["top_k"] = v
+		}
+		if v, ok := gen["stopSequences"]; ok {
+			out["stop_sequences"] = v
+		}
+		// Anthropic's minimum thinking budget is 1024; smaller or dynamic
+		// (-1) budgets leave thinking off.
+		if tc, ok := gen["thinkingConfig"].(map[string]interface{}); ok {
+			if budget, ok := tc["thinkingBudget"].(float64); ok && budget >= 1024 {
+				out["thinking"] = map[string]interface{}{
+					"type":          "enabled",
+					"budget_tokens": int(budget),
+				}
+				if maxTokens <= int(budget) {
+					maxTokens = int(budget) + defaultGeminiClientMaxTokens
+				}
+			}
+		}
+	}
+	out["max_tokens"] = maxTokens
+
+	return json.Marshal(out)
+}
+
+// anthropicBlocksFromGemini converts Gemini parts to Anthropic content
+// blocks. Tool results go first, as Anthropic requires them to lead a turn.
+func anthropicBlocksFromGemini(rawParts interface{}, pending map[string][]string, toolCount *int) []interface{} {
+	parts, _ := rawParts.([]interface{})
+	var results, blocks []interface{}
+	for _, p := range parts {
+		part, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if thought, _ := part["thought"].(bool); thought {
+			continue
+		}
+		switch {
+		case part["text"] != nil:
+			if text, ok := part["text"].(string); ok && text != "" {
+				blocks = append(blocks, map[string]interface{}{"type": "text", "text": text})
+			}
+		case part["inlineData"] != nil:
+			inline, _ := part["inlineData"].(map[string]interface{})
+			mimeType, _ := inline["mimeType"].(string)
+			blockType := "image"
+			if mimeType == "application/pdf" {
+				blockType = "document"
+			}
+			blocks = append(blocks, map[string]interface{}{
+				"type": blockType,
+				"source": map[string]interface{}{
+					"type":       "base64",
+					"media_type": mimeType,
+					"data":       inline["data"],
+				},
+			})
+		case part["fileData"] != nil:
+			file, _ := part["fileData"].(map[string]interface{})
+			blocks = append(blocks, map[string]interface{}{
+				"type": "image",
+				"source": map[string]interface{}{
+					"type": "url",
+					"url":  file["fileUri"],
+				},
+			})
+		case part["functionCall"] != nil:
+			fc, _ := part["functionCall"].(map[string]interface{})
+			name, _ := fc["name"].(string)
+			id := geminiToolCallID(fc, *toolCount)
+			*toolCount++
+			pending[name] = append(pending[name], id)
+			blocks = append(blocks, map[string]interface{}{
+				"type":  "tool_use",
+				"id":    id,
+				"name":  name,
+				"input": geminiArgs(fc["args"]),
+			})
+		case part["functionResponse"] != nil:
+			fr, _ := part["functionResponse"].(map[string]interface{})
+			name, _ := fr["name"].(string)
+			id, _ := fr["id"].(string)
+			if queue := pending[name]; len(queue) > 0 {
+				if id == "" {
+					id = queue[0]
+				}
+				pending[name] = removeID(queue, id)
+			}
+			if id == "" {
+				id = fmt.Sprintf("toolu_%s_%d", name, *toolCount)
+			}
+			result := map[string]interface{}{
+				"type":        "tool_result",
+				"tool_use_id": id,
+				"content":     functionResponseText(fr["response"]),
+			}
+			if resp, ok := fr["response"].(map[string]interface{}); ok && resp["error"] != nil {
+				result["is_error"] = true
+			}
+			results = append(results, result)
+		}
+	}
+	return append(results, blocks...)
+}
+
+func removeID(ids []string, id string) []string {
+	for i, v := range ids {
+		if v == id {
+			return append(ids[:i:i], ids[i+1:]...)
+		}
+	}
+	return ids
+}
+
+// functionResponseText flattens a functionResponse payload to tool result
+// text. Gemini CLI puts tool output under "output" (or "error").
+func functionResponseText(response interface{}) string {
+	resp, ok := response.(map[string]interface{})
+	if !ok {
+		if response == nil {
+			return ""
+		}
+		b, _ := json.Marshal(response)
+		return string(b)
+	}
+	for _, key := range []string{"output", "content", "error"} {
+		if s, ok := resp[key].(string); ok {
+			return s
+		}
+	}
+	b, _ := json.Marshal(resp)
+	return string(b)
+}
+
+// geminiPartsText joins the text of Gemini parts.
+func geminiPartsText(rawParts interface{}) string {
+	parts, _ := rawParts.([]interface{})
+	var texts []string
+	for _, p := range parts {
+		if part, ok := p.(map[string]interface{}); ok {
+			if text, ok := part["text"].(string); ok && text != "" {
+				texts = append(texts, text)
+			}
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// lowerSchemaTypes converts a Gemini OpenAPI schema (type: "OBJECT") to
+// JSON Schema (type: "object").
+func lowerSchemaTypes(schema interface{}) interface{} {
+	switch s := schema.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(s))
+		for k, v := range s {
+			if t, ok := v.(string); ok && k == "type" {
+				out[k] = strings.ToLower(t)
+				continue
+			}
+			out[k] = lowerSchemaTypes(v)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(s))
+		for i, v := range s {
+			out[i] = lowerSchemaTypes(v)
+		}
+		return out
+	default:
+		return schema
+	}
+}
+
+// AnthropicResponseToGemini converts an Anthropic Messages response to a
+// Gemini generateContent response for a Gemini client.
+func AnthropicResponseToGemini(body []byte) ([]byte, error) {
+	data, err := parseJSON(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid response JSON: %w", err)
+	}
+
+	parts := []interface{}{}
+	blocks, _ := data["content"].([]interface{})
+	for _, b := range blocks {
+		block, ok := b.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		switch block["type"] {
+		case "text":
+			if text, ok := block["text"].(string); ok && text != "" {
+				parts = append(parts, map[string]interface{}{"text": text})
+			}
+		case "thinking":
+			if text, ok := block["thinking"].(string); ok && text != "" {
+				parts = append(parts, map[string]interface{}{"text": text, "thought": true})
+			}
+		case "tool_use":
+			parts = append(parts, map[string]interface{}{
+				"functionCall": map[string]interface{}{
+					"id":   block["id"],
+					"name": block["name"],
+					"args": geminiArgs(block["input"]),
+				},
+			})
+		}
+	}
+
+	stopReason, _ := data["stop_reason"].(string)
+	out := map[string]interface{}{
+		"candidates": []interface{}{map[string]interface{}{
+			"content":      map[string]interface{}{"role": "model", "parts": parts},
+			"finishReason": geminiFinishReason(stopReason),
+			"index":        0,
+		}},
+		"usageMetadata": geminiClientUsage(data["usage"], 0),
+	}
+	if model, ok := data["model"].(string); ok {
+		out["modelVersion"] = model
+	}
+	if id, ok := data["id"].(string); ok {
+		out["responseId"] = id
+	}
+	return json.Marshal(out)
+}
+
+// geminiFinishReason maps an Anthropic stop_reason to a Gemini finishReason.
+func geminiFinishReason(stopReason string) string {
+	switch stopReason {
+	case "max_tokens":
+		return "MAX_TOKENS"
+	case "refusal":
+		return "SAFETY"
+	default:
+		return "STOP"
+	}
+}
+
+// geminiClientUsage converts Anthropic usage to usageMetadata. Cached input
+// counts toward promptTokenCount, as it does on Gemini. outputTokens, if
+// set, overrides usage's output_tokens.
+func geminiClientUsage(rawUsage interface{}, outputTokens int) map[string]interface{} {
+	usage, _ := rawUsage.(map[string]interface{})
+	input, _ := usage["input_tokens"].(float64)
+	cacheRead, _ := usage["cache_read_input_tokens"].(float64)
+	cacheWrite, _ := usage["cache_creation_input_tokens"].(float64)
+	output, _ := usage["output_tokens"].(float64)
+	if outputTokens > 0 {
+		output = float64(outputTokens)
+	}
+	prompt := int(input + cacheRead + cacheWrite)
+	meta := map[string]interface{}{
+		"promptTokenCount":     prompt,
+		"candidatesTokenCount": int(output),
+		"totalTokenCount":      prompt + int(output),
+	}
+	if cacheRead > 0 {
+		meta["cachedContentTokenCount"] = int(cacheRead)
+	}
+	return meta
+}
+
+// transformAnthropicToGemini converts Anthropic Messages SSE events to a
+// Gemini streamGenerateContent (alt=sse) stream for a Gemini client.
+func (st *StreamTransformer) transformAnthropicToGemini(r io.Reader, w io.Writer) {
+	scanner := bufio.NewScanner(r)
+	buf := make([]byte, 64*1024)
+	scanner.Buffer(buf, 1024*1024)
+
+	var usage map[string]interface{}
+	type toolCall struct {
+		id, name string
+		input    strings.Builder
+	}
+	tools := map[int]*toolCall{}
+
+	writeChunk := func(parts []interface{}, finishReason string, withUsage bool) {
+		candidate := map[string]interface{}{
+			"content": map[string]interface{}{"role": "model", "parts": parts},
+			"index":   0,
+		}
+		if finishReason != "" {
+			candidate["finishReason"] = finishReason
+		}
+		chunk := map[string]interface{}{
+			"candidates": []interface{}{candidate},
+		}
+		if withUsage {
+			chunk["usageMetadata"] = geminiClientUsage(usage, 0)
+		}
+		if st.Model != "" {
+			chunk["modelVersion"] = st.Model
+		}
+		if st.MessageID != "" {
+			chunk["responseId"] = st.MessageID
+		}
+		b, _ := json.Marshal(chunk)
+		fmt.Fprintf(w, "data: %s\n\n", b)
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var event map[string]interface{}
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event); err != nil {
+			continue
+		}
+		index := 0
+		if v, ok := event["index"].(float64); ok {
+			index = int(v)
+		}
+
+		switch event["type"] {
+		case "message_start":
+			msg, _ := event["message"].(map[string]interface{})
+			if id, ok := msg["id"].(string); ok {
+				st.MessageID = id
+			}
+			if model, ok := msg["model"].(string); ok {
+				st.Model = model
+			}
+			usage, _ = msg["usage"].(map[string]interface{})
+
+		case "content_block_start":
+			block, _ := event["content_block"].(map[string]interface{})
+			if block["type"] == "tool_use" {
+				id, _ := block["id"].(string)
+				name, _ := block["name"].(string)
+				tools[index] = &toolCall{id: id, name: name}
+			}
+
+		case "content_block_delta":
+			delta, _ := event["delta"].(map[string]interface{})
+			switch delta["type"] {
+			case "text_delta":
+				if text, _ := delta["text"].(string); text != "" {
+					writeChunk([]interface{}{map[string]interface{}{"text": text}}, "", false)
+				}
+			case "thinking_delta":
+				if text, _ := delta["thinking"].(string); text != "" {
+					writeChunk([]interface{}{map[string]interface{}{"text": text, "thought": true}}, "", false)
+				}
+			case "input_json_delta":
+				if tc := tools[index]; tc != nil {
+					partial, _ := delta["partial_json"].(string)
+					tc.input.WriteString(partial)
+				}
+			}
+
+		case "content_block_stop":
+			// Gemini sends each function call whole, so emit it once its
+			// arguments are complete.
+			tc := tools[index]
+			if tc == nil {
+				continue
+			}
+			delete(tools, index)
+			var args interface{}
+			if tc.input.Len() > 0 {
+				json.Unmarshal([]byte(tc.input.String()), &args)
+			}
+			writeChunk([]interface{}{map[string]interface{}{
+				"functionCall": map[string]interface{}{
+					"id":   tc.id,
+					"name": tc.name,
+					"args": geminiArgs(args),
+				},
+			}}, "", false)
+
+		case "message_delta":
+			delta, _ := event["delta"].(map[string]interface{})
+			stopReason, _ := delta["stop_reason"].(string)
+			if u, ok := event["usage"].(map[string]interface{}); ok {
+				if usage == nil {
+					usage = map[string]interface{}{}
+				}
+				for k, v := range u {
+					usage[k] = v
+				}
+			}
+			writeChunk([]interface{}{}, geminiFinishReason(stopReason), true)
+
+		case "error":
+			errObj, _ := event["error"].(map[string]interface{})
+			message, _ := errObj["message"].(string)
+			b, _ := json.Marshal(map[string]interface{}{
+				"error": map[string]interface{}{"code": 500, "message": message, "status": "INTERNAL"},
+			})
+			fmt.Fprintf(w, "data: %s\n\n", b)
+		}
+	}
+}
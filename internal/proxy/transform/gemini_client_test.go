@@ -0,0 +1,152 @@
+package transform
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestParseGeminiClientPath(t *testing.T) {
+	tests := []struct {
+		path, model, method string
+		ok                  bool
+	}{
+		{"/v1beta/models/gemini-2.5-pro:generateContent", "gemini-2.5-pro", "generateContent", true},
+		{"/v1/models/gemini-2.5-flash:streamGenerateContent", "gemini-2.5-flash", "streamGenerateContent", true},
+		{"/v1beta/models/gemini-2.5-pro", "", "", false},
+		{"/v1/messages", "", "", false},
+	}
+	for _, tt := range tests {
+		model, method, ok := ParseGeminiClientPath(tt.path)
+		if model != tt.model || method != tt.method || ok != tt.ok {
+			t.Errorf("ParseGeminiClientPath(%q) = %q, %q, %v", tt.path, model, method, ok)
+		}
+	}
+}
+
+func TestGeminiRequestToAnthropic(t *testing.T) {
+	body := `{
+		"contents": [
+			{"role": "user", "parts": [{"text": "list files"}]},
+			{"role": "model", "parts": [{"text": "ok", "thought": true}, {"functionCall": {"name": "ls", "args": {"path": "."}}}]},
+			{"role": "user", "parts": [{"functionResponse": {"name": "ls", "response": {"output": "a.go"}}}]},
+			{"role": "user", "parts": [{"text": "thanks"}]}
+		],
+		"tools": [{"functionDeclarations": [{"name": "ls", "description": "List", "parameters": {"type": "OBJECT", "properties": {"path": {"type": "STRING"}}}}]}],
+		"toolConfig": {"functionCallingConfig": {"mode": "ANY", "allowedFunctionNames": ["ls"]}},
+		"generationConfig": {"maxOutputTokens": 2048, "temperature": 0.2, "thinkingConfig": {"thinkingBudget": 4096}}
+	}`
+	out, err := GeminiRequestToAnthropic([]byte(body), "gemini-2.5-pro", true)
+	if err != nil {
+		t.Fatalf("GeminiRequestToAnthropic() error: %v", err)
+	}
+	var req struct {
+		Model     string  `json:"model"`
+		Stream    bool    `json:"stream"`
+		MaxTokens int     `json:"max_tokens"`
+		Temp      float64 `json:"temperature"`
+		Messages  []struct {
+			Role    string                   `json:"role"`
+			Content []map[string]interface{} `json:"content"`
+		} `json:"messages"`
+		Tools []struct {
+			Name        string                 `json:"name"`
+			InputSchema map[string]interface{} `json:"input_schema"`
+		} `json:"tools"`
+		ToolChoice map[string]interface{} `json:"tool_choice"`
+		Thinking   map[string]interface{} `json:"thinking"`
+	}
+	if err := json.Unmarshal(out, &req); err != nil {
+		t.Fatalf("invalid output JSON: %v", err)
+	}
+
+	if req.Model != "gemini-2.5-pro" || !req.Stream || req.Temp != 0.2 {
+		t.Errorf("model/stream/temperature not carried over: %s", out)
+	}
+	// max_tokens must exceed the thinking budget
+	if req.MaxTokens <= 4096 || req.Thinking["budget_tokens"] != float64(4096) {
+		t.Errorf("max_tokens = %d, thinking = %v", req.MaxTokens, req.Thinking)
+	}
+	if len(req.Messages) != 3 {
+		t.Fatalf("expected 3 messages (consecutive user turns merged), got %d: %s", len(req.Messages), out)
+	}
+	call := req.Messages[1].Content
+	if req.Messages[1].Role != "assistant" || len(call) != 1 || call[0]["type"] != "tool_use" {
+		t.Fatalf("expected a lone tool_use (thought skipped), got %v", call)
+	}
+	result := req.Messages[2].Content
+	if result[0]["type"] != "tool_result" || result[0]["tool_use_id"] != call[0]["id"] || result[0]["content"] != "a.go" {
+		t.Errorf("tool_result not paired with its call: %v", result)
+	}
+	if result[1]["text"] != "thanks" {
+		t.Errorf("expected text after the tool result, got %v", result[1])
+	}
+	if len(req.Tools) != 1 || req.Tools[0].InputSchema["type"] != "object" {
+		t.Errorf("tools not converted: %+v", req.Tools)
+	}
+	if req.ToolChoice["type"] != "tool" || req.ToolChoice["name"] != "ls" {
+		t.Errorf("tool_choice = %v", req.ToolChoice)
+	}
+}
+
+func TestAnthropicResponseToGemini(t *testing.T) {
+	body := `{"id":"msg_1","model":"claude-sonnet-4-5","content":[{"type":"text","text":"Running"},{"type":"tool_use","id":"toolu_1","name":"ls","input":{"path":"."}}],"stop_reason":"tool_use","usage":{"input_tokens":10,"cache_read_input_tokens":90,"output_tokens":5}}`
+	out, err := AnthropicResponseToGemini([]byte(body))
+	if err != nil {
+		t.Fatalf("AnthropicResponseToGemini() error: %v", err)
+	}
+	s := string(out)
+	for _, want := range []string{
+		`"text":"Running"`,
+		`"functionCall":{"args":{"path":"."},"id":"toolu_1","name":"ls"}`,
+		`"finishReason":"STOP"`,
+		`"promptTokenCount":100`,
+		`"cachedContentTokenCount":90`,
+		`"totalTokenCount":105`,
+		`"responseId":"msg_1"`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("response missing %s: %s", want, s)
+		}
+	}
+}
+
+func TestStreamAnthropicToGemini(t *testing.T) {
+	input := "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\",\"model\":\"claude-sonnet-4-5\",\"usage\":{\"input_tokens\":10}}}\n\n" +
+		"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}\n\n" +
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Hi\"}}\n\n" +
+		"event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":0}\n\n" +
+		"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":1,\"content_block\":{\"type\":\"tool_use\",\"id\":\"toolu_1\",\"name\":\"ls\"}}\n\n" +
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":1,\"delta\":{\"type\":\"input_json_delta\",\"partial_json\":\"{\\\"path\\\":\"}}\n\n" +
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":1,\"delta\":{\"type\":\"input_json_delta\",\"partial_json\":\"\\\".\\\"}\"}}\n\n" +
+		"event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":1}\n\n" +
+		"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"tool_use\"},\"usage\":{\"output_tokens\":7}}\n\n" +
+		"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"
+
+	st := &StreamTransformer{ClientFormat: FormatGemini, ProviderFormat: FormatAnthropicMessages}
+	out, _ := io.ReadAll(st.TransformSSEStream(strings.NewReader(input)))
+
+	var chunks []map[string]interface{}
+	for _, line := range strings.Split(string(out), "\n") {
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var chunk map[string]interface{}
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &chunk); err != nil {
+			t.Fatalf("invalid chunk %q: %v", line, err)
+		}
+		chunks = append(chunks, chunk)
+	}
+	if len(chunks) != 3 {
+		t.Fatalf("expected text, function call and final chunks, got %d:\n%s", len(chunks), out)
+	}
+	s := string(out)
+	if !strings.Contains(s, `"text":"Hi"`) || !strings.Contains(s, `"args":{"path":"."}`) {
+		t.Errorf("missing text or complete function call:\n%s", s)
+	}
+	final, _ := json.Marshal(chunks[2])
+	if !strings.Contains(string(final), `"finishReason":"STOP"`) || !strings.Contains(string(final), `"totalTokenCount":17`) {
+		t.Errorf("unexpected final chunk: %s", final)
+	}
+}
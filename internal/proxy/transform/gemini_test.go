@@ -0,0 +1,298 @@
+package transform
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestGeminiTransformer_Name(t *testing.T) {
+	tr := &GeminiTransformer{}
+	if tr.Name() != "gemini" {
+		t.Errorf("Name() = %q, want %q", tr.Name(), "gemini")
+	}
+}
+
+func TestGeminiTransformer_TransformRequest_AnthropicToGemini(t *testing.T) {
+	tr := &GeminiTransformer{SafetySettings: map[string]string{
+		"HARM_CATEGORY_HARASSMENT":  "BLOCK_NONE",
+		"HARM_CATEGORY_HATE_SPEECH": "BLOCK_ONLY_HIGH",
+	}}
+
+	input := `{
+		"model": "gemini-2.0-flash",
+		"max_tokens": 1024,
+		"temperature": 0.5,
+		"stop_sequences": ["END"],
+		"system": "You are helpful.",
+		"stream": true,
+		"tools": [{"name": "read_file", "description": "Read a file", "input_schema": {"$schema": "x", "type": "object", "additionalProperties": false, "properties": {"path": {"type": "string", "default": "."}}}}],
+		"messages": [
+			{"role": "user", "content": "Read main.go"},
+			{"role": "assistant", "content": [
+				{"type": "text", "text": "Reading."},
+				{"type": "tool_use", "id": "toolu_1", "name": "read_file", "input": {"path": "main.go"}}
+			]},
+			{"role": "user", "content": [
+				{"type": "tool_result", "tool_use_id": "toolu_1", "content": [{"type": "text", "text": "package main"}]}
+			]}
+		]
+	}`
+
+	result, err := tr.TransformRequest([]byte(input), "anthropic")
+	if err != nil {
+		t.Fatalf("TransformRequest() error = %v", err)
+	}
+
+	var out map[string]interface{}
+	if err := json.Unmarshal(result, &out); err != nil {
+		t.Fatalf("failed to parse result: %v", err)
+	}
+
+	for _, key := range []string{"model", "stream", "messages", "max_tokens", "system"} {
+		if _, ok := out[key]; ok {
+			t.Errorf("unexpected Anthropic field %q in Gemini request", key)
+		}
+	}
+
+	sys := out["systemInstruction"].(map[string]interface{})["parts"].([]interface{})[0].(map[string]interface{})
+	if sys["text"] != "You are helpful." {
+		t.Errorf("systemInstruction text = %v", sys["text"])
+	}
+
+	contents := out["contents"].([]interface{})
+	if len(contents) != 3 {
+		t.Fatalf("contents len = %d, want 3", len(contents))
+	}
+	model := contents[1].(map[string]interface{})
+	if model["role"] != "model" {
+		t.Errorf("assistant role = %v, want model", model["role"])
+	}
+	fc := model["parts"].([]interface{})[1].(map[string]interface{})["functionCall"].(map[string]interface{})
+	if fc["name"] != "read_file" {
+		t.Errorf("functionCall name = %v", fc["name"])
+	}
+	fr := contents[2].(map[string]interface{})["parts"].([]interface{})[0].(map[string]interface{})["functionResponse"].(map[string]interface{})
+	if fr["name"] != "read_file" {
+		t.Errorf("functionResponse name = %v, want read_file (resolved from tool_use_id)", fr["name"])
+	}
+	if fr["response"].(map[string]interface{})["content"] != "package main" {
+		t.Errorf("functionResponse content = %v", fr["response"])
+	}
+
+	gen := out["generationConfig"].(map[string]interface{})
+	if gen["maxOutputTokens"] != float64(1024) || gen["temperature"] != 0.5 {
+		t.Errorf("generationConfig = %v", gen)
+	}
+	if stops := gen["stopSequences"].([]interface{}); len(stops) != 1 || stops[0] != "END" {
+		t.Errorf("stopSequences = %v", gen["stopSequences"])
+	}
+
+	decl := out["tools"].([]interface{})[0].(map[string]interface{})["functionDeclarations"].([]interface{})[0].(map[string]interface{})
+	params := decl["parameters"].(map[string]interface{})
+	if _, ok := params["$schema"]; ok {
+		t.Error("$schema should be stripped from parameters")
+	}
+	if _, ok := params["additionalProperties"]; ok {
+		t.Error("additionalProperties should be stripped from parameters")
+	}
+	if _, ok := params["properties"].(map[string]interface{})["path"].(map[string]interface{})["default"]; ok {
+		t.Error("default should be stripped from nested properties")
+	}
+
+	safety := out["safetySettings"].([]interface{})
+	if len(safety) != 2 {
+		t.Fatalf("safetySettings len = %d, want 2", len(safety))
+	}
+	first := safety[0].(map[string]interface{})
+	if first["category"] != "HARM_CATEGORY_HARASSMENT" || first["threshold"] != "BLOCK_NONE" {
+		t.Errorf("safetySettings[0] = %v", first)
+	}
+}
+
+func TestGeminiTransformer_TransformRequest_MergesConsecutiveRoles(t *testing.T) {
+	tr := &GeminiTransformer{}
+	input := `{"messages": [{"role": "user", "content": "a"}, {"role": "user", "content": "b"}]}`
+
+	result, err := tr.TransformRequest([]byte(input), "anthropic")
+	if err != nil {
+		t.Fatalf("TransformRequest() error = %v", err)
+	}
+	var out map[string]interface{}
+	json.Unmarshal(result, &out)
+	contents := out["contents"].([]interface{})
+	if len(contents) != 1 {
+		t.Fatalf("contents len = %d, want 1 (merged)", len(contents))
+	}
+	if parts := contents[0].(map[string]interface{})["parts"].([]interface{}); len(parts) != 2 {
+		t.Errorf("parts len = %d, want 2", len(parts))
+	}
+}
+
+func TestGeminiTransformer_TransformRequest_OpenAIChat(t *testing.T) {
+	tr := &GeminiTransformer{}
+	input := `{"model": "gpt-4o", "messages": [{"role": "system", "content": "Be brief."}, {"role": "user", "content": "Hi"}]}`
+
+	result, err := tr.TransformRequest([]byte(input), FormatOpenAIChat)
+	if err != nil {
+		t.Fatalf("TransformRequest() error = %v", err)
+	}
+	var out map[string]interface{}
+	json.Unmarshal(result, &out)
+	if _, ok := out["systemInstruction"]; !ok {
+		t.Error("system message should become systemInstruction")
+	}
+	contents := out["contents"].([]interface{})
+	if len(contents) != 1 || contents[0].(map[string]interface{})["role"] != "user" {
+		t.Errorf("contents = %v", contents)
+	}
+}
+
+func TestGeminiTransformer_TransformResponse(t *testing.T) {
+	body := `{
+		"candidates": [{
+			"content": {"role": "model", "parts": [
+				{"text": "thinking...", "thought": true},
+				{"text": "Let me check."},
+				{"functionCall": {"name": "read_file", "args": {"path": "a.go"}}}
+			]},
+			"finishReason": "STOP"
+		}],
+		"usageMetadata": {"promptTokenCount": 12, "candidatesTokenCount": 7, "thoughtsTokenCount": 3},
+		"modelVersion": "gemini-2.0-flash",
+		"responseId": "resp-1"
+	}`
+
+	tests := []struct {
+		name         string
+		clientFormat string
+		check        func(t *testing.T, out map[string]interface{})
+	}{
+		{"anthropic", "anthropic", func(t *testing.T, out map[string]interface{}) {
+			if out["id"] != "resp-1" || out["model"] != "gemini-2.0-flash" {
+				t.Errorf("id/model = %v/%v", out["id"], out["model"])
+			}
+			if out["stop_reason"] != "tool_use" {
+				t.Errorf("stop_reason = %v, want tool_use", out["stop_reason"])
+			}
+			content := out["content"].([]interface{})
+			if len(content) != 2 {
+				t.Fatalf("content len = %d, want 2 (thought part dropped)", len(content))
+			}
+			tool := content[1].(map[string]interface{})
+			if tool["type"] != "tool_use" || tool["name"] != "read_file" || tool["id"] == "" {
+				t.Errorf("tool_use block = %v", tool)
+			}
+			usage := out["usage"].(map[string]interface{})
+			if usage["input_tokens"] != float64(12) || usage["output_tokens"] != float64(10) {
+				t.Errorf("usage = %v", usage)
+			}
+		}},
+		{"openai chat", FormatOpenAIChat, func(t *testing.T, out map[string]interface{}) {
+			choice := out["choices"].([]interface{})[0].(map[string]interface{})
+			if choice["finish_reason"] != "tool_calls" {
+				t.Errorf("finish_reason = %v, want tool_calls", choice["finish_reason"])
+			}
+			msg := choice["message"].(map[string]interface{})
+			if msg["content"] != "Let me check." {
+				t.Errorf("content = %v", msg["content"])
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := (&GeminiTransformer{}).TransformResponse([]byte(body), tt.clientFormat)
+			if err != nil {
+				t.Fatalf("TransformResponse() error = %v", err)
+			}
+			var out map[string]interface{}
+			if err := json.Unmarshal(result, &out); err != nil {
+				t.Fatalf("failed to parse result: %v", err)
+			}
+			tt.check(t, out)
+		})
+	}
+}
+
+func TestGeminiTransformer_FinishReasonMapping(t *testing.T) {
+	tests := map[string]string{
+		"STOP":       "end_turn",
+		"MAX_TOKENS": "max_tokens",
+		"SAFETY":     "refusal",
+		"RECITATION": "refusal",
+		"OTHER":      "end_turn",
+	}
+	for in, want := range tests {
+		if got := mapGeminiFinishReason(in); got != want {
+			t.Errorf("mapGeminiFinishReason(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestGeminiPathAndQuery(t *testing.T) {
+	if got := GeminiPath("models/gemini-2.0-flash", false); got != "/v1beta/models/gemini-2.0-flash:generateContent" {
+		t.Errorf("GeminiPath() = %q", got)
+	}
+	if got := GeminiPath("gemini-2.0-flash", true); got != "/v1beta/models/gemini-2.0-flash:streamGenerateContent" {
+		t.Errorf("GeminiPath(stream) = %q", got)
+	}
+	if got := GeminiQuery("", true); got != "alt=sse" {
+		t.Errorf("GeminiQuery(stream) = %q", got)
+	}
+	if got := GeminiQuery("beta=true", false); got != "beta=true" {
+		t.Errorf("GeminiQuery() = %q", got)
+	}
+}
+
+func TestStreamTransformer_GeminiToAnthropic(t *testing.T) {
+	input := strings.Join([]string{
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"Hel"}]}}],"usageMetadata":{"promptTokenCount":5},"modelVersion":"gemini-2.0-flash","responseId":"r1"}`,
+		``,
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"lo"}]}}]}`,
+		``,
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"name":"ls","args":{"dir":"."}}}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":5,"candidatesTokenCount":4}}`,
+		``,
+	}, "\n")
+
+	st := &StreamTransformer{ClientFormat: "anthropic", ProviderFormat: FormatGemini}
+	out, _ := io.ReadAll(st.TransformSSEStream(strings.NewReader(input)))
+	events := parseSSEEvents(string(out))
+
+	var types []string
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+	want := []string{
+		"message_start",
+		"content_block_start", "content_block_delta", "content_block_delta", "content_block_stop",
+		"content_block_start", "content_block_delta", "content_block_stop",
+		"message_delta", "message_stop",
+	}
+	if strings.Join(types, ",") != strings.Join(want, ",") {
+		t.Fatalf("events = %v, want %v", types, want)
+	}
+
+	delta := events[8].Data
+	if delta["delta"].(map[string]interface{})["stop_reason"] != "tool_use" {
+		t.Errorf("stop_reason = %v, want tool_use", delta["delta"])
+	}
+	if delta["usage"].(map[string]interface{})["output_tokens"] != float64(4) {
+		t.Errorf("output_tokens = %v, want 4", delta["usage"])
+	}
+}
+
+func TestStreamTransformer_GeminiToOpenAIChat(t *testing.T) {
+	input := `data: {"candidates":[{"content":{"role":"model","parts":[{"text":"Hi"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":2,"candidatesTokenCount":1}}` + "\n\n"
+
+	st := &StreamTransformer{ClientFormat: FormatOpenAIChat, ProviderFormat: FormatGemini}
+	out, _ := io.ReadAll(st.TransformSSEStream(strings.NewReader(input)))
+	s := string(out)
+	if !strings.Contains(s, `"content":"Hi"`) {
+		t.Errorf("expected chat completion delta with text, got:\n%s", s)
+	}
+	if !strings.Contains(s, "data: [DONE]") {
+		t.Errorf("expected [DONE] terminator, got:\n%s", s)
+	}
+}
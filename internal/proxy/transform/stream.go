@@ -56,8 +56,19 @@ func (st *StreamTransformer) TransformSSEStream(r io.Reader) io.Reader {
 
 	go func() {
 		defer pw.Close()
+		// Anthropic → Gemini API client (requests from Gemini clients are
+		// converted to Anthropic Messages at the proxy's edge)
+		if normalizedClient == FormatGemini {
+			st.transformAnthropicToGemini(r, pw)
+		// Gemini → Anthropic, or Gemini → Anthropic → OpenAI
+		} else if normalizedProvider == FormatGemini {
+			if normalizedClient == "anthropic" {
+				st.transformGeminiToAnthropic(r, pw)
+			} else {
+				st.transformGeminiToOpenAI(r, pw)
+			}
 		// Responses API → OpenAI Chat Completions SSE
-		if st.ProviderFormat == FormatOpenAIResponses && st.ClientFormat == FormatOpenAIChat {
+		} else if st.ProviderFormat == FormatOpenAIResponses && st.ClientFormat == FormatOpenAIChat {
 			st.transformResponsesAPIToOpenAIChat(r, pw)
 		// Check specific format first before normalized comparison
 		} else if st.ProviderFormat == FormatOpenAIResponses && normalizedClient == "anthropic" {
@@ -34,6 +34,8 @@ func GetTransformer(providerType string) Transformer {
 	switch providerType {
 	case "openai":
 		return &OpenAITransformer{}
+	case FormatGemini:
+		return &GeminiTransformer{}
 	default:
 		return &AnthropicTransformer{}
 	}
@@ -12,6 +12,7 @@ func TestGetTransformer(t *testing.T) {
 	}{
 		{"anthropic", "anthropic"},
 		{"openai", "openai"},
+		{"gemini", "gemini"},
 		{"", "anthropic"}, // default
 		{"unknown", "anthropic"}, // fallback to default
 	}
@@ -2,6 +2,7 @@ package proxy
 
 import (
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/dopejs/gozen/internal/config"
@@ -9,78 +10,254 @@ import (
 
 // UsageEntry represents a single API usage record.
 type UsageEntry struct {
-	Timestamp    time.Time
-	SessionID    string
-	Provider     string
-	Model        string
-	InputTokens  int
-	OutputTokens int
-	CostUSD      float64
-	LatencyMs    int
-	ProjectPath  string
-	ClientType   string
+	ID                  int64 // row ID, set on entries read back
+	Timestamp           time.Time
+	SessionID           string
+	Provider            string
+	Model               string
+	InputTokens         int
+	OutputTokens        int
+	CacheCreationTokens int
+	CacheReadTokens     int
+	CostUSD             float64
+	LatencyMs           int
+	ProjectPath         string
+	ClientType          string
+	User                string   // label of the ingress key the request was sent with
+	Tags                []string // tags sent with the request in X-Zen-Tags
+	Batch               bool     // served by a batch API
+	Breakdown           CostBreakdown
+}
+
+// CostBreakdown splits a token-priced request's cost by what it was billed
+// for. BatchDiscount is the amount taken off for batch requests, so the
+// request cost is the sum of the other fields minus the discount. Requests
+// billed by a provider cost model have no breakdown.
+type CostBreakdown struct {
+	InputCost      float64 `json:"input_cost_usd"`
+	OutputCost     float64 `json:"output_cost_usd"`
+	CacheWriteCost float64 `json:"cache_write_cost_usd"`
+	CacheReadCost  float64 `json:"cache_read_cost_usd"`
+	BatchDiscount  float64 `json:"batch_discount_usd"`
+}
+
+// Total returns the cost after the batch discount.
+func (b CostBreakdown) Total() float64 {
+	return b.InputCost + b.OutputCost + b.CacheWriteCost + b.CacheReadCost - b.BatchDiscount
 }
 
 // UsageSummary provides aggregated usage statistics.
 type UsageSummary struct {
-	TotalInputTokens  int     `json:"total_input_tokens"`
-	TotalOutputTokens int     `json:"total_output_tokens"`
-	TotalCost         float64 `json:"total_cost"`
-	RequestCount      int     `json:"request_count"`
-	ByProvider        map[string]*UsageStats `json:"by_provider,omitempty"`
-	ByModel           map[string]*UsageStats `json:"by_model,omitempty"`
-	ByProject         map[string]*UsageStats `json:"by_project,omitempty"`
+	TotalInputTokens         int                    `json:"total_input_tokens"`
+	TotalOutputTokens        int                    `json:"total_output_tokens"`
+	TotalCacheCreationTokens int                    `json:"total_cache_creation_tokens"`
+	TotalCacheReadTokens     int                    `json:"total_cache_read_tokens"`
+	CacheHitRate             float64                `json:"cache_hit_rate"`
+	TotalCost                float64                `json:"total_cost"`
+	RequestCount             int                    `json:"request_count"`
+	ByProvider               map[string]*UsageStats `json:"by_provider,omitempty"`
+	ByModel                  map[string]*UsageStats `json:"by_model,omitempty"`
+	ByProject                map[string]*UsageStats `json:"by_project,omitempty"`
+	ByUser                   map[string]*UsageStats `json:"by_user,omitempty"`
+	ByTag                    map[string]*UsageStats `json:"by_tag,omitempty"`
+	ResponseCache            *ResponseCacheStats    `json:"response_cache,omitempty"`
+	Currency                 string                 `json:"currency,omitempty"` // set by ConvertCurrency; costs are USD without it
+}
+
+// ConvertCurrency converts the summary's costs from USD to the display
+// currency.
+func (s *UsageSummary) ConvertCurrency(c *config.CurrencyConfig) {
+	rate := c.GetRate()
+	s.Currency = c.GetCode()
+	s.TotalCost *= rate
+	for _, group := range []map[string]*UsageStats{s.ByProvider, s.ByModel, s.ByProject, s.ByUser, s.ByTag} {
+		for _, stats := range group {
+			stats.Cost *= rate
+		}
+	}
 }
 
 // UsageStats holds usage statistics for a single dimension.
 type UsageStats struct {
-	InputTokens  int     `json:"input_tokens"`
-	OutputTokens int     `json:"output_tokens"`
-	Cost         float64 `json:"cost"`
-	RequestCount int     `json:"request_count"`
+	InputTokens         int     `json:"input_tokens"`
+	OutputTokens        int     `json:"output_tokens"`
+	CacheCreationTokens int     `json:"cache_creation_tokens"`
+	CacheReadTokens     int     `json:"cache_read_tokens"`
+	CacheHitRate        float64 `json:"cache_hit_rate"`
+	Cost                float64 `json:"cost"`
+	RequestCount        int     `json:"request_count"`
+}
+
+// Anthropic bills prompt cache writes and reads relative to the base input
+// rate. These apply to models whose pricing sets no cache rates.
+const (
+	cacheWriteMultiplier = 1.25
+	cacheReadMultiplier  = 0.1
+)
+
+// cacheHitRate returns the fraction of prompt tokens served from cache.
+// Prompt tokens are uncached input plus cache writes plus cache reads.
+func cacheHitRate(input, creation, read int) float64 {
+	total := input + creation + read
+	if total == 0 {
+		return 0
+	}
+	return float64(read) / float64(total)
 }
 
 // UsageTracker tracks API usage and calculates costs.
 type UsageTracker struct {
-	db      *LogDB
-	pricing map[string]*config.ModelPricing
+	db            *LogDB
+	mu            sync.RWMutex // guards pricing and providerCosts, which reloads replace
+	pricing       map[string]*config.ModelPricing
+	providerCosts map[string]CostFormula // provider name -> non-token cost formula
 }
 
 // NewUsageTracker creates a new usage tracker.
 func NewUsageTracker(db *LogDB) *UsageTracker {
 	return &UsageTracker{
-		db:      db,
-		pricing: config.GetPricing(),
+		db:            db,
+		pricing:       config.GetPricing(),
+		providerCosts: loadProviderCostFormulas(),
 	}
 }
 
 // ReloadPricing refreshes the pricing data from config.
 func (t *UsageTracker) ReloadPricing() {
-	t.pricing = config.GetPricing()
+	pricing := config.GetPricing()
+	providerCosts := loadProviderCostFormulas()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pricing = pricing
+	t.providerCosts = providerCosts
 }
 
-// CalculateCost calculates the cost for a given model and token counts.
-func (t *UsageTracker) CalculateCost(model string, inputTokens, outputTokens int) float64 {
-	pricing := t.findPricing(model)
+// rates returns the pricing and provider cost formulas in effect, so a
+// calculation prices everything from the same reload.
+func (t *UsageTracker) rates() (map[string]*config.ModelPricing, map[string]CostFormula) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.pricing, t.providerCosts
+}
+
+// loadProviderCostFormulas builds cost formulas for providers that configure
+// a non-token cost model. Invalid models are skipped; config validation
+// reports them.
+func loadProviderCostFormulas() map[string]CostFormula {
+	formulas := make(map[string]CostFormula)
+	for _, name := range config.ProviderNames() {
+		pc := config.GetProvider(name)
+		if pc == nil || pc.CostModel == nil {
+			continue
+		}
+		if formula, err := NewCostFormula(pc.CostModel); err == nil && formula != nil {
+			formulas[name] = formula
+		}
+	}
+	return formulas
+}
+
+// CalculateRequestCost calculates the cost of a request served by a provider.
+// Providers with a configured cost model are billed by their formula;
+// everything else falls back to per-model token pricing, with prompt cache
+// writes and reads billed at their discounted rates.
+func (t *UsageTracker) CalculateRequestCost(provider string, in CostInput) float64 {
+	cost, _, _ := t.RequestCost(provider, in)
+	return cost
+}
+
+// RequestCostBreakdown returns the cost breakdown of a request served by a
+// provider. ok is false for providers billed by a cost model formula.
+func (t *UsageTracker) RequestCostBreakdown(provider string, in CostInput) (b CostBreakdown, ok bool) {
+	_, b, ok = t.RequestCost(provider, in)
+	return b, ok
+}
+
+// RequestCost returns the cost of a request served by a provider together
+// with its breakdown. ok is false for providers billed by a cost model
+// formula, which have no breakdown.
+func (t *UsageTracker) RequestCost(provider string, in CostInput) (cost float64, b CostBreakdown, ok bool) {
+	pricing, providerCosts := t.rates()
+	if formula, found := providerCosts[provider]; found {
+		return formula.Cost(in), CostBreakdown{}, false
+	}
+	b = costBreakdown(pricing, in)
+	return b.Total(), b, true
+}
+
+// CalculateCostBreakdown prices a request's tokens with the model's pricing.
+// Batch requests get the model's batch discount off the whole cost.
+func (t *UsageTracker) CalculateCostBreakdown(in CostInput) CostBreakdown {
+	pricing, _ := t.rates()
+	return costBreakdown(pricing, in)
+}
+
+// costBreakdown prices a request's tokens with a pricing table.
+func costBreakdown(table map[string]*config.ModelPricing, in CostInput) CostBreakdown {
+	pricing := lookupPricing(table, in.Model)
 	if pricing == nil {
+		return CostBreakdown{}
+	}
+	b := CostBreakdown{
+		InputCost:      float64(in.InputTokens) / 1_000_000 * pricing.InputPerMillion,
+		OutputCost:     float64(in.OutputTokens) / 1_000_000 * pricing.OutputPerMillion,
+		CacheWriteCost: float64(in.CacheCreationTokens) / 1_000_000 * cacheWriteRate(pricing),
+		CacheReadCost:  float64(in.CacheReadTokens) / 1_000_000 * cacheReadRate(pricing),
+	}
+	if in.Batch && pricing.BatchDiscount > 0 {
+		b.BatchDiscount = (b.InputCost + b.OutputCost + b.CacheWriteCost + b.CacheReadCost) * pricing.BatchDiscount
+	}
+	return b
+}
+
+// cacheWriteRate returns the per-million rate for prompt cache writes.
+func cacheWriteRate(p *config.ModelPricing) float64 {
+	if p.CacheWritePerMillion > 0 {
+		return p.CacheWritePerMillion
+	}
+	return p.InputPerMillion * cacheWriteMultiplier
+}
+
+// cacheReadRate returns the per-million rate for prompt cache reads.
+func cacheReadRate(p *config.ModelPricing) float64 {
+	if p.CacheReadPerMillion > 0 {
+		return p.CacheReadPerMillion
+	}
+	return p.InputPerMillion * cacheReadMultiplier
+}
+
+// CalculateCacheCost calculates the cost of prompt cache writes and reads.
+func (t *UsageTracker) CalculateCacheCost(model string, creationTokens, readTokens int) float64 {
+	if creationTokens == 0 && readTokens == 0 {
 		return 0
 	}
+	b := t.CalculateCostBreakdown(CostInput{Model: model, CacheCreationTokens: creationTokens, CacheReadTokens: readTokens})
+	return b.CacheWriteCost + b.CacheReadCost
+}
 
-	inputCost := float64(inputTokens) / 1_000_000 * pricing.InputPerMillion
-	outputCost := float64(outputTokens) / 1_000_000 * pricing.OutputPerMillion
-	return inputCost + outputCost
+// CalculateCost calculates the cost for a given model and token counts.
+func (t *UsageTracker) CalculateCost(model string, inputTokens, outputTokens int) float64 {
+	b := t.CalculateCostBreakdown(CostInput{Model: model, InputTokens: inputTokens, OutputTokens: outputTokens})
+	return b.InputCost + b.OutputCost
 }
 
 // findPricing finds the pricing for a model, supporting partial matches.
 func (t *UsageTracker) findPricing(model string) *config.ModelPricing {
+	pricing, _ := t.rates()
+	return lookupPricing(pricing, model)
+}
+
+// lookupPricing finds a model's entry in a pricing table, supporting partial
+// matches.
+func lookupPricing(pricing map[string]*config.ModelPricing, model string) *config.ModelPricing {
 	// Exact match first
-	if p, ok := t.pricing[model]; ok {
+	if p, ok := pricing[model]; ok {
 		return p
 	}
 
 	// Try partial match (model name contains key)
 	modelLower := strings.ToLower(model)
-	for key, p := range t.pricing {
+	for key, p := range pricing {
 		if strings.Contains(modelLower, strings.ToLower(key)) {
 			return p
 		}
@@ -88,17 +265,17 @@ func (t *UsageTracker) findPricing(model string) *config.ModelPricing {
 
 	// Try matching by model family
 	if strings.Contains(modelLower, "opus") {
-		if p, ok := t.pricing["claude-3-opus-20240229"]; ok {
+		if p, ok := pricing["claude-3-opus-20240229"]; ok {
 			return p
 		}
 	}
 	if strings.Contains(modelLower, "sonnet") {
-		if p, ok := t.pricing["claude-3-5-sonnet-20241022"]; ok {
+		if p, ok := pricing["claude-3-5-sonnet-20241022"]; ok {
 			return p
 		}
 	}
 	if strings.Contains(modelLower, "haiku") {
-		if p, ok := t.pricing["claude-3-5-haiku-20241022"]; ok {
+		if p, ok := pricing["claude-3-5-haiku-20241022"]; ok {
 			return p
 		}
 	}
@@ -112,9 +289,16 @@ func (t *UsageTracker) Record(entry UsageEntry) error {
 		return nil
 	}
 
-	_, err := t.db.db.Exec(`
-		INSERT INTO usage (timestamp, session_id, provider, model, input_tokens, output_tokens, cost_usd, latency_ms, project_path, client_type)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	tx, err := t.db.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`
+		INSERT INTO usage (timestamp, session_id, provider, model, input_tokens, output_tokens, cache_creation_tokens, cache_read_tokens, cost_usd, latency_ms, project_path, client_type, user_label,
+			batch, input_cost_usd, output_cost_usd, cache_write_cost_usd, cache_read_cost_usd, batch_discount_usd)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`,
 		entry.Timestamp.UTC().Format(time.RFC3339Nano),
 		entry.SessionID,
@@ -122,18 +306,50 @@ func (t *UsageTracker) Record(entry UsageEntry) error {
 		entry.Model,
 		entry.InputTokens,
 		entry.OutputTokens,
+		entry.CacheCreationTokens,
+		entry.CacheReadTokens,
 		entry.CostUSD,
 		entry.LatencyMs,
 		entry.ProjectPath,
 		entry.ClientType,
+		entry.User,
+		entry.Batch,
+		entry.Breakdown.InputCost,
+		entry.Breakdown.OutputCost,
+		entry.Breakdown.CacheWriteCost,
+		entry.Breakdown.CacheReadCost,
+		entry.Breakdown.BatchDiscount,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	if len(entry.Tags) > 0 {
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		for _, tag := range entry.Tags {
+			if _, err := tx.Exec(`INSERT OR IGNORE INTO usage_tags (usage_id, tag) VALUES (?, ?)`, id, tag); err != nil {
+				return err
+			}
+		}
+	}
+	if err := addToRollups(tx, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // GetSummary returns usage summary for a time period.
 // period can be "day", "week", "month", or "all".
 // projectPath filters by project (empty string for all projects).
 func (t *UsageTracker) GetSummary(period string, projectPath string) (*UsageSummary, error) {
+	return t.GetTaggedSummary(period, projectPath, "")
+}
+
+// GetTaggedSummary is GetSummary limited to requests sent with tag (empty
+// string for all requests).
+func (t *UsageTracker) GetTaggedSummary(period, projectPath, tag string) (*UsageSummary, error) {
 	if t.db == nil || t.db.db == nil {
 		return &UsageSummary{
 			ByProvider: make(map[string]*UsageStats),
@@ -142,57 +358,85 @@ func (t *UsageTracker) GetSummary(period string, projectPath string) (*UsageSumm
 		}, nil
 	}
 
-	var since time.Time
-	now := time.Now().UTC()
+	return t.querySummary(PeriodStart(period), projectPath, tag)
+}
 
+// PeriodStart returns the start of a trailing period: "day", "week" or
+// "month". Any other period means all time and returns the zero time.
+func PeriodStart(period string) time.Time {
+	now := time.Now().UTC()
 	switch period {
 	case "day":
-		since = now.AddDate(0, 0, -1)
+		return now.AddDate(0, 0, -1)
 	case "week":
-		since = now.AddDate(0, 0, -7)
+		return now.AddDate(0, 0, -7)
 	case "month":
-		since = now.AddDate(0, -1, 0)
+		return now.AddDate(0, -1, 0)
 	default:
-		since = time.Time{} // all time
+		return time.Time{} // all time
 	}
-
-	return t.querySummary(since, projectPath)
 }
 
 // GetDailyCost returns the total cost for today.
 func (t *UsageTracker) GetDailyCost(projectPath string) (float64, error) {
-	return t.getCostSince(time.Now().UTC().Truncate(24*time.Hour), projectPath)
+	return t.getCostSince(startOfDay(), costFilter{project: projectPath})
 }
 
 // GetWeeklyCost returns the total cost for the current week.
 func (t *UsageTracker) GetWeeklyCost(projectPath string) (float64, error) {
-	now := time.Now().UTC()
-	weekStart := now.AddDate(0, 0, -int(now.Weekday()))
-	weekStart = weekStart.Truncate(24 * time.Hour)
-	return t.getCostSince(weekStart, projectPath)
+	return t.getCostSince(startOfWeek(), costFilter{project: projectPath})
 }
 
 // GetMonthlyCost returns the total cost for the current month.
 func (t *UsageTracker) GetMonthlyCost(projectPath string) (float64, error) {
+	return t.getCostSince(startOfMonth(), costFilter{project: projectPath})
+}
+
+func startOfDay() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour)
+}
+
+func startOfWeek() time.Time {
+	now := time.Now().UTC()
+	return now.AddDate(0, 0, -int(now.Weekday())).Truncate(24 * time.Hour)
+}
+
+func startOfMonth() time.Time {
 	now := time.Now().UTC()
-	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
-	return t.getCostSince(monthStart, projectPath)
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
 }
 
-func (t *UsageTracker) getCostSince(since time.Time, projectPath string) (float64, error) {
+// costFilter narrows a cost query. Empty fields match everything; model
+// matches any model whose name contains it, case-insensitively.
+type costFilter struct {
+	project  string
+	provider string
+	model    string
+	user     string
+}
+
+func (t *UsageTracker) getCostSince(since time.Time, f costFilter) (float64, error) {
 	if t.db == nil || t.db.db == nil {
 		return 0, nil
 	}
 
-	var query string
-	var args []interface{}
-
-	if projectPath != "" {
-		query = `SELECT COALESCE(SUM(cost_usd), 0) FROM usage WHERE timestamp >= ? AND project_path = ?`
-		args = []interface{}{since.Format(time.RFC3339Nano), projectPath}
-	} else {
-		query = `SELECT COALESCE(SUM(cost_usd), 0) FROM usage WHERE timestamp >= ?`
-		args = []interface{}{since.Format(time.RFC3339Nano)}
+	query := `SELECT COALESCE(SUM(cost_usd), 0) FROM usage WHERE timestamp >= ?`
+	args := []interface{}{since.Format(time.RFC3339Nano)}
+	if f.project != "" {
+		query += ` AND project_path = ?`
+		args = append(args, f.project)
+	}
+	if f.provider != "" {
+		query += ` AND provider = ?`
+		args = append(args, f.provider)
+	}
+	if f.model != "" {
+		query += ` AND instr(lower(model), ?) > 0`
+		args = append(args, strings.ToLower(f.model))
+	}
+	if f.user != "" {
+		query += ` AND user_label = ?`
+		args = append(args, f.user)
 	}
 
 	var cost float64
@@ -200,7 +444,11 @@ func (t *UsageTracker) getCostSince(since time.Time, projectPath string) (float6
 	return cost, err
 }
 
-func (t *UsageTracker) querySummary(since time.Time, projectPath string) (*UsageSummary, error) {
+func (t *UsageTracker) querySummary(since time.Time, projectPath, tag string) (*UsageSummary, error) {
+	if tag == "" {
+		return t.queryRollupSummaryWithTags(since, time.Now().UTC(), projectPath)
+	}
+
 	summary := &UsageSummary{
 		ByProvider: make(map[string]*UsageStats),
 		ByModel:    make(map[string]*UsageStats),
@@ -218,6 +466,10 @@ func (t *UsageTracker) querySummary(since time.Time, projectPath string) (*Usage
 		conditions = append(conditions, "project_path = ?")
 		args = append(args, projectPath)
 	}
+	if tag != "" {
+		conditions = append(conditions, usageTagCondition)
+		args = append(args, tag)
+	}
 
 	whereClause := ""
 	if len(conditions) > 0 {
@@ -225,14 +477,15 @@ func (t *UsageTracker) querySummary(since time.Time, projectPath string) (*Usage
 	}
 
 	// Query totals
-	query := `SELECT COALESCE(SUM(input_tokens), 0), COALESCE(SUM(output_tokens), 0), COALESCE(SUM(cost_usd), 0), COUNT(*) FROM usage` + whereClause
-	err := t.db.db.QueryRow(query, args...).Scan(&summary.TotalInputTokens, &summary.TotalOutputTokens, &summary.TotalCost, &summary.RequestCount)
+	query := `SELECT COALESCE(SUM(input_tokens), 0), COALESCE(SUM(output_tokens), 0), COALESCE(SUM(cache_creation_tokens), 0), COALESCE(SUM(cache_read_tokens), 0), COALESCE(SUM(cost_usd), 0), COUNT(*) FROM usage` + whereClause
+	err := t.db.db.QueryRow(query, args...).Scan(&summary.TotalInputTokens, &summary.TotalOutputTokens, &summary.TotalCacheCreationTokens, &summary.TotalCacheReadTokens, &summary.TotalCost, &summary.RequestCount)
 	if err != nil {
 		return nil, err
 	}
+	summary.CacheHitRate = cacheHitRate(summary.TotalInputTokens, summary.TotalCacheCreationTokens, summary.TotalCacheReadTokens)
 
 	// Query by provider
-	query = `SELECT provider, SUM(input_tokens), SUM(output_tokens), SUM(cost_usd), COUNT(*) FROM usage` + whereClause + ` GROUP BY provider`
+	query = `SELECT provider, SUM(input_tokens), SUM(output_tokens), COALESCE(SUM(cache_creation_tokens), 0), COALESCE(SUM(cache_read_tokens), 0), SUM(cost_usd), COUNT(*) FROM usage` + whereClause + ` GROUP BY provider`
 	rows, err := t.db.db.Query(query, args...)
 	if err != nil {
 		return nil, err
@@ -242,14 +495,15 @@ func (t *UsageTracker) querySummary(since time.Time, projectPath string) (*Usage
 	for rows.Next() {
 		var provider string
 		var stats UsageStats
-		if err := rows.Scan(&provider, &stats.InputTokens, &stats.OutputTokens, &stats.Cost, &stats.RequestCount); err != nil {
+		if err := rows.Scan(&provider, &stats.InputTokens, &stats.OutputTokens, &stats.CacheCreationTokens, &stats.CacheReadTokens, &stats.Cost, &stats.RequestCount); err != nil {
 			continue
 		}
+		stats.CacheHitRate = cacheHitRate(stats.InputTokens, stats.CacheCreationTokens, stats.CacheReadTokens)
 		summary.ByProvider[provider] = &stats
 	}
 
 	// Query by model
-	query = `SELECT model, SUM(input_tokens), SUM(output_tokens), SUM(cost_usd), COUNT(*) FROM usage` + whereClause + ` GROUP BY model`
+	query = `SELECT model, SUM(input_tokens), SUM(output_tokens), COALESCE(SUM(cache_creation_tokens), 0), COALESCE(SUM(cache_read_tokens), 0), SUM(cost_usd), COUNT(*) FROM usage` + whereClause + ` GROUP BY model`
 	rows, err = t.db.db.Query(query, args...)
 	if err != nil {
 		return nil, err
@@ -259,14 +513,15 @@ func (t *UsageTracker) querySummary(since time.Time, projectPath string) (*Usage
 	for rows.Next() {
 		var model string
 		var stats UsageStats
-		if err := rows.Scan(&model, &stats.InputTokens, &stats.OutputTokens, &stats.Cost, &stats.RequestCount); err != nil {
+		if err := rows.Scan(&model, &stats.InputTokens, &stats.OutputTokens, &stats.CacheCreationTokens, &stats.CacheReadTokens, &stats.Cost, &stats.RequestCount); err != nil {
 			continue
 		}
+		stats.CacheHitRate = cacheHitRate(stats.InputTokens, stats.CacheCreationTokens, stats.CacheReadTokens)
 		summary.ByModel[model] = &stats
 	}
 
 	// Query by project
-	query = `SELECT project_path, SUM(input_tokens), SUM(output_tokens), SUM(cost_usd), COUNT(*) FROM usage` + whereClause + ` GROUP BY project_path`
+	query = `SELECT project_path, SUM(input_tokens), SUM(output_tokens), COALESCE(SUM(cache_creation_tokens), 0), COALESCE(SUM(cache_read_tokens), 0), SUM(cost_usd), COUNT(*) FROM usage` + whereClause + ` GROUP BY project_path`
 	rows, err = t.db.db.Query(query, args...)
 	if err != nil {
 		return nil, err
@@ -276,44 +531,67 @@ func (t *UsageTracker) querySummary(since time.Time, projectPath string) (*Usage
 	for rows.Next() {
 		var project string
 		var stats UsageStats
-		if err := rows.Scan(&project, &stats.InputTokens, &stats.OutputTokens, &stats.Cost, &stats.RequestCount); err != nil {
+		if err := rows.Scan(&project, &stats.InputTokens, &stats.OutputTokens, &stats.CacheCreationTokens, &stats.CacheReadTokens, &stats.Cost, &stats.RequestCount); err != nil {
 			continue
 		}
+		stats.CacheHitRate = cacheHitRate(stats.InputTokens, stats.CacheCreationTokens, stats.CacheReadTokens)
 		if project != "" {
 			summary.ByProject[project] = &stats
 		}
 	}
 
+	summary.ByUser, err = t.queryGroupedStats("user_label", "usage", whereClause, args)
+	if err != nil {
+		return nil, err
+	}
+	summary.ByTag, err = t.queryGroupedStats("usage_tags.tag", usageWithTags, whereClause, args)
+	if err != nil {
+		return nil, err
+	}
+
 	return summary, nil
 }
 
-// AggregateHourly aggregates recent usage data into hourly buckets.
-// This should be called periodically (e.g., every hour) to maintain dashboard performance.
-func (t *UsageTracker) AggregateHourly() error {
-	if t.db == nil || t.db.db == nil {
-		return nil
+// usageTagCondition matches usage rows sent with the tag bound to it.
+const usageTagCondition = "id IN (SELECT usage_id FROM usage_tags WHERE tag = ?)"
+
+// usageWithTags joins each usage row to its tags, for grouping by tag.
+const usageWithTags = "usage JOIN usage_tags ON usage_tags.usage_id = usage.id"
+
+// queryGroupedStats returns usage stats from table grouped by column, leaving
+// out rows where it is empty.
+func (t *UsageTracker) queryGroupedStats(column, table, whereClause string, args []interface{}) (map[string]*UsageStats, error) {
+	if whereClause == "" {
+		whereClause = " WHERE " + column + " != ''"
+	} else {
+		whereClause += " AND " + column + " != ''"
+	}
+	query := `SELECT ` + column + `, SUM(input_tokens), SUM(output_tokens), COALESCE(SUM(cache_creation_tokens), 0), COALESCE(SUM(cache_read_tokens), 0), SUM(cost_usd), COUNT(*) FROM ` + table + whereClause + ` GROUP BY ` + column
+	rows, err := t.db.db.Query(query, args...)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	// Aggregate last 2 hours of data (to catch any late entries)
-	since := time.Now().UTC().Add(-2 * time.Hour).Truncate(time.Hour)
-
-	_, err := t.db.db.Exec(`
-		INSERT OR REPLACE INTO usage_hourly (hour, provider, model, project_path, total_input, total_output, total_cost, request_count)
-		SELECT
-			strftime('%Y-%m-%d %H:00:00', timestamp) as hour,
-			provider,
-			model,
-			project_path,
-			SUM(input_tokens),
-			SUM(output_tokens),
-			SUM(cost_usd),
-			COUNT(*)
-		FROM usage
-		WHERE timestamp >= ?
-		GROUP BY hour, provider, model, project_path
-	`, since.Format(time.RFC3339Nano))
+	result := make(map[string]*UsageStats)
+	for rows.Next() {
+		var key string
+		var stats UsageStats
+		if err := rows.Scan(&key, &stats.InputTokens, &stats.OutputTokens, &stats.CacheCreationTokens, &stats.CacheReadTokens, &stats.Cost, &stats.RequestCount); err != nil {
+			continue
+		}
+		stats.CacheHitRate = cacheHitRate(stats.InputTokens, stats.CacheCreationTokens, stats.CacheReadTokens)
+		result[key] = &stats
+	}
+	return result, rows.Err()
+}
 
-	return err
+// AggregateHourly used to fold recent usage into hourly buckets.
+//
+// Deprecated: Record keeps the hourly and daily rollups current, so there is
+// nothing left to aggregate. AggregateHourly does nothing.
+func (t *UsageTracker) AggregateHourly() error {
+	return nil
 }
 
 // GetHourlySummary returns hourly aggregated data for charts.
@@ -322,15 +600,15 @@ func (t *UsageTracker) GetHourlySummary(hours int) ([]HourlyUsage, error) {
 		return nil, nil
 	}
 
-	since := time.Now().UTC().Add(-time.Duration(hours) * time.Hour)
+	since := time.Now().UTC().Add(-time.Duration(hours) * time.Hour).Truncate(time.Hour)
 
 	rows, err := t.db.db.Query(`
-		SELECT hour, SUM(total_input), SUM(total_output), SUM(total_cost), SUM(request_count)
-		FROM usage_hourly
-		WHERE hour >= ?
-		GROUP BY hour
-		ORDER BY hour ASC
-	`, since.Format(time.RFC3339Nano))
+		SELECT bucket, SUM(input_tokens), SUM(output_tokens), SUM(cost_usd), SUM(request_count)
+		FROM usage_rollup_hourly
+		WHERE bucket >= ?
+		GROUP BY bucket
+		ORDER BY bucket ASC
+	`, since.Format(rollupHourLayout))
 	if err != nil {
 		return nil, err
 	}
@@ -343,7 +621,7 @@ func (t *UsageTracker) GetHourlySummary(hours int) ([]HourlyUsage, error) {
 		if err := rows.Scan(&hourStr, &h.InputTokens, &h.OutputTokens, &h.Cost, &h.RequestCount); err != nil {
 			continue
 		}
-		if t, err := time.Parse("2006-01-02 15:04:05", hourStr); err == nil {
+		if t, err := time.Parse(rollupHourLayout, hourStr); err == nil {
 			h.Hour = t
 		}
 		result = append(result, h)
@@ -387,15 +665,18 @@ func (t *UsageTracker) getHourlySummaryByDimension(dimension string, since, unti
 	}
 
 	query := `
-		SELECT strftime('%Y-%m-%d %H:00:00', timestamp) as hour, ` + dimension + `,
-			SUM(input_tokens), SUM(output_tokens), SUM(cost_usd), COUNT(*)
-		FROM usage
-		WHERE timestamp >= ? AND timestamp < ?
-		GROUP BY hour, ` + dimension + `
-		ORDER BY hour ASC, ` + dimension + ` ASC
+		SELECT bucket, ` + dimension + `,
+			SUM(input_tokens), SUM(output_tokens), SUM(cost_usd), SUM(request_count)
+		FROM usage_rollup_hourly
+		WHERE bucket >= ? AND bucket < ?
+		GROUP BY bucket, ` + dimension + `
+		ORDER BY bucket ASC, ` + dimension + ` ASC
 	`
 
-	rows, err := t.db.db.Query(query, since.Format(time.RFC3339Nano), until.Format(time.RFC3339Nano))
+	// Buckets overlapping the range are included whole
+	from := since.UTC().Truncate(time.Hour).Format(rollupHourLayout)
+	to := ceilTime(until.UTC(), time.Hour).Format(rollupHourLayout)
+	rows, err := t.db.db.Query(query, from, to)
 	if err != nil {
 		return nil, err
 	}
@@ -408,7 +689,7 @@ func (t *UsageTracker) getHourlySummaryByDimension(dimension string, since, unti
 		if err := rows.Scan(&hourStr, &h.Dimension, &h.InputTokens, &h.OutputTokens, &h.Cost, &h.RequestCount); err != nil {
 			continue
 		}
-		if t, err := time.Parse("2006-01-02 15:04:05", hourStr); err == nil {
+		if t, err := time.Parse(rollupHourLayout, hourStr); err == nil {
 			h.Hour = t
 		}
 		result = append(result, h)
@@ -419,6 +700,12 @@ func (t *UsageTracker) getHourlySummaryByDimension(dimension string, since, unti
 
 // GetSummaryByTimeRange returns usage summary for a custom time range.
 func (t *UsageTracker) GetSummaryByTimeRange(since, until time.Time, projectPath string) (*UsageSummary, error) {
+	return t.GetTaggedSummaryByTimeRange(since, until, projectPath, "")
+}
+
+// GetTaggedSummaryByTimeRange is GetSummaryByTimeRange limited to requests
+// sent with tag (empty string for all requests).
+func (t *UsageTracker) GetTaggedSummaryByTimeRange(since, until time.Time, projectPath, tag string) (*UsageSummary, error) {
 	if t.db == nil || t.db.db == nil {
 		return &UsageSummary{
 			ByProvider: make(map[string]*UsageStats),
@@ -427,10 +714,14 @@ func (t *UsageTracker) GetSummaryByTimeRange(since, until time.Time, projectPath
 		}, nil
 	}
 
-	return t.querySummaryByRange(since, until, projectPath)
+	return t.querySummaryByRange(since, until, projectPath, tag)
 }
 
-func (t *UsageTracker) querySummaryByRange(since, until time.Time, projectPath string) (*UsageSummary, error) {
+func (t *UsageTracker) querySummaryByRange(since, until time.Time, projectPath, tag string) (*UsageSummary, error) {
+	if tag == "" {
+		return t.queryRollupSummaryWithTags(since, until, projectPath)
+	}
+
 	summary := &UsageSummary{
 		ByProvider: make(map[string]*UsageStats),
 		ByModel:    make(map[string]*UsageStats),
@@ -447,18 +738,23 @@ func (t *UsageTracker) querySummaryByRange(since, until time.Time, projectPath s
 		conditions = append(conditions, "project_path = ?")
 		args = append(args, projectPath)
 	}
+	if tag != "" {
+		conditions = append(conditions, usageTagCondition)
+		args = append(args, tag)
+	}
 
 	whereClause := " WHERE " + strings.Join(conditions, " AND ")
 
 	// Query totals
-	query := `SELECT COALESCE(SUM(input_tokens), 0), COALESCE(SUM(output_tokens), 0), COALESCE(SUM(cost_usd), 0), COUNT(*) FROM usage` + whereClause
-	err := t.db.db.QueryRow(query, args...).Scan(&summary.TotalInputTokens, &summary.TotalOutputTokens, &summary.TotalCost, &summary.RequestCount)
+	query := `SELECT COALESCE(SUM(input_tokens), 0), COALESCE(SUM(output_tokens), 0), COALESCE(SUM(cache_creation_tokens), 0), COALESCE(SUM(cache_read_tokens), 0), COALESCE(SUM(cost_usd), 0), COUNT(*) FROM usage` + whereClause
+	err := t.db.db.QueryRow(query, args...).Scan(&summary.TotalInputTokens, &summary.TotalOutputTokens, &summary.TotalCacheCreationTokens, &summary.TotalCacheReadTokens, &summary.TotalCost, &summary.RequestCount)
 	if err != nil {
 		return nil, err
 	}
+	summary.CacheHitRate = cacheHitRate(summary.TotalInputTokens, summary.TotalCacheCreationTokens, summary.TotalCacheReadTokens)
 
 	// Query by provider
-	query = `SELECT provider, SUM(input_tokens), SUM(output_tokens), SUM(cost_usd), COUNT(*) FROM usage` + whereClause + ` GROUP BY provider`
+	query = `SELECT provider, SUM(input_tokens), SUM(output_tokens), COALESCE(SUM(cache_creation_tokens), 0), COALESCE(SUM(cache_read_tokens), 0), SUM(cost_usd), COUNT(*) FROM usage` + whereClause + ` GROUP BY provider`
 	rows, err := t.db.db.Query(query, args...)
 	if err != nil {
 		return nil, err
@@ -468,14 +764,15 @@ func (t *UsageTracker) querySummaryByRange(since, until time.Time, projectPath s
 	for rows.Next() {
 		var provider string
 		var stats UsageStats
-		if err := rows.Scan(&provider, &stats.InputTokens, &stats.OutputTokens, &stats.Cost, &stats.RequestCount); err != nil {
+		if err := rows.Scan(&provider, &stats.InputTokens, &stats.OutputTokens, &stats.CacheCreationTokens, &stats.CacheReadTokens, &stats.Cost, &stats.RequestCount); err != nil {
 			continue
 		}
+		stats.CacheHitRate = cacheHitRate(stats.InputTokens, stats.CacheCreationTokens, stats.CacheReadTokens)
 		summary.ByProvider[provider] = &stats
 	}
 
 	// Query by model
-	query = `SELECT model, SUM(input_tokens), SUM(output_tokens), SUM(cost_usd), COUNT(*) FROM usage` + whereClause + ` GROUP BY model`
+	query = `SELECT model, SUM(input_tokens), SUM(output_tokens), COALESCE(SUM(cache_creation_tokens), 0), COALESCE(SUM(cache_read_tokens), 0), SUM(cost_usd), COUNT(*) FROM usage` + whereClause + ` GROUP BY model`
 	rows, err = t.db.db.Query(query, args...)
 	if err != nil {
 		return nil, err
@@ -485,14 +782,15 @@ func (t *UsageTracker) querySummaryByRange(since, until time.Time, projectPath s
 	for rows.Next() {
 		var model string
 		var stats UsageStats
-		if err := rows.Scan(&model, &stats.InputTokens, &stats.OutputTokens, &stats.Cost, &stats.RequestCount); err != nil {
+		if err := rows.Scan(&model, &stats.InputTokens, &stats.OutputTokens, &stats.CacheCreationTokens, &stats.CacheReadTokens, &stats.Cost, &stats.RequestCount); err != nil {
 			continue
 		}
+		stats.CacheHitRate = cacheHitRate(stats.InputTokens, stats.CacheCreationTokens, stats.CacheReadTokens)
 		summary.ByModel[model] = &stats
 	}
 
 	// Query by project
-	query = `SELECT project_path, SUM(input_tokens), SUM(output_tokens), SUM(cost_usd), COUNT(*) FROM usage` + whereClause + ` GROUP BY project_path`
+	query = `SELECT project_path, SUM(input_tokens), SUM(output_tokens), COALESCE(SUM(cache_creation_tokens), 0), COALESCE(SUM(cache_read_tokens), 0), SUM(cost_usd), COUNT(*) FROM usage` + whereClause + ` GROUP BY project_path`
 	rows, err = t.db.db.Query(query, args...)
 	if err != nil {
 		return nil, err
@@ -502,52 +800,170 @@ func (t *UsageTracker) querySummaryByRange(since, until time.Time, projectPath s
 	for rows.Next() {
 		var project string
 		var stats UsageStats
-		if err := rows.Scan(&project, &stats.InputTokens, &stats.OutputTokens, &stats.Cost, &stats.RequestCount); err != nil {
+		if err := rows.Scan(&project, &stats.InputTokens, &stats.OutputTokens, &stats.CacheCreationTokens, &stats.CacheReadTokens, &stats.Cost, &stats.RequestCount); err != nil {
 			continue
 		}
+		stats.CacheHitRate = cacheHitRate(stats.InputTokens, stats.CacheCreationTokens, stats.CacheReadTokens)
 		if project != "" {
 			summary.ByProject[project] = &stats
 		}
 	}
 
+	summary.ByUser, err = t.queryGroupedStats("user_label", "usage", whereClause, args)
+	if err != nil {
+		return nil, err
+	}
+	summary.ByTag, err = t.queryGroupedStats("usage_tags.tag", usageWithTags, whereClause, args)
+	if err != nil {
+		return nil, err
+	}
+
 	return summary, nil
 }
 
 // GetRecentUsage returns recent usage entries.
 func (t *UsageTracker) GetRecentUsage(limit int) ([]UsageEntry, error) {
+	return t.GetRecentTaggedUsage(limit, "")
+}
+
+// GetRecentTaggedUsage returns recent usage entries sent with tag (empty
+// string for all entries).
+func (t *UsageTracker) GetRecentTaggedUsage(limit int, tag string) ([]UsageEntry, error) {
+	entries, _, err := t.ListUsage(tag, Page{Limit: limit})
+	return entries, err
+}
+
+// ListUsage returns a page of the usage entries sent with tag (empty string
+// for all entries), newest first, and whether there are more past it in the
+// direction paged.
+func (t *UsageTracker) ListUsage(tag string, page Page) ([]UsageEntry, bool, error) {
 	if t.db == nil || t.db.db == nil {
-		return nil, nil
+		return nil, false, nil
 	}
 
-	if limit <= 0 {
-		limit = 100
+	conds, args := page.where(usageTagConditions(tag))
+	query := `SELECT id, timestamp, session_id, provider, model, input_tokens, output_tokens, COALESCE(cache_creation_tokens, 0), COALESCE(cache_read_tokens, 0), cost_usd, latency_ms, project_path, client_type, user_label, ` + usageCostColumns + `, ` + usageTagsColumn + `
+		FROM usage`
+	if len(conds) > 0 {
+		query += ` WHERE ` + strings.Join(conds, " AND ")
 	}
+	order, limit := page.orderLimit()
+	query += order
+	args = append(args, limit)
 
-	rows, err := t.db.db.Query(`
-		SELECT timestamp, session_id, provider, model, input_tokens, output_tokens, cost_usd, latency_ms, project_path, client_type
-		FROM usage
-		ORDER BY timestamp DESC
-		LIMIT ?
-	`, limit)
+	rows, err := t.db.db.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 	defer rows.Close()
 
 	var entries []UsageEntry
 	for rows.Next() {
 		var e UsageEntry
-		var tsStr string
-		if err := rows.Scan(&tsStr, &e.SessionID, &e.Provider, &e.Model, &e.InputTokens, &e.OutputTokens, &e.CostUSD, &e.LatencyMs, &e.ProjectPath, &e.ClientType); err != nil {
+		var tsStr, tags string
+		if err := rows.Scan(&e.ID, &tsStr, &e.SessionID, &e.Provider, &e.Model, &e.InputTokens, &e.OutputTokens, &e.CacheCreationTokens, &e.CacheReadTokens, &e.CostUSD, &e.LatencyMs, &e.ProjectPath, &e.ClientType, &e.User,
+			&e.Batch, &e.Breakdown.InputCost, &e.Breakdown.OutputCost, &e.Breakdown.CacheWriteCost, &e.Breakdown.CacheReadCost, &e.Breakdown.BatchDiscount, &tags); err != nil {
 			continue
 		}
+		e.Tags = splitUsageTags(tags)
 		if t, err := time.Parse(time.RFC3339Nano, tsStr); err == nil {
 			e.Timestamp = t
 		}
 		entries = append(entries, e)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	entries, more := trimPage(page, entries)
+	return entries, more, nil
+}
+
+// CountUsage returns the number of usage entries sent with tag (empty string
+// for all entries).
+func (t *UsageTracker) CountUsage(tag string) (int, error) {
+	if t.db == nil || t.db.db == nil {
+		return 0, nil
+	}
+	conds, args := usageTagConditions(tag)
+	query := `SELECT COUNT(*) FROM usage`
+	if len(conds) > 0 {
+		query += ` WHERE ` + strings.Join(conds, " AND ")
+	}
+	var n int
+	err := t.db.db.QueryRow(query, args...).Scan(&n)
+	return n, err
+}
+
+// usageTagConditions returns the WHERE conditions selecting the usage
+// entries sent with tag, none for an empty tag.
+func usageTagConditions(tag string) ([]string, []interface{}) {
+	if tag == "" {
+		return nil, nil
+	}
+	return []string{usageTagCondition}, []interface{}{tag}
+}
 
-	return entries, nil
+// ExportUsage calls fn with each usage entry recorded in [since, until), oldest
+// first, stopping at the first error fn returns. A zero until means now.
+// projectPath and tag filter by project and tag (empty string for all).
+func (t *UsageTracker) ExportUsage(since, until time.Time, projectPath, tag string, fn func(*UsageEntry) error) error {
+	if t.db == nil || t.db.db == nil {
+		return nil
+	}
+	if until.IsZero() {
+		until = time.Now()
+	}
+
+	query := `SELECT timestamp, session_id, provider, model, input_tokens, output_tokens, COALESCE(cache_creation_tokens, 0), COALESCE(cache_read_tokens, 0), cost_usd, latency_ms, project_path, client_type, user_label, ` + usageCostColumns + `, ` + usageTagsColumn + `
+		FROM usage WHERE timestamp >= ? AND timestamp < ?`
+	args := []interface{}{since.UTC().Format(time.RFC3339Nano), until.UTC().Format(time.RFC3339Nano)}
+	if projectPath != "" {
+		query += ` AND project_path = ?`
+		args = append(args, projectPath)
+	}
+	if tag != "" {
+		query += ` AND ` + usageTagCondition
+		args = append(args, tag)
+	}
+	query += ` ORDER BY timestamp ASC`
+
+	rows, err := t.db.db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e UsageEntry
+		var tsStr, tags string
+		if err := rows.Scan(&tsStr, &e.SessionID, &e.Provider, &e.Model, &e.InputTokens, &e.OutputTokens, &e.CacheCreationTokens, &e.CacheReadTokens, &e.CostUSD, &e.LatencyMs, &e.ProjectPath, &e.ClientType, &e.User,
+			&e.Batch, &e.Breakdown.InputCost, &e.Breakdown.OutputCost, &e.Breakdown.CacheWriteCost, &e.Breakdown.CacheReadCost, &e.Breakdown.BatchDiscount, &tags); err != nil {
+			return err
+		}
+		e.Tags = splitUsageTags(tags)
+		if ts, err := time.Parse(time.RFC3339Nano, tsStr); err == nil {
+			e.Timestamp = ts
+		}
+		if err := fn(&e); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// usageCostColumns selects a usage row's batch flag and cost breakdown.
+// Rows recorded before the breakdown was stored read as zero.
+const usageCostColumns = "COALESCE(batch, 0), COALESCE(input_cost_usd, 0), COALESCE(output_cost_usd, 0), COALESCE(cache_write_cost_usd, 0), COALESCE(cache_read_cost_usd, 0), COALESCE(batch_discount_usd, 0)"
+
+// usageTagsColumn selects a usage row's tags as one comma-separated string.
+const usageTagsColumn = "COALESCE((SELECT group_concat(tag, ',') FROM usage_tags WHERE usage_id = usage.id), '')"
+
+// splitUsageTags splits the tags selected by usageTagsColumn.
+func splitUsageTags(tags string) []string {
+	if tags == "" {
+		return nil
+	}
+	return strings.Split(tags, ",")
 }
 
 // GetRecentPaths returns distinct project paths from recent usage, ordered by last use.
@@ -345,6 +345,143 @@ func TestUsageTracker_GetSummary_WithDB(t *testing.T) {
 	}
 }
 
+func TestUsageTracker_GetSummary_CacheTokens(t *testing.T) {
+	ldb, err := OpenLogDB(filepath.Join(t.TempDir(), "logs"))
+	if err != nil {
+		t.Fatalf("OpenLogDB() error: %v", err)
+	}
+	defer ldb.Close()
+
+	tracker := &UsageTracker{db: ldb, pricing: make(map[string]*config.ModelPricing)}
+	tracker.Record(UsageEntry{
+		Timestamp:           time.Now(),
+		SessionID:           "s1",
+		Provider:            "anthropic",
+		Model:               "claude-sonnet-4",
+		InputTokens:         100,
+		OutputTokens:        50,
+		CacheCreationTokens: 900,
+	})
+	tracker.Record(UsageEntry{
+		Timestamp:       time.Now(),
+		SessionID:       "s1",
+		Provider:        "anthropic",
+		Model:           "claude-sonnet-4",
+		InputTokens:     100,
+		OutputTokens:    50,
+		CacheReadTokens: 900,
+	})
+	tracker.Record(UsageEntry{
+		Timestamp:    time.Now(),
+		SessionID:    "s2",
+		Provider:     "openai",
+		Model:        "gpt-4o",
+		InputTokens:  1000,
+		OutputTokens: 50,
+	})
+
+	summary, err := tracker.GetSummary("all", "")
+	if err != nil {
+		t.Fatalf("GetSummary() error: %v", err)
+	}
+	if summary.TotalCacheCreationTokens != 900 || summary.TotalCacheReadTokens != 900 {
+		t.Errorf("cache totals = %d/%d, want 900/900", summary.TotalCacheCreationTokens, summary.TotalCacheReadTokens)
+	}
+	// 900 read of 1200 + 900 + 900 prompt tokens
+	if summary.CacheHitRate != 0.3 {
+		t.Errorf("CacheHitRate = %v, want 0.3", summary.CacheHitRate)
+	}
+
+	anthropic := summary.ByProvider["anthropic"]
+	if anthropic == nil {
+		t.Fatal("missing anthropic provider stats")
+	}
+	// 900 read of 200 + 900 + 900 prompt tokens
+	if anthropic.CacheHitRate != 0.45 {
+		t.Errorf("anthropic CacheHitRate = %v, want 0.45", anthropic.CacheHitRate)
+	}
+	if got := summary.ByModel["gpt-4o"]; got == nil || got.CacheHitRate != 0 {
+		t.Errorf("gpt-4o stats = %+v, want zero cache hit rate", got)
+	}
+}
+
+func TestUsageTracker_CalculateCacheCost(t *testing.T) {
+	tracker := &UsageTracker{
+		pricing: map[string]*config.ModelPricing{
+			"claude-sonnet-4": {InputPerMillion: 3.0, OutputPerMillion: 15.0},
+		},
+	}
+
+	// 1M cache writes at 1.25x + 1M cache reads at 0.1x of $3/M
+	if got := tracker.CalculateCacheCost("claude-sonnet-4", 1_000_000, 1_000_000); got < 4.049 || got > 4.051 {
+		t.Errorf("CalculateCacheCost() = %v, want 4.05", got)
+	}
+	if got := tracker.CalculateCacheCost("unknown-model", 1_000_000, 0); got != 0 {
+		t.Errorf("CalculateCacheCost(unknown) = %v, want 0", got)
+	}
+
+	got := tracker.CalculateRequestCost("p", CostInput{
+		Model:           "claude-sonnet-4",
+		InputTokens:     1_000_000,
+		CacheReadTokens: 1_000_000,
+	})
+	if got < 3.299 || got > 3.301 {
+		t.Errorf("CalculateRequestCost() = %v, want 3.30", got)
+	}
+}
+
+func TestUsageTracker_CostBreakdown(t *testing.T) {
+	tracker := &UsageTracker{
+		pricing: map[string]*config.ModelPricing{
+			"claude-sonnet-4": {InputPerMillion: 3.0, OutputPerMillion: 15.0, CacheWritePerMillion: 6.0, CacheReadPerMillion: 0.5, BatchDiscount: 0.5},
+		},
+		providerCosts: map[string]CostFormula{"flat": perRequestCost{fee: 0.01}},
+	}
+	in := CostInput{
+		Model:               "claude-sonnet-4",
+		InputTokens:         1_000_000,
+		OutputTokens:        1_000_000,
+		CacheCreationTokens: 1_000_000,
+		CacheReadTokens:     1_000_000,
+	}
+
+	b := tracker.CalculateCostBreakdown(in)
+	want := CostBreakdown{InputCost: 3.0, OutputCost: 15.0, CacheWriteCost: 6.0, CacheReadCost: 0.5}
+	if b != want {
+		t.Errorf("CalculateCostBreakdown() = %+v, want %+v", b, want)
+	}
+
+	in.Batch = true
+	b, ok := tracker.RequestCostBreakdown("p", in)
+	if !ok || b.BatchDiscount != 12.25 || b.Total() != 12.25 {
+		t.Errorf("batch breakdown = %+v (ok %v), want a 12.25 discount and 12.25 total", b, ok)
+	}
+	if got := tracker.CalculateRequestCost("p", in); got != 12.25 {
+		t.Errorf("CalculateRequestCost(batch) = %v, want 12.25", got)
+	}
+	if _, ok := tracker.RequestCostBreakdown("flat", in); ok {
+		t.Error("providers with a cost model should have no breakdown")
+	}
+
+	tmpDir := t.TempDir()
+	ldb, err := OpenLogDB(tmpDir)
+	if err != nil {
+		t.Fatalf("OpenLogDB() error: %v", err)
+	}
+	defer ldb.Close()
+	tracker.db = ldb
+	if err := tracker.Record(UsageEntry{Timestamp: time.Now(), SessionID: "s", Provider: "p", Model: in.Model, CostUSD: b.Total(), Batch: true, Breakdown: b}); err != nil {
+		t.Fatalf("Record() error: %v", err)
+	}
+	entries, err := tracker.GetRecentUsage(10)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("GetRecentUsage() = %v, %v", entries, err)
+	}
+	if !entries[0].Batch || entries[0].Breakdown != b {
+		t.Errorf("recorded entry = %+v, want batch with breakdown %+v", entries[0], b)
+	}
+}
+
 func TestUsageTracker_GetRecentUsage_WithDB(t *testing.T) {
 	tmpDir := t.TempDir()
 	oldHome := os.Getenv("HOME")
@@ -579,6 +716,76 @@ func TestUsageTracker_GetHourlySummaryByModel_WithDB(t *testing.T) {
 	_ = result
 }
 
+func TestUsageTracker_Tags(t *testing.T) {
+	ldb, err := OpenLogDB(filepath.Join(t.TempDir(), "logs"))
+	if err != nil {
+		t.Fatalf("OpenLogDB() error: %v", err)
+	}
+	defer ldb.Close()
+
+	tracker := &UsageTracker{db: ldb, pricing: make(map[string]*config.ModelPricing)}
+	now := time.Now()
+	for _, e := range []UsageEntry{
+		{Timestamp: now, SessionID: "s1", Provider: "p", Model: "m", InputTokens: 100, CostUSD: 1, Tags: []string{"ci", "nightly"}},
+		{Timestamp: now, SessionID: "s2", Provider: "p", Model: "m", InputTokens: 200, CostUSD: 2, Tags: []string{"ci"}},
+		{Timestamp: now, SessionID: "s3", Provider: "p", Model: "m", InputTokens: 400, CostUSD: 4},
+	} {
+		if err := tracker.Record(e); err != nil {
+			t.Fatalf("Record() error: %v", err)
+		}
+	}
+
+	summary, err := tracker.GetSummary("all", "")
+	if err != nil {
+		t.Fatalf("GetSummary() error: %v", err)
+	}
+	if summary.RequestCount != 3 || len(summary.ByTag) != 2 {
+		t.Fatalf("summary = %d requests, by_tag %v; want 3 requests, 2 tags", summary.RequestCount, summary.ByTag)
+	}
+	if ci := summary.ByTag["ci"]; ci == nil || ci.RequestCount != 2 || ci.Cost != 3 {
+		t.Errorf("ByTag[ci] = %+v, want 2 requests costing 3", ci)
+	}
+
+	tagged, err := tracker.GetTaggedSummaryByTimeRange(now.Add(-time.Minute), now.Add(time.Minute), "", "nightly")
+	if err != nil {
+		t.Fatalf("GetTaggedSummaryByTimeRange() error: %v", err)
+	}
+	if tagged.RequestCount != 1 || tagged.TotalInputTokens != 100 {
+		t.Errorf("nightly summary = %d requests, %d input tokens; want 1, 100", tagged.RequestCount, tagged.TotalInputTokens)
+	}
+
+	entries, err := tracker.GetRecentTaggedUsage(10, "ci")
+	if err != nil {
+		t.Fatalf("GetRecentTaggedUsage() error: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 ci entries, got %d", len(entries))
+	}
+	for _, e := range entries {
+		if e.SessionID == "s1" && len(e.Tags) != 2 {
+			t.Errorf("s1 tags = %v, want ci and nightly", e.Tags)
+		}
+	}
+
+	page, more, err := tracker.ListUsage("ci", Page{Limit: 1})
+	if err != nil {
+		t.Fatalf("ListUsage() error: %v", err)
+	}
+	if len(page) != 1 || page[0].SessionID != "s2" || !more {
+		t.Fatalf("first ci page = %+v (more %v), want s2 with more", page, more)
+	}
+	page, more, err = tracker.ListUsage("ci", Page{Limit: 1, Before: page[0].ID})
+	if err != nil {
+		t.Fatalf("ListUsage() error: %v", err)
+	}
+	if len(page) != 1 || page[0].SessionID != "s1" || more {
+		t.Errorf("second ci page = %+v (more %v), want s1 without more", page, more)
+	}
+	if n, err := tracker.CountUsage("ci"); err != nil || n != 2 {
+		t.Errorf("CountUsage(ci) = %d, %v; want 2", n, err)
+	}
+}
+
 func TestUsageTracker_GetSummaryByTimeRange_WithDB(t *testing.T) {
 	tmpDir := t.TempDir()
 	oldHome := os.Getenv("HOME")
@@ -746,3 +953,19 @@ func TestHourlyUsageByDimension_Struct(t *testing.T) {
 		t.Errorf("Expected 10 requests, got %d", h.RequestCount)
 	}
 }
+
+func TestReloadPricingConcurrentWithCalculation(t *testing.T) {
+	setupTestConfig(t)
+	tracker := NewUsageTracker(nil)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			tracker.ReloadPricing()
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		tracker.CalculateRequestCost("provider", CostInput{Model: "claude-sonnet-4-5", InputTokens: 1000})
+	}
+	<-done
+}
@@ -7,6 +7,7 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"errors"
+	"fmt"
 	"io"
 
 	"golang.org/x/crypto/pbkdf2"
@@ -16,6 +17,26 @@ const (
 	pbkdf2Iterations = 600_000
 	saltSize         = 16
 	keySize          = 32 // AES-256
+
+	// keyCheckPlaintext is encrypted into each payload so a wrong passphrase
+	// can be told apart from a corrupt payload.
+	keyCheckPlaintext = "gozen-sync-key-check"
+)
+
+var (
+	// ErrWrongPassphrase means the payload is intact but was encrypted with a
+	// different passphrase.
+	ErrWrongPassphrase = errors.New("wrong sync passphrase")
+	// ErrCorruptPayload means the payload cannot be parsed or decrypted even
+	// though the passphrase is right.
+	ErrCorruptPayload = errors.New("sync payload is corrupt")
+	// ErrPassphraseRequired means the payload is encrypted but no passphrase
+	// is configured.
+	ErrPassphraseRequired = errors.New("sync payload is encrypted but no passphrase is set")
+
+	// errAuthFailed is returned by Decrypt when the ciphertext is well formed
+	// but does not authenticate with the key.
+	errAuthFailed = errors.New("message authentication failed")
 )
 
 // GenerateSalt returns a cryptographically random 16-byte salt.
@@ -51,11 +72,12 @@ func Encrypt(plaintext, key []byte) (string, error) {
 	return base64.StdEncoding.EncodeToString(ciphertext), nil
 }
 
-// Decrypt decodes a base64 string and decrypts with AES-256-GCM.
+// Decrypt decodes a base64 string and decrypts with AES-256-GCM. A
+// malformed input fails with ErrCorruptPayload.
 func Decrypt(encoded string, key []byte) ([]byte, error) {
 	data, err := base64.StdEncoding.DecodeString(encoded)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %v", ErrCorruptPayload, err)
 	}
 	block, err := aes.NewCipher(key)
 	if err != nil {
@@ -67,8 +89,34 @@ func Decrypt(encoded string, key []byte) ([]byte, error) {
 	}
 	nonceSize := gcm.NonceSize()
 	if len(data) < nonceSize {
-		return nil, errors.New("ciphertext too short")
+		return nil, fmt.Errorf("%w: ciphertext too short", ErrCorruptPayload)
 	}
 	nonce, ciphertext := data[:nonceSize], data[nonceSize:]
-	return gcm.Open(nil, nonce, ciphertext, nil)
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, errAuthFailed
+	}
+	return plaintext, nil
+}
+
+// NewKeyCheck returns a value that VerifyKey accepts only for key.
+func NewKeyCheck(key []byte) (string, error) {
+	return Encrypt([]byte(keyCheckPlaintext), key)
+}
+
+// VerifyKey checks key against a value from NewKeyCheck. It fails with
+// ErrWrongPassphrase if the key is different and ErrCorruptPayload if the
+// check value is damaged.
+func VerifyKey(check string, key []byte) error {
+	plaintext, err := Decrypt(check, key)
+	if errors.Is(err, errAuthFailed) {
+		return ErrWrongPassphrase
+	}
+	if err != nil {
+		return err
+	}
+	if string(plaintext) != keyCheckPlaintext {
+		return fmt.Errorf("%w: bad key check", ErrCorruptPayload)
+	}
+	return nil
 }
@@ -2,6 +2,7 @@ package sync
 
 import (
 	"bytes"
+	"errors"
 	"testing"
 )
 
@@ -80,3 +81,21 @@ func TestDifferentSaltsDifferentKeys(t *testing.T) {
 		t.Fatal("different salts should produce different keys")
 	}
 }
+
+func TestVerifyKey(t *testing.T) {
+	salt, _ := GenerateSalt()
+	key := DeriveKey("passphrase-1", salt)
+	check, err := NewKeyCheck(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyKey(check, key); err != nil {
+		t.Fatalf("VerifyKey with the right key: %v", err)
+	}
+	if err := VerifyKey(check, DeriveKey("passphrase-2", salt)); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("expected ErrWrongPassphrase, got %v", err)
+	}
+	if err := VerifyKey("not-valid-base64!!!", key); !errors.Is(err, ErrCorruptPayload) {
+		t.Fatalf("expected ErrCorruptPayload, got %v", err)
+	}
+}
@@ -2,8 +2,11 @@ package sync
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -18,6 +21,8 @@ type SyncManager struct {
 	cfg       *config.SyncConfig
 	meta      *SyncMeta
 	isPulling bool // guard to prevent push-after-pull loops
+
+	pushPending bool
 }
 
 // NewSyncManager creates a SyncManager from the current sync config.
@@ -66,14 +71,40 @@ func (m *SyncManager) Status() *SyncStatus {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	return &SyncStatus{
-		Configured: true,
-		Backend:    m.backend.Name(),
-		DeviceID:   m.meta.DeviceID,
-		LastPullAt: m.meta.LastPullAt,
-		LastPushAt: m.meta.LastPushAt,
+		Configured:     true,
+		Backend:        m.backend.Name(),
+		DeviceID:       m.meta.DeviceID,
+		LastPullAt:     m.meta.LastPullAt,
+		LastPushAt:     m.meta.LastPushAt,
+		AutoPull:       m.cfg.AutoPull,
+		AutoPush:       m.cfg.AutoPush,
+		PushPending:    m.pushPending,
+		LastPushStatus: m.meta.LastPushStatus,
+		LastPushError:  m.meta.LastPushError,
+		Encrypted:      m.cfg.Passphrase != "",
+		KeyVersion:     m.meta.KeyVersion,
 	}
 }
 
+// SetPushPending records whether an auto-push is scheduled.
+func (m *SyncManager) SetPushPending(pending bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pushPending = pending
+}
+
+// recordPush records the outcome of a push attempt in the sync meta.
+func (m *SyncManager) recordPush(status string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.meta.LastPushStatus = status
+	m.meta.LastPushError = ""
+	if err != nil {
+		m.meta.LastPushError = err.Error()
+	}
+	m.meta.Save()
+}
+
 // Pull downloads remote payload, merges with local, and applies changes.
 func (m *SyncManager) Pull(ctx context.Context) error {
 	m.mu.Lock()
@@ -114,10 +145,23 @@ func (m *SyncManager) Pull(ctx context.Context) error {
 		return fmt.Errorf("pull apply: %w", err)
 	}
 
+	// When the local config now matches the remote exactly, fingerprint it
+	// so auto-push doesn't send it back. Local changes merged in still need
+	// a push.
+	applied, err := m.buildLocalPayload()
+	if err != nil {
+		return fmt.Errorf("pull build local: %w", err)
+	}
+	hash := contentHash(applied)
+
 	// Update meta
 	m.mu.Lock()
 	m.meta.LastPullAt = time.Now().UTC()
 	m.updateMetaTimestamps(merged)
+	m.meta.KeyVersion = remote.KeyVersion
+	if hash == contentHash(remote) {
+		m.meta.LastSyncedHash = hash
+	}
 	m.mu.Unlock()
 
 	return m.meta.Save()
@@ -125,19 +169,55 @@ func (m *SyncManager) Pull(ctx context.Context) error {
 
 // Push builds local payload, merges with remote, encrypts, and uploads.
 func (m *SyncManager) Push(ctx context.Context) error {
-	m.mu.Lock()
-	if m.isPulling {
-		m.mu.Unlock()
+	if m.IsPulling() {
 		return nil // skip push triggered by pull's config save
 	}
-	m.mu.Unlock()
 
 	// Build local payload
 	local, err := m.buildLocalPayload()
 	if err != nil {
-		return fmt.Errorf("push build local: %w", err)
+		err = fmt.Errorf("push build local: %w", err)
+		m.recordPush(PushStatusFailed, err)
+		return err
+	}
+	return m.pushPayload(ctx, local)
+}
+
+// PushIfChanged pushes the local config unless it is unchanged since the
+// last pull or push. Auto-push uses it, so a change that arrived with a pull
+// is never pushed straight back. It reports whether a push was made.
+func (m *SyncManager) PushIfChanged(ctx context.Context) (bool, error) {
+	if m.IsPulling() {
+		return false, nil // the pull's own saves
+	}
+	local, err := m.buildLocalPayload()
+	if err != nil {
+		err = fmt.Errorf("push build local: %w", err)
+		m.recordPush(PushStatusFailed, err)
+		return false, err
 	}
+	m.mu.Lock()
+	unchanged := m.meta.LastSyncedHash != "" && m.meta.LastSyncedHash == contentHash(local)
+	m.mu.Unlock()
+	if unchanged {
+		m.recordPush(PushStatusSkipped, nil)
+		return false, nil
+	}
+	return true, m.pushPayload(ctx, local)
+}
 
+// pushPayload merges local with the remote payload and uploads the result,
+// recording the outcome.
+func (m *SyncManager) pushPayload(ctx context.Context, local *SyncPayload) error {
+	if err := m.upload(ctx, local); err != nil {
+		m.recordPush(PushStatusFailed, err)
+		return err
+	}
+	m.recordPush(PushStatusOK, nil)
+	return nil
+}
+
+func (m *SyncManager) upload(ctx context.Context, local *SyncPayload) error {
 	// Download remote for merge
 	remoteData, err := m.backend.Download(ctx)
 	if err != nil {
@@ -168,11 +248,38 @@ func (m *SyncManager) Push(ctx context.Context) error {
 	m.mu.Lock()
 	m.meta.LastPushAt = time.Now().UTC()
 	m.updateMetaTimestamps(merged)
+	m.meta.LastSyncedHash = contentHash(local)
+	m.meta.KeyVersion = merged.KeyVersion
 	m.mu.Unlock()
 
 	return m.meta.Save()
 }
 
+// contentHash fingerprints the synced content of a payload: provider and
+// profile configs and the default profile, ignoring timestamps.
+func contentHash(p *SyncPayload) string {
+	content := struct {
+		Providers      map[string]json.RawMessage `json:"providers"`
+		Profiles       map[string]json.RawMessage `json:"profiles"`
+		DefaultProfile string                     `json:"default_profile"`
+	}{
+		Providers: make(map[string]json.RawMessage, len(p.Providers)),
+		Profiles:  make(map[string]json.RawMessage, len(p.Profiles)),
+	}
+	for name, ent := range p.Providers {
+		content.Providers[name] = ent.Config
+	}
+	for name, ent := range p.Profiles {
+		content.Profiles[name] = ent.Config
+	}
+	if p.DefaultProfile != nil {
+		content.DefaultProfile = p.DefaultProfile.Value
+	}
+	data, _ := json.Marshal(content)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
 // buildLocalPayload creates a SyncPayload from the current local config and meta.
 func (m *SyncManager) buildLocalPayload() (*SyncPayload, error) {
 	store := config.DefaultStore()
@@ -318,7 +425,12 @@ func (m *SyncManager) updateMetaTimestamps(payload *SyncPayload) {
 
 // encryptPayload encrypts provider tokens in the payload and marshals to JSON.
 func (m *SyncManager) encryptPayload(payload *SyncPayload) ([]byte, error) {
-	if m.cfg.Passphrase == "" {
+	return encryptPayload(payload, m.cfg.Passphrase)
+}
+
+func encryptPayload(payload *SyncPayload, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		payload.Salt, payload.KeyCheck = "", ""
 		return json.MarshalIndent(payload, "", "  ")
 	}
 
@@ -339,7 +451,16 @@ func (m *SyncManager) encryptPayload(payload *SyncPayload) ([]byte, error) {
 		}
 	}
 	payload.Salt = base64.StdEncoding.EncodeToString(salt)
-	key := DeriveKey(m.cfg.Passphrase, salt)
+	key := DeriveKey(passphrase, salt)
+
+	check, err := NewKeyCheck(key)
+	if err != nil {
+		return nil, err
+	}
+	payload.KeyCheck = check
+	if payload.KeyVersion == 0 {
+		payload.KeyVersion = 1
+	}
 
 	// Encrypt each provider's config (contains auth tokens)
 	for name, ent := range payload.Providers {
@@ -356,11 +477,13 @@ func (m *SyncManager) encryptPayload(payload *SyncPayload) ([]byte, error) {
 	return json.MarshalIndent(payload, "", "  ")
 }
 
-// decryptPayload parses JSON and decrypts provider tokens.
+// decryptPayload parses JSON and decrypts provider tokens. It fails with
+// ErrWrongPassphrase, ErrPassphraseRequired or ErrCorruptPayload so callers
+// can tell the user which one to fix.
 func (m *SyncManager) decryptPayload(data []byte) (*SyncPayload, error) {
 	var payload SyncPayload
 	if err := json.Unmarshal(data, &payload); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %v", ErrCorruptPayload, err)
 	}
 	if payload.Providers == nil {
 		payload.Providers = make(map[string]*SyncEntity)
@@ -372,16 +495,29 @@ func (m *SyncManager) decryptPayload(data []byte) (*SyncPayload, error) {
 		payload.Tombstones = make(map[string]*Tombstone)
 	}
 
-	if m.cfg.Passphrase == "" || payload.Salt == "" {
+	if payload.Salt == "" {
 		return &payload, nil
 	}
+	if m.cfg.Passphrase == "" {
+		return nil, ErrPassphraseRequired
+	}
 
 	salt, err := base64.StdEncoding.DecodeString(payload.Salt)
 	if err != nil {
-		return nil, fmt.Errorf("decode salt: %w", err)
+		return nil, fmt.Errorf("%w: decode salt: %v", ErrCorruptPayload, err)
 	}
 	key := DeriveKey(m.cfg.Passphrase, salt)
 
+	// Payloads written before key checks were added are verified by their
+	// first provider instead.
+	verified := false
+	if payload.KeyCheck != "" {
+		if err := VerifyKey(payload.KeyCheck, key); err != nil {
+			return nil, m.passphraseError(&payload, err)
+		}
+		verified = true
+	}
+
 	// Decrypt each provider's config
 	for name, ent := range payload.Providers {
 		// Config is stored as a JSON string (quoted encrypted base64)
@@ -391,15 +527,101 @@ func (m *SyncManager) decryptPayload(data []byte) (*SyncPayload, error) {
 			continue
 		}
 		decrypted, err := Decrypt(encrypted, key)
+		if errors.Is(err, errAuthFailed) {
+			if !verified {
+				return nil, m.passphraseError(&payload, ErrWrongPassphrase)
+			}
+			err = ErrCorruptPayload
+		}
 		if err != nil {
 			return nil, fmt.Errorf("decrypt provider %s: %w", name, err)
 		}
 		ent.Config = decrypted
+		verified = true
 	}
 
 	return &payload, nil
 }
 
+// passphraseError explains a wrong passphrase when the remote key has been
+// rotated since this device last synced.
+func (m *SyncManager) passphraseError(payload *SyncPayload, err error) error {
+	if !errors.Is(err, ErrWrongPassphrase) {
+		return err
+	}
+	m.mu.Lock()
+	known := m.meta.KeyVersion
+	m.mu.Unlock()
+	if known > 0 && payload.KeyVersion > known {
+		return fmt.Errorf("%w: the passphrase was rotated on device %s (key version %d); set the new passphrase", err, payload.DeviceID, payload.KeyVersion)
+	}
+	return err
+}
+
+// RotatePassphrase re-encrypts the remote payload with a new passphrase and a
+// new salt, bumps its key version and saves the new passphrase in the local
+// sync config. The current passphrase must decrypt the remote payload. An
+// empty passphrase stores the payload unencrypted. Other devices must be
+// given the new passphrase before they can sync again.
+func (m *SyncManager) RotatePassphrase(ctx context.Context, passphrase string) error {
+	m.mu.Lock()
+	if m.isPulling {
+		m.mu.Unlock()
+		return fmt.Errorf("rotate: a pull is in progress")
+	}
+	m.isPulling = true // keep auto-push away until the config is saved
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		m.isPulling = false
+		m.mu.Unlock()
+	}()
+
+	local, err := m.buildLocalPayload()
+	if err != nil {
+		return fmt.Errorf("rotate build local: %w", err)
+	}
+	remoteData, err := m.backend.Download(ctx)
+	if err != nil {
+		return fmt.Errorf("rotate download: %w", err)
+	}
+	merged := local
+	if remoteData != nil {
+		remote, err := m.decryptPayload(remoteData)
+		if err != nil {
+			return fmt.Errorf("rotate decrypt: %w", err)
+		}
+		merged = Merge(local, remote)
+	}
+	merged.KeyVersion++
+	merged.Salt = "" // new key, new salt
+
+	data, err := encryptPayload(merged, passphrase)
+	if err != nil {
+		return fmt.Errorf("rotate encrypt: %w", err)
+	}
+	if err := m.backend.Upload(ctx, data); err != nil {
+		return fmt.Errorf("rotate upload: %w", err)
+	}
+
+	m.mu.Lock()
+	m.cfg.Passphrase = passphrase
+	m.meta.LastPushAt = time.Now().UTC()
+	m.updateMetaTimestamps(merged)
+	m.meta.LastSyncedHash = contentHash(local)
+	m.meta.KeyVersion = merged.KeyVersion
+	m.mu.Unlock()
+	if err := m.meta.Save(); err != nil {
+		return err
+	}
+
+	rotated := *m.cfg
+	if err := config.SetSyncConfig(&rotated); err != nil {
+		return fmt.Errorf("remote payload was re-encrypted but the new passphrase could not be saved: %w", err)
+	}
+	return nil
+}
+
 // generateDeviceID creates a short random device identifier.
 func generateDeviceID() string {
 	b := make([]byte, 4)
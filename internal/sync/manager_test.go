@@ -3,6 +3,8 @@ package sync
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"sync"
@@ -558,3 +560,176 @@ func TestSyncExcludesProxyURL(t *testing.T) {
 		t.Errorf("BaseURL = %q, want %q (should be updated from remote)", updated.BaseURL, "https://api.updated.com")
 	}
 }
+
+func TestManagerPushIfChanged(t *testing.T) {
+	mgr, mock := newTestManager(t, "")
+
+	store := config.DefaultStore()
+	store.SetProvider("auto-prov", &config.ProviderConfig{
+		BaseURL:   "https://auto.example.com",
+		AuthToken: "sk-auto",
+	})
+
+	ctx := context.Background()
+	pushed, err := mgr.PushIfChanged(ctx)
+	if err != nil {
+		t.Fatalf("PushIfChanged failed: %v", err)
+	}
+	if !pushed || mock.data == nil {
+		t.Fatal("first PushIfChanged should upload")
+	}
+	if st := mgr.Status(); st.LastPushStatus != PushStatusOK {
+		t.Fatalf("expected last push status %q, got %q", PushStatusOK, st.LastPushStatus)
+	}
+
+	// Nothing changed since the push
+	pushed, err = mgr.PushIfChanged(ctx)
+	if err != nil {
+		t.Fatalf("PushIfChanged failed: %v", err)
+	}
+	if pushed {
+		t.Fatal("unchanged config should not be pushed again")
+	}
+	if st := mgr.Status(); st.LastPushStatus != PushStatusSkipped {
+		t.Fatalf("expected last push status %q, got %q", PushStatusSkipped, st.LastPushStatus)
+	}
+
+	// A local change is pushed
+	store.SetProvider("auto-prov", &config.ProviderConfig{
+		BaseURL:   "https://auto.example.com",
+		AuthToken: "sk-changed",
+	})
+	if pushed, _ = mgr.PushIfChanged(ctx); !pushed {
+		t.Fatal("changed config should be pushed")
+	}
+}
+
+func TestManagerPushIfChangedAfterPull(t *testing.T) {
+	mgr1, mock := newTestManager(t, "")
+	config.DefaultStore().SetProvider("shared-prov", &config.ProviderConfig{
+		BaseURL:   "https://shared.example.com",
+		AuthToken: "sk-shared",
+	})
+	ctx := context.Background()
+	if err := mgr1.Push(ctx); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+
+	// Another device pulls; what it just pulled must not be pushed back
+	home2 := t.TempDir()
+	t.Setenv("HOME", home2)
+	config.ResetDefaultStore()
+	os.MkdirAll(filepath.Join(home2, ".zen"), 0755)
+	mgr2 := &SyncManager{
+		backend: mock,
+		cfg:     &config.SyncConfig{Backend: "mock"},
+		meta:    NewSyncMeta("device-2"),
+	}
+	if err := mgr2.Pull(ctx); err != nil {
+		t.Fatalf("Pull failed: %v", err)
+	}
+	pushed, err := mgr2.PushIfChanged(ctx)
+	if err != nil {
+		t.Fatalf("PushIfChanged failed: %v", err)
+	}
+	if pushed {
+		t.Fatal("config just pulled should not be pushed back")
+	}
+}
+
+func TestManagerDecryptErrors(t *testing.T) {
+	mgr, _ := newTestManager(t, "right-passphrase")
+
+	payload := NewSyncPayload("device-1")
+	provCfg, _ := json.Marshal(&config.ProviderConfig{AuthToken: "sk-secret"})
+	payload.Providers["p1"] = &SyncEntity{ModifiedAt: time.Now().UTC(), Config: provCfg}
+	data, err := mgr.encryptPayload(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wrong := &SyncManager{cfg: &config.SyncConfig{Passphrase: "wrong-passphrase"}, meta: NewSyncMeta("device-2")}
+	if _, err := wrong.decryptPayload(data); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("expected ErrWrongPassphrase, got %v", err)
+	}
+
+	none := &SyncManager{cfg: &config.SyncConfig{}, meta: NewSyncMeta("device-2")}
+	if _, err := none.decryptPayload(data); !errors.Is(err, ErrPassphraseRequired) {
+		t.Fatalf("expected ErrPassphraseRequired, got %v", err)
+	}
+
+	if _, err := mgr.decryptPayload([]byte("{not json")); !errors.Is(err, ErrCorruptPayload) {
+		t.Fatalf("expected ErrCorruptPayload for bad JSON, got %v", err)
+	}
+
+	// Damage a provider's ciphertext: the key check passes, so it's corrupt
+	var damaged SyncPayload
+	json.Unmarshal(data, &damaged)
+	other, _ := Encrypt([]byte("{}"), DeriveKey("unrelated", []byte("salt")))
+	damaged.Providers["p1"].Config = json.RawMessage(fmt.Sprintf("%q", other))
+	data2, _ := json.Marshal(&damaged)
+	if _, err := mgr.decryptPayload(data2); !errors.Is(err, ErrCorruptPayload) {
+		t.Fatalf("expected ErrCorruptPayload for damaged provider, got %v", err)
+	}
+}
+
+func TestManagerRotatePassphrase(t *testing.T) {
+	mgr, mock := newTestManager(t, "old-passphrase")
+	store := config.DefaultStore()
+	store.SetProvider("rot-prov", &config.ProviderConfig{
+		BaseURL:   "https://rot.example.com",
+		AuthToken: "sk-rot",
+	})
+	store.SetSyncConfig(mgr.cfg)
+
+	ctx := context.Background()
+	if err := mgr.Push(ctx); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+	var before SyncPayload
+	json.Unmarshal(mock.data, &before)
+	if before.KeyVersion != 1 || before.KeyCheck == "" {
+		t.Fatalf("expected key version 1 with a key check, got %d %q", before.KeyVersion, before.KeyCheck)
+	}
+
+	if err := mgr.RotatePassphrase(ctx, "new-passphrase"); err != nil {
+		t.Fatalf("RotatePassphrase failed: %v", err)
+	}
+	var after SyncPayload
+	json.Unmarshal(mock.data, &after)
+	if after.KeyVersion != 2 {
+		t.Fatalf("expected key version 2, got %d", after.KeyVersion)
+	}
+	if after.Salt == before.Salt {
+		t.Fatal("rotation should use a new salt")
+	}
+	if got := config.GetSyncConfig().Passphrase; got != "new-passphrase" {
+		t.Fatalf("expected saved passphrase new-passphrase, got %q", got)
+	}
+	if st := mgr.Status(); st.KeyVersion != 2 {
+		t.Fatalf("expected status key version 2, got %d", st.KeyVersion)
+	}
+
+	// The new passphrase decrypts the remote payload
+	decrypted, err := mgr.decryptPayload(mock.data)
+	if err != nil {
+		t.Fatalf("decrypt with new passphrase: %v", err)
+	}
+	var got config.ProviderConfig
+	json.Unmarshal(decrypted.Providers["rot-prov"].Config, &got)
+	if got.AuthToken != "sk-rot" {
+		t.Fatalf("expected sk-rot, got %s", got.AuthToken)
+	}
+
+	// A device still on the old passphrase is told the key was rotated
+	stale := &SyncManager{
+		backend: mock,
+		cfg:     &config.SyncConfig{Backend: "mock", Passphrase: "old-passphrase"},
+		meta:    NewSyncMeta("device-2"),
+	}
+	stale.meta.KeyVersion = 1
+	err = stale.Pull(ctx)
+	if !errors.Is(err, ErrWrongPassphrase) || !contains(err.Error(), "rotated") {
+		t.Fatalf("expected rotated passphrase error, got %v", err)
+	}
+}
@@ -19,6 +19,7 @@ func Merge(local, remote *SyncPayload) *SyncPayload {
 		UpdatedAt:     time.Now().UTC(),
 		DeviceID:      local.DeviceID,
 		Salt:          local.Salt,
+		KeyVersion:    remote.KeyVersion,
 		Providers:     make(map[string]*SyncEntity),
 		Profiles:      make(map[string]*SyncEntity),
 		Tombstones:    make(map[string]*Tombstone),
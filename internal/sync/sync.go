@@ -21,6 +21,8 @@ type SyncPayload struct {
 	UpdatedAt      time.Time                          `json:"updated_at"`
 	DeviceID       string                             `json:"device_id"`
 	Salt           string                             `json:"salt"`
+	KeyVersion     int                                `json:"key_version,omitempty"` // bumped by each passphrase rotation
+	KeyCheck       string                             `json:"key_check,omitempty"`   // verifies the passphrase before decrypting
 	Providers      map[string]*SyncEntity             `json:"providers"`
 	Profiles       map[string]*SyncEntity             `json:"profiles"`
 	DefaultProfile *SyncScalar                        `json:"default_profile,omitempty"`
@@ -54,15 +56,37 @@ type SyncMeta struct {
 	Profiles       map[string]time.Time  `json:"profiles"`
 	DefaultProfile time.Time             `json:"default_profile,omitempty"`
 	Tombstones     map[string]*Tombstone `json:"tombstones,omitempty"`
+	// LastSyncedHash fingerprints the local config as of the last pull or
+	// push, so auto-push skips configs that are already in sync.
+	LastSyncedHash string `json:"last_synced_hash,omitempty"`
+	LastPushStatus string `json:"last_push_status,omitempty"`
+	LastPushError  string `json:"last_push_error,omitempty"`
+	// KeyVersion is the key version of the remote payload as of the last
+	// pull or push.
+	KeyVersion int `json:"key_version,omitempty"`
 }
 
+// Push outcomes reported in SyncStatus.LastPushStatus.
+const (
+	PushStatusOK      = "ok"
+	PushStatusFailed  = "failed"
+	PushStatusSkipped = "skipped" // nothing changed since the last pull or push
+)
+
 // SyncStatus is returned by the status API.
 type SyncStatus struct {
-	Configured bool      `json:"configured"`
-	Backend    string    `json:"backend,omitempty"`
-	DeviceID   string    `json:"device_id,omitempty"`
-	LastPullAt time.Time `json:"last_pull_at,omitempty"`
-	LastPushAt time.Time `json:"last_push_at,omitempty"`
+	Configured     bool      `json:"configured"`
+	Backend        string    `json:"backend,omitempty"`
+	DeviceID       string    `json:"device_id,omitempty"`
+	LastPullAt     time.Time `json:"last_pull_at,omitempty"`
+	LastPushAt     time.Time `json:"last_push_at,omitempty"`
+	AutoPull       bool      `json:"auto_pull"`
+	AutoPush       bool      `json:"auto_push"`
+	PushPending    bool      `json:"push_pending"`               // an auto-push is waiting out its debounce window
+	LastPushStatus string    `json:"last_push_status,omitempty"` // outcome of the last push attempt
+	LastPushError  string    `json:"last_push_error,omitempty"`
+	Encrypted      bool      `json:"encrypted"`
+	KeyVersion     int       `json:"key_version,omitempty"`
 }
 
 // NewSyncPayload creates an empty payload with initialized maps.
@@ -0,0 +1,158 @@
+package sync
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// TeamPayload is a team layer as stored on the team's backend. Provider
+// configs are encrypted like a sync payload's when a passphrase is set.
+type TeamPayload struct {
+	FormatVersion int                              `json:"format_version"`
+	UpdatedAt     time.Time                        `json:"updated_at"`
+	UpdatedBy     string                           `json:"updated_by,omitempty"`
+	Salt          string                           `json:"salt,omitempty"`
+	KeyCheck      string                           `json:"key_check,omitempty"`
+	Providers     map[string]json.RawMessage       `json:"providers,omitempty"`
+	Profiles      map[string]*config.ProfileConfig `json:"profiles,omitempty"`
+	Budgets       *config.BudgetConfig             `json:"budgets,omitempty"`
+}
+
+// PullTeam downloads the team layer from the team backend and caches it in
+// team.json, where the config store picks it up.
+func PullTeam(ctx context.Context, cfg *config.SyncConfig) (*config.TeamLayer, error) {
+	backend, err := NewBackend(cfg)
+	if err != nil {
+		return nil, err
+	}
+	data, err := backend.Download(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("team download: %w", err)
+	}
+	if data == nil {
+		return nil, fmt.Errorf("no team config has been pushed to %s yet", backend.Name())
+	}
+	layer, err := decodeTeamPayload(data, cfg.Passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("team decrypt: %w", err)
+	}
+	if err := config.DefaultStore().SetTeamLayer(layer); err != nil {
+		return nil, fmt.Errorf("team apply: %w", err)
+	}
+	return layer, nil
+}
+
+// PushTeam uploads a team layer to the team backend, replacing what is
+// there. Team members get it on their next pull.
+func PushTeam(ctx context.Context, cfg *config.SyncConfig, layer *config.TeamLayer) error {
+	backend, err := NewBackend(cfg)
+	if err != nil {
+		return err
+	}
+	if layer.UpdatedBy == "" {
+		layer.UpdatedBy, _ = os.Hostname()
+	}
+	data, err := encodeTeamPayload(layer, cfg.Passphrase)
+	if err != nil {
+		return fmt.Errorf("team encrypt: %w", err)
+	}
+	if err := backend.Upload(ctx, data); err != nil {
+		return fmt.Errorf("team upload: %w", err)
+	}
+	return nil
+}
+
+func encodeTeamPayload(layer *config.TeamLayer, passphrase string) ([]byte, error) {
+	payload := &TeamPayload{
+		FormatVersion: FormatVersion,
+		UpdatedAt:     layer.UpdatedAt,
+		UpdatedBy:     layer.UpdatedBy,
+		Providers:     make(map[string]json.RawMessage, len(layer.Providers)),
+		Profiles:      layer.Profiles,
+		Budgets:       layer.Budgets,
+	}
+	var key []byte
+	if passphrase != "" {
+		salt, err := GenerateSalt()
+		if err != nil {
+			return nil, err
+		}
+		payload.Salt = base64.StdEncoding.EncodeToString(salt)
+		key = DeriveKey(passphrase, salt)
+		if payload.KeyCheck, err = NewKeyCheck(key); err != nil {
+			return nil, err
+		}
+	}
+	for name, p := range layer.Providers {
+		raw, err := json.Marshal(p)
+		if err != nil {
+			return nil, err
+		}
+		if key != nil {
+			encrypted, err := Encrypt(raw, key)
+			if err != nil {
+				return nil, fmt.Errorf("encrypt provider %s: %w", name, err)
+			}
+			raw = json.RawMessage(fmt.Sprintf("%q", encrypted))
+		}
+		payload.Providers[name] = raw
+	}
+	return json.MarshalIndent(payload, "", "  ")
+}
+
+func decodeTeamPayload(data []byte, passphrase string) (*config.TeamLayer, error) {
+	var payload TeamPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptPayload, err)
+	}
+	var key []byte
+	if payload.Salt != "" {
+		if passphrase == "" {
+			return nil, ErrPassphraseRequired
+		}
+		salt, err := base64.StdEncoding.DecodeString(payload.Salt)
+		if err != nil {
+			return nil, fmt.Errorf("%w: decode salt: %v", ErrCorruptPayload, err)
+		}
+		key = DeriveKey(passphrase, salt)
+		if err := VerifyKey(payload.KeyCheck, key); err != nil {
+			return nil, err
+		}
+	}
+	layer := &config.TeamLayer{
+		UpdatedAt: payload.UpdatedAt,
+		UpdatedBy: payload.UpdatedBy,
+		Providers: make(map[string]*config.ProviderConfig, len(payload.Providers)),
+		Profiles:  payload.Profiles,
+		Budgets:   payload.Budgets,
+	}
+	for name, raw := range payload.Providers {
+		if key != nil {
+			var encrypted string
+			if err := json.Unmarshal(raw, &encrypted); err != nil {
+				return nil, fmt.Errorf("%w: provider %s is not encrypted", ErrCorruptPayload, name)
+			}
+			decrypted, err := Decrypt(encrypted, key)
+			if errors.Is(err, errAuthFailed) {
+				err = ErrCorruptPayload
+			}
+			if err != nil {
+				return nil, fmt.Errorf("decrypt provider %s: %w", name, err)
+			}
+			raw = decrypted
+		}
+		var p config.ProviderConfig
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return nil, fmt.Errorf("%w: provider %s: %v", ErrCorruptPayload, name, err)
+		}
+		layer.Providers[name] = &p
+	}
+	return layer, nil
+}
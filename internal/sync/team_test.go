@@ -0,0 +1,49 @@
+package sync
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+func TestTeamPayloadRoundTrip(t *testing.T) {
+	layer := &config.TeamLayer{
+		UpdatedBy: "lead",
+		Providers: map[string]*config.ProviderConfig{
+			"team-api": {BaseURL: "https://team.example.com", AuthToken: "sk-team"},
+		},
+		Profiles: map[string]*config.ProfileConfig{"team": {Providers: []string{"team-api"}}},
+	}
+
+	data, err := encodeTeamPayload(layer, "team-passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "sk-team") {
+		t.Fatal("provider token should be encrypted")
+	}
+	got, err := decodeTeamPayload(data, "team-passphrase")
+	if err != nil {
+		t.Fatalf("decodeTeamPayload failed: %v", err)
+	}
+	if p := got.Providers["team-api"]; p == nil || p.AuthToken != "sk-team" || got.UpdatedBy != "lead" {
+		t.Fatalf("unexpected layer: %+v", got)
+	}
+	if _, err := decodeTeamPayload(data, "wrong"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("expected ErrWrongPassphrase, got %v", err)
+	}
+	if _, err := decodeTeamPayload(data, ""); !errors.Is(err, ErrPassphraseRequired) {
+		t.Fatalf("expected ErrPassphraseRequired, got %v", err)
+	}
+
+	// Without a passphrase the payload is plain JSON
+	data, err = encodeTeamPayload(layer, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err = decodeTeamPayload(data, ""); err != nil || got.Providers["team-api"].AuthToken != "sk-team" {
+		t.Fatalf("plain round trip: %+v, %v", got, err)
+	}
+}
@@ -1,8 +1,12 @@
 package web
 
 import (
+	"errors"
 	"net/http"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/dopejs/gozen/internal/agent"
 	"github.com/dopejs/gozen/internal/config"
@@ -102,6 +106,57 @@ func (s *Server) handleAgentSessions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if len(parts) > 1 && parts[1] == "report" {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		// A session still monitored is reported as it stands; one gone
+		// since, from the report stored when it ended
+		if report := agent.ReportSession(sessionID); report != nil {
+			writeJSON(w, http.StatusOK, report)
+			return
+		}
+		report, err := agent.LoadSessionReport(sessionID)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			writeError(w, http.StatusNotFound, "no report for session")
+		case err != nil:
+			writeError(w, http.StatusInternalServerError, err.Error())
+		default:
+			writeJSON(w, http.StatusOK, report)
+		}
+		return
+	}
+
+	if len(parts) > 1 && parts[1] == "timeline" {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		var after, limit int
+		if v := r.URL.Query().Get("after"); v != "" {
+			if n, err := strconv.Atoi(v); err == nil && n > 0 {
+				after = n
+			}
+		}
+		if v := r.URL.Query().Get("limit"); v != "" {
+			if n, err := strconv.Atoi(v); err == nil && n > 0 {
+				limit = n
+			}
+		}
+		events, ok := obs.GetTimeline(sessionID, after, limit)
+		if !ok {
+			writeError(w, http.StatusNotFound, "session not found")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"session_id": sessionID,
+			"events":     events,
+		})
+		return
+	}
+
 	// Get session details
 	if r.Method != http.MethodGet {
 		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -125,15 +180,68 @@ func (s *Server) handleAgentLocks(w http.ResponseWriter, r *http.Request) {
 
 	path := strings.TrimPrefix(r.URL.Path, "/api/v1/agent/locks")
 	if path == "" || path == "/" {
-		// List all locks
-		if r.Method != http.MethodGet {
+		switch r.Method {
+		case http.MethodGet:
+			// List all locks, queued requests and recent deadlocks
+			locks := coord.GetAllLocks()
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"locks":     locks,
+				"waiting":   coord.GetWaiting(),
+				"deadlocks": coord.GetDeadlocks(),
+			})
+
+		case http.MethodPost:
+			// Acquire a lock on a file, directory ("dir/") or glob ("src/**")
+			var req struct {
+				Path      string `json:"path"`
+				SessionID string `json:"session_id"`
+				TaskID    string `json:"task_id"`
+				Reason    string `json:"reason"`
+				WaitSec   *int   `json:"wait_sec"` // queue this long if taken; default agent.coordinator.lock_wait_sec
+			}
+			if err := readJSON(r, &req); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid request body")
+				return
+			}
+			if req.SessionID == "" {
+				writeError(w, http.StatusBadRequest, "session_id required")
+				return
+			}
+			if !coord.IsEnabled() {
+				writeError(w, http.StatusServiceUnavailable, "coordinator is disabled")
+				return
+			}
+			wait := coord.LockWait()
+			if req.WaitSec != nil {
+				wait = time.Duration(*req.WaitSec) * time.Second
+			}
+			lock, err := coord.AcquireWait(r.Context(), req.Path, req.SessionID, agent.LockOptions{TaskID: req.TaskID, Reason: req.Reason}, wait)
+			var conflict *agent.LockConflictError
+			var deadlock *agent.DeadlockError
+			switch {
+			case errors.As(err, &conflict):
+				writeJSON(w, http.StatusConflict, map[string]interface{}{
+					"error": err.Error(),
+					"held":  conflict.Held,
+				})
+			case errors.As(err, &deadlock):
+				writeJSON(w, http.StatusConflict, map[string]interface{}{
+					"error":    err.Error(),
+					"deadlock": deadlock.Deadlock,
+				})
+			case errors.Is(err, agent.ErrLockWaitTimeout):
+				writeError(w, http.StatusConflict, err.Error())
+			case r.Context().Err() != nil:
+				return
+			case err != nil:
+				writeError(w, http.StatusBadRequest, err.Error())
+			default:
+				writeJSON(w, http.StatusOK, lock)
+			}
+
+		default:
 			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
-			return
 		}
-		locks := coord.GetAllLocks()
-		writeJSON(w, http.StatusOK, map[string]interface{}{
-			"locks": locks,
-		})
 		return
 	}
 
@@ -158,24 +266,94 @@ func (s *Server) handleAgentLocks(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// handleAgentChanges handles file change history.
+// handleAgentChanges handles file change history and review: listing
+// changes with their diffs, approving a session's changes and reverting one.
 func (s *Server) handleAgentChanges(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
-		return
-	}
-
 	coord := agent.GetGlobalCoordinator()
 	if coord == nil {
 		writeError(w, http.StatusServiceUnavailable, "coordinator not initialized")
 		return
 	}
 
-	limit := 50
-	changes := coord.GetRecentChanges(limit)
-	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"changes": changes,
-	})
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/agent/changes"), "/")
+	if path == "" {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		q := r.URL.Query()
+		limit := 50
+		if v := q.Get("limit"); v != "" {
+			if n, err := strconv.Atoi(v); err == nil && n > 0 {
+				limit = n
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"changes": coord.ListChanges(q.Get("session_id"), q.Get("status"), limit),
+		})
+		return
+	}
+
+	if path == "approve" {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		var req struct {
+			SessionID string   `json:"session_id"`
+			IDs       []string `json:"ids"`
+		}
+		if err := readJSON(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON")
+			return
+		}
+		if req.SessionID == "" {
+			writeError(w, http.StatusBadRequest, "session_id is required")
+			return
+		}
+		approved := coord.ApproveChanges(req.SessionID, req.IDs)
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"approved": len(approved),
+			"changes":  approved,
+		})
+		return
+	}
+
+	parts := strings.Split(path, "/")
+	id := parts[0]
+	if len(parts) > 1 && parts[1] == "revert" {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		change, err := coord.RevertChange(id)
+		switch {
+		case errors.Is(err, agent.ErrChangeNotFound):
+			writeError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, agent.ErrChangeReverted), errors.Is(err, agent.ErrNotRevertable), errors.Is(err, agent.ErrRevertConflict):
+			writeError(w, http.StatusConflict, err.Error())
+		case err != nil:
+			writeError(w, http.StatusInternalServerError, err.Error())
+		default:
+			writeJSON(w, http.StatusOK, change)
+		}
+		return
+	}
+	if len(parts) > 1 {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	change := coord.GetChange(id)
+	if change == nil {
+		writeError(w, http.StatusNotFound, "change not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, change)
 }
 
 // handleAgentTasks handles task queue operations.
@@ -285,12 +463,17 @@ func (s *Server) handleAgentRuntime(w http.ResponseWriter, r *http.Request) {
 		}
 		var req struct {
 			Description string `json:"description"`
+			ProjectPath string `json:"project_path"` // run tool calls here, through the project's sandbox
+			MultiAgent  bool   `json:"multi_agent"`  // run with planner, implementer and reviewer sub-agents
 		}
 		if err := readJSON(r, &req); err != nil {
 			writeError(w, http.StatusBadRequest, "invalid request body")
 			return
 		}
-		task, err := rt.StartTask(req.Description)
+		task, err := rt.StartTaskWithOptions(req.Description, agent.RuntimeTaskOptions{
+			ProjectPath: req.ProjectPath,
+			MultiAgent:  req.MultiAgent,
+		})
 		if err != nil {
 			writeError(w, http.StatusBadRequest, err.Error())
 			return
@@ -378,6 +561,18 @@ func (s *Server) handleAgentGuardrails(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// GET /api/v1/agent/guardrails/policy - Get policy rules with hit counts
+	if path == "/policy" {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"rules": gr.GetPolicyStats(),
+		})
+		return
+	}
+
 	// GET /api/v1/agent/guardrails - Get config
 	if path == "" || path == "/" {
 		if r.Method != http.MethodGet {
@@ -406,6 +601,12 @@ func (s *Server) handleAgentStats(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	writeJSON(w, http.StatusOK, agentStats())
+}
+
+// agentStats collects the agent observatory, task queue and guardrail
+// statistics.
+func agentStats() AgentStatsResponse {
 	resp := AgentStatsResponse{
 		Observatory: make(map[string]interface{}),
 		TaskQueue:   make(map[string]int),
@@ -427,5 +628,5 @@ func (s *Server) handleAgentStats(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	writeJSON(w, http.StatusOK, resp)
+	return resp
 }
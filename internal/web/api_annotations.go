@@ -0,0 +1,107 @@
+package web
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dopejs/gozen/internal/proxy"
+)
+
+// annotationPatch is the body of PATCH /api/v1/sessions/{id} and
+// PATCH /api/v1/logs/{id}. Fields left out keep their current value; an
+// annotation left with no labels, note or bookmark is removed.
+type annotationPatch struct {
+	Labels     *[]string `json:"labels"`
+	Note       *string   `json:"note"`
+	Bookmarked *bool     `json:"bookmarked"`
+}
+
+// annotationFilterFromQuery reads the label and bookmarked query parameters.
+func annotationFilterFromQuery(query url.Values) proxy.AnnotationFilter {
+	return proxy.AnnotationFilter{
+		Label:      strings.TrimSpace(query.Get("label")),
+		Bookmarked: query.Get("bookmarked") == "true",
+	}
+}
+
+// patchAnnotation applies a PATCH body to the stored annotation of target
+// and writes back the result, or the annotation as null once it is empty.
+func (s *Server) patchAnnotation(w http.ResponseWriter, r *http.Request, db *proxy.LogDB, kind, target string) {
+	var patch annotationPatch
+	if err := readJSON(r, &patch); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+
+	a, err := db.GetAnnotation(kind, target)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if a == nil {
+		a = &proxy.Annotation{}
+	}
+	if patch.Labels != nil {
+		a.Labels = *patch.Labels
+	}
+	if patch.Note != nil {
+		a.Note = *patch.Note
+	}
+	if patch.Bookmarked != nil {
+		a.Bookmarked = *patch.Bookmarked
+	}
+	if err := a.Normalize(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	a.UpdatedAt = time.Now()
+	if err := db.SetAnnotation(kind, target, a); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if a.IsEmpty() {
+		a = nil
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"annotation": a})
+}
+
+// handleSessionAnnotation labels, notes or bookmarks a session. Sessions no
+// longer in the session cache can still be annotated.
+// PATCH /api/v1/sessions/{id}
+func (s *Server) handleSessionAnnotation(w http.ResponseWriter, r *http.Request, sessionID string) {
+	db := proxy.GetGlobalLogDB()
+	if db == nil {
+		writeError(w, http.StatusServiceUnavailable, "log database not available")
+		return
+	}
+	s.patchAnnotation(w, r, db, proxy.AnnotationSession, sessionID)
+}
+
+// handleLogAnnotation labels, notes or bookmarks a request log entry, by
+// its row ID in the log database (the id field of /api/v1/logs entries).
+// PATCH /api/v1/logs/{id}
+func (s *Server) handleLogAnnotation(w http.ResponseWriter, r *http.Request, id string) {
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || n <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid log entry ID")
+		return
+	}
+	db := proxy.GetGlobalLogDB()
+	if db == nil {
+		writeError(w, http.StatusServiceUnavailable, "log database not available")
+		return
+	}
+	exists, err := db.LogEntryExists(n)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !exists {
+		writeError(w, http.StatusNotFound, "log entry not found")
+		return
+	}
+	s.patchAnnotation(w, r, db, proxy.AnnotationLog, id)
+}
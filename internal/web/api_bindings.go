@@ -11,9 +11,14 @@ import (
 
 // bindingResponse is the JSON shape for a single project binding.
 type bindingResponse struct {
-	Path    string `json:"path"`
-	Profile string `json:"profile"`
-	Client  string `json:"client"`
+	Path        string                      `json:"path"`
+	Profile     string                      `json:"profile"`
+	Client      string                      `json:"client"`
+	Compression *config.CompressionOverride `json:"compression,omitempty"`
+	Sandbox     *config.SandboxConfig       `json:"sandbox,omitempty"`
+
+	ClaudeSettings *config.ClaudeSettingsConfig       `json:"claude_settings,omitempty"`
+	MCPServers     map[string]*config.MCPServerConfig `json:"mcp_servers,omitempty"`
 }
 
 // bindingsResponse is the JSON shape for listing all bindings.
@@ -25,9 +30,14 @@ type bindingsResponse struct {
 
 // bindingRequest is the JSON shape for creating/updating a binding.
 type bindingRequest struct {
-	Path    string `json:"path"`
-	Profile string `json:"profile"`
-	Client  string `json:"client"`
+	Path        string                      `json:"path"`
+	Profile     string                      `json:"profile"`
+	Client      string                      `json:"client"`
+	Compression *config.CompressionOverride `json:"compression,omitempty"`
+	Sandbox     *config.SandboxConfig       `json:"sandbox,omitempty"`
+
+	ClaudeSettings *config.ClaudeSettingsConfig       `json:"claude_settings,omitempty"`
+	MCPServers     map[string]*config.MCPServerConfig `json:"mcp_servers,omitempty"`
 }
 
 func (s *Server) handleBindings(w http.ResponseWriter, r *http.Request) {
@@ -76,9 +86,14 @@ func (s *Server) listBindings(w http.ResponseWriter, r *http.Request) {
 	bindings := make([]bindingResponse, 0, len(allBindings))
 	for path, b := range allBindings {
 		bindings = append(bindings, bindingResponse{
-			Path:    path,
-			Profile: b.Profile,
-			Client:  b.Client,
+			Path:        path,
+			Profile:     b.Profile,
+			Client:      b.Client,
+			Compression: b.Compression,
+			Sandbox:     b.Sandbox,
+
+			ClaudeSettings: b.ClaudeSettings,
+			MCPServers:     mcpServersFor(r, b.MCPServers),
 		})
 	}
 
@@ -98,12 +113,32 @@ func (s *Server) getBinding(w http.ResponseWriter, r *http.Request, path string)
 	}
 
 	writeJSON(w, http.StatusOK, bindingResponse{
-		Path:    path,
-		Profile: binding.Profile,
-		Client:  binding.Client,
+		Path:        path,
+		Profile:     binding.Profile,
+		Client:      binding.Client,
+		Compression: binding.Compression,
+		Sandbox:     binding.Sandbox,
+
+		ClaudeSettings: binding.ClaudeSettings,
+		MCPServers:     mcpServersFor(r, binding.MCPServers),
 	})
 }
 
+// mcpServersFor returns a binding's MCP servers as r may read them, with
+// literal environment values masked for readers who can't see secrets.
+func mcpServersFor(r *http.Request, servers map[string]*config.MCPServerConfig) map[string]*config.MCPServerConfig {
+	if servers == nil || showsSecrets(r) {
+		return servers
+	}
+	redacted := make(map[string]*config.MCPServerConfig, len(servers))
+	for name, server := range servers {
+		c := *server
+		c.Env = maskValues(server.Env)
+		redacted[name] = &c
+	}
+	return redacted
+}
+
 func (s *Server) createBinding(w http.ResponseWriter, r *http.Request) {
 	var req bindingRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -115,6 +150,10 @@ func (s *Server) createBinding(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "path is required")
 		return
 	}
+	if err := config.ValidateBindingKey(req.Path); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	store := config.DefaultStore()
 
@@ -133,11 +172,45 @@ func (s *Server) createBinding(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	if err := req.Sandbox.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	for name, server := range req.MCPServers {
+		if err := config.ValidateMCPServer(name, server); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
 
 	if err := store.BindProject(req.Path, req.Profile, req.Client); err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	if req.Compression != nil {
+		if err := store.SetProjectCompression(req.Path, req.Compression); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	if req.Sandbox != nil {
+		if err := store.SetProjectSandbox(req.Path, req.Sandbox); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	if req.ClaudeSettings != nil {
+		if err := store.SetProjectClaudeSettings(req.Path, req.ClaudeSettings); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	if len(req.MCPServers) > 0 {
+		if err := store.SetProjectMCPServers(req.Path, req.MCPServers); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
 
 	writeJSON(w, http.StatusCreated, bindingResponse(req))
 }
@@ -149,6 +222,11 @@ func (s *Server) updateBinding(w http.ResponseWriter, r *http.Request, path stri
 		return
 	}
 
+	if err := config.ValidateBindingKey(path); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	store := config.DefaultStore()
 
 	// Verify profile exists if specified
@@ -166,16 +244,47 @@ func (s *Server) updateBinding(w http.ResponseWriter, r *http.Request, path stri
 			return
 		}
 	}
+	if err := req.Sandbox.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	for name, server := range req.MCPServers {
+		if err := config.ValidateMCPServer(name, server); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
 
 	if err := store.BindProject(path, req.Profile, req.Client); err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	if err := store.SetProjectCompression(path, req.Compression); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := store.SetProjectSandbox(path, req.Sandbox); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := store.SetProjectClaudeSettings(path, req.ClaudeSettings); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := store.SetProjectMCPServers(path, req.MCPServers); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
 
 	writeJSON(w, http.StatusOK, bindingResponse{
-		Path:    path,
-		Profile: req.Profile,
-		Client:  req.Client,
+		Path:        path,
+		Profile:     req.Profile,
+		Client:      req.Client,
+		Compression: req.Compression,
+		Sandbox:     req.Sandbox,
+
+		ClaudeSettings: req.ClaudeSettings,
+		MCPServers:     req.MCPServers,
 	})
 }
 
@@ -16,6 +16,9 @@ type CompressionConfigResponse struct {
 	SummaryModel    string `json:"summary_model"`
 	PreserveRecent  int    `json:"preserve_recent"`
 	SummaryProvider string `json:"summary_provider"`
+
+	Strategy  string                  `json:"strategy"`
+	Retrieval *config.RetrievalConfig `json:"retrieval,omitempty"`
 }
 
 // CompressionStatsResponse is the API response for compression stats.
@@ -58,6 +61,14 @@ func (s *Server) handleGetCompression(w http.ResponseWriter, r *http.Request) {
 		SummaryModel:    cfg.SummaryModel,
 		PreserveRecent:  cfg.PreserveRecent,
 		SummaryProvider: cfg.SummaryProvider,
+		Strategy:        cfg.Strategy,
+	}
+	if cfg.Retrieval != nil {
+		retrieval := *cfg.Retrieval
+		if retrieval.EmbeddingAPIKey != "" {
+			retrieval.EmbeddingAPIKey = maskToken(retrieval.EmbeddingAPIKey)
+		}
+		resp.Retrieval = &retrieval
 	}
 
 	// Apply defaults for display
@@ -73,6 +84,9 @@ func (s *Server) handleGetCompression(w http.ResponseWriter, r *http.Request) {
 	if resp.PreserveRecent == 0 {
 		resp.PreserveRecent = proxy.DefaultPreserveRecent
 	}
+	if resp.Strategy == "" {
+		resp.Strategy = proxy.CompressionStrategySummarize
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
@@ -94,6 +108,22 @@ func (s *Server) handleSetCompression(w http.ResponseWriter, r *http.Request) {
 		SummaryModel:    req.SummaryModel,
 		PreserveRecent:  req.PreserveRecent,
 		SummaryProvider: req.SummaryProvider,
+		Strategy:        req.Strategy,
+		Retrieval:       req.Retrieval,
+	}
+	switch cfg.Strategy {
+	case "", proxy.CompressionStrategySummarize, proxy.CompressionStrategyRetrieval:
+	default:
+		http.Error(w, "unknown strategy: "+cfg.Strategy, http.StatusBadRequest)
+		return
+	}
+
+	// Preserve the existing embedding API key if the masked value is sent back
+	if existing := config.GetCompression(); existing != nil && existing.Retrieval != nil && cfg.Retrieval != nil {
+		key := existing.Retrieval.EmbeddingAPIKey
+		if cfg.Retrieval.EmbeddingAPIKey == maskToken(key) {
+			cfg.Retrieval.EmbeddingAPIKey = key
+		}
 	}
 
 	if err := config.SetCompression(cfg); err != nil {
@@ -131,3 +161,78 @@ func (s *Server) handleGetCompressionStats(w http.ResponseWriter, r *http.Reques
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
+
+// CompressionPreviewRequest is the request body for a compression dry run.
+type CompressionPreviewRequest struct {
+	SessionID string                    `json:"session_id,omitempty"` // preview the session's last captured request
+	Messages  []proxy.Message           `json:"messages,omitempty"`   // or these messages
+	Project   string                    `json:"project,omitempty"`    // apply this bound project's compression override
+	Config    *config.CompressionConfig `json:"config,omitempty"`     // settings to try; default: the saved settings
+}
+
+// handleCompressionPreview runs the compressor on a session's messages or on
+// the given messages without applying the result.
+// POST /api/v1/compression/preview
+func (s *Server) handleCompressionPreview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req CompressionPreviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	messages := req.Messages
+	if req.SessionID != "" {
+		db := proxy.GetGlobalLogDB()
+		if db == nil {
+			http.Error(w, "log database not available", http.StatusServiceUnavailable)
+			return
+		}
+		capture, err := db.LatestSessionCapture(req.SessionID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if capture == nil {
+			http.Error(w, "no captured request for session; enable body capture or send messages", http.StatusNotFound)
+			return
+		}
+		if capture.RequestTruncated {
+			http.Error(w, "captured request for session is truncated", http.StatusUnprocessableEntity)
+			return
+		}
+		var body struct {
+			Messages []proxy.Message `json:"messages"`
+		}
+		if err := json.Unmarshal([]byte(capture.RequestBody), &body); err != nil {
+			http.Error(w, "captured request is not valid JSON", http.StatusUnprocessableEntity)
+			return
+		}
+		messages = body.Messages
+	}
+	if len(messages) == 0 {
+		http.Error(w, "session_id or messages is required", http.StatusBadRequest)
+		return
+	}
+
+	compressor := proxy.GetGlobalCompressor()
+	if compressor == nil {
+		compressor = proxy.NewContextCompressor(config.GetCompression(), nil)
+	}
+	cfg := req.Config
+	if cfg == nil && req.Project != "" {
+		cfg = compressor.ConfigFor(req.Project)
+	}
+	preview, err := compressor.Preview(messages, cfg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preview)
+}
@@ -0,0 +1,157 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// secretConfigKeys are the config fields whose values are masked in diffs.
+var secretConfigKeys = map[string]bool{
+	"auth_token":        true,
+	"token":             true,
+	"embedding_api_key": true,
+	"access_key":        true,
+	"secret_key":        true,
+	"passphrase":        true,
+	"bot_token":         true,
+	"app_token":         true,
+	"app_secret":        true,
+	"page_token":        true,
+	"verify_token":      true,
+	"secret":            true,
+	"routing_key":       true,
+	"key":               true, // ingress keys
+
+	"web_password_hash":        true,
+	"web_viewer_password_hash": true,
+}
+
+// configDiffResponse is the JSON shape returned by GET /api/v1/config/diff.
+type configDiffResponse struct {
+	From    string                `json:"from"`
+	To      string                `json:"to"`
+	Changes []config.ConfigChange `json:"changes"`
+}
+
+// handleConfigHistory lists the saved versions of zen.json, newest first.
+// GET /api/v1/config/history
+func (s *Server) handleConfigHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	snapshots, err := config.DefaultStore().Snapshots()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if snapshots == nil {
+		snapshots = []config.ConfigSnapshot{}
+	}
+	writeJSON(w, http.StatusOK, snapshots)
+}
+
+// handleConfigDiff compares two versions of zen.json. to defaults to the
+// current file. Secret values are masked.
+// GET /api/v1/config/diff?from={id}&to={id}
+func (s *Server) handleConfigDiff(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	from := r.URL.Query().Get("from")
+	to := r.URL.Query().Get("to")
+	if from == "" {
+		writeError(w, http.StatusBadRequest, "from is required")
+		return
+	}
+	if to == "" {
+		to = config.CurrentSnapshotID
+	}
+
+	store := config.DefaultStore()
+	fromData, err := store.SnapshotData(from)
+	if err != nil {
+		writeSnapshotError(w, err)
+		return
+	}
+	toData, err := store.SnapshotData(to)
+	if err != nil {
+		writeSnapshotError(w, err)
+		return
+	}
+	changes, err := config.DiffConfigs(fromData, toData)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if changes == nil {
+		changes = []config.ConfigChange{}
+	}
+	for i := range changes {
+		changes[i].From = maskConfigValue(changes[i].Path, changes[i].From)
+		changes[i].To = maskConfigValue(changes[i].Path, changes[i].To)
+	}
+	writeJSON(w, http.StatusOK, configDiffResponse{From: from, To: to, Changes: changes})
+}
+
+// handleConfigRollback restores a saved version of zen.json.
+// POST /api/v1/config/rollback {"id": "..."}
+func (s *Server) handleConfigRollback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := readJSON(r, &req); err != nil || req.ID == "" {
+		writeError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+	if err := config.DefaultStore().Rollback(req.ID); err != nil {
+		if errors.Is(err, config.ErrSnapshotNotFound) {
+			writeError(w, http.StatusNotFound, err.Error())
+		} else {
+			writeError(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "rolled_back", "id": req.ID})
+}
+
+func writeSnapshotError(w http.ResponseWriter, err error) {
+	if errors.Is(err, config.ErrSnapshotNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	writeError(w, http.StatusInternalServerError, err.Error())
+}
+
+// maskConfigValue masks v when path names a secret field. Objects, such as
+// a whole provider that was added or removed, are masked recursively.
+func maskConfigValue(path string, v interface{}) interface{} {
+	key := path[strings.LastIndex(path, ".")+1:]
+	switch val := v.(type) {
+	case string:
+		if secretConfigKeys[key] {
+			return maskToken(val)
+		}
+	case map[string]interface{}:
+		masked := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			masked[k] = maskConfigValue(path+"."+k, item)
+		}
+		return masked
+	case []interface{}:
+		masked := make([]interface{}, len(val))
+		for i, item := range val {
+			masked[i] = maskConfigValue(path, item)
+		}
+		return masked
+	}
+	return v
+}
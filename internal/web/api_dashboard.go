@@ -0,0 +1,139 @@
+package web
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/proxy"
+)
+
+// Dashboard tuning: how recently a session must have had a request to count
+// as active, and how many models and providers the top lists hold.
+const (
+	dashboardActiveWindow = 15 * time.Minute
+	dashboardTopN         = 5
+)
+
+// dashboardToday is today's traffic, since midnight UTC like the daily budget.
+type dashboardToday struct {
+	Cost         float64 `json:"cost"`
+	RequestCount int     `json:"request_count"` // requests with recorded usage
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	Responses    int     `json:"responses"`  // provider responses in the request log, failover attempts included
+	Errors       int     `json:"errors"`     // of those, responses with status 400 or above
+	ErrorRate    float64 `json:"error_rate"` // errors / responses, 0 to 1
+}
+
+// dashboardRank is a model or provider in today's top lists.
+type dashboardRank struct {
+	Name         string  `json:"name"`
+	Cost         float64 `json:"cost"`
+	RequestCount int     `json:"request_count"`
+}
+
+// dashboardResponse is the response of GET /api/v1/dashboard.
+type dashboardResponse struct {
+	Version        string              `json:"version"`
+	GeneratedAt    time.Time           `json:"generated_at"`
+	Currency       string              `json:"currency"`
+	Today          dashboardToday      `json:"today"`
+	ActiveSessions int                 `json:"active_sessions"`
+	TopModels      []dashboardRank     `json:"top_models"`
+	TopProviders   []dashboardRank     `json:"top_providers"`
+	Budget         *proxy.BudgetStatus `json:"budget,omitempty"`
+	Health         *HealthSummary      `json:"health"`
+	Agents         AgentStatsResponse  `json:"agents"`
+}
+
+// handleDashboard gathers what the Web UI home page shows in one call:
+// today's spend, requests and error rate, active sessions, the top models
+// and providers, budget status, provider health and agent activity. Costs
+// are in the display currency. Parts whose source is unavailable are left
+// empty.
+// GET /api/v1/dashboard
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	now := time.Now().UTC()
+	midnight := now.Truncate(24 * time.Hour)
+	currency := config.GetCurrency()
+	resp := dashboardResponse{
+		Version:      s.version,
+		GeneratedAt:  now,
+		Currency:     currency.GetCode(),
+		TopModels:    []dashboardRank{},
+		TopProviders: []dashboardRank{},
+		Health:       healthSummary(),
+		Agents:       agentStats(),
+	}
+
+	if tracker := proxy.GetGlobalUsageTracker(); tracker != nil {
+		summary, err := tracker.GetSummaryByTimeRange(midnight, now, "")
+		if err != nil {
+			s.logger.Printf("Failed to summarize today's usage: %v", err)
+		} else {
+			summary.ConvertCurrency(currency)
+			resp.Today.Cost = summary.TotalCost
+			resp.Today.RequestCount = summary.RequestCount
+			resp.Today.InputTokens = summary.TotalInputTokens
+			resp.Today.OutputTokens = summary.TotalOutputTokens
+			resp.TopModels = topUsage(summary.ByModel)
+			resp.TopProviders = topUsage(summary.ByProvider)
+		}
+	}
+
+	if db := proxy.GetGlobalLogDB(); db != nil {
+		var err error
+		if resp.Today.Responses, err = db.Count(proxy.LogFilter{Since: midnight, StatusMin: 1}); err != nil {
+			s.logger.Printf("Failed to count today's responses: %v", err)
+		} else if resp.Today.Errors, err = db.Count(proxy.LogFilter{Since: midnight, StatusMin: 400}); err != nil {
+			s.logger.Printf("Failed to count today's errors: %v", err)
+		} else if resp.Today.Responses > 0 {
+			resp.Today.ErrorRate = float64(resp.Today.Errors) / float64(resp.Today.Responses)
+		}
+	}
+
+	for _, insight := range proxy.GetAllSessionInsights() {
+		if insight.LastActivity != nil && now.Sub(*insight.LastActivity) <= dashboardActiveWindow {
+			resp.ActiveSessions++
+		}
+	}
+
+	if checker := proxy.GetGlobalBudgetChecker(); checker != nil {
+		if status, err := checker.Check(""); err != nil {
+			s.logger.Printf("Failed to check budgets: %v", err)
+		} else {
+			resp.Budget = status
+		}
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// topUsage ranks usage groups by cost, then request count, and keeps the
+// first dashboardTopN.
+func topUsage(stats map[string]*proxy.UsageStats) []dashboardRank {
+	ranks := make([]dashboardRank, 0, len(stats))
+	for name, st := range stats {
+		ranks = append(ranks, dashboardRank{Name: name, Cost: st.Cost, RequestCount: st.RequestCount})
+	}
+	sort.Slice(ranks, func(i, j int) bool {
+		if ranks[i].Cost != ranks[j].Cost {
+			return ranks[i].Cost > ranks[j].Cost
+		}
+		if ranks[i].RequestCount != ranks[j].RequestCount {
+			return ranks[i].RequestCount > ranks[j].RequestCount
+		}
+		return ranks[i].Name < ranks[j].Name
+	})
+	if len(ranks) > dashboardTopN {
+		ranks = ranks[:dashboardTopN]
+	}
+	return ranks
+}
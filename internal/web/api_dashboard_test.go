@@ -0,0 +1,73 @@
+package web
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dopejs/gozen/internal/proxy"
+)
+
+func TestDashboard(t *testing.T) {
+	s := setupTestServer(t)
+	setupProxyInfrastructure(t)
+	if err := proxy.InitGlobalLogger(t.TempDir()); err != nil {
+		t.Fatalf("InitGlobalLogger() error: %v", err)
+	}
+
+	tracker := proxy.GetGlobalUsageTracker()
+	now := time.Now()
+	for _, e := range []proxy.UsageEntry{
+		{Timestamp: now, SessionID: "d1", Provider: "p1", Model: "claude-sonnet", InputTokens: 100, OutputTokens: 10, CostUSD: 0.5},
+		{Timestamp: now, SessionID: "d1", Provider: "p1", Model: "claude-haiku", InputTokens: 100, OutputTokens: 10, CostUSD: 0.1},
+		{Timestamp: now, SessionID: "d2", Provider: "p2", Model: "claude-haiku", InputTokens: 50, OutputTokens: 5, CostUSD: 0.1},
+		{Timestamp: now.Add(-48 * time.Hour), SessionID: "d3", Provider: "p3", Model: "gpt-4o", CostUSD: 9},
+	} {
+		tracker.Record(e)
+	}
+	db := proxy.GetGlobalLogDB()
+	db.Insert(proxy.LogEntry{Timestamp: now, Level: proxy.LogLevelInfo, Provider: "dashboard-provider", Message: "ok", StatusCode: 200})
+	db.Insert(proxy.LogEntry{Timestamp: now, Level: proxy.LogLevelError, Provider: "dashboard-provider", Message: "fail", StatusCode: 529})
+	time.Sleep(700 * time.Millisecond)
+	proxy.AddTurnToSession("dashboard-session", proxy.TurnUsage{InputTokens: 10, Timestamp: now})
+	t.Cleanup(func() { proxy.ClearSessionUsage("dashboard-session") })
+
+	w := doRequest(s, http.MethodGet, "/api/v1/dashboard", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var resp dashboardResponse
+	decodeJSON(t, w, &resp)
+
+	if resp.Today.RequestCount != 3 || resp.Today.InputTokens != 250 || resp.Today.Cost < 0.69 || resp.Today.Cost > 0.71 {
+		t.Errorf("today = %+v", resp.Today)
+	}
+	if resp.Today.Errors < 1 || resp.Today.Responses < 2 ||
+		resp.Today.ErrorRate != float64(resp.Today.Errors)/float64(resp.Today.Responses) {
+		t.Errorf("error rate = %+v", resp.Today)
+	}
+	if len(resp.TopModels) != 2 || resp.TopModels[0].Name != "claude-sonnet" || resp.TopModels[1].RequestCount != 2 {
+		t.Errorf("top models = %+v", resp.TopModels)
+	}
+	if len(resp.TopProviders) != 2 || resp.TopProviders[0].Name != "p1" {
+		t.Errorf("top providers = %+v", resp.TopProviders)
+	}
+	if resp.ActiveSessions < 1 || resp.Budget == nil || resp.Health == nil || resp.Currency != "USD" {
+		t.Errorf("dashboard = %+v", resp)
+	}
+
+	if w := doRequest(s, http.MethodPost, "/api/v1/dashboard", nil); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST: status = %d", w.Code)
+	}
+}
+
+func TestTopUsage(t *testing.T) {
+	stats := map[string]*proxy.UsageStats{}
+	for i, name := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+		stats[name] = &proxy.UsageStats{Cost: float64(i % 3), RequestCount: i}
+	}
+	ranks := topUsage(stats)
+	if len(ranks) != dashboardTopN || ranks[0].Name != "f" || ranks[1].Name != "c" || ranks[2].Name != "e" {
+		t.Errorf("ranks = %+v", ranks)
+	}
+}
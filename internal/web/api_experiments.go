@@ -0,0 +1,25 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/dopejs/gozen/internal/proxy"
+)
+
+// handleExperiments handles GET/DELETE /api/v1/experiments - get or reset
+// the shadow traffic comparison reports.
+func (s *Server) handleExperiments(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"experiments": proxy.GetExperimentReports(),
+		})
+
+	case http.MethodDelete:
+		proxy.ResetExperimentReports()
+		writeJSON(w, http.StatusOK, map[string]string{"status": "reset"})
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
@@ -1,11 +1,15 @@
 package web
 
 import (
+	"html/template"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/dopejs/gozen/internal/config"
 	"github.com/dopejs/gozen/internal/proxy"
 )
 
@@ -16,11 +20,43 @@ func (s *Server) handleHealthProviders(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Get time range for metrics
+	hours := 1
+	if h := r.URL.Query().Get("hours"); h != "" {
+		if n, err := strconv.Atoi(h); err == nil && n > 0 {
+			hours = n
+		}
+	}
+	statuses := providerHealthStatuses(time.Now().Add(-time.Duration(hours) * time.Hour))
+
+	if statuses == nil {
+		statuses = []*proxy.ProviderHealthStatus{}
+	}
+
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+// handleHealthProvider handles GET /api/v1/health/providers/{name} - returns health status for a specific provider.
+func (s *Server) handleHealthProvider(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	// Extract provider name from path
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/health/providers/")
+	providerName := strings.TrimSuffix(path, "/")
+
+	if providerName == "" {
+		writeError(w, http.StatusBadRequest, "provider name required")
+		return
+	}
+
 	checker := proxy.GetGlobalHealthChecker()
 	db := proxy.GetGlobalLogDB()
 
 	// Get time range for metrics
-	hours := 1
+	hours := 24
 	if h := r.URL.Query().Get("hours"); h != "" {
 		if n, err := strconv.Atoi(h); err == nil && n > 0 {
 			hours = n
@@ -28,8 +64,52 @@ func (s *Server) handleHealthProviders(w http.ResponseWriter, r *http.Request) {
 	}
 	since := time.Now().Add(-time.Duration(hours) * time.Hour)
 
-	var statuses []*proxy.ProviderHealthStatus
+	response := struct {
+		Status  *proxy.ProviderHealthStatus `json:"status"`
+		Metrics *proxy.ProviderMetrics      `json:"metrics,omitempty"`
+		Latency []proxy.LatencyPoint        `json:"latency,omitempty"`
+	}{}
+
+	// Get live status
+	if checker != nil {
+		response.Status = checker.GetStatus(providerName)
+	} else {
+		response.Status = &proxy.ProviderHealthStatus{
+			Provider: providerName,
+			Status:   proxy.HealthStatusUnknown,
+		}
+	}
+
+	// Get detailed metrics
+	if db != nil {
+		if metrics, err := db.GetProviderMetrics(providerName, since); err == nil {
+			response.Metrics = metrics
+		}
+
+		// Get latency history for charts
+		bucketMinutes := 5
+		if hours > 24 {
+			bucketMinutes = 30
+		} else if hours > 6 {
+			bucketMinutes = 15
+		}
+
+		if latency, err := db.GetLatencyHistory(providerName, since, bucketMinutes); err == nil {
+			response.Latency = latency
+		}
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// providerHealthStatuses returns the live status of each provider known to
+// the health checker, with success rates and latency from the request
+// metrics since the given time.
+func providerHealthStatuses(since time.Time) []*proxy.ProviderHealthStatus {
+	checker := proxy.GetGlobalHealthChecker()
+	db := proxy.GetGlobalLogDB()
 
+	var statuses []*proxy.ProviderHealthStatus
 	if checker != nil {
 		// Get live status from health checker
 		statuses = checker.GetAllStatus()
@@ -81,76 +161,138 @@ func (s *Server) handleHealthProviders(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 	}
+	return statuses
+}
 
-	if statuses == nil {
-		statuses = []*proxy.ProviderHealthStatus{}
-	}
+// HealthSummary is the overall health of the configured providers.
+type HealthSummary struct {
+	Status    proxy.HealthStatus         `json:"status"`
+	UpdatedAt time.Time                  `json:"updated_at"`
+	Counts    map[proxy.HealthStatus]int `json:"counts"`
+	Providers []ProviderHealthSummary    `json:"providers"`
+}
 
-	writeJSON(w, http.StatusOK, statuses)
+// ProviderHealthSummary is one provider's entry in a HealthSummary.
+type ProviderHealthSummary struct {
+	Name        string             `json:"name"`
+	Status      proxy.HealthStatus `json:"status"`
+	SuccessRate float64            `json:"success_rate"`
+	LatencyMs   int                `json:"latency_ms,omitempty"`
+	LastCheck   *time.Time         `json:"last_check,omitempty"`
+	LastError   string             `json:"last_error,omitempty"`
 }
 
-// handleHealthProvider handles GET /api/v1/health/providers/{name} - returns health status for a specific provider.
-func (s *Server) handleHealthProvider(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
-		return
+// healthSummary summarizes the configured providers' health over the last
+// hour. The overall status is healthy when every provider with data is
+// healthy, unhealthy when none is usable, degraded in between, and unknown
+// without data.
+func healthSummary() *HealthSummary {
+	byName := make(map[string]*proxy.ProviderHealthStatus)
+	for _, st := range providerHealthStatuses(time.Now().Add(-time.Hour)) {
+		byName[st.Provider] = st
 	}
 
-	// Extract provider name from path
-	path := strings.TrimPrefix(r.URL.Path, "/api/v1/health/providers/")
-	providerName := strings.TrimSuffix(path, "/")
-
-	if providerName == "" {
-		writeError(w, http.StatusBadRequest, "provider name required")
-		return
+	summary := &HealthSummary{
+		UpdatedAt: time.Now().UTC(),
+		Counts:    make(map[proxy.HealthStatus]int),
+		Providers: []ProviderHealthSummary{},
 	}
-
-	checker := proxy.GetGlobalHealthChecker()
-	db := proxy.GetGlobalLogDB()
-
-	// Get time range for metrics
-	hours := 24
-	if h := r.URL.Query().Get("hours"); h != "" {
-		if n, err := strconv.Atoi(h); err == nil && n > 0 {
-			hours = n
+	for _, name := range config.ProviderNames() {
+		entry := ProviderHealthSummary{Name: name, Status: proxy.HealthStatusUnknown}
+		if st := byName[name]; st != nil {
+			entry.Status = st.Status
+			entry.SuccessRate = st.SuccessRate
+			entry.LatencyMs = st.LatencyMs
+			entry.LastCheck = st.LastCheck
+			entry.LastError = st.LastErrorMsg
+		}
+		if entry.Status == "" {
+			entry.Status = proxy.HealthStatusUnknown
 		}
+		summary.Counts[entry.Status]++
+		summary.Providers = append(summary.Providers, entry)
 	}
-	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+	sort.Slice(summary.Providers, func(i, j int) bool { return summary.Providers[i].Name < summary.Providers[j].Name })
 
-	response := struct {
-		Status  *proxy.ProviderHealthStatus `json:"status"`
-		Metrics *proxy.ProviderMetrics      `json:"metrics,omitempty"`
-		Latency []proxy.LatencyPoint        `json:"latency,omitempty"`
-	}{}
+	known := len(summary.Providers) - summary.Counts[proxy.HealthStatusUnknown]
+	switch {
+	case known == 0:
+		summary.Status = proxy.HealthStatusUnknown
+	case summary.Counts[proxy.HealthStatusHealthy] == known:
+		summary.Status = proxy.HealthStatusHealthy
+	case summary.Counts[proxy.HealthStatusUnhealthy] == known:
+		summary.Status = proxy.HealthStatusUnhealthy
+	default:
+		summary.Status = proxy.HealthStatusDegraded
+	}
+	return summary
+}
 
-	// Get live status
-	if checker != nil {
-		response.Status = checker.GetStatus(providerName)
-	} else {
-		response.Status = &proxy.ProviderHealthStatus{
-			Provider: providerName,
-			Status:   proxy.HealthStatusUnknown,
-		}
+// handleHealthSummary handles GET /api/v1/health/summary - returns the overall
+// status and the state of each configured provider.
+func (s *Server) handleHealthSummary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
 	}
+	writeJSON(w, http.StatusOK, healthSummary())
+}
 
-	// Get detailed metrics
-	if db != nil {
-		if metrics, err := db.GetProviderMetrics(providerName, since); err == nil {
-			response.Metrics = metrics
-		}
+// badgeColors are the badge colors of each overall status.
+var badgeColors = map[proxy.HealthStatus]string{
+	proxy.HealthStatusHealthy:   "#4c1",
+	proxy.HealthStatusDegraded:  "#dfb317",
+	proxy.HealthStatusUnhealthy: "#e05d44",
+	proxy.HealthStatusUnknown:   "#9f9f9f",
+}
 
-		// Get latency history for charts
-		bucketMinutes := 5
-		if hours > 24 {
-			bucketMinutes = 30
-		} else if hours > 6 {
-			bucketMinutes = 15
-		}
+// badgeTemplate renders a flat badge in the style of shields.io.
+var badgeTemplate = template.Must(template.New("badge").Parse(`<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="20" role="img" aria-label="{{.Label}}: {{.Message}}">
+<title>{{.Label}}: {{.Message}}</title>
+<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>
+<clipPath id="r"><rect width="{{.Width}}" height="20" rx="3" fill="#fff"/></clipPath>
+<g clip-path="url(#r)"><rect width="{{.LabelWidth}}" height="20" fill="#555"/><rect x="{{.LabelWidth}}" width="{{.MessageWidth}}" height="20" fill="{{.Color}}"/><rect width="{{.Width}}" height="20" fill="url(#s)"/></g>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="{{.LabelX}}" y="15" fill="#010101" fill-opacity=".3">{{.Label}}</text><text x="{{.LabelX}}" y="14">{{.Label}}</text>
+<text x="{{.MessageX}}" y="15" fill="#010101" fill-opacity=".3">{{.Message}}</text><text x="{{.MessageX}}" y="14">{{.Message}}</text>
+</g>
+</svg>
+`))
 
-		if latency, err := db.GetLatencyHistory(providerName, since, bucketMinutes); err == nil {
-			response.Latency = latency
-		}
+// badgeTextWidth estimates the rendered width of badge text, padding
+// included. Verdana 11px averages about 7px per character.
+func badgeTextWidth(text string) int {
+	return utf8.RuneCountInString(text)*7 + 10
+}
+
+// handleHealthBadge handles GET /badge/health.svg - an SVG badge showing the
+// overall provider health, for dashboards and READMEs. ?label= replaces the
+// "zen" label. The badge reveals only the overall status, so it is served
+// without authentication.
+func (s *Server) handleHealthBadge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	label := r.URL.Query().Get("label")
+	if label == "" || utf8.RuneCountInString(label) > 40 {
+		label = "zen"
 	}
+	status := healthSummary().Status
 
-	writeJSON(w, http.StatusOK, response)
+	data := struct {
+		Label, Message, Color           string
+		Width, LabelWidth, MessageWidth int
+		LabelX, MessageX                float64
+	}{Label: label, Message: string(status), Color: badgeColors[status]}
+	data.LabelWidth = badgeTextWidth(data.Label)
+	data.MessageWidth = badgeTextWidth(data.Message)
+	data.Width = data.LabelWidth + data.MessageWidth
+	data.LabelX = float64(data.LabelWidth) / 2
+	data.MessageX = float64(data.LabelWidth) + float64(data.MessageWidth)/2
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	// Keep image proxies such as GitHub's from serving a stale status
+	w.Header().Set("Cache-Control", "no-cache, max-age=0")
+	badgeTemplate.Execute(w, data)
 }
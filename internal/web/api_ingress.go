@@ -0,0 +1,115 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// ingressKeyResponse is an ingress key as listed by the API, with the key
+// itself masked. The full key is only returned when it is issued.
+type ingressKeyResponse struct {
+	ID        string    `json:"id"`
+	Key       string    `json:"key"`
+	User      string    `json:"user"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// handleIngress handles GET/PUT /api/v1/ingress - list keys, or set whether
+// a valid key is required.
+func (s *Server) handleIngress(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		ic := config.GetIngress()
+		if ic == nil {
+			ic = &config.IngressConfig{}
+		}
+		keys := make([]ingressKeyResponse, 0, len(ic.Keys))
+		for _, k := range ic.Keys {
+			if k == nil {
+				continue
+			}
+			keys = append(keys, ingressKeyResponse{ID: k.ID, Key: maskToken(k.Key), User: k.User, CreatedAt: k.CreatedAt})
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"required": ic.Required,
+			"keys":     keys,
+		})
+
+	case http.MethodPut:
+		var req struct {
+			Required bool `json:"required"`
+		}
+		if err := readJSON(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+			return
+		}
+		ic := config.GetIngress()
+		if ic == nil {
+			ic = &config.IngressConfig{}
+		}
+		updated := *ic
+		updated.Required = req.Required
+		if err := config.SetIngress(&updated); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "updated"})
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleIngressKeys handles POST /api/v1/ingress/keys - issue a key for a user.
+func (s *Server) handleIngressKeys(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var req struct {
+		User string `json:"user"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+	user := strings.TrimSpace(req.User)
+	if user == "" {
+		writeError(w, http.StatusBadRequest, "user is required")
+		return
+	}
+
+	key, err := config.AddIngressKey(user)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, ingressKeyResponse{ID: key.ID, Key: key.Key, User: key.User, CreatedAt: key.CreatedAt})
+}
+
+// handleIngressKey handles DELETE /api/v1/ingress/keys/{id} - revoke a key.
+func (s *Server) handleIngressKey(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/v1/ingress/keys/"), "/")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "key id required")
+		return
+	}
+	if r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	found, err := config.DeleteIngressKey(id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "key not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
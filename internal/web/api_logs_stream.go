@@ -0,0 +1,164 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dopejs/gozen/internal/proxy"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// logStreamPing is how often an idle log stream is pinged, and
+	// logStreamWait how long the client has to answer.
+	logStreamPing = 30 * time.Second
+	logStreamWait = 60 * time.Second
+
+	// logStreamWriteTimeout bounds each write to a log stream.
+	logStreamWriteTimeout = 10 * time.Second
+)
+
+// logStreamUpgrader accepts same-origin WebSocket connections only, so other
+// sites open in the browser can't read the logs through a local daemon.
+var logStreamUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+}
+
+// streamedLogEntry is a log entry as sent on /api/v1/logs/stream, with the
+// project directory of its session when known.
+type streamedLogEntry struct {
+	proxy.LogEntry
+	Project string `json:"project,omitempty"`
+}
+
+// logStreamFilter selects the entries sent on a log stream: those matching
+// the /api/v1/logs filter, a status class and a project.
+type logStreamFilter struct {
+	proxy.LogFilter
+	classes []string // "2xx" to "5xx", or "error"; empty means any
+	project string   // project directory; empty means any
+}
+
+// parseLogStreamFilter reads a log stream filter from the query. status
+// takes a comma-separated list of status classes: 2xx, 3xx, 4xx, 5xx, or
+// error for requests that failed without a response.
+func parseLogStreamFilter(r *http.Request) (*logStreamFilter, error) {
+	query := r.URL.Query()
+	f := &logStreamFilter{
+		LogFilter: logFilterFromQuery(query),
+		project:   query.Get("project"),
+	}
+	if f.project != "" {
+		f.project = filepath.Clean(f.project)
+	}
+	if status := query.Get("status"); status != "" {
+		for _, class := range strings.Split(status, ",") {
+			class = strings.ToLower(strings.TrimSpace(class))
+			switch class {
+			case "2xx", "3xx", "4xx", "5xx", "error":
+				f.classes = append(f.classes, class)
+			default:
+				return nil, fmt.Errorf("invalid status class %q: use 2xx, 3xx, 4xx, 5xx or error", class)
+			}
+		}
+	}
+	return f, nil
+}
+
+// match reports whether an entry from a session in project passes the
+// filter. A project filter also matches sessions in its subdirectories.
+func (f *logStreamFilter) match(entry proxy.LogEntry, project string) bool {
+	if !f.LogFilter.Match(entry) {
+		return false
+	}
+	if f.project != "" && project != f.project && !strings.HasPrefix(project, f.project+string(filepath.Separator)) {
+		return false
+	}
+	if len(f.classes) == 0 {
+		return true
+	}
+	class := "error"
+	if entry.StatusCode > 0 {
+		class = fmt.Sprintf("%dxx", entry.StatusCode/100)
+	} else if entry.Level != proxy.LogLevelError {
+		return false
+	}
+	for _, c := range f.classes {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// handleLogStream streams new log entries over a WebSocket as they are
+// logged, one JSON text message per entry. The query takes the filters of
+// /api/v1/logs plus status and project.
+// GET /api/v1/logs/stream
+func (s *Server) handleLogStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	filter, err := parseLogStreamFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	logger := proxy.GetGlobalLogger()
+	if logger == nil {
+		writeError(w, http.StatusServiceUnavailable, "request logging not initialized")
+		return
+	}
+
+	conn, err := logStreamUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // the upgrader has replied
+	}
+	defer conn.Close()
+
+	entries, unsubscribe := logger.Subscribe()
+	defer unsubscribe()
+
+	// Read until the client goes away, answering pings and noting pongs
+	closed := make(chan struct{})
+	conn.SetReadDeadline(time.Now().Add(logStreamWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(logStreamWait))
+	})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(logStreamPing)
+	defer ping.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case entry := <-entries:
+			project := proxy.GetSessionProject(entry.SessionID)
+			if !filter.match(entry, project) {
+				continue
+			}
+			conn.SetWriteDeadline(time.Now().Add(logStreamWriteTimeout))
+			if err := conn.WriteJSON(streamedLogEntry{LogEntry: entry, Project: project}); err != nil {
+				return
+			}
+		case <-ping.C:
+			deadline := time.Now().Add(logStreamWriteTimeout)
+			if err := conn.WriteControl(websocket.PingMessage, nil, deadline); err != nil {
+				return
+			}
+		}
+	}
+}
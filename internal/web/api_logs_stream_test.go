@@ -0,0 +1,105 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dopejs/gozen/internal/proxy"
+	"github.com/gorilla/websocket"
+)
+
+func TestLogStreamFilter(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/logs/stream?provider=a&status=5xx,error&project=/work/app", nil)
+	f, err := parseLogStreamFilter(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		entry   proxy.LogEntry
+		project string
+		want    bool
+	}{
+		{"server error", proxy.LogEntry{Provider: "a", StatusCode: 502}, "/work/app", true},
+		{"subdirectory", proxy.LogEntry{Provider: "a", StatusCode: 500}, "/work/app/api", true},
+		{"failed without response", proxy.LogEntry{Provider: "a", Level: proxy.LogLevelError}, "/work/app", true},
+		{"info without status", proxy.LogEntry{Provider: "a", Level: proxy.LogLevelInfo}, "/work/app", false},
+		{"client error", proxy.LogEntry{Provider: "a", StatusCode: 429}, "/work/app", false},
+		{"other provider", proxy.LogEntry{Provider: "b", StatusCode: 500}, "/work/app", false},
+		{"other project", proxy.LogEntry{Provider: "a", StatusCode: 500}, "/work/application", false},
+		{"no project", proxy.LogEntry{Provider: "a", StatusCode: 500}, "", false},
+	}
+	for _, tt := range tests {
+		if got := f.match(tt.entry, tt.project); got != tt.want {
+			t.Errorf("%s: match = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/api/v1/logs/stream?status=6xx", nil)
+	if _, err := parseLogStreamFilter(r); err == nil {
+		t.Error("expected error for invalid status class")
+	}
+}
+
+func TestLogStream(t *testing.T) {
+	if err := proxy.InitGlobalLogger(t.TempDir()); err != nil {
+		t.Fatalf("InitGlobalLogger() error: %v", err)
+	}
+	s := setupTestServer(t)
+	ts := httptest.NewServer(s.httpServer.Handler)
+	defer ts.Close()
+
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/api/v1/logs/stream?provider=stream-test"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// The subscription starts once the upgrade is done; log until an entry
+	// arrives, with one the filter drops in between
+	logger := proxy.GetGlobalLogger()
+	got := make(chan streamedLogEntry, 1)
+	go func() {
+		var entry streamedLogEntry
+		if err := conn.ReadJSON(&entry); err == nil {
+			got <- entry
+		}
+	}()
+	deadline := time.After(5 * time.Second)
+	for {
+		logger.Log(proxy.LogEntry{Level: proxy.LogLevelInfo, Provider: "other", Message: "skip"})
+		logger.Log(proxy.LogEntry{Level: proxy.LogLevelInfo, Provider: "stream-test", StatusCode: 200, Message: "done"})
+		select {
+		case entry := <-got:
+			if entry.Provider != "stream-test" || entry.Message != "done" {
+				t.Errorf("entry = %+v", entry)
+			}
+			return
+		case <-deadline:
+			t.Fatal("no entry streamed")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+}
+
+func TestLogStreamCrossOrigin(t *testing.T) {
+	if err := proxy.InitGlobalLogger(t.TempDir()); err != nil {
+		t.Fatalf("InitGlobalLogger() error: %v", err)
+	}
+	s := setupTestServer(t)
+	ts := httptest.NewServer(s.httpServer.Handler)
+	defer ts.Close()
+
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/api/v1/logs/stream"
+	header := http.Header{"Origin": {"https://evil.example"}}
+	if _, resp, err := websocket.DefaultDialer.Dial(url, header); err == nil {
+		t.Fatal("cross-origin connection accepted")
+	} else if resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403, got %v", err)
+	}
+}
@@ -17,13 +17,25 @@ type MiddlewareConfigResponse struct {
 
 // MiddlewareEntryResponse is the API response for a single middleware entry.
 type MiddlewareEntryResponse struct {
-	Name        string          `json:"name"`
-	Enabled     bool            `json:"enabled"`
-	Source      string          `json:"source"`
-	Version     string          `json:"version,omitempty"`
-	Description string          `json:"description,omitempty"`
-	Priority    int             `json:"priority,omitempty"`
-	Config      json.RawMessage `json:"config,omitempty"`
+	Name        string                  `json:"name"`
+	Enabled     bool                    `json:"enabled"`
+	Source      string                  `json:"source"`
+	Path        string                  `json:"path,omitempty"`
+	URL         string                  `json:"url,omitempty"`
+	Version     string                  `json:"version,omitempty"`
+	Description string                  `json:"description,omitempty"`
+	Priority    int                     `json:"priority,omitempty"`
+	Config      json.RawMessage         `json:"config,omitempty"`
+	When        *config.MiddlewareMatch `json:"when,omitempty"`
+	Stats       interface{}             `json:"stats,omitempty"` // runtime statistics, for middleware that reports them
+
+	Metrics *middleware.MiddlewareStats `json:"metrics,omitempty"` // pipeline latency and error counts, when loaded
+}
+
+// MiddlewareStatsResponse is the API response for a middleware's metrics.
+type MiddlewareStatsResponse struct {
+	middleware.MiddlewareStats
+	Stats interface{} `json:"stats,omitempty"` // the middleware's own statistics, if it reports any
 }
 
 // handleMiddleware routes GET and PUT requests for middleware config.
@@ -44,6 +56,11 @@ func (s *Server) handleMiddleware(w http.ResponseWriter, r *http.Request) {
 			s.handleMiddlewareDisable(w, r, name)
 			return
 		}
+		if strings.HasSuffix(name, "/stats") {
+			name = strings.TrimSuffix(name, "/stats")
+			s.handleMiddlewareStats(w, r, name)
+			return
+		}
 		s.handleMiddlewareByName(w, r, name)
 		return
 	}
@@ -58,6 +75,57 @@ func (s *Server) handleMiddleware(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// configFor returns a middleware config as r may read it. Readers who can't
+// see secrets get every header value and the string values of keys naming a
+// credential masked; plugin configs are opaque, so this goes by key name.
+func configFor(r *http.Request, raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 || showsSecrets(r) {
+		return raw
+	}
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil
+	}
+	out, err := json.Marshal(redactConfigValue(v, false))
+	if err != nil {
+		return nil
+	}
+	return out
+}
+
+func redactConfigValue(v interface{}, secret bool) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			v[k] = redactConfigValue(child, secret || isSecretConfigKey(k))
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = redactConfigValue(child, secret)
+		}
+	case string:
+		if secret {
+			return maskToken(v)
+		}
+	}
+	return v
+}
+
+// isSecretConfigKey reports whether a middleware config key holds
+// credentials, like callout's secret and headers.
+func isSecretConfigKey(k string) bool {
+	k = strings.ToLower(k)
+	if k == "headers" || strings.HasSuffix(k, "key") {
+		return true
+	}
+	for _, s := range []string{"secret", "token", "password", "auth"} {
+		if strings.Contains(k, s) {
+			return true
+		}
+	}
+	return false
+}
+
 // handleGetMiddleware returns the middleware configuration.
 // GET /api/v1/middleware
 func (s *Server) handleGetMiddleware(w http.ResponseWriter, r *http.Request) {
@@ -92,7 +160,10 @@ func (s *Server) handleGetMiddleware(w http.ResponseWriter, r *http.Request) {
 			Name:    entry.Name,
 			Enabled: entry.Enabled,
 			Source:  entry.Source,
-			Config:  entry.Config,
+			Path:    entry.Path,
+			URL:     entry.URL,
+			Config:  configFor(r, entry.Config),
+			When:    entry.When,
 		}
 		if entryResp.Source == "" {
 			entryResp.Source = "builtin"
@@ -104,6 +175,12 @@ func (s *Server) handleGetMiddleware(w http.ResponseWriter, r *http.Request) {
 				entryResp.Version = m.Version()
 				entryResp.Description = m.Description()
 				entryResp.Priority = m.Priority()
+				if sr, ok := m.(middleware.StatsReporter); ok {
+					entryResp.Stats = sr.Stats()
+				}
+				if stats, ok := registry.Pipeline().Stats(entry.Name); ok {
+					entryResp.Metrics = &stats
+				}
 			}
 		}
 
@@ -153,7 +230,10 @@ func (s *Server) handleSetMiddleware(w http.ResponseWriter, r *http.Request) {
 			Name:    entry.Name,
 			Enabled: entry.Enabled,
 			Source:  entry.Source,
+			Path:    entry.Path,
+			URL:     entry.URL,
 			Config:  entry.Config,
+			When:    entry.When,
 		}
 	}
 
@@ -193,7 +273,10 @@ func (s *Server) handleMiddlewareByName(w http.ResponseWriter, r *http.Request,
 				Name:    entry.Name,
 				Enabled: entry.Enabled,
 				Source:  entry.Source,
-				Config:  entry.Config,
+				Path:    entry.Path,
+				URL:     entry.URL,
+				Config:  configFor(r, entry.Config),
+				When:    entry.When,
 			}
 
 			// Get version and description from loaded middleware
@@ -202,6 +285,12 @@ func (s *Server) handleMiddlewareByName(w http.ResponseWriter, r *http.Request,
 					resp.Version = m.Version()
 					resp.Description = m.Description()
 					resp.Priority = m.Priority()
+					if sr, ok := m.(middleware.StatsReporter); ok {
+						resp.Stats = sr.Stats()
+					}
+					if stats, ok := registry.Pipeline().Stats(entry.Name); ok {
+						resp.Metrics = &stats
+					}
 				}
 			}
 
@@ -214,6 +303,34 @@ func (s *Server) handleMiddlewareByName(w http.ResponseWriter, r *http.Request,
 	http.Error(w, "middleware not found", http.StatusNotFound)
 }
 
+// handleMiddlewareStats returns latency and error counts for a loaded middleware.
+// GET /api/v1/middleware/{name}/stats
+func (s *Server) handleMiddlewareStats(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	registry := middleware.GetGlobalRegistry()
+	if registry == nil {
+		http.Error(w, "middleware registry not initialized", http.StatusInternalServerError)
+		return
+	}
+
+	stats, ok := registry.Pipeline().Stats(name)
+	if !ok {
+		http.Error(w, "middleware not loaded", http.StatusNotFound)
+		return
+	}
+	resp := MiddlewareStatsResponse{MiddlewareStats: stats}
+	if sr, ok := registry.Pipeline().Get(name).(middleware.StatsReporter); ok {
+		resp.Stats = sr.Stats()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
 // handleMiddlewareEnable enables a middleware.
 // POST /api/v1/middleware/{name}/enable
 func (s *Server) handleMiddlewareEnable(w http.ResponseWriter, r *http.Request, name string) {
@@ -40,15 +40,16 @@ func (s *Server) handleMiddlewareUpload(w http.ResponseWriter, r *http.Request)
 	defer file.Close()
 
 	// Validate file extension
-	if !strings.HasSuffix(header.Filename, ".so") {
-		writeError(w, http.StatusBadRequest, "only .so files are allowed")
+	ext := filepath.Ext(header.Filename)
+	if ext != ".so" && ext != ".wasm" {
+		writeError(w, http.StatusBadRequest, "only .so and .wasm files are allowed")
 		return
 	}
 
 	// Get plugin name from form or use filename
 	pluginName := r.FormValue("name")
 	if pluginName == "" {
-		pluginName = strings.TrimSuffix(header.Filename, ".so")
+		pluginName = strings.TrimSuffix(header.Filename, ext)
 	}
 
 	// Sanitize plugin name
@@ -88,7 +89,7 @@ func (s *Server) handleMiddlewareUpload(w http.ResponseWriter, r *http.Request)
 
 	// Generate final filename with checksum prefix for cache busting
 	checksum := hex.EncodeToString(hash.Sum(nil))[:8]
-	finalName := fmt.Sprintf("%s-%s.so", pluginName, checksum)
+	finalName := fmt.Sprintf("%s-%s%s", pluginName, checksum, ext)
 	finalPath := filepath.Join(pluginsDir, finalName)
 
 	// Move temp file to final location
@@ -0,0 +1,50 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// handleModelAliases handles GET/PUT /api/v1/model-aliases - get or replace
+// the ordered list of model rewrite rules.
+func (s *Server) handleModelAliases(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		aliases := config.GetModelAliases()
+		if aliases == nil {
+			aliases = []*config.ModelAlias{}
+		}
+		writeJSON(w, http.StatusOK, aliases)
+
+	case http.MethodPut:
+		var aliases []*config.ModelAlias
+		if err := readJSON(r, &aliases); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+			return
+		}
+		for i, a := range aliases {
+			if err := a.Validate(); err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("alias %d: %v", i, err))
+				return
+			}
+			if a.Provider != "" && config.GetProvider(a.Provider) == nil {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("alias %d: provider %q not found", i, a.Provider))
+				return
+			}
+		}
+		if len(aliases) == 0 {
+			aliases = nil
+		}
+
+		if err := config.SetModelAliases(aliases); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "updated"})
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
@@ -0,0 +1,227 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/proxy"
+)
+
+// Provider model lists are cached: listing is slow and the lists rarely
+// change. Failures are cached for less time so a fixed provider shows up
+// soon.
+const (
+	modelListTTL      = 10 * time.Minute
+	modelListErrorTTL = time.Minute
+)
+
+// modelCache holds the model list last fetched from each provider.
+type modelCache struct {
+	mu      sync.Mutex
+	entries map[string]*providerModels
+}
+
+type providerModels struct {
+	baseURL string // the list is refetched if the provider moves
+	models  []string
+	err     string
+	fetched time.Time
+}
+
+func (e *providerModels) fresh(baseURL string, now time.Time) bool {
+	if e == nil || e.baseURL != baseURL {
+		return false
+	}
+	ttl := modelListTTL
+	if e.err != "" {
+		ttl = modelListErrorTTL
+	}
+	return now.Sub(e.fetched) < ttl
+}
+
+// get returns the provider's model list, fetching it if the cached one is
+// stale or refresh is set.
+func (c *modelCache) get(ctx context.Context, name string, pc *config.ProviderConfig, refresh bool) (*providerModels, bool) {
+	c.mu.Lock()
+	cached := c.entries[name]
+	c.mu.Unlock()
+	if !refresh && cached.fresh(pc.BaseURL, time.Now()) {
+		return cached, true
+	}
+
+	entry := &providerModels{baseURL: pc.BaseURL, fetched: time.Now()}
+	models, err := proxy.ListProviderModels(ctx, pc)
+	if err != nil {
+		entry.err = err.Error()
+	} else {
+		entry.models = models
+	}
+	c.mu.Lock()
+	if c.entries == nil {
+		c.entries = make(map[string]*providerModels)
+	}
+	c.entries[name] = entry
+	c.mu.Unlock()
+	return entry, false
+}
+
+// catalogModel is one model in GET /api/v1/models.
+type catalogModel struct {
+	ID         string               `json:"id"`
+	Providers  []string             `json:"providers"` // providers that list the model or are configured with it
+	Pricing    *config.ModelPricing `json:"pricing,omitempty"`
+	References []modelReference     `json:"references,omitempty"`
+}
+
+// modelReference is a place in the config that names a model: a provider's
+// default model field, or a provider route in a profile's scenario.
+type modelReference struct {
+	Profile  string `json:"profile,omitempty"`
+	Scenario string `json:"scenario,omitempty"`
+	Provider string `json:"provider"`
+	Field    string `json:"field,omitempty"` // provider model field, e.g. "model" or "haiku_model"
+}
+
+// catalogProvider reports how a provider's model list was obtained.
+type catalogProvider struct {
+	Name      string    `json:"name"`
+	Models    int       `json:"models"`
+	Cached    bool      `json:"cached"`
+	FetchedAt time.Time `json:"fetched_at"`
+	Error     string    `json:"error,omitempty"`
+}
+
+type modelCatalogResponse struct {
+	Models    []*catalogModel   `json:"models"`
+	Providers []catalogProvider `json:"providers"`
+}
+
+// handleModels lists every model known from the providers' model lists and
+// the config, with its pricing and the profiles, scenarios and provider
+// fields that reference it. Provider lists are cached; refresh=true
+// refetches them.
+// GET /api/v1/models
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	refresh := r.URL.Query().Get("refresh") == "true"
+
+	names := config.ProviderNames()
+	providers := make([]catalogProvider, len(names))
+	lists := make([]*providerModels, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		pc := config.GetProvider(name)
+		if pc == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, name string, pc *config.ProviderConfig) {
+			defer wg.Done()
+			entry, cached := s.models.get(r.Context(), name, pc, refresh)
+			lists[i] = entry
+			providers[i] = catalogProvider{
+				Name:      name,
+				Models:    len(entry.models),
+				Cached:    cached,
+				FetchedAt: entry.fetched,
+				Error:     entry.err,
+			}
+		}(i, name, pc)
+	}
+	wg.Wait()
+
+	byID := make(map[string]*catalogModel)
+	model := func(id string) *catalogModel {
+		m, ok := byID[id]
+		if !ok {
+			m = &catalogModel{ID: id, Providers: []string{}}
+			byID[id] = m
+		}
+		return m
+	}
+	addProvider := func(m *catalogModel, provider string) {
+		for _, p := range m.Providers {
+			if p == provider {
+				return
+			}
+		}
+		m.Providers = append(m.Providers, provider)
+	}
+
+	for i, name := range names {
+		if lists[i] == nil {
+			continue
+		}
+		for _, id := range lists[i].models {
+			addProvider(model(id), name)
+		}
+		pc := config.GetProvider(name)
+		if pc == nil {
+			continue
+		}
+		for _, f := range []struct{ field, id string }{
+			{"model", pc.Model},
+			{"reasoning_model", pc.ReasoningModel},
+			{"haiku_model", pc.HaikuModel},
+			{"opus_model", pc.OpusModel},
+			{"sonnet_model", pc.SonnetModel},
+		} {
+			if f.id == "" {
+				continue
+			}
+			m := model(f.id)
+			addProvider(m, name)
+			m.References = append(m.References, modelReference{Provider: name, Field: f.field})
+		}
+	}
+
+	for _, profile := range config.ListProfiles() {
+		pc := config.GetProfileConfig(profile)
+		if pc == nil {
+			continue
+		}
+		scenarios := make([]string, 0, len(pc.Routing))
+		for scenario := range pc.Routing {
+			scenarios = append(scenarios, scenario)
+		}
+		sort.Strings(scenarios)
+		for _, scenario := range scenarios {
+			policy := pc.Routing[scenario]
+			if policy == nil {
+				continue
+			}
+			for _, route := range policy.Providers {
+				if route == nil || route.Model == "" {
+					continue
+				}
+				m := model(route.Model)
+				addProvider(m, route.Name)
+				m.References = append(m.References, modelReference{Profile: profile, Scenario: scenario, Provider: route.Name})
+			}
+		}
+	}
+
+	pricing := config.GetPricing()
+	models := make([]*catalogModel, 0, len(byID))
+	for id, m := range byID {
+		m.Pricing = pricing[id]
+		sort.Strings(m.Providers)
+		models = append(models, m)
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].ID < models[j].ID })
+
+	resp := modelCatalogResponse{Models: models, Providers: []catalogProvider{}}
+	for _, p := range providers {
+		if p.Name != "" {
+			resp.Providers = append(resp.Providers, p)
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,80 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+func TestModelCatalog(t *testing.T) {
+	s := setupTestServer(t)
+
+	var fetches int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		w.Write([]byte(`{"data":[{"id":"claude-sonnet-4-5"},{"id":"claude-haiku-4-5"}]}`))
+	}))
+	defer upstream.Close()
+
+	config.DeleteProviderByName("backup")
+	config.SetProvider("test-provider", &config.ProviderConfig{
+		BaseURL:   upstream.URL,
+		AuthToken: "sk-test",
+		Model:     "claude-sonnet-4-5",
+	})
+	config.SetProfileConfig("work", &config.ProfileConfig{
+		Providers: []string{"test-provider"},
+		Routing: map[string]*config.RoutePolicy{
+			"think": {Providers: []*config.ProviderRoute{{Name: "test-provider", Model: "claude-opus-4-1"}}},
+		},
+	})
+	config.SetPricing(map[string]*config.ModelPricing{"claude-haiku-4-5": {InputPerMillion: 1, OutputPerMillion: 5}})
+
+	var resp modelCatalogResponse
+	w := doRequest(s, http.MethodGet, "/api/v1/models", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	decodeJSON(t, w, &resp)
+
+	byID := map[string]*catalogModel{}
+	for _, m := range resp.Models {
+		byID[m.ID] = m
+	}
+	if len(byID) != 3 {
+		t.Fatalf("models = %d, want 3 (listed, configured and routed)", len(byID))
+	}
+	if m := byID["claude-haiku-4-5"]; len(m.Providers) != 1 || len(m.References) != 0 {
+		t.Errorf("listed model = %+v", m)
+	}
+	if m := byID["claude-sonnet-4-5"]; len(m.References) != 1 || m.References[0].Field != "model" {
+		t.Errorf("provider default model = %+v", m)
+	}
+	if m := byID["claude-opus-4-1"]; len(m.References) != 1 || m.References[0].Profile != "work" || m.References[0].Scenario != "think" {
+		t.Errorf("routed model = %+v", m)
+	}
+	if p := byID["claude-haiku-4-5"].Pricing; p == nil || p.OutputPerMillion != 5 {
+		t.Errorf("pricing = %+v, want the configured override", p)
+	}
+	if len(resp.Providers) != 1 || resp.Providers[0].Cached || resp.Providers[0].Models != 2 {
+		t.Errorf("providers = %+v", resp.Providers)
+	}
+
+	// The list is cached until refresh is asked for
+	w = doRequest(s, http.MethodGet, "/api/v1/models", nil)
+	decodeJSON(t, w, &resp)
+	if n := atomic.LoadInt32(&fetches); n != 1 || !resp.Providers[0].Cached {
+		t.Errorf("fetches = %d, cached = %v after a second request", n, resp.Providers[0].Cached)
+	}
+	doRequest(s, http.MethodGet, "/api/v1/models?refresh=true", nil)
+	if n := atomic.LoadInt32(&fetches); n != 2 {
+		t.Errorf("fetches = %d after refresh, want 2", n)
+	}
+
+	if w := doRequest(s, http.MethodPost, "/api/v1/models", nil); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d", w.Code)
+	}
+}
@@ -0,0 +1,76 @@
+package web
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/dopejs/gozen/internal/proxy"
+)
+
+// Paginated list APIs (/api/v1/logs, /api/v1/usage, /api/v1/sessions,
+// /api/v1/audit) take limit, before and after query parameters and return
+// total, next_cursor and prev_cursor. Lists run newest first: pass
+// next_cursor as before to read the next, older page, and prev_cursor as
+// after to read the newer one. A cursor is only set when there are rows in
+// that direction.
+
+// pageCursors holds the paging fields of a list response.
+type pageCursors struct {
+	Total      int    `json:"total"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// pageFromQuery reads a page of rows keyed by ID from the query, with
+// limit as the page size when the query doesn't set one.
+func pageFromQuery(query url.Values, limit int) (proxy.Page, error) {
+	page := proxy.Page{Limit: limit}
+	if l := query.Get("limit"); l != "" {
+		if n, err := strconv.Atoi(l); err == nil && n > 0 {
+			page.Limit = n
+		}
+	}
+	var err error
+	if page.Before, err = parseCursor(query, "before"); err != nil {
+		return page, err
+	}
+	if page.After, err = parseCursor(query, "after"); err != nil {
+		return page, err
+	}
+	return page, nil
+}
+
+func parseCursor(query url.Values, name string) (int64, error) {
+	v := query.Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid %s cursor %q", name, v)
+	}
+	return id, nil
+}
+
+// cursorsFor returns the cursors around a page read with page, given the
+// IDs of its first (newest) and last (oldest) rows and whether there were
+// more rows past it in the direction read.
+func cursorsFor(page proxy.Page, more bool, first, last int64) pageCursors {
+	var c pageCursors
+	if first == 0 {
+		return c // empty page
+	}
+	older := more
+	newer := page.Before > 0
+	if page.After > 0 {
+		older, newer = true, more
+	}
+	if older {
+		c.NextCursor = strconv.FormatInt(last, 10)
+	}
+	if newer {
+		c.PrevCursor = strconv.FormatInt(first, 10)
+	}
+	return c
+}
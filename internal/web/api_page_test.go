@@ -0,0 +1,91 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dopejs/gozen/internal/proxy"
+)
+
+func TestUsagePaging(t *testing.T) {
+	s := setupTestServer(t)
+	setupProxyInfrastructure(t)
+	tracker := proxy.GetGlobalUsageTracker()
+	for i := 1; i <= 3; i++ {
+		tracker.Record(proxy.UsageEntry{Timestamp: time.Now(), SessionID: fmt.Sprintf("s%d", i), Provider: "p", Model: "m", Tags: []string{"paging"}})
+	}
+
+	var first usageResponse
+	decodeJSON(t, doRequest(s, "GET", "/api/v1/usage?tag=paging&limit=2", nil), &first)
+	if len(first.Entries) != 2 || first.Entries[0].SessionID != "s3" || first.Total != 3 {
+		t.Fatalf("first page = %+v", first)
+	}
+	if first.NextCursor == "" || first.PrevCursor != "" {
+		t.Fatalf("first page cursors = %q, %q; want next only", first.NextCursor, first.PrevCursor)
+	}
+
+	var second usageResponse
+	decodeJSON(t, doRequest(s, "GET", "/api/v1/usage?tag=paging&limit=2&before="+first.NextCursor, nil), &second)
+	if len(second.Entries) != 1 || second.Entries[0].SessionID != "s1" {
+		t.Fatalf("second page = %+v", second)
+	}
+	if second.NextCursor != "" || second.PrevCursor == "" {
+		t.Fatalf("second page cursors = %q, %q; want prev only", second.NextCursor, second.PrevCursor)
+	}
+
+	var back usageResponse
+	decodeJSON(t, doRequest(s, "GET", "/api/v1/usage?tag=paging&limit=2&after="+second.PrevCursor, nil), &back)
+	if len(back.Entries) != 2 || back.Entries[0].SessionID != "s3" || back.Entries[1].SessionID != "s2" {
+		t.Errorf("page back = %+v, want s3, s2", back.Entries)
+	}
+
+	w := doRequest(s, "GET", "/api/v1/usage?before=abc", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid cursor, got %d", w.Code)
+	}
+}
+
+func TestSessionsPaging(t *testing.T) {
+	s := setupTestServer(t)
+
+	// Far-future activity keeps these ahead of sessions other tests left
+	base := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+	ids := []string{"page-c", "page-b", "page-a"} // most recent first
+	for i, id := range ids {
+		ts := base.Add(-time.Duration(i) * time.Minute)
+		proxy.UpdateSessionUsage(id, &proxy.SessionUsage{TurnCount: 1, Turns: []proxy.TurnUsage{{Timestamp: ts}}})
+		t.Cleanup(func() { proxy.ClearSessionUsage(id) })
+	}
+
+	type sessionsPage struct {
+		Sessions []proxy.SessionInsight `json:"sessions"`
+		pageCursors
+	}
+	var first sessionsPage
+	decodeJSON(t, doRequest(s, "GET", "/api/v1/sessions?limit=2", nil), &first)
+	if len(first.Sessions) != 2 || first.Sessions[0].SessionID != "page-c" || first.Sessions[1].SessionID != "page-b" {
+		t.Fatalf("first page = %+v", first.Sessions)
+	}
+	if first.Total < 3 || first.NextCursor == "" || first.PrevCursor != "" {
+		t.Fatalf("first page total %d, cursors %q, %q", first.Total, first.NextCursor, first.PrevCursor)
+	}
+
+	var second sessionsPage
+	decodeJSON(t, doRequest(s, "GET", "/api/v1/sessions?limit=1&before="+first.NextCursor, nil), &second)
+	if len(second.Sessions) != 1 || second.Sessions[0].SessionID != "page-a" || second.PrevCursor == "" {
+		t.Fatalf("second page = %+v, prev %q", second.Sessions, second.PrevCursor)
+	}
+
+	var back sessionsPage
+	decodeJSON(t, doRequest(s, "GET", "/api/v1/sessions?limit=1&after="+second.PrevCursor, nil), &back)
+	if len(back.Sessions) != 1 || back.Sessions[0].SessionID != "page-b" || back.NextCursor == "" {
+		t.Errorf("page back = %+v, next %q", back.Sessions, back.NextCursor)
+	}
+
+	w := doRequest(s, "GET", "/api/v1/sessions?after=nope", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid cursor, got %d", w.Code)
+	}
+}
@@ -2,6 +2,7 @@ package web
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/dopejs/gozen/internal/config"
 	"github.com/dopejs/gozen/internal/proxy"
@@ -39,8 +40,12 @@ func (s *Server) handlePricing(w http.ResponseWriter, r *http.Request) {
 
 		// Validate pricing values
 		for model, p := range pricing {
-			if p.InputPerMillion < 0 || p.OutputPerMillion < 0 {
-				writeError(w, http.StatusBadRequest, "pricing values must be non-negative for model: "+model)
+			if p == nil {
+				writeError(w, http.StatusBadRequest, "missing pricing for model: "+model)
+				return
+			}
+			if err := p.Validate(); err != nil {
+				writeError(w, http.StatusBadRequest, err.Error()+" for model: "+model)
 				return
 			}
 		}
@@ -92,3 +97,93 @@ func (s *Server) handlePricingReset(w http.ResponseWriter, r *http.Request) {
 
 	writeJSON(w, http.StatusOK, map[string]string{"status": "reset to defaults"})
 }
+
+// handlePricingSync handles GET/POST /api/v1/pricing/sync - show the pricing
+// sync state, or fetch the signed pricing manifest and apply it. POST with
+// ?dry_run=true only reports what the manifest would change.
+func (s *Server) handlePricingSync(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		ps := config.GetPricingSync()
+		if ps == nil {
+			ps = &config.PricingSyncConfig{}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"enabled":        ps.Enabled,
+			"url":            ps.URL,
+			"interval_hours": int(ps.GetInterval().Hours()),
+			"version":        ps.Version,
+			"synced_at":      ps.SyncedAt,
+			"models":         len(ps.Prices),
+		})
+
+	case http.MethodPost:
+		if ps := config.GetPricingSync(); ps == nil || ps.URL == "" {
+			writeError(w, http.StatusBadRequest, "pricing sync is not configured")
+			return
+		}
+		dryRun := r.URL.Query().Get("dry_run") == "true"
+		result, err := proxy.SyncPricing(r.Context(), dryRun)
+		if err != nil {
+			writeError(w, http.StatusBadGateway, "pricing sync failed: "+err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, result)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleCurrency handles GET/PUT /api/v1/currency - get or set the display
+// currency costs and budgets are shown in.
+func (s *Server) handleCurrency(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		c := config.GetCurrency()
+		if c == nil {
+			c = &config.CurrencyConfig{}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"code":         c.GetCode(),
+			"rate":         c.GetRate(),
+			"fixed_rate":   c.Rate,
+			"rate_url":     c.RateURL,
+			"fetched_rate": c.FetchedRate,
+			"fetched_at":   c.FetchedAt,
+		})
+
+	case http.MethodPut:
+		var req struct {
+			Code    string  `json:"code"`
+			Rate    float64 `json:"rate"`
+			RateURL string  `json:"rate_url"`
+		}
+		if err := readJSON(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+			return
+		}
+		if req.Code != "" && len(req.Code) != 3 {
+			writeError(w, http.StatusBadRequest, "code must be a 3-letter ISO 4217 code")
+			return
+		}
+		if req.Rate < 0 {
+			writeError(w, http.StatusBadRequest, "rate must not be negative")
+			return
+		}
+
+		updated := config.CurrencyConfig{Code: strings.ToUpper(req.Code), Rate: req.Rate, RateURL: req.RateURL}
+		// Keep the fetched rate while the currency and its source are unchanged
+		if c := config.GetCurrency(); c != nil && c.GetCode() == updated.GetCode() && c.RateURL == updated.RateURL {
+			updated.FetchedRate, updated.FetchedAt = c.FetchedRate, c.FetchedAt
+		}
+		if err := config.SetCurrency(&updated); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "updated"})
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
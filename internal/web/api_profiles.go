@@ -21,6 +21,7 @@ type scenarioRouteResponse struct {
 	ProviderWeights      map[string]int           `json:"provider_weights,omitempty"`
 	LongContextThreshold *int                     `json:"long_context_threshold,omitempty"`
 	FallbackToDefault    *bool                    `json:"fallback_to_default,omitempty"`
+	Match                *config.ScenarioMatch    `json:"match,omitempty"`
 }
 
 // profileResponse is the JSON shape returned for a single profile.
@@ -29,6 +30,9 @@ type profileResponse struct {
 	Providers        []string                           `json:"providers"`
 	Routing          map[string]*scenarioRouteResponse `json:"routing,omitempty"`
 	ScenarioPriority []string                           `json:"scenario_priority,omitempty"`
+	CacheAffinity    bool                               `json:"cache_affinity,omitempty"`
+	Mirror           *config.MirrorConfig               `json:"mirror,omitempty"`
+	RequestTransform *config.RequestTransform           `json:"request_transform,omitempty"`
 }
 
 type createProfileRequest struct {
@@ -36,12 +40,18 @@ type createProfileRequest struct {
 	Providers        []string                           `json:"providers"`
 	Routing          map[string]*scenarioRouteResponse `json:"routing,omitempty"`
 	ScenarioPriority []string                           `json:"scenario_priority,omitempty"`
+	CacheAffinity    bool                               `json:"cache_affinity,omitempty"`
+	Mirror           *config.MirrorConfig               `json:"mirror,omitempty"`
+	RequestTransform *config.RequestTransform           `json:"request_transform,omitempty"`
 }
 
 type updateProfileRequest struct {
 	Providers        []string                           `json:"providers"`
 	Routing          map[string]*scenarioRouteResponse `json:"routing,omitempty"`
 	ScenarioPriority []string                           `json:"scenario_priority,omitempty"`
+	CacheAffinity    *bool                              `json:"cache_affinity,omitempty"` // nil keeps the current setting
+	Mirror           *config.MirrorConfig               `json:"mirror,omitempty"`
+	RequestTransform *config.RequestTransform           `json:"request_transform,omitempty"`
 }
 
 // profileConfigToResponse converts a ProfileConfig to a profileResponse.
@@ -54,6 +64,9 @@ func profileConfigToResponse(name string, pc *config.ProfileConfig) profileRespo
 		Name:             name,
 		Providers:        providers,
 		ScenarioPriority: pc.ScenarioPriority,
+		CacheAffinity:    pc.CacheAffinity,
+		Mirror:           pc.Mirror,
+		RequestTransform: pc.RequestTransform,
 	}
 	if len(pc.Routing) > 0 {
 		resp.Routing = make(map[string]*scenarioRouteResponse)
@@ -91,6 +104,9 @@ func profileConfigToResponse(name string, pc *config.ProfileConfig) profileRespo
 				scenarioResp.FallbackToDefault = route.FallbackToDefault
 			}
 
+			// Serialize custom scenario match rules
+			scenarioResp.Match = route.Match
+
 			resp.Routing[scenario] = scenarioResp
 		}
 	}
@@ -137,6 +153,9 @@ func routingResponseToConfig(routing map[string]*scenarioRouteResponse) map[stri
 				policy.FallbackToDefault = route.FallbackToDefault
 			}
 
+			// Deserialize custom scenario match rules
+			policy.Match = route.Match
+
 			result[scenario] = policy
 		}
 	}
@@ -146,6 +165,36 @@ func routingResponseToConfig(routing map[string]*scenarioRouteResponse) map[stri
 	return result
 }
 
+// validateScenarioMatches checks the match rules of custom scenarios.
+func validateScenarioMatches(routing map[string]*scenarioRouteResponse) error {
+	for scenario, route := range routing {
+		if route == nil || route.Match == nil {
+			continue
+		}
+		if config.IsBuiltinScenario(scenario) {
+			return fmt.Errorf("builtin scenario %q cannot have match rules", scenario)
+		}
+		if err := route.Match.Validate(); err != nil {
+			return fmt.Errorf("scenario %q: %v", scenario, err)
+		}
+	}
+	return nil
+}
+
+// validateMirror checks a profile's shadow traffic settings.
+func validateMirror(m *config.MirrorConfig) error {
+	if m == nil {
+		return nil
+	}
+	providers := make(map[string]*config.ProviderConfig)
+	if m.Provider != "" {
+		if pc := config.GetProvider(m.Provider); pc != nil {
+			providers[m.Provider] = pc
+		}
+	}
+	return m.Validate(providers)
+}
+
 // handleProfiles handles GET /api/v1/profiles and POST /api/v1/profiles.
 func (s *Server) handleProfiles(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
@@ -212,6 +261,18 @@ func (s *Server) createProfile(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "name is required")
 		return
 	}
+	if err := validateScenarioMatches(req.Routing); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateMirror(req.Mirror); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := req.RequestTransform.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, "request_transform: "+err.Error())
+		return
+	}
 
 	store := config.DefaultStore()
 	existing := store.GetProfileConfig(req.Name)
@@ -229,6 +290,9 @@ func (s *Server) createProfile(w http.ResponseWriter, r *http.Request) {
 		Providers:        providers,
 		Routing:          routingResponseToConfig(req.Routing),
 		ScenarioPriority: req.ScenarioPriority,
+		CacheAffinity:    req.CacheAffinity,
+		Mirror:           req.Mirror,
+		RequestTransform: req.RequestTransform,
 	}
 
 	if err := store.SetProfileConfig(req.Name, pc); err != nil {
@@ -251,6 +315,18 @@ func (s *Server) updateProfile(w http.ResponseWriter, r *http.Request, name stri
 		writeError(w, http.StatusBadRequest, "invalid JSON")
 		return
 	}
+	if err := validateScenarioMatches(req.Routing); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateMirror(req.Mirror); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := req.RequestTransform.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, "request_transform: "+err.Error())
+		return
+	}
 
 	providers := req.Providers
 	if providers == nil {
@@ -260,6 +336,11 @@ func (s *Server) updateProfile(w http.ResponseWriter, r *http.Request, name stri
 	existing.Providers = providers
 	existing.Routing = routingResponseToConfig(req.Routing)
 	existing.ScenarioPriority = req.ScenarioPriority
+	existing.Mirror = req.Mirror
+	existing.RequestTransform = req.RequestTransform
+	if req.CacheAffinity != nil {
+		existing.CacheAffinity = *req.CacheAffinity
+	}
 
 	if err := store.SetProfileConfig(name, existing); err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
@@ -0,0 +1,205 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/proxy"
+)
+
+// previousKeyID names the key a promoted key's predecessor is kept under.
+const previousKeyID = "previous"
+
+// providerKeyResponse is the JSON shape returned for a provider key.
+type providerKeyResponse struct {
+	ID       string     `json:"id"`
+	Token    string     `json:"token"`
+	Priority int        `json:"priority"`
+	AddedAt  *time.Time `json:"added_at,omitempty"`
+	Primary  bool       `json:"primary,omitempty"`
+}
+
+// keyVerifyResponse reports whether a provider accepted a key.
+type keyVerifyResponse struct {
+	ID         string `json:"id"`
+	Valid      bool   `json:"valid"`
+	StatusCode int    `json:"status_code,omitempty"`
+	LatencyMs  int    `json:"latency_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// handleProviderKeys handles the staged rotation of a provider's keys:
+//
+//	GET    /api/v1/providers/{name}/keys               list keys, primary first
+//	POST   /api/v1/providers/{name}/keys               add a fallback key
+//	POST   /api/v1/providers/{name}/keys/{id}/verify   send a probe with a key
+//	POST   /api/v1/providers/{name}/keys/{id}/promote  make a key the primary
+//	DELETE /api/v1/providers/{name}/keys/{id}          retire a key
+func (s *Server) handleProviderKeys(w http.ResponseWriter, r *http.Request, name, sub string) {
+	store := config.DefaultStore()
+	pc := store.GetProvider(name)
+	if pc == nil {
+		writeError(w, http.StatusNotFound, "provider not found")
+		return
+	}
+
+	if sub == "" {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, providerKeys(pc))
+		case http.MethodPost:
+			s.addProviderKey(w, r, name, pc)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+		return
+	}
+
+	id, action, _ := strings.Cut(sub, "/")
+	if id != config.PrimaryKeyID && pc.Key(id) == nil {
+		writeError(w, http.StatusNotFound, "key not found")
+		return
+	}
+	switch {
+	case action == "verify" && r.Method == http.MethodPost:
+		verifyProviderKey(w, name, pc, id)
+	case action == "promote" && r.Method == http.MethodPost:
+		promoteProviderKey(w, name, pc, id)
+	case action == "" && r.Method == http.MethodDelete:
+		deleteProviderKey(w, name, pc, id)
+	case action == "verify", action == "promote", action == "":
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// providerKeys lists a provider's keys with masked tokens, the primary
+// auth_token first and the fallbacks in the order they are tried.
+func providerKeys(pc *config.ProviderConfig) []providerKeyResponse {
+	keys := []providerKeyResponse{{ID: config.PrimaryKeyID, Token: maskToken(pc.AuthToken), Primary: true}}
+	for _, k := range pc.FallbackKeys() {
+		resp := providerKeyResponse{ID: k.ID, Token: maskToken(k.Token), Priority: k.Priority}
+		if !k.AddedAt.IsZero() {
+			addedAt := k.AddedAt
+			resp.AddedAt = &addedAt
+		}
+		keys = append(keys, resp)
+	}
+	return keys
+}
+
+func (s *Server) addProviderKey(w http.ResponseWriter, r *http.Request, name string, pc *config.ProviderConfig) {
+	var key config.ProviderKey
+	if err := readJSON(r, &key); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	if s.keys != nil && key.Token != "" {
+		decrypted, err := s.keys.MaybeDecryptToken(key.Token)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "failed to decrypt token")
+			return
+		}
+		key.Token = decrypted
+	}
+	if key.ID == "" {
+		key.ID = unusedKeyID(pc, "key")
+	}
+	key.AddedAt = time.Now().UTC()
+	pc.Keys = append(pc.Keys, &key)
+	if err := pc.ValidateKeys(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := config.DefaultStore().SetProvider(name, pc); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, providerKeys(pc))
+}
+
+// verifyProviderKey sends a synthetic probe to the provider with one key.
+func verifyProviderKey(w http.ResponseWriter, name string, pc *config.ProviderConfig, id string) {
+	if id != config.PrimaryKeyID {
+		pc.AuthToken = pc.Key(id).Token
+	}
+	checker := proxy.GetGlobalHealthChecker()
+	if checker == nil {
+		checker = proxy.NewHealthChecker(nil)
+	}
+	var probe *config.ProbeConfig
+	if hc := config.GetHealthCheck(); hc != nil {
+		probe = hc.Probe
+	}
+	result := checker.ProbeProvider(name, pc, probe)
+	writeJSON(w, http.StatusOK, keyVerifyResponse{
+		ID:         id,
+		Valid:      result.Healthy,
+		StatusCode: result.StatusCode,
+		LatencyMs:  result.LatencyMs,
+		Error:      result.Error,
+	})
+}
+
+// promoteProviderKey makes a fallback key the provider's auth_token. The
+// old auth_token becomes the first fallback key, so requests still in
+// flight with it keep working until it is retired.
+func promoteProviderKey(w http.ResponseWriter, name string, pc *config.ProviderConfig, id string) {
+	if id == config.PrimaryKeyID {
+		writeJSON(w, http.StatusOK, providerKeys(pc))
+		return
+	}
+	promoted := pc.Key(id)
+	keys := make([]*config.ProviderKey, 0, len(pc.Keys))
+	if pc.AuthToken != "" {
+		keys = append(keys, &config.ProviderKey{
+			ID:      unusedKeyID(pc, previousKeyID),
+			Token:   pc.AuthToken,
+			AddedAt: time.Now().UTC(),
+		})
+	}
+	for _, k := range pc.Keys {
+		if k != promoted {
+			keys = append(keys, k)
+		}
+	}
+	pc.AuthToken = promoted.Token
+	pc.Keys = keys
+	if err := config.DefaultStore().SetProvider(name, pc); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, providerKeys(pc))
+}
+
+func deleteProviderKey(w http.ResponseWriter, name string, pc *config.ProviderConfig, id string) {
+	if id == config.PrimaryKeyID {
+		writeError(w, http.StatusBadRequest, "the primary key cannot be retired; promote another key first")
+		return
+	}
+	keys := make([]*config.ProviderKey, 0, len(pc.Keys))
+	for _, k := range pc.Keys {
+		if k != nil && k.ID != id {
+			keys = append(keys, k)
+		}
+	}
+	pc.Keys = keys
+	if err := config.DefaultStore().SetProvider(name, pc); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, providerKeys(pc))
+}
+
+// unusedKeyID returns prefix, or prefix-N when a key already has that ID.
+func unusedKeyID(pc *config.ProviderConfig, prefix string) string {
+	id := prefix
+	for n := 2; id == config.PrimaryKeyID || pc.Key(id) != nil; n++ {
+		id = fmt.Sprintf("%s-%d", prefix, n)
+	}
+	return id
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/proxy"
 )
 
 // providerResponse is the JSON shape returned for a single provider.
@@ -24,6 +25,15 @@ type providerResponse struct {
 	ClaudeEnvVars   map[string]string          `json:"claude_env_vars,omitempty"`
 	CodexEnvVars    map[string]string          `json:"codex_env_vars,omitempty"`
 	OpenCodeEnvVars map[string]string          `json:"opencode_env_vars,omitempty"`
+	GeminiEnvVars   map[string]string          `json:"gemini_env_vars,omitempty"`
+	AiderEnvVars    map[string]string          `json:"aider_env_vars,omitempty"`
+	SafetySettings  map[string]string          `json:"safety_settings,omitempty"`
+	CostModel       *config.CostModel          `json:"cost_model,omitempty"`
+	Transforms      *config.ProviderTransforms `json:"transforms,omitempty"`
+	CaptureBodies   bool                       `json:"capture_bodies,omitempty"`
+	MaxConcurrent   int                        `json:"max_concurrent,omitempty"`
+	QueueSize       int                        `json:"queue_size,omitempty"`
+	QueueTimeoutSec int                        `json:"queue_timeout_sec,omitempty"`
 	Disabled        *config.UnavailableMarking `json:"disabled,omitempty"`
 }
 
@@ -53,6 +63,15 @@ func toProviderResponse(name string, p *config.ProviderConfig, mask bool) provid
 		ClaudeEnvVars:   p.ClaudeEnvVars,
 		CodexEnvVars:    p.CodexEnvVars,
 		OpenCodeEnvVars: p.OpenCodeEnvVars,
+		GeminiEnvVars:   p.GeminiEnvVars,
+		AiderEnvVars:    p.AiderEnvVars,
+		SafetySettings:  p.SafetySettings,
+		CostModel:       p.CostModel,
+		Transforms:      p.Transforms,
+		CaptureBodies:   p.CaptureBodies,
+		MaxConcurrent:   p.MaxConcurrent,
+		QueueSize:       p.QueueSize,
+		QueueTimeoutSec: p.QueueTimeoutSec,
 	}
 	// Include active disabled status
 	disabled := config.DefaultStore().GetDisabledProviders()
@@ -62,6 +81,25 @@ func toProviderResponse(name string, p *config.ProviderConfig, mask bool) provid
 	return resp
 }
 
+// redactSecrets masks the environment variables and the headers set by
+// transforms, which may hold credentials, for readers who can't see them.
+func (resp *providerResponse) redactSecrets() {
+	resp.EnvVars = maskValues(resp.EnvVars)
+	resp.ClaudeEnvVars = maskValues(resp.ClaudeEnvVars)
+	resp.CodexEnvVars = maskValues(resp.CodexEnvVars)
+	resp.OpenCodeEnvVars = maskValues(resp.OpenCodeEnvVars)
+	resp.GeminiEnvVars = maskValues(resp.GeminiEnvVars)
+	resp.AiderEnvVars = maskValues(resp.AiderEnvVars)
+	if resp.Transforms != nil {
+		resp.Transforms = resp.Transforms.Clone()
+		for _, rules := range []*config.TransformRules{resp.Transforms.Request, resp.Transforms.Response} {
+			if rules != nil {
+				rules.SetHeaders = maskValues(rules.SetHeaders)
+			}
+		}
+	}
+}
+
 // handleProviders handles GET /api/v1/providers and POST /api/v1/providers.
 func (s *Server) handleProviders(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
@@ -76,7 +114,8 @@ func (s *Server) handleProviders(w http.ResponseWriter, r *http.Request) {
 
 // handleProvider handles GET/PUT/DELETE /api/v1/providers/{name}
 // and POST /api/v1/providers/{name}/disable, /api/v1/providers/{name}/enable.
-// Also handles GET /api/v1/providers/disabled (list disabled providers).
+// Also handles GET /api/v1/providers/disabled (list disabled providers) and
+// the key rotation endpoints under /api/v1/providers/{name}/keys.
 func (s *Server) handleProvider(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/api/v1/providers/")
 	if path == "" {
@@ -90,6 +129,12 @@ func (s *Server) handleProvider(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Key rotation: /api/v1/providers/{name}/keys[/{id}[/verify|/promote]]
+	if name, rest, ok := strings.Cut(path, "/keys"); ok && (rest == "" || strings.HasPrefix(rest, "/")) {
+		s.handleProviderKeys(w, r, name, strings.TrimPrefix(rest, "/"))
+		return
+	}
+
 	// Check for /disable and /enable sub-paths
 	if strings.HasSuffix(path, "/disable") {
 		name := strings.TrimSuffix(path, "/disable")
@@ -130,7 +175,11 @@ func (s *Server) listProviders(w http.ResponseWriter, r *http.Request) {
 	for _, name := range names {
 		p := store.GetProvider(name)
 		if p != nil {
-			providers = append(providers, toProviderResponse(name, p, true))
+			resp := toProviderResponse(name, p, true)
+			if !showsSecrets(r) {
+				resp.redactSecrets()
+			}
+			providers = append(providers, resp)
 		}
 	}
 	writeJSON(w, http.StatusOK, providers)
@@ -143,7 +192,12 @@ func (s *Server) getProvider(w http.ResponseWriter, r *http.Request, name string
 		writeError(w, http.StatusNotFound, "provider not found")
 		return
 	}
-	writeJSON(w, http.StatusOK, toProviderResponse(name, p, false))
+	redact := !showsSecrets(r)
+	resp := toProviderResponse(name, p, redact)
+	if redact {
+		resp.redactSecrets()
+	}
+	writeJSON(w, http.StatusOK, resp)
 }
 
 func (s *Server) createProvider(w http.ResponseWriter, r *http.Request) {
@@ -184,11 +238,20 @@ func (s *Server) createProvider(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	if err := req.Config.CostModel.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, "cost_model: "+err.Error())
+		return
+	}
+	if err := req.Config.Transforms.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, "transforms: "+err.Error())
+		return
+	}
 
 	if err := store.SetProvider(req.Name, &req.Config); err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	reloadProviderCosts()
 
 	// Add provider to requested profiles
 	for _, profile := range req.AddToProfiles {
@@ -247,6 +310,34 @@ func (s *Server) updateProvider(w http.ResponseWriter, r *http.Request, name str
 	existing.ClaudeEnvVars = update.ClaudeEnvVars
 	existing.CodexEnvVars = update.CodexEnvVars
 	existing.OpenCodeEnvVars = update.OpenCodeEnvVars
+	// Older Web UIs don't send these; keep them unless given
+	if update.GeminiEnvVars != nil {
+		existing.GeminiEnvVars = update.GeminiEnvVars
+	}
+	if update.AiderEnvVars != nil {
+		existing.AiderEnvVars = update.AiderEnvVars
+	}
+	existing.SafetySettings = update.SafetySettings
+
+	if err := update.CostModel.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, "cost_model: "+err.Error())
+		return
+	}
+	existing.CostModel = update.CostModel
+
+	if err := update.Transforms.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, "transforms: "+err.Error())
+		return
+	}
+	existing.Transforms = update.Transforms
+	existing.CaptureBodies = update.CaptureBodies
+	if update.MaxConcurrent < 0 || update.QueueSize < 0 || update.QueueTimeoutSec < 0 {
+		writeError(w, http.StatusBadRequest, "max_concurrent, queue_size and queue_timeout_sec must not be negative")
+		return
+	}
+	existing.MaxConcurrent = update.MaxConcurrent
+	existing.QueueSize = update.QueueSize
+	existing.QueueTimeoutSec = update.QueueTimeoutSec
 
 	// Validate and apply proxy URL
 	if err := config.ValidateProxyURL(update.ProxyURL); err != nil {
@@ -259,9 +350,17 @@ func (s *Server) updateProvider(w http.ResponseWriter, r *http.Request, name str
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	reloadProviderCosts()
 	writeJSON(w, http.StatusOK, toProviderResponse(name, existing, false))
 }
 
+// reloadProviderCosts refreshes the usage tracker after provider cost models change.
+func reloadProviderCosts() {
+	if tracker := proxy.GetGlobalUsageTracker(); tracker != nil {
+		tracker.ReloadPricing()
+	}
+}
+
 func (s *Server) deleteProvider(w http.ResponseWriter, r *http.Request, name string) {
 	store := config.DefaultStore()
 	if store.GetProvider(name) == nil {
@@ -2,6 +2,9 @@ package web
 
 import (
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/dopejs/gozen/internal/config"
@@ -148,3 +151,73 @@ func TestDisabledProvidersList(t *testing.T) {
 		t.Error("test-provider not found in provider list")
 	}
 }
+
+func TestProviderKeyRotation(t *testing.T) {
+	s := setupTestServer(t)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-api-key") != "sk-new-key-0002" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer upstream.Close()
+	store := config.DefaultStore()
+	pc := store.GetProvider("test-provider")
+	pc.BaseURL, pc.AuthToken, pc.HaikuModel = upstream.URL, "sk-old-key-0001", "m"
+	store.SetProvider("test-provider", pc)
+
+	keyIDs := func(w *httptest.ResponseRecorder) string {
+		var keys []providerKeyResponse
+		json.Unmarshal(w.Body.Bytes(), &keys)
+		ids := make([]string, len(keys))
+		for i, k := range keys {
+			ids[i] = k.ID
+			if strings.Contains(k.Token, "key-000") {
+				t.Errorf("key %s token not masked: %s", k.ID, k.Token)
+			}
+		}
+		return strings.Join(ids, ",")
+	}
+
+	// Stage: add the new key as a fallback
+	w := doRequest(s, "POST", "/api/v1/providers/test-provider/keys", map[string]string{"id": "next", "token": "sk-new-key-0002"})
+	if w.Code != http.StatusCreated || keyIDs(w) != "primary,next" {
+		t.Fatalf("add key = %d %s", w.Code, w.Body.String())
+	}
+	if w := doRequest(s, "POST", "/api/v1/providers/test-provider/keys", map[string]string{"id": "next", "token": "x"}); w.Code != http.StatusBadRequest {
+		t.Errorf("duplicate key id = %d, want 400", w.Code)
+	}
+
+	// Verify: the old key is rejected, the new one accepted
+	var verify keyVerifyResponse
+	decodeJSON(t, doRequest(s, "POST", "/api/v1/providers/test-provider/keys/primary/verify", nil), &verify)
+	if verify.Valid || verify.StatusCode != http.StatusUnauthorized {
+		t.Errorf("verify primary = %+v, want rejected", verify)
+	}
+	decodeJSON(t, doRequest(s, "POST", "/api/v1/providers/test-provider/keys/next/verify", nil), &verify)
+	if !verify.Valid {
+		t.Errorf("verify next = %+v, want valid", verify)
+	}
+
+	// Promote: the old primary stays as a fallback
+	w = doRequest(s, "POST", "/api/v1/providers/test-provider/keys/next/promote", nil)
+	if w.Code != http.StatusOK || keyIDs(w) != "primary,previous" {
+		t.Fatalf("promote = %d %s", w.Code, w.Body.String())
+	}
+	if pc := store.GetProvider("test-provider"); pc.AuthToken != "sk-new-key-0002" || pc.Key("previous").Token != "sk-old-key-0001" {
+		t.Errorf("after promote auth_token=%s previous=%+v", pc.AuthToken, pc.Key("previous"))
+	}
+
+	// Retire the old key; the primary cannot be retired
+	if w := doRequest(s, "DELETE", "/api/v1/providers/test-provider/keys/primary", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("delete primary = %d, want 400", w.Code)
+	}
+	w = doRequest(s, "DELETE", "/api/v1/providers/test-provider/keys/previous", nil)
+	if w.Code != http.StatusOK || keyIDs(w) != "primary" {
+		t.Errorf("delete previous = %d %s", w.Code, w.Body.String())
+	}
+	if w := doRequest(s, "DELETE", "/api/v1/providers/test-provider/keys/previous", nil); w.Code != http.StatusNotFound {
+		t.Errorf("delete missing key = %d, want 404", w.Code)
+	}
+}
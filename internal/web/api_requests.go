@@ -49,9 +49,11 @@ func (s *Server) handleRequestsList(w http.ResponseWriter, r *http.Request) {
 
 	// Build filter
 	filter := proxy.RequestFilter{
-		Provider:  query.Get("provider"),
-		SessionID: query.Get("session"),
-		Model:     query.Get("model"),
+		Provider:   query.Get("provider"),
+		SessionID:  query.Get("session"),
+		Model:      query.Get("model"),
+		ErrorsOnly: query.Get("errors_only") == "true",
+		Project:    query.Get("project"),
 	}
 
 	if minStatus := query.Get("status_min"); minStatus != "" {
@@ -1,23 +1,85 @@
 package web
 
 import (
+	"fmt"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/dopejs/gozen/internal/proxy"
 )
 
-// handleSessions handles GET /api/v1/sessions - returns all active sessions.
+// handleSessions handles GET /api/v1/sessions - returns active sessions,
+// most recently active first.
+// Query params:
+//   - limit: maximum number of sessions (default: 100)
+//   - before, after: page cursors from a previous response
+//   - label: only sessions annotated with this label
+//   - bookmarked: "true" for bookmarked sessions only
 func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 
-	insights := proxy.GetAllSessionInsights()
-	if insights == nil {
-		insights = []*proxy.SessionInsight{}
+	query := r.URL.Query()
+	limit := 100
+	if l := query.Get("limit"); l != "" {
+		if n, err := strconv.Atoi(l); err == nil && n > 0 {
+			limit = n
+		}
+	}
+	before, err := parseSessionCursor(query.Get("before"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	after, err := parseSessionCursor(query.Get("after"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	insights, err := annotateSessions(proxy.GetAllSessionInsights(), annotationFilterFromQuery(query))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	sort.Slice(insights, func(i, j int) bool {
+		return sessionKeyOf(insights[j]).less(sessionKeyOf(insights[i]))
+	})
+
+	// Cut the page out of the sorted list: after takes the sessions just
+	// newer than its cursor, before those just older.
+	lo, hi := 0, len(insights)
+	if after != nil {
+		hi = sort.Search(len(insights), func(i int) bool {
+			return !after.less(sessionKeyOf(insights[i]))
+		})
+		lo = max(hi-limit, 0)
+	} else {
+		if before != nil {
+			lo = sort.Search(len(insights), func(i int) bool {
+				return sessionKeyOf(insights[i]).less(*before)
+			})
+		}
+		hi = min(lo+limit, len(insights))
+	}
+
+	var cursors pageCursors
+	cursors.Total = len(insights)
+	if lo < hi {
+		if hi < len(insights) {
+			cursors.NextCursor = sessionKeyOf(insights[hi-1]).String()
+		}
+		if lo > 0 {
+			cursors.PrevCursor = sessionKeyOf(insights[lo]).String()
+		}
+	}
+	page := insights[lo:hi]
+	if page == nil {
+		page = []*proxy.SessionInsight{}
 	}
 
 	// Get cache stats
@@ -27,18 +89,87 @@ func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
 		Sessions  []*proxy.SessionInsight `json:"sessions"`
 		CacheSize int                     `json:"cache_size"`
 		MaxSize   int                     `json:"max_size"`
+		pageCursors
 	}{
-		Sessions:  insights,
-		CacheSize: size,
-		MaxSize:   maxSize,
+		Sessions:    page,
+		CacheSize:   size,
+		MaxSize:     maxSize,
+		pageCursors: cursors,
 	}
 
 	writeJSON(w, http.StatusOK, response)
 }
 
-// handleSession handles GET /api/v1/sessions/{id} - returns a specific session.
+// annotateSessions attaches the stored annotations to the sessions and
+// keeps those passing the filter. Without a log database there are no
+// annotations, so only an empty filter passes anything.
+func annotateSessions(insights []*proxy.SessionInsight, filter proxy.AnnotationFilter) ([]*proxy.SessionInsight, error) {
+	var annotations map[string]*proxy.Annotation
+	if db := proxy.GetGlobalLogDB(); db != nil {
+		var err error
+		if annotations, err = db.Annotations(proxy.AnnotationSession); err != nil {
+			return nil, err
+		}
+	}
+	kept := insights[:0]
+	for _, insight := range insights {
+		insight.Annotation = annotations[insight.SessionID]
+		if filter.Match(insight.Annotation) {
+			kept = append(kept, insight)
+		}
+	}
+	return kept, nil
+}
+
+// sessionKey orders sessions for paging: by last activity, then by ID.
+type sessionKey struct {
+	activity int64 // Unix nanoseconds
+	id       string
+}
+
+func sessionKeyOf(insight *proxy.SessionInsight) sessionKey {
+	k := sessionKey{id: insight.SessionID}
+	if insight.LastActivity != nil {
+		k.activity = insight.LastActivity.UnixNano()
+	}
+	return k
+}
+
+func (k sessionKey) less(o sessionKey) bool {
+	if k.activity != o.activity {
+		return k.activity < o.activity
+	}
+	return k.id < o.id
+}
+
+// String encodes the key as a cursor.
+func (k sessionKey) String() string {
+	return strconv.FormatInt(k.activity, 10) + "." + k.id
+}
+
+// parseSessionCursor decodes a cursor made by sessionKey.String, returning
+// nil for an empty one.
+func parseSessionCursor(cursor string) (*sessionKey, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	activity, id, ok := strings.Cut(cursor, ".")
+	n, err := strconv.ParseInt(activity, 10, 64)
+	if !ok || err != nil || id == "" {
+		return nil, fmt.Errorf("invalid session cursor %q", cursor)
+	}
+	return &sessionKey{activity: n, id: id}, nil
+}
+
+// handleSession handles GET /api/v1/sessions/{id} - returns a specific
+// session with its context window use over time - and
+// PATCH /api/v1/sessions/{id} - annotates it.
 func (s *Server) handleSession(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
+	if id, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/api/v1/sessions/"), "/replay"); ok {
+		s.handleSessionReplay(w, r, id)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodPatch {
 		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
@@ -51,12 +182,23 @@ func (s *Server) handleSession(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "session ID required")
 		return
 	}
+	if r.Method == http.MethodPatch {
+		s.handleSessionAnnotation(w, r, sessionID)
+		return
+	}
 
 	insight := proxy.GetSessionInsight(sessionID)
 	if insight == nil {
 		writeError(w, http.StatusNotFound, "session not found")
 		return
 	}
+	if db := proxy.GetGlobalLogDB(); db != nil {
+		if a, err := db.GetAnnotation(proxy.AnnotationSession, sessionID); err != nil {
+			s.logger.Printf("Failed to load session annotation: %v", err)
+		} else {
+			insight.Annotation = a
+		}
+	}
 
 	// Check for context warning
 	threshold := 100000 // Default 100k tokens
@@ -69,12 +211,88 @@ func (s *Server) handleSession(w http.ResponseWriter, r *http.Request) {
 	warning := proxy.GetContextWarning(sessionID, threshold)
 
 	response := struct {
-		Insight *proxy.SessionInsight   `json:"insight"`
-		Warning *proxy.ContextWarning   `json:"warning,omitempty"`
+		Insight     *proxy.SessionInsight     `json:"insight"`
+		Warning     *proxy.ContextWarning     `json:"warning,omitempty"`
+		Utilization *proxy.ContextUtilization `json:"utilization,omitempty"`
 	}{
-		Insight: insight,
-		Warning: warning,
+		Insight:     insight,
+		Warning:     warning,
+		Utilization: proxy.GetContextUtilization(sessionID),
 	}
 
 	writeJSON(w, http.StatusOK, response)
 }
+
+// sessionReplayRequest is the body of POST /api/v1/sessions/{id}/replay.
+type sessionReplayRequest struct {
+	RequestID string `json:"request_id,omitempty"` // captured request to replay; default: the session's latest
+	proxy.ReplayOptions
+}
+
+// handleSessionReplay re-sends a captured request of a session, optionally
+// to another provider or model, and returns the diff between the captured
+// response and the new one. The session's requests must have been captured
+// (capture_bodies or the X-Zen-Capture header).
+// POST /api/v1/sessions/{id}/replay
+func (s *Server) handleSessionReplay(w http.ResponseWriter, r *http.Request, sessionID string) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if sessionID == "" || strings.Contains(sessionID, "/") {
+		writeError(w, http.StatusBadRequest, "session ID required")
+		return
+	}
+	var req sessionReplayRequest
+	if r.ContentLength != 0 {
+		if err := readJSON(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+			return
+		}
+	}
+	db := proxy.GetGlobalLogDB()
+	if db == nil {
+		writeError(w, http.StatusServiceUnavailable, "log database not available")
+		return
+	}
+
+	var capture *proxy.BodyCapture
+	if req.RequestID != "" {
+		attempts, err := db.GetBodyCaptures(req.RequestID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		// The last attempt is the one the client got the response of
+		if n := len(attempts); n > 0 && sessionMatches(attempts[n-1].SessionID, sessionID) {
+			capture = &attempts[n-1]
+		}
+	} else {
+		var err error
+		if capture, err = db.LatestSessionCapture(sessionID); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	if capture == nil {
+		writeError(w, http.StatusNotFound, "no captured request for session; enable body capture to replay it")
+		return
+	}
+
+	result, err := proxy.Replay(r.Context(), capture, req.ReplayOptions)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	if s.logger != nil {
+		s.logger.Printf("Replayed request %s of session %s against %s (%s)", capture.RequestID, sessionID,
+			result.Replay.Provider, result.Replay.Model)
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// sessionMatches reports whether a captured session key, which may be
+// "<profile>:<session>", is the session id.
+func sessionMatches(key, id string) bool {
+	return key == id || strings.HasSuffix(key, ":"+id)
+}
@@ -0,0 +1,170 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/proxy"
+)
+
+func TestSessionReplay(t *testing.T) {
+	s := setupTestServer(t)
+	if err := proxy.InitGlobalLogger(t.TempDir()); err != nil {
+		t.Fatalf("InitGlobalLogger() error: %v", err)
+	}
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"type":"message","content":[{"type":"text","text":"new answer"}]}`))
+	}))
+	defer upstream.Close()
+	config.SetProvider("test-provider", &config.ProviderConfig{BaseURL: upstream.URL, AuthToken: "t"})
+
+	db := proxy.GetGlobalLogDB()
+	for _, c := range []proxy.BodyCapture{
+		{RequestID: "replay_req_1", Provider: "test-provider", SessionID: "default:replay-session", StatusCode: 200,
+			RequestBody:  `{"model":"claude-sonnet-4-5","messages":[{"role":"user","content":"hi"}]}`,
+			ResponseBody: `{"type":"message","content":[{"type":"text","text":"old answer"}]}`},
+		{RequestID: "replay_req_other", Provider: "test-provider", SessionID: "default:other-session", StatusCode: 200,
+			RequestBody: `{"model":"m","messages":[]}`},
+	} {
+		c.Timestamp = time.Now()
+		if err := db.InsertBodyCapture(c); err != nil {
+			t.Fatalf("InsertBodyCapture: %v", err)
+		}
+	}
+
+	w := doRequest(s, http.MethodPost, "/api/v1/sessions/replay-session/replay", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var res proxy.ReplayResult
+	decodeJSON(t, w, &res)
+	if res.RequestID != "replay_req_1" || res.Identical || res.Original.Text != "old answer" || res.Replay.Text != "new answer" {
+		t.Errorf("result = %+v", res)
+	}
+	if len(res.Diff) != 2 || res.Diff[0].Op != "-" || res.Diff[1].Op != "+" {
+		t.Errorf("diff = %+v", res.Diff)
+	}
+
+	// A request of another session is not replayed under this one
+	w = doRequest(s, http.MethodPost, "/api/v1/sessions/replay-session/replay", map[string]string{"request_id": "replay_req_other"})
+	if w.Code != http.StatusNotFound {
+		t.Errorf("other session's request: status = %d", w.Code)
+	}
+	w = doRequest(s, http.MethodPost, "/api/v1/sessions/replay-session/replay", map[string]string{"provider": "missing"})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("missing provider: status = %d", w.Code)
+	}
+	w = doRequest(s, http.MethodGet, "/api/v1/sessions/replay-session/replay", nil)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET: status = %d", w.Code)
+	}
+}
+
+func TestSessionAnnotations(t *testing.T) {
+	s := setupTestServer(t)
+	if err := proxy.InitGlobalLogger(t.TempDir()); err != nil {
+		t.Fatalf("InitGlobalLogger() error: %v", err)
+	}
+	proxy.AddTurnToSession("annotated-session", proxy.TurnUsage{InputTokens: 10, Timestamp: time.Now()})
+	proxy.AddTurnToSession("plain-session", proxy.TurnUsage{InputTokens: 10, Timestamp: time.Now()})
+	t.Cleanup(func() {
+		proxy.ClearSessionUsage("annotated-session")
+		proxy.ClearSessionUsage("plain-session")
+	})
+
+	w := doRequest(s, http.MethodPatch, "/api/v1/sessions/annotated-session",
+		map[string]interface{}{"labels": []string{"regression", " regression"}, "note": "wrong tool call"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	// A later patch keeps the fields it leaves out
+	w = doRequest(s, http.MethodPatch, "/api/v1/sessions/annotated-session", map[string]bool{"bookmarked": true})
+	var patched struct {
+		Annotation *proxy.Annotation `json:"annotation"`
+	}
+	decodeJSON(t, w, &patched)
+	if a := patched.Annotation; a == nil || len(a.Labels) != 1 || a.Note != "wrong tool call" || !a.Bookmarked {
+		t.Fatalf("annotation = %+v", a)
+	}
+
+	w = doRequest(s, http.MethodGet, "/api/v1/sessions/annotated-session", nil)
+	var got struct {
+		Insight proxy.SessionInsight `json:"insight"`
+	}
+	decodeJSON(t, w, &got)
+	if got.Insight.Annotation == nil || got.Insight.Annotation.Note != "wrong tool call" {
+		t.Errorf("session annotation = %+v", got.Insight.Annotation)
+	}
+
+	w = doRequest(s, http.MethodGet, "/api/v1/sessions?label=regression", nil)
+	var list struct {
+		Sessions []proxy.SessionInsight `json:"sessions"`
+		Total    int                    `json:"total"`
+	}
+	decodeJSON(t, w, &list)
+	if len(list.Sessions) != 1 || list.Sessions[0].SessionID != "annotated-session" || list.Total != 1 {
+		t.Errorf("sessions labelled regression = %+v", list.Sessions)
+	}
+
+	w = doRequest(s, http.MethodPatch, "/api/v1/sessions/annotated-session", map[string]interface{}{"labels": []string{strings.Repeat("x", 100)}})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("long label: status = %d", w.Code)
+	}
+}
+
+func TestLogAnnotations(t *testing.T) {
+	s := setupTestServer(t)
+	if err := proxy.InitGlobalLogger(t.TempDir()); err != nil {
+		t.Fatalf("InitGlobalLogger() error: %v", err)
+	}
+	db := proxy.GetGlobalLogDB()
+	db.Insert(proxy.LogEntry{Timestamp: time.Now(), Level: proxy.LogLevelError, Provider: "annotated-log-provider", Message: "boom"})
+	time.Sleep(700 * time.Millisecond)
+	entries, err := db.Query(proxy.LogFilter{Provider: "annotated-log-provider", Limit: 1})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Query = %v, %v", entries, err)
+	}
+	id := strconv.FormatInt(entries[0].ID, 10)
+
+	w := doRequest(s, http.MethodPatch, "/api/v1/logs/"+id, map[string]interface{}{"labels": []string{"annotated-log-label"}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	w = doRequest(s, http.MethodGet, "/api/v1/logs?label=annotated-log-label", nil)
+	var resp proxy.LogsResponse
+	decodeJSON(t, w, &resp)
+	if len(resp.Entries) != 1 || resp.Entries[0].Message != "boom" || resp.Entries[0].Annotation == nil || resp.Total != 1 {
+		t.Errorf("labelled logs = %+v", resp.Entries)
+	}
+
+	w = doRequest(s, http.MethodPatch, "/api/v1/logs/999999999", map[string]interface{}{"note": "x"})
+	if w.Code != http.StatusNotFound {
+		t.Errorf("missing entry: status = %d", w.Code)
+	}
+	w = doRequest(s, http.MethodPatch, "/api/v1/logs/abc", map[string]interface{}{"note": "x"})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("bad ID: status = %d", w.Code)
+	}
+}
+
+func TestSessionContextUtilization(t *testing.T) {
+	s := setupTestServer(t)
+	proxy.AddTurnToSession("utilization-session", proxy.TurnUsage{InputTokens: 10, ContextTokens: 50000, Model: "claude-sonnet-4-5", Timestamp: time.Now()})
+	t.Cleanup(func() { proxy.ClearSessionUsage("utilization-session") })
+
+	w := doRequest(s, http.MethodGet, "/api/v1/sessions/utilization-session", nil)
+	var got struct {
+		Utilization *proxy.ContextUtilization `json:"utilization"`
+	}
+	decodeJSON(t, w, &got)
+	if u := got.Utilization; u == nil || len(u.Timeline) != 1 || u.Current.Window != 200000 || u.Current.Percent != 25 || u.WarnPercent != 80 {
+		t.Errorf("utilization = %+v", got.Utilization)
+	}
+}
@@ -3,6 +3,7 @@ package web
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -119,6 +120,40 @@ func (s *Server) handleSyncPush(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]string{"status": "pushed"})
 }
 
+// handleSyncRotatePassphrase handles POST /api/v1/sync/rotate-passphrase
+func (s *Server) handleSyncRotatePassphrase(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var req struct {
+		Passphrase string `json:"passphrase"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+	mgr, err := s.getOrCreateSyncManager()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+	if err := mgr.RotatePassphrase(ctx, req.Passphrase); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, gosync.ErrWrongPassphrase) || errors.Is(err, gosync.ErrPassphraseRequired) {
+			status = http.StatusConflict
+		}
+		writeError(w, status, "rotate failed: "+err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":      "rotated",
+		"key_version": mgr.Status().KeyVersion,
+	})
+}
+
 // handleSyncStatus handles GET /api/v1/sync/status
 func (s *Server) handleSyncStatus(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -0,0 +1,73 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+	gosync "github.com/dopejs/gozen/internal/sync"
+)
+
+// handleTeam handles GET /api/v1/team
+func (s *Server) handleTeam(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeJSON(w, http.StatusOK, config.DefaultStore().TeamStatus())
+}
+
+// handleTeamPull handles POST /api/v1/team/pull
+func (s *Server) handleTeamPull(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	cfg := config.DefaultStore().GetTeamSource()
+	if cfg == nil || cfg.Backend == "" {
+		writeError(w, http.StatusBadRequest, "team not configured")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+	if _, err := gosync.PullTeam(ctx, cfg); err != nil {
+		writeError(w, http.StatusInternalServerError, "pull failed: "+err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, config.DefaultStore().TeamStatus())
+}
+
+// handleTeamPush handles POST /api/v1/team/push
+func (s *Server) handleTeamPush(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var req struct {
+		IncludeTokens bool `json:"include_tokens"`
+	}
+	if r.ContentLength > 0 {
+		if err := readJSON(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+			return
+		}
+	}
+	cfg := config.DefaultStore().GetTeamSource()
+	if cfg == nil || cfg.Backend == "" {
+		writeError(w, http.StatusBadRequest, "team not configured")
+		return
+	}
+	layer := config.DefaultStore().TeamLayerFromConfig(req.IncludeTokens)
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+	if err := gosync.PushTeam(ctx, cfg, layer); err != nil {
+		writeError(w, http.StatusInternalServerError, "push failed: "+err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":    "pushed",
+		"providers": len(layer.Providers),
+		"profiles":  len(layer.Profiles),
+	})
+}
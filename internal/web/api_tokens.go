@@ -0,0 +1,251 @@
+package web
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APITokenFile keeps the API tokens, as SHA-256 hashes, in the config dir.
+const APITokenFile = "api_tokens.json"
+
+// API token scopes.
+const (
+	TokenScopeRead  = "read"  // acts with the viewer role
+	TokenScopeAdmin = "admin" // acts with the admin role
+)
+
+const (
+	apiTokenPrefix = "zen_"
+
+	// tokenUseResolution is how stale a token's last use may get before it
+	// is written again, so busy scripts don't rewrite the file per request.
+	tokenUseResolution = time.Minute
+)
+
+// APIToken is an API token as listed by /api/v1/auth/tokens. The token
+// itself is only returned when it is created.
+type APIToken struct {
+	ID           string     `json:"id"`
+	Name         string     `json:"name"`
+	Scope        string     `json:"scope"`
+	Hint         string     `json:"hint"` // last four characters of the token
+	CreatedAt    time.Time  `json:"created_at"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"`
+	LastUsedFrom string     `json:"last_used_from,omitempty"` // client IP
+}
+
+// role returns the role requests made with the token act with.
+func (t *APIToken) role() Role {
+	if t.Scope == TokenScopeAdmin {
+		return RoleAdmin
+	}
+	return RoleViewer
+}
+
+type storedToken struct {
+	APIToken
+	Hash string `json:"hash"` // hex SHA-256 of the token
+}
+
+// tokenStore holds the API tokens, persisted to APITokenFile.
+type tokenStore struct {
+	mu     sync.Mutex
+	path   string // empty keeps the tokens in memory only
+	tokens []*storedToken
+}
+
+func loadTokenStore(path string) *tokenStore {
+	ts := &tokenStore{path: path}
+	if data, err := os.ReadFile(path); err == nil {
+		// Ignore unmarshal errors - a broken file leaves no tokens valid
+		_ = json.Unmarshal(data, &ts.tokens)
+	}
+	return ts
+}
+
+// saveLocked writes the tokens to disk. The caller holds mu.
+func (ts *tokenStore) saveLocked() error {
+	if ts.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(ts.tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(ts.path, append(data, '\n'), 0600)
+}
+
+func (ts *tokenStore) list() []APIToken {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	tokens := make([]APIToken, 0, len(ts.tokens))
+	for _, t := range ts.tokens {
+		tokens = append(tokens, t.APIToken)
+	}
+	return tokens
+}
+
+// create adds a token and returns it along with the token string, which is
+// not kept.
+func (ts *tokenStore) create(name, scope string) (APIToken, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return APIToken{}, "", err
+	}
+	secret := apiTokenPrefix + hex.EncodeToString(b)
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return APIToken{}, "", err
+	}
+
+	t := &storedToken{
+		APIToken: APIToken{
+			ID:        hex.EncodeToString(id),
+			Name:      name,
+			Scope:     scope,
+			Hint:      secret[len(secret)-4:],
+			CreatedAt: time.Now().UTC(),
+		},
+		Hash: hashToken(secret),
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.tokens = append(ts.tokens, t)
+	if err := ts.saveLocked(); err != nil {
+		ts.tokens = ts.tokens[:len(ts.tokens)-1]
+		return APIToken{}, "", err
+	}
+	return t.APIToken, secret, nil
+}
+
+// revoke removes a token, reporting whether it existed.
+func (ts *tokenStore) revoke(id string) (bool, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	for i, t := range ts.tokens {
+		if t.ID == id {
+			ts.tokens = append(ts.tokens[:i:i], ts.tokens[i+1:]...)
+			return true, ts.saveLocked()
+		}
+	}
+	return false, nil
+}
+
+// authenticate returns the token matching secret, recording its use from
+// ip, or nil if none does.
+func (ts *tokenStore) authenticate(secret, ip string) *APIToken {
+	if !strings.HasPrefix(secret, apiTokenPrefix) {
+		return nil
+	}
+	hash := []byte(hashToken(secret))
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	for _, t := range ts.tokens {
+		if subtle.ConstantTimeCompare(hash, []byte(t.Hash)) != 1 {
+			continue
+		}
+		now := time.Now().UTC()
+		if t.LastUsedAt == nil || now.Sub(*t.LastUsedAt) >= tokenUseResolution || t.LastUsedFrom != ip {
+			t.LastUsedAt = &now
+			t.LastUsedFrom = ip
+			_ = ts.saveLocked()
+		}
+		token := t.APIToken
+		return &token
+	}
+	return nil
+}
+
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header.
+func bearerToken(r *http.Request) (string, bool) {
+	auth := r.Header.Get("Authorization")
+	scheme, token, ok := strings.Cut(auth, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// handleAPITokens handles GET/POST /api/v1/auth/tokens - lists the API
+// tokens or creates one. The created token is only returned once.
+func (s *Server) handleAPITokens(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"tokens": s.tokens.list()})
+
+	case http.MethodPost:
+		var req struct {
+			Name  string `json:"name"`
+			Scope string `json:"scope"`
+		}
+		if err := readJSON(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+			return
+		}
+		req.Name = strings.TrimSpace(req.Name)
+		if req.Name == "" {
+			writeError(w, http.StatusBadRequest, "name is required")
+			return
+		}
+		if req.Scope == "" {
+			req.Scope = TokenScopeRead
+		}
+		if req.Scope != TokenScopeRead && req.Scope != TokenScopeAdmin {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid scope %q: use %s or %s", req.Scope, TokenScopeRead, TokenScopeAdmin))
+			return
+		}
+
+		token, secret, err := s.tokens.create(req.Name, req.Scope)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to create token: "+err.Error())
+			return
+		}
+		writeJSON(w, http.StatusCreated, struct {
+			APIToken
+			Token string `json:"token"`
+		}{token, secret})
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleAPIToken handles DELETE /api/v1/auth/tokens/{id} - revokes a token.
+func (s *Server) handleAPIToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/v1/auth/tokens/"), "/")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "token ID required")
+		return
+	}
+
+	found, err := s.tokens.revoke(id)
+	switch {
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "failed to revoke token: "+err.Error())
+	case !found:
+		writeError(w, http.StatusNotFound, "token not found")
+	default:
+		writeJSON(w, http.StatusOK, map[string]string{"status": "revoked"})
+	}
+}
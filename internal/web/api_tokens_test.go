@@ -0,0 +1,118 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dopejs/gozen/internal/config"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// remoteRequest sends a request from a non-local address, with a Bearer
+// token when token is set.
+func remoteRequest(s *Server, method, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader("{}"))
+	req.RemoteAddr = "10.0.0.1:12345"
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.httpServer.Handler.ServeHTTP(w, req)
+	return w
+}
+
+// localRequest sends a request from localhost, which needs no login.
+func localRequest(s *Server, method, path string, body interface{}) *httptest.ResponseRecorder {
+	data, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, path, bytes.NewReader(data))
+	req.RemoteAddr = "127.0.0.1:12345"
+	w := httptest.NewRecorder()
+	s.httpServer.Handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestAPITokens(t *testing.T) {
+	s, cleanup := setupTestAuth(t)
+	defer cleanup()
+	hash, _ := bcrypt.GenerateFromPassword([]byte("testpass"), bcrypt.MinCost)
+	config.SetWebPasswordHash(string(hash))
+
+	create := func(name, scope string) string {
+		t.Helper()
+		w := localRequest(s, "POST", "/api/v1/auth/tokens", map[string]string{"name": name, "scope": scope})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("create %s token: got %d: %s", scope, w.Code, w.Body.String())
+		}
+		var resp struct {
+			APIToken
+			Token string `json:"token"`
+		}
+		decodeJSON(t, w, &resp)
+		if !strings.HasPrefix(resp.Token, apiTokenPrefix) || resp.Scope != scope || resp.Hint != resp.Token[len(resp.Token)-4:] {
+			t.Fatalf("created token = %+v", resp)
+		}
+		return resp.Token
+	}
+	read := create("ci", TokenScopeRead)
+	admin := create("deploy", TokenScopeAdmin)
+
+	if w := remoteRequest(s, "GET", "/api/v1/providers", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("no token: got %d, want 401", w.Code)
+	}
+	if w := remoteRequest(s, "GET", "/api/v1/providers", apiTokenPrefix+"bogus"); w.Code != http.StatusUnauthorized {
+		t.Errorf("unknown token: got %d, want 401", w.Code)
+	}
+	if w := remoteRequest(s, "GET", "/api/v1/providers", read); w.Code != http.StatusOK {
+		t.Errorf("read token GET: got %d, want 200", w.Code)
+	}
+	if w := remoteRequest(s, "POST", "/api/v1/reload", read); w.Code != http.StatusForbidden {
+		t.Errorf("read token POST: got %d, want 403", w.Code)
+	}
+	if w := remoteRequest(s, "GET", "/api/v1/auth/tokens", read); w.Code != http.StatusForbidden {
+		t.Errorf("read token listing tokens: got %d, want 403", w.Code)
+	}
+	if w := remoteRequest(s, "POST", "/api/v1/reload", admin); w.Code != http.StatusOK {
+		t.Errorf("admin token POST: got %d, want 200", w.Code)
+	}
+
+	// The list shows last use but never the token or its hash
+	w := localRequest(s, "GET", "/api/v1/auth/tokens", nil)
+	if strings.Contains(w.Body.String(), read) || strings.Contains(w.Body.String(), hashToken(read)) {
+		t.Fatalf("token list leaks the token: %s", w.Body.String())
+	}
+	var list struct {
+		Tokens []APIToken `json:"tokens"`
+	}
+	decodeJSON(t, w, &list)
+	if len(list.Tokens) != 2 {
+		t.Fatalf("listed %d tokens, want 2", len(list.Tokens))
+	}
+	ci := list.Tokens[0]
+	if ci.Name != "ci" || ci.LastUsedAt == nil || ci.LastUsedFrom != "10.0.0.1" {
+		t.Errorf("ci token = %+v, want last use from 10.0.0.1", ci)
+	}
+
+	// Tokens survive a restart, and revoked ones stop working
+	reloaded := loadTokenStore(filepath.Join(config.ConfigDirPath(), APITokenFile))
+	if reloaded.authenticate(read, "10.0.0.1") == nil {
+		t.Error("token not persisted")
+	}
+	if w := localRequest(s, "DELETE", "/api/v1/auth/tokens/"+ci.ID, nil); w.Code != http.StatusOK {
+		t.Fatalf("revoke: got %d: %s", w.Code, w.Body.String())
+	}
+	if w := remoteRequest(s, "GET", "/api/v1/providers", read); w.Code != http.StatusUnauthorized {
+		t.Errorf("revoked token: got %d, want 401", w.Code)
+	}
+	if w := localRequest(s, "DELETE", "/api/v1/auth/tokens/"+ci.ID, nil); w.Code != http.StatusNotFound {
+		t.Errorf("revoking twice: got %d, want 404", w.Code)
+	}
+
+	if w := localRequest(s, "POST", "/api/v1/auth/tokens", map[string]string{"name": "x", "scope": "root"}); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid scope: got %d, want 400", w.Code)
+	}
+}
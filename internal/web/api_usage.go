@@ -1,41 +1,66 @@
 package web
 
 import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/dopejs/gozen/internal/config"
 	"github.com/dopejs/gozen/internal/proxy"
 )
 
-// handleUsage handles GET /api/v1/usage - returns recent usage entries.
+// usageResponse is the response of GET /api/v1/usage.
+type usageResponse struct {
+	Entries []proxy.UsageEntry `json:"entries"`
+	pageCursors
+}
+
+// handleUsage handles GET /api/v1/usage - returns usage entries, newest first.
+// Query params:
+//   - limit: maximum number of entries (default: 100)
+//   - before, after: page cursors from a previous response
+//   - tag: only entries sent with this X-Zen-Tags tag
 func (s *Server) handleUsage(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 
+	page, err := pageFromQuery(r.URL.Query(), 100)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	resp := usageResponse{Entries: []proxy.UsageEntry{}}
 	tracker := proxy.GetGlobalUsageTracker()
 	if tracker == nil {
-		writeJSON(w, http.StatusOK, []proxy.UsageEntry{})
+		writeJSON(w, http.StatusOK, resp)
 		return
 	}
 
-	limit := 100
-	if l := r.URL.Query().Get("limit"); l != "" {
-		if n, err := strconv.Atoi(l); err == nil && n > 0 {
-			limit = n
-		}
+	tag := r.URL.Query().Get("tag")
+	entries, more, err := tracker.ListUsage(tag, page)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
 	}
-
-	entries, err := tracker.GetRecentUsage(limit)
+	total, err := tracker.CountUsage(tag)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	writeJSON(w, http.StatusOK, entries)
+	if len(entries) > 0 {
+		resp.Entries = entries
+		resp.pageCursors = cursorsFor(page, more, entries[0].ID, entries[len(entries)-1].ID)
+	}
+	resp.Total = total
+	writeJSON(w, http.StatusOK, resp)
 }
 
 // handleUsageSummary handles GET /api/v1/usage/summary - returns usage summary.
@@ -44,6 +69,7 @@ func (s *Server) handleUsage(w http.ResponseWriter, r *http.Request) {
 //   - since: RFC3339 timestamp for custom range start
 //   - until: RFC3339 timestamp for custom range end
 //   - project: filter by project path
+//   - tag: filter by X-Zen-Tags tag
 func (s *Server) handleUsageSummary(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -61,6 +87,7 @@ func (s *Server) handleUsageSummary(w http.ResponseWriter, r *http.Request) {
 	}
 
 	projectPath := r.URL.Query().Get("project")
+	tag := r.URL.Query().Get("tag")
 
 	// Check for custom time range
 	sinceStr := r.URL.Query().Get("since")
@@ -84,11 +111,13 @@ func (s *Server) handleUsageSummary(w http.ResponseWriter, r *http.Request) {
 			since = until.Add(-maxDuration)
 		}
 
-		summary, err := tracker.GetSummaryByTimeRange(since, until, projectPath)
+		summary, err := tracker.GetTaggedSummaryByTimeRange(since, until, projectPath, tag)
 		if err != nil {
 			writeError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
+		summary.ConvertCurrency(config.GetCurrency())
+		attachResponseCacheStats(summary)
 		writeJSON(w, http.StatusOK, summary)
 		return
 	}
@@ -99,21 +128,37 @@ func (s *Server) handleUsageSummary(w http.ResponseWriter, r *http.Request) {
 		period = "day"
 	}
 
-	summary, err := tracker.GetSummary(period, projectPath)
+	summary, err := tracker.GetTaggedSummary(period, projectPath, tag)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	summary.ConvertCurrency(config.GetCurrency())
+	attachResponseCacheStats(summary)
 
 	writeJSON(w, http.StatusOK, summary)
 }
 
+// attachResponseCacheStats adds response cache hit counters to a summary
+// when the cache is enabled. The counters cover the daemon's lifetime, not
+// the summary's period.
+func attachResponseCacheStats(summary *proxy.UsageSummary) {
+	cache := proxy.GetGlobalResponseCache()
+	if cache == nil || !cache.IsEnabled() {
+		return
+	}
+	stats := cache.Stats()
+	summary.ResponseCache = &stats
+}
+
 // handleUsageHourly handles GET /api/v1/usage/hourly - returns hourly usage for charts.
 // Query params:
 //   - hours: number of hours to look back (default: 24)
 //   - since: RFC3339 timestamp for custom range start
 //   - until: RFC3339 timestamp for custom range end
 //   - group_by: "provider" or "model" for grouped data
+//
+// Costs are in the display currency.
 func (s *Server) handleUsageHourly(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -126,6 +171,7 @@ func (s *Server) handleUsageHourly(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	rate := config.GetCurrency().GetRate()
 	groupBy := r.URL.Query().Get("group_by")
 	sinceStr := r.URL.Query().Get("since")
 	untilStr := r.URL.Query().Get("until")
@@ -168,6 +214,9 @@ func (s *Server) handleUsageHourly(w http.ResponseWriter, r *http.Request) {
 			writeError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
+		for i := range data {
+			data[i].Cost *= rate
+		}
 		writeJSON(w, http.StatusOK, data)
 		return
 	}
@@ -178,6 +227,9 @@ func (s *Server) handleUsageHourly(w http.ResponseWriter, r *http.Request) {
 			writeError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
+		for i := range data {
+			data[i].Cost *= rate
+		}
 		writeJSON(w, http.StatusOK, data)
 		return
 	}
@@ -188,10 +240,162 @@ func (s *Server) handleUsageHourly(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	for i := range data {
+		data[i].Cost *= rate
+	}
 
 	writeJSON(w, http.StatusOK, data)
 }
 
+// usageExportRow is one request in a usage export.
+type usageExportRow struct {
+	Timestamp           string   `json:"timestamp"`
+	SessionID           string   `json:"session_id"`
+	Provider            string   `json:"provider"`
+	Model               string   `json:"model"`
+	InputTokens         int      `json:"input_tokens"`
+	OutputTokens        int      `json:"output_tokens"`
+	CacheCreationTokens int      `json:"cache_creation_tokens"`
+	CacheReadTokens     int      `json:"cache_read_tokens"`
+	CostUSD             float64  `json:"cost_usd"`
+	LatencyMs           int      `json:"latency_ms"`
+	ProjectPath         string   `json:"project_path"`
+	ClientType          string   `json:"client_type"`
+	User                string   `json:"user"`
+	Tags                []string `json:"tags,omitempty"`
+	Batch               bool     `json:"batch"`
+	proxy.CostBreakdown
+}
+
+var usageExportColumns = []string{
+	"timestamp", "session_id", "provider", "model", "input_tokens", "output_tokens",
+	"cache_creation_tokens", "cache_read_tokens", "cost_usd", "latency_ms", "project_path", "client_type", "user", "tags",
+	"batch", "input_cost_usd", "output_cost_usd", "cache_write_cost_usd", "cache_read_cost_usd", "batch_discount_usd",
+}
+
+func (row *usageExportRow) csvRecord() []string {
+	return []string{
+		row.Timestamp, row.SessionID, row.Provider, row.Model,
+		strconv.Itoa(row.InputTokens), strconv.Itoa(row.OutputTokens),
+		strconv.Itoa(row.CacheCreationTokens), strconv.Itoa(row.CacheReadTokens),
+		strconv.FormatFloat(row.CostUSD, 'f', -1, 64), strconv.Itoa(row.LatencyMs),
+		row.ProjectPath, row.ClientType, row.User, strings.Join(row.Tags, ","),
+		strconv.FormatBool(row.Batch),
+		strconv.FormatFloat(row.InputCost, 'f', -1, 64), strconv.FormatFloat(row.OutputCost, 'f', -1, 64),
+		strconv.FormatFloat(row.CacheWriteCost, 'f', -1, 64), strconv.FormatFloat(row.CacheReadCost, 'f', -1, 64),
+		strconv.FormatFloat(row.BatchDiscount, 'f', -1, 64),
+	}
+}
+
+// handleUsageExport handles GET /api/v1/usage/export - streams request-level
+// usage as CSV or JSON Lines.
+// Query params:
+//   - format: "csv" or "jsonl" (default: "csv")
+//   - period: "day", "week", "month" or "all" (default: "all")
+//   - since, until: RFC3339 timestamps for a custom range, overriding period
+//   - project: filter by project path
+//   - tag: filter by X-Zen-Tags tag
+func (s *Server) handleUsageExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	q := r.URL.Query()
+	format := q.Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "jsonl" {
+		writeError(w, http.StatusBadRequest, "format must be csv or jsonl")
+		return
+	}
+
+	since := proxy.PeriodStart(q.Get("period"))
+	var until time.Time
+	if v := q.Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid since timestamp")
+			return
+		}
+		since = t
+	}
+	if v := q.Get("until"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid until timestamp")
+			return
+		}
+		until = t
+	}
+
+	filename := "gozen-usage-" + time.Now().Format("20060102") + "." + format
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	var write func(row *usageExportRow) error
+	var flush func()
+	if format == "csv" {
+		cw := csv.NewWriter(w)
+		if err := cw.Write(usageExportColumns); err != nil {
+			return
+		}
+		write = func(row *usageExportRow) error { return cw.Write(row.csvRecord()) }
+		flush = cw.Flush
+	} else {
+		enc := json.NewEncoder(w)
+		write = func(row *usageExportRow) error { return enc.Encode(row) }
+		flush = func() {}
+	}
+
+	tracker := proxy.GetGlobalUsageTracker()
+	if tracker == nil {
+		flush()
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	n := 0
+	// Headers are already sent, so a failed query just ends the export early
+	tracker.ExportUsage(since, until, q.Get("project"), q.Get("tag"), func(e *proxy.UsageEntry) error {
+		row := usageExportRow{
+			Timestamp:           e.Timestamp.UTC().Format(time.RFC3339Nano),
+			SessionID:           e.SessionID,
+			Provider:            e.Provider,
+			Model:               e.Model,
+			InputTokens:         e.InputTokens,
+			OutputTokens:        e.OutputTokens,
+			CacheCreationTokens: e.CacheCreationTokens,
+			CacheReadTokens:     e.CacheReadTokens,
+			CostUSD:             e.CostUSD,
+			LatencyMs:           e.LatencyMs,
+			ProjectPath:         e.ProjectPath,
+			ClientType:          e.ClientType,
+			User:                e.User,
+			Tags:                e.Tags,
+			Batch:               e.Batch,
+			CostBreakdown:       e.Breakdown,
+		}
+		if err := write(&row); err != nil {
+			return err
+		}
+		// Flush periodically so large exports stream to the client
+		if n++; n%500 == 0 {
+			flush()
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		return nil
+	})
+	flush()
+}
+
 // handleBudget handles GET/PUT /api/v1/budget - get or set budget config.
 func (s *Server) handleBudget(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
@@ -209,6 +413,19 @@ func (s *Server) handleBudget(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		switch budgets.Enforcement {
+		case "", config.BudgetEnforcementAdmission, config.BudgetEnforcementStreaming:
+		default:
+			writeError(w, http.StatusBadRequest, "invalid enforcement: "+string(budgets.Enforcement))
+			return
+		}
+		for _, d := range budgets.Downgrade {
+			if d == nil || d.Match == "" || d.Target == "" {
+				writeError(w, http.StatusBadRequest, "downgrade rules need a match and a target")
+				return
+			}
+		}
+
 		if err := config.SetBudgets(&budgets); err != nil {
 			writeError(w, http.StatusInternalServerError, err.Error())
 			return
@@ -227,6 +444,7 @@ func (s *Server) handleBudget(w http.ResponseWriter, r *http.Request) {
 }
 
 // handleBudgetStatus handles GET /api/v1/budget/status - returns current budget status.
+// An optional model (and provider) adds the model requests would be downgraded to.
 func (s *Server) handleBudgetStatus(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -239,9 +457,8 @@ func (s *Server) handleBudgetStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	projectPath := r.URL.Query().Get("project")
-
-	status, err := checker.Check(projectPath)
+	q := r.URL.Query()
+	status, err := checker.CheckModel(q.Get("project"), q.Get("provider"), q.Get("model"))
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -249,3 +466,52 @@ func (s *Server) handleBudgetStatus(w http.ResponseWriter, r *http.Request) {
 
 	writeJSON(w, http.StatusOK, status)
 }
+
+// handleUsageCompact handles POST /api/v1/usage/compact - applies the
+// usage_retention policy now, deleting expired rows and vacuuming the
+// database.
+func (s *Server) handleUsageCompact(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if proxy.GetGlobalLogDB() == nil {
+		writeError(w, http.StatusServiceUnavailable, "usage database is not open")
+		return
+	}
+	result, err := proxy.CompactUsage()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleUsageStorage handles GET /api/v1/usage/storage - reports the usage
+// database's disk usage and the retention policy applied to it.
+func (s *Server) handleUsageStorage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	db := proxy.GetGlobalLogDB()
+	if db == nil {
+		writeError(w, http.StatusServiceUnavailable, "usage database is not open")
+		return
+	}
+	usage, err := db.DiskUsage()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	rc := config.GetUsageRetention()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"disk": usage,
+		"retention": map[string]interface{}{
+			"enabled":        rc != nil && rc.Enabled,
+			"raw_days":       rc.GetRawDays(),
+			"hourly_days":    rc.GetHourlyDays(),
+			"interval_hours": int(rc.GetInterval().Hours()),
+		},
+	})
+}
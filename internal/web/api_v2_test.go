@@ -2,15 +2,23 @@ package web
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/dopejs/gozen/internal/agent"
+	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/middleware"
+	"github.com/dopejs/gozen/internal/notify"
 	"github.com/dopejs/gozen/internal/proxy"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // --- Agent Config API ---
@@ -76,6 +84,42 @@ func TestAgentSessionsList(t *testing.T) {
 	}
 }
 
+func TestAgentSessionReport(t *testing.T) {
+	setupAgentInfrastructure()
+	s := setupTestServer(t)
+	obs := agent.GetGlobalObservatory()
+	obs.RegisterSession("report-session", "default", "claude", "/work/api")
+	t.Cleanup(func() { obs.RemoveSession("report-session") })
+	obs.RecordActivity(agent.Activity{SessionID: "report-session", Tokens: 200, Cost: 0.5, ToolCalls: []agent.ToolCall{
+		{ID: "e1", Name: "Write", Input: []byte(`{"file_path":"README.md"}`)},
+	}})
+
+	w := doRequest(s, "GET", "/api/v1/agent/sessions/report-session/report", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var report agent.SessionReport
+	decodeJSON(t, w, &report)
+	if report.Tokens != 200 || len(report.Files) != 1 || report.Files[0].Path != "/work/api/README.md" {
+		t.Errorf("report = %+v", report)
+	}
+
+	// Once the session is gone, the stored report is served
+	report.Status = agent.SessionStatusIdle
+	if err := agent.SaveSessionReport(&report); err != nil {
+		t.Fatal(err)
+	}
+	obs.RemoveSession("report-session")
+	w = doRequest(s, "GET", "/api/v1/agent/sessions/report-session/report", nil)
+	decodeJSON(t, w, &report)
+	if w.Code != http.StatusOK || report.Status != agent.SessionStatusIdle {
+		t.Errorf("stored report: status %d, %+v", w.Code, report)
+	}
+	if w := doRequest(s, "GET", "/api/v1/agent/sessions/missing/report", nil); w.Code != http.StatusNotFound {
+		t.Errorf("unknown session: status = %d, want 404", w.Code)
+	}
+}
+
 func TestAgentSessionsMethodNotAllowed(t *testing.T) {
 	s := setupTestServer(t)
 	w := doRequest(s, "POST", "/api/v1/agent/sessions", nil)
@@ -85,6 +129,54 @@ func TestAgentSessionsMethodNotAllowed(t *testing.T) {
 	}
 }
 
+func TestAgentSessionTimeline(t *testing.T) {
+	setupAgentInfrastructure()
+	s := setupTestServer(t)
+	obs := agent.GetGlobalObservatory()
+	obs.RegisterSession("timeline-session", "default", "claude", "")
+	t.Cleanup(func() { obs.RemoveSession("timeline-session") })
+
+	call := agent.ToolCall{ID: "t1", Name: "Bash", Input: []byte(`{"command":"go test ./..."}`)}
+	obs.RecordActivity(agent.Activity{SessionID: "timeline-session", ToolCalls: []agent.ToolCall{call}})
+	obs.RecordActivity(agent.Activity{
+		SessionID:   "timeline-session",
+		ToolResults: []agent.ToolResult{{ToolUseID: "t1", IsError: true, Output: "ok  pkg/a\nFAIL\tpkg/b\n"}},
+	})
+
+	w := doRequest(s, "GET", "/api/v1/agent/sessions/timeline-session/timeline", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Events []agent.TimelineEvent `json:"events"`
+	}
+	decodeJSON(t, w, &resp)
+	var types []string
+	for _, e := range resp.Events {
+		types = append(types, e.Type)
+	}
+	want := []string{agent.TimelineToolStarted, agent.TimelineToolFinished, agent.TimelineTestRun}
+	if strings.Join(types, " ") != strings.Join(want, " ") {
+		t.Fatalf("event types = %v, want %v", types, want)
+	}
+	if run := resp.Events[2]; !run.Failed || run.Command != "go test ./..." || run.Detail != "FAIL\tpkg/b" {
+		t.Errorf("test_run = %+v", run)
+	}
+
+	w = doRequest(s, "GET", "/api/v1/agent/sessions/timeline-session/timeline?after=2", nil)
+	decodeJSON(t, w, &resp)
+	if len(resp.Events) != 1 || resp.Events[0].Seq != 3 {
+		t.Errorf("after=2: events = %+v", resp.Events)
+	}
+
+	if w := doRequest(s, "GET", "/api/v1/agent/sessions/missing/timeline", nil); w.Code != http.StatusNotFound {
+		t.Errorf("unknown session: status = %d, want 404", w.Code)
+	}
+	if w := doRequest(s, "POST", "/api/v1/agent/sessions/timeline-session/timeline", nil); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST: status = %d, want 405", w.Code)
+	}
+}
+
 // --- Agent Locks API ---
 
 func TestAgentLocksList(t *testing.T) {
@@ -199,6 +291,77 @@ func TestCompressionStatsGet(t *testing.T) {
 	}
 }
 
+func TestCompressionPreview(t *testing.T) {
+	s := setupTestServer(t)
+
+	embeddings := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		data := make([]map[string]interface{}, len(req.Input))
+		for i := range req.Input {
+			data[i] = map[string]interface{}{"index": i, "embedding": []float32{1, 0}}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	defer embeddings.Close()
+
+	messages := []map[string]string{
+		{"role": "user", "content": "first question about the build"},
+		{"role": "assistant", "content": "a long answer about the build system and its flags"},
+		{"role": "user", "content": "latest question"},
+	}
+	before := proxy.GetGlobalCompressor()
+	var statsBefore proxy.CompressionStats
+	if before != nil {
+		statsBefore = before.GetStats()
+	}
+
+	w := doRequest(s, "POST", "/api/v1/compression/preview", map[string]interface{}{
+		"messages": messages,
+		"config": map[string]interface{}{
+			"strategy":        "retrieval",
+			"preserve_recent": 1,
+			"retrieval":       map[string]interface{}{"embedding_url": embeddings.URL},
+		},
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var preview proxy.CompressionPreview
+	if err := json.Unmarshal(w.Body.Bytes(), &preview); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if preview.Strategy != "retrieval" || preview.MessagesBefore != 3 || preview.MessagesAfter != 2 || preview.Summary == "" {
+		t.Errorf("preview = %+v", preview)
+	}
+	if preview.WouldCompress {
+		t.Error("expected a short conversation to be below the threshold")
+	}
+	if after := proxy.GetGlobalCompressor(); after != nil && after.GetStats() != statsBefore {
+		t.Error("expected preview not to change compression stats")
+	}
+
+	// Too few messages to compress leaves them unchanged
+	w = doRequest(s, "POST", "/api/v1/compression/preview", map[string]interface{}{"messages": messages[:1]})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	preview = proxy.CompressionPreview{}
+	json.Unmarshal(w.Body.Bytes(), &preview)
+	if preview.TokensAfter != preview.TokensBefore || preview.Summary != "" {
+		t.Errorf("expected no compression, got %+v", preview)
+	}
+
+	if w := doRequest(s, "POST", "/api/v1/compression/preview", map[string]interface{}{}); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without messages, got %d", w.Code)
+	}
+	if w := doRequest(s, "GET", "/api/v1/compression/preview", nil); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+}
+
 // --- Middleware API ---
 
 func TestMiddlewareGet(t *testing.T) {
@@ -298,6 +461,60 @@ func TestPricingResetMethodNotAllowed(t *testing.T) {
 	}
 }
 
+func TestPricingSync(t *testing.T) {
+	s := setupTestServer(t)
+
+	w := doRequest(s, "GET", "/api/v1/pricing/sync", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var status map[string]interface{}
+	decodeJSON(t, w, &status)
+	if status["enabled"] != false || status["interval_hours"] != float64(24) {
+		t.Errorf("unexpected sync status: %v", status)
+	}
+
+	w = doRequest(s, "POST", "/api/v1/pricing/sync", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without a manifest URL, got %d", w.Code)
+	}
+}
+
+func TestUsageCompact(t *testing.T) {
+	s := setupTestServer(t)
+	if err := proxy.InitGlobalLogger(t.TempDir()); err != nil {
+		t.Fatalf("InitGlobalLogger() error: %v", err)
+	}
+
+	w := doRequest(s, "GET", "/api/v1/usage/storage", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var storage struct {
+		Disk      proxy.DiskUsage        `json:"disk"`
+		Retention map[string]interface{} `json:"retention"`
+	}
+	decodeJSON(t, w, &storage)
+	if _, ok := storage.Disk.Rows["usage"]; !ok || storage.Retention["raw_days"] != float64(30) || storage.Retention["hourly_days"] != float64(365) {
+		t.Errorf("unexpected storage report: %+v", storage)
+	}
+
+	w = doRequest(s, "POST", "/api/v1/usage/compact", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var result proxy.CompactionResult
+	decodeJSON(t, w, &result)
+	if result.RawCutoff.IsZero() || !result.HourlyCutoff.Before(result.RawCutoff) {
+		t.Errorf("unexpected compaction result: %+v", result)
+	}
+
+	w = doRequest(s, "GET", "/api/v1/usage/compact", nil)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+}
+
 // --- Sessions API ---
 
 func TestSessionsGet(t *testing.T) {
@@ -366,6 +583,68 @@ func TestHealthProviderMethodNotAllowed(t *testing.T) {
 	}
 }
 
+func TestHealthSummaryAndBadge(t *testing.T) {
+	s := setupTestServer(t)
+	if err := proxy.InitGlobalLogger(t.TempDir()); err != nil {
+		t.Fatalf("InitGlobalLogger() error: %v", err)
+	}
+	db := proxy.GetGlobalLogDB()
+	for i := 0; i < 20; i++ {
+		db.RecordMetric("test-provider", 100, 200, false, false)
+		db.RecordMetric("backup", 100, 503, true, false)
+	}
+
+	w := doRequest(s, "GET", "/api/v1/health/summary", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var summary HealthSummary
+	decodeJSON(t, w, &summary)
+	if summary.Status != proxy.HealthStatusDegraded || len(summary.Providers) != 2 {
+		t.Fatalf("summary = %+v, want degraded with 2 providers", summary)
+	}
+	if p := summary.Providers[0]; p.Name != "backup" || p.Status != proxy.HealthStatusUnhealthy {
+		t.Errorf("providers[0] = %+v, want backup unhealthy", p)
+	}
+	if summary.Counts[proxy.HealthStatusHealthy] != 1 || summary.Counts[proxy.HealthStatusUnhealthy] != 1 {
+		t.Errorf("counts = %v", summary.Counts)
+	}
+
+	// The badge is public even when the Web UI requires a password
+	hash, _ := bcrypt.GenerateFromPassword([]byte("testpass"), bcrypt.MinCost)
+	config.SetWebPasswordHash(string(hash))
+	req := httptest.NewRequest("GET", "/badge/health.svg?label=<ai>", nil)
+	req.RemoteAddr = "10.0.0.1:12345"
+	w = httptest.NewRecorder()
+	s.httpServer.Handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("badge: expected 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "image/svg+xml" {
+		t.Errorf("badge Content-Type = %q", ct)
+	}
+	svg := w.Body.String()
+	if !strings.Contains(svg, ">degraded<") || !strings.Contains(svg, badgeColors[proxy.HealthStatusDegraded]) || !strings.Contains(svg, "&lt;ai&gt;") {
+		t.Errorf("badge = %s", svg)
+	}
+
+	req = httptest.NewRequest("GET", "/api/v1/health/summary", nil)
+	req.RemoteAddr = "10.0.0.1:12345"
+	w = httptest.NewRecorder()
+	s.httpServer.Handler.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("summary without a session: expected 401, got %d", w.Code)
+	}
+}
+
+func TestHealthSummaryMethodNotAllowed(t *testing.T) {
+	s := setupTestServer(t)
+	w := doRequest(s, "POST", "/api/v1/health/summary", nil)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", w.Code)
+	}
+}
+
 // --- Usage API ---
 
 func TestUsageGet(t *testing.T) {
@@ -526,6 +805,77 @@ func TestWebhookDeleteByName(t *testing.T) {
 	}
 }
 
+func TestWebhookFailures(t *testing.T) {
+	s := setupTestServer(t)
+
+	w := doRequest(s, "GET", "/api/v1/webhooks/failures", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Failures []notify.WebhookDelivery `json:"failures"`
+		Pending  *int                     `json:"pending"`
+	}
+	decodeJSON(t, w, &resp)
+	if resp.Failures == nil || resp.Pending == nil {
+		t.Errorf("unexpected failures response: %s", w.Body.String())
+	}
+
+	w = doRequest(s, "POST", "/api/v1/webhooks/failures", map[string]string{"id": "nonexistent"})
+	if w.Code != http.StatusNotFound {
+		t.Errorf("requeue unknown delivery: expected 404, got %d", w.Code)
+	}
+	w = doRequest(s, "DELETE", "/api/v1/webhooks/failures", nil)
+	if w.Code != http.StatusOK {
+		t.Errorf("clear failures: expected 200, got %d", w.Code)
+	}
+}
+
+// --- Ingress Keys API ---
+
+func TestIngressKeys(t *testing.T) {
+	s := setupTestServer(t)
+
+	w := doRequest(s, "POST", "/api/v1/ingress/keys", map[string]string{"user": ""})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without a user, got %d", w.Code)
+	}
+
+	w = doRequest(s, "POST", "/api/v1/ingress/keys", map[string]string{"user": "alice"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var issued ingressKeyResponse
+	decodeJSON(t, w, &issued)
+	if !strings.HasPrefix(issued.Key, "zen-") || issued.ID == "" || issued.User != "alice" {
+		t.Fatalf("unexpected issued key: %+v", issued)
+	}
+
+	w = doRequest(s, "PUT", "/api/v1/ingress", map[string]bool{"required": true})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = doRequest(s, "GET", "/api/v1/ingress", nil)
+	var listed struct {
+		Required bool                 `json:"required"`
+		Keys     []ingressKeyResponse `json:"keys"`
+	}
+	decodeJSON(t, w, &listed)
+	if !listed.Required || len(listed.Keys) != 1 || listed.Keys[0].Key == issued.Key {
+		t.Fatalf("expected one masked key with keys required, got %+v", listed)
+	}
+
+	w = doRequest(s, "DELETE", "/api/v1/ingress/keys/"+issued.ID, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	w = doRequest(s, "DELETE", "/api/v1/ingress/keys/"+issued.ID, nil)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a revoked key, got %d", w.Code)
+	}
+}
+
 // --- Middleware By Name API ---
 
 func TestMiddlewareByNameGet(t *testing.T) {
@@ -579,6 +929,39 @@ func TestMiddlewareDisableMethodNotAllowed(t *testing.T) {
 	}
 }
 
+func TestMiddlewareStats(t *testing.T) {
+	s := setupTestServer(t)
+	middleware.InitGlobalRegistry(nil)
+	pipeline := middleware.GetGlobalPipeline()
+	m := middleware.NewRedaction()
+	m.Init(nil)
+	pipeline.Add(m)
+	defer pipeline.Remove(m.Name())
+	pipeline.SetEnabled(true)
+	defer pipeline.SetEnabled(false)
+
+	ctx := middleware.NewRequestContext()
+	ctx.Body = []byte(`{"messages": [{"role": "user", "content": "hi"}]}`)
+	pipeline.ProcessRequest(ctx)
+
+	w := doRequest(s, "GET", "/api/v1/middleware/redaction/stats", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp MiddlewareStatsResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Name != "redaction" || resp.Request.Calls != 1 || resp.Stats == nil {
+		t.Errorf("unexpected stats: %+v", resp)
+	}
+
+	if w := doRequest(s, "GET", "/api/v1/middleware/not-loaded/stats", nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unloaded middleware, got %d", w.Code)
+	}
+	if w := doRequest(s, "POST", "/api/v1/middleware/redaction/stats", nil); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+}
+
 // --- Additional Compression Tests ---
 
 func TestCompressionStatsMethodNotAllowed(t *testing.T) {
@@ -654,7 +1037,7 @@ func TestAgentGuardrailsMethodNotAllowed(t *testing.T) {
 
 func TestAgentLocksMethodNotAllowed(t *testing.T) {
 	s := setupTestServer(t)
-	w := doRequest(s, "POST", "/api/v1/agent/locks", nil)
+	w := doRequest(s, "PUT", "/api/v1/agent/locks", nil)
 	if w.Code != http.StatusMethodNotAllowed && w.Code != http.StatusServiceUnavailable {
 		t.Fatalf("expected 405 or 503, got %d", w.Code)
 	}
@@ -880,6 +1263,39 @@ func TestAgentGuardrailsOperationsWithGuardrails(t *testing.T) {
 	}
 }
 
+func TestAgentGuardrailsPolicy(t *testing.T) {
+	s := setupTestServer(t)
+	setupAgentInfrastructure()
+	gr := agent.GetGlobalGuardrails()
+	prev := gr.GetConfig()
+	gr.UpdateConfig(&config.GuardrailsConfig{
+		Enabled: true,
+		Policy:  []*config.GuardrailRule{{Name: "no-force-push", Action: config.GuardrailBlock, DenyCommands: []string{`git push .*--force`}}},
+	})
+	t.Cleanup(func() { gr.UpdateConfig(prev) })
+
+	call := agent.ToolCall{Name: "Bash", Input: []byte(`{"command":"git push origin main --force"}`)}
+	if d := gr.CheckToolCall("s1", "", call); !d.Blocks() {
+		t.Fatalf("decision = %+v, want block", d)
+	}
+
+	w := doRequest(s, "GET", "/api/v1/agent/guardrails/policy", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Rules []agent.PolicyRuleStats `json:"rules"`
+	}
+	decodeJSON(t, w, &resp)
+	if len(resp.Rules) != 1 || resp.Rules[0].Name != "no-force-push" || resp.Rules[0].Hits != 1 {
+		t.Errorf("rules = %+v", resp.Rules)
+	}
+
+	if w := doRequest(s, "POST", "/api/v1/agent/guardrails/policy", nil); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST: expected 405, got %d", w.Code)
+	}
+}
+
 func TestAgentChangesWithObservatory(t *testing.T) {
 	s := setupTestServer(t)
 	setupAgentInfrastructure()
@@ -890,6 +1306,64 @@ func TestAgentChangesWithObservatory(t *testing.T) {
 	}
 }
 
+func TestAgentChangesReview(t *testing.T) {
+	s := setupTestServer(t)
+	setupAgentInfrastructure()
+	coord := agent.GetGlobalCoordinator()
+	coord.UpdateConfig(&config.CoordinatorConfig{Enabled: true, LockTimeoutSec: 300})
+	t.Cleanup(func() { coord.UpdateConfig(&config.CoordinatorConfig{LockTimeoutSec: 300}) })
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.py")
+	os.WriteFile(file, []byte("print('hi')\n"), 0644)
+	for i, content := range []string{"print('hello')\n", "print('hello')\nprint('bye')\n"} {
+		id := fmt.Sprintf("review-%d", i)
+		coord.WatchEdit("review-s1", dir, agent.ToolCall{ID: id, Name: "Write", Input: []byte(`{"file_path": "app.py"}`)})
+		os.WriteFile(file, []byte(content), 0644)
+		coord.FinishEdit("review-s1", agent.ToolResult{ToolUseID: id})
+	}
+
+	w := doRequest(s, "GET", "/api/v1/agent/changes?session_id=review-s1&status=pending", nil)
+	var list struct {
+		Changes []agent.FileChange `json:"changes"`
+	}
+	decodeJSON(t, w, &list)
+	if len(list.Changes) != 2 || !strings.Contains(list.Changes[0].Diff, "-print('hi')\n+print('hello')\n") {
+		t.Fatalf("changes = %+v", list.Changes)
+	}
+	first, second := list.Changes[0].ID, list.Changes[1].ID
+
+	if w := doRequest(s, "GET", "/api/v1/agent/changes/"+first, nil); w.Code != http.StatusOK {
+		t.Errorf("get: expected 200, got %d", w.Code)
+	}
+	if w := doRequest(s, "GET", "/api/v1/agent/changes/chg-missing", nil); w.Code != http.StatusNotFound {
+		t.Errorf("get missing: expected 404, got %d", w.Code)
+	}
+
+	w = doRequest(s, "POST", "/api/v1/agent/changes/"+second+"/revert", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("revert: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if data, _ := os.ReadFile(file); string(data) != "print('hello')\n" {
+		t.Errorf("after revert file = %q", data)
+	}
+	if w := doRequest(s, "POST", "/api/v1/agent/changes/"+second+"/revert", nil); w.Code != http.StatusConflict {
+		t.Errorf("second revert: expected 409, got %d", w.Code)
+	}
+
+	if w := doRequest(s, "POST", "/api/v1/agent/changes/approve", map[string]interface{}{}); w.Code != http.StatusBadRequest {
+		t.Errorf("approve without session: expected 400, got %d", w.Code)
+	}
+	w = doRequest(s, "POST", "/api/v1/agent/changes/approve", map[string]interface{}{"session_id": "review-s1"})
+	var approved struct {
+		Approved int `json:"approved"`
+	}
+	decodeJSON(t, w, &approved)
+	if approved.Approved != 1 {
+		t.Errorf("approved %d changes, want 1", approved.Approved)
+	}
+}
+
 // --- Additional Agent Task Tests ---
 
 func TestAgentTasksPost(t *testing.T) {
@@ -947,17 +1421,86 @@ func TestAgentRuntimeGetSpecific(t *testing.T) {
 // --- Additional Agent Locks Tests ---
 
 func TestAgentLocksAcquire(t *testing.T) {
+	// Audit requests, so a queued one can't hold up its release
+	if err := proxy.InitGlobalLogger(t.TempDir()); err != nil {
+		t.Fatalf("InitGlobalLogger() error: %v", err)
+	}
 	s := setupTestServer(t)
 	setupAgentInfrastructure()
+	coord := agent.GetGlobalCoordinator()
+	coord.UpdateConfig(&config.CoordinatorConfig{Enabled: true, LockTimeoutSec: 300})
+	t.Cleanup(func() {
+		coord.ReleaseAllLocks("lock-a")
+		coord.ReleaseAllLocks("lock-b")
+		coord.UpdateConfig(&config.CoordinatorConfig{LockTimeoutSec: 300})
+	})
 
 	body := map[string]interface{}{
-		"session_id": "test-session",
-		"file_path":  "/test/file.txt",
+		"path":       "/proj/src/api/**",
+		"session_id": "lock-a",
+		"task_id":    "task-1",
+		"reason":     "refactor handlers",
 	}
 	w := doRequest(s, "POST", "/api/v1/agent/locks", body)
-	// POST may not be allowed on this endpoint
-	if w.Code != http.StatusOK && w.Code != http.StatusBadRequest && w.Code != http.StatusConflict && w.Code != http.StatusMethodNotAllowed {
-		t.Fatalf("expected 200, 400, 405 or 409, got %d", w.Code)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var lock agent.FileLock
+	decodeJSON(t, w, &lock)
+	if lock.Scope != agent.LockScopeGlob || lock.TaskID != "task-1" || lock.Reason != "refactor handlers" {
+		t.Errorf("lock = %+v", lock)
+	}
+
+	// An overlapping lock from another session conflicts
+	w = doRequest(s, "POST", "/api/v1/agent/locks", map[string]string{"path": "/proj/src/api/users.go", "session_id": "lock-b"})
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d: %s", w.Code, w.Body.String())
+	}
+	var conflict struct {
+		Held agent.FileLock `json:"held"`
+	}
+	decodeJSON(t, w, &conflict)
+	if conflict.Held.SessionID != "lock-a" || conflict.Held.Path != "/proj/src/api/**" {
+		t.Errorf("held = %+v", conflict.Held)
+	}
+
+	w = doRequest(s, "POST", "/api/v1/agent/locks", map[string]string{"path": "/proj/docs/", "session_id": "lock-b"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("disjoint dir: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	for _, bad := range []map[string]string{{"path": "/proj/x"}, {"session_id": "lock-b"}, {"path": "/proj/[", "session_id": "lock-b"}} {
+		if w := doRequest(s, "POST", "/api/v1/agent/locks", bad); w.Code != http.StatusBadRequest {
+			t.Errorf("%v: expected 400, got %d", bad, w.Code)
+		}
+	}
+
+	// A queued request is granted once the lock is released, whichever
+	// form of the path it used
+	granted := make(chan int, 1)
+	go func() {
+		w := doRequest(s, "POST", "/api/v1/agent/locks/", map[string]interface{}{"path": "/proj/src/api/a.go", "session_id": "lock-b", "wait_sec": 5})
+		granted <- w.Code
+	}()
+	deadline := time.Now().Add(2 * time.Second)
+	for len(coord.GetWaiting()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	w = doRequest(s, "GET", "/api/v1/agent/locks", nil)
+	var list struct {
+		Waiting []agent.LockWait `json:"waiting"`
+	}
+	decodeJSON(t, w, &list)
+	if len(list.Waiting) != 1 || list.Waiting[0].SessionID != "lock-b" || list.Waiting[0].BlockedBy[0] != "lock-a" {
+		t.Errorf("waiting = %+v", list.Waiting)
+	}
+
+	w = doRequest(s, "DELETE", "/api/v1/agent/locks/%2Fproj/src/api/**?session_id=lock-a", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("release: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if code := <-granted; code != http.StatusOK {
+		t.Errorf("queued request: expected 200, got %d", code)
 	}
 }
 
@@ -1690,6 +2233,104 @@ func TestUsageHourlyWithTrackerAndHours(t *testing.T) {
 	}
 }
 
+func TestUsageExport(t *testing.T) {
+	s := setupTestServer(t)
+	setupProxyInfrastructure(t)
+	tracker := proxy.GetGlobalUsageTracker()
+	tracker.Record(proxy.UsageEntry{Timestamp: time.Now().Add(-time.Hour), SessionID: "s1", Provider: "p1", Model: "claude-sonnet", InputTokens: 100, OutputTokens: 50, CostUSD: 0.25, ProjectPath: "/a"})
+	tracker.Record(proxy.UsageEntry{Timestamp: time.Now().Add(-48 * time.Hour), SessionID: "s2", Provider: "p2", Model: "gpt-4o", InputTokens: 10, OutputTokens: 5, CostUSD: 0.01, ProjectPath: "/b"})
+
+	w := doRequest(s, "GET", "/api/v1/usage/export?format=csv", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "timestamp,session_id,provider") {
+		t.Fatalf("unexpected csv export: %q", w.Body.String())
+	}
+	if !strings.Contains(lines[1], ",s2,p2,gpt-4o,") || !strings.Contains(lines[2], ",s1,p1,claude-sonnet,100,50,") {
+		t.Errorf("expected rows oldest first, got %q", lines[1:])
+	}
+
+	w = doRequest(s, "GET", "/api/v1/usage/export?format=jsonl&period=day", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	lines = strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected one row for the last day, got %q", w.Body.String())
+	}
+	var row map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &row); err != nil {
+		t.Fatalf("invalid jsonl row: %v", err)
+	}
+	if row["session_id"] != "s1" || row["cost_usd"] != 0.25 || row["project_path"] != "/a" {
+		t.Errorf("unexpected row: %v", row)
+	}
+
+	w = doRequest(s, "GET", "/api/v1/usage/export?format=xml", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unknown format, got %d", w.Code)
+	}
+}
+
+func TestUsageTagFilter(t *testing.T) {
+	s := setupTestServer(t)
+	setupProxyInfrastructure(t)
+	tracker := proxy.GetGlobalUsageTracker()
+	tracker.Record(proxy.UsageEntry{Timestamp: time.Now(), SessionID: "s1", Provider: "p1", Model: "m", CostUSD: 0.5, Tags: []string{"ci"}})
+	tracker.Record(proxy.UsageEntry{Timestamp: time.Now(), SessionID: "s2", Provider: "p1", Model: "m", CostUSD: 0.25})
+
+	w := doRequest(s, "GET", "/api/v1/usage?tag=ci", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp usageResponse
+	decodeJSON(t, w, &resp)
+	if len(resp.Entries) != 1 || resp.Entries[0].SessionID != "s1" || resp.Total != 1 {
+		t.Errorf("expected only the ci entry, got %+v", resp)
+	}
+
+	w = doRequest(s, "GET", "/api/v1/usage/summary?period=day&tag=ci", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var summary proxy.UsageSummary
+	decodeJSON(t, w, &summary)
+	if summary.RequestCount != 1 || summary.TotalCost != 0.5 || summary.ByTag["ci"] == nil {
+		t.Errorf("unexpected ci summary: %+v", summary)
+	}
+}
+
+func TestCurrency(t *testing.T) {
+	s := setupTestServer(t)
+	setupProxyInfrastructure(t)
+	proxy.GetGlobalUsageTracker().Record(proxy.UsageEntry{Timestamp: time.Now(), SessionID: "s1", Provider: "p1", Model: "m", CostUSD: 2})
+
+	w := doRequest(s, "PUT", "/api/v1/currency", map[string]interface{}{"code": "eur", "rate": 0.5})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	w = doRequest(s, "GET", "/api/v1/currency", nil)
+	var cur map[string]interface{}
+	decodeJSON(t, w, &cur)
+	if cur["code"] != "EUR" || cur["rate"] != 0.5 {
+		t.Errorf("unexpected currency: %v", cur)
+	}
+
+	w = doRequest(s, "GET", "/api/v1/usage/summary?period=day", nil)
+	var summary proxy.UsageSummary
+	decodeJSON(t, w, &summary)
+	if summary.Currency != "EUR" || summary.TotalCost != 1 || summary.ByProvider["p1"].Cost != 1 {
+		t.Errorf("expected costs in EUR, got %s %v", summary.Currency, summary.TotalCost)
+	}
+
+	w = doRequest(s, "PUT", "/api/v1/currency", map[string]interface{}{"code": "euro"})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid code, got %d", w.Code)
+	}
+}
+
 func TestBudgetStatusWithChecker(t *testing.T) {
 	s := setupTestServer(t)
 	setupProxyInfrastructure(t)
@@ -18,13 +18,18 @@ func (s *Server) handleWebhooks(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Mask URLs for security (show only domain)
+		dispatcher := notify.GetGlobalDispatcher()
 		masked := make([]map[string]interface{}, len(webhooks))
 		for i, wh := range webhooks {
 			masked[i] = map[string]interface{}{
-				"name":    wh.Name,
-				"url":     maskWebhookURL(wh.URL),
-				"events":  wh.Events,
-				"enabled": wh.Enabled,
+				"name":        wh.Name,
+				"url":         maskWebhookURL(wh.URL),
+				"events":      wh.Events,
+				"enabled":     wh.Enabled,
+				"format":      wh.GetFormat(),
+				"signed":      wh.Secret != "",
+				"client_cert": wh.ClientCert,
+				"stats":       webhookStatsJSON(dispatcher.Stats(wh.Name)),
 			}
 		}
 
@@ -49,6 +54,10 @@ func (s *Server) handleWebhooks(w http.ResponseWriter, r *http.Request) {
 			writeError(w, http.StatusBadRequest, "at least one event is required")
 			return
 		}
+		if err := webhook.Validate(); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 
 		if err := config.AddWebhook(&webhook); err != nil {
 			writeError(w, http.StatusInternalServerError, err.Error())
@@ -86,11 +95,17 @@ func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
 
 		// Mask URL for security
 		masked := map[string]interface{}{
-			"name":    webhook.Name,
-			"url":     maskWebhookURL(webhook.URL),
-			"events":  webhook.Events,
-			"enabled": webhook.Enabled,
-			"headers": len(webhook.Headers) > 0,
+			"name":        webhook.Name,
+			"url":         maskWebhookURL(webhook.URL),
+			"events":      webhook.Events,
+			"enabled":     webhook.Enabled,
+			"headers":     len(webhook.Headers) > 0,
+			"format":      webhook.GetFormat(),
+			"template":    webhook.Template,
+			"signed":      webhook.Secret != "",
+			"client_cert": webhook.ClientCert,
+			"ca_cert":     webhook.CACert,
+			"stats":       webhookStatsJSON(notify.GetGlobalDispatcher().Stats(webhook.Name)),
 		}
 
 		writeJSON(w, http.StatusOK, masked)
@@ -109,6 +124,10 @@ func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
 			writeError(w, http.StatusBadRequest, "url is required")
 			return
 		}
+		if err := webhook.Validate(); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 
 		if err := config.AddWebhook(&webhook); err != nil {
 			writeError(w, http.StatusInternalServerError, err.Error())
@@ -182,6 +201,51 @@ func (s *Server) handleWebhookTest(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]string{"status": "success", "message": "test notification sent"})
 }
 
+// handleWebhookFailures handles GET/POST/DELETE /api/v1/webhooks/failures -
+// list the dead-lettered deliveries, requeue them for delivery, or clear them.
+// POST takes an optional delivery id; without one every failure is requeued.
+func (s *Server) handleWebhookFailures(w http.ResponseWriter, r *http.Request) {
+	dispatcher := notify.GetGlobalDispatcher()
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"failures": dispatcher.Failures(),
+			"pending":  dispatcher.PendingCount(),
+		})
+
+	case http.MethodPost:
+		var req struct {
+			ID string `json:"id"`
+		}
+		if r.ContentLength != 0 {
+			if err := readJSON(r, &req); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+				return
+			}
+		}
+		n := dispatcher.RequeueFailures(req.ID)
+		if n == 0 && req.ID != "" {
+			writeError(w, http.StatusNotFound, "delivery not found")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]int{"requeued": n})
+
+	case http.MethodDelete:
+		writeJSON(w, http.StatusOK, map[string]int{"cleared": dispatcher.ClearFailures()})
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// webhookStatsJSON adds the success rate to a webhook's delivery stats.
+func webhookStatsJSON(stats notify.WebhookStats) interface{} {
+	return struct {
+		notify.WebhookStats
+		SuccessRate float64 `json:"success_rate"`
+	}{stats, stats.SuccessRate()}
+}
+
 // maskWebhookURL masks a webhook URL for display, showing only the domain.
 func maskWebhookURL(url string) string {
 	if url == "" {
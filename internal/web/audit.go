@@ -0,0 +1,255 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/proxy"
+)
+
+// Every POST, PUT, PATCH and DELETE against the web API is recorded in the
+// audit log with who made it, its status and the config fields it changed.
+
+// unauditedPaths are the mutating endpoints that don't change anything.
+var unauditedPaths = map[string]bool{
+	"/api/v1/auth/login":          true,
+	"/api/v1/auth/logout":         true,
+	"/api/v1/config/validate":     true,
+	"/api/v1/sync/test":           true,
+	"/api/v1/webhooks/test":       true,
+	"/api/v1/compression/preview": true,
+	"/api/v1/bot/chat":            true,
+	"/api/v1/bot/skills/test":     true,
+}
+
+type actorKey struct{}
+
+// actor is who made a request, as established by authMiddleware.
+type actor struct {
+	name string // "local", "token:<name>", a trusted-header user, "session" or "anonymous"
+	role Role
+}
+
+func withActor(r *http.Request, name string, role Role) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), actorKey{}, actor{name: name, role: role}))
+}
+
+func actorOf(r *http.Request) actor {
+	if a, ok := r.Context().Value(actorKey{}).(actor); ok {
+		return a
+	}
+	return actor{name: "anonymous", role: RoleAdmin}
+}
+
+func audited(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return false
+	}
+	return strings.HasPrefix(r.URL.Path, "/api/") && !unauditedPaths[strings.TrimSuffix(r.URL.Path, "/")]
+}
+
+// statusRecorder remembers the status code written through it.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// auditMiddleware records mutating requests in the audit log. The config
+// saves made while a request is served are diffed and kept, with secrets
+// masked.
+func (s *Server) auditMiddleware(next http.Handler) http.Handler {
+	auditListenOnce.Do(func() { config.AddSaveListener(auditSaves.record) })
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		db := proxy.GetGlobalLogDB()
+		if db == nil || !audited(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := auditSaves.begin()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		before, after := auditSaves.end(start)
+
+		a := actorOf(r)
+		entry := proxy.AuditEntry{
+			Timestamp: time.Now(),
+			Actor:     a.name,
+			Role:      string(a.role),
+			ClientIP:  clientIP(r),
+			Method:    r.Method,
+			Path:      r.URL.Path,
+			Status:    rec.status,
+			Changes:   auditChanges(before, after),
+		}
+		if err := db.InsertAudit(entry); err != nil && s.logger != nil {
+			s.logger.Printf("audit log: %v", err)
+		}
+	})
+}
+
+// auditSave is a config save made while audited requests were in flight.
+type auditSave struct {
+	seq            uint64
+	previous, data []byte
+}
+
+// auditSaveLog credits config saves to the audited requests that made them.
+// Requests are served concurrently and the store can't tell which one saved,
+// so a save goes to the first request to finish among those in flight when
+// it was made. Handlers return right after saving, so that is the one that
+// made it unless two edits race.
+type auditSaveLog struct {
+	mu       sync.Mutex
+	seq      uint64 // of the latest recorded save
+	inFlight int
+	pending  []auditSave
+}
+
+var (
+	auditSaves      auditSaveLog
+	auditListenOnce sync.Once
+)
+
+func (l *auditSaveLog) record(previous, data []byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inFlight == 0 {
+		return
+	}
+	l.seq++
+	l.pending = append(l.pending, auditSave{seq: l.seq, previous: previous, data: data})
+}
+
+// begin marks an audited request as in flight and returns the position to
+// pass to end.
+func (l *auditSaveLog) begin() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inFlight++
+	return l.seq
+}
+
+// end claims the saves made since start that no other request claimed and
+// returns the config file before the first and after the last of them, or
+// nils when there are none.
+func (l *auditSaveLog) end(start uint64) (before, after []byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inFlight--
+	kept := l.pending[:0]
+	claimed := false
+	for _, save := range l.pending {
+		if save.seq <= start {
+			kept = append(kept, save)
+			continue
+		}
+		if !claimed {
+			before, claimed = save.previous, true
+		}
+		after = save.data
+	}
+	l.pending = kept
+	if l.inFlight == 0 {
+		l.pending = nil
+	}
+	return before, after
+}
+
+// auditChanges diffs two versions of the config file, masking secrets.
+// A missing or unparsable version counts as an empty config.
+func auditChanges(before, after []byte) []config.ConfigChange {
+	if len(before) == 0 {
+		before = []byte("{}")
+	}
+	if len(after) == 0 {
+		after = []byte("{}")
+	}
+	changes, err := config.DiffConfigs(before, after)
+	if err != nil {
+		return nil
+	}
+	for i := range changes {
+		changes[i].From = maskConfigValue(changes[i].Path, changes[i].From)
+		changes[i].To = maskConfigValue(changes[i].Path, changes[i].To)
+	}
+	return changes
+}
+
+// auditResponse is the JSON shape returned by GET /api/v1/audit.
+type auditResponse struct {
+	Entries []proxy.AuditEntry `json:"entries"`
+	pageCursors
+}
+
+// handleAudit lists the audit log, newest first. Filters: actor, method,
+// path (a prefix of the API path), config_path (a changed config field or
+// section, e.g. "default_profile" or "providers.work") and since/until as
+// RFC3339 timestamps. Pages like the other list APIs.
+// GET /api/v1/audit
+func (s *Server) handleAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	db := proxy.GetGlobalLogDB()
+	if db == nil {
+		writeError(w, http.StatusServiceUnavailable, "log database not available")
+		return
+	}
+
+	q := r.URL.Query()
+	filter := proxy.AuditFilter{
+		Actor:      q.Get("actor"),
+		Method:     q.Get("method"),
+		PathPrefix: q.Get("path"),
+		ConfigPath: q.Get("config_path"),
+	}
+	for _, p := range []struct {
+		name string
+		t    *time.Time
+	}{{"since", &filter.Since}, {"until", &filter.Until}} {
+		if v := q.Get(p.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "invalid "+p.name+" timestamp")
+				return
+			}
+			*p.t = t
+		}
+	}
+	page, err := pageFromQuery(q, 100)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	entries, more, err := db.ListAudit(filter, page)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	total, err := db.CountAudit(filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := auditResponse{Entries: entries}
+	if len(entries) > 0 {
+		resp.pageCursors = cursorsFor(page, more, entries[0].ID, entries[len(entries)-1].ID)
+	}
+	resp.Total = total
+	writeJSON(w, http.StatusOK, resp)
+}
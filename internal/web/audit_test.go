@@ -0,0 +1,146 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/proxy"
+)
+
+func TestAuditLog(t *testing.T) {
+	if err := proxy.InitGlobalLogger(t.TempDir()); err != nil {
+		t.Fatalf("InitGlobalLogger() error: %v", err)
+	}
+	s := setupTestServer(t)
+
+	list := func(query string) auditResponse {
+		t.Helper()
+		w := localRequest(s, "GET", "/api/v1/audit"+query, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET /api/v1/audit%s: got %d: %s", query, w.Code, w.Body.String())
+		}
+		var resp auditResponse
+		decodeJSON(t, w, &resp)
+		return resp
+	}
+
+	// The log database is shared by the package's tests: only look at
+	// entries added from here on
+	since := func(query string) []proxy.AuditEntry {
+		return list(query).Entries
+	}
+	if latest := list("?limit=1").Entries; len(latest) > 0 {
+		after := fmt.Sprintf("after=%d", latest[0].ID)
+		since = func(query string) []proxy.AuditEntry {
+			if query == "" {
+				return list("?" + after).Entries
+			}
+			return list(query + "&" + after).Entries
+		}
+	}
+
+	if w := localRequest(s, "PUT", "/api/v1/settings", map[string]string{"default_profile": "work"}); w.Code != http.StatusOK {
+		t.Fatalf("update settings: got %d: %s", w.Code, w.Body.String())
+	}
+	if w := localRequest(s, "PUT", "/api/v1/providers/backup", map[string]string{
+		"base_url": "https://api.backup.com", "auth_token": "sk-rotated-token-9999",
+	}); w.Code != http.StatusOK {
+		t.Fatalf("update provider: got %d: %s", w.Code, w.Body.String())
+	}
+	// Reads and non-mutating POSTs are not recorded
+	localRequest(s, "GET", "/api/v1/settings", nil)
+	localRequest(s, "POST", "/api/v1/config/validate", map[string]string{})
+
+	if all := since(""); len(all) != 2 {
+		t.Fatalf("audit log has %d new entries, want 2", len(all))
+	}
+
+	entries := since("?config_path=default_profile")
+	if len(entries) != 1 {
+		t.Fatalf("config_path filter: %d entries, want 1", len(entries))
+	}
+	e := entries[0]
+	if e.Actor != "local" || e.Role != string(RoleAdmin) || e.Method != "PUT" || e.Path != "/api/v1/settings" || e.Status != http.StatusOK {
+		t.Errorf("entry = %+v", e)
+	}
+	if len(e.Changes) != 1 || e.Changes[0].Path != "default_profile" || e.Changes[0].To != "work" {
+		t.Errorf("changes = %+v", e.Changes)
+	}
+
+	// Secrets are masked in the recorded changes
+	entries = since("?path=/api/v1/providers/")
+	if len(entries) != 1 {
+		t.Fatalf("path filter: %d entries, want 1", len(entries))
+	}
+	for _, c := range entries[0].Changes {
+		if to, ok := c.To.(string); ok && strings.Contains(to, "rotated-token") {
+			t.Errorf("secret not masked: %+v", c)
+		}
+	}
+
+	if entries := since("?actor=someone-else"); len(entries) != 0 {
+		t.Errorf("actor filter: %d entries, want 0", len(entries))
+	}
+	if w := localRequest(s, "GET", "/api/v1/audit?since=yesterday", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid since: got %d, want 400", w.Code)
+	}
+}
+
+func TestAuditConcurrentRequests(t *testing.T) {
+	if err := proxy.InitGlobalLogger(t.TempDir()); err != nil {
+		t.Fatalf("InitGlobalLogger() error: %v", err)
+	}
+	s := setupTestServer(t)
+
+	// A slow request doesn't hold up others, and isn't credited with the
+	// changes they make while it runs
+	started, release := make(chan struct{}), make(chan struct{})
+	h := s.auditMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/slow" {
+			close(started)
+			<-release
+			return
+		}
+		if err := config.SetDefaultProfile("work"); err != nil {
+			t.Error(err)
+		}
+	}))
+	serve := func(method, path string) <-chan struct{} {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, path, nil))
+		}()
+		return done
+	}
+
+	slow := serve("POST", "/api/v1/slow")
+	<-started
+	select {
+	case <-serve("PUT", "/api/v1/fast"):
+	case <-time.After(5 * time.Second):
+		t.Fatal("audited request waited for another to finish")
+	}
+	close(release)
+	<-slow
+
+	entry := func(path string) proxy.AuditEntry {
+		t.Helper()
+		entries, _, err := proxy.GetGlobalLogDB().ListAudit(proxy.AuditFilter{PathPrefix: path}, proxy.Page{Limit: 10})
+		if err != nil || len(entries) != 1 {
+			t.Fatalf("%s: %d entries, %v", path, len(entries), err)
+		}
+		return entries[0]
+	}
+	if changes := entry("/api/v1/fast").Changes; len(changes) != 1 || changes[0].Path != "default_profile" {
+		t.Errorf("fast request changes = %+v", changes)
+	}
+	if changes := entry("/api/v1/slow").Changes; len(changes) != 0 {
+		t.Errorf("slow request credited with %+v", changes)
+	}
+}
@@ -24,6 +24,7 @@ const (
 type AuthManager struct {
 	mu       sync.RWMutex
 	sessions map[string]time.Time // token -> last accessed
+	viewers  map[string]bool      // tokens of viewer sessions; others are admin
 
 	failMu   sync.Mutex
 	failures map[string]*loginFailure // IP -> failure info
@@ -41,6 +42,7 @@ type loginFailure struct {
 func NewAuthManager() *AuthManager {
 	return &AuthManager{
 		sessions: make(map[string]time.Time),
+		viewers:  make(map[string]bool),
 		failures: make(map[string]*loginFailure),
 		stopCh:   make(chan struct{}),
 	}
@@ -49,6 +51,17 @@ func NewAuthManager() *AuthManager {
 // GeneratePassword creates a random 16-character password and stores its bcrypt hash.
 // Returns the plaintext password (for one-time display to the user).
 func GeneratePassword() (string, error) {
+	return generatePassword(config.SetWebPasswordHash)
+}
+
+// GenerateViewerPassword creates a random read-only password like
+// GeneratePassword. Viewer sessions from the previous one stay valid until
+// the daemon restarts.
+func GenerateViewerPassword() (string, error) {
+	return generatePassword(config.SetWebViewerPasswordHash)
+}
+
+func generatePassword(store func(hash string) error) (string, error) {
 	b := make([]byte, 12) // 12 bytes = 16 base64-ish chars
 	if _, err := rand.Read(b); err != nil {
 		return "", err
@@ -61,7 +74,7 @@ func GeneratePassword() (string, error) {
 		return "", err
 	}
 
-	if err := config.SetWebPasswordHash(string(hash)); err != nil {
+	if err := store(string(hash)); err != nil {
 		return "", err
 	}
 	return password, nil
@@ -72,8 +85,25 @@ func HasPassword() bool {
 	return config.GetWebPasswordHash() != ""
 }
 
-// createSession generates a new session token and stores it.
+// passwordRole returns the role a password logs in as, checking the main
+// password first, then the viewer password.
+func passwordRole(password string) (Role, bool) {
+	if hash := config.GetWebPasswordHash(); hash != "" && bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+		return RoleAdmin, true
+	}
+	if hash := config.GetWebViewerPasswordHash(); hash != "" && bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+		return RoleViewer, true
+	}
+	return "", false
+}
+
+// createSession generates a new admin session token and stores it.
 func (am *AuthManager) createSession() string {
+	return am.createSessionAs(RoleAdmin)
+}
+
+// createSessionAs generates a new session token for role and stores it.
+func (am *AuthManager) createSessionAs(role Role) string {
 	b := make([]byte, 32)
 	if _, err := rand.Read(b); err != nil {
 		// Fallback to timestamp-based token if crypto/rand fails
@@ -83,6 +113,9 @@ func (am *AuthManager) createSession() string {
 
 	am.mu.Lock()
 	am.sessions[token] = time.Now()
+	if role == RoleViewer {
+		am.viewers[token] = true
+	}
 	am.mu.Unlock()
 
 	return token
@@ -90,36 +123,45 @@ func (am *AuthManager) createSession() string {
 
 // validateSession checks if a session token is valid and not expired.
 func (am *AuthManager) validateSession(token string) bool {
+	_, ok := am.sessionRole(token)
+	return ok
+}
+
+// sessionRole returns the role of a valid, unexpired session token.
+func (am *AuthManager) sessionRole(token string) (Role, bool) {
 	if token == "" {
-		return false
+		return "", false
 	}
 	am.mu.RLock()
 	lastAccess, ok := am.sessions[token]
 	am.mu.RUnlock()
 
 	if !ok {
-		return false
+		return "", false
 	}
 
 	if time.Since(lastAccess) > sessionMaxAge {
-		am.mu.Lock()
-		delete(am.sessions, token)
-		am.mu.Unlock()
-		return false
+		am.deleteSession(token)
+		return "", false
 	}
 
 	// Refresh last access time
 	am.mu.Lock()
 	am.sessions[token] = time.Now()
+	viewer := am.viewers[token]
 	am.mu.Unlock()
 
-	return true
+	if viewer {
+		return RoleViewer, true
+	}
+	return RoleAdmin, true
 }
 
 // deleteSession removes a session token.
 func (am *AuthManager) deleteSession(token string) {
 	am.mu.Lock()
 	delete(am.sessions, token)
+	delete(am.viewers, token)
 	am.mu.Unlock()
 }
 
@@ -127,6 +169,18 @@ func (am *AuthManager) deleteSession(token string) {
 func (am *AuthManager) invalidateAllSessions() {
 	am.mu.Lock()
 	am.sessions = make(map[string]time.Time)
+	am.viewers = make(map[string]bool)
+	am.mu.Unlock()
+}
+
+// invalidateViewerSessions removes the viewer sessions (used after the
+// viewer password changes).
+func (am *AuthManager) invalidateViewerSessions() {
+	am.mu.Lock()
+	for token := range am.viewers {
+		delete(am.sessions, token)
+	}
+	am.viewers = make(map[string]bool)
 	am.mu.Unlock()
 }
 
@@ -138,6 +192,7 @@ func (am *AuthManager) CleanExpired() {
 	for token, lastAccess := range am.sessions {
 		if now.Sub(lastAccess) > sessionMaxAge {
 			delete(am.sessions, token)
+			delete(am.viewers, token)
 		}
 	}
 }
@@ -248,43 +303,77 @@ func clientIP(r *http.Request) string {
 }
 
 // authMiddleware returns an HTTP middleware that enforces authentication.
-// Local requests are allowed through without authentication.
-// The login and pubkey endpoints are always accessible.
+// Requests a trusted reverse proxy vouches for act with the configured role,
+// and other local requests are allowed through without authentication.
+// The login and pubkey endpoints and the health badge are always accessible.
+// Other clients log in for a session cookie, or send an API token as a
+// Bearer token. Either carries a role, which limits what the request may do.
 func (s *Server) authMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Always allow auth endpoints
 		if r.URL.Path == "/api/v1/auth/login" ||
-			r.URL.Path == "/api/v1/auth/pubkey" {
+			r.URL.Path == "/api/v1/auth/pubkey" ||
+			r.URL.Path == "/badge/health.svg" ||
+			r.URL.Path == "/healthz" {
 			next.ServeHTTP(w, r)
 			return
 		}
 
+		// A trusted reverse proxy vouches for the user. This comes before
+		// the local bypass, since such proxies usually run on this machine.
+		if user, role, ok := trustedIdentity(r); ok {
+			if !role.allows(r) {
+				writeError(w, http.StatusForbidden, "the "+string(role)+" role cannot make this request")
+				return
+			}
+			next.ServeHTTP(w, withActor(r, user, role))
+			return
+		}
+
 		// Local requests bypass auth
 		if isLocalRequest(r) {
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(w, withActor(r, "local", RoleAdmin))
 			return
 		}
 
 		// No password configured = no auth required
 		if !HasPassword() {
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(w, withActor(r, "anonymous", RoleAdmin))
 			return
 		}
 
-		// Check session cookie
-		cookie, err := r.Cookie(sessionCookieName)
-		if err == nil && s.auth.validateSession(cookie.Value) {
-			next.ServeHTTP(w, r)
-			return
+		var role Role
+		name := "session"
+		if secret, ok := bearerToken(r); ok {
+			// Check API token
+			token := s.tokens.authenticate(secret, clientIP(r))
+			if token == nil {
+				writeError(w, http.StatusUnauthorized, "invalid API token")
+				return
+			}
+			role = token.role()
+			name = "token:" + token.Name
+		} else if cookie, err := r.Cookie(sessionCookieName); err == nil {
+			// Check session cookie
+			role, _ = s.auth.sessionRole(cookie.Value)
 		}
 
 		// Not authenticated
-		if isAPIRequest(r) {
-			writeError(w, http.StatusUnauthorized, "authentication required")
-		} else {
-			// Serve login page for browser requests
-			http.Redirect(w, r, "/login.html", http.StatusFound)
+		if role == "" {
+			if isAPIRequest(r) {
+				writeError(w, http.StatusUnauthorized, "authentication required")
+			} else {
+				// Serve login page for browser requests
+				http.Redirect(w, r, "/login.html", http.StatusFound)
+			}
+			return
+		}
+
+		if !role.allows(r) {
+			writeError(w, http.StatusForbidden, "the "+string(role)+" role cannot make this request")
+			return
 		}
+		next.ServeHTTP(w, withActor(r, name, role))
 	})
 }
 
@@ -316,13 +405,13 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hash := config.GetWebPasswordHash()
-	if hash == "" {
+	if !HasPassword() {
 		writeError(w, http.StatusForbidden, "no password configured")
 		return
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(req.Password)); err != nil {
+	role, ok := passwordRole(req.Password)
+	if !ok {
 		s.auth.recordFailure(ip)
 		writeError(w, http.StatusUnauthorized, "invalid password")
 		return
@@ -330,7 +419,7 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 
 	// Success
 	s.auth.resetFailures(ip)
-	token := s.auth.createSession()
+	token := s.auth.createSessionAs(role)
 
 	http.SetCookie(w, &http.Cookie{
 		Name:     sessionCookieName,
@@ -338,11 +427,11 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 		Path:     "/",
 		HttpOnly: true,
 		SameSite: http.SameSiteStrictMode,
-		Secure:   r.Header.Get("X-Forwarded-Proto") == "https",
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
 		MaxAge:   int(sessionMaxAge.Seconds()),
 	})
 
-	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "role": string(role)})
 }
 
 // handleLogout handles POST /api/v1/auth/logout
@@ -420,6 +509,57 @@ func (s *Server) handlePasswordChange(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]string{"status": "password updated"})
 }
 
+// handleViewerPassword handles GET/PUT /api/v1/settings/viewer-password -
+// reports whether read-only logins are on, or sets the viewer password. An
+// empty password turns viewer logins off.
+func (s *Server) handleViewerPassword(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]bool{"enabled": config.GetWebViewerPasswordHash() != ""})
+
+	case http.MethodPut:
+		var req struct {
+			Password string `json:"password"`
+		}
+		if err := readJSON(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON")
+			return
+		}
+
+		var hash []byte
+		if req.Password != "" {
+			if !HasPassword() {
+				writeError(w, http.StatusBadRequest, "set the Web UI password first")
+				return
+			}
+			if len(req.Password) < 6 {
+				writeError(w, http.StatusBadRequest, "password must be at least 6 characters")
+				return
+			}
+			if role, _ := passwordRole(req.Password); role == RoleAdmin {
+				writeError(w, http.StatusBadRequest, "viewer password must differ from the Web UI password")
+				return
+			}
+			var err error
+			if hash, err = bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost); err != nil {
+				writeError(w, http.StatusInternalServerError, "failed to hash password")
+				return
+			}
+		}
+
+		if err := config.SetWebViewerPasswordHash(string(hash)); err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to save password")
+			return
+		}
+		s.auth.invalidateViewerSessions()
+
+		writeJSON(w, http.StatusOK, map[string]bool{"enabled": hash != nil})
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
 // handleAuthCheck handles GET /api/v1/auth/check — returns auth status.
 func (s *Server) handleAuthCheck(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -427,21 +567,24 @@ func (s *Server) handleAuthCheck(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	authenticated := isLocalRequest(r)
-	if !authenticated && HasPassword() {
-		cookie, err := r.Cookie(sessionCookieName)
-		if err == nil {
-			authenticated = s.auth.validateSession(cookie.Value)
-		}
-	} else if !HasPassword() {
-		authenticated = true
-	}
-
-	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"authenticated":     authenticated,
+	resp := map[string]interface{}{
 		"password_required": HasPassword() && !isLocalRequest(r),
 		"is_local":          isLocalRequest(r),
-	})
+	}
+	var role Role
+	if user, trustedRole, ok := trustedIdentity(r); ok {
+		role = trustedRole
+		resp["user"] = user
+		resp["password_required"] = false
+	} else if isLocalRequest(r) || !HasPassword() {
+		role = RoleAdmin
+	} else if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		role, _ = s.auth.sessionRole(cookie.Value)
+	}
+	resp["authenticated"] = role != ""
+	resp["role"] = role
+
+	writeJSON(w, http.StatusOK, resp)
 }
 
 // handlePubKey handles GET /api/v1/auth/pubkey — returns RSA public key for token encryption.
@@ -283,6 +283,28 @@ func TestAuthMiddlewareRemoteBlocked(t *testing.T) {
 	}
 }
 
+func TestAuthMiddlewareHealthzPublic(t *testing.T) {
+	s, cleanup := setupTestAuth(t)
+	defer cleanup()
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("testpass"), bcrypt.MinCost)
+	config.SetWebPasswordHash(string(hash))
+
+	handler := s.authMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Container probes come from outside loopback and carry no credentials
+	req := httptest.NewRequest("GET", "/healthz", nil)
+	req.RemoteAddr = "10.0.0.1:12345"
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("remote /healthz without auth got %d, want 200", w.Code)
+	}
+}
+
 func TestAuthMiddlewareRemoteWithSession(t *testing.T) {
 	s, cleanup := setupTestAuth(t)
 	defer cleanup()
@@ -603,3 +625,163 @@ func TestLoginNoPasswordConfigured(t *testing.T) {
 		t.Errorf("login without password set got %d, want 403", w.Code)
 	}
 }
+
+func TestViewerRole(t *testing.T) {
+	s, cleanup := setupTestAuth(t)
+	defer cleanup()
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("adminpass"), bcrypt.MinCost)
+	config.SetWebPasswordHash(string(hash))
+
+	// Viewer passwords are set by the admin and must differ from theirs
+	if w := localRequest(s, "PUT", "/api/v1/settings/viewer-password", map[string]string{"password": "adminpass"}); w.Code != http.StatusBadRequest {
+		t.Errorf("viewer password equal to admin password: got %d, want 400", w.Code)
+	}
+	if w := localRequest(s, "PUT", "/api/v1/settings/viewer-password", map[string]string{"password": "viewpass"}); w.Code != http.StatusOK {
+		t.Fatalf("set viewer password: got %d: %s", w.Code, w.Body.String())
+	}
+
+	login := func(password string) (*http.Cookie, string) {
+		t.Helper()
+		req := httptest.NewRequest("POST", "/api/v1/auth/login", strings.NewReader(`{"password":"`+password+`"}`))
+		req.RemoteAddr = "10.0.0.1:12345"
+		w := httptest.NewRecorder()
+		s.handleLogin(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("login: got %d", w.Code)
+		}
+		var resp map[string]string
+		decodeJSON(t, w, &resp)
+		return w.Result().Cookies()[0], resp["role"]
+	}
+	viewer, role := login("viewpass")
+	if role != string(RoleViewer) {
+		t.Fatalf("viewer login role = %q", role)
+	}
+	if _, role := login("adminpass"); role != string(RoleAdmin) {
+		t.Fatalf("admin login role = %q", role)
+	}
+
+	// Secrets readable endpoints hold must not reach viewers
+	const secret = "sk-viewer-must-not-see"
+	config.SetProvider("test", &config.ProviderConfig{
+		BaseURL:   "https://api.example.com",
+		AuthToken: secret,
+		EnvVars:   map[string]string{"EXTRA_KEY": secret},
+		Transforms: &config.ProviderTransforms{
+			Request: &config.TransformRules{SetHeaders: map[string]string{"X-Api-Key": secret}},
+		},
+	})
+	project := t.TempDir()
+	config.BindProject(project, "", "")
+	config.SetProjectMCPServers(project, map[string]*config.MCPServerConfig{
+		"fs": {Command: "mcp-fs", Env: map[string]string{"TOKEN": secret}},
+	})
+	config.SetMiddleware(&config.MiddlewareConfig{Middlewares: []*config.MiddlewareEntry{{
+		Name:   "guard",
+		Source: "http",
+		URL:    "https://guard.example.com",
+		Config: json.RawMessage(`{"secret":"` + secret + `","headers":{"Authorization":"Bearer ` + secret + `"}}`),
+	}}})
+
+	tests := []struct {
+		method, path string
+		want         int
+	}{
+		{"GET", "/api/v1/usage/summary", http.StatusOK},
+		{"GET", "/api/v1/health", http.StatusOK},
+		{"GET", "/api/v1/logs", http.StatusOK},
+		{"GET", "/api/v1/providers", http.StatusOK},
+		{"GET", "/api/v1/providers/test", http.StatusOK},
+		{"GET", "/api/v1/bindings", http.StatusOK},
+		{"GET", "/api/v1/middleware", http.StatusOK},
+		{"GET", "/api/v1/middleware/guard", http.StatusOK},
+		{"DELETE", "/api/v1/providers/test", http.StatusForbidden},
+		{"POST", "/api/v1/profiles", http.StatusForbidden},
+		{"PUT", "/api/v1/budget", http.StatusForbidden},
+		{"POST", "/api/v1/sync/push", http.StatusForbidden},
+		{"GET", "/api/v1/sync/config", http.StatusForbidden},
+		{"GET", "/api/v1/logs/req-1/body", http.StatusForbidden},
+		{"GET", "/api/v1/auth/tokens", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader("{}"))
+		req.RemoteAddr = "10.0.0.1:12345"
+		req.AddCookie(viewer)
+		w := httptest.NewRecorder()
+		s.httpServer.Handler.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("viewer %s %s: got %d, want %d", tt.method, tt.path, w.Code, tt.want)
+		}
+		if strings.Contains(w.Body.String(), secret) {
+			t.Errorf("viewer %s %s: response holds a secret: %s", tt.method, tt.path, w.Body.String())
+		}
+	}
+
+	// Admins still read the config they edit
+	if w := localRequest(s, "GET", "/api/v1/providers/test", nil); !strings.Contains(w.Body.String(), secret) {
+		t.Errorf("admin provider response lost the token: %s", w.Body.String())
+	}
+
+	// Changing the viewer password logs viewers out
+	if w := localRequest(s, "PUT", "/api/v1/settings/viewer-password", map[string]string{"password": ""}); w.Code != http.StatusOK {
+		t.Fatalf("clear viewer password: got %d", w.Code)
+	}
+	if s.auth.validateSession(viewer.Value) {
+		t.Error("viewer session still valid after the viewer password changed")
+	}
+}
+
+func TestTrustedHeaderAuth(t *testing.T) {
+	s, cleanup := setupTestAuth(t)
+	defer cleanup()
+	hash, _ := bcrypt.GenerateFromPassword([]byte("testpass"), bcrypt.MinCost)
+	config.SetWebPasswordHash(string(hash))
+	config.SetTrustedHeaderAuth(&config.TrustedHeaderAuthConfig{
+		Enabled:        true,
+		Header:         "Tailscale-User-Login",
+		TrustedProxies: []string{"100.64.0.0/10"},
+	})
+
+	send := func(method, remote, user string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/providers", strings.NewReader("{}"))
+		req.RemoteAddr = remote
+		if user != "" {
+			req.Header.Set("Tailscale-User-Login", user)
+		}
+		w := httptest.NewRecorder()
+		s.httpServer.Handler.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := send("GET", "100.100.1.2:443", "alice@example.com"); w.Code != http.StatusOK {
+		t.Errorf("trusted proxy with header: got %d, want 200", w.Code)
+	}
+	if w := send("GET", "100.100.1.2:443", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("trusted proxy without header: got %d, want 401", w.Code)
+	}
+	if w := send("GET", "10.0.0.1:12345", "alice@example.com"); w.Code != http.StatusUnauthorized {
+		t.Errorf("header from untrusted address: got %d, want 401", w.Code)
+	}
+
+	// The identity is reported by the auth check
+	req := httptest.NewRequest("GET", "/api/v1/auth/check", nil)
+	req.RemoteAddr = "100.100.1.2:443"
+	req.Header.Set("Tailscale-User-Login", "alice@example.com")
+	w := httptest.NewRecorder()
+	s.httpServer.Handler.ServeHTTP(w, req)
+	var check map[string]interface{}
+	decodeJSON(t, w, &check)
+	if check["user"] != "alice@example.com" || check["role"] != string(RoleAdmin) || check["authenticated"] != true {
+		t.Errorf("auth check = %v", check)
+	}
+
+	// Trusted users can be limited to the viewer role, even from localhost
+	config.SetTrustedHeaderAuth(&config.TrustedHeaderAuthConfig{Enabled: true, Header: "Tailscale-User-Login", Role: "viewer"})
+	if w := send("DELETE", "127.0.0.1:12345", "bob"); w.Code != http.StatusForbidden {
+		t.Errorf("viewer DELETE via local proxy: got %d, want 403", w.Code)
+	}
+	if w := send("GET", "127.0.0.1:12345", "bob"); w.Code != http.StatusOK {
+		t.Errorf("viewer GET via local proxy: got %d, want 200", w.Code)
+	}
+}
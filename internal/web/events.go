@@ -0,0 +1,132 @@
+package web
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Events broadcast to the Web UI on /api/v1/events.
+const (
+	// EventConfigReload is sent after the daemon reloads zen.json, with
+	// {"status": "ok"} or {"status": "error", "error": "..."} when the new
+	// file was rejected and the previous config kept.
+	EventConfigReload = "config_reload"
+
+	// EventRequestCompleted is sent when the proxy finishes a request, with
+	// the request's record as listed by /api/v1/monitoring/requests.
+	EventRequestCompleted = "request_completed"
+
+	// EventBudgetWarning is sent when the global budget status changes after
+	// a request: a warning threshold or limit is reached, or the budget is
+	// back under them, in which case the message is empty.
+	EventBudgetWarning = "budget_warning"
+
+	// EventProviderHealth is sent when the health checker sees a provider's
+	// status change, with {"provider", "from", "to", "error"}.
+	EventProviderHealth = "provider_health"
+
+	// EventAgentSession is sent when an agent session is registered,
+	// removed or changes status, with {"id", "profile", "status"}.
+	EventAgentSession = "agent_session"
+
+	// EventContextWarning is sent when a session's prompt grows past
+	// alerts.context_warn_percent of its model's context window, with
+	// {"session_id", "warn_percent", "model", "context_tokens", "window",
+	// "percent", "timestamp"}.
+	EventContextWarning = "context_warning"
+
+	// EventAgentDeadlock is sent when agent sessions are found waiting on
+	// each other's locks, with {"sessions", "paths", "policy", "victim",
+	// "detected_at"}.
+	EventAgentDeadlock = "agent_deadlock"
+)
+
+// eventKeepAlive is how often an idle event stream gets a comment line, so
+// proxies don't close it.
+const eventKeepAlive = 30 * time.Second
+
+// serverEvent is one server-sent event.
+type serverEvent struct {
+	name string
+	data interface{}
+}
+
+// eventHub fans events out to the connected event streams. A subscriber
+// that falls behind drops events rather than blocking the sender.
+type eventHub struct {
+	mu   sync.Mutex
+	subs map[chan serverEvent]struct{}
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{subs: make(map[chan serverEvent]struct{})}
+}
+
+func (h *eventHub) subscribe() chan serverEvent {
+	ch := make(chan serverEvent, 16)
+	h.mu.Lock()
+	h.subs[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch
+}
+
+func (h *eventHub) unsubscribe(ch chan serverEvent) {
+	h.mu.Lock()
+	delete(h.subs, ch)
+	h.mu.Unlock()
+}
+
+func (h *eventHub) publish(ev serverEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// Broadcast sends an event to every connected Web UI.
+func (s *Server) Broadcast(event string, data interface{}) {
+	s.events.publish(serverEvent{name: event, data: data})
+}
+
+// handleEvents streams server events to the Web UI.
+// GET /api/v1/events
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ch := s.events.subscribe()
+	defer s.events.unsubscribe(ch)
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev := <-ch:
+			sendSSE(w, flusher, ev.name, ev.data)
+		case <-keepAlive.C:
+			w.Write([]byte(": keep-alive\n\n"))
+			flusher.Flush()
+		}
+	}
+}
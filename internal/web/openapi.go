@@ -0,0 +1,377 @@
+package web
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// The OpenAPI document is kept in code, next to the routes it describes.
+// TestOpenAPICoversRoutes fails when a route registered on the web server
+// is missing from apiOperations.
+
+// apiOperation documents one method of one API path.
+type apiOperation struct {
+	Method  string
+	Path    string // OpenAPI path template, e.g. /api/v1/providers/{name}
+	Tag     string
+	Summary string
+	Query   []string // query parameters it reads
+	Public  bool     // served without authentication
+}
+
+var pageQuery = []string{"limit", "before", "after"}
+
+var apiOperations = []apiOperation{
+	// Auth
+	{Method: http.MethodPost, Path: "/api/v1/auth/login", Tag: "auth", Summary: "Log in with the Web UI password for a session cookie", Public: true},
+	{Method: http.MethodPost, Path: "/api/v1/auth/logout", Tag: "auth", Summary: "End the current session"},
+	{Method: http.MethodGet, Path: "/api/v1/auth/check", Tag: "auth", Summary: "Report whether the request is authenticated, and with which role"},
+	{Method: http.MethodGet, Path: "/api/v1/auth/pubkey", Tag: "auth", Summary: "Get the public key used to encrypt tokens in transit", Public: true},
+	{Method: http.MethodGet, Path: "/api/v1/auth/tokens", Tag: "auth", Summary: "List API tokens"},
+	{Method: http.MethodPost, Path: "/api/v1/auth/tokens", Tag: "auth", Summary: "Create an API token"},
+	{Method: http.MethodDelete, Path: "/api/v1/auth/tokens/{id}", Tag: "auth", Summary: "Revoke an API token"},
+
+	// Daemon and config
+	{Method: http.MethodGet, Path: "/api/v1/health", Tag: "daemon", Summary: "Web server health and version"},
+	{Method: http.MethodPost, Path: "/api/v1/reload", Tag: "daemon", Summary: "Reload the config file"},
+	{Method: http.MethodGet, Path: "/api/v1/events", Tag: "daemon", Summary: "Server-sent event stream of config, request, budget, health and agent events"},
+	{Method: http.MethodGet, Path: "/api/v1/daemon/status", Tag: "daemon", Summary: "Daemon status, ports and TLS"},
+	{Method: http.MethodGet, Path: "/api/v1/daemon/health", Tag: "daemon", Summary: "Daemon health checks"},
+	{Method: http.MethodGet, Path: "/api/v1/daemon/metrics", Tag: "daemon", Summary: "Daemon runtime metrics"},
+	{Method: http.MethodPost, Path: "/api/v1/daemon/shutdown", Tag: "daemon", Summary: "Stop the daemon"},
+	{Method: http.MethodPost, Path: "/api/v1/daemon/restart", Tag: "daemon", Summary: "Restart the daemon"},
+	{Method: http.MethodPost, Path: "/api/v1/daemon/reload", Tag: "daemon", Summary: "Reload the daemon's config"},
+	{Method: http.MethodGet, Path: "/healthz", Tag: "daemon", Summary: "Container liveness and readiness probe", Public: true},
+	{Method: http.MethodGet, Path: "/api/v1/daemon/sessions", Tag: "daemon", Summary: "List client sessions registered with the daemon"},
+	{Method: http.MethodPost, Path: "/api/v1/daemon/sessions", Tag: "daemon", Summary: "Register a client session"},
+	{Method: http.MethodGet, Path: "/api/v1/daemon/mcp", Tag: "daemon", Summary: "List the MCP servers the daemon runs, by project"},
+	{Method: http.MethodGet, Path: "/api/v1/config/validate", Tag: "config", Summary: "Check the config for errors and warnings"},
+	{Method: http.MethodGet, Path: "/api/v1/config/history", Tag: "config", Summary: "List config snapshots"},
+	{Method: http.MethodGet, Path: "/api/v1/config/diff", Tag: "config", Summary: "Diff two config snapshots", Query: []string{"from", "to"}},
+	{Method: http.MethodPost, Path: "/api/v1/config/rollback", Tag: "config", Summary: "Restore a config snapshot"},
+	{Method: http.MethodGet, Path: "/api/v1/audit", Tag: "config", Summary: "List changes made through the web API",
+		Query: append([]string{"actor", "method", "path", "config_path", "since", "until"}, pageQuery...)},
+	{Method: http.MethodGet, Path: "/api/v1/settings", Tag: "config", Summary: "Get global settings"},
+	{Method: http.MethodPut, Path: "/api/v1/settings", Tag: "config", Summary: "Update global settings"},
+	{Method: http.MethodPut, Path: "/api/v1/settings/password", Tag: "config", Summary: "Change the Web UI password"},
+	{Method: http.MethodGet, Path: "/api/v1/settings/viewer-password", Tag: "config", Summary: "Report whether a viewer password is set"},
+	{Method: http.MethodPut, Path: "/api/v1/settings/viewer-password", Tag: "config", Summary: "Set or clear the viewer password"},
+	{Method: http.MethodGet, Path: "/api/v1/settings/log-level", Tag: "config", Summary: "Get the daemon log level and format"},
+	{Method: http.MethodPut, Path: "/api/v1/settings/log-level", Tag: "config", Summary: "Change the daemon log level until restart"},
+
+	// Providers and profiles
+	{Method: http.MethodGet, Path: "/api/v1/providers", Tag: "providers", Summary: "List providers"},
+	{Method: http.MethodPost, Path: "/api/v1/providers", Tag: "providers", Summary: "Create a provider"},
+	{Method: http.MethodGet, Path: "/api/v1/providers/disabled", Tag: "providers", Summary: "List disabled providers"},
+	{Method: http.MethodGet, Path: "/api/v1/providers/{name}", Tag: "providers", Summary: "Get a provider"},
+	{Method: http.MethodPut, Path: "/api/v1/providers/{name}", Tag: "providers", Summary: "Update a provider"},
+	{Method: http.MethodDelete, Path: "/api/v1/providers/{name}", Tag: "providers", Summary: "Delete a provider"},
+	{Method: http.MethodPost, Path: "/api/v1/providers/{name}/disable", Tag: "providers", Summary: "Disable a provider for today, this month or permanently"},
+	{Method: http.MethodPost, Path: "/api/v1/providers/{name}/enable", Tag: "providers", Summary: "Enable a disabled provider"},
+	{Method: http.MethodGet, Path: "/api/v1/providers/{name}/keys", Tag: "providers", Summary: "List a provider's fallback keys"},
+	{Method: http.MethodPost, Path: "/api/v1/providers/{name}/keys", Tag: "providers", Summary: "Add a fallback key"},
+	{Method: http.MethodDelete, Path: "/api/v1/providers/{name}/keys/{id}", Tag: "providers", Summary: "Remove a fallback key"},
+	{Method: http.MethodPost, Path: "/api/v1/providers/{name}/keys/{id}/verify", Tag: "providers", Summary: "Check a key against the provider"},
+	{Method: http.MethodPost, Path: "/api/v1/providers/{name}/keys/{id}/promote", Tag: "providers", Summary: "Make a key the provider's auth token"},
+	{Method: http.MethodGet, Path: "/api/v1/profiles", Tag: "profiles", Summary: "List profiles"},
+	{Method: http.MethodPost, Path: "/api/v1/profiles", Tag: "profiles", Summary: "Create a profile"},
+	{Method: http.MethodGet, Path: "/api/v1/profiles/{name}", Tag: "profiles", Summary: "Get a profile"},
+	{Method: http.MethodPut, Path: "/api/v1/profiles/{name}", Tag: "profiles", Summary: "Update a profile"},
+	{Method: http.MethodDelete, Path: "/api/v1/profiles/{name}", Tag: "profiles", Summary: "Delete a profile"},
+	{Method: http.MethodPost, Path: "/api/v1/profiles/temp", Tag: "profiles", Summary: "Create a temporary profile"},
+	{Method: http.MethodDelete, Path: "/api/v1/profiles/temp/{id}", Tag: "profiles", Summary: "Delete a temporary profile"},
+	{Method: http.MethodGet, Path: "/api/v1/bindings", Tag: "profiles", Summary: "List project bindings"},
+	{Method: http.MethodPost, Path: "/api/v1/bindings", Tag: "profiles", Summary: "Bind a project directory to a profile"},
+	{Method: http.MethodGet, Path: "/api/v1/bindings/{path}", Tag: "profiles", Summary: "Get a project binding"},
+	{Method: http.MethodPut, Path: "/api/v1/bindings/{path}", Tag: "profiles", Summary: "Update a project binding"},
+	{Method: http.MethodDelete, Path: "/api/v1/bindings/{path}", Tag: "profiles", Summary: "Remove a project binding"},
+	{Method: http.MethodGet, Path: "/api/v1/models", Tag: "models", Summary: "Catalog of models from provider model lists and the config", Query: []string{"refresh"}},
+	{Method: http.MethodGet, Path: "/api/v1/model-aliases", Tag: "models", Summary: "Get model rewrite rules"},
+	{Method: http.MethodPut, Path: "/api/v1/model-aliases", Tag: "models", Summary: "Replace model rewrite rules"},
+	{Method: http.MethodGet, Path: "/api/v1/pricing", Tag: "models", Summary: "Get model pricing, with defaults and overrides"},
+	{Method: http.MethodPut, Path: "/api/v1/pricing", Tag: "models", Summary: "Set pricing overrides"},
+	{Method: http.MethodPost, Path: "/api/v1/pricing/reset", Tag: "models", Summary: "Drop pricing overrides"},
+	{Method: http.MethodGet, Path: "/api/v1/pricing/sync", Tag: "models", Summary: "Get pricing sync settings and status"},
+	{Method: http.MethodPost, Path: "/api/v1/pricing/sync", Tag: "models", Summary: "Sync pricing now"},
+	{Method: http.MethodGet, Path: "/api/v1/currency", Tag: "models", Summary: "Get the display currency"},
+	{Method: http.MethodPut, Path: "/api/v1/currency", Tag: "models", Summary: "Set the display currency"},
+
+	// Logs, usage and monitoring
+	{Method: http.MethodGet, Path: "/api/v1/logs", Tag: "monitoring", Summary: "List request logs",
+		Query: append([]string{"provider", "errors_only", "session_id", "label", "bookmarked"}, pageQuery...)},
+	{Method: http.MethodGet, Path: "/api/v1/logs/stream", Tag: "monitoring", Summary: "WebSocket stream of new log entries",
+		Query: []string{"provider", "errors_only", "session_id", "status", "project"}},
+	{Method: http.MethodPatch, Path: "/api/v1/logs/{id}", Tag: "monitoring", Summary: "Label, note or bookmark a log entry"},
+	{Method: http.MethodGet, Path: "/api/v1/logs/{id}/body", Tag: "monitoring", Summary: "Get a logged request's captured bodies"},
+	{Method: http.MethodGet, Path: "/api/v1/usage", Tag: "usage", Summary: "List usage records", Query: pageQuery},
+	{Method: http.MethodGet, Path: "/api/v1/usage/summary", Tag: "usage", Summary: "Usage totals by provider and model"},
+	{Method: http.MethodGet, Path: "/api/v1/usage/hourly", Tag: "usage", Summary: "Hourly usage"},
+	{Method: http.MethodGet, Path: "/api/v1/usage/export", Tag: "usage", Summary: "Export usage as CSV or JSON",
+		Query: []string{"format", "period", "since", "until", "project", "tag"}},
+	{Method: http.MethodPost, Path: "/api/v1/usage/compact", Tag: "usage", Summary: "Compact old usage records"},
+	{Method: http.MethodGet, Path: "/api/v1/usage/storage", Tag: "usage", Summary: "Log database size and retention"},
+	{Method: http.MethodGet, Path: "/api/v1/budget", Tag: "usage", Summary: "Get budgets"},
+	{Method: http.MethodPut, Path: "/api/v1/budget", Tag: "usage", Summary: "Set budgets"},
+	{Method: http.MethodGet, Path: "/api/v1/budget/status", Tag: "usage", Summary: "Spending against budgets"},
+	{Method: http.MethodGet, Path: "/api/v1/dashboard", Tag: "usage", Summary: "Home page summary: today's spend and errors, sessions, top models, budgets, health, agents"},
+	{Method: http.MethodGet, Path: "/api/v1/health/summary", Tag: "monitoring", Summary: "Provider health overview"},
+	{Method: http.MethodGet, Path: "/api/v1/health/providers", Tag: "monitoring", Summary: "Health of every provider"},
+	{Method: http.MethodGet, Path: "/api/v1/health/providers/{name}", Tag: "monitoring", Summary: "Health history of a provider"},
+	{Method: http.MethodGet, Path: "/badge/health.svg", Tag: "monitoring", Summary: "Provider health badge", Public: true},
+	{Method: http.MethodGet, Path: "/api/v1/monitoring/requests", Tag: "monitoring", Summary: "List recent requests"},
+	{Method: http.MethodGet, Path: "/api/v1/monitoring/requests/{id}", Tag: "monitoring", Summary: "Get a recent request"},
+	{Method: http.MethodGet, Path: "/api/v1/sessions", Tag: "monitoring", Summary: "List sessions by last activity",
+		Query: append([]string{"label", "bookmarked"}, pageQuery...)},
+	{Method: http.MethodGet, Path: "/api/v1/sessions/{id}", Tag: "monitoring", Summary: "Get a session's insight, context warning and context window use", Query: []string{"threshold"}},
+	{Method: http.MethodPatch, Path: "/api/v1/sessions/{id}", Tag: "monitoring", Summary: "Label, note or bookmark a session"},
+	{Method: http.MethodPost, Path: "/api/v1/sessions/{id}/replay", Tag: "monitoring", Summary: "Re-send a captured request of a session and diff the responses"},
+	{Method: http.MethodGet, Path: "/api/v1/experiments", Tag: "monitoring", Summary: "Shadow traffic experiment reports"},
+	{Method: http.MethodDelete, Path: "/api/v1/experiments", Tag: "monitoring", Summary: "Reset experiment reports"},
+
+	// Sync and team
+	{Method: http.MethodGet, Path: "/api/v1/sync/config", Tag: "sync", Summary: "Get config sync settings"},
+	{Method: http.MethodPut, Path: "/api/v1/sync/config", Tag: "sync", Summary: "Set config sync settings"},
+	{Method: http.MethodPost, Path: "/api/v1/sync/pull", Tag: "sync", Summary: "Pull the synced config"},
+	{Method: http.MethodPost, Path: "/api/v1/sync/push", Tag: "sync", Summary: "Push the config"},
+	{Method: http.MethodGet, Path: "/api/v1/sync/status", Tag: "sync", Summary: "Sync status"},
+	{Method: http.MethodPost, Path: "/api/v1/sync/rotate-passphrase", Tag: "sync", Summary: "Re-encrypt the synced config with a new passphrase"},
+	{Method: http.MethodPost, Path: "/api/v1/sync/test", Tag: "sync", Summary: "Test the sync backend"},
+	{Method: http.MethodPost, Path: "/api/v1/sync/create-gist", Tag: "sync", Summary: "Create a gist to sync through"},
+	{Method: http.MethodGet, Path: "/api/v1/team", Tag: "sync", Summary: "Team config status"},
+	{Method: http.MethodPost, Path: "/api/v1/team/pull", Tag: "sync", Summary: "Pull the team config"},
+	{Method: http.MethodPost, Path: "/api/v1/team/push", Tag: "sync", Summary: "Push to the team config"},
+
+	// Webhooks and ingress
+	{Method: http.MethodGet, Path: "/api/v1/webhooks", Tag: "webhooks", Summary: "List webhooks"},
+	{Method: http.MethodPost, Path: "/api/v1/webhooks", Tag: "webhooks", Summary: "Create a webhook"},
+	{Method: http.MethodPost, Path: "/api/v1/webhooks/test", Tag: "webhooks", Summary: "Send a test event"},
+	{Method: http.MethodGet, Path: "/api/v1/webhooks/failures", Tag: "webhooks", Summary: "List failed deliveries"},
+	{Method: http.MethodPost, Path: "/api/v1/webhooks/failures", Tag: "webhooks", Summary: "Requeue failed deliveries"},
+	{Method: http.MethodDelete, Path: "/api/v1/webhooks/failures", Tag: "webhooks", Summary: "Clear failed deliveries"},
+	{Method: http.MethodGet, Path: "/api/v1/webhooks/{name}", Tag: "webhooks", Summary: "Get a webhook"},
+	{Method: http.MethodPut, Path: "/api/v1/webhooks/{name}", Tag: "webhooks", Summary: "Update a webhook"},
+	{Method: http.MethodDelete, Path: "/api/v1/webhooks/{name}", Tag: "webhooks", Summary: "Delete a webhook"},
+	{Method: http.MethodGet, Path: "/api/v1/ingress", Tag: "ingress", Summary: "Get ingress settings"},
+	{Method: http.MethodPut, Path: "/api/v1/ingress", Tag: "ingress", Summary: "Set ingress settings"},
+	{Method: http.MethodPost, Path: "/api/v1/ingress/keys", Tag: "ingress", Summary: "Create an ingress key"},
+	{Method: http.MethodDelete, Path: "/api/v1/ingress/keys/{id}", Tag: "ingress", Summary: "Revoke an ingress key"},
+
+	// Request processing
+	{Method: http.MethodGet, Path: "/api/v1/compression", Tag: "processing", Summary: "Get context compression settings"},
+	{Method: http.MethodPut, Path: "/api/v1/compression", Tag: "processing", Summary: "Set context compression settings"},
+	{Method: http.MethodGet, Path: "/api/v1/compression/stats", Tag: "processing", Summary: "Compression statistics"},
+	{Method: http.MethodPost, Path: "/api/v1/compression/preview", Tag: "processing", Summary: "Preview compression of a request"},
+	{Method: http.MethodGet, Path: "/api/v1/middleware", Tag: "processing", Summary: "Get middleware settings"},
+	{Method: http.MethodPut, Path: "/api/v1/middleware", Tag: "processing", Summary: "Set middleware settings"},
+	{Method: http.MethodPost, Path: "/api/v1/middleware/reload", Tag: "processing", Summary: "Reload middleware"},
+	{Method: http.MethodPost, Path: "/api/v1/middleware/upload", Tag: "processing", Summary: "Upload a middleware plugin"},
+	{Method: http.MethodGet, Path: "/api/v1/middleware/{name}", Tag: "processing", Summary: "Get a middleware"},
+	{Method: http.MethodPost, Path: "/api/v1/middleware/{name}/enable", Tag: "processing", Summary: "Enable a middleware"},
+	{Method: http.MethodPost, Path: "/api/v1/middleware/{name}/disable", Tag: "processing", Summary: "Disable a middleware"},
+	{Method: http.MethodGet, Path: "/api/v1/middleware/{name}/stats", Tag: "processing", Summary: "Middleware statistics"},
+	{Method: http.MethodGet, Path: "/api/v1/auto-permission", Tag: "processing", Summary: "Auto-permission settings of every client"},
+	{Method: http.MethodGet, Path: "/api/v1/auto-permission/{client}", Tag: "processing", Summary: "Get a client's auto-permission settings"},
+	{Method: http.MethodPut, Path: "/api/v1/auto-permission/{client}", Tag: "processing", Summary: "Set a client's auto-permission settings"},
+
+	// Bot
+	{Method: http.MethodGet, Path: "/api/v1/bot", Tag: "bot", Summary: "Get bot settings"},
+	{Method: http.MethodPut, Path: "/api/v1/bot", Tag: "bot", Summary: "Set bot settings"},
+	{Method: http.MethodPost, Path: "/api/v1/bot/chat", Tag: "bot", Summary: "Chat with the bot"},
+	{Method: http.MethodGet, Path: "/api/v1/bot/skills", Tag: "bot", Summary: "List skills"},
+	{Method: http.MethodPost, Path: "/api/v1/bot/skills", Tag: "bot", Summary: "Create a skill"},
+	{Method: http.MethodGet, Path: "/api/v1/bot/skills/{name}", Tag: "bot", Summary: "Get a skill"},
+	{Method: http.MethodPut, Path: "/api/v1/bot/skills/{name}", Tag: "bot", Summary: "Update a skill"},
+	{Method: http.MethodDelete, Path: "/api/v1/bot/skills/{name}", Tag: "bot", Summary: "Delete a skill"},
+	{Method: http.MethodGet, Path: "/api/v1/bot/skills/config", Tag: "bot", Summary: "Get skill matching settings"},
+	{Method: http.MethodPut, Path: "/api/v1/bot/skills/config", Tag: "bot", Summary: "Set skill matching settings"},
+	{Method: http.MethodPost, Path: "/api/v1/bot/skills/test", Tag: "bot", Summary: "Test which skill a message matches"},
+	{Method: http.MethodGet, Path: "/api/v1/bot/skills/logs", Tag: "bot", Summary: "Recent skill matches"},
+
+	// Agents (beta)
+	{Method: http.MethodGet, Path: "/api/v1/agent/config", Tag: "agent", Summary: "Get agent settings"},
+	{Method: http.MethodPut, Path: "/api/v1/agent/config", Tag: "agent", Summary: "Set agent settings"},
+	{Method: http.MethodGet, Path: "/api/v1/agent/stats", Tag: "agent", Summary: "Agent statistics"},
+	{Method: http.MethodGet, Path: "/api/v1/agent/sessions", Tag: "agent", Summary: "List agent sessions"},
+	{Method: http.MethodGet, Path: "/api/v1/agent/sessions/{id}", Tag: "agent", Summary: "Get an agent session"},
+	{Method: http.MethodPost, Path: "/api/v1/agent/sessions/{id}/kill", Tag: "agent", Summary: "Kill an agent session"},
+	{Method: http.MethodPost, Path: "/api/v1/agent/sessions/{id}/pause", Tag: "agent", Summary: "Pause an agent session"},
+	{Method: http.MethodPost, Path: "/api/v1/agent/sessions/{id}/resume", Tag: "agent", Summary: "Resume an agent session"},
+	{Method: http.MethodGet, Path: "/api/v1/agent/sessions/{id}/timeline", Tag: "agent", Summary: "Get an agent session's timeline"},
+	{Method: http.MethodGet, Path: "/api/v1/agent/sessions/{id}/report", Tag: "agent", Summary: "Get an agent session's work summary report"},
+	{Method: http.MethodGet, Path: "/api/v1/agent/locks", Tag: "agent", Summary: "List file, directory and glob locks"},
+	{Method: http.MethodPost, Path: "/api/v1/agent/locks", Tag: "agent", Summary: "Lock a file, directory subtree or glob for a session"},
+	{Method: http.MethodDelete, Path: "/api/v1/agent/locks/{path}", Tag: "agent", Summary: "Release a file, directory or glob lock", Query: []string{"session_id"}},
+	{Method: http.MethodGet, Path: "/api/v1/agent/changes", Tag: "agent", Summary: "Recent file changes by agents, with their diffs", Query: []string{"session_id", "status", "limit"}},
+	{Method: http.MethodPost, Path: "/api/v1/agent/changes/approve", Tag: "agent", Summary: "Approve a session's pending file changes"},
+	{Method: http.MethodGet, Path: "/api/v1/agent/changes/{id}", Tag: "agent", Summary: "Get a file change with its diff"},
+	{Method: http.MethodPost, Path: "/api/v1/agent/changes/{id}/revert", Tag: "agent", Summary: "Revert a file change"},
+	{Method: http.MethodGet, Path: "/api/v1/agent/tasks", Tag: "agent", Summary: "List tasks"},
+	{Method: http.MethodPost, Path: "/api/v1/agent/tasks", Tag: "agent", Summary: "Create a task"},
+	{Method: http.MethodGet, Path: "/api/v1/agent/tasks/{id}", Tag: "agent", Summary: "Get a task"},
+	{Method: http.MethodDelete, Path: "/api/v1/agent/tasks/{id}", Tag: "agent", Summary: "Delete a task"},
+	{Method: http.MethodPost, Path: "/api/v1/agent/tasks/{id}/retry", Tag: "agent", Summary: "Retry a task"},
+	{Method: http.MethodPost, Path: "/api/v1/agent/tasks/{id}/cancel", Tag: "agent", Summary: "Cancel a task"},
+	{Method: http.MethodGet, Path: "/api/v1/agent/runtime", Tag: "agent", Summary: "List runtime tasks"},
+	{Method: http.MethodPost, Path: "/api/v1/agent/runtime/run", Tag: "agent", Summary: "Run a task, optionally with planner, implementer and reviewer sub-agents"},
+	{Method: http.MethodGet, Path: "/api/v1/agent/runtime/{id}", Tag: "agent", Summary: "Get a runtime task"},
+	{Method: http.MethodPost, Path: "/api/v1/agent/runtime/{id}/cancel", Tag: "agent", Summary: "Cancel a runtime task"},
+	{Method: http.MethodGet, Path: "/api/v1/agent/guardrails", Tag: "agent", Summary: "Get guardrail settings"},
+	{Method: http.MethodGet, Path: "/api/v1/agent/guardrails/spending", Tag: "agent", Summary: "Spending against guardrails"},
+	{Method: http.MethodGet, Path: "/api/v1/agent/guardrails/operations", Tag: "agent", Summary: "Recent guarded operations"},
+	{Method: http.MethodGet, Path: "/api/v1/agent/guardrails/policy", Tag: "agent", Summary: "Guardrail policy rules and their hit counts"},
+
+	// This document
+	{Method: http.MethodGet, Path: "/api/v1/openapi.json", Tag: "docs", Summary: "This OpenAPI document"},
+	{Method: http.MethodGet, Path: "/api/v1/docs", Tag: "docs", Summary: "Swagger UI for this document"},
+}
+
+var pathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+// openAPISpec builds the OpenAPI 3 document for the web API.
+func openAPISpec(version string) map[string]interface{} {
+	paths := make(map[string]map[string]interface{})
+	tagSet := make(map[string]bool)
+	for _, op := range apiOperations {
+		var params []map[string]interface{}
+		for _, m := range pathParam.FindAllStringSubmatch(op.Path, -1) {
+			params = append(params, map[string]interface{}{
+				"name": m[1], "in": "path", "required": true,
+				"schema": map[string]string{"type": "string"},
+			})
+		}
+		for _, q := range op.Query {
+			params = append(params, map[string]interface{}{
+				"name": q, "in": "query",
+				"schema": map[string]string{"type": "string"},
+			})
+		}
+		operation := map[string]interface{}{
+			"operationId": operationID(op),
+			"summary":     op.Summary,
+			"tags":        []string{op.Tag},
+			"responses": map[string]interface{}{
+				"200":     map[string]string{"description": "Success"},
+				"default": map[string]interface{}{"$ref": "#/components/responses/Error"},
+			},
+		}
+		if len(params) > 0 {
+			operation["parameters"] = params
+		}
+		if op.Public {
+			operation["security"] = []interface{}{}
+		}
+		if op.Method == http.MethodPost || op.Method == http.MethodPut {
+			operation["requestBody"] = map[string]interface{}{
+				"required": false,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": map[string]string{"type": "object"}},
+				},
+			}
+		}
+		if paths[op.Path] == nil {
+			paths[op.Path] = make(map[string]interface{})
+		}
+		paths[op.Path][strings.ToLower(op.Method)] = operation
+		tagSet[op.Tag] = true
+	}
+
+	tagNames := make([]string, 0, len(tagSet))
+	for t := range tagSet {
+		tagNames = append(tagNames, t)
+	}
+	sort.Strings(tagNames)
+	tags := make([]map[string]string, len(tagNames))
+	for i, t := range tagNames {
+		tags[i] = map[string]string{"name": t}
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]string{
+			"title":       "GoZen Web API",
+			"version":     version,
+			"description": "Manages the GoZen daemon. Requests from this machine need no credentials; others send a session cookie from /api/v1/auth/login or an API token.",
+		},
+		"tags":  tags,
+		"paths": paths,
+		"security": []map[string][]string{
+			{"sessionCookie": {}},
+			{"bearerToken": {}},
+		},
+		"components": map[string]interface{}{
+			"securitySchemes": map[string]interface{}{
+				"sessionCookie": map[string]string{"type": "apiKey", "in": "cookie", "name": sessionCookieName},
+				"bearerToken":   map[string]string{"type": "http", "scheme": "bearer"},
+			},
+			"schemas": map[string]interface{}{
+				"Error": map[string]interface{}{
+					"type":       "object",
+					"properties": map[string]interface{}{"error": map[string]string{"type": "string"}},
+				},
+			},
+			"responses": map[string]interface{}{
+				"Error": map[string]interface{}{
+					"description": "Error",
+					"content": map[string]interface{}{
+						"application/json": map[string]interface{}{"schema": map[string]string{"$ref": "#/components/schemas/Error"}},
+					},
+				},
+			},
+		},
+	}
+}
+
+// operationID derives an identifier such as getProvidersNameKeys from an
+// operation's method and path.
+func operationID(op apiOperation) string {
+	id := strings.ToLower(op.Method)
+	path := strings.TrimPrefix(strings.TrimPrefix(op.Path, "/api/v1"), "/")
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool {
+		return r == '/' || r == '-' || r == '.' || r == '{' || r == '}'
+	}) {
+		id += strings.ToUpper(part[:1]) + part[1:]
+	}
+	return id
+}
+
+// handleOpenAPI serves the OpenAPI document.
+// GET /api/v1/openapi.json
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeJSON(w, http.StatusOK, openAPISpec(s.version))
+}
+
+// swaggerUIPage renders the OpenAPI document with Swagger UI. The page is
+// served by the daemon; its scripts and styles come from a CDN.
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>GoZen API</title>
+<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+<script>
+window.ui = SwaggerUIBundle({url: "/api/v1/openapi.json", dom_id: "#swagger-ui", withCredentials: true});
+</script>
+</body>
+</html>
+`
+
+// handleAPIDocs serves Swagger UI for the OpenAPI document.
+// GET /api/v1/docs
+func (s *Server) handleAPIDocs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(swaggerUIPage))
+}
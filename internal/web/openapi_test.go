@@ -0,0 +1,91 @@
+package web
+
+import (
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// TestOpenAPICoversRoutes checks that every route registered on the web
+// server, including the daemon's, is in the OpenAPI document.
+func TestOpenAPICoversRoutes(t *testing.T) {
+	registered := regexp.MustCompile(`HandleFunc\("([^"]+)"`)
+	var routes []string
+	for _, file := range []string{"server.go", "../daemon/server.go"} {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range registered.FindAllStringSubmatch(string(src), -1) {
+			if strings.HasPrefix(m[1], "/api/v1/") || strings.HasPrefix(m[1], "/badge/") || m[1] == "/healthz" {
+				routes = append(routes, m[1])
+			}
+		}
+	}
+	if len(routes) < 40 {
+		t.Fatalf("found only %d routes; has registration moved?", len(routes))
+	}
+
+	for _, route := range routes {
+		found := false
+		for _, op := range apiOperations {
+			// A pattern ending in / serves the paths below it
+			if op.Path == route || (strings.HasSuffix(route, "/") && strings.HasPrefix(op.Path, route)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("route %s is not in apiOperations", route)
+		}
+	}
+}
+
+func TestOpenAPIEndpoints(t *testing.T) {
+	s := setupTestServer(t)
+
+	w := doRequest(s, http.MethodGet, "/api/v1/openapi.json", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+	var spec struct {
+		OpenAPI string `json:"openapi"`
+		Info    struct {
+			Version string `json:"version"`
+		} `json:"info"`
+		Paths map[string]map[string]struct {
+			OperationID string `json:"operationId"`
+			Parameters  []struct {
+				Name string `json:"name"`
+				In   string `json:"in"`
+			} `json:"parameters"`
+		} `json:"paths"`
+	}
+	decodeJSON(t, w, &spec)
+	if spec.OpenAPI != "3.0.3" || spec.Info.Version != "1.0.0-test" {
+		t.Errorf("openapi = %q, version = %q", spec.OpenAPI, spec.Info.Version)
+	}
+	op, ok := spec.Paths["/api/v1/providers/{name}/keys/{id}"]["delete"]
+	if !ok {
+		t.Fatal("provider key delete operation missing")
+	}
+	if op.OperationID != "deleteProvidersNameKeysId" || len(op.Parameters) != 2 || op.Parameters[0].In != "path" {
+		t.Errorf("operation = %+v", op)
+	}
+
+	seen := map[string]bool{}
+	for _, op := range apiOperations {
+		id := operationID(op)
+		if seen[id] {
+			t.Errorf("duplicate operationId %s", id)
+		}
+		seen[id] = true
+	}
+
+	w = doRequest(s, http.MethodGet, "/api/v1/docs", nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "/api/v1/openapi.json") {
+		t.Errorf("docs status = %d", w.Code)
+	}
+}
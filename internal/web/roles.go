@@ -0,0 +1,61 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Role is what a Web UI user or API token is allowed to do.
+type Role string
+
+const (
+	// RoleAdmin can do everything: local requests, the main password and
+	// admin tokens get it.
+	RoleAdmin Role = "admin"
+	// RoleViewer can read usage, health, logs and the config with its
+	// secrets masked, but not change anything. The viewer password and read
+	// tokens get it.
+	RoleViewer Role = "viewer"
+)
+
+// viewerDeniedPaths are the API paths viewers can't even read, since their
+// responses hold secrets or captured request content.
+var viewerDeniedPaths = []string{
+	"/api/v1/auth/tokens",
+	"/api/v1/settings/password",
+	"/api/v1/settings/viewer-password",
+	"/api/v1/config/",
+	"/api/v1/audit",
+	"/api/v1/sync/",
+	"/api/v1/team",
+	"/api/v1/ingress",
+	"/api/v1/bot",
+}
+
+// allows reports whether the role may make the request. Viewers are limited
+// to GET and HEAD requests outside viewerDeniedPaths.
+func (role Role) allows(r *http.Request) bool {
+	if role == RoleAdmin {
+		return true
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	path := r.URL.Path
+	for _, denied := range viewerDeniedPaths {
+		if strings.HasPrefix(path, denied) {
+			return false
+		}
+	}
+	// Captured request and response bodies
+	if strings.HasPrefix(path, "/api/v1/logs/") && strings.HasSuffix(path, "/body") {
+		return false
+	}
+	return true
+}
+
+// showsSecrets reports whether responses to r may carry secrets such as
+// upstream API keys, which only admins can read.
+func showsSecrets(r *http.Request) bool {
+	return actorOf(r).role == RoleAdmin
+}
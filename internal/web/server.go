@@ -2,13 +2,15 @@ package web
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
-	"io"
 	"io/fs"
 	"log"
 	"net"
 	"net/http"
+	"net/url"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
@@ -28,13 +30,20 @@ type Server struct {
 	version    string
 	port       int
 	auth       *AuthManager
+	tokens     *tokenStore
 	keys       *KeyPair
 	syncMu     sync.RWMutex
 	syncMgr    *gosync.SyncManager
 	botGateway *bot.Gateway
+	lnMu       sync.Mutex
+	listener   net.Listener // the raw listener, handed to a successor daemon
+	tlsConfig  *tls.Config
+	events     *eventHub
+	models     modelCache
 }
 
-// NewServer creates a new web server bound to 127.0.0.1 on the configured port.
+// NewServer creates a new web server bound to 127.0.0.1, or the TLS listen
+// address, on the configured port.
 // If portOverride > 0, it is used instead of the configured port.
 func NewServer(version string, logger *log.Logger, portOverride int) *Server {
 	port := config.GetWebPort()
@@ -46,6 +55,8 @@ func NewServer(version string, logger *log.Logger, portOverride int) *Server {
 		version: version,
 		port:    port,
 		auth:    NewAuthManager(),
+		tokens:  loadTokenStore(filepath.Join(config.ConfigDirPath(), APITokenFile)),
+		events:  newEventHub(),
 	}
 
 	// Generate RSA key pair for encrypted token transport
@@ -64,17 +75,30 @@ func NewServer(version string, logger *log.Logger, portOverride int) *Server {
 	s.mux.HandleFunc("/api/v1/auth/logout", s.handleLogout)
 	s.mux.HandleFunc("/api/v1/auth/check", s.handleAuthCheck)
 	s.mux.HandleFunc("/api/v1/auth/pubkey", s.handlePubKey)
+	s.mux.HandleFunc("/api/v1/auth/tokens", s.handleAPITokens)
+	s.mux.HandleFunc("/api/v1/auth/tokens/", s.handleAPIToken)
 
 	// API routes
 	s.mux.HandleFunc("/api/v1/health", s.handleHealth)
 	s.mux.HandleFunc("/api/v1/reload", s.handleReload)
+	s.mux.HandleFunc("/api/v1/events", s.handleEvents)
+	s.mux.HandleFunc("/api/v1/config/validate", s.handleConfigValidate)
+	s.mux.HandleFunc("/api/v1/config/history", s.handleConfigHistory)
+	s.mux.HandleFunc("/api/v1/config/diff", s.handleConfigDiff)
+	s.mux.HandleFunc("/api/v1/config/rollback", s.handleConfigRollback)
+	s.mux.HandleFunc("/api/v1/audit", s.handleAudit)
+	s.mux.HandleFunc("/api/v1/openapi.json", s.handleOpenAPI)
+	s.mux.HandleFunc("/api/v1/docs", s.handleAPIDocs)
 	s.mux.HandleFunc("/api/v1/providers", s.handleProviders)
 	s.mux.HandleFunc("/api/v1/providers/", s.handleProvider)
 	s.mux.HandleFunc("/api/v1/profiles", s.handleProfiles)
 	s.mux.HandleFunc("/api/v1/profiles/", s.handleProfile)
 	s.mux.HandleFunc("/api/v1/logs", s.handleLogs)
+	s.mux.HandleFunc("/api/v1/logs/stream", s.handleLogStream)
+	s.mux.HandleFunc("/api/v1/logs/", s.handleLogBody)
 	s.mux.HandleFunc("/api/v1/settings", s.handleSettings)
 	s.mux.HandleFunc("/api/v1/settings/password", s.handlePasswordChange)
+	s.mux.HandleFunc("/api/v1/settings/viewer-password", s.handleViewerPassword)
 	s.mux.HandleFunc("/api/v1/bindings", s.handleBindings)
 	s.mux.HandleFunc("/api/v1/bindings/", s.handleBinding)
 
@@ -83,19 +107,29 @@ func NewServer(version string, logger *log.Logger, portOverride int) *Server {
 	s.mux.HandleFunc("/api/v1/sync/pull", s.handleSyncPull)
 	s.mux.HandleFunc("/api/v1/sync/push", s.handleSyncPush)
 	s.mux.HandleFunc("/api/v1/sync/status", s.handleSyncStatus)
+	s.mux.HandleFunc("/api/v1/sync/rotate-passphrase", s.handleSyncRotatePassphrase)
 	s.mux.HandleFunc("/api/v1/sync/test", s.handleSyncTest)
 	s.mux.HandleFunc("/api/v1/sync/create-gist", s.handleSyncCreateGist)
+	s.mux.HandleFunc("/api/v1/team", s.handleTeam)
+	s.mux.HandleFunc("/api/v1/team/pull", s.handleTeamPull)
+	s.mux.HandleFunc("/api/v1/team/push", s.handleTeamPush)
 
 	// Usage & Budget routes
 	s.mux.HandleFunc("/api/v1/usage", s.handleUsage)
 	s.mux.HandleFunc("/api/v1/usage/summary", s.handleUsageSummary)
 	s.mux.HandleFunc("/api/v1/usage/hourly", s.handleUsageHourly)
+	s.mux.HandleFunc("/api/v1/usage/export", s.handleUsageExport)
+	s.mux.HandleFunc("/api/v1/usage/compact", s.handleUsageCompact)
+	s.mux.HandleFunc("/api/v1/usage/storage", s.handleUsageStorage)
 	s.mux.HandleFunc("/api/v1/budget", s.handleBudget)
 	s.mux.HandleFunc("/api/v1/budget/status", s.handleBudgetStatus)
+	s.mux.HandleFunc("/api/v1/dashboard", s.handleDashboard)
 
 	// Health monitoring routes
+	s.mux.HandleFunc("/api/v1/health/summary", s.handleHealthSummary)
 	s.mux.HandleFunc("/api/v1/health/providers", s.handleHealthProviders)
 	s.mux.HandleFunc("/api/v1/health/providers/", s.handleHealthProvider)
+	s.mux.HandleFunc("/badge/health.svg", s.handleHealthBadge)
 
 	// Request monitoring routes
 	s.mux.HandleFunc("/api/v1/monitoring/requests", s.handleRequests)
@@ -108,15 +142,31 @@ func NewServer(version string, logger *log.Logger, portOverride int) *Server {
 	// Webhook routes
 	s.mux.HandleFunc("/api/v1/webhooks", s.handleWebhooks)
 	s.mux.HandleFunc("/api/v1/webhooks/test", s.handleWebhookTest)
+	s.mux.HandleFunc("/api/v1/webhooks/failures", s.handleWebhookFailures)
 	s.mux.HandleFunc("/api/v1/webhooks/", s.handleWebhook)
 
+	// Ingress key routes
+	s.mux.HandleFunc("/api/v1/ingress", s.handleIngress)
+	s.mux.HandleFunc("/api/v1/ingress/keys", s.handleIngressKeys)
+	s.mux.HandleFunc("/api/v1/ingress/keys/", s.handleIngressKey)
+
 	// Pricing routes
 	s.mux.HandleFunc("/api/v1/pricing", s.handlePricing)
 	s.mux.HandleFunc("/api/v1/pricing/reset", s.handlePricingReset)
+	s.mux.HandleFunc("/api/v1/pricing/sync", s.handlePricingSync)
+	s.mux.HandleFunc("/api/v1/currency", s.handleCurrency)
+
+	// Model alias and catalog routes
+	s.mux.HandleFunc("/api/v1/model-aliases", s.handleModelAliases)
+	s.mux.HandleFunc("/api/v1/models", s.handleModels)
+
+	// Shadow traffic experiment routes
+	s.mux.HandleFunc("/api/v1/experiments", s.handleExperiments)
 
 	// Compression routes (BETA)
 	s.mux.HandleFunc("/api/v1/compression", s.handleCompression)
 	s.mux.HandleFunc("/api/v1/compression/stats", s.handleGetCompressionStats)
+	s.mux.HandleFunc("/api/v1/compression/preview", s.handleCompressionPreview)
 
 	// Middleware routes (BETA)
 	s.mux.HandleFunc("/api/v1/middleware", s.handleMiddleware)
@@ -141,6 +191,7 @@ func NewServer(version string, logger *log.Logger, portOverride int) *Server {
 	s.mux.HandleFunc("/api/v1/agent/locks", s.handleAgentLocks)
 	s.mux.HandleFunc("/api/v1/agent/locks/", s.handleAgentLocks)
 	s.mux.HandleFunc("/api/v1/agent/changes", s.handleAgentChanges)
+	s.mux.HandleFunc("/api/v1/agent/changes/", s.handleAgentChanges)
 	s.mux.HandleFunc("/api/v1/agent/tasks", s.handleAgentTasks)
 	s.mux.HandleFunc("/api/v1/agent/tasks/", s.handleAgentTasks)
 	s.mux.HandleFunc("/api/v1/agent/runtime", s.handleAgentRuntime)
@@ -154,16 +205,32 @@ func NewServer(version string, logger *log.Logger, portOverride int) *Server {
 
 	// Static files with SPA fallback
 	staticSub, _ := fs.Sub(staticFS, "dist")
-	s.mux.Handle("/", spaHandler{fs: staticSub})
+	assets, err := loadStaticAssets(staticSub)
+	if err != nil {
+		logger.Printf("Failed to load web UI assets: %v", err)
+	}
+	s.mux.Handle("/", spaHandler{assets: assets})
 
 	s.httpServer = &http.Server{
-		Addr:    fmt.Sprintf("127.0.0.1:%d", port),
-		Handler: httpx.Recover(logger, "web", s.securityHeaders(s.authMiddleware(s.mux))),
+		Addr:    net.JoinHostPort(config.GetTLS().GetListen(), strconv.Itoa(port)),
+		Handler: httpx.Recover(logger, "web", s.securityHeaders(s.authMiddleware(s.auditMiddleware(s.mux)))),
 	}
 
 	return s
 }
 
+// SetTLS serves HTTPS with cfg, alongside plain HTTP for loopback clients.
+// nil serves plain HTTP only. Must be called before Start().
+func (s *Server) SetTLS(cfg *tls.Config) {
+	s.tlsConfig = cfg
+}
+
+// SetConnState sets a hook called when a client connection changes state.
+// Must be called before Start().
+func (s *Server) SetConnState(fn func(net.Conn, http.ConnState)) {
+	s.httpServer.ConnState = fn
+}
+
 // HandleFunc registers an additional handler on the server's mux.
 // Must be called before Start().
 func (s *Server) HandleFunc(pattern string, handler http.HandlerFunc) {
@@ -202,13 +269,39 @@ func (s *Server) Start() error {
 		return fmt.Errorf("port %d is already in use: %w", s.port, err)
 	}
 	s.logger.Printf("Web server listening on %s", s.httpServer.Addr)
-	err = s.httpServer.Serve(ln)
+	return s.serve(ln)
+}
+
+// Serve serves on an existing listener, such as one inherited from a
+// previous daemon. Returns nil on graceful shutdown.
+func (s *Server) Serve(ln net.Listener) error {
+	go s.auth.sessionCleanupLoop()
+	s.logger.Printf("Web server listening on %s (inherited)", ln.Addr())
+	return s.serve(ln)
+}
+
+func (s *Server) serve(ln net.Listener) error {
+	s.lnMu.Lock()
+	s.listener = ln
+	s.lnMu.Unlock()
+	if s.tlsConfig != nil {
+		ln = httpx.TLSListener(ln, s.tlsConfig)
+	}
+	err := s.httpServer.Serve(ln)
 	if err == http.ErrServerClosed {
 		return nil // graceful shutdown
 	}
 	return err
 }
 
+// Listener returns the listener the server is accepting on, or nil before
+// it has started.
+func (s *Server) Listener() net.Listener {
+	s.lnMu.Lock()
+	defer s.lnMu.Unlock()
+	return s.listener
+}
+
 // Shutdown gracefully stops the server.
 func (s *Server) Shutdown(ctx context.Context) error {
 	// Stop the session cleanup loop
@@ -227,10 +320,10 @@ func (s *Server) securityHeaders(next http.Handler) http.Handler {
 
 // --- SPA handler ---
 
-// spaHandler serves static files with SPA fallback.
+// spaHandler serves the embedded frontend with SPA fallback.
 // If the requested file doesn't exist, it serves index.html.
 type spaHandler struct {
-	fs fs.FS
+	assets staticAssets
 }
 
 func (h spaHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -248,32 +341,25 @@ func (h spaHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		path = path[1:] // remove leading slash
 	}
 
-	// Try to open the file
-	f, err := h.fs.Open(path)
-	if err == nil {
-		f.Close()
-		// File exists, serve it
-		http.FileServer(http.FS(h.fs)).ServeHTTP(w, r)
+	if a, ok := h.assets[path]; ok {
+		a.serve(w, r)
 		return
 	}
 
-	// File doesn't exist - serve index.html for SPA routing
-	indexFile, err := h.fs.Open("index.html")
-	if err != nil {
+	// A missing build asset is a stale reference from an older index.html;
+	// answering with HTML would only fail to load as a script or stylesheet.
+	if strings.HasPrefix(path, "assets/") {
 		http.NotFound(w, r)
 		return
 	}
-	defer indexFile.Close()
 
-	stat, err := indexFile.Stat()
-	if err != nil {
+	// File doesn't exist - serve index.html for SPA routing
+	index, ok := h.assets["index.html"]
+	if !ok {
 		http.NotFound(w, r)
 		return
 	}
-
-	// Serve index.html
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	http.ServeContent(w, r, "index.html", stat.ModTime(), indexFile.(io.ReadSeeker))
+	index.serve(w, r)
 }
 
 // --- health & reload ---
@@ -302,6 +388,22 @@ func (s *Server) handleReload(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]string{"status": "reloaded"})
 }
 
+// handleConfigValidate validates zen.json as it is on disk, so a file that
+// was rejected on reload can be diagnosed.
+// GET /api/v1/config/validate
+func (s *Server) handleConfigValidate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	report, err := config.CheckConfigFile(config.ConfigFilePath())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
 // --- helpers ---
 
 func writeJSON(w http.ResponseWriter, status int, v interface{}) {
@@ -321,14 +423,31 @@ func readJSON(r *http.Request, v interface{}) error {
 	return json.NewDecoder(r.Body).Decode(v)
 }
 
-// maskToken masks an auth token for display: "sk-abc...xyz" style.
+// maskToken masks an auth token for display: "sk-abc...xyz" style. Secret
+// references are shown as they are, since they hold no secret.
 func maskToken(token string) string {
+	if config.IsSecretRef(token) {
+		return token
+	}
 	if len(token) <= 8 {
 		return "****"
 	}
 	return token[:5] + "..." + token[len(token)-4:]
 }
 
+// maskValues returns a copy of m with every value masked, for header and
+// environment maps that may hold credentials.
+func maskValues(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	masked := make(map[string]string, len(m))
+	for k, v := range m {
+		masked[k] = maskToken(v)
+	}
+	return masked
+}
+
 // --- logs ---
 
 func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
@@ -337,12 +456,51 @@ func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse query parameters
-	query := r.URL.Query()
+	filter := logFilterFromQuery(r.URL.Query())
+	page, err := pageFromQuery(r.URL.Query(), 100)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter.Limit = page.Limit
+
+	// Page through SQLite (shared across processes) when it is there, else
+	// take the newest entries held in memory.
+	resp := proxy.LogsResponse{Entries: []proxy.LogEntry{}, Providers: []string{}}
+	if db := proxy.GetGlobalLogDB(); db != nil {
+		entries, more, err := db.QueryPage(filter, page)
+		if err != nil {
+			s.logger.Printf("Failed to query log database: %v", err)
+		} else if len(entries) > 0 {
+			resp.Entries = entries
+			c := cursorsFor(page, more, entries[0].ID, entries[len(entries)-1].ID)
+			resp.NextCursor, resp.PrevCursor = c.NextCursor, c.PrevCursor
+		}
+		if resp.Total, err = db.Count(filter); err != nil {
+			s.logger.Printf("Failed to count log entries: %v", err)
+		}
+		if providers, err := db.GetProviders(); err != nil {
+			s.logger.Printf("Failed to query log providers: %v", err)
+		} else if providers != nil {
+			resp.Providers = providers
+		}
+	} else if logger := proxy.GetGlobalLogger(); logger != nil {
+		resp.Entries = logger.GetEntries(filter)
+		resp.Total = len(resp.Entries)
+		resp.Providers = logger.GetProviders()
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// logFilterFromQuery reads a log filter from the query parameters of
+// /api/v1/logs and /api/v1/logs/stream.
+func logFilterFromQuery(query url.Values) proxy.LogFilter {
 	filter := proxy.LogFilter{
 		Provider:   query.Get("provider"),
 		SessionID:  query.Get("session_id"),
 		ClientType: query.Get("client_type"),
+		Annotation: annotationFilterFromQuery(query),
 	}
 
 	if query.Get("errors_only") == "true" {
@@ -376,36 +534,46 @@ func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
 			filter.Limit = l
 		}
 	}
+	return filter
+}
 
-	if filter.Limit <= 0 {
-		filter.Limit = 100 // default limit
+// handleLogBody handles GET /api/v1/logs/{id}/body, returning the captured
+// request/response bodies for a request ID (see /api/v1/monitoring/requests).
+// Bodies are only captured for providers with capture_bodies enabled or
+// requests sent with the X-Zen-Capture header. PATCH /api/v1/logs/{id}
+// annotates a log entry.
+func (s *Server) handleLogBody(w http.ResponseWriter, r *http.Request) {
+	if id := strings.TrimPrefix(r.URL.Path, "/api/v1/logs/"); r.Method == http.MethodPatch && !strings.Contains(id, "/") {
+		s.handleLogAnnotation(w, r, id)
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
 	}
 
-	// Try in-memory logger first (same process as proxy), then SQLite (cross-process).
-	var entries []proxy.LogEntry
-	var providers []string
-
-	logger := proxy.GetGlobalLogger()
-	if logger != nil && logger.HasEntries() {
-		entries = logger.GetEntries(filter)
-		providers = logger.GetProviders()
-	} else if db := proxy.GetGlobalLogDB(); db != nil {
-		var err error
-		entries, err = db.Query(filter)
-		if err != nil {
-			s.logger.Printf("Failed to query log database: %v", err)
-			entries = []proxy.LogEntry{}
-		}
-		providers, err = db.GetProviders()
-		if err != nil {
-			s.logger.Printf("Failed to query log providers: %v", err)
-			providers = []string{}
-		}
+	id, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/api/v1/logs/"), "/body")
+	if !ok || id == "" || strings.Contains(id, "/") {
+		writeError(w, http.StatusNotFound, "not found")
+		return
 	}
 
-	writeJSON(w, http.StatusOK, proxy.LogsResponse{
-		Entries:   entries,
-		Total:     len(entries),
-		Providers: providers,
+	db := proxy.GetGlobalLogDB()
+	if db == nil {
+		writeError(w, http.StatusServiceUnavailable, "log database not available")
+		return
+	}
+	captures, err := db.GetBodyCaptures(id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(captures) == 0 {
+		writeError(w, http.StatusNotFound, "no captured bodies for request")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"request_id": id,
+		"attempts":   captures,
 	})
 }
@@ -13,6 +13,7 @@ import (
 	"testing"
 
 	"github.com/dopejs/gozen/internal/config"
+	"github.com/dopejs/gozen/internal/proxy"
 )
 
 func setupTestServer(t *testing.T) *Server {
@@ -499,6 +500,71 @@ func TestReload(t *testing.T) {
 	}
 }
 
+func TestConfigValidateEndpoint(t *testing.T) {
+	s := setupTestServer(t)
+	w := doRequest(s, "GET", "/api/v1/config/validate", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var report config.ValidationReport
+	decodeJSON(t, w, &report)
+	if !report.Valid || len(report.Errors) != 0 {
+		t.Errorf("report = %+v", report)
+	}
+
+	// The file on disk is checked, even when it no longer loads
+	os.WriteFile(config.ConfigFilePath(), []byte(`{"providers": [}`), 0600)
+	w = doRequest(s, "GET", "/api/v1/config/validate", nil)
+	decodeJSON(t, w, &report)
+	if report.Valid || len(report.Errors) != 1 || report.Errors[0].Hint == "" {
+		t.Errorf("report for a broken file = %+v", report)
+	}
+}
+
+func TestConfigHistoryRollback(t *testing.T) {
+	s := setupTestServer(t)
+
+	update := config.ProviderConfig{BaseURL: "https://api.updated.com", AuthToken: "sk-new-secret-token-9999"}
+	if w := doRequest(s, "PUT", "/api/v1/providers/test-provider", update); w.Code != http.StatusOK {
+		t.Fatalf("update: %d %s", w.Code, w.Body.String())
+	}
+
+	w := doRequest(s, "GET", "/api/v1/config/history", nil)
+	var snapshots []config.ConfigSnapshot
+	decodeJSON(t, w, &snapshots)
+	if len(snapshots) < 2 {
+		t.Fatalf("history = %+v, want the config before and after the edit", snapshots)
+	}
+	original := snapshots[1].ID
+
+	w = doRequest(s, "GET", "/api/v1/config/diff?from="+original, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("diff: %d %s", w.Code, w.Body.String())
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "providers.test-provider.base_url") || !strings.Contains(body, "api.updated.com") {
+		t.Errorf("diff lacks the base_url change: %s", body)
+	}
+	if strings.Contains(body, "sk-new-secret-token-9999") || strings.Contains(body, "sk-test-secret-token-1234") {
+		t.Errorf("diff leaks a token: %s", body)
+	}
+
+	w = doRequest(s, "POST", "/api/v1/config/rollback", map[string]string{"id": original})
+	if w.Code != http.StatusOK {
+		t.Fatalf("rollback: %d %s", w.Code, w.Body.String())
+	}
+	if got := config.GetProvider("test-provider"); got.BaseURL != "https://api.test.com" || got.AuthToken != "sk-test-secret-token-1234" {
+		t.Errorf("provider after rollback = %+v", got)
+	}
+
+	if w = doRequest(s, "POST", "/api/v1/config/rollback", map[string]string{"id": "nope"}); w.Code != http.StatusNotFound {
+		t.Errorf("rollback to unknown snapshot: %d", w.Code)
+	}
+	if w = doRequest(s, "GET", "/api/v1/config/diff", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("diff without from: %d", w.Code)
+	}
+}
+
 func TestReloadMethodNotAllowed(t *testing.T) {
 	s := setupTestServer(t)
 	w := doRequest(s, "GET", "/api/v1/reload", nil)
@@ -851,6 +917,20 @@ func TestCreateBindingMissingPath(t *testing.T) {
 	}
 }
 
+func TestCreateBindingInvalidPath(t *testing.T) {
+	s := setupTestServer(t)
+	for _, path := range []string{"relative/dir", "work/*", "/srv/[repos/*"} {
+		w := doRequest(s, "POST", "/api/v1/bindings", bindingRequest{Path: path, Profile: "default"})
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("POST %q: expected 400, got %d", path, w.Code)
+		}
+	}
+	w := doRequest(s, "POST", "/api/v1/bindings", bindingRequest{Path: "/srv/repos/**", Profile: "default"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST pattern: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
 func TestCreateBindingInvalidProfile(t *testing.T) {
 	s := setupTestServer(t)
 	w := doRequest(s, "POST", "/api/v1/bindings", bindingRequest{Path: "/tmp/x", Profile: "nonexistent"})
@@ -1103,6 +1183,46 @@ func TestLogsEndpointInvalidFilterValues(t *testing.T) {
 	}
 }
 
+func TestLogBodyEndpoint(t *testing.T) {
+	s := setupTestServer(t)
+	if err := proxy.InitGlobalLogger(t.TempDir()); err != nil {
+		t.Fatalf("InitGlobalLogger() error: %v", err)
+	}
+	db := proxy.GetGlobalLogDB()
+	if err := db.InsertBodyCapture(proxy.BodyCapture{
+		RequestID:    "req_body_test",
+		Provider:     "test-provider",
+		StatusCode:   200,
+		RequestBody:  `{"model":"m"}`,
+		ResponseBody: `{"id":"msg"}`,
+	}); err != nil {
+		t.Fatalf("InsertBodyCapture() error: %v", err)
+	}
+
+	w := doRequest(s, "GET", "/api/v1/logs/req_body_test/body", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		RequestID string              `json:"request_id"`
+		Attempts  []proxy.BodyCapture `json:"attempts"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.RequestID != "req_body_test" || len(resp.Attempts) != 1 || resp.Attempts[0].ResponseBody != `{"id":"msg"}` {
+		t.Errorf("unexpected response: %s", w.Body.String())
+	}
+
+	if w := doRequest(s, "GET", "/api/v1/logs/req_unknown/body", nil); w.Code != http.StatusNotFound {
+		t.Errorf("unknown id: expected 404, got %d", w.Code)
+	}
+	if w := doRequest(s, "GET", "/api/v1/logs/req_body_test", nil); w.Code != http.StatusNotFound {
+		t.Errorf("missing /body: expected 404, got %d", w.Code)
+	}
+	if w := doRequest(s, "POST", "/api/v1/logs/req_body_test/body", nil); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST: expected 405, got %d", w.Code)
+	}
+}
+
 func TestCreateProviderWithAddToProfiles(t *testing.T) {
 	s := setupTestServer(t)
 
@@ -1304,6 +1424,36 @@ func TestSyncStatusMethodNotAllowed(t *testing.T) {
 	}
 }
 
+func TestSyncRotatePassphraseNotConfigured(t *testing.T) {
+	s := setupTestServer(t)
+	w := doRequest(s, "POST", "/api/v1/sync/rotate-passphrase", map[string]string{"passphrase": "new"})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+	w = doRequest(s, "GET", "/api/v1/sync/rotate-passphrase", nil)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", w.Code)
+	}
+}
+
+func TestTeamNotConfigured(t *testing.T) {
+	s := setupTestServer(t)
+	w := doRequest(s, "GET", "/api/v1/team", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var resp map[string]interface{}
+	decodeJSON(t, w, &resp)
+	if resp["configured"] != false || resp["loaded"] != false {
+		t.Errorf("unexpected team status: %v", resp)
+	}
+	for _, path := range []string{"/api/v1/team/pull", "/api/v1/team/push"} {
+		if w := doRequest(s, "POST", path, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("POST %s: expected 400, got %d", path, w.Code)
+		}
+	}
+}
+
 func TestSyncPullNotConfigured(t *testing.T) {
 	s := setupTestServer(t)
 	w := doRequest(s, "POST", "/api/v1/sync/pull", nil)
@@ -1553,3 +1703,104 @@ func TestSPAFallback(t *testing.T) {
 		t.Errorf("expected Content-Type text/html, got %s", contentType)
 	}
 }
+
+func TestModelAliasesEndpoint(t *testing.T) {
+	s := setupTestServer(t)
+
+	w := doRequest(s, "GET", "/api/v1/model-aliases", nil)
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != "[]" {
+		t.Fatalf("expected empty list, got %d: %s", w.Code, w.Body.String())
+	}
+
+	aliases := []*config.ModelAlias{
+		{Match: "claude-3-5-sonnet*", Target: "deepseek-chat", Provider: "backup"},
+		{Match: "claude-*", StripDate: true},
+	}
+	if w := doRequest(s, "PUT", "/api/v1/model-aliases", aliases); w.Code != http.StatusOK {
+		t.Fatalf("PUT: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	saved := config.GetModelAliases()
+	if len(saved) != 2 || saved[0].Target != "deepseek-chat" || !saved[1].StripDate {
+		t.Errorf("saved aliases = %+v", saved)
+	}
+
+	bad := map[string][]*config.ModelAlias{
+		"empty match":      {{Target: "x"}},
+		"no target":        {{Match: "claude-*"}},
+		"unknown provider": {{Match: "m", Target: "x", Provider: "missing"}},
+	}
+	for name, aliases := range bad {
+		if w := doRequest(s, "PUT", "/api/v1/model-aliases", aliases); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, w.Code)
+		}
+	}
+	if w := doRequest(s, "DELETE", "/api/v1/model-aliases", nil); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE: expected 405, got %d", w.Code)
+	}
+}
+
+func TestExperimentsEndpoint(t *testing.T) {
+	s := setupTestServer(t)
+
+	w := doRequest(s, "GET", "/api/v1/experiments", nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"experiments":[]`) {
+		t.Fatalf("expected empty report list, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := doRequest(s, "DELETE", "/api/v1/experiments", nil); w.Code != http.StatusOK {
+		t.Errorf("DELETE: expected 200, got %d", w.Code)
+	}
+	if w := doRequest(s, "POST", "/api/v1/experiments", nil); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST: expected 405, got %d", w.Code)
+	}
+}
+
+func TestProfileMirrorValidation(t *testing.T) {
+	s := setupTestServer(t)
+
+	body := map[string]interface{}{
+		"name":      "shadowed",
+		"providers": []string{"test-provider"},
+		"mirror":    map[string]interface{}{"provider": "backup", "percent": 25},
+	}
+	if w := doRequest(s, "POST", "/api/v1/profiles", body); w.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if pc := config.GetProfileConfig("shadowed"); pc == nil || pc.Mirror == nil || pc.Mirror.Percent != 25 {
+		t.Errorf("saved profile = %+v", pc)
+	}
+
+	for name, mirror := range map[string]map[string]interface{}{
+		"unknown provider": {"provider": "missing", "percent": 10},
+		"zero percent":     {"provider": "backup", "percent": 0},
+		"over 100":         {"provider": "backup", "percent": 150},
+	} {
+		body := map[string]interface{}{"providers": []string{"test-provider"}, "mirror": mirror}
+		if w := doRequest(s, "PUT", "/api/v1/profiles/shadowed", body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, w.Code)
+		}
+	}
+}
+
+func TestProfileRequestTransform(t *testing.T) {
+	s := setupTestServer(t)
+
+	body := map[string]interface{}{
+		"name":              "org",
+		"providers":         []string{"test-provider"},
+		"request_transform": map[string]interface{}{"system_prepend": "ORG", "strip_tools": []string{"WebSearch"}},
+	}
+	if w := doRequest(s, "POST", "/api/v1/profiles", body); w.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if pc := config.GetProfileConfig("org"); pc == nil || pc.RequestTransform == nil || pc.RequestTransform.SystemPrepend != "ORG" {
+		t.Errorf("saved profile = %+v", pc)
+	}
+
+	body = map[string]interface{}{
+		"providers":         []string{"test-provider"},
+		"request_transform": map[string]interface{}{"strip_system": []string{"("}},
+	}
+	if w := doRequest(s, "PUT", "/api/v1/profiles/org", body); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid regex: expected 400, got %d", w.Code)
+	}
+}
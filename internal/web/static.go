@@ -0,0 +1,165 @@
+package web
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cache-Control values for the embedded frontend. Vite puts a content hash
+// in the names of the files it writes under assets/, so those never change
+// and can be cached for good. Everything else, index.html above all, must
+// be revalidated so a new release is picked up at once.
+const (
+	cacheImmutable  = "public, max-age=31536000, immutable"
+	cacheRevalidate = "no-cache"
+)
+
+// minCompressSize is the smallest file worth compressing.
+const minCompressSize = 1024
+
+// hashedAssetPattern matches Vite's hashed output names, e.g. index-BZx8k2Qa.js.
+var hashedAssetPattern = regexp.MustCompile(`-[A-Za-z0-9_-]{8,}\.[A-Za-z0-9]+$`)
+
+// staticAsset is one embedded frontend file with its compressed encodings.
+type staticAsset struct {
+	contentType  string
+	cacheControl string
+	etag         string // strong ETag of the uncompressed content
+	data         []byte
+	gzip         []byte // nil if not worth compressing
+	brotli       []byte // only when the build wrote a .br copy
+}
+
+// staticAssets holds the embedded frontend, read once at startup.
+type staticAssets map[string]*staticAsset
+
+// loadStaticAssets reads every file in fsys. Copies with a .br or .gz suffix
+// written by the frontend build become encodings of the original file;
+// compressible files the build did not gzip are gzipped here.
+func loadStaticAssets(fsys fs.FS) (staticAssets, error) {
+	assets := make(staticAssets)
+	encoded := make(map[string][]byte)
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		if strings.HasSuffix(name, ".br") || strings.HasSuffix(name, ".gz") {
+			encoded[name] = data
+			return nil
+		}
+		sum := sha256.Sum256(data)
+		a := &staticAsset{
+			contentType:  mime.TypeByExtension(path.Ext(name)),
+			cacheControl: cacheRevalidate,
+			etag:         `"` + hex.EncodeToString(sum[:16]) + `"`,
+			data:         data,
+		}
+		if a.contentType == "" {
+			a.contentType = http.DetectContentType(data)
+		}
+		if strings.HasPrefix(name, "assets/") && hashedAssetPattern.MatchString(name) {
+			a.cacheControl = cacheImmutable
+		}
+		assets[name] = a
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for name, a := range assets {
+		if br, ok := encoded[name+".br"]; ok && len(br) < len(a.data) {
+			a.brotli = br
+		}
+		if gz, ok := encoded[name+".gz"]; ok && len(gz) < len(a.data) {
+			a.gzip = gz
+		} else if compressible(a.contentType) && len(a.data) >= minCompressSize {
+			if gz := gzipBytes(a.data); len(gz) < len(a.data) {
+				a.gzip = gz
+			}
+		}
+	}
+	return assets, nil
+}
+
+// compressible reports whether a content type is text that compresses well.
+func compressible(contentType string) bool {
+	return strings.HasPrefix(contentType, "text/") ||
+		strings.Contains(contentType, "javascript") ||
+		strings.Contains(contentType, "json") ||
+		strings.Contains(contentType, "xml") // includes image/svg+xml
+}
+
+func gzipBytes(data []byte) []byte {
+	var buf bytes.Buffer
+	zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	zw.Write(data)
+	zw.Close()
+	return buf.Bytes()
+}
+
+// serve writes the asset in the best encoding the client accepts. Each
+// encoding has its own strong ETag, so conditional and range requests work
+// against the bytes actually sent.
+func (a *staticAsset) serve(w http.ResponseWriter, r *http.Request) {
+	h := w.Header()
+	h.Set("Content-Type", a.contentType)
+	h.Set("Cache-Control", a.cacheControl)
+	if a.gzip != nil || a.brotli != nil {
+		h.Add("Vary", "Accept-Encoding")
+	}
+
+	body, etag := a.data, a.etag
+	accept := acceptedEncodings(r.Header.Get("Accept-Encoding"))
+	switch {
+	case a.brotli != nil && accept["br"]:
+		body, etag = a.brotli, strings.TrimSuffix(a.etag, `"`)+`-br"`
+		h.Set("Content-Encoding", "br")
+	case a.gzip != nil && accept["gzip"]:
+		body, etag = a.gzip, strings.TrimSuffix(a.etag, `"`)+`-gz"`
+		h.Set("Content-Encoding", "gzip")
+	}
+	h.Set("ETag", etag)
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(body))
+}
+
+// acceptedEncodings parses an Accept-Encoding header into the set of
+// encodings the client takes, leaving out those with q=0.
+func acceptedEncodings(header string) map[string]bool {
+	accepted := make(map[string]bool)
+	refused := make(map[string]bool)
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				refused[name] = true
+				continue
+			}
+		}
+		accepted[name] = true
+	}
+	if accepted["*"] {
+		for _, name := range []string{"br", "gzip"} {
+			accepted[name] = !refused[name]
+		}
+	}
+	return accepted
+}
@@ -0,0 +1,105 @@
+package web
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSPAHandlerCaching(t *testing.T) {
+	js := []byte(strings.Repeat("console.log('gozen');\n", 200))
+	assets, err := loadStaticAssets(fstest.MapFS{
+		"index.html":                  {Data: []byte("<!doctype html><title>GoZen</title>")},
+		"assets/index-BZx8k2Qa.js":    {Data: js},
+		"assets/index-BZx8k2Qa.js.br": {Data: []byte("brotli")},
+		"favicon.svg":                 {Data: []byte("<svg/>")},
+	})
+	if err != nil {
+		t.Fatalf("loadStaticAssets() error: %v", err)
+	}
+	h := spaHandler{assets: assets}
+	get := func(path string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	// Hashed assets are immutable and served in the best accepted encoding.
+	w := get("/assets/index-BZx8k2Qa.js", map[string]string{"Accept-Encoding": "gzip, br"})
+	if w.Code != http.StatusOK || w.Header().Get("Content-Encoding") != "br" || w.Body.String() != "brotli" {
+		t.Fatalf("br: status %d, encoding %q, body %q", w.Code, w.Header().Get("Content-Encoding"), w.Body.String())
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != cacheImmutable {
+		t.Errorf("Cache-Control = %q", cc)
+	}
+	if w.Header().Get("Vary") != "Accept-Encoding" || !strings.HasSuffix(w.Header().Get("ETag"), `-br"`) {
+		t.Errorf("headers = %v", w.Header())
+	}
+
+	w = get("/assets/index-BZx8k2Qa.js", map[string]string{"Accept-Encoding": "gzip, br;q=0"})
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("gzip: encoding %q", w.Header().Get("Content-Encoding"))
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("gzip.NewReader() error: %v", err)
+	}
+	if body, _ := io.ReadAll(zr); !bytes.Equal(body, js) {
+		t.Error("gzip body does not match the asset")
+	}
+
+	w = get("/assets/index-BZx8k2Qa.js", nil)
+	if w.Header().Get("Content-Encoding") != "" || !bytes.Equal(w.Body.Bytes(), js) {
+		t.Errorf("identity: encoding %q", w.Header().Get("Content-Encoding"))
+	}
+
+	// Matching ETags get 304.
+	etag := w.Header().Get("ETag")
+	if w := get("/assets/index-BZx8k2Qa.js", map[string]string{"If-None-Match": etag}); w.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match: status %d", w.Code)
+	}
+
+	// index.html and unhashed files are revalidated; small files stay uncompressed.
+	for _, path := range []string{"/", "/settings", "/favicon.svg"} {
+		w := get(path, map[string]string{"Accept-Encoding": "gzip, br"})
+		if w.Code != http.StatusOK || w.Header().Get("Cache-Control") != cacheRevalidate || w.Header().Get("Content-Encoding") != "" {
+			t.Errorf("%s: status %d, headers %v", path, w.Code, w.Header())
+		}
+	}
+	if w := get("/settings", nil); !strings.Contains(w.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("SPA fallback Content-Type = %q", w.Header().Get("Content-Type"))
+	}
+
+	// Stale asset references are not answered with index.html.
+	if w := get("/assets/index-old12345.js", nil); w.Code != http.StatusNotFound {
+		t.Errorf("missing asset: status %d", w.Code)
+	}
+}
+
+func TestAcceptedEncodings(t *testing.T) {
+	tests := []struct {
+		header   string
+		br, gzip bool
+	}{
+		{"", false, false},
+		{"gzip, deflate, br", true, true},
+		{"gzip;q=1.0, br;q=0", false, true},
+		{"*", true, true},
+		{"br;q=0, *", false, true},
+	}
+	for _, tt := range tests {
+		got := acceptedEncodings(tt.header)
+		if got["br"] != tt.br || got["gzip"] != tt.gzip {
+			t.Errorf("acceptedEncodings(%q) = %v", tt.header, got)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package web
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// trustedIdentity returns the user a reverse proxy vouches for in the
+// trusted header, and the role they act with. ok is false unless
+// trusted-header auth is on and the request carries the header on a
+// connection from one of the trusted proxies. X-Forwarded-For is not
+// consulted: the header is only as trustworthy as the peer that sent it.
+func trustedIdentity(r *http.Request) (user string, role Role, ok bool) {
+	cfg := config.GetTrustedHeaderAuth()
+	if !cfg.IsEnabled() {
+		return "", "", false
+	}
+	user = strings.TrimSpace(r.Header.Get(cfg.GetHeader()))
+	if user == "" || !fromTrustedProxy(r, cfg.GetTrustedProxies()) {
+		return "", "", false
+	}
+	role = RoleAdmin
+	if cfg.Role == string(RoleViewer) {
+		role = RoleViewer
+	}
+	return user, role, true
+}
+
+// fromTrustedProxy reports whether the request's peer address is in one of
+// proxies. Entries that don't parse are skipped.
+func fromTrustedProxy(r *http.Request, proxies []string) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap().WithZone("")
+	for _, p := range proxies {
+		prefix, err := config.ParsePrefix(p)
+		if err == nil && prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -43,7 +43,7 @@ type editorModel struct {
 	currentEnvCLI   int               // 0=claude, 1=codex, 2=opencode
 	envVarsEdit     bool              // true = editing env vars
 	envVarsModel    envVarsEditorModel
-	providerType    int // 0 = anthropic, 1 = openai
+	providerType    int // 0 = anthropic, 1 = openai, 2 = gemini
 }
 
 func newEditorModel(configName string) editorModel {
@@ -105,8 +105,11 @@ func newEditorModelWithPreset(configName string, presetName string) editorModel
 			m.fields[fieldSonnetModel].SetValue(p.SonnetModel)
 			m.fields[fieldProxyURL].SetValue(p.ProxyURL)
 			// Load provider type
-			if p.GetType() == config.ProviderTypeOpenAI {
+			switch p.GetType() {
+			case config.ProviderTypeOpenAI:
 				m.providerType = 1
+			case config.ProviderTypeGemini:
+				m.providerType = 2
 			}
 			// Load env vars for each CLI
 			if p.ClaudeEnvVars != nil {
@@ -222,7 +225,7 @@ func (m editorModel) update(msg tea.Msg) (editorModel, tea.Cmd) {
 		case "enter":
 			if m.focus == fieldType {
 				// Toggle type on enter
-				m.providerType = (m.providerType + 1) % 2
+				m.providerType = (m.providerType + 1) % 3
 				return m, nil
 			}
 			if !m.standalone && m.focus == fieldEnvVars {
@@ -255,7 +258,7 @@ func (m editorModel) update(msg tea.Msg) (editorModel, tea.Cmd) {
 		case "left", "right":
 			// Toggle type with left/right when focused on type field
 			if m.focus == fieldType {
-				m.providerType = (m.providerType + 1) % 2
+				m.providerType = (m.providerType + 1) % 3
 				return m, nil
 			}
 			// Switch CLI with left/right when focused on env vars field
@@ -336,8 +339,11 @@ func (m editorModel) save() (editorModel, tea.Cmd) {
 
 	// Determine provider type
 	providerType := config.ProviderTypeAnthropic
-	if m.providerType == 1 {
+	switch m.providerType {
+	case 1:
 		providerType = config.ProviderTypeOpenAI
+	case 2:
+		providerType = config.ProviderTypeGemini
 	}
 
 	p := &config.ProviderConfig{
@@ -520,8 +526,11 @@ func (m editorModel) renderFields(b *strings.Builder) {
 				style = lipgloss.NewStyle().Foreground(accentColor).Bold(true)
 			}
 			typeLabel := "Anthropic Messages API"
-			if m.providerType == 1 {
+			switch m.providerType {
+			case 1:
 				typeLabel = "OpenAI Chat Completions API"
+			case 2:
+				typeLabel = "Google Gemini API"
 			}
 			b.WriteString(style.Render(fmt.Sprintf("%sAPI Type:         [%s] (←/→ to change)", cursor, typeLabel)))
 			b.WriteString("\n")
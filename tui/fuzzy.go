@@ -0,0 +1,211 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// fuzzyMaxRows bounds the matches the fuzzy picker shows at once.
+const fuzzyMaxRows = 12
+
+var fuzzyMatchStyle = lipgloss.NewStyle().Foreground(highlightColor).Bold(true)
+
+// fuzzyResult is an item that matches the query, with the positions of the
+// matched runes.
+type fuzzyResult struct {
+	item      string
+	score     int
+	positions []int
+}
+
+// fuzzyMatch reports whether the runes of query appear in s in order,
+// ignoring case, fzf-style. Matches score higher when they are consecutive,
+// start a word or start early in s.
+func fuzzyMatch(query, s string) (score int, positions []int, ok bool) {
+	q := []rune(strings.ToLower(query))
+	if len(q) == 0 {
+		return 0, nil, true
+	}
+	r := []rune(s)
+	prev := -1
+	for i := 0; i < len(r) && len(positions) < len(q); i++ {
+		if unicode.ToLower(r[i]) != q[len(positions)] {
+			continue
+		}
+		switch {
+		case prev >= 0 && i == prev+1:
+			score += 8
+		case i == 0 || strings.ContainsRune("-_./ :", r[i-1]) || (unicode.IsUpper(r[i]) && unicode.IsLower(r[i-1])):
+			score += 6
+		default:
+			score += 1
+		}
+		if prev >= 0 {
+			score -= min(i-prev-1, 4)
+		}
+		positions = append(positions, i)
+		prev = i
+	}
+	if len(positions) < len(q) {
+		return 0, nil, false
+	}
+	return score - min(positions[0], 8), positions, true
+}
+
+// fuzzyPickerModel is a type-to-filter list, like fzf. It uses alt screen
+// so the list vanishes on exit.
+type fuzzyPickerModel struct {
+	title     string
+	items     []string
+	current   string // item marked with ● (the active value)
+	query     string
+	results   []fuzzyResult
+	cursor    int
+	selected  string
+	cancelled bool
+}
+
+func newFuzzyPickerModel(title string, items []string, current string) fuzzyPickerModel {
+	m := fuzzyPickerModel{title: title, items: items, current: current}
+	m.filter()
+	for i, r := range m.results {
+		if r.item == current {
+			m.cursor = i
+			break
+		}
+	}
+	return m
+}
+
+// filter matches the items against the query, best first. With no query
+// the items keep their order.
+func (m *fuzzyPickerModel) filter() {
+	m.results = nil
+	for _, item := range m.items {
+		if score, pos, ok := fuzzyMatch(m.query, item); ok {
+			m.results = append(m.results, fuzzyResult{item, score, pos})
+		}
+	}
+	if m.query != "" {
+		sort.SliceStable(m.results, func(i, j int) bool { return m.results[i].score > m.results[j].score })
+	}
+	m.cursor = 0
+}
+
+func (m fuzzyPickerModel) Init() tea.Cmd { return nil }
+
+func (m fuzzyPickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	switch key.Type {
+	case tea.KeyCtrlC, tea.KeyEsc:
+		m.cancelled = true
+		return m, tea.Quit
+	case tea.KeyEnter:
+		if m.cursor < len(m.results) {
+			m.selected = m.results[m.cursor].item
+			return m, tea.Quit
+		}
+	case tea.KeyUp, tea.KeyCtrlP, tea.KeyCtrlK:
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case tea.KeyDown, tea.KeyCtrlN, tea.KeyCtrlJ, tea.KeyTab:
+		if m.cursor < len(m.results)-1 {
+			m.cursor++
+		}
+	case tea.KeyBackspace:
+		if q := []rune(m.query); len(q) > 0 {
+			m.query = string(q[:len(q)-1])
+			m.filter()
+		}
+	case tea.KeyCtrlU:
+		m.query = ""
+		m.filter()
+	case tea.KeyRunes, tea.KeySpace:
+		m.query += string(key.Runes)
+		m.filter()
+	}
+	return m, nil
+}
+
+func (m fuzzyPickerModel) View() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(m.title))
+	b.WriteString("\n")
+	fmt.Fprintf(&b, "%s %s%s  %s\n\n", lipgloss.NewStyle().Foreground(accentColor).Render(">"), m.query,
+		lipgloss.NewStyle().Foreground(accentColor).Render("█"), dimStyle.Render(fmt.Sprintf("%d/%d", len(m.results), len(m.items))))
+
+	if len(m.results) == 0 {
+		b.WriteString(dimStyle.Render("  no matches"))
+		b.WriteString("\n")
+	}
+	// Scroll to keep the cursor in view
+	start := 0
+	if m.cursor >= fuzzyMaxRows {
+		start = m.cursor - fuzzyMaxRows + 1
+	}
+	for i := start; i < len(m.results) && i < start+fuzzyMaxRows; i++ {
+		r := m.results[i]
+		dot := "○ "
+		if r.item == m.current {
+			dot = "● "
+		}
+		cursor := "  "
+		if i == m.cursor {
+			cursor = "▸ "
+		}
+		b.WriteString(cursor + dot + highlightFuzzy(r, i == m.cursor))
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+	b.WriteString(RenderHelpBar("type to filter • ↑↓ move • Enter select • Esc cancel", 60))
+	return b.String()
+}
+
+// highlightFuzzy renders an item with its matched runes highlighted.
+func highlightFuzzy(r fuzzyResult, selected bool) string {
+	base := dimStyle
+	if selected {
+		base = lipgloss.NewStyle().Foreground(accentColor).Bold(true)
+	}
+	matched := make(map[int]bool, len(r.positions))
+	for _, p := range r.positions {
+		matched[p] = true
+	}
+	var b strings.Builder
+	for i, c := range []rune(r.item) {
+		if matched[i] {
+			b.WriteString(fuzzyMatchStyle.Render(string(c)))
+		} else {
+			b.WriteString(base.Render(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// RunFuzzyPicker runs a type-to-filter picker in alt screen and returns
+// the selected item. current marks the active item with ● and starts the
+// cursor on it. Returns a "cancelled" error on esc/ctrl-c.
+func RunFuzzyPicker(title string, items []string, current string) (string, error) {
+	if len(items) == 0 {
+		return "", fmt.Errorf("nothing to pick from")
+	}
+	p := tea.NewProgram(newFuzzyPickerModel(title, items, current), tea.WithAltScreen())
+	result, err := p.Run()
+	if err != nil {
+		return "", err
+	}
+	fm := result.(fuzzyPickerModel)
+	if fm.cancelled {
+		return "", fmt.Errorf("cancelled")
+	}
+	return fm.selected, nil
+}
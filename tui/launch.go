@@ -193,6 +193,8 @@ func (m LaunchModel) View() string {
 		"claude":   "Claude Code",
 		"codex":    "Codex CLI",
 		"opencode": "OpenCode",
+		"gemini":   "Gemini CLI",
+		"aider":    "Aider",
 	}
 	for i, c := range m.clis {
 		line := c
@@ -0,0 +1,619 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/dopejs/gozen/internal/config"
+)
+
+// topHistory is the number of refreshes a sparkline spans.
+const topHistory = 30
+
+// topRequests is the number of recent requests fetched on each refresh.
+const topRequests = 50
+
+// topSectionStyle titles the dashboard's panes.
+var topSectionStyle = lipgloss.NewStyle().Foreground(primaryColor).Bold(true)
+
+// sparkLevels are the bars of a sparkline, lowest first.
+var sparkLevels = []rune("▁▂▃▄▅▆▇█")
+
+// TopOptions configures the zen top dashboard.
+type TopOptions struct {
+	BaseURL  string        // the daemon's web API, such as http://127.0.0.1:19840
+	Interval time.Duration // time between refreshes
+}
+
+// RunTop starts the live zen top dashboard and blocks until the user quits.
+func RunTop(opts TopOptions) error {
+	if opts.Interval <= 0 {
+		opts.Interval = 2 * time.Second
+	}
+	p := tea.NewProgram(newTopModel(opts), tea.WithAltScreen())
+	_, err := p.Run()
+	return err
+}
+
+// topRequest mirrors the fields of /api/v1/monitoring/requests used by zen top.
+type topRequest struct {
+	ID           string    `json:"id"`
+	Timestamp    time.Time `json:"timestamp"`
+	Provider     string    `json:"provider"`
+	Model        string    `json:"model"`
+	StatusCode   int       `json:"status_code"`
+	DurationMs   int64     `json:"duration_ms"`
+	InputTokens  int       `json:"input_tokens"`
+	OutputTokens int       `json:"output_tokens"`
+	Cost         float64   `json:"cost_usd"`
+	ErrorMessage string    `json:"error_message"`
+}
+
+func (r topRequest) failed() bool {
+	return r.StatusCode >= 400 || (r.StatusCode == 0 && r.ErrorMessage != "")
+}
+
+// topHealth mirrors the fields of /api/v1/health/providers used by zen top.
+type topHealth struct {
+	Provider    string  `json:"provider"`
+	Status      string  `json:"status"`
+	LatencyMs   int     `json:"latency_ms"`
+	SuccessRate float64 `json:"success_rate"`
+}
+
+// topBudget mirrors the fields of /api/v1/budget/status used by zen top.
+type topBudget struct {
+	Currency       string  `json:"currency"`
+	DailySpent     float64 `json:"daily_spent"`
+	DailyLimit     float64 `json:"daily_limit"`
+	DailyPercent   float64 `json:"daily_percent"`
+	MonthlySpent   float64 `json:"monthly_spent"`
+	MonthlyLimit   float64 `json:"monthly_limit"`
+	MonthlyPercent float64 `json:"monthly_percent"`
+	Message        string  `json:"message"`
+}
+
+// topSession mirrors the fields of /api/v1/agent/sessions used by zen top.
+type topSession struct {
+	ID           string    `json:"id"`
+	Client       string    `json:"client"`
+	Profile      string    `json:"profile"`
+	Status       string    `json:"status"`
+	CurrentTask  string    `json:"current_task"`
+	RequestCount int       `json:"request_count"`
+	TotalCost    float64   `json:"total_cost"`
+	LastActivity time.Time `json:"last_activity"`
+	LikelyStuck  bool      `json:"likely_stuck"`
+}
+
+// topSnapshot is one refresh of the daemon's state.
+type topSnapshot struct {
+	at       time.Time
+	requests []topRequest
+	health   []topHealth
+	budget   *topBudget
+	sessions []topSession
+	agentOff bool
+	err      error
+}
+
+type topTickMsg time.Time
+
+// topActionMsg reports the result of a keybinding's action.
+type topActionMsg struct {
+	text string
+	err  error
+}
+
+// topModel is the zen top dashboard: live requests, per-provider latency
+// and error sparklines, budget burn and agent sessions.
+type topModel struct {
+	opts   TopOptions
+	client *http.Client
+	width  int
+	height int
+
+	snap      *topSnapshot
+	seen      map[string]bool      // request IDs already counted in the sparklines
+	latency   map[string][]float64 // per provider, average latency per refresh
+	errors    map[string][]float64 // per provider, failed requests per refresh
+	providers []string
+	disabled  map[string]bool
+
+	// Budget burn is measured from the first refresh
+	burnStart time.Time
+	burnSpent float64
+
+	focusSessions bool
+	provCursor    int
+	sessCursor    int
+	confirmKill   string
+	message       string
+}
+
+func newTopModel(opts TopOptions) topModel {
+	return topModel{
+		opts:     opts,
+		client:   &http.Client{Timeout: 3 * time.Second},
+		latency:  make(map[string][]float64),
+		errors:   make(map[string][]float64),
+		disabled: make(map[string]bool),
+	}
+}
+
+func (m topModel) Init() tea.Cmd {
+	return tea.Batch(m.fetch(), m.tick())
+}
+
+func (m topModel) tick() tea.Cmd {
+	return tea.Tick(m.opts.Interval, func(t time.Time) tea.Msg { return topTickMsg(t) })
+}
+
+// fetch loads a snapshot from the daemon API.
+func (m topModel) fetch() tea.Cmd {
+	client, base := m.client, m.opts.BaseURL
+	return func() tea.Msg {
+		s := &topSnapshot{at: time.Now()}
+		var requests struct {
+			Requests []topRequest `json:"requests"`
+		}
+		if s.err = topGet(client, base, fmt.Sprintf("/api/v1/monitoring/requests?limit=%d", topRequests), &requests); s.err != nil {
+			return s
+		}
+		s.requests = requests.Requests
+		topGet(client, base, "/api/v1/health/providers", &s.health)
+		var budget topBudget
+		if topGet(client, base, "/api/v1/budget/status", &budget) == nil {
+			s.budget = &budget
+		}
+		var sessions struct {
+			Sessions []topSession `json:"sessions"`
+		}
+		if err := topGet(client, base, "/api/v1/agent/sessions", &sessions); err != nil {
+			s.agentOff = true
+		}
+		s.sessions = sessions.Sessions
+		sort.Slice(s.sessions, func(i, j int) bool { return s.sessions[i].LastActivity.After(s.sessions[j].LastActivity) })
+		return s
+	}
+}
+
+// topGet GETs a daemon API path and decodes the JSON response.
+func topGet(client *http.Client, base, path string, v interface{}) error {
+	resp, err := client.Get(base + path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: HTTP %d", path, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// killSession kills an agent session through the daemon API.
+func (m topModel) killSession(id string) tea.Cmd {
+	client, base := m.client, m.opts.BaseURL
+	return func() tea.Msg {
+		resp, err := client.Post(base+"/api/v1/agent/sessions/"+url.PathEscape(id)+"/kill", "application/json", nil)
+		if err != nil {
+			return topActionMsg{err: err}
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return topActionMsg{err: fmt.Errorf("kill %s: HTTP %d", id, resp.StatusCode)}
+		}
+		return topActionMsg{text: "killed session " + id}
+	}
+}
+
+func (m topModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+	case topTickMsg:
+		return m, tea.Batch(m.fetch(), m.tick())
+	case *topSnapshot:
+		m.apply(msg)
+	case topActionMsg:
+		if msg.err != nil {
+			m.message = "error: " + msg.err.Error()
+		} else {
+			m.message = msg.text
+		}
+		return m, m.fetch()
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+	}
+	return m, nil
+}
+
+func (m topModel) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.confirmKill != "" {
+		id := m.confirmKill
+		m.confirmKill = ""
+		if msg.String() == "y" || msg.String() == "Y" {
+			m.message = "killing session " + id + "..."
+			return m, m.killSession(id)
+		}
+		m.message = ""
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "ctrl+c", "q", "esc":
+		return m, tea.Quit
+	case "tab":
+		m.focusSessions = !m.focusSessions
+	case "up", "k":
+		if m.focusSessions {
+			m.sessCursor = max(m.sessCursor-1, 0)
+		} else {
+			m.provCursor = max(m.provCursor-1, 0)
+		}
+	case "down", "j":
+		if m.focusSessions {
+			m.sessCursor = min(m.sessCursor+1, max(len(m.sessions())-1, 0))
+		} else {
+			m.provCursor = min(m.provCursor+1, max(len(m.providers)-1, 0))
+		}
+	case "r":
+		return m, m.fetch()
+	case "p":
+		if m.focusSessions || m.provCursor >= len(m.providers) {
+			return m, nil
+		}
+		name := m.providers[m.provCursor]
+		var err error
+		if m.disabled[name] {
+			err = config.EnableProvider(name)
+			m.message = "resumed " + name
+		} else {
+			err = config.DisableProvider(name, config.MarkingTypeToday)
+			m.message = "paused " + name + " for today"
+		}
+		if err != nil {
+			m.message = "error: " + err.Error()
+		}
+		m.refreshDisabled()
+		return m, m.fetch()
+	case "x":
+		sessions := m.sessions()
+		if m.focusSessions && m.sessCursor < len(sessions) {
+			m.confirmKill = sessions[m.sessCursor].ID
+			m.message = "kill session " + m.confirmKill + "? (y/n)"
+		}
+	}
+	return m, nil
+}
+
+func (m topModel) sessions() []topSession {
+	if m.snap == nil {
+		return nil
+	}
+	return m.snap.sessions
+}
+
+func (m *topModel) refreshDisabled() {
+	m.disabled = make(map[string]bool)
+	for name := range config.GetDisabledProviders() {
+		m.disabled[name] = true
+	}
+}
+
+// apply takes in a snapshot, adding a sample to each provider's sparklines
+// from the requests that are new since the last refresh.
+func (m *topModel) apply(s *topSnapshot) {
+	first := m.snap == nil || m.seen == nil
+	if s.err != nil {
+		if m.snap != nil {
+			m.snap.err = s.err
+		} else {
+			m.snap = s
+		}
+		return
+	}
+	m.snap = s
+	m.refreshDisabled()
+
+	names := make(map[string]bool)
+	for _, name := range config.ProviderNames() {
+		names[name] = true
+	}
+	health := make(map[string]topHealth)
+	for _, h := range s.health {
+		names[h.Provider] = true
+		health[h.Provider] = h
+	}
+
+	type sample struct {
+		total  int64
+		count  int
+		failed int
+	}
+	samples := make(map[string]*sample)
+	seen := make(map[string]bool, len(s.requests))
+	for _, r := range s.requests {
+		seen[r.ID] = true
+		if first || m.seen[r.ID] || r.Provider == "" {
+			continue
+		}
+		names[r.Provider] = true
+		sm := samples[r.Provider]
+		if sm == nil {
+			sm = &sample{}
+			samples[r.Provider] = sm
+		}
+		sm.total += r.DurationMs
+		sm.count++
+		if r.failed() {
+			sm.failed++
+		}
+	}
+	m.seen = seen
+
+	m.providers = m.providers[:0]
+	for name := range names {
+		m.providers = append(m.providers, name)
+	}
+	sort.Strings(m.providers)
+	for _, name := range m.providers {
+		// Without requests this refresh, latency falls back to the health check's
+		lat, failed := float64(health[name].LatencyMs), 0.0
+		if sm := samples[name]; sm != nil {
+			lat, failed = float64(sm.total)/float64(sm.count), float64(sm.failed)
+		}
+		m.latency[name] = appendSample(m.latency[name], lat)
+		m.errors[name] = appendSample(m.errors[name], failed)
+	}
+	m.provCursor = min(m.provCursor, max(len(m.providers)-1, 0))
+	m.sessCursor = min(m.sessCursor, max(len(s.sessions)-1, 0))
+
+	if b := s.budget; b != nil && (m.burnStart.IsZero() || b.DailySpent < m.burnSpent) {
+		// Start over at the first refresh and when the day rolls over
+		m.burnStart, m.burnSpent = s.at, b.DailySpent
+	}
+}
+
+func appendSample(samples []float64, v float64) []float64 {
+	samples = append(samples, v)
+	if len(samples) > topHistory {
+		samples = samples[len(samples)-topHistory:]
+	}
+	return samples
+}
+
+// sparkline renders samples as bars scaled to their maximum.
+func sparkline(samples []float64, width int) string {
+	if len(samples) > width {
+		samples = samples[len(samples)-width:]
+	}
+	var peak float64
+	for _, v := range samples {
+		if v > peak {
+			peak = v
+		}
+	}
+	var b strings.Builder
+	for i := len(samples); i < width; i++ {
+		b.WriteRune(' ')
+	}
+	for _, v := range samples {
+		level := 0
+		if peak > 0 {
+			level = int(v / peak * float64(len(sparkLevels)-1))
+		}
+		b.WriteRune(sparkLevels[level])
+	}
+	return b.String()
+}
+
+// meter renders a fill bar for a percentage.
+func meter(percent float64, width int) string {
+	filled := int(percent / 100 * float64(width))
+	filled = min(max(filled, 0), width)
+	style := successStyle
+	switch {
+	case percent >= 100:
+		style = errorStyle
+	case percent >= 80:
+		style = lipgloss.NewStyle().Foreground(accentColor)
+	}
+	return style.Render(strings.Repeat("█", filled)) + dimStyle.Render(strings.Repeat("░", width-filled))
+}
+
+func (m topModel) View() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("zen top"))
+	b.WriteString("\n")
+
+	if m.snap == nil {
+		b.WriteString(dimStyle.Render("  connecting to zend at " + m.opts.BaseURL + "..."))
+		return b.String()
+	}
+	if m.snap.err != nil {
+		b.WriteString(errorStyle.Render("  daemon not reachable: " + m.snap.err.Error()))
+		b.WriteString("\n\n")
+	}
+
+	b.WriteString(m.viewBudget())
+	b.WriteString("\n")
+	b.WriteString(m.viewProviders())
+	b.WriteString("\n")
+	b.WriteString(m.viewRequests())
+	b.WriteString("\n")
+	b.WriteString(m.viewSessions())
+
+	help := "tab switch pane  ↑↓ select  p pause/resume provider  x kill session  r refresh  q quit"
+	if m.message != "" {
+		help = m.message
+	}
+	b.WriteString("\n")
+	b.WriteString(RenderHelpBar(help, max(m.width, 40)))
+	return b.String()
+}
+
+func (m topModel) viewBudget() string {
+	var b strings.Builder
+	b.WriteString(topSectionStyle.Render("Budget"))
+	b.WriteString("\n")
+	bs := m.snap.budget
+	if bs == nil {
+		b.WriteString(dimStyle.Render("  unavailable"))
+		b.WriteString("\n")
+		return b.String()
+	}
+	amount := func(v float64) string { return config.FormatAmount(bs.Currency, v) }
+	if bs.DailyLimit > 0 {
+		fmt.Fprintf(&b, "  today  %s %s of %s (%.0f%%)", meter(bs.DailyPercent, 20), amount(bs.DailySpent), amount(bs.DailyLimit), bs.DailyPercent)
+	} else {
+		fmt.Fprintf(&b, "  today  %s (no daily budget)", amount(bs.DailySpent))
+	}
+	if elapsed := m.snap.at.Sub(m.burnStart); elapsed >= time.Minute {
+		rate := (bs.DailySpent - m.burnSpent) / elapsed.Hours()
+		fmt.Fprintf(&b, "  burn %s/h", amount(rate))
+		if bs.DailyLimit > bs.DailySpent && rate > 0 {
+			left := time.Duration((bs.DailyLimit - bs.DailySpent) / rate * float64(time.Hour))
+			fmt.Fprintf(&b, ", limit in ~%s", formatTopDuration(left))
+		}
+	}
+	b.WriteString("\n")
+	if bs.MonthlyLimit > 0 {
+		fmt.Fprintf(&b, "  month  %s %s of %s (%.0f%%)\n", meter(bs.MonthlyPercent, 20), amount(bs.MonthlySpent), amount(bs.MonthlyLimit), bs.MonthlyPercent)
+	}
+	if bs.Message != "" {
+		b.WriteString("  " + errorStyle.Render(bs.Message) + "\n")
+	}
+	return b.String()
+}
+
+func (m topModel) viewProviders() string {
+	var b strings.Builder
+	title := "Providers"
+	if !m.focusSessions {
+		title += " ◂"
+	}
+	b.WriteString(topSectionStyle.Render(title))
+	b.WriteString("\n")
+	if len(m.providers) == 0 {
+		b.WriteString(dimStyle.Render("  none configured") + "\n")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "%s\n", dimStyle.Render(fmt.Sprintf("    %-18s %-10s %8s  %-*s  %-*s", "NAME", "STATUS", "LAST", topHistory, "LATENCY", topHistory, "ERRORS")))
+	health := make(map[string]topHealth)
+	for _, h := range m.snap.health {
+		health[h.Provider] = h
+	}
+	for i, name := range m.providers {
+		h := health[name]
+		status := h.Status
+		if status == "" {
+			status = "unknown"
+		}
+		if m.disabled[name] {
+			status = "paused"
+		}
+		lat := m.latency[name]
+		var last float64
+		if len(lat) > 0 {
+			last = lat[len(lat)-1]
+		}
+		line := fmt.Sprintf("%-18s %-10s %6.0fms  %s  %s", truncateTop(name, 18), status, last,
+			sparkline(lat, topHistory), errorStyle.Render(sparkline(m.errors[name], topHistory)))
+		b.WriteString(m.row(!m.focusSessions && i == m.provCursor, line, status))
+	}
+	return b.String()
+}
+
+func (m topModel) viewRequests() string {
+	var b strings.Builder
+	b.WriteString(topSectionStyle.Render("Requests"))
+	b.WriteString("\n")
+	// Leave the other panes their room
+	limit := 8
+	if m.height > 0 {
+		limit = max(m.height-len(m.providers)-len(m.sessions())-20, 3)
+	}
+	if len(m.snap.requests) == 0 {
+		b.WriteString(dimStyle.Render("  no requests yet") + "\n")
+		return b.String()
+	}
+	for i, r := range m.snap.requests {
+		if i == limit {
+			break
+		}
+		code := fmt.Sprintf("%d", r.StatusCode)
+		if r.failed() {
+			code = errorStyle.Render(code)
+		}
+		fmt.Fprintf(&b, "  %s  %-16s %-28s %s %6.1fs %7s %s\n",
+			r.Timestamp.Local().Format("15:04:05"), truncateTop(r.Provider, 16), truncateTop(r.Model, 28), code,
+			float64(r.DurationMs)/1000, fmt.Sprintf("%d/%d", r.InputTokens, r.OutputTokens), dimStyle.Render(fmt.Sprintf("$%.4f", r.Cost)))
+	}
+	return b.String()
+}
+
+func (m topModel) viewSessions() string {
+	var b strings.Builder
+	title := "Agent sessions"
+	if m.focusSessions {
+		title += " ◂"
+	}
+	b.WriteString(topSectionStyle.Render(title))
+	b.WriteString("\n")
+	switch {
+	case m.snap.agentOff:
+		b.WriteString(dimStyle.Render("  agent infrastructure is off") + "\n")
+		return b.String()
+	case len(m.snap.sessions) == 0:
+		b.WriteString(dimStyle.Render("  no sessions") + "\n")
+		return b.String()
+	}
+	for i, s := range m.snap.sessions {
+		status := s.Status
+		if s.LikelyStuck && status == "active" {
+			status = "stuck"
+		}
+		line := fmt.Sprintf("%-20s %-9s %-8s %4d req  $%-8.2f %s", truncateTop(s.ID, 20), truncateTop(s.Client, 9), status,
+			s.RequestCount, s.TotalCost, truncateTop(s.CurrentTask, 40))
+		b.WriteString(m.row(m.focusSessions && i == m.sessCursor, line, status))
+	}
+	return b.String()
+}
+
+// row renders a selectable line, dimmed when its status is inactive.
+func (m topModel) row(selected bool, line, status string) string {
+	switch {
+	case selected:
+		return tableSelectedRowStyle.Render("  ▸ "+line) + "\n"
+	case status == "paused" || status == "killed" || status == "idle":
+		return dimStyle.Render("    "+line) + "\n"
+	case status == "unhealthy" || status == "stuck":
+		return errorStyle.Render("    "+line) + "\n"
+	}
+	return "    " + line + "\n"
+}
+
+// formatTopDuration renders a duration in hours and minutes.
+func formatTopDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+}
+
+func truncateTop(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
@@ -15,6 +15,7 @@ import { MonitoringPage } from '@/pages/monitoring'
 import { UsagePage } from '@/pages/usage'
 import { SettingsPage } from '@/pages/settings'
 import { authApi } from '@/lib/api'
+import { useServerEvents } from '@/hooks/use-server-events'
 
 function App() {
   const { data: authStatus, isLoading } = useQuery({
@@ -23,6 +24,9 @@ function App() {
     retry: false,
   })
 
+  const needsAuth = authStatus?.password_set && !authStatus?.authenticated
+  useServerEvents(!isLoading && !needsAuth)
+
   if (isLoading) {
     return (
       <div className="flex h-screen items-center justify-center bg-background">
@@ -31,8 +35,6 @@ function App() {
     )
   }
 
-  const needsAuth = authStatus?.password_set && !authStatus?.authenticated
-
   if (needsAuth) {
     return (
       <>
@@ -1,5 +1,7 @@
 import { useTranslation } from 'react-i18next'
-import { Moon, Sun, Monitor, Globe, LogOut } from 'lucide-react'
+import { useQuery } from '@tanstack/react-query'
+import { Moon, Sun, Monitor, Globe, LogOut, Eye } from 'lucide-react'
+import { Badge } from '@/components/ui/badge'
 import { Button } from '@/components/ui/button'
 import {
   DropdownMenu,
@@ -24,6 +26,7 @@ const languages = [
 export function Header() {
   const { t, i18n } = useTranslation()
   const { theme, setTheme } = useTheme()
+  const { data: authStatus } = useQuery({ queryKey: ['auth', 'check'], queryFn: authApi.check })
 
   const handleLanguageChange = (lang: string) => {
     i18n.changeLanguage(lang)
@@ -43,6 +46,16 @@ export function Header() {
 
   return (
     <header className="sticky top-0 z-30 flex h-16 items-center justify-end gap-2 border-b bg-background/95 px-6 backdrop-blur supports-[backdrop-filter]:bg-background/60">
+      {authStatus?.user && (
+        <span className="mr-2 text-sm text-muted-foreground">{authStatus.user}</span>
+      )}
+      {authStatus?.role === 'viewer' && (
+        <Badge variant="secondary" className="mr-2">
+          <Eye className="mr-1 h-3 w-3" />
+          {t('common.readOnly')}
+        </Badge>
+      )}
+
       {/* Theme Switcher */}
       <DropdownMenu>
         <DropdownMenuTrigger asChild>
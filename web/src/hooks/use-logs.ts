@@ -1,5 +1,7 @@
+import { useEffect, useState } from 'react'
 import { useQuery } from '@tanstack/react-query'
 import { logsApi } from '@/lib/api'
+import type { LogEntry } from '@/types/api'
 
 export interface LogsParams {
   provider?: string
@@ -7,6 +9,8 @@ export interface LogsParams {
   client_type?: string
   errors_only?: boolean
   limit?: number
+  before?: string // cursors from LogsResponse
+  after?: string
 }
 
 export function useLogs(params?: LogsParams, refetchInterval?: number) {
@@ -16,3 +20,41 @@ export function useLogs(params?: LogsParams, refetchInterval?: number) {
     refetchInterval,
   })
 }
+
+export interface LogStreamParams {
+  provider?: string
+  errors_only?: boolean
+  status?: string // comma-separated status classes: 2xx, 3xx, 4xx, 5xx, error
+  project?: string
+}
+
+const maxStreamedEntries = 500
+
+// Tail new log entries over /api/v1/logs/stream while enabled, newest first.
+export function useLogStream(params: LogStreamParams, enabled: boolean) {
+  const [entries, setEntries] = useState<LogEntry[]>([])
+  const [connected, setConnected] = useState(false)
+  const query = new URLSearchParams()
+  if (params.provider) query.set('provider', params.provider)
+  if (params.errors_only) query.set('errors_only', 'true')
+  if (params.status) query.set('status', params.status)
+  if (params.project) query.set('project', params.project)
+  const queryString = query.toString()
+
+  useEffect(() => {
+    if (!enabled || typeof WebSocket === 'undefined') return
+
+    setEntries([])
+    const scheme = window.location.protocol === 'https:' ? 'wss' : 'ws'
+    const socket = new WebSocket(`${scheme}://${window.location.host}/api/v1/logs/stream?${queryString}`)
+    socket.onopen = () => setConnected(true)
+    socket.onclose = () => setConnected(false)
+    socket.onmessage = (e) => {
+      const entry = JSON.parse(e.data) as LogEntry
+      setEntries((prev) => [entry, ...prev].slice(0, maxStreamedEntries))
+    }
+    return () => socket.close()
+  }, [enabled, queryString])
+
+  return { entries, connected }
+}
@@ -0,0 +1,9 @@
+import { useQuery } from '@tanstack/react-query'
+import { modelsApi } from '@/lib/api'
+
+export function useModelCatalog() {
+  return useQuery({
+    queryKey: ['models'],
+    queryFn: () => modelsApi.catalog(),
+  })
+}
@@ -0,0 +1,58 @@
+import { useEffect } from 'react'
+import { useQueryClient } from '@tanstack/react-query'
+import { toast } from 'sonner'
+
+// Keep the Web UI live from the daemon's event stream: refetch what an
+// event changes instead of polling every endpoint.
+export function useServerEvents(enabled: boolean) {
+  const queryClient = useQueryClient()
+
+  useEffect(() => {
+    if (!enabled || typeof EventSource === 'undefined') return
+
+    const invalidate = (...keys: string[]) => {
+      for (const key of keys) {
+        queryClient.invalidateQueries({ queryKey: [key] })
+      }
+    }
+
+    const source = new EventSource('/api/v1/events')
+    source.addEventListener('config_reload', (e) => {
+      const data = JSON.parse((e as MessageEvent).data) as { status: string; error?: string }
+      if (data.status === 'ok') {
+        queryClient.invalidateQueries()
+      } else {
+        console.warn('config reload failed:', data.error)
+      }
+    })
+    source.addEventListener('request_completed', () => {
+      invalidate('requests', 'logs', 'usage')
+    })
+    source.addEventListener('budget_warning', (e) => {
+      const data = JSON.parse((e as MessageEvent).data) as { message?: string }
+      invalidate('budget')
+      if (data.message) {
+        toast.warning(data.message)
+      }
+    })
+    source.addEventListener('provider_health', () => {
+      invalidate('health')
+    })
+    source.addEventListener('agent_session', () => {
+      invalidate('agent')
+    })
+    source.addEventListener('context_warning', (e) => {
+      const data = JSON.parse((e as MessageEvent).data) as { session_id: string; percent: number }
+      toast.warning(`Session ${data.session_id} has filled ${Math.round(data.percent)}% of its context window`)
+    })
+    source.addEventListener('agent_deadlock', (e) => {
+      const data = JSON.parse((e as MessageEvent).data) as { sessions: string[]; victim?: string }
+      invalidate('agent')
+      toast.error(
+        `Agent sessions ${data.sessions.join(', ')} are deadlocked on file locks` +
+          (data.victim ? `; aborted ${data.victim}` : ''),
+      )
+    })
+    return () => source.close()
+  }, [enabled, queryClient])
+}
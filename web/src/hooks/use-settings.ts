@@ -1,6 +1,6 @@
 import { useQuery, useMutation, useQueryClient } from '@tanstack/react-query'
-import { settingsApi, bindingsApi, syncApi } from '@/lib/api'
-import type { Settings, SyncConfig } from '@/types/api'
+import { settingsApi, bindingsApi, syncApi, authApi } from '@/lib/api'
+import type { ApiTokenScope, Settings, SyncConfig } from '@/types/api'
 
 export function useSettings() {
   return useQuery({
@@ -26,6 +26,33 @@ export function useChangePassword() {
   })
 }
 
+export function useApiTokens() {
+  return useQuery({
+    queryKey: ['auth', 'tokens'],
+    queryFn: authApi.listTokens,
+  })
+}
+
+export function useCreateApiToken() {
+  const queryClient = useQueryClient()
+  return useMutation({
+    mutationFn: ({ name, scope }: { name: string; scope: ApiTokenScope }) => authApi.createToken(name, scope),
+    onSuccess: () => {
+      queryClient.invalidateQueries({ queryKey: ['auth', 'tokens'] })
+    },
+  })
+}
+
+export function useRevokeApiToken() {
+  const queryClient = useQueryClient()
+  return useMutation({
+    mutationFn: (id: string) => authApi.revokeToken(id),
+    onSuccess: () => {
+      queryClient.invalidateQueries({ queryKey: ['auth', 'tokens'] })
+    },
+  })
+}
+
 export function useBindings() {
   return useQuery({
     queryKey: ['bindings'],
@@ -18,11 +18,22 @@ import type {
   Session,
   AuthCheckResponse,
   LoginResponse,
+  ApiToken,
+  ApiTokenScope,
+  CreatedApiToken,
   HealthResponse,
   BotConfig,
   MiddlewareConfig,
+  MiddlewareStats,
   AutoPermissionConfig,
   AutoPermissionAll,
+  ModelCatalog,
+  ReplayRequest,
+  ReplayResult,
+  Annotation,
+  AnnotationPatch,
+  Dashboard,
+  AgentFileChange,
 } from '@/types/api'
 
 const API_BASE = '/api/v1'
@@ -69,6 +80,14 @@ export const authApi = {
     }),
   logout: () => request<{ success: boolean }>('/auth/logout', { method: 'POST' }),
   getPubKey: () => request<{ public_key: string }>('/auth/pubkey'),
+  listTokens: () => request<{ tokens: ApiToken[] }>('/auth/tokens'),
+  createToken: (name: string, scope: ApiTokenScope) =>
+    request<CreatedApiToken>('/auth/tokens', {
+      method: 'POST',
+      body: JSON.stringify({ name, scope }),
+    }),
+  revokeToken: (id: string) =>
+    request<{ status: string }>(`/auth/tokens/${encodeURIComponent(id)}`, { method: 'DELETE' }),
 }
 
 // Health API
@@ -129,6 +148,11 @@ export const profilesApi = {
     }),
 }
 
+// Models API
+export const modelsApi = {
+  catalog: (refresh = false) => request<ModelCatalog>(`/models${refresh ? '?refresh=true' : ''}`),
+}
+
 // Logs API
 export const logsApi = {
   list: (params?: {
@@ -136,17 +160,30 @@ export const logsApi = {
     session_id?: string
     client_type?: string
     errors_only?: boolean
+    label?: string
+    bookmarked?: boolean
     limit?: number
+    before?: string
+    after?: string
   }) => {
     const searchParams = new URLSearchParams()
     if (params?.provider) searchParams.set('provider', params.provider)
     if (params?.session_id) searchParams.set('session_id', params.session_id)
     if (params?.client_type) searchParams.set('client_type', params.client_type)
     if (params?.errors_only) searchParams.set('errors_only', 'true')
+    if (params?.label) searchParams.set('label', params.label)
+    if (params?.bookmarked) searchParams.set('bookmarked', 'true')
     if (params?.limit) searchParams.set('limit', params.limit.toString())
+    if (params?.before) searchParams.set('before', params.before)
+    if (params?.after) searchParams.set('after', params.after)
     const query = searchParams.toString()
     return request<LogsResponse>(`/logs${query ? `?${query}` : ''}`)
   },
+  annotate: (id: number, patch: AnnotationPatch) =>
+    request<{ annotation: Annotation | null }>(`/logs/${id}`, {
+      method: 'PATCH',
+      body: JSON.stringify(patch),
+    }),
 }
 
 // Request monitoring API
@@ -207,6 +244,11 @@ export const usageApi = {
   },
 }
 
+// Dashboard API
+export const dashboardApi = {
+  get: () => request<Dashboard>('/dashboard'),
+}
+
 // Budget API
 export const budgetApi = {
   get: () => request<Budget>('/budget'),
@@ -316,6 +358,38 @@ export const sessionsApi = {
     request<{ success: boolean }>(`/sessions/${encodeURIComponent(id)}`, {
       method: 'DELETE',
     }),
+  replay: (id: string, req: ReplayRequest = {}) =>
+    request<ReplayResult>(`/sessions/${encodeURIComponent(id)}/replay`, {
+      method: 'POST',
+      body: JSON.stringify(req),
+    }),
+  annotate: (id: string, patch: AnnotationPatch) =>
+    request<{ annotation: Annotation | null }>(`/sessions/${encodeURIComponent(id)}`, {
+      method: 'PATCH',
+      body: JSON.stringify(patch),
+    }),
+}
+
+// Agent change review API
+export const agentChangesApi = {
+  list: (params?: { session_id?: string; status?: string; limit?: number }) => {
+    const searchParams = new URLSearchParams()
+    if (params?.session_id) searchParams.set('session_id', params.session_id)
+    if (params?.status) searchParams.set('status', params.status)
+    if (params?.limit) searchParams.set('limit', params.limit.toString())
+    const query = searchParams.toString()
+    return request<{ changes: AgentFileChange[] }>(`/agent/changes${query ? `?${query}` : ''}`)
+  },
+  get: (id: string) => request<AgentFileChange>(`/agent/changes/${encodeURIComponent(id)}`),
+  revert: (id: string) =>
+    request<AgentFileChange>(`/agent/changes/${encodeURIComponent(id)}/revert`, {
+      method: 'POST',
+    }),
+  approve: (sessionId: string, ids?: string[]) =>
+    request<{ approved: number; changes: AgentFileChange[] }>('/agent/changes/approve', {
+      method: 'POST',
+      body: JSON.stringify({ session_id: sessionId, ids }),
+    }),
 }
 
 // Bot API
@@ -406,6 +480,7 @@ export const middlewareApi = {
     request<{ status: string }>(`/middleware/${encodeURIComponent(name)}/disable`, {
       method: 'POST',
     }),
+  stats: (name: string) => request<MiddlewareStats>(`/middleware/${encodeURIComponent(name)}/stats`),
   reload: () =>
     request<{ status: string }>('/middleware/reload', {
       method: 'POST',
@@ -3,19 +3,14 @@ import { useQuery } from '@tanstack/react-query'
 import { Server, Activity, DollarSign, Zap } from 'lucide-react'
 import { Card, CardContent, CardHeader, CardTitle } from '@/components/ui/card'
 import { Badge } from '@/components/ui/badge'
-import { healthApi, usageApi, providerHealthApi } from '@/lib/api'
+import { dashboardApi, providerHealthApi } from '@/lib/api'
 
 export function DashboardPage() {
   const { t } = useTranslation()
 
-  const { data: health } = useQuery({
-    queryKey: ['health'],
-    queryFn: healthApi.check,
-  })
-
-  const { data: usage } = useQuery({
-    queryKey: ['usage', 'summary', 'today'],
-    queryFn: () => usageApi.summary({ period: 'today' }),
+  const { data: dashboard, isError } = useQuery({
+    queryKey: ['dashboard'],
+    queryFn: dashboardApi.get,
   })
 
   const { data: providerHealth } = useQuery({
@@ -23,8 +18,8 @@ export function DashboardPage() {
     queryFn: providerHealthApi.list,
   })
 
-  const healthyProviders = providerHealth?.filter((p) => p.status === 'healthy').length ?? 0
-  const totalProviders = providerHealth?.length ?? 0
+  const healthyProviders = dashboard?.health.counts.healthy ?? 0
+  const totalProviders = dashboard?.health.providers.length ?? 0
 
   return (
     <div className="space-y-6">
@@ -38,13 +33,13 @@ export function DashboardPage() {
           </CardHeader>
           <CardContent>
             <div className="text-2xl font-bold">
-              {health?.status === 'ok' ? (
+              {dashboard && !isError ? (
                 <Badge variant="success">{t('usage.healthy')}</Badge>
               ) : (
                 <Badge variant="destructive">{t('common.error')}</Badge>
               )}
             </div>
-            <p className="text-xs text-muted-foreground">v{health?.version}</p>
+            <p className="text-xs text-muted-foreground">v{dashboard?.version}</p>
           </CardContent>
         </Card>
 
@@ -67,7 +62,7 @@ export function DashboardPage() {
             <Zap className="h-4 w-4 text-muted-foreground" />
           </CardHeader>
           <CardContent>
-            <div className="text-2xl font-bold">{usage?.request_count ?? 0}</div>
+            <div className="text-2xl font-bold">{dashboard?.today.request_count ?? 0}</div>
             <p className="text-xs text-muted-foreground">{t('usage.today')}</p>
           </CardContent>
         </Card>
@@ -78,7 +73,7 @@ export function DashboardPage() {
             <DollarSign className="h-4 w-4 text-muted-foreground" />
           </CardHeader>
           <CardContent>
-            <div className="text-2xl font-bold">${(usage?.total_cost ?? 0).toFixed(4)}</div>
+            <div className="text-2xl font-bold">{(dashboard?.today.cost ?? 0).toFixed(4)} {dashboard?.currency}</div>
             <p className="text-xs text-muted-foreground">{t('usage.today')}</p>
           </CardContent>
         </Card>
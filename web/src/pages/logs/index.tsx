@@ -1,13 +1,13 @@
 import { useSearchParams } from 'react-router-dom'
 import { useTranslation } from 'react-i18next'
-import { RefreshCw, ScrollText, AlertCircle } from 'lucide-react'
+import { RefreshCw, ScrollText, AlertCircle, Radio, ChevronLeft, ChevronRight } from 'lucide-react'
 import { Button } from '@/components/ui/button'
 import { Card, CardContent, CardHeader, CardTitle } from '@/components/ui/card'
 import { Badge } from '@/components/ui/badge'
 import { Switch } from '@/components/ui/switch'
 import { Label } from '@/components/ui/label'
 import { Select, SelectContent, SelectItem, SelectTrigger, SelectValue } from '@/components/ui/select'
-import { useLogs } from '@/hooks/use-logs'
+import { useLogs, useLogStream } from '@/hooks/use-logs'
 
 export function LogsPage() {
   const { t } = useTranslation()
@@ -16,9 +16,18 @@ export function LogsPage() {
   const autoRefresh = searchParams.get('autoRefresh') === 'true'
   const errorsOnly = searchParams.get('errorsOnly') === 'true'
   const selectedProvider = searchParams.get('provider') || 'all'
+  const live = searchParams.get('live') === 'true'
+  const statusClass = searchParams.get('status') || 'all'
+  const before = searchParams.get('before') || undefined
+  const after = searchParams.get('after') || undefined
 
   const updateParams = (updates: Record<string, string | null>) => {
     const newParams = new URLSearchParams(searchParams)
+    // Any other change starts again from the newest page
+    if (!('before' in updates) && !('after' in updates)) {
+      newParams.delete('before')
+      newParams.delete('after')
+    }
     for (const [key, value] of Object.entries(updates)) {
       if (value === null || value === 'false' || value === 'all') {
         newParams.delete(key)
@@ -34,9 +43,21 @@ export function LogsPage() {
       provider: selectedProvider === 'all' ? undefined : selectedProvider,
       errors_only: errorsOnly,
       limit: 100,
+      before,
+      after,
+    },
+    autoRefresh && !live ? 5000 : undefined
+  )
+
+  const stream = useLogStream(
+    {
+      provider: selectedProvider === 'all' ? undefined : selectedProvider,
+      errors_only: errorsOnly,
+      status: statusClass === 'all' ? undefined : statusClass,
     },
-    autoRefresh ? 5000 : undefined
+    live
   )
+  const entries = live ? stream.entries : data?.entries
 
   const formatTimestamp = (ts: string) => {
     return new Date(ts).toLocaleString()
@@ -72,7 +93,7 @@ export function LogsPage() {
           <h1 className="text-3xl font-bold">{t('logs.title')}</h1>
           <p className="text-muted-foreground">{t('logs.description')}</p>
         </div>
-        <Button variant="outline" onClick={() => refetch()}>
+        <Button variant="outline" onClick={() => refetch()} disabled={live}>
           <RefreshCw className="mr-2 h-4 w-4" />
           {t('common.refresh')}
         </Button>
@@ -103,10 +124,35 @@ export function LogsPage() {
             <Label htmlFor="errors-only">{t('logs.errorsOnly')}</Label>
           </div>
 
+          {!live && (
+            <div className="flex items-center gap-2">
+              <Switch id="auto-refresh" checked={autoRefresh} onCheckedChange={(v) => updateParams({ autoRefresh: v.toString() })} />
+              <Label htmlFor="auto-refresh">{t('logs.autoRefresh')}</Label>
+            </div>
+          )}
+
           <div className="flex items-center gap-2">
-            <Switch id="auto-refresh" checked={autoRefresh} onCheckedChange={(v) => updateParams({ autoRefresh: v.toString() })} />
-            <Label htmlFor="auto-refresh">{t('logs.autoRefresh')}</Label>
+            <Switch id="live" checked={live} onCheckedChange={(v) => updateParams({ live: v.toString() })} />
+            <Label htmlFor="live">{t('logs.live')}</Label>
           </div>
+
+          {live && (
+            <div className="flex items-center gap-2">
+              <Label htmlFor="status-filter">{t('logs.status')}</Label>
+              <Select value={statusClass} onValueChange={(v) => updateParams({ status: v })}>
+                <SelectTrigger id="status-filter" className="w-32">
+                  <SelectValue />
+                </SelectTrigger>
+                <SelectContent>
+                  <SelectItem value="all">{t('logs.allStatuses')}</SelectItem>
+                  <SelectItem value="2xx">2xx</SelectItem>
+                  <SelectItem value="4xx">4xx</SelectItem>
+                  <SelectItem value="5xx">5xx</SelectItem>
+                  <SelectItem value="error">{t('logs.noResponse')}</SelectItem>
+                </SelectContent>
+              </Select>
+            </div>
+          )}
         </CardContent>
       </Card>
 
@@ -115,13 +161,19 @@ export function LogsPage() {
         <CardHeader>
           <CardTitle className="flex items-center gap-2">
             <ScrollText className="h-5 w-5" />
-            {t('logs.title')} ({data?.total ?? 0})
+            {t('logs.title')} ({live ? stream.entries.length : (data?.total ?? 0)})
+            {live && (
+              <Badge variant={stream.connected ? 'success' : 'secondary'} className="ml-2">
+                <Radio className="mr-1 h-3 w-3" />
+                {stream.connected ? t('logs.liveConnected') : t('logs.liveDisconnected')}
+              </Badge>
+            )}
           </CardTitle>
         </CardHeader>
         <CardContent>
-          {isLoading ? (
+          {isLoading && !live ? (
             <div className="flex justify-center py-8">{t('common.loading')}</div>
-          ) : data?.entries && data.entries.length > 0 ? (
+          ) : entries && entries.length > 0 ? (
             <div className="overflow-x-auto">
               <table className="w-full text-sm">
                 <thead>
@@ -134,7 +186,7 @@ export function LogsPage() {
                   </tr>
                 </thead>
                 <tbody>
-                  {data.entries.map((entry, index) => (
+                  {entries.map((entry, index) => (
                     <tr key={index} className="border-b hover:bg-muted/50">
                       <td className="px-4 py-3 text-muted-foreground whitespace-nowrap">{formatTimestamp(entry.timestamp)}</td>
                       <td className="px-4 py-3">{getLevelBadge(entry.level)}</td>
@@ -145,11 +197,33 @@ export function LogsPage() {
                   ))}
                 </tbody>
               </table>
+              {!live && (data?.prev_cursor || data?.next_cursor) && (
+                <div className="flex justify-end gap-2 pt-4">
+                  <Button
+                    variant="outline"
+                    size="sm"
+                    disabled={!data?.prev_cursor}
+                    onClick={() => updateParams({ after: data?.prev_cursor ?? null, before: null })}
+                  >
+                    <ChevronLeft className="mr-1 h-4 w-4" />
+                    {t('common.previous')}
+                  </Button>
+                  <Button
+                    variant="outline"
+                    size="sm"
+                    disabled={!data?.next_cursor}
+                    onClick={() => updateParams({ before: data?.next_cursor ?? null, after: null })}
+                  >
+                    {t('common.next')}
+                    <ChevronRight className="ml-1 h-4 w-4" />
+                  </Button>
+                </div>
+              )}
             </div>
           ) : (
             <div className="flex flex-col items-center justify-center py-12">
               <AlertCircle className="mb-4 h-12 w-12 text-muted-foreground" />
-              <p className="text-muted-foreground">{t('logs.noLogs')}</p>
+              <p className="text-muted-foreground">{live ? t('logs.liveWaiting') : t('logs.noLogs')}</p>
             </div>
           )}
         </CardContent>
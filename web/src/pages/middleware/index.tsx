@@ -90,14 +90,14 @@ export function MiddlewarePage() {
   const handleFileSelect = (e: React.ChangeEvent<HTMLInputElement>) => {
     const file = e.target.files?.[0]
     if (file) {
-      if (!file.name.endsWith('.so')) {
+      if (!file.name.endsWith('.so') && !file.name.endsWith('.wasm')) {
         toast.error(t('middleware.onlySoFiles'))
         return
       }
       setSelectedFile(file)
       // Auto-fill name from filename if empty
       if (!installName) {
-        setInstallName(file.name.replace('.so', ''))
+        setInstallName(file.name.replace(/\.(so|wasm)$/, ''))
       }
     }
   }
@@ -136,7 +136,7 @@ export function MiddlewarePage() {
       const newEntry: MiddlewareEntry = {
         name: installName.trim(),
         enabled: true,
-        source: installSource === 'upload' ? 'local' : 'remote',
+        source: installSource === 'remote' ? 'remote' : selectedFile?.name.endsWith('.wasm') ? 'wasm' : 'local',
         ...(installSource === 'upload' ? { path: pluginPath } : { url: installUrl.trim() }),
       }
 
@@ -309,6 +309,16 @@ export function MiddlewarePage() {
                           {middleware.url}
                         </p>
                       )}
+                      {middleware.metrics && middleware.metrics.request.calls > 0 && (
+                        <p className="text-xs text-muted-foreground mt-1">
+                          {t('middleware.metricsSummary', {
+                            calls: middleware.metrics.request.calls,
+                            avg: middleware.metrics.request.avg_ms.toFixed(1),
+                            max: middleware.metrics.request.max_ms.toFixed(1),
+                            rejections: middleware.metrics.rejections,
+                          })}
+                        </p>
+                      )}
                     </div>
                   </div>
                   <div className="flex items-center gap-2">
@@ -388,7 +398,7 @@ export function MiddlewarePage() {
                 <div className="flex gap-2">
                   <Input
                     type="file"
-                    accept=".so"
+                    accept=".so,.wasm"
                     ref={fileInputRef}
                     onChange={handleFileSelect}
                     className="cursor-pointer"
@@ -17,6 +17,7 @@ import {
 } from '@/components/ui/select'
 import { useProfile, useCreateProfile, useUpdateProfile } from '@/hooks/use-profiles'
 import { useProviders } from '@/hooks/use-providers'
+import { useModelCatalog } from '@/hooks/use-models'
 import {
   BUILTIN_SCENARIOS,
   SCENARIO_LABELS,
@@ -398,9 +399,14 @@ interface ScenarioCardProps {
 
 function ScenarioCard({ scenario, route, providers, expanded, onToggle, onUpdate, onRemove }: ScenarioCardProps) {
   const { t } = useTranslation()
+  const { data: catalog } = useModelCatalog()
   const hasRoute = route && route.providers.length > 0
   const isCustom = onRemove !== undefined
 
+  // Suggest the models the chosen provider offers, or all known models
+  const modelsFor = (provider: string) =>
+    (catalog?.models || []).filter((m) => !provider || m.providers.includes(provider))
+
   const addScenarioProvider = () => {
     const newProviders: ProviderRoute[] = [...(route?.providers || []), { name: '' }]
     onUpdate({ ...route, providers: newProviders })
@@ -483,7 +489,13 @@ function ScenarioCard({ scenario, route, providers, expanded, onToggle, onUpdate
                 onChange={(e) => updateScenarioProvider(index, { ...providerRoute, model: e.target.value || undefined })}
                 placeholder={t('profiles.modelOverride')}
                 className="flex-1"
+                list={`models-${scenario}-${index}`}
               />
+              <datalist id={`models-${scenario}-${index}`}>
+                {modelsFor(providerRoute.name).map((m) => (
+                  <option key={m.id} value={m.id} />
+                ))}
+              </datalist>
               <Button variant="ghost" size="icon" onClick={() => removeScenarioProvider(index)}>
                 <Trash2 className="h-4 w-4" />
               </Button>
@@ -50,6 +50,8 @@ export function ProviderEditPage() {
     claude_env_vars: {},
     codex_env_vars: {},
     opencode_env_vars: {},
+    gemini_env_vars: {},
+    aider_env_vars: {},
   })
 
   // Initialize form with existing data
@@ -85,6 +87,8 @@ export function ProviderEditPage() {
       claude: 'claude_env_vars',
       codex: 'codex_env_vars',
       opencode: 'opencode_env_vars',
+      gemini: 'gemini_env_vars',
+      aider: 'aider_env_vars',
     }
     const field = fieldMap[client]
     setFormData((prev) => ({
@@ -99,6 +103,8 @@ export function ProviderEditPage() {
       claude: 'claude_env_vars',
       codex: 'codex_env_vars',
       opencode: 'opencode_env_vars',
+      gemini: 'gemini_env_vars',
+      aider: 'aider_env_vars',
     }
     const field = fieldMap[client]
     const current = { ...(formData[field] as Record<string, string>) }
@@ -159,7 +165,7 @@ export function ProviderEditPage() {
                 <Label htmlFor="type">{t('providers.type')}</Label>
                 <Select
                   value={formData.type || 'anthropic'}
-                  onValueChange={(value) => setFormData({ ...formData, type: value as 'anthropic' | 'openai' })}
+                  onValueChange={(value) => setFormData({ ...formData, type: value as 'anthropic' | 'openai' | 'gemini' })}
                 >
                   <SelectTrigger id="type">
                     <SelectValue />
@@ -167,6 +173,7 @@ export function ProviderEditPage() {
                   <SelectContent>
                     <SelectItem value="anthropic">Anthropic</SelectItem>
                     <SelectItem value="openai">OpenAI Compatible</SelectItem>
+                    <SelectItem value="gemini">Google Gemini</SelectItem>
                   </SelectContent>
                 </Select>
               </div>
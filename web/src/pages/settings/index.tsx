@@ -5,6 +5,7 @@ import { GeneralSettings } from './tabs/GeneralSettings'
 import { BindingsSettings } from './tabs/BindingsSettings'
 import { SyncSettings } from './tabs/SyncSettings'
 import { PasswordSettings } from './tabs/PasswordSettings'
+import { ApiTokenSettings } from './tabs/ApiTokenSettings'
 import { PermissionSettings } from './tabs/PermissionSettings'
 
 export function SettingsPage() {
@@ -48,8 +49,9 @@ export function SettingsPage() {
           <SyncSettings />
         </TabsContent>
 
-        <TabsContent value="password" className="mt-4">
+        <TabsContent value="password" className="mt-4 space-y-4">
           <PasswordSettings />
+          <ApiTokenSettings />
         </TabsContent>
       </Tabs>
     </div>
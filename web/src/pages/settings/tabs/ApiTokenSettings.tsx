@@ -0,0 +1,118 @@
+import { useState } from 'react'
+import { useTranslation } from 'react-i18next'
+import { toast } from 'sonner'
+import { KeyRound, Trash2 } from 'lucide-react'
+import { Badge } from '@/components/ui/badge'
+import { Button } from '@/components/ui/button'
+import { Card, CardContent, CardDescription, CardHeader, CardTitle } from '@/components/ui/card'
+import { Input } from '@/components/ui/input'
+import { Label } from '@/components/ui/label'
+import { Select, SelectContent, SelectItem, SelectTrigger, SelectValue } from '@/components/ui/select'
+import { useApiTokens, useCreateApiToken, useRevokeApiToken } from '@/hooks/use-settings'
+import type { ApiTokenScope } from '@/types/api'
+
+export function ApiTokenSettings() {
+  const { t } = useTranslation()
+  const { data } = useApiTokens()
+  const createToken = useCreateApiToken()
+  const revokeToken = useRevokeApiToken()
+  const [name, setName] = useState('')
+  const [scope, setScope] = useState<ApiTokenScope>('read')
+  const [created, setCreated] = useState<string | null>(null)
+
+  const handleCreate = async (e: React.FormEvent) => {
+    e.preventDefault()
+    try {
+      const token = await createToken.mutateAsync({ name, scope })
+      setCreated(token.token)
+      setName('')
+    } catch (err) {
+      toast.error(err instanceof Error ? err.message : t('common.error'))
+    }
+  }
+
+  const handleRevoke = async (id: string) => {
+    try {
+      await revokeToken.mutateAsync(id)
+      toast.success(t('settings.tokenRevoked'))
+    } catch (err) {
+      toast.error(err instanceof Error ? err.message : t('common.error'))
+    }
+  }
+
+  const tokens = data?.tokens ?? []
+
+  return (
+    <Card>
+      <CardHeader>
+        <CardTitle className="flex items-center gap-2">
+          <KeyRound className="h-5 w-5" />
+          {t('settings.apiTokens')}
+        </CardTitle>
+        <CardDescription>{t('settings.apiTokensDesc')}</CardDescription>
+      </CardHeader>
+      <CardContent className="space-y-4">
+        <form onSubmit={handleCreate} className="flex flex-wrap items-end gap-4">
+          <div className="grid gap-2">
+            <Label htmlFor="token-name">{t('settings.tokenName')}</Label>
+            <Input id="token-name" value={name} onChange={(e) => setName(e.target.value)} placeholder="ci" />
+          </div>
+          <div className="grid gap-2">
+            <Label htmlFor="token-scope">{t('settings.tokenScope')}</Label>
+            <Select value={scope} onValueChange={(v) => setScope(v as ApiTokenScope)}>
+              <SelectTrigger id="token-scope" className="w-40">
+                <SelectValue />
+              </SelectTrigger>
+              <SelectContent>
+                <SelectItem value="read">{t('settings.scopeRead')}</SelectItem>
+                <SelectItem value="admin">{t('settings.scopeAdmin')}</SelectItem>
+              </SelectContent>
+            </Select>
+          </div>
+          <Button type="submit" disabled={!name.trim() || createToken.isPending}>
+            {t('settings.createToken')}
+          </Button>
+        </form>
+
+        {created && (
+          <div className="space-y-2 rounded-md border p-4">
+            <p className="text-sm">{t('settings.tokenCreated')}</p>
+            <code className="block break-all rounded bg-muted p-2 text-sm">{created}</code>
+          </div>
+        )}
+
+        {tokens.length === 0 ? (
+          <p className="text-sm text-muted-foreground">{t('settings.noTokens')}</p>
+        ) : (
+          <div className="space-y-2">
+            {tokens.map((token) => (
+              <div key={token.id} className="flex items-center justify-between rounded-md border p-3">
+                <div className="space-y-1">
+                  <div className="flex items-center gap-2">
+                    <span className="font-medium">{token.name}</span>
+                    <Badge variant={token.scope === 'admin' ? 'destructive' : 'secondary'}>
+                      {token.scope === 'admin' ? t('settings.scopeAdmin') : t('settings.scopeRead')}
+                    </Badge>
+                    <code className="text-xs text-muted-foreground">…{token.hint}</code>
+                  </div>
+                  <p className="text-xs text-muted-foreground">
+                    {token.last_used_at
+                      ? t('settings.tokenLastUsed', {
+                          time: new Date(token.last_used_at).toLocaleString(),
+                          from: token.last_used_from ?? '-',
+                        })
+                      : t('settings.tokenNeverUsed')}
+                  </p>
+                </div>
+                <Button variant="outline" size="sm" onClick={() => handleRevoke(token.id)} disabled={revokeToken.isPending}>
+                  <Trash2 className="mr-1 h-3 w-3" />
+                  {t('settings.revokeToken')}
+                </Button>
+              </div>
+            ))}
+          </div>
+        )}
+      </CardContent>
+    </Card>
+  )
+}
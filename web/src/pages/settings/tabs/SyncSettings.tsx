@@ -56,6 +56,8 @@ function SyncForm({ initialConfig }: { initialConfig?: SyncConfig }) {
   const [passphrase, setPassphrase] = useState(initialConfig?.passphrase ?? '')
   const [autoPull, setAutoPull] = useState(initialConfig?.auto_pull ?? false)
   const [pullInterval, setPullInterval] = useState(initialConfig?.pull_interval ?? 300)
+  const [autoPush, setAutoPush] = useState(initialConfig?.auto_push ?? false)
+  const [pushDebounce, setPushDebounce] = useState(initialConfig?.push_debounce ?? 10)
 
   const saveMutation = useMutation({
     mutationFn: async (config: Partial<SyncConfig>) => {
@@ -83,6 +85,8 @@ function SyncForm({ initialConfig }: { initialConfig?: SyncConfig }) {
       backend,
       auto_pull: autoPull,
       pull_interval: pullInterval,
+      auto_push: autoPush,
+      push_debounce: pushDebounce,
       passphrase,
     }
     if (backend === 'gist') {
@@ -172,6 +176,17 @@ function SyncForm({ initialConfig }: { initialConfig?: SyncConfig }) {
                   <Input type="number" value={pullInterval} onChange={e => setPullInterval(Number(e.target.value))} min={60} />
                 </div>
               )}
+              <div className="flex items-center gap-2">
+                <Switch id="auto-push" checked={autoPush} onCheckedChange={setAutoPush} />
+                <Label htmlFor="auto-push">{t('settings.sync.autoPush', 'Auto Push')}</Label>
+              </div>
+              {autoPush && (
+                <div className="space-y-2">
+                  <Label>{t('settings.sync.pushDebounce', 'Push Delay (seconds)')}</Label>
+                  <Input type="number" value={pushDebounce} onChange={e => setPushDebounce(Number(e.target.value))} min={1} />
+                  <p className="text-xs text-muted-foreground">{t('settings.sync.pushDebounceHint', 'Changes are pushed once the config has been quiet this long')}</p>
+                </div>
+              )}
             </>
           )}
 
@@ -196,8 +211,12 @@ function SyncForm({ initialConfig }: { initialConfig?: SyncConfig }) {
 
           {syncStatus && initialConfig?.configured && (
             <div className="text-sm text-muted-foreground pt-2">
-              {syncStatus.last_sync && <p>{t('settings.sync.lastSync', 'Last sync')}: {new Date(syncStatus.last_sync).toLocaleString()}</p>}
-              {syncStatus.last_error && <p className="text-destructive">{t('settings.sync.lastError', 'Last error')}: {syncStatus.last_error}</p>}
+              {isSet(syncStatus.last_pull_at) && <p>{t('settings.sync.lastPull', 'Last pull')}: {new Date(syncStatus.last_pull_at!).toLocaleString()}</p>}
+              {isSet(syncStatus.last_push_at) && <p>{t('settings.sync.lastPush', 'Last push')}: {new Date(syncStatus.last_push_at!).toLocaleString()}</p>}
+              {syncStatus.encrypted && !!syncStatus.key_version && <p>{t('settings.sync.keyVersion', 'Encryption key version')}: {syncStatus.key_version}</p>}
+              {syncStatus.push_pending && <p>{t('settings.sync.pushPending', 'Push pending')}</p>}
+              {syncStatus.last_push_status === 'skipped' && <p>{t('settings.sync.pushSkipped', 'Last push skipped: already in sync')}</p>}
+              {syncStatus.last_push_error && <p className="text-destructive">{t('settings.sync.lastError', 'Last error')}: {syncStatus.last_push_error}</p>}
             </div>
           )}
         </CardContent>
@@ -206,6 +225,12 @@ function SyncForm({ initialConfig }: { initialConfig?: SyncConfig }) {
   )
 }
 
+// isSet reports whether a timestamp from the API is set; Go encodes the
+// zero time rather than omitting it.
+function isSet(ts?: string) {
+  return !!ts && !ts.startsWith('0001-')
+}
+
 function GistFields({ gistId, setGistId, token, setToken }: {
   gistId: string; setGistId: (v: string) => void
   token: string; setToken: (v: string) => void
@@ -56,6 +56,20 @@ export const handlers = [
     return HttpResponse.json({ success: true })
   }),
 
+  // Models
+  http.get('/api/v1/models', () => {
+    return HttpResponse.json({
+      models: [
+        { id: 'claude-sonnet-4-5', providers: ['anthropic'], references: [{ provider: 'anthropic', field: 'model' }] },
+        { id: 'gpt-4', providers: ['openai'], references: [{ provider: 'openai', field: 'model' }] },
+      ],
+      providers: [
+        { name: 'anthropic', models: 1, cached: false, fetched_at: '2024-01-01T00:00:00Z' },
+        { name: 'openai', models: 1, cached: false, fetched_at: '2024-01-01T00:00:00Z' },
+      ],
+    })
+  }),
+
   // Settings
   http.get('/api/v1/settings', () => {
     return HttpResponse.json({
@@ -164,7 +178,7 @@ export const handlers = [
   }),
 
   http.get('/api/v1/sync/status', () => {
-    return HttpResponse.json({ enabled: false })
+    return HttpResponse.json({ configured: false, auto_pull: false, auto_push: false, push_pending: false })
   }),
 
   // Auth
@@ -284,6 +298,35 @@ export const handlers = [
     return HttpResponse.json({ gist_id: 'abc123', gist_url: 'https://gist.github.com/abc123' })
   }),
 
+  // Dashboard
+  http.get('/api/v1/dashboard', () => {
+    return HttpResponse.json({
+      version: '3.0.0',
+      generated_at: '2024-01-01T12:00:00Z',
+      currency: 'USD',
+      today: {
+        cost: 1.5,
+        request_count: 42,
+        input_tokens: 12000,
+        output_tokens: 3400,
+        responses: 44,
+        errors: 2,
+        error_rate: 0.045,
+      },
+      active_sessions: 1,
+      top_models: [{ name: 'claude-sonnet-4-5', cost: 1.2, request_count: 30 }],
+      top_providers: [{ name: 'anthropic', cost: 1.5, request_count: 42 }],
+      budget: { currency: 'USD', daily_spent: 1.5, daily_limit: 10 },
+      health: {
+        status: 'healthy',
+        updated_at: '2024-01-01T12:00:00Z',
+        counts: { healthy: 1 },
+        providers: [{ name: 'anthropic', status: 'healthy', success_rate: 100, latency_ms: 150 }],
+      },
+      agents: { observatory: {}, task_queue: {}, guardrails: {} },
+    })
+  }),
+
   // Budget
   http.get('/api/v1/budget', () => {
     return HttpResponse.json({
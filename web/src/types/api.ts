@@ -7,7 +7,7 @@ export interface UnavailableMarking {
 
 export interface Provider {
   name: string
-  type?: 'anthropic' | 'openai'
+  type?: 'anthropic' | 'openai' | 'gemini'
   base_url: string
   auth_token: string
   proxy_url?: string
@@ -20,11 +20,47 @@ export interface Provider {
   claude_env_vars?: Record<string, string>
   codex_env_vars?: Record<string, string>
   opencode_env_vars?: Record<string, string>
+  gemini_env_vars?: Record<string, string>
+  aider_env_vars?: Record<string, string>
+  safety_settings?: Record<string, string>
+  cost_model?: CostModel
+  transforms?: ProviderTransforms
+  capture_bodies?: boolean
+  max_concurrent?: number
+  queue_size?: number
+  queue_timeout_sec?: number
   disabled?: UnavailableMarking
 }
 
+export interface TransformRules {
+  set_headers?: Record<string, string>
+  remove_headers?: string[]
+  remove_fields?: string[]
+  rename_fields?: Record<string, string>
+  default_fields?: Record<string, unknown>
+  set_fields?: Record<string, unknown>
+}
+
+export interface ProviderTransforms {
+  request?: TransformRules
+  response?: TransformRules
+}
+
+export interface PricingTier {
+  up_to_input_tokens?: number
+  input_per_million: number
+  output_per_million: number
+}
+
+export interface CostModel {
+  type: 'token' | 'per_request' | 'per_second' | 'tiered'
+  per_request?: number
+  per_second?: number
+  tiers?: PricingTier[]
+}
+
 // Available clients
-export const AVAILABLE_CLIENTS = ['claude', 'codex', 'opencode'] as const
+export const AVAILABLE_CLIENTS = ['claude', 'codex', 'opencode', 'gemini', 'aider'] as const
 export type ClientType = (typeof AVAILABLE_CLIENTS)[number]
 
 // Common environment variables per client (excluding API keys and models which are set by provider)
@@ -45,6 +81,14 @@ export const CLIENT_ENV_HINTS: Record<ClientType, string[]> = {
     'OPENCODE_PROVIDER',
     'OPENCODE_AUTO_COMPACT',
   ],
+  gemini: [
+    'GEMINI_MODEL',
+    'GEMINI_SANDBOX',
+  ],
+  aider: [
+    'AIDER_MODEL',
+    'AIDER_AUTO_COMMITS',
+  ],
 }
 
 // Scenarios
@@ -81,6 +125,15 @@ export interface ScenarioRoute {
   provider_weights?: Record<string, number>
   long_context_threshold?: number
   fallback_to_default?: boolean
+  match?: ScenarioMatch
+}
+
+// Match rules for a custom scenario (every condition set must hold)
+export interface ScenarioMatch {
+  system_pattern?: string
+  tools?: string[]
+  min_tokens?: number
+  max_tokens?: number
 }
 
 // Load balance strategy
@@ -100,11 +153,84 @@ export interface Profile {
   routing?: Record<string, ScenarioRoute>
   long_context_threshold?: number
   strategy?: LoadBalanceStrategy
+  cache_affinity?: boolean
+  mirror?: MirrorConfig
+  request_transform?: RequestTransform
   is_default?: boolean
 }
 
+// Rewrites applied to every request sent through a profile
+export interface RequestTransform {
+  system_prepend?: string
+  system_append?: string
+  strip_system?: string[]
+  strip_tools?: string[]
+  max_tokens_cap?: number
+  temperature?: number
+}
+
+// Shadow traffic: mirror a share of requests to a provider under evaluation
+export interface MirrorConfig {
+  provider: string
+  percent: number
+}
+
+export interface ExperimentSide {
+  errors: number
+  error_rate: number
+  avg_latency_ms: number
+  input_tokens: number
+  output_tokens: number
+  cost_usd: number
+}
+
+export interface ExperimentReport {
+  profile: string
+  mirror_provider: string
+  samples: number
+  primary: ExperimentSide
+  mirror: ExperimentSide
+  latency_diff_ms: number
+  cost_diff_usd: number
+  error_rate_diff: number
+  last_sample_at: string
+}
+
+// Captured request/response bodies for one provider attempt
+export interface BodyCapture {
+  request_id: string
+  timestamp: string
+  provider: string
+  session_id?: string
+  status_code: number
+  request_body: string
+  response_body: string
+  request_truncated?: boolean
+  response_truncated?: boolean
+}
+
+export interface BodyCaptureResponse {
+  request_id: string
+  attempts: BodyCapture[]
+}
+
 // Log types
+export interface Annotation {
+  labels: string[]
+  note?: string
+  bookmarked?: boolean
+  updated_at: string
+}
+
+// Body of PATCH /sessions/{id} and /logs/{id}; omitted fields are kept
+export interface AnnotationPatch {
+  labels?: string[]
+  note?: string
+  bookmarked?: boolean
+}
+
 export interface LogEntry {
+  id?: number
   timestamp: string
   level: string
   provider: string
@@ -116,12 +242,16 @@ export interface LogEntry {
   response_body?: string
   session_id?: string
   client_type?: string
+  project?: string // set on entries from /api/v1/logs/stream
+  annotation?: Annotation
 }
 
 export interface LogsResponse {
   entries: LogEntry[]
   total: number
   providers: string[]
+  next_cursor?: string // pass as before for the next, older page
+  prev_cursor?: string // pass as after for the previous, newer page
 }
 
 // Request monitoring types
@@ -163,16 +293,75 @@ export interface UsageSummary {
   total_requests: number
   total_input_tokens: number
   total_output_tokens: number
+  total_cache_creation_tokens?: number
+  total_cache_read_tokens?: number
+  cache_hit_rate?: number
   total_cost: number
   request_count: number
   by_provider: Record<string, ProviderUsage>
   by_model: Record<string, ModelUsage>
+  response_cache?: ResponseCacheStats
+}
+
+export interface ModelAlias {
+  match: string
+  target?: string
+  provider?: string
+  strip_date?: boolean
+}
+
+export interface ModelPricing {
+  input_per_million: number
+  output_per_million: number
+  cache_write_per_million?: number
+  cache_read_per_million?: number
+  batch_discount?: number
+}
+
+export interface ModelReference {
+  profile?: string
+  scenario?: string
+  provider: string
+  field?: string
+}
+
+export interface CatalogModel {
+  id: string
+  providers: string[]
+  pricing?: ModelPricing
+  references?: ModelReference[]
+}
+
+export interface CatalogProvider {
+  name: string
+  models: number
+  cached: boolean
+  fetched_at: string
+  error?: string
+}
+
+export interface ModelCatalog {
+  models: CatalogModel[]
+  providers: CatalogProvider[]
+}
+
+export interface ResponseCacheStats {
+  enabled: boolean
+  entries: number
+  hits: number
+  misses: number
+  hit_rate: number
+  saved_input_tokens: number
+  saved_output_tokens: number
 }
 
 export interface ProviderUsage {
   requests: number
   input_tokens: number
   output_tokens: number
+  cache_creation_tokens?: number
+  cache_read_tokens?: number
+  cache_hit_rate?: number
   cost: number
 }
 
@@ -180,6 +369,9 @@ export interface ModelUsage {
   requests: number
   input_tokens: number
   output_tokens: number
+  cache_creation_tokens?: number
+  cache_read_tokens?: number
+  cache_hit_rate?: number
   cost: number
 }
 
@@ -226,6 +418,9 @@ export interface ProviderHealth {
   success_rate: number
   last_check: string
   error?: string
+  avg_ttft_ms?: number
+  tokens_per_sec?: number
+  stream_samples?: number
 }
 
 // Auto-permission types
@@ -262,6 +457,16 @@ export interface Binding {
   path: string
   profile?: string
   cli?: string
+  compression?: CompressionOverride
+}
+
+export interface CompressionOverride {
+  enabled?: boolean
+  threshold_tokens?: number
+  target_tokens?: number
+  summary_model?: string
+  preserve_recent?: number
+  strategy?: string
 }
 
 // Sync types
@@ -291,13 +496,23 @@ export interface SyncConfig {
   // Common
   auto_pull?: boolean
   pull_interval?: number
+  auto_push?: boolean
+  push_debounce?: number
 }
 
 export interface SyncStatus {
-  enabled: boolean
-  last_sync?: string
-  last_error?: string
-  gist_url?: string
+  configured: boolean
+  backend?: string
+  device_id?: string
+  last_pull_at?: string
+  last_push_at?: string
+  auto_pull?: boolean
+  auto_push?: boolean
+  push_pending?: boolean
+  last_push_status?: 'ok' | 'failed' | 'skipped'
+  last_push_error?: string
+  encrypted?: boolean
+  key_version?: number
 }
 
 // Webhook types
@@ -319,6 +534,49 @@ export interface Session {
   last_activity: string
   request_count: number
   total_tokens: number
+  annotation?: Annotation
+}
+
+export interface ContextPoint {
+  timestamp: string
+  model?: string
+  context_tokens: number
+  window: number
+  percent: number
+}
+
+// Context window use over a session's turns, from GET /sessions/{id}
+export interface ContextUtilization {
+  session_id: string
+  current: ContextPoint
+  peak_percent: number
+  warn_percent: number
+  timeline: ContextPoint[]
+}
+
+export interface ReplayRequest {
+  request_id?: string
+  provider?: string
+  model?: string
+}
+
+export interface ReplayResponse {
+  provider: string
+  model?: string
+  status_code: number
+  latency_ms?: number
+  text: string
+  body?: string
+  error?: string
+}
+
+export interface ReplayResult {
+  request_id: string
+  session_id?: string
+  original: ReplayResponse
+  replay: ReplayResponse
+  identical: boolean
+  diff: { op: '=' | '-' | '+'; text: string }[]
 }
 
 // Middleware types
@@ -332,6 +590,35 @@ export interface MiddlewareEntry {
   description?: string
   priority?: number
   config?: Record<string, unknown>
+  when?: MiddlewareMatch
+  stats?: unknown
+  metrics?: MiddlewareStats
+}
+
+export interface MiddlewareMatch {
+  providers?: string[]
+  exclude_providers?: string[]
+  models?: string[]
+  projects?: string[]
+  headers?: Record<string, string>
+}
+
+export interface MiddlewarePhaseStats {
+  calls: number
+  errors: number
+  total_ms: number
+  avg_ms: number
+  max_ms: number
+  last_ms: number
+}
+
+export interface MiddlewareStats {
+  name: string
+  request: MiddlewarePhaseStats
+  response: MiddlewarePhaseStats
+  rejections: number
+  since: string
+  stats?: unknown
 }
 
 export interface MiddlewareConfig {
@@ -343,6 +630,24 @@ export interface MiddlewareConfig {
 export interface AuthCheckResponse {
   authenticated: boolean
   password_set: boolean
+  role?: 'admin' | 'viewer'
+  user?: string // set when a trusted reverse proxy identified the user
+}
+
+export type ApiTokenScope = 'read' | 'admin'
+
+export interface ApiToken {
+  id: string
+  name: string
+  scope: ApiTokenScope
+  hint: string // last four characters of the token
+  created_at: string
+  last_used_at?: string
+  last_used_from?: string
+}
+
+export interface CreatedApiToken extends ApiToken {
+  token: string // only returned on creation
 }
 
 export interface LoginRequest {
@@ -367,6 +672,58 @@ export interface HealthResponse {
   version: string
 }
 
+// Dashboard summary (GET /api/v1/dashboard)
+export interface DashboardRank {
+  name: string
+  cost: number
+  request_count: number
+}
+
+export interface Dashboard {
+  version: string
+  generated_at: string
+  currency: string
+  today: {
+    cost: number
+    request_count: number
+    input_tokens: number
+    output_tokens: number
+    responses: number
+    errors: number
+    error_rate: number
+  }
+  active_sessions: number
+  top_models: DashboardRank[]
+  top_providers: DashboardRank[]
+  budget?: Record<string, number | string | boolean>
+  health: {
+    status: string
+    updated_at: string
+    counts: Record<string, number>
+    providers: { name: string; status: string; success_rate: number; latency_ms?: number }[]
+  }
+  agents: {
+    observatory: Record<string, unknown>
+    task_queue: Record<string, number>
+    guardrails: Record<string, unknown>
+  }
+}
+
+// Agent file changes (GET /api/v1/agent/changes)
+export interface AgentFileChange {
+  id: string
+  path: string
+  session_id: string
+  project_path?: string
+  change_type: 'create' | 'modify' | 'delete'
+  summary: string
+  diff?: string
+  status: 'pending' | 'approved' | 'reverted'
+  revertable: boolean
+  timestamp: string
+  reviewed_at?: string
+}
+
 // Bot types
 export interface BotConfig {
   enabled: boolean
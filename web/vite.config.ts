@@ -1,12 +1,46 @@
-import { defineConfig } from 'vite'
+import { defineConfig, type Plugin } from 'vite'
 import react from '@vitejs/plugin-react'
+import fs from 'fs'
 import path from 'path'
+import zlib from 'zlib'
 
 // API port: use VITE_API_PORT env var or default to 19840 (production)
 const apiPort = process.env.VITE_API_PORT || '19840'
 
+// Writes .br and .gz copies of compressible build output next to each file.
+// The daemon embeds them and serves them to clients that accept them.
+function precompress(): Plugin {
+  const compressible = /\.(html|js|mjs|css|json|svg|txt|map)$/
+  return {
+    name: 'gozen-precompress',
+    apply: 'build',
+    closeBundle() {
+      const walk = (dir: string) => {
+        for (const entry of fs.readdirSync(dir, { withFileTypes: true })) {
+          const file = path.join(dir, entry.name)
+          if (entry.isDirectory()) {
+            walk(file)
+            continue
+          }
+          if (!compressible.test(entry.name)) continue
+          const data = fs.readFileSync(file)
+          if (data.length < 1024) continue
+          fs.writeFileSync(
+            `${file}.br`,
+            zlib.brotliCompressSync(data, {
+              params: { [zlib.constants.BROTLI_PARAM_QUALITY]: zlib.constants.BROTLI_MAX_QUALITY },
+            }),
+          )
+          fs.writeFileSync(`${file}.gz`, zlib.gzipSync(data, { level: 9 }))
+        }
+      }
+      walk(path.resolve(__dirname, '../internal/web/dist'))
+    },
+  }
+}
+
 export default defineConfig({
-  plugins: [react()],
+  plugins: [react(), precompress()],
   resolve: {
     alias: {
       '@': path.resolve(__dirname, './src'),
@@ -18,6 +52,9 @@ export default defineConfig({
       '/api': {
         target: `http://127.0.0.1:${apiPort}`,
         changeOrigin: true,
+        // WebSocket endpoints only accept same-origin connections
+        ws: true,
+        headers: { origin: `http://127.0.0.1:${apiPort}` },
       },
     },
   },